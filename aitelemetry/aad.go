@@ -0,0 +1,73 @@
+package aitelemetry
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/pkg/errors"
+)
+
+// refresh tokens this long before they expire so in-flight batches are not rejected
+const tokenRefreshMargin = 5 * time.Minute
+
+// aadScope returns the token scope for ingestion in the given cloud, the public cloud by default.
+func aadScope(cloud string) (string, error) {
+	if cloud == "" {
+		cloud = AzurePublicCloud
+	}
+	audience, ok := azureMonitorAudiences[cloud]
+	if !ok {
+		return "", errors.Wrapf(ErrUnknownCloud, "%q", cloud)
+	}
+	return fmt.Sprintf(azureMonitorScopeFmt, audience), nil
+}
+
+// bearerTokenTransport authenticates requests to the ingestion endpoint with an AAD token.
+type bearerTokenTransport struct {
+	base       http.RoundTripper
+	credential azcore.TokenCredential
+	scope      string
+
+	mu    sync.Mutex
+	token azcore.AccessToken
+}
+
+func newBearerTokenTransport(base http.RoundTripper, credential azcore.TokenCredential, scope string) *bearerTokenTransport {
+	return &bearerTokenTransport{
+		base:       base,
+		credential: credential,
+		scope:      scope,
+	}
+}
+
+func (t *bearerTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.getToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the original request
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req) //nolint:wrapcheck // pass through transport errors unchanged
+}
+
+func (t *bearerTokenTransport) getToken(req *http.Request) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token.Token != "" && time.Until(t.token.ExpiresOn) > tokenRefreshMargin {
+		return t.token.Token, nil
+	}
+
+	token, err := t.credential.GetToken(req.Context(), policy.TokenRequestOptions{Scopes: []string{t.scope}})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get token for telemetry ingestion")
+	}
+	t.token = token
+	return token.Token, nil
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/microsoft/ApplicationInsights-Go/appinsights"
 )
 
@@ -40,6 +41,18 @@ type AIConfig struct {
 	GetEnvRetryCount             int
 	GetEnvRetryWaitTimeInSecs    int
 	DebugMode                    bool
+	// Cloud selects the Azure Monitor audience for AAD authenticated ingestion, AzurePublicCloud by default.
+	Cloud string
+	// Credential, if set, authenticates ingestion with AAD tokens instead of the bare instrumentation key.
+	Credential azcore.TokenCredential
+	// IngestionEndpoint overrides the endpoint telemetry is sent to, for sovereign and air-gapped clouds.
+	// It takes precedence over the endpoint of a connection string, and when set NewAITelemetry does not
+	// require the node to be in the public cloud.
+	IngestionEndpoint string
+	// Proxy configures the proxy telemetry is sent through, see ProxyConfig.
+	Proxy ProxyConfig
+	// QueueSize is the number of items buffered for sending. The oldest item is dropped when it is full.
+	QueueSize int
 }
 
 // TelmetryHandle holds appinsight handles and metadata
@@ -52,6 +65,7 @@ type telemetryHandle struct {
 	disableMetadataRefreshThread bool
 	refreshTimeout               int
 	rwmutex                      sync.RWMutex
+	queue                        *sendQueue
 }
 
 // Telemetry Interface to send metrics/Logs to appinsights
@@ -69,4 +83,6 @@ type TelemetryHandle interface {
 	Close(timeout int)
 	// Flush - forces the current queue to be sent
 	Flush()
+	// Stats returns the counters of queued and dropped items.
+	Stats() TelemetryStats
 }
@@ -0,0 +1,120 @@
+package aitelemetry
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	connStrInstrumentationKey = "instrumentationkey"
+	connStrIngestionEndpoint  = "ingestionendpoint"
+	connStrEndpointSuffix     = "endpointsuffix"
+
+	// AAD authenticated ingestion is only served by the v2.1 track API
+	aadTrackPath    = "v2.1/track"
+	trackPath       = "v2/track"
+	defaultEndpoint = "https://dc.services.visualstudio.com/"
+)
+
+// Clouds with well known Azure Monitor audiences.
+const (
+	AzurePublicCloud     = "AzurePublicCloud"
+	AzureChinaCloud      = "AzureChinaCloud"
+	AzureUSGovernment    = "AzureUSGovernment"
+	azureMonitorScopeFmt = "https://%s//.default"
+)
+
+// azureMonitorAudiences maps each cloud to the audience tokens for ingestion are requested for.
+var azureMonitorAudiences = map[string]string{
+	AzurePublicCloud:  "monitor.azure.com",
+	AzureChinaCloud:   "monitor.azure.cn",
+	AzureUSGovernment: "monitor.azure.us",
+}
+
+var (
+	ErrInvalidConnectionString = errors.New("invalid connection string")
+	ErrUnknownCloud            = errors.New("unknown cloud")
+	ErrInvalidEndpoint         = errors.New("invalid ingestion endpoint")
+)
+
+// ConnectionString is a parsed Azure Monitor connection string.
+type ConnectionString struct {
+	InstrumentationKey string
+	IngestionEndpoint  string
+}
+
+// ParseConnectionString parses an Azure Monitor connection string of the form
+// "InstrumentationKey=<key>;IngestionEndpoint=<url>". If no ingestion endpoint is given
+// it is derived from EndpointSuffix, or defaults to the public cloud endpoint.
+func ParseConnectionString(s string) (ConnectionString, error) {
+	var cs ConnectionString
+	var suffix string
+
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return cs, errors.Wrapf(ErrInvalidConnectionString, "malformed segment %q", part)
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case connStrInstrumentationKey:
+			cs.InstrumentationKey = strings.TrimSpace(value)
+		case connStrIngestionEndpoint:
+			cs.IngestionEndpoint = strings.TrimSpace(value)
+		case connStrEndpointSuffix:
+			suffix = strings.TrimSpace(value)
+		}
+	}
+
+	if cs.InstrumentationKey == "" {
+		return cs, errors.Wrap(ErrInvalidConnectionString, "missing InstrumentationKey")
+	}
+
+	switch {
+	case cs.IngestionEndpoint != "":
+	case suffix != "":
+		cs.IngestionEndpoint = "https://dc." + strings.TrimPrefix(suffix, ".")
+	default:
+		cs.IngestionEndpoint = defaultEndpoint
+	}
+
+	var err error
+	if cs.IngestionEndpoint, err = NormalizeIngestionEndpoint(cs.IngestionEndpoint); err != nil {
+		return cs, errors.Wrap(ErrInvalidConnectionString, err.Error())
+	}
+
+	return cs, nil
+}
+
+// NormalizeIngestionEndpoint validates an ingestion endpoint, such as the endpoint of a sovereign or
+// air-gapped cloud, and returns it with a trailing slash. The endpoint must be an absolute http or https
+// URL without query or fragment.
+func NormalizeIngestionEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", errors.Wrapf(ErrInvalidEndpoint, "%q: %v", endpoint, err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return "", errors.Wrapf(ErrInvalidEndpoint, "%q: scheme must be http or https", endpoint)
+	}
+	if u.Host == "" {
+		return "", errors.Wrapf(ErrInvalidEndpoint, "%q: missing host", endpoint)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", errors.Wrapf(ErrInvalidEndpoint, "%q: must not have a query or fragment", endpoint)
+	}
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint += "/"
+	}
+	return endpoint, nil
+}
+
+// TrackURL returns the URL telemetry is submitted to with AAD authentication.
+func (cs ConnectionString) TrackURL() string {
+	return cs.IngestionEndpoint + aadTrackPath
+}
@@ -0,0 +1,162 @@
+package aitelemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConnectionString(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    ConnectionString
+		wantErr bool
+	}{
+		{
+			name: "ingestion endpoint",
+			in:   "InstrumentationKey=00000000-0000-0000-0000-000000000000;IngestionEndpoint=https://eastus-0.in.applicationinsights.azure.com/",
+			want: ConnectionString{
+				InstrumentationKey: "00000000-0000-0000-0000-000000000000",
+				IngestionEndpoint:  "https://eastus-0.in.applicationinsights.azure.com/",
+			},
+		},
+		{
+			name: "endpoint suffix for sovereign cloud",
+			in:   "instrumentationkey=key;EndpointSuffix=applicationinsights.azure.cn",
+			want: ConnectionString{InstrumentationKey: "key", IngestionEndpoint: "https://dc.applicationinsights.azure.cn/"},
+		},
+		{
+			name: "default endpoint",
+			in:   "InstrumentationKey=key;",
+			want: ConnectionString{InstrumentationKey: "key", IngestionEndpoint: defaultEndpoint},
+		},
+		{
+			name:    "missing key",
+			in:      "IngestionEndpoint=https://example.com",
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			in:      "InstrumentationKey",
+			wantErr: true,
+		},
+		{
+			name:    "invalid endpoint",
+			in:      "InstrumentationKey=key;IngestionEndpoint=ftp://example.com",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConnectionString(tt.in)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidConnectionString)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizeIngestionEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "adds trailing slash",
+			in:   "https://dc.applicationinsights.azure.cn",
+			want: "https://dc.applicationinsights.azure.cn/",
+		},
+		{
+			name: "air-gapped http collector with path",
+			in:   "http://10.0.0.4:8080/ingest/",
+			want: "http://10.0.0.4:8080/ingest/",
+		},
+		{
+			name:    "relative",
+			in:      "dc.applicationinsights.azure.cn",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			in:      "ftp://dc.applicationinsights.azure.cn",
+			wantErr: true,
+		},
+		{
+			name:    "query",
+			in:      "https://dc.applicationinsights.azure.cn/?key=value",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeIngestionEndpoint(tt.in)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidEndpoint)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAADScope(t *testing.T) {
+	scope, err := aadScope("")
+	require.NoError(t, err)
+	require.Equal(t, "https://monitor.azure.com//.default", scope)
+
+	scope, err = aadScope(AzureUSGovernment)
+	require.NoError(t, err)
+	require.Equal(t, "https://monitor.azure.us//.default", scope)
+
+	_, err = aadScope("Mars")
+	require.ErrorIs(t, err, ErrUnknownCloud)
+}
+
+type fakeCredential struct {
+	calls  int
+	scopes []string
+}
+
+func (f *fakeCredential) GetToken(_ context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	f.calls++
+	f.scopes = opts.Scopes
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestBearerTokenTransport(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	cred := &fakeCredential{}
+	client := &http.Client{Transport: newBearerTokenTransport(http.DefaultTransport, cred, "https://monitor.azure.com//.default")}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL) //nolint:noctx // test request
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	require.Equal(t, "Bearer token", gotAuth)
+	require.Equal(t, 1, cred.calls, "token should be cached until close to expiry")
+	require.Equal(t, []string{"https://monitor.azure.com//.default"}, cred.scopes)
+}
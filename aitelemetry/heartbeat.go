@@ -0,0 +1,117 @@
+package aitelemetry
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeartbeatMetricName is the name of the heartbeat metric. Summing it gives the number of heartbeats received.
+	HeartbeatMetricName = "HeartBeat"
+
+	// Heartbeat dimensions
+	HeartbeatComponentStr       = "Component"
+	HeartbeatBuildVersionStr    = "BuildVersion"
+	HeartbeatGoVersionStr       = "GoVersion"
+	HeartbeatEnabledTogglesStr  = "EnabledToggles"
+	HeartbeatDisabledTogglesStr = "DisabledToggles"
+	HeartbeatUptimeSecondsStr   = "UptimeSeconds"
+	HeartbeatGoroutinesStr      = "Goroutines"
+)
+
+// Heartbeat builds the periodic heartbeat metric of an agent. Every heartbeat carries the build version,
+// the feature toggles and the health counters of the agent, so that dashboards can detect agents which
+// stopped reporting or which are running with an unexpected configuration.
+type Heartbeat struct {
+	component string
+	version   string
+	enabled   string
+	disabled  string
+	counters  func() map[string]float64
+	start     time.Time
+}
+
+// NewHeartbeat creates the heartbeat of component. counters, if not nil, is called on each heartbeat and
+// the returned counters are added to the uptime and goroutine counters which are always reported.
+func NewHeartbeat(component, version string, toggles map[string]bool, counters func() map[string]float64) *Heartbeat {
+	var enabled, disabled []string
+	for name, on := range toggles {
+		if on {
+			enabled = append(enabled, name)
+		} else {
+			disabled = append(disabled, name)
+		}
+	}
+	sort.Strings(enabled)
+	sort.Strings(disabled)
+
+	return &Heartbeat{
+		component: component,
+		version:   version,
+		enabled:   strings.Join(enabled, ","),
+		disabled:  strings.Join(disabled, ","),
+		counters:  counters,
+		start:     time.Now(),
+	}
+}
+
+// Metric returns the heartbeat metric as of now.
+func (h *Heartbeat) Metric() Metric {
+	dims := map[string]string{
+		HeartbeatComponentStr:       h.component,
+		HeartbeatBuildVersionStr:    h.version,
+		HeartbeatGoVersionStr:       runtime.Version(),
+		HeartbeatEnabledTogglesStr:  h.enabled,
+		HeartbeatDisabledTogglesStr: h.disabled,
+		HeartbeatUptimeSecondsStr:   strconv.FormatInt(int64(time.Since(h.start).Seconds()), 10),
+		HeartbeatGoroutinesStr:      strconv.Itoa(runtime.NumGoroutine()),
+	}
+	if h.counters != nil {
+		for name, value := range h.counters() {
+			dims[name] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+
+	return Metric{
+		Name:             HeartbeatMetricName,
+		Value:            1.0,
+		AppVersion:       h.version,
+		CustomDimensions: dims,
+	}
+}
+
+// Run sends a heartbeat through send every interval until ctx is done.
+func (h *Heartbeat) Run(ctx context.Context, interval time.Duration, send func(Metric)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			send(h.Metric())
+		}
+	}
+}
+
+// TogglesFromStruct returns the bool fields of a toggle or config struct, keyed by field name.
+func TogglesFromStruct(v interface{}) map[string]bool {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	toggles := map[string]bool{}
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if field.IsExported() && field.Type.Kind() == reflect.Bool {
+			toggles[field.Name] = rv.Field(i).Bool()
+		}
+	}
+	return toggles
+}
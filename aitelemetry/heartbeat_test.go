@@ -0,0 +1,67 @@
+package aitelemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeatMetric(t *testing.T) {
+	toggles := map[string]bool{"EnableB": true, "EnableA": true, "DisableC": false}
+	hb := NewHeartbeat("azure-cns", "v1.6.0", toggles, func() map[string]float64 {
+		return map[string]float64{"DroppedReports": 3}
+	})
+
+	m := hb.Metric()
+	assert.Equal(t, HeartbeatMetricName, m.Name)
+	assert.InDelta(t, 1.0, m.Value, 0)
+	assert.Equal(t, "v1.6.0", m.AppVersion)
+	assert.Equal(t, "azure-cns", m.CustomDimensions[HeartbeatComponentStr])
+	assert.Equal(t, "v1.6.0", m.CustomDimensions[HeartbeatBuildVersionStr])
+	assert.Equal(t, "EnableA,EnableB", m.CustomDimensions[HeartbeatEnabledTogglesStr])
+	assert.Equal(t, "DisableC", m.CustomDimensions[HeartbeatDisabledTogglesStr])
+	assert.Equal(t, "3", m.CustomDimensions["DroppedReports"])
+	assert.Contains(t, m.CustomDimensions, HeartbeatUptimeSecondsStr)
+	assert.Contains(t, m.CustomDimensions, HeartbeatGoroutinesStr)
+}
+
+func TestHeartbeatRun(t *testing.T) {
+	hb := NewHeartbeat("azure-npm", "v1", nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	sent := make(chan Metric)
+
+	done := make(chan struct{})
+	go func() {
+		hb.Run(ctx, time.Millisecond, func(m Metric) { sent <- m })
+		close(done)
+	}()
+
+	m := <-sent
+	assert.Equal(t, "azure-npm", m.CustomDimensions[HeartbeatComponentStr])
+	cancel()
+	// drain a heartbeat which may have been in flight when ctx was cancelled
+	for {
+		select {
+		case <-sent:
+			continue
+		case <-done:
+		}
+		break
+	}
+}
+
+func TestTogglesFromStruct(t *testing.T) {
+	type config struct {
+		EnableFoo bool
+		EnableBar bool
+		Name      string
+		hidden    bool //nolint:unused // verifies unexported fields are skipped
+	}
+
+	toggles := TogglesFromStruct(&config{EnableFoo: true, Name: "x"})
+	require.Equal(t, map[string]bool{"EnableFoo": true, "EnableBar": false}, toggles)
+	require.Nil(t, TogglesFromStruct("not a struct"))
+}
@@ -0,0 +1,54 @@
+package aitelemetry
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// hostEndpoints are only reachable from the host itself, so requests to them must never be proxied.
+var hostEndpoints = []string{
+	"168.63.129.16",   // wireserver
+	"169.254.169.254", // IMDS
+}
+
+// ProxyConfig configures the proxy used to send telemetry. Empty fields fall back to the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables, in the format those variables use.
+type ProxyConfig struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty"`
+}
+
+// proxyFunc returns the proxy selection function for the config merged with the environment.
+// The wireserver and IMDS endpoints are always excluded from proxying.
+func (c ProxyConfig) proxyFunc() func(*url.URL) (*url.URL, error) {
+	env := httpproxy.FromEnvironment()
+	cfg := &httpproxy.Config{
+		HTTPProxy:  firstNonEmpty(c.HTTPProxy, env.HTTPProxy),
+		HTTPSProxy: firstNonEmpty(c.HTTPSProxy, env.HTTPSProxy),
+		NoProxy:    strings.Join(append([]string{firstNonEmpty(c.NoProxy, env.NoProxy)}, hostEndpoints...), ","),
+	}
+	return cfg.ProxyFunc()
+}
+
+// newHTTPTransport returns a transport with the default settings which selects proxies by the config.
+func newHTTPTransport(proxy ProxyConfig) *http.Transport {
+	proxyFunc := proxy.proxyFunc()
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+	return t
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
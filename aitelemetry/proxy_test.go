@@ -0,0 +1,64 @@
+package aitelemetry
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func proxyFor(t *testing.T, c ProxyConfig, target string) string {
+	t.Helper()
+	u, err := url.Parse(target)
+	require.NoError(t, err)
+	proxy, err := c.proxyFunc()(u)
+	require.NoError(t, err)
+	if proxy == nil {
+		return ""
+	}
+	return proxy.String()
+}
+
+func clearProxyEnv(t *testing.T) {
+	for _, key := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestProxyConfigOverridesEnvironment(t *testing.T) {
+	clearProxyEnv(t)
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+
+	c := ProxyConfig{HTTPSProxy: "http://config-proxy:3128"}
+	require.Equal(t, "http://config-proxy:3128", proxyFor(t, c, "https://dc.services.visualstudio.com/v2/track"))
+}
+
+func TestProxyConfigFallsBackToEnvironment(t *testing.T) {
+	clearProxyEnv(t)
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+
+	require.Equal(t, "http://env-proxy:3128", proxyFor(t, ProxyConfig{}, "https://dc.services.visualstudio.com/v2/track"))
+	require.Empty(t, proxyFor(t, ProxyConfig{}, "http://dc.services.visualstudio.com/v2/track"))
+}
+
+func TestProxyConfigNeverProxiesHostEndpoints(t *testing.T) {
+	clearProxyEnv(t)
+	c := ProxyConfig{HTTPProxy: "http://proxy:3128", HTTPSProxy: "http://proxy:3128"}
+
+	require.Empty(t, proxyFor(t, c, "http://168.63.129.16/machine/plugins"))
+	require.Empty(t, proxyFor(t, c, "http://169.254.169.254/metadata/instance"))
+	require.Equal(t, "http://proxy:3128", proxyFor(t, c, "https://dc.services.visualstudio.com/v2/track"))
+}
+
+func TestProxyConfigHonorsNoProxy(t *testing.T) {
+	clearProxyEnv(t)
+	t.Setenv("NO_PROXY", ".visualstudio.com")
+	c := ProxyConfig{HTTPSProxy: "http://proxy:3128"}
+
+	require.Empty(t, proxyFor(t, c, "https://dc.services.visualstudio.com/v2/track"))
+	require.Equal(t, "http://proxy:3128", proxyFor(t, c, "https://example.com"))
+
+	c.NoProxy = "example.com"
+	require.Empty(t, proxyFor(t, c, "https://example.com"))
+	require.Empty(t, proxyFor(t, c, "http://169.254.169.254/metadata/instance"))
+}
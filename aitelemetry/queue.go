@@ -0,0 +1,118 @@
+package aitelemetry
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/microsoft/ApplicationInsights-Go/appinsights"
+)
+
+// defaultQueueSize is the number of telemetry items buffered for sending when AIConfig.QueueSize is not set.
+const defaultQueueSize = 1000
+
+// TelemetryStats are counters of the items sent through a TelemetryHandle.
+type TelemetryStats struct {
+	// Queued is the number of items accepted for sending.
+	Queued uint64
+	// Dropped is the number of items discarded because the queue was full.
+	Dropped uint64
+}
+
+// sendQueue decouples callers of the Track functions from the appinsights channel, which blocks when its
+// buffer is full. Items are queued without blocking and sent by a single goroutine. When the queue is
+// full the oldest item is dropped, as fresh telemetry is more useful than stale.
+type sendQueue struct {
+	mu    sync.Mutex
+	items []appinsights.Telemetry
+	head  int
+	count int
+
+	ready    chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	queued  atomic.Uint64
+	dropped atomic.Uint64
+}
+
+func newSendQueue(size int) *sendQueue {
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	return &sendQueue{
+		items: make([]appinsights.Telemetry, size),
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+// push queues the item without blocking.
+func (q *sendQueue) push(item appinsights.Telemetry) {
+	q.mu.Lock()
+	if q.count == len(q.items) {
+		q.items[q.head] = nil
+		q.head = (q.head + 1) % len(q.items)
+		q.count--
+		q.dropped.Add(1)
+	}
+	q.items[(q.head+q.count)%len(q.items)] = item
+	q.count++
+	q.mu.Unlock()
+
+	q.queued.Add(1)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *sendQueue) pop() (appinsights.Telemetry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.count == 0 {
+		return nil, false
+	}
+	item := q.items[q.head]
+	q.items[q.head] = nil
+	q.head = (q.head + 1) % len(q.items)
+	q.count--
+	return item, true
+}
+
+// drain sends all queued items.
+func (q *sendQueue) drain(send func(appinsights.Telemetry)) {
+	for item, ok := q.pop(); ok; item, ok = q.pop() {
+		send(item)
+	}
+}
+
+// start sends queued items until stop is called.
+func (q *sendQueue) start(send func(appinsights.Telemetry)) {
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		for {
+			select {
+			case <-q.ready:
+				q.drain(send)
+			case <-q.done:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops the sending goroutine and sends whatever is left in the queue.
+func (q *sendQueue) stop(send func(appinsights.Telemetry)) {
+	q.stopOnce.Do(func() { close(q.done) })
+	q.wg.Wait()
+	q.drain(send)
+}
+
+func (q *sendQueue) stats() TelemetryStats {
+	return TelemetryStats{
+		Queued:  q.queued.Load(),
+		Dropped: q.dropped.Load(),
+	}
+}
@@ -0,0 +1,56 @@
+package aitelemetry
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/microsoft/ApplicationInsights-Go/appinsights"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendQueueDropsOldest(t *testing.T) {
+	q := newSendQueue(2)
+	for _, name := range []string{"a", "b", "c"} {
+		q.push(appinsights.NewEventTelemetry(name))
+	}
+
+	var sent []string
+	q.drain(func(item appinsights.Telemetry) {
+		sent = append(sent, item.(*appinsights.EventTelemetry).Name)
+	})
+
+	require.Equal(t, []string{"b", "c"}, sent)
+	require.Equal(t, TelemetryStats{Queued: 3, Dropped: 1}, q.stats())
+}
+
+func TestSendQueueDoesNotBlockOnSlowSender(t *testing.T) {
+	q := newSendQueue(4)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var sent int
+	q.start(func(appinsights.Telemetry) {
+		<-release
+		mu.Lock()
+		sent++
+		mu.Unlock()
+	})
+
+	// the sender is stuck, pushes must still return
+	for i := 0; i < 100; i++ {
+		q.push(appinsights.NewEventTelemetry("event"))
+	}
+	require.Positive(t, q.stats().Dropped)
+
+	close(release)
+	q.stop(func(appinsights.Telemetry) {
+		mu.Lock()
+		sent++
+		mu.Unlock()
+	})
+	// stopping twice is safe
+	q.stop(func(appinsights.Telemetry) {})
+
+	stats := q.stats()
+	require.Equal(t, uint64(100), stats.Queued)
+	require.Equal(t, int(stats.Queued-stats.Dropped), sent)
+}
@@ -2,6 +2,7 @@ package aitelemetry
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
 	"time"
@@ -169,6 +170,18 @@ func NewAITelemetry(
 	}
 
 	setAIConfigDefaults(&aiConfig)
+	telemetryConfig := appinsights.NewTelemetryConfiguration(id)
+
+	if aiConfig.IngestionEndpoint != "" {
+		// the endpoint was chosen for the cloud the node is in, so there is no need to check the cloud
+		endpoint, err := NormalizeIngestionEndpoint(aiConfig.IngestionEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		telemetryConfig.EndpointUrl = endpoint + trackPath
+		debugLog("[AppInsights] Using ingestion endpoint %s", telemetryConfig.EndpointUrl)
+		return newTelemetryHandle(telemetryConfig, aiConfig), nil
+	}
 
 	// check if azure instance is in public cloud
 	isPublic, err := isPublicEnvironment(azEnvUrl, aiConfig.GetEnvRetryCount, aiConfig.GetEnvRetryWaitTimeInSecs)
@@ -176,7 +189,74 @@ func NewAITelemetry(
 		return nil, err
 	}
 
+	return newTelemetryHandle(telemetryConfig, aiConfig), nil
+}
+
+// NewAITelemetryWithConnectionString creates telemetry handle which sends to the ingestion endpoint of the
+// Azure Monitor connection string. If aiConfig.Credential is set, ingestion is authenticated with AAD tokens
+// for aiConfig.Cloud. Unlike NewAITelemetry this does not require the node to be in the public cloud, as the
+// connection string carries the endpoint of the cloud it belongs to.
+func NewAITelemetryWithConnectionString(
+	connectionString string,
+	aiConfig AIConfig,
+) (TelemetryHandle, error) {
+	debugMode = aiConfig.DebugMode
+
+	cs, err := ParseConnectionString(connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if aiConfig.IngestionEndpoint != "" {
+		if cs.IngestionEndpoint, err = NormalizeIngestionEndpoint(aiConfig.IngestionEndpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	setAIConfigDefaults(&aiConfig)
+
+	telemetryConfig := appinsights.NewTelemetryConfiguration(cs.InstrumentationKey)
+	telemetryConfig.EndpointUrl = cs.IngestionEndpoint + trackPath
+
+	if aiConfig.Credential != nil {
+		scope, err := aadScope(aiConfig.Cloud)
+		if err != nil {
+			return nil, err
+		}
+		telemetryConfig.EndpointUrl = cs.TrackURL()
+		telemetryConfig.Client = &http.Client{Transport: newBearerTokenTransport(newHTTPTransport(aiConfig.Proxy), aiConfig.Credential, scope)}
+		debugLog("[AppInsights] Using AAD authenticated ingestion at %s", telemetryConfig.EndpointUrl)
+	}
+
+	return newTelemetryHandle(telemetryConfig, aiConfig), nil
+}
+
+// NewTelemetryConfiguration creates the appinsights configuration NewAITelemetry sends with, for other appinsights
+// clients of the same component, such as zap log forwarding. Unlike NewAITelemetry, it does not check the cloud.
+func NewTelemetryConfiguration(id string, aiConfig AIConfig) (*appinsights.TelemetryConfiguration, error) {
+	if id == "" {
+		return nil, fmt.Errorf("AI key is empty")
+	}
+
+	setAIConfigDefaults(&aiConfig)
 	telemetryConfig := appinsights.NewTelemetryConfiguration(id)
+	if aiConfig.IngestionEndpoint != "" {
+		endpoint, err := NormalizeIngestionEndpoint(aiConfig.IngestionEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		telemetryConfig.EndpointUrl = endpoint + trackPath
+	}
+	telemetryConfig.Client = &http.Client{Transport: newHTTPTransport(aiConfig.Proxy)}
+	telemetryConfig.MaxBatchSize = aiConfig.BatchSize
+	telemetryConfig.MaxBatchInterval = time.Duration(aiConfig.BatchInterval) * time.Second
+	return telemetryConfig, nil
+}
+
+func newTelemetryHandle(telemetryConfig *appinsights.TelemetryConfiguration, aiConfig AIConfig) *telemetryHandle {
+	if telemetryConfig.Client == nil {
+		telemetryConfig.Client = &http.Client{Transport: newHTTPTransport(aiConfig.Proxy)}
+	}
 	telemetryConfig.MaxBatchSize = aiConfig.BatchSize
 	telemetryConfig.MaxBatchInterval = time.Duration(aiConfig.BatchInterval) * time.Second
 
@@ -187,7 +267,9 @@ func NewAITelemetry(
 		diagListener:                 messageListener(),
 		disableMetadataRefreshThread: aiConfig.DisableMetadataRefreshThread,
 		refreshTimeout:               aiConfig.RefreshTimeout,
+		queue:                        newSendQueue(aiConfig.QueueSize),
 	}
+	th.queue.start(th.client.Track)
 
 	if th.disableMetadataRefreshThread {
 		getMetadata(th)
@@ -195,7 +277,7 @@ func NewAITelemetry(
 		go getMetadata(th)
 	}
 
-	return th, nil
+	return th
 }
 
 // TrackLog function sends report (trace) to appinsights resource. It overrides few of the existing columns with app information
@@ -242,7 +324,7 @@ func (th *telemetryHandle) TrackLog(report Report) {
 	}
 
 	// send to appinsights resource
-	th.client.Track(trace)
+	th.queue.push(trace)
 }
 
 // TrackEvent function sends events to appinsights resource. It overrides a few of the existing columns
@@ -285,7 +367,7 @@ func (th *telemetryHandle) TrackEvent(event Event) {
 	aiEvent.Properties[osStr] = runtime.GOOS
 	aiEvent.Properties[appNameStr] = th.appName
 	aiEvent.Properties[versionStr] = th.appVersion
-	th.client.Track(aiEvent)
+	th.queue.push(aiEvent)
 }
 
 // TrackMetric function sends metric to appinsights resource. It overrides few of the existing columns with app information
@@ -321,7 +403,7 @@ func (th *telemetryHandle) TrackMetric(metric Metric) {
 	}
 
 	// send metric to appinsights
-	th.client.Track(aimetric)
+	th.queue.push(aimetric)
 }
 
 // Close - should be called for each NewAITelemetry call. Will release resources acquired
@@ -330,9 +412,14 @@ func (th *telemetryHandle) Close(timeout int) {
 		timeout = defaultTimeout
 	}
 
-	// wait for items to be sent otherwise timeout
+	// hand the queued items to the channel, then wait for them to be sent otherwise timeout
+	th.queue.stop(th.client.Track)
 	<-th.client.Channel().Close(time.Duration(timeout) * time.Second)
 
+	if stats := th.queue.stats(); stats.Dropped > 0 {
+		debugLog("[AppInsights] Dropped %d of %d telemetry items because the send queue was full", stats.Dropped, stats.Queued)
+	}
+
 	// Remove diganostic message listener
 	if th.diagListener != nil {
 		th.diagListener.Remove()
@@ -342,5 +429,11 @@ func (th *telemetryHandle) Close(timeout int) {
 
 // Flush - forces the current queue to be sent
 func (th *telemetryHandle) Flush() {
+	th.queue.drain(th.client.Track)
 	th.client.Channel().Flush()
 }
+
+// Stats returns the counters of queued and dropped items.
+func (th *telemetryHandle) Stats() TelemetryStats {
+	return th.queue.stats()
+}
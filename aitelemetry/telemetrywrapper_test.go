@@ -12,6 +12,7 @@ import (
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/platform"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -113,6 +114,40 @@ func TestNewAITelemetry(t *testing.T) {
 	}
 }
 
+func TestNewAITelemetryWithIngestionEndpoint(t *testing.T) {
+	aiConfig := AIConfig{
+		AppName:                      "testapp",
+		AppVersion:                   "v1.0.26",
+		GetEnvRetryCount:             1,
+		DisableMetadataRefreshThread: true,
+		IngestionEndpoint:            "https://dc.applicationinsights.azure.cn",
+	}
+	// the cloud check would fail against this url, an overridden endpoint does not need it
+	handle, err := NewAITelemetry("http://127.0.0.1:1", "00ca2a73-c8d6-4929-a0c2-cf84545ec225", aiConfig)
+	require.NoError(t, err)
+	handle.Close(1)
+
+	aiConfig.IngestionEndpoint = "dc.applicationinsights.azure.cn"
+	_, err = NewAITelemetry("http://127.0.0.1:1", "00ca2a73-c8d6-4929-a0c2-cf84545ec225", aiConfig)
+	require.ErrorIs(t, err, ErrInvalidEndpoint)
+}
+
+func TestNewTelemetryConfiguration(t *testing.T) {
+	aiConfig := AIConfig{IngestionEndpoint: "https://dc.applicationinsights.azure.cn"}
+	telemetryConfig, err := NewTelemetryConfiguration("00ca2a73-c8d6-4929-a0c2-cf84545ec225", aiConfig)
+	require.NoError(t, err)
+	require.Equal(t, "https://dc.applicationinsights.azure.cn/v2/track", telemetryConfig.EndpointUrl)
+	require.Equal(t, defaultBatchSizeInBytes, telemetryConfig.MaxBatchSize)
+	require.NotNil(t, telemetryConfig.Client)
+
+	_, err = NewTelemetryConfiguration("", aiConfig)
+	require.Error(t, err)
+
+	aiConfig.IngestionEndpoint = "dc.applicationinsights.azure.cn"
+	_, err = NewTelemetryConfiguration("00ca2a73-c8d6-4929-a0c2-cf84545ec225", aiConfig)
+	require.ErrorIs(t, err, ErrInvalidEndpoint)
+}
+
 func TestTrackMetric(t *testing.T) {
 	metric := Metric{
 		Name:             "test",
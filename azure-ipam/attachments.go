@@ -0,0 +1,109 @@
+package main
+
+import (
+	"time"
+
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	attachmentsKey = "Attachments"
+	// IPs assigned more recently than this are never released by GC, as the runtime may have listed the valid
+	// attachments before the ADD which assigned them completed
+	gcGracePeriod = 5 * time.Minute
+)
+
+// attachment is a pod interface which the plugin requested IPs for in a network.
+type attachment struct {
+	Network string
+	AddedAt time.Time
+}
+
+// attachmentStore persists the network of the pod interfaces the plugin requested IPs for, so that CmdGC only
+// releases the IPs of the network it is invoked for. CNS doesn't know which network, nor which plugin, an IP was
+// assigned for, and is shared with the other networks and plugins on the node.
+// The store is shared by concurrent invocations through a file lock.
+type attachmentStore struct {
+	store store.KeyValueStore
+}
+
+func newAttachmentStore(path string, logger *zap.Logger) (*attachmentStore, error) {
+	lock, err := processlock.NewFileLock(path + store.LockExtension)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create attachment store lock")
+	}
+	kvs, err := store.NewJsonFileStore(path, lock, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create attachment store")
+	}
+	return &attachmentStore{store: kvs}, nil
+}
+
+// update calls fn with the attachments, keyed by pod interface ID, and persists them if fn changed them.
+func (s *attachmentStore) update(fn func(attachments map[string]*attachment) bool) error {
+	if err := s.store.Lock(store.DefaultLockTimeout); err != nil {
+		return errors.Wrap(err, "failed to lock attachment store")
+	}
+	defer s.store.Unlock() //nolint:errcheck // the lock is released when the process exits
+
+	attachments := map[string]*attachment{}
+	if err := s.store.Read(attachmentsKey, &attachments); err != nil &&
+		!errors.Is(err, store.ErrKeyNotFound) && !errors.Is(err, store.ErrStoreEmpty) {
+		return errors.Wrap(err, "failed to read attachment store")
+	}
+	if !fn(attachments) {
+		return nil
+	}
+	return errors.Wrap(s.store.Write(attachmentsKey, attachments), "failed to write attachment store")
+}
+
+// recordAttachment persists that the plugin requested IPs for the pod interface in the network.
+func (p *IPAMPlugin) recordAttachment(podInterfaceID, network string) {
+	if p.attachments == nil {
+		return
+	}
+	err := p.attachments.update(func(attachments map[string]*attachment) bool {
+		attachments[podInterfaceID] = &attachment{Network: network, AddedAt: time.Now()}
+		return true
+	})
+	if err != nil {
+		// GC won't release the IPs of the pod interface, which are left to DEL and to the CNS garbage collection
+		p.logger.Error("Failed to record attachment", zap.Error(err), zap.String("podInterfaceID", podInterfaceID))
+	}
+}
+
+// forgetAttachment removes the pod interface whose IPs were released, or queued for release.
+func (p *IPAMPlugin) forgetAttachment(podInterfaceID string) {
+	if p.attachments == nil {
+		return
+	}
+	err := p.attachments.update(func(attachments map[string]*attachment) bool {
+		if _, ok := attachments[podInterfaceID]; !ok {
+			return false
+		}
+		delete(attachments, podInterfaceID)
+		return true
+	})
+	if err != nil {
+		p.logger.Error("Failed to forget attachment", zap.Error(err), zap.String("podInterfaceID", podInterfaceID))
+	}
+}
+
+// staleAttachments returns the pod interfaces which the plugin requested IPs for in the network at least
+// gcGracePeriod ago, and which aren't among the valid ones.
+func (p *IPAMPlugin) staleAttachments(network string, valid map[string]struct{}) (map[string]struct{}, error) {
+	stale := map[string]struct{}{}
+	err := p.attachments.update(func(attachments map[string]*attachment) bool {
+		for podInterfaceID, a := range attachments {
+			if _, ok := valid[podInterfaceID]; ok || a.Network != network || time.Since(a.AddedAt) < gcGracePeriod {
+				continue
+			}
+			stale[podInterfaceID] = struct{}{}
+		}
+		return false
+	})
+	return stale, err
+}
@@ -8,6 +8,12 @@ const (
 	pluginName    = "azure-ipam"
 	cnsBaseURL    = "" // fallback to default http://localhost:10090
 	cnsReqTimeout = 15 * time.Second
+	// releaseQueuePath is the file the releases which failed are persisted to, to be retried
+	releaseQueuePath = "/var/run/azure-ipam/release-queue.json"
+	// attachmentsPath is the file the network of the pod interfaces the IPs were requested for is persisted to
+	attachmentsPath = "/var/run/azure-ipam/attachments.json"
+	// breakerStatePath is the file the state of the circuit breakers of the CNS requests is persisted to
+	breakerStatePath = "/var/run/azure-ipam/cns-breakers.json"
 )
 
 // plugin specific error codes
@@ -17,4 +23,14 @@ const (
 	ErrCreateIPConfigsRequest uint = iota + 200
 	ErrRequestIPConfigFromCNS
 	ErrProcessIPConfigResponse
+	ErrGetIPConfigsFromCNS
+	ErrProcessIPConfigStatuses
+	ErrIPConfigsDiverged
+)
+
+// error codes of CNI spec 1.1, which the CNI library doesn't define yet
+// https://www.cni.dev/docs/spec/#error
+const (
+	// ErrPluginNotAvailable means the plugin can't service ADD commands.
+	ErrPluginNotAvailable uint = 50
 )
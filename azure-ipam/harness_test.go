@@ -0,0 +1,540 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	cnsclient "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/log/zaplog"
+	cniSkel "github.com/containernetworking/cni/pkg/skel"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
+	types100 "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeCNS serves the CNS IPAM APIs called by azure-ipam over HTTP from a pool of IPs which tests can program.
+// It mirrors cns/fakes.CNSServerFake, which is not available in the version of the module azure-ipam depends on.
+type fakeCNS struct {
+	*httptest.Server
+
+	sync.Mutex
+	available  []string
+	assigned   map[string]string
+	assignedAt map[string]time.Time
+	failNext   []types.ResponseCode
+	// subnetExhausted is reported by the IPAM status API, which isn't served if noIPAMStatus is set
+	subnetExhausted bool
+	noIPAMStatus    bool
+}
+
+func newFakeCNS(t *testing.T, ips ...string) *fakeCNS {
+	t.Helper()
+	f := &fakeCNS{available: ips, assigned: map[string]string{}, assignedAt: map[string]time.Time{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc(cns.RequestIPConfigs, f.requestIPConfigs)
+	mux.HandleFunc(cns.ReleaseIPConfigs, f.releaseIPConfigs)
+	mux.HandleFunc(cns.PathDebugIPAddresses, f.getIPAddresses)
+	mux.HandleFunc(cns.PathIPAMStatus, f.ipamStatus)
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeCNS) requestIPConfigs(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	var req cns.IPConfigsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := cns.IPConfigsResponse{}
+	ip, ok := f.assigned[req.PodInterfaceID]
+	switch {
+	case len(f.failNext) > 0:
+		resp.Response = cns.Response{ReturnCode: f.failNext[0], Message: "injected failure"}
+		f.failNext = f.failNext[1:]
+	case !ok && len(req.DesiredIPAddresses) > 0:
+		ip = req.DesiredIPAddresses[0]
+		if !f.take(ip) {
+			resp.Response = cns.Response{ReturnCode: types.FailedToAllocateIPConfig, Message: "desired IP not available"}
+			break
+		}
+		f.assigned[req.PodInterfaceID] = ip
+		f.assignedAt[req.PodInterfaceID] = time.Now()
+		resp.PodIPInfo = f.podIPInfo(ip)
+	case !ok && len(f.available) == 0:
+		resp.Response = cns.Response{ReturnCode: types.FailedToAllocateIPConfig, Message: "no IPs available"}
+	default:
+		if !ok {
+			ip, f.available = f.available[0], f.available[1:]
+			f.assigned[req.PodInterfaceID] = ip
+			f.assignedAt[req.PodInterfaceID] = time.Now()
+		}
+		resp.PodIPInfo = f.podIPInfo(ip)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// take removes the IP from the available ones, if it is one of them.
+func (f *fakeCNS) take(ip string) bool {
+	for i := range f.available {
+		if f.available[i] == ip {
+			f.available = append(f.available[:i], f.available[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeCNS) podIPInfo(ip string) []cns.PodIpInfo {
+	return []cns.PodIpInfo{{
+		PodIPConfig: cns.IPSubnet{IPAddress: ip, PrefixLength: 16},
+		NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+			IPSubnet:         cns.IPSubnet{IPAddress: "10.240.0.4", PrefixLength: 16},
+			GatewayIPAddress: "10.240.0.1",
+		},
+	}}
+}
+
+func (f *fakeCNS) releaseIPConfigs(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	var req cns.IPConfigsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ip, ok := f.assigned[req.PodInterfaceID]; ok {
+		delete(f.assigned, req.PodInterfaceID)
+		delete(f.assignedAt, req.PodInterfaceID)
+		f.available = append(f.available, ip)
+	}
+	_ = json.NewEncoder(w).Encode(cns.Response{ReturnCode: types.Success})
+}
+
+func (f *fakeCNS) getIPAddresses(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	var req cns.GetIPAddressesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := cns.GetIPAddressStatusResponse{}
+	if len(f.failNext) > 0 {
+		resp.Response = cns.Response{ReturnCode: f.failNext[0], Message: "injected failure"}
+		f.failNext = f.failNext[1:]
+	}
+	for _, state := range req.IPConfigStateFilter {
+		switch state {
+		case types.Assigned:
+			for podInterfaceID, ip := range f.assigned {
+				resp.IPConfigurationStatus = append(resp.IPConfigurationStatus, cns.IPConfigurationStatus{
+					IPAddress:           ip,
+					LastStateTransition: f.assignedAt[podInterfaceID],
+					PodInfo:             cns.NewPodInfo(podInterfaceID, podInterfaceID, "", ""),
+				})
+			}
+		case types.Available:
+			for _, ip := range f.available {
+				resp.IPConfigurationStatus = append(resp.IPConfigurationStatus, cns.IPConfigurationStatus{IPAddress: ip})
+			}
+		}
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeCNS) ipamStatus(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	if f.noIPAMStatus {
+		http.NotFound(w, r)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(cns.IPAMStatusResponse{
+		PoolSize:        len(f.available) + len(f.assigned),
+		Allocated:       len(f.assigned),
+		Free:            len(f.available),
+		SubnetExhausted: f.subnetExhausted,
+	})
+}
+
+func (f *fakeCNS) assignedIPs() map[string]string {
+	f.Lock()
+	defer f.Unlock()
+	assigned := map[string]string{}
+	for k, v := range f.assigned {
+		assigned[k] = v
+	}
+	return assigned
+}
+
+// ipamHarness runs the azure-ipam ADD, DEL, CHECK, GC and STATUS commands in-process against a fakeCNS through the
+// CNS client, like the plugin binary does.
+type ipamHarness struct {
+	t   *testing.T
+	cns *fakeCNS
+	// attachmentsPath is the attachment store shared by the invocations of the plugin
+	attachmentsPath string
+}
+
+func newIPAMHarness(t *testing.T, ips ...string) *ipamHarness {
+	t.Helper()
+	return &ipamHarness{t: t, cns: newFakeCNS(t, ips...), attachmentsPath: filepath.Join(t.TempDir(), "attachments.json")}
+}
+
+func (h *ipamHarness) plugin(out *bytes.Buffer) *IPAMPlugin {
+	h.t.Helper()
+	pluginLogger, cleanup, err := zaplog.New(&zaplog.Config{})
+	require.NoError(h.t, err)
+	h.t.Cleanup(cleanup)
+	client, err := cnsclient.New(h.cns.URL, time.Second)
+	require.NoError(h.t, err)
+	plugin, err := NewPlugin(pluginLogger, client, out)
+	require.NoError(h.t, err)
+	plugin.attachments, err = newAttachmentStore(h.attachmentsPath, pluginLogger)
+	require.NoError(h.t, err)
+	return plugin
+}
+
+// age makes the IPs of the pods look assigned, and their attachments recorded, longer than gcGracePeriod ago.
+func (h *ipamHarness) age(podNames ...string) {
+	h.t.Helper()
+	before := time.Now().Add(-gcGracePeriod - time.Minute)
+	h.cns.Lock()
+	for _, podName := range podNames {
+		h.cns.assignedAt[podName+"-container"] = before
+	}
+	h.cns.Unlock()
+	store, err := newAttachmentStore(h.attachmentsPath, zap.NewNop())
+	require.NoError(h.t, err)
+	require.NoError(h.t, store.update(func(attachments map[string]*attachment) bool {
+		for _, podName := range podNames {
+			attachments[podName+"-container"].AddedAt = before
+		}
+		return true
+	}))
+}
+
+func (h *ipamHarness) args(podName string) *cniSkel.CmdArgs {
+	h.t.Helper()
+	netConf, err := json.Marshal(&cniTypes.NetConf{CNIVersion: "1.0.0", Name: "azure"})
+	require.NoError(h.t, err)
+	return buildArgs(podName+"-container", "K8S_POD_NAMESPACE=default;K8S_POD_NAME="+podName, netConf)
+}
+
+func (h *ipamHarness) add(podName string) (*types100.Result, error) {
+	h.t.Helper()
+	return h.addArgs(h.args(podName))
+}
+
+func (h *ipamHarness) addArgs(args *cniSkel.CmdArgs) (*types100.Result, error) {
+	h.t.Helper()
+	var out bytes.Buffer
+	if err := h.plugin(&out).CmdAdd(args); err != nil {
+		return nil, err
+	}
+	result := &types100.Result{}
+	require.NoError(h.t, json.Unmarshal(out.Bytes(), result))
+	return result, nil
+}
+
+func (h *ipamHarness) del(podName string) error {
+	h.t.Helper()
+	return h.plugin(&bytes.Buffer{}).CmdDel(h.args(podName))
+}
+
+func (h *ipamHarness) check(podName string, prevResult *types100.Result) error {
+	h.t.Helper()
+	args := h.args(podName)
+	rawPrevResult, err := json.Marshal(prevResult)
+	require.NoError(h.t, err)
+	netConf := map[string]interface{}{}
+	require.NoError(h.t, json.Unmarshal(args.StdinData, &netConf))
+	netConf["prevResult"] = json.RawMessage(rawPrevResult)
+	args.StdinData, err = json.Marshal(netConf)
+	require.NoError(h.t, err)
+	return h.plugin(&bytes.Buffer{}).CmdCheck(args)
+}
+
+func (h *ipamHarness) gc(validContainerIDs ...string) error {
+	h.t.Helper()
+	attachments := make([]map[string]string, 0, len(validContainerIDs))
+	for _, containerID := range validContainerIDs {
+		attachments = append(attachments, map[string]string{"containerID": containerID, "ifname": "eth0"})
+	}
+	return h.gcNetConf(map[string]interface{}{
+		"cniVersion":                cniSpecVersion110,
+		"name":                      "azure",
+		"cni.dev/valid-attachments": attachments,
+	})
+}
+
+func (h *ipamHarness) gcNetConf(conf map[string]interface{}) error {
+	h.t.Helper()
+	netConf, err := json.Marshal(conf)
+	require.NoError(h.t, err)
+	return h.plugin(&bytes.Buffer{}).CmdGC(&cniSkel.CmdArgs{StdinData: netConf})
+}
+
+func (h *ipamHarness) status() error {
+	h.t.Helper()
+	netConf, err := json.Marshal(&cniTypes.NetConf{CNIVersion: cniSpecVersion110, Name: "azure"})
+	require.NoError(h.t, err)
+	return h.plugin(&bytes.Buffer{}).CmdStatus(&cniSkel.CmdArgs{StdinData: netConf})
+}
+
+func TestHarnessAddCheckDelete(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10", "10.240.0.11")
+
+	result, err := h.add("pod-a")
+	require.NoError(t, err)
+	require.Len(t, result.IPs, 1)
+	require.Equal(t, "10.240.0.10/16", result.IPs[0].Address.String())
+	require.Equal(t, map[string]string{"pod-a-container": "10.240.0.10"}, h.cns.assignedIPs())
+
+	// a repeated ADD returns the same IP.
+	result, err = h.add("pod-a")
+	require.NoError(t, err)
+	require.Equal(t, "10.240.0.10/16", result.IPs[0].Address.String())
+
+	require.NoError(t, h.check("pod-a", result))
+
+	require.NoError(t, h.del("pod-a"))
+	require.Empty(t, h.cns.assignedIPs())
+	require.NoError(t, h.del("pod-a"))
+}
+
+func TestHarnessPoolExhausted(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10")
+
+	_, err := h.add("pod-a")
+	require.NoError(t, err)
+	_, err = h.add("pod-b")
+	require.Error(t, err)
+
+	require.NoError(t, h.del("pod-a"))
+	result, err := h.add("pod-b")
+	require.NoError(t, err)
+	require.Equal(t, "10.240.0.10/16", result.IPs[0].Address.String())
+}
+
+func TestHarnessCNSFailure(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10")
+	h.cns.failNext = []types.ResponseCode{types.UnexpectedError}
+
+	_, err := h.add("pod-a")
+	require.Error(t, err)
+	require.Empty(t, h.cns.assignedIPs())
+
+	_, err = h.add("pod-a")
+	require.NoError(t, err)
+}
+
+func TestHarnessCheckDiverged(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10", "10.240.0.11")
+
+	resultA, err := h.add("pod-a")
+	require.NoError(t, err)
+	resultB, err := h.add("pod-b")
+	require.NoError(t, err)
+	require.NoError(t, h.check("pod-a", resultA))
+
+	// the IP of another pod.
+	require.Error(t, h.check("pod-a", resultB))
+
+	// CNS failing to list the assigned IPs.
+	h.cns.failNext = []types.ResponseCode{types.UnexpectedError}
+	require.Error(t, h.check("pod-a", resultA))
+
+	// the IP released in CNS behind the runtime's back.
+	require.NoError(t, h.del("pod-a"))
+	err = h.check("pod-a", resultA)
+	require.Error(t, err)
+	cniErr := &cniTypes.Error{}
+	require.ErrorAs(t, err, &cniErr)
+	require.Equal(t, ErrIPConfigsDiverged, cniErr.Code)
+}
+
+func TestHarnessDesiredIP(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10", "10.240.0.11", "10.240.0.12")
+
+	// the ips capability in the runtime config.
+	args := h.args("pod-a")
+	netConf := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(args.StdinData, &netConf))
+	netConf["runtimeConfig"] = map[string]interface{}{"ips": []string{"10.240.0.11/16"}}
+	stdin, err := json.Marshal(netConf)
+	require.NoError(t, err)
+	args.StdinData = stdin
+	result, err := h.addArgs(args)
+	require.NoError(t, err)
+	require.Equal(t, "10.240.0.11/16", result.IPs[0].Address.String())
+
+	// the IP arg.
+	args = h.args("pod-b")
+	args.Args += ";IP=10.240.0.12"
+	result, err = h.addArgs(args)
+	require.NoError(t, err)
+	require.Equal(t, "10.240.0.12/16", result.IPs[0].Address.String())
+
+	// a desired IP which is already assigned.
+	args = h.args("pod-c")
+	args.Args += ";IP=10.240.0.12"
+	_, err = h.addArgs(args)
+	require.Error(t, err)
+
+	// an invalid desired IP.
+	args = h.args("pod-c")
+	args.Args += ";IP=10.240.0"
+	_, err = h.addArgs(args)
+	require.Error(t, err)
+
+	require.Equal(t, map[string]string{"pod-a-container": "10.240.0.11", "pod-b-container": "10.240.0.12"}, h.cns.assignedIPs())
+}
+
+func TestHarnessGC(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10", "10.240.0.11", "10.240.0.12")
+
+	for _, pod := range []string{"pod-a", "pod-b", "pod-c"} {
+		_, err := h.add(pod)
+		require.NoError(t, err)
+	}
+
+	// the IPs of pod-b leaked, e.g. its DEL was never run.
+	h.age("pod-a", "pod-b", "pod-c")
+	require.NoError(t, h.gc("pod-a-container", "pod-c-container"))
+	require.Equal(t, map[string]string{"pod-a-container": "10.240.0.10", "pod-c-container": "10.240.0.12"}, h.cns.assignedIPs())
+
+	// CNS failing to list the assigned IPs.
+	h.cns.failNext = []types.ResponseCode{types.UnexpectedError}
+	err := h.gc()
+	require.Error(t, err)
+	cniErr := &cniTypes.Error{}
+	require.ErrorAs(t, err, &cniErr)
+	require.Equal(t, ErrGetIPConfigsFromCNS, cniErr.Code)
+	require.Len(t, h.cns.assignedIPs(), 2)
+
+	// no valid attachments.
+	require.NoError(t, h.gc())
+	require.Empty(t, h.cns.assignedIPs())
+}
+
+func TestHarnessGCWithoutValidAttachments(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10")
+	_, err := h.add("pod-a")
+	require.NoError(t, err)
+	h.age("pod-a")
+
+	// a runtime which doesn't set the valid attachments would have every IP released.
+	err = h.gcNetConf(map[string]interface{}{"cniVersion": cniSpecVersion110, "name": "azure"})
+	cniErr := &cniTypes.Error{}
+	require.ErrorAs(t, err, &cniErr)
+	require.Equal(t, uint(cniTypes.ErrInvalidNetworkConfig), cniErr.Code)
+	require.Len(t, h.cns.assignedIPs(), 1)
+}
+
+func TestHarnessGCKeepsIPsOfOtherNetworks(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10", "10.240.0.11")
+	_, err := h.add("pod-a")
+	require.NoError(t, err)
+	args := h.args("pod-b")
+	netConf, err := json.Marshal(&cniTypes.NetConf{CNIVersion: "1.0.0", Name: "other"})
+	require.NoError(t, err)
+	args.StdinData = netConf
+	_, err = h.addArgs(args)
+	require.NoError(t, err)
+	h.age("pod-a", "pod-b")
+
+	// pod-b is attached to the other network, whose valid attachments aren't known to the GC of this one.
+	require.NoError(t, h.gc())
+	require.Equal(t, map[string]string{"pod-b-container": "10.240.0.11"}, h.cns.assignedIPs())
+}
+
+func TestHarnessGCKeepsRecentIPs(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10", "10.240.0.11")
+	_, err := h.add("pod-a")
+	require.NoError(t, err)
+
+	// the runtime listed the valid attachments before the ADD of pod-a completed.
+	require.NoError(t, h.gc())
+	require.Len(t, h.cns.assignedIPs(), 1)
+
+	// the IP of pod-b was released and assigned again by CNS after its attachment was recorded.
+	_, err = h.add("pod-b")
+	require.NoError(t, err)
+	h.age("pod-a", "pod-b")
+	h.cns.Lock()
+	h.cns.assignedAt["pod-b-container"] = time.Now()
+	h.cns.Unlock()
+	require.NoError(t, h.gc())
+	require.Equal(t, map[string]string{"pod-b-container": "10.240.0.11"}, h.cns.assignedIPs())
+}
+
+func TestHarnessStatus(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10")
+	require.NoError(t, h.status())
+
+	// the pool has no free IPs, but grows on demand.
+	_, err := h.add("pod-a")
+	require.NoError(t, err)
+	require.NoError(t, h.status())
+
+	// the pool can't grow since the subnet is exhausted.
+	h.cns.Lock()
+	h.cns.subnetExhausted = true
+	h.cns.Unlock()
+	err = h.status()
+	require.Error(t, err)
+	cniErr := &cniTypes.Error{}
+	require.ErrorAs(t, err, &cniErr)
+	require.Equal(t, ErrPluginNotAvailable, cniErr.Code)
+
+	require.NoError(t, h.del("pod-a"))
+	require.NoError(t, h.status())
+
+	// CNS predates the IPAM status API.
+	h.cns.Lock()
+	h.cns.noIPAMStatus = true
+	h.cns.Unlock()
+	require.NoError(t, h.status())
+
+	// CNS is not reachable.
+	h.cns.Close()
+	err = h.status()
+	require.ErrorAs(t, err, &cniErr)
+	require.Equal(t, ErrPluginNotAvailable, cniErr.Code)
+}
+
+func TestHarnessSpecVersion110(t *testing.T) {
+	h := newIPAMHarness(t, "10.240.0.10")
+
+	args := h.args("pod-a")
+	netConf, err := json.Marshal(&cniTypes.NetConf{CNIVersion: cniSpecVersion110, Name: "azure"})
+	require.NoError(t, err)
+	args.StdinData = netConf
+	result, err := h.addArgs(args)
+	require.NoError(t, err)
+	require.Equal(t, cniSpecVersion110, result.CNIVersion)
+	require.Equal(t, "10.240.0.10/16", result.IPs[0].Address.String())
+
+	// the prevResult of CHECK has the version of the network config.
+	netConfWithPrevResult := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(netConf, &netConfWithPrevResult))
+	netConfWithPrevResult["prevResult"] = result
+	args.StdinData, err = json.Marshal(netConfWithPrevResult)
+	require.NoError(t, err)
+	require.NoError(t, h.plugin(&bytes.Buffer{}).CmdCheck(args))
+
+	require.Contains(t, supportedVersions.SupportedVersions(), cniSpecVersion110)
+}
@@ -3,20 +3,32 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net"
+	"net/netip"
+	"time"
 
 	"github.com/Azure/azure-container-networking/azure-ipam/internal/buildinfo"
 	"github.com/Azure/azure-container-networking/azure-ipam/ipconfig"
 	"github.com/Azure/azure-container-networking/cns"
 	cnscli "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/types"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	types100 "github.com/containernetworking/cni/pkg/types/100"
+	cniVersion "github.com/containernetworking/cni/pkg/version"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
 
+// cniSpecVersion110 is CNI spec 1.1.0, which adds the GC and STATUS commands. The CNI library doesn't implement it
+// yet, but its results have the same format as 1.0.0 ones.
+const cniSpecVersion110 = "1.1.0"
+
+// supportedVersions are the CNI spec versions azure-ipam supports.
+var supportedVersions = cniVersion.PluginSupports(append(cniVersion.All.SupportedVersions(), cniSpecVersion110)...)
+
 // IPAMPlugin is the struct for the delegated azure-ipam plugin
 // https://www.cni.dev/docs/spec/#section-4-plugin-delegation
 type IPAMPlugin struct {
@@ -26,6 +38,10 @@ type IPAMPlugin struct {
 	logger    *zap.Logger
 	cnsClient cnsClient
 	out       io.Writer // indicate the output channel for the plugin
+	// releaseQueue persists the releases which failed to retry them. It is optional.
+	releaseQueue *releaseQueue
+	// attachments persists the network of the pod interfaces the plugin requested IPs for. GC is a no-op without it.
+	attachments *attachmentStore
 }
 
 type cnsClient interface {
@@ -33,6 +49,8 @@ type cnsClient interface {
 	RequestIPs(context.Context, cns.IPConfigsRequest) (*cns.IPConfigsResponse, error)
 	ReleaseIPs(context.Context, cns.IPConfigsRequest) error
 	ReleaseIPAddress(context.Context, cns.IPConfigRequest) error
+	GetIPAddressesMatchingStates(context.Context, ...types.IPState) ([]cns.IPConfigurationStatus, error)
+	GetIPAMStatusSummary(context.Context) (*cns.IPAMStatusResponse, error)
 }
 
 // NewPlugin constructs a new IPAM plugin instance with given logger and CNS client
@@ -63,6 +81,7 @@ func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
 		return cniTypes.NewError(cniTypes.ErrDecodingFailure, err.Error(), "failed to parse CNI network config from stdin")
 	}
 	p.logger.Debug("Parsed network config", zap.Any("netconf", nwCfg))
+	p.configureCNSRetries(nwCfg)
 
 	// Create ip config request from args
 	req, err := ipconfig.CreateIPConfigsReq(args)
@@ -70,7 +89,15 @@ func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
 		p.logger.Error("Failed to create CNS IP configs request", zap.Error(err))
 		return cniTypes.NewError(ErrCreateIPConfigsRequest, err.Error(), "failed to create CNS IP configs request")
 	}
+
+	desiredIPs, err := ipconfig.DesiredIPs(args)
+	if err != nil {
+		p.logger.Error("Failed to parse desired IPs", zap.Error(err))
+		return cniTypes.NewError(ErrCreateIPConfigsRequest, err.Error(), "failed to parse desired IPs")
+	}
+	req.DesiredIPAddresses = desiredIPs
 	p.logger.Debug("Created CNS IP config request", zap.Any("request", req))
+	p.dropPendingRelease(req.PodInterfaceID)
 
 	p.logger.Debug("Making request to CNS")
 	// if this fails, the caller plugin should execute again with cmdDel before returning error.
@@ -84,6 +111,14 @@ func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
 				p.logger.Error("Failed to create CNS IP config request", zap.Error(err))
 				return cniTypes.NewError(ErrCreateIPConfigRequest, err.Error(), "failed to create CNS IP config request")
 			}
+			// the old API takes a single desired IP
+			if len(desiredIPs) > 1 {
+				p.logger.Error("CNS does not support requesting multiple desired IPs using RequestIPAddress", zap.Strings("desiredIPs", desiredIPs))
+				return cniTypes.NewError(ErrCreateIPConfigRequest, "multiple desired IPs", "CNS does not support requesting multiple desired IPs using RequestIPAddress")
+			}
+			if len(desiredIPs) == 1 {
+				ipconfigReq.DesiredIPAddress = desiredIPs[0]
+			}
 			p.logger.Debug("Created CNS IP config request", zap.Any("request", ipconfigReq))
 
 			p.logger.Debug("Making request to CNS")
@@ -101,12 +136,16 @@ func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
 					res.PodIpInfo,
 				},
 			}
+		} else if cnscli.IsSubnetExhausted(err) {
+			p.logger.Error("Failed to request IP address from CNS as the subnet is exhausted", zap.Error(err), zap.Any("request", req))
+			return cniTypes.NewError(cniTypes.ErrTryAgainLater, err.Error(), "subnet is exhausted and no IP addresses are available on the node, try again later")
 		} else {
 			p.logger.Error("Failed to request IP address from CNS", zap.Error(err), zap.Any("request", req))
 			return cniTypes.NewError(ErrRequestIPConfigFromCNS, err.Error(), "failed to request IP address from CNS")
 		}
 	}
 	p.logger.Debug("Received CNS IP config response", zap.Any("response", resp))
+	p.recordAttachment(req.PodInterfaceID, nwCfg.Name)
 
 	// Get Pod IP and gateway IP from ip config response
 	podIPNet, err := ipconfig.ProcessIPConfigsResp(resp)
@@ -114,7 +153,25 @@ func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
 		p.logger.Error("Failed to interpret CNS IPConfigResponse", zap.Error(err), zap.Any("response", resp))
 		return cniTypes.NewError(ErrProcessIPConfigResponse, err.Error(), "failed to interpret CNS IPConfigResponse")
 	}
+	podInterfaces, interfaceIndexes, err := ipconfig.ProcessPodInterfaces(resp, args.IfName)
+	if err != nil {
+		p.logger.Error("Failed to interpret CNS IPConfigResponse", zap.Error(err), zap.Any("response", resp))
+		return cniTypes.NewError(ErrProcessIPConfigResponse, err.Error(), "failed to interpret CNS IPConfigResponse")
+	}
 	cniResult := &types100.Result{}
+	// the interfaces are only listed for pods with multiple NICs, so that chained plugins can tell the IPs of each NIC
+	// apart, and the result for a single NIC stays as it was
+	multiNIC := len(podInterfaces) > 1
+	if multiNIC {
+		cniResult.Interfaces = make([]*types100.Interface, len(podInterfaces))
+		for i := range podInterfaces {
+			cniResult.Interfaces[i] = &types100.Interface{
+				Name:    podInterfaces[i].Name,
+				Mac:     podInterfaces[i].Mac,
+				Sandbox: args.Netns,
+			}
+		}
+	}
 	cniResult.IPs = make([]*types100.IPConfig, len(*podIPNet))
 	for i, ipNet := range *podIPNet {
 		p.logger.Debug("Parsed pod IP", zap.String("podIPNet", ipNet.String()))
@@ -130,11 +187,14 @@ func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
 				Mask: net.CIDRMask(ipNet.Bits(), 128), // nolint
 			}
 		}
+		if multiNIC {
+			ipConfig.Interface = types100.Int(interfaceIndexes[i])
+		}
 		cniResult.IPs[i] = ipConfig
 	}
 
 	// Get versioned result
-	versionedCniResult, err := cniResult.GetAsVersion(nwCfg.CNIVersion)
+	versionedCniResult, err := versionedResult(cniResult, nwCfg.CNIVersion)
 	if err != nil {
 		p.logger.Error("Failed to interpret CNI result with netconf CNI version", zap.Error(err), zap.Any("cniVersion", nwCfg.CNIVersion))
 		return cniTypes.NewError(cniTypes.ErrIncompatibleCNIVersion, err.Error(), "failed to interpret CNI result with netconf CNI version")
@@ -156,6 +216,13 @@ func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
 func (p *IPAMPlugin) CmdDel(args *cniSkel.CmdArgs) error {
 	p.logger.Info("DEL called", zap.Any("args", args))
 
+	// DEL must succeed even if the network config is invalid, which then retries with the defaults
+	if nwCfg, err := parseNetConf(args.StdinData); err != nil {
+		p.logger.Error("Failed to parse CNI network config from stdin", zap.Error(err), zap.Any("argStdinData", args.StdinData))
+	} else {
+		p.configureCNSRetries(nwCfg)
+	}
+
 	// Create ip config request from args
 	req, err := ipconfig.CreateIPConfigsReq(args)
 	if err != nil {
@@ -163,55 +230,278 @@ func (p *IPAMPlugin) CmdDel(args *cniSkel.CmdArgs) error {
 		return cniTypes.NewError(cniTypes.ErrTryAgainLater, err.Error(), "failed to create CNS IP configs request")
 	}
 	p.logger.Debug("Created CNS IP config request", zap.Any("request", req))
+	p.retryPendingReleasesForInvocation(req.PodInterfaceID)
+
+	err = p.releaseIPs(req)
+	p.forgetAttachment(req.PodInterfaceID)
+	if err != nil {
+		// the caller may not call DEL again, e.g. if the pod was deleted, so the release is retried by later invocations
+		p.enqueueRelease(req)
+		return err
+	}
+
+	p.logger.Info("DEL success")
+
+	return nil
+}
+
+// CmdCheck handles CNI check commands. It verifies that the IPs in the prevResult are the ones CNS has assigned to
+// the pod, so that the runtime recreates the sandbox when they have diverged.
+func (p *IPAMPlugin) CmdCheck(args *cniSkel.CmdArgs) error {
+	p.logger.Info("CHECK called", zap.Any("args", args))
+
+	// Parsing network conf
+	nwCfg, err := parseNetConf(args.StdinData)
+	if err != nil {
+		p.logger.Error("Failed to parse CNI network config from stdin", zap.Error(err), zap.Any("argStdinData", args.StdinData))
+		return cniTypes.NewError(cniTypes.ErrDecodingFailure, err.Error(), "failed to parse CNI network config from stdin")
+	}
+	asSpecVersion100(&nwCfg.NetConf)
+	if err = cniVersion.ParsePrevResult(&nwCfg.NetConf); err != nil {
+		p.logger.Error("Failed to parse prevResult from CNI network config", zap.Error(err))
+		return cniTypes.NewError(cniTypes.ErrDecodingFailure, err.Error(), "failed to parse prevResult from CNI network config")
+	}
+	if nwCfg.PrevResult == nil {
+		p.logger.Error("CNI network config has no prevResult")
+		return cniTypes.NewError(cniTypes.ErrInvalidNetworkConfig, "missing prevResult", "CNI network config has no prevResult")
+	}
+	prevResult, err := types100.NewResultFromResult(nwCfg.PrevResult)
+	if err != nil {
+		p.logger.Error("Failed to convert prevResult to the current CNI version", zap.Error(err), zap.Any("prevResult", nwCfg.PrevResult))
+		return cniTypes.NewError(cniTypes.ErrIncompatibleCNIVersion, err.Error(), "failed to convert prevResult to the current CNI version")
+	}
+	p.logger.Debug("Parsed prevResult", zap.Any("prevResult", prevResult))
 
 	p.logger.Debug("Making request to CNS")
 	// cnsClient enforces it own timeout
-	if err := p.cnsClient.ReleaseIPs(context.TODO(), req); err != nil {
-		// if we fail a request with a 404 error try using the old API
-		if cnscli.IsUnsupportedAPI(err) {
-			p.logger.Error("Failed to release IPs using ReleaseIPs from CNS, going to try ReleaseIPAddress", zap.Error(err), zap.Any("request", req))
-			ipconfigReq, err := ipconfig.CreateIPConfigReq(args)
-			if err != nil {
-				p.logger.Error("Failed to create CNS IP config request", zap.Error(err))
-				return cniTypes.NewError(ErrCreateIPConfigRequest, err.Error(), "failed to create CNS IP config request")
-			}
-			p.logger.Debug("Created CNS IP config request", zap.Any("request", ipconfigReq))
+	statuses, err := p.cnsClient.GetIPAddressesMatchingStates(context.TODO(), types.Assigned)
+	if err != nil {
+		p.logger.Error("Failed to get assigned IP addresses from CNS", zap.Error(err))
+		return cniTypes.NewError(ErrGetIPConfigsFromCNS, err.Error(), "failed to get assigned IP addresses from CNS")
+	}
 
-			p.logger.Debug("Making request to CNS")
-			err = p.cnsClient.ReleaseIPAddress(context.TODO(), ipconfigReq)
+	podIPs, err := ipconfig.AssignedPodIPs(args, statuses)
+	if err != nil {
+		p.logger.Error("Failed to interpret CNS IP config statuses", zap.Error(err))
+		return cniTypes.NewError(ErrProcessIPConfigStatuses, err.Error(), "failed to interpret CNS IP config statuses")
+	}
+	p.logger.Debug("Found pod IPs assigned in CNS", zap.Any("podIPs", podIPs))
 
-			if err != nil {
-				p.logger.Error("Failed to release IP address to CNS using ReleaseIPAddress", zap.Error(err), zap.Any("request", ipconfigReq))
-				return cniTypes.NewError(ErrRequestIPConfigFromCNS, err.Error(), "failed to release IP address from CNS using ReleaseIPAddress")
-			}
-		} else {
-			p.logger.Error("Failed to release IP addresses from CNS", zap.Error(err), zap.Any("request", req))
-			return cniTypes.NewError(cniTypes.ErrTryAgainLater, err.Error(), "failed to release IP addresses from CNS")
+	assigned := make(map[netip.Addr]struct{}, len(podIPs))
+	for _, ip := range podIPs {
+		assigned[ip] = struct{}{}
+	}
+	expected := make(map[netip.Addr]struct{}, len(prevResult.IPs))
+	for _, ipConfig := range prevResult.IPs {
+		ip, ok := netip.AddrFromSlice(ipConfig.Address.IP)
+		if !ok {
+			p.logger.Error("Invalid IP in prevResult", zap.Any("ipConfig", ipConfig))
+			return cniTypes.NewError(cniTypes.ErrInvalidNetworkConfig, "invalid IP "+ipConfig.Address.String(), "invalid IP in prevResult")
+		}
+		expected[ip.Unmap()] = struct{}{}
+	}
+	if len(assigned) != len(expected) {
+		return p.divergedError(podIPs, prevResult)
+	}
+	for ip := range expected {
+		if _, ok := assigned[ip]; !ok {
+			return p.divergedError(podIPs, prevResult)
 		}
 	}
 
-	p.logger.Info("DEL success")
+	p.logger.Info("CHECK success")
 
 	return nil
 }
 
-// CmdCheck handles CNI check command - not implemented
-func (p *IPAMPlugin) CmdCheck(args *cniSkel.CmdArgs) error {
-	p.logger.Info("CHECK called")
+// CmdGC handles CNI GC commands. It releases the IPs which CNS has assigned to containers that the plugin added to the
+// network but that aren't among the valid attachments given by the runtime, which leak when the runtime couldn't run
+// DEL. The IPs assigned for other networks or plugins, or assigned too recently for the runtime to have listed their
+// attachment, are never released.
+func (p *IPAMPlugin) CmdGC(args *cniSkel.CmdArgs) error {
+	p.logger.Info("GC called", zap.Any("args", args))
+
+	// Parsing network conf
+	nwCfg := &gcNetConf{}
+	if err := json.Unmarshal(args.StdinData, nwCfg); err != nil {
+		p.logger.Error("Failed to parse CNI network config from stdin", zap.Error(err), zap.Any("argStdinData", args.StdinData))
+		return cniTypes.NewError(cniTypes.ErrDecodingFailure, err.Error(), "failed to parse CNI network config from stdin")
+	}
+	// without the valid attachments, every IP would look stale
+	if nwCfg.ValidAttachments == nil {
+		p.logger.Error("Network config has no valid attachments", zap.Any("argStdinData", args.StdinData))
+		return cniTypes.NewError(cniTypes.ErrInvalidNetworkConfig, "missing cni.dev/valid-attachments", "network config has no valid attachments")
+	}
+	p.configureCNSRetries(&nwCfg.netConf)
+	if p.attachments == nil {
+		p.logger.Info("GC skipped as the attachments of the network are unknown")
+		return nil
+	}
+	validContainerIDs := make(map[string]struct{}, len(*nwCfg.ValidAttachments))
+	for _, a := range *nwCfg.ValidAttachments {
+		validContainerIDs[a.ContainerID] = struct{}{}
+	}
+	p.retryPendingReleasesForInvocation("")
+	stale, err := p.staleAttachments(nwCfg.Name, validContainerIDs)
+	if err != nil {
+		p.logger.Error("Failed to read the attachments of the network", zap.Error(err))
+		return cniTypes.NewError(cniTypes.ErrIOFailure, err.Error(), "failed to read the attachments of the network")
+	}
+
+	p.logger.Debug("Making request to CNS")
+	// cnsClient enforces it own timeout
+	statuses, err := p.cnsClient.GetIPAddressesMatchingStates(context.TODO(), types.Assigned)
+	if err != nil {
+		p.logger.Error("Failed to get assigned IP addresses from CNS", zap.Error(err))
+		return cniTypes.NewError(ErrGetIPConfigsFromCNS, err.Error(), "failed to get assigned IP addresses from CNS")
+	}
+
+	// a release covers all the IPs of a pod interface
+	staleReqs := map[string]cns.IPConfigsRequest{}
+	for i := range statuses {
+		podInfo := statuses[i].PodInfo
+		// the IPs which CNS keeps without a container ID can't be matched to attachments
+		if podInfo == nil || podInfo.InfraContainerID() == "" {
+			continue
+		}
+		if _, ok := stale[podInfo.InfraContainerID()]; !ok {
+			continue
+		}
+		// the IP may have been released and assigned again since the attachment was recorded
+		if time.Since(statuses[i].LastStateTransition) < gcGracePeriod {
+			delete(stale, podInfo.InfraContainerID())
+			continue
+		}
+		orchestratorContext, err := podInfo.OrchestratorContext()
+		if err != nil {
+			p.logger.Error("Failed to create orchestrator context", zap.Error(err), zap.Any("podInfo", podInfo))
+			continue
+		}
+		staleReqs[podInfo.InterfaceID()] = cns.IPConfigsRequest{
+			PodInterfaceID:      podInfo.InterfaceID(),
+			InfraContainerID:    podInfo.InfraContainerID(),
+			OrchestratorContext: orchestratorContext,
+		}
+	}
+
+	// a dual-stack pod interface is kept if any of its IPs was assigned recently
+	for podInterfaceID, req := range staleReqs {
+		if _, ok := stale[req.InfraContainerID]; !ok {
+			delete(staleReqs, podInterfaceID)
+		}
+	}
+
+	var releaseErr error
+	for _, req := range staleReqs {
+		p.logger.Info("Releasing IPs of stale attachment", zap.Any("request", req))
+		if err := p.releaseIPs(req); err != nil {
+			p.enqueueRelease(req)
+			releaseErr = err
+		}
+	}
+	// the stale attachments have had their IPs released or queued for release, or CNS no longer has IPs assigned to them
+	for podInterfaceID := range stale {
+		p.forgetAttachment(podInterfaceID)
+	}
+	if releaseErr != nil {
+		return releaseErr
+	}
+
+	p.logger.Info("GC success", zap.Int("released", len(staleReqs)))
+
 	return nil
 }
 
+// CmdStatus handles CNI STATUS commands. azure-ipam can service ADD commands when CNS is reachable, unless the pool
+// has no free IPs and can't grow since the subnet is exhausted. A pool without free IPs otherwise grows on demand.
+func (p *IPAMPlugin) CmdStatus(args *cniSkel.CmdArgs) error {
+	p.logger.Info("STATUS called", zap.Any("args", args))
+
+	// Parsing network conf
+	if _, err := parseNetConf(args.StdinData); err != nil {
+		p.logger.Error("Failed to parse CNI network config from stdin", zap.Error(err), zap.Any("argStdinData", args.StdinData))
+		return cniTypes.NewError(cniTypes.ErrDecodingFailure, err.Error(), "failed to parse CNI network config from stdin")
+	}
+
+	p.logger.Debug("Making request to CNS")
+	// cnsClient enforces it own timeout
+	status, err := p.cnsClient.GetIPAMStatusSummary(context.TODO())
+	if cnscli.IsUnsupportedAPI(err) {
+		// CNS is reachable but predates the IPAM status API
+		p.logger.Info("STATUS success without the IP pool status", zap.Error(err))
+		return nil
+	}
+	if err != nil {
+		p.logger.Error("Failed to get the IP pool status from CNS", zap.Error(err))
+		return cniTypes.NewError(ErrPluginNotAvailable, err.Error(), "CNS is not reachable")
+	}
+	if status.Free == 0 && status.SubnetExhausted {
+		p.logger.Error("No IP addresses available in CNS and the subnet is exhausted", zap.Int("poolSize", status.PoolSize))
+		return cniTypes.NewError(ErrPluginNotAvailable, "no IP addresses available and the subnet is exhausted", "the IP pool has no capacity")
+	}
+
+	p.logger.Info("STATUS success", zap.Int("available", status.Free), zap.Int("poolSize", status.PoolSize))
+
+	return nil
+}
+
+func (p *IPAMPlugin) divergedError(podIPs []netip.Addr, prevResult *types100.Result) error {
+	msg := fmt.Sprintf("pod IPs %v in prevResult do not match IPs %v assigned in CNS", prevResult.IPs, podIPs)
+	p.logger.Error("Pod IPs diverged from CNS", zap.Any("podIPs", podIPs), zap.Any("prevResult", prevResult))
+	return cniTypes.NewError(ErrIPConfigsDiverged, msg, "pod IPs diverged from CNS")
+}
+
+// gcNetConf is the network config of GC commands.
+// https://www.cni.dev/docs/spec/#gc-clean-up-any-stale-resources
+type gcNetConf struct {
+	netConf
+	// ValidAttachments is nil when the runtime didn't set it, and empty when there are no valid attachments.
+	ValidAttachments *[]struct {
+		ContainerID string `json:"containerID"`
+		IfName      string `json:"ifname"`
+	} `json:"cni.dev/valid-attachments,omitempty"`
+}
+
+// versionedResult converts the result to the given CNI version. 1.1.0 results are 1.0.0 ones with the version changed.
+func versionedResult(result *types100.Result, version string) (cniTypes.Result, error) {
+	if version != cniSpecVersion110 {
+		return result.GetAsVersion(version)
+	}
+	result.CNIVersion = cniSpecVersion110
+	return result, nil
+}
+
+// asSpecVersion100 makes a 1.1.0 network config and its prevResult parsable by the CNI library as 1.0.0 ones,
+// which have the same format.
+func asSpecVersion100(nwCfg *cniTypes.NetConf) {
+	if nwCfg.CNIVersion != cniSpecVersion110 {
+		return
+	}
+	nwCfg.CNIVersion = types100.ImplementedSpecVersion
+	if nwCfg.RawPrevResult != nil {
+		nwCfg.RawPrevResult["cniVersion"] = types100.ImplementedSpecVersion
+	}
+}
+
+// netConf is the network config of azure-ipam.
+type netConf struct {
+	cniTypes.NetConf
+	// CNSRetry configures the retries of the CNS requests and their circuit breaker. It is the default config
+	// when unset, and its unset fields take the default values.
+	CNSRetry *cnscli.RetryConfig `json:"cnsRetry,omitempty"`
+}
+
 // Parse network config from given byte array
-func parseNetConf(b []byte) (*cniTypes.NetConf, error) {
-	netConf := &cniTypes.NetConf{}
-	err := json.Unmarshal(b, netConf)
+func parseNetConf(b []byte) (*netConf, error) {
+	nwCfg := &netConf{}
+	err := json.Unmarshal(b, nwCfg)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal net conf")
 	}
 
-	if netConf.CNIVersion == "" {
-		netConf.CNIVersion = "0.2.0" // default CNI version
+	if nwCfg.CNIVersion == "" {
+		nwCfg.CNIVersion = "0.2.0" // default CNI version
 	}
 
-	return netConf, nil
+	return nwCfg, nil
 }
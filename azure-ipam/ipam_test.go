@@ -7,10 +7,10 @@ import (
 	"net"
 	"testing"
 
-	"github.com/Azure/azure-container-networking/azure-ipam/logger"
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/client"
 	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/log/zaplog"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	types100 "github.com/containernetworking/cni/pkg/types/100"
@@ -21,7 +21,7 @@ import (
 var (
 	errFoo                           = errors.New("err")
 	errUnsupportedAPI                = errors.New("Unsupported API")
-	loggerCfg         *logger.Config = &logger.Config{}
+	loggerCfg         *zaplog.Config = &zaplog.Config{}
 )
 
 // MOckCNSClient is a mock implementation of the CNSClient interface
@@ -92,6 +92,8 @@ func (c *MockCNSClient) RequestIPs(ctx context.Context, ipconfig cns.IPConfigsRe
 	switch ipconfig.InfraContainerID {
 	case "failRequestCNSArgs":
 		return nil, errFoo
+	case "failRequestCNSSubnetExhausted":
+		return nil, errSubnetExhausted
 	case "happyArgsSingle", "failProcessCNSRespSingleIP", "failRequestCNSArgsSingleIP":
 		e := &client.CNSClientError{}
 		e.Code = types.UnsupportedAPI
@@ -145,6 +147,84 @@ func (c *MockCNSClient) RequestIPs(ctx context.Context, ipconfig cns.IPConfigsRe
 			},
 		}
 		return result, nil
+	case "happyArgsMultiNIC":
+		result := &cns.IPConfigsResponse{
+			PodIPInfo: []cns.PodIpInfo{
+				{
+					PodIPConfig: cns.IPSubnet{
+						IPAddress:    "10.0.1.10",
+						PrefixLength: 24,
+					},
+					NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+						IPSubnet: cns.IPSubnet{
+							IPAddress:    "10.0.1.0",
+							PrefixLength: 24,
+						},
+						GatewayIPAddress: "10.0.0.1",
+					},
+					NICType: cns.InfraNIC,
+				},
+				{
+					PodIPConfig: cns.IPSubnet{
+						IPAddress:    "20.0.1.10",
+						PrefixLength: 24,
+					},
+					NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+						IPSubnet: cns.IPSubnet{
+							IPAddress:    "20.0.1.0",
+							PrefixLength: 24,
+						},
+						GatewayIPAddress: "20.0.1.1",
+					},
+					NICType:    cns.DelegatedVMNIC,
+					MacAddress: "00-0D-3A-12-34-56",
+				},
+				{
+					PodIPConfig: cns.IPSubnet{
+						IPAddress:    "fd11:1234::1",
+						PrefixLength: 120,
+					},
+					NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+						IPSubnet: cns.IPSubnet{
+							IPAddress:    "fd11:1234::",
+							PrefixLength: 120,
+						},
+						GatewayIPAddress: "fe80::1234:5678:9abc",
+					},
+					NICType: cns.InfraNIC,
+				},
+			},
+			Response: cns.Response{
+				ReturnCode: 0,
+				Message:    "",
+			},
+		}
+		return result, nil
+	case "failProcessCNSRespMAC":
+		result := &cns.IPConfigsResponse{
+			PodIPInfo: []cns.PodIpInfo{
+				{
+					PodIPConfig: cns.IPSubnet{
+						IPAddress:    "20.0.1.10",
+						PrefixLength: 24,
+					},
+					NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+						IPSubnet: cns.IPSubnet{
+							IPAddress:    "20.0.1.0",
+							PrefixLength: 24,
+						},
+						GatewayIPAddress: "20.0.1.1",
+					},
+					NICType:    cns.DelegatedVMNIC,
+					MacAddress: "invalidMAC",
+				},
+			},
+			Response: cns.Response{
+				ReturnCode: 0,
+				Message:    "",
+			},
+		}
+		return result, nil
 	default:
 		result := &cns.IPConfigsResponse{
 			PodIPInfo: []cns.PodIpInfo{
@@ -219,6 +299,20 @@ func (c *MockCNSClient) ReleaseIPs(ctx context.Context, ipconfig cns.IPConfigsRe
 	}
 }
 
+func (c *MockCNSClient) GetIPAddressesMatchingStates(ctx context.Context, states ...types.IPState) ([]cns.IPConfigurationStatus, error) {
+	return []cns.IPConfigurationStatus{
+		{IPAddress: "10.0.1.10", PodInfo: cns.NewPodInfo("happyArgsDual", "happyArgsDual", "testname", "testns")},
+		{IPAddress: "fd11:1234::1", PodInfo: cns.NewPodInfo("happyArgsDual", "happyArgsDual", "testname", "testns")},
+		{IPAddress: "10.0.1.11", PodInfo: cns.NewPodInfo("", "", "legacyname", "testns")},
+		{IPAddress: "10.0.1.12", PodInfo: cns.NewPodInfo("otherArgs", "otherArgs", "othername", "testns")},
+		{IPAddress: "10.0.1.10.2", PodInfo: cns.NewPodInfo("failProcessCNSStatuses", "failProcessCNSStatuses", "testname", "testns")},
+	}, nil
+}
+
+func (c *MockCNSClient) GetIPAMStatusSummary(context.Context) (*cns.IPAMStatusResponse, error) {
+	return &cns.IPAMStatusResponse{PoolSize: 5, Allocated: 4, Free: 1}, nil
+}
+
 // cniResultsWriter is a helper struct to write CNI results to a byte array
 type cniResultsWriter struct {
 	result *types100.Result
@@ -316,6 +410,53 @@ func TestCmdAdd(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Happy CNI add multiple NICs",
+			args: buildArgs("happyArgsMultiNIC", happyPodArgs, happyNetConfByteArr),
+			want: &types100.Result{
+				CNIVersion: "1.0.0",
+				Interfaces: []*types100.Interface{
+					{
+						Name:    "testifname",
+						Sandbox: "testnetns",
+					},
+					{
+						Mac:     "00:0d:3a:12:34:56",
+						Sandbox: "testnetns",
+					},
+				},
+				IPs: []*types100.IPConfig{
+					{
+						Interface: types100.Int(0),
+						Address: net.IPNet{
+							IP:   net.IPv4(10, 0, 1, 10),
+							Mask: net.CIDRMask(24, 32),
+						},
+					},
+					{
+						Interface: types100.Int(1),
+						Address: net.IPNet{
+							IP:   net.IPv4(20, 0, 1, 10),
+							Mask: net.CIDRMask(24, 32),
+						},
+					},
+					{
+						Interface: types100.Int(0),
+						Address: net.IPNet{
+							IP:   net.ParseIP("fd11:1234::1"),
+							Mask: net.CIDRMask(120, 128),
+						},
+					},
+				},
+				DNS: cniTypes.DNS{},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Fail process CNS response with invalid MAC address during CmdAdd",
+			args:    buildArgs("failProcessCNSRespMAC", happyPodArgs, happyNetConfByteArr),
+			wantErr: true,
+		},
 		{
 			name:    "Fail request CNS ipconfig during CmdAdd",
 			args:    buildArgs("failRequestCNSArgs", happyPodArgs, happyNetConfByteArr),
@@ -352,7 +493,7 @@ func TestCmdAdd(t *testing.T) {
 		writer := &cniResultsWriter{}
 		t.Run(tt.name, func(t *testing.T) {
 			mockCNSClient := &MockCNSClient{}
-			testLogger, cleanup, err := logger.New(loggerCfg)
+			testLogger, cleanup, err := zaplog.New(loggerCfg)
 			if err != nil {
 				fmt.Println(err)
 				return
@@ -372,6 +513,21 @@ func TestCmdAdd(t *testing.T) {
 	}
 }
 
+func TestCmdAddSubnetExhausted(t *testing.T) {
+	netConf, err := json.Marshal(&cniTypes.NetConf{CNIVersion: "1.0.0", Name: "happynetconf"})
+	require.NoError(t, err)
+	testLogger, cleanup, err := zaplog.New(loggerCfg)
+	require.NoError(t, err)
+	defer cleanup()
+	ipamPlugin, err := NewPlugin(testLogger, &MockCNSClient{}, &cniResultsWriter{})
+	require.NoError(t, err)
+
+	err = ipamPlugin.CmdAdd(buildArgs("failRequestCNSSubnetExhausted", happyPodArgs, netConf))
+	var cniErr *cniTypes.Error
+	require.ErrorAs(t, err, &cniErr)
+	require.Equal(t, uint(cniTypes.ErrTryAgainLater), cniErr.Code)
+}
+
 func TestCmdDel(t *testing.T) {
 	happyNetConf := &cniTypes.NetConf{
 		CNIVersion: "1.0.0",
@@ -409,7 +565,7 @@ func TestCmdDel(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			mockCNSClient := &MockCNSClient{}
-			testLogger, cleanup, err := logger.New(loggerCfg)
+			testLogger, cleanup, err := zaplog.New(loggerCfg)
 			if err != nil {
 				return
 			}
@@ -425,14 +581,89 @@ func TestCmdDel(t *testing.T) {
 	}
 }
 
-func TestCmdCheck(t *testing.T) {
-	mockCNSClient := &MockCNSClient{}
-	testLogger, cleanup, err := logger.New(loggerCfg)
-	if err != nil {
-		return
+// build a network config with a prevResult holding the given IPs for tests
+func buildNetConfWithPrevResult(t *testing.T, ips ...string) []byte {
+	t.Helper()
+	prevResult := &types100.Result{CNIVersion: "1.0.0"}
+	for _, ip := range ips {
+		addr, ipNet, err := net.ParseCIDR(ip)
+		require.NoError(t, err)
+		prevResult.IPs = append(prevResult.IPs, &types100.IPConfig{Address: net.IPNet{IP: addr, Mask: ipNet.Mask}})
 	}
-	defer cleanup()
-	ipamPlugin, _ := NewPlugin(testLogger, mockCNSClient, nil)
-	err = ipamPlugin.CmdCheck(nil)
+	raw, err := json.Marshal(prevResult)
+	require.NoError(t, err)
+	netConf := map[string]interface{}{
+		"cniVersion": "1.0.0",
+		"name":       "happynetconf",
+		"prevResult": json.RawMessage(raw),
+	}
+	b, err := json.Marshal(netConf)
+	require.NoError(t, err)
+	return b
+}
+
+func TestCmdCheck(t *testing.T) {
+	happyNetConfByteArr, err := json.Marshal(&cniTypes.NetConf{CNIVersion: "1.0.0", Name: "happynetconf"})
 	require.NoError(t, err)
+	dualNetConfByteArr := buildNetConfWithPrevResult(t, "10.0.1.10/24", "fd11:1234::1/120")
+	legacyPodArgs := "K8S_POD_NAMESPACE=testns;K8S_POD_NAME=legacyname"
+
+	tests := []scenario{
+		{
+			name: "Happy CNI check dual IP",
+			args: buildArgs("happyArgsDual", happyPodArgs, dualNetConfByteArr),
+		},
+		{
+			name: "Happy CNI check IP assigned without container ID",
+			args: buildArgs("legacyArgs", legacyPodArgs, buildNetConfWithPrevResult(t, "10.0.1.11/24")),
+		},
+		{
+			name:    "Fail CNI check without prevResult",
+			args:    buildArgs("happyArgsDual", happyPodArgs, happyNetConfByteArr),
+			wantErr: true,
+		},
+		{
+			name:    "Fail CNI check with invalid netconf",
+			args:    buildArgs("happyArgsDual", happyPodArgs, []byte("invalidNetConf")),
+			wantErr: true,
+		},
+		{
+			name:    "Fail CNI check with IP missing in CNS",
+			args:    buildArgs("happyArgsDual", happyPodArgs, buildNetConfWithPrevResult(t, "10.0.1.10/24")),
+			wantErr: true,
+		},
+		{
+			name:    "Fail CNI check with IP of another pod",
+			args:    buildArgs("happyArgsDual", happyPodArgs, buildNetConfWithPrevResult(t, "10.0.1.12/24", "fd11:1234::1/120")),
+			wantErr: true,
+		},
+		{
+			name:    "Fail CNI check with no IPs assigned in CNS",
+			args:    buildArgs("unknownArgs", happyPodArgs, dualNetConfByteArr),
+			wantErr: true,
+		},
+		{
+			name:    "Fail process CNS IP config statuses during CmdCheck",
+			args:    buildArgs("failProcessCNSStatuses", happyPodArgs, dualNetConfByteArr),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mockCNSClient := &MockCNSClient{}
+			testLogger, cleanup, err := zaplog.New(loggerCfg)
+			if err != nil {
+				return
+			}
+			defer cleanup()
+			ipamPlugin, _ := NewPlugin(testLogger, mockCNSClient, nil)
+			err = ipamPlugin.CmdCheck(tt.args)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
 }
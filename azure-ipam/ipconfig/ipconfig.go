@@ -3,7 +3,9 @@ package ipconfig
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/netip"
+	"strings"
 
 	"github.com/Azure/azure-container-networking/cns"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
@@ -82,11 +84,125 @@ func ProcessIPConfigsResp(resp *cns.IPConfigsResponse) (*[]netip.Prefix, error)
 	return &podIPNets, nil
 }
 
+// PodInterface is an interface of the pod to which CNS assigned some of the pod IPs.
+type PodInterface struct {
+	Name string
+	Mac  string
+}
+
+// ProcessPodInterfaces returns the distinct interfaces of the pod IPs in the given CNS response, in the order in which
+// they first appear, and the index of the interface of each pod IP. The pod IPs which CNS returns without an interface
+// name or MAC address, such as those of the InfraNIC, are on the interface of the given name from the CNI args.
+func ProcessPodInterfaces(resp *cns.IPConfigsResponse, ifName string) ([]PodInterface, []int, error) {
+	var podInterfaces []PodInterface
+	indexes := make([]int, len(resp.PodIPInfo))
+	seen := map[PodInterface]int{}
+
+	for i := range resp.PodIPInfo {
+		podInterface := PodInterface{Name: resp.PodIPInfo[i].InterfaceName}
+		if mac := resp.PodIPInfo[i].MacAddress; mac != "" {
+			hwAddr, err := net.ParseMAC(mac)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "cns returned invalid MAC address %q", mac)
+			}
+			podInterface.Mac = hwAddr.String()
+		}
+		if podInterface.Name == "" && podInterface.Mac == "" {
+			podInterface.Name = ifName
+		}
+		idx, ok := seen[podInterface]
+		if !ok {
+			idx = len(podInterfaces)
+			seen[podInterface] = idx
+			podInterfaces = append(podInterfaces, podInterface)
+		}
+		indexes[i] = idx
+	}
+
+	return podInterfaces, indexes, nil
+}
+
+// AssignedPodIPs returns the IPs out of the given CNS IP config statuses which are assigned to the pod of the given
+// CNI args. The IPs are matched by the container ID they were requested with, or by the pod name and namespace for
+// the IPs which CNS keeps without one.
+func AssignedPodIPs(args *cniSkel.CmdArgs, statuses []cns.IPConfigurationStatus) ([]netip.Addr, error) {
+	podConf, err := parsePodConf(args.Args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse pod config from CNI args")
+	}
+
+	var podIPs []netip.Addr
+	for i := range statuses {
+		podInfo := statuses[i].PodInfo
+		if podInfo == nil {
+			continue
+		}
+		if podInfo.InfraContainerID() != args.ContainerID &&
+			(podInfo.InfraContainerID() != "" ||
+				podInfo.Name() != string(podConf.K8S_POD_NAME) ||
+				podInfo.Namespace() != string(podConf.K8S_POD_NAMESPACE)) {
+			continue
+		}
+		ip, err := netip.ParseAddr(statuses[i].IPAddress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cns returned invalid pod IP %q", statuses[i].IPAddress)
+		}
+		podIPs = append(podIPs, ip)
+	}
+
+	return podIPs, nil
+}
+
+// runtimeConf is the runtime config passed in the network config of the given CNI args, with the capabilities
+// which azure-ipam supports.
+// https://github.com/containernetworking/cni/blob/main/CONVENTIONS.md#dynamic-plugin-specific-fields-capabilities--runtime-configuration
+type runtimeConf struct {
+	RuntimeConfig struct {
+		IPs []string `json:"ips,omitempty"`
+	} `json:"runtimeConfig,omitempty"`
+}
+
+// DesiredIPs returns the static IPs requested for the pod of the given CNI args, from the ips capability in the
+// runtime config or from the IP arg, which is a comma separated list. The IPs may be given with their prefix length,
+// which CNS doesn't need, so only the addresses are returned.
+func DesiredIPs(args *cniSkel.CmdArgs) ([]string, error) {
+	conf := runtimeConf{}
+	if err := json.Unmarshal(args.StdinData, &conf); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal runtime config")
+	}
+	ips := conf.RuntimeConfig.IPs
+	if len(ips) == 0 {
+		podConf, err := parsePodConf(args.Args)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse pod config from CNI args")
+		}
+		if podConf.IP != "" {
+			ips = strings.Split(string(podConf.IP), ",")
+		}
+	}
+
+	desiredIPs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
+		if prefix, err := netip.ParsePrefix(ip); err == nil {
+			desiredIPs = append(desiredIPs, prefix.Addr().String())
+			continue
+		}
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid desired IP %q", ip)
+		}
+		desiredIPs = append(desiredIPs, addr.String())
+	}
+	return desiredIPs, nil
+}
+
 type k8sPodEnvArgs struct {
 	cniTypes.CommonArgs
 	K8S_POD_NAMESPACE          cniTypes.UnmarshallableString `json:"K8S_POD_NAMESPACE,omitempty"`          // nolint
 	K8S_POD_NAME               cniTypes.UnmarshallableString `json:"K8S_POD_NAME,omitempty"`               // nolint
 	K8S_POD_INFRA_CONTAINER_ID cniTypes.UnmarshallableString `json:"K8S_POD_INFRA_CONTAINER_ID,omitempty"` // nolint
+	IP                         cniTypes.UnmarshallableString `json:"IP,omitempty"`
 }
 
 func parsePodConf(args string) (*k8sPodEnvArgs, error) {
@@ -1,18 +1,26 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"io"
 	"log"
 	"os"
 
 	"github.com/Azure/azure-container-networking/azure-ipam/internal/buildinfo"
-	"github.com/Azure/azure-container-networking/azure-ipam/logger"
 	cnsclient "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/log/zaplog"
 	"github.com/containernetworking/cni/pkg/skel"
-	"github.com/containernetworking/cni/pkg/version"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
 	bv "github.com/containernetworking/plugins/pkg/utils/buildversion"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
+// flushReleaseQueue makes the binary retry all the IP releases queued by failed DEL commands and exit,
+// instead of running as a CNI plugin.
+var flushReleaseQueue = flag.Bool("flush-release-queue", false, "retry all pending IP releases and exit")
+
 func main() {
 	if err := executePlugin(); err != nil {
 		log.Printf("error executing azure-ipam plugin: %v\n", err)
@@ -21,15 +29,18 @@ func main() {
 }
 
 func executePlugin() error {
+	flag.Parse()
+
 	// logger config
-	loggerCfg := &logger.Config{
+	loggerCfg := &zaplog.Config{
+		Component:   "azure-ipam",
 		Level:       "debug",
 		Filepath:    "/var/log/azure-ipam.log",
 		MaxSizeInMB: 5, // MegaBytes
 		MaxBackups:  8,
 	}
 	// Create logger
-	pluginLogger, cleanup, err := logger.New(loggerCfg)
+	pluginLogger, cleanup, err := zaplog.New(loggerCfg)
 	if err != nil {
 		return errors.Wrapf(err, "failed to setup IPAM logging")
 	}
@@ -42,17 +53,58 @@ func executePlugin() error {
 		return errors.Wrapf(err, "failed to initialize CNS client")
 	}
 
-	// Create IPAM plugin
-	plugin, err := NewPlugin(pluginLogger, client, os.Stdout)
+	// Diagnose the connection to CNS when run by an operator rather than a container runtime
+	if os.Getenv("CNI_COMMAND") == "" && flag.Arg(0) == statusCommand {
+		return newStatusChecker(client, os.Stdout).run(context.Background())
+	}
+
+	var breakers cnsclient.BreakerStore
+	if s, err := newBreakerStore(breakerStatePath, pluginLogger); err != nil {
+		// the breakers then only span this invocation
+		pluginLogger.Error("Failed to create circuit breaker store", zap.Error(err))
+	} else {
+		breakers = s
+	}
+
+	// Create IPAM plugin, retrying the IP requests and releases while CNS is unavailable
+	plugin, err := NewPlugin(pluginLogger, newRetryingCNSClient(client, breakers, pluginLogger), os.Stdout)
 	if err != nil {
 		pluginLogger.Error("Failed to create IPAM plugin")
 		return errors.Wrapf(err, "failed to create IPAM plugin")
 	}
 
+	queue, err := newReleaseQueue(releaseQueuePath, pluginLogger)
+	if err != nil {
+		// the plugin works without the queue, but leaks the IPs of failed releases until CNS garbage collects them
+		pluginLogger.Error("Failed to create IP release queue", zap.Error(err))
+	} else {
+		plugin.releaseQueue = queue
+	}
+
+	attachments, err := newAttachmentStore(attachmentsPath, pluginLogger)
+	if err != nil {
+		// the plugin works without the store, but GC can't tell which IPs were assigned for its network
+		pluginLogger.Error("Failed to create attachment store", zap.Error(err))
+	} else {
+		plugin.attachments = attachments
+	}
+
+	if *flushReleaseQueue {
+		return errors.Wrap(plugin.flushReleaseQueue(), "failed to flush IP release queue")
+	}
+
 	bv.BuildVersion = buildinfo.Version
 
+	// the CNI library doesn't dispatch the commands added in CNI spec 1.1 yet
+	switch os.Getenv("CNI_COMMAND") {
+	case "GC":
+		return executeCommand(plugin.CmdGC)
+	case "STATUS":
+		return executeCommand(plugin.CmdStatus)
+	}
+
 	// Execute CNI plugin
-	cniErr := skel.PluginMainWithError(plugin.CmdAdd, plugin.CmdCheck, plugin.CmdDel, version.All, bv.BuildString(pluginName))
+	cniErr := skel.PluginMainWithError(plugin.CmdAdd, plugin.CmdCheck, plugin.CmdDel, supportedVersions, bv.BuildString(pluginName))
 	if cniErr != nil {
 		cniErr.Print()
 		return cniErr
@@ -60,3 +112,24 @@ func executePlugin() error {
 
 	return nil
 }
+
+// executeCommand runs a CNI command which only takes the network config from stdin, and prints its error like the CNI
+// library does.
+func executeCommand(cmd func(*skel.CmdArgs) error) error {
+	stdinData, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		err = cniTypes.NewError(cniTypes.ErrIOFailure, err.Error(), "failed to read network config from stdin")
+	} else {
+		err = cmd(&skel.CmdArgs{StdinData: stdinData})
+	}
+	if err == nil {
+		return nil
+	}
+
+	cniErr := &cniTypes.Error{}
+	if !errors.As(err, &cniErr) {
+		cniErr = cniTypes.NewError(cniTypes.ErrInternal, err.Error(), "")
+	}
+	cniErr.Print()
+	return cniErr
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/Azure/azure-container-networking/store"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	pendingReleasesKey = "PendingReleases"
+	// pending releases older than this are dropped, and their IPs left to the CNS garbage collection
+	pendingReleaseTTL = 24 * time.Hour
+)
+
+var errReleasesPending = errors.New("IP releases still pending")
+
+// pendingRelease is a release of the IPs of a pod interface which failed, persisted to be retried.
+type pendingRelease struct {
+	Request  cns.IPConfigsRequest
+	QueuedAt time.Time
+	Attempts int
+}
+
+// releaseQueue persists the releases which failed in CmdDel, so that they are retried by later invocations of the
+// plugin instead of leaking the IPs until CNS garbage collects them. A release covers all the IPs of the pod
+// interface, so a dual-stack pod never has only one of its IP families released.
+// The queue is shared by concurrent invocations through a file lock.
+type releaseQueue struct {
+	store store.KeyValueStore
+}
+
+func newReleaseQueue(path string, logger *zap.Logger) (*releaseQueue, error) {
+	lock, err := processlock.NewFileLock(path + store.LockExtension)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create release queue lock")
+	}
+	kvs, err := store.NewJsonFileStore(path, lock, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create release queue store")
+	}
+	return &releaseQueue{store: kvs}, nil
+}
+
+// update calls fn with the pending releases, keyed by pod interface ID, and persists them if fn changed them.
+func (q *releaseQueue) update(fn func(pending map[string]*pendingRelease) bool) error {
+	if err := q.store.Lock(store.DefaultLockTimeout); err != nil {
+		return errors.Wrap(err, "failed to lock release queue")
+	}
+	defer q.store.Unlock() //nolint:errcheck // the lock is released when the process exits
+
+	pending := map[string]*pendingRelease{}
+	if err := q.store.Read(pendingReleasesKey, &pending); err != nil &&
+		!errors.Is(err, store.ErrKeyNotFound) && !errors.Is(err, store.ErrStoreEmpty) {
+		return errors.Wrap(err, "failed to read release queue")
+	}
+	if !fn(pending) {
+		return nil
+	}
+	return errors.Wrap(q.store.Write(pendingReleasesKey, pending), "failed to write release queue")
+}
+
+// enqueueRelease persists the failed release of the IPs in req.
+func (p *IPAMPlugin) enqueueRelease(req cns.IPConfigsRequest) {
+	if p.releaseQueue == nil {
+		return
+	}
+	err := p.releaseQueue.update(func(pending map[string]*pendingRelease) bool {
+		if _, ok := pending[req.PodInterfaceID]; !ok {
+			pending[req.PodInterfaceID] = &pendingRelease{Request: req, QueuedAt: time.Now()}
+		}
+		return true
+	})
+	if err != nil {
+		p.logger.Error("Failed to queue IP release for retry", zap.Error(err), zap.Any("request", req))
+		return
+	}
+	p.logger.Info("Queued IP release for retry", zap.String("podInterfaceID", req.PodInterfaceID))
+}
+
+// retryPendingReleases retries the pending releases, except the one of the pod interface the plugin was invoked for,
+// which is dropped since the invocation supersedes it. Unless flushing, it stops at the first failure, as CNS is
+// likely still unavailable, so that invocations aren't slowed down by retries. It returns the number of releases
+// still pending.
+func (p *IPAMPlugin) retryPendingReleases(currentPodInterfaceID string, flush bool) (int, error) {
+	if p.releaseQueue == nil {
+		return 0, nil
+	}
+	remaining := 0
+	err := p.releaseQueue.update(func(pending map[string]*pendingRelease) bool {
+		changed := false
+		if _, ok := pending[currentPodInterfaceID]; ok {
+			delete(pending, currentPodInterfaceID)
+			changed = true
+		}
+		failed := false
+		for podInterfaceID, release := range pending {
+			if time.Since(release.QueuedAt) > pendingReleaseTTL {
+				p.logger.Info("Dropping expired IP release", zap.String("podInterfaceID", podInterfaceID), zap.Int("attempts", release.Attempts))
+				delete(pending, podInterfaceID)
+				changed = true
+				continue
+			}
+			if failed && !flush {
+				continue
+			}
+			release.Attempts++
+			changed = true
+			if err := p.releaseIPs(release.Request); err != nil {
+				p.logger.Error("Failed to retry IP release", zap.String("podInterfaceID", podInterfaceID), zap.Int("attempts", release.Attempts))
+				failed = true
+				continue
+			}
+			p.logger.Info("Retried IP release", zap.String("podInterfaceID", podInterfaceID), zap.Int("attempts", release.Attempts))
+			delete(pending, podInterfaceID)
+		}
+		remaining = len(pending)
+		return changed
+	})
+	return remaining, err
+}
+
+// flushReleaseQueue retries all the pending releases, and fails if any is still pending.
+func (p *IPAMPlugin) flushReleaseQueue() error {
+	remaining, err := p.retryPendingReleases("", true)
+	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return errors.Wrapf(errReleasesPending, "%d releases failed", remaining)
+	}
+	p.logger.Info("Flushed IP release queue")
+	return nil
+}
+
+// retryPendingReleasesForInvocation retries the pending releases before handling the DEL or GC invocation for the pod
+// interface. Failures are only logged, as they don't concern the invocation.
+func (p *IPAMPlugin) retryPendingReleasesForInvocation(podInterfaceID string) {
+	remaining, err := p.retryPendingReleases(podInterfaceID, false)
+	if err != nil {
+		p.logger.Error("Failed to retry pending IP releases", zap.Error(err))
+		return
+	}
+	if remaining > 0 {
+		p.logger.Info("IP releases still pending", zap.Int("count", remaining))
+	}
+}
+
+// dropPendingRelease drops the pending release of the pod interface the plugin is adding, since its IPs must no
+// longer be released. The other releases are only retried by DEL and GC, so that ADD isn't slowed down by calls to
+// CNS which don't concern the invocation.
+func (p *IPAMPlugin) dropPendingRelease(podInterfaceID string) {
+	if p.releaseQueue == nil {
+		return
+	}
+	err := p.releaseQueue.update(func(pending map[string]*pendingRelease) bool {
+		if _, ok := pending[podInterfaceID]; !ok {
+			return false
+		}
+		delete(pending, podInterfaceID)
+		return true
+	})
+	if err != nil {
+		p.logger.Error("Failed to drop pending IP release", zap.Error(err), zap.String("podInterfaceID", podInterfaceID))
+	}
+}
+
+// releaseIPs releases the IPs of the pod interface in req from CNS, falling back to the legacy API if ReleaseIPs
+// isn't supported.
+func (p *IPAMPlugin) releaseIPs(req cns.IPConfigsRequest) error {
+	p.logger.Debug("Making request to CNS")
+	// cnsClient enforces it own timeout
+	err := p.cnsClient.ReleaseIPs(context.TODO(), req)
+	if err == nil {
+		return nil
+	}
+	if !cnscli.IsUnsupportedAPI(err) {
+		p.logger.Error("Failed to release IP addresses from CNS", zap.Error(err), zap.Any("request", req))
+		return cniTypes.NewError(cniTypes.ErrTryAgainLater, err.Error(), "failed to release IP addresses from CNS")
+	}
+
+	// if we fail a request with a 404 error try using the old API
+	p.logger.Error("Failed to release IPs using ReleaseIPs from CNS, going to try ReleaseIPAddress", zap.Error(err), zap.Any("request", req))
+	ipconfigReq := cns.IPConfigRequest{
+		PodInterfaceID:      req.PodInterfaceID,
+		InfraContainerID:    req.InfraContainerID,
+		OrchestratorContext: req.OrchestratorContext,
+		Ifname:              req.Ifname,
+	}
+	p.logger.Debug("Created CNS IP config request", zap.Any("request", ipconfigReq))
+
+	p.logger.Debug("Making request to CNS")
+	if err = p.cnsClient.ReleaseIPAddress(context.TODO(), ipconfigReq); err != nil {
+		p.logger.Error("Failed to release IP address to CNS using ReleaseIPAddress", zap.Error(err), zap.Any("request", ipconfigReq))
+		return cniTypes.NewError(ErrRequestIPConfigFromCNS, err.Error(), "failed to release IP address from CNS using ReleaseIPAddress")
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/log/zaplog"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// flakyReleaseCNSClient fails ReleaseIPs while failRelease is set, and records the released pod interfaces.
+type flakyReleaseCNSClient struct {
+	MockCNSClient
+	failRelease bool
+	released    []string
+}
+
+func (c *flakyReleaseCNSClient) ReleaseIPs(_ context.Context, req cns.IPConfigsRequest) error {
+	if c.failRelease {
+		return errFoo
+	}
+	c.released = append(c.released, req.PodInterfaceID)
+	return nil
+}
+
+func newReleaseQueuePlugin(t *testing.T, client cnsClient, path string) *IPAMPlugin {
+	t.Helper()
+	testLogger, cleanup, err := zaplog.New(loggerCfg)
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+	plugin, err := NewPlugin(testLogger, client, nil)
+	require.NoError(t, err)
+	plugin.releaseQueue, err = newReleaseQueue(path, testLogger)
+	require.NoError(t, err)
+	return plugin
+}
+
+func pendingReleases(t *testing.T, path string) map[string]*pendingRelease {
+	t.Helper()
+	// read through a new queue, like another invocation of the plugin
+	q, err := newReleaseQueue(path, zap.NewNop())
+	require.NoError(t, err)
+	var pending map[string]*pendingRelease
+	require.NoError(t, q.update(func(p map[string]*pendingRelease) bool {
+		pending = p
+		return false
+	}))
+	return pending
+}
+
+func TestCmdDelQueuesFailedRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "release-queue.json")
+	client := &flakyReleaseCNSClient{failRelease: true}
+	plugin := newReleaseQueuePlugin(t, client, path)
+
+	require.Error(t, plugin.CmdDel(buildArgs("pod-a", happyPodArgs, nil)))
+	require.Error(t, plugin.CmdDel(buildArgs("pod-b", happyPodArgs, nil)))
+	pending := pendingReleases(t, path)
+	require.Len(t, pending, 2)
+	require.Equal(t, "pod-a", pending["pod-a"].Request.PodInterfaceID)
+
+	// CNS is back: the next invocation releases the IPs of pod-a and pod-b along with its own
+	client.failRelease = false
+	require.NoError(t, plugin.CmdDel(buildArgs("pod-c", happyPodArgs, nil)))
+	require.ElementsMatch(t, []string{"pod-a", "pod-b", "pod-c"}, client.released)
+	require.Empty(t, pendingReleases(t, path))
+}
+
+func TestCmdAddDropsPendingReleaseOfContainer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "release-queue.json")
+	client := &flakyReleaseCNSClient{failRelease: true}
+	plugin := newReleaseQueuePlugin(t, client, path)
+	require.Error(t, plugin.CmdDel(buildArgs("happyArgsDual", happyPodArgs, nil)))
+
+	// the container is added again, so its IPs must not be released
+	plugin.out = &cniResultsWriter{}
+	require.NoError(t, plugin.CmdAdd(buildArgs("happyArgsDual", happyPodArgs, []byte(`{"cniVersion":"1.0.0","name":"azure"}`))))
+	require.Empty(t, pendingReleases(t, path))
+	require.Empty(t, client.released)
+}
+
+func TestCmdAddDoesNotRetryPendingReleases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "release-queue.json")
+	client := &flakyReleaseCNSClient{failRelease: true}
+	plugin := newReleaseQueuePlugin(t, client, path)
+	require.Error(t, plugin.CmdDel(buildArgs("pod-a", happyPodArgs, nil)))
+
+	// CNS is back, but the release of pod-a is left to the next DEL or GC
+	client.failRelease = false
+	plugin.out = &cniResultsWriter{}
+	require.NoError(t, plugin.CmdAdd(buildArgs("happyArgsDual", happyPodArgs, []byte(`{"cniVersion":"1.0.0","name":"azure"}`))))
+	require.Empty(t, client.released)
+	pending := pendingReleases(t, path)
+	require.Len(t, pending, 1)
+	require.Zero(t, pending["pod-a"].Attempts)
+}
+
+func TestFlushReleaseQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "release-queue.json")
+	client := &flakyReleaseCNSClient{failRelease: true}
+	plugin := newReleaseQueuePlugin(t, client, path)
+	for _, id := range []string{"pod-a", "pod-b", "pod-c"} {
+		require.Error(t, plugin.CmdDel(buildArgs(id, happyPodArgs, nil)))
+	}
+
+	// DEL stops retrying at the first failure, but flushing retries every release
+	before := pendingReleases(t, path)
+	require.ErrorIs(t, plugin.flushReleaseQueue(), errReleasesPending)
+	for id, release := range pendingReleases(t, path) {
+		require.Equal(t, before[id].Attempts+1, release.Attempts)
+	}
+
+	// expired releases are dropped without being retried
+	require.NoError(t, plugin.releaseQueue.update(func(pending map[string]*pendingRelease) bool {
+		pending["pod-a"].QueuedAt = time.Now().Add(-pendingReleaseTTL - time.Minute)
+		return true
+	}))
+	client.failRelease = false
+	require.NoError(t, plugin.flushReleaseQueue())
+	require.ElementsMatch(t, []string{"pod-b", "pod-c"}, client.released)
+	require.Empty(t, pendingReleases(t, path))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// retryingCNSClient retries the IP requests and releases to CNS which fail because CNS is unavailable, e.g. while it
+// restarts, so that pod sandbox creation doesn't fail. The state of the circuit breakers is persisted, so that they
+// open when successive invocations of the plugin keep failing.
+type retryingCNSClient struct {
+	cnsClient
+	retrying *cnscli.RetryingClient
+}
+
+func newRetryingCNSClient(c cnsClient, breakers cnscli.BreakerStore, logger *zap.Logger) *retryingCNSClient {
+	return &retryingCNSClient{
+		cnsClient: c,
+		retrying:  cnscli.NewRetryingClient(c, cnscli.DefaultRetryConfig, breakers, logger),
+	}
+}
+
+func (c *retryingCNSClient) RequestIPs(ctx context.Context, req cns.IPConfigsRequest) (*cns.IPConfigsResponse, error) {
+	return c.retrying.RequestIPs(ctx, req) //nolint:wrapcheck // the error of the CNS client is returned as is
+}
+
+func (c *retryingCNSClient) ReleaseIPs(ctx context.Context, req cns.IPConfigsRequest) error {
+	return c.retrying.ReleaseIPs(ctx, req) //nolint:wrapcheck // the error of the CNS client is returned as is
+}
+
+// configureCNSRetries applies the retry config of the network config, if any, to the CNS client.
+func (p *IPAMPlugin) configureCNSRetries(nwCfg *netConf) {
+	c, ok := p.cnsClient.(*retryingCNSClient)
+	if !ok || nwCfg.CNSRetry == nil {
+		return
+	}
+	c.retrying.SetConfig(*nwCfg.CNSRetry)
+}
+
+// breakerStore persists the state of the circuit breakers of the CNS requests next to the release queue. It is
+// shared by concurrent invocations through versioned writes, which don't lose the updates made concurrently.
+type breakerStore struct {
+	store store.KeyValueStore
+}
+
+func newBreakerStore(path string, logger *zap.Logger) (*breakerStore, error) {
+	lock, err := processlock.NewFileLock(path + store.LockExtension)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create circuit breaker lock")
+	}
+	kvs, err := store.NewJsonFileStore(path, lock, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create circuit breaker store")
+	}
+	return &breakerStore{store: kvs}, nil
+}
+
+func (s *breakerStore) Load(op string) (cnscli.BreakerState, error) {
+	var state cnscli.BreakerState
+	if _, err := s.store.ReadWithVersion(op, &state); err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return cnscli.BreakerState{}, errors.Wrap(err, "failed to read circuit breaker state")
+	}
+	return state, nil
+}
+
+func (s *breakerStore) Update(op string, fn func(*cnscli.BreakerState) bool) error {
+	for {
+		var state cnscli.BreakerState
+		version, err := s.store.ReadWithVersion(op, &state)
+		if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+			return errors.Wrap(err, "failed to read circuit breaker state")
+		}
+		if !fn(&state) {
+			return nil
+		}
+		_, err = s.store.WriteIfVersion(op, state, version)
+		if !errors.Is(err, store.ErrVersionConflict) {
+			return errors.Wrap(err, "failed to write circuit breaker state")
+		}
+	}
+}
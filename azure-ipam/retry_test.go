@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var (
+	errUnsupported     = &cnscli.CNSClientError{Code: types.UnsupportedAPI, Err: errors.New("Unsupported API")}
+	errSubnetExhausted = &cnscli.CNSClientError{Code: types.SubnetExhausted, Err: errors.New("subnet exhausted"), RetryAfter: time.Second}
+)
+
+// scriptedCNSClient returns the errors in order from RequestIPs and ReleaseIPs, then succeeds.
+type scriptedCNSClient struct {
+	MockCNSClient
+	errs  []error
+	calls int
+}
+
+func (c *scriptedCNSClient) next() error {
+	c.calls++
+	if len(c.errs) == 0 {
+		return nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+func (c *scriptedCNSClient) RequestIPs(context.Context, cns.IPConfigsRequest) (*cns.IPConfigsResponse, error) {
+	if err := c.next(); err != nil {
+		return nil, err
+	}
+	return &cns.IPConfigsResponse{}, nil
+}
+
+func (c *scriptedCNSClient) ReleaseIPs(context.Context, cns.IPConfigsRequest) error {
+	return c.next()
+}
+
+func TestBreakerStatePersistedAcrossInvocations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cns-breakers.json")
+	inner := &scriptedCNSClient{errs: []error{errUnsupported, errUnsupported, errUnsupported}}
+
+	// each invocation of the plugin makes one request, which never opens an in-memory breaker
+	for i := 0; i < cnscli.DefaultRetryConfig.BreakerThreshold; i++ {
+		breakers, err := newBreakerStore(path, zap.NewNop())
+		require.NoError(t, err)
+		c := newRetryingCNSClient(inner, breakers, zap.NewNop())
+		_, err = c.RequestIPs(context.Background(), cns.IPConfigsRequest{})
+		require.True(t, cnscli.IsUnsupportedAPI(err))
+	}
+
+	breakers, err := newBreakerStore(path, zap.NewNop())
+	require.NoError(t, err)
+	c := newRetryingCNSClient(inner, breakers, zap.NewNop())
+	_, err = c.RequestIPs(context.Background(), cns.IPConfigsRequest{})
+	var openErr *cnscli.CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	require.True(t, cnscli.IsUnsupportedAPI(err), "the caller must still fall back to the legacy API")
+	require.Equal(t, cnscli.DefaultRetryConfig.BreakerThreshold, inner.calls)
+}
+
+func TestBreakerStoreConcurrentUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cns-breakers.json")
+	const writers, updates = 4, 10
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		breakers, err := newBreakerStore(path, zap.NewNop())
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < updates; j++ {
+				err := breakers.Update(cns.ReleaseIPConfigs, func(state *cnscli.BreakerState) bool {
+					state.Failures++
+					return true
+				})
+				require.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	breakers, err := newBreakerStore(path, zap.NewNop())
+	require.NoError(t, err)
+	state, err := breakers.Load(cns.ReleaseIPConfigs)
+	require.NoError(t, err)
+	require.Equal(t, writers*updates, state.Failures)
+
+	state, err = breakers.Load(cns.RequestIPConfigs)
+	require.NoError(t, err)
+	require.Equal(t, cnscli.BreakerState{}, state)
+}
+
+func TestCNSRetryConfigFromNetConf(t *testing.T) {
+	inner := &scriptedCNSClient{errs: []error{&cnscli.FailedHTTPRequest{Code: 503}}}
+	c := newRetryingCNSClient(inner, nil, zap.NewNop())
+	plugin, err := NewPlugin(zap.NewNop(), c, nil)
+	require.NoError(t, err)
+
+	// a single failed attempt opens the breaker once the netconf lowers the threshold
+	stdin := []byte(`{"cniVersion":"1.0.0","name":"azure","cnsRetry":{"maxAttempts":1,"breakerThreshold":1,"breakerCooldown":"1h"}}`)
+	require.Error(t, plugin.CmdDel(buildArgs("retryconfig", happyPodArgs, stdin)))
+	require.Equal(t, 1, inner.calls)
+	require.ErrorAs(t, c.ReleaseIPs(context.Background(), cns.IPConfigsRequest{}), new(*cnscli.CircuitOpenError))
+	require.Equal(t, 1, inner.calls)
+
+	nwCfg, err := parseNetConf(stdin)
+	require.NoError(t, err)
+	require.Equal(t, &cnscli.RetryConfig{MaxAttempts: 1, BreakerThreshold: 1, BreakerCooldown: time.Hour}, nwCfg.CNSRetry)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+)
+
+// statusCommand is the argument which makes the binary diagnose its connection to CNS and exit, when it isn't
+// invoked by a container runtime.
+const statusCommand = "status"
+
+const (
+	// cnsDefaultBaseURL is the URL the CNS client falls back to when cnsBaseURL is unset.
+	cnsDefaultBaseURL = "http://localhost:10090"
+	// cnsHealthURL is the readiness probe of CNS, served on its default metrics address.
+	cnsHealthURL      = "http://localhost:9090/readyz"
+	statusDialTimeout = 5 * time.Second
+)
+
+// statusIPStates are the IP states the pool usage is broken down by, in the order they are printed.
+var statusIPStates = []types.IPState{types.Available, types.Assigned, types.PendingProgramming, types.PendingRelease}
+
+var errStatusChecksFailed = errors.New("status checks failed")
+
+// statusCNSClient is the part of the CNS client the status command uses.
+type statusCNSClient interface {
+	GetIPAddressesMatchingStates(context.Context, ...types.IPState) ([]cns.IPConfigurationStatus, error)
+}
+
+// statusChecker diagnoses the connection of the plugin to CNS: it validates that the CNS address is reachable,
+// queries the health of CNS, and dumps the usage of the IP pool.
+type statusChecker struct {
+	cnsURL     string
+	healthURL  string
+	cnsClient  statusCNSClient
+	httpClient *http.Client
+	out        io.Writer
+}
+
+func newStatusChecker(c statusCNSClient, out io.Writer) *statusChecker {
+	cnsURL := cnsBaseURL
+	if cnsURL == "" {
+		cnsURL = cnsDefaultBaseURL
+	}
+	return &statusChecker{
+		cnsURL:     cnsURL,
+		healthURL:  cnsHealthURL,
+		cnsClient:  c,
+		httpClient: &http.Client{Timeout: cnsReqTimeout},
+		out:        out,
+	}
+}
+
+// run runs all the checks, printing their results, and returns an error if any of them failed.
+func (s *statusChecker) run(ctx context.Context) error {
+	checks := []func(context.Context) error{s.checkConnectivity, s.checkHealth, s.checkPoolUsage}
+	failed := 0
+	for _, check := range checks {
+		if err := check(ctx); err != nil {
+			fmt.Fprintf(s.out, "  FAIL: %v\n", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return errors.Wrapf(errStatusChecksFailed, "%d of %d checks failed", failed, len(checks))
+	}
+	fmt.Fprintln(s.out, "OK")
+	return nil
+}
+
+// checkConnectivity validates that a connection can be opened to the CNS address.
+func (s *statusChecker) checkConnectivity(ctx context.Context) error {
+	u, err := url.Parse(s.cnsURL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse CNS URL %s", s.cnsURL)
+	}
+	fmt.Fprintf(s.out, "CNS address %s:\n", u.Host)
+
+	dialer := net.Dialer{Timeout: statusDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return errors.Wrap(err, "CNS is not reachable")
+	}
+	conn.Close()
+
+	fmt.Fprintln(s.out, "  reachable")
+	return nil
+}
+
+// checkHealth queries the readiness probe of CNS.
+func (s *statusChecker) checkHealth(ctx context.Context) error {
+	fmt.Fprintf(s.out, "CNS health %s:\n", s.healthURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.healthURL, http.NoBody)
+	if err != nil {
+		return errors.Wrap(err, "failed to build request")
+	}
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to query CNS health")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("CNS is not ready: http response %d", res.StatusCode)
+	}
+
+	fmt.Fprintln(s.out, "  ready")
+	return nil
+}
+
+// checkPoolUsage dumps the IPs of the pool by state and by network container. It fails if no IPs are available,
+// since the plugin can't service ADD commands then.
+func (s *statusChecker) checkPoolUsage(ctx context.Context) error {
+	fmt.Fprintln(s.out, "IP pool:")
+
+	total := 0
+	byState := map[types.IPState]int{}
+	byNC := map[string]map[types.IPState]int{}
+	for _, state := range statusIPStates {
+		statuses, err := s.cnsClient.GetIPAddressesMatchingStates(ctx, state)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get %s IP addresses from CNS", state)
+		}
+		for i := range statuses {
+			if byNC[statuses[i].NCID] == nil {
+				byNC[statuses[i].NCID] = map[types.IPState]int{}
+			}
+			byNC[statuses[i].NCID][state]++
+		}
+		byState[state] = len(statuses)
+		total += len(statuses)
+	}
+
+	fmt.Fprintf(s.out, "  %d IPs\n", total)
+	for _, state := range statusIPStates {
+		fmt.Fprintf(s.out, "  %s: %d\n", state, byState[state])
+	}
+
+	ncIDs := make([]string, 0, len(byNC))
+	for ncID := range byNC {
+		ncIDs = append(ncIDs, ncID)
+	}
+	sort.Strings(ncIDs)
+	for _, ncID := range ncIDs {
+		fmt.Fprintf(s.out, "  NC %s: %d available, %d assigned\n", ncID, byNC[ncID][types.Available], byNC[ncID][types.Assigned])
+	}
+
+	if byState[types.Available] == 0 {
+		return errors.New("no IP addresses available")
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/require"
+)
+
+type statusMockCNSClient map[types.IPState][]cns.IPConfigurationStatus
+
+func (c statusMockCNSClient) GetIPAddressesMatchingStates(_ context.Context, states ...types.IPState) ([]cns.IPConfigurationStatus, error) {
+	var statuses []cns.IPConfigurationStatus
+	for _, state := range states {
+		statuses = append(statuses, c[state]...)
+	}
+	return statuses, nil
+}
+
+func TestStatus(t *testing.T) {
+	cnsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer cnsServer.Close()
+
+	pool := statusMockCNSClient{
+		types.Available: {{IPAddress: "10.0.0.4", NCID: "nc1"}, {IPAddress: "10.0.0.5", NCID: "nc1"}},
+		types.Assigned:  {{IPAddress: "10.0.0.6", NCID: "nc1"}, {IPAddress: "10.1.0.4", NCID: "nc2"}},
+	}
+
+	tests := []struct {
+		name      string
+		healthURL string
+		client    statusCNSClient
+		wantOut   []string
+		wantErr   bool
+	}{
+		{
+			name:      "healthy",
+			healthURL: cnsServer.URL + "/readyz",
+			client:    pool,
+			wantOut:   []string{"reachable", "ready", "4 IPs", "Available: 2", "Assigned: 2", "NC nc1: 2 available, 1 assigned", "NC nc2: 0 available, 1 assigned", "OK"},
+		},
+		{
+			name:      "not ready",
+			healthURL: cnsServer.URL + "/notready",
+			client:    pool,
+			wantOut:   []string{"reachable", "FAIL: CNS is not ready: http response 503", "4 IPs"},
+			wantErr:   true,
+		},
+		{
+			name:      "no available IPs",
+			healthURL: cnsServer.URL + "/readyz",
+			client:    statusMockCNSClient{types.Assigned: pool[types.Assigned]},
+			wantOut:   []string{"ready", "Available: 0", "FAIL: no IP addresses available"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			s := newStatusChecker(tt.client, out)
+			s.cnsURL = cnsServer.URL
+			s.healthURL = tt.healthURL
+
+			err := s.run(context.Background())
+			if tt.wantErr {
+				require.ErrorIs(t, err, errStatusChecksFailed)
+			} else {
+				require.NoError(t, err)
+			}
+			for _, want := range tt.wantOut {
+				require.Contains(t, out.String(), want)
+			}
+		})
+	}
+}
+
+func TestStatusCNSUnreachable(t *testing.T) {
+	cnsServer := httptest.NewServer(http.NotFoundHandler())
+	cnsServer.Close()
+
+	out := &bytes.Buffer{}
+	s := newStatusChecker(statusMockCNSClient{}, out)
+	s.cnsURL = cnsServer.URL
+	s.healthURL = cnsServer.URL + "/readyz"
+
+	err := s.run(context.Background())
+	require.ErrorIs(t, err, errStatusChecksFailed)
+	require.Contains(t, out.String(), "FAIL: CNS is not reachable")
+}
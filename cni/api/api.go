@@ -38,3 +38,37 @@ func (a *AzureCNIState) PrintResult() error {
 
 	return nil
 }
+
+// IPAMAllocation is an address reserved by the legacy azure-vnet-ipam plugin, with the pod it is reserved for when
+// the pod's endpoint is found in the Azure CNI state.
+type IPAMAllocation struct {
+	AddressSpace  string
+	PoolID        string
+	IPAddress     string
+	PrefixLength  int
+	Gateway       string
+	ContainerID   string
+	PodName       string
+	PodNamespace  string
+	PodEndpointId string //nolint:revive,stylecheck // same as PodNetworkInterfaceInfo
+}
+
+type AzureIPAMState struct {
+	Allocations []IPAMAllocation
+}
+
+func (a *AzureIPAMState) PrintResult() error {
+	b, err := json.MarshalIndent(a, "", "    ")
+	if err != nil {
+		logger.Error("Failed to marshal Azure IPAM state", zap.Error(err))
+	}
+
+	// write result to stdout to be captured by caller
+	_, err = os.Stdout.Write(b)
+	if err != nil {
+		logger.Error("Failed to write response to stdout", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
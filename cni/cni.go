@@ -23,6 +23,9 @@ const (
 
 	// nonstandard CNI spec command, used to dump CNI state to stdout
 	CmdGetEndpointsState = "GET_ENDPOINT_STATE"
+	// nonstandard CNI spec commands of the IPAM plugin, used to dump its allocations to stdout and to migrate them to CNS
+	CmdGetIPAMState     = "GET_IPAM_STATE"
+	CmdMigrateIPAMState = "MIGRATE_IPAM_STATE"
 
 	// CNI errors.
 	ErrRuntime = 100
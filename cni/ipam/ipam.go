@@ -71,8 +71,9 @@ func (plugin *ipamPlugin) Start(config *common.PluginConfig) error {
 	// Log platform information.
 	logger.Info("Plugin version.", zap.String("name", plugin.Name),
 		zap.String("version", plugin.Version))
+	osInfo, _ := platform.GetOSInfo()
 	logger.Info("Running on",
-		zap.String("platform", platform.GetOSInfo()))
+		zap.String("platform", osInfo.String()))
 
 	// Initialize address manager. rehyrdration not required on reboot for cni ipam plugin
 	err = plugin.am.Initialize(config, false, plugin.Options)
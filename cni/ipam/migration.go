@@ -0,0 +1,102 @@
+package ipam
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cni/api"
+	cniclient "github.com/Azure/azure-container-networking/cni/client"
+	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/ipam"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"k8s.io/utils/exec"
+)
+
+const defaultCNSRequestTimeout = 15 * time.Second
+
+// GetIPAMState returns the addresses reserved by the plugin. The pod of each address is looked up in the state of the
+// azure-vnet plugin, by the container ID the address was reserved with, or by the address itself for the reservations
+// made without one. The addresses of the pods which are not found have no pod info.
+func (plugin *ipamPlugin) GetIPAMState() *api.AzureIPAMState {
+	allocations := plugin.am.GetAddressAllocations()
+
+	cniState, err := cniclient.New(exec.New()).GetEndpointState()
+	if err != nil {
+		logger.Error("Failed to get Azure CNI state, the allocations have no pod info", zap.Error(err))
+		cniState = &api.AzureCNIState{}
+	}
+
+	return &api.AzureIPAMState{Allocations: joinEndpointState(allocations, cniState)}
+}
+
+func joinEndpointState(allocations []ipam.AddressAllocation, cniState *api.AzureCNIState) []api.IPAMAllocation {
+	byContainerID := map[string]api.PodNetworkInterfaceInfo{}
+	byIP := map[string]api.PodNetworkInterfaceInfo{}
+	for _, info := range cniState.ContainerInterfaces { //nolint:gocritic // ignore copy
+		if info.ContainerID != "" {
+			byContainerID[info.ContainerID] = info
+		}
+		for _, ipNet := range info.IPAddresses {
+			byIP[ipNet.IP.String()] = info
+		}
+	}
+
+	state := make([]api.IPAMAllocation, 0, len(allocations))
+	for _, a := range allocations {
+		allocation := api.IPAMAllocation{
+			AddressSpace: a.AddressSpace,
+			PoolID:       a.PoolID,
+			IPAddress:    a.Address,
+			PrefixLength: a.PrefixLength,
+			Gateway:      a.Gateway,
+			ContainerID:  a.ID,
+		}
+		info, ok := byContainerID[a.ID]
+		if !ok {
+			info, ok = byIP[net.ParseIP(a.Address).String()]
+		}
+		if ok {
+			allocation.PodName = info.PodName
+			allocation.PodNamespace = info.PodNamespace
+			allocation.PodEndpointId = info.PodEndpointId
+			if allocation.ContainerID == "" {
+				allocation.ContainerID = info.ContainerID
+			}
+		}
+		state = append(state, allocation)
+	}
+	return state
+}
+
+// MigrateToCNS assigns the addresses reserved by the plugin to their pods in CNS, so that the node can switch to CNS
+// IPAM without draining it. With dryRun, CNS only reports which addresses can be migrated.
+func (plugin *ipamPlugin) MigrateToCNS(ctx context.Context, cnsURL string, dryRun bool) (*cns.MigrateLegacyIPAMResponse, error) {
+	state := plugin.GetIPAMState()
+	req := cns.MigrateLegacyIPAMRequest{
+		Allocations: make([]cns.LegacyIPAMAllocation, 0, len(state.Allocations)),
+		DryRun:      dryRun,
+	}
+	for i := range state.Allocations {
+		a := &state.Allocations[i]
+		req.Allocations = append(req.Allocations, cns.LegacyIPAMAllocation{
+			IPAddress:        a.IPAddress,
+			InfraContainerID: a.ContainerID,
+			PodInterfaceID:   a.PodEndpointId,
+			PodName:          a.PodName,
+			PodNamespace:     a.PodNamespace,
+		})
+	}
+
+	cnsClient, err := cnscli.New(cnsURL, defaultCNSRequestTimeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cns client")
+	}
+	resp, err := cnsClient.MigrateLegacyIPAM(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to migrate allocations to cns")
+	}
+	return resp, nil
+}
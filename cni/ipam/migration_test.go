@@ -0,0 +1,51 @@
+package ipam
+
+import (
+	"net"
+
+	"github.com/Azure/azure-container-networking/cni/api"
+	"github.com/Azure/azure-container-networking/ipam"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test IPAM state", func() {
+	Describe("Test joinEndpointState", func() {
+		Context("When the pods of the allocations are in the Azure CNI state", func() {
+			It("Should add the pod info of the allocations", func() {
+				allocations := []ipam.AddressAllocation{
+					{AddressSpace: "local", PoolID: "10.0.0.0/16", Address: "10.0.0.5", PrefixLength: 16, ID: "container1"},
+					{AddressSpace: "local", PoolID: "10.0.0.0/16", Address: "10.0.0.6", PrefixLength: 16},
+					{AddressSpace: "local", PoolID: "10.0.0.0/16", Address: "10.0.0.7", PrefixLength: 16, ID: "container3"},
+				}
+				cniState := &api.AzureCNIState{
+					ContainerInterfaces: map[string]api.PodNetworkInterfaceInfo{
+						"container1-eth0": {
+							PodName: "pod1", PodNamespace: "ns1", PodEndpointId: "container1-eth0", ContainerID: "container1",
+							IPAddresses: []net.IPNet{{IP: net.ParseIP("10.0.0.5"), Mask: net.CIDRMask(16, 32)}},
+						},
+						"container2-eth0": {
+							PodName: "pod2", PodNamespace: "ns2", PodEndpointId: "container2-eth0", ContainerID: "container2",
+							IPAddresses: []net.IPNet{{IP: net.ParseIP("10.0.0.6"), Mask: net.CIDRMask(16, 32)}},
+						},
+					},
+				}
+
+				Expect(joinEndpointState(allocations, cniState)).To(Equal([]api.IPAMAllocation{
+					{
+						AddressSpace: "local", PoolID: "10.0.0.0/16", IPAddress: "10.0.0.5", PrefixLength: 16,
+						ContainerID: "container1", PodName: "pod1", PodNamespace: "ns1", PodEndpointId: "container1-eth0",
+					},
+					{
+						AddressSpace: "local", PoolID: "10.0.0.0/16", IPAddress: "10.0.0.6", PrefixLength: 16,
+						ContainerID: "container2", PodName: "pod2", PodNamespace: "ns2", PodEndpointId: "container2-eth0",
+					},
+					{
+						AddressSpace: "local", PoolID: "10.0.0.0/16", IPAddress: "10.0.0.7", PrefixLength: 16,
+						ContainerID: "container3",
+					},
+				}))
+			})
+		})
+	})
+})
@@ -4,15 +4,25 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cni/ipam"
+	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/common"
 )
 
-const name = "azure-vnet-ipam"
+const (
+	name = "azure-vnet-ipam"
+	// envCNSURL overrides the CNS URL the allocations are migrated to.
+	envCNSURL = "AZURE_IPAM_CNS_URL"
+	// envMigrateDryRun set to true only reports which allocations can be migrated to CNS.
+	envMigrateDryRun = "AZURE_IPAM_MIGRATE_DRY_RUN"
+)
 
 // Version is populated by make during build.
 var version string
@@ -49,7 +59,23 @@ func main() {
 		panic("ipam plugin fatal error")
 	}
 
-	err = ipamPlugin.Execute(cni.PluginApi(ipamPlugin))
+	switch os.Getenv(cni.Cmd) {
+	case cni.CmdGetIPAMState:
+		err = ipamPlugin.GetIPAMState().PrintResult()
+	case cni.CmdMigrateIPAMState:
+		dryRun, _ := strconv.ParseBool(os.Getenv(envMigrateDryRun))
+		var resp *cns.MigrateLegacyIPAMResponse
+		if resp, err = ipamPlugin.MigrateToCNS(context.Background(), os.Getenv(envCNSURL), dryRun); err != nil {
+			fmt.Printf("Failed to migrate IPAM state to CNS, err:%v.\n", err)
+			break
+		}
+		var b []byte
+		if b, err = json.MarshalIndent(resp, "", "    "); err == nil {
+			_, err = os.Stdout.Write(b)
+		}
+	default:
+		err = ipamPlugin.Execute(cni.PluginApi(ipamPlugin))
+	}
 
 	ipamPlugin.Stop()
 
@@ -3,9 +3,9 @@ package log
 import (
 	"os"
 
+	"github.com/Azure/azure-container-networking/log/zaplog"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 var (
@@ -15,28 +15,43 @@ var (
 )
 
 const (
+	// LogPath is the path where log files are stored.
+	LogPath = zaplog.LogPath
+
 	maxLogFileSizeInMb = 5
 	maxLogFileCount    = 8
 )
 
 func initZapLog(logFile string) *zap.Logger {
-	logFileCNIWriter := zapcore.AddSync(&lumberjack.Logger{
-		Filename:   LogPath + logFile,
-		MaxSize:    maxLogFileSizeInMb,
-		MaxBackups: maxLogFileCount,
-	})
-
-	encoderConfig := zap.NewProductionEncoderConfig()
-	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	jsonEncoder := zapcore.NewJSONEncoder(encoderConfig)
-
-	core := zapcore.NewCore(jsonEncoder, logFileCNIWriter, zapcore.DebugLevel)
-	Logger := zap.New(core)
-	return Logger
+	cfg := &zaplog.Config{
+		Level:       zapcore.DebugLevel.String(),
+		Encoding:    zaplog.EncodingJSON,
+		Filepath:    LogPath + logFile,
+		MaxSizeInMB: maxLogFileSizeInMb,
+		MaxBackups:  maxLogFileCount,
+		Levels:      Levels,
+	}
+
+	logger, _, err := zaplog.New(cfg)
+	if err != nil {
+		// the config above is static, so this only fails if zaplog rejects its own encoding or level
+		return zap.NewNop()
+	}
+	return logger
 }
 
 var (
+	// Levels are the runtime levels of the CNI components, configured through the zaplog.EnvLogLevels
+	// and zaplog.EnvLogLevelsFile environment variables. Everything is logged at debug by default.
+	Levels = zaplog.NewLevels(zapcore.DebugLevel)
+
 	CNILogger       = initZapLog(zapCNILogFile).With(zap.Int("pid", os.Getpid()))
 	IPamLogger      = initZapLog(zapIpamLogFile).With(zap.Int("pid", os.Getpid()))
 	TelemetryLogger = initZapLog(zapTelemetryLogFile).With(zap.Int("pid", os.Getpid()))
 )
+
+func init() {
+	if err := Levels.LoadEnv(); err != nil {
+		CNILogger.Error("Failed to load log levels", zap.Error(err))
+	}
+}
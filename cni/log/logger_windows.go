@@ -1,6 +0,0 @@
-package log
-
-const (
-	// LogPath is the path where log files are stored.
-	LogPath = ""
-)
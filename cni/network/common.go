@@ -18,6 +18,9 @@ import (
 func ReportPluginError(reportManager *telemetry.ReportManager, tb *telemetry.TelemetryBuffer, err error) {
 	logger.Error("Report plugin error")
 	reflect.ValueOf(reportManager.Report).Elem().FieldByName("ErrorMessage").SetString(err.Error())
+	if cniReport, ok := reportManager.Report.(*telemetry.CNIReport); ok {
+		cniReport.SetResult(err)
+	}
 
 	if err := reportManager.SendReport(tb); err != nil {
 		logger.Error("SendReport failed", zap.Error(err))
@@ -0,0 +1,208 @@
+package network
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cni"
+	"github.com/Azure/azure-container-networking/cni/util"
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/common"
+	acnnetwork "github.com/Azure/azure-container-networking/network"
+	"github.com/Azure/azure-container-networking/nns"
+	"github.com/Azure/azure-container-networking/telemetry"
+	cniSkel "github.com/containernetworking/cni/pkg/skel"
+	"github.com/stretchr/testify/require"
+)
+
+// cniHarness runs the azure-vnet ADD, DEL and CHECK commands in-process against a fake CNS.
+// Each command runs on a new NetPlugin, like each invocation of the plugin binary, while the network manager state
+// is kept between commands in a mock network manager, so no netlink or HNS calls are made.
+type cniHarness struct {
+	t     *testing.T
+	cns   *fakes.CNSServerFake
+	nm    *acnnetwork.MockNetworkManager
+	nwCfg cni.NetworkConfig
+}
+
+// cniResult is the part of the CNI result printed by ADD which the tests check.
+type cniResult struct {
+	IPs []struct {
+		Address string `json:"address"`
+		Gateway string `json:"gateway"`
+	} `json:"ips"`
+}
+
+func newCNIHarness(t *testing.T, ips ...string) *cniHarness {
+	t.Helper()
+	cnsServer := fakes.NewCNSServerFake(fakes.DefaultCNSServerFakeConfig(), ips...)
+	t.Cleanup(cnsServer.Close)
+	h := &cniHarness{
+		t:   t,
+		cns: cnsServer,
+		nm:  acnnetwork.NewMockNetworkmanager(acnnetwork.NewMockEndpointClient(nil)),
+		nwCfg: cni.NetworkConfig{
+			Name:       "azure",
+			CNIVersion: "0.3.0",
+			Type:       "azure-vnet",
+			Mode:       "transparent",
+			Master:     eth0IfName,
+			CNSUrl:     cnsServer.URL,
+		},
+	}
+	h.nwCfg.IPAM.Type = acnnetwork.AzureCNS
+	// overlay mode does not program the SNAT iptables rules of the host.
+	h.nwCfg.IPAM.Mode = string(util.V4Overlay)
+	return h
+}
+
+func (h *cniHarness) newPlugin() *NetPlugin {
+	h.t.Helper()
+	plugin, err := NewPlugin("azure-vnet", &common.PluginConfig{}, &nns.MockGrpcClient{}, &Multitenancy{})
+	require.NoError(h.t, err)
+	plugin.report = &telemetry.CNIReport{}
+	plugin.nm = h.nm
+	return plugin
+}
+
+// args returns the args of a command for the pod. The container ID is derived from the pod name,
+// since the plugin identifies endpoints by a prefix of it.
+func (h *cniHarness) args(podName string) *cniSkel.CmdArgs {
+	containerID := fmt.Sprintf("%x", sha256.Sum256([]byte(podName)))
+	return &cniSkel.CmdArgs{
+		ContainerID: containerID,
+		Netns:       "netns-" + containerID,
+		IfName:      eth0IfName,
+		Args:        fmt.Sprintf("K8S_POD_NAME=%s;K8S_POD_NAMESPACE=%s", podName, "default"),
+		StdinData:   h.nwCfg.Serialize(),
+	}
+}
+
+// captureStdout runs the command and returns what it printed, which is where the plugin writes its result.
+func (h *cniHarness) captureStdout(cmd func() error) ([]byte, error) {
+	h.t.Helper()
+	r, w, err := os.Pipe()
+	require.NoError(h.t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan []byte)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, r)
+		out <- buf.Bytes()
+	}()
+	cmdErr := cmd()
+	os.Stdout = stdout
+	w.Close()
+	return <-out, cmdErr
+}
+
+func (h *cniHarness) add(podName string) (*cniResult, error) {
+	h.t.Helper()
+	plugin := h.newPlugin()
+	stdout, err := h.captureStdout(func() error { return plugin.Add(h.args(podName)) })
+	if err != nil {
+		return nil, err
+	}
+	result := &cniResult{}
+	require.NoError(h.t, json.Unmarshal(stdout, result), "failed to parse ADD result %q", stdout)
+	return result, nil
+}
+
+func (h *cniHarness) del(podName string) error {
+	h.t.Helper()
+	return h.newPlugin().Delete(h.args(podName))
+}
+
+func (h *cniHarness) check(podName string) error {
+	h.t.Helper()
+	plugin := h.newPlugin()
+	_, err := h.captureStdout(func() error { return plugin.Get(h.args(podName)) })
+	return err
+}
+
+func TestHarnessAddCheckDelete(t *testing.T) {
+	h := newCNIHarness(t, "10.240.0.10", "10.240.0.11")
+
+	result, err := h.add("pod-a")
+	require.NoError(t, err)
+	require.Len(t, result.IPs, 1)
+	require.Equal(t, "10.240.0.10/16", result.IPs[0].Address)
+	require.Equal(t, "10.240.0.1", result.IPs[0].Gateway)
+	require.Equal(t, []string{"10.240.0.10"}, valuesOf(h.cns.AssignedIPs()))
+
+	endpoints, err := h.nm.GetAllEndpoints(h.nwCfg.Name)
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+
+	require.NoError(t, h.check("pod-a"))
+
+	require.NoError(t, h.del("pod-a"))
+	require.Empty(t, h.cns.AssignedIPs())
+	require.ElementsMatch(t, []string{"10.240.0.10", "10.240.0.11"}, h.cns.AvailableIPs())
+	endpoints, err = h.nm.GetAllEndpoints(h.nwCfg.Name)
+	require.NoError(t, err)
+	require.Empty(t, endpoints)
+
+	// a repeated DEL succeeds, as required by the CNI spec.
+	require.NoError(t, h.del("pod-a"))
+}
+
+func TestHarnessPoolExhausted(t *testing.T) {
+	h := newCNIHarness(t, "10.240.0.10")
+
+	_, err := h.add("pod-a")
+	require.NoError(t, err)
+	_, err = h.add("pod-b")
+	require.Error(t, err)
+	require.Len(t, h.cns.AssignedIPs(), 1)
+
+	// the IP is allocated once it is freed up.
+	require.NoError(t, h.del("pod-a"))
+	result, err := h.add("pod-b")
+	require.NoError(t, err)
+	require.Equal(t, "10.240.0.10/16", result.IPs[0].Address)
+}
+
+func TestHarnessTransientCNSFailure(t *testing.T) {
+	h := newCNIHarness(t, "10.240.0.10")
+	h.cns.FailNext(cns.RequestIPConfigs, types.UnexpectedError)
+
+	_, err := h.add("pod-a")
+	require.Error(t, err)
+	require.Empty(t, h.cns.AssignedIPs())
+
+	_, err = h.add("pod-a")
+	require.NoError(t, err)
+	require.Len(t, h.cns.AssignedIPs(), 1)
+}
+
+func TestHarnessLegacyCNSAPI(t *testing.T) {
+	h := newCNIHarness(t, "10.240.0.10")
+	h.cns.SetUnsupported(cns.RequestIPConfigs, true)
+	h.cns.SetUnsupported(cns.ReleaseIPConfigs, true)
+
+	result, err := h.add("pod-a")
+	require.NoError(t, err)
+	require.Equal(t, "10.240.0.10/16", result.IPs[0].Address)
+	require.Equal(t, 1, h.cns.RequestCount(cns.RequestIPConfig))
+
+	require.NoError(t, h.del("pod-a"))
+	require.Equal(t, 1, h.cns.RequestCount(cns.ReleaseIPConfig))
+	require.Empty(t, h.cns.AssignedIPs())
+}
+
+func valuesOf(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
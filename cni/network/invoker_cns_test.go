@@ -10,12 +10,16 @@ import (
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cni/util"
 	"github.com/Azure/azure-container-networking/cns"
+	cnsfake "github.com/Azure/azure-container-networking/cns/client/fake"
 	"github.com/Azure/azure-container-networking/iptables"
 	"github.com/Azure/azure-container-networking/network"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
 	"github.com/stretchr/testify/require"
 )
 
+// the fake CNS client must stay usable in place of the real one.
+var _ cnsclient = (*cnsfake.Client)(nil)
+
 var testPodInfo cns.KubernetesPodInfo
 
 func getTestIPConfigRequest() cns.IPConfigRequest {
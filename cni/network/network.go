@@ -287,6 +287,25 @@ func SetCustomDimensions(cniMetric *telemetry.AIMetric, nwCfg *cni.NetworkConfig
 	}
 }
 
+// interfaceSummary returns the number of interfaces in the IPAM result and the IPs assigned to them.
+func interfaceSummary(result IPAMAddResult) (int, []net.IP) {
+	var (
+		count int
+		ips   []net.IP
+	)
+	infos := append([]network.InterfaceInfo{result.defaultInterfaceInfo}, result.secondaryInterfacesInfo...)
+	for i := range infos {
+		if len(infos[i].IPConfigs) == 0 {
+			continue
+		}
+		count++
+		for _, ipconfig := range infos[i].IPConfigs {
+			ips = append(ips, ipconfig.Address.IP)
+		}
+	}
+	return count, ips
+}
+
 func (plugin *NetPlugin) setCNIReportDetails(nwCfg *cni.NetworkConfig, opType, msg string) {
 	plugin.report.OperationType = opType
 	plugin.report.SubContext = fmt.Sprintf("%+v", nwCfg)
@@ -575,6 +594,8 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 			return err
 		}
 
+		plugin.report.SetResult(nil)
+		plugin.report.SetInterfaceSummary(interfaceSummary(ipamAddResult))
 		sendEvent(plugin, fmt.Sprintf("CNI ADD succeeded: IP:%+v, VlanID: %v, podname %v, namespace %v numendpoints:%d",
 			ipamAddResult.defaultInterfaceInfo.IPConfigs, epInfo.Data[network.VlanIDKey], k8sPodName, k8sNamespace, plugin.nm.GetNumberOfEndpoints("", nwCfg.Name)))
 	}
@@ -991,7 +1012,7 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 	if plugin.ipamInvoker == nil {
 		switch nwCfg.IPAM.Type {
 		case network.AzureCNS:
-			cnsClient, cnsErr := cnscli.New("", defaultRequestTimeout)
+			cnsClient, cnsErr := cnscli.New(nwCfg.CNSUrl, defaultRequestTimeout)
 			if cnsErr != nil {
 				logger.Error("failed to create cns client", zap.Error(cnsErr))
 				return errors.Wrap(cnsErr, "failed to create cns client")
@@ -1103,6 +1124,7 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 			}
 		}
 	}
+	plugin.report.SetResult(nil)
 	sendEvent(plugin, fmt.Sprintf("CNI DEL succeeded : Released ip %+v podname %v namespace %v", nwCfg.IPAM.Address, k8sPodName, k8sNamespace))
 
 	return err
@@ -1,10 +1,14 @@
 package network
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"runtime"
+	"sync/atomic"
 	"testing"
 
 	"github.com/Azure/azure-container-networking/cni"
@@ -170,6 +174,46 @@ func TestPluginDelete(t *testing.T) {
 	}
 }
 
+// DEL of a new plugin, which has no IPAM invoker yet, releases the IPs through the CNS URL of the network config.
+func TestPluginDeleteUsesConfiguredCNSUrl(t *testing.T) {
+	var releaseRequests atomic.Int32
+	cnsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == cns.ReleaseIPConfigs || r.URL.Path == cns.ReleaseIPConfig {
+			releaseRequests.Add(1)
+		}
+		_ = json.NewEncoder(w).Encode(cns.IPConfigsResponse{})
+	}))
+	defer cnsServer.Close()
+
+	plugin, err := NewPlugin("test", &common.PluginConfig{}, &nns.MockGrpcClient{}, &Multitenancy{})
+	require.NoError(t, err)
+	plugin.report = &telemetry.CNIReport{}
+	nm := acnnetwork.NewMockNetworkmanager(acnnetwork.NewMockEndpointClient(nil))
+	plugin.nm = nm
+
+	cfg := cni.NetworkConfig{
+		Name:       "test-cns-url",
+		CNIVersion: "0.3.0",
+		Type:       "azure-vnet",
+		Master:     eth0IfName,
+		CNSUrl:     cnsServer.URL,
+	}
+	cfg.IPAM.Type = acnnetwork.AzureCNS
+	cfg.IPAM.Mode = string(util.V4Overlay)
+	require.NoError(t, nm.CreateNetwork(&acnnetwork.NetworkInfo{Id: cfg.Name}))
+
+	// the endpoint doesn't exist, so DEL releases the IPs of the container
+	err = plugin.Delete(&cniSkel.CmdArgs{
+		StdinData:   cfg.Serialize(),
+		ContainerID: "test-container",
+		Netns:       "test-container",
+		Args:        fmt.Sprintf("K8S_POD_NAME=%v;K8S_POD_NAMESPACE=%v", "test-pod", "test-pod-ns"),
+		IfName:      eth0IfName,
+	})
+	require.NoError(t, err)
+	require.Positive(t, releaseRequests.Load())
+}
+
 // Test multiple cni add calls
 func TestPluginSecondAddDifferentPod(t *testing.T) {
 	plugin := GetTestResources()
@@ -47,6 +47,33 @@ var args = common.ArgumentList{
 	},
 }
 
+// sendLockMetrics reports how long the invocation waited for the store lock, and the stale locks it took over.
+func sendLockMetrics(plugin *cni.Plugin, tb *telemetry.TelemetryBuffer) {
+	stats, ok := plugin.LockStats()
+	if !ok {
+		return
+	}
+
+	metrics := []aitelemetry.Metric{{
+		Name:             telemetry.CNILockWaitTimeStr,
+		Value:            float64(stats.TotalWait.Milliseconds()),
+		CustomDimensions: make(map[string]string),
+	}}
+	if stats.Takeovers > 0 {
+		metrics = append(metrics, aitelemetry.Metric{
+			Name:             telemetry.CNILockTakeoverStr,
+			Value:            float64(stats.Takeovers),
+			CustomDimensions: make(map[string]string),
+		})
+	}
+
+	for _, metric := range metrics {
+		if err := telemetry.SendCNIMetric(&telemetry.AIMetric{Metric: metric}, tb); err != nil {
+			logger.Error("Couldn't send lock metric", zap.String("name", metric.Name), zap.Error(err))
+		}
+	}
+}
+
 // Prints version information.
 func printVersion() {
 	fmt.Printf("Azure CNI Version %v\n", version)
@@ -70,6 +97,7 @@ func rootExecute() error {
 			BridgeDetails:    telemetry.BridgeInfo{},
 			Version:          version,
 			Logger:           logger,
+			SchemaVersion:    telemetry.CNIReportSchemaVersion,
 		},
 	}
 
@@ -105,10 +133,10 @@ func rootExecute() error {
 		if err = netPlugin.Plugin.InitializeKeyValueStore(&config); err != nil {
 			network.PrintCNIError(fmt.Sprintf("Failed to initialize key-value store of network plugin: %v", err))
 
-			tb = telemetry.NewTelemetryBuffer(logger)
+			// reports are spooled while the telemetry service is unreachable, and replayed by a later invocation
+			tb = newTelemetryBuffer()
 			if tberr := tb.Connect(); tberr != nil {
 				logger.Error("Cannot connect to telemetry service", zap.Error(tberr))
-				return errors.Wrap(err, "lock acquire error")
 			}
 
 			network.ReportPluginError(reportManager, tb, err)
@@ -142,10 +170,12 @@ func rootExecute() error {
 
 		// Start telemetry process if not already started. This should be done inside lock, otherwise multiple process
 		// end up creating/killing telemetry process results in undesired state.
-		tb = telemetry.NewTelemetryBuffer(logger)
+		tb = newTelemetryBuffer()
 		tb.ConnectToTelemetryService(telemetryNumRetries, telemetryWaitTimeInMilliseconds)
 		defer tb.Close()
 
+		sendLockMetrics(netPlugin.Plugin, tb)
+
 		netPlugin.SetCNIReport(cniReport, tb)
 
 		t := time.Now()
@@ -196,6 +226,18 @@ func rootExecute() error {
 	return errors.Wrap(err, "Execute netplugin failure")
 }
 
+// newTelemetryBuffer returns a telemetry buffer which spools the reports sent while it is disconnected.
+func newTelemetryBuffer() *telemetry.TelemetryBuffer {
+	tb := telemetry.NewTelemetryBuffer(logger)
+	spool, err := telemetry.NewSpool(telemetry.CNITelemetrySpoolDir, telemetry.DefaultSpoolSizeInBytes)
+	if err != nil {
+		logger.Error("Failed to create telemetry spool, reports are dropped while disconnected", zap.Error(err))
+		return tb
+	}
+	tb.SetSpool(spool)
+	return tb
+}
+
 // Main is the entry point for CNI network plugin.
 func main() {
 	// Initialize and parse command line arguments.
@@ -74,6 +74,7 @@ func rootExecute() error {
 			InterfaceDetails: telemetry.InterfaceInfo{},
 			BridgeDetails:    telemetry.BridgeInfo{},
 			Version:          version,
+			SchemaVersion:    telemetry.CNIReportSchemaVersion,
 		},
 	}
 
@@ -35,6 +35,8 @@ var errEmptyContent = errors.New("read content is zero bytes")
 type Plugin struct {
 	*common.Plugin
 	version string
+	// lockManager is the store lock created by InitializeKeyValueStore.
+	lockManager *processlock.LockManager
 }
 
 // NewPlugin creates a new CNI plugin.
@@ -185,11 +187,13 @@ func (plugin *Plugin) RetriableError(err error) *cniTypes.Error {
 func (plugin *Plugin) InitializeKeyValueStore(config *common.PluginConfig) error {
 	// Create the key value store.
 	if plugin.Store == nil {
-		lockclient, err := processlock.NewFileLock(platform.CNILockPath + plugin.Name + store.LockExtension)
+		// the lock manager recovers the lock if a previous invocation crashed while holding it
+		lockclient, err := processlock.NewLockManager(platform.CNILockPath+plugin.Name+store.LockExtension, processlock.DefaultStaleLockAge, storeLogger)
 		if err != nil {
 			logger.Error("Error initializing file lock", zap.Error(err))
 			return errors.Wrap(err, "error creating new filelock")
 		}
+		plugin.lockManager = lockclient
 
 		plugin.Store, err = store.NewJsonFileStore(platform.CNIRuntimePath+plugin.Name+".json", lockclient, storeLogger)
 		if err != nil {
@@ -214,6 +218,14 @@ func (plugin *Plugin) InitializeKeyValueStore(config *common.PluginConfig) error
 	return nil
 }
 
+// LockStats returns the counters of the store lock, if it was created by InitializeKeyValueStore.
+func (plugin *Plugin) LockStats() (processlock.LockStats, bool) {
+	if plugin.lockManager == nil {
+		return processlock.LockStats{}, false
+	}
+	return plugin.lockManager.Stats(), true
+}
+
 // Uninitialize key-value store
 func (plugin *Plugin) UninitializeKeyValueStore() error {
 	if plugin.Store != nil {
@@ -12,6 +12,7 @@ import (
 	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/cni/log"
 	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/platform"
 	"github.com/Azure/azure-container-networking/telemetry"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -24,6 +25,8 @@ const (
 	defaultBatchIntervalInSecs        = 15
 	defaultGetEnvRetryCount           = 2
 	defaultGetEnvRetryWaitTimeInSecs  = 3
+	defaultHeartbeatIntervalInMins    = 30
+	defaultDedupWindowInSecs          = 60
 	pluginName                        = "AzureCNI"
 	azureVnetTelemetry                = "azure-vnet-telemetry"
 	configExtension                   = ".config"
@@ -66,6 +69,13 @@ var args = acn.ArgumentList{
 		Type:         "string",
 		DefaultValue: telemetry.CniInstallDir,
 	},
+	{
+		Name:         acn.OptService,
+		Shorthand:    acn.OptServiceAlias,
+		Description:  "Run a service lifecycle command (install, uninstall, start, stop or status) and exit",
+		Type:         "string",
+		DefaultValue: "",
+	},
 }
 
 // Prints description and version information.
@@ -98,6 +108,14 @@ func setTelemetryDefaults(config *telemetry.TelemetryConfig) {
 	if config.GetEnvRetryWaitTimeInSecs == 0 {
 		config.GetEnvRetryWaitTimeInSecs = defaultGetEnvRetryWaitTimeInSecs
 	}
+
+	if config.DedupWindowInSeconds == 0 {
+		config.DedupWindowInSeconds = defaultDedupWindowInSecs
+	}
+
+	if config.HeartbeatIntervalInMins == 0 {
+		config.HeartbeatIntervalInMins = defaultHeartbeatIntervalInMins
+	}
 }
 
 func main() {
@@ -109,14 +127,39 @@ func main() {
 	acn.ParseArgs(&args, printVersion)
 	configDirectory := acn.GetArg(acn.OptTelemetryConfigDir).(string)
 	vers := acn.GetArg(acn.OptVersion).(bool)
+	serviceCmd := acn.GetArg(acn.OptService).(string)
 
 	if vers {
 		printVersion()
 		os.Exit(0)
 	}
 
+	if serviceCmd != "" {
+		execPath, err := os.Executable()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		serviceConfig := platform.TelemetryServiceConfig(execPath, platform.ServiceArgs(os.Args[1:], acn.OptService, acn.OptServiceAlias)...)
+		out, err := platform.RunServiceCommand(context.Background(), serviceCmd, serviceConfig)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println(out)
+		os.Exit(0)
+	}
+
 	logger := log.TelemetryLogger.With(zap.String("component", "cni-telemetry"))
 
+	// Report to the service manager, if the telemetry service was started by it.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serviceHost, err := platform.RunAsService(platform.TelemetryServiceName, cancel)
+	if err != nil {
+		logger.Error("Failed to connect to the service manager", zap.Error(err))
+	}
+
 	logger.Info("Telemetry invocation info", zap.Any("arguments", os.Args))
 
 	if runtime.GOOS == "linux" {
@@ -143,6 +186,30 @@ func main() {
 	tbtemp.Cleanup(telemetry.FdName)
 
 	tb = telemetry.NewTelemetryBuffer(logger)
+	redactor, err := telemetry.NewRedactor(config.Redaction)
+	if err != nil {
+		// never send reports unredacted when the operator asked for redaction
+		logger.Error("Invalid telemetry redaction config, disabling telemetry", zap.Error(err))
+		config.DisableAll = true
+	}
+	tb.SetRedactor(redactor)
+	if config.IngestionEndpoint != "" {
+		if _, err = aitelemetry.NormalizeIngestionEndpoint(config.IngestionEndpoint); err != nil {
+			logger.Error("Invalid telemetry ingestion endpoint, disabling telemetry", zap.Error(err))
+			config.DisableAll = true
+		}
+	}
+	tb.SetDedupWindow(time.Duration(config.DedupWindowInSeconds) * time.Second)
+	// the AI exporter sends nothing when telemetry is disabled, as no AI handle is created
+	var exporter telemetry.Exporter = telemetry.AIExporter{}
+	if !config.DisableAll {
+		if exporter, err = telemetry.NewExporter(config, pluginName); err != nil {
+			logger.Error("Invalid telemetry exporter config, disabling telemetry", zap.Error(err))
+			config.DisableAll = true
+			exporter = telemetry.AIExporter{}
+		}
+	}
+	tb.SetExporter(exporter)
 	for {
 		logger.Info("Starting telemetry server")
 		err = tb.StartServer()
@@ -165,12 +232,30 @@ func main() {
 		DebugMode:                    config.DebugMode,
 		GetEnvRetryCount:             config.GetEnvRetryCount,
 		GetEnvRetryWaitTimeInSecs:    config.GetEnvRetryWaitTimeInSecs,
+		IngestionEndpoint:            config.IngestionEndpoint,
+		Proxy:                        config.Proxy,
 	}
 
 	if tb.CreateAITelemetryHandle(aiConfig, config.DisableAll, config.DisableTrace, config.DisableMetric) != nil {
 		logger.Error("AI Handle creation error", zap.Error(err))
 	}
 	logger.Info("Report to host interval", zap.Duration("seconds", config.ReportToHostIntervalInSeconds))
-	tb.PushData(context.Background())
+
+	heartbeat := aitelemetry.NewHeartbeat(azureVnetTelemetry, version, aitelemetry.TogglesFromStruct(config), func() map[string]float64 {
+		stats := tb.Stats()
+		return map[string]float64{
+			"Reconnects":     float64(stats.Reconnects),
+			"WriteFailures":  float64(stats.WriteFailures),
+			"DroppedReports": float64(stats.DroppedReports),
+		}
+	})
+	go heartbeat.Run(ctx, time.Duration(config.HeartbeatIntervalInMins)*time.Minute, func(m aitelemetry.Metric) {
+		if err := exporter.Send(telemetry.AIMetric{Metric: m}); err != nil {
+			logger.Error("Failed to send heartbeat", zap.Error(err))
+		}
+	})
+
+	tb.PushData(ctx)
 	telemetry.CloseAITelemetryHandle()
+	serviceHost.Exited(nil)
 }
@@ -184,7 +184,8 @@ func main() {
 	}
 
 	// Log platform information.
-	log.Printf("Running on %v", platform.GetOSInfo())
+	osInfo, _ := platform.GetOSInfo()
+	log.Printf("Running on %v", osInfo)
 	common.LogNetworkInterfaces()
 
 	// Set plugin options.
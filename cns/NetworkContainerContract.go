@@ -32,12 +32,21 @@ const (
 	RequestIPConfigs                         = "/network/requestipconfigs"
 	ReleaseIPConfig                          = "/network/releaseipconfig"
 	ReleaseIPConfigs                         = "/network/releaseipconfigs"
+	WatchIPConfigs                           = "/network/watchipconfigs"
 	PathDebugIPAddresses                     = "/debug/ipaddresses"
 	PathDebugPodContext                      = "/debug/podcontext"
 	PathDebugRestData                        = "/debug/restdata"
-	NumberOfCPUCores                         = NumberOfCPUCoresPath
-	NMAgentSupportedAPIs                     = NmAgentSupportedApisPath
-	EndpointAPI                              = EndpointPath
+	MigrateLegacyIPAM                        = "/network/ipam/migratelegacy"
+	PathIPAMStatus                           = "/ipam/status"
+	// IPAMStatusSummaryQuery is the query parameter of PathIPAMStatus which omits the network containers and pods
+	// from the response, for callers which only need the counts.
+	IPAMStatusSummaryQuery = "summary"
+	NumberOfCPUCores       = NumberOfCPUCoresPath
+	NMAgentSupportedAPIs   = NmAgentSupportedApisPath
+	EndpointAPI            = EndpointPath
+	// ProvisionNetworkContainer and DeprovisionNetworkContainer are only served under the V2Prefix.
+	ProvisionNetworkContainer   = V2Prefix + "/network/provisionnetworkcontainer"
+	DeprovisionNetworkContainer = V2Prefix + "/network/deprovisionnetworkcontainer"
 )
 
 // NetworkContainer Prefixes
@@ -530,6 +539,106 @@ type GetIPAddressStatusResponse struct {
 	Response              Response
 }
 
+// IPConfigEventType is the kind of change of an IP configuration in a WatchIPConfigs stream.
+type IPConfigEventType string
+
+const (
+	// IPConfigAdded is sent for an IP added to CNS, and for every IP in CNS when the watch starts.
+	IPConfigAdded IPConfigEventType = "Added"
+	// IPConfigUpdated is sent when the state or the pod of an IP changes.
+	IPConfigUpdated IPConfigEventType = "Updated"
+	// IPConfigDeleted is sent for an IP removed from CNS.
+	IPConfigDeleted IPConfigEventType = "Deleted"
+)
+
+// IPConfigEvent is a change of an IP configuration, streamed as JSON lines by the WatchIPConfigs API.
+type IPConfigEvent struct {
+	Type                  IPConfigEventType
+	IPConfigurationStatus IPConfigurationStatus
+}
+
+// LegacyIPAMAllocation is an address reserved for a pod by the legacy azure-vnet-ipam plugin.
+type LegacyIPAMAllocation struct {
+	IPAddress        string
+	InfraContainerID string
+	PodInterfaceID   string
+	PodName          string
+	PodNamespace     string
+}
+
+// MigrateLegacyIPAMRequest is used to migrate the reservations of the legacy azure-vnet-ipam plugin to CNS, so that
+// the pods keep their IPs when the node switches to CNS IPAM. With DryRun, the reservations are only validated.
+type MigrateLegacyIPAMRequest struct {
+	Allocations []LegacyIPAMAllocation
+	DryRun      bool
+}
+
+// LegacyIPAMMigrationStatus is the outcome of migrating a legacy IPAM reservation.
+type LegacyIPAMMigrationStatus string
+
+const (
+	// LegacyIPAMMigrated means the IP is now assigned to the pod in CNS.
+	LegacyIPAMMigrated LegacyIPAMMigrationStatus = "Migrated"
+	// LegacyIPAMAlreadyMigrated means the IP was already assigned to the pod in CNS.
+	LegacyIPAMAlreadyMigrated LegacyIPAMMigrationStatus = "AlreadyMigrated"
+	// LegacyIPAMMigratable means the IP would be assigned to the pod, in a dry run.
+	LegacyIPAMMigratable LegacyIPAMMigrationStatus = "Migratable"
+	// LegacyIPAMMissingPodInfo means the reservation has no pod, e.g. it was leaked by the legacy plugin.
+	LegacyIPAMMissingPodInfo LegacyIPAMMigrationStatus = "MissingPodInfo"
+	// LegacyIPAMNotInPool means the IP is not one of the secondary IPs CNS manages.
+	LegacyIPAMNotInPool LegacyIPAMMigrationStatus = "NotInPool"
+	// LegacyIPAMConflict means the IP is assigned to another pod, or not assignable, in CNS.
+	LegacyIPAMConflict LegacyIPAMMigrationStatus = "Conflict"
+	// LegacyIPAMFailed means CNS failed to assign the IPs of the pod.
+	LegacyIPAMFailed LegacyIPAMMigrationStatus = "Failed"
+)
+
+// LegacyIPAMMigrationResult is the outcome of migrating a legacy IPAM reservation.
+type LegacyIPAMMigrationResult struct {
+	IPAddress    string
+	PodName      string
+	PodNamespace string
+	Status       LegacyIPAMMigrationStatus
+	Message      string `json:",omitempty"`
+}
+
+// MigrateLegacyIPAMResponse is the response to MigrateLegacyIPAMRequest, with a result per reservation.
+type MigrateLegacyIPAMResponse struct {
+	Results  []LegacyIPAMMigrationResult
+	Response Response
+}
+
+// NCIPAMStatus is the state of the secondary IPs of a network container in the CNS pool.
+type NCIPAMStatus struct {
+	NCID               string
+	Total              int
+	Allocated          int
+	Free               int
+	PendingRelease     int
+	PendingProgramming int
+}
+
+// PodIPAllocation is the IPs assigned to a pod interface.
+type PodIPAllocation struct {
+	PodName      string
+	PodNamespace string
+	InterfaceID  string
+	IPAddresses  []string
+}
+
+// IPAMStatusResponse is the size of the CNS IP pool, its allocated and free IPs per network container, and the IPs
+// assigned to each pod. Allocated IPs are the Assigned ones, and free IPs the Available ones.
+type IPAMStatusResponse struct {
+	PoolSize  int
+	Allocated int
+	Free      int
+	// SubnetExhausted is whether the subnet of the pod IPs is exhausted, in which case the pool can't grow.
+	SubnetExhausted   bool
+	NetworkContainers []NCIPAMStatus
+	Pods              []PodIPAllocation
+	Response          Response
+}
+
 // GetPodContextResponse is used in CNS Client debug mode to get mapping of Orchestrator Context to Pod IP UUIDs
 type GetPodContextResponse struct {
 	PodContext map[string][]string // Can have multiple Pod IP UUIDs in the case of dualstack
@@ -552,6 +661,38 @@ type DeleteNetworkContainerResponse struct {
 	Response Response
 }
 
+// ProvisionNetworkContainerRequest creates or updates a network container in CNS without a CRD, through the
+// same code path as the multitenant operator.
+type ProvisionNetworkContainerRequest struct {
+	// IdempotencyKey makes the request safe to retry. A request with the key of an earlier successful request
+	// gets its response without being applied again, and fails with IdempotencyKeyConflict if the requests differ.
+	// Requests without a key are always applied.
+	IdempotencyKey   string
+	NetworkContainer CreateNetworkContainerRequest
+}
+
+// ProvisionNetworkContainerResponse describes the response to provision a network container.
+type ProvisionNetworkContainerResponse struct {
+	Response Response
+	// Replayed is true if the response is the one of an earlier request with the same IdempotencyKey.
+	Replayed bool
+}
+
+// DeprovisionNetworkContainerRequest deletes a network container from CNS without a CRD.
+// Deleting a network container which doesn't exist succeeds.
+type DeprovisionNetworkContainerRequest struct {
+	// IdempotencyKey is like the one of ProvisionNetworkContainerRequest.
+	IdempotencyKey     string
+	NetworkContainerID string
+}
+
+// DeprovisionNetworkContainerResponse describes the response to deprovision a network container.
+type DeprovisionNetworkContainerResponse struct {
+	Response Response
+	// Replayed is true if the response is the one of an earlier request with the same IdempotencyKey.
+	Replayed bool
+}
+
 // GetInterfaceForContainerRequest specifies the container ID for which interface needs to be identified.
 type GetInterfaceForContainerRequest struct {
 	NetworkContainerID string
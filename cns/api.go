@@ -161,6 +161,11 @@ func (i *IPConfigurationStatus) UnmarshalJSON(b []byte) error {
 			return errors.Wrap(err, "failed to unmarshal key state to IPConfigState")
 		}
 	}
+	if s, ok := m["LastStateTransition"]; ok {
+		if err := json.Unmarshal(s, &(i.LastStateTransition)); err != nil {
+			return errors.Wrap(err, "failed to unmarshal key LastStateTransition to time")
+		}
+	}
 	if s, ok := m["PodInfo"]; ok {
 		pi, err := UnmarshalPodInfo(s)
 		if err != nil {
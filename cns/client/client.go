@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
@@ -33,9 +34,12 @@ var clientPaths = []string{
 	cns.RequestIPConfigs,
 	cns.ReleaseIPConfig,
 	cns.ReleaseIPConfigs,
+	cns.WatchIPConfigs,
 	cns.PathDebugIPAddresses,
 	cns.PathDebugPodContext,
 	cns.PathDebugRestData,
+	cns.MigrateLegacyIPAM,
+	cns.PathIPAMStatus,
 	cns.UnpublishNetworkContainer,
 	cns.PublishNetworkContainer,
 	cns.CreateOrUpdateNetworkContainer,
@@ -46,6 +50,8 @@ var clientPaths = []string{
 	cns.NetworkContainersURLPath,
 	cns.GetHomeAz,
 	cns.EndpointAPI,
+	cns.ProvisionNetworkContainer,
+	cns.DeprovisionNetworkContainer,
 }
 
 type do interface {
@@ -330,8 +336,12 @@ func (c *Client) RequestIPAddress(ctx context.Context, ipconfig cns.IPConfigRequ
 
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusTooManyRequests {
+		return nil, subnetExhaustedError(res)
+	}
+
 	if res.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("http response %d", res.StatusCode)
+		return nil, &FailedHTTPRequest{Code: res.StatusCode}
 	}
 
 	var response cns.IPConfigResponse
@@ -347,6 +357,25 @@ func (c *Client) RequestIPAddress(ctx context.Context, ipconfig cns.IPConfigRequ
 	return &response, nil
 }
 
+// subnetExhaustedError returns the error for a request which CNS rejected because the subnet is exhausted, with the
+// Retry-After that CNS set.
+func subnetExhaustedError(res *http.Response) error {
+	var response struct {
+		Response cns.Response
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		response.Response.Message = "subnet exhausted"
+	}
+	e := &CNSClientError{
+		Code: types.SubnetExhausted,
+		Err:  errors.New(response.Response.Message),
+	}
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return e
+}
+
 // ReleaseIPAddress calls releaseIPAddress on CNS, ipaddress ex: (10.0.0.1)
 func (c *Client) ReleaseIPAddress(ctx context.Context, ipconfig cns.IPConfigRequest) error {
 	var body bytes.Buffer
@@ -370,7 +399,7 @@ func (c *Client) ReleaseIPAddress(ctx context.Context, ipconfig cns.IPConfigRequ
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return errors.Errorf("http response %d", res.StatusCode)
+		return &FailedHTTPRequest{Code: res.StatusCode}
 	}
 
 	var resp cns.Response
@@ -423,8 +452,12 @@ func (c *Client) RequestIPs(ctx context.Context, ipconfig cns.IPConfigsRequest)
 		}
 	}
 
+	if res.StatusCode == http.StatusTooManyRequests {
+		return nil, subnetExhaustedError(res)
+	}
+
 	if res.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("http response %d", res.StatusCode)
+		return nil, &FailedHTTPRequest{Code: res.StatusCode}
 	}
 
 	var response cns.IPConfigsResponse
@@ -471,7 +504,7 @@ func (c *Client) ReleaseIPs(ctx context.Context, ipconfig cns.IPConfigsRequest)
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return errors.Errorf("http response %d", res.StatusCode)
+		return &FailedHTTPRequest{Code: res.StatusCode}
 	}
 
 	var resp cns.Response
@@ -533,6 +566,140 @@ func (c *Client) GetIPAddressesMatchingStates(ctx context.Context, stateFilter .
 	return resp.IPConfigurationStatus, nil
 }
 
+// WatchIPConfigs streams the IP configurations in CNS: an IPConfigAdded event for every IP, followed by the changes
+// to them. The channel is closed when the context is done or the stream ends, e.g. because the watcher fell too far
+// behind, in which case the caller should watch again to get the current state.
+// The stream is not bound by the request timeout of the client.
+func (c *Client) WatchIPConfigs(ctx context.Context) (<-chan cns.IPConfigEvent, error) {
+	u := c.routes[cns.WatchIPConfigs]
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+
+	watchClient := c.client
+	if httpClient, ok := c.client.(*http.Client); ok && httpClient.Timeout != 0 {
+		noTimeout := *httpClient
+		noTimeout.Timeout = 0
+		watchClient = &noTimeout
+	}
+	res, err := watchClient.Do(req)
+	if err != nil {
+		return nil, &ConnectionFailureErr{cause: err}
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	events := make(chan cns.IPConfigEvent)
+	go func() {
+		defer close(events)
+		defer res.Body.Close()
+		dec := json.NewDecoder(res.Body)
+		for {
+			var event cns.IPConfigEvent
+			if err := dec.Decode(&event); err != nil {
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// MigrateLegacyIPAM calls the MigrateLegacyIPAM API on CNS, to assign the IPs reserved by the legacy azure-vnet-ipam
+// plugin to their pods in CNS.
+func (c *Client) MigrateLegacyIPAM(ctx context.Context, migrateRequest cns.MigrateLegacyIPAMRequest) (*cns.MigrateLegacyIPAMResponse, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(migrateRequest); err != nil {
+		return nil, errors.Wrap(err, "failed to encode MigrateLegacyIPAMRequest")
+	}
+
+	u := c.routes[cns.MigrateLegacyIPAM]
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+	req.Header.Set(headerContentType, contentTypeJSON)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	var resp cns.MigrateLegacyIPAMResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, errors.Wrap(err, "failed to decode MigrateLegacyIPAMResponse")
+	}
+
+	if resp.Response.ReturnCode != 0 {
+		return nil, errors.New(resp.Response.Message)
+	}
+
+	return &resp, nil
+}
+
+// GetIPAMStatus calls the IPAMStatus API on CNS, to get the size of the IP pool, the allocated and free IPs of each
+// network container, and the IPs assigned to each pod.
+func (c *Client) GetIPAMStatus(ctx context.Context) (*cns.IPAMStatusResponse, error) {
+	return c.getIPAMStatus(ctx, false)
+}
+
+// GetIPAMStatusSummary calls the IPAMStatus API on CNS, to get the size of the IP pool, its allocated and free IPs,
+// and whether it can grow, without the network containers and pods.
+func (c *Client) GetIPAMStatusSummary(ctx context.Context) (*cns.IPAMStatusResponse, error) {
+	return c.getIPAMStatus(ctx, true)
+}
+
+func (c *Client) getIPAMStatus(ctx context.Context, summary bool) (*cns.IPAMStatusResponse, error) {
+	u := c.routes[cns.PathIPAMStatus]
+	if summary {
+		q := u.Query()
+		q.Set(cns.IPAMStatusSummaryQuery, "true")
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, &CNSClientError{
+			Code: types.UnsupportedAPI,
+			Err:  errors.Errorf("Unsupported API"),
+		}
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &FailedHTTPRequest{Code: res.StatusCode}
+	}
+
+	var resp cns.IPAMStatusResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, errors.Wrap(err, "failed to decode IPAMStatusResponse")
+	}
+
+	if resp.Response.ReturnCode != 0 {
+		return nil, errors.New(resp.Response.Message)
+	}
+
+	return &resp, nil
+}
+
 // GetPodOrchestratorContext calls GetPodIpOrchestratorContext API on CNS
 func (c *Client) GetPodOrchestratorContext(ctx context.Context) (map[string][]string, error) {
 	u := c.routes[cns.PathDebugPodContext]
@@ -988,6 +1155,69 @@ func (c *Client) PostAllNetworkContainers(ctx context.Context, createNcRequest c
 	return nil
 }
 
+// ProvisionNetworkContainer creates or updates a network container in CNS. Requests with an IdempotencyKey
+// can be retried safely, e.g. after a timeout; a key reused for a different request fails with a
+// CNSClientError of code IdempotencyKeyConflict.
+func (c *Client) ProvisionNetworkContainer(ctx context.Context, pncr *cns.ProvisionNetworkContainerRequest) (*cns.ProvisionNetworkContainerResponse, error) {
+	var out cns.ProvisionNetworkContainerResponse
+	if err := c.postNetworkContainerRequest(ctx, cns.ProvisionNetworkContainer, pncr, &out); err != nil {
+		return nil, err
+	}
+	if out.Response.ReturnCode != types.Success {
+		return nil, &CNSClientError{
+			Code: out.Response.ReturnCode,
+			Err:  errors.New(out.Response.Message),
+		}
+	}
+	return &out, nil
+}
+
+// DeprovisionNetworkContainer deletes a network container from CNS. Deleting a network container which
+// doesn't exist succeeds.
+func (c *Client) DeprovisionNetworkContainer(ctx context.Context, dncr cns.DeprovisionNetworkContainerRequest) (*cns.DeprovisionNetworkContainerResponse, error) {
+	if dncr.NetworkContainerID == "" {
+		return nil, errors.New("no network container ID provided")
+	}
+	var out cns.DeprovisionNetworkContainerResponse
+	if err := c.postNetworkContainerRequest(ctx, cns.DeprovisionNetworkContainer, dncr, &out); err != nil {
+		return nil, err
+	}
+	if out.Response.ReturnCode != types.Success {
+		return nil, &CNSClientError{
+			Code: out.Response.ReturnCode,
+			Err:  errors.New(out.Response.Message),
+		}
+	}
+	return &out, nil
+}
+
+// postNetworkContainerRequest posts the request as JSON to the route and decodes the response into out.
+func (c *Client) postNetworkContainerRequest(ctx context.Context, route string, in, out interface{}) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(in); err != nil {
+		return errors.Wrap(err, "encoding request body")
+	}
+	u := c.routes[route]
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return errors.Wrap(err, "building HTTP request")
+	}
+	req.Header.Set(headerContentType, contentTypeJSON)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return &ConnectionFailureErr{cause: err}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("http response %d", res.StatusCode)
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "decoding response as JSON")
+	}
+	return nil
+}
+
 // GetHomeAz gets home AZ of host
 func (c *Client) GetHomeAz(ctx context.Context) (*cns.GetHomeAzResponse, error) {
 	// build the request
@@ -283,6 +283,111 @@ func TestCNSClientPodContextApi(t *testing.T) {
 	require.NoError(t, err, "Expected to not fail when releasing IP reservation found with context")
 }
 
+func TestCNSClientRequestIPsSubnetExhausted(t *testing.T) {
+	cnsClient, _ := New("", 2*time.Second)
+	addTestStateToRestServer(t, []string{primaryIP})
+	defer svc.SetSubnetExhausted(false)
+
+	podInfo := cns.NewPodInfo("some-guid-1", "abc-eth0", testpodname, testpodnamespace)
+	orchestratorContext, err := json.Marshal(podInfo)
+	require.NoError(t, err)
+	req := cns.IPConfigsRequest{OrchestratorContext: orchestratorContext}
+	otherPodInfo := cns.NewPodInfo("some-guid-2", "def-eth0", "otherpod", testpodnamespace)
+	otherOrchestratorContext, err := json.Marshal(otherPodInfo)
+	require.NoError(t, err)
+	otherReq := cns.IPConfigsRequest{OrchestratorContext: otherOrchestratorContext}
+
+	// IPs already allocated to the Node are still assigned while the subnet is exhausted
+	svc.SetSubnetExhausted(true)
+	_, err = cnsClient.RequestIPs(context.TODO(), req)
+	require.NoError(t, err, "get IP from CNS failed")
+
+	// once the pool is empty, the request fails with a retry after
+	_, err = cnsClient.RequestIPs(context.TODO(), otherReq)
+	require.True(t, IsSubnetExhausted(err), "expected subnet exhausted error, got %v", err)
+	var clientErr *CNSClientError
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, 30*time.Second, clientErr.RetryAfter)
+
+	svc.SetSubnetExhausted(false)
+	_, err = cnsClient.RequestIPs(context.TODO(), otherReq)
+	require.Error(t, err)
+	assert.False(t, IsSubnetExhausted(err))
+
+	err = cnsClient.ReleaseIPs(context.TODO(), req)
+	require.NoError(t, err, "Expected to not fail when releasing IP reservation found with context")
+}
+
+func TestCNSClientIPAMStatus(t *testing.T) {
+	secondaryIps := []string{primaryIP}
+	cnsClient, _ := New("", 2*time.Second)
+
+	addTestStateToRestServer(t, secondaryIps)
+
+	podInfo := cns.NewPodInfo("some-guid-1", "abc-eth0", testpodname, testpodnamespace)
+	orchestratorContext, err := json.Marshal(podInfo)
+	require.NoError(t, err)
+	_, err = cnsClient.RequestIPAddress(context.TODO(), cns.IPConfigRequest{OrchestratorContext: orchestratorContext})
+	require.NoError(t, err, "get IP from CNS failed")
+
+	status, err := cnsClient.GetIPAMStatus(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.PoolSize)
+	assert.Equal(t, 1, status.Allocated)
+	require.Len(t, status.Pods, 1)
+	assert.Equal(t, testpodname, status.Pods[0].PodName)
+	assert.Equal(t, testpodnamespace, status.Pods[0].PodNamespace)
+	assert.Equal(t, []string{primaryIP}, status.Pods[0].IPAddresses)
+
+	err = cnsClient.ReleaseIPAddress(context.TODO(), cns.IPConfigRequest{OrchestratorContext: orchestratorContext})
+	require.NoError(t, err, "Expected to not fail when releasing IP reservation found with context")
+}
+
+func TestCNSClientWatchIPConfigs(t *testing.T) {
+	secondaryIps := []string{primaryIP}
+	cnsClient, _ := New("", 2*time.Second)
+
+	addTestStateToRestServer(t, secondaryIps)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := cnsClient.WatchIPConfigs(ctx)
+	require.NoError(t, err)
+
+	waitForEvent := func(eventType cns.IPConfigEventType, state types.IPState) {
+		t.Helper()
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case event, ok := <-events:
+				require.True(t, ok, "watch ended before the %s %s event", eventType, state)
+				if event.Type == eventType && event.IPConfigurationStatus.IPAddress == primaryIP && event.IPConfigurationStatus.GetState() == state {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("timed out waiting for the %s %s event", eventType, state)
+			}
+		}
+	}
+
+	// the initial state
+	waitForEvent(cns.IPConfigAdded, types.Available)
+
+	podInfo := cns.NewPodInfo("some-guid-1", "abc-eth0", testpodname, testpodnamespace)
+	orchestratorContext, err := json.Marshal(podInfo)
+	require.NoError(t, err)
+	_, err = cnsClient.RequestIPAddress(context.TODO(), cns.IPConfigRequest{OrchestratorContext: orchestratorContext})
+	require.NoError(t, err, "get IP from CNS failed")
+	waitForEvent(cns.IPConfigUpdated, types.Assigned)
+
+	err = cnsClient.ReleaseIPAddress(context.TODO(), cns.IPConfigRequest{OrchestratorContext: orchestratorContext})
+	require.NoError(t, err, "Expected to not fail when releasing IP reservation found with context")
+	waitForEvent(cns.IPConfigUpdated, types.Available)
+
+	cancel()
+	for range events { //nolint:revive // drain until closed
+	}
+}
+
 func TestCNSClientDebugAPI(t *testing.T) {
 	podName := testpodname
 	podNamespace := testpodnamespace
@@ -2886,3 +2991,82 @@ func TestGetEndpoint(t *testing.T) {
 		})
 	}
 }
+
+func TestProvisionNetworkContainer(t *testing.T) {
+	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
+	tests := []struct {
+		name     string
+		exp      *cns.ProvisionNetworkContainerResponse
+		httpCode int
+		errCode  types.ResponseCode
+	}{
+		{
+			name: "happy path",
+			exp: &cns.ProvisionNetworkContainerResponse{
+				Response: cns.Response{ReturnCode: types.Success},
+				Replayed: true,
+			},
+			httpCode: http.StatusOK,
+		},
+		{
+			name: "idempotency key conflict",
+			exp: &cns.ProvisionNetworkContainerResponse{
+				Response: cns.Response{ReturnCode: types.IdempotencyKeyConflict, Message: "conflict"},
+			},
+			httpCode: http.StatusOK,
+			errCode:  types.IdempotencyKeyConflict,
+		},
+		{
+			name:     "bad request",
+			exp:      &cns.ProvisionNetworkContainerResponse{},
+			httpCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &Client{
+				client: &mockdo{
+					objToReturn:            test.exp,
+					httpStatusCodeToReturn: test.httpCode,
+				},
+				routes: emptyRoutes,
+			}
+
+			got, err := client.ProvisionNetworkContainer(context.Background(), &cns.ProvisionNetworkContainerRequest{IdempotencyKey: "key"})
+			if test.httpCode != http.StatusOK {
+				require.Error(t, err)
+				return
+			}
+			if test.errCode != types.Success {
+				var cnsErr *CNSClientError
+				require.ErrorAs(t, err, &cnsErr)
+				require.Equal(t, test.errCode, cnsErr.Code)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.exp, got)
+		})
+	}
+}
+
+func TestDeprovisionNetworkContainer(t *testing.T) {
+	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
+	client := &Client{
+		client: &mockdo{
+			objToReturn:            &cns.DeprovisionNetworkContainerResponse{Response: cns.Response{ReturnCode: types.Success}},
+			httpStatusCodeToReturn: http.StatusOK,
+		},
+		routes: emptyRoutes,
+	}
+
+	_, err := client.DeprovisionNetworkContainer(context.Background(), cns.DeprovisionNetworkContainerRequest{})
+	require.Error(t, err, "the network container ID is required")
+
+	got, err := client.DeprovisionNetworkContainer(context.Background(), cns.DeprovisionNetworkContainerRequest{NetworkContainerID: "nc"})
+	require.NoError(t, err)
+	require.Equal(t, types.Success, got.Response.ReturnCode)
+}
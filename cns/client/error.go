@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns/types"
 )
@@ -22,6 +23,8 @@ func (f *FailedHTTPRequest) Error() string {
 type CNSClientError struct {
 	Code types.ResponseCode
 	Err  error
+	// RetryAfter is how long CNS asked to wait before retrying the request, if it did.
+	RetryAfter time.Duration
 }
 
 func (e *CNSClientError) Error() string {
@@ -41,3 +44,10 @@ func IsUnsupportedAPI(err error) bool {
 	e := &CNSClientError{}
 	return errors.As(err, &e) && (e.Code == types.UnsupportedAPI)
 }
+
+// IsSubnetExhausted tests if the provided error is of type CNSClientError and then
+// further tests if the error code is of type SubnetExhausted
+func IsSubnetExhausted(err error) bool {
+	e := &CNSClientError{}
+	return errors.As(err, &e) && (e.Code == types.SubnetExhausted)
+}
@@ -0,0 +1,227 @@
+// Package fake provides a programmable in-memory CNS client for unit tests of the consumers of the CNS client,
+// such as the CNI plugins and azure-ipam, so they don't have to hand roll a mock.
+package fake
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+)
+
+// AnyContainer programs the response to the calls for every container which has no response of its own.
+const AnyContainer = "*"
+
+// Names of the methods of the client, as recorded in the Calls.
+const (
+	RequestIPAddress        = "RequestIPAddress"
+	RequestIPs              = "RequestIPs"
+	ReleaseIPAddress        = "ReleaseIPAddress"
+	ReleaseIPs              = "ReleaseIPs"
+	GetNetworkContainer     = "GetNetworkContainer"
+	GetAllNetworkContainers = "GetAllNetworkContainers"
+)
+
+// ErrNotProgrammed is returned by the calls for which the test has not programmed a response.
+var ErrNotProgrammed = errors.New("no response programmed for call")
+
+// UnsupportedAPIError returns the error of the real client for a CNS which does not serve the API,
+// which the consumers use to fall back to the older API.
+func UnsupportedAPIError() error {
+	return &client.CNSClientError{Code: types.UnsupportedAPI, Err: errors.New("unsupported API")}
+}
+
+// Call is a call made to the Client.
+type Call struct {
+	Method string
+	// ContainerID is the InfraContainerID of the IP config requests, empty for the other calls.
+	ContainerID string
+	// Request is the IP config request or the orchestrator context passed to the call.
+	Request interface{}
+}
+
+type response struct {
+	value interface{}
+	err   error
+}
+
+// Client is an in-memory CNS client. The tests program the response of each method per container ID,
+// and may inject latency. Every call is recorded, including the calls which fail.
+// It is safe for concurrent use.
+type Client struct {
+	sync.Mutex
+	latency   time.Duration
+	responses map[string]map[string][]response
+	calls     []Call
+}
+
+// NewClient returns a Client without any programmed response, so every call fails with ErrNotProgrammed.
+func NewClient() *Client {
+	return &Client{
+		responses: map[string]map[string][]response{},
+	}
+}
+
+// SetLatency delays every following call by latency, or until the context of the call is done.
+func (c *Client) SetLatency(latency time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.latency = latency
+}
+
+// OnRequestIPAddress programs the response of RequestIPAddress for the container.
+func (c *Client) OnRequestIPAddress(containerID string, resp *cns.IPConfigResponse, err error) *Client {
+	return c.program(RequestIPAddress, containerID, resp, err)
+}
+
+// OnRequestIPs programs the response of RequestIPs for the container.
+func (c *Client) OnRequestIPs(containerID string, resp *cns.IPConfigsResponse, err error) *Client {
+	return c.program(RequestIPs, containerID, resp, err)
+}
+
+// OnReleaseIPAddress programs the error returned by ReleaseIPAddress for the container.
+func (c *Client) OnReleaseIPAddress(containerID string, err error) *Client {
+	return c.program(ReleaseIPAddress, containerID, nil, err)
+}
+
+// OnReleaseIPs programs the error returned by ReleaseIPs for the container.
+func (c *Client) OnReleaseIPs(containerID string, err error) *Client {
+	return c.program(ReleaseIPs, containerID, nil, err)
+}
+
+// OnGetNetworkContainer programs the response of GetNetworkContainer. Its calls don't have a container ID,
+// so the response applies to every call.
+func (c *Client) OnGetNetworkContainer(resp *cns.GetNetworkContainerResponse, err error) *Client {
+	return c.program(GetNetworkContainer, AnyContainer, resp, err)
+}
+
+// OnGetAllNetworkContainers programs the response of GetAllNetworkContainers. Its calls don't have a container ID,
+// so the response applies to every call.
+func (c *Client) OnGetAllNetworkContainers(resp []cns.GetNetworkContainerResponse, err error) *Client {
+	return c.program(GetAllNetworkContainers, AnyContainer, resp, err)
+}
+
+// program queues a response for the method and container. The responses are returned in the order they were
+// programmed, and the last one is returned for all the calls after it, so programming a single response makes
+// every call return it, while programming an error then a response makes only the first call fail.
+func (c *Client) program(method, containerID string, value interface{}, err error) *Client {
+	c.Lock()
+	defer c.Unlock()
+	if c.responses[method] == nil {
+		c.responses[method] = map[string][]response{}
+	}
+	c.responses[method][containerID] = append(c.responses[method][containerID], response{value: value, err: err})
+	return c
+}
+
+// Calls returns the calls made so far, in order.
+func (c *Client) Calls() []Call {
+	c.Lock()
+	defer c.Unlock()
+	return append([]Call{}, c.calls...)
+}
+
+// CallCount returns the number of calls made to the method for the container, or for every container with AnyContainer.
+func (c *Client) CallCount(method, containerID string) int {
+	c.Lock()
+	defer c.Unlock()
+	count := 0
+	for _, call := range c.calls {
+		if call.Method == method && (containerID == AnyContainer || call.ContainerID == containerID) {
+			count++
+		}
+	}
+	return count
+}
+
+// Reset drops the programmed responses and the recorded calls.
+func (c *Client) Reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.latency = 0
+	c.responses = map[string]map[string][]response{}
+	c.calls = nil
+}
+
+// call records the call, waits for the injected latency, and returns the next response programmed for it.
+func (c *Client) call(ctx context.Context, method, containerID string, request interface{}) (interface{}, error) {
+	c.Lock()
+	c.calls = append(c.calls, Call{Method: method, ContainerID: containerID, Request: request})
+	latency := c.latency
+	resp, ok := c.next(method, containerID)
+	c.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "%s for container %s", method, containerID)
+		}
+	}
+	if !ok {
+		return nil, errors.Wrapf(ErrNotProgrammed, "%s for container %s", method, containerID)
+	}
+	return resp.value, resp.err
+}
+
+// next pops the next response for the container, falling back to the responses for AnyContainer.
+// It must be called with the lock held.
+func (c *Client) next(method, containerID string) (response, bool) {
+	for _, id := range []string{containerID, AnyContainer} {
+		queue := c.responses[method][id]
+		if len(queue) == 0 {
+			continue
+		}
+		if len(queue) > 1 {
+			c.responses[method][id] = queue[1:]
+		}
+		return queue[0], true
+	}
+	return response{}, false
+}
+
+// RequestIPAddress records the call and returns the response programmed with OnRequestIPAddress.
+func (c *Client) RequestIPAddress(ctx context.Context, ipconfig cns.IPConfigRequest) (*cns.IPConfigResponse, error) {
+	value, err := c.call(ctx, RequestIPAddress, ipconfig.InfraContainerID, ipconfig)
+	resp, _ := value.(*cns.IPConfigResponse)
+	return resp, err
+}
+
+// RequestIPs records the call and returns the response programmed with OnRequestIPs.
+func (c *Client) RequestIPs(ctx context.Context, ipconfig cns.IPConfigsRequest) (*cns.IPConfigsResponse, error) {
+	value, err := c.call(ctx, RequestIPs, ipconfig.InfraContainerID, ipconfig)
+	resp, _ := value.(*cns.IPConfigsResponse)
+	return resp, err
+}
+
+// ReleaseIPAddress records the call and returns the response programmed with OnReleaseIPAddress.
+func (c *Client) ReleaseIPAddress(ctx context.Context, ipconfig cns.IPConfigRequest) error {
+	_, err := c.call(ctx, ReleaseIPAddress, ipconfig.InfraContainerID, ipconfig)
+	return err
+}
+
+// ReleaseIPs records the call and returns the response programmed with OnReleaseIPs.
+func (c *Client) ReleaseIPs(ctx context.Context, ipconfig cns.IPConfigsRequest) error {
+	_, err := c.call(ctx, ReleaseIPs, ipconfig.InfraContainerID, ipconfig)
+	return err
+}
+
+// GetNetworkContainer records the call and returns the response programmed with OnGetNetworkContainer.
+func (c *Client) GetNetworkContainer(ctx context.Context, orchestratorContext []byte) (*cns.GetNetworkContainerResponse, error) {
+	value, err := c.call(ctx, GetNetworkContainer, "", orchestratorContext)
+	resp, _ := value.(*cns.GetNetworkContainerResponse)
+	return resp, err
+}
+
+// GetAllNetworkContainers records the call and returns the response programmed with OnGetAllNetworkContainers.
+func (c *Client) GetAllNetworkContainers(ctx context.Context, orchestratorContext []byte) ([]cns.GetNetworkContainerResponse, error) {
+	value, err := c.call(ctx, GetAllNetworkContainers, "", orchestratorContext)
+	resp, _ := value.([]cns.GetNetworkContainerResponse)
+	return resp, err
+}
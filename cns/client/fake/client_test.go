@@ -0,0 +1,117 @@
+package fake_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/client/fake"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ipamClient is the CNS client interface of azure-ipam.
+type ipamClient interface {
+	RequestIPAddress(context.Context, cns.IPConfigRequest) (*cns.IPConfigResponse, error)
+	RequestIPs(context.Context, cns.IPConfigsRequest) (*cns.IPConfigsResponse, error)
+	ReleaseIPs(context.Context, cns.IPConfigsRequest) error
+	ReleaseIPAddress(context.Context, cns.IPConfigRequest) error
+}
+
+var _ ipamClient = (*fake.Client)(nil)
+
+var errFoo = errors.New("foo")
+
+func TestClientResponses(t *testing.T) {
+	ctx := context.Background()
+	resp := &cns.IPConfigsResponse{PodIPInfo: []cns.PodIpInfo{{PodIPConfig: cns.IPSubnet{IPAddress: "10.0.0.4", PrefixLength: 24}}}}
+	c := fake.NewClient().
+		OnRequestIPs("flaky", nil, errFoo).
+		OnRequestIPs("flaky", resp, nil).
+		OnRequestIPs("legacy", nil, fake.UnsupportedAPIError()).
+		OnRequestIPs(fake.AnyContainer, resp, nil).
+		OnReleaseIPs(fake.AnyContainer, nil)
+
+	_, err := c.RequestIPs(ctx, cns.IPConfigsRequest{InfraContainerID: "flaky"})
+	require.ErrorIs(t, err, errFoo)
+	for i := 0; i < 2; i++ {
+		got, err := c.RequestIPs(ctx, cns.IPConfigsRequest{InfraContainerID: "flaky"})
+		require.NoError(t, err)
+		assert.Equal(t, resp, got)
+	}
+
+	_, err = c.RequestIPs(ctx, cns.IPConfigsRequest{InfraContainerID: "legacy"})
+	assert.True(t, client.IsUnsupportedAPI(err))
+
+	got, err := c.RequestIPs(ctx, cns.IPConfigsRequest{InfraContainerID: "other"})
+	require.NoError(t, err)
+	assert.Equal(t, resp, got)
+	require.NoError(t, c.ReleaseIPs(ctx, cns.IPConfigsRequest{InfraContainerID: "other"}))
+
+	// calls without a programmed response fail
+	_, err = c.RequestIPAddress(ctx, cns.IPConfigRequest{InfraContainerID: "other"})
+	require.ErrorIs(t, err, fake.ErrNotProgrammed)
+
+	assert.Equal(t, 3, c.CallCount(fake.RequestIPs, "flaky"))
+	assert.Equal(t, 5, c.CallCount(fake.RequestIPs, fake.AnyContainer))
+	calls := c.Calls()
+	require.Len(t, calls, 7)
+	assert.Equal(t, fake.Call{
+		Method:      fake.ReleaseIPs,
+		ContainerID: "other",
+		Request:     cns.IPConfigsRequest{InfraContainerID: "other"},
+	}, calls[5])
+
+	c.Reset()
+	assert.Empty(t, c.Calls())
+	_, err = c.RequestIPs(ctx, cns.IPConfigsRequest{InfraContainerID: "flaky"})
+	require.ErrorIs(t, err, fake.ErrNotProgrammed)
+}
+
+func TestClientNetworkContainers(t *testing.T) {
+	ctx := context.Background()
+	nc := cns.GetNetworkContainerResponse{NetworkContainerID: "nc1"}
+	c := fake.NewClient().
+		OnGetNetworkContainer(&nc, nil).
+		OnGetAllNetworkContainers(nil, errFoo)
+
+	got, err := c.GetNetworkContainer(ctx, []byte("{}"))
+	require.NoError(t, err)
+	assert.Equal(t, &nc, got)
+	_, err = c.GetAllNetworkContainers(ctx, []byte("{}"))
+	require.ErrorIs(t, err, errFoo)
+	assert.Equal(t, 1, c.CallCount(fake.GetNetworkContainer, fake.AnyContainer))
+}
+
+func TestClientLatency(t *testing.T) {
+	c := fake.NewClient().OnReleaseIPs(fake.AnyContainer, nil)
+	c.SetLatency(50 * time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, c.ReleaseIPs(context.Background(), cns.IPConfigsRequest{}))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// the latency is cut short by the context of the call, like a timeout of the real client
+	c.SetLatency(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, c.ReleaseIPs(ctx, cns.IPConfigsRequest{}), context.DeadlineExceeded)
+}
+
+func TestClientConcurrent(t *testing.T) {
+	c := fake.NewClient().OnReleaseIPAddress(fake.AnyContainer, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.ReleaseIPAddress(context.Background(), cns.IPConfigRequest{InfraContainerID: "c"}))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, c.CallCount(fake.ReleaseIPAddress, "c"))
+}
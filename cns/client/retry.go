@@ -0,0 +1,354 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// DefaultRetryConfig retries a request twice, which rides out a CNS restart without exceeding the CNI timeouts of the
+// container runtime given a request timeout of 15s.
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts:      3,
+	InitialBackoff:   500 * time.Millisecond,
+	MaxBackoff:       4 * time.Second,
+	BreakerThreshold: 3,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// RetryConfig configures the retries of the requests of a RetryingClient, and the circuit breaker which stops
+// sending a request to CNS once it keeps failing. The fields left zero take the value of DefaultRetryConfig.
+// In JSON, such as in the network config of a CNI plugin, the durations are strings like "500ms".
+type RetryConfig struct {
+	// MaxAttempts is the number of attempts of a request, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. It doubles with each retry, up to MaxBackoff, and is
+	// jittered so that concurrent callers don't retry in lockstep.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// BreakerThreshold is the number of consecutive failed attempts of a request after which its breaker opens.
+	// A negative threshold disables the breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long a breaker stays open before an attempt is let through to probe CNS.
+	BreakerCooldown time.Duration
+}
+
+type retryConfigJSON struct {
+	MaxAttempts      int    `json:"maxAttempts,omitempty"`
+	InitialBackoff   string `json:"initialBackoff,omitempty"`
+	MaxBackoff       string `json:"maxBackoff,omitempty"`
+	BreakerThreshold int    `json:"breakerThreshold,omitempty"`
+	BreakerCooldown  string `json:"breakerCooldown,omitempty"`
+}
+
+func (c *RetryConfig) UnmarshalJSON(b []byte) error {
+	var raw retryConfigJSON
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return errors.Wrap(err, "failed to unmarshal retry config")
+	}
+	config := RetryConfig{MaxAttempts: raw.MaxAttempts, BreakerThreshold: raw.BreakerThreshold}
+	for _, d := range []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{name: "initialBackoff", value: raw.InitialBackoff, field: &config.InitialBackoff},
+		{name: "maxBackoff", value: raw.MaxBackoff, field: &config.MaxBackoff},
+		{name: "breakerCooldown", value: raw.BreakerCooldown, field: &config.BreakerCooldown},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s", d.name)
+		}
+		*d.field = parsed
+	}
+	*c = config
+	return nil
+}
+
+func (c RetryConfig) MarshalJSON() ([]byte, error) {
+	raw := retryConfigJSON{MaxAttempts: c.MaxAttempts, BreakerThreshold: c.BreakerThreshold}
+	if c.InitialBackoff != 0 {
+		raw.InitialBackoff = c.InitialBackoff.String()
+	}
+	if c.MaxBackoff != 0 {
+		raw.MaxBackoff = c.MaxBackoff.String()
+	}
+	if c.BreakerCooldown != 0 {
+		raw.BreakerCooldown = c.BreakerCooldown.String()
+	}
+	return json.Marshal(raw) //nolint:wrapcheck // the fields always marshal
+}
+
+// withDefaults returns the config with its zero fields set from DefaultRetryConfig.
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultRetryConfig.MaxAttempts
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = DefaultRetryConfig.InitialBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = DefaultRetryConfig.MaxBackoff
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		c.MaxBackoff = c.InitialBackoff
+	}
+	if c.BreakerThreshold == 0 {
+		c.BreakerThreshold = DefaultRetryConfig.BreakerThreshold
+	}
+	if c.BreakerCooldown <= 0 {
+		c.BreakerCooldown = DefaultRetryConfig.BreakerCooldown
+	}
+	return c
+}
+
+// failureKind classifies the errors of CNS requests.
+type failureKind int
+
+const (
+	// failureNone is a success, or a failure which isn't caused by CNS being unavailable.
+	failureNone failureKind = iota
+	// failureTransient is a connection failure or a 5xx response, which is retried.
+	failureTransient
+	// failureUnsupported is an UnsupportedAPI response, which isn't retried as the caller falls back to the
+	// legacy API.
+	failureUnsupported
+)
+
+func classifyFailure(err error) failureKind {
+	if err == nil {
+		return failureNone
+	}
+	if IsUnsupportedAPI(err) {
+		return failureUnsupported
+	}
+	var connErr *ConnectionFailureErr
+	var urlErr *url.Error
+	if errors.As(err, &connErr) || errors.As(err, &urlErr) {
+		return failureTransient
+	}
+	var httpErr *FailedHTTPRequest
+	if errors.As(err, &httpErr) {
+		if httpErr.Code >= 500 {
+			return failureTransient
+		}
+		return failureNone
+	}
+	return failureNone
+}
+
+// CircuitOpenError is returned without calling CNS while the breaker of a request is open. It wraps the error which
+// opened the breaker, so an UnsupportedAPI error still makes the caller fall back to the legacy API.
+type CircuitOpenError struct {
+	Op  string
+	Err error
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "circuit breaker open for CNS " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return e.Err
+}
+
+// BreakerState is the state of the circuit breaker of a request.
+type BreakerState struct {
+	// Failures is the number of consecutive failed attempts.
+	Failures int
+	// OpenUntil is when the breaker lets an attempt through again, once Failures reached the threshold.
+	OpenUntil time.Time
+	// LastError and LastErrorCode are the message and the CNS response code, if any, of the last failed attempt.
+	LastError     string
+	LastErrorCode types.ResponseCode
+}
+
+// err returns the error of the last failed attempt, as a CNSClientError if it had a response code so that it is
+// still recognized by IsUnsupportedAPI.
+func (s *BreakerState) err() error {
+	err := errors.New(s.LastError)
+	if s.LastErrorCode != types.Success {
+		return &CNSClientError{Code: s.LastErrorCode, Err: err}
+	}
+	return err
+}
+
+// BreakerStore holds the state of the circuit breakers of a RetryingClient, keyed by request. Persisting it lets the
+// breakers protect CNS across short-lived processes, such as the invocations of a CNI plugin, which would otherwise
+// never make enough requests to open a breaker.
+type BreakerStore interface {
+	// Load returns the state of the breaker of op, which is zero if op has none.
+	Load(op string) (BreakerState, error)
+	// Update calls fn with the state of the breaker of op, and saves it if fn changed it. It must not lose the
+	// updates made concurrently by other users of the store.
+	Update(op string, fn func(*BreakerState) bool) error
+}
+
+// memoryBreakerStore is a BreakerStore for a single process.
+type memoryBreakerStore struct {
+	mu     sync.Mutex
+	states map[string]BreakerState
+}
+
+func (s *memoryBreakerStore) Load(op string) (BreakerState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[op], nil
+}
+
+func (s *memoryBreakerStore) Update(op string, fn func(*BreakerState) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.states[op]
+	if fn(&state) {
+		s.states[op] = state
+	}
+	return nil
+}
+
+// IPClient is the part of the Client API which RetryingClient retries.
+type IPClient interface {
+	RequestIPs(context.Context, cns.IPConfigsRequest) (*cns.IPConfigsResponse, error)
+	ReleaseIPs(context.Context, cns.IPConfigsRequest) error
+}
+
+// RetryingClient retries the IP requests and releases to CNS which fail because CNS is unavailable, e.g. while it
+// restarts. Each request has a circuit breaker, which opens after BreakerThreshold consecutive failed attempts so
+// that callers fail fast while CNS keeps failing, and lets an attempt through once BreakerCooldown has elapsed: the
+// breaker closes after a success, or opens again after the next failure.
+type RetryingClient struct {
+	client   IPClient
+	breakers BreakerStore
+	logger   *zap.Logger
+
+	mu     sync.Mutex
+	config RetryConfig
+}
+
+// NewRetryingClient returns a RetryingClient for c. The state of the breakers is kept in memory if breakers is nil.
+func NewRetryingClient(c IPClient, config RetryConfig, breakers BreakerStore, logger *zap.Logger) *RetryingClient {
+	if breakers == nil {
+		breakers = &memoryBreakerStore{states: map[string]BreakerState{}}
+	}
+	return &RetryingClient{client: c, breakers: breakers, logger: logger, config: config.withDefaults()}
+}
+
+// SetConfig replaces the config of the client, e.g. with the one of the network config of a CNI invocation.
+func (c *RetryingClient) SetConfig(config RetryConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = config.withDefaults()
+}
+
+func (c *RetryingClient) getConfig() RetryConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.config
+}
+
+func (c *RetryingClient) RequestIPs(ctx context.Context, req cns.IPConfigsRequest) (*cns.IPConfigsResponse, error) {
+	var resp *cns.IPConfigsResponse
+	err := c.do(ctx, cns.RequestIPConfigs, func(ctx context.Context) error {
+		var err error
+		resp, err = c.client.RequestIPs(ctx, req)
+		return err //nolint:wrapcheck // the error of the client is returned as is
+	})
+	return resp, err
+}
+
+func (c *RetryingClient) ReleaseIPs(ctx context.Context, req cns.IPConfigsRequest) error {
+	return c.do(ctx, cns.ReleaseIPConfigs, func(ctx context.Context) error {
+		return c.client.ReleaseIPs(ctx, req) //nolint:wrapcheck // the error of the client is returned as is
+	})
+}
+
+// do makes the attempts of the request op with backoff until one succeeds, fails for a reason other than CNS being
+// unavailable, MaxAttempts is reached, or the breaker of op opens.
+func (c *RetryingClient) do(ctx context.Context, op string, attempt func(context.Context) error) error {
+	config := c.getConfig()
+	backoff := config.InitialBackoff
+	for i := 1; ; i++ {
+		if lastErr := c.allow(config, op, time.Now()); lastErr != nil {
+			c.logger.Error("Not calling CNS while circuit breaker is open", zap.String("op", op), zap.Error(lastErr))
+			return &CircuitOpenError{Op: op, Err: lastErr}
+		}
+
+		err := attempt(ctx)
+		kind := classifyFailure(err)
+		c.record(config, op, time.Now(), err, kind)
+		if kind != failureTransient || i >= config.MaxAttempts {
+			return err
+		}
+
+		// wait between half and the full backoff
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)) //nolint:gosec // jitter does not need crypto rand
+		c.logger.Info("Retrying CNS request", zap.String("op", op), zap.Int("attempt", i), zap.Duration("wait", wait), zap.Error(err))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+		if backoff *= 2; backoff > config.MaxBackoff {
+			backoff = config.MaxBackoff
+		}
+	}
+}
+
+// allow returns the error which opened the breaker of op if the attempt must not be made. The attempt is made if the
+// state of the breaker can't be loaded, since the breaker only protects CNS.
+func (c *RetryingClient) allow(config RetryConfig, op string, now time.Time) error {
+	if config.BreakerThreshold < 0 {
+		return nil
+	}
+	state, err := c.breakers.Load(op)
+	if err != nil {
+		c.logger.Error("Failed to load circuit breaker state", zap.String("op", op), zap.Error(err))
+		return nil
+	}
+	if state.Failures < config.BreakerThreshold || !now.Before(state.OpenUntil) {
+		return nil
+	}
+	return state.err()
+}
+
+func (c *RetryingClient) record(config RetryConfig, op string, now time.Time, err error, kind failureKind) {
+	if config.BreakerThreshold < 0 {
+		return
+	}
+	updateErr := c.breakers.Update(op, func(state *BreakerState) bool {
+		if kind == failureNone {
+			if state.Failures == 0 {
+				return false
+			}
+			*state = BreakerState{}
+			return true
+		}
+		state.Failures++
+		state.LastError = err.Error()
+		state.LastErrorCode = types.Success
+		var cnsErr *CNSClientError
+		if errors.As(err, &cnsErr) {
+			state.LastError = cnsErr.Err.Error()
+			state.LastErrorCode = cnsErr.Code
+		}
+		if state.Failures >= config.BreakerThreshold {
+			state.OpenUntil = now.Add(config.BreakerCooldown)
+		}
+		return true
+	})
+	if updateErr != nil {
+		c.logger.Error("Failed to save circuit breaker state", zap.String("op", op), zap.Error(updateErr))
+	}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var (
+	errRetryConnRefused     = errors.Wrap(&url.Error{Op: "Post", URL: "http://localhost:10090", Err: errors.New("connection refused")}, "http request failed")
+	errRetryServerError     = &FailedHTTPRequest{Code: 503}
+	errRetryUnsupported     = &CNSClientError{Code: types.UnsupportedAPI, Err: errors.New("Unsupported API")}
+	errRetrySubnetExhausted = &CNSClientError{Code: types.SubnetExhausted, Err: errors.New("subnet exhausted"), RetryAfter: time.Second}
+	errRetryOther           = errors.New("other")
+)
+
+// scriptedIPClient returns the errors in order from RequestIPs and ReleaseIPs, then succeeds.
+type scriptedIPClient struct {
+	errs  []error
+	calls int
+}
+
+func (c *scriptedIPClient) next() error {
+	c.calls++
+	if len(c.errs) == 0 {
+		return nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+func (c *scriptedIPClient) RequestIPs(context.Context, cns.IPConfigsRequest) (*cns.IPConfigsResponse, error) {
+	if err := c.next(); err != nil {
+		return nil, err
+	}
+	return &cns.IPConfigsResponse{}, nil
+}
+
+func (c *scriptedIPClient) ReleaseIPs(context.Context, cns.IPConfigsRequest) error {
+	return c.next()
+}
+
+func testRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts:      3,
+		InitialBackoff:   time.Millisecond,
+		MaxBackoff:       2 * time.Millisecond,
+		BreakerThreshold: 5,
+		BreakerCooldown:  time.Hour,
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want failureKind
+	}{
+		{name: "success", want: failureNone},
+		{name: "connection failure", err: errRetryConnRefused, want: failureTransient},
+		{name: "connection failure on release", err: &ConnectionFailureErr{cause: errRetryOther}, want: failureTransient},
+		{name: "server error", err: errRetryServerError, want: failureTransient},
+		{name: "failed http request", err: &FailedHTTPRequest{Code: 500}, want: failureTransient},
+		{name: "client error", err: &FailedHTTPRequest{Code: 400}, want: failureNone},
+		{name: "subnet exhausted", err: errRetrySubnetExhausted, want: failureNone},
+		{name: "unsupported API", err: errRetryUnsupported, want: failureUnsupported},
+		{name: "CNS response error", err: errRetryOther, want: failureNone},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, classifyFailure(tt.err))
+		})
+	}
+}
+
+func TestRetryConfigJSON(t *testing.T) {
+	var config RetryConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"maxAttempts":5,"initialBackoff":"100ms","breakerCooldown":"1m"}`), &config))
+	require.Equal(t, RetryConfig{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, BreakerCooldown: time.Minute}, config)
+
+	// the zero fields take the defaults
+	config = config.withDefaults()
+	require.Equal(t, DefaultRetryConfig.MaxBackoff, config.MaxBackoff)
+	require.Equal(t, DefaultRetryConfig.BreakerThreshold, config.BreakerThreshold)
+
+	b, err := json.Marshal(config)
+	require.NoError(t, err)
+	var roundTripped RetryConfig
+	require.NoError(t, json.Unmarshal(b, &roundTripped))
+	require.Equal(t, config, roundTripped)
+
+	require.Error(t, json.Unmarshal([]byte(`{"maxBackoff":"soon"}`), &config))
+}
+
+func TestRetryingClientRetriesTransientFailures(t *testing.T) {
+	inner := &scriptedIPClient{errs: []error{errRetryConnRefused, errRetryServerError}}
+	c := NewRetryingClient(inner, testRetryConfig(), nil, zap.NewNop())
+
+	resp, err := c.RequestIPs(context.Background(), cns.IPConfigsRequest{})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.Equal(t, 3, inner.calls)
+}
+
+func TestRetryingClientGivesUp(t *testing.T) {
+	inner := &scriptedIPClient{errs: []error{errRetryServerError, errRetryServerError, errRetryServerError, errRetryServerError}}
+	c := NewRetryingClient(inner, testRetryConfig(), nil, zap.NewNop())
+
+	require.ErrorIs(t, c.ReleaseIPs(context.Background(), cns.IPConfigsRequest{}), errRetryServerError)
+	require.Equal(t, 3, inner.calls)
+}
+
+func TestRetryingClientDoesNotRetryOtherFailures(t *testing.T) {
+	inner := &scriptedIPClient{errs: []error{errRetryOther}}
+	c := NewRetryingClient(inner, testRetryConfig(), nil, zap.NewNop())
+
+	require.ErrorIs(t, c.ReleaseIPs(context.Background(), cns.IPConfigsRequest{}), errRetryOther)
+	require.Equal(t, 1, inner.calls)
+}
+
+func TestRetryingClientCircuitBreakerOutlivesClient(t *testing.T) {
+	config := testRetryConfig()
+	config.BreakerThreshold = 2
+	config.BreakerCooldown = 50 * time.Millisecond
+	breakers := &memoryBreakerStore{states: map[string]BreakerState{}}
+	inner := &scriptedIPClient{errs: []error{errRetryUnsupported, errRetryUnsupported}}
+
+	// UnsupportedAPI is not retried, but opens the breaker once repeated by successive clients sharing the store,
+	// as the invocations of a CNI plugin do
+	for i := 0; i < 2; i++ {
+		c := NewRetryingClient(inner, config, breakers, zap.NewNop())
+		_, err := c.RequestIPs(context.Background(), cns.IPConfigsRequest{})
+		require.True(t, IsUnsupportedAPI(err))
+	}
+	require.Equal(t, 2, inner.calls)
+
+	// while open, CNS is not called and the caller still sees the UnsupportedAPI error to fall back
+	c := NewRetryingClient(inner, config, breakers, zap.NewNop())
+	_, err := c.RequestIPs(context.Background(), cns.IPConfigsRequest{})
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	require.True(t, IsUnsupportedAPI(err))
+	require.Equal(t, 2, inner.calls)
+
+	// the breakers of the requests are independent
+	require.NoError(t, c.ReleaseIPs(context.Background(), cns.IPConfigsRequest{}))
+	require.Equal(t, 3, inner.calls)
+
+	// after the cooldown, a successful attempt closes the breaker
+	time.Sleep(config.BreakerCooldown)
+	_, err = c.RequestIPs(context.Background(), cns.IPConfigsRequest{})
+	require.NoError(t, err)
+	_, err = c.RequestIPs(context.Background(), cns.IPConfigsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 5, inner.calls)
+	require.Equal(t, BreakerState{}, breakers.states[cns.RequestIPConfigs])
+}
+
+func TestRetryingClientBreakerDisabled(t *testing.T) {
+	config := testRetryConfig()
+	config.MaxAttempts = 1
+	config.BreakerThreshold = -1
+	inner := &scriptedIPClient{errs: []error{errRetryServerError, errRetryServerError, errRetryServerError}}
+	c := NewRetryingClient(inner, config, nil, zap.NewNop())
+
+	for i := 0; i < 3; i++ {
+		require.ErrorIs(t, c.ReleaseIPs(context.Background(), cns.IPConfigsRequest{}), errRetryServerError)
+	}
+	require.Equal(t, 3, inner.calls)
+}
+
+func TestRetryingClientStopsOnContextDone(t *testing.T) {
+	config := testRetryConfig()
+	config.InitialBackoff = time.Hour
+	config.MaxBackoff = time.Hour
+	inner := &scriptedIPClient{errs: []error{errRetryServerError}}
+	c := NewRetryingClient(inner, config, nil, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, c.ReleaseIPs(ctx, cns.IPConfigsRequest{}), errRetryServerError)
+	require.Equal(t, 1, inner.calls)
+}
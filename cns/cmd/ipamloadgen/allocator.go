@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/ipampool"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/pkg/errors"
+)
+
+// pod identifies a simulated pod.
+type pod struct {
+	name      string
+	namespace string
+}
+
+func (p pod) infraContainerID() string {
+	return p.namespace + "-" + p.name
+}
+
+// allocator requests and releases pod IPs the way the CNI does on pod create and delete.
+type allocator interface {
+	allocate(ctx context.Context, p pod) error
+	release(ctx context.Context, p pod) error
+}
+
+// cnsAllocator allocates IPs from a running CNS through its HTTP API.
+type cnsAllocator struct {
+	client *client.Client
+}
+
+func newCNSAllocator(url string, timeout time.Duration) (*cnsAllocator, error) {
+	c, err := client.New(url, timeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create CNS client for %s", url)
+	}
+	return &cnsAllocator{client: c}, nil
+}
+
+func ipConfigsRequest(p pod) (cns.IPConfigsRequest, error) {
+	orchestratorContext, err := json.Marshal(cns.KubernetesPodInfo{PodName: p.name, PodNamespace: p.namespace})
+	if err != nil {
+		return cns.IPConfigsRequest{}, errors.Wrap(err, "failed to marshal pod info")
+	}
+	return cns.IPConfigsRequest{
+		PodInterfaceID:      p.infraContainerID() + "-eth0",
+		InfraContainerID:    p.infraContainerID(),
+		OrchestratorContext: orchestratorContext,
+	}, nil
+}
+
+func (a *cnsAllocator) allocate(ctx context.Context, p pod) error {
+	req, err := ipConfigsRequest(p)
+	if err != nil {
+		return err
+	}
+	_, err = a.client.RequestIPs(ctx, req)
+	return errors.Wrapf(err, "failed to request IPs for pod %s/%s", p.namespace, p.name)
+}
+
+func (a *cnsAllocator) release(ctx context.Context, p pod) error {
+	req, err := ipConfigsRequest(p)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(a.client.ReleaseIPs(ctx, req), "failed to release IPs for pod %s/%s", p.namespace, p.name)
+}
+
+// lockedHTTPService guards the reads of the fake CNS state, which the pool monitor makes concurrently
+// with the allocations of the load generator.
+type lockedHTTPService struct {
+	*fakes.HTTPServiceFake
+}
+
+func (s lockedHTTPService) GetPodIPConfigState() map[string]cns.IPConfigurationStatus {
+	s.IPStateManager.RLock()
+	defer s.IPStateManager.RUnlock()
+	return s.HTTPServiceFake.GetPodIPConfigState()
+}
+
+func (s lockedHTTPService) GetAvailableIPConfigs() []cns.IPConfigurationStatus {
+	s.IPStateManager.RLock()
+	defer s.IPStateManager.RUnlock()
+	return s.HTTPServiceFake.GetAvailableIPConfigs()
+}
+
+func (s lockedHTTPService) GetAssignedIPConfigs() []cns.IPConfigurationStatus {
+	s.IPStateManager.RLock()
+	defer s.IPStateManager.RUnlock()
+	return s.HTTPServiceFake.GetAssignedIPConfigs()
+}
+
+func (s lockedHTTPService) GetPendingReleaseIPConfigs() []cns.IPConfigurationStatus {
+	s.IPStateManager.RLock()
+	defer s.IPStateManager.RUnlock()
+	return s.HTTPServiceFake.GetPendingReleaseIPConfigs()
+}
+
+func (s lockedHTTPService) GetPendingProgramIPConfigs() []cns.IPConfigurationStatus {
+	s.IPStateManager.RLock()
+	defer s.IPStateManager.RUnlock()
+	return s.HTTPServiceFake.GetPendingProgramIPConfigs()
+}
+
+// fakeAllocator allocates IPs from an in-process fake CNS, whose pool is scaled by the real pool monitor.
+// The NodeNetworkConfig is backed by a fake DNC, which fulfils spec updates after the configured latency.
+type fakeAllocator struct {
+	service lockedHTTPService
+	rc      *fakes.RequestControllerFake
+	monitor *ipampool.Monitor
+	latency time.Duration
+
+	// nncMu serializes the updates of the fake NodeNetworkConfig.
+	nncMu sync.Mutex
+
+	podsMu sync.Mutex
+	pods   map[pod]string
+}
+
+type fakeAllocatorConfig struct {
+	scaler       v1alpha.Scaler
+	initialIPs   int64
+	dncLatency   time.Duration
+	refreshDelay time.Duration
+}
+
+func newFakeAllocator(cfg fakeAllocatorConfig) *fakeAllocator {
+	service := lockedHTTPService{fakes.NewHTTPServiceFake()}
+	a := &fakeAllocator{
+		service: service,
+		rc:      fakes.NewRequestControllerFake(service.HTTPServiceFake, cfg.scaler, "10.0.0.0/8", cfg.initialIPs),
+		latency: cfg.dncLatency,
+		pods:    map[pod]string{},
+	}
+	a.monitor = ipampool.NewMonitor(service, a, nil, &ipampool.Options{RefreshDelay: cfg.refreshDelay})
+	service.PoolMonitor = a.monitor
+	return a
+}
+
+// start runs the pool monitor until the context is cancelled.
+func (a *fakeAllocator) start(ctx context.Context) {
+	go func() {
+		_ = a.monitor.Start(ctx)
+	}()
+	a.nncMu.Lock()
+	nnc := a.rc.NNC.DeepCopy()
+	a.nncMu.Unlock()
+	_ = a.monitor.Update(nnc)
+}
+
+// PatchSpec updates the requested spec and fulfils it after the DNC latency, like DNC reconciling the
+// NodeNetworkConfig. It is called by the pool monitor.
+func (a *fakeAllocator) PatchSpec(ctx context.Context, spec *v1alpha.NodeNetworkConfigSpec, _ string) (*v1alpha.NodeNetworkConfig, error) {
+	a.nncMu.Lock()
+	a.rc.NNC.Spec = *spec
+	nnc := a.rc.NNC.DeepCopy()
+	a.nncMu.Unlock()
+
+	time.AfterFunc(a.latency, func() {
+		if ctx.Err() != nil {
+			return
+		}
+		a.nncMu.Lock()
+		spec := a.rc.NNC.Spec
+		if diff := spec.RequestedIPCount - int64(len(a.service.GetPodIPConfigState())); diff > 0 {
+			a.rc.CarveIPConfigsAndAddToStatusAndCNS(diff)
+		}
+		a.service.IPStateManager.RemovePendingReleaseIPConfigs(spec.IPsNotInUse)
+		nnc := a.rc.NNC.DeepCopy()
+		a.nncMu.Unlock()
+		_ = a.monitor.Update(nnc)
+	})
+	return nnc, nil
+}
+
+func (a *fakeAllocator) allocate(_ context.Context, p pod) error {
+	ipconfig, err := a.service.IPStateManager.ReserveIPConfig()
+	if err != nil {
+		return errors.Wrapf(err, "no IP available for pod %s/%s", p.namespace, p.name)
+	}
+	a.podsMu.Lock()
+	defer a.podsMu.Unlock()
+	a.pods[p] = ipconfig.ID
+	return nil
+}
+
+func (a *fakeAllocator) release(_ context.Context, p pod) error {
+	a.podsMu.Lock()
+	id, ok := a.pods[p]
+	delete(a.pods, p)
+	a.podsMu.Unlock()
+	if !ok {
+		return errors.Errorf("pod %s/%s has no IP", p.namespace, p.name)
+	}
+	_, err := a.service.IPStateManager.ReleaseIPConfig(id)
+	return errors.Wrapf(err, "failed to release IP of pod %s/%s", p.namespace, p.name)
+}
+
+// poolSummary describes the current state of the fake IP pool.
+func (a *fakeAllocator) poolSummary() string {
+	a.nncMu.Lock()
+	requested := a.rc.NNC.Spec.RequestedIPCount
+	a.nncMu.Unlock()
+	return fmt.Sprintf("pool: requested %d, assigned %d, available %d, pending release %d", requested,
+		len(a.service.GetAssignedIPConfigs()), len(a.service.GetAvailableIPConfigs()), len(a.service.GetPendingReleaseIPConfigs()))
+}
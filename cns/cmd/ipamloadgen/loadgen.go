@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	opCreate = "create"
+	opDelete = "delete"
+)
+
+type loadConfig struct {
+	// rate is the number of pods created per second. Each pod is deleted after the podLifetime,
+	// so once the first pods are deleted, the same number of pods is deleted per second.
+	rate        int
+	duration    time.Duration
+	podLifetime time.Duration
+	// maxInFlight bounds the number of concurrent requests. Pod creates which would exceed it are skipped
+	// and counted, since they mean the target could not keep up with the rate.
+	maxInFlight int
+	namespace   string
+	// reportInterval is the interval of the progress reports. Progress is not reported if it is zero.
+	reportInterval time.Duration
+}
+
+// opStats are the latencies and errors of one kind of operation.
+type opStats struct {
+	latencies []time.Duration
+	errors    int
+	lastErr   error
+}
+
+func (s *opStats) total() int {
+	return len(s.latencies)
+}
+
+// errorRate is the fraction of failed operations.
+func (s *opStats) errorRate() float64 {
+	if s.total() == 0 {
+		return 0
+	}
+	return float64(s.errors) / float64(s.total())
+}
+
+// percentile returns the latency below which p percent of the operations completed, using the nearest rank.
+func (s *opStats) percentile(p float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(s.latencies))
+	copy(sorted, s.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted)))) //nolint:gomnd // percent
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// stats collects the results of the load generation.
+type stats struct {
+	mu      sync.Mutex
+	ops     map[string]*opStats
+	skipped int
+}
+
+func newStats() *stats {
+	return &stats{ops: map[string]*opStats{opCreate: {}, opDelete: {}}}
+}
+
+func (s *stats) record(op string, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.ops[op]
+	o.latencies = append(o.latencies, latency)
+	if err != nil {
+		o.errors++
+		o.lastErr = err
+	}
+}
+
+func (s *stats) skip() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skipped++
+}
+
+// report writes a table with the latency percentiles and error rates of the operations.
+func (s *stats) report(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:gomnd // padding
+	fmt.Fprintln(tw, "OP\tTOTAL\tERRORS\tERROR RATE\tP50\tP90\tP99\tMAX")
+	for _, op := range []string{opCreate, opDelete} {
+		o := s.ops[op]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", op, o.total(), o.errors,
+			strconv.FormatFloat(o.errorRate()*100, 'f', 2, 64)+"%", //nolint:gomnd // percent
+			o.percentile(50), o.percentile(90), o.percentile(99), o.percentile(100)) //nolint:gomnd // percentiles
+	}
+	tw.Flush()
+	if s.skipped > 0 {
+		fmt.Fprintf(w, "skipped %d pod creates because too many requests were in flight\n", s.skipped)
+	}
+	for _, op := range []string{opCreate, opDelete} {
+		if err := s.ops[op].lastErr; err != nil {
+			fmt.Fprintf(w, "last %s error: %v\n", op, err)
+		}
+	}
+}
+
+// generate creates cfg.rate pods per second for cfg.duration against the allocator and deletes each of them after
+// cfg.podLifetime. Pods which are still running when the duration has passed, or the context is cancelled,
+// are deleted before it returns.
+func generate(ctx context.Context, alloc allocator, cfg loadConfig, progress io.Writer) *stats {
+	st := newStats()
+	inFlight := make(chan struct{}, cfg.maxInFlight)
+	// pods are deleted even if the load generation is interrupted, so that no IPs are leaked.
+	deleteCtx := context.WithoutCancel(ctx)
+
+	create := func(p pod) bool {
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			st.skip()
+			return false
+		}
+		defer func() { <-inFlight }()
+		start := time.Now()
+		err := alloc.allocate(ctx, p)
+		st.record(opCreate, time.Since(start), err)
+		return err == nil
+	}
+	// deletes wait for room in flight, since the pod has to be deleted eventually.
+	remove := func(p pod) {
+		inFlight <- struct{}{}
+		defer func() { <-inFlight }()
+		start := time.Now()
+		err := alloc.release(deleteCtx, p)
+		st.record(opDelete, time.Since(start), err)
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+	var wg sync.WaitGroup
+	deleteNow := make(chan struct{})
+
+	ticker := time.NewTicker(time.Second / time.Duration(cfg.rate))
+	defer ticker.Stop()
+	var reports <-chan time.Time
+	if cfg.reportInterval > 0 {
+		reportTicker := time.NewTicker(cfg.reportInterval)
+		defer reportTicker.Stop()
+		reports = reportTicker.C
+	}
+
+	created := 0
+loop:
+	for {
+		select {
+		case <-loadCtx.Done():
+			break loop
+		case <-reports:
+			fmt.Fprintf(progress, "--- %d pods created\n", created)
+			st.report(progress)
+		case <-ticker.C:
+			p := pod{name: fmt.Sprintf("loadgen-%d", created), namespace: cfg.namespace}
+			created++
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if !create(p) {
+					return
+				}
+				select {
+				case <-time.After(cfg.podLifetime):
+				case <-deleteNow:
+				}
+				remove(p)
+			}()
+		}
+	}
+	close(deleteNow)
+	wg.Wait()
+	return st
+}
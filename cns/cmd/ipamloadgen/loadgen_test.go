@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	o := &opStats{}
+	assert.Equal(t, time.Duration(0), o.percentile(50))
+	for i := 10; i >= 1; i-- {
+		o.latencies = append(o.latencies, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 1*time.Millisecond, o.percentile(0))
+	assert.Equal(t, 5*time.Millisecond, o.percentile(50))
+	assert.Equal(t, 9*time.Millisecond, o.percentile(90))
+	assert.Equal(t, 10*time.Millisecond, o.percentile(99))
+	assert.Equal(t, 10*time.Millisecond, o.percentile(100))
+}
+
+func TestErrorRate(t *testing.T) {
+	st := newStats()
+	assert.Zero(t, st.ops[opCreate].errorRate())
+	st.record(opCreate, time.Millisecond, nil)
+	st.record(opCreate, time.Millisecond, errors.New("no IP"))
+	assert.InDelta(t, 0.5, st.ops[opCreate].errorRate(), 0.001)
+}
+
+func TestGenerateFake(t *testing.T) {
+	logger.InitLogger("testlogs", 0, 0, t.TempDir()+"/")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fake := newFakeAllocator(fakeAllocatorConfig{
+		scaler:       v1alpha.Scaler{BatchSize: 10, RequestThresholdPercent: 50, ReleaseThresholdPercent: 150, MaxIPCount: 250},
+		initialIPs:   10,
+		dncLatency:   10 * time.Millisecond,
+		refreshDelay: 10 * time.Millisecond,
+	})
+	fake.start(ctx)
+
+	st := generate(ctx, fake, loadConfig{
+		rate:        200,
+		duration:    500 * time.Millisecond,
+		podLifetime: 200 * time.Millisecond,
+		maxInFlight: 10,
+		namespace:   "test",
+	}, io.Discard)
+
+	create, del := st.ops[opCreate], st.ops[opDelete]
+	require.NotZero(t, create.total())
+	// every pod which got an IP is deleted before generate returns.
+	assert.Equal(t, create.total()-create.errors, del.total())
+	assert.Zero(t, del.errors)
+	assert.Empty(t, fake.service.GetAssignedIPConfigs())
+	// the pool has been scaled up beyond the initial IPs to fit the pods.
+	assert.Greater(t, len(fake.service.GetPodIPConfigState()), 10)
+}
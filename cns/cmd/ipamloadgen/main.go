@@ -0,0 +1,101 @@
+// ipamloadgen simulates pods being created and deleted at a steady rate against the CNS IPAM, and reports the
+// latency percentiles and error rates of the IP allocations. It is used to validate the tuning of the IP pool
+// monitor (batch size and request/release thresholds) before rolling it out.
+//
+// It targets either a running CNS through its HTTP API, or an in-process fake CNS whose pool is scaled by the
+// real pool monitor against a fake NodeNetworkConfig, which DNC fulfils after a configurable latency:
+//
+//	ipamloadgen -rate 10 -duration 5m -pod-lifetime 30s -cns-url http://localhost:10090
+//	ipamloadgen -rate 10 -duration 5m -pod-lifetime 30s -fake -batch 16 -dnc-latency 10s
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	acnlog "github.com/Azure/azure-container-networking/log"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var (
+		cfg        loadConfig
+		cnsURL     string
+		timeout    time.Duration
+		useFake    bool
+		fakeCfg    fakeAllocatorConfig
+		maxErrRate float64
+		logDir     string
+	)
+	flag.IntVar(&cfg.rate, "rate", 10, "pods created per second")
+	flag.DurationVar(&cfg.duration, "duration", time.Minute, "duration of the load generation")
+	flag.DurationVar(&cfg.podLifetime, "pod-lifetime", 30*time.Second, "time after which each pod is deleted")
+	flag.IntVar(&cfg.maxInFlight, "max-in-flight", 100, "maximum number of concurrent requests")
+	flag.StringVar(&cfg.namespace, "namespace", "loadgen", "namespace of the simulated pods")
+	flag.DurationVar(&cfg.reportInterval, "report-interval", 10*time.Second, "interval of progress reports, 0 to disable")
+	flag.StringVar(&cnsURL, "cns-url", "http://localhost:10090", "URL of the CNS to allocate IPs from")
+	flag.DurationVar(&timeout, "timeout", client.DefaultTimeout, "timeout of the requests to CNS")
+	flag.BoolVar(&useFake, "fake", false, "allocate IPs from an in-process fake CNS instead of a running CNS")
+	flag.Int64Var(&fakeCfg.scaler.BatchSize, "batch", 16, "batch size of the fake pool")
+	flag.Int64Var(&fakeCfg.scaler.RequestThresholdPercent, "request-threshold", 50, "request threshold percent of the fake pool")
+	flag.Int64Var(&fakeCfg.scaler.ReleaseThresholdPercent, "release-threshold", 150, "release threshold percent of the fake pool")
+	flag.Int64Var(&fakeCfg.scaler.MaxIPCount, "max-ips", 250, "maximum number of IPs of the fake pool")
+	flag.Int64Var(&fakeCfg.initialIPs, "initial-ips", 16, "initial number of IPs of the fake pool")
+	flag.DurationVar(&fakeCfg.dncLatency, "dnc-latency", 5*time.Second, "time after which the fake DNC fulfils a pool scaling request")
+	flag.DurationVar(&fakeCfg.refreshDelay, "refresh-delay", time.Second, "refresh delay of the fake pool monitor")
+	flag.Float64Var(&maxErrRate, "max-error-rate", 0, "exit with a non-zero code if the create error rate exceeds this fraction")
+	flag.StringVar(&logDir, "log-dir", os.TempDir(), "directory of the pool monitor log of the fake CNS")
+	flag.Parse()
+
+	if cfg.rate < 1 || cfg.maxInFlight < 1 {
+		fmt.Fprintln(os.Stderr, "-rate and -max-in-flight must be positive")
+		return 2 //nolint:gomnd // usage error
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var (
+		alloc allocator
+		fake  *fakeAllocator
+	)
+	if useFake {
+		logger.InitLogger("ipamloadgen", acnlog.LevelInfo, acnlog.TargetLogfile, logDir+"/")
+		fake = newFakeAllocator(fakeCfg)
+		fake.start(ctx)
+		alloc = fake
+		fmt.Printf("allocating from fake CNS with scaler %+v, DNC latency %s\n", fakeCfg.scaler, fakeCfg.dncLatency)
+	} else {
+		cnsAlloc, err := newCNSAllocator(cnsURL, timeout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		alloc = cnsAlloc
+		fmt.Printf("allocating from CNS at %s\n", cnsURL)
+	}
+
+	fmt.Printf("creating %d pods/s for %s, deleting each after %s\n", cfg.rate, cfg.duration, cfg.podLifetime)
+	st := generate(ctx, alloc, cfg, os.Stdout)
+	fmt.Println("--- results")
+	st.report(os.Stdout)
+	if fake != nil {
+		fmt.Println(fake.poolSummary())
+	}
+
+	if rate := st.ops[opCreate].errorRate(); rate > maxErrRate {
+		fmt.Fprintf(os.Stderr, "create error rate %.4f exceeds %.4f\n", rate, maxErrRate)
+		return 1
+	}
+	return 0
+}
@@ -6,11 +6,15 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/zapai"
 	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
 )
 
 type SWIFTV2Mode string
@@ -26,26 +30,42 @@ const (
 )
 
 type CNSConfig struct {
-	AZRSettings                 AZRSettings
-	AsyncPodDeletePath          string
-	CNIConflistFilepath         string
-	CNIConflistScenario         string
-	ChannelMode                 string
+	AZRSettings         AZRSettings
+	AsyncPodDeletePath  string
+	CNIConflistFilepath string
+	CNIConflistScenario string
+	ChannelMode         string
+	// ControllerMetricsBindAddress is the address the CRD controller manager serves its metrics on.
+	// The metrics are always served by the healthserver on MetricsBindAddress, so it defaults to "0" (disabled).
+	ControllerMetricsBindAddress string
+	// ControllerManagerSettings configures the controller-runtime managers of the CRD controllers.
+	ControllerManagerSettings   ControllerManagerSettings
 	EnableAsyncPodDelete        bool
 	EnableCNIConflistGeneration bool
 	EnableIPAMv2                bool
-	EnablePprof                 bool
-	EnableStateMigration        bool
-	EnableSubnetScarcity        bool
-	EnableSwiftV2               bool
-	InitializeFromCNI           bool
-	KeyVaultSettings            KeyVaultSettings
+	// EnableOverlayExtensionConfig programs the extension IP ranges of the OverlayExtensionConfigs onto the node.
+	EnableOverlayExtensionConfig bool
+	EnablePprof                  bool
+	EnableStateMigration         bool
+	EnableSubnetScarcity         bool
+	EnableSwiftV2                bool
+	InitializeFromCNI            bool
+	// InstallCRDs applies the CRDs embedded in CNS which the enabled features depend on at startup.
+	// CNS must be granted permission to create and update customresourcedefinitions.
+	InstallCRDs      bool
+	KeyVaultSettings KeyVaultSettings
+	// LogLevels sets the level of individual logging components, e.g. {"default": "info", "ipam": "debug"}.
+	LogLevels                   map[string]string
 	MSISettings                 MSISettings
 	ManageEndpointState         bool
 	ManagedSettings             ManagedSettings
 	MellanoxMonitorIntervalSecs int
 	MetricsBindAddress          string
-	ProgramSNATIPTables         bool
+	// MultiTenantNCWebhook configures the MultiTenantNetworkContainer webhooks served by the multitenant operator.
+	MultiTenantNCWebhook WebhookSettings
+	ProgramSNATIPTables  bool
+	// PublishNodeCapabilities publishes the dataplane capabilities of the node in its NodeCapabilities CRD.
+	PublishNodeCapabilities     bool
 	SWIFTV2Mode                 SWIFTV2Mode
 	SyncHostNCTimeoutMs         int
 	SyncHostNCVersionIntervalMs int
@@ -84,6 +104,90 @@ type TelemetrySettings struct {
 	SnapshotIntervalInMins int
 	// AppInsightsInstrumentationKey allows the user to override the default appinsights ikey
 	AppInsightsInstrumentationKey string
+	// IngestionEndpoint overrides the Application Insights endpoint telemetry is sent to, for sovereign and
+	// air-gapped clouds. DisableAll turns off sending altogether.
+	IngestionEndpoint string
+	// Proxy configures the proxy telemetry is sent through. Unset fields are taken from the environment.
+	Proxy aitelemetry.ProxyConfig
+	// ZapAI configures forwarding of the zap logs to Application Insights.
+	ZapAI ZapAISettings
+}
+
+// ZapAISettings configures forwarding of the zap logs to Application Insights, in batches sent in the background.
+type ZapAISettings struct {
+	Enable bool
+	// Level is the minimum severity forwarded, info by default.
+	Level string
+	// QueueSize is the number of logs buffered for sending. Logs are dropped when it is full.
+	QueueSize int
+	// BatchSize is the number of logs handed to the telemetry client at once.
+	BatchSize int
+	// FlushIntervalInSecs is the longest a log is buffered before it is handed to the telemetry client.
+	FlushIntervalInSecs int
+	// EnqueueTimeoutInMs is how long logging waits for room in a full queue before dropping the log, 0 to never wait.
+	EnqueueTimeoutInMs int
+	// Tags maps log field names to Application Insights tags, in addition to the zapai default mappers.
+	Tags map[string]string
+	// Dimensions renames log fields in the custom dimensions, e.g. {"ncID": "NetworkContainerID"}.
+	Dimensions map[string]string
+}
+
+// BatchConfig converts the settings to the zapai configuration, without the telemetry configuration.
+func (s *ZapAISettings) BatchConfig() (*zapai.BatchConfig, error) {
+	level := zapcore.InfoLevel
+	if s.Level != "" {
+		var err error
+		if level, err = zapcore.ParseLevel(s.Level); err != nil {
+			return nil, errors.Wrap(err, "invalid zapai log level")
+		}
+	}
+	tags := make(map[string]string, len(zapai.DefaultMappers)+len(s.Tags))
+	for field, tag := range zapai.DefaultMappers {
+		tags[field] = tag
+	}
+	for field, tag := range s.Tags {
+		tags[field] = tag
+	}
+	return &zapai.BatchConfig{
+		Level:          level,
+		QueueSize:      s.QueueSize,
+		BatchSize:      s.BatchSize,
+		FlushInterval:  time.Duration(s.FlushIntervalInSecs) * time.Second,
+		EnqueueTimeout: time.Duration(s.EnqueueTimeoutInMs) * time.Millisecond,
+		Tags:           tags,
+		Dimensions:     s.Dimensions,
+	}, nil
+}
+
+// ControllerManagerSettings configures the controller-runtime managers which run the CRD controllers
+// and the multitenant operator. Unset durations and client limits use the controller-runtime defaults.
+type ControllerManagerSettings struct {
+	// LeaderElection enables leader election. CNS runs a manager on every Node, so this should only be
+	// enabled when a single active manager is expected.
+	LeaderElection          bool
+	LeaderElectionID        string
+	LeaderElectionNamespace string
+	LeaseDurationSecs       int
+	RenewDeadlineSecs       int
+	RetryPeriodSecs         int
+	// ClientQPS and ClientBurst rate limit the requests the manager makes to the apiserver.
+	ClientQPS   float32
+	ClientBurst int
+	// CacheSyncTimeoutSecs is how long the controllers wait for their caches to sync when starting.
+	CacheSyncTimeoutSecs int
+	// HealthProbeBindAddress is the address the manager serves its healthz and readyz probes on, "0" disables them.
+	HealthProbeBindAddress string
+}
+
+// WebhookSettings configures an admission webhook server.
+type WebhookSettings struct {
+	// Enable starts the webhook server and registers the webhooks with it.
+	Enable bool
+	// Port is the port the webhook server listens on, the controller-runtime default if unset.
+	Port int
+	// CertDir is the directory containing the tls.crt and tls.key served by the webhook server,
+	// the controller-runtime default if unset.
+	CertDir string
 }
 
 type ManagedSettings struct {
@@ -189,6 +293,18 @@ func setAZRSettingsDefaults(azrSettings *AZRSettings) {
 	}
 }
 
+func setControllerManagerSettingsDefaults(cms *ControllerManagerSettings) {
+	if cms.LeaderElectionID == "" {
+		cms.LeaderElectionID = "azure-cns"
+	}
+	if cms.LeaderElectionNamespace == "" {
+		cms.LeaderElectionNamespace = "kube-system"
+	}
+	if cms.HealthProbeBindAddress == "" {
+		cms.HealthProbeBindAddress = "0"
+	}
+}
+
 func setKeyVaultSettingsDefaults(kvs *KeyVaultSettings) {
 	if kvs.RefreshIntervalInHrs == 0 {
 		kvs.RefreshIntervalInHrs = 12 //nolint:gomnd // default times
@@ -201,6 +317,7 @@ func SetCNSConfigDefaults(config *CNSConfig) {
 	setManagedSettingDefaults(&config.ManagedSettings)
 	setKeyVaultSettingsDefaults(&config.KeyVaultSettings)
 	setAZRSettingsDefaults(&config.AZRSettings)
+	setControllerManagerSettingsDefaults(&config.ControllerManagerSettings)
 
 	if config.ChannelMode == "" {
 		config.ChannelMode = cns.Direct
@@ -208,6 +325,9 @@ func SetCNSConfigDefaults(config *CNSConfig) {
 	if config.MetricsBindAddress == "" {
 		config.MetricsBindAddress = ":9090"
 	}
+	if config.ControllerMetricsBindAddress == "" {
+		config.ControllerMetricsBindAddress = "0"
+	}
 	if config.SyncHostNCVersionIntervalMs == 0 {
 		config.SyncHostNCVersionIntervalMs = 1000 //nolint:gomnd // default times
 	}
@@ -4,10 +4,13 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/zapai"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestGetConfigFilePath(t *testing.T) {
@@ -150,6 +153,35 @@ func TestSetTelemetrySettingDefaults(t *testing.T) {
 	}
 }
 
+func TestZapAISettingsBatchConfig(t *testing.T) {
+	settings := ZapAISettings{
+		Level:               "warn",
+		QueueSize:           10,
+		FlushIntervalInSecs: 2,
+		EnqueueTimeoutInMs:  50,
+		Tags:                map[string]string{"version": "ai.custom.ver", "node": "ai.cloud.roleInstance"},
+		Dimensions:          map[string]string{"ncID": "NetworkContainerID"},
+	}
+	batchConfig, err := settings.BatchConfig()
+	require.NoError(t, err)
+	assert.Equal(t, zapcore.WarnLevel, batchConfig.Level)
+	assert.Equal(t, 10, batchConfig.QueueSize)
+	assert.Equal(t, 2*time.Second, batchConfig.FlushInterval)
+	assert.Equal(t, 50*time.Millisecond, batchConfig.EnqueueTimeout)
+	// the configured tags are added to, and override, the default mappers
+	assert.Equal(t, "ai.custom.ver", batchConfig.Tags["version"])
+	assert.Equal(t, "ai.cloud.roleInstance", batchConfig.Tags["node"])
+	assert.Equal(t, zapai.DefaultMappers["operation_id"], batchConfig.Tags["operation_id"])
+	assert.Equal(t, settings.Dimensions, batchConfig.Dimensions)
+
+	batchConfig, err = (&ZapAISettings{}).BatchConfig()
+	require.NoError(t, err)
+	assert.Equal(t, zapcore.InfoLevel, batchConfig.Level)
+
+	_, err = (&ZapAISettings{Level: "loud"}).BatchConfig()
+	require.Error(t, err)
+}
+
 func Test_setManagedSettingDefaults(t *testing.T) {
 	tests := []struct {
 		name string
@@ -192,7 +224,13 @@ func TestSetCNSConfigDefaults(t *testing.T) {
 			name: "unset defaults",
 			in:   CNSConfig{},
 			want: CNSConfig{
-				ChannelMode: "Direct",
+				ChannelMode:                  "Direct",
+				ControllerMetricsBindAddress: "0",
+				ControllerManagerSettings: ControllerManagerSettings{
+					LeaderElectionID:        "azure-cns",
+					LeaderElectionNamespace: "kube-system",
+					HealthProbeBindAddress:  "0",
+				},
 				ManagedSettings: ManagedSettings{
 					NodeSyncIntervalInSeconds: 30,
 				},
@@ -219,7 +257,13 @@ func TestSetCNSConfigDefaults(t *testing.T) {
 		{
 			name: "don't overwrite set values",
 			in: CNSConfig{
-				ChannelMode: "Other",
+				ChannelMode:                  "Other",
+				ControllerMetricsBindAddress: ":9092",
+				ControllerManagerSettings: ControllerManagerSettings{
+					LeaderElectionID:        "cns-leader",
+					LeaderElectionNamespace: "default",
+					HealthProbeBindAddress:  ":8081",
+				},
 				ManagedSettings: ManagedSettings{
 					NodeSyncIntervalInSeconds: 1,
 				},
@@ -241,7 +285,13 @@ func TestSetCNSConfigDefaults(t *testing.T) {
 				},
 			},
 			want: CNSConfig{
-				ChannelMode: "Other",
+				ChannelMode:                  "Other",
+				ControllerMetricsBindAddress: ":9092",
+				ControllerManagerSettings: ControllerManagerSettings{
+					LeaderElectionID:        "cns-leader",
+					LeaderElectionNamespace: "default",
+					HealthProbeBindAddress:  ":8081",
+				},
 				ManagedSettings: ManagedSettings{
 					NodeSyncIntervalInSeconds: 1,
 				},
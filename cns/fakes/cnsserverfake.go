@@ -0,0 +1,235 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package fakes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+)
+
+// CNSServerFakeConfig is the network returned with every IP allocated by the CNSServerFake.
+type CNSServerFakeConfig struct {
+	// NCPrimaryIP and PrefixLength describe the NC subnet the pod IPs are allocated from.
+	NCPrimaryIP   string
+	PrefixLength  uint8
+	GatewayIP     string
+	HostPrimaryIP string
+	HostSubnet    string
+	HostGateway   string
+}
+
+// DefaultCNSServerFakeConfig returns a config for a 10.240.0.0/16 NC subnet on a node in 10.224.0.0/16.
+func DefaultCNSServerFakeConfig() CNSServerFakeConfig {
+	return CNSServerFakeConfig{
+		NCPrimaryIP:   "10.240.0.4",
+		PrefixLength:  16,
+		GatewayIP:     "10.240.0.1",
+		HostPrimaryIP: "10.224.0.4",
+		HostSubnet:    "10.224.0.0/16",
+		HostGateway:   "10.224.0.1",
+	}
+}
+
+// CNSServerFake is an httptest server serving the CNS IPAM APIs called by the CNI plugins, backed by a pool of IPs
+// which tests can program. It allows running the CNI plugins end to end without a CNS.
+// Pods are identified by their PodInterfaceID, and requesting an IP again for the same pod returns the same IP.
+type CNSServerFake struct {
+	*httptest.Server
+
+	sync.Mutex
+	cfg         CNSServerFakeConfig
+	available   []string
+	assigned    map[string]string
+	failures    map[string][]types.ResponseCode
+	unsupported map[string]bool
+	requests    map[string]int
+}
+
+// NewCNSServerFake starts a CNSServerFake with the IPs available for allocation. It has to be closed with Close.
+func NewCNSServerFake(cfg CNSServerFakeConfig, ips ...string) *CNSServerFake {
+	fake := &CNSServerFake{
+		cfg:         cfg,
+		available:   append([]string{}, ips...),
+		assigned:    map[string]string{},
+		failures:    map[string][]types.ResponseCode{},
+		unsupported: map[string]bool{},
+		requests:    map[string]int{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(cns.RequestIPConfigs, fake.serve(cns.RequestIPConfigs, fake.requestIPConfigs,
+		func(resp cns.Response) interface{} { return cns.IPConfigsResponse{Response: resp} }))
+	mux.HandleFunc(cns.ReleaseIPConfigs, fake.serve(cns.ReleaseIPConfigs, fake.releaseIPConfigs, responseOf))
+	mux.HandleFunc(cns.RequestIPConfig, fake.serve(cns.RequestIPConfig, fake.requestIPConfig,
+		func(resp cns.Response) interface{} { return cns.IPConfigResponse{Response: resp} }))
+	mux.HandleFunc(cns.ReleaseIPConfig, fake.serve(cns.ReleaseIPConfig, fake.releaseIPConfig, responseOf))
+	fake.Server = httptest.NewServer(mux)
+	return fake
+}
+
+// AddIPs makes the IPs available for allocation.
+func (fake *CNSServerFake) AddIPs(ips ...string) {
+	fake.Lock()
+	defer fake.Unlock()
+	fake.available = append(fake.available, ips...)
+}
+
+// SetAvailableIPs replaces the IPs available for allocation. The assigned IPs are not changed.
+func (fake *CNSServerFake) SetAvailableIPs(ips ...string) {
+	fake.Lock()
+	defer fake.Unlock()
+	fake.available = append([]string{}, ips...)
+}
+
+// AvailableIPs returns the IPs available for allocation.
+func (fake *CNSServerFake) AvailableIPs() []string {
+	fake.Lock()
+	defer fake.Unlock()
+	return append([]string{}, fake.available...)
+}
+
+// AssignedIPs returns the assigned IPs by PodInterfaceID.
+func (fake *CNSServerFake) AssignedIPs() map[string]string {
+	fake.Lock()
+	defer fake.Unlock()
+	assigned := make(map[string]string, len(fake.assigned))
+	for podInterfaceID, ip := range fake.assigned {
+		assigned[podInterfaceID] = ip
+	}
+	return assigned
+}
+
+// FailNext makes the next request to the API path fail with the response code.
+// Calling it multiple times fails as many requests.
+func (fake *CNSServerFake) FailNext(path string, code types.ResponseCode) {
+	fake.Lock()
+	defer fake.Unlock()
+	fake.failures[path] = append(fake.failures[path], code)
+}
+
+// SetUnsupported makes the API path return 404, like a CNS which does not support the API yet.
+func (fake *CNSServerFake) SetUnsupported(path string, unsupported bool) {
+	fake.Lock()
+	defer fake.Unlock()
+	fake.unsupported[path] = unsupported
+}
+
+// RequestCount returns the number of requests made to the API path.
+func (fake *CNSServerFake) RequestCount(path string) int {
+	fake.Lock()
+	defer fake.Unlock()
+	return fake.requests[path]
+}
+
+func responseOf(resp cns.Response) interface{} {
+	return resp
+}
+
+// serve wraps an API handler with the request counting and the injected failures. The handler is called
+// with the lock held and returns the response to encode. failure wraps an injected failure in the response of the API.
+func (fake *CNSServerFake) serve(path string, handler func(*http.Request) (interface{}, error),
+	failure func(cns.Response) interface{},
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fake.Lock()
+		defer fake.Unlock()
+		fake.requests[path]++
+		if fake.unsupported[path] {
+			http.NotFound(w, r)
+			return
+		}
+		if failures := fake.failures[path]; len(failures) > 0 {
+			fake.failures[path] = failures[1:]
+			writeJSON(w, failure(cns.Response{ReturnCode: failures[0], Message: "injected failure"}))
+			return
+		}
+		resp, err := handler(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, resp)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// allocate returns the IP assigned to the pod, assigning one if it has none yet.
+func (fake *CNSServerFake) allocate(podInterfaceID string) (cns.PodIpInfo, cns.Response) {
+	ip, ok := fake.assigned[podInterfaceID]
+	if !ok {
+		if len(fake.available) == 0 {
+			return cns.PodIpInfo{}, cns.Response{ReturnCode: types.FailedToAllocateIPConfig, Message: "no IPs available"}
+		}
+		ip, fake.available = fake.available[0], fake.available[1:]
+		fake.assigned[podInterfaceID] = ip
+	}
+	return cns.PodIpInfo{
+		PodIPConfig: cns.IPSubnet{IPAddress: ip, PrefixLength: fake.cfg.PrefixLength},
+		NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+			IPSubnet:         cns.IPSubnet{IPAddress: fake.cfg.NCPrimaryIP, PrefixLength: fake.cfg.PrefixLength},
+			GatewayIPAddress: fake.cfg.GatewayIP,
+		},
+		HostPrimaryIPInfo: cns.HostIPInfo{
+			Gateway:   fake.cfg.HostGateway,
+			PrimaryIP: fake.cfg.HostPrimaryIP,
+			Subnet:    fake.cfg.HostSubnet,
+		},
+		NICType: cns.InfraNIC,
+	}, cns.Response{ReturnCode: types.Success}
+}
+
+// release returns the IP assigned to the pod to the pool. Releasing a pod without an IP succeeds, like in CNS.
+func (fake *CNSServerFake) release(podInterfaceID string) {
+	if ip, ok := fake.assigned[podInterfaceID]; ok {
+		delete(fake.assigned, podInterfaceID)
+		fake.available = append(fake.available, ip)
+	}
+}
+
+func (fake *CNSServerFake) requestIPConfigs(r *http.Request) (interface{}, error) {
+	var req cns.IPConfigsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err //nolint:wrapcheck // returned as the http error
+	}
+	info, resp := fake.allocate(req.PodInterfaceID)
+	if resp.ReturnCode != types.Success {
+		return cns.IPConfigsResponse{Response: resp}, nil
+	}
+	return cns.IPConfigsResponse{PodIPInfo: []cns.PodIpInfo{info}, Response: resp}, nil
+}
+
+func (fake *CNSServerFake) releaseIPConfigs(r *http.Request) (interface{}, error) {
+	var req cns.IPConfigsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err //nolint:wrapcheck // returned as the http error
+	}
+	fake.release(req.PodInterfaceID)
+	return cns.Response{ReturnCode: types.Success}, nil
+}
+
+func (fake *CNSServerFake) requestIPConfig(r *http.Request) (interface{}, error) {
+	var req cns.IPConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err //nolint:wrapcheck // returned as the http error
+	}
+	info, resp := fake.allocate(req.PodInterfaceID)
+	return cns.IPConfigResponse{PodIpInfo: info, Response: resp}, nil
+}
+
+func (fake *CNSServerFake) releaseIPConfig(r *http.Request) (interface{}, error) {
+	var req cns.IPConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err //nolint:wrapcheck // returned as the http error
+	}
+	fake.release(req.PodInterfaceID)
+	return cns.Response{ReturnCode: types.Success}, nil
+}
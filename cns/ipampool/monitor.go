@@ -103,8 +103,8 @@ func (pm *Monitor) Start(ctx context.Context) error {
 				// if we have initialized and enter this case, we proceed out of the select and continue to reconcile.
 			}
 		case css := <-pm.cssSource: // received an updated ClusterSubnetState
-			pm.metastate.exhausted = css.Status.Exhausted
-			logger.Printf("subnet exhausted status = %t", pm.metastate.exhausted)
+			pm.metastate.exhausted = css.IsExhausted()
+			logger.Printf("subnet exhausted status = %t, override = %q", pm.metastate.exhausted, css.Spec.ExhaustionOverride)
 			IpamSubnetExhaustionCount.With(prometheus.Labels{
 				subnetLabel: pm.metastate.subnet, subnetCIDRLabel: pm.metastate.subnetCIDR,
 				podnetARMIDLabel: pm.metastate.subnetARMID, subnetExhaustionStateLabel: strconv.FormatBool(pm.metastate.exhausted),
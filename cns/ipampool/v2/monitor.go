@@ -26,6 +26,7 @@ type nodeNetworkConfigSpecUpdater interface {
 type ipStateStore interface {
 	GetPendingReleaseIPConfigs() []cns.IPConfigurationStatus
 	MarkNIPsPendingRelease(n int) (map[string]cns.IPConfigurationStatus, error)
+	MarkNIPsPendingReleaseForFamily(n int, family v1alpha.IPFamily) (map[string]cns.IPConfigurationStatus, error)
 }
 
 type scaler struct {
@@ -36,17 +37,19 @@ type scaler struct {
 }
 
 type Monitor struct {
-	z            *zap.Logger
-	scaler       scaler
-	nnccli       nodeNetworkConfigSpecUpdater
-	store        ipStateStore
-	demand       int64
-	request      int64
-	demandSource <-chan int
-	cssSource    <-chan v1alpha1.ClusterSubnetState
-	nncSource    <-chan v1alpha.NodeNetworkConfig
-	started      chan interface{}
-	once         sync.Once
+	z               *zap.Logger
+	scaler          scaler
+	nnccli          nodeNetworkConfigSpecUpdater
+	store           ipStateStore
+	demand          int64
+	request         int64
+	families        []v1alpha.IPFamily
+	requestByFamily v1alpha.IPFamilyCounts
+	demandSource    <-chan int
+	cssSource       <-chan v1alpha1.ClusterSubnetState
+	nncSource       <-chan v1alpha.NodeNetworkConfig
+	started         chan interface{}
+	once            sync.Once
 }
 
 func NewMonitor(z *zap.Logger, store ipStateStore, nnccli nodeNetworkConfigSpecUpdater, demandSource <-chan int, nncSource <-chan v1alpha.NodeNetworkConfig, cssSource <-chan v1alpha1.ClusterSubnetState) *Monitor { //nolint:lll // it's fine
@@ -75,18 +78,22 @@ func (pm *Monitor) Start(ctx context.Context) error {
 			pm.demand = int64(demand)
 			pm.z.Info("demand update", zap.Int64("demand", pm.demand))
 		case css := <-pm.cssSource: // received an updated ClusterSubnetState, recalculate request
-			pm.scaler.exhausted = css.Status.Exhausted
-			pm.z.Info("exhaustion update", zap.Bool("exhausted", pm.scaler.exhausted))
+			pm.scaler.exhausted = css.IsExhausted()
+			pm.z.Info("exhaustion update", zap.Bool("exhausted", pm.scaler.exhausted), zap.String("override", string(css.Spec.ExhaustionOverride)))
 		case nnc := <-pm.nncSource: // received a new NodeNetworkConfig, extract the data from it and recalculate request
 			pm.scaler.max = int64(math.Min(float64(nnc.Status.Scaler.MaxIPCount), DefaultMaxIPs))
 			pm.scaler.batch = int64(math.Min(math.Max(float64(nnc.Status.Scaler.BatchSize), 1), float64(pm.scaler.max)))
 			pm.scaler.buffer = math.Abs(float64(nnc.Status.Scaler.RequestThresholdPercent)) / 100 //nolint:gomnd // it's a percentage
+			pm.families = nnc.IPFamilies()
 			pm.once.Do(func() {
 				pm.request = nnc.Spec.RequestedIPCount
+				if nnc.Spec.RequestedIPCountByFamily != nil {
+					pm.requestByFamily = *nnc.Spec.RequestedIPCountByFamily
+				}
 				close(pm.started) // close the init channel the first time we fully receive a NodeNetworkConfig.
 				pm.z.Debug("started", zap.Int64("initial request", pm.request))
 			})
-			pm.z.Info("scaler update", zap.Int64("batch", pm.scaler.batch), zap.Float64("buffer", pm.scaler.buffer), zap.Int64("max", pm.scaler.max), zap.Int64("request", pm.request))
+			pm.z.Info("scaler update", zap.Int64("batch", pm.scaler.batch), zap.Float64("buffer", pm.scaler.buffer), zap.Int64("max", pm.scaler.max), zap.Int64("request", pm.request), zap.Any("families", pm.families))
 		}
 		select {
 		case <-pm.started: // this blocks until we have initialized
@@ -111,6 +118,10 @@ func (pm *Monitor) reconcile(ctx context.Context) error {
 		s.buffer = 1
 	}
 
+	if len(pm.families) > 1 {
+		return pm.reconcileDualStack(ctx, s)
+	}
+
 	// calculate the target state from the current pool state and scaler
 	target := calculateTargetIPCountOrMax(pm.demand, s.batch, s.max, s.buffer)
 	pm.z.Info("calculated new request", zap.Int64("demand", pm.demand), zap.Int64("batch", s.batch), zap.Int64("max", s.max), zap.Float64("buffer", s.buffer), zap.Int64("target", target))
@@ -132,6 +143,41 @@ func (pm *Monitor) reconcile(ctx context.Context) error {
 	return nil
 }
 
+// reconcileDualStack scales the pool of each IP family independently.
+// Every Pod on a dual-stack Node is assigned one IP of each family, so the demand is the same for
+// every family, but the current request and the IPs to release are tracked per family.
+func (pm *Monitor) reconcileDualStack(ctx context.Context, s scaler) error {
+	target := v1alpha.IPFamilyCounts{}
+	var changed bool
+	for _, family := range pm.families {
+		familyTarget := calculateTargetIPCountOrMax(pm.demand, s.batch, s.max, s.buffer)
+		target.Set(family, familyTarget)
+		delta := familyTarget - pm.requestByFamily.Get(family)
+		pm.z.Info("calculated new request", zap.String("family", string(family)), zap.Int64("demand", pm.demand), zap.Int64("batch", s.batch), zap.Int64("max", s.max), zap.Float64("buffer", s.buffer), zap.Int64("target", familyTarget)) //nolint:lll // it's fine
+		if delta == 0 {
+			continue
+		}
+		changed = true
+		pm.z.Info("scaling pool", zap.String("family", string(family)), zap.Int64("delta", delta))
+		// try to release -delta IPs of this family. this is no-op if delta is negative.
+		if _, err := pm.store.MarkNIPsPendingReleaseForFamily(int(-delta), family); err != nil {
+			return errors.Wrapf(err, "failed to mark sufficient %s IPs as PendingRelease, wanted %d", family, -delta)
+		}
+	}
+	if !changed {
+		return nil
+	}
+	spec := pm.buildNNCSpec(target.Total())
+	spec.RequestedIPCountByFamily = &target
+	if _, err := pm.nnccli.PatchSpec(ctx, &spec, fieldManager); err != nil {
+		return errors.Wrap(err, "failed to UpdateSpec with NNC client")
+	}
+	pm.request = target.Total()
+	pm.requestByFamily = target
+	pm.z.Info("scaled pool", zap.Int64("request", pm.request), zap.Int64("ipv4", target.IPv4), zap.Int64("ipv6", target.IPv6))
+	return nil
+}
+
 // buildNNCSpec translates CNS's map of IPs to be released and requested IP count into an NNC Spec.
 func (pm *Monitor) buildNNCSpec(request int64) v1alpha.NodeNetworkConfigSpec {
 	// Get All Pending IPs from CNS and populate it again.
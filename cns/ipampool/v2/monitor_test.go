@@ -36,6 +36,10 @@ func (m *ipStateStoreMock) MarkNIPsPendingRelease(n int) (map[string]cns.IPConfi
 	return m.pendingReleaseIPConfigs, nil
 }
 
+func (m *ipStateStoreMock) MarkNIPsPendingReleaseForFamily(n int, _ v1alpha.IPFamily) (map[string]cns.IPConfigurationStatus, error) {
+	return m.MarkNIPsPendingRelease(n)
+}
+
 // pendingReleaseGenerator generates a variable number of random pendingRelease IPConfigs.
 func pendingReleaseGenerator(n int) map[string]cns.IPConfigurationStatus {
 	m := make(map[string]cns.IPConfigurationStatus, n)
@@ -356,3 +360,75 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileDualStack(t *testing.T) {
+	tests := []struct {
+		name                string
+		demand              int64
+		requestByFamily     v1alpha.IPFamilyCounts
+		wantRequestByFamily v1alpha.IPFamilyCounts
+		wantPendingRelease  int
+		wantPatched         bool
+	}{
+		{
+			name:                "no delta",
+			demand:              5,
+			requestByFamily:     v1alpha.IPFamilyCounts{IPv4: 16, IPv6: 16},
+			wantRequestByFamily: v1alpha.IPFamilyCounts{IPv4: 16, IPv6: 16},
+		},
+		{
+			name:                "scale up both families",
+			demand:              20,
+			requestByFamily:     v1alpha.IPFamilyCounts{IPv4: 16, IPv6: 16},
+			wantRequestByFamily: v1alpha.IPFamilyCounts{IPv4: 32, IPv6: 32},
+			wantPatched:         true,
+		},
+		{
+			name:                "scale up only v6",
+			demand:              20,
+			requestByFamily:     v1alpha.IPFamilyCounts{IPv4: 32, IPv6: 16},
+			wantRequestByFamily: v1alpha.IPFamilyCounts{IPv4: 32, IPv6: 32},
+			wantPatched:         true,
+		},
+		{
+			name:                "scale down only v4",
+			demand:              5,
+			requestByFamily:     v1alpha.IPFamilyCounts{IPv4: 48, IPv6: 16},
+			wantRequestByFamily: v1alpha.IPFamilyCounts{IPv4: 16, IPv6: 16},
+			wantPendingRelease:  32,
+			wantPatched:         true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			nnccli := &nncClientMock{}
+			pm := &Monitor{
+				z:               zap.NewNop(),
+				demand:          tt.demand,
+				request:         tt.requestByFamily.Total(),
+				families:        []v1alpha.IPFamily{v1alpha.IPv4, v1alpha.IPv6},
+				requestByFamily: tt.requestByFamily,
+				scaler: scaler{
+					batch:  16,
+					buffer: .5,
+					max:    250,
+				},
+				nnccli: nnccli,
+				store:  &ipStateStoreMock{},
+			}
+			require.NoError(t, pm.reconcile(context.Background()))
+			assert.Equal(t, tt.wantRequestByFamily, pm.requestByFamily)
+			assert.Equal(t, tt.wantRequestByFamily.Total(), pm.request)
+			if !tt.wantPatched {
+				assert.Nil(t, nnccli.req.RequestedIPCountByFamily)
+				return
+			}
+			require.NotNil(t, nnccli.req.RequestedIPCountByFamily)
+			assert.Equal(t, tt.wantRequestByFamily, *nnccli.req.RequestedIPCountByFamily)
+			assert.Equal(t, tt.wantRequestByFamily.Total(), nnccli.req.RequestedIPCount)
+			assert.Len(t, nnccli.req.IPsNotInUse, tt.wantPendingRelease)
+		})
+	}
+}
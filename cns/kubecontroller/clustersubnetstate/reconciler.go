@@ -2,12 +2,17 @@ package clustersubnetstate
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
+	"github.com/Azure/azure-container-networking/cns/metric"
 	"github.com/Azure/azure-container-networking/crd/clustersubnetstate"
 	"github.com/Azure/azure-container-networking/crd/clustersubnetstate/api/v1alpha1"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -16,14 +21,38 @@ type cssClient interface {
 	Get(context.Context, types.NamespacedName) (*v1alpha1.ClusterSubnetState, error)
 }
 
+// exhaustionSetter is told whether the subnet is exhausted, so that IP requests which can't be served fail fast.
+type exhaustionSetter interface {
+	SetSubnetExhausted(bool)
+}
+
+// Event reasons recorded on the Node when a subnet transitions to or from exhaustion.
+const (
+	ReasonSubnetExhausted = "SubnetExhausted"
+	ReasonSubnetAvailable = "SubnetAvailable"
+)
+
 type Reconciler struct {
-	cli  cssClient
-	sink chan<- v1alpha1.ClusterSubnetState
+	cli      cssClient
+	sink     chan<- v1alpha1.ClusterSubnetState
+	setter   exhaustionSetter
+	recorder record.EventRecorder
+	node     *corev1.Node
+
+	sync.Mutex
+	exhausted map[types.NamespacedName]bool
 }
 
-func New(sink chan<- v1alpha1.ClusterSubnetState) *Reconciler {
+// New creates a ClusterSubnetState Reconciler which publishes ClusterSubnetStates to the sink, sets whether the
+// subnet is exhausted on the setter, and records an Event on the Node each time a subnet transitions to or from
+// exhaustion.
+func New(sink chan<- v1alpha1.ClusterSubnetState, setter exhaustionSetter, recorder record.EventRecorder, node *corev1.Node) *Reconciler {
 	return &Reconciler{
-		sink: sink,
+		sink:      sink,
+		setter:    setter,
+		recorder:  recorder,
+		node:      node,
+		exhausted: map[types.NamespacedName]bool{},
 	}
 }
 
@@ -34,14 +63,34 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 		return reconcile.Result{}, errors.Wrapf(err, "failed to get css %s", req.String())
 	}
 	cssReconcilerErrorCount.With(prometheus.Labels{cssReconcilerCRDWatcherStateLabel: "succeeded"}).Inc()
+	r.recordTransition(req.NamespacedName, css)
+	r.setter.SetSubnetExhausted(css.IsExhausted())
 	r.sink <- *css
 	return reconcile.Result{}, nil
 }
 
+// recordTransition records an Event on the Node if the exhaustion state of the subnet has changed
+// since it was last observed. A subnet which is available when first observed is not reported.
+func (r *Reconciler) recordTransition(key types.NamespacedName, css *v1alpha1.ClusterSubnetState) {
+	r.Lock()
+	defer r.Unlock()
+	exhausted := css.IsExhausted()
+	last, seen := r.exhausted[key]
+	r.exhausted[key] = exhausted
+	if (seen && exhausted == last) || (!seen && !exhausted) {
+		return
+	}
+	if exhausted {
+		r.recorder.Event(r.node, corev1.EventTypeWarning, ReasonSubnetExhausted, fmt.Sprintf("Subnet %s is exhausted, IPs will not be scaled up", css.Name))
+		return
+	}
+	r.recorder.Event(r.node, corev1.EventTypeNormal, ReasonSubnetAvailable, fmt.Sprintf("Subnet %s is no longer exhausted", css.Name))
+}
+
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.cli = clustersubnetstate.NewClient(mgr.GetClient())
 	err := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.ClusterSubnetState{}).
-		Complete(r)
+		Complete(metric.InstrumentReconciler("clustersubnetstate", r))
 	return errors.Wrap(err, "failed to setup clustersubnetstate reconciler with manager")
 }
@@ -0,0 +1,69 @@
+package clustersubnetstate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/crd/clustersubnetstate/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type mockCSSClient struct {
+	css *v1alpha1.ClusterSubnetState
+}
+
+func (m *mockCSSClient) Get(context.Context, types.NamespacedName) (*v1alpha1.ClusterSubnetState, error) {
+	return m.css.DeepCopy(), nil
+}
+
+type mockExhaustionSetter struct {
+	exhausted bool
+}
+
+func (m *mockExhaustionSetter) SetSubnetExhausted(exhausted bool) {
+	m.exhausted = exhausted
+}
+
+func TestReconcileRecordsExhaustionTransitions(t *testing.T) {
+	css := &v1alpha1.ClusterSubnetState{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "subnet"}}
+	sink := make(chan v1alpha1.ClusterSubnetState, 10)
+	recorder := record.NewFakeRecorder(10)
+	setter := &mockExhaustionSetter{}
+	r := New(sink, setter, recorder, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}})
+	r.cli = &mockCSSClient{css: css}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "kube-system", Name: "subnet"}}
+
+	tests := []struct {
+		name      string
+		exhausted bool
+		override  v1alpha1.ExhaustionOverride
+		wantEvent string
+	}{
+		{name: "initially available"},
+		{name: "exhausted", exhausted: true, wantEvent: "Warning SubnetExhausted Subnet subnet is exhausted, IPs will not be scaled up"},
+		{name: "still exhausted", exhausted: true},
+		{name: "overridden available", exhausted: true, override: v1alpha1.OverrideAvailable, wantEvent: "Normal SubnetAvailable Subnet subnet is no longer exhausted"},
+		{name: "available"},
+	}
+	for _, tt := range tests {
+		css.Status.Exhausted = tt.exhausted
+		css.Spec.ExhaustionOverride = tt.override
+		_, err := r.Reconcile(context.Background(), req)
+		require.NoError(t, err, tt.name)
+		got := <-sink
+		assert.Equal(t, tt.exhausted && tt.override == "", got.IsExhausted(), tt.name)
+		assert.Equal(t, got.IsExhausted(), setter.exhausted, tt.name)
+		select {
+		case event := <-recorder.Events:
+			assert.Equal(t, tt.wantEvent, event, tt.name)
+		default:
+			assert.Empty(t, tt.wantEvent, tt.name)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package multitenantpodnetworkconfig
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ErrNICReserved is returned when the delegated NIC of a pod is reserved for another pod on the node.
+var ErrNICReserved = errors.New("delegated nic is reserved for another pod")
+
+// AssignmentState is the progress of the delegated NIC assignment of a SwiftV2 pod.
+type AssignmentState string
+
+const (
+	// AssignmentPending indicates the control plane has not finished assigning the delegated NIC.
+	AssignmentPending AssignmentState = "Pending"
+	// AssignmentReady indicates the delegated NIC is assigned and published in the MTPNC.
+	AssignmentReady AssignmentState = "Ready"
+	// AssignmentFailed indicates the delegated NIC cannot be assigned until the PodNetwork or
+	// PodNetworkInstance is fixed.
+	AssignmentFailed AssignmentState = "Failed"
+)
+
+// Reasons reported along with the AssignmentState.
+const (
+	ReasonAssigned                   = "DelegatedNICAssigned"
+	ReasonWaitingForNIC              = "WaitingForNICAssignment"
+	ReasonPodNetworkNotFound         = "PodNetworkNotFound"
+	ReasonPodNetworkNotReady         = "PodNetworkNotReady"
+	ReasonPodNetworkInstanceNotFound = "PodNetworkInstanceNotFound"
+	ReasonPodNetworkInstanceNotReady = "PodNetworkInstanceNotReady"
+	ReasonNICReserved                = "DelegatedNICReserved"
+)
+
+// Assignment is the delegated NIC assignment progress of a pod, with the reason it is not Ready.
+type Assignment struct {
+	State   AssignmentState
+	Reason  string
+	Message string
+}
+
+func (a Assignment) String() string {
+	return fmt.Sprintf("%s (%s): %s", a.State, a.Reason, a.Message)
+}
+
+// AssignmentStore holds the latest Assignment of each SwiftV2 pod on the node, keyed by the
+// name of the pod, which is also the name of its MTPNC, and the delegated NIC reserved for it.
+//
+// A delegated NIC is reserved for a pod, by the MTPNC Reconciler or by the CNS IPAM path when it
+// hands the NIC to the pod, whichever sees the NIC first, and stays reserved until the pod is gone.
+// Until then, the NIC is not handed to another pod, even if the control plane publishes it in the
+// MTPNC of another pod, as the NIC can only be moved into the network namespace of one pod.
+type AssignmentStore struct {
+	sync.RWMutex
+	assignments map[types.NamespacedName]Assignment
+	// nics maps the MAC address of each reserved NIC to the pod it is reserved for.
+	nics map[string]types.NamespacedName
+}
+
+func NewAssignmentStore() *AssignmentStore {
+	return &AssignmentStore{
+		assignments: map[types.NamespacedName]Assignment{},
+		nics:        map[string]types.NamespacedName{},
+	}
+}
+
+// Reserve reserves the delegated NIC with the MAC address for the pod, releasing any other NIC
+// reserved for it. It returns ErrNICReserved if the NIC is reserved for another pod.
+func (s *AssignmentStore) Reserve(key types.NamespacedName, mac string) error {
+	mac = strings.ToLower(mac)
+	s.Lock()
+	defer s.Unlock()
+	if owner, ok := s.nics[mac]; ok {
+		if owner != key {
+			return errors.Wrapf(ErrNICReserved, "nic %s is reserved for pod %s", mac, owner)
+		}
+		return nil
+	}
+	s.release(key)
+	s.nics[mac] = key
+	return nil
+}
+
+// release releases the NIC reserved for the pod, if any.
+func (s *AssignmentStore) release(key types.NamespacedName) {
+	for mac, owner := range s.nics {
+		if owner == key {
+			delete(s.nics, mac)
+		}
+	}
+}
+
+// Get returns the Assignment of the pod, if it is known.
+func (s *AssignmentStore) Get(key types.NamespacedName) (Assignment, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	a, ok := s.assignments[key]
+	return a, ok
+}
+
+// Describe returns a description of the Assignment of the pod, if it is known.
+func (s *AssignmentStore) Describe(key types.NamespacedName) (string, bool) {
+	a, ok := s.Get(key)
+	if !ok {
+		return "", false
+	}
+	return a.String(), true
+}
+
+// set stores the Assignment of the pod and returns whether it changed.
+func (s *AssignmentStore) set(key types.NamespacedName, a Assignment) bool {
+	s.Lock()
+	defer s.Unlock()
+	prev, ok := s.assignments[key]
+	s.assignments[key] = a
+	return !ok || prev != a
+}
+
+// delete forgets the Assignment of the pod and releases the NIC reserved for it.
+func (s *AssignmentStore) delete(key types.NamespacedName) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.assignments, key)
+	s.release(key)
+}
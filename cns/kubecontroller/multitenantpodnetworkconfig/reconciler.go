@@ -2,17 +2,207 @@ package multitenantpodnetworkconfig
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/cns/metric"
 	"github.com/Azure/azure-container-networking/crd/multitenancy/api/v1alpha1"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-// SetupWithManager registers a noop MTPNC reconciler
-func SetupWithManager(mgr ctrl.Manager) error {
+// requeueInterval is how often an Assignment which is not Ready is reassessed, as changes to the
+// PodNetworks and PodNetworkInstances, which are not cached on the node, are not watched.
+const requeueInterval = 30 * time.Second
+
+// Reconciler tracks the delegated NIC assignment of the SwiftV2 pods on the node.
+// The control plane reserves the delegated subnet and NIC of each pod according to its PodNetwork
+// and PodNetworkInstance, and publishes the result in the pod's MTPNC. The Reconciler follows
+// that progress, reserves the published NIC for the pod on the node, records the Assignment in the
+// AssignmentStore for the CNS IPAM path, reports it in the NICAssigned condition of the MTPNC, and
+// reports changes as events on the pod.
+//
+// The Reconciler labels the MTPNCs of the pods on the node with v1alpha1.NodeLabel, so that the
+// MTPNC cache can be scoped to the node with a label selector. MTPNCs, PodNetworks and
+// PodNetworkInstances are read directly from the API server, and must not be cached cluster-wide.
+type Reconciler struct {
+	cli      client.Client
+	nodeName string
+	store    *AssignmentStore
+	recorder record.EventRecorder
+}
+
+func NewReconciler(cli client.Client, nodeName string, store *AssignmentStore, recorder record.EventRecorder) *Reconciler {
+	return &Reconciler{
+		cli:      cli,
+		nodeName: nodeName,
+		store:    store,
+		recorder: recorder,
+	}
+}
+
+// Reconcile is called for a SwiftV2 pod on the node or for its MTPNC, which shares the name of the pod.
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	pod := &corev1.Pod{}
+	if err := r.cli.Get(ctx, req.NamespacedName, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.store.delete(req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get pod %s", req.NamespacedName)
+	}
+	if pod.Spec.NodeName != r.nodeName {
+		return reconcile.Result{}, nil
+	}
+
+	mtpnc := &v1alpha1.MultitenantPodNetworkConfig{}
+	if err := r.cli.Get(ctx, req.NamespacedName, mtpnc); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.store.delete(req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get mtpnc %s", req.NamespacedName)
+	}
+	if err := r.label(ctx, mtpnc); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	assignment, err := r.assess(ctx, mtpnc)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if assignment.State == AssignmentReady {
+		if err := r.store.Reserve(req.NamespacedName, mtpnc.Status.MacAddress); err != nil {
+			assignment = Assignment{AssignmentFailed, ReasonNICReserved, err.Error()}
+		}
+	}
+	if err := r.updateStatus(ctx, mtpnc, assignment); err != nil {
+		return reconcile.Result{}, err
+	}
+	if r.store.set(req.NamespacedName, assignment) {
+		eventType := corev1.EventTypeNormal
+		if assignment.State == AssignmentFailed {
+			eventType = corev1.EventTypeWarning
+		}
+		r.recorder.Event(pod, eventType, assignment.Reason, assignment.Message)
+	}
+	if assignment.State != AssignmentReady {
+		return reconcile.Result{RequeueAfter: requeueInterval}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// label sets the v1alpha1.NodeLabel of the MTPNC to the node, if it is not already set.
+func (r *Reconciler) label(ctx context.Context, mtpnc *v1alpha1.MultitenantPodNetworkConfig) error {
+	if mtpnc.Labels[v1alpha1.NodeLabel] == r.nodeName {
+		return nil
+	}
+	patch := client.MergeFrom(mtpnc.DeepCopy())
+	metav1.SetMetaDataLabel(&mtpnc.ObjectMeta, v1alpha1.NodeLabel, r.nodeName)
+	if err := r.cli.Patch(ctx, mtpnc, patch); err != nil {
+		return errors.Wrapf(err, "failed to label mtpnc %s", client.ObjectKeyFromObject(mtpnc))
+	}
+	return nil
+}
+
+// updateStatus reports the Assignment in the NICAssigned condition of the MTPNC, if it changed.
+// The condition is patched, so that the status published by the control plane is left as it is.
+func (r *Reconciler) updateStatus(ctx context.Context, mtpnc *v1alpha1.MultitenantPodNetworkConfig, a Assignment) error {
+	status := metav1.ConditionFalse
+	if a.State == AssignmentReady {
+		status = metav1.ConditionTrue
+	}
+	if c := meta.FindStatusCondition(mtpnc.Status.Conditions, v1alpha1.ConditionNICAssigned); c != nil &&
+		c.Status == status && c.Reason == a.Reason && c.Message == a.Message && c.ObservedGeneration == mtpnc.Generation {
+		return nil
+	}
+	patch := client.MergeFrom(mtpnc.DeepCopy())
+	meta.SetStatusCondition(&mtpnc.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionNICAssigned,
+		Status:             status,
+		ObservedGeneration: mtpnc.Generation,
+		Reason:             a.Reason,
+		Message:            a.Message,
+	})
+	if err := r.cli.Status().Patch(ctx, mtpnc, patch); err != nil {
+		return errors.Wrapf(err, "failed to update status of mtpnc %s", client.ObjectKeyFromObject(mtpnc))
+	}
+	return nil
+}
+
+// assess determines the Assignment of the MTPNC from its status and the status of the
+// PodNetwork and PodNetworkInstance it references.
+func (r *Reconciler) assess(ctx context.Context, mtpnc *v1alpha1.MultitenantPodNetworkConfig) (Assignment, error) {
+	pn := &v1alpha1.PodNetwork{}
+	if err := r.cli.Get(ctx, types.NamespacedName{Name: mtpnc.Spec.PodNetwork}, pn); err != nil {
+		if apierrors.IsNotFound(err) {
+			return Assignment{AssignmentFailed, ReasonPodNetworkNotFound, fmt.Sprintf("PodNetwork %s does not exist", mtpnc.Spec.PodNetwork)}, nil
+		}
+		return Assignment{}, errors.Wrapf(err, "failed to get podnetwork %s", mtpnc.Spec.PodNetwork)
+	}
+	switch pn.Status.Status {
+	case v1alpha1.Ready, v1alpha1.InUse:
+	case "":
+		return Assignment{AssignmentPending, ReasonPodNetworkNotReady, fmt.Sprintf("PodNetwork %s is not ready", pn.Name)}, nil
+	default:
+		return Assignment{AssignmentFailed, string(pn.Status.Status), fmt.Sprintf("PodNetwork %s is %s", pn.Name, pn.Status.Status)}, nil
+	}
+
+	if mtpnc.Spec.PodNetworkInstance != "" {
+		key := types.NamespacedName{Namespace: mtpnc.Namespace, Name: mtpnc.Spec.PodNetworkInstance}
+		pni := &v1alpha1.PodNetworkInstance{}
+		if err := r.cli.Get(ctx, key, pni); err != nil {
+			if apierrors.IsNotFound(err) {
+				return Assignment{AssignmentFailed, ReasonPodNetworkInstanceNotFound, fmt.Sprintf("PodNetworkInstance %s does not exist", key)}, nil
+			}
+			return Assignment{}, errors.Wrapf(err, "failed to get podnetworkinstance %s", key)
+		}
+		progress := fmt.Sprintf("%d of %d IPs reserved", len(pni.Status.PodIPAddresses), pni.Spec.PodIPReservationSize)
+		switch pni.Status.Status {
+		case v1alpha1.PNIStatusReady:
+		case "", v1alpha1.PNIStatusPodNetworkNotReady:
+			return Assignment{AssignmentPending, ReasonPodNetworkInstanceNotReady, fmt.Sprintf("PodNetworkInstance %s is not ready, %s", key, progress)}, nil
+		default:
+			return Assignment{AssignmentFailed, string(pni.Status.Status), fmt.Sprintf("PodNetworkInstance %s is %s, %s", key, pni.Status.Status, progress)}, nil
+		}
+	}
+
+	if !IsReady(mtpnc) {
+		return Assignment{AssignmentPending, ReasonWaitingForNIC, "waiting for the delegated NIC to be assigned"}, nil
+	}
+	return Assignment{AssignmentReady, ReasonAssigned, fmt.Sprintf("delegated NIC %s assigned with IP %s", mtpnc.Status.MacAddress, mtpnc.Status.PrimaryIP)}, nil
+}
+
+// IsReady returns whether the control plane has published the delegated NIC of the MTPNC.
+func IsReady(mtpnc *v1alpha1.MultitenantPodNetworkConfig) bool {
+	return mtpnc.Status.PrimaryIP != "" && mtpnc.Status.MacAddress != "" && mtpnc.Status.NCID != "" && mtpnc.Status.GatewayIP != ""
+}
+
+// SetupWithManager registers the MTPNC reconciler, which is triggered by changes to the SwiftV2
+// pods on the node and to their MTPNCs. The manager cache must be scoped to the pods of the node
+// and to the MTPNCs with the v1alpha1.NodeLabel of the node.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	err := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.MultitenantPodNetworkConfig{}).
-		Complete(reconcile.Func(func(context.Context, ctrl.Request) (ctrl.Result, error) { return ctrl.Result{}, nil }))
+		Watches(&corev1.Pod{}, &handler.EnqueueRequestForObject{}, builder.WithPredicates(predicate.NewPredicateFuncs(isSwiftV2Pod))).
+		Complete(metric.InstrumentReconciler("multitenantpodnetworkconfig", r))
 	return errors.Wrap(err, "failed to set up mtpnc reconciler")
 }
+
+// isSwiftV2Pod returns whether the object is a pod which is attached to a PodNetwork.
+func isSwiftV2Pod(o client.Object) bool {
+	_, ok := o.GetLabels()[configuration.LabelPodSwiftV2]
+	return ok
+}
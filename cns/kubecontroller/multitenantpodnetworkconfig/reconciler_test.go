@@ -0,0 +1,229 @@
+package multitenantpodnetworkconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/crd/multitenancy/api/v1alpha1"
+	acnscheme "github.com/Azure/azure-container-networking/crd/scheme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	testNode      = "node1"
+	testNamespace = "ns"
+	testPod       = "pod1"
+	testPN        = "pn1"
+	testPNI       = "pni1"
+)
+
+var testKey = types.NamespacedName{Namespace: testNamespace, Name: testPod}
+
+func newPod(node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      testPod,
+			Labels:    map[string]string{configuration.LabelPodSwiftV2: testPN},
+		},
+		Spec: corev1.PodSpec{NodeName: node},
+	}
+}
+
+func newPN(status v1alpha1.Status) *v1alpha1.PodNetwork {
+	return &v1alpha1.PodNetwork{
+		ObjectMeta: metav1.ObjectMeta{Name: testPN},
+		Status:     v1alpha1.PodNetworkStatus{Status: status},
+	}
+}
+
+func newPNI(status v1alpha1.PNIStatus, reserved int) *v1alpha1.PodNetworkInstance {
+	pni := &v1alpha1.PodNetworkInstance{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testPNI},
+		Spec:       v1alpha1.PodNetworkInstanceSpec{PodNetwork: testPN, PodIPReservationSize: 2},
+		Status:     v1alpha1.PodNetworkInstanceStatus{Status: status},
+	}
+	for i := 0; i < reserved; i++ {
+		pni.Status.PodIPAddresses = append(pni.Status.PodIPAddresses, "10.0.0.1")
+	}
+	return pni
+}
+
+func newMTPNC(ready bool) *v1alpha1.MultitenantPodNetworkConfig {
+	mtpnc := &v1alpha1.MultitenantPodNetworkConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testPod},
+		Spec: v1alpha1.MultitenantPodNetworkConfigSpec{
+			PodNetwork:         testPN,
+			PodNetworkInstance: testPNI,
+			PodName:            testPod,
+		},
+	}
+	if ready {
+		mtpnc.Status = v1alpha1.MultitenantPodNetworkConfigStatus{
+			PrimaryIP:  "192.168.0.1/32",
+			MacAddress: "00:00:00:00:00:00",
+			GatewayIP:  "10.0.0.1",
+			NCID:       "testncid",
+		}
+	}
+	return mtpnc
+}
+
+func newReconciler(t *testing.T, objs ...client.Object) (*Reconciler, *AssignmentStore, *record.FakeRecorder) {
+	scheme, err := acnscheme.New()
+	require.NoError(t, err)
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&v1alpha1.MultitenantPodNetworkConfig{}).Build()
+	store := NewAssignmentStore()
+	recorder := record.NewFakeRecorder(10)
+	return NewReconciler(cli, testNode, store, recorder), store, recorder
+}
+
+func getMTPNC(t *testing.T, r *Reconciler, key types.NamespacedName) *v1alpha1.MultitenantPodNetworkConfig {
+	mtpnc := &v1alpha1.MultitenantPodNetworkConfig{}
+	require.NoError(t, r.cli.Get(context.Background(), key, mtpnc))
+	return mtpnc
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name      string
+		objs      []client.Object
+		wantState AssignmentState
+		wantRsn   string
+		wantEvent string
+		wantCond  metav1.ConditionStatus
+	}{
+		{
+			name:      "podnetwork not found",
+			objs:      []client.Object{newPod(testNode), newMTPNC(false)},
+			wantState: AssignmentFailed,
+			wantRsn:   ReasonPodNetworkNotFound,
+			wantEvent: corev1.EventTypeWarning,
+			wantCond:  metav1.ConditionFalse,
+		},
+		{
+			name:      "podnetwork subnet not delegated",
+			objs:      []client.Object{newPod(testNode), newMTPNC(false), newPN(v1alpha1.SubnetNotDelegated)},
+			wantState: AssignmentFailed,
+			wantRsn:   string(v1alpha1.SubnetNotDelegated),
+			wantEvent: corev1.EventTypeWarning,
+			wantCond:  metav1.ConditionFalse,
+		},
+		{
+			name:      "podnetworkinstance not ready",
+			objs:      []client.Object{newPod(testNode), newMTPNC(false), newPN(v1alpha1.Ready), newPNI("", 1)},
+			wantState: AssignmentPending,
+			wantRsn:   ReasonPodNetworkInstanceNotReady,
+			wantEvent: corev1.EventTypeNormal,
+			wantCond:  metav1.ConditionFalse,
+		},
+		{
+			name:      "waiting for nic",
+			objs:      []client.Object{newPod(testNode), newMTPNC(false), newPN(v1alpha1.Ready), newPNI(v1alpha1.PNIStatusReady, 2)},
+			wantState: AssignmentPending,
+			wantRsn:   ReasonWaitingForNIC,
+			wantEvent: corev1.EventTypeNormal,
+			wantCond:  metav1.ConditionFalse,
+		},
+		{
+			name:      "assigned",
+			objs:      []client.Object{newPod(testNode), newMTPNC(true), newPN(v1alpha1.InUse), newPNI(v1alpha1.PNIStatusReady, 2)},
+			wantState: AssignmentReady,
+			wantRsn:   ReasonAssigned,
+			wantEvent: corev1.EventTypeNormal,
+			wantCond:  metav1.ConditionTrue,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r, store, recorder := newReconciler(t, tt.objs...)
+			res, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: testKey})
+			require.NoError(t, err)
+			got, ok := store.Get(testKey)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantState, got.State)
+			assert.Equal(t, tt.wantRsn, got.Reason)
+			require.Len(t, recorder.Events, 1)
+			assert.Contains(t, <-recorder.Events, tt.wantEvent+" "+tt.wantRsn)
+			if tt.wantState == AssignmentReady {
+				assert.Zero(t, res.RequeueAfter)
+			} else {
+				assert.Equal(t, requeueInterval, res.RequeueAfter, "assignments which are not ready must be reassessed")
+			}
+
+			// the mtpnc is labeled with the node and reports the assignment in its status
+			mtpnc := getMTPNC(t, r, testKey)
+			assert.Equal(t, testNode, mtpnc.Labels[v1alpha1.NodeLabel])
+			cond := meta.FindStatusCondition(mtpnc.Status.Conditions, v1alpha1.ConditionNICAssigned)
+			require.NotNil(t, cond)
+			assert.Equal(t, tt.wantCond, cond.Status)
+			assert.Equal(t, tt.wantRsn, cond.Reason)
+			assert.Equal(t, got.Message, cond.Message)
+
+			// an unchanged assignment is not reported again
+			_, err = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: testKey})
+			require.NoError(t, err)
+			assert.Empty(t, recorder.Events)
+			assert.Equal(t, mtpnc.ResourceVersion, getMTPNC(t, r, testKey).ResourceVersion)
+		})
+	}
+}
+
+func TestReconcileSkipsOtherNodes(t *testing.T) {
+	r, store, recorder := newReconciler(t, newPod("node2"), newMTPNC(true), newPN(v1alpha1.Ready), newPNI(v1alpha1.PNIStatusReady, 2))
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: testKey})
+	require.NoError(t, err)
+	_, ok := store.Get(testKey)
+	assert.False(t, ok)
+	assert.Empty(t, recorder.Events)
+}
+
+func TestReconcileForgetsDeletedPods(t *testing.T) {
+	r, store, _ := newReconciler(t)
+	store.set(testKey, Assignment{AssignmentReady, ReasonAssigned, ""})
+	require.NoError(t, store.Reserve(testKey, "00:00:00:00:00:00"))
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: testKey})
+	require.NoError(t, err)
+	_, ok := store.Get(testKey)
+	assert.False(t, ok)
+	// the nic of the deleted pod is released
+	assert.NoError(t, store.Reserve(types.NamespacedName{Namespace: testNamespace, Name: "pod2"}, "00:00:00:00:00:00"))
+}
+
+func TestReconcileNICReservedForAnotherPod(t *testing.T) {
+	otherKey := types.NamespacedName{Namespace: testNamespace, Name: "pod2"}
+	r, store, recorder := newReconciler(t, newPod(testNode), newMTPNC(true), newPN(v1alpha1.Ready), newPNI(v1alpha1.PNIStatusReady, 2))
+	// the nic was handed to another pod, which is still on the node
+	require.NoError(t, store.Reserve(otherKey, "00:00:00:00:00:00"))
+
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: testKey})
+	require.NoError(t, err)
+	got, ok := store.Get(testKey)
+	require.True(t, ok)
+	assert.Equal(t, AssignmentFailed, got.State)
+	assert.Equal(t, ReasonNICReserved, got.Reason)
+	assert.Contains(t, <-recorder.Events, corev1.EventTypeWarning+" "+ReasonNICReserved)
+	cond := meta.FindStatusCondition(getMTPNC(t, r, testKey).Status.Conditions, v1alpha1.ConditionNICAssigned)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+
+	// once the other pod is gone, the nic is reserved for the pod
+	_, err = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: otherKey})
+	require.NoError(t, err)
+	_, err = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: testKey})
+	require.NoError(t, err)
+	got, _ = store.Get(testKey)
+	assert.Equal(t, AssignmentReady, got.State)
+	assert.ErrorIs(t, store.Reserve(otherKey, "00:00:00:00:00:00"), ErrNICReserved)
+}
@@ -0,0 +1,68 @@
+package node
+
+import (
+	"context"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/metric"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type nodeGetter interface {
+	Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error
+}
+
+type scalerOverrideSink interface {
+	SetScalerOverride(context.Context, ScalerOverride) error
+}
+
+// Reconciler watches the Node that CNS is running on and publishes the ScalerOverride set in its
+// labels and annotations to the sink.
+type Reconciler struct {
+	cli      nodeGetter
+	sink     scalerOverrideSink
+	nodeName string
+}
+
+// NewReconciler creates a Node Reconciler which publishes the ScalerOverride of the named Node to the sink.
+func NewReconciler(sink scalerOverrideSink, nodeName string) *Reconciler {
+	return &Reconciler{
+		sink:     sink,
+		nodeName: nodeName,
+	}
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	node := &corev1.Node{}
+	if err := r.cli.Get(ctx, req.NamespacedName, node); err != nil {
+		return reconcile.Result{}, errors.Wrapf(client.IgnoreNotFound(err), "failed to get node %s", req.Name)
+	}
+	override, err := ParseScalerOverride(node)
+	if err != nil {
+		// a malformed override will not be fixed by retrying, so log it and wait for the Node to change.
+		logger.Errorf("[node-reconciler] ignoring invalid scaler override on node %s: %v", node.Name, err)
+		return reconcile.Result{}, nil
+	}
+	if err := r.sink.SetScalerOverride(ctx, override); err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "failed to set scaler override")
+	}
+	return reconcile.Result{}, nil
+}
+
+// SetupWithManager sets up the Reconciler with the manager, filtering to the Node named nodeName.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.cli = mgr.GetClient()
+	err := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Node{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			return object.GetName() == r.nodeName
+		})).
+		WithEventFilter(predicate.Or(predicate.LabelChangedPredicate{}, predicate.AnnotationChangedPredicate{})).
+		Complete(metric.InstrumentReconciler("node", r))
+	return errors.Wrap(err, "failed to setup node reconciler with manager")
+}
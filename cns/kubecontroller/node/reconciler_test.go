@@ -0,0 +1,150 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func ptr(i int64) *int64 {
+	return &i
+}
+
+func TestParseScalerOverride(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    *corev1.Node
+		want    ScalerOverride
+		wantErr bool
+	}{
+		{
+			name: "none",
+			node: &corev1.Node{},
+			want: ScalerOverride{},
+		},
+		{
+			name: "labels",
+			node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				KeyScalerBatchSize:               "32",
+				KeyScalerRequestThresholdPercent: "75",
+			}}},
+			want: ScalerOverride{BatchSize: ptr(32), RequestThresholdPercent: ptr(75)},
+		},
+		{
+			name: "annotation takes precedence",
+			node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Labels:      map[string]string{KeyScalerBatchSize: "32"},
+				Annotations: map[string]string{KeyScalerBatchSize: "64", KeyScalerReleaseThresholdPercent: "200"},
+			}},
+			want: ScalerOverride{BatchSize: ptr(64), ReleaseThresholdPercent: ptr(200)},
+		},
+		{
+			name:    "not a number",
+			node:    &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{KeyScalerBatchSize: "lots"}}},
+			wantErr: true,
+		},
+		{
+			name:    "not positive",
+			node:    &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{KeyScalerBatchSize: "0"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScalerOverride(tt.node)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "want %+v, got %+v", tt.want, got)
+		})
+	}
+}
+
+type mockPoolMonitor struct {
+	cns.IPAMPoolMonitor
+	updates []v1alpha.NodeNetworkConfig
+}
+
+func (m *mockPoolMonitor) Update(nnc *v1alpha.NodeNetworkConfig) error {
+	m.updates = append(m.updates, *nnc)
+	return nil
+}
+
+func TestScalerOverrideMonitor(t *testing.T) {
+	inner := &mockPoolMonitor{}
+	m := NewScalerOverrideMonitor(inner)
+
+	// overrides set before an NNC is received are not pushed.
+	require.NoError(t, m.SetScalerOverride(context.Background(), ScalerOverride{BatchSize: ptr(32)}))
+	assert.Empty(t, inner.updates)
+
+	nnc := &v1alpha.NodeNetworkConfig{Status: v1alpha.NodeNetworkConfigStatus{Scaler: v1alpha.Scaler{BatchSize: 16, RequestThresholdPercent: 50}}}
+	require.NoError(t, m.Update(nnc))
+	require.Len(t, inner.updates, 1)
+	assert.Equal(t, int64(32), inner.updates[0].Status.Scaler.BatchSize)
+	assert.Equal(t, int64(50), inner.updates[0].Status.Scaler.RequestThresholdPercent)
+	// the passed NNC is not modified.
+	assert.Equal(t, int64(16), nnc.Status.Scaler.BatchSize)
+
+	// an unchanged override does not push the NNC again.
+	require.NoError(t, m.SetScalerOverride(context.Background(), ScalerOverride{BatchSize: ptr(32)}))
+	assert.Len(t, inner.updates, 1)
+
+	// removing the override pushes the original NNC.
+	require.NoError(t, m.SetScalerOverride(context.Background(), ScalerOverride{}))
+	require.Len(t, inner.updates, 2)
+	assert.Equal(t, int64(16), inner.updates[1].Status.Scaler.BatchSize)
+}
+
+type mockOverrideSink struct {
+	override *ScalerOverride
+}
+
+func (m *mockOverrideSink) SetScalerOverride(_ context.Context, o ScalerOverride) error {
+	m.override = &o
+	return nil
+}
+
+func TestReconcile(t *testing.T) {
+	logger.InitLogger("", 0, 0, "")
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:        "node",
+		Annotations: map[string]string{KeyScalerBatchSize: "8"},
+	}}
+	invalid := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:        "invalid",
+		Annotations: map[string]string{KeyScalerBatchSize: "-1"},
+	}}
+	sink := &mockOverrideSink{}
+	r := NewReconciler(sink, "node")
+	r.cli = fake.NewClientBuilder().WithObjects(node, invalid).Build()
+
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "node"}})
+	require.NoError(t, err)
+	require.NotNil(t, sink.override)
+	assert.True(t, ScalerOverride{BatchSize: ptr(8)}.Equal(*sink.override))
+
+	// invalid overrides are not published.
+	sink.override = nil
+	_, err = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "invalid"}})
+	require.NoError(t, err)
+	assert.Nil(t, sink.override)
+
+	// missing Nodes are ignored.
+	_, err = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "missing"}})
+	require.NoError(t, err)
+	assert.Nil(t, sink.override)
+}
@@ -0,0 +1,128 @@
+package node
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Node labels or annotations which override the pool Scaler of the NodeNetworkConfig for the Node.
+// If both a label and an annotation are set, the annotation takes precedence.
+const (
+	// KeyScalerBatchSize overrides the number of IPs requested or released at a time.
+	KeyScalerBatchSize = "acn.azure.com/ipam-batch-size"
+	// KeyScalerRequestThresholdPercent overrides the percent of the batch size which is kept free (the buffer).
+	KeyScalerRequestThresholdPercent = "acn.azure.com/ipam-request-threshold-percent"
+	// KeyScalerReleaseThresholdPercent overrides the percent of the batch size above which free IPs are released.
+	KeyScalerReleaseThresholdPercent = "acn.azure.com/ipam-release-threshold-percent"
+)
+
+// ScalerOverride holds the pool Scaler values which are overridden for this Node.
+// Unset values are nil and are not overridden.
+type ScalerOverride struct {
+	BatchSize               *int64
+	RequestThresholdPercent *int64
+	ReleaseThresholdPercent *int64
+}
+
+// ParseScalerOverride reads the ScalerOverride from the labels and annotations of the Node.
+func ParseScalerOverride(node *corev1.Node) (ScalerOverride, error) {
+	o := ScalerOverride{}
+	for key, field := range map[string]**int64{
+		KeyScalerBatchSize:               &o.BatchSize,
+		KeyScalerRequestThresholdPercent: &o.RequestThresholdPercent,
+		KeyScalerReleaseThresholdPercent: &o.ReleaseThresholdPercent,
+	} {
+		val, ok := node.Annotations[key]
+		if !ok {
+			val, ok = node.Labels[key]
+		}
+		if !ok {
+			continue
+		}
+		i, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return ScalerOverride{}, errors.Wrapf(err, "invalid value %q for %s", val, key)
+		}
+		if i < 1 {
+			return ScalerOverride{}, errors.Errorf("invalid value %q for %s, must be positive", val, key)
+		}
+		*field = &i
+	}
+	return o, nil
+}
+
+// Apply sets the overridden values on the Scaler.
+func (o ScalerOverride) Apply(scaler *v1alpha.Scaler) {
+	if o.BatchSize != nil {
+		scaler.BatchSize = *o.BatchSize
+	}
+	if o.RequestThresholdPercent != nil {
+		scaler.RequestThresholdPercent = *o.RequestThresholdPercent
+	}
+	if o.ReleaseThresholdPercent != nil {
+		scaler.ReleaseThresholdPercent = *o.ReleaseThresholdPercent
+	}
+}
+
+// Equal returns true if the ScalerOverrides override the same values.
+func (o ScalerOverride) Equal(other ScalerOverride) bool {
+	eq := func(a, b *int64) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return *a == *b
+	}
+	return eq(o.BatchSize, other.BatchSize) &&
+		eq(o.RequestThresholdPercent, other.RequestThresholdPercent) &&
+		eq(o.ReleaseThresholdPercent, other.ReleaseThresholdPercent)
+}
+
+// ScalerOverrideMonitor wraps an IPAMPoolMonitor and applies the Node ScalerOverride to every
+// NodeNetworkConfig before passing it to the wrapped Monitor.
+type ScalerOverrideMonitor struct {
+	cns.IPAMPoolMonitor
+
+	sync.Mutex
+	override ScalerOverride
+	nnc      *v1alpha.NodeNetworkConfig
+}
+
+// NewScalerOverrideMonitor creates a ScalerOverrideMonitor wrapping the passed IPAMPoolMonitor.
+func NewScalerOverrideMonitor(monitor cns.IPAMPoolMonitor) *ScalerOverrideMonitor {
+	return &ScalerOverrideMonitor{IPAMPoolMonitor: monitor}
+}
+
+// Update applies the current ScalerOverride to the NodeNetworkConfig and pushes it to the wrapped Monitor.
+func (m *ScalerOverrideMonitor) Update(nnc *v1alpha.NodeNetworkConfig) error {
+	m.Lock()
+	defer m.Unlock()
+	m.nnc = nnc.DeepCopy()
+	return m.update()
+}
+
+// SetScalerOverride sets the ScalerOverride. If it has changed and the Monitor has already received
+// a NodeNetworkConfig, the last NodeNetworkConfig is pushed again with the new ScalerOverride applied.
+func (m *ScalerOverrideMonitor) SetScalerOverride(_ context.Context, override ScalerOverride) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.override.Equal(override) {
+		return nil
+	}
+	m.override = override
+	if m.nnc == nil {
+		return nil
+	}
+	return m.update()
+}
+
+func (m *ScalerOverrideMonitor) update() error {
+	nnc := m.nnc.DeepCopy()
+	m.override.Apply(&nnc.Status.Scaler)
+	return errors.Wrap(m.IPAMPoolMonitor.Update(nnc), "failed to update pool monitor")
+}
@@ -0,0 +1,83 @@
+package nodenetworkconfig
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type secondaryIPHealthSource interface {
+	SecondaryIPHealth() *v1alpha.SecondaryIPHealth
+}
+
+type secondaryIPHealthPatcher interface {
+	Get(context.Context, types.NamespacedName) (*v1alpha.NodeNetworkConfig, error)
+	PatchSecondaryIPHealth(context.Context, types.NamespacedName, *v1alpha.SecondaryIPHealth, string) error
+}
+
+// HealthReporter periodically publishes the SecondaryIPHealth of CNS to the status of the NodeNetworkConfig,
+// so that Nodes whose IPs are stuck waiting to be programmed can be alerted on.
+type HealthReporter struct {
+	cli          secondaryIPHealthPatcher
+	source       secondaryIPHealthSource
+	key          types.NamespacedName
+	fieldManager string
+	interval     time.Duration
+}
+
+// NewHealthReporter creates a HealthReporter which reports the SecondaryIPHealth of the source to the
+// NodeNetworkConfig identified by the key every interval.
+func NewHealthReporter(cli secondaryIPHealthPatcher, source secondaryIPHealthSource, key types.NamespacedName, fieldManager string, interval time.Duration) *HealthReporter {
+	return &HealthReporter{
+		cli:          cli,
+		source:       source,
+		key:          key,
+		fieldManager: fieldManager,
+		interval:     interval,
+	}
+}
+
+// Start reports the SecondaryIPHealth every interval until the context is closed.
+// Start implements manager.Runnable.
+func (h *HealthReporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := h.Report(ctx); err != nil {
+				logger.Errorf("[nnc-health] failed to report secondary ip health: %v", err)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, as every CNS reports the health of its own NodeNetworkConfig.
+func (*HealthReporter) NeedLeaderElection() bool {
+	return false
+}
+
+// Report patches the current SecondaryIPHealth to the NodeNetworkConfig if it differs from the one in its status.
+// The health is compared with the NodeNetworkConfig rather than with the last report, as DNC-RC may
+// overwrite the status without it.
+func (h *HealthReporter) Report(ctx context.Context) error {
+	health := h.source.SecondaryIPHealth()
+	nnc, err := h.cli.Get(ctx, h.key)
+	if err != nil {
+		return errors.Wrap(err, "failed to get nnc to report secondary ip health")
+	}
+	if equality.Semantic.DeepEqual(health, nnc.Status.SecondaryIPHealth) {
+		return nil
+	}
+	if err := h.cli.PatchSecondaryIPHealth(ctx, h.key, health, h.fieldManager); err != nil {
+		return errors.Wrap(err, "failed to report secondary ip health")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package nodenetworkconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type mockHealthSource struct {
+	health *v1alpha.SecondaryIPHealth
+}
+
+func (m *mockHealthSource) SecondaryIPHealth() *v1alpha.SecondaryIPHealth {
+	return m.health.DeepCopy()
+}
+
+type mockHealthPatcher struct {
+	patches int
+	err     error
+	nnc     v1alpha.NodeNetworkConfig
+}
+
+func (m *mockHealthPatcher) Get(context.Context, types.NamespacedName) (*v1alpha.NodeNetworkConfig, error) {
+	return m.nnc.DeepCopy(), nil
+}
+
+func (m *mockHealthPatcher) PatchSecondaryIPHealth(_ context.Context, _ types.NamespacedName, health *v1alpha.SecondaryIPHealth, _ string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.patches++
+	m.nnc.Status.SecondaryIPHealth = health
+	return nil
+}
+
+func TestHealthReporterReport(t *testing.T) {
+	source := &mockHealthSource{health: &v1alpha.SecondaryIPHealth{HealthyIPCount: 10, UnprogrammedIPCount: 2}}
+	patcher := &mockHealthPatcher{}
+	h := NewHealthReporter(patcher, source, types.NamespacedName{Namespace: "kube-system", Name: "node"}, "test", 0)
+
+	require.NoError(t, h.Report(context.Background()))
+	assert.Equal(t, 1, patcher.patches)
+	assert.Equal(t, 2, patcher.nnc.Status.SecondaryIPHealth.UnprogrammedIPCount)
+
+	// unchanged health is not re-reported
+	require.NoError(t, h.Report(context.Background()))
+	assert.Equal(t, 1, patcher.patches)
+
+	source.health.HealthyIPCount = 12
+	source.health.UnprogrammedIPCount = 0
+	require.NoError(t, h.Report(context.Background()))
+	assert.Equal(t, 2, patcher.patches)
+	assert.Equal(t, 12, patcher.nnc.Status.SecondaryIPHealth.HealthyIPCount)
+
+	// failed reports are retried on the next Report
+	source.health.UnprogrammedIPCount = 1
+	patcher.err = errors.New("patch failed")
+	require.Error(t, h.Report(context.Background()))
+	patcher.err = nil
+	require.NoError(t, h.Report(context.Background()))
+	assert.Equal(t, 3, patcher.patches)
+
+	// health is re-reported after DNC-RC overwrites the status without it
+	patcher.nnc.Status = v1alpha.NodeNetworkConfigStatus{}
+	require.NoError(t, h.Report(context.Background()))
+	assert.Equal(t, 4, patcher.patches)
+	assert.Equal(t, 1, patcher.nnc.Status.SecondaryIPHealth.UnprogrammedIPCount)
+}
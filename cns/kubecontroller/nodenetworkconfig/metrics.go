@@ -24,6 +24,19 @@ var (
 			Help: "Unused IP count.",
 		},
 	)
+	patchRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nnc_patch_retries_total",
+			Help: "NNC patch retries, by the reason the previous attempt was rejected.",
+		},
+		[]string{"reason"},
+	)
+	patchRetriesExhausted = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nnc_patch_retries_exhausted_total",
+			Help: "NNC patches which failed after exhausting all retries.",
+		},
+	)
 )
 
 func init() {
@@ -31,5 +44,7 @@ func init() {
 		allocatedIPs,
 		requestedIPs,
 		unusedIPs,
+		patchRetries,
+		patchRetriesExhausted,
 	)
 }
@@ -2,17 +2,32 @@ package nodenetworkconfig
 
 import (
 	"context"
+	"time"
 
+	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 )
 
+// DefaultPatchBackoff is the jittered backoff between retries of a NodeNetworkConfig patch which
+// was rejected by the API server because of contention.
+var DefaultPatchBackoff = wait.Backoff{
+	Duration: 100 * time.Millisecond, //nolint:gomnd // 100ms initial backoff
+	Factor:   2,                      //nolint:gomnd // double every attempt
+	Jitter:   0.5,                    //nolint:gomnd // up to 50% jitter
+	Steps:    5,                      //nolint:gomnd // 5 attempts
+}
+
 // ScopedClient is provided to interface with a single configured NodeNetworkConfig.
 type ScopedClient struct {
 	types.NamespacedName
 	*nodenetworkconfig.Client
+	backoff wait.Backoff
 }
 
 // NewScopedClient returns a NodeNetworkConfig client scoped to a single NodeNetworkConfig.
@@ -20,6 +35,7 @@ func NewScopedClient(cli *nodenetworkconfig.Client, key types.NamespacedName) *S
 	return &ScopedClient{
 		NamespacedName: key,
 		Client:         cli,
+		backoff:        DefaultPatchBackoff,
 	}
 }
 
@@ -30,7 +46,40 @@ func (sc *ScopedClient) Get(ctx context.Context) (*v1alpha.NodeNetworkConfig, er
 }
 
 // PatchSpec updates the associated NodeNetworkConfig with the passed NodeNetworkConfigSpec.
+// The patch only contains the Spec fields, and is retried with a jittered backoff if it is
+// rejected because of contention on the NodeNetworkConfig.
 func (sc *ScopedClient) PatchSpec(ctx context.Context, spec *v1alpha.NodeNetworkConfigSpec, fieldManager string) (*v1alpha.NodeNetworkConfig, error) {
-	nnc, err := sc.Client.PatchSpec(ctx, sc.NamespacedName, spec, fieldManager)
+	var nnc *v1alpha.NodeNetworkConfig
+	var lastErr error
+	attempt := 0
+	err := retry.OnError(sc.backoff, func(err error) bool {
+		return retryReason(err) != "" && ctx.Err() == nil
+	}, func() error {
+		if attempt > 0 {
+			reason := retryReason(lastErr)
+			patchRetries.WithLabelValues(reason).Inc()
+			logger.Printf("[nnc-client] retrying patch of nnc %v after %s (attempt %d): %v", sc.NamespacedName, reason, attempt, lastErr)
+		}
+		attempt++
+		nnc, lastErr = sc.Client.PatchSpec(ctx, sc.NamespacedName, spec, fieldManager)
+		return lastErr
+	})
+	if err != nil && retryReason(err) != "" {
+		patchRetriesExhausted.Inc()
+	}
 	return nnc, errors.Wrapf(err, "failed to patch nnc %v", sc.NamespacedName)
 }
+
+// retryReason returns the reason a failed patch should be retried, or "" if it should not be.
+func retryReason(err error) string {
+	switch {
+	case apierrors.IsConflict(err):
+		return "conflict"
+	case apierrors.IsTooManyRequests(err):
+		return "throttled"
+	case apierrors.IsServerTimeout(err), apierrors.IsTimeout(err):
+		return "timeout"
+	default:
+		return ""
+	}
+}
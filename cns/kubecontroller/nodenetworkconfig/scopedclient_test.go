@@ -0,0 +1,97 @@
+package nodenetworkconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newTestScopedClient(patch func(int) error) (*ScopedClient, *int) {
+	calls := 0
+	cli := fake.NewClientBuilder().WithScheme(nodenetworkconfig.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(context.Context, client.WithWatch, client.Object, client.Patch, ...client.PatchOption) error {
+			calls++
+			return patch(calls)
+		},
+	}).Build()
+	sc := NewScopedClient(nodenetworkconfig.NewClient(cli), types.NamespacedName{Namespace: "kube-system", Name: "node1"})
+	sc.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Jitter: 0.5, Steps: 3}
+	return sc, &calls
+}
+
+func TestPatchSpecRetries(t *testing.T) {
+	logger.InitLogger("", 0, 0, "")
+	gr := schema.GroupResource{Group: v1alpha.GroupVersion.Group, Resource: "nodenetworkconfigs"}
+	conflict := apierrors.NewConflict(gr, "node1", nil)
+
+	tests := []struct {
+		name          string
+		patch         func(int) error
+		wantErr       bool
+		wantCalls     int
+		wantConflicts float64
+		wantExhausted float64
+	}{
+		{
+			name:      "success",
+			patch:     func(int) error { return nil },
+			wantCalls: 1,
+		},
+		{
+			name: "conflict then success",
+			patch: func(call int) error {
+				if call == 1 {
+					return conflict
+				}
+				return nil
+			},
+			wantCalls:     2,
+			wantConflicts: 1,
+		},
+		{
+			name:          "conflicts exhaust retries",
+			patch:         func(int) error { return conflict },
+			wantErr:       true,
+			wantCalls:     3,
+			wantConflicts: 2,
+			wantExhausted: 1,
+		},
+		{
+			name:      "not retriable",
+			patch:     func(int) error { return apierrors.NewNotFound(gr, "node1") },
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := testutil.ToFloat64(patchRetries.WithLabelValues("conflict"))
+			exhausted := testutil.ToFloat64(patchRetriesExhausted)
+			sc, calls := newTestScopedClient(tt.patch)
+			_, err := sc.PatchSpec(context.Background(), &v1alpha.NodeNetworkConfigSpec{RequestedIPCount: 16}, "test")
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, *calls)
+			assert.Equal(t, tt.wantConflicts, testutil.ToFloat64(patchRetries.WithLabelValues("conflict"))-conflicts)
+			assert.Equal(t, tt.wantExhausted, testutil.ToFloat64(patchRetriesExhausted)-exhausted)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package overlayextensionconfig
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/iptables"
+	goiptables "github.com/coreos/go-iptables/iptables"
+	"github.com/pkg/errors"
+)
+
+// Chain is the nat chain, jumped to first from POSTROUTING, which accepts the traffic to the extension IP
+// ranges so that it is not masqueraded by the rules after it.
+const Chain = "OVERLAY-EXT-POSTROUTING"
+
+type iptablesClient interface {
+	ChainExists(table, chain string) (bool, error)
+	NewChain(table, chain string) error
+	Exists(table, chain string, rulespec ...string) (bool, error)
+	Insert(table, chain string, pos int, rulespec ...string) error
+	Append(table, chain string, rulespec ...string) error
+	Delete(table, chain string, rulespec ...string) error
+	List(table, chain string) ([]string, error)
+}
+
+type iptablesProgrammer struct {
+	v4, v6 iptablesClient
+}
+
+// NewProgrammer returns a Programmer which excludes the extension IP ranges from SNAT with iptables.
+func NewProgrammer() (Programmer, error) {
+	v4, err := goiptables.NewWithProtocol(goiptables.ProtocolIPv4)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create iptables client")
+	}
+	v6, err := goiptables.NewWithProtocol(goiptables.ProtocolIPv6)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create ip6tables client")
+	}
+	return &iptablesProgrammer{v4: v4, v6: v6}, nil
+}
+
+func (p *iptablesProgrammer) Sync(prefixes []netip.Prefix) error {
+	var v4, v6 []netip.Prefix
+	for _, prefix := range prefixes {
+		if prefix.Addr().Is4() {
+			v4 = append(v4, prefix)
+		} else {
+			v6 = append(v6, prefix)
+		}
+	}
+	if err := sync(p.v4, v4); err != nil {
+		return errors.Wrap(err, "failed to sync iptables")
+	}
+	if err := sync(p.v6, v6); err != nil {
+		return errors.Wrap(err, "failed to sync ip6tables")
+	}
+	return nil
+}
+
+func ruleFor(prefix netip.Prefix) []string {
+	return []string{"-d", prefix.String(), "-j", iptables.Accept}
+}
+
+// sync makes the Chain accept exactly the passed prefixes, adding the missing rules before removing the
+// stale ones so that traffic to ranges which are kept is never masqueraded.
+func sync(ipt iptablesClient, prefixes []netip.Prefix) error {
+	exists, err := ipt.ChainExists(iptables.Nat, Chain)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check for chain %s", Chain)
+	}
+	if !exists {
+		if err := ipt.NewChain(iptables.Nat, Chain); err != nil {
+			return errors.Wrapf(err, "failed to create chain %s", Chain)
+		}
+	}
+	jump, err := ipt.Exists(iptables.Nat, iptables.Postrouting, "-j", Chain)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check for jump to chain %s", Chain)
+	}
+	if !jump {
+		if err := ipt.Insert(iptables.Nat, iptables.Postrouting, 1, "-j", Chain); err != nil {
+			return errors.Wrapf(err, "failed to insert jump to chain %s", Chain)
+		}
+	}
+
+	want := map[string]struct{}{}
+	for _, prefix := range prefixes {
+		rule := ruleFor(prefix)
+		want[strings.Join(rule, " ")] = struct{}{}
+		exists, err := ipt.Exists(iptables.Nat, Chain, rule...)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check for rule %v", rule)
+		}
+		if exists {
+			continue
+		}
+		logger.Printf("[oec] accepting traffic to extension ip range %s", prefix)
+		if err := ipt.Append(iptables.Nat, Chain, rule...); err != nil {
+			return errors.Wrapf(err, "failed to append rule %v", rule)
+		}
+	}
+
+	rules, err := ipt.List(iptables.Nat, Chain)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list chain %s", Chain)
+	}
+	for _, rule := range rules {
+		// rules are listed as "-A <chain> <rulespec>", the chain itself as "-N <chain>".
+		fields := strings.Fields(rule)
+		if len(fields) < 3 || fields[0] != "-A" {
+			continue
+		}
+		if _, ok := want[strings.Join(fields[2:], " ")]; ok {
+			continue
+		}
+		logger.Printf("[oec] removing stale rule %s", rule)
+		if err := ipt.Delete(iptables.Nat, Chain, fields[2:]...); err != nil {
+			return errors.Wrapf(err, "failed to delete rule %s", rule)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package overlayextensionconfig
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/iptables"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIPTables keeps the rules of each table/chain as joined rulespecs.
+type fakeIPTables struct {
+	chains map[string][]string
+}
+
+func newFakeIPTables() *fakeIPTables {
+	return &fakeIPTables{chains: map[string][]string{iptables.Nat + "/" + iptables.Postrouting: {"-j MASQUERADE"}}}
+}
+
+func (f *fakeIPTables) ChainExists(table, chain string) (bool, error) {
+	_, ok := f.chains[table+"/"+chain]
+	return ok, nil
+}
+
+func (f *fakeIPTables) NewChain(table, chain string) error {
+	f.chains[table+"/"+chain] = []string{}
+	return nil
+}
+
+func (f *fakeIPTables) Exists(table, chain string, rulespec ...string) (bool, error) {
+	for _, rule := range f.chains[table+"/"+chain] {
+		if rule == strings.Join(rulespec, " ") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeIPTables) Insert(table, chain string, pos int, rulespec ...string) error {
+	key := table + "/" + chain
+	f.chains[key] = append(f.chains[key][:pos-1], append([]string{strings.Join(rulespec, " ")}, f.chains[key][pos-1:]...)...)
+	return nil
+}
+
+func (f *fakeIPTables) Append(table, chain string, rulespec ...string) error {
+	key := table + "/" + chain
+	f.chains[key] = append(f.chains[key], strings.Join(rulespec, " "))
+	return nil
+}
+
+func (f *fakeIPTables) Delete(table, chain string, rulespec ...string) error {
+	key := table + "/" + chain
+	for i, rule := range f.chains[key] {
+		if rule == strings.Join(rulespec, " ") {
+			f.chains[key] = append(f.chains[key][:i], f.chains[key][i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeIPTables) List(table, chain string) ([]string, error) {
+	rules := []string{"-N " + chain}
+	for _, rule := range f.chains[table+"/"+chain] {
+		rules = append(rules, "-A "+chain+" "+rule)
+	}
+	return rules, nil
+}
+
+func TestSync(t *testing.T) {
+	logger.InitLogger("", 0, 0, "")
+	v4, v6 := newFakeIPTables(), newFakeIPTables()
+	p := &iptablesProgrammer{v4: v4, v6: v6}
+
+	require.NoError(t, p.Sync([]netip.Prefix{netip.MustParsePrefix("10.1.0.0/16"), netip.MustParsePrefix("10.2.0.0/16"), netip.MustParsePrefix("fd00::/64")}))
+	assert.Equal(t, []string{"-j " + Chain, "-j MASQUERADE"}, v4.chains[iptables.Nat+"/"+iptables.Postrouting], "the chain must be jumped to before masquerading")
+	assert.Equal(t, []string{"-d 10.1.0.0/16 -j ACCEPT", "-d 10.2.0.0/16 -j ACCEPT"}, v4.chains[iptables.Nat+"/"+Chain])
+	assert.Equal(t, []string{"-d fd00::/64 -j ACCEPT"}, v6.chains[iptables.Nat+"/"+Chain])
+
+	require.NoError(t, p.Sync([]netip.Prefix{netip.MustParsePrefix("10.2.0.0/16"), netip.MustParsePrefix("10.3.0.0/16")}))
+	assert.Equal(t, []string{"-j " + Chain, "-j MASQUERADE"}, v4.chains[iptables.Nat+"/"+iptables.Postrouting])
+	assert.Equal(t, []string{"-d 10.2.0.0/16 -j ACCEPT", "-d 10.3.0.0/16 -j ACCEPT"}, v4.chains[iptables.Nat+"/"+Chain])
+	assert.Empty(t, v6.chains[iptables.Nat+"/"+Chain])
+}
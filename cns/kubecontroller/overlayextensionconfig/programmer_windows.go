@@ -0,0 +1,25 @@
+package overlayextensionconfig
+
+import (
+	"net/netip"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotSupported is returned when extension IP ranges are programmed on Windows, where the SNAT
+// exceptions of each endpoint are set by CNI when the endpoint is created.
+var ErrNotSupported = errors.New("overlay extension ip ranges are not supported on windows")
+
+type unsupportedProgrammer struct{}
+
+// NewProgrammer returns a Programmer which fails to program any extension IP range.
+func NewProgrammer() (Programmer, error) {
+	return unsupportedProgrammer{}, nil
+}
+
+func (unsupportedProgrammer) Sync(prefixes []netip.Prefix) error {
+	if len(prefixes) == 0 {
+		return nil
+	}
+	return ErrNotSupported
+}
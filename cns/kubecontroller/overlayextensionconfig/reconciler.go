@@ -0,0 +1,98 @@
+package overlayextensionconfig
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/metric"
+	"github.com/Azure/azure-container-networking/crd/overlayextensionconfig"
+	"github.com/Azure/azure-container-networking/crd/overlayextensionconfig/api/v1alpha1"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Programmer programs the node so that its pods can reach, and be reached from, the extension IP ranges
+// without SNAT. Sync is passed every extension IP range, and removes any previously programmed range
+// which is no longer passed.
+type Programmer interface {
+	Sync([]netip.Prefix) error
+}
+
+// Reconciler programs the ExtensionIPRanges of all the OverlayExtensionConfigs onto the node, and reports
+// the result in each OverlayExtensionConfig's status for the node.
+type Reconciler struct {
+	cli        *overlayextensionconfig.Client
+	nodeName   string
+	programmer Programmer
+}
+
+func NewReconciler(cli *overlayextensionconfig.Client, nodeName string, programmer Programmer) *Reconciler {
+	return &Reconciler{
+		cli:        cli,
+		nodeName:   nodeName,
+		programmer: programmer,
+	}
+}
+
+// Reconcile syncs the ranges of all OverlayExtensionConfigs whenever any of them changes, so that
+// deleted OverlayExtensionConfigs are removed from the node without finalizers.
+func (r *Reconciler) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	oecs, err := r.cli.List(ctx)
+	if err != nil {
+		return reconcile.Result{}, err //nolint:wrapcheck // already wrapped by the client
+	}
+
+	statuses := make([]v1alpha1.NodeStatus, len(oecs.Items))
+	prefixes := []netip.Prefix{}
+	for i := range oecs.Items {
+		statuses[i] = v1alpha1.NodeStatus{Node: r.nodeName, ObservedGeneration: oecs.Items[i].Generation}
+		prefix, err := netip.ParsePrefix(oecs.Items[i].Spec.ExtensionIPRange)
+		if err != nil {
+			statuses[i].State = v1alpha1.Failed
+			statuses[i].Message = "invalid extensionIPRange: " + err.Error()
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+		statuses[i].State = v1alpha1.Succeeded
+	}
+
+	syncErr := r.programmer.Sync(prefixes)
+	if syncErr != nil {
+		logger.Errorf("[oec-rc] failed to program extension ip ranges %v: %v", prefixes, syncErr)
+	}
+	for i := range oecs.Items {
+		if syncErr != nil && statuses[i].State == v1alpha1.Succeeded {
+			statuses[i].State = v1alpha1.Failed
+			statuses[i].Message = syncErr.Error()
+		}
+		if err := r.applyStatus(ctx, &oecs.Items[i], statuses[i]); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{}, errors.Wrap(syncErr, "failed to program extension ip ranges")
+}
+
+// applyStatus applies the node's status to the OverlayExtensionConfig if it has changed.
+func (r *Reconciler) applyStatus(ctx context.Context, oec *v1alpha1.OverlayExtensionConfig, status v1alpha1.NodeStatus) error {
+	for i := range oec.Status.Nodes {
+		if oec.Status.Nodes[i] == status {
+			return nil
+		}
+	}
+	key := types.NamespacedName{Namespace: oec.Namespace, Name: oec.Name}
+	return r.cli.ApplyNodeStatus(ctx, key, status, "azure-cns-"+r.nodeName) //nolint:wrapcheck // already wrapped by the client
+}
+
+// SetupWithManager sets up the reconciler with the manager. Status changes do not change the generation and
+// are not reconciled.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.OverlayExtensionConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(metric.InstrumentReconciler("overlayextensionconfig", r))
+	return errors.Wrap(err, "failed to setup overlayextensionconfig reconciler with manager")
+}
@@ -0,0 +1,74 @@
+package overlayextensionconfig
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	crdfake "github.com/Azure/azure-container-networking/crd/fake"
+	"github.com/Azure/azure-container-networking/crd/overlayextensionconfig"
+	"github.com/Azure/azure-container-networking/crd/overlayextensionconfig/api/v1alpha1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type fakeProgrammer struct {
+	prefixes []netip.Prefix
+	err      error
+}
+
+func (f *fakeProgrammer) Sync(prefixes []netip.Prefix) error {
+	f.prefixes = prefixes
+	return f.err
+}
+
+func newOEC(name, cidr string) *v1alpha1.OverlayExtensionConfig {
+	return &v1alpha1.OverlayExtensionConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Generation: 1},
+		Spec:       v1alpha1.OverlayExtensionConfigSpec{ExtensionIPRange: cidr},
+	}
+}
+
+func nodeStatus(t *testing.T, cli client.Client, name string) v1alpha1.NodeStatus {
+	oec := &v1alpha1.OverlayExtensionConfig{}
+	require.NoError(t, cli.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, oec))
+	require.Len(t, oec.Status.Nodes, 1)
+	return oec.Status.Nodes[0]
+}
+
+func TestReconcile(t *testing.T) {
+	logger.InitLogger("", 0, 0, "")
+	cli := crdfake.NewClientBuilder(overlayextensionconfig.Scheme).
+		WithStatusSubresource(&v1alpha1.OverlayExtensionConfig{}).
+		WithObjects(newOEC("a", "10.1.0.0/16"), newOEC("b", "fd00::1/64"), newOEC("bad", "not-a-cidr")).
+		Build()
+	programmer := &fakeProgrammer{}
+	r := NewReconciler(overlayextensionconfig.NewClient(cli), "node1", programmer)
+
+	_, err := r.Reconcile(context.Background(), reconcile.Request{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16"), netip.MustParsePrefix("fd00::/64")}, programmer.prefixes)
+	assert.Equal(t, v1alpha1.NodeStatus{Node: "node1", State: v1alpha1.Succeeded, ObservedGeneration: 1}, nodeStatus(t, cli, "a"))
+	assert.Equal(t, v1alpha1.Succeeded, nodeStatus(t, cli, "b").State)
+	assert.Equal(t, v1alpha1.Failed, nodeStatus(t, cli, "bad").State)
+
+	// programming failures are reported on the node's status and retried.
+	programmer.err = errors.New("iptables is broken")
+	_, err = r.Reconcile(context.Background(), reconcile.Request{})
+	require.ErrorIs(t, err, programmer.err)
+	got := nodeStatus(t, cli, "a")
+	assert.Equal(t, v1alpha1.Failed, got.State)
+	assert.Equal(t, "iptables is broken", got.Message)
+
+	// deleted configs are removed from the node.
+	programmer.err = nil
+	require.NoError(t, cli.Delete(context.Background(), newOEC("b", "")))
+	_, err = r.Reconcile(context.Background(), reconcile.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}, programmer.prefixes)
+}
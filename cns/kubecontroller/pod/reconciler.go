@@ -4,6 +4,7 @@ import (
 	"context"
 	"strconv"
 
+	"github.com/Azure/azure-container-networking/cns/metric"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
@@ -104,7 +105,7 @@ func (p *watcher) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error
 				return false
 			},
 		}).
-		Complete(p); err != nil {
+		Complete(metric.InstrumentReconciler("pod", p)); err != nil {
 		return errors.Wrap(err, "failed to set up pod watcher with manager")
 	}
 	return nil
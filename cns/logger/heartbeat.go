@@ -10,21 +10,8 @@ import (
 	"github.com/Azure/azure-container-networking/aitelemetry"
 )
 
-func SendHeartBeat(ctx context.Context, heartbeatIntervalInMins int) {
-	ticker := time.NewTicker(time.Minute * time.Duration(heartbeatIntervalInMins))
-	defer ticker.Stop()
-	metric := aitelemetry.Metric{
-		Name: HeartBeatMetricStr,
-		// This signifies 1 heartbeat is sent. Sum of this metric will give us number of heartbeats received
-		Value:            1.0,
-		CustomDimensions: make(map[string]string),
-	}
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			SendMetric(metric)
-		}
-	}
+// SendHeartBeat sends the CNS heartbeat, carrying the build version, feature toggles and health
+// counters of hb, every heartbeatIntervalInMins until ctx is done.
+func SendHeartBeat(ctx context.Context, heartbeatIntervalInMins int, hb *aitelemetry.Heartbeat) {
+	hb.Run(ctx, time.Minute*time.Duration(heartbeatIntervalInMins), SendMetric)
 }
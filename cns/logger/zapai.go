@@ -0,0 +1,22 @@
+package logger
+
+import (
+	"github.com/Azure/azure-container-networking/aitelemetry"
+	"github.com/Azure/azure-container-networking/zapai"
+	"github.com/pkg/errors"
+)
+
+// NewZapAICore creates a zap core which forwards logs to appinsights in batches, with the same appinsights
+// configuration as the CNS telemetry. The build time instrumentation key is used unless one is passed.
+// The core must be closed to send the buffered logs.
+func NewZapAICore(aiConfig aitelemetry.AIConfig, instrumentationKey string, batchConfig *zapai.BatchConfig) (*zapai.BatchCore, error) {
+	if instrumentationKey == "" {
+		instrumentationKey = aiMetadata
+	}
+	telemetryConfig, err := aitelemetry.NewTelemetryConfiguration(instrumentationKey, aiConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create appinsights configuration")
+	}
+	batchConfig.TelemetryConfiguration = *telemetryConfig
+	return zapai.NewBatchCore(batchConfig), nil
+}
@@ -0,0 +1,45 @@
+package metric
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestInstrumentReconciler(t *testing.T) {
+	var inFlight float64
+	var res reconcile.Result
+	var err error
+	r := InstrumentReconciler("test", reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+		inFlight = testutil.ToFloat64(reconcileInFlight.WithLabelValues("test"))
+		return res, err
+	}))
+
+	_, gotErr := r.Reconcile(context.Background(), reconcile.Request{})
+	assert.NoError(t, gotErr)
+	assert.Equal(t, float64(1), inFlight)
+	assert.Equal(t, float64(0), testutil.ToFloat64(reconcileInFlight.WithLabelValues("test")))
+
+	res = reconcile.Result{RequeueAfter: time.Second}
+	gotRes, gotErr := r.Reconcile(context.Background(), reconcile.Request{})
+	assert.NoError(t, gotErr)
+	assert.Equal(t, res, gotRes)
+
+	res = reconcile.Result{}
+	err = apierrors.NewConflict(schema.GroupResource{Resource: "tests"}, "test", nil)
+	_, gotErr = r.Reconcile(context.Background(), reconcile.Request{})
+	assert.ErrorIs(t, gotErr, err)
+	err = context.Canceled
+	_, gotErr = r.Reconcile(context.Background(), reconcile.Request{})
+	assert.ErrorIs(t, gotErr, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(reconcileErrors.WithLabelValues("test", "Conflict")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(reconcileErrors.WithLabelValues("test", "Unknown")))
+	assert.Equal(t, 3, testutil.CollectAndCount(reconcileLatency, "crd_reconcile_latency_seconds"))
+}
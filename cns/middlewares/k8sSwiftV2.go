@@ -30,8 +30,20 @@ const (
 	overlayGatewayV6 = "fe80::1234:5678:9abc"
 )
 
+// AssignmentTracker tracks the delegated NIC assignment of the pods on the node.
+type AssignmentTracker interface {
+	// Describe describes the progress of the delegated NIC assignment of the pod.
+	Describe(k8stypes.NamespacedName) (string, bool)
+	// Reserve reserves the delegated NIC with the MAC address for the pod, failing if it is
+	// reserved for another pod.
+	Reserve(k8stypes.NamespacedName, string) error
+}
+
 type K8sSWIFTv2Middleware struct {
 	Cli client.Client
+	// Assignments, if set, is used to explain why a pod's MTPNC is not ready, and to reserve the
+	// delegated NIC for the pod before it is handed to the pod.
+	Assignments AssignmentTracker
 }
 
 // Verify interface compliance at compile time
@@ -126,7 +138,7 @@ func (m *K8sSWIFTv2Middleware) validateIPConfigsRequest(ctx context.Context, req
 		}
 		// Check if the MTPNC CRD is ready. If one of the fields is empty, return error
 		if mtpnc.Status.PrimaryIP == "" || mtpnc.Status.MacAddress == "" || mtpnc.Status.NCID == "" || mtpnc.Status.GatewayIP == "" {
-			return nil, types.UnexpectedError, errMTPNCNotReady.Error()
+			return nil, types.UnexpectedError, m.mtpncNotReady(mtpncNamespacedName).Error()
 		}
 	}
 	logger.Printf("[SWIFTv2Middleware] pod %s has secondary interface : %v", podInfo.Name(), req.SecondaryInterfacesExist)
@@ -145,9 +157,14 @@ func (m *K8sSWIFTv2Middleware) getIPConfig(ctx context.Context, podInfo cns.PodI
 
 	// Check if the MTPNC CRD is ready. If one of the fields is empty, return error
 	if mtpnc.Status.PrimaryIP == "" || mtpnc.Status.MacAddress == "" || mtpnc.Status.NCID == "" || mtpnc.Status.GatewayIP == "" {
-		return cns.PodIpInfo{}, errMTPNCNotReady
+		return cns.PodIpInfo{}, m.mtpncNotReady(mtpncNamespacedName)
 	}
 	logger.Printf("[SWIFTv2Middleware] mtpnc for pod %s is : %+v", podInfo.Name(), mtpnc)
+	if m.Assignments != nil {
+		if err := m.Assignments.Reserve(mtpncNamespacedName, mtpnc.Status.MacAddress); err != nil {
+			return cns.PodIpInfo{}, errors.Wrapf(err, "failed to reserve delegated nic for pod %s", podInfo.Name())
+		}
+	}
 
 	// Parse MTPNC primaryIP to get the IP address and prefix length
 	p, err := netip.ParsePrefix(mtpnc.Status.PrimaryIP)
@@ -174,6 +191,17 @@ func (m *K8sSWIFTv2Middleware) getIPConfig(ctx context.Context, podInfo cns.PodI
 	return podIPInfo, nil
 }
 
+// mtpncNotReady returns errMTPNCNotReady, with the progress of the delegated NIC assignment if it is known.
+func (m *K8sSWIFTv2Middleware) mtpncNotReady(key k8stypes.NamespacedName) error {
+	if m.Assignments == nil {
+		return errMTPNCNotReady
+	}
+	if desc, ok := m.Assignments.Describe(key); ok {
+		return errors.Wrap(errMTPNCNotReady, desc)
+	}
+	return errMTPNCNotReady
+}
+
 // setRoutes sets the routes for podIPInfo used in SWIFT V2 scenario.
 func (m *K8sSWIFTv2Middleware) setRoutes(podIPInfo *cns.PodIpInfo) error {
 	logger.Printf("[SWIFTv2Middleware] set routes for pod with nic type : %s", podIPInfo.NICType)
@@ -10,7 +10,9 @@ import (
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/middlewares/mock"
 	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
 	"gotest.tools/v3/assert"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 )
 
 var (
@@ -197,6 +199,48 @@ func TestGetSWIFTv2IPConfigFailure(t *testing.T) {
 	assert.Error(t, err, errMTPNCNotReady.Error())
 }
 
+type assignmentTracker struct {
+	descs      map[k8stypes.NamespacedName]string
+	reserveErr error
+}
+
+func (a assignmentTracker) Describe(key k8stypes.NamespacedName) (string, bool) {
+	desc, ok := a.descs[key]
+	return desc, ok
+}
+
+func (a assignmentTracker) Reserve(k8stypes.NamespacedName, string) error {
+	return a.reserveErr
+}
+
+func TestGetSWIFTv2IPConfigNotReadyReason(t *testing.T) {
+	middleware := K8sSWIFTv2Middleware{
+		Cli: mock.NewClient(),
+		Assignments: assignmentTracker{descs: map[k8stypes.NamespacedName]string{
+			{Namespace: "testpod4namespace", Name: "testpod4"}: "Pending (PodNetworkInstanceNotReady): 1 of 2 IPs reserved",
+		}},
+	}
+
+	// The assignment progress of the pod is included in the error
+	_, err := middleware.getIPConfig(context.TODO(), testPod4Info)
+	assert.Assert(t, errors.Is(err, errMTPNCNotReady))
+	assert.ErrorContains(t, err, "1 of 2 IPs reserved")
+
+	// Pods without a known assignment get the plain error
+	middleware.Assignments = assignmentTracker{}
+	_, err = middleware.getIPConfig(context.TODO(), testPod4Info)
+	assert.Equal(t, err, errMTPNCNotReady)
+}
+
+func TestGetSWIFTv2IPConfigNICReserved(t *testing.T) {
+	errReserved := errors.New("nic is reserved for another pod")
+	middleware := K8sSWIFTv2Middleware{Cli: mock.NewClient(), Assignments: assignmentTracker{reserveErr: errReserved}}
+
+	// The NIC is not handed to the pod while it is reserved for another pod
+	_, err := middleware.getIPConfig(context.TODO(), testPod1Info)
+	assert.Assert(t, errors.Is(err, errReserved))
+}
+
 func TestSetRoutesSuccess(t *testing.T) {
 	middleware := K8sSWIFTv2Middleware{Cli: mock.NewClient()}
 	t.Setenv(configuration.EnvPodCIDRs, "10.0.1.10/24,16A0:0010:AB00:001E::2/32")
@@ -35,32 +35,18 @@ func (m *MockcnsRESTservice) EXPECT() *MockcnsRESTserviceMockRecorder {
 	return m.recorder
 }
 
-// CreateOrUpdateNetworkContainerInternal mocks base method.
-func (m *MockcnsRESTservice) CreateOrUpdateNetworkContainerInternal(arg0 *cns.CreateNetworkContainerRequest) types.ResponseCode {
+// DeprovisionNetworkContainerInternal mocks base method.
+func (m *MockcnsRESTservice) DeprovisionNetworkContainerInternal(arg0 cns.DeprovisionNetworkContainerRequest) cns.DeprovisionNetworkContainerResponse {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateOrUpdateNetworkContainerInternal", arg0)
-	ret0, _ := ret[0].(types.ResponseCode)
+	ret := m.ctrl.Call(m, "DeprovisionNetworkContainerInternal", arg0)
+	ret0, _ := ret[0].(cns.DeprovisionNetworkContainerResponse)
 	return ret0
 }
 
-// CreateOrUpdateNetworkContainerInternal indicates an expected call of CreateOrUpdateNetworkContainerInternal.
-func (mr *MockcnsRESTserviceMockRecorder) CreateOrUpdateNetworkContainerInternal(arg0 interface{}) *gomock.Call {
+// DeprovisionNetworkContainerInternal indicates an expected call of DeprovisionNetworkContainerInternal.
+func (mr *MockcnsRESTserviceMockRecorder) DeprovisionNetworkContainerInternal(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrUpdateNetworkContainerInternal", reflect.TypeOf((*MockcnsRESTservice)(nil).CreateOrUpdateNetworkContainerInternal), arg0)
-}
-
-// DeleteNetworkContainerInternal mocks base method.
-func (m *MockcnsRESTservice) DeleteNetworkContainerInternal(arg0 cns.DeleteNetworkContainerRequest) types.ResponseCode {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteNetworkContainerInternal", arg0)
-	ret0, _ := ret[0].(types.ResponseCode)
-	return ret0
-}
-
-// DeleteNetworkContainerInternal indicates an expected call of DeleteNetworkContainerInternal.
-func (mr *MockcnsRESTserviceMockRecorder) DeleteNetworkContainerInternal(arg0 interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNetworkContainerInternal", reflect.TypeOf((*MockcnsRESTservice)(nil).DeleteNetworkContainerInternal), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeprovisionNetworkContainerInternal", reflect.TypeOf((*MockcnsRESTservice)(nil).DeprovisionNetworkContainerInternal), arg0)
 }
 
 // GetNetworkContainerInternal mocks base method.
@@ -77,3 +63,17 @@ func (mr *MockcnsRESTserviceMockRecorder) GetNetworkContainerInternal(arg0 inter
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNetworkContainerInternal", reflect.TypeOf((*MockcnsRESTservice)(nil).GetNetworkContainerInternal), arg0)
 }
+
+// ProvisionNetworkContainerInternal mocks base method.
+func (m *MockcnsRESTservice) ProvisionNetworkContainerInternal(arg0 *cns.ProvisionNetworkContainerRequest) cns.ProvisionNetworkContainerResponse {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProvisionNetworkContainerInternal", arg0)
+	ret0, _ := ret[0].(cns.ProvisionNetworkContainerResponse)
+	return ret0
+}
+
+// ProvisionNetworkContainerInternal indicates an expected call of ProvisionNetworkContainerInternal.
+func (mr *MockcnsRESTserviceMockRecorder) ProvisionNetworkContainerInternal(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvisionNetworkContainerInternal", reflect.TypeOf((*MockcnsRESTservice)(nil).ProvisionNetworkContainerInternal), arg0)
+}
@@ -2,7 +2,6 @@ package multitenantoperator
 
 import (
 	"context"
-	"errors"
 	"os"
 	"sync"
 
@@ -10,19 +9,22 @@ import (
 	"github.com/Azure/azure-container-networking/cns/multitenantcontroller"
 	"github.com/Azure/azure-container-networking/cns/restserver"
 	"github.com/Azure/azure-container-networking/crd"
-	ncapi "github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1alpha1"
-	"k8s.io/apimachinery/pkg/runtime"
-	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer"
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1beta1"
+	acnscheme "github.com/Azure/azure-container-networking/crd/scheme"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
-	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
 const (
-	nodeNameEnvVar    = "NODENAME"
-	prometheusAddress = "0" // 0 means disabled
+	nodeNameEnvVar = "NODENAME"
 )
 
 var _ (multitenantcontroller.RequestController) = (*requestController)(nil)
@@ -37,8 +39,10 @@ type requestController struct {
 	lock       sync.Mutex
 }
 
-// New creates a new multi-tenant CRD operator.
-func New(restService *restserver.HTTPRestService, kubeconfig *rest.Config) (*requestController, error) {
+// New creates a new multi-tenant CRD operator. The manager is created with the passed Options, which
+// have their Scheme and Cache set by the operator. If the Options set a WebhookServer, the
+// MultiTenantNetworkContainer validating and conversion webhooks are served from it.
+func New(restService *restserver.HTTPRestService, kubeconfig *rest.Config, opts ctrl.Options) (*requestController, error) {
 	// Check that logger package has been initialized.
 	if logger.Log == nil {
 		return nil, errors.New("Must initialize logger before calling")
@@ -50,26 +54,46 @@ func New(restService *restserver.HTTPRestService, kubeconfig *rest.Config) (*req
 		return nil, errors.New("Must declare " + nodeNameEnvVar + " environment variable.")
 	}
 
-	// Add client-go scheme to runtime scheme so manager can recognize it.
-	scheme := runtime.NewScheme()
-	if err := clientgoscheme.AddToScheme(scheme); err != nil {
-		return nil, errors.New("Error adding client-go scheme to runtime scheme")
-	}
-
-	// Add CRD scheme to runtime sheme so manager can recognize it.
-	if err := ncapi.AddToScheme(scheme); err != nil {
-		return nil, errors.New("Error adding NetworkContainer scheme to runtime scheme")
+	// Build a scheme with the client-go and ACN CRD types so manager can recognize them.
+	scheme, err := acnscheme.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build runtime scheme")
 	}
 
 	// Create manager for multiTenantController.
-	mgr, err := ctrl.NewManager(kubeconfig, ctrl.Options{
-		Scheme:  scheme,
-		Metrics: ctrlmetrics.Options{BindAddress: prometheusAddress},
-	})
+	// Only cache the Pods on this node, which are watched to release the NCs they consumed.
+	opts.Scheme = scheme
+	opts.Cache = cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.Pod{}: {
+				Field: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}),
+			},
+		},
+	}
+	mgr, err := ctrl.NewManager(kubeconfig, opts)
 	if err != nil {
 		logger.Errorf("Error creating new multiTenantController: %v", err)
 		return nil, err
 	}
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		logger.Errorf("Error adding healthz check to multiTenantController: %v", err)
+		return nil, err
+	}
+	if err := mgr.AddReadyzCheck("ping", healthz.Ping); err != nil {
+		logger.Errorf("Error adding readyz check to multiTenantController: %v", err)
+		return nil, err
+	}
+
+	if opts.WebhookServer != nil {
+		if err := multitenantnetworkcontainer.SetupWebhookWithManager(mgr); err != nil {
+			logger.Errorf("Error setting up multiTenantController validating webhook: %v", err)
+			return nil, err
+		}
+		if err := v1beta1.SetupWebhookWithManager(mgr); err != nil {
+			logger.Errorf("Error setting up multiTenantController conversion webhook: %v", err)
+			return nil, err
+		}
+	}
 
 	// Create multiTenantCrdReconciler
 	reconciler := &multiTenantCrdReconciler{
@@ -8,6 +8,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 var _ = Describe("multiTenantController", func() {
@@ -20,7 +21,7 @@ var _ = Describe("multiTenantController", func() {
 		kubeconfig := &rest.Config{}
 
 		It("Should exist with an error when nodeName is not set", func() {
-			ctl, err := New(restService, kubeconfig)
+			ctl, err := New(restService, kubeconfig, ctrl.Options{})
 			Expect(ctl).To(BeNil())
 			Expect(err).NotTo(BeNil())
 			Expect(err.Error()).To(Equal("Must declare NODENAME environment variable."))
@@ -29,7 +30,7 @@ var _ = Describe("multiTenantController", func() {
 		It("Should report an error when apiserver is not available", func() {
 			val := os.Getenv(nodeNameEnvVar)
 			os.Setenv(nodeNameEnvVar, "nodeName")
-			ctl, err := New(nil, nil)
+			ctl, err := New(nil, nil, ctrl.Options{})
 			os.Setenv(nodeNameEnvVar, val)
 			Expect(ctl).To(BeNil())
 			Expect(err).NotTo(BeNil())
@@ -10,14 +10,20 @@ import (
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/metric"
 	"github.com/Azure/azure-container-networking/cns/restserver"
 	"github.com/Azure/azure-container-networking/cns/types"
 	ncapi "github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -29,12 +35,15 @@ const (
 	NCStateSucceeded = "Succeeded"
 	// NCStateTerminated indicates the NC has been terminated by CNS.
 	NCStateTerminated = "Terminated"
+	// NCFinalizer is added to the NCs persisted in CNS, so that they are not deleted until CNS
+	// has removed them from the node, even if CNS is down when the NC is deleted.
+	NCFinalizer = "networking.azure.com/cns-cleanup"
 )
 
 type cnsRESTservice interface {
-	DeleteNetworkContainerInternal(cns.DeleteNetworkContainerRequest) types.ResponseCode
+	DeprovisionNetworkContainerInternal(cns.DeprovisionNetworkContainerRequest) cns.DeprovisionNetworkContainerResponse
 	GetNetworkContainerInternal(cns.GetNetworkContainerRequest) (cns.GetNetworkContainerResponse, types.ResponseCode)
-	CreateOrUpdateNetworkContainerInternal(*cns.CreateNetworkContainerRequest) types.ResponseCode
+	ProvisionNetworkContainerInternal(*cns.ProvisionNetworkContainerRequest) cns.ProvisionNetworkContainerResponse
 }
 
 // multiTenantCrdReconciler reconciles multi-tenant network containers.
@@ -60,31 +69,28 @@ func (r *multiTenantCrdReconciler) Reconcile(ctx context.Context, request reconc
 	}
 
 	if !nc.ObjectMeta.DeletionTimestamp.IsZero() {
-		// Do nothing if the NC has already in Terminated state.
+		// Only release the NC if it has not already been terminated.
 		if nc.Status.State == NCStateTerminated {
-			logger.Printf("MultiTenantNetworkContainer %s already terminated, skip reconciling", request.NamespacedName.String())
-			return ctrl.Result{}, nil
+			logger.Printf("MultiTenantNetworkContainer %s already terminated, skip releasing", request.NamespacedName.String())
+		} else if err := r.release(ctx, &nc, "NC has been removed from CNS"); err != nil {
+			return ctrl.Result{}, err
 		}
 
-		// Remove the deleted network container from CNS.
-		responseCode := r.CNSRestService.DeleteNetworkContainerInternal(cns.DeleteNetworkContainerRequest{
-			NetworkContainerid: nc.Spec.UUID,
-		})
-		err := restserver.ResponseCodeToError(responseCode)
+		return ctrl.Result{}, r.removeFinalizer(ctx, &nc)
+	}
+
+	// Release the NC as soon as the Pod consuming it is gone, instead of waiting for the NC to be deleted.
+	if nc.Status.State == NCStateSucceeded {
+		gone, err := r.podGone(ctx, request.NamespacedName)
 		if err != nil {
-			logger.Errorf("Failed to delete NC %s (UUID: %s) from CNS: %v", request.NamespacedName.String(), nc.Spec.UUID, err)
+			logger.Errorf("Failed to fetch pod for NC %s (UUID: %s): %v", request.NamespacedName.String(), nc.Spec.UUID, err)
 			return ctrl.Result{}, err
 		}
-
-		// Update NC state to Terminated.
-		nc.Status.State = NCStateTerminated
-		if err := r.KubeClient.Status().Update(ctx, &nc); err != nil {
-			logger.Errorf("Failed to update network container state for %s (UUID: %s): %v", request.NamespacedName.String(), nc.Spec.UUID, err)
-			return ctrl.Result{}, err
+		if gone {
+			return ctrl.Result{}, r.release(ctx, &nc, "Pod consuming the NC has been deleted")
 		}
-
-		logger.Printf("NC has been terminated for %s (UUID: %s)", request.NamespacedName.String(), nc.Spec.UUID)
-		return ctrl.Result{}, nil
+		// NCs persisted before the finalizer was introduced get it here.
+		return ctrl.Result{}, r.addFinalizer(ctx, &nc)
 	}
 
 	// Do nothing if the network container hasn't been initialized yet from control plane.
@@ -112,7 +118,7 @@ func (r *multiTenantCrdReconciler) Reconcile(ctx context.Context, request reconc
 	err = restserver.ResponseCodeToError(returnCode)
 	if err == nil {
 		logger.Printf("NC %s (UUID: %s) has already been created in CNS", request.NamespacedName.String(), nc.Spec.UUID)
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.addFinalizer(ctx, &nc)
 	}
 
 	// return any error except UnknownContainerID
@@ -154,9 +160,17 @@ func (r *multiTenantCrdReconciler) Reconcile(ctx context.Context, request reconc
 			ID:        int(nc.Status.MultiTenantInfo.ID),
 		},
 	}
+	// The finalizer must be in place before the NC is persisted, so that it cannot be deleted without
+	// being removed from CNS.
+	if err := r.addFinalizer(ctx, &nc); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	logger.Printf("CreateOrUpdateNC with networkContainerRequest: %#v", networkContainerRequest)
-	responseCode := r.CNSRestService.CreateOrUpdateNetworkContainerInternal(networkContainerRequest)
-	err = restserver.ResponseCodeToError(responseCode)
+	resp := r.CNSRestService.ProvisionNetworkContainerInternal(&cns.ProvisionNetworkContainerRequest{
+		NetworkContainer: *networkContainerRequest,
+	})
+	err = restserver.ResponseCodeToError(resp.Response.ReturnCode)
 	if err != nil {
 		logger.Errorf("Failed to persist state for NC %s (UUID: %s) to CNS: %v", request.NamespacedName.String(), nc.Spec.UUID, err)
 		return ctrl.Result{}, err
@@ -173,12 +187,105 @@ func (r *multiTenantCrdReconciler) Reconcile(ctx context.Context, request reconc
 	return reconcile.Result{}, nil
 }
 
+// release removes the NC from CNS and sets its state to Terminated.
+func (r *multiTenantCrdReconciler) release(ctx context.Context, nc *ncapi.MultiTenantNetworkContainer, message string) error {
+	name := k8stypes.NamespacedName{Namespace: nc.Namespace, Name: nc.Name}.String()
+	resp := r.CNSRestService.DeprovisionNetworkContainerInternal(cns.DeprovisionNetworkContainerRequest{
+		NetworkContainerID: nc.Spec.UUID,
+	})
+	if err := restserver.ResponseCodeToError(resp.Response.ReturnCode); err != nil {
+		logger.Errorf("Failed to delete NC %s (UUID: %s) from CNS: %v", name, nc.Spec.UUID, err)
+		return err
+	}
+
+	nc.Status.State = NCStateTerminated
+	if err := r.KubeClient.Status().Update(ctx, nc); err != nil {
+		logger.Errorf("Failed to update network container state for %s (UUID: %s): %v", name, nc.Spec.UUID, err)
+		return err
+	}
+
+	logger.Printf("NC has been terminated for %s (UUID: %s): %s", name, nc.Spec.UUID, message)
+	return nil
+}
+
+// addFinalizer adds the NCFinalizer to the NC if it is missing.
+func (r *multiTenantCrdReconciler) addFinalizer(ctx context.Context, nc *ncapi.MultiTenantNetworkContainer) error {
+	if controllerutil.ContainsFinalizer(nc, NCFinalizer) {
+		return nil
+	}
+	patch := client.MergeFromWithOptions(nc.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.AddFinalizer(nc, NCFinalizer)
+	if err := r.KubeClient.Patch(ctx, nc, patch); err != nil {
+		logger.Errorf("Failed to add finalizer to NC %s/%s (UUID: %s): %v", nc.Namespace, nc.Name, nc.Spec.UUID, err)
+		return err
+	}
+	return nil
+}
+
+// removeFinalizer removes the NCFinalizer from the NC, allowing it to be deleted.
+func (r *multiTenantCrdReconciler) removeFinalizer(ctx context.Context, nc *ncapi.MultiTenantNetworkContainer) error {
+	if !controllerutil.ContainsFinalizer(nc, NCFinalizer) {
+		return nil
+	}
+	patch := client.MergeFromWithOptions(nc.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.RemoveFinalizer(nc, NCFinalizer)
+	if err := r.KubeClient.Patch(ctx, nc, patch); err != nil {
+		logger.Errorf("Failed to remove finalizer from NC %s/%s (UUID: %s): %v", nc.Namespace, nc.Name, nc.Spec.UUID, err)
+		return err
+	}
+	logger.Printf("Removed finalizer from NC %s/%s (UUID: %s)", nc.Namespace, nc.Name, nc.Spec.UUID)
+	return nil
+}
+
+// podGone returns whether the Pod consuming the NC, which shares the NC's name and namespace,
+// has been deleted or has run to completion.
+func (r *multiTenantCrdReconciler) podGone(ctx context.Context, key k8stypes.NamespacedName) (bool, error) {
+	var pod corev1.Pod
+	if err := r.KubeClient.Get(ctx, key, &pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return podTerminal(&pod), nil
+}
+
+func podTerminal(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
 // SetupWithManager Sets up the reconciler with a new manager, filtering using NodeNetworkConfigFilter
 func (r *multiTenantCrdReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&ncapi.MultiTenantNetworkContainer{}).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(podToNC), builder.WithPredicates(podReleasePredicate())).
 		WithEventFilter(r.predicate()).
-		Complete(r)
+		Complete(metric.InstrumentReconciler("multitenantnetworkcontainer", r))
+}
+
+// podToNC maps a Pod to the NC it consumes, which shares the Pod's name and namespace.
+func podToNC(_ context.Context, o client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}}}
+}
+
+// podReleasePredicate passes the Pod events after which the NC consumed by the Pod can be released.
+func podReleasePredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool {
+			return false
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod, okOld := e.ObjectOld.(*corev1.Pod)
+			newPod, okNew := e.ObjectNew.(*corev1.Pod)
+			return okOld && okNew && !podTerminal(oldPod) && podTerminal(newPod)
+		},
+		DeleteFunc: func(event.DeleteEvent) bool {
+			return true
+		},
+	}
 }
 
 func (r *multiTenantCrdReconciler) predicate() predicate.Predicate {
@@ -199,9 +306,11 @@ func (r *multiTenantCrdReconciler) predicate() predicate.Predicate {
 }
 
 func (r *multiTenantCrdReconciler) equalNode(o runtime.Object) bool {
-	nc, ok := o.(*ncapi.MultiTenantNetworkContainer)
-	if ok {
-		return strings.EqualFold(nc.Spec.Node, r.NodeName)
+	switch obj := o.(type) {
+	case *ncapi.MultiTenantNetworkContainer:
+		return strings.EqualFold(obj.Spec.Node, r.NodeName)
+	case *corev1.Pod:
+		return strings.EqualFold(obj.Spec.NodeName, r.NodeName)
 	}
 
 	return false
@@ -3,6 +3,7 @@ package multitenantoperator
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/logger"
@@ -12,10 +13,14 @@ import (
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -49,6 +54,14 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 		}
 	})
 
+	expectFinalizer := func(present bool) *gomock.Call {
+		return kubeClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&ncapi.MultiTenantNetworkContainer{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+				Expect(controllerutil.ContainsFinalizer(obj, NCFinalizer)).To(Equal(present))
+				return nil
+			})
+	}
+
 	Context("lifecycle", func() {
 		It("Should succeed when the NC has already been deleted", func() {
 			expectedError := &apierrors.StatusError{
@@ -122,8 +135,9 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 						EncapType: "Vlan",
 						ID:        1,
 					},
-					IPSubnet: "10.0.0.0/8",
-					IP:       "10.1.0.0",
+					IPSubnet:                   "10.0.0.0/8",
+					IP:                         "10.1.0.0",
+					PrimaryInterfaceIdentifier: "10.240.0.4/16",
 				},
 			}
 
@@ -136,7 +150,8 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 				OrchestratorContext: orchestratorContext,
 			}).Return(cns.GetNetworkContainerResponse{}, cnstypes.UnknownContainerID)
 
-			cnsRestService.EXPECT().CreateOrUpdateNetworkContainerInternal(&cns.CreateNetworkContainerRequest{
+			addFinalizer := expectFinalizer(true)
+			cnsRestService.EXPECT().ProvisionNetworkContainerInternal(&cns.ProvisionNetworkContainerRequest{NetworkContainer: cns.CreateNetworkContainerRequest{
 				NetworkContainerid:   nc.Spec.UUID,
 				OrchestratorContext:  orchestratorContext,
 				NetworkContainerType: cns.Kubernetes,
@@ -153,11 +168,15 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 					EncapType: nc.Status.MultiTenantInfo.EncapType,
 					ID:        int(nc.Status.MultiTenantInfo.ID),
 				},
-			}).Return(cnstypes.Success)
+			}}).Return(provisioned(cnstypes.Success)).After(addFinalizer)
 
 			kubeClient.EXPECT().Status().DoAndReturn(func() client.SubResourceWriter {
-				nc.Status.State = NCStateSucceeded
-				statusWriter.EXPECT().Update(gomock.Any(), gomock.Any()).SetArg(1, nc)
+				statusWriter.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+						updated := obj.(*ncapi.MultiTenantNetworkContainer)
+						Expect(updated.Status.State).To(Equal(NCStateSucceeded))
+						return nil
+					})
 				return statusWriter
 			})
 
@@ -194,6 +213,7 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 				NetworkContainerid:  uuid,
 				OrchestratorContext: orchestratorContext,
 			}).Return(cns.GetNetworkContainerResponse{}, cnstypes.Success)
+			expectFinalizer(true)
 			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{
 				NamespacedName: namespacedName,
 			})
@@ -286,11 +306,168 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 				NetworkContainerid:  uuid,
 				OrchestratorContext: orchestratorContext,
 			}).Return(cns.GetNetworkContainerResponse{}, cnstypes.Success)
-			cnsRestService.EXPECT().CreateOrUpdateNetworkContainerInternal(networkContainerRequest).Return(cnstypes.Success)
+			expectFinalizer(true)
+			cnsRestService.EXPECT().ProvisionNetworkContainerInternal(&cns.ProvisionNetworkContainerRequest{
+				NetworkContainer: networkContainerRequest,
+			}).Return(provisioned(cnstypes.Success))
 			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{
 				NamespacedName: namespacedName,
 			})
 			Expect(err).To(BeNil())
 		})
 	})
+
+	Context("pod deletion", func() {
+		var nc ncapi.MultiTenantNetworkContainer
+
+		BeforeEach(func() {
+			nc = ncapi.MultiTenantNetworkContainer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      namespacedName.Name,
+					Namespace: namespacedName.Namespace,
+				},
+				Spec: ncapi.MultiTenantNetworkContainerSpec{
+					UUID: uuidValue,
+					Node: mockNodeName,
+				},
+				Status: ncapi.MultiTenantNetworkContainerStatus{
+					State: NCStateSucceeded,
+				},
+			}
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.AssignableToTypeOf(&ncapi.MultiTenantNetworkContainer{})).SetArg(2, nc)
+		})
+
+		expectRelease := func() {
+			cnsRestService.EXPECT().DeprovisionNetworkContainerInternal(cns.DeprovisionNetworkContainerRequest{
+				NetworkContainerID: uuidValue,
+			}).Return(deprovisioned(cnstypes.Success))
+			kubeClient.EXPECT().Status().DoAndReturn(func() client.SubResourceWriter {
+				statusWriter.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+						updated := obj.(*ncapi.MultiTenantNetworkContainer)
+						Expect(updated.Status.State).To(Equal(NCStateTerminated))
+						return nil
+					})
+				return statusWriter
+			})
+		}
+
+		It("Should not release the NC while its pod is running", func() {
+			pod := corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.AssignableToTypeOf(&corev1.Pod{})).SetArg(2, pod)
+			expectFinalizer(true)
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: namespacedName})
+			Expect(err).To(BeNil())
+		})
+
+		It("Should release the NC when its pod has been deleted", func() {
+			notFound := apierrors.NewNotFound(corev1.Resource("pods"), namespacedName.Name)
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.AssignableToTypeOf(&corev1.Pod{})).Return(notFound)
+			expectRelease()
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: namespacedName})
+			Expect(err).To(BeNil())
+		})
+
+		It("Should release the NC when its pod has completed", func() {
+			pod := corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}}
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.AssignableToTypeOf(&corev1.Pod{})).SetArg(2, pod)
+			expectRelease()
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: namespacedName})
+			Expect(err).To(BeNil())
+		})
+
+		It("Should fail when the pod cannot be fetched", func() {
+			internalErr := apierrors.NewInternalError(errors.New("boom"))
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.AssignableToTypeOf(&corev1.Pod{})).Return(internalErr)
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: namespacedName})
+			Expect(err).To(Equal(internalErr))
+		})
+	})
+
+	Context("finalizer", func() {
+		var nc ncapi.MultiTenantNetworkContainer
+
+		BeforeEach(func() {
+			nc = ncapi.MultiTenantNetworkContainer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              namespacedName.Name,
+					Namespace:         namespacedName.Namespace,
+					DeletionTimestamp: &metav1.Time{Time: time.Now()},
+					Finalizers:        []string{NCFinalizer},
+				},
+				Spec: ncapi.MultiTenantNetworkContainerSpec{
+					UUID: uuidValue,
+					Node: mockNodeName,
+				},
+				Status: ncapi.MultiTenantNetworkContainerStatus{
+					State: NCStateSucceeded,
+				},
+			}
+		})
+
+		It("Should remove the NC from CNS before removing the finalizer", func() {
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			deleteNC := cnsRestService.EXPECT().DeprovisionNetworkContainerInternal(cns.DeprovisionNetworkContainerRequest{
+				NetworkContainerID: uuidValue,
+			}).Return(deprovisioned(cnstypes.Success))
+			statusWriter.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+			kubeClient.EXPECT().Status().Return(statusWriter)
+			expectFinalizer(false).After(deleteNC)
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: namespacedName})
+			Expect(err).To(BeNil())
+		})
+
+		It("Should keep the finalizer when the NC cannot be removed from CNS", func() {
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			cnsRestService.EXPECT().DeprovisionNetworkContainerInternal(cns.DeprovisionNetworkContainerRequest{
+				NetworkContainerID: uuidValue,
+			}).Return(deprovisioned(cnstypes.UnexpectedError))
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: namespacedName})
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("Should only remove the finalizer when the NC is already terminated", func() {
+			nc.Status.State = NCStateTerminated
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			expectFinalizer(false)
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: namespacedName})
+			Expect(err).To(BeNil())
+		})
+
+		It("Should not patch an NC which already has the finalizer", func() {
+			nc.DeletionTimestamp = nil
+			pod := corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.AssignableToTypeOf(&ncapi.MultiTenantNetworkContainer{})).SetArg(2, nc)
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.AssignableToTypeOf(&corev1.Pod{})).SetArg(2, pod)
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: namespacedName})
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("pod predicate", func() {
+		running := &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+		failed := &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodFailed}}
+
+		It("Should only pass pod deletions and transitions to a terminal phase", func() {
+			p := podReleasePredicate()
+			Expect(p.Create(event.CreateEvent{Object: running})).To(BeFalse())
+			Expect(p.Delete(event.DeleteEvent{Object: running})).To(BeTrue())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: running})).To(BeFalse())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: failed})).To(BeTrue())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: failed, ObjectNew: failed})).To(BeFalse())
+		})
+
+		It("Should only pass pods on the same node", func() {
+			Expect(reconciler.equalNode(&corev1.Pod{Spec: corev1.PodSpec{NodeName: mockNodeName}})).To(BeTrue())
+			Expect(reconciler.equalNode(&corev1.Pod{Spec: corev1.PodSpec{NodeName: "other"}})).To(BeFalse())
+		})
+	})
 })
+
+func provisioned(code cnstypes.ResponseCode) cns.ProvisionNetworkContainerResponse {
+	return cns.ProvisionNetworkContainerResponse{Response: cns.Response{ReturnCode: code}}
+}
+
+func deprovisioned(code cnstypes.ResponseCode) cns.DeprovisionNetworkContainerResponse {
+	return cns.DeprovisionNetworkContainerResponse{Response: cns.Response{ReturnCode: code}}
+}
@@ -0,0 +1,93 @@
+// Package nodecapabilities detects the dataplane capabilities of the node and publishes them in the
+// NodeCapabilities CRD of the node.
+package nodecapabilities
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"time"
+
+	"github.com/Azure/azure-container-networking/crd/nodecapabilities"
+	"github.com/Azure/azure-container-networking/crd/nodecapabilities/api/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// detectTimeout bounds the commands run to detect capabilities.
+const detectTimeout = 5 * time.Second
+
+// Detect returns the capabilities of the node the caller runs on. datapathMode is the CNI datapath
+// scenario the node is configured for, if known.
+func Detect(ctx context.Context, node *corev1.Node, datapathMode string) v1alpha1.NodeCapabilitiesStatus {
+	status := v1alpha1.NodeCapabilitiesStatus{
+		OS:           runtime.GOOS,
+		DualStack:    isDualStack(node),
+		DatapathMode: datapathMode,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, detectTimeout)
+	defer cancel()
+	detectPlatform(ctx, &status)
+	return status
+}
+
+// isDualStack returns true if the node has pod CIDRs or internal addresses of both IP families.
+func isDualStack(node *corev1.Node) bool {
+	var hasV4, hasV6 bool
+	add := func(ip net.IP) {
+		if ip == nil {
+			return
+		}
+		if ip.To4() != nil {
+			hasV4 = true
+		} else {
+			hasV6 = true
+		}
+	}
+
+	for _, cidr := range node.Spec.PodCIDRs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err == nil {
+			add(ip)
+		}
+	}
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP {
+			add(net.ParseIP(addr.Address))
+		}
+	}
+	return hasV4 && hasV6
+}
+
+// Publish creates or updates the NodeCapabilities of the node with the status, as published by the
+// publisher at the version. The NodeCapabilities is owned by the node so that it is removed with it.
+func Publish(ctx context.Context, restConfig *rest.Config, node *corev1.Node, status v1alpha1.NodeCapabilitiesStatus, publisher, version string) error {
+	directcli, err := client.New(restConfig, client.Options{Scheme: nodecapabilities.Scheme})
+	if err != nil {
+		return errors.Wrap(err, "failed to create ctrl client")
+	}
+
+	status.Publisher = publisher
+	status.PublisherVersion = version
+	status.LastUpdated = metav1.Now()
+	nodeCapabilities := &v1alpha1.NodeCapabilities{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: node.Name,
+		},
+		Status: status,
+	}
+
+	if err := controllerutil.SetOwnerReference(node, nodeCapabilities, nodecapabilities.Scheme); err != nil {
+		return errors.Wrap(err, "failed to set nodecapabilities owner reference to node")
+	}
+
+	if err := nodecapabilities.NewClient(directcli).CreateOrUpdate(ctx, nodeCapabilities, publisher); err != nil {
+		return errors.Wrap(err, "error ensuring nodecapabilities CRD exists and is up-to-date")
+	}
+	return nil
+}
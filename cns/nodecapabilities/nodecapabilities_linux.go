@@ -0,0 +1,29 @@
+package nodecapabilities
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/crd/nodecapabilities/api/v1alpha1"
+)
+
+func detectPlatform(ctx context.Context, status *v1alpha1.NodeCapabilitiesStatus) {
+	out, err := exec.CommandContext(ctx, "iptables", "--version").Output()
+	if err == nil {
+		status.IPTablesBackend = parseIPTablesBackend(string(out))
+	}
+}
+
+// parseIPTablesBackend returns the backend from the output of "iptables --version", e.g.
+// "iptables v1.8.7 (nf_tables)". iptables before 1.8 only had the legacy backend and does not print it.
+func parseIPTablesBackend(out string) v1alpha1.IPTablesBackend {
+	switch {
+	case strings.Contains(out, "(nf_tables)"):
+		return v1alpha1.IPTablesBackendNft
+	case strings.Contains(out, "iptables v"):
+		return v1alpha1.IPTablesBackendLegacy
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,15 @@
+package nodecapabilities
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/crd/nodecapabilities/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIPTablesBackend(t *testing.T) {
+	assert.Equal(t, v1alpha1.IPTablesBackendNft, parseIPTablesBackend("iptables v1.8.7 (nf_tables)\n"))
+	assert.Equal(t, v1alpha1.IPTablesBackendLegacy, parseIPTablesBackend("iptables v1.8.4 (legacy)\n"))
+	assert.Equal(t, v1alpha1.IPTablesBackendLegacy, parseIPTablesBackend("iptables v1.6.1\n"))
+	assert.Equal(t, v1alpha1.IPTablesBackend(""), parseIPTablesBackend(""))
+}
@@ -0,0 +1,61 @@
+package nodecapabilities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestIsDualStack(t *testing.T) {
+	tests := []struct {
+		name string
+		node corev1.Node
+		want bool
+	}{
+		{
+			name: "v4 only",
+			node: corev1.Node{
+				Spec: corev1.NodeSpec{PodCIDRs: []string{"10.244.0.0/24"}},
+				Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeInternalIP, Address: "10.224.0.4"},
+				}},
+			},
+			want: false,
+		},
+		{
+			name: "dual-stack pod CIDRs",
+			node: corev1.Node{
+				Spec: corev1.NodeSpec{PodCIDRs: []string{"10.244.0.0/24", "fd00:10:244::/64"}},
+			},
+			want: true,
+		},
+		{
+			name: "dual-stack internal addresses",
+			node: corev1.Node{
+				Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeInternalIP, Address: "10.224.0.4"},
+					{Type: corev1.NodeInternalIP, Address: "fd00::4"},
+				}},
+			},
+			want: true,
+		},
+		{
+			name: "external v6 address is ignored",
+			node: corev1.Node{
+				Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeInternalIP, Address: "10.224.0.4"},
+					{Type: corev1.NodeExternalIP, Address: "2001:db8::4"},
+				}},
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isDualStack(&tt.node))
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package nodecapabilities
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/crd/nodecapabilities/api/v1alpha1"
+	"github.com/Microsoft/hcsshim/hcn"
+)
+
+func detectPlatform(_ context.Context, status *v1alpha1.NodeCapabilitiesStatus) {
+	if globals, err := hcn.GetGlobals(); err == nil {
+		status.HNSVersion = fmt.Sprintf("%d.%d", globals.Version.Major, globals.Version.Minor)
+	}
+
+	status.HNSSchemaVersion = v1alpha1.HNSSchemaV1
+	if hcn.V2ApiSupported() == nil {
+		status.HNSSchemaVersion = v1alpha1.HNSSchemaV2
+	}
+}
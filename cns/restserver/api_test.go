@@ -14,6 +14,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -31,9 +32,8 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-const (
-	cnsJsonFileName = "azure-cns.json"
-)
+// cnsJsonFileName is the CNS state file of the test service, in a temporary directory created by TestMain.
+var cnsJsonFileName string
 
 type IPAddress struct {
 	XMLName   xml.Name `xml:"IPAddress"`
@@ -172,9 +172,17 @@ func TestMain(m *testing.M) {
 	var err error
 	logger.InitLogger("testlogs", 0, 0, "./")
 
+	stateDir, err := os.MkdirTemp("", "cns-restserver")
+	if err != nil {
+		fmt.Printf("Failed to create CNS state directory. Error: %v", err)
+		os.Exit(1)
+	}
+	cnsJsonFileName = filepath.Join(stateDir, "azure-cns.json")
+
 	// Create the service.
 	if err = startService(); err != nil {
 		fmt.Printf("Failed to start CNS Service. Error: %v", err)
+		os.RemoveAll(stateDir)
 		os.Exit(1)
 	}
 
@@ -204,6 +212,7 @@ func TestMain(m *testing.M) {
 	// Cleanup.
 	service.Stop()
 	nmAgentServer.Stop()
+	os.RemoveAll(stateDir)
 
 	os.Exit(exitCode)
 }
@@ -263,6 +263,9 @@ func (service *HTTPRestService) syncHostNCVersion(ctx context.Context, channelMo
 		ncInfo.HostVersion = nmaNCVersionStr
 		logger.Printf("Updated NC %s host version to %s", ncID, ncInfo.HostVersion)
 		service.state.ContainerStatus[ncID] = ncInfo
+		if nmaNCVersion > localNCVersion {
+			service.lastNCVersionUpdate = time.Now()
+		}
 		// if we successfully updated the NC, pop it from the needs update set.
 		delete(outdatedNCs, ncID)
 	}
@@ -16,6 +16,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	"github.com/Azure/azure-container-networking/store"
 	"github.com/pkg/errors"
 	"golang.org/x/exp/maps"
@@ -27,6 +28,7 @@ var (
 	ErrNoNCs                  = errors.New("no NCs found in the CNS internal state")
 	ErrOptManageEndpointState = errors.New("CNS is not set to manage the endpoint state")
 	ErrEndpointStateNotFound  = errors.New("endpoint state could not be found in the statefile")
+	ErrNoAvailableIPs         = errors.New("not enough IPs available")
 )
 
 const (
@@ -52,6 +54,16 @@ func (service *HTTPRestService) requestIPConfigHandlerHelper(ctx context.Context
 
 	podIPInfo, err := requestIPConfigsHelper(service, ipconfigsRequest) //nolint:contextcheck // appease linter for revert PR
 	if err != nil {
+		if errors.Is(err, ErrNoAvailableIPs) && service.subnetExhausted.Load() {
+			// the pool won't be scaled up while the subnet is exhausted, so the request can only be served once IPs are released
+			return &cns.IPConfigsResponse{
+				Response: cns.Response{
+					ReturnCode: types.SubnetExhausted,
+					Message:    fmt.Sprintf("AllocateIPConfig failed: subnet is exhausted: %v, IP config request is %v", err, ipconfigsRequest),
+				},
+				PodIPInfo: podIPInfo,
+			}, err
+		}
 		return &cns.IPConfigsResponse{
 			Response: cns.Response{
 				ReturnCode: types.FailedToAllocateIPConfig,
@@ -136,6 +148,7 @@ func (service *HTTPRestService) RequestIPConfigHandler(w http.ResponseWriter, r
 			Response: ipConfigsResp.Response,
 		}
 		w.Header().Set(cnsReturnCode, reserveResp.Response.ReturnCode.String())
+		writeRetryAfterIfSubnetExhausted(w, reserveResp.Response.ReturnCode)
 		err = service.Listener.Encode(w, &reserveResp)
 		logger.ResponseEx(service.Name+operationName, ipconfigsRequest, reserveResp, reserveResp.Response.ReturnCode, err)
 		return
@@ -188,6 +201,7 @@ func (service *HTTPRestService) RequestIPConfigsHandler(w http.ResponseWriter, r
 
 	if err != nil {
 		w.Header().Set(cnsReturnCode, ipConfigsResp.Response.ReturnCode.String())
+		writeRetryAfterIfSubnetExhausted(w, ipConfigsResp.Response.ReturnCode)
 		err = service.Listener.Encode(w, &ipConfigsResp)
 		logger.ResponseEx(service.Name+operationName, ipconfigsRequest, ipConfigsResp, ipConfigsResp.Response.ReturnCode, err)
 		return
@@ -450,6 +464,25 @@ func (service *HTTPRestService) MarkIPAsPendingRelease(totalIpsToRelease int) (m
 // and return an error.
 // MarkNIPsPendingRelease is no-op if [n] is not a positive integer.
 func (service *HTTPRestService) MarkNIPsPendingRelease(n int) (map[string]cns.IPConfigurationStatus, error) {
+	return service.markNIPsPendingRelease(n, func(cns.IPConfigurationStatus) bool { return true })
+}
+
+// MarkNIPsPendingReleaseForFamily is MarkNIPsPendingRelease, restricted to IPs of the passed IP family.
+// It is used to scale the v4 and v6 pools of a dual-stack Node independently.
+func (service *HTTPRestService) MarkNIPsPendingReleaseForFamily(n int, family v1alpha.IPFamily) (map[string]cns.IPConfigurationStatus, error) {
+	return service.markNIPsPendingRelease(n, func(ipConfig cns.IPConfigurationStatus) bool {
+		ip := net.ParseIP(ipConfig.IPAddress)
+		if ip == nil {
+			return false
+		}
+		if family == v1alpha.IPv6 {
+			return ip.To4() == nil
+		}
+		return ip.To4() != nil
+	})
+}
+
+func (service *HTTPRestService) markNIPsPendingRelease(n int, match func(cns.IPConfigurationStatus) bool) (map[string]cns.IPConfigurationStatus, error) {
 	service.Lock()
 	defer service.Unlock()
 	// try to release from PendingProgramming
@@ -458,7 +491,7 @@ func (service *HTTPRestService) MarkNIPsPendingRelease(n int) (map[string]cns.IP
 		if n <= 0 {
 			break
 		}
-		if ipConfig.GetState() == types.PendingProgramming {
+		if ipConfig.GetState() == types.PendingProgramming && match(ipConfig) {
 			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, ipConfig.PodInfo)
 			if err != nil {
 				return nil, err
@@ -475,7 +508,7 @@ func (service *HTTPRestService) MarkNIPsPendingRelease(n int) (map[string]cns.IP
 		if n <= 0 {
 			break
 		}
-		if ipConfig.GetState() == types.Available {
+		if ipConfig.GetState() == types.Available && match(ipConfig) {
 			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, ipConfig.PodInfo)
 			if err != nil {
 				return nil, err
@@ -509,6 +542,7 @@ func (service *HTTPRestService) updateIPConfigState(ipID string, updatedState ty
 		ipConfig.SetState(updatedState)
 		ipConfig.PodInfo = podInfo
 		service.PodIPConfigState[ipID] = ipConfig
+		service.ipConfigWatchers.publish(cns.IPConfigUpdated, ipConfig)
 		return ipConfig, nil
 	}
 
@@ -727,6 +761,7 @@ func (service *HTTPRestService) MarkExistingIPsAsPendingRelease(pendingIPIDs []s
 			logger.Printf("[MarkExistingIPsAsPending]: Marking IP [%+v] to PendingRelease", ipconfig)
 			ipconfig.SetState(types.PendingRelease)
 			service.PodIPConfigState[id] = ipconfig
+			service.ipConfigWatchers.publish(cns.IPConfigUpdated, ipconfig)
 		} else {
 			logger.Errorf("Inconsistent state, ipconfig with ID [%v] marked as pending release, but does not exist in state", id)
 		}
@@ -903,7 +938,7 @@ func (service *HTTPRestService) AssignAvailableIPConfigs(podInfo cns.PodInfo) ([
 			if _, found := ipsToAssign[ncID]; found {
 				continue
 			}
-			return podIPInfo, errors.Errorf("not enough IPs available for %s, waiting on Azure CNS to allocate more with NC Status: %s",
+			return podIPInfo, errors.Wrapf(ErrNoAvailableIPs, "no IP available for %s, waiting on Azure CNS to allocate more with NC Status: %s",
 				ncID, string(service.state.ContainerStatus[ncID].CreateNetworkContainerRequest.NCStatus))
 		}
 	}
@@ -15,9 +15,11 @@ import (
 	"github.com/Azure/azure-container-networking/cns/middlewares"
 	"github.com/Azure/azure-container-networking/cns/middlewares/mock"
 	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	"github.com/Azure/azure-container-networking/store"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -756,6 +758,7 @@ func IPAMFailToGetIPWhenAllIPsAreAssigned(t *testing.T, ncStates []ncState) {
 	if err == nil {
 		t.Fatalf("Expected failure requesting IP when there are no more IPs: %+v", err)
 	}
+	assert.ErrorIs(t, err, ErrNoAvailableIPs)
 }
 
 func TestIPAMRequestThenReleaseThenRequestAgainSingleNC(t *testing.T) {
@@ -1170,6 +1173,46 @@ func IPAMMarkIPCountAsPending(t *testing.T, ncStates []ncState) {
 	}
 }
 
+func TestIPAMMarkNIPsPendingReleaseForFamily(t *testing.T) {
+	svc := getTestService()
+	ncStates := []ncState{
+		{
+			ncID: testNCID,
+			ips: []string{
+				testIP1,
+			},
+		},
+		{
+			ncID: testNCIDv6,
+			ips: []string{
+				testIP1v6,
+			},
+		},
+	}
+	ipconfigs := make(map[string]cns.IPConfigurationStatus, 0)
+	for i := range ncStates {
+		state, _ := NewPodStateWithOrchestratorContext(ncStates[i].ips[0], ipIDs[i][0], ncStates[i].ncID, types.Available, prefixes[i], 0, testPod1Info)
+		ipconfigs[state.ID] = state
+		err := UpdatePodIPConfigState(t, svc, ipconfigs, ncStates[i].ncID)
+		require.NoError(t, err)
+	}
+
+	ips, err := svc.MarkNIPsPendingReleaseForFamily(1, v1alpha.IPv6)
+	require.NoError(t, err)
+	require.Len(t, ips, 1)
+	assert.Contains(t, ips, ipIDs[1][0])
+
+	// the only v6 IP is already pending release, so another v6 release must fail and leave the v4 IP alone.
+	_, err = svc.MarkNIPsPendingReleaseForFamily(1, v1alpha.IPv6)
+	require.Error(t, err)
+	assert.Len(t, svc.GetAvailableIPConfigs(), 1)
+
+	ips, err = svc.MarkNIPsPendingReleaseForFamily(1, v1alpha.IPv4)
+	require.NoError(t, err)
+	assert.Contains(t, ips, ipIDs[0][0])
+	assert.Len(t, svc.GetPendingReleaseIPConfigs(), 2)
+}
+
 func TestIPAMMarkIPAsPendingWithPendingProgrammingIPs(t *testing.T) {
 	svc := getTestService()
 
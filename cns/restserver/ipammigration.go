@@ -0,0 +1,115 @@
+package restserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+)
+
+// MigrateLegacyIPAMHandler assigns the IPs reserved by the legacy azure-vnet-ipam plugin to their pods, so that the
+// node can switch from the legacy IPAM to CNS IPAM without draining it. Each reservation is validated against the
+// CNS pool, and the IPs of a pod are assigned together.
+func (service *HTTPRestService) MigrateLegacyIPAMHandler(w http.ResponseWriter, r *http.Request) {
+	var req cns.MigrateLegacyIPAMRequest
+	if err := service.Listener.Decode(w, r, &req); err != nil {
+		resp := cns.MigrateLegacyIPAMResponse{
+			Response: cns.Response{
+				ReturnCode: types.UnexpectedError,
+				Message:    err.Error(),
+			},
+		}
+		err = service.Listener.Encode(w, &resp)
+		logger.ResponseEx(service.Name, req, resp, resp.Response.ReturnCode, err)
+		return
+	}
+
+	resp := cns.MigrateLegacyIPAMResponse{
+		Results: service.migrateLegacyIPAM(req),
+	}
+	err := service.Listener.Encode(w, &resp)
+	logger.ResponseEx(service.Name, req, resp, resp.Response.ReturnCode, err)
+}
+
+// legacyIPAMPod is the IPs of a pod to migrate, and the index of their results.
+type legacyIPAMPod struct {
+	podInfo cns.PodInfo
+	ips     []string
+	results []int
+}
+
+func (service *HTTPRestService) migrateLegacyIPAM(req cns.MigrateLegacyIPAMRequest) []cns.LegacyIPAMMigrationResult {
+	service.RLock()
+	ipConfigByIP := make(map[string]cns.IPConfigurationStatus, len(service.PodIPConfigState))
+	for _, ipConfig := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+		ipConfigByIP[ipConfig.IPAddress] = ipConfig
+	}
+	service.RUnlock()
+
+	results := make([]cns.LegacyIPAMMigrationResult, len(req.Allocations))
+	pods := map[string]*legacyIPAMPod{}
+	podKeys := []string{}
+	for i, allocation := range req.Allocations {
+		results[i] = cns.LegacyIPAMMigrationResult{
+			IPAddress:    allocation.IPAddress,
+			PodName:      allocation.PodName,
+			PodNamespace: allocation.PodNamespace,
+		}
+		if allocation.PodName == "" || allocation.PodNamespace == "" || allocation.InfraContainerID == "" {
+			results[i].Status = cns.LegacyIPAMMissingPodInfo
+			continue
+		}
+		ipConfig, ok := ipConfigByIP[allocation.IPAddress]
+		if !ok {
+			results[i].Status = cns.LegacyIPAMNotInPool
+			continue
+		}
+
+		podInfo := cns.NewPodInfo(allocation.InfraContainerID, allocation.PodInterfaceID, allocation.PodName, allocation.PodNamespace)
+		switch ipConfig.GetState() { //nolint:exhaustive // only assignable states are migrated
+		case types.Available, types.PendingProgramming:
+		case types.Assigned:
+			if ipConfig.PodInfo != nil && ipConfig.PodInfo.Key() == podInfo.Key() {
+				results[i].Status = cns.LegacyIPAMAlreadyMigrated
+				continue
+			}
+			results[i].Status = cns.LegacyIPAMConflict
+			if ipConfig.PodInfo != nil {
+				results[i].Message = fmt.Sprintf("assigned to pod %s/%s", ipConfig.PodInfo.Namespace(), ipConfig.PodInfo.Name())
+			}
+			continue
+		default:
+			results[i].Status = cns.LegacyIPAMConflict
+			results[i].Message = fmt.Sprintf("ip is %s", ipConfig.GetState())
+			continue
+		}
+
+		pod, ok := pods[podInfo.Key()]
+		if !ok {
+			pod = &legacyIPAMPod{podInfo: podInfo}
+			pods[podInfo.Key()] = pod
+			podKeys = append(podKeys, podInfo.Key())
+		}
+		pod.ips = append(pod.ips, allocation.IPAddress)
+		pod.results = append(pod.results, i)
+	}
+
+	for _, key := range podKeys {
+		pod := pods[key]
+		status, message := cns.LegacyIPAMMigratable, ""
+		if !req.DryRun {
+			status = cns.LegacyIPAMMigrated
+			if _, err := service.AssignDesiredIPConfigs(pod.podInfo, pod.ips); err != nil {
+				status, message = cns.LegacyIPAMFailed, err.Error()
+			}
+			logger.Printf("[MigrateLegacyIPAM] Migrating IPs %v of pod %s: %s %s", pod.ips, key, status, message)
+		}
+		for _, i := range pod.results {
+			results[i].Status = status
+			results[i].Message = message
+		}
+	}
+	return results
+}
@@ -0,0 +1,87 @@
+package restserver
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func legacyIPAMAllocation(ip string, podInfo cns.PodInfo) cns.LegacyIPAMAllocation {
+	return cns.LegacyIPAMAllocation{
+		IPAddress:        ip,
+		InfraContainerID: podInfo.InfraContainerID(),
+		PodInterfaceID:   podInfo.InterfaceID(),
+		PodName:          podInfo.Name(),
+		PodNamespace:     podInfo.Namespace(),
+	}
+}
+
+func TestMigrateLegacyIPAM(t *testing.T) {
+	svc := getTestService()
+
+	ipconfigs := make(map[string]cns.IPConfigurationStatus, 0)
+	state1, _ := NewPodStateWithOrchestratorContext(testIP1, testIPID1, testNCID, types.Assigned, ipPrefixBitsv4, 0, testPod1Info)
+	ipconfigs[state1.ID] = state1
+	state2 := NewPodState(testIP2, testIPID2, testNCID, types.Available, 0)
+	ipconfigs[state2.ID] = state2
+	state3 := NewPodState(testIP3, testIPID3, testNCID, types.Available, 0)
+	ipconfigs[state3.ID] = state3
+	require.NoError(t, UpdatePodIPConfigState(t, svc, ipconfigs, testNCID))
+
+	missingPodInfo := legacyIPAMAllocation(testIP3, testPod3Info)
+	missingPodInfo.PodName = ""
+	req := cns.MigrateLegacyIPAMRequest{
+		Allocations: []cns.LegacyIPAMAllocation{
+			legacyIPAMAllocation(testIP1, testPod1Info),
+			legacyIPAMAllocation(testIP1, testPod2Info),
+			legacyIPAMAllocation(testIP2, testPod2Info),
+			legacyIPAMAllocation("10.0.0.9", testPod3Info),
+			missingPodInfo,
+		},
+		DryRun: true,
+	}
+
+	statuses := func(results []cns.LegacyIPAMMigrationResult) []cns.LegacyIPAMMigrationStatus {
+		s := make([]cns.LegacyIPAMMigrationStatus, len(results))
+		for i := range results {
+			s[i] = results[i].Status
+		}
+		return s
+	}
+
+	// a dry run reports what would be migrated without assigning anything
+	results := svc.migrateLegacyIPAM(req)
+	assert.Equal(t, []cns.LegacyIPAMMigrationStatus{
+		cns.LegacyIPAMAlreadyMigrated,
+		cns.LegacyIPAMConflict,
+		cns.LegacyIPAMMigratable,
+		cns.LegacyIPAMNotInPool,
+		cns.LegacyIPAMMissingPodInfo,
+	}, statuses(results))
+	ipConfig := svc.PodIPConfigState[testIPID2]
+	assert.Equal(t, types.Available, ipConfig.GetState())
+
+	req.DryRun = false
+	results = svc.migrateLegacyIPAM(req)
+	assert.Equal(t, []cns.LegacyIPAMMigrationStatus{
+		cns.LegacyIPAMAlreadyMigrated,
+		cns.LegacyIPAMConflict,
+		cns.LegacyIPAMMigrated,
+		cns.LegacyIPAMNotInPool,
+		cns.LegacyIPAMMissingPodInfo,
+	}, statuses(results))
+	assert.Contains(t, results[1].Message, testPod1Info.Name())
+	ipConfig = svc.PodIPConfigState[testIPID2]
+	assert.Equal(t, types.Assigned, ipConfig.GetState())
+	assert.Equal(t, testPod2Info.Key(), ipConfig.PodInfo.Key())
+	assert.Equal(t, []string{testIPID2}, svc.PodIPIDByPodInterfaceKey[testPod2Info.Key()])
+	ipConfig = svc.PodIPConfigState[testIPID3]
+	assert.Equal(t, types.Available, ipConfig.GetState())
+
+	// migrating again is idempotent
+	results = svc.migrateLegacyIPAM(req)
+	assert.Equal(t, cns.LegacyIPAMAlreadyMigrated, results[2].Status)
+}
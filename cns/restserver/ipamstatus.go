@@ -0,0 +1,104 @@
+package restserver
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+)
+
+// HandleIPAMStatus returns the size of the IP pool, the allocated and free IPs of each network container, and the
+// IPs assigned to each pod, for the azure-ipam CLI and debug tooling. With the summary query parameter, it only
+// returns the counts, for the STATUS command of azure-ipam.
+func (service *HTTPRestService) HandleIPAMStatus(w http.ResponseWriter, r *http.Request) {
+	summary, _ := strconv.ParseBool(r.URL.Query().Get(cns.IPAMStatusSummaryQuery))
+	resp := service.ipamStatus(summary)
+	err := service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp, resp.Response.ReturnCode, err)
+}
+
+func (service *HTTPRestService) ipamStatus(summary bool) cns.IPAMStatusResponse {
+	service.RLock()
+	defer service.RUnlock()
+
+	resp := cns.IPAMStatusResponse{
+		PoolSize:        len(service.PodIPConfigState),
+		SubnetExhausted: service.subnetExhausted.Load(),
+	}
+	if summary {
+		for _, ipConfig := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+			switch ipConfig.GetState() {
+			case types.Assigned:
+				resp.Allocated++
+			case types.Available:
+				resp.Free++
+			}
+		}
+		return resp
+	}
+	ncs := map[string]*cns.NCIPAMStatus{}
+	pods := map[string]*cns.PodIPAllocation{}
+	for _, ipConfig := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+		nc, ok := ncs[ipConfig.NCID]
+		if !ok {
+			nc = &cns.NCIPAMStatus{NCID: ipConfig.NCID}
+			ncs[ipConfig.NCID] = nc
+		}
+		nc.Total++
+
+		switch ipConfig.GetState() {
+		case types.Assigned:
+			nc.Allocated++
+			resp.Allocated++
+		case types.Available:
+			nc.Free++
+			resp.Free++
+		case types.PendingRelease:
+			nc.PendingRelease++
+		case types.PendingProgramming:
+			nc.PendingProgramming++
+		}
+
+		if ipConfig.GetState() != types.Assigned || ipConfig.PodInfo == nil {
+			continue
+		}
+		key := ipConfig.PodInfo.Key()
+		pod, ok := pods[key]
+		if !ok {
+			pod = &cns.PodIPAllocation{
+				PodName:      ipConfig.PodInfo.Name(),
+				PodNamespace: ipConfig.PodInfo.Namespace(),
+				InterfaceID:  ipConfig.PodInfo.InterfaceID(),
+			}
+			pods[key] = pod
+		}
+		pod.IPAddresses = append(pod.IPAddresses, ipConfig.IPAddress)
+	}
+
+	resp.NetworkContainers = make([]cns.NCIPAMStatus, 0, len(ncs))
+	for _, nc := range ncs {
+		resp.NetworkContainers = append(resp.NetworkContainers, *nc)
+	}
+	sort.Slice(resp.NetworkContainers, func(i, j int) bool {
+		return resp.NetworkContainers[i].NCID < resp.NetworkContainers[j].NCID
+	})
+
+	resp.Pods = make([]cns.PodIPAllocation, 0, len(pods))
+	for _, pod := range pods {
+		sort.Strings(pod.IPAddresses)
+		resp.Pods = append(resp.Pods, *pod)
+	}
+	sort.Slice(resp.Pods, func(i, j int) bool {
+		if resp.Pods[i].PodNamespace != resp.Pods[j].PodNamespace {
+			return resp.Pods[i].PodNamespace < resp.Pods[j].PodNamespace
+		}
+		if resp.Pods[i].PodName != resp.Pods[j].PodName {
+			return resp.Pods[i].PodName < resp.Pods[j].PodName
+		}
+		return resp.Pods[i].InterfaceID < resp.Pods[j].InterfaceID
+	})
+	return resp
+}
@@ -0,0 +1,64 @@
+package restserver
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPAMStatus(t *testing.T) {
+	svc := getTestService()
+
+	ipconfigs := make(map[string]cns.IPConfigurationStatus, 0)
+	state1, _ := NewPodStateWithOrchestratorContext(testIP1, testIPID1, testNCID, types.Assigned, ipPrefixBitsv4, 0, testPod1Info)
+	ipconfigs[state1.ID] = state1
+	state2, _ := NewPodStateWithOrchestratorContext(testIP2, testIPID2, testNCID, types.Assigned, ipPrefixBitsv4, 0, testPod2Info)
+	ipconfigs[state2.ID] = state2
+	state3 := NewPodState(testIP3, testIPID3, testNCID, types.Available, 0)
+	ipconfigs[state3.ID] = state3
+	require.NoError(t, UpdatePodIPConfigState(t, svc, ipconfigs, testNCID))
+
+	ipconfigsv6 := make(map[string]cns.IPConfigurationStatus, 0)
+	state1v6, _ := NewPodStateWithOrchestratorContext(testIP1v6, testIPID1v6, testNCIDv6, types.Assigned, ipPrefixBitsv6, 0, testPod1Info)
+	ipconfigsv6[state1v6.ID] = state1v6
+	state2v6 := NewPodState(testIP2v6, testIPID2v6, testNCIDv6, types.Available, 0)
+	ipconfigsv6[state2v6.ID] = state2v6
+	require.NoError(t, UpdatePodIPConfigState(t, svc, ipconfigsv6, testNCIDv6))
+
+	// an IP being released by the pool monitor is neither allocated nor free
+	state3.SetState(types.PendingRelease)
+	svc.PodIPConfigState[state3.ID] = state3
+
+	resp := svc.ipamStatus(false)
+	assert.Equal(t, 5, resp.PoolSize)
+	assert.Equal(t, 3, resp.Allocated)
+	assert.Equal(t, 1, resp.Free)
+	assert.Equal(t, []cns.NCIPAMStatus{
+		{NCID: testNCID, Total: 3, Allocated: 2, PendingRelease: 1},
+		{NCID: testNCIDv6, Total: 2, Allocated: 1, Free: 1},
+	}, resp.NetworkContainers)
+
+	// the IPs of a dual-stack pod are listed together
+	assert.Equal(t, []cns.PodIPAllocation{
+		{
+			PodName:      testPod1Info.Name(),
+			PodNamespace: testPod1Info.Namespace(),
+			InterfaceID:  testPod1Info.InterfaceID(),
+			IPAddresses:  []string{testIP1, testIP1v6},
+		},
+		{
+			PodName:      testPod2Info.Name(),
+			PodNamespace: testPod2Info.Namespace(),
+			InterfaceID:  testPod2Info.InterfaceID(),
+			IPAddresses:  []string{testIP2},
+		},
+	}, resp.Pods)
+	assert.False(t, resp.SubnetExhausted)
+
+	// the summary only has the counts
+	svc.SetSubnetExhausted(true)
+	assert.Equal(t, cns.IPAMStatusResponse{PoolSize: 5, Allocated: 3, Free: 1, SubnetExhausted: true}, svc.ipamStatus(true))
+}
@@ -0,0 +1,109 @@
+package restserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+)
+
+// ipConfigWatcherBuffer is the number of events buffered for a watcher. A watcher which falls further behind
+// has its stream ended, and has to watch again to get the current state.
+const ipConfigWatcherBuffer = 256
+
+// ipConfigWatchers fans out the changes of the PodIPConfigState to the WatchIPConfigs streams.
+type ipConfigWatchers struct {
+	sync.Mutex
+	watchers map[chan cns.IPConfigEvent]struct{}
+}
+
+func (w *ipConfigWatchers) subscribe() chan cns.IPConfigEvent {
+	w.Lock()
+	defer w.Unlock()
+	if w.watchers == nil {
+		w.watchers = make(map[chan cns.IPConfigEvent]struct{})
+	}
+	events := make(chan cns.IPConfigEvent, ipConfigWatcherBuffer)
+	w.watchers[events] = struct{}{}
+	return events
+}
+
+func (w *ipConfigWatchers) unsubscribe(events chan cns.IPConfigEvent) {
+	w.Lock()
+	defer w.Unlock()
+	if _, ok := w.watchers[events]; ok {
+		delete(w.watchers, events)
+		close(events)
+	}
+}
+
+// publish sends the event to every watcher without blocking, dropping the watchers which can't keep up.
+func (w *ipConfigWatchers) publish(eventType cns.IPConfigEventType, ipConfig cns.IPConfigurationStatus) { //nolint:gocritic // ipConfig is copied on purpose
+	w.Lock()
+	defer w.Unlock()
+	event := cns.IPConfigEvent{Type: eventType, IPConfigurationStatus: ipConfig}
+	for events := range w.watchers {
+		select {
+		case events <- event:
+		default:
+			logger.Errorf("[WatchIPConfigs] dropping watcher which fell %d events behind", ipConfigWatcherBuffer)
+			delete(w.watchers, events)
+			close(events)
+		}
+	}
+}
+
+// WatchIPConfigsHandler streams the IP configurations as JSON lines of cns.IPConfigEvent: an Added event for every
+// IP in CNS, followed by the changes to them until the client disconnects.
+func (service *HTTPRestService) WatchIPConfigsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// subscribe while holding the service lock, so that no change is missed or sent twice after the initial state
+	service.RLock()
+	events := service.ipConfigWatchers.subscribe()
+	initial := make([]cns.IPConfigurationStatus, 0, len(service.PodIPConfigState))
+	for _, ipConfig := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+		initial = append(initial, ipConfig)
+	}
+	service.RUnlock()
+	defer service.ipConfigWatchers.unsubscribe(events)
+
+	logger.Printf("[WatchIPConfigs] starting watch with %d IPs", len(initial))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	for i := range initial {
+		if err := enc.Encode(cns.IPConfigEvent{Type: cns.IPConfigAdded, IPConfigurationStatus: initial[i]}); err != nil {
+			logger.Errorf("[WatchIPConfigs] failed to send initial state: %v", err)
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			logger.Printf("[WatchIPConfigs] watch ended by the client")
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := enc.Encode(event); err != nil {
+				logger.Errorf("[WatchIPConfigs] failed to send event: %v", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
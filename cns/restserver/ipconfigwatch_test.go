@@ -0,0 +1,80 @@
+package restserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchIPConfigsHandler(t *testing.T) {
+	svc := getTestService()
+	svc.PodIPConfigState = map[string]cns.IPConfigurationStatus{
+		testPod1GUID: NewPodState(testIP1, testPod1GUID, testNCID, types.Available, 0),
+	}
+	server := httptest.NewServer(http.HandlerFunc(svc.WatchIPConfigsHandler))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	dec := json.NewDecoder(res.Body)
+
+	next := func() cns.IPConfigEvent {
+		t.Helper()
+		var event cns.IPConfigEvent
+		require.NoError(t, dec.Decode(&event))
+		return event
+	}
+
+	// the initial state
+	event := next()
+	require.Equal(t, cns.IPConfigAdded, event.Type)
+	require.Equal(t, testIP1, event.IPConfigurationStatus.IPAddress)
+	require.Equal(t, types.Available, event.IPConfigurationStatus.GetState())
+
+	svc.Lock()
+	_, err = svc.updateIPConfigState(testPod1GUID, types.Assigned, testPod1Info)
+	svc.Unlock()
+	require.NoError(t, err)
+	event = next()
+	require.Equal(t, cns.IPConfigUpdated, event.Type)
+	require.Equal(t, types.Assigned, event.IPConfigurationStatus.GetState())
+	require.Equal(t, testPod1Info.Name(), event.IPConfigurationStatus.PodInfo.Name())
+
+	svc.Lock()
+	svc.removeToBeDeletedIPStateUntransacted(testPod1GUID, true)
+	svc.Unlock()
+	event = next()
+	require.Equal(t, cns.IPConfigDeleted, event.Type)
+	require.Equal(t, testIP1, event.IPConfigurationStatus.IPAddress)
+}
+
+func TestIPConfigWatchersDropSlowWatcher(t *testing.T) {
+	var watchers ipConfigWatchers
+	events := watchers.subscribe()
+
+	for i := 0; i <= ipConfigWatcherBuffer; i++ {
+		watchers.publish(cns.IPConfigUpdated, cns.IPConfigurationStatus{IPAddress: testIP1})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	require.Equal(t, ipConfigWatcherBuffer, received)
+	require.Empty(t, watchers.watchers)
+
+	// unsubscribing a dropped watcher is a no-op
+	watchers.unsubscribe(events)
+}
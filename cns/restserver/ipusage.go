@@ -3,6 +3,8 @@ package restserver
 import (
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type ipState struct {
@@ -56,3 +58,25 @@ func (service *HTTPRestService) buildIPState() *ipState {
 	)
 	return &state
 }
+
+// SecondaryIPHealth returns the number of secondary IPs which are programmed on the host and which are
+// pending programming, and the last time the host version of an NC was bumped.
+func (service *HTTPRestService) SecondaryIPHealth() *v1alpha.SecondaryIPHealth {
+	service.RLock()
+	defer service.RUnlock()
+
+	health := &v1alpha.SecondaryIPHealth{}
+	//nolint:gocritic // This has to iterate over the IP Config state to get the counts.
+	for _, ipConfig := range service.PodIPConfigState {
+		if ipConfig.GetState() == types.PendingProgramming {
+			health.UnprogrammedIPCount++
+			continue
+		}
+		health.HealthyIPCount++
+	}
+	if !service.lastNCVersionUpdate.IsZero() {
+		t := metav1.NewTime(service.lastNCVersionUpdate)
+		health.LastNCVersionUpdate = &t
+	}
+	return health
+}
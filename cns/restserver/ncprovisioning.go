@@ -0,0 +1,201 @@
+package restserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+)
+
+// ncRequestTTL is how long the response of a provisioning request is kept for retries with the same idempotency key.
+const ncRequestTTL = 15 * time.Minute
+
+type ncRequestRecord struct {
+	request  interface{}
+	response cns.Response
+	expires  time.Time
+}
+
+// ncRequests remembers the responses of the successful provisioning requests by their idempotency keys.
+// Its lock is held for the whole request, so that concurrent retries of a request apply it once.
+type ncRequests struct {
+	sync.Mutex
+	records map[string]ncRequestRecord
+	now     func() time.Time
+}
+
+func (n *ncRequests) clock() time.Time {
+	if n.now == nil {
+		return time.Now()
+	}
+	return n.now()
+}
+
+// replay returns the response of the earlier request with the key, if there is one.
+// It fails with IdempotencyKeyConflict if that request differs from this one.
+func (n *ncRequests) replay(key string, request interface{}) (cns.Response, bool) {
+	if key == "" {
+		return cns.Response{}, false
+	}
+	record, ok := n.records[key]
+	if !ok || n.clock().After(record.expires) {
+		return cns.Response{}, false
+	}
+	if !reflect.DeepEqual(record.request, request) {
+		return cns.Response{
+			ReturnCode: types.IdempotencyKeyConflict,
+			Message:    fmt.Sprintf("[Azure CNS] Error. IdempotencyKey %s was used by a different request", key),
+		}, true
+	}
+	return record.response, true
+}
+
+// record saves the response of a successful request with a key, and drops the expired records.
+func (n *ncRequests) record(key string, request interface{}, response cns.Response) {
+	if key == "" || response.ReturnCode != types.Success {
+		return
+	}
+	now := n.clock()
+	if n.records == nil {
+		n.records = make(map[string]ncRequestRecord)
+	}
+	for k, r := range n.records {
+		if now.After(r.expires) {
+			delete(n.records, k)
+		}
+	}
+	n.records[key] = ncRequestRecord{request: request, response: response, expires: now.Add(ncRequestTTL)}
+}
+
+// validateProvisionRequest checks the network container beyond what CreateOrUpdateNetworkContainerInternal does,
+// since the requests of external orchestrators are not validated by a CRD schema.
+func validateProvisionRequest(req *cns.CreateNetworkContainerRequest) (types.ResponseCode, error) {
+	if req.NetworkContainerid == "" {
+		return types.NetworkContainerNotSpecified, errors.New("NetworkContainerid is empty")
+	}
+	if err := req.Validate(); err != nil {
+		return types.InvalidRequest, err
+	}
+	switch req.NetworkContainerType {
+	case cns.WebApps, cns.AzureContainerInstance:
+		// these are programmed on the host by the createOrUpdateNetworkContainer handler
+		return types.UnsupportedNetworkContainerType, errors.Errorf("NetworkContainerType %s can't be provisioned", req.NetworkContainerType)
+	}
+	if req.Version != "" {
+		if _, err := strconv.Atoi(req.Version); err != nil {
+			return types.UnsupportedNCVersion, errors.Errorf("Version %s is not an integer", req.Version)
+		}
+	}
+	seen := make(map[string]string, len(req.SecondaryIPConfigs))
+	for id, ipConfig := range req.SecondaryIPConfigs {
+		ip := net.ParseIP(ipConfig.IPAddress)
+		if ip == nil {
+			return types.InvalidSecondaryIPConfig, errors.Errorf("secondary IP %s has invalid IPAddress %q", id, ipConfig.IPAddress)
+		}
+		if other, ok := seen[ip.String()]; ok {
+			return types.InvalidSecondaryIPConfig, errors.Errorf("secondary IPs %s and %s have the same IPAddress %s", other, id, ip)
+		}
+		seen[ip.String()] = id
+	}
+	return types.Success, nil
+}
+
+// ProvisionNetworkContainerInternal validates the network container and creates or updates it.
+// It is the code path of the multitenant operator and of the ProvisionNetworkContainer API.
+func (service *HTTPRestService) ProvisionNetworkContainerInternal(req *cns.ProvisionNetworkContainerRequest) cns.ProvisionNetworkContainerResponse {
+	service.ncRequests.Lock()
+	defer service.ncRequests.Unlock()
+
+	if resp, ok := service.ncRequests.replay(req.IdempotencyKey, *req); ok {
+		logger.Printf("[Azure CNS] replaying ProvisionNetworkContainer with IdempotencyKey %s", req.IdempotencyKey)
+		return cns.ProvisionNetworkContainerResponse{Response: resp, Replayed: resp.ReturnCode != types.IdempotencyKeyConflict}
+	}
+
+	if returnCode, err := validateProvisionRequest(&req.NetworkContainer); err != nil {
+		logger.Errorf("[Azure CNS] invalid ProvisionNetworkContainer request %s: %v", req.NetworkContainer.String(), err)
+		return cns.ProvisionNetworkContainerResponse{
+			Response: cns.Response{ReturnCode: returnCode, Message: "[Azure CNS] Error. " + err.Error()},
+		}
+	}
+
+	nc := req.NetworkContainer
+	returnCode := service.CreateOrUpdateNetworkContainerInternal(&nc)
+	resp := cns.Response{ReturnCode: returnCode}
+	if returnCode != types.Success {
+		resp.Message = fmt.Sprintf("[Azure CNS] Error. ProvisionNetworkContainer failed for NC %s: %s", nc.NetworkContainerid, returnCode)
+	}
+	service.ncRequests.record(req.IdempotencyKey, *req, resp)
+	return cns.ProvisionNetworkContainerResponse{Response: resp}
+}
+
+// DeprovisionNetworkContainerInternal deletes the network container.
+// It is the code path of the multitenant operator and of the DeprovisionNetworkContainer API.
+func (service *HTTPRestService) DeprovisionNetworkContainerInternal(req cns.DeprovisionNetworkContainerRequest) cns.DeprovisionNetworkContainerResponse {
+	service.ncRequests.Lock()
+	defer service.ncRequests.Unlock()
+
+	if resp, ok := service.ncRequests.replay(req.IdempotencyKey, req); ok {
+		logger.Printf("[Azure CNS] replaying DeprovisionNetworkContainer with IdempotencyKey %s", req.IdempotencyKey)
+		return cns.DeprovisionNetworkContainerResponse{Response: resp, Replayed: resp.ReturnCode != types.IdempotencyKeyConflict}
+	}
+
+	if req.NetworkContainerID == "" {
+		return cns.DeprovisionNetworkContainerResponse{
+			Response: cns.Response{ReturnCode: types.NetworkContainerNotSpecified, Message: "[Azure CNS] Error. NetworkContainerID is empty"},
+		}
+	}
+
+	returnCode := service.DeleteNetworkContainerInternal(cns.DeleteNetworkContainerRequest{NetworkContainerid: req.NetworkContainerID})
+	resp := cns.Response{ReturnCode: returnCode}
+	if returnCode != types.Success {
+		resp.Message = fmt.Sprintf("[Azure CNS] Error. DeprovisionNetworkContainer failed for NC %s: %s", req.NetworkContainerID, returnCode)
+	}
+	service.ncRequests.record(req.IdempotencyKey, req, resp)
+	return cns.DeprovisionNetworkContainerResponse{Response: resp}
+}
+
+// provisionNetworkContainer handles the ProvisionNetworkContainer API.
+func (service *HTTPRestService) provisionNetworkContainer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req cns.ProvisionNetworkContainerRequest
+	if err := service.Listener.Decode(w, r, &req); err != nil {
+		logger.Errorf("[Azure CNS] could not decode request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	logger.Request(service.Name, req.NetworkContainer.String(), nil)
+
+	resp := service.ProvisionNetworkContainerInternal(&req)
+	err := service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp, resp.Response.ReturnCode, err)
+}
+
+// deprovisionNetworkContainer handles the DeprovisionNetworkContainer API.
+func (service *HTTPRestService) deprovisionNetworkContainer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req cns.DeprovisionNetworkContainerRequest
+	err := service.Listener.Decode(w, r, &req)
+	logger.Request(service.Name, &req, err)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp := service.DeprovisionNetworkContainerInternal(req)
+	err = service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp, resp.Response.ReturnCode, err)
+}
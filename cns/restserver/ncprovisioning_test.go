@@ -0,0 +1,147 @@
+package restserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisionNetworkContainerInternal(t *testing.T) {
+	svc := getTestService()
+	now := time.Now()
+	svc.ncRequests.now = func() time.Time { return now }
+
+	secondaryIPs := map[string]cns.SecondaryIPConfig{"ip-1": newSecondaryIPConfig("10.0.0.6", 0)}
+	req := &cns.ProvisionNetworkContainerRequest{
+		IdempotencyKey:   "create-1",
+		NetworkContainer: *generateNetworkContainerRequest(secondaryIPs, ncID, "0"),
+	}
+	resp := svc.ProvisionNetworkContainerInternal(req)
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	require.False(t, resp.Replayed)
+	require.Contains(t, svc.state.ContainerStatus, ncID)
+
+	// a retry gets the same response without applying the request again
+	delete(svc.state.ContainerStatus, ncID)
+	resp = svc.ProvisionNetworkContainerInternal(req)
+	require.Equal(t, types.Success, resp.Response.ReturnCode)
+	require.True(t, resp.Replayed)
+	require.NotContains(t, svc.state.ContainerStatus, ncID)
+
+	// the key can't be reused for a different request
+	changed := *req
+	changed.NetworkContainer.Version = "1"
+	resp = svc.ProvisionNetworkContainerInternal(&changed)
+	require.Equal(t, types.IdempotencyKeyConflict, resp.Response.ReturnCode)
+	require.False(t, resp.Replayed)
+
+	// until it expires
+	now = now.Add(ncRequestTTL + time.Second)
+	resp = svc.ProvisionNetworkContainerInternal(&changed)
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	require.False(t, resp.Replayed)
+	require.Equal(t, "1", svc.state.ContainerStatus[ncID].CreateNetworkContainerRequest.Version)
+
+	deprovision := cns.DeprovisionNetworkContainerRequest{IdempotencyKey: "delete-1", NetworkContainerID: ncID}
+	deprovisioned := svc.DeprovisionNetworkContainerInternal(deprovision)
+	require.Equal(t, types.Success, deprovisioned.Response.ReturnCode)
+	require.NotContains(t, svc.state.ContainerStatus, ncID)
+	require.True(t, svc.DeprovisionNetworkContainerInternal(deprovision).Replayed)
+
+	deprovision.IdempotencyKey = ""
+	deprovision.NetworkContainerID = ""
+	require.Equal(t, types.NetworkContainerNotSpecified, svc.DeprovisionNetworkContainerInternal(deprovision).Response.ReturnCode)
+}
+
+func TestValidateProvisionRequest(t *testing.T) {
+	valid := func() *cns.CreateNetworkContainerRequest {
+		return generateNetworkContainerRequest(map[string]cns.SecondaryIPConfig{
+			"ip-1": newSecondaryIPConfig("10.0.0.6", 0),
+			"ip-2": newSecondaryIPConfig("10.0.0.7", 0),
+		}, ncID, "0")
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*cns.CreateNetworkContainerRequest)
+		want   types.ResponseCode
+	}{
+		{
+			name:   "valid",
+			modify: func(*cns.CreateNetworkContainerRequest) {},
+			want:   types.Success,
+		},
+		{
+			name:   "empty NC ID",
+			modify: func(req *cns.CreateNetworkContainerRequest) { req.NetworkContainerid = "" },
+			want:   types.NetworkContainerNotSpecified,
+		},
+		{
+			name:   "NC ID is not a UUID",
+			modify: func(req *cns.CreateNetworkContainerRequest) { req.NetworkContainerid = "nc" },
+			want:   types.InvalidRequest,
+		},
+		{
+			name:   "WebApps NC",
+			modify: func(req *cns.CreateNetworkContainerRequest) { req.NetworkContainerType = cns.WebApps },
+			want:   types.UnsupportedNetworkContainerType,
+		},
+		{
+			name:   "version is not an integer",
+			modify: func(req *cns.CreateNetworkContainerRequest) { req.Version = "v1" },
+			want:   types.UnsupportedNCVersion,
+		},
+		{
+			name: "invalid secondary IP",
+			modify: func(req *cns.CreateNetworkContainerRequest) {
+				req.SecondaryIPConfigs["ip-2"] = newSecondaryIPConfig("10.0.0.256", 0)
+			},
+			want: types.InvalidSecondaryIPConfig,
+		},
+		{
+			name: "duplicate secondary IP",
+			modify: func(req *cns.CreateNetworkContainerRequest) {
+				req.SecondaryIPConfigs["ip-2"] = newSecondaryIPConfig("10.0.0.6", 0)
+			},
+			want: types.InvalidSecondaryIPConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(req)
+			code, err := validateProvisionRequest(req)
+			require.Equal(t, tt.want, code)
+			require.Equal(t, tt.want != types.Success, err != nil)
+		})
+	}
+}
+
+func TestDeprovisionNetworkContainerHandler(t *testing.T) {
+	body, err := json.Marshal(cns.DeprovisionNetworkContainerRequest{NetworkContainerID: "00000000-0000-0000-0000-000000000000"})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, cns.DeprovisionNetworkContainer, http.NoBody)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	// deleting an NC which doesn't exist succeeds
+	req, err = http.NewRequest(http.MethodPost, cns.DeprovisionNetworkContainer, bytes.NewReader(body))
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var resp cns.DeprovisionNetworkContainerResponse
+	require.NoError(t, decodeResponse(w, &resp))
+	require.Equal(t, types.Success, resp.Response.ReturnCode)
+}
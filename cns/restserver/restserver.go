@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
@@ -72,6 +73,10 @@ type HTTPRestService struct {
 	cniConflistGenerator       CNIConflistGenerator
 	generateCNIConflistOnce    sync.Once
 	IPConfigsHandlerMiddleware cns.IPConfigsHandlerMiddleware
+	lastNCVersionUpdate        time.Time
+	ipConfigWatchers           ipConfigWatchers
+	ncRequests                 ncRequests
+	subnetExhausted            atomic.Bool
 }
 
 type CNIConflistGenerator interface {
@@ -265,13 +270,18 @@ func (service *HTTPRestService) Init(config *common.ServiceConfig) error {
 	listener.AddHandler(cns.ReleaseIPConfig, NewHandlerFuncWithHistogram(service.ReleaseIPConfigHandler, HTTPRequestLatency))
 	listener.AddHandler(cns.ReleaseIPConfigs, NewHandlerFuncWithHistogram(service.ReleaseIPConfigsHandler, HTTPRequestLatency))
 	listener.AddHandler(cns.NmAgentSupportedApisPath, service.nmAgentSupportedApisHandler)
+	listener.AddHandler(cns.WatchIPConfigs, service.WatchIPConfigsHandler)
 	listener.AddHandler(cns.PathDebugIPAddresses, service.HandleDebugIPAddresses)
 	listener.AddHandler(cns.PathDebugPodContext, service.HandleDebugPodContext)
 	listener.AddHandler(cns.PathDebugRestData, service.HandleDebugRestData)
+	listener.AddHandler(cns.MigrateLegacyIPAM, service.MigrateLegacyIPAMHandler)
+	listener.AddHandler(cns.PathIPAMStatus, service.HandleIPAMStatus)
 	listener.AddHandler(cns.NetworkContainersURLPath, service.getOrRefreshNetworkContainers)
 	listener.AddHandler(cns.GetHomeAz, service.getHomeAz)
 	listener.AddHandler(cns.EndpointPath, service.EndpointHandlerAPI)
 	// handlers for v0.2
+	listener.AddHandler(cns.ProvisionNetworkContainer, service.provisionNetworkContainer)
+	listener.AddHandler(cns.DeprovisionNetworkContainer, service.deprovisionNetworkContainer)
 	listener.AddHandler(cns.V2Prefix+cns.SetEnvironmentPath, service.setEnvironment)
 	listener.AddHandler(cns.V2Prefix+cns.CreateNetworkPath, service.createNetwork)
 	listener.AddHandler(cns.V2Prefix+cns.DeleteNetworkPath, service.deleteNetwork)
@@ -325,6 +335,13 @@ func (service *HTTPRestService) RegisterPProfEndpoints() {
 	}
 }
 
+// RegisterLogLevelsEndpoint serves the runtime log levels of CNS components, which can be changed with a PUT.
+func (service *HTTPRestService) RegisterLogLevelsEndpoint(levels http.Handler) {
+	if service.Listener != nil {
+		service.Listener.GetMux().Handle("/debug/loglevels", levels)
+	}
+}
+
 // Start starts the CNS listener.
 func (service *HTTPRestService) Start(config *common.ServiceConfig) error {
 	// Start the listener.
@@ -0,0 +1,33 @@
+package restserver
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+)
+
+// subnetExhaustedRetryAfter is how long clients are asked to wait before retrying an IP request which failed because
+// the subnet is exhausted. IPs only become available again once pods are deleted or the subnet is no longer exhausted.
+const subnetExhaustedRetryAfter = 30 * time.Second
+
+// SetSubnetExhausted records whether the subnet of the pod IPs is exhausted, as reported by its ClusterSubnetState.
+// The pool isn't scaled up while the subnet is exhausted, so the IP requests which can't be served from the IPs
+// already allocated to the Node fail with SubnetExhausted instead of waiting on more IPs.
+func (service *HTTPRestService) SetSubnetExhausted(exhausted bool) {
+	if service.subnetExhausted.Swap(exhausted) != exhausted {
+		logger.Printf("[SetSubnetExhausted] subnet exhausted = %t", exhausted)
+	}
+}
+
+// writeRetryAfterIfSubnetExhausted makes the response to a request which failed with SubnetExhausted a 429 with a
+// Retry-After header, so that clients back off instead of retrying right away.
+func writeRetryAfterIfSubnetExhausted(w http.ResponseWriter, code types.ResponseCode) {
+	if code != types.SubnetExhausted {
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(subnetExhaustedRetryAfter.Seconds())))
+	w.WriteHeader(http.StatusTooManyRequests)
+}
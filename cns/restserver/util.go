@@ -310,6 +310,7 @@ func (service *HTTPRestService) addIPConfigStateUntransacted(ncID string, hostVe
 		logger.Printf("[Azure-Cns] Add IP %s as %s", ipconfig.IPAddress, newIPCNSStatus)
 
 		service.PodIPConfigState[ipID] = ipconfigStatus
+		service.ipConfigWatchers.publish(cns.IPConfigAdded, ipconfigStatus)
 
 		// Todo Update batch API and maintain the count
 	}
@@ -347,7 +348,10 @@ func (service *HTTPRestService) removeToBeDeletedIPStateUntransacted(
 	logger.Printf("[Azure-Cns] Delete the PodIpConfigState, IpId: %s, IPConfigStatus: %v",
 		ipID,
 		service.PodIPConfigState[ipID])
-	delete(service.PodIPConfigState, ipID)
+	if ipConfigStatus, exists := service.PodIPConfigState[ipID]; exists {
+		delete(service.PodIPConfigState, ipID)
+		service.ipConfigWatchers.publish(cns.IPConfigDeleted, ipConfigStatus)
+	}
 	return 0, ""
 }
 
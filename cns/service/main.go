@@ -36,51 +36,63 @@ import (
 	ipampoolv2 "github.com/Azure/azure-container-networking/cns/ipampool/v2"
 	cssctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/clustersubnetstate"
 	mtpncctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/multitenantpodnetworkconfig"
+	nodectrl "github.com/Azure/azure-container-networking/cns/kubecontroller/node"
 	nncctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/nodenetworkconfig"
+	oecctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/overlayextensionconfig"
 	podctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/pod"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/middlewares"
 	"github.com/Azure/azure-container-networking/cns/multitenantcontroller"
 	"github.com/Azure/azure-container-networking/cns/multitenantcontroller/multitenantoperator"
+	"github.com/Azure/azure-container-networking/cns/nodecapabilities"
 	"github.com/Azure/azure-container-networking/cns/restserver"
 	cnstypes "github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/cns/wireserver"
 	acn "github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/crd"
+	"github.com/Azure/azure-container-networking/crd/clustersubnetstate"
 	cssv1alpha1 "github.com/Azure/azure-container-networking/crd/clustersubnetstate/api/v1alpha1"
 	"github.com/Azure/azure-container-networking/crd/multitenancy"
 	mtv1alpha1 "github.com/Azure/azure-container-networking/crd/multitenancy/api/v1alpha1"
+	crdnodecapabilities "github.com/Azure/azure-container-networking/crd/nodecapabilities"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/Azure/azure-container-networking/crd/overlayextensionconfig"
+	acnscheme "github.com/Azure/azure-container-networking/crd/scheme"
 	acnfs "github.com/Azure/azure-container-networking/internal/fs"
 	"github.com/Azure/azure-container-networking/log"
+	"github.com/Azure/azure-container-networking/log/zaplog"
 	"github.com/Azure/azure-container-networking/nmagent"
 	"github.com/Azure/azure-container-networking/platform"
 	"github.com/Azure/azure-container-networking/processlock"
 	localtls "github.com/Azure/azure-container-networking/server/tls"
 	"github.com/Azure/azure-container-networking/store"
 	"github.com/Azure/azure-container-networking/telemetry"
+	"github.com/Azure/azure-container-networking/zapai"
 	"github.com/avast/retry-go/v4"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
-	kuberuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	ctrlzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
 	ctrlmgr "sigs.k8s.io/controller-runtime/pkg/manager"
 	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 const (
@@ -98,6 +110,9 @@ const (
 	maxRetryNodeRegister = 720
 	initCNSInitalDelay   = 10 * time.Second
 
+	// nncHealthReportInterval is how often the secondary IP health is reported to the NNC status
+	nncHealthReportInterval = 30 * time.Second
+
 	// envVarEnableCNIConflistGeneration enables cni conflist generation if set (value doesn't matter)
 	envVarEnableCNIConflistGeneration = "CNS_ENABLE_CNI_CONFLIST_GENERATION"
 
@@ -117,7 +132,11 @@ const (
 var (
 	rootCtx   context.Context
 	rootErrCh chan error
-	z         *zap.Logger
+	// rootErr is the unhandled error CNS is exiting with, set before rootCtx is done.
+	rootErr error
+	// serviceStopCh is closed when the service manager asks CNS to stop.
+	serviceStopCh = make(chan struct{})
+	z             *zap.Logger
 )
 
 // Version is populated by make during build.
@@ -333,6 +352,13 @@ var args = acn.ArgumentList{
 		Type:         "string",
 		DefaultValue: "",
 	},
+	{
+		Name:         acn.OptService,
+		Shorthand:    acn.OptServiceAlias,
+		Description:  "Run a service lifecycle command (install, uninstall, start, stop or status) and exit",
+		Type:         "string",
+		DefaultValue: "",
+	},
 }
 
 // init() is executed before main() whenever this package is imported
@@ -362,6 +388,9 @@ func init() {
 			log.Errorf("caught exit signal %v, exiting", sig)
 		case err := <-rootErrCh:
 			log.Errorf("unhandled error %v, exiting", err)
+			rootErr = err
+		case <-serviceStopCh:
+			log.Printf("service stop requested, exiting")
 		}
 		cancel()
 	}()
@@ -463,7 +492,7 @@ func startTelemetryService(ctx context.Context) {
 	tb := telemetry.NewTelemetryBuffer(nil)
 	err := tb.CreateAITelemetryHandle(config, false, false, false)
 	if err != nil {
-		log.Errorf("AI telemetry handle creation failed..:%w", err)
+		log.Errorf("AI telemetry handle creation failed..:%v", err)
 		return
 	}
 
@@ -474,7 +503,7 @@ func startTelemetryService(ctx context.Context) {
 	err = tb.StartServer()
 	log.Printf("Telemetry service for CNI started")
 	if err != nil {
-		log.Errorf("Telemetry service failed to start: %w", err)
+		log.Errorf("Telemetry service failed to start: %v", err)
 		return
 	}
 	tb.PushData(rootCtx)
@@ -512,12 +541,29 @@ func main() {
 	telemetryDaemonEnabled := acn.GetArg(acn.OptTelemetryService).(bool)
 	cniConflistFilepathArg := acn.GetArg(acn.OptCNIConflistFilepath).(string)
 	cniConflistScenarioArg := acn.GetArg(acn.OptCNIConflistScenario).(string)
+	serviceCmd := acn.GetArg(acn.OptService).(string)
 
 	if vers {
 		printVersion()
 		os.Exit(0)
 	}
 
+	if serviceCmd != "" {
+		execPath, err := os.Executable()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		serviceConfig := platform.CNSServiceConfig(execPath, platform.ServiceArgs(os.Args[1:], acn.OptService, acn.OptServiceAlias)...)
+		out, err := platform.RunServiceCommand(rootCtx, serviceCmd, serviceConfig)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println(out)
+		os.Exit(0)
+	}
+
 	// Initialize CNS.
 	var (
 		err                error
@@ -542,6 +588,12 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Report to the service manager, if CNS was started by it.
+	serviceHost, err := platform.RunAsService(platform.CNSServiceName, func() { close(serviceStopCh) })
+	if err != nil {
+		logger.Errorf("Failed to connect to the service manager: %v", err)
+	}
+
 	if !telemetryEnabled {
 		logger.Errorf("[Azure CNS] Cannot disable telemetry via cmdline. Update cns_config.json to disable telemetry.")
 	}
@@ -553,12 +605,19 @@ func main() {
 			cnsconfig = &configuration.CNSConfig{}
 		} else {
 			logger.Errorf("fatal: failed to read cns config: %v", err)
-			os.Exit(1)
+			exitWithError(serviceHost, errors.Wrap(err, "failed to read cns config"))
 		}
 	}
 	configuration.SetCNSConfigDefaults(cnsconfig)
 
+	var zapAICore *zapai.BatchCore
 	disableTelemetry := cnsconfig.TelemetrySettings.DisableAll
+	if endpoint := cnsconfig.TelemetrySettings.IngestionEndpoint; endpoint != "" && !disableTelemetry {
+		if _, err = aitelemetry.NormalizeIngestionEndpoint(endpoint); err != nil {
+			logger.Errorf("Invalid telemetry ingestion endpoint, disabling telemetry: %v", err)
+			disableTelemetry = true
+		}
+	}
 	if !disableTelemetry {
 		ts := cnsconfig.TelemetrySettings
 		aiConfig := aitelemetry.AIConfig{
@@ -569,6 +628,8 @@ func main() {
 			RefreshTimeout:               ts.RefreshIntervalInSecs,
 			DisableMetadataRefreshThread: ts.DisableMetadataRefreshThread,
 			DebugMode:                    ts.DebugMode,
+			IngestionEndpoint:            ts.IngestionEndpoint,
+			Proxy:                        ts.Proxy,
 		}
 
 		if aiKey := cnsconfig.TelemetrySettings.AppInsightsInstrumentationKey; aiKey != "" {
@@ -576,6 +637,15 @@ func main() {
 		} else {
 			logger.InitAI(aiConfig, ts.DisableTrace, ts.DisableMetric, ts.DisableEvent)
 		}
+
+		if ts.ZapAI.Enable {
+			batchConfig, err := ts.ZapAI.BatchConfig() //nolint:govet // shadow okay
+			if err != nil {
+				logger.Errorf("Invalid zapai settings, not forwarding zap logs to AI: %v", err)
+			} else if zapAICore, err = logger.NewZapAICore(aiConfig, ts.AppInsightsInstrumentationKey, batchConfig); err != nil {
+				logger.Errorf("Failed to create zapai core, not forwarding zap logs to AI: %v", err)
+			}
+		}
 	}
 
 	logger.Printf("[Azure CNS] Using config: %+v", cnsconfig)
@@ -591,7 +661,7 @@ func main() {
 		writer, newWriterErr := acnfs.NewAtomicWriter(conflistFilepath)
 		if newWriterErr != nil {
 			logger.Errorf("unable to create atomic writer to generate cni conflist: %v", newWriterErr)
-			os.Exit(1)
+			exitWithError(serviceHost, errors.Wrap(newWriterErr, "failed to create cni conflist writer"))
 		}
 
 		// allow the scenario to get overridden by command line arg
@@ -613,17 +683,35 @@ func main() {
 			conflistGenerator = &cniconflist.SWIFTGenerator{Writer: writer}
 		default:
 			logger.Errorf("unable to generate cni conflist for unknown scenario: %s", scenario)
-			os.Exit(1)
+			exitWithError(serviceHost, errors.Errorf("unknown cni conflist scenario %s", scenario))
 		}
 	}
 
-	// configure zap logger
+	// configure zap logger. The core is enabled at debug and entries are filtered by the level of their component.
+	logLevels := zaplog.NewLevels(zapcore.InfoLevel)
+	if err = logLevels.Apply(cnsconfig.LogLevels); err != nil {
+		logger.Errorf("Invalid log levels in config: %v", err)
+	}
+	if err = logLevels.LoadEnv(); err != nil {
+		logger.Errorf("Invalid log levels in environment: %v", err)
+	}
 	zconfig := zap.NewProductionConfig()
+	zconfig.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
 	zconfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	if z, err = zconfig.Build(); err != nil {
+	zopts := []zap.Option{}
+	if zapAICore != nil {
+		// tee before the component levels are applied, so that they filter the logs forwarded to AI too
+		zopts = append(zopts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, zapAICore)
+		}))
+	}
+	zopts = append(zopts, logLevels.Option())
+	if z, err = zconfig.Build(zopts...); err != nil {
 		fmt.Printf("failed to create logger: %v", err)
-		os.Exit(1)
+		exitWithError(serviceHost, errors.Wrap(err, "failed to create logger"))
 	}
+	// route the shared packages which still log through the legacy std logger onto zap
+	log.SetZapLogger(z.With(zap.String("component", "acn")))
 
 	// start the healthz/readyz/metrics server
 	readyCh := make(chan interface{})
@@ -642,13 +730,13 @@ func main() {
 	nmaConfig, err := nmagent.NewConfig(cnsconfig.WireserverIP)
 	if err != nil {
 		logger.Errorf("[Azure CNS] Failed to produce NMAgent config from the supplied wireserver ip: %v", err)
-		return
+		exitWithError(serviceHost, errors.Wrap(err, "failed to create nmagent config"))
 	}
 
 	nmaClient, err := nmagent.NewClient(nmaConfig)
 	if err != nil {
 		logger.Errorf("[Azure CNS] Failed to start nmagent client due to error: %v", err)
-		return
+		exitWithError(serviceHost, errors.Wrap(err, "failed to create nmagent client"))
 	}
 
 	if cnsconfig.ChannelMode == cns.Managed {
@@ -678,18 +766,19 @@ func main() {
 	}
 
 	// Log platform information.
-	logger.Printf("Running on %v", platform.GetOSInfo())
+	osInfo, _ := platform.GetOSInfo()
+	logger.Printf("Running on %v", osInfo)
 
 	err = platform.CreateDirectory(storeFileLocation)
 	if err != nil {
 		logger.Errorf("Failed to create File Store directory %s, due to Error:%v", storeFileLocation, err.Error())
-		return
+		exitWithError(serviceHost, errors.Wrap(err, "failed to create store directory"))
 	}
 
 	lockclient, err := processlock.NewFileLock(platform.CNILockPath + name + store.LockExtension)
 	if err != nil {
 		log.Printf("Error initializing file lock:%v", err)
-		return
+		exitWithError(serviceHost, errors.Wrap(err, "failed to create store file lock"))
 	}
 
 	// Create the key value store.
@@ -697,7 +786,7 @@ func main() {
 	config.Store, err = store.NewJsonFileStore(storeFileName, lockclient, nil)
 	if err != nil {
 		logger.Errorf("Failed to create store file: %s, due to error %v\n", storeFileName, err)
-		return
+		exitWithError(serviceHost, errors.Wrap(err, "failed to create store"))
 	}
 
 	// Initialize endpoint state store if cns is managing endpoint state.
@@ -706,14 +795,14 @@ func main() {
 		endpointStoreLock, err := processlock.NewFileLock(platform.CNILockPath + endpointStoreName + store.LockExtension) // nolint
 		if err != nil {
 			log.Printf("Error initializing endpoint state file lock:%v", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to create endpoint state store file lock"))
 		}
 		defer endpointStoreLock.Unlock() // nolint
 
 		err = platform.CreateDirectory(endpointStorePath)
 		if err != nil {
 			logger.Errorf("Failed to create File Store directory %s, due to Error:%v", storeFileLocation, err.Error())
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to create endpoint state store directory"))
 		}
 		// Create the key value store.
 		storeFileName := endpointStorePath + endpointStoreName + ".json"
@@ -721,7 +810,7 @@ func main() {
 		endpointStateStore, err = store.NewJsonFileStore(storeFileName, endpointStoreLock, nil)
 		if err != nil {
 			logger.Errorf("Failed to create endpoint state store file: %s, due to error %v\n", storeFileName, err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to create endpoint state store"))
 		}
 	}
 
@@ -740,7 +829,7 @@ func main() {
 		endpointStateStore, conflistGenerator, homeAzMonitor)
 	if err != nil {
 		logger.Errorf("Failed to create CNS object, err:%v.\n", err)
-		return
+		exitWithError(serviceHost, errors.Wrap(err, "failed to create CNS"))
 	}
 
 	// Set CNS options.
@@ -759,7 +848,7 @@ func main() {
 			logger.Printf("[Azure CNS] Successfully created default ext network")
 		} else {
 			logger.Printf("[Azure CNS] Failed to create default ext network due to error: %v", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to create default ext network"))
 		}
 	}
 
@@ -780,7 +869,7 @@ func main() {
 		err = httpRestService.Init(&config)
 		if err != nil {
 			logger.Errorf("Failed to init HTTPService, err:%v.\n", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to init HTTPService"))
 		}
 	}
 
@@ -789,7 +878,7 @@ func main() {
 	err = platform.SetSdnRemoteArpMacAddress(execClient)
 	if err != nil {
 		logger.Errorf("Failed to set remote ARP MAC address: %v", err)
-		return
+		exitWithError(serviceHost, errors.Wrap(err, "failed to set remote ARP MAC address"))
 	}
 
 	// We are only setting the PriorityVLANTag in 'cns.Direct' mode, because it neatly maps today, to 'isUsingMultitenancy'
@@ -810,7 +899,7 @@ func main() {
 		// stub an empty JSON object
 		if err := cnireconciler.WriteObjectToCNIStatefile(); err != nil {
 			logger.Errorf("Failed to write empty object to CNI state: %v", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to write empty object to CNI state"))
 		}
 
 		// We might be configured to reinitialize state from the CNI instead of the apiserver.
@@ -844,7 +933,7 @@ func main() {
 		err = InitializeCRDState(rootCtx, httpRestService, cnsconfig)
 		if err != nil {
 			logger.Errorf("Failed to start CRD Controller, err:%v.\n", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to start CRD controller"))
 		}
 	}
 
@@ -854,7 +943,7 @@ func main() {
 		err = InitializeMultiTenantController(rootCtx, httpRestService, *cnsconfig)
 		if err != nil {
 			logger.Errorf("Failed to start multiTenantController, err:%v.\n", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to start multitenant controller"))
 		}
 
 	}
@@ -863,12 +952,13 @@ func main() {
 	if httpRestService != nil {
 		if cnsconfig.EnablePprof {
 			httpRestService.RegisterPProfEndpoints()
+			httpRestService.RegisterLogLevelsEndpoint(logLevels)
 		}
 
 		err = httpRestService.Start(&config)
 		if err != nil {
 			logger.Errorf("Failed to start CNS, err:%v.\n", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to start CNS"))
 		}
 	}
 
@@ -896,7 +986,8 @@ func main() {
 	}
 
 	if !disableTelemetry {
-		go logger.SendHeartBeat(rootCtx, cnsconfig.TelemetrySettings.HeartBeatIntervalInMins)
+		heartbeat := aitelemetry.NewHeartbeat(name, version, aitelemetry.TogglesFromStruct(cnsconfig), nil)
+		go logger.SendHeartBeat(rootCtx, cnsconfig.TelemetrySettings.HeartBeatIntervalInMins, heartbeat)
 		go httpRestService.SendNCSnapShotPeriodically(rootCtx, cnsconfig.TelemetrySettings.SnapshotIntervalInMins)
 	}
 
@@ -907,7 +998,7 @@ func main() {
 				privateEndpoint,
 				infravnet,
 				nodeID)
-			return
+			exitWithError(serviceHost, errors.New("missing required values to run in managed mode"))
 		}
 
 		httpRestService.SetOption(acn.OptPrivateEndpoint, privateEndpoint)
@@ -924,7 +1015,7 @@ func main() {
 				privateEndpoint,
 				infravnet,
 				nodeID)
-			return
+			exitWithError(serviceHost, errors.Wrap(registerErr, "failed to register node"))
 		}
 		go func(ep, vnet, node string) {
 			// Periodically poll DNC for node updates
@@ -953,20 +1044,20 @@ func main() {
 		netPlugin, err = network.NewPlugin(&pluginConfig)
 		if err != nil {
 			logger.Errorf("Failed to create network plugin, err:%v.\n", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to create network plugin"))
 		}
 
 		// Create IPAM plugin.
 		ipamPlugin, err = ipam.NewPlugin(&pluginConfig)
 		if err != nil {
 			logger.Errorf("Failed to create IPAM plugin, err:%v.\n", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to create IPAM plugin"))
 		}
 
 		lockclientCnm, err = processlock.NewFileLock(platform.CNILockPath + pluginName + store.LockExtension)
 		if err != nil {
 			log.Printf("Error initializing file lock:%v", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to create plugin store file lock"))
 		}
 
 		// Create the key value store.
@@ -974,7 +1065,7 @@ func main() {
 		pluginConfig.Store, err = store.NewJsonFileStore(pluginStoreFile, lockclientCnm, nil)
 		if err != nil {
 			logger.Errorf("Failed to create plugin store file %s, due to error : %v\n", pluginStoreFile, err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to create plugin store"))
 		}
 
 		// Set plugin options.
@@ -982,7 +1073,7 @@ func main() {
 		logger.Printf("Start netplugin\n")
 		if err := netPlugin.Start(&pluginConfig); err != nil {
 			logger.Errorf("Failed to create network plugin, err:%v.\n", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to start network plugin"))
 		}
 
 		ipamPlugin.SetOption(acn.OptEnvironment, environment)
@@ -991,7 +1082,7 @@ func main() {
 		ipamPlugin.SetOption(acn.OptIpamQueryInterval, ipamQueryInterval)
 		if err := ipamPlugin.Start(&pluginConfig); err != nil {
 			logger.Errorf("Failed to create IPAM plugin, err:%v.\n", err)
-			return
+			exitWithError(serviceHost, errors.Wrap(err, "failed to start IPAM plugin"))
 		}
 	}
 
@@ -1035,9 +1126,22 @@ func main() {
 	}
 
 	logger.Printf("CNS exited")
+	serviceHost.Exited(rootErr)
+	if zapAICore != nil {
+		if err = zapAICore.Close(); err != nil {
+			logger.Errorf("Failed to close zapai core: %v", err)
+		}
+	}
 	logger.Close()
 }
 
+// exitWithError reports the failure to the service manager, if CNS was started by it, and exits.
+func exitWithError(serviceHost *platform.ServiceHost, err error) {
+	serviceHost.Exited(err)
+	logger.Close()
+	os.Exit(1)
+}
+
 func InitializeMultiTenantController(ctx context.Context, httpRestService cns.HTTPService, cnsconfig configuration.CNSConfig) error {
 	var multiTenantController multitenantcontroller.RequestController
 	kubeConfig, err := ctrl.GetConfig()
@@ -1061,7 +1165,14 @@ func InitializeMultiTenantController(ctx context.Context, httpRestService cns.HT
 	httpRestServiceImpl.SetNodeOrchestrator(&orchestrator)
 
 	// Create multiTenantController.
-	multiTenantController, err = multitenantoperator.New(httpRestServiceImpl, kubeConfig)
+	managerConfig, managerOpts := controllerManagerOptions(kubeConfig, &cnsconfig)
+	if cnsconfig.MultiTenantNCWebhook.Enable {
+		managerOpts.WebhookServer = webhook.NewServer(webhook.Options{
+			Port:    cnsconfig.MultiTenantNCWebhook.Port,
+			CertDir: cnsconfig.MultiTenantNCWebhook.CertDir,
+		})
+	}
+	multiTenantController, err = multitenantoperator.New(httpRestServiceImpl, managerConfig, managerOpts)
 	if err != nil {
 		logger.Errorf("Failed to create multiTenantController:%v", err)
 		return err
@@ -1178,6 +1289,68 @@ func reconcileInitialCNSState(ctx context.Context, cli nodeNetworkConfigGetter,
 	return nil
 }
 
+// controllerManagerOptions builds the controller-runtime manager Options from the CNS config, and returns
+// a copy of the kubeConfig with the configured client rate limits for the manager to use.
+func controllerManagerOptions(kubeConfig *rest.Config, cnsconfig *configuration.CNSConfig) (*rest.Config, ctrlmgr.Options) {
+	settings := cnsconfig.ControllerManagerSettings
+	managerConfig := rest.CopyConfig(kubeConfig)
+	if settings.ClientQPS > 0 {
+		managerConfig.QPS = settings.ClientQPS
+	}
+	if settings.ClientBurst > 0 {
+		managerConfig.Burst = settings.ClientBurst
+	}
+	opts := ctrlmgr.Options{
+		Metrics:                 ctrlmetrics.Options{BindAddress: cnsconfig.ControllerMetricsBindAddress},
+		HealthProbeBindAddress:  settings.HealthProbeBindAddress,
+		LeaderElection:          settings.LeaderElection,
+		LeaderElectionID:        settings.LeaderElectionID,
+		LeaderElectionNamespace: settings.LeaderElectionNamespace,
+		Controller:              ctrlconfig.Controller{CacheSyncTimeout: time.Duration(settings.CacheSyncTimeoutSecs) * time.Second},
+	}
+	if settings.LeaseDurationSecs > 0 {
+		d := time.Duration(settings.LeaseDurationSecs) * time.Second
+		opts.LeaseDuration = &d
+	}
+	if settings.RenewDeadlineSecs > 0 {
+		d := time.Duration(settings.RenewDeadlineSecs) * time.Second
+		opts.RenewDeadline = &d
+	}
+	if settings.RetryPeriodSecs > 0 {
+		d := time.Duration(settings.RetryPeriodSecs) * time.Second
+		opts.RetryPeriod = &d
+	}
+	return managerConfig, opts
+}
+
+// installCRDs applies the CRDs embedded in CNS which the enabled features depend on.
+func installCRDs(ctx context.Context, kubeConfig *rest.Config, cnsconfig *configuration.CNSConfig) error {
+	cli, err := crd.NewCRDClientFromConfig(kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to create crd client")
+	}
+	embedded := []func() (*apiextensionsv1.CustomResourceDefinition, error){nodenetworkconfig.GetNodeNetworkConfigs}
+	if cnsconfig.EnableSubnetScarcity {
+		embedded = append(embedded, clustersubnetstate.GetClusterSubnetStates)
+	}
+	if cnsconfig.PublishNodeCapabilities {
+		embedded = append(embedded, crdnodecapabilities.GetNodeCapabilities)
+	}
+	if cnsconfig.EnableOverlayExtensionConfig {
+		embedded = append(embedded, overlayextensionconfig.GetOverlayExtensionConfigs)
+	}
+	crds := make([]*apiextensionsv1.CustomResourceDefinition, len(embedded))
+	for i := range embedded {
+		if crds[i], err = embedded[i](); err != nil {
+			return errors.Wrap(err, "failed to get embedded crd")
+		}
+	}
+	if err := crd.NewManager(cli, z, name, version).Install(ctx, crds...); err != nil {
+		return errors.Wrap(err, "failed to install crds")
+	}
+	return nil
+}
+
 // InitializeCRDState builds and starts the CRD controllers.
 func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cnsconfig *configuration.CNSConfig) error {
 	// convert interface type to implementation type
@@ -1218,6 +1391,12 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		return errors.Wrapf(err, "failed to get node %s", nodeName)
 	}
 
+	if cnsconfig.InstallCRDs {
+		if err := installCRDs(ctx, kubeConfig, cnsconfig); err != nil {
+			return errors.Wrap(err, "failed to install CRDs")
+		}
+	}
+
 	// check the Node labels for Swift V2
 	if _, ok := node.Labels[configuration.LabelNodeSwiftV2]; ok {
 		cnsconfig.EnableSwiftV2 = true
@@ -1227,6 +1406,14 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		}
 	}
 
+	if cnsconfig.PublishNodeCapabilities {
+		capabilities := nodecapabilities.Detect(ctx, node, cnsconfig.CNIConflistScenario)
+		// the capabilities are informational, failing to publish them must not keep CNS from starting
+		if err := nodecapabilities.Publish(ctx, kubeConfig, node, capabilities, name, version); err != nil {
+			logger.Errorf("Failed to publish node capabilities: %v", err)
+		}
+	}
+
 	// perform state migration from CNI in case CNS is set to manage the endpoint state and has emty state
 	if cnsconfig.EnableStateMigration && !httpRestServiceImplementation.EndpointStateStore.Exists() {
 		if err = PopulateCNSEndpointState(httpRestServiceImplementation.EndpointStateStore); err != nil {
@@ -1303,18 +1490,9 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 	}
 	logger.Printf("reconciled initial CNS state after %d attempts", attempt)
 
-	scheme := kuberuntime.NewScheme()
-	if err := corev1.AddToScheme(scheme); err != nil { //nolint:govet // intentional shadow
-		return errors.Wrap(err, "failed to add corev1 to scheme")
-	}
-	if err = v1alpha.AddToScheme(scheme); err != nil {
-		return errors.Wrap(err, "failed to add nodenetworkconfig/v1alpha to scheme")
-	}
-	if err = cssv1alpha1.AddToScheme(scheme); err != nil {
-		return errors.Wrap(err, "failed to add clustersubnetstate/v1alpha1 to scheme")
-	}
-	if err = mtv1alpha1.AddToScheme(scheme); err != nil {
-		return errors.Wrap(err, "failed to add multitenantpodnetworkconfig/v1alpha1 to scheme")
+	scheme, err := acnscheme.New()
+	if err != nil {
+		return errors.Wrap(err, "failed to create scheme")
 	}
 
 	// Set Selector options on the Manager cache which are used
@@ -1329,26 +1507,48 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 					"kube-system": {FieldSelector: fields.SelectorFromSet(fields.Set{"metadata.name": nodeName})},
 				},
 			},
+			&corev1.Node{}: {
+				Field: fields.SelectorFromSet(fields.Set{"metadata.name": nodeName}),
+			},
 		},
 	}
 
-	if cnsconfig.WatchPods {
+	// the swiftv2 mtpnc reconciler reads the Pods on this Node.
+	if cnsconfig.WatchPods || cnsconfig.EnableSwiftV2 {
 		cacheOpts.ByObject[&corev1.Pod{}] = cache.ByObject{
 			Field: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}),
 		}
 	}
 
-	managerOpts := ctrlmgr.Options{
-		Scheme:  scheme,
-		Metrics: ctrlmetrics.Options{BindAddress: "0"},
-		Cache:   cacheOpts,
-		Logger:  ctrlzap.New(),
+	// MTPNCs can't be selected by Node server-side until the mtpnc reconciler has labeled them, and
+	// PodNetworks and PodNetworkInstances not at all, so they are read directly instead of being cached
+	// cluster-wide. Only the labeled MTPNCs of this Node are watched.
+	if cnsconfig.EnableSwiftV2 {
+		cacheOpts.ByObject[&mtv1alpha1.MultitenantPodNetworkConfig{}] = cache.ByObject{
+			Label: labels.SelectorFromSet(labels.Set{mtv1alpha1.NodeLabel: nodeName}),
+		}
 	}
 
-	manager, err := ctrl.NewManager(kubeConfig, managerOpts)
+	managerConfig, managerOpts := controllerManagerOptions(kubeConfig, cnsconfig)
+	managerOpts.Scheme = scheme
+	managerOpts.Cache = cacheOpts
+	managerOpts.Logger = ctrlzap.New()
+	if cnsconfig.EnableSwiftV2 {
+		managerOpts.Client.Cache = &client.CacheOptions{
+			DisableFor: []client.Object{&mtv1alpha1.MultitenantPodNetworkConfig{}, &mtv1alpha1.PodNetwork{}, &mtv1alpha1.PodNetworkInstance{}},
+		}
+	}
+
+	manager, err := ctrl.NewManager(managerConfig, managerOpts)
 	if err != nil {
 		return errors.Wrap(err, "failed to create manager")
 	}
+	if err := manager.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return errors.Wrap(err, "failed to add healthz check")
+	}
+	if err := manager.AddReadyzCheck("ping", healthz.Ping); err != nil {
+		return errors.Wrap(err, "failed to add readyz check")
+	}
 
 	// this cachedscopedclient is built using the Manager's cached client, which is
 	// NOT SAFE TO USE UNTIL THE MANAGER IS STARTED!
@@ -1372,6 +1572,14 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		poolMonitor = ipampool.NewMonitor(httpRestServiceImplementation, cachedscopedcli, cssCh, &poolOpts)
 	}
 
+	// wrap the pool monitor to apply the scaler overrides set on this Node
+	scalerOverrideMonitor := nodectrl.NewScalerOverrideMonitor(poolMonitor)
+	poolMonitor = scalerOverrideMonitor
+	nodeReconciler := nodectrl.NewReconciler(scalerOverrideMonitor, nodeName)
+	if err := nodeReconciler.SetupWithManager(manager); err != nil {
+		return errors.Wrapf(err, "failed to setup node reconciler with manager")
+	}
+
 	// Start building the NNC Reconciler
 
 	// get CNS Node IP to compare NC Node IP with this Node IP to ensure NCs were created for this node
@@ -1382,9 +1590,17 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		return errors.Wrapf(err, "failed to setup nnc reconciler with manager")
 	}
 
+	// report the secondary IP health of this Node to the NNC status. The reporter reads the NNC from the
+	// Manager's cache, which is scoped to this Node's NNC and synced before the reporter is started.
+	healthReporter := nncctrl.NewHealthReporter(nodenetworkconfig.NewClient(manager.GetClient()), httpRestServiceImplementation,
+		types.NamespacedName{Namespace: "kube-system", Name: nodeName}, name, nncHealthReportInterval)
+	if err := manager.Add(healthReporter); err != nil {
+		return errors.Wrapf(err, "failed to add nnc health reporter to manager")
+	}
+
 	if cnsconfig.EnableSubnetScarcity {
 		// ClusterSubnetState reconciler
-		cssReconciler := cssctrl.New(cssCh)
+		cssReconciler := cssctrl.New(cssCh, httpRestServiceImplementation, manager.GetEventRecorderFor("azure-cns"), node)
 		if err := cssReconciler.SetupWithManager(manager); err != nil {
 			return errors.Wrapf(err, "failed to setup css reconciler with manager")
 		}
@@ -1404,8 +1620,21 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		}
 	}
 
+	if cnsconfig.EnableOverlayExtensionConfig {
+		programmer, err := oecctrl.NewProgrammer()
+		if err != nil {
+			return errors.Wrap(err, "failed to create overlay extension programmer")
+		}
+		oecReconciler := oecctrl.NewReconciler(overlayextensionconfig.NewClient(manager.GetClient()), nodeName, programmer)
+		if err := oecReconciler.SetupWithManager(manager); err != nil {
+			return errors.Wrapf(err, "failed to setup overlayextensionconfig reconciler with manager")
+		}
+	}
+
 	if cnsconfig.EnableSwiftV2 {
-		if err := mtpncctrl.SetupWithManager(manager); err != nil {
+		assignments := mtpncctrl.NewAssignmentStore()
+		mtpncReconciler := mtpncctrl.NewReconciler(manager.GetClient(), nodeName, assignments, manager.GetEventRecorderFor("azure-cns"))
+		if err := mtpncReconciler.SetupWithManager(manager); err != nil {
 			return errors.Wrapf(err, "failed to setup mtpnc reconciler with manager")
 		}
 		// if SWIFT v2 is enabled on CNS, attach multitenant middleware to rest service
@@ -1413,12 +1642,12 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		var swiftV2Middleware cns.IPConfigsHandlerMiddleware
 		switch cnsconfig.SWIFTV2Mode {
 		case configuration.K8sSWIFTV2:
-			swiftV2Middleware = &middlewares.K8sSWIFTv2Middleware{Cli: manager.GetClient()}
+			swiftV2Middleware = &middlewares.K8sSWIFTv2Middleware{Cli: manager.GetClient(), Assignments: assignments}
 		case configuration.SFSWIFTV2:
 		default:
 			// default to K8s middleware for now, in a later changes we where start to pass in
 			// SWIFT v2 mode in CNS config, this should throw an error if the mode is not set.
-			swiftV2Middleware = &middlewares.K8sSWIFTv2Middleware{Cli: manager.GetClient()}
+			swiftV2Middleware = &middlewares.K8sSWIFTv2Middleware{Cli: manager.GetClient(), Assignments: assignments}
 		}
 		httpRestService.AttachIPConfigsHandlerMiddleware(swiftV2Middleware)
 	}
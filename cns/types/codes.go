@@ -43,6 +43,8 @@ const (
 	NmAgentInternalServerError             ResponseCode = 41
 	StatusUnauthorized                     ResponseCode = 42
 	UnsupportedAPI                         ResponseCode = 43
+	SubnetExhausted                        ResponseCode = 44
+	IdempotencyKeyConflict                 ResponseCode = 45
 	UnexpectedError                        ResponseCode = 99
 )
 
@@ -123,6 +125,10 @@ func (c ResponseCode) String() string {
 		return "NmAgentInternalServerError"
 	case StatusUnauthorized:
 		return "StatusUnauthorized"
+	case SubnetExhausted:
+		return "SubnetExhausted"
+	case IdempotencyKeyConflict:
+		return "IdempotencyKeyConflict"
 	default:
 		return "UnknownError"
 	}
@@ -147,4 +147,8 @@ const (
 	OptCNIConflistScenario = "cni-conflist-scenario"
 	// OptCNIConflistScenarioAlias "shorthand" for the cni conflist scenairo, see above
 	OptCNIConflistScenarioAlias = "cniconflistscenario"
+
+	// Service lifecycle command, one of install, uninstall, start, stop or status
+	OptService      = "service"
+	OptServiceAlias = "svc"
 )
@@ -18,6 +18,13 @@ func NewIOShim() *IOShim {
 	}
 }
 
+// NewRecordingIOShim creates an IOShim which records the commands to the recorder instead of running them.
+func NewRecordingIOShim(recorder *ShimRecorder) *IOShim {
+	return &IOShim{
+		Exec: &recordingExec{recorder: recorder},
+	}
+}
+
 func NewMockIOShim(calls []testutils.TestCmd) *IOShim {
 	return &IOShim{
 		Exec: testutils.GetFakeExecWithScripts(calls),
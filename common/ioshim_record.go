@@ -0,0 +1,162 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	utilexec "k8s.io/utils/exec"
+)
+
+const (
+	// ShimCallExec is the kind of the commands recorded by an IOShim in recording mode.
+	ShimCallExec = "exec"
+	// ShimCallHNS is the kind of the HNS mutations recorded by an IOShim in recording mode.
+	ShimCallHNS = "hns"
+)
+
+// ShimCall is a command or HNS mutation recorded by an IOShim in recording mode, instead of being made.
+type ShimCall struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	// Name is the command, or the HNS method.
+	Name string   `json:"name"`
+	Args []string `json:"args,omitempty"`
+	// Stdin is the input of the command, like the rules passed to iptables-restore.
+	Stdin string `json:"stdin,omitempty"`
+	// Objects are the arguments of the HNS method, like the endpoint or policy request.
+	Objects []interface{} `json:"objects,omitempty"`
+}
+
+// ShimRecorder keeps the calls recorded by an IOShim in recording mode, and writes each of them as a line of JSON
+// to its log, if any.
+type ShimRecorder struct {
+	sync.Mutex
+	log   io.Writer
+	calls []ShimCall
+}
+
+// NewShimRecorder creates a ShimRecorder writing to the passed log, which may be nil to only keep the calls.
+func NewShimRecorder(log io.Writer) *ShimRecorder {
+	return &ShimRecorder{log: log}
+}
+
+// Record keeps the call and writes it to the log.
+func (r *ShimRecorder) Record(call ShimCall) {
+	if call.Time.IsZero() {
+		call.Time = time.Now()
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.calls = append(r.calls, call)
+	if r.log == nil {
+		return
+	}
+	b, err := json.Marshal(call)
+	if err != nil {
+		// log the call without the objects which don't marshal, rather than not at all
+		call.Objects = nil
+		b, _ = json.Marshal(call)
+	}
+	_, _ = r.log.Write(append(b, '\n'))
+}
+
+// RecordHNS records an HNS mutation, with the arguments of the method.
+func (r *ShimRecorder) RecordHNS(method string, objects ...interface{}) {
+	r.Record(ShimCall{Kind: ShimCallHNS, Name: method, Objects: objects})
+}
+
+// Calls returns the calls recorded so far, in order.
+func (r *ShimRecorder) Calls() []ShimCall {
+	r.Lock()
+	defer r.Unlock()
+	calls := make([]ShimCall, len(r.calls))
+	copy(calls, r.calls)
+	return calls
+}
+
+// Reset forgets the calls recorded so far.
+func (r *ShimRecorder) Reset() {
+	r.Lock()
+	defer r.Unlock()
+	r.calls = nil
+}
+
+// recordingExec implements utilexec.Interface by recording the commands instead of running them.
+type recordingExec struct {
+	recorder *ShimRecorder
+}
+
+func (e *recordingExec) Command(cmd string, args ...string) utilexec.Cmd {
+	return &recordingCmd{recorder: e.recorder, name: cmd, args: args}
+}
+
+func (e *recordingExec) CommandContext(_ context.Context, cmd string, args ...string) utilexec.Cmd {
+	return e.Command(cmd, args...)
+}
+
+// LookPath finds every command, since none of them is run.
+func (e *recordingExec) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+// recordingCmd records the command when it would run, and succeeds without output.
+type recordingCmd struct {
+	recorder *ShimRecorder
+	name     string
+	args     []string
+	stdin    io.Reader
+}
+
+func (c *recordingCmd) Run() error {
+	call := ShimCall{Kind: ShimCallExec, Name: c.name, Args: c.args}
+	if c.stdin != nil {
+		var sb strings.Builder
+		_, _ = io.Copy(&sb, c.stdin)
+		call.Stdin = sb.String()
+	}
+	c.recorder.Record(call)
+	return nil
+}
+
+func (c *recordingCmd) CombinedOutput() ([]byte, error) {
+	return []byte{}, c.Run()
+}
+
+func (c *recordingCmd) Output() ([]byte, error) {
+	return []byte{}, c.Run()
+}
+
+func (c *recordingCmd) SetDir(string) {}
+
+func (c *recordingCmd) SetStdin(in io.Reader) {
+	c.stdin = in
+}
+
+func (c *recordingCmd) SetStdout(io.Writer) {}
+
+func (c *recordingCmd) SetStderr(io.Writer) {}
+
+func (c *recordingCmd) SetEnv([]string) {}
+
+func (c *recordingCmd) StdoutPipe() (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (c *recordingCmd) StderrPipe() (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (c *recordingCmd) Start() error {
+	return c.Run()
+}
+
+func (c *recordingCmd) Wait() error {
+	return nil
+}
+
+func (c *recordingCmd) Stop() {}
@@ -23,6 +23,23 @@ func NewIOShim() *IOShim {
 	}
 }
 
+// NewRecordingIOShim creates an IOShim which records the commands and HNS mutations to the recorder instead of making
+// them. HNS is still read, so that the callers see the state of the node.
+func NewRecordingIOShim(recorder *ShimRecorder) *IOShim {
+	return NewRecordingIOShimWithHNS(recorder, &hnswrapper.Hnsv2wrapper{})
+}
+
+// NewRecordingIOShimWithHNS creates a recording IOShim reading from the passed HNS, like a fake HNS in tests.
+func NewRecordingIOShimWithHNS(recorder *ShimRecorder, hns hnswrapper.HnsV2WrapperInterface) *IOShim {
+	return &IOShim{
+		Exec: &recordingExec{recorder: recorder},
+		Hns: &hnswrapper.Hnsv2wrapperRecorder{
+			Hnsv2:  hns,
+			Record: recorder.RecordHNS,
+		},
+	}
+}
+
 func NewMockIOShim(calls []testutils.TestCmd) *IOShim {
 	hns := hnswrapper.NewHnsv2wrapperFake()
 	network := &hcn.HostComputeNetwork{
@@ -16,20 +16,59 @@ import (
 // +kubebuilder:resource:scope=Namespaced
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Exhausted",type=string,JSONPath=`.status.exhausted`
+// +kubebuilder:printcolumn:name="Override",type=string,JSONPath=`.spec.exhaustionOverride`
 // +kubebuilder:printcolumn:name="Updated",type=string,JSONPath=`.status.timestamp`
 type ClusterSubnetState struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
+	Spec   ClusterSubnetStateSpec   `json:"spec,omitempty"`
 	Status ClusterSubnetStateStatus `json:"status,omitempty"`
 }
 
+// ExhaustionOverride forces the exhaustion state of a subnet.
+// +kubebuilder:validation:Enum=Exhausted;Available
+type ExhaustionOverride string
+
+const (
+	// OverrideExhausted treats the subnet as exhausted regardless of its status.
+	OverrideExhausted ExhaustionOverride = "Exhausted"
+	// OverrideAvailable treats the subnet as available regardless of its status.
+	OverrideAvailable ExhaustionOverride = "Available"
+)
+
+// ClusterSubnetStateSpec defines the operator overrides of ClusterSubnetState
+// +kubebuilder:validation:XValidation:rule="!has(self.reason) || has(self.exhaustionOverride)",message="reason can only be set with exhaustionOverride"
+type ClusterSubnetStateSpec struct {
+	// ExhaustionOverride forces the subnet to be treated as exhausted or available, e.g. during
+	// maintenance or migration. It takes precedence over Status.Exhausted while set.
+	// +kubebuilder:validation:Optional
+	ExhaustionOverride ExhaustionOverride `json:"exhaustionOverride,omitempty"`
+	// Reason records why the override was set.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=256
+	Reason string `json:"reason,omitempty"`
+}
+
 // ClusterSubnetStateStatus defines the observed state of ClusterSubnetState
 type ClusterSubnetStateStatus struct {
 	Exhausted bool   `json:"exhausted"`
 	Timestamp string `json:"timestamp"`
 }
 
+// IsExhausted returns whether the subnet is to be treated as exhausted. An override in the spec takes
+// precedence over the status, which is used when no override is set.
+func (css *ClusterSubnetState) IsExhausted() bool {
+	switch css.Spec.ExhaustionOverride {
+	case OverrideExhausted:
+		return true
+	case OverrideAvailable:
+		return false
+	default:
+		return css.Status.Exhausted
+	}
+}
+
 // +kubebuilder:object:root=true
 
 // ClusterSubnetStateList contains a list of ClusterSubnetState
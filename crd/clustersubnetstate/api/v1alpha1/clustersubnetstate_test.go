@@ -0,0 +1,33 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsExhausted(t *testing.T) {
+	tests := []struct {
+		name      string
+		override  ExhaustionOverride
+		exhausted bool
+		want      bool
+	}{
+		{name: "no override, exhausted", exhausted: true, want: true},
+		{name: "no override, available", exhausted: false, want: false},
+		{name: "forced exhausted", override: OverrideExhausted, exhausted: false, want: true},
+		{name: "forced available", override: OverrideAvailable, exhausted: true, want: false},
+		{name: "unknown override falls back to status", override: "Maybe", exhausted: true, want: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			css := ClusterSubnetState{
+				Spec:   ClusterSubnetStateSpec{ExhaustionOverride: tt.override},
+				Status: ClusterSubnetStateStatus{Exhausted: tt.exhausted},
+			}
+			assert.Equal(t, tt.want, css.IsExhausted())
+		})
+	}
+}
@@ -13,6 +13,7 @@ func (in *ClusterSubnetState) DeepCopyInto(out *ClusterSubnetState) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
 	out.Status = in.Status
 }
 
@@ -66,6 +67,21 @@ func (in *ClusterSubnetStateList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSubnetStateSpec) DeepCopyInto(out *ClusterSubnetStateSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSubnetStateSpec.
+func (in *ClusterSubnetStateSpec) DeepCopy() *ClusterSubnetStateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSubnetStateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterSubnetStateStatus) DeepCopyInto(out *ClusterSubnetStateStatus) {
 	*out = *in
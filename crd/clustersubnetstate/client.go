@@ -13,6 +13,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -103,3 +104,44 @@ func (c *Client) Get(ctx context.Context, key types.NamespacedName) (*v1alpha1.C
 	err := c.cli.Get(ctx, key, clusterSubnetState)
 	return clusterSubnetState, errors.Wrapf(err, "failed to get css %v", key)
 }
+
+// List returns the ClusterSubnetStates matching the ListOptions.
+func (c *Client) List(ctx context.Context, opts ...client.ListOption) (*v1alpha1.ClusterSubnetStateList, error) {
+	clusterSubnetStates := &v1alpha1.ClusterSubnetStateList{}
+	err := c.cli.List(ctx, clusterSubnetStates, opts...)
+	return clusterSubnetStates, errors.Wrap(err, "failed to list css")
+}
+
+// Watch watches the ClusterSubnetStates matching the ListOptions.
+// The underlying client must implement client.WithWatch.
+func (c *Client) Watch(ctx context.Context, opts ...client.ListOption) (watch.Interface, error) {
+	wcli, ok := c.cli.(client.WithWatch)
+	if !ok {
+		return nil, crd.ErrWatchNotSupported
+	}
+	w, err := wcli.Watch(ctx, &v1alpha1.ClusterSubnetStateList{}, opts...)
+	return w, errors.Wrap(err, "failed to watch css")
+}
+
+// PatchStatus performs a server-side patch of the passed ClusterSubnetStateStatus to the ClusterSubnetState specified by the NamespacedName.
+func (c *Client) PatchStatus(ctx context.Context, key types.NamespacedName, status *v1alpha1.ClusterSubnetStateStatus, fieldManager string) (*v1alpha1.ClusterSubnetState, error) {
+	obj := genPatchSkel(key)
+	obj.Status = *status
+	if err := c.cli.Status().Patch(ctx, obj, client.Apply, client.ForceOwnership, client.FieldOwner(fieldManager)); err != nil {
+		return nil, errors.Wrapf(err, "failed to patch css %v status", key)
+	}
+	return obj, nil
+}
+
+func genPatchSkel(key types.NamespacedName) *v1alpha1.ClusterSubnetState {
+	return &v1alpha1.ClusterSubnetState{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "ClusterSubnetState",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+		},
+	}
+}
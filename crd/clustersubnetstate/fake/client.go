@@ -0,0 +1,24 @@
+// Package fake provides an in-memory ClusterSubnetState client for unit tests.
+package fake
+
+import (
+	"github.com/Azure/azure-container-networking/crd/clustersubnetstate"
+	"github.com/Azure/azure-container-networking/crd/clustersubnetstate/api/v1alpha1"
+	crdfake "github.com/Azure/azure-container-networking/crd/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewStore returns an in-memory client.WithWatch seeded with the passed ClusterSubnetStates.
+// Tests can use it to act as the other writers of the ClusterSubnetStates.
+func NewStore(objs ...*v1alpha1.ClusterSubnetState) client.WithWatch {
+	builder := crdfake.NewClientBuilder(clustersubnetstate.Scheme).WithStatusSubresource(&v1alpha1.ClusterSubnetState{})
+	for i := range objs {
+		builder = builder.WithObjects(objs[i])
+	}
+	return builder.Build()
+}
+
+// NewClient returns a ClusterSubnetState Client backed by a NewStore seeded with the passed ClusterSubnetStates.
+func NewClient(objs ...*v1alpha1.ClusterSubnetState) *clustersubnetstate.Client {
+	return clustersubnetstate.NewClient(NewStore(objs...))
+}
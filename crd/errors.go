@@ -7,6 +7,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ErrWatchNotSupported is returned by the CRD clients when asked to Watch through a client.Client
+// which does not implement client.WithWatch, such as the cached client of a controller-runtime manager.
+var ErrWatchNotSupported = errors.New("client does not support watch")
+
 // IsNotDefined tells whether the given error is a CRD not defined error
 func IsNotDefined(err error) bool {
 	if !apierr.IsNotFound(err) {
@@ -0,0 +1,36 @@
+// Package fake provides an in-memory controller-runtime client for unit testing the ACN CRD clients without envtest.
+package fake
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// NewClientBuilder returns a fake ClientBuilder for the passed scheme, which like the API server
+// creates objects which do not exist when they are patched with server-side apply.
+// Field managers are not tracked.
+func NewClientBuilder(scheme *runtime.Scheme) *fake.ClientBuilder {
+	return fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: createOnApply,
+	})
+}
+
+func createOnApply(ctx context.Context, cli client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() == types.ApplyPatchType {
+		existing, ok := obj.DeepCopyObject().(client.Object)
+		if !ok {
+			return errors.Errorf("unexpected object type %T", obj)
+		}
+		if err := cli.Get(ctx, client.ObjectKeyFromObject(obj), existing); apierrors.IsNotFound(err) {
+			return cli.Create(ctx, obj) //nolint:wrapcheck // fake passthrough
+		}
+	}
+	return cli.Patch(ctx, obj, patch, opts...) //nolint:wrapcheck // fake passthrough
+}
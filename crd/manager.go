@@ -0,0 +1,191 @@
+package crd
+
+import (
+	"context"
+	"reflect"
+
+	semver "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	typedv1 "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// OwnerAnnotation records the component which manages the CRD.
+	OwnerAnnotation = "acn.azure.com/crd-owner"
+	// VersionAnnotation records the version of the binary which last applied the CRD.
+	VersionAnnotation = "acn.azure.com/crd-version"
+)
+
+var (
+	// ErrNotOwner is returned when the CRD in the cluster is managed by a different component.
+	ErrNotOwner = errors.New("crd is owned by another component")
+	// ErrDowngrade is returned when the CRD in the cluster is newer than the embedded manifest.
+	ErrDowngrade = errors.New("refusing to downgrade crd")
+	// ErrStoredVersionRemoved is returned when the embedded manifest drops an API version
+	// that objects in the cluster are still stored as.
+	ErrStoredVersionRemoved = errors.New("crd stored version removed")
+	// ErrInvalidStorageVersion is returned when the embedded manifest does not have exactly one storage version.
+	ErrInvalidStorageVersion = errors.New("crd must have exactly one storage version")
+)
+
+// Manager applies CRD manifests embedded in a binary to the cluster at startup, so that the CRDs
+// a component depends on ship with, and are upgraded alongside, that component.
+//
+// CRDs are annotated with the owner and version of the binary that applied them. The Manager
+// will not modify a CRD owned by a different component, and will not replace a CRD that was
+// applied by a newer binary or that stores API versions unknown to the embedded manifest.
+type Manager struct {
+	cli     typedv1.CustomResourceDefinitionInterface
+	logger  *zap.Logger
+	owner   string
+	version string
+}
+
+// NewManager creates a Manager which applies CRDs as the passed owner at the passed binary version.
+// If the version is not a valid semver, version ordering is not enforced and only the
+// API version checks protect against downgrades.
+func NewManager(cli typedv1.CustomResourceDefinitionInterface, logger *zap.Logger, owner, version string) *Manager {
+	return &Manager{
+		cli:     cli,
+		logger:  logger,
+		owner:   owner,
+		version: version,
+	}
+}
+
+// Install installs or upgrades each of the passed CRDs, stopping at the first failure.
+// Updates are retried on conflict, as every replica of a component may apply its CRDs concurrently.
+// CRDs owned by another component or applied by a newer binary are left as they are in the cluster,
+// as that component or binary is expected to keep managing them, and are not failures.
+func (m *Manager) Install(ctx context.Context, crds ...*v1.CustomResourceDefinition) error {
+	for _, crd := range crds {
+		crd := crd
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			_, err := m.InstallOrUpdate(ctx, crd)
+			return err
+		})
+		if errors.Is(err, ErrNotOwner) || errors.Is(err, ErrDowngrade) {
+			m.logger.Info("skipping crd install", zap.String("crd", crd.Name), zap.Error(err))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InstallOrUpdate creates the passed CRD in the cluster, or updates the existing CRD if it differs
+// and the version and ownership checks allow it. It returns the CRD as it is in the cluster.
+func (m *Manager) InstallOrUpdate(ctx context.Context, crd *v1.CustomResourceDefinition) (*v1.CustomResourceDefinition, error) {
+	crd = crd.DeepCopy()
+	if err := validateStorageVersion(crd); err != nil {
+		return nil, errors.Wrapf(err, "invalid crd %s", crd.Name)
+	}
+	metav1.SetMetaDataAnnotation(&crd.ObjectMeta, OwnerAnnotation, m.owner)
+	metav1.SetMetaDataAnnotation(&crd.ObjectMeta, VersionAnnotation, m.version)
+
+	current, err := m.cli.Create(ctx, crd, metav1.CreateOptions{})
+	if err == nil {
+		return current, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return nil, errors.Wrapf(err, "failed to create crd %s", crd.Name)
+	}
+	current, err = m.cli.Get(ctx, crd.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get existing crd %s", crd.Name)
+	}
+	if err := m.checkUpgrade(current, crd); err != nil {
+		return current, errors.Wrapf(err, "crd %s", crd.Name)
+	}
+
+	// keep the cluster's pruning policy and any conversion webhook configured out of band
+	// (such as the service and CA bundle) rather than resetting them to the manifest's.
+	crd.Spec.PreserveUnknownFields = current.Spec.PreserveUnknownFields
+	if current.Spec.Conversion != nil && current.Spec.Conversion.Strategy == v1.WebhookConverter &&
+		(crd.Spec.Conversion == nil || crd.Spec.Conversion.Strategy == v1.NoneConverter) {
+		crd.Spec.Conversion = current.Spec.Conversion
+	}
+	if reflect.DeepEqual(crd.Spec, current.Spec) && current.Annotations[VersionAnnotation] == m.version {
+		return current, nil
+	}
+
+	for k, v := range current.Annotations {
+		if _, ok := crd.Annotations[k]; !ok {
+			metav1.SetMetaDataAnnotation(&crd.ObjectMeta, k, v)
+		}
+	}
+	crd.Labels = mergeLabels(current.Labels, crd.Labels)
+	crd.SetResourceVersion(current.GetResourceVersion())
+	updated, err := m.cli.Update(ctx, crd, metav1.UpdateOptions{})
+	if err != nil {
+		return current, errors.Wrapf(err, "failed to update existing crd %s", crd.Name)
+	}
+	return updated, nil
+}
+
+// checkUpgrade returns an error if the desired CRD must not replace the current CRD.
+func (m *Manager) checkUpgrade(current, desired *v1.CustomResourceDefinition) error {
+	// CRDs without an owner were applied out of band (for example from shipped YAML) and are adopted.
+	if owner, ok := current.Annotations[OwnerAnnotation]; ok && owner != m.owner {
+		return errors.Wrapf(ErrNotOwner, "owner is %q, not %q", owner, m.owner)
+	}
+
+	if installed, ok := current.Annotations[VersionAnnotation]; ok {
+		installedVer, errInstalled := semver.NewVersion(installed)
+		ver, errVer := semver.NewVersion(m.version)
+		if errInstalled == nil && errVer == nil && installedVer.GreaterThan(ver) {
+			return errors.Wrapf(ErrDowngrade, "installed by version %s, this is version %s", installed, m.version)
+		}
+	}
+
+	desiredVersions := map[string]struct{}{}
+	for i := range desired.Spec.Versions {
+		desiredVersions[desired.Spec.Versions[i].Name] = struct{}{}
+	}
+	// a storage version we don't know about can only have been introduced by a newer manifest.
+	for i := range current.Spec.Versions {
+		if _, ok := desiredVersions[current.Spec.Versions[i].Name]; !ok && current.Spec.Versions[i].Storage {
+			return errors.Wrapf(ErrDowngrade, "installed crd stores unknown version %s", current.Spec.Versions[i].Name)
+		}
+	}
+	for _, stored := range current.Status.StoredVersions {
+		if _, ok := desiredVersions[stored]; !ok {
+			return errors.Wrapf(ErrStoredVersionRemoved, "objects are stored as %s", stored)
+		}
+	}
+	return nil
+}
+
+func validateStorageVersion(crd *v1.CustomResourceDefinition) error {
+	var storage int
+	for i := range crd.Spec.Versions {
+		if crd.Spec.Versions[i].Storage {
+			storage++
+		}
+	}
+	if storage != 1 {
+		return errors.Wrapf(ErrInvalidStorageVersion, "found %d", storage)
+	}
+	return nil
+}
+
+func mergeLabels(current, desired map[string]string) map[string]string {
+	if len(current) == 0 {
+		return desired
+	}
+	merged := make(map[string]string, len(current)+len(desired))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range desired {
+		merged[k] = v
+	}
+	return merged
+}
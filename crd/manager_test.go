@@ -0,0 +1,238 @@
+package crd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testCRDName = "widgets.acn.azure.com"
+	testOwner   = "azure-cns"
+)
+
+func testCRD(versions ...string) *v1.CustomResourceDefinition {
+	crd := &v1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: testCRDName},
+		Spec: v1.CustomResourceDefinitionSpec{
+			Group: "acn.azure.com",
+			Names: v1.CustomResourceDefinitionNames{Plural: "widgets", Kind: "Widget"},
+			Scope: v1.NamespaceScoped,
+		},
+	}
+	for i, version := range versions {
+		crd.Spec.Versions = append(crd.Spec.Versions, v1.CustomResourceDefinitionVersion{
+			Name:    version,
+			Served:  true,
+			Storage: i == len(versions)-1,
+		})
+	}
+	return crd
+}
+
+func installed(crd *v1.CustomResourceDefinition, owner, version string, stored ...string) *v1.CustomResourceDefinition {
+	crd.Annotations = map[string]string{}
+	if owner != "" {
+		crd.Annotations[OwnerAnnotation] = owner
+	}
+	if version != "" {
+		crd.Annotations[VersionAnnotation] = version
+	}
+	crd.Status.StoredVersions = stored
+	return crd
+}
+
+func TestManagerInstallOrUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    *v1.CustomResourceDefinition
+		desired     *v1.CustomResourceDefinition
+		version     string
+		wantErr     error
+		wantVersion string
+		wantServed  []string
+	}{
+		{
+			name:        "fresh install",
+			desired:     testCRD("v1alpha1"),
+			version:     "v1.5.2",
+			wantVersion: "v1.5.2",
+			wantServed:  []string{"v1alpha1"},
+		},
+		{
+			name:        "upgrade",
+			existing:    installed(testCRD("v1alpha1"), testOwner, "v1.5.1", "v1alpha1"),
+			desired:     testCRD("v1alpha1", "v1beta1"),
+			version:     "v1.5.2",
+			wantVersion: "v1.5.2",
+			wantServed:  []string{"v1alpha1", "v1beta1"},
+		},
+		{
+			name:        "adopt unowned",
+			existing:    installed(testCRD("v1alpha1"), "", "", "v1alpha1"),
+			desired:     testCRD("v1alpha1", "v1beta1"),
+			version:     "v1.5.2",
+			wantVersion: "v1.5.2",
+			wantServed:  []string{"v1alpha1", "v1beta1"},
+		},
+		{
+			name:        "drop unstored version",
+			existing:    installed(testCRD("v1alpha1", "v1beta1"), testOwner, "v1.5.1", "v1beta1"),
+			desired:     testCRD("v1beta1"),
+			version:     "v1.5.2",
+			wantVersion: "v1.5.2",
+			wantServed:  []string{"v1beta1"},
+		},
+		{
+			name:        "refuse semver downgrade",
+			existing:    installed(testCRD("v1alpha1"), testOwner, "v1.6.0", "v1alpha1"),
+			desired:     testCRD("v1alpha1"),
+			version:     "v1.5.2",
+			wantErr:     ErrDowngrade,
+			wantVersion: "v1.6.0",
+			wantServed:  []string{"v1alpha1"},
+		},
+		{
+			name:        "refuse unknown storage version",
+			existing:    installed(testCRD("v1alpha1", "v1beta1"), testOwner, "dev", "v1alpha1"),
+			desired:     testCRD("v1alpha1"),
+			version:     "v1.5.2",
+			wantErr:     ErrDowngrade,
+			wantVersion: "dev",
+			wantServed:  []string{"v1alpha1", "v1beta1"},
+		},
+		{
+			name:        "refuse removing stored version",
+			existing:    installed(testCRD("v1alpha1", "v1beta1"), testOwner, "v1.5.1", "v1alpha1", "v1beta1"),
+			desired:     testCRD("v1beta1"),
+			version:     "v1.5.2",
+			wantErr:     ErrStoredVersionRemoved,
+			wantVersion: "v1.5.1",
+			wantServed:  []string{"v1alpha1", "v1beta1"},
+		},
+		{
+			name:        "refuse other owner",
+			existing:    installed(testCRD("v1alpha1"), "dnc", "v1.5.1", "v1alpha1"),
+			desired:     testCRD("v1alpha1", "v1beta1"),
+			version:     "v1.5.2",
+			wantErr:     ErrNotOwner,
+			wantVersion: "v1.5.1",
+			wantServed:  []string{"v1alpha1"},
+		},
+		{
+			name: "refuse multiple storage versions",
+			desired: func() *v1.CustomResourceDefinition {
+				c := testCRD("v1alpha1", "v1beta1")
+				c.Spec.Versions[0].Storage = true
+				return c
+			}(),
+			version: "v1.5.2",
+			wantErr: ErrInvalidStorageVersion,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset()
+			cli := cs.ApiextensionsV1().CustomResourceDefinitions()
+			if tt.existing != nil {
+				_, err := cli.Create(context.Background(), tt.existing, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+			m := NewManager(cli, zap.NewNop(), testOwner, tt.version)
+			_, err := m.InstallOrUpdate(context.Background(), tt.desired)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			got, err := cli.Get(context.Background(), testCRDName, metav1.GetOptions{})
+			if tt.existing == nil && tt.wantErr != nil {
+				require.Error(t, err, "crd should not have been created")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVersion, got.Annotations[VersionAnnotation])
+			served := []string{}
+			for i := range got.Spec.Versions {
+				served = append(served, got.Spec.Versions[i].Name)
+			}
+			assert.Equal(t, tt.wantServed, served)
+		})
+	}
+}
+
+func TestManagerPreservesClusterConfiguration(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	cli := cs.ApiextensionsV1().CustomResourceDefinitions()
+	existing := installed(testCRD("v1alpha1", "v1beta1"), testOwner, "v1.5.1", "v1beta1")
+	existing.Annotations["example.com/other"] = "kept"
+	existing.Spec.PreserveUnknownFields = true
+	existing.Spec.Conversion = &v1.CustomResourceConversion{
+		Strategy: v1.WebhookConverter,
+		Webhook: &v1.WebhookConversion{
+			ClientConfig:             &v1.WebhookClientConfig{CABundle: []byte("ca")},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	}
+	_, err := cli.Create(context.Background(), existing, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	desired := testCRD("v1alpha1", "v1beta1")
+	desired.Spec.Conversion = &v1.CustomResourceConversion{Strategy: v1.NoneConverter}
+	got, err := NewManager(cli, zap.NewNop(), testOwner, "v1.5.2").InstallOrUpdate(context.Background(), desired)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.5.2", got.Annotations[VersionAnnotation])
+	assert.Equal(t, testOwner, got.Annotations[OwnerAnnotation])
+	assert.Equal(t, "kept", got.Annotations["example.com/other"])
+	assert.True(t, got.Spec.PreserveUnknownFields)
+	require.NotNil(t, got.Spec.Conversion)
+	assert.Equal(t, v1.WebhookConverter, got.Spec.Conversion.Strategy)
+	assert.Equal(t, []byte("ca"), got.Spec.Conversion.Webhook.ClientConfig.CABundle)
+	assert.Nil(t, desired.Annotations, "the passed crd must not be modified")
+}
+
+func TestManagerInstall(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	cli := cs.ApiextensionsV1().CustomResourceDefinitions()
+	other := testCRD("v1alpha1")
+	other.Name = "gadgets.acn.azure.com"
+	require.NoError(t, NewManager(cli, zap.NewNop(), testOwner, "v1.5.2").Install(context.Background(), testCRD("v1alpha1"), other))
+	list, err := cli.List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 2)
+}
+
+func TestManagerInstallSkipsRefusedCRDs(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	cli := cs.ApiextensionsV1().CustomResourceDefinitions()
+	owned := installed(testCRD("v1alpha1"), "dnc", "v1.5.1", "v1alpha1")
+	_, err := cli.Create(context.Background(), owned, metav1.CreateOptions{})
+	require.NoError(t, err)
+	newer := installed(testCRD("v1alpha1"), testOwner, "v1.6.0", "v1alpha1")
+	newer.Name = "gadgets.acn.azure.com"
+	_, err = cli.Create(context.Background(), newer, metav1.CreateOptions{})
+	require.NoError(t, err)
+	other := testCRD("v1alpha1")
+	other.Name = "gizmos.acn.azure.com"
+
+	desiredNewer := testCRD("v1alpha1")
+	desiredNewer.Name = newer.Name
+	require.NoError(t, NewManager(cli, zap.NewNop(), testOwner, "v1.5.2").Install(context.Background(), testCRD("v1alpha1", "v1beta1"), desiredNewer, other))
+
+	got, err := cli.Get(context.Background(), testCRDName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "dnc", got.Annotations[OwnerAnnotation])
+	assert.Len(t, got.Spec.Versions, 1)
+	got, err = cli.Get(context.Background(), newer.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "v1.6.0", got.Annotations[VersionAnnotation])
+	_, err = cli.Get(context.Background(), other.Name, metav1.GetOptions{})
+	require.NoError(t, err, "crds after a refused crd must still be installed")
+}
@@ -9,6 +9,14 @@ import (
 
 // Important: Run "make" to regenerate code after modifying this file
 
+// NodeLabel is set by CNS on the MTPNCs of the pods on its node, to the name of the node, so
+// that CNS only watches the MTPNCs of its own node.
+const NodeLabel = "multitenancy.acn.azure.com/node"
+
+// ConditionNICAssigned is the condition type set by CNS, which is true once the delegated NIC
+// of the pod has been assigned and reserved for the pod on its node.
+const ConditionNICAssigned = "NICAssigned"
+
 // +kubebuilder:object:root=true
 
 // MultitenantPodNetworkConfig is the Schema for the multitenantpodnetworkconfigs API
@@ -61,6 +69,10 @@ type MultitenantPodNetworkConfigStatus struct {
 	MacAddress string `json:"macAddress,omitempty"`
 	// Gateway IP
 	GatewayIP string `json:"gatewayIP,omitempty"`
+	// Conditions reported by CNS, see ConditionNICAssigned.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 func init() {
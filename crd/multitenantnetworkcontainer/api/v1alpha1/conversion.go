@@ -0,0 +1,113 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1beta1"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// v1beta1StatusAnnotation holds the v1beta1 status fields v1alpha1 can't represent, so that objects
+// written through v1alpha1 by clients which predate v1beta1 don't drop them.
+const v1beta1StatusAnnotation = "networking.azure.com/v1beta1-status"
+
+// v1beta1Status is the part of the v1beta1 status stored in the v1beta1StatusAnnotation.
+type v1beta1Status struct {
+	ObservedGeneration int64                 `json:"observedGeneration,omitempty"`
+	Conditions         json.RawMessage       `json:"conditions,omitempty"`
+	Interface          v1beta1.InterfaceInfo `json:"interface,omitempty"`
+}
+
+var _ conversion.Convertible = &MultiTenantNetworkContainer{}
+
+// ConvertTo converts this MultiTenantNetworkContainer to the hub version, v1beta1.
+func (src *MultiTenantNetworkContainer) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.MultiTenantNetworkContainer)
+	if !ok {
+		return errors.Errorf("unexpected conversion hub %T", dstRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = v1beta1.MultiTenantNetworkContainerSpec(src.Spec)
+	dst.Status = v1beta1.MultiTenantNetworkContainerStatus{
+		IP:       src.Status.IP,
+		Gateway:  src.Status.Gateway,
+		State:    src.Status.State,
+		IPSubnet: src.Status.IPSubnet,
+		Interface: v1beta1.InterfaceInfo{
+			PrimaryInterfaceIdentifier: src.Status.PrimaryInterfaceIdentifier,
+		},
+		MultiTenantInfo: v1beta1.MultiTenantInfo(src.Status.MultiTenantInfo),
+	}
+
+	raw, ok := dst.Annotations[v1beta1StatusAnnotation]
+	if !ok {
+		return nil
+	}
+	delete(dst.Annotations, v1beta1StatusAnnotation)
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+
+	var status v1beta1Status
+	if err := json.Unmarshal([]byte(raw), &status); err != nil {
+		return errors.Wrapf(err, "failed to decode %s annotation", v1beta1StatusAnnotation)
+	}
+	if len(status.Conditions) > 0 {
+		if err := json.Unmarshal(status.Conditions, &dst.Status.Conditions); err != nil {
+			return errors.Wrapf(err, "failed to decode conditions in %s annotation", v1beta1StatusAnnotation)
+		}
+	}
+	dst.Status.ObservedGeneration = status.ObservedGeneration
+	dst.Status.Interface.MACAddress = status.Interface.MACAddress
+	dst.Status.Interface.MTU = status.Interface.MTU
+	return nil
+}
+
+// ConvertFrom converts from the hub version, v1beta1, to this version.
+func (dst *MultiTenantNetworkContainer) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.MultiTenantNetworkContainer)
+	if !ok {
+		return errors.Errorf("unexpected conversion hub %T", srcRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = MultiTenantNetworkContainerSpec(src.Spec)
+	dst.Status = MultiTenantNetworkContainerStatus{
+		IP:                         src.Status.IP,
+		Gateway:                    src.Status.Gateway,
+		State:                      src.Status.State,
+		IPSubnet:                   src.Status.IPSubnet,
+		PrimaryInterfaceIdentifier: src.Status.Interface.PrimaryInterfaceIdentifier,
+		MultiTenantInfo:            MultiTenantInfo(src.Status.MultiTenantInfo),
+	}
+
+	status := v1beta1Status{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Interface: v1beta1.InterfaceInfo{
+			MACAddress: src.Status.Interface.MACAddress,
+			MTU:        src.Status.Interface.MTU,
+		},
+	}
+	if len(src.Status.Conditions) > 0 {
+		conditions, err := json.Marshal(src.Status.Conditions)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode conditions")
+		}
+		status.Conditions = conditions
+	}
+	if status.ObservedGeneration == 0 && status.Conditions == nil && status.Interface == (v1beta1.InterfaceInfo{}) {
+		return nil
+	}
+
+	raw, err := json.Marshal(status)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode %s annotation", v1beta1StatusAnnotation)
+	}
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[v1beta1StatusAnnotation] = string(raw)
+	return nil
+}
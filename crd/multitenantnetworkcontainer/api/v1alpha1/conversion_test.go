@@ -0,0 +1,96 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1beta1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConvertRoundTripFromV1alpha1(t *testing.T) {
+	src := &MultiTenantNetworkContainer{
+		ObjectMeta: metav1.ObjectMeta{Name: "nc", Namespace: "default", Labels: map[string]string{"app": "test"}},
+		Spec: MultiTenantNetworkContainerSpec{
+			UUID:          "uuid",
+			Node:          "node",
+			InterfaceName: "eth1",
+		},
+		Status: MultiTenantNetworkContainerStatus{
+			IP:                         "10.1.0.4",
+			Gateway:                    "10.1.0.1",
+			State:                      "Initialized",
+			IPSubnet:                   "10.1.0.0/24",
+			PrimaryInterfaceIdentifier: "10.0.0.4",
+			MultiTenantInfo:            MultiTenantInfo{EncapType: "Vlan", ID: 10},
+		},
+	}
+
+	hub := &v1beta1.MultiTenantNetworkContainer{}
+	require.NoError(t, src.ConvertTo(hub))
+	require.Equal(t, "10.0.0.4", hub.Status.Interface.PrimaryInterfaceIdentifier)
+	require.Equal(t, v1beta1.MultiTenantInfo{EncapType: "Vlan", ID: 10}, hub.Status.MultiTenantInfo)
+	require.Empty(t, hub.Status.Conditions)
+
+	dst := &MultiTenantNetworkContainer{}
+	require.NoError(t, dst.ConvertFrom(hub))
+	require.Equal(t, src, dst)
+}
+
+func TestConvertRoundTripFromV1beta1(t *testing.T) {
+	src := &v1beta1.MultiTenantNetworkContainer{
+		ObjectMeta: metav1.ObjectMeta{Name: "nc", Namespace: "default", Generation: 3},
+		Spec:       v1beta1.MultiTenantNetworkContainerSpec{UUID: "uuid", Node: "node"},
+		Status: v1beta1.MultiTenantNetworkContainerStatus{
+			ObservedGeneration: 3,
+			Conditions: []metav1.Condition{{
+				Type:               v1beta1.ConditionReady,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: 3,
+				LastTransitionTime: metav1.NewTime(time.Unix(1700000000, 0)),
+				Reason:             "Persisted",
+				Message:            "persisted by CNS",
+			}},
+			IP:       "10.1.0.4",
+			State:    v1beta1.NCStateSucceeded,
+			IPSubnet: "10.1.0.0/24",
+			Interface: v1beta1.InterfaceInfo{
+				PrimaryInterfaceIdentifier: "10.0.0.4",
+				MACAddress:                 "00:0d:3a:00:00:01",
+				MTU:                        1500,
+			},
+		},
+	}
+
+	spoke := &MultiTenantNetworkContainer{}
+	require.NoError(t, spoke.ConvertFrom(src))
+	require.Equal(t, "10.0.0.4", spoke.Status.PrimaryInterfaceIdentifier)
+	require.Contains(t, spoke.Annotations, v1beta1StatusAnnotation)
+
+	// a client which only knows v1alpha1 updates the state, the v1beta1 fields must survive
+	spoke.Status.State = "Terminated"
+	src.Status.State = "Terminated"
+
+	dst := &v1beta1.MultiTenantNetworkContainer{}
+	require.NoError(t, spoke.ConvertTo(dst))
+	require.Equal(t, src, dst)
+}
+
+func TestConvertFromWithoutV1beta1FieldsAddsNoAnnotation(t *testing.T) {
+	src := &v1beta1.MultiTenantNetworkContainer{
+		ObjectMeta: metav1.ObjectMeta{Name: "nc"},
+		Status:     v1beta1.MultiTenantNetworkContainerStatus{State: v1beta1.NCStateInitialized},
+	}
+
+	dst := &MultiTenantNetworkContainer{}
+	require.NoError(t, dst.ConvertFrom(src))
+	require.Nil(t, dst.Annotations)
+}
+
+func TestConvertToInvalidAnnotation(t *testing.T) {
+	src := &MultiTenantNetworkContainer{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1beta1StatusAnnotation: "{"}},
+	}
+	require.Error(t, src.ConvertTo(&v1beta1.MultiTenantNetworkContainer{}))
+}
@@ -13,14 +13,19 @@ import (
 // MultiTenantInfo holds the encap type and id for the NC
 type MultiTenantInfo struct {
 	// EncapType is type of encapsulation
+	// +kubebuilder:default=Vlan
 	EncapType string `json:"encapType,omitempty"`
 	// ID of encapsulation, can be vlanid, vxlanid, gre-key, etc depending on EncapType
+	// +kubebuilder:validation:Minimum=0
 	ID int64 `json:"id,omitempty"`
 }
 
 // MultiTenantNetworkContainerSpec defines the desired state of MultiTenantNetworkContainer
+// +kubebuilder:validation:XValidation:rule="!has(oldSelf.uuid) || (has(self.uuid) && self.uuid == oldSelf.uuid)",message="uuid is immutable once set"
+// +kubebuilder:validation:XValidation:rule="!has(oldSelf.node) || (has(self.node) && self.node == oldSelf.node)",message="node is immutable once set"
 type MultiTenantNetworkContainerSpec struct {
 	// UUID - network container UUID
+	// +kubebuilder:validation:Format=uuid
 	UUID string `json:"uuid,omitempty"`
 	// Network - customer VNet GUID
 	Network string `json:"network,omitempty"`
@@ -43,8 +48,10 @@ type MultiTenantNetworkContainerStatus struct {
 	// The gateway IP address
 	Gateway string `json:"gateway,omitempty"`
 	// The state of network container
+	// +kubebuilder:validation:Enum=Initialized;Succeeded;Terminated
 	State string `json:"state,omitempty"`
 	// The subnet CIDR
+	// +kubebuilder:validation:Format=cidr
 	IPSubnet string `json:"ipSubnet,omitempty"`
 	// The primary interface identifier
 	PrimaryInterfaceIdentifier string `json:"primaryInterfaceIdentifier,omitempty"`
@@ -53,6 +60,7 @@ type MultiTenantNetworkContainerStatus struct {
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:storageversion
 // +kubebuilder:subresource:status
 
 // MultiTenantNetworkContainer is the Schema for the MultiTenantnetworkcontainers API
@@ -0,0 +1,18 @@
+package v1beta1
+
+import (
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Hub marks v1beta1 as the version the other versions of MultiTenantNetworkContainer convert through.
+func (*MultiTenantNetworkContainer) Hub() {}
+
+// SetupWebhookWithManager registers the conversion webhook for MultiTenantNetworkContainer with the
+// manager's webhook server. It is served by the component the CRD's conversion strategy points to.
+func SetupWebhookWithManager(mgr ctrl.Manager) error {
+	err := ctrl.NewWebhookManagedBy(mgr).
+		For(&MultiTenantNetworkContainer{}).
+		Complete()
+	return errors.Wrap(err, "failed to setup multitenantnetworkcontainer conversion webhook")
+}
@@ -0,0 +1,121 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+// Important: Run "make" to regenerate code after modifying this file
+
+// States of the network container, as reported in status.state.
+const (
+	// NCStateInitialized indicates the NC has been initialized by DNC.
+	NCStateInitialized = "Initialized"
+	// NCStateSucceeded indicates the NC has been persisted by CNS.
+	NCStateSucceeded = "Succeeded"
+	// NCStateTerminated indicates the NC has been terminated by CNS.
+	NCStateTerminated = "Terminated"
+)
+
+// ConditionReady is the condition type which is true once the NC has been persisted by CNS on the node.
+const ConditionReady = "Ready"
+
+// MultiTenantInfo holds the encap type and id for the NC
+type MultiTenantInfo struct {
+	// EncapType is type of encapsulation
+	// +kubebuilder:default=Vlan
+	EncapType string `json:"encapType,omitempty"`
+	// ID of encapsulation, can be vlanid, vxlanid, gre-key, etc depending on EncapType
+	// +kubebuilder:validation:Minimum=0
+	ID int64 `json:"id,omitempty"`
+}
+
+// InterfaceInfo describes the interface the network container is attached to.
+type InterfaceInfo struct {
+	// PrimaryInterfaceIdentifier identifies the primary interface of the NC.
+	PrimaryInterfaceIdentifier string `json:"primaryInterfaceIdentifier,omitempty"`
+	// MACAddress of the interface.
+	// +kubebuilder:validation:Format=mac
+	MACAddress string `json:"macAddress,omitempty"`
+	// MTU of the interface, the node default if unset.
+	// +kubebuilder:validation:Minimum=0
+	MTU int32 `json:"mtu,omitempty"`
+}
+
+// MultiTenantNetworkContainerSpec defines the desired state of MultiTenantNetworkContainer
+// +kubebuilder:validation:XValidation:rule="!has(oldSelf.uuid) || (has(self.uuid) && self.uuid == oldSelf.uuid)",message="uuid is immutable once set"
+// +kubebuilder:validation:XValidation:rule="!has(oldSelf.node) || (has(self.node) && self.node == oldSelf.node)",message="node is immutable once set"
+type MultiTenantNetworkContainerSpec struct {
+	// UUID - network container UUID
+	// +kubebuilder:validation:Format=uuid
+	UUID string `json:"uuid,omitempty"`
+	// Network - customer VNet GUID
+	Network string `json:"network,omitempty"`
+	// Subnet - customer subnet name
+	Subnet string `json:"subnet,omitempty"`
+	// Node - kubernetes node name
+	Node string `json:"node,omitempty"`
+	// InterfaceName - the interface name for consuming Pod
+	InterfaceName string `json:"interfaceName,omitempty"`
+	// ReservationID - reservation ID for allocating IP
+	ReservationID string `json:"reservationID,omitempty"`
+	// ReservationSetID - reservationSet for networkcontainer
+	ReservationSetID string `json:"reservationSetID,omitempty"`
+}
+
+// MultiTenantNetworkContainerStatus defines the observed state of MultiTenantNetworkContainer
+type MultiTenantNetworkContainerStatus struct {
+	// ObservedGeneration is the generation of the spec the status was last reconciled for.
+	// +kubebuilder:validation:Minimum=0
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the network container, see ConditionReady.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// The IP address
+	IP string `json:"ip,omitempty"`
+	// The gateway IP address
+	Gateway string `json:"gateway,omitempty"`
+	// The state of network container
+	// +kubebuilder:validation:Enum=Initialized;Succeeded;Terminated
+	State string `json:"state,omitempty"`
+	// The subnet CIDR
+	// +kubebuilder:validation:Format=cidr
+	IPSubnet string `json:"ipSubnet,omitempty"`
+	// Interface the network container is attached to
+	Interface InterfaceInfo `json:"interface,omitempty"`
+	// MultiTenantInfo holds the encap type and id
+	MultiTenantInfo MultiTenantInfo `json:"multiTenantInfo,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:unservedversion
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.node`
+
+// MultiTenantNetworkContainer is the Schema for the MultiTenantnetworkcontainers API
+type MultiTenantNetworkContainer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MultiTenantNetworkContainerSpec   `json:"spec,omitempty"`
+	Status MultiTenantNetworkContainerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MultiTenantNetworkContainerList contains a list of MultiTenantNetworkContainer
+type MultiTenantNetworkContainerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MultiTenantNetworkContainer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MultiTenantNetworkContainer{}, &MultiTenantNetworkContainerList{})
+}
@@ -0,0 +1,86 @@
+package multitenantnetworkcontainer
+
+import (
+	"context"
+
+	"github.com/Azure/azure-container-networking/crd"
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1alpha1"
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1beta1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Scheme is a runtime scheme containing the client-go scheme and the MultiTenantNetworkContainer scheme.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	_ = scheme.AddToScheme(Scheme)
+	_ = v1alpha1.AddToScheme(Scheme)
+	_ = v1beta1.AddToScheme(Scheme)
+}
+
+// Client provides methods to interact with instances of the MultiTenantNetworkContainer custom resource.
+type Client struct {
+	cli client.Client
+}
+
+// NewClient creates a new MultiTenantNetworkContainer client around the passed ctrlcli.Client.
+func NewClient(cli client.Client) *Client {
+	return &Client{
+		cli: cli,
+	}
+}
+
+// Get returns the MultiTenantNetworkContainer identified by the NamespacedName.
+func (c *Client) Get(ctx context.Context, key types.NamespacedName) (*v1alpha1.MultiTenantNetworkContainer, error) {
+	multiTenantNetworkContainer := &v1alpha1.MultiTenantNetworkContainer{}
+	err := c.cli.Get(ctx, key, multiTenantNetworkContainer)
+	return multiTenantNetworkContainer, errors.Wrapf(err, "failed to get mtnc %v", key)
+}
+
+// List returns the MultiTenantNetworkContainers matching the ListOptions.
+func (c *Client) List(ctx context.Context, opts ...client.ListOption) (*v1alpha1.MultiTenantNetworkContainerList, error) {
+	multiTenantNetworkContainers := &v1alpha1.MultiTenantNetworkContainerList{}
+	err := c.cli.List(ctx, multiTenantNetworkContainers, opts...)
+	return multiTenantNetworkContainers, errors.Wrap(err, "failed to list mtncs")
+}
+
+// Watch watches the MultiTenantNetworkContainers matching the ListOptions.
+// The underlying client must implement client.WithWatch.
+func (c *Client) Watch(ctx context.Context, opts ...client.ListOption) (watch.Interface, error) {
+	wcli, ok := c.cli.(client.WithWatch)
+	if !ok {
+		return nil, crd.ErrWatchNotSupported
+	}
+	w, err := wcli.Watch(ctx, &v1alpha1.MultiTenantNetworkContainerList{}, opts...)
+	return w, errors.Wrap(err, "failed to watch mtncs")
+}
+
+// PatchStatus performs a server-side patch of the passed MultiTenantNetworkContainerStatus to the
+// MultiTenantNetworkContainer specified by the NamespacedName.
+func (c *Client) PatchStatus(ctx context.Context, key types.NamespacedName, status *v1alpha1.MultiTenantNetworkContainerStatus, fieldManager string) (*v1alpha1.MultiTenantNetworkContainer, error) {
+	obj := genPatchSkel(key)
+	obj.Status = *status
+	if err := c.cli.Status().Patch(ctx, obj, client.Apply, client.ForceOwnership, client.FieldOwner(fieldManager)); err != nil {
+		return nil, errors.Wrapf(err, "failed to patch mtnc %v status", key)
+	}
+	return obj, nil
+}
+
+func genPatchSkel(key types.NamespacedName) *v1alpha1.MultiTenantNetworkContainer {
+	return &v1alpha1.MultiTenantNetworkContainer{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "MultiTenantNetworkContainer",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+		},
+	}
+}
@@ -0,0 +1,24 @@
+// Package fake provides an in-memory MultiTenantNetworkContainer client for unit tests.
+package fake
+
+import (
+	crdfake "github.com/Azure/azure-container-networking/crd/fake"
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer"
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewStore returns an in-memory client.WithWatch seeded with the passed MultiTenantNetworkContainers.
+// Tests can use it to act as the other writers of the MultiTenantNetworkContainers.
+func NewStore(objs ...*v1alpha1.MultiTenantNetworkContainer) client.WithWatch {
+	builder := crdfake.NewClientBuilder(multitenantnetworkcontainer.Scheme).WithStatusSubresource(&v1alpha1.MultiTenantNetworkContainer{})
+	for i := range objs {
+		builder = builder.WithObjects(objs[i])
+	}
+	return builder.Build()
+}
+
+// NewClient returns a MultiTenantNetworkContainer Client backed by a NewStore seeded with the passed MultiTenantNetworkContainers.
+func NewClient(objs ...*v1alpha1.MultiTenantNetworkContainer) *multitenantnetworkcontainer.Client {
+	return multitenantnetworkcontainer.NewClient(NewStore(objs...))
+}
@@ -0,0 +1,42 @@
+package fake_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/crd"
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer"
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1alpha1"
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "nc1"}
+	seed := &v1alpha1.MultiTenantNetworkContainer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		Spec:       v1alpha1.MultiTenantNetworkContainerSpec{UUID: "uuid", Node: "node1"},
+	}
+	cli := fake.NewClient(seed)
+
+	_, err := cli.PatchStatus(ctx, key, &v1alpha1.MultiTenantNetworkContainerStatus{State: "Succeeded", IP: "10.0.0.4"}, "test")
+	require.NoError(t, err)
+
+	nc, err := cli.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "Succeeded", nc.Status.State)
+	assert.Equal(t, "node1", nc.Spec.Node, "status patch must not modify the spec")
+
+	ncs, err := cli.List(ctx, client.InNamespace(key.Namespace))
+	require.NoError(t, err)
+	assert.Len(t, ncs.Items, 1)
+
+	// the manager's cached client cannot watch.
+	_, err = multitenantnetworkcontainer.NewClient(struct{ client.Client }{fake.NewStore()}).Watch(ctx)
+	assert.ErrorIs(t, err, crd.ErrWatchNotSupported)
+}
@@ -0,0 +1,139 @@
+package multitenantnetworkcontainer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1alpha1"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-networking-azure-com-v1alpha1-multitenantnetworkcontainer,mutating=false,failurePolicy=fail,sideEffects=None,groups=networking.azure.com,resources=multitenantnetworkcontainers;multitenantnetworkcontainers/status,verbs=create;update,versions=v1alpha1,name=vmultitenantnetworkcontainer.networking.azure.com,admissionReviewVersions=v1
+
+// ncStateTerminated is the state of an NC which has been removed from CNS, whose assignments can be reused.
+const ncStateTerminated = "Terminated"
+
+var _ admission.CustomValidator = (*Validator)(nil)
+
+// Validator is a validating admission webhook which rejects MultiTenantNetworkContainers whose assignments
+// conflict with those of another MultiTenantNetworkContainer. The UUID must be unique in the cluster, and
+// the interface name and IP must be unique on the node.
+//
+// The existing MultiTenantNetworkContainers are listed from the API server on each admission which sets
+// or changes one of those fields, rather than from an informer cache, so that every replica serving the
+// webhook sees the MultiTenantNetworkContainers admitted by the others.
+type Validator struct {
+	cli client.Reader
+}
+
+// NewValidator creates a Validator which looks up the existing MultiTenantNetworkContainers with the passed
+// client, which should read from the API server.
+func NewValidator(cli client.Reader) *Validator {
+	return &Validator{cli: cli}
+}
+
+// SetupWebhookWithManager registers the validating webhook for MultiTenantNetworkContainer with the
+// manager's webhook server, using the manager's API reader to look up the existing MultiTenantNetworkContainers.
+func SetupWebhookWithManager(mgr ctrl.Manager) error {
+	err := ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.MultiTenantNetworkContainer{}).
+		WithValidator(NewValidator(mgr.GetAPIReader())).
+		Complete()
+	return errors.Wrap(err, "failed to setup multitenantnetworkcontainer validating webhook")
+}
+
+// ValidateCreate rejects the MultiTenantNetworkContainer if it conflicts with an existing one.
+func (v *Validator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	nc, ok := obj.(*v1alpha1.MultiTenantNetworkContainer)
+	if !ok {
+		return nil, errors.Errorf("expected a MultiTenantNetworkContainer but got %T", obj)
+	}
+	return nil, v.validate(ctx, nc)
+}
+
+// ValidateUpdate rejects the MultiTenantNetworkContainer if it conflicts with an existing one.
+// Updates which don't change the fields which must be unique are allowed without looking up the
+// existing MultiTenantNetworkContainers, such as the state updates made by CNS.
+func (v *Validator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	nc, ok := newObj.(*v1alpha1.MultiTenantNetworkContainer)
+	if !ok {
+		return nil, errors.Errorf("expected a MultiTenantNetworkContainer but got %T", newObj)
+	}
+	old, ok := oldObj.(*v1alpha1.MultiTenantNetworkContainer)
+	if !ok {
+		return nil, errors.Errorf("expected a MultiTenantNetworkContainer but got %T", oldObj)
+	}
+	if !nc.DeletionTimestamp.IsZero() {
+		// don't block removing finalizers from a MultiTenantNetworkContainer which is being deleted.
+		return nil, nil
+	}
+	if !assignmentsChanged(old, nc) {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, nc)
+}
+
+// assignmentsChanged returns whether the update sets or changes a field which must be unique, or revives
+// a terminated MultiTenantNetworkContainer.
+func assignmentsChanged(old, nc *v1alpha1.MultiTenantNetworkContainer) bool {
+	return !strings.EqualFold(old.Spec.UUID, nc.Spec.UUID) ||
+		!strings.EqualFold(old.Spec.Node, nc.Spec.Node) ||
+		old.Spec.InterfaceName != nc.Spec.InterfaceName ||
+		old.Status.IP != nc.Status.IP ||
+		(old.Status.State == ncStateTerminated && nc.Status.State != ncStateTerminated)
+}
+
+// ValidateDelete allows all deletes.
+func (*Validator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *Validator) validate(ctx context.Context, nc *v1alpha1.MultiTenantNetworkContainer) error {
+	ncs := &v1alpha1.MultiTenantNetworkContainerList{}
+	if err := v.cli.List(ctx, ncs); err != nil {
+		return errors.Wrap(err, "failed to list mtncs")
+	}
+	key := types.NamespacedName{Namespace: nc.Namespace, Name: nc.Name}
+	var errs field.ErrorList
+	for i := range ncs.Items {
+		other := &ncs.Items[i]
+		otherKey := types.NamespacedName{Namespace: other.Namespace, Name: other.Name}
+		if otherKey == key || other.Status.State == ncStateTerminated {
+			continue
+		}
+		errs = append(errs, conflicts(nc, other, otherKey)...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(v1alpha1.GroupVersion.WithKind("MultiTenantNetworkContainer").GroupKind(), nc.Name, errs)
+}
+
+// conflicts returns the fields of the nc which conflict with the other nc.
+func conflicts(nc, other *v1alpha1.MultiTenantNetworkContainer, otherKey types.NamespacedName) field.ErrorList {
+	var errs field.ErrorList
+	used := func(path *field.Path, value string) *field.Error {
+		return field.Duplicate(path, fmt.Sprintf("%s (used by %s)", value, otherKey))
+	}
+	if nc.Spec.UUID != "" && strings.EqualFold(nc.Spec.UUID, other.Spec.UUID) {
+		errs = append(errs, used(field.NewPath("spec", "uuid"), nc.Spec.UUID))
+	}
+	if nc.Spec.Node == "" || !strings.EqualFold(nc.Spec.Node, other.Spec.Node) {
+		return errs
+	}
+	if nc.Spec.InterfaceName != "" && nc.Spec.InterfaceName == other.Spec.InterfaceName {
+		errs = append(errs, used(field.NewPath("spec", "interfaceName"), nc.Spec.InterfaceName))
+	}
+	if nc.Status.IP != "" && nc.Status.IP == other.Status.IP {
+		errs = append(errs, used(field.NewPath("status", "ip"), nc.Status.IP))
+	}
+	return errs
+}
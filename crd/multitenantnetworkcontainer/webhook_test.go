@@ -0,0 +1,128 @@
+package multitenantnetworkcontainer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1alpha1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newNC(name string, spec v1alpha1.MultiTenantNetworkContainerSpec, status v1alpha1.MultiTenantNetworkContainerStatus) *v1alpha1.MultiTenantNetworkContainer {
+	return &v1alpha1.MultiTenantNetworkContainer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec:       spec,
+		Status:     status,
+	}
+}
+
+func TestValidator(t *testing.T) {
+	existing := newNC("existing",
+		v1alpha1.MultiTenantNetworkContainerSpec{UUID: "uuid-1", Node: "node1", InterfaceName: "eth1"},
+		v1alpha1.MultiTenantNetworkContainerStatus{IP: "10.0.0.4"},
+	)
+	terminated := newNC("terminated",
+		v1alpha1.MultiTenantNetworkContainerSpec{UUID: "uuid-2", Node: "node1", InterfaceName: "eth2"},
+		v1alpha1.MultiTenantNetworkContainerStatus{State: ncStateTerminated},
+	)
+	v := NewValidator(fake.NewClientBuilder().WithScheme(Scheme).WithObjects(existing, terminated).Build())
+
+	tests := []struct {
+		name      string
+		nc        *v1alpha1.MultiTenantNetworkContainer
+		wantField []string
+	}{
+		{
+			name: "no conflict",
+			nc:   newNC("new", v1alpha1.MultiTenantNetworkContainerSpec{UUID: "uuid-3", Node: "node1", InterfaceName: "eth3"}, v1alpha1.MultiTenantNetworkContainerStatus{}),
+		},
+		{
+			name: "same interface on another node",
+			nc:   newNC("new", v1alpha1.MultiTenantNetworkContainerSpec{UUID: "uuid-3", Node: "node2", InterfaceName: "eth1"}, v1alpha1.MultiTenantNetworkContainerStatus{IP: "10.0.0.4"}),
+		},
+		{
+			name: "reuses a terminated nc",
+			nc:   newNC("new", v1alpha1.MultiTenantNetworkContainerSpec{UUID: "uuid-2", Node: "node1", InterfaceName: "eth2"}, v1alpha1.MultiTenantNetworkContainerStatus{}),
+		},
+		{
+			name: "updating itself",
+			nc:   existing.DeepCopy(),
+		},
+		{
+			name:      "duplicate uuid on another node",
+			nc:        newNC("new", v1alpha1.MultiTenantNetworkContainerSpec{UUID: "UUID-1", Node: "node2"}, v1alpha1.MultiTenantNetworkContainerStatus{}),
+			wantField: []string{"spec.uuid"},
+		},
+		{
+			name: "conflicting assignments on the same node",
+			nc: newNC("new", v1alpha1.MultiTenantNetworkContainerSpec{UUID: "uuid-3", Node: "node1", InterfaceName: "eth1"},
+				v1alpha1.MultiTenantNetworkContainerStatus{IP: "10.0.0.4"}),
+			wantField: []string{"spec.interfaceName", "status.ip"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.ValidateCreate(context.Background(), tt.nc)
+			if len(tt.wantField) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, apierrors.IsInvalid(err), "expected an invalid error, got %v", err)
+			var fields []string
+			for _, cause := range err.(*apierrors.StatusError).ErrStatus.Details.Causes { //nolint:errorlint // NewInvalid returns a StatusError
+				fields = append(fields, cause.Field)
+			}
+			assert.ElementsMatch(t, tt.wantField, fields)
+		})
+	}
+}
+
+func TestValidatorUpdateDeleting(t *testing.T) {
+	existing := newNC("existing", v1alpha1.MultiTenantNetworkContainerSpec{UUID: "uuid-1", Node: "node1"}, v1alpha1.MultiTenantNetworkContainerStatus{})
+	v := NewValidator(fake.NewClientBuilder().WithScheme(Scheme).WithObjects(existing).Build())
+
+	deleting := newNC("new", v1alpha1.MultiTenantNetworkContainerSpec{UUID: "uuid-1", Node: "node1"}, v1alpha1.MultiTenantNetworkContainerStatus{})
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	_, err := v.ValidateUpdate(context.Background(), deleting, deleting)
+	require.NoError(t, err)
+}
+
+// failingReader fails every lookup, to assert that no lookup is made.
+type failingReader struct {
+	client.Reader
+}
+
+func (failingReader) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return errors.New("unexpected list")
+}
+
+func TestValidatorUpdateOnlyChecksChangedAssignments(t *testing.T) {
+	v := NewValidator(failingReader{})
+	old := newNC("nc", v1alpha1.MultiTenantNetworkContainerSpec{UUID: "uuid-1", Node: "node1", InterfaceName: "eth1"}, v1alpha1.MultiTenantNetworkContainerStatus{State: "Initialized", IP: "10.0.0.4"})
+
+	// state updates made by CNS don't look up the existing ncs
+	updated := old.DeepCopy()
+	updated.Status.State = "Succeeded"
+	_, err := v.ValidateUpdate(context.Background(), old, updated)
+	require.NoError(t, err)
+
+	// changing an assignment does
+	updated.Status.IP = "10.0.0.5"
+	_, err = v.ValidateUpdate(context.Background(), old, updated)
+	require.Error(t, err)
+
+	// and so does reviving a terminated nc
+	old.Status.State = ncStateTerminated
+	updated = old.DeepCopy()
+	updated.Status.State = "Initialized"
+	_, err = v.ValidateUpdate(context.Background(), old, updated)
+	require.Error(t, err)
+}
@@ -0,0 +1,89 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Important: Run "make" to regenerate code after modifying this file
+
+// +kubebuilder:object:root=true
+
+// NodeCapabilities is the Schema for the NodeCapabilities API. It is named after the Node and reports the
+// dataplane capabilities of the node, as published by the networking agent running on it.
+// +kubebuilder:resource:shortName=ncap,scope=Cluster,path=nodecapabilities
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="OS",type=string,JSONPath=`.status.os`
+// +kubebuilder:printcolumn:name="Datapath",type=string,JSONPath=`.status.datapathMode`
+// +kubebuilder:printcolumn:name="DualStack",type=boolean,JSONPath=`.status.dualStack`
+// +kubebuilder:printcolumn:name="IPTables",type=string,priority=1,JSONPath=`.status.iptablesBackend`
+// +kubebuilder:printcolumn:name="HNS",type=string,priority=1,JSONPath=`.status.hnsVersion`
+// +kubebuilder:printcolumn:name="Publisher",type=string,priority=1,JSONPath=`.status.publisherVersion`
+type NodeCapabilities struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status NodeCapabilitiesStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NodeCapabilitiesList contains a list of NodeCapabilities
+type NodeCapabilitiesList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeCapabilities `json:"items"`
+}
+
+// IPTablesBackend is the kernel backend used by iptables.
+// +kubebuilder:validation:Enum=nft;legacy
+type IPTablesBackend string
+
+const (
+	IPTablesBackendNft    IPTablesBackend = "nft"
+	IPTablesBackendLegacy IPTablesBackend = "legacy"
+)
+
+// HNSSchemaVersion is the newest schema of the Windows Host Networking Service API supported by the node.
+// +kubebuilder:validation:Enum=v1;v2
+type HNSSchemaVersion string
+
+const (
+	HNSSchemaV1 HNSSchemaVersion = "v1"
+	HNSSchemaV2 HNSSchemaVersion = "v2"
+)
+
+// NodeCapabilitiesStatus defines the observed capabilities of the node
+type NodeCapabilitiesStatus struct {
+	// OS of the node, linux or windows.
+	OS string `json:"os"`
+	// DualStack is true if the node has both IPv4 and IPv6 addresses or pod CIDRs.
+	DualStack bool `json:"dualStack"`
+	// DatapathMode is the CNI datapath scenario the node is configured for, such as v4overlay,
+	// dualStackOverlay or cilium.
+	// +kubebuilder:validation:Optional
+	DatapathMode string `json:"datapathMode,omitempty"`
+	// IPTablesBackend is the backend of iptables on linux nodes.
+	// +kubebuilder:validation:Optional
+	IPTablesBackend IPTablesBackend `json:"iptablesBackend,omitempty"`
+	// HNSVersion is the major.minor version of the Host Networking Service on windows nodes.
+	// +kubebuilder:validation:Optional
+	HNSVersion string `json:"hnsVersion,omitempty"`
+	// HNSSchemaVersion is the newest HNS API schema supported by windows nodes.
+	// +kubebuilder:validation:Optional
+	HNSSchemaVersion HNSSchemaVersion `json:"hnsSchemaVersion,omitempty"`
+	// Publisher is the agent which published the capabilities, such as azure-cns.
+	Publisher string `json:"publisher"`
+	// PublisherVersion is the version of the publisher.
+	// +kubebuilder:validation:Optional
+	PublisherVersion string `json:"publisherVersion,omitempty"`
+	// LastUpdated is the time the capabilities were last published.
+	// +kubebuilder:validation:Optional
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeCapabilities{}, &NodeCapabilitiesList{})
+}
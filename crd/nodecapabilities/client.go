@@ -0,0 +1,119 @@
+package nodecapabilities
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/Azure/azure-container-networking/crd"
+	"github.com/Azure/azure-container-networking/crd/nodecapabilities/api/v1alpha1"
+	"github.com/pkg/errors"
+	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	typedv1 "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Scheme is a runtime scheme containing the client-go scheme and the NodeCapabilities scheme.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	_ = scheme.AddToScheme(Scheme)
+	_ = v1alpha1.AddToScheme(Scheme)
+}
+
+// Installer provides methods to manage the lifecycle of the NodeCapabilities resource definition.
+type Installer struct {
+	cli typedv1.CustomResourceDefinitionInterface
+}
+
+func NewInstaller(c *rest.Config) (*Installer, error) {
+	cli, err := crd.NewCRDClientFromConfig(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to init crd client")
+	}
+	return &Installer{
+		cli: cli,
+	}, nil
+}
+
+func (i *Installer) create(ctx context.Context, res *v1.CustomResourceDefinition) (*v1.CustomResourceDefinition, error) {
+	res, err := i.cli.Create(ctx, res, metav1.CreateOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create nodecapabilities crd")
+	}
+	return res, nil
+}
+
+// Install installs the embedded NodeCapabilities CRD definition in the cluster.
+func (i *Installer) Install(ctx context.Context) (*v1.CustomResourceDefinition, error) {
+	nodeCapabilities, err := GetNodeCapabilities()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get embedded nodecapabilities crd")
+	}
+	return i.create(ctx, nodeCapabilities)
+}
+
+// InstallOrUpdate installs the embedded NodeCapabilities CRD definition in the cluster or updates it if present.
+func (i *Installer) InstallOrUpdate(ctx context.Context) (*v1.CustomResourceDefinition, error) {
+	nodeCapabilities, err := GetNodeCapabilities()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get embedded nodecapabilities crd")
+	}
+	current, err := i.create(ctx, nodeCapabilities)
+	if !apierrors.IsAlreadyExists(err) {
+		return current, err
+	}
+	if current == nil {
+		current, err = i.cli.Get(ctx, nodeCapabilities.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get existing nodecapabilities crd")
+		}
+	}
+	if !reflect.DeepEqual(nodeCapabilities.Spec.Versions, current.Spec.Versions) {
+		nodeCapabilities.SetResourceVersion(current.GetResourceVersion())
+		previous := *current
+		current, err = i.cli.Update(ctx, nodeCapabilities, metav1.UpdateOptions{})
+		if err != nil {
+			return &previous, errors.Wrap(err, "failed to update existing nodecapabilities crd")
+		}
+	}
+	return current, nil
+}
+
+// Client provides methods to interact with instances of the NodeCapabilities custom resource.
+type Client struct {
+	cli client.Client
+}
+
+// NewClient creates a new NodeCapabilities client from the passed ctrlcli.Client.
+func NewClient(cli client.Client) *Client {
+	return &Client{
+		cli: cli,
+	}
+}
+
+// CreateOrUpdate creates the NodeCapabilities if it does not exist, then applies its status as the
+// fieldOwner.
+func (c *Client) CreateOrUpdate(ctx context.Context, nodeCapabilities *v1alpha1.NodeCapabilities, fieldOwner string) error {
+	// the status subresource is not written on create, so the status is always applied separately
+	if err := c.cli.Create(ctx, nodeCapabilities.DeepCopy()); err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "error creating nodecapabilities crd")
+	}
+	if err := c.cli.Status().Patch(ctx, &v1alpha1.NodeCapabilities{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "NodeCapabilities",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodeCapabilities.Name,
+		},
+		Status: nodeCapabilities.Status,
+	}, client.Apply, client.ForceOwnership, client.FieldOwner(fieldOwner)); err != nil {
+		return errors.Wrap(err, "error patching nodecapabilities status")
+	}
+	return nil
+}
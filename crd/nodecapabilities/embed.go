@@ -0,0 +1,26 @@
+package nodecapabilities
+
+import (
+	_ "embed"
+
+	// import the manifests package so that caller of this package have the manifests compiled in as a side-effect.
+	_ "github.com/Azure/azure-container-networking/crd/nodecapabilities/manifests"
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// NodeCapabilitiesYAML embeds the CRD YAML for downstream consumers.
+//
+//go:embed manifests/acn.azure.com_nodecapabilities.yaml
+var NodeCapabilitiesYAML []byte
+
+// GetNodeCapabilities parses the raw []byte NodeCapabilities in
+// to a CustomResourceDefinition and returns it or an unmarshalling error.
+func GetNodeCapabilities() (*apiextensionsv1.CustomResourceDefinition, error) {
+	nodeCapabilities := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(NodeCapabilitiesYAML, &nodeCapabilities); err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling embedded nodecapabilities")
+	}
+	return nodeCapabilities, nil
+}
@@ -0,0 +1,21 @@
+package nodecapabilities
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const filename = "manifests/acn.azure.com_nodecapabilities.yaml"
+
+func TestEmbed(t *testing.T) {
+	b, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, b, NodeCapabilitiesYAML)
+}
+
+func TestGetNodeCapabilities(t *testing.T) {
+	_, err := GetNodeCapabilities()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,55 @@
+package v1alpha
+
+import "net/netip"
+
+// Get returns the count for the passed IP family.
+func (c IPFamilyCounts) Get(family IPFamily) int64 {
+	if family == IPv6 {
+		return c.IPv6
+	}
+	return c.IPv4
+}
+
+// Set sets the count for the passed IP family.
+func (c *IPFamilyCounts) Set(family IPFamily, count int64) {
+	if family == IPv6 {
+		c.IPv6 = count
+		return
+	}
+	c.IPv4 = count
+}
+
+// Total returns the sum of the counts across all IP families.
+func (c IPFamilyCounts) Total() int64 {
+	return c.IPv4 + c.IPv6
+}
+
+// IPFamilies returns the IP families of the subnets of the NetworkContainers in the NNC Status,
+// in v4, v6 order. NetworkContainers with an unparseable SubnetAddressSpace are ignored.
+func (nnc *NodeNetworkConfig) IPFamilies() []IPFamily {
+	var v4, v6 bool
+	for i := range nnc.Status.NetworkContainers {
+		prefix, err := netip.ParsePrefix(nnc.Status.NetworkContainers[i].SubnetAddressSpace)
+		if err != nil {
+			continue
+		}
+		if prefix.Addr().Is4() {
+			v4 = true
+		} else {
+			v6 = true
+		}
+	}
+	families := []IPFamily{}
+	if v4 {
+		families = append(families, IPv4)
+	}
+	if v6 {
+		families = append(families, IPv6)
+	}
+	return families
+}
+
+// IsDualStack returns true if the NNC has both v4 and v6 NetworkContainers.
+func (nnc *NodeNetworkConfig) IsDualStack() bool {
+	return len(nnc.IPFamilies()) == 2 //nolint:gomnd // v4 and v6
+}
@@ -0,0 +1,55 @@
+package v1alpha
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPFamilies(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnets []string
+		want    []IPFamily
+	}{
+		{
+			name: "no NCs",
+			want: []IPFamily{},
+		},
+		{
+			name:    "v4 only",
+			subnets: []string{"10.0.0.0/24"},
+			want:    []IPFamily{IPv4},
+		},
+		{
+			name:    "dual-stack",
+			subnets: []string{"fd00::/64", "10.0.0.0/24"},
+			want:    []IPFamily{IPv4, IPv6},
+		},
+		{
+			name:    "unparseable subnet ignored",
+			subnets: []string{"10.0.0.0/24", "invalid"},
+			want:    []IPFamily{IPv4},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			nnc := &NodeNetworkConfig{}
+			for _, subnet := range tt.subnets {
+				nnc.Status.NetworkContainers = append(nnc.Status.NetworkContainers, NetworkContainer{SubnetAddressSpace: subnet})
+			}
+			assert.Equal(t, tt.want, nnc.IPFamilies())
+			assert.Equal(t, len(tt.want) == 2, nnc.IsDualStack())
+		})
+	}
+}
+
+func TestIPFamilyCounts(t *testing.T) {
+	c := IPFamilyCounts{}
+	c.Set(IPv4, 16)
+	c.Set(IPv6, 32)
+	assert.Equal(t, int64(16), c.Get(IPv4))
+	assert.Equal(t, int64(32), c.Get(IPv6))
+	assert.Equal(t, int64(48), c.Total())
+}
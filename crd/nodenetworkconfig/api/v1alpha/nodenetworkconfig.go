@@ -23,6 +23,7 @@ import (
 // +kubebuilder:printcolumn:name="NC Mode",type=string,priority=0,JSONPath=`.status.networkContainers[*].assignmentMode`
 // +kubebuilder:printcolumn:name="NC Type",type=string,priority=1,JSONPath=`.status.networkContainers[*].type`
 // +kubebuilder:printcolumn:name="NC Version",type=integer,priority=0,JSONPath=`.status.networkContainers[*].version`
+// +kubebuilder:printcolumn:name="Unprogrammed IPs",type=integer,priority=1,JSONPath=`.status.secondaryIPHealth.unprogrammedIPCount`
 type NodeNetworkConfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -41,11 +42,54 @@ type NodeNetworkConfigList struct {
 }
 
 // NodeNetworkConfigSpec defines the desired state of NetworkConfig
+// +kubebuilder:validation:XValidation:rule="!has(self.requestedIPCountByFamily) || self.requestedIPCount == self.requestedIPCountByFamily.ipv4 + self.requestedIPCountByFamily.ipv6",message="requestedIPCount must be the sum of requestedIPCountByFamily"
 type NodeNetworkConfigSpec struct {
+	// RequestedIPCount is the total number of IPs requested for the Node.
+	// When RequestedIPCountByFamily is set, this is the sum of the per-family counts.
 	// +kubebuilder:default=0
 	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
 	RequestedIPCount int64    `json:"requestedIPCount"`
 	IPsNotInUse      []string `json:"ipsNotInUse,omitempty"`
+	// RequestedIPCountByFamily is the number of IPs requested for each IP family on dual-stack Nodes.
+	// When set, the v4 and v6 pools are scaled independently to these counts.
+	// +kubebuilder:validation:Optional
+	RequestedIPCountByFamily *IPFamilyCounts `json:"requestedIPCountByFamily,omitempty"`
+}
+
+// IPFamily is an IP address family.
+// +kubebuilder:validation:Enum=ipv4;ipv6
+type IPFamily string
+
+const (
+	IPv4 IPFamily = "ipv4"
+	IPv6 IPFamily = "ipv6"
+)
+
+// IPFamilyCounts groups IP counts by IP family.
+type IPFamilyCounts struct {
+	// +kubebuilder:default=0
+	// +kubebuilder:validation:Minimum=0
+	IPv4 int64 `json:"ipv4,omitempty"`
+	// +kubebuilder:default=0
+	// +kubebuilder:validation:Minimum=0
+	IPv6 int64 `json:"ipv6,omitempty"`
+}
+
+// SecondaryIPHealth counts the secondary IPs by whether they are programmed on the host.
+type SecondaryIPHealth struct {
+	// HealthyIPCount is the number of secondary IPs which are programmed on the host.
+	// +kubebuilder:default=0
+	// +kubebuilder:validation:Minimum=0
+	HealthyIPCount int `json:"healthyIPCount"`
+	// UnprogrammedIPCount is the number of secondary IPs which are waiting for the host to be
+	// programmed with their NC version.
+	// +kubebuilder:default=0
+	// +kubebuilder:validation:Minimum=0
+	UnprogrammedIPCount int `json:"unprogrammedIPCount"`
+	// LastNCVersionUpdate is the last time the host was successfully programmed with a newer NC version.
+	// +kubebuilder:validation:Optional
+	LastNCVersionUpdate *metav1.Time `json:"lastNCVersionUpdate,omitempty"`
 }
 
 // Status indicates the NNC reconcile status
@@ -75,18 +119,31 @@ const (
 type NodeNetworkConfigStatus struct {
 	// +kubebuilder:default=0
 	// +kubebuilder:validation:Optional
-	AssignedIPCount   int                `json:"assignedIPCount"`
-	Scaler            Scaler             `json:"scaler,omitempty"`
-	Status            Status             `json:"status,omitempty"`
-	NetworkContainers []NetworkContainer `json:"networkContainers,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	AssignedIPCount int `json:"assignedIPCount"`
+	// AssignedIPCountByFamily is the number of IPs assigned for each IP family on dual-stack Nodes.
+	// +kubebuilder:validation:Optional
+	AssignedIPCountByFamily *IPFamilyCounts    `json:"assignedIPCountByFamily,omitempty"`
+	Scaler                  Scaler             `json:"scaler,omitempty"`
+	Status                  Status             `json:"status,omitempty"`
+	NetworkContainers       []NetworkContainer `json:"networkContainers,omitempty"`
+	// SecondaryIPHealth is reported by CNS and summarizes how many of the secondary IPs are
+	// programmed on the host.
+	// +kubebuilder:validation:Optional
+	SecondaryIPHealth *SecondaryIPHealth `json:"secondaryIPHealth,omitempty"`
 }
 
-// Scaler groups IP request params together
+// Scaler groups IP request params together.
+// On dual-stack Nodes the parameters apply to each IP family independently.
 type Scaler struct {
-	BatchSize               int64 `json:"batchSize,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	BatchSize int64 `json:"batchSize,omitempty"`
+	// +kubebuilder:validation:Minimum=0
 	ReleaseThresholdPercent int64 `json:"releaseThresholdPercent,omitempty"`
+	// +kubebuilder:validation:Minimum=0
 	RequestThresholdPercent int64 `json:"requestThresholdPercent,omitempty"`
-	MaxIPCount              int64 `json:"maxIPCount,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	MaxIPCount int64 `json:"maxIPCount,omitempty"`
 }
 
 // AssignmentMode is whether we are allocated an entire block or IP by IP.
@@ -113,12 +170,13 @@ type NetworkContainer struct {
 	// +kubebuilder:default=dynamic
 	AssignmentMode AssignmentMode `json:"assignmentMode,omitempty"`
 	// +kubebuilder:default=vnet
-	Type               NCType         `json:"type,omitempty"`
-	PrimaryIP          string         `json:"primaryIP,omitempty"`
-	SubnetName         string         `json:"subnetName,omitempty"`
-	IPAssignments      []IPAssignment `json:"ipAssignments,omitempty"`
-	DefaultGateway     string         `json:"defaultGateway,omitempty"`
-	SubnetAddressSpace string         `json:"subnetAddressSpace,omitempty"`
+	Type           NCType         `json:"type,omitempty"`
+	PrimaryIP      string         `json:"primaryIP,omitempty"`
+	SubnetName     string         `json:"subnetName,omitempty"`
+	IPAssignments  []IPAssignment `json:"ipAssignments,omitempty"`
+	DefaultGateway string         `json:"defaultGateway,omitempty"`
+	// +kubebuilder:validation:Format=cidr
+	SubnetAddressSpace string `json:"subnetAddressSpace,omitempty"`
 	// +kubebuilder:default=0
 	// +kubebuilder:validation:Optional
 	Version         int64    `json:"version"`
@@ -23,6 +23,21 @@ func (in *IPAssignment) DeepCopy() *IPAssignment {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPFamilyCounts) DeepCopyInto(out *IPFamilyCounts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPFamilyCounts.
+func (in *IPFamilyCounts) DeepCopy() *IPFamilyCounts {
+	if in == nil {
+		return nil
+	}
+	out := new(IPFamilyCounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkContainer) DeepCopyInto(out *NetworkContainer) {
 	*out = *in
@@ -110,6 +125,11 @@ func (in *NodeNetworkConfigSpec) DeepCopyInto(out *NodeNetworkConfigSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RequestedIPCountByFamily != nil {
+		in, out := &in.RequestedIPCountByFamily, &out.RequestedIPCountByFamily
+		*out = new(IPFamilyCounts)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeNetworkConfigSpec.
@@ -125,6 +145,11 @@ func (in *NodeNetworkConfigSpec) DeepCopy() *NodeNetworkConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeNetworkConfigStatus) DeepCopyInto(out *NodeNetworkConfigStatus) {
 	*out = *in
+	if in.AssignedIPCountByFamily != nil {
+		in, out := &in.AssignedIPCountByFamily, &out.AssignedIPCountByFamily
+		*out = new(IPFamilyCounts)
+		**out = **in
+	}
 	out.Scaler = in.Scaler
 	if in.NetworkContainers != nil {
 		in, out := &in.NetworkContainers, &out.NetworkContainers
@@ -133,6 +158,11 @@ func (in *NodeNetworkConfigStatus) DeepCopyInto(out *NodeNetworkConfigStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SecondaryIPHealth != nil {
+		in, out := &in.SecondaryIPHealth, &out.SecondaryIPHealth
+		*out = new(SecondaryIPHealth)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeNetworkConfigStatus.
@@ -159,3 +189,22 @@ func (in *Scaler) DeepCopy() *Scaler {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecondaryIPHealth) DeepCopyInto(out *SecondaryIPHealth) {
+	*out = *in
+	if in.LastNCVersionUpdate != nil {
+		in, out := &in.LastNCVersionUpdate, &out.LastNCVersionUpdate
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecondaryIPHealth.
+func (in *SecondaryIPHealth) DeepCopy() *SecondaryIPHealth {
+	if in == nil {
+		return nil
+	}
+	out := new(SecondaryIPHealth)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -2,6 +2,7 @@ package nodenetworkconfig
 
 import (
 	"context"
+	"encoding/json"
 	"reflect"
 
 	"github.com/Azure/azure-container-networking/crd"
@@ -13,6 +14,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -105,6 +107,24 @@ func (c *Client) Get(ctx context.Context, key types.NamespacedName) (*v1alpha.No
 	return nodeNetworkConfig, errors.Wrapf(err, "failed to get nnc %v", key)
 }
 
+// List returns the NodeNetworkConfigs matching the ListOptions.
+func (c *Client) List(ctx context.Context, opts ...client.ListOption) (*v1alpha.NodeNetworkConfigList, error) {
+	nodeNetworkConfigs := &v1alpha.NodeNetworkConfigList{}
+	err := c.cli.List(ctx, nodeNetworkConfigs, opts...)
+	return nodeNetworkConfigs, errors.Wrap(err, "failed to list nncs")
+}
+
+// Watch watches the NodeNetworkConfigs matching the ListOptions.
+// The underlying client must implement client.WithWatch.
+func (c *Client) Watch(ctx context.Context, opts ...client.ListOption) (watch.Interface, error) {
+	wcli, ok := c.cli.(client.WithWatch)
+	if !ok {
+		return nil, crd.ErrWatchNotSupported
+	}
+	w, err := wcli.Watch(ctx, &v1alpha.NodeNetworkConfigList{}, opts...)
+	return w, errors.Wrap(err, "failed to watch nncs")
+}
+
 // PatchSpec performs a server-side patch of the passed NodeNetworkConfigSpec to the NodeNetworkConfig specified by the NamespacedName.
 func (c *Client) PatchSpec(ctx context.Context, key types.NamespacedName, spec *v1alpha.NodeNetworkConfigSpec, fieldManager string) (*v1alpha.NodeNetworkConfig, error) {
 	obj := genPatchSkel(key)
@@ -115,20 +135,35 @@ func (c *Client) PatchSpec(ctx context.Context, key types.NamespacedName, spec *
 	return obj, nil
 }
 
-// UpdateSpec does a fetch, deepcopy, and update of the NodeNetworkConfig with the passed spec.
+// UpdateSpec does a fetch of the NodeNetworkConfig and a JSON merge patch of the passed spec, so
+// that only the changed Spec fields are sent and concurrent writes to the rest of the object do not conflict.
 // Deprecated: UpdateSpec is deprecated and usage should migrate to PatchSpec.
 func (c *Client) UpdateSpec(ctx context.Context, key types.NamespacedName, spec *v1alpha.NodeNetworkConfigSpec) (*v1alpha.NodeNetworkConfig, error) {
 	nnc, err := c.Get(ctx, key)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get nnc")
 	}
+	patch := client.MergeFrom(nnc.DeepCopy())
 	spec.DeepCopyInto(&nnc.Spec)
-	if err := c.cli.Update(ctx, nnc); err != nil {
-		return nil, errors.Wrap(err, "failed to update nnc")
+	if err := c.cli.Patch(ctx, nnc, patch); err != nil {
+		return nil, errors.Wrap(err, "failed to patch nnc")
 	}
 	return nnc, nil
 }
 
+// PatchSecondaryIPHealth sets the SecondaryIPHealth in the status of the NodeNetworkConfig specified by the NamespacedName.
+// A JSON merge patch is used, so that the rest of the status, which is owned by DNC-RC, is not modified.
+func (c *Client) PatchSecondaryIPHealth(ctx context.Context, key types.NamespacedName, health *v1alpha.SecondaryIPHealth, fieldManager string) error {
+	body, err := json.Marshal(map[string]any{"status": map[string]any{"secondaryIPHealth": health}})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal secondary ip health")
+	}
+	if err := c.cli.Status().Patch(ctx, genPatchSkel(key), client.RawPatch(types.MergePatchType, body), client.FieldOwner(fieldManager)); err != nil {
+		return errors.Wrapf(err, "failed to patch nnc %v secondary ip health", key)
+	}
+	return nil
+}
+
 // SetOwnerRef sets the controller of the NodeNetworkConfig to the given object atomically, using HTTP Patch.
 // Deprecated: SetOwnerRef is deprecated, use the more correctly named SetControllerRef.
 func (c *Client) SetOwnerRef(ctx context.Context, key types.NamespacedName, owner metav1.Object, fieldManager string) (*v1alpha.NodeNetworkConfig, error) {
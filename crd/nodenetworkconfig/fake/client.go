@@ -0,0 +1,24 @@
+// Package fake provides an in-memory NodeNetworkConfig client for unit tests.
+package fake
+
+import (
+	crdfake "github.com/Azure/azure-container-networking/crd/fake"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewStore returns an in-memory client.WithWatch seeded with the passed NodeNetworkConfigs.
+// Tests can use it to act as the other writers of the NodeNetworkConfigs.
+func NewStore(objs ...*v1alpha.NodeNetworkConfig) client.WithWatch {
+	builder := crdfake.NewClientBuilder(nodenetworkconfig.Scheme).WithStatusSubresource(&v1alpha.NodeNetworkConfig{})
+	for i := range objs {
+		builder = builder.WithObjects(objs[i])
+	}
+	return builder.Build()
+}
+
+// NewClient returns a NodeNetworkConfig Client backed by a NewStore seeded with the passed NodeNetworkConfigs.
+func NewClient(objs ...*v1alpha.NodeNetworkConfig) *nodenetworkconfig.Client {
+	return nodenetworkconfig.NewClient(NewStore(objs...))
+}
@@ -0,0 +1,46 @@
+package fake_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "kube-system", Name: "node1"}
+	seed := &v1alpha.NodeNetworkConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		Status:     v1alpha.NodeNetworkConfigStatus{Scaler: v1alpha.Scaler{BatchSize: 16}},
+	}
+	cli := fake.NewClient(seed)
+
+	w, err := cli.Watch(ctx, client.InNamespace(key.Namespace))
+	require.NoError(t, err)
+	defer w.Stop()
+
+	_, err = cli.PatchSpec(ctx, key, &v1alpha.NodeNetworkConfigSpec{RequestedIPCount: 32}, "test")
+	require.NoError(t, err)
+	event := <-w.ResultChan()
+	assert.Equal(t, watch.Modified, event.Type)
+
+	nnc, err := cli.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(32), nnc.Spec.RequestedIPCount)
+	assert.Equal(t, int64(16), nnc.Status.Scaler.BatchSize, "spec patch must not modify the status")
+
+	// patching an NNC which does not exist creates it, like server-side apply.
+	_, err = cli.PatchSpec(ctx, types.NamespacedName{Namespace: key.Namespace, Name: "node2"}, &v1alpha.NodeNetworkConfigSpec{RequestedIPCount: 16}, "test")
+	require.NoError(t, err)
+	nncs, err := cli.List(ctx, client.InNamespace(key.Namespace))
+	require.NoError(t, err)
+	assert.Len(t, nncs.Items, 2)
+}
@@ -0,0 +1,87 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Important: Run "make" to regenerate code after modifying this file
+
+// +kubebuilder:object:root=true
+
+// OverlayExtensionConfig is the Schema for the overlayextensionconfigs API. It extends the address
+// space reachable from an overlay cluster's pods without SNAT to the ExtensionIPRange.
+// +kubebuilder:resource:shortName=oec,scope=Namespaced
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="ExtensionIPRange",type=string,JSONPath=`.spec.extensionIPRange`
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+type OverlayExtensionConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OverlayExtensionConfigSpec   `json:"spec,omitempty"`
+	Status OverlayExtensionConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OverlayExtensionConfigList contains a list of OverlayExtensionConfig
+type OverlayExtensionConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OverlayExtensionConfig `json:"items"`
+}
+
+// OverlayExtensionConfigSpec defines the desired state of OverlayExtensionConfig
+// +kubebuilder:validation:XValidation:rule="!has(oldSelf.extensionIPRange) || self.extensionIPRange == oldSelf.extensionIPRange", message="extensionIPRange is immutable"
+type OverlayExtensionConfigSpec struct {
+	// ExtensionIPRange is a CIDR which the pods on every node can reach, and be reached from, with their pod IPs.
+	// +kubebuilder:validation:Format=cidr
+	ExtensionIPRange string `json:"extensionIPRange,omitempty"`
+}
+
+// OECState is the programming state of an OverlayExtensionConfig.
+// +kubebuilder:validation:Enum=Pending;Succeeded;Failed
+type OECState string
+
+const (
+	Pending   OECState = "Pending"
+	Succeeded OECState = "Succeeded"
+	Failed    OECState = "Failed"
+)
+
+// OverlayExtensionConfigStatus defines the observed state of OverlayExtensionConfig
+type OverlayExtensionConfigStatus struct {
+	// State is the overall state of the OverlayExtensionConfig, set by the control plane.
+	// +kubebuilder:validation:Optional
+	State OECState `json:"state,omitempty"`
+	// Message explains the State.
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+	// Nodes is the programming state of the ExtensionIPRange on each node, each written by the agent on that node.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=node
+	Nodes []NodeStatus `json:"nodes,omitempty"`
+}
+
+// NodeStatus is the programming state of the ExtensionIPRange on a node.
+type NodeStatus struct {
+	// Node is the name of the node.
+	Node string `json:"node"`
+	// State is the programming state on the node.
+	State OECState `json:"state"`
+	// Message explains the State.
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+	// ObservedGeneration is the generation of the OverlayExtensionConfig the State applies to.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OverlayExtensionConfig{}, &OverlayExtensionConfigList{})
+}
@@ -0,0 +1,63 @@
+package overlayextensionconfig
+
+import (
+	"context"
+
+	"github.com/Azure/azure-container-networking/crd/overlayextensionconfig/api/v1alpha1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Scheme is a runtime scheme containing the client-go scheme and the OverlayExtensionConfig scheme.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	_ = scheme.AddToScheme(Scheme)
+	_ = v1alpha1.AddToScheme(Scheme)
+}
+
+// Client provides methods to interact with instances of the OverlayExtensionConfig custom resource.
+type Client struct {
+	cli client.Client
+}
+
+// NewClient creates a new OverlayExtensionConfig client from the passed ctrlcli.Client.
+func NewClient(cli client.Client) *Client {
+	return &Client{
+		cli: cli,
+	}
+}
+
+// List returns the OverlayExtensionConfigs matching the ListOptions.
+func (c *Client) List(ctx context.Context, opts ...client.ListOption) (*v1alpha1.OverlayExtensionConfigList, error) {
+	overlayExtensionConfigs := &v1alpha1.OverlayExtensionConfigList{}
+	err := c.cli.List(ctx, overlayExtensionConfigs, opts...)
+	return overlayExtensionConfigs, errors.Wrap(err, "failed to list oecs")
+}
+
+// ApplyNodeStatus performs a server-side patch of the passed NodeStatus to the OverlayExtensionConfig
+// specified by the NamespacedName. Each node must use its own fieldOwner, so that it only owns its entry in the
+// status and the nodes do not overwrite each other's.
+func (c *Client) ApplyNodeStatus(ctx context.Context, key types.NamespacedName, status v1alpha1.NodeStatus, fieldOwner string) error {
+	obj := &v1alpha1.OverlayExtensionConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "OverlayExtensionConfig",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+		},
+		Status: v1alpha1.OverlayExtensionConfigStatus{
+			Nodes: []v1alpha1.NodeStatus{status},
+		},
+	}
+	if err := c.cli.Status().Patch(ctx, obj, client.Apply, client.ForceOwnership, client.FieldOwner(fieldOwner)); err != nil {
+		return errors.Wrapf(err, "failed to apply node %s status to oec %v", status.Node, key)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package overlayextensionconfig
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const filename = "manifests/acn.azure.com_overlayextensionconfigs.yaml"
+
+func TestEmbed(t *testing.T) {
+	b, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, b, OverlayExtensionConfigsYAML)
+}
+
+func TestGetOverlayExtensionConfigs(t *testing.T) {
+	_, err := GetOverlayExtensionConfigs()
+	assert.NoError(t, err)
+}
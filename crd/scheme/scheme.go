@@ -0,0 +1,42 @@
+// Package scheme provides a runtime.Scheme builder which registers the client-go core types,
+// the apiextensions types and every ACN CRD, so that components and tests do not need to
+// assemble their own schemes.
+package scheme
+
+import (
+	cssv1alpha1 "github.com/Azure/azure-container-networking/crd/clustersubnetstate/api/v1alpha1"
+	mtv1alpha1 "github.com/Azure/azure-container-networking/crd/multitenancy/api/v1alpha1"
+	mtncv1alpha1 "github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1alpha1"
+	mtncv1beta1 "github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1beta1"
+	ncapv1alpha1 "github.com/Azure/azure-container-networking/crd/nodecapabilities/api/v1alpha1"
+	nncv1alpha "github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	oecv1alpha1 "github.com/Azure/azure-container-networking/crd/overlayextensionconfig/api/v1alpha1"
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+var builder = runtime.NewSchemeBuilder(
+	clientgoscheme.AddToScheme,
+	apiextensionsv1.AddToScheme,
+	cssv1alpha1.AddToScheme,
+	mtv1alpha1.AddToScheme,
+	mtncv1alpha1.AddToScheme,
+	mtncv1beta1.AddToScheme,
+	ncapv1alpha1.AddToScheme,
+	nncv1alpha.AddToScheme,
+	oecv1alpha1.AddToScheme,
+)
+
+// AddToScheme adds the client-go core types, the apiextensions types and all ACN CRDs to the Scheme.
+var AddToScheme = builder.AddToScheme
+
+// New returns a new Scheme with all the types registered by AddToScheme.
+func New() (*runtime.Scheme, error) {
+	s := runtime.NewScheme()
+	if err := AddToScheme(s); err != nil {
+		return nil, errors.Wrap(err, "failed to build scheme")
+	}
+	return s, nil
+}
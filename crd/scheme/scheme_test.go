@@ -0,0 +1,38 @@
+package scheme
+
+import (
+	"testing"
+
+	cssv1alpha1 "github.com/Azure/azure-container-networking/crd/clustersubnetstate/api/v1alpha1"
+	mtv1alpha1 "github.com/Azure/azure-container-networking/crd/multitenancy/api/v1alpha1"
+	mtncv1beta1 "github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1beta1"
+	ncapv1alpha1 "github.com/Azure/azure-container-networking/crd/nodecapabilities/api/v1alpha1"
+	nncv1alpha "github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	oecv1alpha1 "github.com/Azure/azure-container-networking/crd/overlayextensionconfig/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestNew(t *testing.T) {
+	s, err := New()
+	require.NoError(t, err)
+	for _, obj := range []runtime.Object{
+		&corev1.Pod{},
+		&corev1.Node{},
+		&apiextensionsv1.CustomResourceDefinition{},
+		&cssv1alpha1.ClusterSubnetState{},
+		&mtv1alpha1.MultitenantPodNetworkConfig{},
+		&mtv1alpha1.PodNetwork{},
+		&mtv1alpha1.PodNetworkInstance{},
+		&mtncv1beta1.MultiTenantNetworkContainer{},
+		&ncapv1alpha1.NodeCapabilities{},
+		&nncv1alpha.NodeNetworkConfig{},
+		&oecv1alpha1.OverlayExtensionConfig{},
+	} {
+		_, _, err := s.ObjectKinds(obj)
+		assert.NoError(t, err, "%T is not registered", obj)
+	}
+}
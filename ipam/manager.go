@@ -4,6 +4,7 @@
 package ipam
 
 import (
+	"sort"
 	"sync"
 	"time"
 
@@ -45,6 +46,8 @@ type AddressManager interface {
 
 	RequestAddress(asId, poolId, address string, options map[string]string) (string, error)
 	ReleaseAddress(asId, poolId, address string, options map[string]string) error
+
+	GetAddressAllocations() []AddressAllocation
 }
 
 // AddressConfigSource configures the address pools managed by AddressManager.
@@ -402,3 +405,45 @@ func (am *addressManager) ReleaseAddress(asId string, poolId string, address str
 
 	return nil
 }
+
+// GetAddressAllocations returns the addresses in use in all address spaces, ordered by address space, pool and address.
+func (am *addressManager) GetAddressAllocations() []AddressAllocation {
+	am.Lock()
+	defer am.Unlock()
+
+	allocations := []AddressAllocation{}
+	for _, as := range am.AddrSpaces {
+		for _, ap := range as.Pools {
+			prefixLength, _ := ap.Subnet.Mask.Size()
+			var gateway string
+			if ap.Gateway != nil {
+				gateway = ap.Gateway.String()
+			}
+			for _, ar := range ap.Addresses {
+				if !ar.InUse {
+					continue
+				}
+				allocations = append(allocations, AddressAllocation{
+					AddressSpace: as.Id,
+					PoolID:       ap.Id,
+					Address:      ar.Addr.String(),
+					PrefixLength: prefixLength,
+					Gateway:      gateway,
+					ID:           ar.ID,
+				})
+			}
+		}
+	}
+
+	sort.Slice(allocations, func(i, j int) bool {
+		a, b := allocations[i], allocations[j]
+		if a.AddressSpace != b.AddressSpace {
+			return a.AddressSpace < b.AddressSpace
+		}
+		if a.PoolID != b.PoolID {
+			return a.PoolID < b.PoolID
+		}
+		return a.Address < b.Address
+	})
+	return allocations
+}
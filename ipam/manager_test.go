@@ -387,4 +387,32 @@ var _ = Describe("Test Manager", func() {
 			})
 		})
 	})
+
+	Describe("Test GetAddressAllocations", func() {
+		Context("When addresses are in use", func() {
+			It("Should return only the addresses in use, in order", func() {
+				am := &addressManager{
+					AddrSpaces: make(map[string]*addressSpace),
+				}
+				ap := &addressPool{
+					Id:      "10.0.1.0/24",
+					Subnet:  subnet1,
+					Gateway: net.IPv4(10, 0, 1, 1),
+					Addresses: map[string]*addressRecord{
+						addr12.String(): {ID: "container2", Addr: addr12, InUse: true},
+						addr11.String(): {Addr: addr11},
+						addr13.String(): {ID: "container3", Addr: addr13, InUse: true},
+					},
+				}
+				am.AddrSpaces[LocalDefaultAddressSpaceId] = &addressSpace{
+					Id:    LocalDefaultAddressSpaceId,
+					Pools: map[string]*addressPool{ap.Id: ap},
+				}
+				Expect(am.GetAddressAllocations()).To(Equal([]AddressAllocation{
+					{AddressSpace: LocalDefaultAddressSpaceId, PoolID: "10.0.1.0/24", Address: "10.0.1.2", PrefixLength: 24, Gateway: "10.0.1.1", ID: "container2"},
+					{AddressSpace: LocalDefaultAddressSpaceId, PoolID: "10.0.1.0/24", Address: "10.0.1.3", PrefixLength: 24, Gateway: "10.0.1.1", ID: "container3"},
+				}))
+			})
+		})
+	})
 })
@@ -75,6 +75,17 @@ type AddressPoolInfo struct {
 	Capacity       int
 }
 
+// AddressAllocation is an address in use in an address pool, exported to migrate the allocations to another IPAM.
+type AddressAllocation struct {
+	AddressSpace string
+	PoolID       string
+	Address      string
+	PrefixLength int
+	Gateway      string
+	// ID is the identifier the address was requested with, the container ID for CNI.
+	ID string
+}
+
 // Represents an IP address in a pool.
 type addressRecord struct {
 	ID        string
@@ -3,7 +3,9 @@ package iptables
 // This package contains wrapper functions to program iptables rules
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cni/log"
 	"github.com/Azure/azure-container-networking/platform"
@@ -61,6 +63,8 @@ const (
 	iptables    = "iptables"
 	ip6tables   = "ip6tables"
 	lockTimeout = 60
+	// cmdTimeout leaves headroom over the xtables lock wait so a command that waited for the lock can still finish
+	cmdTimeout = (lockTimeout + 10) * time.Second
 )
 
 const (
@@ -109,7 +113,10 @@ func (c *Client) RunCmd(version, params string) error {
 		cmd = fmt.Sprintf("%s -w %d %s", iptCmd, lockTimeout, params)
 	}
 
-	if _, err := p.ExecuteCommand(cmd); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
+	defer cancel()
+
+	if _, err := p.ExecuteCommandContext(ctx, cmd); err != nil {
 		return err
 	}
 
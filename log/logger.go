@@ -10,6 +10,10 @@ import (
 	"os"
 	"path"
 	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Log level
@@ -40,8 +44,17 @@ const (
 	maxLogFileSize   = 5 * 1024 * 1024
 	maxLogFileCount  = 8
 	rotationCheckFrq = 8
+
+	// Frames between a legacy call site and the zap logger: Logger.zapf, Logger.logAt and the entry point.
+	zapCallerSkip = 3
+	// Frames an entry point can add between itself and Logger.logAt, e.g. Request and Response.
+	maxZapCallerDepth = 1
 )
 
+// zapLoggers holds the zap logger for each caller depth, so that the caller is the legacy call site whichever
+// entry point, a Logger method or a package level function, is used.
+type zapLoggers [maxZapCallerDepth + 1]*zap.Logger
+
 // Logger object
 type Logger struct {
 	l            *log.Logger
@@ -54,6 +67,8 @@ type Logger struct {
 	callCount    int
 	directory    string
 	mutex        *sync.Mutex
+	// zl, when set, receives all log calls in place of the legacy writer.
+	zl atomic.Pointer[zapLoggers]
 }
 
 var pid = os.Getpid()
@@ -98,6 +113,34 @@ func NewLogger(name string, level, target int, logDir string) *Logger {
 	return logger
 }
 
+// SetZapLogger routes all subsequent log calls to z, leaving level filtering and
+// rotation to the zap logger. This lets existing call sites move onto zaplog unchanged.
+func (logger *Logger) SetZapLogger(z *zap.Logger) {
+	if z == nil {
+		logger.zl.Store(nil)
+		return
+	}
+	// skip the facade frames so the caller is the legacy call site
+	var zls zapLoggers
+	for depth := range zls {
+		zls[depth] = z.WithOptions(zap.AddCaller(), zap.AddCallerSkip(zapCallerSkip+depth))
+	}
+	logger.zl.Store(&zls)
+}
+
+// zapf writes the formatted string at lvl to the zap logger for the caller depth and reports whether a zap logger
+// is set.
+func (logger *Logger) zapf(depth int, lvl zapcore.Level, format string, args ...interface{}) bool {
+	zls := logger.zl.Load()
+	if zls == nil {
+		return false
+	}
+	if ce := zls[depth].Check(lvl, fmt.Sprintf(format, args...)); ce != nil {
+		ce.Write()
+	}
+	return true
+}
+
 // SetName sets the log name.
 func (logger *Logger) SetName(name string) {
 	logger.name = name
@@ -201,32 +244,40 @@ func (logger *Logger) rotate() error {
 
 // Request logs a structured request.
 func (logger *Logger) Request(tag string, request interface{}, err error) {
+	logger.request(tag, request, err)
+}
+
+func (logger *Logger) request(tag string, request interface{}, err error) {
 	if err == nil {
-		logger.Printf("[%s] Received %T %+v.", tag, request, request)
+		logger.logAt(1, zapcore.InfoLevel, LevelInfo, "[%s] Received %T %+v.", tag, request, request)
 	} else {
-		logger.Errorf("[%s] Failed to decode %T %+v %s.", tag, request, request, err.Error())
+		logger.logAt(1, zapcore.ErrorLevel, LevelAlert, "[%s] Failed to decode %T %+v %s.", tag, request, request, err.Error())
 	}
 }
 
 // Response logs a structured response.
 func (logger *Logger) Response(tag string, response interface{}, returnCode int, returnStr string, err error) {
+	logger.response(tag, response, returnCode, returnStr, err)
+}
+
+func (logger *Logger) response(tag string, response interface{}, returnCode int, returnStr string, err error) {
 	if err == nil && returnCode == 0 {
-		logger.Printf("[%s] Sent %T %+v.", tag, response, response)
+		logger.logAt(1, zapcore.InfoLevel, LevelInfo, "[%s] Sent %T %+v.", tag, response, response)
 	} else if err != nil {
-		logger.Errorf("[%s] Code:%s, %+v %s.", tag, returnStr, response, err.Error())
+		logger.logAt(1, zapcore.ErrorLevel, LevelAlert, "[%s] Code:%s, %+v %s.", tag, returnStr, response, err.Error())
 	} else {
-		logger.Errorf("[%s] Code:%s, %+v.", tag, returnStr, response)
+		logger.logAt(1, zapcore.ErrorLevel, LevelAlert, "[%s] Code:%s, %+v.", tag, returnStr, response)
 	}
 }
 
 // ResponseEx logs a structured response and the request associate with it.
 func (logger *Logger) ResponseEx(tag string, request interface{}, response interface{}, returnCode int, returnStr string, err error) {
 	if err == nil && returnCode == 0 {
-		logger.Printf("[%s] Sent %T %+v %T %+v.", tag, request, request, response, response)
+		logger.logAt(0, zapcore.InfoLevel, LevelInfo, "[%s] Sent %T %+v %T %+v.", tag, request, request, response, response)
 	} else if err != nil {
-		logger.Errorf("[%s] Code:%s, %+v, %+v %s.", tag, returnStr, request, response, err.Error())
+		logger.logAt(0, zapcore.ErrorLevel, LevelAlert, "[%s] Code:%s, %+v, %+v %s.", tag, returnStr, request, response, err.Error())
 	} else {
-		logger.Errorf("[%s] Code:%s, %+v, %+v.", tag, returnStr, request, response)
+		logger.logAt(0, zapcore.ErrorLevel, LevelAlert, "[%s] Code:%s, %+v, %+v.", tag, returnStr, request, response)
 	}
 }
 
@@ -242,41 +293,43 @@ func (logger *Logger) logf(format string, args ...interface{}) {
 	logger.l.Printf(format, args...)
 }
 
-// Logf wraps logf.
-func (logger *Logger) Logf(format string, args ...interface{}) {
+// logAt logs a formatted string to the zap logger at lvl or, without one, to the local log if the logger is at least
+// at level. depth is the number of frames between the exported entry point and logAt, beyond the calling method.
+func (logger *Logger) logAt(depth int, lvl zapcore.Level, level int, format string, args ...interface{}) {
+	if logger.zapf(depth, lvl, format, args...) {
+		return
+	}
+
+	if logger.level < level {
+		return
+	}
+
 	logger.mutex.Lock()
 	logger.logf(format, args...)
 	logger.mutex.Unlock()
 }
 
+// Logf wraps logf.
+func (logger *Logger) Logf(format string, args ...interface{}) {
+	logger.logAt(0, zapcore.InfoLevel, LevelAlert, format, args...)
+}
+
 // Printf logs a formatted string at info level.
 func (logger *Logger) Printf(format string, args ...interface{}) {
-	if logger.level < LevelInfo {
-		return
-	}
-
-	logger.Logf(format, args...)
+	logger.logAt(0, zapcore.InfoLevel, LevelInfo, format, args...)
 }
 
 // Debugf logs a formatted string at info level.
 func (logger *Logger) Debugf(format string, args ...interface{}) {
-	if logger.level < LevelDebug {
-		return
-	}
-
-	logger.Logf(format, args...)
+	logger.logAt(0, zapcore.DebugLevel, LevelDebug, format, args...)
 }
 
 // Errorf logs a formatted string at info level and sends the string to TelemetryBuffer.
 func (logger *Logger) Errorf(format string, args ...interface{}) {
-	logger.Logf(format, args...)
+	logger.logAt(0, zapcore.ErrorLevel, LevelAlert, format, args...)
 }
 
 // Warnf logs a formatted string at warninglevel
 func (logger *Logger) Warnf(format string, args ...interface{}) {
-	if logger.level < LevelWarning {
-		return
-	}
-
-	logger.Logf(format, args...)
+	logger.logAt(0, zapcore.WarnLevel, LevelWarning, format, args...)
 }
@@ -7,11 +7,15 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"runtime"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 const (
@@ -131,3 +135,68 @@ func TestPid(t *testing.T) {
 		t.Fatalf("Unexpected log: %s.", log)
 	}
 }
+
+func TestSetZapLogger(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	l := NewLogger(logName, LevelInfo, TargetStderr, "")
+	l.SetZapLogger(zap.New(core))
+
+	l.Printf("info %d", 1)
+	l.Debugf("debug %d", 2)
+	l.Errorf("error %d", 3)
+
+	entries := observed.AllUntimed()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "info 1", entries[0].Message)
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	assert.True(t, entries[0].Caller.Defined)
+	assert.Equal(t, "logger_test.go", path.Base(entries[0].Caller.File))
+	assert.Equal(t, zapcore.DebugLevel, entries[1].Level)
+	assert.Equal(t, zapcore.ErrorLevel, entries[2].Level)
+
+	l.SetZapLogger(nil)
+	l.Printf("legacy")
+	assert.Len(t, observed.AllUntimed(), 3)
+}
+
+func TestZapLoggerCaller(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	l := NewLogger(logName, LevelInfo, TargetStderr, "")
+	l.SetZapLogger(zap.New(core))
+	SetZapLogger(zap.New(core))
+	defer SetZapLogger(nil)
+
+	// each entry point logs on the line after the one recorded
+	var lines []int
+	line := func() {
+		_, _, l, _ := runtime.Caller(1)
+		lines = append(lines, l+1)
+	}
+	line()
+	Printf("std %d", 1)
+	line()
+	Errorf("std %d", 2)
+	line()
+	Request("tag", struct{}{}, nil)
+	line()
+	Response("tag", struct{}{}, 1, "failed", nil)
+	line()
+	GetStd().Printf("std %d", 3)
+	line()
+	GetStd().Request("tag", struct{}{}, nil)
+	line()
+	l.Printf("logger %d", 1)
+	line()
+	l.Warnf("logger %d", 2)
+	line()
+	l.Response("tag", struct{}{}, 0, "", nil)
+	line()
+	l.ResponseEx("tag", struct{}{}, struct{}{}, 0, "", nil)
+
+	entries := observed.AllUntimed()
+	require.Len(t, entries, len(lines))
+	for i, entry := range entries {
+		assert.Equal(t, "logger_test.go", path.Base(entry.Caller.File), entry.Message)
+		assert.Equal(t, lines[i], entry.Caller.Line, entry.Message)
+	}
+}
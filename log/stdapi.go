@@ -3,6 +3,11 @@
 
 package log
 
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
 // Standard logger is a pre-defined logger for convenience.
 // Set log directory as the current location
 var stdLog = NewLogger("azure-container-networking", LevelInfo, TargetStderr, "")
@@ -12,6 +17,12 @@ func GetStd() *Logger {
 	return stdLog
 }
 
+// SetZapLogger routes the standard logger to z. The package level functions log through the same frames as the
+// Logger methods, so the caller is reported for both.
+func SetZapLogger(z *zap.Logger) {
+	stdLog.SetZapLogger(z)
+}
+
 func SetName(name string) {
 	stdLog.SetName(name)
 }
@@ -37,27 +48,27 @@ func GetLogDirectory() string {
 }
 
 func Request(tag string, request interface{}, err error) {
-	stdLog.Request(tag, request, err)
+	stdLog.request(tag, request, err)
 }
 
 func Response(tag string, response interface{}, returnCode int, returnStr string, err error) {
-	stdLog.Response(tag, response, returnCode, returnStr, err)
+	stdLog.response(tag, response, returnCode, returnStr, err)
 }
 
 // Logf logs to the local log.
 func Logf(format string, args ...interface{}) {
-	stdLog.Logf(format, args...)
+	stdLog.logAt(0, zapcore.InfoLevel, LevelAlert, format, args...)
 }
 
 // Printf logs to the local log and send the log through the channel.
 func Printf(format string, args ...interface{}) {
-	stdLog.Printf(format, args...)
+	stdLog.logAt(0, zapcore.InfoLevel, LevelInfo, format, args...)
 }
 
 func Debugf(format string, args ...interface{}) {
-	stdLog.Debugf(format, args...)
+	stdLog.logAt(0, zapcore.DebugLevel, LevelDebug, format, args...)
 }
 
 func Errorf(format string, args ...interface{}) {
-	stdLog.Errorf(format, args...)
+	stdLog.logAt(0, zapcore.ErrorLevel, LevelAlert, format, args...)
 }
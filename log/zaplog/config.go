@@ -0,0 +1,95 @@
+package zaplog
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// Encodings supported by the logger.
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+)
+
+const (
+	defaultLevel        = "info"
+	defaultMaxSizeInMB  = 5
+	defaultMaxBackups   = 8
+	defaultMaxAgeInDays = 0 // keep backups regardless of age
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported log encoding")
+
+// Config is the logger configuration of a single component.
+type Config struct {
+	// Component is attached to every entry as the "component" field, if set.
+	Component string
+	// Level is the minimum enabled level, info by default.
+	Level string
+	// Encoding is either json or console, json by default.
+	Encoding string
+	// Filepath of the log file. If empty, file logging is disabled.
+	Filepath string
+	// MaxSizeInMB is the size at which the log file is rotated.
+	MaxSizeInMB int
+	// MaxBackups is the number of rotated files kept.
+	MaxBackups int
+	// MaxAgeInDays is the number of days rotated files are kept, 0 keeps them regardless of age.
+	MaxAgeInDays int
+	// Compress rotated files with gzip.
+	Compress bool
+	// Stdout tees log entries to stdout in addition to the log file.
+	Stdout bool
+	// Levels, if set, filters entries by the runtime level of their component instead of Level.
+	Levels *Levels
+}
+
+// DefaultConfig returns the default configuration for a component logging to LogPath/<component>.log.
+func DefaultConfig(component string) *Config {
+	return &Config{
+		Component:    component,
+		Level:        defaultLevel,
+		Encoding:     EncodingJSON,
+		Filepath:     filepath.Join(LogPath, component+".log"),
+		MaxSizeInMB:  defaultMaxSizeInMB,
+		MaxBackups:   defaultMaxBackups,
+		MaxAgeInDays: defaultMaxAgeInDays,
+	}
+}
+
+func (c *Config) level() (zapcore.Level, error) {
+	if c.Level == "" {
+		return zapcore.InfoLevel, nil
+	}
+	lvl, err := zapcore.ParseLevel(c.Level)
+	if err != nil {
+		return lvl, errors.Wrapf(err, "failed to parse log level")
+	}
+	return lvl, nil
+}
+
+func (c *Config) encoder() (zapcore.Encoder, error) {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+	switch c.Encoding {
+	case "", EncodingJSON:
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	case EncodingConsole:
+		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedEncoding, "%q", c.Encoding)
+	}
+}
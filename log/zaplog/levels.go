@@ -0,0 +1,252 @@
+package zaplog
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// EnvLogLevels configures component levels as a comma separated list of component=level pairs,
+	// e.g. "net=debug,ipam=warn". The "default" component sets the level of all other components.
+	EnvLogLevels = "ACN_LOG_LEVELS"
+	// EnvLogLevelsFile is the path of a JSON file mapping components to levels, in the same format
+	// as the EnvLogLevels pairs, e.g. {"default": "info", "telemetry": "error"}.
+	EnvLogLevelsFile = "ACN_LOG_LEVELS_FILE"
+
+	// DefaultComponent is the key which sets the level of components without their own level.
+	DefaultComponent = "default"
+
+	componentKey = "component"
+)
+
+var ErrInvalidLevelSpec = errors.New("invalid log level spec")
+
+// Levels holds the runtime log levels of components. Loggers built from a core wrapped by Levels.WrapCore
+// filter entries by the level of the "component" field they were created With, so the level of one
+// noisy component can be changed without affecting the others.
+//
+// A component is matched by its full name, then by its name with trailing "-" separated parts removed,
+// then by any of its parts: "cni-ipam" is matched by "cni-ipam", "cni" or "ipam", in that order.
+type Levels struct {
+	set atomic.Pointer[levelSet]
+}
+
+type levelSet struct {
+	def        zapcore.Level
+	components map[string]zapcore.Level
+}
+
+// NewLevels returns Levels in which all components log at def.
+func NewLevels(def zapcore.Level) *Levels {
+	l := &Levels{}
+	l.set.Store(&levelSet{def: def, components: map[string]zapcore.Level{}})
+	return l
+}
+
+// Level returns the level in effect for the component.
+func (l *Levels) Level(component string) zapcore.Level {
+	return l.set.Load().level(component)
+}
+
+// SetLevel sets the level of a component, or the default level if component is DefaultComponent.
+func (l *Levels) SetLevel(component string, level zapcore.Level) {
+	l.update(map[string]zapcore.Level{component: level})
+}
+
+// Apply parses and sets the levels of spec, which maps components to level names. Nothing is changed
+// if any of the levels is invalid.
+func (l *Levels) Apply(spec map[string]string) error {
+	parsed := make(map[string]zapcore.Level, len(spec))
+	for component, level := range spec {
+		lvl, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return errors.Wrapf(ErrInvalidLevelSpec, "component %s: %v", component, err)
+		}
+		parsed[component] = lvl
+	}
+	l.update(parsed)
+	return nil
+}
+
+// Snapshot returns the configured levels, including the default level under DefaultComponent.
+func (l *Levels) Snapshot() map[string]string {
+	set := l.set.Load()
+	out := make(map[string]string, len(set.components)+1)
+	out[DefaultComponent] = set.def.String()
+	for component, level := range set.components {
+		out[component] = level.String()
+	}
+	return out
+}
+
+// LoadFile applies the levels in the JSON file at path.
+func (l *Levels) LoadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read log levels file %s", path)
+	}
+	var spec map[string]string
+	if err := json.Unmarshal(b, &spec); err != nil {
+		return errors.Wrapf(ErrInvalidLevelSpec, "file %s: %v", path, err)
+	}
+	return l.Apply(spec)
+}
+
+// LoadEnv applies the levels file named by EnvLogLevelsFile and then the levels in EnvLogLevels,
+// so the environment variable takes precedence. Unset variables are ignored.
+func (l *Levels) LoadEnv() error {
+	if path := os.Getenv(EnvLogLevelsFile); path != "" {
+		if err := l.LoadFile(path); err != nil {
+			return err
+		}
+	}
+	if s := os.Getenv(EnvLogLevels); s != "" {
+		spec, err := ParseLevelSpec(s)
+		if err != nil {
+			return err
+		}
+		return l.Apply(spec)
+	}
+	return nil
+}
+
+// ParseLevelSpec parses a comma separated list of component=level pairs.
+func ParseLevelSpec(s string) (map[string]string, error) {
+	spec := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		component, level, ok := strings.Cut(pair, "=")
+		if !ok || component == "" {
+			return nil, errors.Wrapf(ErrInvalidLevelSpec, "%q", pair)
+		}
+		spec[strings.TrimSpace(component)] = strings.TrimSpace(level)
+	}
+	return spec, nil
+}
+
+// ServeHTTP reports the levels on GET and applies the JSON encoded levels in the request body on PUT.
+func (l *Levels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var spec map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := l.Apply(spec); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(l.Snapshot())
+}
+
+// WrapCore returns a core which filters entries by the level of their component. The wrapped core
+// should be enabled at the lowest level any component may be set to.
+func (l *Levels) WrapCore(core zapcore.Core) zapcore.Core {
+	return &componentCore{Core: core, levels: l}
+}
+
+// Option returns a zap.Option which wraps the logger's core with WrapCore.
+func (l *Levels) Option() zap.Option {
+	return zap.WrapCore(l.WrapCore)
+}
+
+func (l *Levels) update(levels map[string]zapcore.Level) {
+	for {
+		old := l.set.Load()
+		next := &levelSet{def: old.def, components: make(map[string]zapcore.Level, len(old.components)+len(levels))}
+		for component, level := range old.components {
+			next.components[component] = level
+		}
+		for component, level := range levels {
+			if component == DefaultComponent {
+				next.def = level
+				continue
+			}
+			next.components[component] = level
+		}
+		if l.set.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (s *levelSet) level(component string) zapcore.Level {
+	if component == "" || len(s.components) == 0 {
+		return s.def
+	}
+	if level, ok := s.components[component]; ok {
+		return level
+	}
+
+	for prefix := component; ; {
+		i := strings.LastIndexByte(prefix, '-')
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+		if level, ok := s.components[prefix]; ok {
+			return level
+		}
+	}
+
+	// match on parts in a stable order so the result does not depend on map iteration
+	parts := strings.Split(component, "-")
+	sort.Strings(parts)
+	for _, part := range parts {
+		if level, ok := s.components[part]; ok {
+			return level
+		}
+	}
+
+	return s.def
+}
+
+// componentCore enables entries by the level of the component it was created With.
+type componentCore struct {
+	zapcore.Core
+	levels    *Levels
+	component string
+}
+
+func (c *componentCore) Enabled(level zapcore.Level) bool {
+	return c.levels.Level(c.component).Enabled(level)
+}
+
+func (c *componentCore) With(fields []zapcore.Field) zapcore.Core {
+	component := c.component
+	for i := range fields {
+		if fields[i].Key == componentKey && fields[i].Type == zapcore.StringType {
+			component = fields[i].String
+		}
+	}
+	return &componentCore{Core: c.Core.With(fields), levels: c.levels, component: component}
+}
+
+// Check filters by the component level and leaves the rest of the decision to the wrapped core, so that
+// cores which filter or sample in their own Check still apply.
+func (c *componentCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	return ce
+}
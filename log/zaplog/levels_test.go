@@ -0,0 +1,149 @@
+package zaplog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevelsComponentMatching(t *testing.T) {
+	levels := NewLevels(zapcore.InfoLevel)
+	require.NoError(t, levels.Apply(map[string]string{
+		"net":      "debug",
+		"net-hns":  "error",
+		"ipam":     "warn",
+		"cni-main": "debug",
+	}))
+
+	tests := []struct {
+		component string
+		want      zapcore.Level
+	}{
+		{component: "net", want: zapcore.DebugLevel},
+		{component: "net-hns", want: zapcore.ErrorLevel},
+		{component: "net-policy", want: zapcore.DebugLevel},
+		{component: "cni-ipam", want: zapcore.WarnLevel},
+		{component: "ipam-pool-monitor", want: zapcore.WarnLevel},
+		{component: "cni-main", want: zapcore.DebugLevel},
+		{component: "telemetry", want: zapcore.InfoLevel},
+		{component: "", want: zapcore.InfoLevel},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, levels.Level(tt.component), tt.component)
+	}
+}
+
+func TestLevelsApplyInvalid(t *testing.T) {
+	levels := NewLevels(zapcore.InfoLevel)
+	require.ErrorIs(t, levels.Apply(map[string]string{"net": "debug", "ipam": "loud"}), ErrInvalidLevelSpec)
+	assert.Equal(t, zapcore.InfoLevel, levels.Level("net"), "no level is applied from an invalid spec")
+}
+
+func TestWrapCoreFiltersByComponent(t *testing.T) {
+	levels := NewLevels(zapcore.InfoLevel)
+	levels.SetLevel("telemetry", zapcore.ErrorLevel)
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(levels.WrapCore(core))
+
+	net := logger.With(zap.String("component", "net"))
+	telemetry := logger.With(zap.String("component", "telemetry"))
+
+	net.Debug("net debug")
+	net.Info("net info")
+	telemetry.Warn("telemetry warn")
+	telemetry.Error("telemetry error")
+
+	// levels apply to existing loggers as soon as they are changed
+	levels.SetLevel("net", zapcore.DebugLevel)
+	net.Debug("net debug after")
+
+	var msgs []string
+	for _, e := range logs.All() {
+		msgs = append(msgs, e.Message)
+	}
+	assert.Equal(t, []string{"net info", "telemetry error", "net debug after"}, msgs)
+}
+
+func TestWrapCoreDelegatesCheck(t *testing.T) {
+	levels := NewLevels(zapcore.DebugLevel)
+	core, logs := observer.New(zapcore.WarnLevel)
+	logger := zap.New(levels.WrapCore(core)).With(zap.String("component", "net"))
+
+	logger.Info("below the wrapped core level")
+	logger.Warn("at the wrapped core level")
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, "at the wrapped core level", logs.All()[0].Message)
+}
+
+func TestLevelsLoadEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "levels.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"default": "warn", "net": "error", "ipam": "debug"}`), 0o600))
+	t.Setenv(EnvLogLevelsFile, path)
+	t.Setenv(EnvLogLevels, "net=debug, telemetry=error")
+
+	levels := NewLevels(zapcore.InfoLevel)
+	require.NoError(t, levels.LoadEnv())
+
+	assert.Equal(t, map[string]string{
+		DefaultComponent: "warn",
+		"net":            "debug",
+		"ipam":           "debug",
+		"telemetry":      "error",
+	}, levels.Snapshot())
+}
+
+func TestParseLevelSpecInvalid(t *testing.T) {
+	_, err := ParseLevelSpec("net=debug,ipam")
+	require.ErrorIs(t, err, ErrInvalidLevelSpec)
+}
+
+func TestLevelsServeHTTP(t *testing.T) {
+	levels := NewLevels(zapcore.InfoLevel)
+
+	rec := httptest.NewRecorder()
+	levels.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/debug/loglevels", strings.NewReader(`{"platform": "debug"}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, zapcore.DebugLevel, levels.Level("platform"))
+
+	rec = httptest.NewRecorder()
+	levels.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/loglevels", http.NoBody))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"default": "info", "platform": "debug"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	levels.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/debug/loglevels", strings.NewReader(`{"platform": "loud"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	levels.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/debug/loglevels", http.NoBody))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestNewWithLevels(t *testing.T) {
+	levels := NewLevels(zapcore.WarnLevel)
+	cfg := DefaultConfig("ipam")
+	cfg.Filepath = filepath.Join(t.TempDir(), "test.log")
+	cfg.Levels = levels
+
+	logger, cleanup, err := New(cfg)
+	require.NoError(t, err)
+	logger.Info("filtered")
+	levels.SetLevel("ipam", zapcore.DebugLevel)
+	logger.Debug("hello")
+	cleanup()
+
+	b, err := os.ReadFile(cfg.Filepath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "filtered")
+	assert.Contains(t, string(b), "hello")
+}
@@ -0,0 +1,74 @@
+// Package zaplog builds the zap loggers shared by ACN components: leveled, structured,
+// JSON or console encoded, with size and age based rotation of the log file.
+package zaplog
+
+import (
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// New creates and returns a zap logger and a clean up function which flushes it.
+func New(cfg *Config) (*zap.Logger, func(), error) {
+	core, err := NewCore(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.Levels != nil {
+		core = cfg.Levels.WrapCore(core)
+	}
+
+	logger := zap.New(core)
+	if cfg.Component != "" {
+		logger = logger.With(zap.String("component", cfg.Component))
+	}
+
+	cleanup := func() {
+		_ = logger.Sync()
+	}
+	return logger, cleanup, nil
+}
+
+// NewCore creates the zapcore.Core described by cfg, so callers can tee it with other cores.
+func NewCore(cfg *Config) (zapcore.Core, error) {
+	level, err := cfg.level()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Levels != nil {
+		// filtering is left to the component levels
+		level = zapcore.DebugLevel
+	}
+
+	encoder, err := cfg.encoder()
+	if err != nil {
+		return nil, err
+	}
+
+	var syncers []zapcore.WriteSyncer
+	if cfg.Filepath != "" {
+		syncers = append(syncers, zapcore.AddSync(newRotatingWriter(cfg)))
+	}
+	if cfg.Stdout {
+		syncers = append(syncers, zapcore.Lock(os.Stdout))
+	}
+	if len(syncers) == 0 {
+		return zapcore.NewNopCore(), nil
+	}
+
+	return zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(syncers...), level), nil
+}
+
+// newRotatingWriter returns a lumberjack file writer which rotates by size and prunes by count and age.
+func newRotatingWriter(cfg *Config) *lumberjack.Logger {
+	return &lumberjack.Logger{
+		Filename:   cfg.Filepath,
+		MaxSize:    cfg.MaxSizeInMB, // MegaBytes
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAgeInDays,
+		Compress:   cfg.Compress,
+	}
+}
@@ -0,0 +1,55 @@
+package zaplog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWritesJSONToFile(t *testing.T) {
+	cfg := DefaultConfig("test")
+	cfg.Filepath = filepath.Join(t.TempDir(), "test.log")
+
+	logger, cleanup, err := New(cfg)
+	require.NoError(t, err)
+	logger.Debug("filtered")
+	logger.Info("hello")
+	cleanup()
+
+	b, err := os.ReadFile(cfg.Filepath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 1)
+
+	entry := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "hello", entry["msg"])
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "test", entry["component"])
+}
+
+func TestNewConsoleEncoding(t *testing.T) {
+	cfg := &Config{Level: "debug", Encoding: EncodingConsole, Filepath: filepath.Join(t.TempDir(), "test.log")}
+
+	logger, cleanup, err := New(cfg)
+	require.NoError(t, err)
+	logger.Debug("hello")
+	cleanup()
+
+	b, err := os.ReadFile(cfg.Filepath)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "debug\thello")
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	_, _, err := New(&Config{Level: "loud"})
+	require.Error(t, err)
+
+	_, _, err = New(&Config{Encoding: "xml"})
+	require.ErrorIs(t, err, ErrUnsupportedEncoding)
+}
@@ -0,0 +1,4 @@
+package zaplog
+
+// LogPath is the default directory log files are written to.
+const LogPath = ""
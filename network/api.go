@@ -20,6 +20,8 @@ var (
 	errMultipleEndpointsFound = fmt.Errorf("Multiple endpoints found")
 	errEndpointInUse          = fmt.Errorf("Endpoint is already joined to a sandbox")
 	errEndpointNotInUse       = fmt.Errorf("Endpoint is not joined to a sandbox")
+	errInterfaceNotFound      = fmt.Errorf("Interface not found")
+	errHotPlugUnsupported     = fmt.Errorf("Interfaces can't be hot-plugged into this endpoint")
 )
 
 type networkNotFoundError struct{}
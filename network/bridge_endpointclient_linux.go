@@ -27,6 +27,7 @@ type LinuxBridgeEndpointClient struct {
 	containerMac      net.HardwareAddr
 	hostIPAddresses   []*net.IPNet
 	mode              string
+	ipv6Mode          string
 	netlink           netlink.NetlinkInterface
 	plClient          platform.ExecClient
 	netioshim         netio.NetIOInterface
@@ -38,6 +39,7 @@ func NewLinuxBridgeEndpointClient(
 	hostVethName string,
 	containerVethName string,
 	mode string,
+	ipv6Mode string,
 	nl netlink.NetlinkInterface,
 	plc platform.ExecClient,
 ) *LinuxBridgeEndpointClient {
@@ -49,6 +51,7 @@ func NewLinuxBridgeEndpointClient(
 		hostPrimaryMac:    extIf.MacAddress,
 		hostIPAddresses:   []*net.IPNet{},
 		mode:              mode,
+		ipv6Mode:          ipv6Mode,
 		netlink:           nl,
 		plClient:          plc,
 		netioshim:         &netio.NetIO{},
@@ -96,6 +99,14 @@ func (client *LinuxBridgeEndpointClient) AddEndpointRules(epInfo *EndpointInfo)
 			return err
 		}
 
+		if client.ipv6Mode == IPV6Only && ipAddr.IP.To4() == nil {
+			// Add NDP proxy entry so the bridge answers neighbor solicitations for the pod IP.
+			logger.Info("Adding NDP proxy for IP address", zap.String("address", ipAddr.String()), zap.String("bridgeName", client.bridgeName))
+			if err := client.nuc.AddOrDeleteNDPProxy(client.bridgeName, ipAddr.IP, netlink.ADD); err != nil {
+				return err
+			}
+		}
+
 		if client.mode != opModeTunnel && ipAddr.IP.To4() != nil {
 			logger.Info("Adding static arp for IP address and MAC in VM", zap.String("address", ipAddr.String()), zap.String("MAC", client.containerMac.String()))
 			linkInfo := netlink.LinkInfo{
@@ -140,6 +151,13 @@ func (client *LinuxBridgeEndpointClient) DeleteEndpointRules(ep *endpoint) {
 			logger.Error("Failed to delete MAC DNAT rule for IP address", zap.String("address", ipAddr.String()), zap.Error(err))
 		}
 
+		if client.ipv6Mode == IPV6Only && ipAddr.IP.To4() == nil {
+			logger.Info("Deleting NDP proxy for IP address", zap.String("address", ipAddr.String()), zap.String("id", ep.Id))
+			if err := client.nuc.AddOrDeleteNDPProxy(client.bridgeName, ipAddr.IP, netlink.REMOVE); err != nil {
+				logger.Error("Failed to delete NDP proxy for IP address", zap.String("address", ipAddr.String()), zap.Error(err))
+			}
+		}
+
 		if client.mode != opModeTunnel && ipAddr.IP.To4() != nil {
 			logger.Info("Removing static arp for IP address and MAC from VM", zap.String("address", ipAddr.String()), zap.String("MAC", ep.MacAddress.String()))
 			linkInfo := netlink.LinkInfo{
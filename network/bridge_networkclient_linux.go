@@ -16,7 +16,10 @@ const (
 	multicastSolicitPrefix = "ff02::1:ff00:0/104"
 )
 
-var errorLinuxBridgeClient = errors.New("LinuxBridgeClient Error")
+var (
+	errorLinuxBridgeClient = errors.New("LinuxBridgeClient Error")
+	errIPV6OnlySubnets     = errors.New("ipv6only mode requires only IPv6 subnets")
+)
 
 func newErrorLinuxBridgeClient(errStr string) error {
 	return fmt.Errorf("%w : %s", errorLinuxBridgeClient, errStr)
@@ -86,6 +89,12 @@ func (client *LinuxBridgeClient) DeleteBridge() error {
 }
 
 func (client *LinuxBridgeClient) AddL2Rules(extIf *externalInterface) error {
+	if client.nwInfo.IPV6Mode == IPV6Only {
+		if err := validateIPV6OnlySubnets(client.nwInfo.Subnets); err != nil {
+			return err
+		}
+	}
+
 	hostIf, err := net.InterfaceByName(client.hostInterfaceName)
 	if err != nil {
 		return err
@@ -97,24 +106,26 @@ func (client *LinuxBridgeClient) AddL2Rules(extIf *externalInterface) error {
 		return err
 	}
 
-	// Add ARP reply rule for host primary IP address.
-	// ARP requests for all IP addresses are forwarded to the SDN fabric, but fabric
-	// doesn't respond to ARP requests from the VM for its own primary IP address.
-	primary := extIf.IPAddresses[0].IP
-	logger.Info("Adding ARP reply rule for primary IP address", zap.Any("address", primary))
-	if err := ebtables.SetArpReply(primary, hostIf.HardwareAddr, ebtables.Append); err != nil {
-		return err
-	}
+	if client.nwInfo.IPV6Mode != IPV6Only {
+		// Add ARP reply rule for host primary IP address.
+		// ARP requests for all IP addresses are forwarded to the SDN fabric, but fabric
+		// doesn't respond to ARP requests from the VM for its own primary IP address.
+		primary := extIf.IPAddresses[0].IP
+		logger.Info("Adding ARP reply rule for primary IP address", zap.Any("address", primary))
+		if err := ebtables.SetArpReply(primary, hostIf.HardwareAddr, ebtables.Append); err != nil {
+			return err
+		}
 
-	// Add DNAT rule to forward ARP replies to container interfaces.
-	logger.Info("Adding DNAT rule for ingress ARP traffic on interface", zap.String("hostInterfaceName", client.hostInterfaceName))
-	if err := ebtables.SetDnatForArpReplies(client.hostInterfaceName, ebtables.Append); err != nil {
-		return err
+		// Add DNAT rule to forward ARP replies to container interfaces.
+		logger.Info("Adding DNAT rule for ingress ARP traffic on interface", zap.String("hostInterfaceName", client.hostInterfaceName))
+		if err := ebtables.SetDnatForArpReplies(client.hostInterfaceName, ebtables.Append); err != nil {
+			return err
+		}
 	}
 
 	if client.nwInfo.IPV6Mode != "" {
 		// for ipv6 node cidr set broute accept
-		if err := ebtables.SetBrouteAcceptByCidr(&client.nwInfo.Subnets[1].Prefix, ebtables.IPV6, ebtables.Append, ebtables.Accept); err != nil {
+		if err := ebtables.SetBrouteAcceptByCidr(client.ipv6SubnetPrefix(), ebtables.IPV6, ebtables.Append, ebtables.Accept); err != nil {
 			return err
 		}
 
@@ -136,6 +147,15 @@ func (client *LinuxBridgeClient) AddL2Rules(extIf *externalInterface) error {
 		}
 	}
 
+	if client.nwInfo.IPV6Mode == IPV6Only {
+		// Neighbor solicitations from the fabric for pod IPs are answered by the bridge
+		// from the NDP proxy entries the endpoint client adds for each pod.
+		logger.Info("Enabling proxy NDP for", zap.String("bridgeName", client.bridgeName))
+		if err := client.nuClient.EnableProxyNDPForInterface(client.bridgeName); err != nil {
+			return err
+		}
+	}
+
 	// Enable VEPA for host policy enforcement if necessary.
 	if client.nwInfo.Mode == opModeTunnel {
 		logger.Info("Enabling VEPA mode for", zap.String("hostInterfaceName", client.hostInterfaceName))
@@ -149,13 +169,22 @@ func (client *LinuxBridgeClient) AddL2Rules(extIf *externalInterface) error {
 
 func (client *LinuxBridgeClient) DeleteL2Rules(extIf *externalInterface) {
 	ebtables.SetVepaMode(client.bridgeName, commonInterfacePrefix, virtualMacAddress, ebtables.Delete)
-	ebtables.SetDnatForArpReplies(extIf.Name, ebtables.Delete)
-	ebtables.SetArpReply(extIf.IPAddresses[0].IP, extIf.MacAddress, ebtables.Delete)
+	if client.nwInfo.IPV6Mode != IPV6Only {
+		ebtables.SetDnatForArpReplies(extIf.Name, ebtables.Delete)
+		ebtables.SetArpReply(extIf.IPAddresses[0].IP, extIf.MacAddress, ebtables.Delete)
+	}
 	ebtables.SetSnatForInterface(extIf.Name, extIf.MacAddress, ebtables.Delete)
-	if client.nwInfo.IPV6Mode != "" {
-		if len(extIf.IPAddresses) > 1 {
-			ebtables.SetBrouteAcceptByCidr(extIf.IPAddresses[1], ebtables.IPV6, ebtables.Delete, ebtables.Accept)
+	if client.nwInfo.IPV6Mode == IPV6Only {
+		for _, ipAddr := range extIf.IPAddresses {
+			if ipAddr.IP.To4() == nil {
+				ebtables.SetBrouteAcceptByCidr(ipAddr, ebtables.IPV6, ebtables.Delete, ebtables.Accept)
+				break
+			}
 		}
+	} else if client.nwInfo.IPV6Mode != "" && len(extIf.IPAddresses) > 1 {
+		ebtables.SetBrouteAcceptByCidr(extIf.IPAddresses[1], ebtables.IPV6, ebtables.Delete, ebtables.Accept)
+	}
+	if client.nwInfo.IPV6Mode != "" {
 		_, mIpNet, _ := net.ParseCIDR(multicastSolicitPrefix)
 		ebtables.SetBrouteAcceptByCidr(mIpNet, ebtables.IPV6, ebtables.Delete, ebtables.Accept)
 		client.setBrouteRedirect(ebtables.Delete)
@@ -163,6 +192,29 @@ func (client *LinuxBridgeClient) DeleteL2Rules(extIf *externalInterface) {
 	}
 }
 
+// ipv6SubnetPrefix returns the prefix of the IPv6 subnet, which follows the IPv4 subnet in dual stack.
+func (client *LinuxBridgeClient) ipv6SubnetPrefix() *net.IPNet {
+	if client.nwInfo.IPV6Mode == IPV6Only {
+		return &client.nwInfo.Subnets[0].Prefix
+	}
+	return &client.nwInfo.Subnets[1].Prefix
+}
+
+// validateIPV6OnlySubnets checks there's at least one subnet and all subnets are IPv6.
+func validateIPV6OnlySubnets(subnets []SubnetInfo) error {
+	if len(subnets) == 0 {
+		return fmt.Errorf("%w: no subnets", errIPV6OnlySubnets)
+	}
+
+	for _, subnet := range subnets {
+		if subnet.Prefix.IP.To4() != nil || subnet.Prefix.IP.To16() == nil {
+			return fmt.Errorf("%w: subnet %s isn't IPv6", errIPV6OnlySubnets, subnet.Prefix.String())
+		}
+	}
+
+	return nil
+}
+
 func (client *LinuxBridgeClient) SetBridgeMasterToHostInterface() error {
 	err := client.netlink.SetLinkMaster(client.hostInterfaceName, client.bridgeName)
 	if err != nil {
@@ -0,0 +1,68 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/stretchr/testify/require"
+)
+
+func parseSubnet(t *testing.T, cidr string) SubnetInfo {
+	t.Helper()
+	_, prefix, err := net.ParseCIDR(cidr)
+	require.NoError(t, err)
+	return SubnetInfo{Prefix: *prefix}
+}
+
+func TestValidateIPV6OnlySubnets(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnets []SubnetInfo
+		wantErr bool
+	}{
+		{
+			name:    "ipv6 subnet",
+			subnets: []SubnetInfo{parseSubnet(t, "fd00::/64")},
+		},
+		{
+			name:    "no subnets",
+			wantErr: true,
+		},
+		{
+			name:    "dual stack subnets",
+			subnets: []SubnetInfo{parseSubnet(t, "10.0.0.0/16"), parseSubnet(t, "fd00::/64")},
+			wantErr: true,
+		},
+		{
+			name:    "empty prefix",
+			subnets: []SubnetInfo{{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIPV6OnlySubnets(tt.subnets)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errIPV6OnlySubnets)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAddL2RulesIPV6OnlyRejectsIPv4Subnets(t *testing.T) {
+	nwInfo := NetworkInfo{
+		IPV6Mode: IPV6Only,
+		Subnets:  []SubnetInfo{parseSubnet(t, "10.0.0.0/16")},
+	}
+	client := NewLinuxBridgeClient(bridgeName, hostIntf, nwInfo, netlink.NewMockNetlink(false, ""), platform.NewMockExecClient(false))
+	require.ErrorIs(t, client.AddL2Rules(&externalInterface{Name: hostIntf}), errIPV6OnlySubnets)
+}
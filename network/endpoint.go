@@ -15,6 +15,7 @@ import (
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/policy"
 	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
 
@@ -308,6 +309,40 @@ func (ep *endpoint) detach() error {
 	return nil
 }
 
+// attachInterfaces hot-plugs secondary interfaces into the network namespace of an existing endpoint.
+func (nw *network) attachInterfaces(nl netlink.NetlinkInterface, plc platform.ExecClient, nioc netio.NetIOInterface, nsc NamespaceClientInterface,
+	endpointID string, epInfos []*EndpointInfo,
+) error {
+	ep, err := nw.getEndpoint(endpointID)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Attaching interfaces to endpoint", zap.String("endpointID", endpointID), zap.Int("numInterfaces", len(epInfos)))
+	// Call the platform implementation.
+	return nw.attachInterfacesImpl(nl, plc, nioc, nsc, ep, epInfos)
+}
+
+// detachInterfaces hot-unplugs secondary interfaces from the network namespace of an existing endpoint.
+func (nw *network) detachInterfaces(nl netlink.NetlinkInterface, plc platform.ExecClient, nioc netio.NetIOInterface, nsc NamespaceClientInterface,
+	endpointID string, ifNames []string,
+) error {
+	ep, err := nw.getEndpoint(endpointID)
+	if err != nil {
+		return err
+	}
+
+	for _, ifName := range ifNames {
+		if _, ok := ep.SecondaryInterfaces[ifName]; !ok {
+			return errors.Wrap(errInterfaceNotFound, ifName)
+		}
+	}
+
+	logger.Info("Detaching interfaces from endpoint", zap.String("endpointID", endpointID), zap.Strings("ifNames", ifNames))
+	// Call the platform implementation.
+	return nw.detachInterfacesImpl(nl, plc, nioc, nsc, ep, ifNames)
+}
+
 // updateEndpoint updates an existing endpoint in the network.
 func (nm *networkManager) updateEndpoint(nw *network, existingEpInfo, targetEpInfo *EndpointInfo) error {
 	var err error
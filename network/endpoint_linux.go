@@ -16,6 +16,7 @@ import (
 	"github.com/Azure/azure-container-networking/network/networkutils"
 	"github.com/Azure/azure-container-networking/ovsctl"
 	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
 
@@ -98,6 +99,15 @@ func (nw *network) newEndpointImpl(
 		contIfName = fmt.Sprintf("%s%s-2", hostVEthInterfacePrefix, defaultEpInfo.Id[:7])
 	}
 
+	if nw.Mode == opModeSRIOV {
+		// the VF is moved to the container as is, and only renamed there
+		hostIfName, err = findAvailableVF(nw.extIf.Name)
+		if err != nil {
+			return nil, err
+		}
+		contIfName = hostIfName
+	}
+
 	ep := &endpoint{
 		Id:                       defaultEpInfo.Id,
 		IfName:                   contIfName, // container veth pair name. In cnm, we won't rename this and docker expects veth name.
@@ -154,9 +164,12 @@ func (nw *network) newEndpointImpl(
 						plc,
 						iptc)
 				}
+			} else if nw.Mode == opModeSRIOV {
+				logger.Info("SR-IOV client")
+				epClient = NewSRIOVEndpointClient(hostIfName, nl, netioCli, plc, nsc)
 			} else if nw.Mode != opModeTransparent {
 				logger.Info("Bridge client")
-				epClient = NewLinuxBridgeEndpointClient(nw.extIf, hostIfName, contIfName, nw.Mode, nl, plc)
+				epClient = NewLinuxBridgeEndpointClient(nw.extIf, hostIfName, contIfName, nw.Mode, nw.IPV6Mode, nl, plc)
 			} else if epInfo.NICType == cns.DelegatedVMNIC {
 				logger.Info("Secondary client")
 				epClient = NewSecondaryEndpointClient(nl, netioCli, plc, nsc, ep)
@@ -274,8 +287,10 @@ func (nw *network) deleteEndpointImpl(nl netlink.NetlinkInterface, plc platform.
 			} else {
 				epClient = NewOVSEndpointClient(nw, epInfo, ep.HostIfName, "", ep.VlanID, ep.LocalIP, nl, ovsctl.NewOvsctl(), plc, iptc)
 			}
+		} else if nw.Mode == opModeSRIOV {
+			epClient = NewSRIOVEndpointClient(ep.HostIfName, nl, nioc, plc, nsc)
 		} else if nw.Mode != opModeTransparent {
-			epClient = NewLinuxBridgeEndpointClient(nw.extIf, ep.HostIfName, "", nw.Mode, nl, plc)
+			epClient = NewLinuxBridgeEndpointClient(nw.extIf, ep.HostIfName, "", nw.Mode, nw.IPV6Mode, nl, plc)
 		} else {
 			if len(ep.SecondaryInterfaces) > 0 {
 				epClient = NewSecondaryEndpointClient(nl, nioc, plc, nsc, ep)
@@ -297,6 +312,113 @@ func (nw *network) deleteEndpointImpl(nl netlink.NetlinkInterface, plc platform.
 	return nil
 }
 
+// attachInterfacesImpl moves delegated NICs into the network namespace of the endpoint and configures them.
+// The interfaces attached by a failed call are moved back to the host.
+func (nw *network) attachInterfacesImpl(nl netlink.NetlinkInterface, plc platform.ExecClient, nioc netio.NetIOInterface, nsc NamespaceClientInterface,
+	ep *endpoint, epInfos []*EndpointInfo,
+) error {
+	// delegated NICs are only added to the endpoints of transparent networks
+	if nw.Mode != opModeTransparent {
+		return errHotPlugUnsupported
+	}
+	if ep.NetworkNameSpace == "" {
+		return errNamespaceNotFound
+	}
+	for _, epInfo := range epInfos {
+		if epInfo.NICType != cns.DelegatedVMNIC {
+			return errors.Wrapf(errHotPlugUnsupported, "NIC type %s", epInfo.NICType)
+		}
+	}
+
+	if ep.SecondaryInterfaces == nil {
+		ep.SecondaryInterfaces = make(map[string]*InterfaceInfo)
+	}
+	client := NewSecondaryEndpointClient(nl, nioc, plc, nsc, ep)
+
+	existing := make(map[string]struct{}, len(ep.SecondaryInterfaces))
+	for ifName := range ep.SecondaryInterfaces {
+		existing[ifName] = struct{}{}
+	}
+	attached := make([]string, 0, len(epInfos))
+	for _, epInfo := range epInfos {
+		epInfo.NetNsPath = ep.NetworkNameSpace
+		err := attachSecondaryInterface(client, nsc, epInfo)
+		if _, ok := existing[epInfo.IfName]; !ok {
+			if _, ok := ep.SecondaryInterfaces[epInfo.IfName]; ok {
+				attached = append(attached, epInfo.IfName)
+			}
+		}
+		if err != nil {
+			logger.Error("Failed to attach interface, detaching the interfaces attached so far",
+				zap.String("IfName", epInfo.IfName), zap.Strings("attached", attached), zap.Error(err))
+			if delErr := client.DeleteInterfaces(ep, attached); delErr != nil {
+				logger.Error("Failed to detach interfaces", zap.Error(delErr))
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachSecondaryInterface moves a delegated NIC into the network namespace and configures its IPs and routes.
+func attachSecondaryInterface(client *SecondaryEndpointClient, nsc NamespaceClientInterface, epInfo *EndpointInfo) error {
+	if err := client.AddEndpoints(epInfo); err != nil {
+		return err
+	}
+
+	// Open the network namespace.
+	logger.Info("Opening netns", zap.Any("NetNsPath", epInfo.NetNsPath))
+	ns, err := nsc.OpenNamespace(epInfo.NetNsPath)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+
+	if err := client.MoveEndpointsToContainerNS(epInfo, ns.GetFd()); err != nil {
+		return err
+	}
+
+	// Enter the container network namespace.
+	logger.Info("Entering netns", zap.Any("NetNsPath", epInfo.NetNsPath))
+	if err := ns.Enter(); err != nil {
+		return err
+	}
+
+	// Return to host network namespace.
+	defer func() {
+		logger.Info("Exiting netns", zap.Any("NetNsPath", epInfo.NetNsPath))
+		if err := ns.Exit(); err != nil {
+			logger.Error("Failed to exit netns with", zap.Error(err))
+		}
+	}()
+
+	if err := client.SetupContainerInterfaces(epInfo); err != nil {
+		return err
+	}
+
+	return client.ConfigureContainerInterfacesAndRoutes(epInfo)
+}
+
+// detachInterfacesImpl moves secondary interfaces of the endpoint back to the host.
+func (nw *network) detachInterfacesImpl(nl netlink.NetlinkInterface, plc platform.ExecClient, nioc netio.NetIOInterface, nsc NamespaceClientInterface,
+	ep *endpoint, ifNames []string,
+) error {
+	client := NewSecondaryEndpointClient(nl, nioc, plc, nsc, ep)
+	if err := client.DeleteInterfaces(ep, ifNames); err != nil {
+		return err
+	}
+
+	// interfaces which couldn't be moved are kept in the endpoint so the detach can be retried
+	for _, ifName := range ifNames {
+		if _, ok := ep.SecondaryInterfaces[ifName]; ok {
+			return errors.Errorf("failed to detach interface %s", ifName)
+		}
+	}
+
+	return nil
+}
+
 // getInfoImpl returns information about the endpoint.
 func (ep *endpoint) getInfoImpl(epInfo *EndpointInfo) {
 }
@@ -321,6 +321,10 @@ func (nw *network) createHostNCApipaEndpoint(cli apipaClient, epInfo *EndpointIn
 
 // newEndpointImplHnsV2 creates a new endpoint in the network using Hnsv2
 func (nw *network) newEndpointImplHnsV2(cli apipaClient, epInfo *EndpointInfo) (*endpoint, error) {
+	if nw.Mode == opModeL1VH {
+		return nw.newL1VHEndpoint(epInfo)
+	}
+
 	hcnEndpoint, err := nw.configureHcnEndpoint(epInfo)
 	if err != nil {
 		logger.Error("Failed to configure hcn endpoint due to", zap.Error(err))
@@ -497,6 +501,20 @@ func (nm *networkManager) updateEndpointImpl(nw *network, existingEpInfo *Endpoi
 	return nil, nil
 }
 
+// attachInterfacesImpl is not supported in windows, where each interface of a pod is a separate HNS endpoint.
+func (nw *network) attachInterfacesImpl(_ netlink.NetlinkInterface, _ platform.ExecClient, _ netio.NetIOInterface, _ NamespaceClientInterface,
+	_ *endpoint, _ []*EndpointInfo,
+) error {
+	return errHotPlugUnsupported
+}
+
+// detachInterfacesImpl is not supported in windows, where each interface of a pod is a separate HNS endpoint.
+func (nw *network) detachInterfacesImpl(_ netlink.NetlinkInterface, _ platform.ExecClient, _ netio.NetIOInterface, _ NamespaceClientInterface,
+	_ *endpoint, _ []string,
+) error {
+	return errHotPlugUnsupported
+}
+
 // GetEndpointInfoByIPImpl returns an endpointInfo with the corrsponding HNS Endpoint ID that matches an specific IP Address.
 func (epInfo *EndpointInfo) GetEndpointInfoByIPImpl(ipAddresses []net.IPNet, networkID string) (*EndpointInfo, error) {
 	// check if network exists, only create the network does not exist
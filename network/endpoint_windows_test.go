@@ -7,12 +7,15 @@
 package network
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"testing"
 	"time"
 
 	"github.com/Azure/azure-container-networking/network/hnswrapper"
+	"github.com/Azure/azure-container-networking/network/policy"
+	"github.com/Microsoft/hcsshim/hcn"
 )
 
 func TestNewAndDeleteEndpointImplHnsV2(t *testing.T) {
@@ -53,6 +56,67 @@ func TestNewAndDeleteEndpointImplHnsV2(t *testing.T) {
 	}
 }
 
+func TestNewAndDeleteL1VHEndpoint(t *testing.T) {
+	nw := &network{
+		Mode:      opModeL1VH,
+		Endpoints: map[string]*endpoint{},
+	}
+
+	Hnsv2 = hnswrapper.NewHnsv2wrapperFake()
+
+	mac, _ := net.ParseMAC("00:0d:3a:00:53:01")
+	epInfo := &EndpointInfo{
+		Id:          "753d3fb6-e9b3-49e2-a109-2acc5dda61f1",
+		ContainerID: "545055c2-1462-42c8-b222-e75d0b291632",
+		NetNsPath:   "fakeNameSpace",
+		IfName:      "eth0",
+		Data:        make(map[string]interface{}),
+		MacAddress:  mac,
+		Policies: []policy.Policy{
+			{Type: policy.EndpointPolicy, Data: []byte(`{"Type": "OutBoundNAT", "ExceptionList": ["10.240.0.0/16"]}`)},
+			{Type: policy.EndpointPolicy, Data: []byte(`{"Type": "ACL", "Protocols": "6", "Action": "Block", "Direction": "Out", "RemoteAddresses": "168.63.129.16/32", "RemotePorts": "80", "Priority": 200, "RuleType": "Switch"}`)},
+		},
+	}
+	hcnEndpoint, err := nw.configureL1VHEndpoint(epInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hcnEndpoint.MacAddress != mac.String() {
+		t.Fatalf("expected the MAC address of the delegated vNIC %s, got %s", mac, hcnEndpoint.MacAddress)
+	}
+	// the traffic of the delegated vNIC isn't NATed by the host, so only the ACL applies
+	if len(hcnEndpoint.Policies) != 1 || hcnEndpoint.Policies[0].Type != hcn.ACL {
+		t.Fatalf("expected only the ACL policy, got %+v", hcnEndpoint.Policies)
+	}
+
+	endpoint, err := nw.newEndpointImplHnsV2(nil, epInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = nw.deleteEndpointImplHnsV2(endpoint); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewL1VHEndpointWithoutMacAddress(t *testing.T) {
+	nw := &network{
+		Mode:      opModeL1VH,
+		Endpoints: map[string]*endpoint{},
+	}
+	Hnsv2 = hnswrapper.NewHnsv2wrapperFake()
+
+	epInfo := &EndpointInfo{
+		Id:          "753d3fb6-e9b3-49e2-a109-2acc5dda61f1",
+		ContainerID: "545055c2-1462-42c8-b222-e75d0b291632",
+		NetNsPath:   "fakeNameSpace",
+		IfName:      "eth0",
+		Data:        make(map[string]interface{}),
+	}
+	if _, err := nw.newEndpointImplHnsV2(nil, epInfo); !errors.Is(err, errL1VHMacAddressRequired) {
+		t.Fatalf("expected errL1VHMacAddressRequired, got %v", err)
+	}
+}
+
 func TestNewEndpointImplHnsv2Timesout(t *testing.T) {
 	nw := &network{
 		Endpoints: map[string]*endpoint{},
@@ -0,0 +1,178 @@
+//go:build windows
+// +build windows
+
+package hnswrapper
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// EndpointNotification is a change to the endpoints of the host which HNS notified about.
+type EndpointNotification int
+
+const (
+	// EndpointAttached means an endpoint was attached to a network compartment, e.g. for a new pod.
+	EndpointAttached EndpointNotification = iota
+	// EndpointDetached means an endpoint was detached from its network compartment, e.g. for a deleted pod.
+	EndpointDetached
+	// ServiceDisconnected means the connection to HNS was lost, e.g. when HNS restarts, so notifications may have
+	// been missed.
+	ServiceDisconnected
+)
+
+// EndpointNotifier is implemented by the HNS wrappers which can notify about changes to the endpoints of the host.
+type EndpointNotifier interface {
+	// SubscribeEndpointNotifications calls callback with each notification until unsubscribe is called.
+	// The callback runs on an HNS thread and must not block.
+	SubscribeEndpointNotifications(callback func(EndpointNotification)) (unsubscribe func(), err error)
+}
+
+var errEndpointNotificationsUnsupported = errors.New("the HNS wrapper doesn't support endpoint notifications")
+
+// HCN_NOTIFICATIONS of computenetwork.h
+const (
+	hcnNotificationNetworkEndpointAttached = 0x00000009
+	hcnNotificationNetworkEndpointDetached = 0x00000010
+	hcnNotificationServiceDisconnect       = 0x01000000
+)
+
+var (
+	modcomputenetwork                = windows.NewLazySystemDLL("computenetwork.dll")
+	procHcnRegisterServiceCallback   = modcomputenetwork.NewProc("HcnRegisterServiceCallback")
+	procHcnUnregisterServiceCallback = modcomputenetwork.NewProc("HcnUnregisterServiceCallback")
+
+	// the callbacks created by windows.NewCallback are never released, so all subscriptions share one
+	hcnNotificationCallback     uintptr
+	hcnNotificationCallbackOnce sync.Once
+
+	// hcnSubscriptions are the callbacks of the subscriptions, by the context HNS passes to hcnNotificationCallback
+	hcnSubscriptions      = map[uintptr]func(EndpointNotification){}
+	hcnSubscriptionsMutex sync.Mutex
+	nextHcnSubscription   uintptr
+)
+
+// SubscribeEndpointNotifications registers an HCN service callback, which HNS calls when endpoints are attached to
+// and detached from network compartments.
+func (Hnsv2wrapper) SubscribeEndpointNotifications(callback func(EndpointNotification)) (func(), error) {
+	if err := procHcnRegisterServiceCallback.Find(); err != nil {
+		return nil, fmt.Errorf("HCN service callbacks are unavailable: %w", err)
+	}
+	hcnNotificationCallbackOnce.Do(func() {
+		hcnNotificationCallback = windows.NewCallback(onHcnNotification)
+	})
+
+	hcnSubscriptionsMutex.Lock()
+	nextHcnSubscription++
+	id := nextHcnSubscription
+	hcnSubscriptions[id] = callback
+	hcnSubscriptionsMutex.Unlock()
+
+	var handle uintptr
+	hr, _, _ := procHcnRegisterServiceCallback.Call(hcnNotificationCallback, id, uintptr(unsafe.Pointer(&handle)))
+	if hr != 0 {
+		removeHcnSubscription(id)
+		return nil, fmt.Errorf("HcnRegisterServiceCallback failed: %w", windows.Errno(hr))
+	}
+
+	var unsubscribeOnce sync.Once
+	return func() {
+		unsubscribeOnce.Do(func() {
+			procHcnUnregisterServiceCallback.Call(handle) //nolint:errcheck // nothing to do if HNS is gone
+			removeHcnSubscription(id)
+		})
+	}, nil
+}
+
+// onHcnNotification is the HCN_NOTIFICATION_CALLBACK of all subscriptions. The notification data isn't needed since
+// the subscribers list the endpoints they care about.
+func onHcnNotification(notificationType uint32, context uintptr, _ uintptr, _ *uint16) uintptr {
+	var notification EndpointNotification
+	switch notificationType {
+	case hcnNotificationNetworkEndpointAttached:
+		notification = EndpointAttached
+	case hcnNotificationNetworkEndpointDetached:
+		notification = EndpointDetached
+	case hcnNotificationServiceDisconnect:
+		notification = ServiceDisconnected
+	default:
+		return 0
+	}
+
+	hcnSubscriptionsMutex.Lock()
+	callback, ok := hcnSubscriptions[context]
+	hcnSubscriptionsMutex.Unlock()
+	if ok {
+		callback(notification)
+	}
+	return 0
+}
+
+func removeHcnSubscription(id uintptr) {
+	hcnSubscriptionsMutex.Lock()
+	delete(hcnSubscriptions, id)
+	hcnSubscriptionsMutex.Unlock()
+}
+
+// SubscribeEndpointNotifications subscribes to the wrapped HNS, since subscribing doesn't mutate HNS.
+func (h Hnsv2wrapperRecorder) SubscribeEndpointNotifications(callback func(EndpointNotification)) (func(), error) {
+	notifier, ok := h.Hnsv2.(EndpointNotifier)
+	if !ok {
+		return nil, errEndpointNotificationsUnsupported
+	}
+	return notifier.SubscribeEndpointNotifications(callback) //nolint:wrapcheck // no need to wrap check for this wrapper
+}
+
+// SubscribeEndpointNotifications subscribes to the wrapped HNS. Registering the callback doesn't wait on HNS calls.
+func (h Hnsv2wrapperwithtimeout) SubscribeEndpointNotifications(callback func(EndpointNotification)) (func(), error) {
+	notifier, ok := h.Hnsv2.(EndpointNotifier)
+	if !ok {
+		return nil, errEndpointNotificationsUnsupported
+	}
+	return notifier.SubscribeEndpointNotifications(callback) //nolint:wrapcheck // no need to wrap check for this wrapper
+}
+
+// fakeEndpointNotifier holds the subscriptions to the endpoint notifications of the Hnsv2wrapperFake.
+// It is shared by all copies of the fake, since the fake methods have value receivers.
+type fakeEndpointNotifier struct {
+	sync.Mutex
+	nextID        int
+	subscriptions map[int]func(EndpointNotification)
+}
+
+func newFakeEndpointNotifier() *fakeEndpointNotifier {
+	return &fakeEndpointNotifier{subscriptions: map[int]func(EndpointNotification){}}
+}
+
+func (n *fakeEndpointNotifier) notify(notification EndpointNotification) {
+	n.Lock()
+	defer n.Unlock()
+	for _, callback := range n.subscriptions {
+		callback(notification)
+	}
+}
+
+// SubscribeEndpointNotifications notifies callback when endpoints are created and deleted in the fake,
+// or when a notification is sent with NotifyEndpoints.
+func (f Hnsv2wrapperFake) SubscribeEndpointNotifications(callback func(EndpointNotification)) (func(), error) {
+	n := f.notifier
+	n.Lock()
+	defer n.Unlock()
+	n.nextID++
+	id := n.nextID
+	n.subscriptions[id] = callback
+	return func() {
+		n.Lock()
+		delete(n.subscriptions, id)
+		n.Unlock()
+	}, nil
+}
+
+// NotifyEndpoints sends the notification to the subscribers of the fake, e.g. to simulate an HNS restart.
+func (f Hnsv2wrapperFake) NotifyEndpoints(notification EndpointNotification) {
+	f.notifier.notify(notification)
+}
@@ -10,7 +10,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -27,12 +29,16 @@ func newErrorFakeHNS(errStr string) error {
 type Hnsv2wrapperFake struct {
 	Cache FakeHNSCache
 	*sync.Mutex
-	Delay time.Duration
+	Delay    time.Duration
+	faults   *fakeFaults
+	notifier *fakeEndpointNotifier
 }
 
 func NewHnsv2wrapperFake() *Hnsv2wrapperFake {
 	return &Hnsv2wrapperFake{
-		Mutex: &sync.Mutex{},
+		Mutex:    &sync.Mutex{},
+		faults:   newFakeFaults(),
+		notifier: newFakeEndpointNotifier(),
 		Cache: FakeHNSCache{
 			networks:  map[string]*FakeHostComputeNetwork{},
 			endpoints: map[string]*FakeHostComputeEndpoint{},
@@ -49,21 +55,24 @@ func (f Hnsv2wrapperFake) CreateNetwork(network *hcn.HostComputeNetwork) (*hcn.H
 	f.Lock()
 	defer f.Unlock()
 
-	delayHnsCall(f.Delay)
+	if err := f.call("CreateNetwork", network); err != nil {
+		return nil, err
+	}
 	f.Cache.networks[network.Name] = NewFakeHostComputeNetwork(network)
 	return network, nil
 }
 
 func (f Hnsv2wrapperFake) DeleteNetwork(network *hcn.HostComputeNetwork) error {
-	delayHnsCall(f.Delay)
-	return nil
+	return f.call("DeleteNetwork", network)
 }
 
 func (f Hnsv2wrapperFake) ModifyNetworkSettings(network *hcn.HostComputeNetwork, request *hcn.ModifyNetworkSettingRequest) error {
 	f.Lock()
 	defer f.Unlock()
 
-	delayHnsCall(f.Delay)
+	if err := f.call("ModifyNetworkSettings", network, request); err != nil {
+		return err
+	}
 
 	networkCache, ok := f.Cache.networks[network.Name]
 	if !ok {
@@ -169,19 +178,19 @@ func (f Hnsv2wrapperFake) ModifyNetworkSettings(network *hcn.HostComputeNetwork,
 }
 
 func (f Hnsv2wrapperFake) AddNetworkPolicy(network *hcn.HostComputeNetwork, networkPolicy hcn.PolicyNetworkRequest) error {
-	delayHnsCall(f.Delay)
-	return nil
+	return f.call("AddNetworkPolicy", network, networkPolicy)
 }
 
 func (f Hnsv2wrapperFake) RemoveNetworkPolicy(network *hcn.HostComputeNetwork, networkPolicy hcn.PolicyNetworkRequest) error {
-	delayHnsCall(f.Delay)
-	return nil
+	return f.call("RemoveNetworkPolicy", network, networkPolicy)
 }
 
 func (f Hnsv2wrapperFake) GetNetworkByName(networkName string) (*hcn.HostComputeNetwork, error) {
 	f.Lock()
 	defer f.Unlock()
-	delayHnsCall(f.Delay)
+	if err := f.call("GetNetworkByName", networkName); err != nil {
+		return nil, err
+	}
 	if network, ok := f.Cache.networks[networkName]; ok {
 		return network.GetHCNObj(), nil
 	}
@@ -193,7 +202,9 @@ func (f Hnsv2wrapperFake) GetNetworkByName(networkName string) (*hcn.HostCompute
 func (f Hnsv2wrapperFake) GetNetworkByID(networkID string) (*hcn.HostComputeNetwork, error) {
 	f.Lock()
 	defer f.Unlock()
-	delayHnsCall(f.Delay)
+	if err := f.call("GetNetworkByID", networkID); err != nil {
+		return nil, err
+	}
 	for _, network := range f.Cache.networks {
 		if network.ID == networkID {
 			return network.GetHCNObj(), nil
@@ -205,7 +216,9 @@ func (f Hnsv2wrapperFake) GetNetworkByID(networkID string) (*hcn.HostComputeNetw
 func (f Hnsv2wrapperFake) GetEndpointByID(endpointID string) (*hcn.HostComputeEndpoint, error) {
 	f.Lock()
 	defer f.Unlock()
-	delayHnsCall(f.Delay)
+	if err := f.call("GetEndpointByID", endpointID); err != nil {
+		return nil, err
+	}
 	if ep, ok := f.Cache.endpoints[endpointID]; ok {
 		return ep.GetHCNObj(), nil
 	}
@@ -215,39 +228,47 @@ func (f Hnsv2wrapperFake) GetEndpointByID(endpointID string) (*hcn.HostComputeEn
 func (f Hnsv2wrapperFake) CreateEndpoint(endpoint *hcn.HostComputeEndpoint) (*hcn.HostComputeEndpoint, error) {
 	f.Lock()
 	defer f.Unlock()
-	delayHnsCall(f.Delay)
+	if err := f.call("CreateEndpoint", endpoint); err != nil {
+		return nil, err
+	}
 	f.Cache.endpoints[endpoint.Id] = NewFakeHostComputeEndpoint(endpoint)
+	f.notifier.notify(EndpointAttached)
 	return endpoint, nil
 }
 
 func (f Hnsv2wrapperFake) DeleteEndpoint(endpoint *hcn.HostComputeEndpoint) error {
 	f.Lock()
 	defer f.Unlock()
-	delayHnsCall(f.Delay)
+	if err := f.call("DeleteEndpoint", endpoint); err != nil {
+		return err
+	}
 	delete(f.Cache.endpoints, endpoint.Id)
+	f.notifier.notify(EndpointDetached)
 	return nil
 }
 
 func (f Hnsv2wrapperFake) GetNamespaceByID(netNamespacePath string) (*hcn.HostComputeNamespace, error) {
-	delayHnsCall(f.Delay)
+	if err := f.call("GetNamespaceByID", netNamespacePath); err != nil {
+		return nil, err
+	}
 	nameSpace := &hcn.HostComputeNamespace{Id: "ea37ac15-119e-477b-863b-cc23d6eeaa4d", NamespaceId: 1000}
 	return nameSpace, nil
 }
 
 func (f Hnsv2wrapperFake) AddNamespaceEndpoint(namespaceId string, endpointId string) error {
-	delayHnsCall(f.Delay)
-	return nil
+	return f.call("AddNamespaceEndpoint", namespaceId, endpointId)
 }
 
 func (f Hnsv2wrapperFake) RemoveNamespaceEndpoint(namespaceId string, endpointId string) error {
-	delayHnsCall(f.Delay)
-	return nil
+	return f.call("RemoveNamespaceEndpoint", namespaceId, endpointId)
 }
 
 func (f Hnsv2wrapperFake) ListEndpointsOfNetwork(networkId string) ([]hcn.HostComputeEndpoint, error) {
 	f.Lock()
 	defer f.Unlock()
-	delayHnsCall(f.Delay)
+	if err := f.call("ListEndpointsOfNetwork", networkId); err != nil {
+		return nil, err
+	}
 	endpoints := make([]hcn.HostComputeEndpoint, 0)
 	for _, endpoint := range f.Cache.endpoints {
 		if endpoint.HostComputeNetwork == networkId {
@@ -261,7 +282,9 @@ func (f Hnsv2wrapperFake) ListEndpointsOfNetwork(networkId string) ([]hcn.HostCo
 func (f Hnsv2wrapperFake) ListEndpointsQuery(_ hcn.HostComputeQuery) ([]hcn.HostComputeEndpoint, error) {
 	f.Lock()
 	defer f.Unlock()
-	delayHnsCall(f.Delay)
+	if err := f.call("ListEndpointsQuery"); err != nil {
+		return nil, err
+	}
 	endpoints := make([]hcn.HostComputeEndpoint, 0)
 	for _, endpoint := range f.Cache.endpoints {
 		e := *endpoint.GetHCNObj()
@@ -276,7 +299,9 @@ func (f Hnsv2wrapperFake) ListEndpointsQuery(_ hcn.HostComputeQuery) ([]hcn.Host
 func (f Hnsv2wrapperFake) ApplyEndpointPolicy(endpoint *hcn.HostComputeEndpoint, requestType hcn.RequestType, endpointPolicy hcn.PolicyEndpointRequest) error {
 	f.Lock()
 	defer f.Unlock()
-	delayHnsCall(f.Delay)
+	if err := f.call("ApplyEndpointPolicy", endpoint, requestType, endpointPolicy); err != nil {
+		return err
+	}
 	epCache, ok := f.Cache.endpoints[endpoint.Id]
 	if !ok {
 		return newErrorFakeHNS(fmt.Sprintf("[FakeHNS] could not find endpoint %s", endpoint.Id))
@@ -330,7 +355,9 @@ func (f Hnsv2wrapperFake) ApplyEndpointPolicy(endpoint *hcn.HostComputeEndpoint,
 }
 
 func (f Hnsv2wrapperFake) GetEndpointByName(endpointName string) (*hcn.HostComputeEndpoint, error) {
-	delayHnsCall(f.Delay)
+	if err := f.call("GetEndpointByName", endpointName); err != nil {
+		return nil, err
+	}
 	return nil, hcn.EndpointNotFoundError{EndpointName: endpointName}
 }
 
@@ -425,6 +452,124 @@ func (fCache FakeHNSCache) EndpointIP(id string) string {
 	return ""
 }
 
+// ValidateReferences checks that every member of a nested SetPolicy is a SetPolicy in the same network,
+// and that every address of an ACL which is not an IP or CIDR is the ID of an existing SetPolicy.
+// It is meant for UTs, to catch bookkeeping bugs of the callers which real HNS would reject or silently ignore.
+func (fCache FakeHNSCache) ValidateReferences() error {
+	problems := make([]string, 0)
+	for _, network := range fCache.networks {
+		for _, setPolicy := range network.Policies {
+			if setPolicy.Type != hcn.SetPolicyTypeNestedIpSet || setPolicy.Values == "" {
+				continue
+			}
+			for _, memberID := range strings.Split(setPolicy.Values, ",") {
+				if _, ok := network.Policies[memberID]; !ok {
+					problems = append(problems, fmt.Sprintf("nested SetPolicy %s has member %s which does not exist", setPolicy.Id, memberID))
+				}
+			}
+		}
+	}
+
+	for _, ep := range fCache.endpoints {
+		for _, acl := range ep.Policies {
+			for _, addresses := range []string{acl.LocalAddresses, acl.RemoteAddresses} {
+				if addresses == "" {
+					continue
+				}
+				for _, address := range strings.Split(addresses, ",") {
+					if isIPOrCIDR(address) {
+						continue
+					}
+					if fCache.SetPolicy(address) == nil {
+						problems = append(problems, fmt.Sprintf("ACL %s on endpoint %s references SetPolicy %s which does not exist", acl.ID, ep.ID, address))
+					}
+				}
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return newErrorFakeHNS(strings.Join(problems, "; "))
+}
+
+func isIPOrCIDR(address string) bool {
+	if net.ParseIP(address) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(address)
+	return err == nil
+}
+
+// FakeHNSSnapshot is a deep copy of the networks and endpoints of a FakeHNSCache.
+// It marshals to JSON, so a state captured once can be checked in and restored by UTs.
+type FakeHNSSnapshot struct {
+	// Networks maps network name to network object
+	Networks map[string]*FakeHostComputeNetwork
+	// Endpoints maps endpoint ID to endpoint object
+	Endpoints map[string]*FakeHostComputeEndpoint
+}
+
+// Snapshot returns a deep copy of the cache, which later changes to the cache don't affect.
+func (fCache FakeHNSCache) Snapshot() *FakeHNSSnapshot {
+	return &FakeHNSSnapshot{
+		Networks:  copyNetworks(fCache.networks),
+		Endpoints: copyEndpoints(fCache.endpoints),
+	}
+}
+
+// Restore replaces the networks and endpoints of the cache with a deep copy of the snapshot,
+// so the same snapshot can be restored many times.
+// Like the other cache methods, it must not be called concurrently with calls to the fake HNS.
+func (fCache FakeHNSCache) Restore(snapshot *FakeHNSSnapshot) {
+	for name := range fCache.networks {
+		delete(fCache.networks, name)
+	}
+	for name, network := range copyNetworks(snapshot.Networks) {
+		fCache.networks[name] = network
+	}
+
+	for id := range fCache.endpoints {
+		delete(fCache.endpoints, id)
+	}
+	for id, endpoint := range copyEndpoints(snapshot.Endpoints) {
+		fCache.endpoints[id] = endpoint
+	}
+}
+
+func copyNetworks(networks map[string]*FakeHostComputeNetwork) map[string]*FakeHostComputeNetwork {
+	copied := make(map[string]*FakeHostComputeNetwork, len(networks))
+	for name, network := range networks {
+		policies := make(map[string]*hcn.SetPolicySetting, len(network.Policies))
+		for id, setPolicy := range network.Policies {
+			setPolicyCopy := *setPolicy
+			policies[id] = &setPolicyCopy
+		}
+		copied[name] = &FakeHostComputeNetwork{
+			ID:       network.ID,
+			Name:     network.Name,
+			Policies: policies,
+		}
+	}
+	return copied
+}
+
+func copyEndpoints(endpoints map[string]*FakeHostComputeEndpoint) map[string]*FakeHostComputeEndpoint {
+	copied := make(map[string]*FakeHostComputeEndpoint, len(endpoints))
+	for id, endpoint := range endpoints {
+		endpointCopy := *endpoint
+		endpointCopy.Policies = make([]*FakeEndpointPolicy, 0, len(endpoint.Policies))
+		for _, acl := range endpoint.Policies {
+			aclCopy := *acl
+			endpointCopy.Policies = append(endpointCopy.Policies, &aclCopy)
+		}
+		copied[id] = &endpointCopy
+	}
+	return copied
+}
+
 type FakeHostComputeNetwork struct {
 	ID   string
 	Name string
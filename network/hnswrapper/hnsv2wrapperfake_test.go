@@ -0,0 +1,98 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+//go:build windows
+// +build windows
+
+package hnswrapper
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Microsoft/hcsshim/hcn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeCacheValidateReferences(t *testing.T) {
+	newHNS := func(t *testing.T) *Hnsv2wrapperFake {
+		hns := NewHnsv2wrapperFake()
+		_, err := hns.CreateNetwork(&hcn.HostComputeNetwork{Id: "network-id", Name: "azure"})
+		require.NoError(t, err)
+		_, err = hns.CreateEndpoint(&hcn.HostComputeEndpoint{Id: "ep1", HostComputeNetwork: "network-id"})
+		require.NoError(t, err)
+		network := hns.Cache.networks["azure"]
+		network.Policies["set1"] = &hcn.SetPolicySetting{Id: "set1", Type: hcn.SetPolicyTypeIpSet, Values: "10.0.0.1"}
+		network.Policies["list1"] = &hcn.SetPolicySetting{Id: "list1", Type: hcn.SetPolicyTypeNestedIpSet, Values: "set1"}
+		hns.Cache.endpoints["ep1"].Policies = []*FakeEndpointPolicy{
+			{ID: "acl1", LocalAddresses: "list1", RemoteAddresses: "168.63.129.16,10.0.0.0/8"},
+		}
+		require.NoError(t, hns.Cache.ValidateReferences())
+		return hns
+	}
+
+	t.Run("missing nested member", func(t *testing.T) {
+		hns := newHNS(t)
+		hns.Cache.networks["azure"].Policies["list1"].Values = "set1,set2"
+		require.ErrorIs(t, hns.Cache.ValidateReferences(), errorFakeHNS)
+	})
+
+	t.Run("ACL references missing SetPolicy", func(t *testing.T) {
+		hns := newHNS(t)
+		hns.Cache.endpoints["ep1"].Policies[0].RemoteAddresses = "set2"
+		require.ErrorIs(t, hns.Cache.ValidateReferences(), errorFakeHNS)
+	})
+}
+
+func TestFakeCacheSnapshotRestore(t *testing.T) {
+	hns := NewHnsv2wrapperFake()
+	_, err := hns.CreateNetwork(&hcn.HostComputeNetwork{Id: "network-id", Name: "azure"})
+	require.NoError(t, err)
+	_, err = hns.CreateEndpoint(&hcn.HostComputeEndpoint{Id: "ep1", HostComputeNetwork: "network-id"})
+	require.NoError(t, err)
+	hns.Cache.networks["azure"].Policies["set1"] = &hcn.SetPolicySetting{Id: "set1", Type: hcn.SetPolicyTypeIpSet, Values: "10.0.0.1"}
+	hns.Cache.endpoints["ep1"].Policies = []*FakeEndpointPolicy{{ID: "acl1", RemoteAddresses: "set1"}}
+	expected := hns.Cache.PrettyString()
+
+	snapshot := hns.Cache.Snapshot()
+	for i := 0; i < 2; i++ {
+		hns.Cache.networks["azure"].Policies["set1"].Values = "10.0.0.2"
+		hns.Cache.endpoints["ep1"].Policies[0].RemoteAddresses = "set2"
+		_, err = hns.CreateEndpoint(&hcn.HostComputeEndpoint{Id: "ep2", HostComputeNetwork: "network-id"})
+		require.NoError(t, err)
+
+		hns.Cache.Restore(snapshot)
+		require.Equal(t, expected, hns.Cache.PrettyString())
+		require.NoError(t, hns.Cache.ValidateReferences())
+	}
+
+	// a snapshot round trips through JSON, so a captured state can be checked in
+	rawSnapshot, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+	loaded := &FakeHNSSnapshot{}
+	require.NoError(t, json.Unmarshal(rawSnapshot, loaded))
+	fresh := NewHnsv2wrapperFake()
+	fresh.Cache.Restore(loaded)
+	require.Equal(t, expected, fresh.Cache.PrettyString())
+}
+
+func TestFakeEndpointNotifications(t *testing.T) {
+	hns := NewHnsv2wrapperFake()
+	var notifications []EndpointNotification
+	unsubscribe, err := hns.SubscribeEndpointNotifications(func(n EndpointNotification) {
+		notifications = append(notifications, n)
+	})
+	require.NoError(t, err)
+
+	endpoint := &hcn.HostComputeEndpoint{Id: "ep1"}
+	_, err = hns.CreateEndpoint(endpoint)
+	require.NoError(t, err)
+	require.NoError(t, hns.DeleteEndpoint(endpoint))
+	hns.NotifyEndpoints(ServiceDisconnected)
+	require.Equal(t, []EndpointNotification{EndpointAttached, EndpointDetached, ServiceDisconnected}, notifications)
+
+	unsubscribe()
+	_, err = hns.CreateEndpoint(endpoint)
+	require.NoError(t, err)
+	require.Len(t, notifications, 3)
+}
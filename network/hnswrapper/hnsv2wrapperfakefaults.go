@@ -0,0 +1,108 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+//go:build windows
+// +build windows
+
+package hnswrapper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FakeHNSCall is a call made to the Hnsv2wrapperFake.
+type FakeHNSCall struct {
+	// Method is the name of the called method, e.g. "ApplyEndpointPolicy".
+	Method string
+	Args   []interface{}
+	// Err is the error injected into the call, if any.
+	Err error
+}
+
+type injectedFailures struct {
+	remaining int
+	err       error
+}
+
+// fakeFaults holds the latency and errors injected into the calls of the Hnsv2wrapperFake and records the calls.
+// It is shared by all copies of the fake, since the fake methods have value receivers.
+type fakeFaults struct {
+	sync.Mutex
+	latency  map[string]time.Duration
+	failures map[string]*injectedFailures
+	calls    []FakeHNSCall
+}
+
+func newFakeFaults() *fakeFaults {
+	return &fakeFaults{
+		latency:  map[string]time.Duration{},
+		failures: map[string]*injectedFailures{},
+	}
+}
+
+// SetCallLatency delays every call to the method by the latency, in addition to the Delay of all calls.
+func (f Hnsv2wrapperFake) SetCallLatency(method string, latency time.Duration) {
+	f.faults.Lock()
+	defer f.faults.Unlock()
+	f.faults.latency[method] = latency
+}
+
+// FailNextCalls makes the next n calls to the method return the error, after which calls succeed again.
+// If err is nil, an errorFakeHNS error is returned.
+func (f Hnsv2wrapperFake) FailNextCalls(method string, n int, err error) {
+	f.faults.Lock()
+	defer f.faults.Unlock()
+	if err == nil {
+		err = newErrorFakeHNS(fmt.Sprintf("[FakeHNS] injected failure of %s", method))
+	}
+	f.faults.failures[method] = &injectedFailures{remaining: n, err: err}
+}
+
+// Calls returns the calls made to the fake, in order.
+func (f Hnsv2wrapperFake) Calls() []FakeHNSCall {
+	f.faults.Lock()
+	defer f.faults.Unlock()
+	calls := make([]FakeHNSCall, len(f.faults.calls))
+	copy(calls, f.faults.calls)
+	return calls
+}
+
+// CallCount returns the number of calls made to the method, including failed calls.
+func (f Hnsv2wrapperFake) CallCount(method string) int {
+	f.faults.Lock()
+	defer f.faults.Unlock()
+	count := 0
+	for i := range f.faults.calls {
+		if f.faults.calls[i].Method == method {
+			count++
+		}
+	}
+	return count
+}
+
+// ResetFaults removes all injected latency and errors and forgets the recorded calls.
+func (f Hnsv2wrapperFake) ResetFaults() {
+	f.faults.Lock()
+	defer f.faults.Unlock()
+	f.faults.latency = map[string]time.Duration{}
+	f.faults.failures = map[string]*injectedFailures{}
+	f.faults.calls = nil
+}
+
+// call records the call, delays it by the configured latency and returns the error injected into it, if any.
+func (f Hnsv2wrapperFake) call(method string, args ...interface{}) error {
+	f.faults.Lock()
+	latency := f.Delay + f.faults.latency[method]
+	var err error
+	if failures, ok := f.faults.failures[method]; ok && failures.remaining > 0 {
+		failures.remaining--
+		err = failures.err
+	}
+	f.faults.calls = append(f.faults.calls, FakeHNSCall{Method: method, Args: args, Err: err})
+	f.faults.Unlock()
+
+	delayHnsCall(latency)
+	return err
+}
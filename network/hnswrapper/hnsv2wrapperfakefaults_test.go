@@ -0,0 +1,65 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+//go:build windows
+// +build windows
+
+package hnswrapper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Microsoft/hcsshim/hcn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeFailNextCalls(t *testing.T) {
+	hns := NewHnsv2wrapperFake()
+	errTransient := errors.New("transient")
+	hns.FailNextCalls("CreateEndpoint", 2, errTransient)
+
+	ep := &hcn.HostComputeEndpoint{Id: "ep1"}
+	for i := 0; i < 2; i++ {
+		_, err := hns.CreateEndpoint(ep)
+		require.ErrorIs(t, err, errTransient)
+	}
+	_, err := hns.CreateEndpoint(ep)
+	require.NoError(t, err)
+
+	_, err = hns.GetEndpointByID("ep1")
+	require.NoError(t, err)
+
+	calls := hns.Calls()
+	require.Len(t, calls, 4)
+	assert.Equal(t, "CreateEndpoint", calls[0].Method)
+	assert.Equal(t, errTransient, calls[1].Err)
+	assert.NoError(t, calls[2].Err)
+	assert.Equal(t, []interface{}{"ep1"}, calls[3].Args)
+	assert.Equal(t, 3, hns.CallCount("CreateEndpoint"))
+
+	hns.ResetFaults()
+	assert.Empty(t, hns.Calls())
+}
+
+func TestFakeDefaultInjectedError(t *testing.T) {
+	hns := NewHnsv2wrapperFake()
+	hns.FailNextCalls("DeleteNetwork", 1, nil)
+	require.ErrorIs(t, hns.DeleteNetwork(&hcn.HostComputeNetwork{}), errorFakeHNS)
+	require.NoError(t, hns.DeleteNetwork(&hcn.HostComputeNetwork{}))
+}
+
+func TestFakeCallLatency(t *testing.T) {
+	hns := NewHnsv2wrapperFake()
+	hns.SetCallLatency("GetNetworkByName", 50*time.Millisecond)
+
+	start := time.Now()
+	_, _ = hns.GetNetworkByName("azure")
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	start = time.Now()
+	_, _ = hns.GetNetworkByID("id")
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
@@ -0,0 +1,94 @@
+//go:build windows
+// +build windows
+
+package hnswrapper
+
+import (
+	"github.com/Microsoft/hcsshim/hcn"
+)
+
+// Hnsv2wrapperRecorder records the HNS mutations instead of making them, and passes the reads to the wrapped HNS.
+// The mutations succeed, and the created objects are returned as passed.
+type Hnsv2wrapperRecorder struct {
+	Hnsv2 HnsV2WrapperInterface
+	// Record is called with the name of each mutating method and its arguments.
+	Record func(method string, objects ...interface{})
+}
+
+func (h Hnsv2wrapperRecorder) CreateEndpoint(endpoint *hcn.HostComputeEndpoint) (*hcn.HostComputeEndpoint, error) {
+	h.Record("CreateEndpoint", endpoint)
+	return endpoint, nil
+}
+
+func (h Hnsv2wrapperRecorder) DeleteEndpoint(endpoint *hcn.HostComputeEndpoint) error {
+	h.Record("DeleteEndpoint", endpoint)
+	return nil
+}
+
+func (h Hnsv2wrapperRecorder) CreateNetwork(network *hcn.HostComputeNetwork) (*hcn.HostComputeNetwork, error) {
+	h.Record("CreateNetwork", network)
+	return network, nil
+}
+
+func (h Hnsv2wrapperRecorder) DeleteNetwork(network *hcn.HostComputeNetwork) error {
+	h.Record("DeleteNetwork", network)
+	return nil
+}
+
+func (h Hnsv2wrapperRecorder) ModifyNetworkSettings(network *hcn.HostComputeNetwork, request *hcn.ModifyNetworkSettingRequest) error {
+	h.Record("ModifyNetworkSettings", network.Id, request)
+	return nil
+}
+
+func (h Hnsv2wrapperRecorder) AddNetworkPolicy(network *hcn.HostComputeNetwork, networkPolicy hcn.PolicyNetworkRequest) error {
+	h.Record("AddNetworkPolicy", network.Id, networkPolicy)
+	return nil
+}
+
+func (h Hnsv2wrapperRecorder) RemoveNetworkPolicy(network *hcn.HostComputeNetwork, networkPolicy hcn.PolicyNetworkRequest) error {
+	h.Record("RemoveNetworkPolicy", network.Id, networkPolicy)
+	return nil
+}
+
+func (h Hnsv2wrapperRecorder) GetNamespaceByID(netNamespacePath string) (*hcn.HostComputeNamespace, error) {
+	return h.Hnsv2.GetNamespaceByID(netNamespacePath) // nolint:wrapcheck // no need to wrap check for this wrapper
+}
+
+func (h Hnsv2wrapperRecorder) AddNamespaceEndpoint(namespaceID, endpointID string) error {
+	h.Record("AddNamespaceEndpoint", namespaceID, endpointID)
+	return nil
+}
+
+func (h Hnsv2wrapperRecorder) RemoveNamespaceEndpoint(namespaceID, endpointID string) error {
+	h.Record("RemoveNamespaceEndpoint", namespaceID, endpointID)
+	return nil
+}
+
+func (h Hnsv2wrapperRecorder) GetNetworkByName(networkName string) (*hcn.HostComputeNetwork, error) {
+	return h.Hnsv2.GetNetworkByName(networkName) // nolint:wrapcheck // no need to wrap check for this wrapper
+}
+
+func (h Hnsv2wrapperRecorder) GetNetworkByID(networkID string) (*hcn.HostComputeNetwork, error) {
+	return h.Hnsv2.GetNetworkByID(networkID) // nolint:wrapcheck // no need to wrap check for this wrapper
+}
+
+func (h Hnsv2wrapperRecorder) GetEndpointByID(endpointID string) (*hcn.HostComputeEndpoint, error) {
+	return h.Hnsv2.GetEndpointByID(endpointID) // nolint:wrapcheck // no need to wrap check for this wrapper
+}
+
+func (h Hnsv2wrapperRecorder) ListEndpointsOfNetwork(networkID string) ([]hcn.HostComputeEndpoint, error) {
+	return h.Hnsv2.ListEndpointsOfNetwork(networkID) // nolint:wrapcheck // no need to wrap check for this wrapper
+}
+
+func (h Hnsv2wrapperRecorder) ListEndpointsQuery(query hcn.HostComputeQuery) ([]hcn.HostComputeEndpoint, error) {
+	return h.Hnsv2.ListEndpointsQuery(query) // nolint:wrapcheck // no need to wrap check for this wrapper
+}
+
+func (h Hnsv2wrapperRecorder) ApplyEndpointPolicy(endpoint *hcn.HostComputeEndpoint, requestType hcn.RequestType, endpointPolicy hcn.PolicyEndpointRequest) error {
+	h.Record("ApplyEndpointPolicy", endpoint.Id, requestType, endpointPolicy)
+	return nil
+}
+
+func (h Hnsv2wrapperRecorder) GetEndpointByName(endpointName string) (*hcn.HostComputeEndpoint, error) {
+	return h.Hnsv2.GetEndpointByName(endpointName) // nolint:wrapcheck // no need to wrap check for this wrapper
+}
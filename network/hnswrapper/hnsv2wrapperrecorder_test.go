@@ -0,0 +1,40 @@
+//go:build windows
+// +build windows
+
+package hnswrapper
+
+import (
+	"testing"
+
+	"github.com/Microsoft/hcsshim/hcn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderRecordsMutationsOnly(t *testing.T) {
+	fake := NewHnsv2wrapperFake()
+	_, err := fake.CreateNetwork(&hcn.HostComputeNetwork{Id: "network-id", Name: "azure"})
+	require.NoError(t, err)
+
+	var methods []string
+	hns := Hnsv2wrapperRecorder{
+		Hnsv2: fake,
+		Record: func(method string, _ ...interface{}) {
+			methods = append(methods, method)
+		},
+	}
+
+	network, err := hns.GetNetworkByName("azure")
+	require.NoError(t, err)
+	_, err = hns.CreateEndpoint(&hcn.HostComputeEndpoint{Id: "ep1", HostComputeNetwork: network.Id})
+	require.NoError(t, err)
+	require.NoError(t, hns.AddNetworkPolicy(network, hcn.PolicyNetworkRequest{}))
+	require.NoError(t, hns.DeleteNetwork(network))
+
+	require.Equal(t, []string{"CreateEndpoint", "AddNetworkPolicy", "DeleteNetwork"}, methods)
+
+	// nothing was changed in the wrapped HNS
+	_, err = fake.GetEndpointByID("ep1")
+	require.Error(t, err)
+	_, err = fake.GetNetworkByName("azure")
+	require.NoError(t, err)
+}
@@ -0,0 +1,132 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package network
+
+import (
+	"net"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/network/policy"
+	"github.com/Microsoft/hcsshim/hcn"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+var (
+	errL1VHAdapterRequired    = errors.New("l1vh networks require the adapter name of the delegated vNIC")
+	errL1VHMacAddressRequired = errors.New("l1vh endpoints require the MAC address of the delegated vNIC")
+)
+
+// configureL1VHEndpoint configures an hcn endpoint which attaches the pod to the delegated vNIC of an L1VH network.
+// The endpoint has the MAC address of the vNIC, so its traffic is accepted by the fabric without NAT. Since the
+// traffic doesn't go through the host, only the ACL policies of the endpoint apply.
+func (nw *network) configureL1VHEndpoint(epInfo *EndpointInfo) (*hcn.HostComputeEndpoint, error) {
+	if len(epInfo.MacAddress) == 0 {
+		return nil, errL1VHMacAddressRequired
+	}
+
+	infraEpName, _ := ConstructEndpointID(epInfo.ContainerID, epInfo.NetNsPath, epInfo.IfName)
+	hcnEndpoint := &hcn.HostComputeEndpoint{
+		Name:               infraEpName,
+		HostComputeNetwork: nw.HnsId,
+		Dns: hcn.Dns{
+			Search:     strings.Split(epInfo.DNS.Suffix, ","),
+			ServerList: epInfo.DNS.Servers,
+			Options:    epInfo.DNS.Options,
+		},
+		SchemaVersion: hcn.SchemaVersion{
+			Major: hcnSchemaVersionMajor,
+			Minor: hcnSchemaVersionMinor,
+		},
+		MacAddress: epInfo.MacAddress.String(),
+	}
+
+	var aclPolicies []policy.Policy
+	for _, p := range epInfo.Policies {
+		if p.Type == policy.EndpointPolicy && policy.GetPolicyType(p) == policy.ACLPolicy {
+			aclPolicies = append(aclPolicies, p)
+		}
+	}
+	endpointPolicies, err := policy.GetHcnEndpointPolicies(policy.EndpointPolicy, aclPolicies, epInfo.Data, false, false, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get l1vh endpoint policies")
+	}
+	hcnEndpoint.Policies = endpointPolicies
+
+	for _, route := range epInfo.Routes {
+		hcnEndpoint.Routes = append(hcnEndpoint.Routes, hcn.Route{
+			NextHop:           route.Gw.String(),
+			DestinationPrefix: route.Dst.String(),
+		})
+	}
+
+	for _, ipAddress := range epInfo.IPAddresses {
+		prefixLength, _ := ipAddress.Mask.Size()
+		hcnEndpoint.IpConfigurations = append(hcnEndpoint.IpConfigurations, hcn.IpConfig{
+			IpAddress:    ipAddress.IP.String(),
+			PrefixLength: uint8(prefixLength),
+		})
+	}
+
+	return hcnEndpoint, nil
+}
+
+// newL1VHEndpoint creates an hcn endpoint on the delegated vNIC of an L1VH network and adds it to the pod's namespace.
+// The endpoint is removed again if it can't be added to the namespace. It's deleted like any other hcn endpoint.
+func (nw *network) newL1VHEndpoint(epInfo *EndpointInfo) (*endpoint, error) {
+	hcnEndpoint, err := nw.configureL1VHEndpoint(epInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Creating l1vh hcn endpoint", zap.String("name", hcnEndpoint.Name), zap.String("computenetwork", hcnEndpoint.HostComputeNetwork),
+		zap.String("macAddress", hcnEndpoint.MacAddress))
+	hnsResponse, err := Hnsv2.CreateEndpoint(hcnEndpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create l1vh endpoint %s", hcnEndpoint.Name)
+	}
+
+	defer func() {
+		if err != nil {
+			logger.Info("Deleting l1vh hcn endpoint with id", zap.String("id", hnsResponse.Id))
+			if errDelete := Hnsv2.DeleteEndpoint(hnsResponse); errDelete != nil {
+				logger.Error("Failed to delete l1vh hcn endpoint", zap.String("id", hnsResponse.Id), zap.Error(errDelete))
+			}
+		}
+	}()
+
+	var namespace *hcn.HostComputeNamespace
+	if namespace, err = Hnsv2.GetNamespaceByID(epInfo.NetNsPath); err != nil {
+		return nil, errors.Wrapf(err, "failed to get hcn namespace %s", epInfo.NetNsPath)
+	}
+
+	if err = Hnsv2.AddNamespaceEndpoint(namespace.Id, hnsResponse.Id); err != nil {
+		return nil, errors.Wrapf(err, "failed to add l1vh endpoint %s to hcn namespace %s", hnsResponse.Id, namespace.Id)
+	}
+
+	logger.Info("Successfully created l1vh hcn endpoint", zap.String("id", hnsResponse.Id))
+
+	var gateway net.IP
+	if len(hnsResponse.Routes) > 0 {
+		gateway = net.ParseIP(hnsResponse.Routes[0].NextHop)
+	}
+
+	ep := &endpoint{
+		Id:           hcnEndpoint.Name,
+		HnsId:        hnsResponse.Id,
+		SandboxKey:   epInfo.ContainerID,
+		IfName:       epInfo.IfName,
+		IPAddresses:  epInfo.IPAddresses,
+		Gateways:     []net.IP{gateway},
+		DNS:          epInfo.DNS,
+		Routes:       epInfo.Routes,
+		NetNs:        epInfo.NetNsPath,
+		ContainerID:  epInfo.ContainerID,
+		PODName:      epInfo.PODName,
+		PODNameSpace: epInfo.PODNameSpace,
+	}
+	ep.MacAddress, _ = net.ParseMAC(hnsResponse.MacAddress)
+
+	return ep, nil
+}
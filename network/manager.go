@@ -106,6 +106,8 @@ type NetworkManager interface {
 	AttachEndpoint(networkID string, endpointID string, sandboxKey string) (*endpoint, error)
 	DetachEndpoint(networkID string, endpointID string) error
 	UpdateEndpoint(networkID string, existingEpInfo *EndpointInfo, targetEpInfo *EndpointInfo) error
+	AttachInterfaces(networkID string, endpointID string, epInfos []*EndpointInfo) error
+	DetachInterfaces(networkID string, endpointID string, ifNames []string) error
 	GetNumberOfEndpoints(ifName string, networkID string) int
 	GetEndpointID(containerID, ifName string) string
 	IsStatelessCNIMode() bool
@@ -664,6 +666,44 @@ func (nm *networkManager) UpdateEndpoint(networkID string, existingEpInfo *Endpo
 	return nil
 }
 
+// AttachInterfaces hot-plugs secondary interfaces, such as delegated NICs, into the network namespace of an existing
+// endpoint without tearing down its other interfaces.
+func (nm *networkManager) AttachInterfaces(networkID string, endpointID string, epInfos []*EndpointInfo) error {
+	nm.Lock()
+	defer nm.Unlock()
+
+	nw, err := nm.getNetwork(networkID)
+	if err != nil {
+		return err
+	}
+
+	err = nw.attachInterfaces(nm.netlink, nm.plClient, nm.netio, nm.nsClient, endpointID, epInfos)
+	if err != nil {
+		return err
+	}
+
+	return nm.save()
+}
+
+// DetachInterfaces moves secondary interfaces of an existing endpoint back to the host, leaving its other interfaces
+// in the network namespace of the container.
+func (nm *networkManager) DetachInterfaces(networkID string, endpointID string, ifNames []string) error {
+	nm.Lock()
+	defer nm.Unlock()
+
+	nw, err := nm.getNetwork(networkID)
+	if err != nil {
+		return err
+	}
+
+	err = nw.detachInterfaces(nm.netlink, nm.plClient, nm.netio, nm.nsClient, endpointID, ifNames)
+	if err != nil {
+		return err
+	}
+
+	return nm.save()
+}
+
 func (nm *networkManager) GetNumberOfEndpoints(ifName string, networkId string) int {
 	if ifName == "" {
 		for key := range nm.ExternalInterfaces {
@@ -125,6 +125,16 @@ func (nm *MockNetworkManager) UpdateEndpoint(networkID string, existingEpInfo *E
 	return nil
 }
 
+// AttachInterfaces mock
+func (nm *MockNetworkManager) AttachInterfaces(_, _ string, _ []*EndpointInfo) error {
+	return nil
+}
+
+// DetachInterfaces mock
+func (nm *MockNetworkManager) DetachInterfaces(_, _ string, _ []string) error {
+	return nil
+}
+
 // GetNumberOfEndpoints mock
 func (nm *MockNetworkManager) GetNumberOfEndpoints(ifName string, networkID string) int {
 	return 0
@@ -19,12 +19,16 @@ const (
 	opModeTunnel          = "tunnel"
 	opModeTransparent     = "transparent"
 	opModeTransparentVlan = "transparent-vlan"
+	opModeSRIOV           = "sriov"
+	opModeL1VH            = "l1vh" // Windows only, pods are attached to a delegated vNIC of the host
 	opModeDefault         = opModeTunnel
 )
 
 const (
 	// ipv6 modes
 	IPV6Nat = "ipv6nat"
+	// IPV6Only is single stack IPv6 in bridge mode, where the bridge proxies NDP for the pods instead of replying to ARP
+	IPV6Only = "ipv6only"
 )
 
 // externalInterface is a host network interface that bridges containers to external networks.
@@ -54,6 +58,7 @@ type network struct {
 	EnableSnatOnHost bool
 	NetNs            string
 	SnatBridgeIP     string
+	IPV6Mode         string `json:",omitempty"`
 }
 
 // NetworkInfo contains read-only information about a container network.
@@ -90,6 +90,10 @@ func (nm *networkManager) newNetworkImpl(nwInfo *NetworkInfo, extIf *externalInt
 				return nil, fmt.Errorf("Ipv6 forwarding failed: %w", err)
 			}
 		}
+	case opModeSRIOV:
+		// the containers get VFs of the external interface, which is the SR-IOV physical function
+		logger.Info("SR-IOV mode")
+		ifName = extIf.Name
 	case opModeTransparentVlan:
 		logger.Info("Transparent vlan mode")
 		ifName = extIf.Name
@@ -123,6 +127,7 @@ func (nm *networkManager) newNetworkImpl(nwInfo *NetworkInfo, extIf *externalInt
 		VlanId:           vlanid,
 		DNS:              nwInfo.DNS,
 		EnableSnatOnHost: nwInfo.EnableSnatOnHost,
+		IPV6Mode:         nwInfo.IPV6Mode,
 	}
 
 	return nw, nil
@@ -154,7 +159,7 @@ func (nm *networkManager) deleteNetworkImpl(nw *network) error {
 	if nw.VlanId != 0 {
 		networkClient = NewOVSClient(nw.extIf.BridgeName, nw.extIf.Name, ovsctl.NewOvsctl(), nm.netlink, nm.plClient)
 	} else {
-		networkClient = NewLinuxBridgeClient(nw.extIf.BridgeName, nw.extIf.Name, NetworkInfo{}, nm.netlink, nm.plClient)
+		networkClient = NewLinuxBridgeClient(nw.extIf.BridgeName, nw.extIf.Name, NetworkInfo{IPV6Mode: nw.IPV6Mode}, nm.netlink, nm.plClient)
 	}
 
 	// Disconnect the interface if this was the last network using it.
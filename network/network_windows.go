@@ -251,8 +251,8 @@ func (nm *networkManager) configureHcnNetwork(nwInfo *NetworkInfo, extIf *extern
 	// per hns team, the hns calls fails if passed a vSwitch interface
 	// Pass adapter name here if it is not empty, this is cause if we don't tell HNS which adapter to use
 	// it will just pick one randomly, this is a problem for customers that have multiple adapters
+	var adapterName string
 	if nwInfo.AdapterName != "" || !strings.HasPrefix(extIf.Name, vEthernetAdapterPrefix) {
-		var adapterName string
 		if nwInfo.AdapterName != "" {
 			adapterName = nwInfo.AdapterName
 		} else {
@@ -295,6 +295,13 @@ func (nm *networkManager) configureHcnNetwork(nwInfo *NetworkInfo, extIf *extern
 		hcnNetwork.Type = hcn.L2Bridge
 	case opModeTunnel:
 		hcnNetwork.Type = hcn.L2Tunnel
+	case opModeL1VH:
+		// the network must be bound to the delegated vNIC, which is dedicated to pods, so it has no host vNIC
+		if adapterName == "" {
+			return nil, errL1VHAdapterRequired
+		}
+		hcnNetwork.Type = hcn.Transparent
+		hcnNetwork.Flags = hcn.DisableHostPort
 	default:
 		return nil, errNetworkModeInvalid
 	}
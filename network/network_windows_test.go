@@ -59,6 +59,37 @@ func TestNewAndDeleteNetworkImplHnsV2(t *testing.T) {
 	}
 }
 
+func TestConfigureHcnNetworkL1VH(t *testing.T) {
+	nm := &networkManager{
+		ExternalInterfaces: map[string]*externalInterface{},
+	}
+	extInterface := &externalInterface{
+		Name: "vEthernet (Ethernet)",
+	}
+
+	nwInfo := &NetworkInfo{
+		Id:          "d3e97a83-ba4c-45d5-ba88-dc56757ece28",
+		AdapterName: "Ethernet 2",
+		Mode:        opModeL1VH,
+	}
+	hcnNetwork, err := nm.configureHcnNetwork(nwInfo, extInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hcnNetwork.Type != hcn.Transparent || hcnNetwork.Flags != hcn.DisableHostPort {
+		t.Fatalf("expected a transparent network without host port, got type %s flags %d", hcnNetwork.Type, hcnNetwork.Flags)
+	}
+	if len(hcnNetwork.Policies) != 1 || hcnNetwork.Policies[0].Type != hcn.NetAdapterName {
+		t.Fatalf("expected the network to be bound to the delegated vNIC, got policies %+v", hcnNetwork.Policies)
+	}
+
+	// the vSwitch of the master interface can't be used for the delegated vNIC
+	nwInfo.AdapterName = ""
+	if _, err := nm.configureHcnNetwork(nwInfo, extInterface); !errors.Is(err, errL1VHAdapterRequired) {
+		t.Fatalf("expected errL1VHAdapterRequired, got %v", err)
+	}
+}
+
 func TestSuccesfulNetworkCreationWhenAlreadyExists(t *testing.T) {
 	nm := &networkManager{
 		ExternalInterfaces: map[string]*externalInterface{},
@@ -36,6 +36,8 @@ const (
 	enableIPV4ForwardCmd = "sysctl -w net.ipv4.conf.all.forwarding=1"
 	disableRACmd         = "sysctl -w net.ipv6.conf.%s.accept_ra=0"
 	acceptRAV6File       = "/proc/sys/net/ipv6/conf/%s/accept_ra"
+	enableProxyNDPCmd    = "sysctl -w net.ipv6.conf.%s.proxy_ndp=1"
+	ndpProxyCmd          = "ip -6 neigh %s proxy %s dev %s"
 )
 
 var logger = log.CNILogger.With(zap.String("component", "net-utils"))
@@ -275,6 +277,25 @@ func (nu NetworkUtils) SetProxyArp(ifName string) error {
 	return errors.Wrapf(err, "failed to set proxy arp for interface %v", ifName)
 }
 
+// EnableProxyNDPForInterface makes the interface answer neighbor solicitations for the addresses of its NDP proxy entries.
+func (nu NetworkUtils) EnableProxyNDPForInterface(ifName string) error {
+	cmd := fmt.Sprintf(enableProxyNDPCmd, ifName)
+	_, err := nu.plClient.ExecuteCommand(cmd)
+	return errors.Wrapf(err, "failed to enable proxy ndp for interface %v", ifName)
+}
+
+// AddOrDeleteNDPProxy adds (netlink.ADD) or deletes (netlink.REMOVE) an NDP proxy entry for an IPv6 address on the interface.
+func (nu NetworkUtils) AddOrDeleteNDPProxy(ifName string, ipAddress net.IP, operation int) error {
+	action := "add"
+	if operation == netlink.REMOVE {
+		action = "del"
+	}
+
+	cmd := fmt.Sprintf(ndpProxyCmd, action, ipAddress.String(), ifName)
+	_, err := nu.plClient.ExecuteCommand(cmd)
+	return errors.Wrapf(err, "failed to %s ndp proxy for %v on interface %v", action, ipAddress, ifName)
+}
+
 func getPrivateIPSpace() []string {
 	privateIPAddresses := []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "169.254.0.0/16"}
 	return privateIPAddresses
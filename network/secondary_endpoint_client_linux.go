@@ -131,6 +131,17 @@ func (client *SecondaryEndpointClient) ConfigureContainerInterfacesAndRoutes(epI
 }
 
 func (client *SecondaryEndpointClient) DeleteEndpoints(ep *endpoint) error {
+	ifNames := make([]string, 0, len(ep.SecondaryInterfaces))
+	for iface := range ep.SecondaryInterfaces {
+		ifNames = append(ifNames, iface)
+	}
+
+	return client.DeleteInterfaces(ep, ifNames)
+}
+
+// DeleteInterfaces moves the given secondary interfaces of the endpoint back to the VM network namespace,
+// leaving its other interfaces in the container network namespace.
+func (client *SecondaryEndpointClient) DeleteInterfaces(ep *endpoint, ifNames []string) error {
 	// Get VM namespace
 	vmns, err := netns.New().Get()
 	if err != nil {
@@ -142,8 +153,10 @@ func (client *SecondaryEndpointClient) DeleteEndpoints(ep *endpoint) error {
 	ns, err := client.nsClient.OpenNamespace(ep.NetworkNameSpace)
 	if err != nil {
 		if strings.Contains(err.Error(), errFileNotExist.Error()) {
-			// clear SecondaryInterfaces map since network namespace doesn't exist anymore
-			ep.SecondaryInterfaces = make(map[string]*InterfaceInfo)
+			// remove the interfaces since network namespace doesn't exist anymore
+			for _, iface := range ifNames {
+				delete(ep.SecondaryInterfaces, iface)
+			}
 			return nil
 		}
 
@@ -155,7 +168,9 @@ func (client *SecondaryEndpointClient) DeleteEndpoints(ep *endpoint) error {
 	logger.Info("Entering netns", zap.Any("NetNsPath", ep.NetworkNameSpace))
 	if err := ns.Enter(); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			ep.SecondaryInterfaces = make(map[string]*InterfaceInfo)
+			for _, iface := range ifNames {
+				delete(ep.SecondaryInterfaces, iface)
+			}
 			return nil
 		}
 
@@ -170,7 +185,7 @@ func (client *SecondaryEndpointClient) DeleteEndpoints(ep *endpoint) error {
 		}
 	}()
 
-	for iface := range ep.SecondaryInterfaces {
+	for _, iface := range ifNames {
 		if err := client.netlink.SetLinkNetNs(iface, uintptr(vmns)); err != nil {
 			logger.Error("Failed to move interface", zap.String("IfName", iface), zap.Error(newErrorSecondaryEndpointClient(err)))
 			continue
@@ -7,6 +7,7 @@ import (
 	"net"
 	"testing"
 
+	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
@@ -336,3 +337,49 @@ func TestSecondaryConfigureContainerInterfacesAndRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestAttachDetachInterfaces(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	plc := platform.NewMockExecClient(false)
+	nioc := netio.NewMockNetIO(false, 0)
+	nsc := NewMockNamespaceClient()
+	mac, _ := net.ParseMAC("ab:cd:ef:12:34:56")
+	newEpInfo := func(nicType cns.NICType, routes []RouteInfo) *EndpointInfo {
+		return &EndpointInfo{
+			MacAddress:  mac,
+			NICType:     nicType,
+			IPAddresses: []net.IPNet{{IP: net.ParseIP("192.168.0.4"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)}},
+			Routes:      routes,
+		}
+	}
+	routes := []RouteInfo{{Dst: net.IPNet{IP: net.ParseIP("192.168.0.4"), Mask: net.CIDRMask(ipv4FullMask, ipv4Bits)}}}
+
+	ep := &endpoint{Id: "ep1", NetworkNameSpace: "testns", SecondaryInterfaces: make(map[string]*InterfaceInfo)}
+	nw := &network{Mode: opModeTransparent, Endpoints: map[string]*endpoint{ep.Id: ep}}
+
+	// only delegated NICs can be hot-plugged
+	err := nw.attachInterfaces(nl, plc, nioc, nsc, ep.Id, []*EndpointInfo{newEpInfo(cns.InfraNIC, routes)})
+	require.ErrorIs(t, err, errHotPlugUnsupported)
+
+	// a failed attach is rolled back
+	require.Error(t, nw.attachInterfaces(nl, plc, nioc, nsc, ep.Id, []*EndpointInfo{newEpInfo(cns.DelegatedVMNIC, nil)}))
+	require.Empty(t, ep.SecondaryInterfaces)
+
+	require.NoError(t, nw.attachInterfaces(nl, plc, nioc, nsc, ep.Id, []*EndpointInfo{newEpInfo(cns.DelegatedVMNIC, routes)}))
+	require.Len(t, ep.SecondaryInterfaces, 1)
+	require.Equal(t, routes, ep.SecondaryInterfaces["eth1"].Routes)
+
+	// attaching the same NIC again leaves the attached interface in place
+	require.Error(t, nw.attachInterfaces(nl, plc, nioc, nsc, ep.Id, []*EndpointInfo{newEpInfo(cns.DelegatedVMNIC, routes)}))
+	require.Len(t, ep.SecondaryInterfaces, 1)
+
+	require.ErrorIs(t, nw.detachInterfaces(nl, plc, nioc, nsc, ep.Id, []string{"eth2"}), errInterfaceNotFound)
+	require.NoError(t, nw.detachInterfaces(nl, plc, nioc, nsc, ep.Id, []string{"eth1"}))
+	require.Empty(t, ep.SecondaryInterfaces)
+
+	require.ErrorIs(t, nw.attachInterfaces(nl, plc, nioc, nsc, "ep2", nil), errEndpointNotFound)
+
+	nw.Mode = opModeBridge
+	err = nw.attachInterfaces(nl, plc, nioc, nsc, ep.Id, []*EndpointInfo{newEpInfo(cns.DelegatedVMNIC, routes)})
+	require.ErrorIs(t, err, errHotPlugUnsupported)
+}
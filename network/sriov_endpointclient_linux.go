@@ -0,0 +1,205 @@
+package network
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/netns"
+	"github.com/Azure/azure-container-networking/network/networkutils"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+var (
+	errorSRIOVEndpointClient = errors.New("SRIOVEndpointClient Error")
+	errNoAvailableVF         = errors.New("no available VF")
+)
+
+// sysfsNetPath is where the network interfaces of the host are listed in sysfs. It's only changed in tests.
+var sysfsNetPath = "/sys/class/net"
+
+func newErrorSRIOVEndpointClient(err error) error {
+	return errors.Wrapf(err, "%s", errorSRIOVEndpointClient)
+}
+
+// findAvailableVF returns the name of a virtual function of the SR-IOV physical function pfName which is in the host
+// network namespace, so not in use by a container. The VFs moved to a container have no network interface in the
+// host's sysfs.
+func findAvailableVF(pfName string) (string, error) {
+	virtfns, err := filepath.Glob(filepath.Join(sysfsNetPath, pfName, "device", "virtfn*"))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list VFs of %s", pfName)
+	}
+	// virtfn0, virtfn1, ... sorted by index, so VFs are allocated in order
+	sort.Slice(virtfns, func(i, j int) bool {
+		if len(virtfns[i]) != len(virtfns[j]) {
+			return len(virtfns[i]) < len(virtfns[j])
+		}
+		return virtfns[i] < virtfns[j]
+	})
+
+	for _, virtfn := range virtfns {
+		ifaces, err := os.ReadDir(filepath.Join(virtfn, "net"))
+		if err != nil || len(ifaces) == 0 {
+			continue
+		}
+		return ifaces[0].Name(), nil
+	}
+	return "", errors.Wrapf(errNoAvailableVF, "on %s", pfName)
+}
+
+// SRIOVEndpointClient gives containers a virtual function of the SR-IOV capable host interface, e.g. on nodes with
+// accelerated networking, so that their traffic bypasses the host network stack.
+type SRIOVEndpointClient struct {
+	vfName         string
+	containerVFIf  string
+	netlink        netlink.NetlinkInterface
+	netioshim      netio.NetIOInterface
+	plClient       platform.ExecClient
+	netUtilsClient networkutils.NetworkUtils
+	nsClient       NamespaceClientInterface
+}
+
+func NewSRIOVEndpointClient(
+	vfName string,
+	nl netlink.NetlinkInterface,
+	nioc netio.NetIOInterface,
+	plc platform.ExecClient,
+	nsc NamespaceClientInterface,
+) *SRIOVEndpointClient {
+	client := &SRIOVEndpointClient{
+		vfName:         vfName,
+		containerVFIf:  vfName,
+		netlink:        nl,
+		netioshim:      nioc,
+		plClient:       plc,
+		netUtilsClient: networkutils.NewNetworkUtils(nl, plc),
+		nsClient:       nsc,
+	}
+
+	return client
+}
+
+func (client *SRIOVEndpointClient) AddEndpoints(_ *EndpointInfo) error {
+	// the VF already exists, it only has to be moved to the container
+	if _, err := client.netioshim.GetNetworkInterfaceByName(client.vfName); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	return nil
+}
+
+func (client *SRIOVEndpointClient) AddEndpointRules(_ *EndpointInfo) error {
+	return nil
+}
+
+func (client *SRIOVEndpointClient) DeleteEndpointRules(_ *endpoint) {
+}
+
+func (client *SRIOVEndpointClient) MoveEndpointsToContainerNS(epInfo *EndpointInfo, nsID uintptr) error {
+	// Move the VF to container's network namespace.
+	logger.Info("[net] Setting link netns", zap.String("vfName", client.vfName), zap.String("NetNsPath", epInfo.NetNsPath))
+	if err := client.netlink.SetLinkNetNs(client.vfName, nsID); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	return nil
+}
+
+func (client *SRIOVEndpointClient) SetupContainerInterfaces(epInfo *EndpointInfo) error {
+	if err := client.netUtilsClient.SetupContainerInterface(client.vfName, epInfo.IfName); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	client.containerVFIf = epInfo.IfName
+
+	return nil
+}
+
+func (client *SRIOVEndpointClient) ConfigureContainerInterfacesAndRoutes(epInfo *EndpointInfo) error {
+	// the VF is on the subnet of the host interface, so the subnet route added with the IPs is kept
+	if err := client.netUtilsClient.AssignIPToInterface(client.containerVFIf, epInfo.IPAddresses); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	routes := epInfo.Routes
+	if !epInfo.SkipDefaultRoutes {
+		for _, gw := range epInfo.Gateways {
+			// ip route add default via <subnet gateway> dev eth0
+			dst := net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, net.IPv4len*8)}
+			if gw.To4() == nil {
+				dst = net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, net.IPv6len*8)}
+			}
+			routes = append(routes, RouteInfo{Dst: dst, Gw: gw})
+		}
+	}
+
+	if err := addRoutes(client.netlink, client.netioshim, client.containerVFIf, routes); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	return nil
+}
+
+// DeleteEndpoints gives the VF back to the host, with its name on the host, so that it can be allocated again.
+// If the container network namespace was already deleted, the kernel has moved the VF back to the host.
+func (client *SRIOVEndpointClient) DeleteEndpoints(ep *endpoint) error {
+	// Get VM namespace
+	vmns, err := netns.New().Get()
+	if err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	// Open the network namespace.
+	logger.Info("Opening netns", zap.Any("NetNsPath", ep.NetworkNameSpace))
+	ns, err := client.nsClient.OpenNamespace(ep.NetworkNameSpace)
+	if err != nil {
+		if strings.Contains(err.Error(), errFileNotExist.Error()) {
+			return nil
+		}
+
+		return newErrorSRIOVEndpointClient(err)
+	}
+	defer ns.Close()
+
+	// Enter the container network namespace.
+	logger.Info("Entering netns", zap.Any("NetNsPath", ep.NetworkNameSpace))
+	if err := ns.Enter(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	// Return to host network namespace.
+	defer func() {
+		logger.Info("Exiting netns", zap.Any("NetNsPath", ep.NetworkNameSpace))
+		if err := ns.Exit(); err != nil {
+			logger.Error("Failed to exit netns with", zap.Error(newErrorSRIOVEndpointClient(err)))
+		}
+	}()
+
+	// the VF was renamed in the container, so it is found by its MAC address
+	vf, err := client.netioshim.GetNetworkInterfaceByMac(ep.MacAddress)
+	if err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	if err := client.netUtilsClient.SetupContainerInterface(vf.Name, client.vfName); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	logger.Info("[net] Setting link netns", zap.String("vfName", client.vfName), zap.Int("vmns", vmns))
+	if err := client.netlink.SetLinkNetNs(client.vfName, uintptr(vmns)); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSysfsVFs creates the sysfs entries of the VFs of the physical function eth0, with the interface of each VF which
+// is in the host network namespace, or none for the VFs in containers.
+func fakeSysfsVFs(t *testing.T, vfIfNames ...string) {
+	t.Helper()
+	root := t.TempDir()
+	for i, ifName := range vfIfNames {
+		netDir := filepath.Join(root, "eth0", "device", "virtfn"+strconv.Itoa(i), "net")
+		require.NoError(t, os.MkdirAll(netDir, 0o755))
+		if ifName != "" {
+			require.NoError(t, os.Mkdir(filepath.Join(netDir, ifName), 0o755))
+		}
+	}
+	previous := sysfsNetPath
+	sysfsNetPath = root
+	t.Cleanup(func() { sysfsNetPath = previous })
+}
+
+func TestFindAvailableVF(t *testing.T) {
+	fakeSysfsVFs(t, "", "enP1s1", "enP1s2")
+	vf, err := findAvailableVF("eth0")
+	require.NoError(t, err)
+	require.Equal(t, "enP1s1", vf)
+
+	fakeSysfsVFs(t, "", "")
+	_, err = findAvailableVF("eth0")
+	require.ErrorIs(t, err, errNoAvailableVF)
+
+	_, err = findAvailableVF("eth1")
+	require.ErrorIs(t, err, errNoAvailableVF)
+}
+
+func TestSRIOVConfigureContainerInterfacesAndRoutes(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	routes := []*netlink.Route{}
+	nl.SetAddRouteValidationFn(func(r *netlink.Route) error {
+		routes = append(routes, r)
+		return nil
+	})
+	client := NewSRIOVEndpointClient("enP1s1", nl, netio.NewMockNetIO(false, 0), platform.NewMockExecClient(false), NewMockNamespaceClient())
+
+	epInfo := &EndpointInfo{
+		IfName:      "eth0",
+		IPAddresses: []net.IPNet{{IP: net.ParseIP("10.240.0.5"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)}},
+		Gateways:    []net.IP{net.ParseIP("10.240.0.1")},
+	}
+	require.NoError(t, client.AddEndpoints(epInfo))
+	require.NoError(t, client.MoveEndpointsToContainerNS(epInfo, 1))
+	require.NoError(t, client.SetupContainerInterfaces(epInfo))
+	require.Equal(t, "eth0", client.containerVFIf)
+	require.NoError(t, client.ConfigureContainerInterfacesAndRoutes(epInfo))
+
+	// the default route is via the subnet gateway
+	require.Len(t, routes, 1)
+	require.Equal(t, "10.240.0.1", routes[0].Gw.String())
+	require.Equal(t, "0.0.0.0/0", routes[0].Dst.String())
+
+	// without default routes, only the given routes are added
+	routes = routes[:0]
+	epInfo.SkipDefaultRoutes = true
+	epInfo.Routes = []RouteInfo{{Dst: net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.CIDRMask(8, ipv4Bits)}, Gw: net.ParseIP("10.240.0.1")}}
+	require.NoError(t, client.ConfigureContainerInterfacesAndRoutes(epInfo))
+	require.Len(t, routes, 1)
+	require.Equal(t, "10.0.0.0/8", routes[0].Dst.String())
+}
+
+func TestSRIOVEndpointClientErrors(t *testing.T) {
+	client := NewSRIOVEndpointClient("enP1s1", netlink.NewMockNetlink(true, "netlink fail"), netio.NewMockNetIO(false, 0),
+		platform.NewMockExecClient(false), NewMockNamespaceClient())
+	err := client.MoveEndpointsToContainerNS(&EndpointInfo{}, 1)
+	require.EqualError(t, err, "SRIOVEndpointClient Error: "+netlink.ErrorMockNetlink.Error()+" : netlink fail")
+
+	client.netioshim = netio.NewMockNetIO(true, 1)
+	require.ErrorContains(t, client.AddEndpoints(&EndpointInfo{}), errorSRIOVEndpointClient.Error())
+}
+
+func TestSRIOVDeleteEndpoints(t *testing.T) {
+	client := NewSRIOVEndpointClient("enP1s1", netlink.NewMockNetlink(false, ""), netio.NewMockNetIO(false, 0),
+		platform.NewMockExecClient(false), NewMockNamespaceClient())
+
+	// the VF is found by its MAC address in the container and moved back to the host
+	require.NoError(t, client.DeleteEndpoints(&endpoint{NetworkNameSpace: "testns", MacAddress: netio.HwAddr}))
+
+	// the kernel moved the VF back to the host when the namespace was deleted
+	require.NoError(t, client.DeleteEndpoints(&endpoint{MacAddress: netio.HwAddr}))
+
+	require.Error(t, client.DeleteEndpoints(&endpoint{NetworkNameSpace: failToEnterNamespaceName, MacAddress: netio.HwAddr}))
+	require.Error(t, client.DeleteEndpoints(&endpoint{NetworkNameSpace: "testns", MacAddress: netio.BadHwAddr}))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-container-networking/common"
+	npmconfig "github.com/Azure/azure-container-networking/npm/config"
+	"github.com/Azure/azure-container-networking/npm/pkg/benchmark"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/dpshim"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const benchNodeName = "bench-node"
+
+func newBenchCmd() *cobra.Command {
+	benchCmd := &cobra.Command{
+		Use:    "bench",
+		Short:  "Measures NPM throughput with synthetic namespace, pod and network policy events",
+		Hidden: true,
+		Long: "Feeds the NPM v2 controllers synthetic namespaces, pods and network policies without an API server, and " +
+			"reports how fast each batch of events is processed. By default the events are only translated, into the " +
+			"dataplane shim of the controlplane. With --kernel, they are applied to the iptables and ipsets or HNS of this " +
+			"node with the dataplane config of the NPM ConfigMap, which resets the NPM state of the node. " +
+			"With background apply toggles enabled, the kernel may still be catching up when a batch is reported.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := benchmark.Config{NodeName: benchNodeName}
+			cfg.Namespaces, _ = cmd.Flags().GetInt("namespaces")
+			cfg.PodsPerNamespace, _ = cmd.Flags().GetInt("pods")
+			cfg.LabelsPerPod, _ = cmd.Flags().GetInt("labels")
+			cfg.PoliciesPerNamespace, _ = cmd.Flags().GetInt("policies")
+			cfg.Rate, _ = cmd.Flags().GetInt("rate")
+			cfg.Delete, _ = cmd.Flags().GetBool("delete")
+			cfg.Timeout, _ = cmd.Flags().GetDuration("timeout")
+			kernel, _ := cmd.Flags().GetBool("kernel")
+			asJSON, _ := cmd.Flags().GetBool("json")
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			var dp dataplane.GenericDataplane
+			var err error
+			if kernel {
+				dp, err = newBenchDataplane(ctx.Done())
+			} else {
+				dp, err = newBenchShim(ctx.Done())
+			}
+			if err != nil {
+				return err
+			}
+
+			report, err := benchmark.Run(ctx, cfg, dp)
+			if report != nil {
+				if asJSON {
+					if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+						return fmt.Errorf("failed to encode report: %w", err)
+					}
+				} else if err := report.Print(os.Stdout); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("benchmark failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	benchCmd.Flags().Int("namespaces", 10, "number of namespaces")
+	benchCmd.Flags().Int("pods", 50, "number of pods per namespace")
+	benchCmd.Flags().Int("labels", 4, "number of labels per pod")
+	benchCmd.Flags().Int("policies", 10, "number of network policies per namespace")
+	benchCmd.Flags().Int("rate", 0, "events created per second, 0 for as fast as possible")
+	benchCmd.Flags().Bool("delete", false, "also measure deleting the policies, pods and namespaces")
+	benchCmd.Flags().Duration("timeout", 10*time.Minute, "time the controllers have to process each batch of events")
+	benchCmd.Flags().Bool("kernel", false, "apply the events to the dataplane of this node instead of only translating them")
+	benchCmd.Flags().Bool("json", false, "print the report as JSON, for regression tracking")
+
+	return benchCmd
+}
+
+// newBenchShim creates the dataplane shim of the controlplane, discarding the goal states it sends to the daemons.
+func newBenchShim(stopCh <-chan struct{}) (*dpshim.DPShim, error) {
+	dp, err := dpshim.NewDPSim(stopCh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dataplane shim: %w", err)
+	}
+	go func() {
+		for {
+			select {
+			case <-dp.OutChannel:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return dp, nil
+}
+
+// newBenchDataplane creates the dataplane of this node like the start command does.
+func newBenchDataplane(stopCh <-chan struct{}) (*dataplane.DataPlane, error) {
+	config := &npmconfig.Config{}
+	if err := viper.Unmarshal(config); err != nil {
+		return nil, fmt.Errorf("failed to load config with error: %w", err)
+	}
+	updateV2DataplaneConfig(*config)
+
+	var nodeIP string
+	if util.IsWindowsDP() {
+		var err error
+		nodeIP, err = util.NodeIP()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node IP: %w", err)
+		}
+	}
+	npmV2DataplaneCfg.NodeIP = nodeIP
+
+	dp, err := dataplane.NewDataPlane(benchNodeName, common.NewIOShim(), npmV2DataplaneCfg, stopCh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dataplane with error %w", err)
+	}
+	return dp, nil
+}
@@ -31,6 +31,8 @@ func newDebugCmd() *cobra.Command {
 	debugCmd.AddCommand(newParseIPTableCmd())
 	debugCmd.AddCommand(newConvertIPTableCmd())
 	debugCmd.AddCommand(newGetTuples())
+	debugCmd.AddCommand(newTraceCmd())
+	debugCmd.AddCommand(newDumpDataplaneCmd())
 
 	return debugCmd
 }
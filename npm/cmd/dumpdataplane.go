@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/Azure/azure-container-networking/npm/http/api"
+	"github.com/spf13/cobra"
+)
+
+var errDumpDataplaneStatus = fmt.Errorf("unexpected response from the NPM debug API")
+
+func newDumpDataplaneCmd() *cobra.Command {
+	dumpDataplaneCmd := &cobra.Command{
+		Use:   "dump-dataplane",
+		Short: "Dump the ipsets, policies, and endpoints of the running NPM dataplane to JSON",
+		Long: "Connects to the debug API of the NPM running on this node and dumps the state of its dataplane cache: " +
+			"the ipsets and their members, the policies, the endpoints and their policies in Windows, and the stale " +
+			"chains yet to be deleted in Linux. The JSON can be attached to support tickets.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host, _ := cmd.Flags().GetString("host")
+			port, _ := cmd.Flags().GetString("port")
+			outputF, _ := cmd.Flags().GetString("output")
+
+			state, err := getDataplaneState(cmd.Context(), host, port)
+			if err != nil {
+				return err
+			}
+
+			if outputF == "" {
+				_, err = cmd.OutOrStdout().Write(state)
+				if err != nil {
+					return fmt.Errorf("failed to write the dataplane state: %w", err)
+				}
+				return nil
+			}
+			if err := os.WriteFile(outputF, state, 0o600); err != nil {
+				return fmt.Errorf("failed to write the dataplane state to %s: %w", outputF, err)
+			}
+			return nil
+		},
+	}
+
+	dumpDataplaneCmd.Flags().String("host", "http://localhost", "Set the NPM debug API host")
+	dumpDataplaneCmd.Flags().String("port", api.DefaultHttpPort, "Set the NPM debug API port")
+	dumpDataplaneCmd.Flags().StringP("output", "o", "", "Set the file to write the JSON to (optional, defaults to stdout)")
+
+	return dumpDataplaneCmd
+}
+
+// getDataplaneState requests the dataplane state from the NPM debug API and indents it.
+func getDataplaneState(ctx context.Context, host, port string) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%v:%v%v", host, port, api.NPMDataplanePath), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request the dataplane state: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the dataplane state: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d: %s", errDumpDataplaneStatus, resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	var state bytes.Buffer
+	if err := json.Indent(&state, body, "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to parse the dataplane state: %w", err)
+	}
+	state.WriteByte('\n')
+	return state.Bytes(), nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/http/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpDataplaneCmd(t *testing.T) {
+	const (
+		dumpDataplaneCmdString = "dump-dataplane"
+		state                  = `{"IPSets":[{"Name":"ns-x"}],"Policies":[{"PolicyKey":"x/deny"}]}`
+		wantState              = "{\n  \"IPSets\": [\n    {\n      \"Name\": \"ns-x\"\n    }\n  ],\n  \"Policies\": [\n    {\n      \"PolicyKey\": \"x/deny\"\n    }\n  ]\n}\n"
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != api.NPMDataplanePath {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(state))
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	host := "http://" + u.Hostname()
+
+	baseArgs := []string{debugCmdString, dumpDataplaneCmdString, "--host", host}
+
+	t.Run("stdout", func(t *testing.T) {
+		rootCMD := NewRootCmd()
+		b := &bytes.Buffer{}
+		rootCMD.SetOut(b)
+		rootCMD.SetArgs(concatArgs(baseArgs, "--port", u.Port()))
+		require.NoError(t, rootCMD.Execute())
+		require.Equal(t, wantState, b.String())
+	})
+
+	t.Run("output file", func(t *testing.T) {
+		outputF := filepath.Join(t.TempDir(), "dataplane.json")
+		rootCMD := NewRootCmd()
+		rootCMD.SetArgs(concatArgs(baseArgs, "--port", u.Port(), "-o", outputF))
+		require.NoError(t, rootCMD.Execute())
+		b, err := os.ReadFile(outputF)
+		require.NoError(t, err)
+		require.Equal(t, wantState, string(b))
+	})
+
+	t.Run("no NPM", func(t *testing.T) {
+		unused := httptest.NewServer(http.NotFoundHandler())
+		unusedURL, err := url.Parse(unused.URL)
+		require.NoError(t, err)
+		unused.Close()
+
+		rootCMD := NewRootCmd()
+		rootCMD.SetArgs(concatArgs(baseArgs, "--port", unusedURL.Port()))
+		require.Error(t, rootCMD.Execute())
+	})
+
+	t.Run("debug API disabled", func(t *testing.T) {
+		notFound := httptest.NewServer(http.NotFoundHandler())
+		defer notFound.Close()
+		notFoundURL, err := url.Parse(notFound.URL)
+		require.NoError(t, err)
+
+		rootCMD := NewRootCmd()
+		rootCMD.SetArgs(concatArgs(baseArgs, "--port", notFoundURL.Port()))
+		require.ErrorIs(t, rootCMD.Execute(), errDumpDataplaneStatus)
+	})
+}
@@ -5,10 +5,10 @@ package main
 import (
 	"runtime/debug"
 
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	"k8s.io/klog"
 )
 
 const (
@@ -26,12 +26,16 @@ var version string
 // panicRecoverAndExitWithStackTrace - recovery from panic, print a failure message and stack trace and exit the program
 func panicRecoverAndExitWithStackTrace() {
 	if r := recover(); r != nil {
-		klog.Infoln(r)
-		klog.Errorf("Stack trace: %s", string(debug.Stack()))
+		logger.Info(r)
+		logger.Errorf("Stack trace: %s", string(debug.Stack()))
 	}
 }
 
 func main() {
+	base, cleanup, err := npmlogger.New()
+	cobra.CheckErr(err)
+	defer cleanup()
+	npmlogger.SetBase(base)
 	defer panicRecoverAndExitWithStackTrace()
 
 	rootCmd := NewRootCmd()
@@ -8,7 +8,6 @@ import (
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"k8s.io/klog"
 )
 
 // NewRootCmd returns a root cobra command
@@ -28,9 +27,9 @@ func NewRootCmd() *cobra.Command {
 			// If a config file is found, read it in.
 			// NOTE: there is no config merging with default, if config is loaded, options must be set
 			if err := viper.ReadInConfig(); err == nil {
-				klog.Infof("Using config file: %+v", viper.ConfigFileUsed())
+				logger.Infof("Using config file: %+v", viper.ConfigFileUsed())
 			} else {
-				klog.Infof("Failed to load config from env %s: %v", npmconfig.ConfigEnvPath, err)
+				logger.Infof("Failed to load config from env %s: %v", npmconfig.ConfigEnvPath, err)
 				b, _ := json.Marshal(npmconfig.DefaultConfig) //nolint // skip checking error
 				err := viper.ReadConfig(bytes.NewBuffer(b))
 				if err != nil {
@@ -49,6 +48,7 @@ func NewRootCmd() *cobra.Command {
 	rootCmd.AddCommand(startCmd)
 
 	rootCmd.AddCommand(newDebugCmd())
+	rootCmd.AddCommand(newBenchCmd())
 
 	return rootCmd
 }
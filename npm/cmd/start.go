@@ -4,9 +4,12 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"math/rand"
+	"os"
 	"time"
 
+	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm"
@@ -16,27 +19,32 @@ import (
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/pkg/models"
+	"github.com/Azure/azure-container-networking/npm/pkg/transport"
 	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"k8s.io/apimachinery/pkg/util/wait"
 	k8sversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/klog"
 	"k8s.io/utils/exec"
 )
 
+var logger = npmlogger.For(npmlogger.NPM)
+
 var npmV2DataplaneCfg = &dataplane.Config{
 	IPSetManagerCfg: &ipsets.IPSetManagerCfg{
 		// NOTE: NetworkName and IPSetMode must be set later by the npm ConfigMap or default config
 	},
 	PolicyManagerCfg: &policies.PolicyManagerCfg{
-		PolicyMode: policies.IPSetPolicyMode,
-		// NOTE: PlaceAzureChainFirst must be set later by the npm ConfigMap or default config
+		// NOTE: PolicyMode, PlaceAzureChainFirst, UseNftables, EnableAdminNetworkPolicy, AuditMode, and RollbackPolicyOnFailure must be set later by the npm ConfigMap or default config
 	},
 }
 
@@ -66,14 +74,20 @@ func newStartNPMCmd() *cobra.Command {
 }
 
 func start(config npmconfig.Config, flags npmconfig.Flags) error {
-	klog.Infof("loaded config: %+v", config)
+	logger.Infof("loaded config: %+v", config)
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("failed to start with invalid config:\n%w", err)
+	}
+	if err := npmlogger.SetLevels(config.LogLevels); err != nil {
+		logger.Errorf("failed to set log levels: %v", err)
+	}
 	if util.IsWindowsDP() {
 		config.Toggles.EnableV2NPM = true
-		klog.Infof("NPM is running on Windows Dataplane. Enabling V2 NPM")
+		logger.Infof("NPM is running on Windows Dataplane. Enabling V2 NPM")
 	} else {
-		klog.Infof("NPM is running on Linux Dataplane")
+		logger.Infof("NPM is running on Linux Dataplane")
 	}
-	klog.Infof("starting NPM version %d with image %s", config.NPMVersion(), version)
+	logger.Infof("starting NPM version %d with image %s", config.NPMVersion(), version)
 
 	var err error
 
@@ -82,19 +96,19 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 		return err
 	}
 
-	klog.Infof("initializing metrics")
+	logger.Infof("initializing metrics")
 	metrics.InitializeAll()
 
 	// Create the kubernetes client
 	var k8sConfig *rest.Config
 	if flags.KubeConfigPath == "" {
-		klog.Infof("loading in cluster kubeconfig")
+		logger.Infof("loading in cluster kubeconfig")
 		k8sConfig, err = rest.InClusterConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load in cluster config: %w", err)
 		}
 	} else {
-		klog.Infof("loading kubeconfig from flag: %s", flags.KubeConfigPath)
+		logger.Infof("loading kubeconfig from flag: %s", flags.KubeConfigPath)
 		k8sConfig, err = clientcmd.BuildConfigFromFlags("", flags.KubeConfigPath)
 		if err != nil {
 			return fmt.Errorf("failed to load kubeconfig [%s] with err config: %w", flags.KubeConfigPath, err)
@@ -104,7 +118,7 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 	// Creates the clientset
 	clientset, err := kubernetes.NewForConfig(k8sConfig)
 	if err != nil {
-		klog.Infof("clientset creation failed with error %v.", err)
+		logger.Infof("clientset creation failed with error %v.", err)
 		return fmt.Errorf("failed to generate clientset with cluster config: %w", err)
 	}
 
@@ -114,54 +128,24 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 	// Adding some randomness so all NPM pods will not request for info at once.
 	factor := rand.Float64() + 1 //nolint
 	resyncPeriod := time.Duration(float64(minResyncPeriod.Nanoseconds()) * factor)
-	klog.Infof("Resync period for NPM pod is set to %d.", int(resyncPeriod/time.Minute))
+	logger.Infof("Resync period for NPM pod is set to %d.", int(resyncPeriod/time.Minute))
 	factory := informers.NewSharedInformerFactory(clientset, resyncPeriod)
 
+	var dynamicFactory dynamicinformer.DynamicSharedInformerFactory
+	if config.Toggles.EnableAdminNetworkPolicy {
+		dynamicClient, err := dynamic.NewForConfig(k8sConfig)
+		if err != nil {
+			return fmt.Errorf("failed to generate dynamic client with cluster config: %w", err)
+		}
+		dynamicFactory = dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, resyncPeriod)
+	}
+
 	k8sServerVersion := k8sServerVersion(clientset)
 
 	var dp dataplane.GenericDataplane
 	stopChannel := wait.NeverStop
 	if config.Toggles.EnableV2NPM {
-		// update the dataplane config
-		npmV2DataplaneCfg.MaxBatchedACLsPerPod = config.MaxBatchedACLsPerPod
-
-		npmV2DataplaneCfg.NetPolInBackground = config.Toggles.NetPolInBackground
-		if config.NetPolInvervalInMilliseconds > 0 {
-			npmV2DataplaneCfg.NetPolInterval = time.Duration(config.NetPolInvervalInMilliseconds * int(time.Millisecond))
-		} else {
-			npmV2DataplaneCfg.NetPolInterval = time.Duration(npmconfig.DefaultConfig.NetPolInvervalInMilliseconds * int(time.Millisecond))
-		}
-
-		if config.MaxPendingNetPols > 0 {
-			npmV2DataplaneCfg.MaxPendingNetPols = config.MaxPendingNetPols
-		} else {
-			npmV2DataplaneCfg.MaxPendingNetPols = npmconfig.DefaultConfig.MaxPendingNetPols
-		}
-
-		npmV2DataplaneCfg.ApplyInBackground = config.Toggles.ApplyInBackground
-		if config.ApplyMaxBatches > 0 {
-			npmV2DataplaneCfg.ApplyMaxBatches = config.ApplyMaxBatches
-		} else {
-			npmV2DataplaneCfg.ApplyMaxBatches = npmconfig.DefaultConfig.ApplyMaxBatches
-		}
-		if config.ApplyIntervalInMilliseconds > 0 {
-			npmV2DataplaneCfg.ApplyInterval = time.Duration(config.ApplyIntervalInMilliseconds * int(time.Millisecond))
-		} else {
-			npmV2DataplaneCfg.ApplyInterval = time.Duration(npmconfig.DefaultConfig.ApplyIntervalInMilliseconds * int(time.Millisecond))
-		}
-
-		if config.WindowsNetworkName == "" {
-			npmV2DataplaneCfg.NetworkName = util.AzureNetworkName
-		} else {
-			npmV2DataplaneCfg.NetworkName = config.WindowsNetworkName
-		}
-
-		npmV2DataplaneCfg.PlaceAzureChainFirst = config.Toggles.PlaceAzureChainFirst
-		if config.Toggles.ApplyIPSetsOnNeed {
-			npmV2DataplaneCfg.IPSetMode = ipsets.ApplyOnNeed
-		} else {
-			npmV2DataplaneCfg.IPSetMode = ipsets.ApplyAllIPSets
-		}
+		updateV2DataplaneConfig(config)
 
 		var nodeIP string
 		if util.IsWindowsDP() {
@@ -170,24 +154,44 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 				metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to get node IP while booting up: %v", err)
 				return fmt.Errorf("failed to get node IP while booting up: %w", err)
 			}
-			klog.Infof("node IP is %s", nodeIP)
+			logger.Infof("node IP is %s", nodeIP)
 		}
 		npmV2DataplaneCfg.NodeIP = nodeIP
 
-		dp, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, stopChannel)
+		var ioShim *common.IOShim
+		ioShim, err = newIOShim(config)
+		if err != nil {
+			metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to create ioshim with error %v", err)
+			return err
+		}
+		dp, err = dataplane.NewDataPlane(models.GetNodeName(), ioShim, npmV2DataplaneCfg, stopChannel)
 		if err != nil {
 			metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to create dataplane with error %v", err)
 			return fmt.Errorf("failed to create dataplane with error %w", err)
 		}
+		if config.Toggles.EnableAuditEvents {
+			if err = startAuditServer(config, dp, stopChannel); err != nil {
+				metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to start audit server with error %v", err)
+				return err
+			}
+		}
 		dp.RunPeriodicTasks()
+		watchConfig(config, dp)
 	}
-	npMgr := npm.NewNetworkPolicyManager(config, factory, dp, exec.New(), version, k8sServerVersion)
-	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata())
+	npMgr, err := npm.NewNetworkPolicyManager(config, factory, dynamicFactory, dp, exec.New(), version, k8sServerVersion)
 	if err != nil {
-		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
+		metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to create NPM with error %v", err)
+		return fmt.Errorf("failed to create NPM with error %w", err)
+	}
+	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata(), aitelemetry.TogglesFromStruct(config.Toggles))
+	if err != nil {
+		logger.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
 	}
 
-	go restserver.NPMRestServerListenAndServe(config, npMgr)
+	// only the v2 dataplane traces flows
+	flowTracer, _ := dp.(restserver.FlowTracer)
+	dataplaneDumper, _ := dp.(restserver.DataplaneDumper)
+	go restserver.NPMRestServerListenAndServe(config, npMgr, flowTracer, dataplaneDumper)
 
 	metrics.SendLog(util.NpmID, "starting NPM", metrics.PrintLog)
 	if err = npMgr.Start(config, stopChannel); err != nil {
@@ -198,6 +202,164 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 	select {}
 }
 
+// tunableDataplane is a dataplane whose tunables can be changed while NPM is running.
+type tunableDataplane interface {
+	UpdateTunables(tunables dataplane.Tunables) error
+}
+
+// watchConfig reloads the log levels and dataplane tunables when the mounted npm ConfigMap changes.
+// Changes to other fields are logged and ignored, since they only take effect when NPM restarts.
+func watchConfig(config npmconfig.Config, dp dataplane.GenericDataplane) {
+	tunableDP, ok := dp.(tunableDataplane)
+	if !ok {
+		return
+	}
+	cfgFile := viper.ConfigFileUsed()
+	if _, err := os.Stat(cfgFile); err != nil {
+		logger.Infof("not watching config file %q for changes: %v", cfgFile, err)
+		return
+	}
+
+	logger.Infof("watching config file %q for changes", cfgFile)
+	viper.OnConfigChange(func(event fsnotify.Event) {
+		logger.Infof("config file changed: %s", event.String())
+		updated := npmconfig.Config{}
+		if err := viper.Unmarshal(&updated); err != nil {
+			metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to load changed config: %v", err)
+			return
+		}
+		if err := reloadConfig(config, updated, tunableDP); err != nil {
+			metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to reload config: %v", err)
+		}
+	})
+	viper.WatchConfig()
+}
+
+// reloadConfig applies the log levels and the tunables of the updated config to the dataplane.
+// The running config is the one NPM started with, so fields requiring a restart are reported until they're reverted.
+func reloadConfig(running, updated npmconfig.Config, dp tunableDataplane) error {
+	if changed := npmconfig.RestartRequiredChanges(running, updated); len(changed) > 0 {
+		metrics.SendErrorLogAndMetric(util.NpmID, "error: ignoring changed config fields which require a restart: %v", changed)
+	}
+
+	if err := npmlogger.SetLevels(updated.LogLevels); err != nil {
+		metrics.SendErrorLogAndMetric(util.NpmID, "error: ignoring invalid log levels in changed config: %v", err)
+	}
+
+	tunables := dataplaneTunables(updated)
+	if err := dp.UpdateTunables(tunables); err != nil {
+		return fmt.Errorf("failed to update dataplane tunables: %w", err)
+	}
+	logger.Infof("reloaded dataplane tunables: %+v", tunables)
+	return nil
+}
+
+// dataplaneTunables returns the dataplane tunables from the npm ConfigMap or default config.
+func dataplaneTunables(config npmconfig.Config) dataplane.Tunables {
+	tunables := dataplane.Tunables{
+		MaxBatchedACLsPerPod: config.MaxBatchedACLsPerPod,
+		ApplyMaxBatches:      npmconfig.DefaultConfig.ApplyMaxBatches,
+		ApplyInterval:        time.Duration(npmconfig.DefaultConfig.ApplyIntervalInMilliseconds * int(time.Millisecond)),
+		MaxPendingNetPols:    npmconfig.DefaultConfig.MaxPendingNetPols,
+		NetPolInterval:       time.Duration(npmconfig.DefaultConfig.NetPolInvervalInMilliseconds * int(time.Millisecond)),
+	}
+	if config.ApplyMaxBatches > 0 {
+		tunables.ApplyMaxBatches = config.ApplyMaxBatches
+	}
+	if config.ApplyIntervalInMilliseconds > 0 {
+		tunables.ApplyInterval = time.Duration(config.ApplyIntervalInMilliseconds * int(time.Millisecond))
+	}
+	if config.MaxPendingNetPols > 0 {
+		tunables.MaxPendingNetPols = config.MaxPendingNetPols
+	}
+	if config.NetPolInvervalInMilliseconds > 0 {
+		tunables.NetPolInterval = time.Duration(config.NetPolInvervalInMilliseconds * int(time.Millisecond))
+	}
+	return tunables
+}
+
+// updateV2DataplaneConfig sets the dataplane config from the npm ConfigMap or default config.
+func updateV2DataplaneConfig(config npmconfig.Config) {
+	tunables := dataplaneTunables(config)
+	npmV2DataplaneCfg.MaxBatchedACLsPerPod = tunables.MaxBatchedACLsPerPod
+	npmV2DataplaneCfg.NetPolInBackground = config.Toggles.NetPolInBackground
+	npmV2DataplaneCfg.NetPolInterval = tunables.NetPolInterval
+	npmV2DataplaneCfg.MaxPendingNetPols = tunables.MaxPendingNetPols
+	npmV2DataplaneCfg.ApplyInBackground = config.Toggles.ApplyInBackground
+	npmV2DataplaneCfg.ApplyMaxBatches = tunables.ApplyMaxBatches
+	npmV2DataplaneCfg.ApplyInterval = tunables.ApplyInterval
+
+	if config.EndpointConcurrency > 0 {
+		npmV2DataplaneCfg.EndpointConcurrency = config.EndpointConcurrency
+	} else {
+		npmV2DataplaneCfg.EndpointConcurrency = npmconfig.DefaultConfig.EndpointConcurrency
+	}
+
+	if config.WindowsNetworkName == "" {
+		npmV2DataplaneCfg.NetworkName = util.AzureNetworkName
+	} else {
+		npmV2DataplaneCfg.NetworkName = config.WindowsNetworkName
+	}
+	npmV2DataplaneCfg.SecondaryNetworkNames = config.WindowsSecondaryNetworkNames
+
+	if config.WindowsPolicyMode == "" {
+		npmV2DataplaneCfg.PolicyMode = policies.IPSetPolicyMode
+	} else {
+		npmV2DataplaneCfg.PolicyMode = policies.PolicyManagerMode(config.WindowsPolicyMode)
+	}
+
+	npmV2DataplaneCfg.PlaceAzureChainFirst = config.Toggles.PlaceAzureChainFirst
+	npmV2DataplaneCfg.UseNftables = config.Toggles.EnableNftables
+	npmV2DataplaneCfg.EnableAdminNetworkPolicy = config.Toggles.EnableAdminNetworkPolicy
+	npmV2DataplaneCfg.AddSetsToNftables = config.Toggles.EnableNftables
+	npmV2DataplaneCfg.PolicyManagerCfg.AuditMode = config.Toggles.AuditMode
+	npmV2DataplaneCfg.IPSetManagerCfg.AuditMode = config.Toggles.AuditMode
+	npmV2DataplaneCfg.RollbackPolicyOnFailure = config.Toggles.RollbackPolicyOnFailure
+	npmV2DataplaneCfg.WatchEndpoints = config.Toggles.WatchHNSEndpoints
+	if config.Toggles.ApplyIPSetsOnNeed {
+		npmV2DataplaneCfg.IPSetMode = ipsets.ApplyOnNeed
+	} else {
+		npmV2DataplaneCfg.IPSetMode = ipsets.ApplyAllIPSets
+	}
+}
+
+// auditableDataplane is a dataplane which reports its changes to policy auditors.
+type auditableDataplane interface {
+	SetAuditPublisher(publisher dataplane.AuditPublisher)
+}
+
+// startAuditServer streams the audit events of the dataplane to gRPC watchers on the audit port.
+func startAuditServer(config npmconfig.Config, dp dataplane.GenericDataplane, stopCh <-chan struct{}) error {
+	auditDP, ok := dp.(auditableDataplane)
+	if !ok {
+		return nil
+	}
+	auditServer := transport.NewAuditServer()
+	if err := auditServer.Start(config.Transport.Address, config.Transport.AuditPort, stopCh); err != nil {
+		return fmt.Errorf("failed to start audit server: %w", err)
+	}
+	auditDP.SetAuditPublisher(auditServer)
+	return nil
+}
+
+// newIOShim creates the IOShim of the dataplane, which only records the changes it would make with EnableDryRun.
+func newIOShim(config npmconfig.Config) (*common.IOShim, error) {
+	if !config.Toggles.EnableDryRun {
+		return common.NewIOShim(), nil
+	}
+
+	var dryRunLog io.Writer = os.Stdout
+	if config.DryRunLogPath != "" {
+		f, err := os.OpenFile(config.DryRunLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644) //nolint:gomnd // log file permissions
+		if err != nil {
+			return nil, fmt.Errorf("failed to open dry run log: %w", err)
+		}
+		dryRunLog = f
+	}
+	logger.Infof("dry run enabled: recording the dataplane changes to %q instead of making them", config.DryRunLogPath)
+	return common.NewRecordingIOShim(common.NewShimRecorder(dryRunLog)), nil
+}
+
 func initLogging() error {
 	log.SetName("azure-npm")
 	log.SetLevel(log.LevelInfo)
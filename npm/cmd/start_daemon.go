@@ -6,7 +6,7 @@ import (
 	"os"
 	"strconv"
 
-	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/npm"
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
 	"github.com/Azure/azure-container-networking/npm/daemon"
@@ -20,7 +20,6 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/klog"
 )
 
 const (
@@ -47,61 +46,68 @@ func newStartNPMDaemonCmd() *cobra.Command {
 }
 
 func startDaemon(config npmconfig.Config) error {
-	klog.Infof("loaded config: %+v", config)
-	klog.Infof("starting NPM fan-out daemon with image: %s", version)
+	logger.Infof("loaded config: %+v", config)
+	logger.Infof("starting NPM fan-out daemon with image: %s", version)
 	// Read these ENV variables from the Pod spec `env` section.
 	pod := os.Getenv(podNameEnv)
 	node := os.Getenv(nodeNameEnv)
 
-	klog.Infof("initializing metrics")
+	logger.Infof("initializing metrics")
 	metrics.InitializeAll()
 
 	addr := config.Transport.Address + ":" + strconv.Itoa(config.Transport.ServicePort)
 	ctx := context.Background()
 	err := initLogging()
 	if err != nil {
-		klog.Errorf("failed to init logging : %v", err)
+		logger.Errorf("failed to init logging : %v", err)
 		return err
 	}
 
 	var dp dataplane.GenericDataplane
 
-	dp, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, wait.NeverStop)
+	ioShim, err := newIOShim(config)
 	if err != nil {
-		klog.Errorf("failed to create dataplane: %v", err)
+		logger.Errorf("failed to create ioshim: %v", err)
+		return err
+	}
+	dp, err = dataplane.NewDataPlane(models.GetNodeName(), ioShim, npmV2DataplaneCfg, wait.NeverStop)
+	if err != nil {
+		logger.Errorf("failed to create dataplane: %v", err)
 		return fmt.Errorf("failed to create dataplane with error %w", err)
 	}
 
 	dp.RunPeriodicTasks()
 	// TODO Daemon should implement cache encoder
-	go restserver.NPMRestServerListenAndServe(config, nil)
+	flowTracer, _ := dp.(restserver.FlowTracer)
+	dataplaneDumper, _ := dp.(restserver.DataplaneDumper)
+	go restserver.NPMRestServerListenAndServe(config, nil, flowTracer, dataplaneDumper)
 
 	client, err := transport.NewEventsClient(ctx, pod, node, addr)
 	if err != nil {
-		klog.Errorf("failed to create dataplane events client with error %v", err)
+		logger.Errorf("failed to create dataplane events client with error %v", err)
 		return fmt.Errorf("failed to create dataplane events client: %w", err)
 	}
 
 	gsp, err := goalstateprocessor.NewGoalStateProcessor(ctx, node, pod, client.EventsChannel(), dp)
 	if err != nil {
-		klog.Errorf("failed to create goalstate processor with error %v", err)
+		logger.Errorf("failed to create goalstate processor with error %v", err)
 		return fmt.Errorf("failed to create goalstate processor: %w", err)
 	}
 
 	n, err := daemon.NewNetworkPolicyDaemon(ctx, config, dp, gsp, client, version)
 	if err != nil {
-		klog.Errorf("failed to create dataplane : %v", err)
+		logger.Errorf("failed to create dataplane : %v", err)
 		return fmt.Errorf("failed to create dataplane: %w", err)
 	}
 
-	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata())
+	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata(), aitelemetry.TogglesFromStruct(config.Toggles))
 	if err != nil {
-		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
+		logger.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
 	}
 
 	err = n.Start(config, wait.NeverStop)
 	if err != nil {
-		klog.Errorf("failed to start dataplane : %v", err)
+		logger.Errorf("failed to start dataplane : %v", err)
 		return fmt.Errorf("failed to start dataplane: %w", err)
 	}
 
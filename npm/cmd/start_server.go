@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/npm"
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
 	"github.com/Azure/azure-container-networking/npm/controller"
@@ -21,7 +22,6 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/klog"
 )
 
 func newStartNPMControlplaneCmd() *cobra.Command {
@@ -49,34 +49,34 @@ func newStartNPMControlplaneCmd() *cobra.Command {
 }
 
 func startControlplane(config npmconfig.Config, flags npmconfig.Flags) error {
-	klog.Infof("loaded config: %+v", config)
-	klog.Infof("starting NPM fan-out server with image: %s", version)
+	logger.Infof("loaded config: %+v", config)
+	logger.Infof("starting NPM fan-out server with image: %s", version)
 
 	var err error
 
 	err = initLogging()
 	if err != nil {
-		klog.Errorf("failed to init logging : %v", err)
+		logger.Errorf("failed to init logging : %v", err)
 		return err
 	}
 
-	klog.Infof("initializing metrics")
+	logger.Infof("initializing metrics")
 	metrics.InitializeAll()
 
 	// Create the kubernetes client
 	var k8sConfig *rest.Config
 	if flags.KubeConfigPath == "" {
-		klog.Infof("loading in cluster kubeconfig")
+		logger.Infof("loading in cluster kubeconfig")
 		k8sConfig, err = rest.InClusterConfig()
 		if err != nil {
-			klog.Errorf("failed to get in cluster config: %v", err)
+			logger.Errorf("failed to get in cluster config: %v", err)
 			return fmt.Errorf("failed to load in cluster config: %w", err)
 		}
 	} else {
-		klog.Infof("loading kubeconfig from flag: %s", flags.KubeConfigPath)
+		logger.Infof("loading kubeconfig from flag: %s", flags.KubeConfigPath)
 		k8sConfig, err = clientcmd.BuildConfigFromFlags("", flags.KubeConfigPath)
 		if err != nil {
-			klog.Errorf("failed to load kubeconfig: %v", err)
+			logger.Errorf("failed to load kubeconfig: %v", err)
 			return fmt.Errorf("failed to load kubeconfig [%s] with err config: %w", flags.KubeConfigPath, err)
 		}
 	}
@@ -84,7 +84,7 @@ func startControlplane(config npmconfig.Config, flags npmconfig.Flags) error {
 	// Creates the clientset
 	clientset, err := kubernetes.NewForConfig(k8sConfig)
 	if err != nil {
-		klog.Infof("clientset creation failed with error %v.", err)
+		logger.Infof("clientset creation failed with error %v.", err)
 		return fmt.Errorf("failed to generate clientset with cluster config: %w", err)
 	}
 
@@ -94,14 +94,14 @@ func startControlplane(config npmconfig.Config, flags npmconfig.Flags) error {
 	// Adding some randomness so all NPM pods will not request for info at once.
 	factor := rand.Float64() + 1 //nolint
 	resyncPeriod := time.Duration(float64(minResyncPeriod.Nanoseconds()) * factor)
-	klog.Infof("Resync period for NPM pod is set to %d.", int(resyncPeriod/time.Minute))
+	logger.Infof("Resync period for NPM pod is set to %d.", int(resyncPeriod/time.Minute))
 	factory := informers.NewSharedInformerFactory(clientset, resyncPeriod)
 
 	k8sServerVersion := k8sServerVersion(clientset)
 
 	dp, err := dpshim.NewDPSim(wait.NeverStop)
 	if err != nil {
-		klog.Errorf("failed to create dataplane shim with error: %v", err)
+		logger.Errorf("failed to create dataplane shim with error: %v", err)
 		return fmt.Errorf("failed to create dataplane with error: %w", err)
 	}
 
@@ -109,16 +109,16 @@ func startControlplane(config npmconfig.Config, flags npmconfig.Flags) error {
 
 	npMgr, err := controller.NewNetworkPolicyServer(config, factory, mgr, dp, version, k8sServerVersion)
 	if err != nil {
-		klog.Errorf("failed to create NPM controlplane manager with error: %v", err)
+		logger.Errorf("failed to create NPM controlplane manager with error: %v", err)
 		return fmt.Errorf("failed to create NPM controlplane manager: %w", err)
 	}
 
-	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata())
+	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata(), aitelemetry.TogglesFromStruct(config.Toggles))
 	if err != nil {
-		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
+		logger.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
 	}
 
-	go restserver.NPMRestServerListenAndServe(config, npMgr)
+	go restserver.NPMRestServerListenAndServe(config, npMgr, nil, nil)
 
 	metrics.SendLog(util.FanOutServerID, "starting fan-out server", metrics.PrintLog)
 
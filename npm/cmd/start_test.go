@@ -1,10 +1,20 @@
 package main
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/log"
+	npmconfig "github.com/Azure/azure-container-networking/npm/config"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestInitLogging(t *testing.T) {
@@ -13,3 +23,64 @@ func TestInitLogging(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, expectedLogPath, log.GetLogDirectory())
 }
+
+func TestNewIOShimDryRun(t *testing.T) {
+	config := npmconfig.DefaultConfig
+	config.Toggles.EnableDryRun = true
+	config.DryRunLogPath = filepath.Join(t.TempDir(), "dryrun.log")
+
+	ioShim, err := newIOShim(config)
+	require.NoError(t, err)
+
+	cmd := ioShim.Exec.Command("iptables-nft-restore", "-w", "60", "-T", "filter", "--noflush")
+	cmd.SetStdin(strings.NewReader("*filter\n-N AZURE-NPM\nCOMMIT\n"))
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err)
+	require.Empty(t, out)
+
+	b, err := os.ReadFile(config.DryRunLogPath)
+	require.NoError(t, err)
+	var call common.ShimCall
+	require.NoError(t, json.Unmarshal(b, &call))
+	require.Equal(t, common.ShimCallExec, call.Kind)
+	require.Equal(t, "iptables-nft-restore", call.Name)
+	require.Equal(t, []string{"-w", "60", "-T", "filter", "--noflush"}, call.Args)
+	require.Equal(t, "*filter\n-N AZURE-NPM\nCOMMIT\n", call.Stdin)
+}
+
+type fakeTunableDataplane struct {
+	tunables []dataplane.Tunables
+}
+
+func (f *fakeTunableDataplane) UpdateTunables(tunables dataplane.Tunables) error {
+	f.tunables = append(f.tunables, tunables)
+	return nil
+}
+
+func TestReloadConfig(t *testing.T) {
+	running := npmconfig.DefaultConfig
+	updated := running
+	updated.ApplyIntervalInMilliseconds = 100
+	updated.MaxBatchedACLsPerPod = 10
+	updated.MaxPendingNetPols = 0
+	// requires a restart, so it's ignored
+	updated.WindowsNetworkName = "Calico"
+	updated.LogLevels = map[string]string{npmlogger.Policies: "debug"}
+	t.Cleanup(func() { npmlogger.Levels.SetLevel(npmlogger.Policies, zapcore.InfoLevel) })
+
+	require.Equal(t, []string{"WindowsNetworkName"}, npmconfig.RestartRequiredChanges(running, updated))
+
+	dp := &fakeTunableDataplane{}
+	require.NoError(t, reloadConfig(running, updated, dp))
+	require.Equal(t, []dataplane.Tunables{
+		{
+			ApplyMaxBatches:      npmconfig.DefaultConfig.ApplyMaxBatches,
+			ApplyInterval:        100 * time.Millisecond,
+			MaxPendingNetPols:    npmconfig.DefaultConfig.MaxPendingNetPols,
+			NetPolInterval:       time.Duration(npmconfig.DefaultConfig.NetPolInvervalInMilliseconds) * time.Millisecond,
+			MaxBatchedACLsPerPod: 10,
+		},
+	}, dp.tunables)
+	require.Equal(t, zapcore.DebugLevel, npmlogger.Levels.Level(npmlogger.Policies))
+	require.Equal(t, zapcore.InfoLevel, npmlogger.Levels.Level(npmlogger.IPSets))
+}
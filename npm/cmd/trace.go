@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	npmconfig "github.com/Azure/azure-container-networking/npm/config"
+	"github.com/Azure/azure-container-networking/npm/http/api"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/debug"
+	"github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var errSpecifyTraceFiles = fmt.Errorf("must specify either a bundle, or both an iptables save file and an ipset save file, or neither to trace on this node")
+
+func newTraceCmd() *cobra.Command {
+	traceCmd := &cobra.Command{
+		Use:   "trace",
+		Short: "Trace a packet through the NPM iptables chains and ipsets, step by step",
+		Long: "Walks the first packet of a connection from the source to the destination through the AZURE-NPM chains, " +
+			"printing each rule evaluated, the ipset memberships it matched, and the verdict. The state is read from " +
+			"an acncli support bundle, from iptables-save and ipset save files, or from this node and its NPM debug API. " +
+			"The source and destination are IPs, or namespace/pod names when the NPM cache is available.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, _ := cmd.Flags().GetString("src")
+			if src == "" {
+				return fmt.Errorf("%w", errors.ErrSrcNotSpecified)
+			}
+			dst, _ := cmd.Flags().GetString("dst")
+			if dst == "" {
+				return fmt.Errorf("%w", errors.ErrDstNotSpecified)
+			}
+			protocol, _ := cmd.Flags().GetString("protocol")
+			srcPort, _ := cmd.Flags().GetInt("src-port")
+			dstPort, _ := cmd.Flags().GetInt("dst-port")
+			bundleF, _ := cmd.Flags().GetString("bundle")
+			npmCacheF, _ := cmd.Flags().GetString("cache-file")
+			iptableSaveF, _ := cmd.Flags().GetString("iptables-file")
+			ipsetSaveF, _ := cmd.Flags().GetString("ipset-file")
+
+			config := &npmconfig.Config{}
+			err := viper.Unmarshal(config)
+			if err != nil {
+				return fmt.Errorf("failed to load config with err %w", err)
+			}
+
+			c := &debug.Converter{
+				NPMDebugEndpointHost: "http://localhost",
+				NPMDebugEndpointPort: api.DefaultHttpPort,
+				EnableV2NPM:          config.Toggles.EnableV2NPM,
+			}
+
+			var tracer *debug.Tracer
+			switch {
+			case bundleF != "" && iptableSaveF == "" && ipsetSaveF == "" && npmCacheF == "":
+				tracer, err = c.TracerFromBundle(bundleF)
+			case bundleF == "" && iptableSaveF != "" && ipsetSaveF != "":
+				tracer, err = c.TracerFromFiles(npmCacheF, iptableSaveF, ipsetSaveF)
+			case bundleF == "" && iptableSaveF == "" && ipsetSaveF == "" && npmCacheF == "":
+				tracer, err = c.TracerFromNode()
+			default:
+				return errSpecifyTraceFiles
+			}
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			packet := &debug.Packet{Protocol: strings.ToLower(protocol), SrcPort: srcPort, DstPort: dstPort}
+			if packet.SrcIP, err = tracer.ResolveIP(src); err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			if packet.DstIP, err = tracer.ResolveIP(dst); err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			trace, err := tracer.Trace(packet)
+			if trace != nil {
+				if printErr := trace.Print(os.Stdout); printErr != nil {
+					return fmt.Errorf("%w", printErr)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("failed to trace packet: %w", err)
+			}
+			return nil
+		},
+	}
+
+	traceCmd.Flags().StringP("src", "s", "", "set the source IP or namespace/pod")
+	traceCmd.Flags().StringP("dst", "d", "", "set the destination IP or namespace/pod")
+	traceCmd.Flags().StringP("protocol", "p", "tcp", "set the protocol, one of tcp, udp or sctp")
+	traceCmd.Flags().Int("src-port", 0, "set the source port")
+	traceCmd.Flags().Int("dst-port", 0, "set the destination port")
+	traceCmd.Flags().StringP("bundle", "b", "", "Set the acncli support bundle path (optional)")
+	traceCmd.Flags().StringP("iptables-file", "i", "", "Set the iptables-save file path (optional, but required with an ipset save file)")
+	traceCmd.Flags().StringP("ipset-file", "e", "", "Set the ipset save file path (optional, but required with an iptables save file)")
+	traceCmd.Flags().StringP("cache-file", "c", "", "Set the NPM cache file path (optional, names the ipsets and resolves pods)")
+
+	return traceCmd
+}
@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestTraceCmd(t *testing.T) {
+	const (
+		traceIptableSaveFile = "../pkg/dataplane/testdata/trace/iptablesave"
+		traceIPSetSaveFile   = "../pkg/dataplane/testdata/trace/ipsetsave"
+		traceNPMCacheFile    = "../pkg/dataplane/testdata/trace/npmcache.json"
+		ipsetSaveFileFlag    = "-e"
+		bundleFlag           = "-b"
+		dstPortFlag          = "--dst-port"
+		traceCmdString       = "trace"
+	)
+	baseArgs := []string{debugCmdString, traceCmdString}
+	standardArgs := concatArgs(baseArgs, srcFlag, "10.224.0.20", dstFlag, "10.224.0.10", dstPortFlag, "80")
+
+	tests := []*testCases{
+		{
+			name:    "no src or dst",
+			args:    baseArgs,
+			wantErr: true,
+		},
+		{
+			name:    "no dst",
+			args:    concatArgs(baseArgs, srcFlag, "10.224.0.20"),
+			wantErr: true,
+		},
+		{
+			name:    "iptables save file but no ipset save file",
+			args:    concatArgs(standardArgs, iptablesSaveFileFlag, traceIptableSaveFile),
+			wantErr: true,
+		},
+		{
+			name:    "bundle and files",
+			args:    concatArgs(standardArgs, bundleFlag, nonExistingFile, iptablesSaveFileFlag, traceIptableSaveFile, ipsetSaveFileFlag, traceIPSetSaveFile),
+			wantErr: true,
+		},
+		{
+			name:    "bad bundle",
+			args:    concatArgs(standardArgs, bundleFlag, nonExistingFile),
+			wantErr: true,
+		},
+		{
+			name:    "bad ipset save file",
+			args:    concatArgs(standardArgs, iptablesSaveFileFlag, traceIptableSaveFile, ipsetSaveFileFlag, nonExistingFile),
+			wantErr: true,
+		},
+		{
+			name:    "pod names without cache file",
+			args:    concatArgs(baseArgs, srcFlag, "x/c", dstFlag, "y/a", iptablesSaveFileFlag, traceIptableSaveFile, ipsetSaveFileFlag, traceIPSetSaveFile),
+			wantErr: true,
+		},
+		{
+			name:    "correct files",
+			args:    concatArgs(standardArgs, iptablesSaveFileFlag, traceIptableSaveFile, ipsetSaveFileFlag, traceIPSetSaveFile),
+			wantErr: false,
+		},
+		{
+			name: "pod names with cache file",
+			args: concatArgs(baseArgs, srcFlag, "x/c", dstFlag, "y/a", dstPortFlag, "80",
+				iptablesSaveFileFlag, traceIptableSaveFile, ipsetSaveFileFlag, traceIPSetSaveFile, npmCacheFlag, traceNPMCacheFile),
+			wantErr: false,
+		},
+	}
+
+	testCommand(t, tests)
+}
@@ -1,24 +1,45 @@
 package npmconfig
 
-import "github.com/Azure/azure-container-networking/npm/util"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+	"go.uber.org/zap/zapcore"
+)
 
 const (
 	defaultResyncPeriod         = 15
 	defaultApplyMaxBatches      = 100
 	defaultApplyInterval        = 500
 	defaultMaxBatchedACLsPerPod = 30
+	defaultEndpointConcurrency  = 4
 	defaultMaxPendingNetPols    = 100
 	defaultNetPolInterval       = 500
 	defaultListeningPort        = 10091
 	defaultGrpcPort             = 10092
 	defaultGrpcServicePort      = 9002
+	defaultAuditPort            = 10093
 	// ConfigEnvPath is what's used by viper to load config path
 	ConfigEnvPath = "NPM_CONFIG"
 
 	v1 = 1
 	v2 = 2
+
+	// maxBatchedACLsPerPodLimit bounds MaxBatchedACLsPerPod. Each batch is added to an endpoint in one HNS call,
+	// and HNS calls with thousands of ACLs outlast the timeout of the HNS wrapper.
+	maxBatchedACLsPerPodLimit = 1000
+
+	// the WindowsPolicyMode values, which match policies.PolicyManagerMode
+	ipSetPolicyMode = "IPSet"
+	ipPolicyMode    = "IP"
 )
 
+// ErrInvalidConfig is returned by Validate for configs NPM can't run with.
+var ErrInvalidConfig = errors.New("invalid npm config")
+
 // DefaultConfig is the guaranteed configuration NPM can run in out of the box
 var DefaultConfig = Config{
 	ResyncPeriodInMinutes: defaultResyncPeriod,
@@ -30,12 +51,14 @@ var DefaultConfig = Config{
 		Address:     "0.0.0.0",
 		Port:        defaultGrpcPort,
 		ServicePort: defaultGrpcServicePort,
+		AuditPort:   defaultAuditPort,
 	},
 
 	WindowsNetworkName:          util.AzureNetworkName,
 	ApplyMaxBatches:             defaultApplyMaxBatches,
 	ApplyIntervalInMilliseconds: defaultApplyInterval,
 	MaxBatchedACLsPerPod:        defaultMaxBatchedACLsPerPod,
+	EndpointConcurrency:         defaultEndpointConcurrency,
 
 	MaxPendingNetPols:            defaultMaxPendingNetPols,
 	NetPolInvervalInMilliseconds: defaultNetPolInterval,
@@ -54,6 +77,16 @@ var DefaultConfig = Config{
 	},
 }
 
+// reloadableFields are the fields of Config which are applied to the running dataplane when the config file changes.
+var reloadableFields = map[string]struct{}{
+	"ApplyMaxBatches":              {},
+	"ApplyIntervalInMilliseconds":  {},
+	"MaxBatchedACLsPerPod":         {},
+	"MaxPendingNetPols":            {},
+	"NetPolInvervalInMilliseconds": {},
+	"LogLevels":                    {},
+}
+
 type GrpcServerConfig struct {
 	// Address is the address on which the gRPC server will listen
 	Address string `json:"Address,omitempty"`
@@ -61,6 +94,8 @@ type GrpcServerConfig struct {
 	Port int `json:"Port,omitempty"`
 	// ServicePort is the service port for the client to connect to the gRPC server
 	ServicePort int `json:"ServicePort,omitempty"`
+	// AuditPort is the port on which the gRPC server streaming dataplane audit events listens when EnableAuditEvents is set
+	AuditPort int `json:"AuditPort,omitempty"`
 }
 
 type Config struct {
@@ -71,16 +106,41 @@ type Config struct {
 	// WindowsNetworkName can be either 'azure' or 'Calico' (case sensitive).
 	// It can also be the empty string, which results in the default value of 'azure'.
 	WindowsNetworkName string `json:"WindowsNetworkName,omitempty"`
+	// WindowsSecondaryNetworkNames are other HNS networks with pod endpoints, e.g. the transparent network of multi-NIC pods.
+	// Policies are enforced on the endpoints of every network.
+	WindowsSecondaryNetworkNames []string `json:"WindowsSecondaryNetworkNames,omitempty"`
+	// WindowsPolicyMode can be either 'IPSet' or 'IP' (case sensitive). It can also be the empty string, which results in 'IPSet'.
+	// In 'IP' mode, ACLs contain the IPs of ipsets instead of referring to SetPolicies, for HNS versions without SetPolicy support.
+	WindowsPolicyMode string `json:"WindowsPolicyMode,omitempty"`
 	// Apply options for Windows only. Relevant when ApplyInBackground is true.
 	ApplyMaxBatches             int `json:"ApplyDataPlaneMaxBatches,omitempty"`
 	ApplyIntervalInMilliseconds int `json:"ApplyDataPlaneMaxWaitInMilliseconds,omitempty"`
 	// MaxBatchedACLsPerPod is the maximum number of ACLs that can be added to a Pod at once in Windows.
 	// The zero value is valid.
 	// A NetworkPolicy's ACLs are always in the same batch, and there will be at least one NetworkPolicy per batch.
-	MaxBatchedACLsPerPod         int     `json:"MaxBatchedACLsPerPod,omitempty"`
+	MaxBatchedACLsPerPod int `json:"MaxBatchedACLsPerPod,omitempty"`
+	// EndpointConcurrency is the maximum number of endpoints a NetworkPolicy is added to or removed from at once in Windows.
+	EndpointConcurrency          int     `json:"EndpointConcurrency,omitempty"`
 	MaxPendingNetPols            int     `json:"MaxPendingNetPols,omitempty"`
 	NetPolInvervalInMilliseconds int     `json:"NetPolInvervalInMilliseconds,omitempty"`
 	Toggles                      Toggles `json:"Toggles,omitempty"`
+	// NamespaceExclusion configures the namespaces which are ignored by the v2 controllers.
+	NamespaceExclusion NamespaceExclusion `json:"NamespaceExclusion,omitempty"`
+	// LogLevels sets the level of NPM logging components, e.g. {"default": "info", "policies": "debug"}.
+	// The components are npm, policies, ipsets, controllers, and dataplane. The levels can be changed at runtime through the HTTP debug API.
+	LogLevels map[string]string `json:"LogLevels,omitempty"`
+	// DryRunLogPath is the file the dataplane changes are recorded to when EnableDryRun is set. It defaults to stdout.
+	DryRunLogPath string `json:"DryRunLogPath,omitempty"`
+}
+
+// NamespaceExclusion lists the namespaces which will never have network policies, e.g. kube-system.
+// Pods and network policies in excluded namespaces are not added to ipsets or the dataplane,
+// so they can't be selected by network policies in other namespaces.
+type NamespaceExclusion struct {
+	// Namespaces are the names of the excluded namespaces.
+	Namespaces []string `json:"Namespaces,omitempty"`
+	// LabelSelector excludes the namespaces whose labels match it. Changes to namespace labels are honored dynamically.
+	LabelSelector string `json:"LabelSelector,omitempty"`
 }
 
 type Toggles struct {
@@ -94,6 +154,25 @@ type Toggles struct {
 	ApplyInBackground bool
 	// NetPolInBackground
 	NetPolInBackground bool
+	// EnableDryRun records the commands and HNS mutations of the v2 dataplane as JSON lines instead of making them
+	EnableDryRun bool
+	// EnableAuditEvents streams the policy, ipset membership, and endpoint changes of the v2 dataplane to gRPC watchers
+	EnableAuditEvents bool
+	// EnableNftables programs policies and ipsets with nftables instead of iptables and ipset (Linux v2 only)
+	EnableNftables bool
+	// AuditMode translates network policies and logs the iptables rules or HNS ACLs they would produce, and the
+	// ipsets or HNS SetPolicies they refer to, instead of programming them, so that policies can be validated before
+	// they are enforced (v2 only)
+	AuditMode bool
+	// RollbackPolicyOnFailure removes the ACLs of a NetworkPolicy from the endpoints it was added to
+	// when adding it to other endpoints fails, so that each policy is applied to all or none of its endpoints (Windows only)
+	RollbackPolicyOnFailure bool
+	// WatchHNSEndpoints subscribes to HNS endpoint notifications, so that policies are applied to new pods as soon as
+	// their endpoints are attached instead of on the next background apply (Windows only)
+	WatchHNSEndpoints bool
+	// EnableAdminNetworkPolicy watches AdminNetworkPolicies and the BaselineAdminNetworkPolicy of policy.networking.k8s.io
+	// and enforces them before and after NetworkPolicies (v2 only, not supported with nftables)
+	EnableAdminNetworkPolicy bool
 }
 
 type Flags struct {
@@ -107,3 +186,95 @@ func (c Config) NPMVersion() int {
 	}
 	return v1
 }
+
+// Validate checks the invariants of the config which would otherwise fail deep inside the dataplane,
+// and returns an error listing every violation with the field to fix.
+// Zero values are valid for the numeric fields, since NPM falls back to their defaults.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidConfig}, args...)...))
+	}
+
+	if c.Toggles.NetPolInBackground {
+		if c.NetPolInvervalInMilliseconds < 0 {
+			invalid("NetPolInvervalInMilliseconds must not be negative when Toggles.NetPolInBackground is set, got %d", c.NetPolInvervalInMilliseconds)
+		}
+		if c.MaxPendingNetPols < 0 {
+			invalid("MaxPendingNetPols must not be negative when Toggles.NetPolInBackground is set, got %d", c.MaxPendingNetPols)
+		}
+	}
+	if c.Toggles.ApplyInBackground {
+		if c.ApplyIntervalInMilliseconds < 0 {
+			invalid("ApplyDataPlaneMaxWaitInMilliseconds must not be negative when Toggles.ApplyInBackground is set, got %d", c.ApplyIntervalInMilliseconds)
+		}
+		if c.ApplyMaxBatches < 0 {
+			invalid("ApplyDataPlaneMaxBatches must not be negative when Toggles.ApplyInBackground is set, got %d", c.ApplyMaxBatches)
+		}
+	}
+
+	if c.Toggles.EnableAdminNetworkPolicy {
+		if !c.Toggles.EnableV2NPM {
+			invalid("Toggles.EnableAdminNetworkPolicy requires Toggles.EnableV2NPM")
+		}
+		if c.Toggles.EnableNftables {
+			invalid("Toggles.EnableAdminNetworkPolicy is not supported with Toggles.EnableNftables")
+		}
+	}
+
+	switch c.WindowsNetworkName {
+	case "", util.AzureNetworkName, util.CalicoNetworkName:
+	default:
+		invalid("WindowsNetworkName must be %q or %q (case sensitive), got %q", util.AzureNetworkName, util.CalicoNetworkName, c.WindowsNetworkName)
+	}
+	for _, name := range c.WindowsSecondaryNetworkNames {
+		if name == "" {
+			invalid("WindowsSecondaryNetworkNames must not contain empty names")
+			break
+		}
+	}
+
+	switch c.WindowsPolicyMode {
+	case "", ipSetPolicyMode, ipPolicyMode:
+	default:
+		invalid("WindowsPolicyMode must be %q or %q (case sensitive), got %q", ipSetPolicyMode, ipPolicyMode, c.WindowsPolicyMode)
+	}
+
+	if c.MaxBatchedACLsPerPod < 0 || c.MaxBatchedACLsPerPod > maxBatchedACLsPerPodLimit {
+		invalid("MaxBatchedACLsPerPod must be between 0 and %d, got %d", maxBatchedACLsPerPodLimit, c.MaxBatchedACLsPerPod)
+	}
+	if c.EndpointConcurrency < 0 {
+		invalid("EndpointConcurrency must not be negative, got %d", c.EndpointConcurrency)
+	}
+
+	components := make([]string, 0, len(c.LogLevels))
+	for component := range c.LogLevels {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		if _, err := zapcore.ParseLevel(c.LogLevels[component]); err != nil {
+			invalid("LogLevels of component %q: %v", component, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RestartRequiredChanges returns the names of the fields which differ between the configs
+// and only take effect when NPM restarts.
+func RestartRequiredChanges(running, updated Config) []string {
+	changed := []string{}
+	runningVal := reflect.ValueOf(running)
+	updatedVal := reflect.ValueOf(updated)
+	for i := 0; i < runningVal.NumField(); i++ {
+		name := runningVal.Type().Field(i).Name
+		if _, ok := reloadableFields[name]; ok {
+			continue
+		}
+		if !reflect.DeepEqual(runningVal.Field(i).Interface(), updatedVal.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
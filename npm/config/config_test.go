@@ -0,0 +1,54 @@
+package npmconfig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, DefaultConfig.Validate())
+	require.NoError(t, Config{}.Validate(), "zero values fall back to defaults")
+
+	config := DefaultConfig
+	config.NetPolInvervalInMilliseconds = -1
+	config.ApplyMaxBatches = -1
+	config.WindowsNetworkName = "calico"
+	config.WindowsPolicyMode = "ipset"
+	config.MaxBatchedACLsPerPod = maxBatchedACLsPerPodLimit + 1
+	config.LogLevels = map[string]string{"default": "info", "policies": "verbose"}
+
+	err := config.Validate()
+	require.ErrorIs(t, err, ErrInvalidConfig)
+	msg := err.Error()
+	for _, field := range []string{
+		"NetPolInvervalInMilliseconds",
+		"ApplyDataPlaneMaxBatches",
+		"WindowsNetworkName",
+		"WindowsPolicyMode",
+		"MaxBatchedACLsPerPod",
+		`LogLevels of component "policies"`,
+	} {
+		require.Contains(t, msg, field)
+	}
+	require.Len(t, strings.Split(msg, "\n"), 6, "one line per violation")
+
+	// the intervals only matter when their background loops are enabled
+	config = DefaultConfig
+	config.Toggles.NetPolInBackground = false
+	config.NetPolInvervalInMilliseconds = -1
+	require.NoError(t, config.Validate())
+
+	var joined interface{ Unwrap() []error }
+	config.Toggles.NetPolInBackground = true
+	require.True(t, errors.As(config.Validate(), &joined))
+	require.Len(t, joined.Unwrap(), 1)
+
+	config = DefaultConfig
+	config.Toggles.EnableAdminNetworkPolicy = true
+	require.NoError(t, config.Validate())
+	config.Toggles.EnableNftables = true
+	require.ErrorContains(t, config.Validate(), "Toggles.EnableAdminNetworkPolicy")
+}
@@ -10,15 +10,17 @@ import (
 	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
 	controllersv2 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v2"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/pkg/models"
 	"github.com/Azure/azure-container-networking/npm/pkg/transport"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/klog"
 )
 
+var logger = npmlogger.For(npmlogger.NPM)
+
 var aiMetadata string //nolint // aiMetadata is set in Makefile
 
 type NetworkPolicyServer struct {
@@ -53,7 +55,7 @@ func NewNetworkPolicyServer(
 	npmVersion string,
 	k8sServerVersion *version.Info,
 ) (*NetworkPolicyServer, error) {
-	klog.Infof("API server version: %+v AI metadata %+v", k8sServerVersion, aiMetadata)
+	logger.Infof("API server version: %+v AI metadata %+v", k8sServerVersion, aiMetadata)
 
 	if informerFactory == nil {
 		return nil, ErrInformerFactoryNil
@@ -88,10 +90,14 @@ func NewNetworkPolicyServer(
 		},
 	}
 
+	exclusion, err := common.NewNamespaceExclusion(config.NamespaceExclusion.Namespaces, config.NamespaceExclusion.LabelSelector, n.NsInformer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create namespace exclusion")
+	}
 	n.NpmNamespaceCacheV2 = &controllersv2.NpmNamespaceCache{NsMap: make(map[string]*common.Namespace)}
-	n.PodControllerV2 = controllersv2.NewPodController(n.PodInformer, dp, n.NpmNamespaceCacheV2)
-	n.NamespaceControllerV2 = controllersv2.NewNamespaceController(n.NsInformer, dp, n.NpmNamespaceCacheV2)
-	n.NetPolControllerV2 = controllersv2.NewNetworkPolicyController(n.NpInformer, dp)
+	n.PodControllerV2 = controllersv2.NewPodController(n.PodInformer, dp, n.NpmNamespaceCacheV2, exclusion)
+	n.NamespaceControllerV2 = controllersv2.NewNamespaceController(n.NsInformer, dp, n.NpmNamespaceCacheV2, exclusion)
+	n.NetPolControllerV2 = controllersv2.NewNetworkPolicyController(n.NpInformer, dp, exclusion)
 
 	return n, nil
 }
@@ -6,6 +6,9 @@ const (
 	NodeMetricsPath    = "/node-metrics"
 	ClusterMetricsPath = "/cluster-metrics"
 	NPMMgrPath         = "/npm/v1/debug/manager"
+	NPMTracePath       = "/npm/v1/debug/trace"
+	NPMDataplanePath   = "/npm/v1/debug/dataplane"
+	NPMLogLevelsPath   = "/npm/v1/debug/loglevels"
 )
 
 type DescribeIPSetRequest struct{}
@@ -2,26 +2,46 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	_ "net/http/pprof"
+	"net/url"
+	"strconv"
+	"strings"
 
-	"github.com/Azure/azure-container-networking/log"
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
 	"github.com/Azure/azure-container-networking/npm/http/api"
 	"github.com/Azure/azure-container-networking/npm/metrics"
-	"k8s.io/klog"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 
 	"github.com/gorilla/mux"
 )
 
+var logger = npmlogger.For(npmlogger.NPM)
+
+var errInvalidTraceQuery = errors.New("invalid trace query")
+
+// FlowTracer reports which NetworkPolicies and ACLs allow or drop a flow.
+type FlowTracer interface {
+	TraceFlow(flow *policies.Flow) (*policies.FlowTrace, error)
+}
+
+// DataplaneDumper dumps the ipsets, policies, and endpoints in the dataplane cache.
+type DataplaneDumper interface {
+	DumpState() *dataplane.StateDump
+}
+
 type NPMRestServer struct {
 	listeningAddress string
 	router           *mux.Router
 }
 
-func NPMRestServerListenAndServe(config npmconfig.Config, npmEncoder json.Marshaler) {
+func NPMRestServerListenAndServe(config npmconfig.Config, npmEncoder json.Marshaler, flowTracer FlowTracer, dataplaneDumper DataplaneDumper) {
 	rs := NPMRestServer{}
 
 	rs.router = mux.NewRouter()
@@ -38,6 +58,21 @@ func NPMRestServerListenAndServe(config npmconfig.Config, npmEncoder json.Marsha
 		rs.router.Handle(api.NPMMgrPath, rs.npmCacheHandler(npmEncoder)).Methods(http.MethodGet)
 	}
 
+	// the nil check is for NPM v1 and fan-out npm, which have no dataplane to trace
+	if config.Toggles.EnableHTTPDebugAPI && flowTracer != nil {
+		rs.router.Handle(api.NPMTracePath, rs.traceHandler(flowTracer)).Methods(http.MethodGet)
+	}
+
+	// the nil check is for NPM v1 and fan-out npm, which have no dataplane to dump
+	if config.Toggles.EnableHTTPDebugAPI && dataplaneDumper != nil {
+		rs.router.Handle(api.NPMDataplanePath, rs.dataplaneHandler(dataplaneDumper)).Methods(http.MethodGet)
+	}
+
+	// the log levels of the components are reported on GET and changed by a PUT of the JSON encoded levels
+	if config.Toggles.EnableHTTPDebugAPI {
+		rs.router.Handle(api.NPMLogLevelsPath, npmlogger.Levels).Methods(http.MethodGet, http.MethodPut)
+	}
+
 	if config.Toggles.EnablePprof {
 		rs.router.PathPrefix("/debug/").Handler(http.DefaultServeMux)
 		rs.router.HandleFunc("/debug/pprof/", pprof.Index)
@@ -57,8 +92,8 @@ func NPMRestServerListenAndServe(config npmconfig.Config, npmEncoder json.Marsha
 		Addr:    rs.listeningAddress,
 	}
 
-	klog.Infof("Starting NPM HTTP API on %s... ", rs.listeningAddress)
-	klog.Errorf("Failed to start NPM HTTP Server with error: %+v", srv.ListenAndServe())
+	logger.Infof("Starting NPM HTTP API on %s... ", rs.listeningAddress)
+	logger.Errorf("Failed to start NPM HTTP Server with error: %+v", srv.ListenAndServe())
 }
 
 func (n *NPMRestServer) npmCacheHandler(npmCacheEncoder json.Marshaler) http.Handler {
@@ -70,7 +105,77 @@ func (n *NPMRestServer) npmCacheHandler(npmCacheEncoder json.Marshaler) http.Han
 		}
 		_, err = w.Write(b)
 		if err != nil {
-			log.Errorf("failed to write resp: %v", err)
+			logger.Errorf("failed to write resp: %v", err)
 		}
 	})
 }
+
+// traceHandler traces the flow of the srcIP, dstIP, dstPort and proto query parameters through the NetworkPolicies.
+// The protocol defaults to TCP, and the port is only needed for protocols with ports.
+func (n *NPMRestServer) traceHandler(flowTracer FlowTracer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flow, err := parseTraceQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		trace, err := flowTracer.TraceFlow(flow)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		b, err := json.Marshal(trace)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, err = w.Write(b)
+		if err != nil {
+			logger.Errorf("failed to write resp: %v", err)
+		}
+	})
+}
+
+// dataplaneHandler dumps the state of the dataplane cache.
+func (n *NPMRestServer) dataplaneHandler(dataplaneDumper DataplaneDumper) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.Marshal(dataplaneDumper.DumpState())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, err = w.Write(b)
+		if err != nil {
+			logger.Errorf("failed to write resp: %v", err)
+		}
+	})
+}
+
+func parseTraceQuery(query url.Values) (*policies.Flow, error) {
+	flow := &policies.Flow{
+		SrcIP:    net.ParseIP(query.Get("srcIP")),
+		DstIP:    net.ParseIP(query.Get("dstIP")),
+		Protocol: policies.TCP,
+	}
+	if flow.SrcIP == nil {
+		return nil, fmt.Errorf("%w: srcIP %q is not an IP", errInvalidTraceQuery, query.Get("srcIP"))
+	}
+	if flow.DstIP == nil {
+		return nil, fmt.Errorf("%w: dstIP %q is not an IP", errInvalidTraceQuery, query.Get("dstIP"))
+	}
+
+	if proto := query.Get("proto"); proto != "" {
+		flow.Protocol = policies.Protocol(strings.ToUpper(proto))
+	}
+	switch flow.Protocol {
+	case policies.TCP, policies.UDP, policies.SCTP:
+		port, err := strconv.Atoi(query.Get("dstPort"))
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("%w: dstPort %q is not a port", errInvalidTraceQuery, query.Get("dstPort"))
+		}
+		flow.DstPort = port
+	default:
+		// protocols without ports, like ICMP, only match ACLs for any protocol
+	}
+	return flow, nil
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,7 +11,11 @@ import (
 	"github.com/Azure/azure-container-networking/npm"
 	"github.com/Azure/azure-container-networking/npm/http/api"
 	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetNPMCacheHandler(t *testing.T) {
@@ -55,3 +60,91 @@ func TestGetNPMCacheHandler(t *testing.T) {
 
 	assert.Exactly(expected, actual)
 }
+
+type fakeFlowTracer struct {
+	flow *policies.Flow
+}
+
+func (f *fakeFlowTracer) TraceFlow(flow *policies.Flow) (*policies.FlowTrace, error) {
+	f.flow = flow
+	return &policies.FlowTrace{Flow: *flow, Verdict: policies.FlowAllowed}, nil
+}
+
+func TestTraceHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantFlow   *policies.Flow
+	}{
+		{
+			name:       "tcp flow",
+			query:      "srcIP=10.0.0.1&dstIP=10.0.0.2&dstPort=80",
+			wantStatus: http.StatusOK,
+			wantFlow:   &policies.Flow{SrcIP: net.ParseIP("10.0.0.1"), DstIP: net.ParseIP("10.0.0.2"), DstPort: 80, Protocol: policies.TCP},
+		},
+		{
+			name:       "udp flow",
+			query:      "srcIP=10.0.0.1&dstIP=10.0.0.2&dstPort=53&proto=udp",
+			wantStatus: http.StatusOK,
+			wantFlow:   &policies.Flow{SrcIP: net.ParseIP("10.0.0.1"), DstIP: net.ParseIP("10.0.0.2"), DstPort: 53, Protocol: policies.UDP},
+		},
+		{
+			name:       "flow without ports",
+			query:      "srcIP=10.0.0.1&dstIP=10.0.0.2&proto=icmp",
+			wantStatus: http.StatusOK,
+			wantFlow:   &policies.Flow{SrcIP: net.ParseIP("10.0.0.1"), DstIP: net.ParseIP("10.0.0.2"), Protocol: policies.Protocol("ICMP")},
+		},
+		{
+			name:       "invalid src IP",
+			query:      "srcIP=pod&dstIP=10.0.0.2&dstPort=80",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing port",
+			query:      "srcIP=10.0.0.1&dstIP=10.0.0.2",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := &fakeFlowTracer{}
+			n := &NPMRestServer{}
+			req := httptest.NewRequest(http.MethodGet, api.NPMTracePath+"?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			n.traceHandler(tracer).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.Equal(t, tt.wantFlow, tracer.flow)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			trace := &policies.FlowTrace{}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), trace))
+			require.Equal(t, policies.FlowAllowed, trace.Verdict)
+		})
+	}
+}
+
+type fakeDataplaneDumper struct{}
+
+func (fakeDataplaneDumper) DumpState() *dataplane.StateDump {
+	return &dataplane.StateDump{
+		IPSets:      []*ipsets.IPSetDump{{Name: "ns-x", Kind: ipsets.HashSet, Members: map[string]string{"10.0.0.1": "x/a"}}},
+		Policies:    []*policies.NPMNetworkPolicy{{PolicyKey: "x/deny"}},
+		StaleChains: []string{"AZURE-NPM-INGRESS-123"},
+	}
+}
+
+func TestDataplaneHandler(t *testing.T) {
+	n := &NPMRestServer{}
+	req := httptest.NewRequest(http.MethodGet, api.NPMDataplanePath, nil)
+	rr := httptest.NewRecorder()
+	n.dataplaneHandler(fakeDataplaneDumper{}).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	state := &dataplane.StateDump{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), state))
+	require.Equal(t, fakeDataplaneDumper{}.DumpState(), state)
+}
@@ -12,13 +12,15 @@ import (
 	"sync"
 	"syscall"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
 	"github.com/pkg/errors"
 	utilexec "k8s.io/utils/exec"
 )
 
+var logger = npmlogger.For(npmlogger.IPSets)
+
 // ReferCountOperation is used to indicate whether ipset refer count should be increased or decreased.
 type ReferCountOperation bool
 
@@ -205,7 +207,7 @@ func (ipsMgr *IpsetManager) run(entry *ipsEntry) (int, error) {
 	cmdArgs := append([]string{entry.operationFlag, util.IpsetExistFlag, entry.set}, entry.spec...)
 	cmdArgs = util.DropEmptyFields(cmdArgs)
 
-	log.Logf("Executing ipset command %s %v", cmdName, cmdArgs)
+	logger.Infof("Executing ipset command %s %v", cmdName, cmdArgs)
 
 	cmd := ipsMgr.exec.Command(cmdName, cmdArgs...)
 	output, err := cmd.CombinedOutput()
@@ -237,7 +239,7 @@ func (ipsMgr *IpsetManager) CreateListNoLock(listName string) error {
 		set:           util.GetHashedName(listName),
 		spec:          []string{util.IpsetSetListFlag},
 	}
-	log.Logf("Creating List: %+v", entry)
+	logger.Infof("Creating List: %+v", entry)
 	timer := metrics.StartNewTimer()
 	errCode, err := ipsMgr.run(entry)
 	metrics.RecordIPSetExecTime(timer) // record execution time regardless of failure
@@ -271,7 +273,7 @@ func (ipsMgr *IpsetManager) CreateSetNoLock(setName string, spec []string) error
 		set:  util.GetHashedName(setName),
 		spec: spec,
 	}
-	log.Logf("Creating Set: %+v", entry)
+	logger.Infof("Creating Set: %+v", entry)
 
 	// (TODO): need to differentiate errCode handler
 	// since errCode can be one in case of "set with the same name already exists"
@@ -468,7 +470,7 @@ func (ipsMgr *IpsetManager) AddToSetNoLock(setName, ip, spec, podKey string) err
 		// make sure we have updated the podKey in case it gets changed
 		cachedPodKey := ipsMgr.setMap[setName].elements[ip]
 		if cachedPodKey != podKey {
-			log.Logf("AddToSet: PodOwner has changed for Ip: %s, setName:%s, Old podKey: %s, new podKey: %s. Replace context with new PodOwner.",
+			logger.Infof("AddToSet: PodOwner has changed for Ip: %s, setName:%s, Old podKey: %s, new podKey: %s. Replace context with new PodOwner.",
 				ip, setName, cachedPodKey, podKey)
 
 			ipsMgr.setMap[setName].elements[ip] = podKey
@@ -532,7 +534,7 @@ func (ipsMgr *IpsetManager) DeleteFromSet(setName, ip, podKey string) error {
 
 	ipSet, exists := ipsMgr.setMap[setName]
 	if !exists {
-		log.Logf("ipset with name %s not found", setName)
+		logger.Infof("ipset with name %s not found", setName)
 		return nil
 	}
 
@@ -550,7 +552,7 @@ func (ipsMgr *IpsetManager) DeleteFromSet(setName, ip, podKey string) error {
 		// in case the IP belongs to a new Pod, then ignore this Delete call as this might be stale
 		cachedPodKey := ipSet.elements[ip]
 		if cachedPodKey != podKey {
-			log.Logf("DeleteFromSet: PodOwner has changed for Ip: %s, setName:%s, Old podKey: %s, new podKey: %s. Ignore the delete as this is stale update",
+			logger.Infof("DeleteFromSet: PodOwner has changed for Ip: %s, setName:%s, Old podKey: %s, new podKey: %s. Ignore the delete as this is stale update",
 				ip, setName, cachedPodKey, podKey)
 
 			return nil
@@ -588,7 +590,7 @@ func (ipsMgr *IpsetManager) DeleteFromSet(setName, ip, podKey string) error {
 
 // DestroyNpmIpsets destroys only ipsets created by NPM
 func (ipsMgr *IpsetManager) DestroyNpmIpsets() error {
-	log.Logf("Azure-NPM creating, cleaning existing Azure NPM IPSets")
+	logger.Infof("Azure-NPM creating, cleaning existing Azure NPM IPSets")
 
 	ipsMgr.Lock()
 	defer ipsMgr.Unlock()
@@ -614,7 +616,7 @@ func (ipsMgr *IpsetManager) DestroyNpmIpsets() error {
 	ipsetRegexSlice := re.FindAllSubmatch(reply, -1)
 
 	if len(ipsetRegexSlice) == 0 {
-		log.Logf("No Azure-NPM IPsets are found in the Node.")
+		logger.Infof("No Azure-NPM IPsets are found in the Node.")
 		return nil
 	}
 
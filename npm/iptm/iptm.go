@@ -10,14 +10,16 @@ import (
 	"strings"
 	"time"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
 	"github.com/Azure/azure-container-networking/npm/util/ioutil"
 	utilexec "k8s.io/utils/exec"
 	// utiliptables "k8s.io/kubernetes/pkg/util/iptables"
 )
 
+var logger = npmlogger.For(npmlogger.Policies)
+
 const (
 	iptablesErrDoesNotExist     int = 1
 	reconcileChainTimeInMinutes     = 5
@@ -93,7 +95,7 @@ func NewIptablesManager(exec utilexec.Interface, io ioshim, placeAzureChainFirst
 
 // InitNpmChains initializes Azure NPM chains in iptables.
 func (iptMgr *IptablesManager) InitNpmChains() error {
-	log.Logf("Initializing AZURE-NPM chains.")
+	logger.Infof("Initializing AZURE-NPM chains.")
 
 	if err := iptMgr.addAllChains(); err != nil {
 		return err
@@ -173,7 +175,7 @@ func (iptMgr *IptablesManager) UninitNpmChains() error {
 
 // Add adds a rule in iptables.
 func (iptMgr *IptablesManager) Add(entry *IptEntry) error {
-	log.Logf("Adding iptables entry: %+v.", entry)
+	logger.Infof("Adding iptables entry: %+v.", entry)
 
 	// Since there is a RETURN statement added to each DROP chain, we need to make sure
 	// any new DROP rule added to ingress or egress DROPS chain is added at the BOTTOM
@@ -197,7 +199,7 @@ func (iptMgr *IptablesManager) Add(entry *IptEntry) error {
 
 // Delete removes a rule in iptables.
 func (iptMgr *IptablesManager) Delete(entry *IptEntry) error {
-	log.Logf("Deleting iptables entry: %+v", entry)
+	logger.Infof("Deleting iptables entry: %+v", entry)
 
 	exists, err := iptMgr.exists(entry)
 	if err != nil {
@@ -407,7 +409,7 @@ func (iptMgr *IptablesManager) addChain(chain string) error {
 	errCode, err := iptMgr.run(entry)
 	if err != nil {
 		if errCode == iptablesErrDoesNotExist {
-			log.Logf("Chain already exists %s.", entry.Chain)
+			logger.Infof("Chain already exists %s.", entry.Chain)
 			return nil
 		}
 
@@ -470,7 +472,7 @@ func (iptMgr *IptablesManager) deleteChain(chain string) error {
 	errCode, err := iptMgr.run(entry)
 	if err != nil {
 		if errCode == iptablesErrDoesNotExist {
-			log.Logf("Chain doesn't exist %s.", entry.Chain)
+			logger.Infof("Chain doesn't exist %s.", entry.Chain)
 			return nil
 		}
 
@@ -495,7 +497,7 @@ func (iptMgr *IptablesManager) run(entry *IptEntry) (int, error) {
 	cmdArgs := append([]string{util.IptablesWaitFlag, entry.LockWaitTimeInSeconds, iptMgr.OperationFlag, entry.Chain}, entry.Specs...)
 
 	if iptMgr.OperationFlag != util.IptablesCheckFlag {
-		log.Logf("Executing iptables command %s %v", cmdName, cmdArgs)
+		logger.Infof("Executing iptables command %s %v", cmdName, cmdArgs)
 	}
 
 	output, err := iptMgr.exec.Command(cmdName, cmdArgs...).CombinedOutput()
@@ -22,6 +22,13 @@ func IncIPTablesRestoreFailures(op OperationKind) {
 	iptablesRestoreFailures.With(labels).Inc()
 }
 
+// IncIPTablesRepairs counts the rules or chains of the given kind which reconciling re-inserted.
+func IncIPTablesRepairs(kind IPTablesRepairKind, count int) {
+	iptablesRepairs.With(prometheus.Labels{
+		repairLabel: string(kind),
+	}).Add(float64(count))
+}
+
 func TotalIPTablesRestoreLatencyCalls(op OperationKind) (int, error) {
 	return histogramVecCount(itpablesRestoreLatency, prometheus.Labels{
 		operationLabel: string(op),
@@ -41,3 +48,9 @@ func TotalIPTablesRestoreFailures(op OperationKind) (int, error) {
 		operationLabel: string(op),
 	}))
 }
+
+func TotalIPTablesRepairs(kind IPTablesRepairKind) (int, error) {
+	return counterValue(iptablesRepairs.With(prometheus.Labels{
+		repairLabel: string(kind),
+	}))
+}
@@ -47,3 +47,17 @@ func TestIncIPTablesRestoreFailures(t *testing.T) {
 	require.Nil(t, err, "failed to get metric")
 	require.Equal(t, 1, count, "should have failed to update once")
 }
+
+func TestIncIPTablesRepairs(t *testing.T) {
+	IncIPTablesRepairs(ForwardJumpRepair, 1)
+	IncIPTablesRepairs(PolicyChainRepair, 2)
+	IncIPTablesRepairs(ForwardJumpRepair, 1)
+
+	count, err := TotalIPTablesRepairs(ForwardJumpRepair)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 2, count, "should have repaired the jump twice")
+
+	count, err = TotalIPTablesRepairs(PolicyChainRepair)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 2, count, "should have repaired two policy chains")
+}
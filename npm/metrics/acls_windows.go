@@ -18,6 +18,23 @@ func IncACLFailures(op OperationKind) {
 	aclFailures.With(labels).Inc()
 }
 
+// IncOrphanedACLsRemovedBy should be used in Windows DP to record the number of ACLs of deleted policies removed from endpoints.
+func IncOrphanedACLsRemovedBy(amount int) {
+	orphanedACLsRemoved.Add(float64(amount))
+}
+
+// RecordACLBatch should be used in Windows DP to record the number of rules and the latency of a batch of ACLs
+// applied to an endpoint when adding all policies of a pod.
+func RecordACLBatch(timer *Timer, numRules int) {
+	aclBatchRules.Observe(float64(numRules))
+	aclBatchLatency.Observe(timer.timeElapsedSeconds())
+}
+
+// RecordACLBatchesPerPod should be used in Windows DP to record the number of batches needed to add all policies of a pod.
+func RecordACLBatchesPerPod(numBatches int) {
+	aclBatchesPerPod.Observe(float64(numBatches))
+}
+
 func TotalACLLatencyCalls(op OperationKind) (int, error) {
 	return histogramVecCount(aclLatency, prometheus.Labels{
 		operationLabel: string(op),
@@ -29,3 +46,20 @@ func TotalACLFailures(op OperationKind) (int, error) {
 		operationLabel: string(op),
 	}))
 }
+
+func TotalOrphanedACLsRemoved() (int, error) {
+	return counterValue(orphanedACLsRemoved)
+}
+
+func TotalACLBatches() (int, error) {
+	return histogramCount(aclBatchLatency)
+}
+
+func TotalACLBatchRules() (int, error) {
+	sum, err := histogramSum(aclBatchRules)
+	return int(sum), err
+}
+
+func TotalACLBatchesPerPodCalls() (int, error) {
+	return histogramCount(aclBatchesPerPod)
+}
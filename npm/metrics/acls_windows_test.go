@@ -37,3 +37,31 @@ func TestIncACLFailures(t *testing.T) {
 	require.Nil(t, err, "failed to get metric")
 	require.Equal(t, 1, count, "should have failed to update once")
 }
+
+func TestIncOrphanedACLsRemovedBy(t *testing.T) {
+	IncOrphanedACLsRemovedBy(2)
+	IncOrphanedACLsRemovedBy(0)
+	IncOrphanedACLsRemovedBy(1)
+
+	count, err := TotalOrphanedACLsRemoved()
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 3, count, "should have removed three orphaned ACLs")
+}
+
+func TestRecordACLBatch(t *testing.T) {
+	RecordACLBatch(StartNewTimer(), 3)
+	RecordACLBatch(StartNewTimer(), 2)
+	RecordACLBatchesPerPod(2)
+
+	count, err := TotalACLBatches()
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 2, count, "should have recorded two batches")
+
+	count, err = TotalACLBatchRules()
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 5, count, "should have recorded five rules")
+
+	count, err = TotalACLBatchesPerPodCalls()
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 1, count, "should have recorded one pod")
+}
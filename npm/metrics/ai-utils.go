@@ -6,21 +6,25 @@ import (
 	"time"
 
 	"github.com/Azure/azure-container-networking/aitelemetry"
-	"github.com/Azure/azure-container-networking/log"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
-	"k8s.io/klog"
 )
 
+var logger = npmlogger.For(npmlogger.NPM)
+
 var (
 	th         aitelemetry.TelemetryHandle
+	heartbeat  *aitelemetry.Heartbeat
 	npmVersion int
 	PrintLog   = true
 	DonotPrint = false
 )
 
-// CreateTelemetryHandle creates a handler to initialize AI telemetry
-func CreateTelemetryHandle(npmVersionNum int, imageVersion, aiMetadata string) error {
+// CreateTelemetryHandle creates a handler to initialize AI telemetry.
+// toggles are the NPM feature toggles reported with each heartbeat.
+func CreateTelemetryHandle(npmVersionNum int, imageVersion, aiMetadata string, toggles map[string]bool) error {
 	npmVersion = npmVersionNum
+	heartbeat = aitelemetry.NewHeartbeat(util.AzureNpmFlag, imageVersion, toggles, nil)
 	aiConfig := aitelemetry.AIConfig{
 		AppName:                   util.AzureNpmFlag,
 		AppVersion:                imageVersion,
@@ -36,7 +40,7 @@ func CreateTelemetryHandle(npmVersionNum int, imageVersion, aiMetadata string) e
 	for i := 0; i < util.AiInitializeRetryCount; i++ {
 		th, err = aitelemetry.NewAITelemetry("", aiMetadata, aiConfig)
 		if err != nil {
-			log.Logf("Failed to init AppInsights with err: %+v for %d time", err, i+1)
+			logger.Infof("Failed to init AppInsights with err: %+v for %d time", err, i+1)
 			time.Sleep(time.Minute * time.Duration(util.AiInitializeRetryInMin))
 		} else {
 			break
@@ -48,7 +52,7 @@ func CreateTelemetryHandle(npmVersionNum int, imageVersion, aiMetadata string) e
 	}
 
 	if th != nil {
-		log.Logf("Initialized AppInsights handle")
+		logger.Infof("Initialized AppInsights handle")
 	}
 
 	return nil
@@ -70,7 +74,7 @@ func SendErrorLogAndMetric(operationID int, format string, args ...interface{})
 
 	// Send error logs
 	msg := fmt.Sprintf(format, args...)
-	log.Errorf(msg)
+	logger.Error(msg)
 	SendLog(operationID, msg, DonotPrint)
 }
 
@@ -91,7 +95,7 @@ func SendLog(operationID int, msg string, printLog bool) {
 		CustomDimensions: make(map[string]string),
 	}
 	if printLog {
-		klog.Infof(msg)
+		logger.Info(msg)
 	}
 	if th == nil {
 		return
@@ -106,7 +110,15 @@ func SendHeartbeatWithNumPolicies() {
 		message = fmt.Sprintf("info: NPM heartbeat. Current num policies: %d", numPolicies)
 	} else {
 		message = fmt.Sprintf("warn: NPM hearbeat. Couldn't get number of policies for telemetry log: %v", err)
-		klog.Warning(message)
+		logger.Warn(message)
 	}
 	SendLog(util.NpmID, message, DonotPrint)
+
+	if heartbeat != nil {
+		metric := heartbeat.Metric()
+		if err == nil {
+			metric.CustomDimensions[util.NumPoliciesDimension] = strconv.Itoa(numPolicies)
+		}
+		SendMetric(metric)
+	}
 }
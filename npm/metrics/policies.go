@@ -1,5 +1,7 @@
 package metrics
 
+import "github.com/prometheus/client_golang/prometheus"
+
 // IncNumPolicies increments the number of policies.
 func IncNumPolicies() {
 	numPolicies.Inc()
@@ -39,3 +41,91 @@ func GetControllerPolicyExecCount(op OperationKind, hadError bool) (int, error)
 	}
 	return getCountVecValue(controllerPolicyExecTime, getCRUDExecTimeLabels(op, hadError))
 }
+
+// namespacePolicyCounts are the values of the per-namespace policy metrics for a namespace.
+type namespacePolicyCounts struct {
+	policies  int
+	aclRules  int
+	endpoints int
+}
+
+var namespacePolicyInventory map[string]*namespacePolicyCounts
+
+// UpdateNamespacePolicyMetrics adds the deltas to the number of policies, ACL rules, and endpoints covered for the namespace.
+// The namespace's metrics are removed once it has no policies.
+func UpdateNamespacePolicyMetrics(ns string, policiesDelta, aclRulesDelta, endpointsDelta int) {
+	counts, ok := namespacePolicyInventory[ns]
+	if !ok {
+		counts = &namespacePolicyCounts{}
+		namespacePolicyInventory[ns] = counts
+	}
+	counts.policies += policiesDelta
+	counts.aclRules += aclRulesDelta
+	counts.endpoints += endpointsDelta
+
+	labels := prometheus.Labels{namespaceLabel: ns}
+	if counts.policies <= 0 {
+		delete(namespacePolicyInventory, ns)
+		namespacePolicies.Delete(labels)
+		namespaceACLRules.Delete(labels)
+		namespaceEndpoints.Delete(labels)
+		return
+	}
+
+	namespacePolicies.With(labels).Set(float64(counts.policies))
+	namespaceACLRules.With(labels).Set(float64(counts.aclRules))
+	namespaceEndpoints.With(labels).Set(float64(counts.endpoints))
+}
+
+// ResetNamespacePolicyMetrics removes the per-namespace policy metrics of every namespace.
+func ResetNamespacePolicyMetrics() {
+	namespacePolicies.Reset()
+	namespaceACLRules.Reset()
+	namespaceEndpoints.Reset()
+	namespacePolicyInventory = make(map[string]*namespacePolicyCounts)
+}
+
+// GetNumPoliciesForNamespace returns the number of policies in the dataplane for the namespace.
+// This function is slow.
+func GetNumPoliciesForNamespace(ns string) (int, error) {
+	if _, ok := namespacePolicyInventory[ns]; !ok {
+		// avoid creating the metric for the namespace
+		return 0, nil
+	}
+	return getVecValue(namespacePolicies, prometheus.Labels{namespaceLabel: ns})
+}
+
+// GetNumACLRulesForNamespace returns the number of ACL rules produced by the policies of the namespace.
+// This function is slow.
+func GetNumACLRulesForNamespace(ns string) (int, error) {
+	if _, ok := namespacePolicyInventory[ns]; !ok {
+		// avoid creating the metric for the namespace
+		return 0, nil
+	}
+	return getVecValue(namespaceACLRules, prometheus.Labels{namespaceLabel: ns})
+}
+
+// GetNumPolicyEndpointsForNamespace returns the number of endpoints covered by the policies of the namespace.
+// This function is slow.
+func GetNumPolicyEndpointsForNamespace(ns string) (int, error) {
+	if _, ok := namespacePolicyInventory[ns]; !ok {
+		// avoid creating the metric for the namespace
+		return 0, nil
+	}
+	return getVecValue(namespaceEndpoints, prometheus.Labels{namespaceLabel: ns})
+}
+
+// SetAuditMode records whether network policies are only logged instead of programmed.
+func SetAuditMode(enabled bool) {
+	if enabled {
+		auditMode.Set(1)
+	} else {
+		auditMode.Set(0)
+	}
+}
+
+// GetAuditMode returns 1 if network policies are only logged instead of programmed, and 0 otherwise.
+// This function is slow.
+func GetAuditMode() (int, error) {
+	return getValue(auditMode)
+}
@@ -1,6 +1,11 @@
 package metrics
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/metrics/promutil"
+	"github.com/stretchr/testify/require"
+)
 
 var numPoliciesMetric = &basicMetric{ResetNumPolicies, IncNumPolicies, DecNumPolicies, GetNumPolicies}
 
@@ -22,3 +27,48 @@ func TestDecNumPolicies(t *testing.T) {
 func TestResetNumPolicies(t *testing.T) {
 	testResetMetric(t, numPoliciesMetric)
 }
+
+func TestUpdateNamespacePolicyMetrics(t *testing.T) {
+	ResetNamespacePolicyMetrics()
+	UpdateNamespacePolicyMetrics("x", 1, 3, 2)
+	UpdateNamespacePolicyMetrics("x", 1, 4, 0)
+	UpdateNamespacePolicyMetrics("y", 1, 5, 1)
+	UpdateNamespacePolicyMetrics("x", 0, -2, -1)
+	assertNamespaceMetrics(t, "x", 2, 5, 1)
+	assertNamespaceMetrics(t, "y", 1, 5, 1)
+
+	// the namespace's metrics are removed with its last policy
+	UpdateNamespacePolicyMetrics("y", -1, -5, -1)
+	assertNamespaceMetrics(t, "y", 0, 0, 0)
+	require.NotContains(t, namespacePolicyInventory, "y")
+
+	ResetNamespacePolicyMetrics()
+	assertNamespaceMetrics(t, "x", 0, 0, 0)
+}
+
+func assertNamespaceMetrics(t *testing.T, ns string, expectedPolicies, expectedACLRules, expectedEndpoints int) {
+	t.Helper()
+	numPolicies, err := GetNumPoliciesForNamespace(ns)
+	promutil.NotifyIfErrors(t, err)
+	require.Equal(t, expectedPolicies, numPolicies)
+
+	numACLRules, err := GetNumACLRulesForNamespace(ns)
+	promutil.NotifyIfErrors(t, err)
+	require.Equal(t, expectedACLRules, numACLRules)
+
+	numEndpoints, err := GetNumPolicyEndpointsForNamespace(ns)
+	promutil.NotifyIfErrors(t, err)
+	require.Equal(t, expectedEndpoints, numEndpoints)
+}
+
+func TestSetAuditMode(t *testing.T) {
+	SetAuditMode(true)
+	val, err := GetAuditMode()
+	promutil.NotifyIfErrors(t, err)
+	require.Equal(t, 1, val)
+
+	SetAuditMode(false)
+	val, err = GetAuditMode()
+	promutil.NotifyIfErrors(t, err)
+	require.Equal(t, 0, val)
+}
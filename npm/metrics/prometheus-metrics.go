@@ -3,11 +3,9 @@ package metrics
 import (
 	"net/http"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm/util"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"k8s.io/klog"
 )
 
 // Constants for metric names and descriptions as well as exported labels for Vector metrics
@@ -41,6 +39,17 @@ const (
 	setNameLabel       = "set_name"
 	setHashLabel       = "set_hash"
 
+	namespacePoliciesName  = "namespace_num_policies"
+	namespacePoliciesHelp  = "The number of network policies in the dataplane for each namespace"
+	namespaceACLRulesName  = "namespace_num_acl_rules"
+	namespaceACLRulesHelp  = "The number of ACL rules produced by the network policies of each namespace"
+	namespaceEndpointsName = "namespace_num_policy_endpoints"
+	namespaceEndpointsHelp = "The number of endpoints covered by the network policies of each namespace, summed over the policies (Windows only)"
+	namespaceLabel         = "namespace"
+
+	auditModeName = "audit_mode"
+	auditModeHelp = "1 if network policies are only logged instead of programmed, in which case the policy and ACL rule metrics count the rules which would be programmed"
+
 	// perf metrics added after v1.4.16
 	// all these metrics have "npm_controller_" prepended to their name
 	operationLabel = "operation"
@@ -84,6 +93,14 @@ var (
 	ipsetInventory       *prometheus.GaugeVec
 	ipsetInventoryLabels = []string{setNameLabel, setHashLabel}
 
+	// per-namespace policy metrics
+	namespacePolicies       *prometheus.GaugeVec
+	namespaceACLRules       *prometheus.GaugeVec
+	namespaceEndpoints      *prometheus.GaugeVec
+	namespacePoliciesLabels = []string{namespaceLabel}
+
+	auditMode prometheus.Gauge
+
 	// controller perf metrics
 	// used to be a regular Summary in v1.4.16 and below
 	addPolicyExecTime       *prometheus.SummaryVec
@@ -116,15 +133,37 @@ var (
 	getNetworkFailures    prometheus.Counter
 	aclFailures           *prometheus.CounterVec
 	setPolicyFailures     *prometheus.CounterVec
+	orphanedACLsRemoved   prometheus.Counter
+	aclBatchRules         prometheus.Histogram
+	aclBatchesPerPod      prometheus.Histogram
+	aclBatchLatency       prometheus.Histogram
 )
 
-const linuxPrefix = "linux"
+const (
+	linuxPrefix = "linux"
+	repairLabel = "repair"
+)
 
 // linux metrics added in v1.5.5
 var (
 	itpablesRestoreLatency  *prometheus.HistogramVec
 	iptablesDeleteLatency   prometheus.Histogram
 	iptablesRestoreFailures *prometheus.CounterVec
+	iptablesRepairs         *prometheus.CounterVec
+)
+
+// IPTablesRepairKind is what reconciling had to re-insert in iptables.
+type IPTablesRepairKind string
+
+const (
+	// ForwardJumpRepair is a missing or misplaced jump from the FORWARD chain to the AZURE-NPM chain.
+	ForwardJumpRepair IPTablesRepairKind = "forward_jump"
+	// PolicyChainRepair is a missing chain of a policy in the cache.
+	PolicyChainRepair IPTablesRepairKind = "policy_chain"
+	// TierRepair is a missing AdminNetworkPolicy tier chain or jump to it.
+	TierRepair IPTablesRepairKind = "tier"
+	// NftablesTableRepair is a missing nftables table of NPM, or a table missing its FORWARD chain.
+	NftablesTableRepair IPTablesRepairKind = "nftables_table"
 )
 
 type RegistryType string
@@ -158,7 +197,7 @@ func (op OperationKind) isValid() bool {
 // Would need to consider how it seems like you can't register a metric twice, even in a separate registry, so you couldn't throw away the Metrics struct and create a new one.
 func InitializeAll() {
 	if haveInitialized {
-		klog.Infof("metrics have already been initialized")
+		logger.Infof("metrics have already been initialized")
 		return
 	}
 
@@ -178,7 +217,7 @@ func InitializeAll() {
 	if util.IsWindowsDP() {
 		InitializeWindowsMetrics()
 
-		klog.Infof("registering windows metrics")
+		logger.Infof("registering windows metrics")
 		register(listEndpointsLatency, "list_endpoints_latency_seconds", NodeMetrics)
 		register(getEndpointLatency, "get_endpoint_latency_seconds", NodeMetrics)
 		register(getNetworkLatency, "get_network_latency_seconds", NodeMetrics)
@@ -189,30 +228,35 @@ func InitializeAll() {
 		register(getNetworkFailures, "get_network_failure_total", NodeMetrics)
 		register(aclFailures, "acl_failure_total", NodeMetrics)
 		register(setPolicyFailures, "setpolicy_failure_total", NodeMetrics)
+		register(orphanedACLsRemoved, "orphaned_acls_removed_total", NodeMetrics)
+		register(aclBatchRules, "acl_batch_rules", NodeMetrics)
+		register(aclBatchesPerPod, "acl_batches_per_pod", NodeMetrics)
+		register(aclBatchLatency, "acl_batch_latency_seconds", NodeMetrics)
 	} else {
 		InitializeLinuxMetrics()
 
-		klog.Infof("registering linux metrics")
+		logger.Infof("registering linux metrics")
 		register(itpablesRestoreLatency, "iptables_restore_latency_seconds", NodeMetrics)
 		register(iptablesDeleteLatency, "iptables_delete_latency_seconds", NodeMetrics)
 		register(iptablesRestoreFailures, "iptables_restore_failure_total", NodeMetrics)
+		register(iptablesRepairs, "iptables_repair_total", NodeMetrics)
 	}
 
-	log.Logf("Finished initializing all Prometheus metrics")
+	logger.Infof("Finished initializing all Prometheus metrics")
 	haveInitialized = true
 }
 
 // ReinitializeAll creates/replaces Prometheus metrics.
 // This function is intended for UTs.
 func ReinitializeAll() {
-	klog.Infof("reinitializing Prometheus metrics. This may cause error messages of the form: 'error creating metric' from trying to re-register each metric")
+	logger.Infof("reinitializing Prometheus metrics. This may cause error messages of the form: 'error creating metric' from trying to re-register each metric")
 	haveInitialized = false
 	InitializeAll()
 }
 
 // InitializeWindowsMetrics should NOT be called externally except for resetting metrics for UTs.
 func InitializeWindowsMetrics() {
-	klog.Infof("initializing Windows metrics. will not register the newly created metrics in this function")
+	logger.Infof("initializing Windows metrics. will not register the newly created metrics in this function")
 
 	listEndpointsLatency = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
@@ -317,10 +361,51 @@ func InitializeWindowsMetrics() {
 		},
 		[]string{operationLabel, isNestedLabel},
 	)
+	orphanedACLsRemoved = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orphaned_acls_removed_total",
+			Subsystem: windowsPrefix,
+			Help:      "Number of ACLs of deleted policies removed from HNS endpoints",
+		},
+	)
+
+	aclBatchRules = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "acl_batch_rules",
+			Subsystem: windowsPrefix,
+			Help:      "Number of ACL rules in each batch applied to an endpoint when adding all policies of a pod, bounded by MaxBatchedACLsPerPod",
+			//nolint:gomnd // default bucket consts
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12), // upper bounds of 1 to 2048 rules
+		},
+	)
+
+	aclBatchesPerPod = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "acl_batches_per_pod",
+			Subsystem: windowsPrefix,
+			Help:      "Number of batches needed to add all policies of a pod to its endpoint",
+			//nolint:gomnd // default bucket consts
+			Buckets: prometheus.ExponentialBuckets(1, 2, 8), // upper bounds of 1 to 128 batches
+		},
+	)
+
+	aclBatchLatency = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "acl_batch_latency_seconds",
+			Subsystem: windowsPrefix,
+			Help:      "Latency in seconds to apply a batch of ACL rules to an endpoint when adding all policies of a pod",
+			//nolint:gomnd // default bucket consts
+			Buckets: prometheus.ExponentialBuckets(0.008, 2, 14), // upper bounds of 8 ms to 65 seconds
+		},
+	)
 }
 
 func InitializeLinuxMetrics() {
-	klog.Infof("initializing Linux metrics. will not register the newly created metrics in this function")
+	logger.Infof("initializing Linux metrics. will not register the newly created metrics in this function")
 
 	itpablesRestoreLatency = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -354,13 +439,23 @@ func InitializeLinuxMetrics() {
 		},
 		[]string{operationLabel},
 	)
+
+	iptablesRepairs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "iptables_repair_total",
+			Subsystem: linuxPrefix,
+			Help:      "Number of iptables rules and chains which were missing and re-inserted while reconciling, by repair label (forward_jump/policy_chain)",
+		},
+		[]string{repairLabel},
+	)
 }
 
 // GetHandler returns the HTTP handler for the metrics endpoint
 func GetHandler(registryType RegistryType) http.Handler {
 	if !haveInitialized {
 		// not sure if this will ever happen, but just in case
-		klog.Infof("in GetHandler, metrics weren't initialized. Initializing now")
+		logger.Infof("in GetHandler, metrics weren't initialized. Initializing now")
 		InitializeAll()
 	}
 	return promhttp.HandlerFor(getRegistry(registryType), promhttp.HandlerOpts{})
@@ -374,6 +469,11 @@ func initializeDaemonMetrics() {
 	numIPSetEntries = createClusterGauge(numIPSetEntriesName, numIPSetEntriesHelp)
 	ipsetInventory = createClusterGaugeVec(ipsetInventoryName, ipsetInventoryHelp, ipsetInventoryLabels)
 	ipsetInventoryMap = make(map[string]int)
+	namespacePolicies = createClusterGaugeVec(namespacePoliciesName, namespacePoliciesHelp, namespacePoliciesLabels)
+	namespaceACLRules = createClusterGaugeVec(namespaceACLRulesName, namespaceACLRulesHelp, namespacePoliciesLabels)
+	namespaceEndpoints = createClusterGaugeVec(namespaceEndpointsName, namespaceEndpointsHelp, namespacePoliciesLabels)
+	namespacePolicyInventory = make(map[string]*namespacePolicyCounts)
+	auditMode = createClusterGauge(auditModeName, auditModeHelp)
 
 	// NODE METRICS
 	addACLRuleExecTime = createNodeSummary(addACLRuleExecTimeName, addACLRuleExecTimeHelp)
@@ -398,9 +498,9 @@ func initializeControllerMetrics() {
 func register(collector prometheus.Collector, name string, registryType RegistryType) {
 	err := getRegistry(registryType).Register(collector)
 	if err != nil {
-		log.Errorf("Error creating metric %s", name)
+		logger.Errorf("Error creating metric %s", name)
 	} else {
-		klog.Infof("registered metric %s to registry %s", name, registryType)
+		logger.Infof("registered metric %s to registry %s", name, registryType)
 	}
 }
 
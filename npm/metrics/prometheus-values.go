@@ -33,6 +33,14 @@ func histogramCount(histogram prometheus.Collector) (int, error) {
 	return int(dtoMetric.Histogram.GetSampleCount()), nil
 }
 
+func histogramSum(histogram prometheus.Collector) (float64, error) {
+	dtoMetric, err := getDTOMetric(histogram)
+	if err != nil {
+		return 0, err
+	}
+	return dtoMetric.Histogram.GetSampleSum(), nil
+}
+
 // getValue returns a Gauge metric's value.
 // This function is slow.
 func getValue(gaugeMetric prometheus.Gauge) (int, error) {
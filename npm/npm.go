@@ -9,20 +9,24 @@ import (
 
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
 	"github.com/Azure/azure-container-networking/npm/ipsm"
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/policy/v1alpha1"
 	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
 	controllersv1 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v1"
 	controllersv2 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v2"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/pkg/models"
 	"github.com/Azure/azure-container-networking/npm/util"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/klog"
 	utilexec "k8s.io/utils/exec"
 )
 
+var logger = npmlogger.For(npmlogger.NPM)
+
 var aiMetadata string //nolint // aiMetadata is set in Makefile
 
 // waitDurationAfterStartingNetPolController is used when configured to apply dataplane in the background
@@ -55,14 +59,16 @@ type NetworkPolicyManager struct {
 	models.AzureConfig
 }
 
-// NewNetworkPolicyManager creates a NetworkPolicyManager
+// NewNetworkPolicyManager creates a NetworkPolicyManager.
+// dynamicInformerFactory is only used when Toggles.EnableAdminNetworkPolicy is set and may be nil otherwise.
 func NewNetworkPolicyManager(config npmconfig.Config,
 	informerFactory informers.SharedInformerFactory,
+	dynamicInformerFactory dynamicinformer.DynamicSharedInformerFactory,
 	dp dataplane.GenericDataplane,
 	exec utilexec.Interface,
 	npmVersion string,
-	k8sServerVersion *version.Info) *NetworkPolicyManager {
-	klog.Infof("API server version: %+v AI metadata %+v", k8sServerVersion, aiMetadata)
+	k8sServerVersion *version.Info) (*NetworkPolicyManager, error) {
+	logger.Infof("API server version: %+v AI metadata %+v", k8sServerVersion, aiMetadata)
 
 	npMgr := &NetworkPolicyManager{
 		config:    config,
@@ -72,6 +78,8 @@ func NewNetworkPolicyManager(config npmconfig.Config,
 			PodInformer:     informerFactory.Core().V1().Pods(),
 			NsInformer:      informerFactory.Core().V1().Namespaces(),
 			NpInformer:      informerFactory.Networking().V1().NetworkPolicies(),
+
+			DynamicInformerFactory: dynamicInformerFactory,
 		},
 		AzureConfig: models.AzureConfig{
 			K8sServerVersion: k8sServerVersion,
@@ -83,12 +91,23 @@ func NewNetworkPolicyManager(config npmconfig.Config,
 
 	// create v2 NPM specific components.
 	if npMgr.config.Toggles.EnableV2NPM {
+		exclusion, err := common.NewNamespaceExclusion(config.NamespaceExclusion.Namespaces, config.NamespaceExclusion.LabelSelector, npMgr.NsInformer)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create namespace exclusion")
+		}
 		npMgr.NpmNamespaceCacheV2 = &controllersv2.NpmNamespaceCache{NsMap: make(map[string]*common.Namespace)}
-		npMgr.PodControllerV2 = controllersv2.NewPodController(npMgr.PodInformer, dp, npMgr.NpmNamespaceCacheV2)
-		npMgr.NamespaceControllerV2 = controllersv2.NewNamespaceController(npMgr.NsInformer, dp, npMgr.NpmNamespaceCacheV2)
+		npMgr.PodControllerV2 = controllersv2.NewPodController(npMgr.PodInformer, dp, npMgr.NpmNamespaceCacheV2, exclusion)
+		npMgr.NamespaceControllerV2 = controllersv2.NewNamespaceController(npMgr.NsInformer, dp, npMgr.NpmNamespaceCacheV2, exclusion)
 		// Question(jungukcho): Is config.Toggles.PlaceAzureChainFirst needed for v2?
-		npMgr.NetPolControllerV2 = controllersv2.NewNetworkPolicyController(npMgr.NpInformer, dp)
-		return npMgr
+		npMgr.NetPolControllerV2 = controllersv2.NewNetworkPolicyController(npMgr.NpInformer, dp, exclusion)
+		if config.Toggles.EnableAdminNetworkPolicy {
+			npMgr.AdminNetPolControllerV2 = controllersv2.NewAdminNetworkPolicyController(
+				dynamicInformerFactory.ForResource(v1alpha1.AdminNetworkPolicyResource),
+				dynamicInformerFactory.ForResource(v1alpha1.BaselineAdminNetworkPolicyResource),
+				dp,
+			)
+		}
+		return npMgr, nil
 	}
 
 	// create v1 NPM specific components.
@@ -98,7 +117,7 @@ func NewNetworkPolicyManager(config npmconfig.Config,
 	npMgr.PodControllerV1 = controllersv1.NewPodController(npMgr.PodInformer, npMgr.ipsMgr, npMgr.NpmNamespaceCacheV1)
 	npMgr.NamespaceControllerV1 = controllersv1.NewNameSpaceController(npMgr.NsInformer, npMgr.ipsMgr, npMgr.NpmNamespaceCacheV1)
 	npMgr.NetPolControllerV1 = controllersv1.NewNetworkPolicyController(npMgr.NpInformer, npMgr.ipsMgr, config.Toggles.PlaceAzureChainFirst)
-	return npMgr
+	return npMgr, nil
 }
 
 // Dear Time Traveler:
@@ -200,12 +219,25 @@ func (npMgr *NetworkPolicyManager) Start(config npmconfig.Config, stopCh <-chan
 		return fmt.Errorf("NetworkPolicy informer error: %w", models.ErrInformerSyncFailure)
 	}
 
+	if npMgr.AdminNetPolControllerV2 != nil {
+		npMgr.DynamicInformerFactory.Start(stopCh)
+		for gvr, synced := range npMgr.DynamicInformerFactory.WaitForCacheSync(stopCh) {
+			if !synced {
+				return fmt.Errorf("%s informer error: %w", gvr.Resource, models.ErrInformerSyncFailure)
+			}
+		}
+	}
+
 	// start v2 NPM controllers after synced
 	if config.Toggles.EnableV2NPM {
+		// admin policies are evaluated before NetworkPolicies, so program them first
+		if npMgr.AdminNetPolControllerV2 != nil {
+			go npMgr.AdminNetPolControllerV2.Run(stopCh)
+		}
 		go npMgr.NetPolControllerV2.Run(stopCh)
 
 		if util.IsWindowsDP() && config.Toggles.ApplyInBackground {
-			klog.Infof("optimizing NPM bootup by letting NetPol controller process changes first. waiting %v before starting pod and namespace controllers", waitDurationAfterStartingNetPolController)
+			logger.Infof("optimizing NPM bootup by letting NetPol controller process changes first. waiting %v before starting pod and namespace controllers", waitDurationAfterStartingNetPolController)
 			time.Sleep(waitDurationAfterStartingNetPolController)
 		}
 
@@ -0,0 +1,167 @@
+// Package v1alpha1 has the types of the AdminNetworkPolicy and BaselineAdminNetworkPolicy APIs of
+// sig-network (sigs.k8s.io/network-policy-api), group policy.networking.k8s.io.
+// Only the fields NPM translates are mirrored. NPM watches the resources with a dynamic client
+// and converts them from unstructured objects, so the types need no generated clients or deep copies.
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupName is the group of the AdminNetworkPolicy APIs.
+const GroupName = "policy.networking.k8s.io"
+
+var (
+	// AdminNetworkPolicyResource is the cluster-scoped resource of AdminNetworkPolicies.
+	AdminNetworkPolicyResource = schema.GroupVersionResource{Group: GroupName, Version: "v1alpha1", Resource: "adminnetworkpolicies"}
+	// BaselineAdminNetworkPolicyResource is the cluster-scoped resource of BaselineAdminNetworkPolicies.
+	BaselineAdminNetworkPolicyResource = schema.GroupVersionResource{Group: GroupName, Version: "v1alpha1", Resource: "baselineadminnetworkpolicies"}
+)
+
+// BaselineAdminNetworkPolicyName is the only name a BaselineAdminNetworkPolicy may have, since there is one per cluster.
+const BaselineAdminNetworkPolicyName = "default"
+
+// AdminNetworkPolicy is a cluster-wide policy which is evaluated before NetworkPolicies.
+// Its rules can allow or deny traffic regardless of NetworkPolicies, or pass it on to them.
+type AdminNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AdminNetworkPolicySpec `json:"spec"`
+}
+
+// AdminNetworkPolicySpec is the spec of an AdminNetworkPolicy.
+type AdminNetworkPolicySpec struct {
+	// Priority orders the AdminNetworkPolicies from 0 (evaluated first) to 1000.
+	// The order of AdminNetworkPolicies with the same priority is undefined.
+	Priority int32 `json:"priority"`
+	// Subject selects the pods the policy applies to.
+	Subject AdminNetworkPolicySubject `json:"subject"`
+	// Ingress rules are evaluated in order, and the first rule matching the traffic decides it.
+	Ingress []AdminNetworkPolicyIngressRule `json:"ingress,omitempty"`
+	// Egress rules are evaluated in order, and the first rule matching the traffic decides it.
+	Egress []AdminNetworkPolicyEgressRule `json:"egress,omitempty"`
+}
+
+// AdminNetworkPolicyRuleAction is what happens to the traffic matched by a rule.
+type AdminNetworkPolicyRuleAction string
+
+const (
+	// AdminNetworkPolicyRuleActionAllow allows the traffic, skipping lower-priority policies and NetworkPolicies.
+	AdminNetworkPolicyRuleActionAllow AdminNetworkPolicyRuleAction = "Allow"
+	// AdminNetworkPolicyRuleActionDeny denies the traffic, skipping lower-priority policies and NetworkPolicies.
+	AdminNetworkPolicyRuleActionDeny AdminNetworkPolicyRuleAction = "Deny"
+	// AdminNetworkPolicyRuleActionPass skips lower-priority AdminNetworkPolicies, so that NetworkPolicies decide the traffic.
+	AdminNetworkPolicyRuleActionPass AdminNetworkPolicyRuleAction = "Pass"
+)
+
+// AdminNetworkPolicySubject selects pods by their namespaces, or by their namespaces and labels. Exactly one field is set.
+type AdminNetworkPolicySubject struct {
+	Namespaces *metav1.LabelSelector `json:"namespaces,omitempty"`
+	Pods       *NamespacedPod        `json:"pods,omitempty"`
+}
+
+// NamespacedPod selects the pods matching PodSelector in the namespaces matching NamespaceSelector.
+type NamespacedPod struct {
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+	PodSelector       metav1.LabelSelector `json:"podSelector"`
+}
+
+// AdminNetworkPolicyIngressRule matches traffic to the subject from any of its peers, on any of its ports.
+type AdminNetworkPolicyIngressRule struct {
+	Name   string                          `json:"name,omitempty"`
+	Action AdminNetworkPolicyRuleAction    `json:"action"`
+	From   []AdminNetworkPolicyIngressPeer `json:"from"`
+	// Ports restrict the destination ports of the traffic. All ports match if Ports is nil.
+	Ports *[]AdminNetworkPolicyPort `json:"ports,omitempty"`
+}
+
+// AdminNetworkPolicyEgressRule matches traffic from the subject to any of its peers, on any of its ports.
+type AdminNetworkPolicyEgressRule struct {
+	Name   string                         `json:"name,omitempty"`
+	Action AdminNetworkPolicyRuleAction   `json:"action"`
+	To     []AdminNetworkPolicyEgressPeer `json:"to"`
+	// Ports restrict the destination ports of the traffic. All ports match if Ports is nil.
+	Ports *[]AdminNetworkPolicyPort `json:"ports,omitempty"`
+}
+
+// AdminNetworkPolicyIngressPeer selects the sources of ingress traffic. Exactly one field is set.
+type AdminNetworkPolicyIngressPeer struct {
+	Namespaces *metav1.LabelSelector `json:"namespaces,omitempty"`
+	Pods       *NamespacedPod        `json:"pods,omitempty"`
+}
+
+// AdminNetworkPolicyEgressPeer selects the destinations of egress traffic. Exactly one field is set.
+type AdminNetworkPolicyEgressPeer struct {
+	Namespaces *metav1.LabelSelector `json:"namespaces,omitempty"`
+	Pods       *NamespacedPod        `json:"pods,omitempty"`
+	// Networks are CIDRs, e.g. of destinations outside the cluster.
+	Networks []CIDR `json:"networks,omitempty"`
+	// Nodes selects nodes by their labels.
+	Nodes *metav1.LabelSelector `json:"nodes,omitempty"`
+}
+
+// CIDR is an IPv4 or IPv6 network, like 10.0.0.0/8.
+type CIDR string
+
+// AdminNetworkPolicyPort selects destination ports. Exactly one field is set.
+type AdminNetworkPolicyPort struct {
+	PortNumber *Port      `json:"portNumber,omitempty"`
+	NamedPort  *string    `json:"namedPort,omitempty"`
+	PortRange  *PortRange `json:"portRange,omitempty"`
+}
+
+// Port is a port of a protocol.
+type Port struct {
+	Protocol corev1.Protocol `json:"protocol"`
+	Port     int32           `json:"port"`
+}
+
+// PortRange is the ports from Start to End, inclusive, of a protocol.
+type PortRange struct {
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+	Start    int32           `json:"start"`
+	End      int32           `json:"end"`
+}
+
+// BaselineAdminNetworkPolicy is the cluster-wide default policy, which decides the traffic of pods
+// which no NetworkPolicy selects. There is at most one, named "default".
+type BaselineAdminNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BaselineAdminNetworkPolicySpec `json:"spec"`
+}
+
+// BaselineAdminNetworkPolicySpec is the spec of a BaselineAdminNetworkPolicy.
+type BaselineAdminNetworkPolicySpec struct {
+	Subject AdminNetworkPolicySubject               `json:"subject"`
+	Ingress []BaselineAdminNetworkPolicyIngressRule `json:"ingress,omitempty"`
+	Egress  []BaselineAdminNetworkPolicyEgressRule  `json:"egress,omitempty"`
+}
+
+// BaselineAdminNetworkPolicyRuleAction is what happens to the traffic matched by a baseline rule.
+type BaselineAdminNetworkPolicyRuleAction string
+
+const (
+	BaselineAdminNetworkPolicyRuleActionAllow BaselineAdminNetworkPolicyRuleAction = "Allow"
+	BaselineAdminNetworkPolicyRuleActionDeny  BaselineAdminNetworkPolicyRuleAction = "Deny"
+)
+
+// BaselineAdminNetworkPolicyIngressRule is like AdminNetworkPolicyIngressRule without the Pass action.
+type BaselineAdminNetworkPolicyIngressRule struct {
+	Name   string                               `json:"name,omitempty"`
+	Action BaselineAdminNetworkPolicyRuleAction `json:"action"`
+	From   []AdminNetworkPolicyIngressPeer      `json:"from"`
+	Ports  *[]AdminNetworkPolicyPort            `json:"ports,omitempty"`
+}
+
+// BaselineAdminNetworkPolicyEgressRule is like AdminNetworkPolicyEgressRule without the Pass action.
+type BaselineAdminNetworkPolicyEgressRule struct {
+	Name   string                               `json:"name,omitempty"`
+	Action BaselineAdminNetworkPolicyRuleAction `json:"action"`
+	To     []AdminNetworkPolicyEgressPeer       `json:"to"`
+	Ports  *[]AdminNetworkPolicyPort            `json:"ports,omitempty"`
+}
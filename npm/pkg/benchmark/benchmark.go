@@ -0,0 +1,314 @@
+// Package benchmark feeds the NPM v2 controllers synthetic namespace, pod and network policy events without an API
+// server, and measures how fast they are translated and applied to a dataplane. It is used for capacity planning and
+// to track performance regressions between releases.
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
+	controllersv2 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v2"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+var logger = npmlogger.For(npmlogger.Dataplane)
+
+const (
+	pollInterval = 10 * time.Millisecond
+	// maxUndelivered bounds the events which the informers have not received yet, since the watches of the fake
+	// clientset panic once their buffer of 100 events is full. Informers only enqueue the events, so they keep up.
+	maxUndelivered = 50
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid benchmark config")
+	ErrTimeout       = errors.New("timed out waiting for the controllers to process the events")
+)
+
+// Config sizes the synthetic cluster and sets the rate of its events.
+type Config struct {
+	Namespaces           int `json:"namespaces"`
+	PodsPerNamespace     int `json:"podsPerNamespace"`
+	LabelsPerPod         int `json:"labelsPerPod"`
+	PoliciesPerNamespace int `json:"policiesPerNamespace"`
+	// Rate is the number of events created per second in each phase. 0 creates them as fast as possible.
+	Rate int `json:"rate"`
+	// Delete also measures deleting the policies, pods and namespaces once they are created.
+	Delete bool `json:"delete"`
+	// Timeout bounds the time the controllers have to process the events of each phase.
+	Timeout time.Duration `json:"timeout"`
+	// NodeName is the node of the synthetic pods.
+	NodeName string `json:"nodeName"`
+}
+
+func (cfg Config) validate() error {
+	if cfg.Namespaces < 1 || cfg.PodsPerNamespace < 0 || cfg.LabelsPerPod < 0 || cfg.PoliciesPerNamespace < 0 ||
+		cfg.Rate < 0 || cfg.Timeout <= 0 {
+		return fmt.Errorf("%w: need at least one namespace, non-negative sizes and rate, and a positive timeout", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// PhaseResult is the measurement of a batch of events of the same kind.
+type PhaseResult struct {
+	Name   string `json:"name"`
+	Events int    `json:"events"`
+	// Elapsed is the time from creating the first event until the controllers processed all of them.
+	Elapsed time.Duration `json:"elapsed"`
+	// Drain is the time from creating the last event until the controllers processed all of them.
+	// A drain which grows with the rate means the controllers cannot keep up with it.
+	Drain time.Duration `json:"drain"`
+}
+
+// Throughput is the number of events processed per second.
+func (r PhaseResult) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Events) / r.Elapsed.Seconds()
+}
+
+// Report is the result of a benchmark run.
+type Report struct {
+	Config Config        `json:"config"`
+	Phases []PhaseResult `json:"phases"`
+}
+
+// Print writes the report as a table.
+func (r *Report) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:gomnd // padding
+	fmt.Fprintln(tw, "PHASE\tEVENTS\tELAPSED\tDRAIN\tEVENTS/S")
+	for _, p := range r.Phases {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%.1f\n", p.Name, p.Events, p.Elapsed.Round(time.Millisecond), p.Drain.Round(time.Millisecond), p.Throughput())
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to print report: %w", err)
+	}
+	return nil
+}
+
+type benchmark struct {
+	cfg Config
+	// sent and delivered count the events created in the fake clientset and received by the informers.
+	sent      int64
+	delivered int64
+	clientset kubernetes.Interface
+	nsCache   *controllersv2.NpmNamespaceCache
+	podCtrl   *controllersv2.PodController
+	netPolCtr *controllersv2.NetworkPolicyController
+}
+
+// Run creates the namespaces, then the pods, then the policies of the config, and measures the time the v2
+// controllers take to process each batch into the dataplane. The dataplane must already be booted up.
+func Run(ctx context.Context, cfg Config, dp dataplane.GenericDataplane) (*Report, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	// the controllers record their metrics, which must exist.
+	metrics.InitializeAll()
+
+	clientset := fake.NewSimpleClientset()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := factory.Core().V1().Pods()
+	nsInformer := factory.Core().V1().Namespaces()
+	npInformer := factory.Networking().V1().NetworkPolicies()
+
+	b := &benchmark{
+		cfg:       cfg,
+		clientset: clientset,
+		nsCache:   &controllersv2.NpmNamespaceCache{NsMap: make(map[string]*common.Namespace)},
+	}
+	b.podCtrl = controllersv2.NewPodController(podInformer, dp, b.nsCache, nil)
+	nsCtrl := controllersv2.NewNamespaceController(nsInformer, dp, b.nsCache, nil)
+	b.netPolCtr = controllersv2.NewNetworkPolicyController(npInformer, dp, nil)
+	for _, informer := range []cache.SharedIndexInformer{podInformer.Informer(), nsInformer.Informer(), npInformer.Informer()} {
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(interface{}) { atomic.AddInt64(&b.delivered, 1) },
+			UpdateFunc: func(interface{}, interface{}) { atomic.AddInt64(&b.delivered, 1) },
+			DeleteFunc: func(interface{}) { atomic.AddInt64(&b.delivered, 1) },
+		})
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, podInformer.Informer().HasSynced, nsInformer.Informer().HasSynced, npInformer.Informer().HasSynced) {
+		return nil, fmt.Errorf("failed to sync informers: %w", ErrTimeout)
+	}
+	go b.netPolCtr.Run(stopCh)
+	dp.FinishBootupPhase()
+	go b.podCtrl.Run(stopCh)
+	go nsCtrl.Run(stopCh)
+
+	report := &Report{Config: cfg}
+	for _, phase := range b.phases() {
+		logger.Infof("[benchmark] starting phase %s with %d events", phase.name, phase.events)
+		result, err := b.run(ctx, phase)
+		if err != nil {
+			return report, fmt.Errorf("phase %s failed: %w", phase.name, err)
+		}
+		report.Phases = append(report.Phases, result)
+	}
+	return report, nil
+}
+
+// phase creates or deletes the i-th object of a batch, and counts the objects the controllers have processed.
+type phase struct {
+	name   string
+	events int
+	event  func(ctx context.Context, i int) error
+	count  func() int
+	// want is the count once the controllers have processed the events.
+	want int
+}
+
+func (b *benchmark) phases() []phase {
+	numPods := b.cfg.Namespaces * b.cfg.PodsPerNamespace
+	numPolicies := b.cfg.Namespaces * b.cfg.PoliciesPerNamespace
+	phases := []phase{
+		{name: "create-namespaces", events: b.cfg.Namespaces, event: b.createNamespace, count: b.namespaces, want: b.cfg.Namespaces},
+		{name: "create-pods", events: numPods, event: b.createPod, count: b.pods, want: numPods},
+		{name: "create-policies", events: numPolicies, event: b.createPolicy, count: b.policies, want: numPolicies},
+	}
+	if b.cfg.Delete {
+		phases = append(phases,
+			phase{name: "delete-policies", events: numPolicies, event: b.deletePolicy, count: b.policies},
+			phase{name: "delete-pods", events: numPods, event: b.deletePod, count: b.pods},
+			phase{name: "delete-namespaces", events: b.cfg.Namespaces, event: b.deleteNamespace, count: b.namespaces},
+		)
+	}
+	return phases
+}
+
+func (b *benchmark) run(ctx context.Context, p phase) (PhaseResult, error) {
+	result := PhaseResult{Name: p.name, Events: p.events}
+	var tick <-chan time.Time
+	if b.cfg.Rate > 0 {
+		if interval := time.Second / time.Duration(b.cfg.Rate); interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+	}
+
+	start := time.Now()
+	for i := 0; i < p.events; i++ {
+		if tick != nil && i > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return result, fmt.Errorf("interrupted: %w", ctx.Err())
+			}
+		}
+		for b.sent-atomic.LoadInt64(&b.delivered) >= maxUndelivered {
+			select {
+			case <-time.After(time.Millisecond):
+			case <-ctx.Done():
+				return result, fmt.Errorf("interrupted: %w", ctx.Err())
+			}
+		}
+		if err := p.event(ctx, i); err != nil {
+			return result, err
+		}
+		b.sent++
+	}
+	created := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+	for p.count() != p.want {
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return result, fmt.Errorf("%w: processed %d of %d", ErrTimeout, p.count(), p.want)
+		}
+	}
+	end := time.Now()
+	result.Elapsed = end.Sub(start)
+	result.Drain = end.Sub(created)
+	return result, nil
+}
+
+func (b *benchmark) namespaces() int {
+	b.nsCache.RLock()
+	defer b.nsCache.RUnlock()
+	return len(b.nsCache.NsMap)
+}
+
+func (b *benchmark) pods() int {
+	b.podCtrl.RLock()
+	defer b.podCtrl.RUnlock()
+	return b.podCtrl.LengthOfPodMap()
+}
+
+func (b *benchmark) policies() int {
+	b.netPolCtr.RLock()
+	defer b.netPolCtr.RUnlock()
+	return b.netPolCtr.LengthOfRawNpMap()
+}
+
+// objectIndex maps the i-th event of a pod or policy phase to the namespace and the index of the object in it.
+func objectIndex(i, perNamespace int) (namespace string, index int) {
+	return namespaceName(i / perNamespace), i % perNamespace
+}
+
+func (b *benchmark) createNamespace(ctx context.Context, i int) error {
+	if _, err := b.clientset.CoreV1().Namespaces().Create(ctx, newNamespace(i), metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create namespace: %w", err)
+	}
+	return nil
+}
+
+func (b *benchmark) deleteNamespace(ctx context.Context, i int) error {
+	if err := b.clientset.CoreV1().Namespaces().Delete(ctx, namespaceName(i), metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("failed to delete namespace: %w", err)
+	}
+	return nil
+}
+
+func (b *benchmark) createPod(ctx context.Context, i int) error {
+	ns, index := objectIndex(i, b.cfg.PodsPerNamespace)
+	pod := newPod(ns, index, i, b.cfg.LabelsPerPod, b.cfg.NodeName)
+	if _, err := b.clientset.CoreV1().Pods(ns).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create pod: %w", err)
+	}
+	return nil
+}
+
+func (b *benchmark) deletePod(ctx context.Context, i int) error {
+	ns, index := objectIndex(i, b.cfg.PodsPerNamespace)
+	if err := b.clientset.CoreV1().Pods(ns).Delete(ctx, podName(index), metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("failed to delete pod: %w", err)
+	}
+	return nil
+}
+
+func (b *benchmark) createPolicy(ctx context.Context, i int) error {
+	ns, index := objectIndex(i, b.cfg.PoliciesPerNamespace)
+	policy := newNetworkPolicy(ns, index, b.cfg.LabelsPerPod)
+	if _, err := b.clientset.NetworkingV1().NetworkPolicies(ns).Create(ctx, policy, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create network policy: %w", err)
+	}
+	return nil
+}
+
+func (b *benchmark) deletePolicy(ctx context.Context, i int) error {
+	ns, index := objectIndex(i, b.cfg.PoliciesPerNamespace)
+	if err := b.clientset.NetworkingV1().NetworkPolicies(ns).Delete(ctx, networkPolicyName(index), metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("failed to delete network policy: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package benchmark
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/dpshim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	dp, err := dpshim.NewDPSim(stopCh)
+	require.NoError(t, err)
+	go func() {
+		for {
+			select {
+			case <-dp.OutChannel:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
+	cfg := Config{
+		Namespaces:           3,
+		PodsPerNamespace:     4,
+		LabelsPerPod:         2,
+		PoliciesPerNamespace: 2,
+		Rate:                 1000,
+		Delete:               true,
+		Timeout:              30 * time.Second,
+		NodeName:             "node",
+	}
+	report, err := Run(context.Background(), cfg, dp)
+	require.NoError(t, err)
+
+	expected := map[string]int{
+		"create-namespaces": 3,
+		"create-pods":       12,
+		"create-policies":   6,
+		"delete-policies":   6,
+		"delete-pods":       12,
+		"delete-namespaces": 3,
+	}
+	require.Len(t, report.Phases, len(expected))
+	for _, phase := range report.Phases {
+		require.Equal(t, expected[phase.Name], phase.Events, phase.Name)
+		require.Positive(t, phase.Elapsed, phase.Name)
+		require.GreaterOrEqual(t, phase.Elapsed, phase.Drain, phase.Name)
+	}
+
+	var sb strings.Builder
+	require.NoError(t, report.Print(&sb))
+	require.Contains(t, sb.String(), "create-policies")
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	_, err := Run(context.Background(), Config{Namespaces: 1}, nil)
+	require.ErrorIs(t, err, ErrInvalidConfig)
+}
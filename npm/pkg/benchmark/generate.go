@@ -0,0 +1,140 @@
+package benchmark
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	namespacePrefix = "bench-ns-"
+	// groupLabel splits the namespaces into groups, which the policies select with namespace selectors.
+	groupLabel = "bench-group"
+	numGroups  = 2
+	// labelValues is the number of values of each pod label, so each label and value ipset holds 1/labelValues of the pods.
+	labelValues = 5
+	// numPorts is the number of distinct ports the policies allow.
+	numPorts       = 10
+	basePort       = 8000
+	namedPort      = "bench-http"
+	namedPortValue = 80
+)
+
+func namespaceName(i int) string {
+	return fmt.Sprintf("%s%d", namespacePrefix, i)
+}
+
+func podName(i int) string {
+	return fmt.Sprintf("bench-pod-%d", i)
+}
+
+func networkPolicyName(i int) string {
+	return fmt.Sprintf("bench-policy-%d", i)
+}
+
+func labelKey(k int) string {
+	return fmt.Sprintf("bench-label-%d", k)
+}
+
+func labelValue(v int) string {
+	return fmt.Sprintf("value-%d", v%labelValues)
+}
+
+func newNamespace(i int) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   namespaceName(i),
+			Labels: map[string]string{groupLabel: fmt.Sprintf("group-%d", i%numGroups)},
+		},
+	}
+}
+
+// newPod creates the i-th pod of the namespace. index is unique across namespaces and determines the pod IP.
+func newPod(namespace string, i, index, numLabels int, nodeName string) *corev1.Pod {
+	labels := make(map[string]string, numLabels)
+	for k := 0; k < numLabels; k++ {
+		labels[labelKey(k)] = labelValue(i + k)
+	}
+	ip := podIP(index)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName(i),
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{
+				{
+					Name:  "bench",
+					Ports: []corev1.ContainerPort{{Name: namedPort, ContainerPort: namedPortValue, Protocol: corev1.ProtocolTCP}},
+				},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase:  corev1.PodRunning,
+			PodIP:  ip,
+			PodIPs: []corev1.PodIP{{IP: ip}},
+		},
+	}
+}
+
+// podIP returns a unique IP in 10.0.0.0/8 for the index.
+func podIP(index int) string {
+	return fmt.Sprintf("10.%d.%d.%d", (index>>16)&0xff, (index>>8)&0xff, index&0xff) //nolint:gomnd // octets
+}
+
+// newNetworkPolicy creates the i-th policy of the namespace. The policies select pods by label, and allow ingress
+// from pods of a group of namespaces on a numeric and a named port, and egress to the pod CIDR except the first pod.
+// Named ports and except CIDRs are left out on Windows, where they are not supported.
+func newNetworkPolicy(namespace string, i, numLabels int) *networkingv1.NetworkPolicy {
+	podSelector := metav1.LabelSelector{}
+	peerSelector := &metav1.LabelSelector{}
+	if numLabels > 0 {
+		podSelector.MatchLabels = map[string]string{labelKey(0): labelValue(i)}
+		peerSelector.MatchLabels = map[string]string{labelKey(numLabels - 1): labelValue(i + 1)}
+	}
+	tcp := corev1.ProtocolTCP
+	port := intstr.FromInt(basePort + i%numPorts)
+	ports := []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}}
+	ipBlock := &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}
+	if !util.IsWindowsDP() {
+		named := intstr.FromString(namedPort)
+		ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: &named})
+		ipBlock.Except = []string{podIP(0) + "/32"}
+	}
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      networkPolicyName(i),
+			Namespace: namespace,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: podSelector,
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						{
+							PodSelector: peerSelector,
+							NamespaceSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{groupLabel: fmt.Sprintf("group-%d", i%numGroups)},
+							},
+						},
+					},
+					Ports: ports,
+				},
+			},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{
+					To: []networkingv1.NetworkPolicyPeer{
+						{IPBlock: ipBlock},
+					},
+				},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+}
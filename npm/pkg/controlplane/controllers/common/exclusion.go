@@ -0,0 +1,124 @@
+package common
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	coreinformer "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NamespaceExclusion decides which namespaces are ignored by the controllers.
+// A namespace is excluded if it is in the list of names, or if its labels match the label selector.
+// Since the labels of a namespace can change, listeners registered with OnChange are notified
+// when a namespace starts or stops being excluded, so that its pods and policies can be resynced.
+// A nil NamespaceExclusion excludes nothing.
+type NamespaceExclusion struct {
+	names     map[string]struct{}
+	selector  k8slabels.Selector
+	nsLister  corelisters.NamespaceLister
+	listeners []func(namespace string)
+}
+
+// NewNamespaceExclusion creates a NamespaceExclusion from the list of namespace names and the label selector.
+// An empty label selector matches no namespaces.
+func NewNamespaceExclusion(names []string, labelSelector string, nsInformer coreinformer.NamespaceInformer) (*NamespaceExclusion, error) {
+	e := &NamespaceExclusion{
+		names:    make(map[string]struct{}, len(names)),
+		nsLister: nsInformer.Lister(),
+	}
+	for _, name := range names {
+		e.names[name] = struct{}{}
+	}
+	if labelSelector != "" {
+		selector, err := k8slabels.Parse(labelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse namespace exclusion label selector %q: %w", labelSelector, err)
+		}
+		e.selector = selector
+	}
+
+	nsInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    e.addNamespace,
+			UpdateFunc: e.updateNamespace,
+		},
+	)
+	return e, nil
+}
+
+// OnChange registers a listener which is called with the name of a namespace whenever it starts or stops being excluded.
+// Listeners must be registered before the namespace informer is started.
+func (e *NamespaceExclusion) OnChange(listener func(namespace string)) {
+	if e == nil {
+		return
+	}
+	e.listeners = append(e.listeners, listener)
+}
+
+// IsExcluded returns true if the controllers should ignore the namespace.
+func (e *NamespaceExclusion) IsExcluded(namespace string) bool {
+	if e == nil {
+		return false
+	}
+	if _, ok := e.names[namespace]; ok {
+		return true
+	}
+	if e.selector == nil {
+		return false
+	}
+	nsObj, err := e.nsLister.Get(namespace)
+	if err != nil {
+		// the namespace is not known yet, so it can't be matched by the selector.
+		// if it is excluded, listeners will be notified once its labels are seen.
+		return false
+	}
+	return e.matches(nsObj)
+}
+
+func (e *NamespaceExclusion) matches(nsObj *corev1.Namespace) bool {
+	return e.selector != nil && e.selector.Matches(k8slabels.Set(nsObj.Labels))
+}
+
+func (e *NamespaceExclusion) isExcludedNamespace(nsObj *corev1.Namespace) bool {
+	if _, ok := e.names[nsObj.Name]; ok {
+		return true
+	}
+	return e.matches(nsObj)
+}
+
+// addNamespace notifies the listeners of namespaces excluded by the label selector, since their pods and
+// policies may have been synced before the namespace labels were known.
+func (e *NamespaceExclusion) addNamespace(obj interface{}) {
+	nsObj, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	if _, ok := e.names[nsObj.Name]; ok || !e.matches(nsObj) {
+		return
+	}
+	e.notify(nsObj.Name)
+}
+
+func (e *NamespaceExclusion) updateNamespace(old, newns interface{}) {
+	oldNsObj, ok := old.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	newNsObj, ok := newns.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	if e.isExcludedNamespace(oldNsObj) == e.isExcludedNamespace(newNsObj) {
+		return
+	}
+	e.notify(newNsObj.Name)
+}
+
+func (e *NamespaceExclusion) notify(namespace string) {
+	for _, listener := range e.listeners {
+		listener(namespace)
+	}
+}
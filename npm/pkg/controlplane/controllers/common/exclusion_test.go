@@ -0,0 +1,58 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestNamespaceExclusion(t *testing.T) {
+	kubeInformer := kubeinformers.NewSharedInformerFactory(k8sfake.NewSimpleClientset(), 0)
+	nsInformer := kubeInformer.Core().V1().Namespaces()
+	logging := namespace("logging", map[string]string{"npm": "exclude"})
+	require.NoError(t, nsInformer.Informer().GetIndexer().Add(logging))
+	require.NoError(t, nsInformer.Informer().GetIndexer().Add(namespace("default", nil)))
+
+	e, err := NewNamespaceExclusion([]string{"kube-system"}, "npm=exclude", nsInformer)
+	require.NoError(t, err)
+
+	assert.True(t, e.IsExcluded("kube-system"))
+	assert.True(t, e.IsExcluded("logging"))
+	assert.False(t, e.IsExcluded("default"))
+	assert.False(t, e.IsExcluded("unknown"))
+
+	var changed []string
+	e.OnChange(func(ns string) { changed = append(changed, ns) })
+
+	// namespaces which start or stop matching the selector are notified.
+	e.updateNamespace(namespace("default", nil), namespace("default", map[string]string{"npm": "exclude"}))
+	e.updateNamespace(logging, namespace("logging", nil))
+	// namespaces whose exclusion does not change are not.
+	e.updateNamespace(namespace("kube-system", nil), namespace("kube-system", map[string]string{"npm": "exclude"}))
+	e.updateNamespace(namespace("other", nil), namespace("other", map[string]string{"app": "other"}))
+	// new namespaces excluded by the selector are notified.
+	e.addNamespace(namespace("new", map[string]string{"npm": "exclude"}))
+	e.addNamespace(namespace("kube-system", nil))
+	assert.Equal(t, []string{"default", "logging", "new"}, changed)
+}
+
+func TestNamespaceExclusionInvalidSelector(t *testing.T) {
+	kubeInformer := kubeinformers.NewSharedInformerFactory(k8sfake.NewSimpleClientset(), 0)
+	_, err := NewNamespaceExclusion(nil, "npm in (", kubeInformer.Core().V1().Namespaces())
+	require.Error(t, err)
+}
+
+func TestNilNamespaceExclusion(t *testing.T) {
+	var e *NamespaceExclusion
+	e.OnChange(func(string) {})
+	assert.False(t, e.IsExcluded("kube-system"))
+}
@@ -21,7 +21,6 @@ import (
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
-	"k8s.io/klog"
 )
 
 // NpmNamespaceCache to store namespace struct in nameSpaceController.go.
@@ -93,7 +92,7 @@ func (nsc *NamespaceController) needSync(obj interface{}, event string) (string,
 		return key, needSync
 	}
 
-	klog.Infof("[NAMESPACE %s EVENT] for namespace [%s]", event, key)
+	logger.Infof("[NAMESPACE %s EVENT] for namespace [%s]", event, key)
 
 	needSync = true
 	return key, needSync
@@ -102,7 +101,7 @@ func (nsc *NamespaceController) needSync(obj interface{}, event string) (string,
 func (nsc *NamespaceController) addNamespace(obj interface{}) {
 	key, needSync := nsc.needSync(obj, "ADD")
 	if !needSync {
-		klog.Infof("[NAMESPACE ADD EVENT] No need to sync this namespace [%s]", key)
+		logger.Infof("[NAMESPACE ADD EVENT] No need to sync this namespace [%s]", key)
 		return
 	}
 	nsc.workqueue.Add(key)
@@ -111,7 +110,7 @@ func (nsc *NamespaceController) addNamespace(obj interface{}) {
 func (nsc *NamespaceController) updateNamespace(old, newns interface{}) {
 	key, needSync := nsc.needSync(newns, "UPDATE")
 	if !needSync {
-		klog.Infof("[NAMESPACE UPDATE EVENT] No need to sync this namespace [%s]", key)
+		logger.Infof("[NAMESPACE UPDATE EVENT] No need to sync this namespace [%s]", key)
 		return
 	}
 
@@ -119,7 +118,7 @@ func (nsc *NamespaceController) updateNamespace(old, newns interface{}) {
 	oldNsObj, ok := old.(*corev1.Namespace)
 	if ok {
 		if oldNsObj.ResourceVersion == nsObj.ResourceVersion {
-			klog.Infof("[NAMESPACE UPDATE EVENT] Resourceversion is same for this namespace [%s]", key)
+			logger.Infof("[NAMESPACE UPDATE EVENT] Resourceversion is same for this namespace [%s]", key)
 			return
 		}
 	}
@@ -161,14 +160,14 @@ func (nsc *NamespaceController) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer nsc.workqueue.ShutDown()
 
-	klog.Info("Starting Namespace controller\n")
-	klog.Info("Starting workers")
+	logger.Info("Starting Namespace controller\n")
+	logger.Info("Starting workers")
 	// Launch workers to process namespace resources
 	go wait.Until(nsc.runWorker, time.Second, stopCh)
 
-	klog.Info("Started workers")
+	logger.Info("Started workers")
 	<-stopCh
-	klog.Info("Shutting down workers")
+	logger.Info("Shutting down workers")
 }
 
 func (nsc *NamespaceController) runWorker() {
@@ -206,7 +205,7 @@ func (nsc *NamespaceController) processNextWorkItem() bool {
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
 		nsc.workqueue.Forget(obj)
-		klog.Infof("Successfully synced '%s'", key)
+		logger.Infof("Successfully synced '%s'", key)
 		return nil
 	}(obj)
 	if err != nil {
@@ -241,7 +240,7 @@ func (nsc *NamespaceController) syncNameSpace(key string) error {
 	defer nsc.npmNamespaceCache.Unlock()
 	if err != nil {
 		if errors.IsNotFound(err) {
-			klog.Infof("NameSpace %s not found, may be it is deleted", key)
+			logger.Infof("NameSpace %s not found, may be it is deleted", key)
 
 			if _, ok := nsc.npmNamespaceCache.NsMap[cachedNsKey]; ok {
 				// record time to delete namespace if it exists (can't call within cleanDeletedNamespace because this can be called by a pod update)
@@ -271,7 +270,7 @@ func (nsc *NamespaceController) syncNameSpace(key string) error {
 	cachedNsObj, nsExists := nsc.npmNamespaceCache.NsMap[cachedNsKey]
 	if nsExists {
 		if k8slabels.Equals(cachedNsObj.LabelsMap, nsObj.ObjectMeta.Labels) {
-			klog.Infof("[NAMESPACE UPDATE EVENT] Namespace [%s] labels did not change", key)
+			logger.Infof("[NAMESPACE UPDATE EVENT] Namespace [%s] labels did not change", key)
 			return nil
 		}
 	}
@@ -289,7 +288,7 @@ func (nsc *NamespaceController) syncNameSpace(key string) error {
 func (nsc *NamespaceController) syncAddNameSpace(nsObj *corev1.Namespace) error {
 	var err error
 	corev1NsName, corev1NsLabels := util.GetNSNameWithPrefix(nsObj.ObjectMeta.Name), nsObj.ObjectMeta.Labels
-	klog.Infof("NAMESPACE CREATING: [%s/%v]", corev1NsName, corev1NsLabels)
+	logger.Infof("NAMESPACE CREATING: [%s/%v]", corev1NsName, corev1NsLabels)
 
 	// Create ipset for the namespace.
 	if err = nsc.ipsMgr.CreateSet(corev1NsName, []string{util.IpsetNetHashFlag}); err != nil {
@@ -308,14 +307,14 @@ func (nsc *NamespaceController) syncAddNameSpace(nsObj *corev1.Namespace) error
 	// Add the namespace to its label's ipset list.
 	for nsLabelKey, nsLabelVal := range corev1NsLabels {
 		labelIpsetName := util.GetNSNameWithPrefix(nsLabelKey)
-		klog.Infof("Adding namespace %s to ipset list %s", corev1NsName, labelIpsetName)
+		logger.Infof("Adding namespace %s to ipset list %s", corev1NsName, labelIpsetName)
 		if err = nsc.ipsMgr.AddToList(labelIpsetName, corev1NsName); err != nil {
 			metrics.SendErrorLogAndMetric(util.NSID, "[AddNamespace] Error: failed to add namespace %s to ipset list %s with err: %v", corev1NsName, labelIpsetName, err)
 			return err
 		}
 
 		labelIpsetName = util.GetNSNameWithPrefix(util.GetIpSetFromLabelKV(nsLabelKey, nsLabelVal))
-		klog.Infof("Adding namespace %s to ipset list %s", corev1NsName, labelIpsetName)
+		logger.Infof("Adding namespace %s to ipset list %s", corev1NsName, labelIpsetName)
 		if err = nsc.ipsMgr.AddToList(labelIpsetName, corev1NsName); err != nil {
 			metrics.SendErrorLogAndMetric(util.NSID, "[AddNamespace] Error: failed to add namespace %s to ipset list %s with err: %v", corev1NsName, labelIpsetName, err)
 			return err
@@ -332,7 +331,7 @@ func (nsc *NamespaceController) syncAddNameSpace(nsObj *corev1.Namespace) error
 func (nsc *NamespaceController) syncUpdateNameSpace(newNsObj *corev1.Namespace) (metrics.OperationKind, error) {
 	var err error
 	newNsName, newNsLabel := util.GetNSNameWithPrefix(newNsObj.ObjectMeta.Name), newNsObj.ObjectMeta.Labels
-	klog.Infof("NAMESPACE UPDATING:\n namespace: [%s/%v]", newNsName, newNsLabel)
+	logger.Infof("NAMESPACE UPDATING:\n namespace: [%s/%v]", newNsName, newNsLabel)
 
 	// If previous syncAddNameSpace failed for some reasons
 	// before caching npm namespace object or syncUpdateNameSpace is called due to namespace creation event,
@@ -354,7 +353,7 @@ func (nsc *NamespaceController) syncUpdateNameSpace(newNsObj *corev1.Namespace)
 	// Delete the namespace from its label's ipset list.
 	for _, nsLabelVal := range deleteFromIPSets {
 		labelKey := util.GetNSNameWithPrefix(nsLabelVal)
-		klog.Infof("Deleting namespace %s from ipset list %s", newNsName, labelKey)
+		logger.Infof("Deleting namespace %s from ipset list %s", newNsName, labelKey)
 		if err = nsc.ipsMgr.DeleteFromList(labelKey, newNsName); err != nil {
 			metrics.SendErrorLogAndMetric(util.NSID, "[UpdateNamespace] Error: failed to delete namespace %s from ipset list %s with err: %v", newNsName, labelKey, err)
 			return metrics.UpdateOp, fmt.Errorf("failed to delete namespace %s from ipset list %s with err: %w", newNsName, labelKey, err)
@@ -371,7 +370,7 @@ func (nsc *NamespaceController) syncUpdateNameSpace(newNsObj *corev1.Namespace)
 	// Add the namespace to its label's ipset list.
 	for _, nsLabelVal := range addToIPSets {
 		labelKey := util.GetNSNameWithPrefix(nsLabelVal)
-		klog.Infof("Adding namespace %s to ipset list %s", newNsName, labelKey)
+		logger.Infof("Adding namespace %s to ipset list %s", newNsName, labelKey)
 		if err = nsc.ipsMgr.AddToList(labelKey, newNsName); err != nil {
 			metrics.SendErrorLogAndMetric(util.NSID, "[UpdateNamespace] Error: failed to add namespace %s to ipset list %s with err: %v", newNsName, labelKey, err)
 			return metrics.UpdateOp, fmt.Errorf("failed to add namespace %s to ipset list %s with err: %w", newNsName, labelKey, err)
@@ -395,26 +394,26 @@ func (nsc *NamespaceController) syncUpdateNameSpace(newNsObj *corev1.Namespace)
 
 // cleanDeletedNamespace handles deleting namespace from ipset.
 func (nsc *NamespaceController) cleanDeletedNamespace(cachedNsKey string) error {
-	klog.Infof("NAMESPACE DELETING: [%s]", cachedNsKey)
+	logger.Infof("NAMESPACE DELETING: [%s]", cachedNsKey)
 	cachedNsObj, exists := nsc.npmNamespaceCache.NsMap[cachedNsKey]
 	if !exists {
 		return nil
 	}
 
-	klog.Infof("NAMESPACE DELETING cached labels: [%s/%v]", cachedNsKey, cachedNsObj.LabelsMap)
+	logger.Infof("NAMESPACE DELETING cached labels: [%s/%v]", cachedNsKey, cachedNsObj.LabelsMap)
 
 	var err error
 	// Delete the namespace from its label's ipset list.
 	for nsLabelKey, nsLabelVal := range cachedNsObj.LabelsMap {
 		labelIpsetName := util.GetNSNameWithPrefix(nsLabelKey)
-		klog.Infof("Deleting namespace %s from ipset list %s", cachedNsKey, labelIpsetName)
+		logger.Infof("Deleting namespace %s from ipset list %s", cachedNsKey, labelIpsetName)
 		if err = nsc.ipsMgr.DeleteFromList(labelIpsetName, cachedNsKey); err != nil {
 			metrics.SendErrorLogAndMetric(util.NSID, "[DeleteNamespace] Error: failed to delete namespace %s from ipset list %s with err: %v", cachedNsKey, labelIpsetName, err)
 			return err
 		}
 
 		labelIpsetName = util.GetNSNameWithPrefix(util.GetIpSetFromLabelKV(nsLabelKey, nsLabelVal))
-		klog.Infof("Deleting namespace %s from ipset list %s", cachedNsKey, labelIpsetName)
+		logger.Infof("Deleting namespace %s from ipset list %s", cachedNsKey, labelIpsetName)
 		if err = nsc.ipsMgr.DeleteFromList(labelIpsetName, cachedNsKey); err != nil {
 			metrics.SendErrorLogAndMetric(util.NSID, "[DeleteNamespace] Error: failed to delete namespace %s from ipset list %s with err: %v", cachedNsKey, labelIpsetName, err)
 			return err
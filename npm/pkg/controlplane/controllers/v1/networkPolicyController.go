@@ -10,6 +10,7 @@ import (
 	"github.com/Azure/azure-container-networking/npm/ipsm"
 	"github.com/Azure/azure-container-networking/npm/iptm"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -19,10 +20,11 @@ import (
 	netpollister "k8s.io/client-go/listers/networking/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
-	"k8s.io/klog"
 	"k8s.io/utils/exec"
 )
 
+var logger = npmlogger.For(npmlogger.Controllers)
+
 // IsSafeCleanUpAzureNpmChain is used to indicate whether default Azure NPM chain can be safely deleted or not.
 type IsSafeCleanUpAzureNpmChain bool
 
@@ -67,7 +69,7 @@ func NewNetworkPolicyController(npInformer networkinginformers.NetworkPolicyInfo
 // BootupDataplane does all initialization tasks for data plane
 // TODO(jungukcho) Need to refactor UninitNpmChains since it assumes it has already AZURE-NPM chains
 func (c *NetworkPolicyController) BootupDataplane() error {
-	klog.Infof("Initiailize data plane. Clean up Azure-NPM chains and start reconcile iptables")
+	logger.Infof("Initiailize data plane. Clean up Azure-NPM chains and start reconcile iptables")
 
 	// TODO(jungukcho): will clean-up error handling codes to initialize iptables and ipset in a separate PR
 	// It is important to keep order to clean-up iptables and ipset.
@@ -177,12 +179,12 @@ func (c *NetworkPolicyController) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.workqueue.ShutDown()
 
-	klog.Infof("Starting Network Policy worker")
+	logger.Infof("Starting Network Policy worker")
 	go wait.Until(c.runWorker, time.Second, stopCh)
 
-	klog.Infof("Started Network Policy worker")
+	logger.Infof("Started Network Policy worker")
 	<-stopCh
-	klog.Info("Shutting down Network Policy workers")
+	logger.Info("Shutting down Network Policy workers")
 }
 
 func (c *NetworkPolicyController) runWorker() {
@@ -219,7 +221,7 @@ func (c *NetworkPolicyController) processNextWorkItem() bool {
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
 		c.workqueue.Forget(obj)
-		klog.Infof("Successfully synced '%s'", key)
+		logger.Infof("Successfully synced '%s'", key)
 		return nil
 	}(obj)
 	if err != nil {
@@ -253,7 +255,7 @@ func (c *NetworkPolicyController) syncNetPol(key string) error {
 	netPolObj, err := c.netPolLister.NetworkPolicies(namespace).Get(name)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			klog.Infof("Network Policy %s is not found, may be it is deleted", key)
+			logger.Infof("Network Policy %s is not found, may be it is deleted", key)
 
 			if _, ok := c.rawNpMap[key]; ok {
 				// record time to delete policy if it exists (can't call within cleanUpNetworkPolicy because this can be called by a pod update)
@@ -373,13 +375,13 @@ func (c *NetworkPolicyController) syncAddAndUpdateNetPol(netPolObj *networkingv1
 
 	sets, namedPorts, lists, ingressIPCidrs, egressIPCidrs, iptEntries := translatePolicy(netPolObj)
 	for _, set := range sets {
-		klog.Infof("Creating set: %v, hashedSet: %v", set, util.GetHashedName(set))
+		logger.Infof("Creating set: %v, hashedSet: %v", set, util.GetHashedName(set))
 		if err = c.ipsMgr.CreateSetNoLock(set, []string{util.IpsetNetHashFlag}); err != nil {
 			return operationKind, fmt.Errorf("[syncAddAndUpdateNetPol] Error: creating ipset %s with err: %w", set, err)
 		}
 	}
 	for _, set := range namedPorts {
-		klog.Infof("Creating set: %v, hashedSet: %v", set, util.GetHashedName(set))
+		logger.Infof("Creating set: %v, hashedSet: %v", set, util.GetHashedName(set))
 		if err = c.ipsMgr.CreateSetNoLock(set, []string{util.IpsetIPPortHashFlag}); err != nil {
 			return operationKind, fmt.Errorf("[syncAddAndUpdateNetPol] Error: creating ipset named port %s with err: %w", set, err)
 		}
@@ -490,7 +492,7 @@ func (c *NetworkPolicyController) createCidrsRule(direction, policyName, ns stri
 			continue
 		}
 		setName := policyName + "-in-ns-" + ns + "-" + strconv.Itoa(i) + direction
-		klog.Infof("Creating set: %v, hashedSet: %v", setName, util.GetHashedName(setName))
+		logger.Infof("Creating set: %v, hashedSet: %v", setName, util.GetHashedName(setName))
 		if err := c.ipsMgr.CreateSetNoLock(setName, spec); err != nil {
 			return fmt.Errorf("[createCidrsRule] Error: creating ipset %s with err: %w", ipCidrSet, err)
 		}
@@ -521,7 +523,7 @@ func (c *NetworkPolicyController) removeCidrsRule(direction, policyName, ns stri
 			continue
 		}
 		setName := policyName + "-in-ns-" + ns + "-" + strconv.Itoa(i) + direction
-		klog.Infof("Delete set: %v, hashedSet: %v", setName, util.GetHashedName(setName))
+		logger.Infof("Delete set: %v, hashedSet: %v", setName, util.GetHashedName(setName))
 		if err := c.ipsMgr.DeleteSet(setName); err != nil {
 			return fmt.Errorf("[removeCidrsRule] deleting ipset %s with err: %w", ipCidrSet, err)
 		}
@@ -7,7 +7,6 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm/util"
 )
 
@@ -208,7 +207,7 @@ func FlattenNameSpaceSelector(nsSelector *metav1.LabelSelector) []metav1.LabelSe
 			// since Exists and NotExists do not contain any values, NPM can safely add them to the baseSelector
 			baseSelector.MatchExpressions = append(baseSelector.MatchExpressions, req)
 		} else {
-			log.Errorf("Invalid operator [%s] for selector [%v] requirement", req.Operator, *nsSelector)
+			logger.Errorf("Invalid operator [%s] for selector [%v] requirement", req.Operator, *nsSelector)
 		}
 	}
 
@@ -311,7 +310,7 @@ func parseSelector(selector *metav1.LabelSelector) ([]string, map[string][]strin
 			k = util.IptablesNotFlag + req.Key
 			labels = append(labels, k)
 		default:
-			log.Errorf("Invalid operator [%s] for selector [%v] requirement", op, *selector)
+			logger.Errorf("Invalid operator [%s] for selector [%v] requirement", op, *selector)
 		}
 	}
 
@@ -7,7 +7,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm/iptm"
 	"github.com/Azure/azure-container-networking/npm/util"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -235,7 +234,7 @@ func translateIngress(ns string, policyName string, targetSelector metav1.LabelS
 		addedPortEntry  bool // add drop entries at the end of the chain when there are non ALLOW-ALL* rules
 	)
 
-	log.Logf("started parsing ingress rule")
+	logger.Infof("started parsing ingress rule")
 	netHashIPsets = append(netHashIPsets, "ns-"+ns)
 	ipCidrs = make([][]string, len(rules))
 	listIPsets = make(map[string][]string)
@@ -361,7 +360,7 @@ func translateIngress(ns string, policyName string, targetSelector metav1.LabelS
 					)
 					entries = append(entries, entry)
 				default:
-					log.Logf("Invalid NetworkPolicyPort.")
+					logger.Infof("Invalid NetworkPolicyPort.")
 				}
 			}
 			continue
@@ -459,7 +458,7 @@ func translateIngress(ns string, policyName string, targetSelector metav1.LabelS
 								)
 								fromRuleEntries = append(fromRuleEntries, entry)
 							default:
-								log.Logf("Invalid NetworkPolicyPort.")
+								logger.Infof("Invalid NetworkPolicyPort.")
 							}
 						}
 					} else {
@@ -579,7 +578,7 @@ func translateIngress(ns string, policyName string, targetSelector metav1.LabelS
 								)
 								entries = append(entries, entry)
 							default:
-								log.Logf("Invalid NetworkPolicyPort.")
+								logger.Infof("Invalid NetworkPolicyPort.")
 							}
 						}
 					} else {
@@ -689,7 +688,7 @@ func translateIngress(ns string, policyName string, targetSelector metav1.LabelS
 							)
 							entries = append(entries, entry)
 						default:
-							log.Logf("Invalid NetworkPolicyPort.")
+							logger.Infof("Invalid NetworkPolicyPort.")
 						}
 					}
 				} else {
@@ -811,7 +810,7 @@ func translateIngress(ns string, policyName string, targetSelector metav1.LabelS
 							)
 							entries = append(entries, entry)
 						default:
-							log.Logf("Invalid NetworkPolicyPort.")
+							logger.Infof("Invalid NetworkPolicyPort.")
 						}
 					}
 				} else {
@@ -873,7 +872,7 @@ func translateIngress(ns string, policyName string, targetSelector metav1.LabelS
 		entries = append(fromRuleEntries, entries...)
 	}
 
-	log.Logf("finished parsing ingress rule")
+	logger.Infof("finished parsing ingress rule")
 	return util.DropEmptyFields(netHashIPsets), util.DropEmptyFields(namedPorts), listIPsets, ipCidrs, entries
 }
 
@@ -889,7 +888,7 @@ func translateEgress(ns string, policyName string, targetSelector metav1.LabelSe
 		addedPortEntry bool // add drop entry when there are non ALLOW-ALL* rules
 	)
 
-	log.Logf("started parsing egress rule")
+	logger.Infof("started parsing egress rule")
 	netHashIPsets = append(netHashIPsets, "ns-"+ns)
 	ipCidrs = make([][]string, len(rules))
 	listIPsets = make(map[string][]string)
@@ -1011,7 +1010,7 @@ func translateEgress(ns string, policyName string, targetSelector metav1.LabelSe
 					)
 					entries = append(entries, entry)
 				default:
-					log.Logf("Invalid NetworkPolicyPort.")
+					logger.Infof("Invalid NetworkPolicyPort.")
 				}
 			}
 			continue
@@ -1109,7 +1108,7 @@ func translateEgress(ns string, policyName string, targetSelector metav1.LabelSe
 								)
 								toRuleEntries = append(toRuleEntries, entry)
 							default:
-								log.Logf("Invalid NetworkPolicyPort.")
+								logger.Infof("Invalid NetworkPolicyPort.")
 							}
 						}
 					} else {
@@ -1235,7 +1234,7 @@ func translateEgress(ns string, policyName string, targetSelector metav1.LabelSe
 								)
 								entries = append(entries, entry)
 							default:
-								log.Logf("Invalid NetworkPolicyPort.")
+								logger.Infof("Invalid NetworkPolicyPort.")
 							}
 						}
 					} else {
@@ -1344,7 +1343,7 @@ func translateEgress(ns string, policyName string, targetSelector metav1.LabelSe
 							)
 							entries = append(entries, entry)
 						default:
-							log.Logf("Invalid NetworkPolicyPort.")
+							logger.Infof("Invalid NetworkPolicyPort.")
 						}
 					}
 				} else {
@@ -1467,7 +1466,7 @@ func translateEgress(ns string, policyName string, targetSelector metav1.LabelSe
 							)
 							entries = append(entries, entry)
 						default:
-							log.Logf("Invalid NetworkPolicyPort.")
+							logger.Infof("Invalid NetworkPolicyPort.")
 						}
 					}
 				} else {
@@ -1530,7 +1529,7 @@ func translateEgress(ns string, policyName string, targetSelector metav1.LabelSe
 		entries = append(toRuleEntries, entries...)
 	}
 
-	log.Logf("finished parsing egress rule")
+	logger.Infof("finished parsing egress rule")
 	return util.DropEmptyFields(netHashIPsets), util.DropEmptyFields(namedPorts), listIPsets, ipCidrs, entries
 }
 
@@ -1598,12 +1597,12 @@ func translatePolicy(npObj *networkingv1.NetworkPolicy) ([]string, []string, map
 	)
 
 	defer func() {
-		log.Logf("Finished translatePolicy")
-		log.Logf("sets: %v", resultSets)
-		log.Logf("lists: %v", resultListMap)
-		log.Logf("entries: ")
+		logger.Infof("Finished translatePolicy")
+		logger.Infof("sets: %v", resultSets)
+		logger.Infof("lists: %v", resultListMap)
+		logger.Infof("entries: ")
 		for _, entry := range entries {
-			log.Logf("entry: %+v", entry)
+			logger.Infof("entry: %+v", entry)
 		}
 	}()
 
@@ -0,0 +1,230 @@
+// Copyright 2018 Microsoft. All rights reserved.
+// MIT License
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/policy/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var errAdminPolicyObjFormat = errors.New("invalid admin network policy object")
+
+// AdminNetworkPolicyController programs AdminNetworkPolicies and the BaselineAdminNetworkPolicy, which are watched
+// with a dynamic informer since they are not part of the core APIs.
+// Workqueue keys are the PolicyKeys of the NPMNetworkPolicies, i.e. <tier>/<name>.
+type AdminNetworkPolicyController struct {
+	anpLister  cache.GenericLister
+	banpLister cache.GenericLister
+	workqueue  workqueue.RateLimitingInterface
+	// rawSpecMap has the last applied spec of each policy by key
+	rawSpecMap map[string]interface{}
+	dp         dataplane.GenericDataplane
+}
+
+// NewAdminNetworkPolicyController creates an AdminNetworkPolicyController from informers of the
+// AdminNetworkPolicy and BaselineAdminNetworkPolicy resources.
+func NewAdminNetworkPolicyController(anpInformer, banpInformer informers.GenericInformer, dp dataplane.GenericDataplane) *AdminNetworkPolicyController {
+	c := &AdminNetworkPolicyController{
+		anpLister:  anpInformer.Lister(),
+		banpLister: banpInformer.Lister(),
+		workqueue:  workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "AdminNetworkPolicy"),
+		rawSpecMap: make(map[string]interface{}),
+		dp:         dp,
+	}
+
+	anpInformer.Informer().AddEventHandler(c.eventHandler(policies.AdminTier))
+	banpInformer.Informer().AddEventHandler(c.eventHandler(policies.BaselineTier))
+	return c
+}
+
+func (c *AdminNetworkPolicyController) eventHandler(tier policies.PolicyTier) cache.ResourceEventHandlerFuncs {
+	enqueue := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			metrics.SendErrorLogAndMetric(util.NetpolID, "[%s EVENT] Received unexpected object type: %v", tier, obj)
+			return
+		}
+		c.workqueue.Add(policyKey(tier, u.GetName()))
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(old, newObj interface{}) {
+			oldU, oldOK := old.(*unstructured.Unstructured)
+			newU, newOK := newObj.(*unstructured.Unstructured)
+			if oldOK && newOK && oldU.GetResourceVersion() == newU.GetResourceVersion() {
+				// Periodic resync will send update events for all known policies.
+				return
+			}
+			enqueue(newObj)
+		},
+		DeleteFunc: enqueue,
+	}
+}
+
+func policyKey(tier policies.PolicyTier, name string) string {
+	return fmt.Sprintf("%s/%s", tier, name)
+}
+
+func (c *AdminNetworkPolicyController) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	logger.Infof("Starting Admin Network Policy worker")
+	go wait.Until(c.runWorker, time.Second, stopCh)
+
+	logger.Infof("Started Admin Network Policy worker")
+	<-stopCh
+	logger.Info("Shutting down Admin Network Policy workers")
+}
+
+func (c *AdminNetworkPolicyController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *AdminNetworkPolicyController) processNextWorkItem() bool {
+	obj, shutdown := c.workqueue.Get()
+
+	if shutdown {
+		return false
+	}
+
+	err := func(obj interface{}) error {
+		defer c.workqueue.Done(obj)
+		var key string
+		var ok bool
+		if key, ok = obj.(string); !ok {
+			c.workqueue.Forget(obj)
+			utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v, err %w", obj, errWorkqueueFormatting))
+			return nil
+		}
+		if err := c.syncAdminPolicy(key); err != nil {
+			// Put the item back on the workqueue to handle any transient errors.
+			c.workqueue.AddRateLimited(key)
+			return fmt.Errorf("error syncing '%s': %w, requeuing", key, err)
+		}
+		c.workqueue.Forget(obj)
+		logger.Infof("Successfully synced '%s'", key)
+		return nil
+	}(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		metrics.SendErrorLogAndMetric(util.NetpolID, "syncAdminPolicy error due to %v", err)
+		return true
+	}
+
+	return true
+}
+
+// syncAdminPolicy compares the actual state with the desired, and attempts to converge the two.
+func (c *AdminNetworkPolicyController) syncAdminPolicy(key string) error {
+	tier, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s err: %w", key, errNetPolKeyFormat))
+		return nil //nolint HandleError  is used instead of returning error to caller
+	}
+
+	lister := c.anpLister
+	if policies.PolicyTier(tier) == policies.BaselineTier {
+		lister = c.banpLister
+	}
+	obj, err := lister.Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			logger.Infof("%s %s is not found, may be it is deleted", tier, name)
+			return c.cleanUpAdminPolicy(key)
+		}
+		return err
+	}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("%w: unexpected type %T", errAdminPolicyObjFormat, obj)
+	}
+	if u.GetDeletionTimestamp() != nil {
+		return c.cleanUpAdminPolicy(key)
+	}
+
+	spec, npmNetPol, err := c.translate(policies.PolicyTier(tier), u)
+	if err != nil {
+		if isUnsupportedWindowsTranslationErr(err) {
+			logger.Warnf("%s %s is not translated because it has unsupported translated features of Windows: %s", tier, name, err.Error())
+			return nil
+		}
+		// Returning nil to prevent re-queuing since this is not a transient error.
+		logger.Errorf("Failed to translate %s %s: %s", tier, name, err.Error())
+		return nil
+	}
+	if npmNetPol == nil {
+		return nil
+	}
+
+	if cachedSpec, ok := c.rawSpecMap[key]; ok && reflect.DeepEqual(cachedSpec, spec) {
+		return nil
+	}
+
+	// DP update policy call will check if this policy already exists in kernel
+	if err := c.dp.UpdatePolicy(npmNetPol); err != nil {
+		return fmt.Errorf("[syncAdminPolicy] Error: failed to update translated NPMNetworkPolicy into Dataplane due to %w", err)
+	}
+	c.rawSpecMap[key] = spec
+	return nil
+}
+
+// translate converts the object to its typed spec and translates it. It returns a nil policy for
+// BaselineAdminNetworkPolicies other than the one named "default", which are ignored.
+func (c *AdminNetworkPolicyController) translate(tier policies.PolicyTier, u *unstructured.Unstructured) (interface{}, *policies.NPMNetworkPolicy, error) {
+	if tier == policies.BaselineTier {
+		banp := &v1alpha1.BaselineAdminNetworkPolicy{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), banp); err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", errAdminPolicyObjFormat, err)
+		}
+		if banp.Name != v1alpha1.BaselineAdminNetworkPolicyName {
+			logger.Warnf("ignoring BaselineAdminNetworkPolicy %s, only the one named %s is applied", banp.Name, v1alpha1.BaselineAdminNetworkPolicyName)
+			return nil, nil, nil
+		}
+		npmNetPol, err := translation.TranslateBaselineAdminPolicy(banp)
+		return &banp.Spec, npmNetPol, err
+	}
+
+	anp := &v1alpha1.AdminNetworkPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), anp); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errAdminPolicyObjFormat, err)
+	}
+	npmNetPol, err := translation.TranslateAdminPolicy(anp)
+	return &anp.Spec, npmNetPol, err
+}
+
+// cleanUpAdminPolicy removes the policy from the dataplane if it was applied.
+func (c *AdminNetworkPolicyController) cleanUpAdminPolicy(key string) error {
+	if _, ok := c.rawSpecMap[key]; !ok {
+		return nil
+	}
+
+	if err := c.dp.RemovePolicy(key); err != nil {
+		return fmt.Errorf("[cleanUpAdminPolicy] Error: failed to remove policy due to %w", err)
+	}
+	delete(c.rawSpecMap, key)
+	return nil
+}
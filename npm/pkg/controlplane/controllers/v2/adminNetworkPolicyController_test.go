@@ -0,0 +1,111 @@
+// Copyright 2018 Microsoft. All rights reserved.
+// MIT License
+package controllers
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/policy/v1alpha1"
+	dpmocks "github.com/Azure/azure-container-networking/npm/pkg/dataplane/mocks"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newAdminNetworkPolicyController(t *testing.T, dp *dpmocks.MockGenericDataplane) (*AdminNetworkPolicyController, dynamicinformer.DynamicSharedInformerFactory) {
+	t.Helper()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		v1alpha1.AdminNetworkPolicyResource:         "AdminNetworkPolicyList",
+		v1alpha1.BaselineAdminNetworkPolicyResource: "BaselineAdminNetworkPolicyList",
+	})
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	c := NewAdminNetworkPolicyController(
+		factory.ForResource(v1alpha1.AdminNetworkPolicyResource),
+		factory.ForResource(v1alpha1.BaselineAdminNetworkPolicyResource),
+		dp,
+	)
+	return c, factory
+}
+
+func toUnstructured(t *testing.T, obj interface{}) *unstructured.Unstructured {
+	t.Helper()
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	require.NoError(t, err)
+	return &unstructured.Unstructured{Object: content}
+}
+
+func TestSyncAdminPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	c, factory := newAdminNetworkPolicyController(t, dp)
+	anpIndexer := factory.ForResource(v1alpha1.AdminNetworkPolicyResource).Informer().GetIndexer()
+
+	anp := &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "deny-all", ResourceVersion: "1"},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: 5,
+			Subject:  v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+				{
+					Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+					From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+				},
+			},
+		},
+	}
+	require.NoError(t, anpIndexer.Add(toUnstructured(t, anp)))
+	key := "AdminNetworkPolicy/deny-all"
+
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(policy *policies.NPMNetworkPolicy) error {
+		require.Equal(t, key, policy.PolicyKey)
+		require.Equal(t, int32(5), policy.Priority)
+		require.Len(t, policy.ACLs, 1)
+		return nil
+	}).Times(1)
+	require.NoError(t, c.syncAdminPolicy(key))
+	require.Contains(t, c.rawSpecMap, key)
+
+	// the spec didn't change (will be a no-op)
+	require.NoError(t, c.syncAdminPolicy(key))
+
+	dp.EXPECT().RemovePolicy(key).Return(nil).Times(1)
+	require.NoError(t, anpIndexer.Delete(toUnstructured(t, anp)))
+	require.NoError(t, c.syncAdminPolicy(key))
+	require.NotContains(t, c.rawSpecMap, key)
+}
+
+func TestSyncBaselineAdminPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	c, factory := newAdminNetworkPolicyController(t, dp)
+	banpIndexer := factory.ForResource(v1alpha1.BaselineAdminNetworkPolicyResource).Informer().GetIndexer()
+
+	spec := v1alpha1.BaselineAdminNetworkPolicySpec{
+		Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+		Egress: []v1alpha1.BaselineAdminNetworkPolicyEgressRule{
+			{
+				Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+				To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Networks: []v1alpha1.CIDR{"10.0.0.0/8"}}},
+			},
+		},
+	}
+	for _, name := range []string{v1alpha1.BaselineAdminNetworkPolicyName, "other"} {
+		banp := &v1alpha1.BaselineAdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: spec}
+		require.NoError(t, banpIndexer.Add(toUnstructured(t, banp)))
+	}
+
+	dp.EXPECT().UpdatePolicy(gomock.Any()).Times(1)
+	require.NoError(t, c.syncAdminPolicy("BaselineAdminNetworkPolicy/default"))
+
+	// only the BaselineAdminNetworkPolicy named default is applied
+	require.NoError(t, c.syncAdminPolicy("BaselineAdminNetworkPolicy/other"))
+	require.Len(t, c.rawSpecMap, 1)
+}
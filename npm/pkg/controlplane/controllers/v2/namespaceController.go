@@ -23,7 +23,6 @@ import (
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
-	"k8s.io/klog"
 )
 
 var errWorkqueueFormatting = errors.New("error in formatting")
@@ -59,14 +58,20 @@ type NamespaceController struct {
 	nameSpaceLister   corelisters.NamespaceLister
 	workqueue         workqueue.RateLimitingInterface
 	npmNamespaceCache *NpmNamespaceCache
+	exclusion         *common.NamespaceExclusion
 }
 
-func NewNamespaceController(nameSpaceInformer coreinformer.NamespaceInformer, dp dataplane.GenericDataplane, npmNamespaceCache *NpmNamespaceCache) *NamespaceController {
+// NewNamespaceController creates a NamespaceController. Namespaces excluded by the (optional) NamespaceExclusion
+// are removed from the cache and ipsets.
+func NewNamespaceController(nameSpaceInformer coreinformer.NamespaceInformer, dp dataplane.GenericDataplane, npmNamespaceCache *NpmNamespaceCache,
+	exclusion *common.NamespaceExclusion,
+) *NamespaceController {
 	nameSpaceController := &NamespaceController{
 		dp:                dp,
 		nameSpaceLister:   nameSpaceInformer.Lister(),
 		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Namespaces"),
 		npmNamespaceCache: npmNamespaceCache,
+		exclusion:         exclusion,
 	}
 
 	nameSpaceInformer.Informer().AddEventHandler(
@@ -164,14 +169,14 @@ func (nsc *NamespaceController) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer nsc.workqueue.ShutDown()
 
-	klog.Info("Starting Namespace controller\n")
-	klog.Info("Starting workers")
+	logger.Info("Starting Namespace controller\n")
+	logger.Info("Starting workers")
 	// Launch workers to process namespace resources
 	go wait.Until(nsc.runWorker, time.Second, stopCh)
 
-	klog.Info("Started workers")
+	logger.Info("Started workers")
 	<-stopCh
-	klog.Info("Shutting down workers")
+	logger.Info("Shutting down workers")
 }
 
 func (nsc *NamespaceController) runWorker() {
@@ -209,7 +214,7 @@ func (nsc *NamespaceController) processNextWorkItem() bool {
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
 		nsc.workqueue.Forget(obj)
-		klog.Infof("Successfully synced '%s'", key)
+		logger.Infof("Successfully synced '%s'", key)
 		return nil
 	}(obj)
 	if err != nil {
@@ -232,7 +237,7 @@ func (nsc *NamespaceController) syncNamespace(nsKey string) error {
 	operationKind := metrics.NoOp
 	defer func() {
 		if err != nil {
-			klog.Infof("[syncNamespace] failed to sync namespace, but will apply any changes to the dataplane. err: %s", err.Error())
+			logger.Infof("[syncNamespace] failed to sync namespace, but will apply any changes to the dataplane. err: %s", err.Error())
 		}
 
 		dperr := nsc.dp.ApplyDataPlane()
@@ -245,7 +250,7 @@ func (nsc *NamespaceController) syncNamespace(nsKey string) error {
 		metrics.RecordControllerNamespaceExecTime(timer, operationKind, err != nil && dperr != nil)
 
 		if dperr != nil {
-			klog.Errorf("failed to apply dataplane changes while syncing namespace. err: %s", dperr.Error())
+			logger.Errorf("failed to apply dataplane changes while syncing namespace. err: %s", dperr.Error())
 			metrics.SendErrorLogAndMetric(util.NSID, "[syncNamespace] failed to apply dataplane changes while syncing namespace. err: %s", dperr.Error())
 
 			// Seems like setting err below does nothing.
@@ -266,7 +271,7 @@ func (nsc *NamespaceController) syncNamespace(nsKey string) error {
 	defer nsc.npmNamespaceCache.Unlock()
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			klog.Infof("Namespace %s not found, may be it is deleted", nsKey)
+			logger.Infof("Namespace %s not found, may be it is deleted", nsKey)
 
 			if _, ok := nsc.npmNamespaceCache.NsMap[nsKey]; ok {
 				// record time to delete namespace if it exists (can't call within cleanDeletedNamespace because this can be called by a pod update)
@@ -285,7 +290,7 @@ func (nsc *NamespaceController) syncNamespace(nsKey string) error {
 		return err
 	}
 
-	if nsObj.DeletionTimestamp != nil || nsObj.DeletionGracePeriodSeconds != nil {
+	if nsObj.DeletionTimestamp != nil || nsObj.DeletionGracePeriodSeconds != nil || nsc.exclusion.IsExcluded(nsObj.Name) {
 		if _, ok := nsc.npmNamespaceCache.NsMap[nsKey]; ok {
 			// record time to delete namespace if it exists (can't call within cleanDeletedNamespace because this can be called by a pod update)
 			operationKind = metrics.DeleteOp
@@ -296,7 +301,7 @@ func (nsc *NamespaceController) syncNamespace(nsKey string) error {
 	cachedNsObj, nsExists := nsc.npmNamespaceCache.NsMap[nsKey]
 	if nsExists {
 		if k8slabels.Equals(cachedNsObj.LabelsMap, nsObj.ObjectMeta.Labels) {
-			klog.Infof("[NAMESPACE UPDATE EVENT] Namespace [%s] labels did not change", nsKey)
+			logger.Infof("[NAMESPACE UPDATE EVENT] Namespace [%s] labels did not change", nsKey)
 			return nil
 		}
 	}
@@ -321,7 +326,7 @@ func (nsc *NamespaceController) syncAddNamespace(nsObj *corev1.Namespace) error
 	// Add the namespace to its label's ipset list.
 	for nsLabelKey, nsLabelVal := range nsObj.ObjectMeta.Labels {
 		nsLabelKeyValue := util.GetIpSetFromLabelKV(nsLabelKey, nsLabelVal)
-		klog.Infof("Adding namespace %s to ipset list %s and %s", nsObj.ObjectMeta.Name, nsLabelKey, nsLabelKeyValue)
+		logger.Infof("Adding namespace %s to ipset list %s and %s", nsObj.ObjectMeta.Name, nsLabelKey, nsLabelKeyValue)
 		labelIPSets := []*ipsets.IPSetMetadata{
 			ipsets.NewIPSetMetadata(nsLabelKey, ipsets.KeyLabelOfNamespace),
 			ipsets.NewIPSetMetadata(nsLabelKeyValue, ipsets.KeyValueLabelOfNamespace),
@@ -344,7 +349,7 @@ func (nsc *NamespaceController) syncAddNamespace(nsObj *corev1.Namespace) error
 func (nsc *NamespaceController) syncUpdateNamespace(newNsObj *corev1.Namespace) (metrics.OperationKind, error) {
 	var err error
 	newNsName, newNsLabel := newNsObj.ObjectMeta.Name, newNsObj.ObjectMeta.Labels
-	klog.Infof("NAMESPACE UPDATING:\n namespace: [%s/%v]", newNsName, newNsLabel)
+	logger.Infof("NAMESPACE UPDATING:\n namespace: [%s/%v]", newNsName, newNsLabel)
 
 	// If previous syncAddNamespace failed for some reasons
 	// before caching npm namespace object or syncUpdateNamespace is called due to namespace creation event,
@@ -373,7 +378,7 @@ func (nsc *NamespaceController) syncUpdateNamespace(newNsObj *corev1.Namespace)
 		}
 		toBeRemoved := []*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(newNsName, ipsets.Namespace)}
 
-		klog.Infof("Deleting namespace %s from ipset list %s", newNsName, nsLabelVal)
+		logger.Infof("Deleting namespace %s from ipset list %s", newNsName, nsLabelVal)
 		if err = nsc.dp.RemoveFromList(labelSet, toBeRemoved); err != nil {
 			metrics.SendErrorLogAndMetric(util.NSID, "[UpdateNamespace] Error: failed to delete namespace %s from ipset list %s with err: %v", newNsName, nsLabelVal, err)
 			return metrics.UpdateOp, fmt.Errorf("failed to remove from list during sync update namespace with err %w", err)
@@ -389,7 +394,7 @@ func (nsc *NamespaceController) syncUpdateNamespace(newNsObj *corev1.Namespace)
 
 	// Add the namespace to its label's ipset list.
 	for _, nsLabelVal := range addToIPSets {
-		klog.Infof("Adding namespace %s to ipset list %s", newNsName, nsLabelVal)
+		logger.Infof("Adding namespace %s to ipset list %s", newNsName, nsLabelVal)
 
 		var labelSet []*ipsets.IPSetMetadata
 		if util.IsKeyValueLabelSetName(nsLabelVal) {
@@ -422,13 +427,13 @@ func (nsc *NamespaceController) syncUpdateNamespace(newNsObj *corev1.Namespace)
 
 // cleanDeletedNamespace handles deleting namespace from ipset.
 func (nsc *NamespaceController) cleanDeletedNamespace(cachedNsKey string) error {
-	klog.Infof("NAMESPACE DELETING: [%s]", cachedNsKey)
+	logger.Infof("NAMESPACE DELETING: [%s]", cachedNsKey)
 	cachedNsObj, exists := nsc.npmNamespaceCache.NsMap[cachedNsKey]
 	if !exists {
 		return nil
 	}
 
-	klog.Infof("NAMESPACE DELETING cached labels: [%s/%v]", cachedNsKey, cachedNsObj.LabelsMap)
+	logger.Infof("NAMESPACE DELETING cached labels: [%s/%v]", cachedNsKey, cachedNsObj.LabelsMap)
 
 	var err error
 	toBeDeletedNs := []*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(cachedNsKey, ipsets.Namespace)}
@@ -436,7 +441,7 @@ func (nsc *NamespaceController) cleanDeletedNamespace(cachedNsKey string) error
 	for nsLabelKey, nsLabelVal := range cachedNsObj.LabelsMap {
 
 		labelKey := ipsets.NewIPSetMetadata(nsLabelKey, ipsets.KeyLabelOfNamespace)
-		klog.Infof("Deleting namespace %s from ipset list %s", cachedNsKey, labelKey)
+		logger.Infof("Deleting namespace %s from ipset list %s", cachedNsKey, labelKey)
 		if err = nsc.dp.RemoveFromList(labelKey, toBeDeletedNs); err != nil {
 			metrics.SendErrorLogAndMetric(util.NSID, "[DeleteNamespace] Error: failed to delete namespace %s from ipset list %s with err: %v", cachedNsKey, labelKey, err)
 			return fmt.Errorf("failed to clean deleted namespace when deleting key with err %w", err)
@@ -444,7 +449,7 @@ func (nsc *NamespaceController) cleanDeletedNamespace(cachedNsKey string) error
 
 		labelIpsetName := util.GetIpSetFromLabelKV(nsLabelKey, nsLabelVal)
 		labelKeyValue := ipsets.NewIPSetMetadata(labelIpsetName, ipsets.KeyValueLabelOfNamespace)
-		klog.Infof("Deleting namespace %s from ipset list %s", cachedNsKey, labelIpsetName)
+		logger.Infof("Deleting namespace %s from ipset list %s", cachedNsKey, labelIpsetName)
 		if err = nsc.dp.RemoveFromList(labelKeyValue, toBeDeletedNs); err != nil {
 			metrics.SendErrorLogAndMetric(util.NSID, "[DeleteNamespace] Error: failed to delete namespace %s from ipset list %s with err: %v", cachedNsKey, labelIpsetName, err)
 			return fmt.Errorf("failed to clean deleted namespace when deleting key value with err %w", err)
@@ -93,7 +93,7 @@ func (f *nameSpaceFixture) newNsController(_ chan struct{}) {
 
 	npmNamespaceCache := &NpmNamespaceCache{NsMap: make(map[string]*common.Namespace)}
 	f.nsController = NewNamespaceController(
-		f.kubeInformer.Core().V1().Namespaces(), f.dp, npmNamespaceCache)
+		f.kubeInformer.Core().V1().Namespaces(), f.dp, npmNamespaceCache, nil)
 
 	for _, ns := range f.nsLister {
 		err := f.kubeInformer.Core().V1().Namespaces().Informer().GetIndexer().Add(ns)
@@ -10,23 +10,27 @@ import (
 	"time"
 
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
 	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
 	networkingv1 "k8s.io/api/networking/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	networkinginformers "k8s.io/client-go/informers/networking/v1"
 	netpollister "k8s.io/client-go/listers/networking/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
-	"k8s.io/klog"
 )
 
 var (
 	errNetPolKeyFormat          = errors.New("invalid network policy key format")
 	errNetPolTranslationFailure = errors.New("failed to translate network policy")
+
+	logger = npmlogger.For(npmlogger.Controllers)
 )
 
 type NetworkPolicyController struct {
@@ -35,6 +39,7 @@ type NetworkPolicyController struct {
 	workqueue    workqueue.RateLimitingInterface
 	rawNpSpecMap map[string]*networkingv1.NetworkPolicySpec // Key is <nsname>/<policyname>
 	dp           dataplane.GenericDataplane
+	exclusion    *common.NamespaceExclusion
 }
 
 func (c *NetworkPolicyController) GetCache() map[string]*networkingv1.NetworkPolicySpec {
@@ -43,13 +48,19 @@ func (c *NetworkPolicyController) GetCache() map[string]*networkingv1.NetworkPol
 	return c.rawNpSpecMap
 }
 
-func NewNetworkPolicyController(npInformer networkinginformers.NetworkPolicyInformer, dp dataplane.GenericDataplane) *NetworkPolicyController {
+// NewNetworkPolicyController creates a NetworkPolicyController. Network policies in namespaces excluded by the
+// (optional) NamespaceExclusion are ignored.
+func NewNetworkPolicyController(npInformer networkinginformers.NetworkPolicyInformer, dp dataplane.GenericDataplane,
+	exclusion *common.NamespaceExclusion,
+) *NetworkPolicyController {
 	netPolController := &NetworkPolicyController{
 		netPolLister: npInformer.Lister(),
 		workqueue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "NetworkPolicy"),
 		rawNpSpecMap: make(map[string]*networkingv1.NetworkPolicySpec),
 		dp:           dp,
+		exclusion:    exclusion,
 	}
+	exclusion.OnChange(netPolController.enqueueNamespace)
 
 	npInformer.Informer().AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
@@ -89,6 +100,11 @@ func (c *NetworkPolicyController) addNetworkPolicy(obj interface{}) {
 		return
 	}
 
+	netPolObj, _ := obj.(*networkingv1.NetworkPolicy)
+	if c.exclusion.IsExcluded(netPolObj.Namespace) {
+		return
+	}
+
 	c.workqueue.Add(netPolkey)
 }
 
@@ -101,6 +117,9 @@ func (c *NetworkPolicyController) updateNetworkPolicy(old, newnetpol interface{}
 
 	// new network policy object is already checked validation by calling getNetworkPolicyKey function.
 	newNetPol, _ := newnetpol.(*networkingv1.NetworkPolicy)
+	if c.exclusion.IsExcluded(newNetPol.Namespace) {
+		return
+	}
 	oldNetPol, ok := old.(*networkingv1.NetworkPolicy)
 	if ok {
 		if oldNetPol.ResourceVersion == newNetPol.ResourceVersion {
@@ -142,16 +161,34 @@ func (c *NetworkPolicyController) deleteNetworkPolicy(obj interface{}) {
 	c.workqueue.Add(netPolkey)
 }
 
+// enqueueNamespace enqueues all network policies in the namespace, so that they are synced when the namespace
+// starts or stops being excluded.
+func (c *NetworkPolicyController) enqueueNamespace(namespace string) {
+	netPols, err := c.netPolLister.NetworkPolicies(namespace).List(k8slabels.Everything())
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.NetpolID, "[NETPOL NAMESPACE EXCLUSION] failed to list network policies in %s: %v", namespace, err)
+		return
+	}
+	for _, netPolObj := range netPols {
+		netPolkey, err := c.getNetworkPolicyKey(netPolObj)
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+		c.workqueue.Add(netPolkey)
+	}
+}
+
 func (c *NetworkPolicyController) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.workqueue.ShutDown()
 
-	klog.Infof("Starting Network Policy worker")
+	logger.Infof("Starting Network Policy worker")
 	go wait.Until(c.runWorker, time.Second, stopCh)
 
-	klog.Infof("Started Network Policy worker")
+	logger.Infof("Started Network Policy worker")
 	<-stopCh
-	klog.Info("Shutting down Network Policy workers")
+	logger.Info("Shutting down Network Policy workers")
 }
 
 func (c *NetworkPolicyController) runWorker() {
@@ -188,7 +225,7 @@ func (c *NetworkPolicyController) processNextWorkItem() bool {
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
 		c.workqueue.Forget(obj)
-		klog.Infof("Successfully synced '%s'", key)
+		logger.Infof("Successfully synced '%s'", key)
 		return nil
 	}(obj)
 	if err != nil {
@@ -222,7 +259,7 @@ func (c *NetworkPolicyController) syncNetPol(key string) error {
 	netPolObj, err := c.netPolLister.NetworkPolicies(namespace).Get(name)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			klog.Infof("Network Policy %s is not found, may be it is deleted", key)
+			logger.Infof("Network Policy %s is not found, may be it is deleted", key)
 
 			if _, ok := c.rawNpSpecMap[key]; ok {
 				// record time to delete policy if it exists (can't call within cleanUpNetworkPolicy because this can be called by a pod update)
@@ -240,9 +277,10 @@ func (c *NetworkPolicyController) syncNetPol(key string) error {
 		return err
 	}
 
-	// If DeletionTimestamp of the netPolObj is set, start cleaning up lastly applied states.
+	// If DeletionTimestamp of the netPolObj is set, or its namespace is excluded, start cleaning up lastly applied states.
 	// This is early cleaning up process from updateNetPol event
-	if netPolObj.ObjectMeta.DeletionTimestamp != nil || netPolObj.ObjectMeta.DeletionGracePeriodSeconds != nil {
+	if netPolObj.ObjectMeta.DeletionTimestamp != nil || netPolObj.ObjectMeta.DeletionGracePeriodSeconds != nil ||
+		c.exclusion.IsExcluded(netPolObj.Namespace) {
 		if _, ok := c.rawNpSpecMap[key]; ok {
 			// record time to delete policy if it exists (can't call within cleanUpNetworkPolicy because this can be called by a pod update)
 			operationKind = metrics.DeleteOp
@@ -286,7 +324,7 @@ func (c *NetworkPolicyController) syncAddAndUpdateNetPol(netPolObj *networkingv1
 	npmNetPolObj, err := translation.TranslatePolicy(netPolObj)
 	if err != nil {
 		if isUnsupportedWindowsTranslationErr(err) {
-			klog.Warningf("NetworkPolicy %s in namespace %s is not translated because it has unsupported translated features of Windows: %s",
+			logger.Warnf("NetworkPolicy %s in namespace %s is not translated because it has unsupported translated features of Windows: %s",
 				netPolObj.ObjectMeta.Name, netPolObj.ObjectMeta.Namespace, err.Error())
 
 			// We can safely suppress unsupported network policy because re-Queuing will result in same error.
@@ -294,7 +332,7 @@ func (c *NetworkPolicyController) syncAddAndUpdateNetPol(netPolObj *networkingv1
 			return metrics.NoOp, nil
 		}
 
-		klog.Errorf("Failed to translate podSelector in NetworkPolicy %s in namespace %s: %s", netPolObj.ObjectMeta.Name, netPolObj.ObjectMeta.Namespace, err.Error())
+		logger.Errorf("Failed to translate podSelector in NetworkPolicy %s in namespace %s: %s", netPolObj.ObjectMeta.Name, netPolObj.ObjectMeta.Namespace, err.Error())
 		// The exec time isn't relevant here, so consider a no-op. Returning nil to prevent re-queuing since this is not a transient error.
 		return metrics.NoOp, nil
 	}
@@ -350,5 +388,7 @@ func isUnsupportedWindowsTranslationErr(err error) bool {
 	return errors.Is(err, translation.ErrUnsupportedNamedPort) ||
 		errors.Is(err, translation.ErrUnsupportedNegativeMatch) ||
 		errors.Is(err, translation.ErrUnsupportedSCTP) ||
-		errors.Is(err, translation.ErrUnsupportedExceptCIDR)
+		errors.Is(err, translation.ErrUnsupportedExceptCIDR) ||
+		errors.Is(err, translation.ErrUnsupportedPassAction) ||
+		errors.Is(err, translation.ErrUnsupportedAdminPriority)
 }
@@ -17,13 +17,13 @@ import (
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	coreinformer "k8s.io/client-go/informers/core/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
-	"k8s.io/klog"
 )
 
 // NamedPortOperation decides opeartion (e.g., delete or add) for named port ipset in manageNamedPortIpsets
@@ -46,16 +46,22 @@ type PodController struct {
 	podMap    map[string]*common.NpmPod // Key is <nsname>/<podname>
 	sync.RWMutex
 	npmNamespaceCache *NpmNamespaceCache
+	exclusion         *common.NamespaceExclusion
 }
 
-func NewPodController(podInformer coreinformer.PodInformer, dp dataplane.GenericDataplane, npmNamespaceCache *NpmNamespaceCache) *PodController {
+// NewPodController creates a PodController. Pods in namespaces excluded by the (optional) NamespaceExclusion are ignored.
+func NewPodController(podInformer coreinformer.PodInformer, dp dataplane.GenericDataplane, npmNamespaceCache *NpmNamespaceCache,
+	exclusion *common.NamespaceExclusion,
+) *PodController {
 	podController := &PodController{
 		podLister:         podInformer.Lister(),
 		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Pods"),
 		dp:                dp,
 		podMap:            make(map[string]*common.NpmPod),
 		npmNamespaceCache: npmNamespaceCache,
+		exclusion:         exclusion,
 	}
+	exclusion.OnChange(podController.enqueueNamespace)
 
 	podInformer.Informer().AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
@@ -103,6 +109,10 @@ func (c *PodController) needSync(eventType string, obj interface{}) (string, boo
 		return key, needSync
 	}
 
+	if c.exclusion.IsExcluded(podObj.Namespace) {
+		return key, needSync
+	}
+
 	var err error
 	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
 		utilruntime.HandleError(err)
@@ -170,7 +180,7 @@ func (c *PodController) deletePod(obj interface{}) {
 		}
 	}
 
-	klog.Infof("[POD DELETE EVENT] for %s in %s", podObj.Name, podObj.Namespace)
+	logger.Infof("[POD DELETE EVENT] for %s in %s", podObj.Name, podObj.Namespace)
 	if isHostNetworkPod(podObj) {
 		return
 	}
@@ -187,16 +197,36 @@ func (c *PodController) deletePod(obj interface{}) {
 	c.workqueue.Add(key)
 }
 
+// enqueueNamespace enqueues all Pods in the namespace, so that they are synced when the namespace starts or stops being excluded.
+func (c *PodController) enqueueNamespace(namespace string) {
+	pods, err := c.podLister.Pods(namespace).List(k8slabels.Everything())
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.PodID, "[POD NAMESPACE EXCLUSION] failed to list pods in %s: %v", namespace, err)
+		return
+	}
+	for _, podObj := range pods {
+		if isHostNetworkPod(podObj) {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(podObj)
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+		c.workqueue.Add(key)
+	}
+}
+
 func (c *PodController) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.workqueue.ShutDown()
 
-	klog.Infof("Starting Pod worker")
+	logger.Infof("Starting Pod worker")
 	go wait.Until(c.runWorker, time.Second, stopCh)
 
-	klog.Info("Started Pod workers")
+	logger.Info("Started Pod workers")
 	<-stopCh
-	klog.Info("Shutting down Pod workers")
+	logger.Info("Shutting down Pod workers")
 }
 
 func (c *PodController) runWorker() {
@@ -234,7 +264,7 @@ func (c *PodController) processNextWorkItem() bool {
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
 		c.workqueue.Forget(obj)
-		klog.Infof("Successfully synced '%s'", key)
+		logger.Infof("Successfully synced '%s'", key)
 		return nil
 	}(obj)
 	if err != nil {
@@ -264,7 +294,7 @@ func (c *PodController) syncPod(key string) error {
 	operationKind := metrics.NoOp
 	defer func() {
 		if err != nil {
-			klog.Infof("[syncPod] failed to sync pod, but will apply any changes to the dataplane. err: %s", err.Error())
+			logger.Infof("[syncPod] failed to sync pod, but will apply any changes to the dataplane. err: %s", err.Error())
 		}
 
 		dperr := c.dp.ApplyDataPlane()
@@ -273,7 +303,7 @@ func (c *PodController) syncPod(key string) error {
 		metrics.RecordControllerPodExecTime(timer, operationKind, err != nil && dperr != nil)
 
 		if dperr != nil {
-			klog.Errorf("failed to apply dataplane changes while syncing pod. err: %s", dperr.Error())
+			logger.Errorf("failed to apply dataplane changes while syncing pod. err: %s", dperr.Error())
 			metrics.SendErrorLogAndMetric(util.PodID, "[syncPod] failed to apply dataplane changes while syncing pod. err: %s", dperr.Error())
 
 			// Seems like setting err below does nothing.
@@ -294,7 +324,7 @@ func (c *PodController) syncPod(key string) error {
 
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			klog.Infof("pod %s not found, may be it is deleted", key)
+			logger.Infof("pod %s not found, may be it is deleted", key)
 
 			if _, ok := c.podMap[key]; ok {
 				// record time to delete pod if it exists (can't call within cleanUpDeletedPod because this can be called by a pod update)
@@ -315,9 +345,9 @@ func (c *PodController) syncPod(key string) error {
 	}
 
 	// If this pod is completely in terminated states (which means pod is gracefully shutdown),
-	// NPM starts clean-up the lastly applied states even in update events.
+	// or its namespace is excluded, NPM starts clean-up the lastly applied states even in update events.
 	// This proactive clean-up helps to miss stale pod object in case delete event is missed.
-	if isCompletePod(pod) {
+	if isCompletePod(pod) || c.exclusion.IsExcluded(pod.Namespace) {
 		if _, ok := c.podMap[key]; ok {
 			// record time to delete pod if it exists (can't call within cleanUpDeletedPod because this can be called by a pod update)
 			operationKind = metrics.DeleteOp
@@ -347,7 +377,7 @@ func (c *PodController) syncPod(key string) error {
 }
 
 func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
-	klog.Infof("POD CREATING: [%s/%s/%s/%s/%+v/%s]", string(podObj.GetUID()), podObj.Namespace,
+	logger.Infof("POD CREATING: [%s/%s/%s/%s/%+v/%s]", string(podObj.GetUID()), podObj.Namespace,
 		podObj.Name, podObj.Spec.NodeName, podObj.Labels, podObj.Status.PodIP)
 
 	if !util.IsIPV4(podObj.Status.PodIP) {
@@ -369,7 +399,7 @@ func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
 	namespaceSet := []*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(podObj.Namespace, ipsets.Namespace)}
 
 	// Add the pod ip information into namespace's ipset.
-	klog.Infof("Adding pod %s (ip : %s) to ipset %s", podKey, podObj.Status.PodIP, podObj.Namespace)
+	logger.Infof("Adding pod %s (ip : %s) to ipset %s", podKey, podObj.Status.PodIP, podObj.Namespace)
 	if err = c.dp.AddToSets(namespaceSet, podMetadata); err != nil {
 		return fmt.Errorf("[syncAddedPod] Error: failed to add pod to namespace ipset with err: %w", err)
 	}
@@ -387,8 +417,8 @@ func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
 		targetSetKeyValue := ipsets.NewIPSetMetadata(labelKeyValue, ipsets.KeyValueLabelOfPod)
 		allSets := []*ipsets.IPSetMetadata{targetSetKey, targetSetKeyValue}
 
-		klog.Infof("Creating ipsets %+v and %+v if they do not exist", targetSetKey, targetSetKeyValue)
-		klog.Infof("Adding pod %s (ip : %s) to ipset %s and %s", podKey, npmPodObj.PodIP, labelKey, labelKeyValue)
+		logger.Infof("Creating ipsets %+v and %+v if they do not exist", targetSetKey, targetSetKeyValue)
+		logger.Infof("Adding pod %s (ip : %s) to ipset %s and %s", podKey, npmPodObj.PodIP, labelKey, labelKeyValue)
 		if err = c.dp.AddToSets(allSets, podMetadata); err != nil {
 			return fmt.Errorf("[syncAddedPod] Error: failed to add pod to label ipset with err: %w", err)
 		}
@@ -396,7 +426,7 @@ func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
 	}
 
 	// Add pod's named ports from its ipset.
-	klog.Infof("Adding named port ipsets")
+	logger.Infof("Adding named port ipsets")
 	containerPorts := common.GetContainerPortList(podObj)
 	if err = c.manageNamedPortIpsets(containerPorts, podKey, npmPodObj.PodIP, podObj.Spec.NodeName, addNamedPort); err != nil {
 		return fmt.Errorf("[syncAddedPod] Error: failed to add pod to named port ipset with err: %w", err)
@@ -430,7 +460,7 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 	c.npmNamespaceCache.Unlock()
 
 	cachedNpmPod, exists := c.podMap[podKey]
-	klog.Infof("[syncAddAndUpdatePod] updating Pod with key %s", podKey)
+	logger.Infof("[syncAddAndUpdatePod] updating Pod with key %s", podKey)
 	// No cached npmPod exists. start adding the pod in a cache
 	if !exists {
 		return metrics.CreateOp, c.syncAddedPod(newPodObj)
@@ -446,15 +476,15 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 	// NPM should clean up existing references of cached pod obj and its IP.
 	// then, re-add new pod obj.
 	if cachedNpmPod.PodIP != newPodObj.Status.PodIP {
-		klog.Infof("Pod (Namespace:%s, Name:%s, newUid:%s), has cachedPodIp:%s which is different from PodIp:%s",
+		logger.Infof("Pod (Namespace:%s, Name:%s, newUid:%s), has cachedPodIp:%s which is different from PodIp:%s",
 			newPodObj.Namespace, newPodObj.Name, string(newPodObj.UID), cachedNpmPod.PodIP, newPodObj.Status.PodIP)
 
-		klog.Infof("Deleting cached Pod with key:%s first due to IP Mistmatch", podKey)
+		logger.Infof("Deleting cached Pod with key:%s first due to IP Mistmatch", podKey)
 		if er := c.cleanUpDeletedPod(podKey); er != nil {
 			return metrics.UpdateOp, er
 		}
 
-		klog.Infof("Adding back Pod with key:%s after IP Mistmatch", podKey)
+		logger.Infof("Adding back Pod with key:%s after IP Mistmatch", podKey)
 		return metrics.UpdateOp, c.syncAddedPod(newPodObj)
 	}
 
@@ -468,7 +498,7 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 	cachedPodMetadata := dataplane.NewPodMetadata(podKey, cachedNpmPod.PodIP, newPodMetadata.NodeName)
 	// Delete the pod from its label's ipset.
 	for _, removeIPSetName := range deleteFromIPSets {
-		klog.Infof("Deleting pod %s (ip : %s) from ipset %s", podKey, cachedNpmPod.PodIP, removeIPSetName)
+		logger.Infof("Deleting pod %s (ip : %s) from ipset %s", podKey, cachedNpmPod.PodIP, removeIPSetName)
 
 		var toRemoveSet *ipsets.IPSetMetadata
 		if util.IsKeyValueLabelSetName(removeIPSetName) {
@@ -490,7 +520,7 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 	// Add the pod to its label's ipset.
 	for _, addIPSetName := range addToIPSets {
 
-		klog.Infof("Creating ipset %s if it doesn't already exist", addIPSetName)
+		logger.Infof("Creating ipset %s if it doesn't already exist", addIPSetName)
 
 		var toAddSet *ipsets.IPSetMetadata
 		if util.IsKeyValueLabelSetName(addIPSetName) {
@@ -499,7 +529,7 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 			toAddSet = ipsets.NewIPSetMetadata(addIPSetName, ipsets.KeyLabelOfPod)
 		}
 
-		klog.Infof("Adding pod %s (ip : %s) to ipset %s", podKey, newPodObj.Status.PodIP, addIPSetName)
+		logger.Infof("Adding pod %s (ip : %s) to ipset %s", podKey, newPodObj.Status.PodIP, addIPSetName)
 		if err = c.dp.AddToSets([]*ipsets.IPSetMetadata{toAddSet}, newPodMetadata); err != nil {
 			return metrics.UpdateOp, fmt.Errorf("[syncAddAndUpdatePod] Error: failed to add pod to label ipset with err: %w", err)
 		}
@@ -542,7 +572,7 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 
 // cleanUpDeletedPod cleans up all ipset associated with this pod
 func (c *PodController) cleanUpDeletedPod(cachedNpmPodKey string) error {
-	klog.Infof("[cleanUpDeletedPod] deleting Pod with key %s", cachedNpmPodKey)
+	logger.Infof("[cleanUpDeletedPod] deleting Pod with key %s", cachedNpmPodKey)
 	// If cached npmPod does not exist, return nil
 	cachedNpmPod, exist := c.podMap[cachedNpmPodKey]
 	if !exist {
@@ -562,7 +592,7 @@ func (c *PodController) cleanUpDeletedPod(cachedNpmPodKey string) error {
 	// Get lists of podLabelKey and podLabelKey + podLavelValue ,and then start deleting them from ipsets
 	for labelKey, labelVal := range cachedNpmPod.Labels {
 		labelKeyValue := util.GetIpSetFromLabelKV(labelKey, labelVal)
-		klog.Infof("Deleting pod %s (ip : %s) from ipsets %s and %s", cachedNpmPodKey, cachedNpmPod.PodIP, labelKey, labelKeyValue)
+		logger.Infof("Deleting pod %s (ip : %s) from ipsets %s and %s", cachedNpmPodKey, cachedNpmPod.PodIP, labelKey, labelKeyValue)
 		if err = c.dp.RemoveFromSets(
 			[]*ipsets.IPSetMetadata{
 				ipsets.NewIPSetMetadata(labelKey, ipsets.KeyLabelOfPod),
@@ -591,11 +621,11 @@ func (c *PodController) manageNamedPortIpsets(portList []corev1.ContainerPort, p
 	if util.IsWindowsDP() {
 		// NOTE: if we support namedport operations, need to be careful of implications of including the node name in the pod metadata below
 		// since we say the node name is "" in cleanUpDeletedPod
-		klog.Warningf("Windows Dataplane does not support NamedPort operations. Operation: %s portList is %+v", namedPortOperation, portList)
+		logger.Warnf("Windows Dataplane does not support NamedPort operations. Operation: %s portList is %+v", namedPortOperation, portList)
 		return nil
 	}
 	for _, port := range portList {
-		klog.Infof("port is %+v", port)
+		logger.Infof("port is %+v", port)
 		if port.Name == "" {
 			continue
 		}
@@ -84,7 +84,7 @@ func (f *podFixture) newPodController(_ chan struct{}) {
 	f.kubeInformer = kubeinformers.NewSharedInformerFactory(kubeclient, noResyncPeriodFunc())
 
 	npmNamespaceCache := &NpmNamespaceCache{NsMap: make(map[string]*common.Namespace)}
-	f.podController = NewPodController(f.kubeInformer.Core().V1().Pods(), f.dp, npmNamespaceCache)
+	f.podController = NewPodController(f.kubeInformer.Core().V1().Pods(), f.dp, npmNamespaceCache, nil)
 
 	for _, pod := range f.podLister {
 		err := f.kubeInformer.Core().V1().Pods().Informer().GetIndexer().Add(pod)
@@ -412,6 +412,49 @@ func TestAddHostNetworkPod(t *testing.T) {
 	}
 }
 
+func TestAddPodInExcludedNamespace(t *testing.T) {
+	labels := map[string]string{
+		"app": "test-pod",
+	}
+	podObj := createPod("test-pod", "kube-system", "0", "1.2.3.4", labels, NonHostNetwork, corev1.PodRunning)
+	podKey := getKey(podObj, t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newFixture(t, dp)
+	f.podLister = append(f.podLister, podObj)
+	f.kubeobjects = append(f.kubeobjects, podObj)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	f.newPodController(stopCh)
+	exclusion, err := common.NewNamespaceExclusion([]string{"kube-system"}, "", f.kubeInformer.Core().V1().Namespaces())
+	require.NoError(t, err)
+	f.podController.exclusion = exclusion
+
+	addPod(t, f, podObj)
+	testCases := []expectedValues{
+		{0, 0, 0, podPromVals{0, 0, 0, 0, 0, 0, 0}},
+	}
+	// sleep in case rate limiter adds back to workqueue
+	time.Sleep(sleepDurationForRateLimiter)
+	checkPodTestResult("TestAddPodInExcludedNamespace", f, testCases)
+
+	if _, exists := f.podController.podMap[podKey]; exists {
+		t.Error("TestAddPodInExcludedNamespace failed @ cached pod obj exists check")
+	}
+
+	// the pod is enqueued when its namespace stops or starts being excluded, and is ignored when synced.
+	dp.EXPECT().ApplyDataPlane().Return(nil).Times(1)
+	f.podController.enqueueNamespace("kube-system")
+	require.Equal(t, 1, f.podController.workqueue.Len())
+	f.podController.processNextWorkItem()
+	if _, exists := f.podController.podMap[podKey]; exists {
+		t.Error("TestAddPodInExcludedNamespace failed @ cached pod obj exists check after resync")
+	}
+}
+
 func TestDeletePod(t *testing.T) {
 	labels := map[string]string{
 		"app": "test-pod",
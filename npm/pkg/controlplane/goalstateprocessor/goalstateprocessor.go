@@ -8,12 +8,14 @@ import (
 	cp "github.com/Azure/azure-container-networking/npm/pkg/controlplane"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/pkg/protos"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
-	"k8s.io/klog"
 )
 
+var logger = npmlogger.For(npmlogger.Dataplane)
+
 var ErrPodOrNodeNameNil = fmt.Errorf("both pod and node name must be set")
 
 type GoalStateProcessor struct {
@@ -37,7 +39,7 @@ func NewGoalStateProcessor(
 		return nil, ErrPodOrNodeNameNil
 	}
 
-	klog.Infof("Creating GoalStateProcessor for node %s", nodeID)
+	logger.Infof("Creating GoalStateProcessor for node %s", nodeID)
 
 	return &GoalStateProcessor{
 		ctx:            ctx,
@@ -51,18 +53,18 @@ func NewGoalStateProcessor(
 
 // Start kicks off the GoalStateProcessor
 func (gsp *GoalStateProcessor) Start(stopCh <-chan struct{}) {
-	klog.Infof("Starting GoalStateProcessor for node %s", gsp.nodeID)
+	logger.Infof("Starting GoalStateProcessor for node %s", gsp.nodeID)
 	go gsp.run(stopCh)
 }
 
 // Stop stops the GoalStateProcessor
 func (gsp *GoalStateProcessor) Stop() {
-	klog.Infof("Stopping GoalStateProcessor for node %s", gsp.nodeID)
+	logger.Infof("Stopping GoalStateProcessor for node %s", gsp.nodeID)
 	gsp.cancel()
 }
 
 func (gsp *GoalStateProcessor) run(stopCh <-chan struct{}) {
-	klog.Infof("Starting dataplane for node %s", gsp.nodeID)
+	logger.Infof("Starting dataplane for node %s", gsp.nodeID)
 
 	for gsp.processNext(stopCh) {
 	}
@@ -74,52 +76,52 @@ func (gsp *GoalStateProcessor) processNext(stopCh <-chan struct{}) bool {
 	// on a previous event
 	case inputEvents := <-gsp.inputChannel:
 		// TODO remove this large print later
-		klog.Infof("Received event %s", inputEvents)
+		logger.Infof("Received event %s", inputEvents)
 		gsp.process(inputEvents)
 		return true
 	case backoffEvents := <-gsp.backoffChannel:
 		// For now keep it simple. Do not worry about backoff events
 		// but if we need to handle them, we can do it here.
 		// TODO remove this large print later
-		klog.Infof("Received backoff event %s", backoffEvents)
+		logger.Infof("Received backoff event %s", backoffEvents)
 		gsp.process(backoffEvents)
 		return true
 
 	case <-gsp.ctx.Done():
-		klog.Infof("GoalStateProcessor for node %s received context Done", gsp.nodeID)
+		logger.Infof("GoalStateProcessor for node %s received context Done", gsp.nodeID)
 		return false
 	case <-stopCh:
-		klog.Infof("GoalStateProcessor for node %s stopped", gsp.nodeID)
+		logger.Infof("GoalStateProcessor for node %s stopped", gsp.nodeID)
 		return false
 	}
 }
 
 func (gsp *GoalStateProcessor) process(inputEvent *protos.Events) {
-	klog.Infof("Processing event")
+	logger.Infof("Processing event")
 	// apply dataplane after syncing
 	defer func() {
 		dperr := gsp.dp.ApplyDataPlane()
 		if dperr != nil {
-			klog.Errorf("Apply Dataplane failed with %v", dperr)
+			logger.Errorf("Apply Dataplane failed with %v", dperr)
 		}
 	}()
 
 	payload := inputEvent.GetPayload()
 	if !validatePayload(payload) {
-		klog.Warningf("Empty payload in event %s", inputEvent)
+		logger.Warnf("Empty payload in event %s", inputEvent)
 		return
 	}
 
 	switch inputEvent.GetEventType() {
 	case protos.Events_Hydration:
 		// in hydration event, any thing in local cache and not in event should be deleted.
-		klog.Infof("Received hydration event")
+		logger.Infof("Received hydration event")
 		gsp.processHydrationEvent(payload)
 	case protos.Events_GoalState:
-		klog.Infof("Received goal state event")
+		logger.Infof("Received goal state event")
 		gsp.processGoalStateEvent(payload)
 	default:
-		klog.Errorf("Received unknown event type %s", inputEvent.GetEventType())
+		logger.Errorf("Received unknown event type %s", inputEvent.GetEventType())
 	}
 }
 
@@ -142,14 +144,14 @@ func (gsp *GoalStateProcessor) processHydrationEvent(payload map[string]*protos.
 	if ipsetApplyPayload, ok := payload[cp.IpsetApply]; ok {
 		appendedIPSets, err = gsp.processIPSetsApplyEvent(ipsetApplyPayload)
 		if err != nil {
-			klog.Errorf("Error processing IPSET apply HYDRATION event %s", err)
+			logger.Errorf("Error processing IPSET apply HYDRATION event %s", err)
 		}
 	}
 
 	if policyApplyPayload, ok := payload[cp.PolicyApply]; ok {
 		appendedPolicies, err = gsp.processPolicyApplyEvent(policyApplyPayload)
 		if err != nil {
-			klog.Errorf("Error processing POLICY apply HYDRATION event %s", err)
+			logger.Errorf("Error processing POLICY apply HYDRATION event %s", err)
 		}
 	}
 
@@ -166,10 +168,10 @@ func (gsp *GoalStateProcessor) processHydrationEvent(payload map[string]*protos.
 	}
 
 	if len(toDeletePolicies) > 0 {
-		klog.Infof("Deleting %d policies", len(toDeletePolicies))
+		logger.Infof("Deleting %d policies", len(toDeletePolicies))
 		err = gsp.processPolicyRemoveEvent(toDeletePolicies)
 		if err != nil {
-			klog.Errorf("Error processing POLICY remove HYDRATION event %s", err)
+			logger.Errorf("Error processing POLICY remove HYDRATION event %s", err)
 		}
 	}
 
@@ -195,7 +197,7 @@ func (gsp *GoalStateProcessor) processHydrationEvent(payload map[string]*protos.
 	}
 
 	if len(toDeleteIPSets) > 0 {
-		klog.Infof("Deleting %d ipsets", len(toDeleteIPSets))
+		logger.Infof("Deleting %d ipsets", len(toDeleteIPSets))
 		gsp.processIPSetsRemoveEvent(toDeleteIPSets, util.ForceDelete)
 	}
 }
@@ -209,14 +211,14 @@ func (gsp *GoalStateProcessor) processGoalStateEvent(payload map[string]*protos.
 	if ipsetApplyPayload, ok := payload[cp.IpsetApply]; ok {
 		_, err := gsp.processIPSetsApplyEvent(ipsetApplyPayload)
 		if err != nil {
-			klog.Errorf("Error processing IPSET apply event %s", err)
+			logger.Errorf("Error processing IPSET apply event %s", err)
 		}
 	}
 
 	if policyApplyPayload, ok := payload[cp.PolicyApply]; ok {
 		_, err := gsp.processPolicyApplyEvent(policyApplyPayload)
 		if err != nil {
-			klog.Errorf("Error processing POLICY apply event %s", err)
+			logger.Errorf("Error processing POLICY apply event %s", err)
 		}
 	}
 
@@ -224,11 +226,11 @@ func (gsp *GoalStateProcessor) processGoalStateEvent(payload map[string]*protos.
 		payload := bytes.NewBuffer(policyRemovePayload.GetData())
 		netpolNames, err := cp.DecodeStrings(payload)
 		if err != nil {
-			klog.Errorf("Error processing POLICY remove event, failed to decode Policy remove event %s", err)
+			logger.Errorf("Error processing POLICY remove event, failed to decode Policy remove event %s", err)
 		}
 		err = gsp.processPolicyRemoveEvent(netpolNames)
 		if err != nil {
-			klog.Errorf("Error processing POLICY remove event %s", err)
+			logger.Errorf("Error processing POLICY remove event %s", err)
 		}
 	}
 
@@ -236,7 +238,7 @@ func (gsp *GoalStateProcessor) processGoalStateEvent(payload map[string]*protos.
 		payload := bytes.NewBuffer(ipsetRemovePayload.GetData())
 		ipsetNames, err := cp.DecodeStrings(payload)
 		if err != nil {
-			klog.Errorf("Error processing IPSET remove event, failed to decode IPSet remove event: %s", err)
+			logger.Errorf("Error processing IPSET remove event, failed to decode IPSet remove event: %s", err)
 		}
 		gsp.processIPSetsRemoveEvent(ipsetNames, util.SoftDelete)
 	}
@@ -249,22 +251,22 @@ func (gsp *GoalStateProcessor) processIPSetsApplyEvent(goalState *protos.GoalSta
 		return nil, npmerrors.SimpleErrorWrapper("failed to decode IPSet apply event", err)
 	}
 
-	klog.Infof("Processing IPSet apply event %v", payloadIPSets)
+	logger.Infof("Processing IPSet apply event %v", payloadIPSets)
 	appendedIPSets := make(map[string]struct{}, len(payloadIPSets))
 	for _, ipset := range payloadIPSets {
 		if ipset == nil {
-			klog.Warningf("Empty IPSet apply event")
+			logger.Warnf("Empty IPSet apply event")
 			continue
 		}
 
-		klog.Infof("ipset: %v", ipset)
+		logger.Infof("ipset: %v", ipset)
 
 		ipsetName := ipset.GetPrefixName()
-		klog.Infof("Processing %s IPSET apply event", ipsetName)
+		logger.Infof("Processing %s IPSET apply event", ipsetName)
 
 		cachedIPSet := gsp.dp.GetIPSet(ipsetName)
 		if cachedIPSet == nil {
-			klog.Infof("IPSet %s not found in cache, adding to cache", ipsetName)
+			logger.Infof("IPSet %s not found in cache, adding to cache", ipsetName)
 		}
 
 		switch ipset.GetSetKind() {
@@ -354,14 +356,14 @@ func (gsp *GoalStateProcessor) applyLists(ipSet *cp.ControllerIPSets, cachedIPSe
 func (gsp *GoalStateProcessor) processIPSetsRemoveEvent(ipsetNames []string, forceDelete util.DeleteOption) {
 	for _, ipsetName := range ipsetNames {
 		if ipsetName == "" {
-			klog.Warningf("Empty IPSet remove event")
+			logger.Warnf("Empty IPSet remove event")
 			continue
 		}
-		klog.Infof("Processing %s IPSET remove event", ipsetName)
+		logger.Infof("Processing %s IPSET remove event", ipsetName)
 
 		cachedIPSet := gsp.dp.GetIPSet(ipsetName)
 		if cachedIPSet == nil {
-			klog.Infof("IPSet %s not found in cache, ignoring delete call.", ipsetName)
+			logger.Infof("IPSet %s not found in cache, ignoring delete call.", ipsetName)
 			continue
 		}
 
@@ -379,15 +381,15 @@ func (gsp *GoalStateProcessor) processPolicyApplyEvent(goalState *protos.GoalSta
 	appendedPolicies := make(map[string]struct{}, len(netpols))
 	for _, netpol := range netpols {
 		if netpol == nil {
-			klog.Warningf("Empty Policy apply event")
+			logger.Warnf("Empty Policy apply event")
 			continue
 		}
-		klog.Infof("Processing %s Policy ADD event", netpol.PolicyKey)
-		klog.Infof("Netpol: %v", netpol)
+		logger.Infof("Processing %s Policy ADD event", netpol.PolicyKey)
+		logger.Infof("Netpol: %v", netpol)
 
 		err = gsp.dp.UpdatePolicy(netpol)
 		if err != nil {
-			klog.Errorf("Error applying policy %s to dataplane with error: %s", netpol.PolicyKey, err.Error())
+			logger.Errorf("Error applying policy %s to dataplane with error: %s", netpol.PolicyKey, err.Error())
 			return nil, npmerrors.SimpleErrorWrapper("failed update policy event", err)
 		}
 		appendedPolicies[netpol.PolicyKey] = struct{}{}
@@ -397,16 +399,16 @@ func (gsp *GoalStateProcessor) processPolicyApplyEvent(goalState *protos.GoalSta
 
 func (gsp *GoalStateProcessor) processPolicyRemoveEvent(netpolNames []string) error {
 	for _, netpolName := range netpolNames {
-		klog.Infof("Processing %s Policy remove event", netpolName)
+		logger.Infof("Processing %s Policy remove event", netpolName)
 
 		if netpolName == "" {
-			klog.Warningf("Empty Policy remove event")
+			logger.Warnf("Empty Policy remove event")
 			continue
 		}
 
 		err := gsp.dp.RemovePolicy(netpolName)
 		if err != nil {
-			klog.Errorf("Error removing policy %s from dataplane with error: %s", netpolName, err.Error())
+			logger.Errorf("Error removing policy %s from dataplane with error: %s", netpolName, err.Error())
 			return npmerrors.SimpleErrorWrapper("failed remove policy event", err)
 		}
 	}
@@ -5,12 +5,14 @@ import (
 
 	"regexp"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+var logger = npmlogger.For(npmlogger.Controllers)
+
 // validLabelRegex is defined from the result of kubectl (this includes empty string matches):
 // a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with
 // an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?'
@@ -95,7 +97,7 @@ func flattenNameSpaceSelector(nsSelector *metav1.LabelSelector) ([]metav1.LabelS
 			// since Exists and NotExists do not contain any values, NPM can safely add them to the baseSelector
 			baseSelector.MatchExpressions = append(baseSelector.MatchExpressions, req)
 		default:
-			log.Errorf("Invalid operator [%s] for selector [%v] requirement", req.Operator, *nsSelector)
+			logger.Errorf("Invalid operator [%s] for selector [%v] requirement", req.Operator, *nsSelector)
 		}
 	}
 
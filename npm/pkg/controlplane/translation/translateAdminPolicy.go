@@ -0,0 +1,317 @@
+package translation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/policy/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var (
+	// ErrUnsupportedNodesPeer is returned when a peer of an AdminNetworkPolicy selects nodes.
+	ErrUnsupportedNodesPeer = errors.New("unsupported nodes peer in AdminNetworkPolicy")
+	// ErrUnsupportedSubjectSelector is returned when the namespace selector of a subject has matchExpressions with multiple values,
+	// since the sets of the subject can only be intersected.
+	ErrUnsupportedSubjectSelector = errors.New("unsupported namespace selector with multiple values in a matchExpression of the subject")
+	// ErrUnsupportedPassAction is returned when the Pass action of AdminNetworkPolicies is used in windows.
+	ErrUnsupportedPassAction = errors.New("unsupported Pass action used on windows")
+	// ErrUnsupportedAdminPriority is returned when an AdminNetworkPolicy priority is too high for windows.
+	ErrUnsupportedAdminPriority = fmt.Errorf("unsupported AdminNetworkPolicy priority above %d used on windows", policies.MaxWindowsAdminPriority)
+
+	errUnknownAction = errors.New("unknown action")
+	errEmptyPeer     = errors.New("peer has no namespaces, pods, networks or nodes")
+	errEmptySubject  = errors.New("subject has no namespaces or pods")
+)
+
+const (
+	adminSetPrefix    = "anp"
+	baselineSetPrefix = "banp"
+	// networksSetNameFormat is "<anp or banp>-<policy name>-<rule index>-<peer index><direction>"
+	networksSetNameFormat = "%s-%s-%d-%d%s"
+)
+
+// tierRule is an ingress or egress rule of an AdminNetworkPolicy or BaselineAdminNetworkPolicy.
+// Ingress peers are a subset of egress peers, so both use the egress peer.
+type tierRule struct {
+	action policies.Verdict
+	peers  []v1alpha1.AdminNetworkPolicyEgressPeer
+	ports  *[]v1alpha1.AdminNetworkPolicyPort
+}
+
+// TranslateAdminPolicy translates an AdminNetworkPolicy to an NPMNetworkPolicy of the AdminTier.
+// Unlike NetworkPolicies, there is no default drop ACL: traffic which matches no rule is decided by the next tiers.
+func TranslateAdminPolicy(anp *v1alpha1.AdminNetworkPolicy) (*policies.NPMNetworkPolicy, error) {
+	if util.IsWindowsDP() && anp.Spec.Priority > policies.MaxWindowsAdminPriority {
+		return nil, ErrUnsupportedAdminPriority
+	}
+
+	npmNetPol := policies.NewAdminNPMNetworkPolicy(anp.Name, anp.Spec.Priority)
+	if err := tierSubject(npmNetPol, &anp.Spec.Subject); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range anp.Spec.Ingress {
+		action, err := adminAction(rule.Action)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate ingress rule %d: %w", i, err)
+		}
+		r := tierRule{action: action, peers: ingressPeers(rule.From), ports: rule.Ports}
+		if err := translateTierRule(npmNetPol, adminSetPrefix, anp.Name, policies.Ingress, policies.SrcMatch, i, r); err != nil {
+			return nil, err
+		}
+	}
+	for i, rule := range anp.Spec.Egress {
+		action, err := adminAction(rule.Action)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate egress rule %d: %w", i, err)
+		}
+		r := tierRule{action: action, peers: rule.To, ports: rule.Ports}
+		if err := translateTierRule(npmNetPol, adminSetPrefix, anp.Name, policies.Egress, policies.DstMatch, i, r); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateWindowsProtocols(npmNetPol); err != nil {
+		return nil, err
+	}
+	return npmNetPol, nil
+}
+
+// TranslateBaselineAdminPolicy translates a BaselineAdminNetworkPolicy to an NPMNetworkPolicy of the BaselineTier.
+func TranslateBaselineAdminPolicy(banp *v1alpha1.BaselineAdminNetworkPolicy) (*policies.NPMNetworkPolicy, error) {
+	npmNetPol := policies.NewBaselineNPMNetworkPolicy(banp.Name)
+	if err := tierSubject(npmNetPol, &banp.Spec.Subject); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range banp.Spec.Ingress {
+		action, err := baselineAction(rule.Action)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate ingress rule %d: %w", i, err)
+		}
+		r := tierRule{action: action, peers: ingressPeers(rule.From), ports: rule.Ports}
+		if err := translateTierRule(npmNetPol, baselineSetPrefix, banp.Name, policies.Ingress, policies.SrcMatch, i, r); err != nil {
+			return nil, err
+		}
+	}
+	for i, rule := range banp.Spec.Egress {
+		action, err := baselineAction(rule.Action)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate egress rule %d: %w", i, err)
+		}
+		r := tierRule{action: action, peers: rule.To, ports: rule.Ports}
+		if err := translateTierRule(npmNetPol, baselineSetPrefix, banp.Name, policies.Egress, policies.DstMatch, i, r); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateWindowsProtocols(npmNetPol); err != nil {
+		return nil, err
+	}
+	return npmNetPol, nil
+}
+
+func adminAction(action v1alpha1.AdminNetworkPolicyRuleAction) (policies.Verdict, error) {
+	switch action {
+	case v1alpha1.AdminNetworkPolicyRuleActionAllow:
+		return policies.Allowed, nil
+	case v1alpha1.AdminNetworkPolicyRuleActionDeny:
+		return policies.Dropped, nil
+	case v1alpha1.AdminNetworkPolicyRuleActionPass:
+		if util.IsWindowsDP() {
+			return "", ErrUnsupportedPassAction
+		}
+		return policies.Passed, nil
+	}
+	return "", fmt.Errorf("%w: %s", errUnknownAction, action)
+}
+
+func baselineAction(action v1alpha1.BaselineAdminNetworkPolicyRuleAction) (policies.Verdict, error) {
+	switch action {
+	case v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow:
+		return policies.Allowed, nil
+	case v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny:
+		return policies.Dropped, nil
+	}
+	return "", fmt.Errorf("%w: %s", errUnknownAction, action)
+}
+
+func ingressPeers(from []v1alpha1.AdminNetworkPolicyIngressPeer) []v1alpha1.AdminNetworkPolicyEgressPeer {
+	peers := make([]v1alpha1.AdminNetworkPolicyEgressPeer, len(from))
+	for i, peer := range from {
+		peers[i] = v1alpha1.AdminNetworkPolicyEgressPeer{Namespaces: peer.Namespaces, Pods: peer.Pods}
+	}
+	return peers
+}
+
+// tierSubject translates the subject to the pod selector of the policy.
+// The pod selector is an intersection of sets, so the namespace selector must not need flattening.
+func tierSubject(npmNetPol *policies.NPMNetworkPolicy, subject *v1alpha1.AdminNetworkPolicySubject) error {
+	var nsSelector *metav1.LabelSelector
+	switch {
+	case subject.Namespaces != nil:
+		nsSelector = subject.Namespaces
+	case subject.Pods != nil:
+		nsSelector = &subject.Pods.NamespaceSelector
+		psResult, err := podSelector(npmNetPol.PolicyKey, policies.EitherMatch, &subject.Pods.PodSelector)
+		if err != nil {
+			return err
+		}
+		npmNetPol.PodSelectorIPSets = psResult.psSets
+		npmNetPol.ChildPodSelectorIPSets = psResult.childPSSets
+		npmNetPol.PodSelectorList = psResult.psList
+	default:
+		return errEmptySubject
+	}
+
+	flattenNSSelector, err := flattenNameSpaceSelector(nsSelector)
+	if err != nil {
+		return err
+	}
+	if len(flattenNSSelector) != 1 {
+		return ErrUnsupportedSubjectSelector
+	}
+	nsSelectorIPSets, nsSelectorList := nameSpaceSelector(policies.EitherMatch, &flattenNSSelector[0])
+	npmNetPol.PodSelectorIPSets = append(npmNetPol.PodSelectorIPSets, nsSelectorIPSets...)
+	npmNetPol.PodSelectorList = append(npmNetPol.PodSelectorList, nsSelectorList...)
+	return nil
+}
+
+// translateTierRule adds an ACL with the action of the rule for each peer and port, in the order of the rule,
+// since the first matching ACL decides the traffic.
+func translateTierRule(npmNetPol *policies.NPMNetworkPolicy, setPrefix, policyName string, direction policies.Direction, matchType policies.MatchType,
+	ruleIndex int, rule tierRule, //nolint // gofumpt
+) error {
+	ports := tierPorts(rule.ports)
+	for peerIdx, peer := range rule.peers {
+		peerSetInfos, err := tierPeer(npmNetPol, setPrefix, policyName, direction, matchType, ruleIndex, peerIdx, peer)
+		if err != nil {
+			return err
+		}
+
+		for _, setInfo := range peerSetInfos {
+			if len(ports) == 0 {
+				acl := policies.NewACLPolicy(rule.action, direction)
+				acl.AddSetInfo(setInfo)
+				npmNetPol.ACLs = append(npmNetPol.ACLs, acl)
+				continue
+			}
+
+			for i := range ports {
+				portKind, err := portType(ports[i])
+				if err != nil {
+					return err
+				}
+				acl := policies.NewACLPolicy(rule.action, direction)
+				acl.AddSetInfo(setInfo)
+				npmNetPol.RuleIPSets = portRule(npmNetPol.RuleIPSets, acl, &ports[i], portKind)
+				npmNetPol.ACLs = append(npmNetPol.ACLs, acl)
+			}
+		}
+	}
+	return nil
+}
+
+// tierPeer translates a peer to the SetInfos of each of its alternatives, i.e. of each flattened namespace selector
+// or its networks, and adds the sets to the RuleIPSets of the policy.
+func tierPeer(npmNetPol *policies.NPMNetworkPolicy, setPrefix, policyName string, direction policies.Direction, matchType policies.MatchType,
+	ruleIndex, peerIndex int, peer v1alpha1.AdminNetworkPolicyEgressPeer, //nolint // gofumpt
+) ([][]policies.SetInfo, error) {
+	switch {
+	case peer.Namespaces != nil:
+		return peerNameSpaces(npmNetPol, matchType, peer.Namespaces, nil)
+	case peer.Pods != nil:
+		psResult, err := podSelector(npmNetPol.PolicyKey, matchType, &peer.Pods.PodSelector)
+		if err != nil {
+			return nil, err
+		}
+		npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, psResult.psSets...)
+		npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, psResult.childPSSets...)
+		return peerNameSpaces(npmNetPol, matchType, &peer.Pods.NamespaceSelector, psResult.psList)
+	case len(peer.Networks) > 0:
+		networksIPSet, err := networksIPSet(fmt.Sprintf(networksSetNameFormat, setPrefix, policyName, ruleIndex, peerIndex, direction), peer.Networks)
+		if err != nil {
+			return nil, err
+		}
+		npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, networksIPSet)
+		return [][]policies.SetInfo{{policies.NewSetInfo(networksIPSet.Metadata.Name, ipsets.CIDRBlocks, included, matchType)}}, nil
+	case peer.Nodes != nil:
+		return nil, ErrUnsupportedNodesPeer
+	}
+	return nil, errEmptyPeer
+}
+
+// peerNameSpaces returns the SetInfos of each flattened namespace selector, intersected with the pod selector if any.
+func peerNameSpaces(npmNetPol *policies.NPMNetworkPolicy, matchType policies.MatchType, selector *metav1.LabelSelector,
+	psList []policies.SetInfo, //nolint // gofumpt
+) ([][]policies.SetInfo, error) {
+	flattenNSSelector, err := flattenNameSpaceSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	setInfos := make([][]policies.SetInfo, 0, len(flattenNSSelector))
+	for i := range flattenNSSelector {
+		nsSelectorIPSets, nsSelectorList := nameSpaceSelector(matchType, &flattenNSSelector[i])
+		npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, nsSelectorIPSets...)
+		setInfos = append(setInfos, append(nsSelectorList, psList...))
+	}
+	return setInfos, nil
+}
+
+// networksIPSet returns a CIDRBlocks set of the networks. Like for IPBlocks, 0.0.0.0/0 is split in halves since
+// ipset doesn't allow it.
+func networksIPSet(setName string, networks []v1alpha1.CIDR) (*ipsets.TranslatedIPSet, error) {
+	members := make([]string, 0, len(networks))
+	for _, network := range networks {
+		cidr := string(network)
+		if !util.IsIPV4(cidr) {
+			return nil, ErrUnsupportedIPAddress
+		}
+		if cidr == "0.0.0.0/0" {
+			members = append(members, "0.0.0.0/1", "128.0.0.0/1")
+			continue
+		}
+		members = append(members, cidr)
+	}
+	return ipsets.NewTranslatedIPSet(setName, ipsets.CIDRBlocks, members...), nil
+}
+
+// tierPorts converts the ports of a rule to NetworkPolicyPorts, so that they are translated like the ports of NetworkPolicies.
+func tierPorts(ports *[]v1alpha1.AdminNetworkPolicyPort) []networkingv1.NetworkPolicyPort {
+	if ports == nil {
+		return nil
+	}
+
+	netpolPorts := make([]networkingv1.NetworkPolicyPort, 0, len(*ports))
+	for _, port := range *ports {
+		switch {
+		case port.PortNumber != nil:
+			portNumber := intstr.FromInt(int(port.PortNumber.Port))
+			netpolPorts = append(netpolPorts, networkingv1.NetworkPolicyPort{Protocol: protocolOrNil(port.PortNumber.Protocol), Port: &portNumber})
+		case port.NamedPort != nil:
+			namedPort := intstr.FromString(*port.NamedPort)
+			netpolPorts = append(netpolPorts, networkingv1.NetworkPolicyPort{Port: &namedPort})
+		case port.PortRange != nil:
+			start := intstr.FromInt(int(port.PortRange.Start))
+			end := port.PortRange.End
+			netpolPorts = append(netpolPorts, networkingv1.NetworkPolicyPort{Protocol: protocolOrNil(port.PortRange.Protocol), Port: &start, EndPort: &end})
+		}
+	}
+	return netpolPorts
+}
+
+// protocolOrNil returns nil for an empty protocol, which defaults to TCP like in NetworkPolicies.
+func protocolOrNil(protocol corev1.Protocol) *corev1.Protocol {
+	if protocol == "" {
+		return nil
+	}
+	return &protocol
+}
@@ -0,0 +1,215 @@
+package translation
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/policy/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTranslateAdminPolicy(t *testing.T) {
+	serverPods := &v1alpha1.NamespacedPod{
+		NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+		PodSelector:       metav1.LabelSelector{MatchLabels: map[string]string{"app": "server"}},
+	}
+	monitoringNS := &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "monitoring"}}
+
+	tests := []struct {
+		name        string
+		anp         *v1alpha1.AdminNetworkPolicy
+		npmNetPol   *policies.NPMNetworkPolicy
+		wantErr     error
+		skipWindows bool
+	}{
+		{
+			name: "pass ingress from namespace and deny egress to networks",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "server"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Priority: 10,
+					Subject:  v1alpha1.AdminNetworkPolicySubject{Pods: serverPods},
+					Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+						{
+							Action: v1alpha1.AdminNetworkPolicyRuleActionPass,
+							From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: monitoringNS}},
+						},
+					},
+					Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
+						{
+							Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+							To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Networks: []v1alpha1.CIDR{"0.0.0.0/0"}}},
+							Ports: &[]v1alpha1.AdminNetworkPolicyPort{
+								{PortRange: &v1alpha1.PortRange{Protocol: v1.ProtocolUDP, Start: 1000, End: 2000}},
+							},
+						},
+					},
+				},
+			},
+			npmNetPol: &policies.NPMNetworkPolicy{
+				PolicyKey: "AdminNetworkPolicy/server",
+				Tier:      policies.AdminTier,
+				Priority:  10,
+				PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("app:server", ipsets.KeyValueLabelOfPod),
+					ipsets.NewTranslatedIPSet("team:a", ipsets.KeyValueLabelOfNamespace),
+				},
+				ChildPodSelectorIPSets: []*ipsets.TranslatedIPSet{},
+				PodSelectorList: []policies.SetInfo{
+					policies.NewSetInfo("app:server", ipsets.KeyValueLabelOfPod, included, policies.EitherMatch),
+					policies.NewSetInfo("team:a", ipsets.KeyValueLabelOfNamespace, included, policies.EitherMatch),
+				},
+				RuleIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("kubernetes.io/metadata.name:monitoring", ipsets.KeyValueLabelOfNamespace),
+					ipsets.NewTranslatedIPSet("anp-server-0-0OUT", ipsets.CIDRBlocks, "0.0.0.0/1", "128.0.0.0/1"),
+				},
+				ACLs: []*policies.ACLPolicy{
+					{
+						Target:    policies.Passed,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo("kubernetes.io/metadata.name:monitoring", ipsets.KeyValueLabelOfNamespace, included, policies.SrcMatch),
+						},
+					},
+					{
+						Target:    policies.Dropped,
+						Direction: policies.Egress,
+						DstList: []policies.SetInfo{
+							policies.NewSetInfo("anp-server-0-0OUT", ipsets.CIDRBlocks, included, policies.DstMatch),
+						},
+						DstPorts: policies.Ports{Port: 1000, EndPort: 2000},
+						Protocol: "UDP",
+					},
+				},
+			},
+			skipWindows: true,
+		},
+		{
+			name: "nodes peer",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "nodes"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+					Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
+						{
+							Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+							To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Nodes: &metav1.LabelSelector{}}},
+						},
+					},
+				},
+			},
+			wantErr: ErrUnsupportedNodesPeer,
+		},
+		{
+			name: "subject with multiple namespace values",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "multiple-values"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Subject: v1alpha1.AdminNetworkPolicySubject{
+						Namespaces: &metav1.LabelSelector{
+							MatchExpressions: []metav1.LabelSelectorRequirement{
+								{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: ErrUnsupportedSubjectSelector,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			npmNetPol, err := TranslateAdminPolicy(tt.anp)
+			if tt.skipWindows && util.IsWindowsDP() {
+				require.ErrorIs(t, err, ErrUnsupportedPassAction)
+				return
+			}
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			// the ACL policy ID is only set on windows
+			tt.npmNetPol.ACLPolicyID = policies.NewAdminNPMNetworkPolicy(tt.anp.Name, tt.anp.Spec.Priority).ACLPolicyID
+			require.Equal(t, tt.npmNetPol, npmNetPol)
+		})
+	}
+}
+
+func TestTranslateBaselineAdminPolicy(t *testing.T) {
+	tcp := v1.ProtocolTCP
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.BaselineAdminNetworkPolicyName},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{
+				{
+					Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow,
+					From: []v1alpha1.AdminNetworkPolicyIngressPeer{
+						{
+							Pods: &v1alpha1.NamespacedPod{
+								PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "client"}},
+							},
+						},
+					},
+					Ports: &[]v1alpha1.AdminNetworkPolicyPort{
+						{PortNumber: &v1alpha1.Port{Protocol: tcp, Port: 80}},
+					},
+				},
+				{
+					Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+				},
+			},
+		},
+	}
+
+	npmNetPol, err := TranslateBaselineAdminPolicy(banp)
+	require.NoError(t, err)
+	expected := &policies.NPMNetworkPolicy{
+		PolicyKey: "BaselineAdminNetworkPolicy/default",
+		Tier:      policies.BaselineTier,
+		PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+			ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+		},
+		PodSelectorList: []policies.SetInfo{
+			policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.EitherMatch),
+		},
+		RuleIPSets: []*ipsets.TranslatedIPSet{
+			ipsets.NewTranslatedIPSet("app:client", ipsets.KeyValueLabelOfPod),
+			ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+			ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+		},
+		ACLs: []*policies.ACLPolicy{
+			{
+				Target:    policies.Allowed,
+				Direction: policies.Ingress,
+				SrcList: []policies.SetInfo{
+					policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.SrcMatch),
+					policies.NewSetInfo("app:client", ipsets.KeyValueLabelOfPod, included, policies.SrcMatch),
+				},
+				DstPorts: policies.Ports{Port: 80},
+				Protocol: "TCP",
+			},
+			{
+				Target:    policies.Dropped,
+				Direction: policies.Ingress,
+				SrcList: []policies.SetInfo{
+					policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.SrcMatch),
+				},
+			},
+		},
+	}
+	expected.ACLPolicyID = policies.NewBaselineNPMNetworkPolicy(banp.Name).ACLPolicyID
+	require.Equal(t, expected, npmNetPol)
+
+	banp.Spec.Ingress[0].Action = "Pass"
+	_, err = TranslateBaselineAdminPolicy(banp)
+	require.ErrorIs(t, err, errUnknownAction)
+}
@@ -610,13 +610,21 @@ func TranslatePolicy(npObj *networkingv1.NetworkPolicy) (*policies.NPMNetworkPol
 		}
 	}
 
-	// ad-hoc validation to reduce code changes (modifying function signatures and returning errors in all the correct places)
+	if err := validateWindowsProtocols(npmNetPol); err != nil {
+		return nil, err
+	}
+	return npmNetPol, nil
+}
+
+// validateWindowsProtocols returns an error if an ACL uses a protocol which windows doesn't support.
+// ad-hoc validation to reduce code changes (modifying function signatures and returning errors in all the correct places)
+func validateWindowsProtocols(npmNetPol *policies.NPMNetworkPolicy) error {
 	if util.IsWindowsDP() {
 		for _, acl := range npmNetPol.ACLs {
 			if acl.Protocol == policies.SCTP {
-				return nil, ErrUnsupportedSCTP
+				return ErrUnsupportedSCTP
 			}
 		}
 	}
-	return npmNetPol, nil
+	return nil
 }
@@ -0,0 +1,82 @@
+package dataplane
+
+import (
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+)
+
+// AuditEventType is the kind of dataplane change reported to external policy auditors.
+type AuditEventType string
+
+const (
+	AuditPolicyAdded            AuditEventType = "PolicyAdded"
+	AuditPolicyRemoved          AuditEventType = "PolicyRemoved"
+	AuditIPSetMemberAdded       AuditEventType = "IPSetMemberAdded"
+	AuditIPSetMemberRemoved     AuditEventType = "IPSetMemberRemoved"
+	AuditEndpointPolicyAttached AuditEventType = "EndpointPolicyAttached"
+	AuditEndpointPolicyDetached AuditEventType = "EndpointPolicyDetached"
+)
+
+// AuditEvent is a change made to the dataplane cache.
+// Only the fields relevant to the event type are set.
+type AuditEvent struct {
+	Type      AuditEventType
+	Timestamp time.Time
+	PolicyKey string
+	// SetName is the prefixed name of the ipset or list for membership events.
+	SetName string
+	// Member is the IP (or CIDR) of an ipset, or the prefixed name of a list's member set.
+	Member     string
+	PodKey     string
+	EndpointID string
+	EndpointIP string
+}
+
+// AuditPublisher receives the dataplane's audit events.
+// Publish is called while the dataplane holds its locks, so it must not block.
+type AuditPublisher interface {
+	Publish(event *AuditEvent)
+}
+
+// SetAuditPublisher makes the dataplane report policy, ipset membership, and endpoint changes to the publisher.
+// It must be called before the controllers start.
+func (dp *DataPlane) SetAuditPublisher(publisher AuditPublisher) {
+	dp.auditPublisher = publisher
+}
+
+func (dp *DataPlane) publishAudit(event *AuditEvent) {
+	if dp.auditPublisher == nil {
+		return
+	}
+	event.Timestamp = time.Now().UTC()
+	dp.auditPublisher.Publish(event)
+}
+
+func (dp *DataPlane) publishSetMembers(eventType AuditEventType, setMetadatas []*ipsets.IPSetMetadata, member, podKey string) {
+	if dp.auditPublisher == nil {
+		return
+	}
+	for _, setMetadata := range setMetadatas {
+		dp.publishAudit(&AuditEvent{
+			Type:    eventType,
+			SetName: setMetadata.GetPrefixName(),
+			Member:  member,
+			PodKey:  podKey,
+		})
+	}
+}
+
+func (dp *DataPlane) publishEndpoints(eventType AuditEventType, policyKey string, endpoints map[string]string) {
+	if dp.auditPublisher == nil {
+		return
+	}
+	for ip, endpointID := range endpoints {
+		dp.publishAudit(&AuditEvent{
+			Type:       eventType,
+			PolicyKey:  policyKey,
+			EndpointID: endpointID,
+			EndpointIP: ip,
+		})
+	}
+}
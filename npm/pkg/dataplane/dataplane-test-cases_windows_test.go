@@ -493,6 +493,70 @@ func basicTests() []*SerialTestCase {
 				},
 			},
 		},
+		{
+			Description: "policy created, then pod created which satisfies policy, then pod relabeled away and back before applying DP",
+			Actions: []*Action{
+				FinishBootupPhase(),
+				UpdatePolicy(policyXBaseOnK1V1()),
+				CreateEndpoint(endpoint1, ip1),
+				CreatePod("x", "a", ip1, thisNode, map[string]string{"k1": "v1"}),
+				ApplyDP(),
+				UpdatePodLabels("x", "a", ip1, thisNode, map[string]string{"k1": "v1"}, map[string]string{"k2": "v2"}),
+				UpdatePodLabels("x", "a", ip1, thisNode, map[string]string{"k2": "v2"}, map[string]string{"k1": "v1"}),
+				ApplyDP(),
+			},
+			TestCaseMetadata: &TestCaseMetadata{
+				Tags: []Tag{
+					podCrudTag,
+					netpolCrudTag,
+				},
+				DpCfg:            defaultWindowsDPCfg,
+				InitialEndpoints: nil,
+				ExpectedSetPolicies: []*hcn.SetPolicySetting{
+					dptestutils.SetPolicy(emptySet),
+					dptestutils.SetPolicy(allNamespaces, emptySet.GetHashedName(), nsXSet.GetHashedName()),
+					dptestutils.SetPolicy(nsXSet, ip1),
+					dptestutils.SetPolicy(podK1Set, ip1),
+					dptestutils.SetPolicy(podK1V1Set, ip1),
+					// flapped labels (not yet garbage collected)
+					dptestutils.SetPolicy(podK2Set),
+					dptestutils.SetPolicy(podK2V2Set),
+				},
+				ExpectedEnpdointACLs: map[string][]*hnswrapper.FakeEndpointPolicy{
+					endpoint1: {
+						{
+							ID:              "azure-acl-x-base",
+							Protocols:       "",
+							Action:          "Allow",
+							Direction:       "In",
+							LocalAddresses:  "",
+							RemoteAddresses: "",
+							LocalPorts:      "",
+							RemotePorts:     "",
+							Priority:        222,
+						},
+						{
+							ID:              "azure-acl-x-base",
+							Protocols:       "",
+							Action:          "Allow",
+							Direction:       "Out",
+							LocalAddresses:  "",
+							RemoteAddresses: "",
+							LocalPorts:      "",
+							RemotePorts:     "",
+							Priority:        222,
+						},
+						{
+							ID:              "azure-acl-x-base",
+							Action:          "Allow",
+							Direction:       "In",
+							RemoteAddresses: testNodeIP,
+							Priority:        201,
+						},
+					},
+				},
+			},
+		},
 		{
 			Description: "Pod B replaces Pod A with same IP",
 			Actions: []*Action{
@@ -2039,35 +2103,7 @@ func podAssignmentSequence3Tests() []*SerialTestCase {
 							RemoteAddresses: testNodeIP,
 							Priority:        201,
 						},
-						{
-							ID:              "azure-acl-x-base3",
-							Protocols:       "",
-							Action:          "Allow",
-							Direction:       "In",
-							LocalAddresses:  "",
-							RemoteAddresses: "",
-							LocalPorts:      "",
-							RemotePorts:     "",
-							Priority:        222,
-						},
-						{
-							ID:              "azure-acl-x-base3",
-							Protocols:       "",
-							Action:          "Allow",
-							Direction:       "Out",
-							LocalAddresses:  "",
-							RemoteAddresses: "",
-							LocalPorts:      "",
-							RemotePorts:     "",
-							Priority:        222,
-						},
-						{
-							ID:              "azure-acl-x-base3",
-							Action:          "Allow",
-							Direction:       "In",
-							RemoteAddresses: testNodeIP,
-							Priority:        201,
-						},
+						// x-base3 has the same rules as x-base2, so they're only applied once
 					},
 				},
 			},
@@ -2506,25 +2542,7 @@ func applyInBackgroundBootupPhaseTests() []*SerialTestCase {
 							RemoteAddresses: testNodeIP,
 							Priority:        201,
 						},
-						{
-							ID:        "azure-acl-x-base2",
-							Action:    "Allow",
-							Direction: "In",
-							Priority:  222,
-						},
-						{
-							ID:        "azure-acl-x-base2",
-							Action:    "Allow",
-							Direction: "Out",
-							Priority:  222,
-						},
-						{
-							ID:              "azure-acl-x-base2",
-							Action:          "Allow",
-							Direction:       "In",
-							RemoteAddresses: testNodeIP,
-							Priority:        201,
-						},
+						// x-base2 has the same rules as x-base, so they're only applied once
 					},
 				},
 			},
@@ -10,11 +10,13 @@ import (
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
-	"k8s.io/klog"
 )
 
+var logger = npmlogger.For(npmlogger.Dataplane)
+
 const (
 	reconcileDuration = time.Duration(5 * time.Minute)
 
@@ -23,11 +25,13 @@ const (
 	contextAddNetPol       = "ADD-NETPOL"
 	contextAddNetPolBootup = "BOOTUP-ADD-NETPOL"
 	contextDelNetPol       = "DEL-NETPOL"
+	contextEndpointEvent   = "ENDPOINT-EVENT"
 )
 
 var (
 	ErrInvalidApplyConfig       = errors.New("invalid apply config")
 	ErrIncorrectNumberOfNetPols = errors.New("expected to have exactly one netpol since dp.netPolInBackground == false")
+	ErrInvalidTunables          = errors.New("invalid tunables")
 )
 
 type PolicyMode string
@@ -43,18 +47,31 @@ type Config struct {
 	NetPolInBackground bool
 	MaxPendingNetPols  int
 	NetPolInterval     time.Duration
+	// WatchEndpoints subscribes to HNS endpoint notifications (Windows only), so that the endpoint cache is refreshed
+	// and pending pod updates are applied as soon as endpoints are attached or detached, instead of on the next apply.
+	WatchEndpoints bool
 	*ipsets.IPSetManagerCfg
 	*policies.PolicyManagerCfg
 }
 
+// Tunables are the settings of the dataplane which can be changed while NPM is running.
+type Tunables struct {
+	ApplyMaxBatches      int
+	ApplyInterval        time.Duration
+	MaxPendingNetPols    int
+	NetPolInterval       time.Duration
+	MaxBatchedACLsPerPod int
+}
+
 type DataPlane struct {
 	*Config
 	applyInBackground  bool
 	netPolInBackground bool
 	policyMgr          *policies.PolicyManager
 	ipsetMgr           *ipsets.IPSetManager
-	networkID          string
-	nodeName           string
+	// networks maps the IDs of the HNS networks (Windows only) to their names
+	networks map[string]string
+	nodeName string
 	// endpointCache stores all endpoints of the network (including off-node)
 	// Key is PodIP
 	endpointCache  *endpointCache
@@ -64,12 +81,17 @@ type DataPlane struct {
 	applyInfo      *applyInfo
 	netPolQueue    *netPolQueue
 	stopChannel    <-chan struct{}
+	// applyIntervalUpdates and netPolIntervalUpdates hand new intervals to the background loops
+	applyIntervalUpdates  chan time.Duration
+	netPolIntervalUpdates chan time.Duration
+
+	auditPublisher AuditPublisher
 }
 
 func NewDataPlane(nodeName string, ioShim *common.IOShim, cfg *Config, stopChannel <-chan struct{}) (*DataPlane, error) {
 	metrics.InitializeAll()
 	if util.IsWindowsDP() {
-		klog.Infof("[DataPlane] enabling AddEmptySetToLists for Windows")
+		logger.Infof("[DataPlane] enabling AddEmptySetToLists for Windows")
 		cfg.IPSetManagerCfg.AddEmptySetToLists = true
 	}
 
@@ -77,8 +99,8 @@ func NewDataPlane(nodeName string, ioShim *common.IOShim, cfg *Config, stopChann
 		Config:    cfg,
 		policyMgr: policies.NewPolicyManager(ioShim, cfg.PolicyManagerCfg),
 		ipsetMgr:  ipsets.NewIPSetManager(cfg.IPSetManagerCfg, ioShim),
-		// networkID is set when initializing Windows dataplane
-		networkID:     "",
+		// networks are set when initializing Windows dataplane
+		networks:      make(map[string]string),
 		endpointCache: newEndpointCache(),
 		nodeName:      nodeName,
 		ioShim:        ioShim,
@@ -86,26 +108,28 @@ func NewDataPlane(nodeName string, ioShim *common.IOShim, cfg *Config, stopChann
 		applyInfo: &applyInfo{
 			inBootupPhase: true,
 		},
-		netPolQueue: newNetPolQueue(),
-		stopChannel: stopChannel,
+		netPolQueue:           newNetPolQueue(),
+		stopChannel:           stopChannel,
+		applyIntervalUpdates:  make(chan time.Duration, 1),
+		netPolIntervalUpdates: make(chan time.Duration, 1),
 	}
 
 	// do not let Linux apply in background
 	dp.applyInBackground = cfg.ApplyInBackground && util.IsWindowsDP()
 	if dp.applyInBackground {
-		klog.Infof("[DataPlane] dataplane configured to apply in background every %v or every %d calls to ApplyDataPlane()", dp.ApplyInterval, dp.ApplyMaxBatches)
+		logger.Infof("[DataPlane] dataplane configured to apply in background every %v or every %d calls to ApplyDataPlane()", dp.ApplyInterval, dp.ApplyMaxBatches)
 		dp.updatePodCache = newUpdatePodCache(cfg.ApplyMaxBatches)
 		if dp.ApplyMaxBatches <= 0 || dp.ApplyInterval == 0 {
 			return nil, ErrInvalidApplyConfig
 		}
 	} else {
-		klog.Info("[DataPlane] dataplane configured to NOT apply in background")
+		logger.Info("[DataPlane] dataplane configured to NOT apply in background")
 		dp.updatePodCache = newUpdatePodCache(1)
 	}
 
 	err := dp.BootupDataplane()
 	if err != nil {
-		klog.Errorf("Failed to reset dataplane: %v", err)
+		logger.Errorf("Failed to reset dataplane: %v", err)
 		return nil, err
 	}
 
@@ -138,12 +162,16 @@ func (dp *DataPlane) FinishBootupPhase() {
 	dp.applyInfo.Lock()
 	defer dp.applyInfo.Unlock()
 
-	klog.Infof("[DataPlane] finished bootup phase")
+	logger.Infof("[DataPlane] finished bootup phase")
 	dp.applyInfo.inBootupPhase = false
 }
 
 // RunPeriodicTasks runs periodic tasks. Should only be called once.
 func (dp *DataPlane) RunPeriodicTasks() {
+	if dp.WatchEndpoints {
+		dp.watchEndpoints()
+	}
+
 	go func() {
 		ticker := time.NewTicker(reconcileDuration)
 		defer ticker.Stop()
@@ -159,9 +187,12 @@ func (dp *DataPlane) RunPeriodicTasks() {
 				// locks ipset manager
 				dp.ipsetMgr.Reconcile()
 
-				// in Windows, does nothing
+				// in Windows, locks policy manager and repairs ACL drift in HNS
 				// in Linux, locks policy manager but can be interrupted
 				dp.policyMgr.Reconcile()
+
+				// in Windows, locks policy manager and removes ACLs of deleted policies from all endpoints of the network
+				dp.removeOrphanedACLs()
 			}
 		}
 	}()
@@ -175,6 +206,8 @@ func (dp *DataPlane) RunPeriodicTasks() {
 				select {
 				case <-dp.stopChannel:
 					return
+				case interval := <-dp.netPolIntervalUpdates:
+					ticker.Reset(interval)
 				case <-ticker.C:
 					// Choose to keep netPolQueue locked while running iptables-restore within addPoliciesWithRetry.
 					// We are only blocking the NetPol controller, which wouldn't be able to use the PolicyManager while it's reconciling.
@@ -204,6 +237,8 @@ func (dp *DataPlane) RunPeriodicTasks() {
 			select {
 			case <-dp.stopChannel:
 				return
+			case interval := <-dp.applyIntervalUpdates:
+				ticker.Reset(interval)
 			case <-ticker.C:
 				dp.applyInfo.Lock()
 				numBatches := dp.applyInfo.numBatches
@@ -213,7 +248,7 @@ func (dp *DataPlane) RunPeriodicTasks() {
 				}
 
 				if err := dp.applyDataPlaneNow(contextBackground); err != nil {
-					klog.Errorf("[DataPlane] failed to apply dataplane in background: %v", err)
+					logger.Errorf("[DataPlane] failed to apply dataplane in background: %v", err)
 					metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] failed to apply dataplane in background: %v", err)
 				}
 			}
@@ -221,6 +256,49 @@ func (dp *DataPlane) RunPeriodicTasks() {
 	}()
 }
 
+// UpdateTunables changes the tunables of the running dataplane.
+// The background loops switch to new intervals without waiting for their current tick.
+func (dp *DataPlane) UpdateTunables(tunables Tunables) error {
+	if tunables.ApplyMaxBatches <= 0 || tunables.ApplyInterval <= 0 || tunables.MaxPendingNetPols <= 0 ||
+		tunables.NetPolInterval <= 0 || tunables.MaxBatchedACLsPerPod < 0 {
+		return fmt.Errorf("%w: %+v", ErrInvalidTunables, tunables)
+	}
+
+	dp.applyInfo.Lock()
+	dp.ApplyMaxBatches = tunables.ApplyMaxBatches
+	if dp.ApplyInterval != tunables.ApplyInterval {
+		dp.ApplyInterval = tunables.ApplyInterval
+		sendInterval(dp.applyIntervalUpdates, tunables.ApplyInterval)
+	}
+	dp.applyInfo.Unlock()
+
+	dp.netPolQueue.Lock()
+	dp.MaxPendingNetPols = tunables.MaxPendingNetPols
+	if dp.NetPolInterval != tunables.NetPolInterval {
+		dp.NetPolInterval = tunables.NetPolInterval
+		sendInterval(dp.netPolIntervalUpdates, tunables.NetPolInterval)
+	}
+	dp.netPolQueue.Unlock()
+
+	dp.policyMgr.SetMaxBatchedACLsPerPod(tunables.MaxBatchedACLsPerPod)
+
+	metrics.SendLog(util.DaemonDataplaneID, fmt.Sprintf("[DataPlane] updated tunables: %+v", tunables), true)
+	return nil
+}
+
+// sendInterval hands the interval to a background loop, replacing an interval which the loop hasn't picked up yet.
+// The caller must hold the lock guarding the interval.
+func sendInterval(updates chan time.Duration, interval time.Duration) {
+	select {
+	case <-updates:
+	default:
+	}
+	select {
+	case updates <- interval:
+	default:
+	}
+}
+
 func (dp *DataPlane) GetIPSet(setName string) *ipsets.IPSet {
 	return dp.ipsetMgr.GetIPSet(setName)
 }
@@ -243,9 +321,10 @@ func (dp *DataPlane) AddToSets(setNames []*ipsets.IPSetMetadata, podMetadata *Po
 	if err != nil {
 		return fmt.Errorf("[DataPlane] error while adding to set: %w", err)
 	}
+	dp.publishSetMembers(AuditIPSetMemberAdded, setNames, podMetadata.PodIP, podMetadata.PodKey)
 
 	if dp.shouldUpdatePod() && podMetadata.NodeName == dp.nodeName {
-		klog.Infof("[DataPlane] Updating Sets to Add for pod key %s", podMetadata.PodKey)
+		logger.Infof("[DataPlane] Updating Sets to Add for pod key %s", podMetadata.PodKey)
 
 		// lock updatePodCache while reading/modifying or setting the updatePod in the cache
 		dp.updatePodCache.Lock()
@@ -265,9 +344,10 @@ func (dp *DataPlane) RemoveFromSets(setNames []*ipsets.IPSetMetadata, podMetadat
 	if err != nil {
 		return fmt.Errorf("[DataPlane] error while removing from set: %w", err)
 	}
+	dp.publishSetMembers(AuditIPSetMemberRemoved, setNames, podMetadata.PodIP, podMetadata.PodKey)
 
 	if dp.shouldUpdatePod() && podMetadata.NodeName == dp.nodeName {
-		klog.Infof("[DataPlane] Updating Sets to Remove for pod key %s", podMetadata.PodKey)
+		logger.Infof("[DataPlane] Updating Sets to Remove for pod key %s", podMetadata.PodKey)
 
 		// lock updatePodCache while reading/modifying or setting the updatePod in the cache
 		dp.updatePodCache.Lock()
@@ -287,6 +367,9 @@ func (dp *DataPlane) AddToLists(listName, setNames []*ipsets.IPSetMetadata) erro
 	if err != nil {
 		return fmt.Errorf("[DataPlane] error while adding to list: %w", err)
 	}
+	for _, setName := range setNames {
+		dp.publishSetMembers(AuditIPSetMemberAdded, listName, setName.GetPrefixName(), "")
+	}
 	return nil
 }
 
@@ -297,6 +380,9 @@ func (dp *DataPlane) RemoveFromList(listName *ipsets.IPSetMetadata, setNames []*
 	if err != nil {
 		return fmt.Errorf("[DataPlane] error while removing from list: %w", err)
 	}
+	for _, setName := range setNames {
+		dp.publishSetMembers(AuditIPSetMemberRemoved, []*ipsets.IPSetMetadata{listName}, setName.GetPrefixName(), "")
+	}
 	return nil
 }
 
@@ -314,12 +400,13 @@ func (dp *DataPlane) ApplyDataPlane() error {
 	dp.applyInfo.Lock()
 	dp.applyInfo.numBatches++
 	newCount := dp.applyInfo.numBatches
+	maxBatches := dp.ApplyMaxBatches
 	dp.applyInfo.Unlock()
 
-	klog.Infof("[DataPlane] [%s] new batch count: %d", contextApplyDP, newCount)
+	logger.Infof("[DataPlane] [%s] new batch count: %d", contextApplyDP, newCount)
 
-	if newCount >= dp.ApplyMaxBatches {
-		klog.Infof("[DataPlane] [%s] applying now since reached maximum batch count: %d", contextApplyDP, newCount)
+	if newCount >= maxBatches {
+		logger.Infof("[DataPlane] [%s] applying now since reached maximum batch count: %d", contextApplyDP, newCount)
 		return dp.applyDataPlaneNow(contextApplyDP)
 	}
 
@@ -327,12 +414,14 @@ func (dp *DataPlane) ApplyDataPlane() error {
 }
 
 func (dp *DataPlane) applyDataPlaneNow(context string) error {
-	klog.Infof("[DataPlane] [ApplyDataPlane] [%s] starting to apply ipsets", context)
+	logger.Infof("[DataPlane] [ApplyDataPlane] [%s] starting to apply ipsets", context)
 	err := dp.ipsetMgr.ApplyIPSets()
 	if err != nil {
 		return fmt.Errorf("[DataPlane] [%s] error while applying IPSets: %w", context, err)
 	}
-	klog.Infof("[DataPlane] [ApplyDataPlane] [%s] finished applying ipsets", context)
+	logger.Infof("[DataPlane] [ApplyDataPlane] [%s] finished applying ipsets", context)
+
+	dp.refreshPolicyIPs()
 
 	if dp.applyInBackground {
 		dp.applyInfo.Lock()
@@ -350,7 +439,7 @@ func (dp *DataPlane) applyDataPlaneNow(context string) error {
 		}
 		dp.updatePodCache.Unlock()
 
-		klog.Infof("[DataPlane] [ApplyDataPlane] [%s] refreshing endpoints before updating pods", context)
+		logger.Infof("[DataPlane] [ApplyDataPlane] [%s] refreshing endpoints before updating pods", context)
 
 		err := dp.refreshPodEndpoints()
 		if err != nil {
@@ -359,14 +448,14 @@ func (dp *DataPlane) applyDataPlaneNow(context string) error {
 			return nil
 		}
 
-		klog.Infof("[DataPlane] [ApplyDataPlane] [%s] refreshed endpoints", context)
+		logger.Infof("[DataPlane] [ApplyDataPlane] [%s] refreshed endpoints", context)
 
 		// lock updatePodCache while driving goal state to kernel
 		// prevents another ApplyDataplane call from updating the same pods
 		dp.updatePodCache.Lock()
 		defer dp.updatePodCache.Unlock()
 
-		klog.Infof("[DataPlane] [ApplyDataPlane] [%s] starting to update pods", context)
+		logger.Infof("[DataPlane] [ApplyDataPlane] [%s] starting to update pods", context)
 		for !dp.updatePodCache.isEmpty() {
 			pod := dp.updatePodCache.dequeue()
 			if pod == nil {
@@ -384,14 +473,14 @@ func (dp *DataPlane) applyDataPlaneNow(context string) error {
 			}
 		}
 
-		klog.Infof("[DataPlane] [ApplyDataPlane] [%s] finished updating pods", context)
+		logger.Infof("[DataPlane] [ApplyDataPlane] [%s] finished updating pods", context)
 	}
 	return nil
 }
 
 // AddPolicy takes in a translated NPMNetworkPolicy object and applies on dataplane
 func (dp *DataPlane) AddPolicy(policy *policies.NPMNetworkPolicy) error {
-	klog.Infof("[DataPlane] Add Policy called for %s", policy.PolicyKey)
+	logger.Infof("[DataPlane] Add Policy called for %s", policy.PolicyKey)
 
 	if !dp.netPolInBackground {
 		return dp.addPolicies([]*policies.NPMNetworkPolicy{policy})
@@ -405,10 +494,10 @@ func (dp *DataPlane) AddPolicy(policy *policies.NPMNetworkPolicy) error {
 	dp.netPolQueue.enqueue(policy)
 	newCount := dp.netPolQueue.len()
 
-	klog.Infof("[DataPlane] [%s] new pending netpol count: %d", contextAddNetPol, newCount)
+	logger.Infof("[DataPlane] [%s] new pending netpol count: %d", contextAddNetPol, newCount)
 
 	if newCount >= dp.MaxPendingNetPols {
-		klog.Infof("[DataPlane] [%s] applying now since reached maximum batch count: %d", contextAddNetPol, newCount)
+		logger.Infof("[DataPlane] [%s] applying now since reached maximum batch count: %d", contextAddNetPol, newCount)
 		dp.addPoliciesWithRetry(contextAddNetPol)
 	}
 	return nil
@@ -418,17 +507,17 @@ func (dp *DataPlane) AddPolicy(policy *policies.NPMNetworkPolicy) error {
 // The caller must lock netPolQueue.
 func (dp *DataPlane) addPoliciesWithRetry(context string) {
 	netPols := dp.netPolQueue.dump()
-	klog.Infof("[DataPlane] adding policies %+v", netPols)
+	logger.Infof("[DataPlane] adding policies %+v", netPols)
 
 	err := dp.addPolicies(netPols)
 	if err == nil {
 		// clear queue and return on success
-		klog.Infof("[DataPlane] [%s] added policies successfully", context)
+		logger.Infof("[DataPlane] [%s] added policies successfully", context)
 		dp.netPolQueue.clear()
 		return
 	}
 
-	klog.Errorf("[DataPlane] [%s] failed to add policies. will retry one policy at a time. err: %s", context, err.Error())
+	logger.Errorf("[DataPlane] [%s] failed to add policies. will retry one policy at a time. err: %s", context, err.Error())
 	metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] [%s] failed to add policies. err: %s", context, err.Error())
 
 	// retry one policy at a time
@@ -436,11 +525,11 @@ func (dp *DataPlane) addPoliciesWithRetry(context string) {
 		err = dp.addPolicies([]*policies.NPMNetworkPolicy{netPol})
 		if err == nil {
 			// remove from queue on success
-			klog.Infof("[DataPlane] [%s] added policy successfully one at a time. policyKey: %s", context, netPol.PolicyKey)
+			logger.Infof("[DataPlane] [%s] added policy successfully one at a time. policyKey: %s", context, netPol.PolicyKey)
 			dp.netPolQueue.delete(netPol.PolicyKey)
 		} else {
 			// keep in queue on failure
-			klog.Errorf("[DataPlane] [%s] failed to add policy one at a time. policyKey: %s. err: %s", context, netPol.PolicyKey, err.Error())
+			logger.Errorf("[DataPlane] [%s] failed to add policy one at a time. policyKey: %s. err: %s", context, netPol.PolicyKey, err.Error())
 			metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] [%s] failed to add policy one at a time. %s. err: %s", context, netPol.PolicyKey, err.Error())
 		}
 	}
@@ -448,13 +537,13 @@ func (dp *DataPlane) addPoliciesWithRetry(context string) {
 
 func (dp *DataPlane) addPolicies(netPols []*policies.NPMNetworkPolicy) error {
 	if !dp.netPolInBackground && len(netPols) != 1 {
-		klog.Errorf("[DataPlane] expected to have one NetPol in dp.addPolicies() since dp.netPolInBackground == false")
+		logger.Errorf("[DataPlane] expected to have one NetPol in dp.addPolicies() since dp.netPolInBackground == false")
 		metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] expected to have one NetPol in dp.addPolicies() since dp.netPolInBackground == false")
 		return ErrIncorrectNumberOfNetPols
 	}
 
 	if len(netPols) == 0 {
-		klog.Infof("[DataPlane] expected to have at least one NetPol in dp.addPolicies()")
+		logger.Infof("[DataPlane] expected to have at least one NetPol in dp.addPolicies()")
 		return nil
 	}
 
@@ -477,14 +566,14 @@ func (dp *DataPlane) addPolicies(netPols []*policies.NPMNetworkPolicy) error {
 		// Create and add references for Selector IPSets first
 		err := dp.createIPSetsAndReferences(netPol.AllPodSelectorIPSets(), netPol.PolicyKey, ipsets.SelectorType)
 		if err != nil {
-			klog.Infof("[DataPlane] error while adding Selector IPSet references: %s", err.Error())
+			logger.Infof("[DataPlane] error while adding Selector IPSet references: %s", err.Error())
 			return fmt.Errorf("[DataPlane] error while adding Selector IPSet references: %w", err)
 		}
 
 		// Create and add references for Rule IPSets
 		err = dp.createIPSetsAndReferences(netPol.RuleIPSets, netPol.PolicyKey, ipsets.NetPolType)
 		if err != nil {
-			klog.Infof("[DataPlane] error while adding Rule IPSet references: %s", err.Error())
+			logger.Infof("[DataPlane] error while adding Rule IPSet references: %s", err.Error())
 			return fmt.Errorf("[DataPlane] error while adding Rule IPSet references: %w", err)
 		}
 
@@ -497,15 +586,15 @@ func (dp *DataPlane) addPolicies(netPols []*policies.NPMNetworkPolicy) error {
 			// increment batch and apply IPSets if needed
 			dp.applyInfo.numBatches++
 			newCount := dp.applyInfo.numBatches
-			klog.Infof("[DataPlane] [%s] new batch count: %d", contextAddNetPolBootup, newCount)
+			logger.Infof("[DataPlane] [%s] new batch count: %d", contextAddNetPolBootup, newCount)
 			if newCount >= dp.ApplyMaxBatches {
-				klog.Infof("[DataPlane] [%s] applying now since reached maximum batch count: %d", contextAddNetPolBootup, newCount)
-				klog.Infof("[DataPlane] [%s] starting to apply ipsets", contextAddNetPolBootup)
+				logger.Infof("[DataPlane] [%s] applying now since reached maximum batch count: %d", contextAddNetPolBootup, newCount)
+				logger.Infof("[DataPlane] [%s] starting to apply ipsets", contextAddNetPolBootup)
 				err = dp.ipsetMgr.ApplyIPSets()
 				if err != nil {
 					return fmt.Errorf("[DataPlane] [%s] error while applying IPSets: %w", contextAddNetPolBootup, err)
 				}
-				klog.Infof("[DataPlane] [%s] finished applying ipsets", contextAddNetPolBootup)
+				logger.Infof("[DataPlane] [%s] finished applying ipsets", contextAddNetPolBootup)
 
 				dp.applyInfo.numBatches = 0
 			}
@@ -537,12 +626,17 @@ func (dp *DataPlane) addPolicies(netPols []*policies.NPMNetworkPolicy) error {
 		return fmt.Errorf("[DataPlane] [%s] error while adding policies: %w", contextAddNetPolBootup, err)
 	}
 
+	for _, netPol := range netPols {
+		dp.publishAudit(&AuditEvent{Type: AuditPolicyAdded, PolicyKey: netPol.PolicyKey})
+		dp.publishEndpoints(AuditEndpointPolicyAttached, netPol.PolicyKey, endpointList)
+	}
+
 	return nil
 }
 
 // RemovePolicy takes in network policyKey (namespace/name of network policy) and removes it from dataplane and cache
 func (dp *DataPlane) RemovePolicy(policyKey string) error {
-	klog.Infof("[DataPlane] Remove Policy called for %s", policyKey)
+	logger.Infof("[DataPlane] Remove Policy called for %s", policyKey)
 
 	if dp.netPolInBackground {
 		// make sure to not add this NetPol if we're deleting it
@@ -557,7 +651,7 @@ func (dp *DataPlane) RemovePolicy(policyKey string) error {
 	// keep a local copy to remove references for ipsets
 	policy, ok := dp.policyMgr.GetPolicy(policyKey)
 	if !ok {
-		klog.Infof("[DataPlane] Policy %s is not found. Might been deleted already", policyKey)
+		logger.Infof("[DataPlane] Policy %s is not found. Might been deleted already", policyKey)
 		return nil
 	}
 
@@ -572,6 +666,8 @@ func (dp *DataPlane) RemovePolicy(policyKey string) error {
 	if err != nil {
 		return fmt.Errorf("[DataPlane] error while removing policy: %w", err)
 	}
+	dp.publishAudit(&AuditEvent{Type: AuditPolicyRemoved, PolicyKey: policyKey})
+	dp.publishEndpoints(AuditEndpointPolicyDetached, policyKey, endpoints)
 
 	if dp.shouldUpdatePod() {
 
@@ -609,10 +705,10 @@ func (dp *DataPlane) RemovePolicy(policyKey string) error {
 // UpdatePolicy takes in updated policy object, calculates the delta and applies changes
 // onto dataplane accordingly
 func (dp *DataPlane) UpdatePolicy(policy *policies.NPMNetworkPolicy) error {
-	klog.Infof("[DataPlane] Update Policy called for %s", policy.PolicyKey)
+	logger.Infof("[DataPlane] Update Policy called for %s", policy.PolicyKey)
 	ok := dp.policyMgr.PolicyExists(policy.PolicyKey)
 	if !ok {
-		klog.Infof("[DataPlane] Policy %s is not found.", policy.PolicyKey)
+		logger.Infof("[DataPlane] Policy %s is not found.", policy.PolicyKey)
 		return dp.AddPolicy(policy)
 	}
 
@@ -632,6 +728,17 @@ func (dp *DataPlane) UpdatePolicy(policy *policies.NPMNetworkPolicy) error {
 	return nil
 }
 
+// TraceFlow reports which NetworkPolicies and ACLs allow or drop the flow, based on the policies and ipsets in the cache.
+// On Windows, the trace has the HNS ACLs of these policies on the endpoints of the flow too.
+func (dp *DataPlane) TraceFlow(flow *policies.Flow) (*policies.FlowTrace, error) {
+	trace, err := dp.policyMgr.TraceFlow(flow, dp.ipsetMgr)
+	if err != nil {
+		return nil, fmt.Errorf("[DataPlane] failed to trace flow %s: %w", flow, err)
+	}
+	dp.traceHNSACLs(trace)
+	return trace, nil
+}
+
 func (dp *DataPlane) GetAllIPSets() map[string]string {
 	return dp.ipsetMgr.GetAllIPSets()
 }
@@ -686,7 +793,7 @@ func (dp *DataPlane) deleteIPSetsAndReferences(sets []*ipsets.TranslatedIPSet, n
 		prefixName := set.Metadata.GetPrefixName()
 		if err := dp.ipsetMgr.DeleteReference(prefixName, netpolName, referenceType); err != nil {
 			// with current implementation of DeleteReference(), err will be ipsets.ErrSetDoesNotExist
-			klog.Infof("[DataPlane] ignoring delete reference on non-existent set. ipset: %s. netpol: %s. referenceType: %s", prefixName, netpolName, referenceType)
+			logger.Infof("[DataPlane] ignoring delete reference on non-existent set. ipset: %s. netpol: %s. referenceType: %s", prefixName, netpolName, referenceType)
 		}
 	}
 
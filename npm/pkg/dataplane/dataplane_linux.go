@@ -11,6 +11,27 @@ func (dp *DataPlane) getEndpointsToApplyPolicies(_ []*policies.NPMNetworkPolicy)
 	return nil, nil
 }
 
+func (dp *DataPlane) refreshPolicyIPs() {
+	// NOOP in Linux
+}
+
+func (dp *DataPlane) removeOrphanedACLs() {
+	// NOOP in Linux, where stale chains are cleaned up when reconciling the policy manager
+}
+
+func (dp *DataPlane) traceHNSACLs(_ *policies.FlowTrace) {
+	// NOOP in Linux
+}
+
+func (dp *DataPlane) dumpEndpoints() []*EndpointDump {
+	// NOOP in Linux, where policies aren't applied to endpoints
+	return nil
+}
+
+func (dp *DataPlane) watchEndpoints() {
+	// NOOP in Linux, where policies aren't applied to endpoints
+}
+
 func (dp *DataPlane) shouldUpdatePod() bool {
 	return false
 }
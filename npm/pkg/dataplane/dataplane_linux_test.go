@@ -81,6 +81,7 @@ func TestNetPolInBackgroundUpdatePolicy(t *testing.T) {
 	defer ioshim.VerifyCalls(t, calls)
 	dp, err := NewDataPlane("testnode", ioshim, netpolInBackgroundCfg, nil)
 	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
 
 	dp.RunPeriodicTasks()
 
@@ -105,6 +106,7 @@ func TestNetPolInBackgroundSkipAddAfterRemove(t *testing.T) {
 	defer ioshim.VerifyCalls(t, calls)
 	dp, err := NewDataPlane("testnode", ioshim, netpolInBackgroundCfg, nil)
 	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
 
 	require.NoError(t, dp.AddPolicy(&testPolicyobj))
 	require.NoError(t, dp.RemovePolicy(testPolicyobj.PolicyKey))
@@ -165,6 +167,7 @@ func TestNetPolInBackgroundFailureToAddFirstTime(t *testing.T) {
 	defer ioshim.VerifyCalls(t, calls)
 	dp, err := NewDataPlane("testnode", ioshim, netpolInBackgroundCfg, nil)
 	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
 
 	require.NoError(t, dp.AddPolicy(&testPolicyobj))
 	require.NoError(t, dp.AddPolicy(&testPolicy2))
@@ -3,6 +3,7 @@ package dataplane
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/npm/metrics"
@@ -74,6 +75,14 @@ var (
 	}
 )
 
+// validateCacheIntegrityOnCleanup checks the bookkeeping of the ipset cache at the end of the test.
+func validateCacheIntegrityOnCleanup(t *testing.T, dp *DataPlane) {
+	t.Helper()
+	t.Cleanup(func() {
+		require.NoError(t, dp.ipsetMgr.ValidateCacheIntegrity(), "ipset cache is inconsistent at the end of the test")
+	})
+}
+
 func TestNewDataPlane(t *testing.T) {
 	metrics.InitializeAll()
 
@@ -82,9 +91,43 @@ func TestNewDataPlane(t *testing.T) {
 	defer ioshim.VerifyCalls(t, calls)
 	dp, err := NewDataPlane("testnode", ioshim, dpCfg, nil)
 	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
 	assert.NotNil(t, dp)
 }
 
+func TestUpdateTunables(t *testing.T) {
+	metrics.InitializeAll()
+
+	calls := getBootupTestCalls()
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	cfg := *dpCfg
+	policyMgrCfg := *dpCfg.PolicyManagerCfg
+	cfg.PolicyManagerCfg = &policyMgrCfg
+	dp, err := NewDataPlane("testnode", ioshim, &cfg, nil)
+	require.NoError(t, err)
+
+	tunables := Tunables{
+		ApplyMaxBatches:      10,
+		ApplyInterval:        time.Second,
+		MaxPendingNetPols:    20,
+		NetPolInterval:       2 * time.Second,
+		MaxBatchedACLsPerPod: 5,
+	}
+	require.NoError(t, dp.UpdateTunables(tunables))
+	require.Equal(t, 10, dp.ApplyMaxBatches)
+	require.Equal(t, time.Second, dp.ApplyInterval)
+	require.Equal(t, 20, dp.MaxPendingNetPols)
+	require.Equal(t, 2*time.Second, dp.NetPolInterval)
+	require.Equal(t, 5, dp.policyMgr.MaxBatchedACLsPerPod)
+	require.Equal(t, time.Second, <-dp.applyIntervalUpdates)
+	require.Equal(t, 2*time.Second, <-dp.netPolIntervalUpdates)
+
+	tunables.ApplyInterval = 0
+	require.ErrorIs(t, dp.UpdateTunables(tunables), ErrInvalidTunables)
+	require.Equal(t, time.Second, dp.ApplyInterval)
+}
+
 func TestCreateAndDeleteIpSets(t *testing.T) {
 	metrics.InitializeAll()
 
@@ -93,6 +136,7 @@ func TestCreateAndDeleteIpSets(t *testing.T) {
 	defer ioshim.VerifyCalls(t, calls)
 	dp, err := NewDataPlane("testnode", ioshim, dpCfg, nil)
 	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
 	assert.NotNil(t, dp)
 	setsTocreate := []*ipsets.IPSetMetadata{
 		{
@@ -135,6 +179,7 @@ func TestAddToSet(t *testing.T) {
 	defer ioshim.VerifyCalls(t, calls)
 	dp, err := NewDataPlane("testnode", ioshim, dpCfg, nil)
 	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
 
 	setsTocreate := []*ipsets.IPSetMetadata{
 		{
@@ -199,6 +244,7 @@ func TestApplyPolicy(t *testing.T) {
 	defer ioshim.VerifyCalls(t, calls)
 	dp, err := NewDataPlane("testnode", ioshim, dpCfg, nil)
 	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
 
 	err = dp.AddPolicy(&testPolicyobj)
 	require.NoError(t, err)
@@ -213,6 +259,7 @@ func TestRemovePolicy(t *testing.T) {
 	defer ioshim.VerifyCalls(t, calls)
 	dp, err := NewDataPlane("testnode", ioshim, dpCfg, nil)
 	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
 
 	err = dp.AddPolicy(&testPolicyobj)
 	require.NoError(t, err)
@@ -221,6 +268,89 @@ func TestRemovePolicy(t *testing.T) {
 	require.NoError(t, err)
 }
 
+type fakeAuditPublisher struct {
+	events []*AuditEvent
+}
+
+func (f *fakeAuditPublisher) Publish(event *AuditEvent) {
+	f.events = append(f.events, event)
+}
+
+func TestAuditEvents(t *testing.T) {
+	metrics.InitializeAll()
+
+	calls := append(getBootupTestCalls(), getAddPolicyTestCallsForDP(&testPolicyobj)...)
+	calls = append(calls, getRemovePolicyTestCallsForDP(&testPolicyobj)...)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	dp, err := NewDataPlane("testnode", ioshim, dpCfg, nil)
+	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
+
+	publisher := &fakeAuditPublisher{}
+	dp.SetAuditPublisher(publisher)
+
+	set := &ipsets.IPSetMetadata{Name: "test", Type: ipsets.Namespace}
+	dp.CreateIPSets([]*ipsets.IPSetMetadata{set})
+	podMetadata := NewPodMetadata("testns/a", "10.0.0.1", "othernode")
+	require.NoError(t, dp.AddToSets([]*ipsets.IPSetMetadata{set}, podMetadata))
+	require.NoError(t, dp.RemoveFromSets([]*ipsets.IPSetMetadata{set}, podMetadata))
+	dp.DeleteIPSet(set, util.SoftDelete)
+
+	require.NoError(t, dp.AddPolicy(&testPolicyobj))
+	require.NoError(t, dp.RemovePolicy(testPolicyobj.PolicyKey))
+
+	require.Len(t, publisher.events, 4)
+	require.Equal(t, AuditIPSetMemberAdded, publisher.events[0].Type)
+	require.Equal(t, set.GetPrefixName(), publisher.events[0].SetName)
+	require.Equal(t, "10.0.0.1", publisher.events[0].Member)
+	require.Equal(t, "testns/a", publisher.events[0].PodKey)
+	require.Equal(t, AuditIPSetMemberRemoved, publisher.events[1].Type)
+	require.Equal(t, AuditPolicyAdded, publisher.events[2].Type)
+	require.Equal(t, testPolicyobj.PolicyKey, publisher.events[2].PolicyKey)
+	require.Equal(t, AuditPolicyRemoved, publisher.events[3].Type)
+	require.Equal(t, testPolicyobj.PolicyKey, publisher.events[3].PolicyKey)
+	for _, event := range publisher.events {
+		require.False(t, event.Timestamp.IsZero())
+	}
+}
+
+func TestDumpState(t *testing.T) {
+	metrics.InitializeAll()
+
+	calls := append(getBootupTestCalls(), getAddPolicyTestCallsForDP(&testPolicyobj)...)
+	calls = append(calls, getRemovePolicyTestCallsForDP(&testPolicyobj)...)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	dp, err := NewDataPlane("testnode", ioshim, dpCfg, nil)
+	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
+
+	set := &ipsets.IPSetMetadata{Name: "test", Type: ipsets.Namespace}
+	dp.CreateIPSets([]*ipsets.IPSetMetadata{set})
+	podMetadata := NewPodMetadata("testns/a", "10.0.0.1", "othernode")
+	require.NoError(t, dp.AddToSets([]*ipsets.IPSetMetadata{set}, podMetadata))
+	require.NoError(t, dp.AddPolicy(&testPolicyobj))
+
+	state := dp.DumpState()
+	require.Len(t, state.Policies, 1)
+	require.Equal(t, testPolicyobj.PolicyKey, state.Policies[0].PolicyKey)
+	var setDump *ipsets.IPSetDump
+	for _, dump := range state.IPSets {
+		if dump.Name == set.GetPrefixName() {
+			setDump = dump
+		}
+	}
+	require.NotNil(t, setDump)
+	require.Equal(t, ipsets.HashSet, setDump.Kind)
+	require.Equal(t, map[string]string{"10.0.0.1": "testns/a"}, setDump.Members)
+	require.Empty(t, state.StaleChains)
+
+	require.NoError(t, dp.RemoveFromSets([]*ipsets.IPSetMetadata{set}, podMetadata))
+	dp.DeleteIPSet(set, util.SoftDelete)
+	require.NoError(t, dp.RemovePolicy(testPolicyobj.PolicyKey))
+}
+
 func TestUpdatePolicy(t *testing.T) {
 	metrics.InitializeAll()
 
@@ -242,6 +372,7 @@ func TestUpdatePolicy(t *testing.T) {
 	defer ioshim.VerifyCalls(t, calls)
 	dp, err := NewDataPlane("testnode", ioshim, dpCfg, nil)
 	require.NoError(t, err)
+	validateCacheIntegrityOnCleanup(t, dp)
 
 	err = dp.AddPolicy(&testPolicyobj)
 	require.NoError(t, err)
@@ -392,6 +523,48 @@ func TestUpdatePodCache(t *testing.T) {
 	require.Equal(t, c.cache, map[string]*updateNPMPod{m1.PodKey: p1, m3Node2.PodKey: p})
 }
 
+func TestUpdateNPMPodSetDiff(t *testing.T) {
+	k1 := ipsets.NewIPSetMetadata("k1", ipsets.KeyLabelOfPod)
+	k1v1 := ipsets.NewIPSetMetadata("k1:v1", ipsets.KeyValueLabelOfPod)
+	k1v2 := ipsets.NewIPSetMetadata("k1:v2", ipsets.KeyValueLabelOfPod)
+
+	p := newUpdateNPMPod(NewPodMetadata("x/a", "10.0.0.1", nodeName))
+
+	// duplicates are ignored
+	p.updateIPSetsToAdd([]*ipsets.IPSetMetadata{k1, k1v1})
+	p.updateIPSetsToAdd([]*ipsets.IPSetMetadata{k1v1})
+	require.Equal(t, []string{k1.GetPrefixName(), k1v1.GetPrefixName()}, p.IPSetsToAdd)
+	require.Empty(t, p.IPSetsToRemove)
+
+	// label value changes: the old value cancels out, the new value is removed
+	p.updateIPSetsToRemove([]*ipsets.IPSetMetadata{k1v1, k1v2})
+	require.Equal(t, []string{k1.GetPrefixName()}, p.IPSetsToAdd)
+	require.Equal(t, []string{k1v2.GetPrefixName()}, p.IPSetsToRemove)
+
+	// label flaps back
+	p.updateIPSetsToAdd([]*ipsets.IPSetMetadata{k1v2})
+	p.updateIPSetsToRemove([]*ipsets.IPSetMetadata{k1})
+	require.Empty(t, p.IPSetsToAdd)
+	require.Empty(t, p.IPSetsToRemove)
+}
+
+func TestUpdatePodCacheIPChange(t *testing.T) {
+	m := NewPodMetadata("x/a", "10.0.0.1", nodeName)
+	mNewIP := NewPodMetadata("x/a", "10.0.0.2", nodeName)
+	set := ipsets.NewIPSetMetadata("k1", ipsets.KeyLabelOfPod)
+
+	c := newUpdatePodCache(1)
+	c.enqueue(m).updateIPSetsToRemove([]*ipsets.IPSetMetadata{set})
+
+	// the recreated pod's set changes must not cancel out the old pod's
+	p := c.enqueue(mNewIP)
+	p.updateIPSetsToAdd([]*ipsets.IPSetMetadata{set})
+	require.Equal(t, mNewIP, p.PodMetadata)
+	require.Equal(t, []string{set.GetPrefixName()}, p.IPSetsToAdd)
+	require.Empty(t, p.IPSetsToRemove)
+	require.Equal(t, []string{mNewIP.PodKey}, c.queue)
+}
+
 func getBootupTestCalls() []testutils.TestCmd {
 	return append(policies.GetBootupTestCalls(true), ipsets.GetResetTestCalls()...)
 }
@@ -4,15 +4,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/Azure/azure-container-networking/network/hnswrapper"
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
 	"github.com/Microsoft/hcsshim/hcn"
-	"k8s.io/klog"
 )
 
 const (
@@ -24,16 +25,23 @@ const (
 	hcnSchemaMinorVersion = 0
 )
 
-var errPolicyModeUnsupported = errors.New("only IPSet policy mode is supported")
+var errPolicyModeUnsupported = errors.New("only IPSet and IP policy modes are supported")
 
 // initializeDataPlane will help gather network and endpoint details
 func (dp *DataPlane) initializeDataPlane() error {
-	klog.Infof("[DataPlane] Initializing dataplane for windows")
+	logger.Infof("[DataPlane] Initializing dataplane for windows")
 
 	if dp.PolicyMode == "" {
 		dp.PolicyMode = policies.IPSetPolicyMode
 	}
-	if dp.PolicyMode != policies.IPSetPolicyMode {
+	switch dp.PolicyMode {
+	case policies.IPSetPolicyMode:
+	case policies.IPPolicyMode:
+		// ACLs contain the IPs of ipsets, for HNS versions without SetPolicy support
+		logger.Infof("[DataPlane] using IP policy mode, ipsets won't be added to HNS")
+		dp.IPSetManagerCfg.SkipSetPolicies = true
+		dp.policyMgr.SetIPSetIPsGetter(dp.ipsetMgr)
+	default:
 		return errPolicyModeUnsupported
 	}
 
@@ -66,29 +74,39 @@ func (dp *DataPlane) initializeDataPlane() error {
 }
 
 func (dp *DataPlane) getNetworkInfo() error {
+	for _, networkName := range dp.NetworkNames() {
+		if err := dp.waitForNetwork(networkName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForNetwork waits for the CNI to create the network before recording its ID.
+func (dp *DataPlane) waitForNetwork(networkName string) error {
 	retryNumber := 0
 	ticker := time.NewTicker(time.Second * time.Duration(maxNoNetSleepTime))
 	defer ticker.Stop()
 
 	var err error
 	for ; true; <-ticker.C {
-		err = dp.setNetworkIDByName(dp.NetworkName)
-		if err == nil || !isNetworkNotFoundErr(err) {
+		err = dp.setNetworkIDByName(networkName)
+		if err == nil || !isNetworkNotFoundErr(err, networkName) {
 			return err
 		}
 		retryNumber++
 		if retryNumber >= maxNoNetRetryCount {
 			break
 		}
-		klog.Infof("[DataPlane Windows] Network with name %s not found. Retrying in %d seconds, Current retry number %d, max retries: %d",
-			dp.NetworkName,
+		logger.Infof("[DataPlane Windows] Network with name %s not found. Retrying in %d seconds, Current retry number %d, max retries: %d",
+			networkName,
 			maxNoNetSleepTime,
 			retryNumber,
 			maxNoNetRetryCount,
 		)
 	}
 
-	return fmt.Errorf("failed to get network info after %d retries with err %w", maxNoNetRetryCount, err)
+	return fmt.Errorf("failed to get network info for network %s after %d retries with err %w", networkName, maxNoNetRetryCount, err)
 }
 
 func (dp *DataPlane) bootupDataPlane() error {
@@ -122,6 +140,95 @@ func (dp *DataPlane) bootupDataPlane() error {
 	return nil
 }
 
+// refreshPolicyIPs updates the ACLs of the policies referring to updated ipsets in IP policy mode,
+// since the ACLs contain the IPs of the ipsets.
+func (dp *DataPlane) refreshPolicyIPs() {
+	if dp.PolicyMode != policies.IPPolicyMode {
+		return
+	}
+
+	policyKeys := dp.ipsetMgr.TakeNetPolsWithUpdatedSets()
+	if len(policyKeys) == 0 {
+		return
+	}
+
+	if err := dp.policyMgr.RefreshPolicyIPs(policyKeys); err != nil {
+		// the ACLs will be fixed when reconciling the policies
+		metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] failed to refresh the IPs of policies. err: [%s]", err.Error())
+	}
+}
+
+// removeOrphanedACLs removes the ACLs of deleted policies from all endpoints of the network,
+// which reconciling the policies misses on the endpoints that aren't attached to running pods.
+func (dp *DataPlane) removeOrphanedACLs() {
+	endpoints, err := dp.getAllPodEndpoints()
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] failed to list endpoints while removing orphaned ACLs. err: [%s]", err.Error())
+		return
+	}
+
+	numRemoved, err := dp.policyMgr.RemoveOrphanedACLs(endpoints)
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] failed to remove orphaned ACLs. err: [%s]", err.Error())
+	}
+	if numRemoved > 0 {
+		logger.Infof("[DataPlane] removed %d orphaned ACLs from endpoints of networks %v", numRemoved, dp.NetworkNames())
+	}
+}
+
+// traceHNSACLs adds the HNS ACLs of the traced policies on the endpoints of the source and destination pods.
+// Pods which are not on this node have no endpoint to trace.
+func (dp *DataPlane) traceHNSACLs(trace *policies.FlowTrace) {
+	for _, directionTrace := range []*policies.DirectionTrace{&trace.Egress, &trace.Ingress} {
+		podIP := trace.Flow.DstIP
+		if directionTrace.Direction == policies.Egress {
+			podIP = trace.Flow.SrcIP
+		}
+		dp.endpointCache.Lock()
+		endpoint, ok := dp.endpointCache.cache[podIP.String()]
+		dp.endpointCache.Unlock()
+		if !ok || len(directionTrace.Policies) == 0 {
+			continue
+		}
+
+		policyKeys := make([]string, 0, len(directionTrace.Policies))
+		for _, policy := range directionTrace.Policies {
+			policyKeys = append(policyKeys, policy.PolicyKey)
+		}
+		acls, err := dp.policyMgr.GetEndpointACLs(endpoint.id, policyKeys)
+		if err != nil {
+			logger.Warnf("[DataPlane] failed to trace the HNS ACLs of endpoint %s. err: %s", endpoint.id, err.Error())
+			continue
+		}
+		trace.HNSACLs = append(trace.HNSACLs, acls...)
+	}
+}
+
+// dumpEndpoints returns the endpoints in the cache, sorted by IP, with the policies applied to them.
+func (dp *DataPlane) dumpEndpoints() []*EndpointDump {
+	dp.endpointCache.Lock()
+	defer dp.endpointCache.Unlock()
+
+	endpoints := make([]*EndpointDump, 0, len(dp.endpointCache.cache))
+	for _, endpoint := range dp.endpointCache.cache {
+		policyKeys := make([]string, 0, len(endpoint.netPolReference))
+		for policyKey := range endpoint.netPolReference {
+			policyKeys = append(policyKeys, policyKey)
+		}
+		sort.Strings(policyKeys)
+		endpoints = append(endpoints, &EndpointDump{
+			ID:       endpoint.id,
+			IP:       endpoint.ip,
+			PodKey:   endpoint.podKey,
+			Policies: policyKeys,
+		})
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].IP < endpoints[j].IP
+	})
+	return endpoints
+}
+
 func (dp *DataPlane) shouldUpdatePod() bool {
 	return true
 }
@@ -131,7 +238,7 @@ func (dp *DataPlane) shouldUpdatePod() bool {
 // 2. Will check for existing applicable network policies and applies it on endpoint.
 // Assumption: a Pod won't take up its previously used IP when restarting (see https://stackoverflow.com/questions/52362514/when-will-the-kubernetes-pod-ip-change)
 func (dp *DataPlane) updatePod(pod *updateNPMPod) error {
-	klog.Infof("[DataPlane] updatePod called. podKey: %s", pod.PodKey)
+	logger.Infof("[DataPlane] updatePod called. podKey: %s", pod.PodKey)
 	if len(pod.IPSetsToAdd) == 0 && len(pod.IPSetsToRemove) == 0 {
 		// nothing to do
 		return nil
@@ -146,20 +253,20 @@ func (dp *DataPlane) updatePod(pod *updateNPMPod) error {
 	if !ok {
 		// ignore this err and pod endpoint will be deleted in ApplyDP
 		// if the endpoint is not found, it means the pod is not part of this node or pod got deleted.
-		klog.Warningf("[DataPlane] ignoring pod update since there is no corresponding endpoint. IP: %s. podKey: %s", pod.PodIP, pod.PodKey)
+		logger.Warnf("[DataPlane] ignoring pod update since there is no corresponding endpoint. IP: %s. podKey: %s", pod.PodIP, pod.PodKey)
 		return nil
 	}
 
 	if endpoint.podKey == unspecifiedPodKey {
 		// while refreshing pod endpoints, newly discovered endpoints are given an unspecified pod key
-		klog.Infof("[DataPlane] associating pod with endpoint. podKey: %s. endpoint: %+v", pod.PodKey, endpoint)
+		logger.Infof("[DataPlane] associating pod with endpoint. podKey: %s. endpoint: %+v", pod.PodKey, endpoint)
 		endpoint.podKey = pod.PodKey
 	} else if pod.PodKey == endpoint.previousIncorrectPodKey {
-		klog.Infof("[DataPlane] ignoring pod update since this pod was previously and incorrectly assigned to this endpoint. endpoint: %+v", endpoint)
+		logger.Infof("[DataPlane] ignoring pod update since this pod was previously and incorrectly assigned to this endpoint. endpoint: %+v", endpoint)
 		return nil
 	} else if pod.PodKey != endpoint.podKey {
 		// solves issue 1729
-		klog.Infof("[DataPlane] pod key has changed. will reset endpoint acls and skip looking ipsets to remove. new podKey: %s. previous endpoint: %+v", pod.PodKey, endpoint)
+		logger.Infof("[DataPlane] pod key has changed. will reset endpoint acls and skip looking ipsets to remove. new podKey: %s. previous endpoint: %+v", pod.PodKey, endpoint)
 		if err := dp.policyMgr.ResetEndpoint(endpoint.id); err != nil {
 			return fmt.Errorf("failed to reset endpoint for pod with incorrect pod key. new podKey: %s. previous endpoint: %+v. err: %w", pod.PodKey, endpoint, err)
 		}
@@ -171,98 +278,32 @@ func (dp *DataPlane) updatePod(pod *updateNPMPod) error {
 		// all ACLs were removed, so in case there were ipsets to remove, there's no need to look for policies to delete
 		pod.IPSetsToRemove = nil
 
-		if dp.NetworkName == util.CalicoNetworkName {
-			klog.Infof("adding back base ACLs for calico CNI endpoint after resetting ACLs. endpoint: %+v", endpoint)
+		if dp.isCalicoEndpoint(endpoint.networkID) {
+			logger.Infof("adding back base ACLs for calico CNI endpoint after resetting ACLs. endpoint: %+v", endpoint)
 			dp.policyMgr.AddBaseACLsForCalicoCNI(endpoint.id)
 		}
 	}
 
-	// for every ipset we're removing from the endpoint, remove from the endpoint any policy that requires the set
-	for _, setName := range pod.IPSetsToRemove {
-		/*
-			Scenarios:
-			1. There's a chance a policy is/was just removed, but the ipset's selector hasn't been updated yet.
-			   We may try to remove the policy again here, which is ok.
-
-			2. If a policy is added to the ipset's selector after getting the selector (meaning dp.AddPolicy() was called),
-			   we won't try to remove the policy, which is fine since the policy must've never existed on the endpoint.
-
-			3. If a policy is added to the ipset's selector in a dp.AddPolicy() thread AFTER getting the selector here,
-			   then the ensuing policyMgr.AddPolicy() call will wait for this function to release the endpointCache lock.
-
-			4. If a policy is added to the ipset's selector in a dp.AddPolicy() thread BEFORE getting the selector here,
-			   there could be a race between policyMgr.RemovePolicy() here and policyMgr.AddPolicy() there.
-		*/
-		selectorReference, err := dp.ipsetMgr.GetSelectorReferencesBySet(setName)
-		if err != nil {
-			// ignore this set since it may have been deleted in the background reconcile thread
-			klog.Infof("[DataPlane] ignoring pod update for ipset to remove since the set does not exist. pod: %+v. set: %s", pod, setName)
-			continue
-		}
+	// diff the policies applied to the endpoint against the policies that apply to the pod now.
+	// Only policies selecting a set the pod left or joined can change, and only those that changed are touched.
+	toRemovePolicies, toAddPolicies, err := dp.diffEndpointPolicies(pod, endpoint)
+	if err != nil {
+		return err
+	}
 
-		for policyKey := range selectorReference {
-			// Now check if any of these network policies are applied on this endpoint.
-			// If yes then proceed to delete the network policy.
-			if _, ok := endpoint.netPolReference[policyKey]; ok {
-				// Delete the network policy
-				endpointList := map[string]string{
-					endpoint.ip: endpoint.id,
-				}
-				err := dp.policyMgr.RemovePolicyForEndpoints(policyKey, endpointList)
-				if err != nil {
-					return err
-				}
-				delete(endpoint.netPolReference, policyKey)
-			}
-		}
+	if len(toRemovePolicies) == 0 && len(toAddPolicies) == 0 {
+		logger.Infof("[DataPlane] no policy changes for pod. podKey: %s. endpoint: %+v", pod.PodKey, endpoint)
+		return nil
 	}
 
-	// for every ipset we're adding to the endpoint, consider adding to the endpoint every policy that the set touches
-	// add policy if:
-	// 1. it's not already there
-	// 2. the pod IP is part of every set that the policy requires (every set in the pod selector)
-	toAddPolicies := make(map[string]struct{})
-	for _, setName := range pod.IPSetsToAdd {
-		/*
-			Scenarios:
-			1. If a policy is added to the ipset's selector after getting the selector (meaning dp.AddPolicy() was called),
-			   we will miss adding the policy here, but will add the policy to all endpoints in that other thread, which has
-			   to wait on the endpointCache lock when calling getEndpointsToApplyPolicies().
-
-			2. We may add the policy here and in the dp.AddPolicy() thread if the policy is added to the ipset's selector before
-			   that other thread calls policyMgr.AddPolicy(), which is ok.
-
-			3. FIXME: If a policy is/was just removed, but the ipset's selector hasn't been updated yet,
-			   we may try to add the policy again here...
-		*/
-		selectorReference, err := dp.ipsetMgr.GetSelectorReferencesBySet(setName)
-		if err != nil {
-			// ignore this set since it may have been deleted in the background reconcile thread
-			klog.Infof("[DataPlane] ignoring pod update for ipset to remove since the set does not exist. pod: %+v. set: %s", pod, setName)
-			continue
+	if len(toRemovePolicies) > 0 {
+		removedPolicies, err := dp.policyMgr.RemoveAllPolicies(toRemovePolicies, endpoint.id, endpoint.ip)
+		for policyKey := range removedPolicies {
+			delete(endpoint.netPolReference, policyKey)
+			dp.publishEndpoints(AuditEndpointPolicyDetached, policyKey, map[string]string{endpoint.ip: endpoint.id})
 		}
-
-		for policyKey := range selectorReference {
-			if _, ok := endpoint.netPolReference[policyKey]; ok {
-				continue
-			}
-
-			policy, ok := dp.policyMgr.GetPolicy(policyKey)
-			if !ok {
-				klog.Infof("[DataPlane] while updating pod, policy is referenced but does not exist. pod: [%s], policy: [%s], set [%s]", pod.PodKey, policyKey, setName)
-				continue
-			}
-
-			selectorIPSets := dp.getSelectorIPSets(policy)
-			ok, err := dp.ipsetMgr.DoesIPSatisfySelectorIPSets(pod.PodIP, pod.PodKey, selectorIPSets)
-			if err != nil {
-				return fmt.Errorf("[DataPlane] error getting IPs satisfying selector ipsets: %w", err)
-			}
-			if !ok {
-				continue
-			}
-
-			toAddPolicies[policyKey] = struct{}{}
+		if err != nil {
+			return fmt.Errorf("failed to remove all policies while updating pod. endpoint: %+v. policies: %+v. err: %w", endpoint, toRemovePolicies, err)
 		}
 	}
 
@@ -273,22 +314,96 @@ func (dp *DataPlane) updatePod(pod *updateNPMPod) error {
 	successfulPolicies, err := dp.policyMgr.AddAllPolicies(toAddPolicies, endpoint.id, endpoint.ip)
 	for policyKey := range successfulPolicies {
 		endpoint.netPolReference[policyKey] = struct{}{}
+		dp.publishEndpoints(AuditEndpointPolicyAttached, policyKey, map[string]string{endpoint.ip: endpoint.id})
 	}
 	if err != nil {
 		return fmt.Errorf("failed to add all policies while updating pod. endpoint: %+v. policies: %+v. err: %w", endpoint, toAddPolicies, err)
 	}
 
-	klog.Infof("[DataPlane] updatedPod complete. podKey: %s. endpoint: %+v", pod.PodKey, endpoint)
+	logger.Infof("[DataPlane] updatedPod complete. podKey: %s. endpoint: %+v", pod.PodKey, endpoint)
 
 	return nil
 }
 
+// diffEndpointPolicies returns the policies to remove from and add to the pod's endpoint.
+// The candidates are the policies selecting a set that the pod left or joined. A candidate is removed if it's applied
+// but no longer selects the pod, and added if it selects the pod but isn't applied yet.
+// Candidates whose applicability is unchanged, e.g. when a label flaps between values that the same policy selects, are left alone.
+func (dp *DataPlane) diffEndpointPolicies(pod *updateNPMPod, endpoint *npmEndpoint) (toRemove, toAdd map[string]struct{}, err error) {
+	/*
+		Scenarios:
+		1. There's a chance a policy is/was just removed, but the ipset's selector hasn't been updated yet.
+		   We may try to remove the policy again here, which is ok.
+
+		2. If a policy is added to the ipset's selector after getting the selector (meaning dp.AddPolicy() was called),
+		   we will miss the policy here, but the other thread will add the policy to all endpoints, which has
+		   to wait on the endpointCache lock when calling getEndpointsToApplyPolicies().
+
+		3. We may add the policy here and in the dp.AddPolicy() thread if the policy is added to the ipset's selector before
+		   that other thread calls policyMgr.AddPolicy(), which is ok.
+
+		4. If a policy is added to the ipset's selector in a dp.AddPolicy() thread BEFORE getting the selector here,
+		   there could be a race between policyMgr.RemovePolicy() here and policyMgr.AddPolicy() there.
+	*/
+	candidates := make(map[string]struct{})
+	for _, setNames := range [][]string{pod.IPSetsToRemove, pod.IPSetsToAdd} {
+		for _, setName := range setNames {
+			selectorReference, err := dp.ipsetMgr.GetSelectorReferencesBySet(setName)
+			if err != nil {
+				// ignore this set since it may have been deleted in the background reconcile thread
+				logger.Infof("[DataPlane] ignoring pod update for ipset since the set does not exist. pod: %+v. set: %s", pod, setName)
+				continue
+			}
+
+			for policyKey := range selectorReference {
+				candidates[policyKey] = struct{}{}
+			}
+		}
+	}
+
+	toRemove = make(map[string]struct{})
+	toAdd = make(map[string]struct{})
+	for policyKey := range candidates {
+		_, applied := endpoint.netPolReference[policyKey]
+
+		policy, ok := dp.policyMgr.GetPolicy(policyKey)
+		if !ok {
+			if applied {
+				toRemove[policyKey] = struct{}{}
+			} else {
+				logger.Infof("[DataPlane] while updating pod, policy is referenced but does not exist. pod: [%s], policy: [%s]", pod.PodKey, policyKey)
+			}
+			continue
+		}
+
+		selectorIPSets := dp.getSelectorIPSets(policy)
+		selected, err := dp.ipsetMgr.DoesIPSatisfySelectorIPSets(pod.PodIP, pod.PodKey, selectorIPSets)
+		if err != nil {
+			if applied {
+				// a selector set may have been deleted in the background reconcile thread, so the policy no longer selects the pod
+				toRemove[policyKey] = struct{}{}
+				continue
+			}
+			return nil, nil, fmt.Errorf("[DataPlane] error getting IPs satisfying selector ipsets: %w", err)
+		}
+
+		switch {
+		case applied && !selected:
+			toRemove[policyKey] = struct{}{}
+		case !applied && selected:
+			toAdd[policyKey] = struct{}{}
+		}
+	}
+
+	return toRemove, toAdd, nil
+}
+
 func (dp *DataPlane) getSelectorIPSets(policy *policies.NPMNetworkPolicy) map[string]struct{} {
 	selectorIpSets := make(map[string]struct{})
 	for _, ipset := range policy.PodSelectorIPSets {
 		selectorIpSets[ipset.Metadata.GetPrefixName()] = struct{}{}
 	}
-	klog.Infof("policy %s has policy selector: %+v", policy.PolicyKey, selectorIpSets)
+	logger.Infof("policy %s has policy selector: %+v", policy.PolicyKey, selectorIpSets)
 	return selectorIpSets
 }
 
@@ -312,13 +427,13 @@ func (dp *DataPlane) getEndpointsToApplyPolicies(netPols []*policies.NPMNetworkP
 	for ip, podKey := range netpolSelectorIPs {
 		endpoint, ok := dp.endpointCache.cache[ip]
 		if !ok {
-			klog.Infof("[DataPlane] ignoring selector IP since it was not found in the endpoint cache and might not be in the HNS network. ip: %s. podKey: %s", ip, podKey)
+			logger.Infof("[DataPlane] ignoring selector IP since it was not found in the endpoint cache and might not be in the HNS network. ip: %s. podKey: %s", ip, podKey)
 			continue
 		}
 
 		if endpoint.podKey != podKey {
 			// in case the pod controller hasn't updated the dp yet that the IP's pod owner has changed
-			klog.Infof("[DataPlane] ignoring selector IP since the endpoint is assigned to a different podKey. ip: %s. podKey: %s. endpoint: %+v", ip, podKey, endpoint)
+			logger.Infof("[DataPlane] ignoring selector IP since the endpoint is assigned to a different podKey. ip: %s. podKey: %s. endpoint: %+v", ip, podKey, endpoint)
 			continue
 		}
 
@@ -329,24 +444,26 @@ func (dp *DataPlane) getEndpointsToApplyPolicies(netPols []*policies.NPMNetworkP
 }
 
 func (dp *DataPlane) getAllPodEndpoints() ([]*hcn.HostComputeEndpoint, error) {
-	klog.Infof("getting all endpoints for network ID %s", dp.networkID)
-	timer := metrics.StartNewTimer()
-	endpoints, err := dp.ioShim.Hns.ListEndpointsOfNetwork(dp.networkID)
-	metrics.RecordListEndpointsLatency(timer)
-	if err != nil {
-		metrics.IncListEndpointsFailures()
-		return nil, npmerrors.SimpleErrorWrapper("failed to get all pod endpoints", err)
-	}
+	epPointers := make([]*hcn.HostComputeEndpoint, 0)
+	for networkID := range dp.networks {
+		logger.Infof("getting all endpoints for network ID %s", networkID)
+		timer := metrics.StartNewTimer()
+		endpoints, err := dp.ioShim.Hns.ListEndpointsOfNetwork(networkID)
+		metrics.RecordListEndpointsLatency(timer)
+		if err != nil {
+			metrics.IncListEndpointsFailures()
+			return nil, npmerrors.SimpleErrorWrapper("failed to get all pod endpoints", err)
+		}
 
-	epPointers := make([]*hcn.HostComputeEndpoint, 0, len(endpoints))
-	for k := range endpoints {
-		epPointers = append(epPointers, &endpoints[k])
+		for k := range endpoints {
+			epPointers = append(epPointers, &endpoints[k])
+		}
 	}
 	return epPointers, nil
 }
 
 func (dp *DataPlane) getLocalPodEndpoints() ([]*hcn.HostComputeEndpoint, error) {
-	klog.Info("getting local endpoints")
+	logger.Info("getting local endpoints")
 	timer := metrics.StartNewTimer()
 	endpoints, err := dp.ioShim.Hns.ListEndpointsQuery(dp.endpointQuery.query)
 	metrics.RecordListEndpointsLatency(timer)
@@ -392,12 +509,12 @@ func (dp *DataPlane) refreshPodEndpoints() error {
 	existingIPs := make(map[string]struct{})
 	for _, endpoint := range endpoints {
 		if len(endpoint.IpConfigurations) == 0 {
-			klog.Infof("Endpoint ID %s has no IPAddreses", endpoint.Id)
+			logger.Infof("Endpoint ID %s has no IPAddreses", endpoint.Id)
 			continue
 		}
 		ip := endpoint.IpConfigurations[0].IpAddress
 		if ip == "" {
-			klog.Infof("Endpoint ID %s has empty IPAddress field", endpoint.Id)
+			logger.Infof("Endpoint ID %s has empty IPAddress field", endpoint.Id)
 			continue
 		}
 
@@ -409,13 +526,13 @@ func (dp *DataPlane) refreshPodEndpoints() error {
 			npmEP := newNPMEndpoint(endpoint)
 			dp.endpointCache.cache[ip] = npmEP
 			// NOTE: TSGs rely on this log line
-			klog.Infof("updating endpoint cache to include %s: %+v", npmEP.ip, npmEP)
+			logger.Infof("updating endpoint cache to include %s: %+v", npmEP.ip, npmEP)
 
-			if dp.NetworkName == util.CalicoNetworkName {
+			if dp.isCalicoEndpoint(npmEP.networkID) {
 				// NOTE 1: connectivity may be broken for an endpoint until this method is called
 				// NOTE 2: if NPM restarted, technically we could call into HNS to add the base ACLs even if they already exist on the Endpoint.
 				// It doesn't seem worthwhile to account for these edge-cases since using calico network is currently intended just for testing
-				klog.Infof("adding base ACLs for calico CNI endpoint. IP: %s. ID: %s", ip, npmEP.id)
+				logger.Infof("adding base ACLs for calico CNI endpoint. IP: %s. ID: %s", ip, npmEP.id)
 				dp.policyMgr.AddBaseACLsForCalicoCNI(npmEP.id)
 			}
 		} else if oldNPMEP.id != endpoint.Id {
@@ -423,14 +540,14 @@ func (dp *DataPlane) refreshPodEndpoints() error {
 			// throw away old endpoints that have the same IP as a current endpoint (the old endpoint is getting deleted)
 			// we don't have to worry about cleaning up network policies on endpoints that are getting deleted
 			npmEP := newNPMEndpoint(endpoint)
-			klog.Infof("[DataPlane] updating endpoint cache for IP with a new endpoint. old endpoint: %+v. new endpoint: %+v", oldNPMEP, npmEP)
+			logger.Infof("[DataPlane] updating endpoint cache for IP with a new endpoint. old endpoint: %+v. new endpoint: %+v", oldNPMEP, npmEP)
 			dp.endpointCache.cache[ip] = npmEP
 
-			if dp.NetworkName == util.CalicoNetworkName {
+			if dp.isCalicoEndpoint(npmEP.networkID) {
 				// NOTE 1: connectivity may be broken for an endpoint until this method is called
 				// NOTE 2: if NPM restarted, technically we could call into HNS to add the base ACLs even if they already exist on the Endpoint.
 				// It doesn't seem worthwhile to account for these edge-cases since using calico network is currently intended just for testing
-				klog.Infof("adding base ACLs for calico CNI endpoint. IP: %s. ID: %s", ip, npmEP.id)
+				logger.Infof("adding base ACLs for calico CNI endpoint. IP: %s. ID: %s", ip, npmEP.id)
 				dp.policyMgr.AddBaseACLsForCalicoCNI(npmEP.id)
 			}
 		}
@@ -439,7 +556,7 @@ func (dp *DataPlane) refreshPodEndpoints() error {
 	// garbage collection for the endpoint cache
 	for ip, ep := range dp.endpointCache.cache {
 		if _, ok := existingIPs[ip]; !ok {
-			klog.Infof("[DataPlane] deleting endpoint from cache. endpoint: %+v", ep)
+			logger.Infof("[DataPlane] deleting endpoint from cache. endpoint: %+v", ep)
 			delete(dp.endpointCache.cache, ip)
 		}
 	}
@@ -447,6 +564,66 @@ func (dp *DataPlane) refreshPodEndpoints() error {
 	return nil
 }
 
+// watchEndpoints subscribes to HNS endpoint notifications until the dataplane is stopped.
+// Each notification refreshes the endpoint cache, and applies the pending pod updates if there are any,
+// so that new pods don't wait on the next background apply. Notifications arriving while handling one are coalesced.
+// Polling HNS before updating pods is kept, so a missed notification only delays a pod until the next apply.
+func (dp *DataPlane) watchEndpoints() {
+	notifier, ok := dp.ioShim.Hns.(hnswrapper.EndpointNotifier)
+	if !ok {
+		logger.Infof("[DataPlane] HNS doesn't support endpoint notifications. endpoints will be refreshed while applying the dataplane")
+		return
+	}
+
+	events := make(chan hnswrapper.EndpointNotification, 1)
+	unsubscribe, err := notifier.SubscribeEndpointNotifications(func(notification hnswrapper.EndpointNotification) {
+		select {
+		case events <- notification:
+		default:
+			// a refresh is already pending
+		}
+	})
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] failed to subscribe to HNS endpoint notifications. endpoints will be refreshed while applying the dataplane. err: [%s]", err.Error())
+		return
+	}
+	logger.Infof("[DataPlane] subscribed to HNS endpoint notifications")
+
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case <-dp.stopChannel:
+				return
+			case notification := <-events:
+				dp.onEndpointNotification(notification)
+			}
+		}
+	}()
+}
+
+func (dp *DataPlane) onEndpointNotification(notification hnswrapper.EndpointNotification) {
+	if notification == hnswrapper.ServiceDisconnected {
+		logger.Infof("[DataPlane] HNS disconnected. refreshing endpoints in case notifications were missed")
+	}
+
+	dp.updatePodCache.Lock()
+	pendingPods := !dp.updatePodCache.isEmpty()
+	dp.updatePodCache.Unlock()
+
+	if pendingPods {
+		// refreshes the endpoints before updating the pods
+		if err := dp.applyDataPlaneNow(contextEndpointEvent); err != nil {
+			metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] failed to apply dataplane after endpoint notification. err: [%s]", err.Error())
+		}
+		return
+	}
+
+	if err := dp.refreshPodEndpoints(); err != nil {
+		metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] failed to refresh endpoints after endpoint notification. err: [%s]", err.Error())
+	}
+}
+
 func (dp *DataPlane) setNetworkIDByName(networkName string) error {
 	// Get Network ID
 	timer := metrics.StartNewTimer()
@@ -457,11 +634,15 @@ func (dp *DataPlane) setNetworkIDByName(networkName string) error {
 		return err
 	}
 
-	dp.networkID = network.Id
+	dp.networks[network.Id] = networkName
 	return nil
 }
 
-func isNetworkNotFoundErr(err error) bool {
-	return strings.Contains(err.Error(), fmt.Sprintf("Network name %q not found", util.AzureNetworkName)) ||
-		strings.Contains(err.Error(), fmt.Sprintf("Network name %q not found", util.CalicoNetworkName))
+// isCalicoEndpoint returns whether the endpoint is in the Calico network, which needs base ACLs.
+func (dp *DataPlane) isCalicoEndpoint(networkID string) bool {
+	return dp.networks[networkID] == util.CalicoNetworkName
+}
+
+func isNetworkNotFoundErr(err error, networkName string) bool {
+	return strings.Contains(err.Error(), fmt.Sprintf("Network name %q not found", networkName))
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/network/hnswrapper"
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
@@ -28,6 +29,7 @@ func TestMetrics(t *testing.T) {
 	io := common.NewMockIOShimWithFakeHNS(hns)
 	dp, err := NewDataPlane(thisNode, io, cfg, nil)
 	require.NoError(t, err, "failed to initialize dp")
+	validateCacheIntegrityOnCleanup(t, dp)
 	require.NotNil(t, dp, "failed to initialize dp (nil)")
 
 	count, err := metrics.TotalGetNetworkLatencyCalls()
@@ -78,6 +80,48 @@ func TestApplyInBackgroundBootupPhase(t *testing.T) {
 	testSerialCases(t, applyInBackgroundBootupPhaseTests(), time.Duration(200*time.Millisecond))
 }
 
+func TestWatchEndpoints(t *testing.T) {
+	cfg := *defaultWindowsDPCfg
+	cfg.ApplyInBackground = true
+	cfg.ApplyMaxBatches = 100
+	// pod updates would wait on the background apply without the endpoint notification
+	cfg.ApplyInterval = time.Hour
+	cfg.WatchEndpoints = true
+
+	hns := ipsets.GetHNSFake(t, cfg.NetworkName)
+	io := common.NewMockIOShimWithFakeHNS(hns)
+	dp, err := NewDataPlane(thisNode, io, &cfg, nil)
+	require.NoError(t, err, "failed to initialize dp")
+	dp.RunPeriodicTasks()
+
+	actions := []*Action{
+		FinishBootupPhase(),
+		UpdatePolicy(policyXBaseOnK1V1()),
+		CreatePod("x", "a", ip1, thisNode, map[string]string{"k1": "v1"}),
+		ApplyDP(),
+		CreateEndpoint(endpoint1, ip1),
+	}
+	for i, a := range actions {
+		if a.HNSAction != nil {
+			err = a.HNSAction.Do(hns)
+		} else {
+			err = a.DPAction.Do(dp)
+		}
+		require.NoError(t, err, "failed to run action %d", i)
+	}
+
+	require.Eventually(t, func() bool {
+		return len(hns.Cache.GetAllACLs()[endpoint1]) == 3
+	}, 5*time.Second, 10*time.Millisecond, "policy not applied to the new endpoint")
+	dptestutils.VerifyACLs(t, hns, map[string][]*hnswrapper.FakeEndpointPolicy{
+		endpoint1: {
+			{ID: "azure-acl-x-base", Action: "Allow", Direction: "In", Priority: 222},
+			{ID: "azure-acl-x-base", Action: "Allow", Direction: "Out", Priority: 222},
+			{ID: "azure-acl-x-base", Action: "Allow", Direction: "In", RemoteAddresses: testNodeIP, Priority: 201},
+		},
+	})
+}
+
 func TestAllMultiJobCases(t *testing.T) {
 	testMultiJobCases(t, getAllMultiJobTests(), 0)
 }
@@ -104,6 +148,7 @@ func testSerialCases(t *testing.T, tests []*SerialTestCase, finalSleep time.Dura
 
 			dp, err := NewDataPlane(thisNode, io, tt.DpCfg, nil)
 			require.NoError(t, err, "failed to initialize dp")
+			validateCacheIntegrityOnCleanup(t, dp)
 			require.NotNil(t, dp, "failed to initialize dp (nil)")
 
 			dp.RunPeriodicTasks()
@@ -144,6 +189,7 @@ func testMultiJobCases(t *testing.T, tests []*MultiJobTestCase, finalSleep time.
 			// the dp is necessary for NPM tests
 			dp, err := NewDataPlane(thisNode, io, tt.DpCfg, nil)
 			require.NoError(t, err, "failed to initialize dp")
+			validateCacheIntegrityOnCleanup(t, dp)
 
 			dp.RunPeriodicTasks()
 
@@ -0,0 +1,198 @@
+package debug
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+)
+
+const (
+	ipsetTypeList   = "list:set"
+	ipsetTypeIPPort = "hash:ip,port"
+	// maxListDepth bounds the recursion into list:set members, which can't nest in the kernel anyway.
+	maxListDepth = 8
+)
+
+var (
+	ErrIPSetSaveLine    = fmt.Errorf("unexpected ipset save line")
+	ErrUnknownIPSet     = fmt.Errorf("ipset does not exist")
+	ErrUnsupportedIPSet = fmt.Errorf("unsupported ipset type")
+)
+
+// IPSetSave is the membership of the ipsets of a node, as listed by ipset save.
+type IPSetSave struct {
+	sets map[string]*savedIPSet
+}
+
+type savedIPSet struct {
+	setType string
+	members []string
+}
+
+// ParseIPSetSave parses the output of ipset save.
+func ParseIPSetSave(r io.Reader) (*IPSetSave, error) {
+	save := &IPSetSave{sets: make(map[string]*savedIPSet)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 3 { //nolint:gomnd // command, set name and type or member
+			return nil, fmt.Errorf("%w: %s", ErrIPSetSaveLine, scanner.Text())
+		}
+		switch fields[0] {
+		case "create":
+			save.sets[fields[1]] = &savedIPSet{setType: fields[2]}
+		case "add":
+			set, ok := save.sets[fields[1]]
+			if !ok {
+				return nil, fmt.Errorf("%w: member of %s added before the set is created", ErrIPSetSaveLine, fields[1])
+			}
+			// keep the nomatch option of hash:net members, drop the others such as timeout
+			member := fields[2]
+			for _, option := range fields[3:] {
+				if option == "nomatch" {
+					member += " nomatch"
+				}
+			}
+			set.members = append(set.members, member)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrIPSetSaveLine, scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ipset save: %w", err)
+	}
+	return save, nil
+}
+
+// IPSetSaveFromNode runs ipset save on this node and parses its output.
+func IPSetSaveFromNode() (*IPSetSave, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(util.Ipset, util.IpsetSaveFlag) //nolint:gosec // constant arguments
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run ipset save: %s: %w", strings.TrimSpace(stderr.String()), err)
+	}
+	return ParseIPSetSave(&stdout)
+}
+
+// match reports whether the packet is in the set, looking up the packet fields named by the direction flags of the
+// match-set option, e.g. "src" or "dst,dst". It also returns the member which matched, for the trace.
+func (s *IPSetSave) match(name, flags string, p *Packet) (bool, string, error) {
+	return s.matchDepth(name, strings.Split(flags, ","), p, 0)
+}
+
+func (s *IPSetSave) matchDepth(name string, flags []string, p *Packet, depth int) (bool, string, error) {
+	set, ok := s.sets[name]
+	if !ok {
+		return false, "", fmt.Errorf("%w: %s", ErrUnknownIPSet, name)
+	}
+
+	switch {
+	case set.setType == ipsetTypeList:
+		if depth >= maxListDepth {
+			return false, "", fmt.Errorf("%w: list %s nests too deep", ErrUnsupportedIPSet, name)
+		}
+		for _, member := range set.members {
+			matched, matchedMember, err := s.matchDepth(member, flags, p, depth+1)
+			if err != nil {
+				return false, "", err
+			}
+			if matched {
+				return true, member + " > " + matchedMember, nil
+			}
+		}
+		return false, "", nil
+
+	case set.setType == ipsetTypeIPPort:
+		if len(flags) < 2 { //nolint:gomnd // ip and port
+			return false, "", fmt.Errorf("%w: %s needs an ip and a port flag, got %v", ErrUnsupportedIPSet, name, flags)
+		}
+		ip := p.ip(flags[0])
+		key := fmt.Sprintf("%s,%s:%d", ip, strings.ToLower(p.Protocol), p.port(flags[1]))
+		for _, member := range set.members {
+			if member == key {
+				return true, member, nil
+			}
+		}
+		return false, "", nil
+
+	case strings.HasPrefix(set.setType, "hash:net") || strings.HasPrefix(set.setType, "hash:ip"):
+		return matchNetMembers(set.members, p.ip(flags[0]))
+
+	default:
+		return false, "", fmt.Errorf("%w: %s of set %s", ErrUnsupportedIPSet, set.setType, name)
+	}
+}
+
+// matchNetMembers matches the IP against hash:net members like the kernel does: the most specific member containing
+// the IP wins, so an IP in a nomatch member is not in the set even when a broader member contains it.
+func matchNetMembers(members []string, ip net.IP) (bool, string, error) {
+	bestOnes := -1
+	best := ""
+	for _, member := range members {
+		cidr := strings.TrimSuffix(member, " nomatch")
+		if !strings.Contains(cidr, "/") {
+			if ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to parse member %s: %w", member, err)
+		}
+		if !ipnet.Contains(ip) {
+			continue
+		}
+		if ones, _ := ipnet.Mask.Size(); ones > bestOnes {
+			bestOnes = ones
+			best = member
+		}
+	}
+	if best == "" || strings.HasSuffix(best, " nomatch") {
+		return false, best, nil
+	}
+	return true, best, nil
+}
+
+// Packet is the 5-tuple of a packet to trace. Ports are 0 for protocols without ports.
+type Packet struct {
+	SrcIP    net.IP
+	DstIP    net.IP
+	Protocol string
+	SrcPort  int
+	DstPort  int
+}
+
+func (p *Packet) String() string {
+	return fmt.Sprintf("%s %s -> %s",
+		strings.ToUpper(p.Protocol),
+		net.JoinHostPort(p.SrcIP.String(), strconv.Itoa(p.SrcPort)),
+		net.JoinHostPort(p.DstIP.String(), strconv.Itoa(p.DstPort)))
+}
+
+func (p *Packet) ip(flag string) net.IP {
+	if flag == "src" {
+		return p.SrcIP
+	}
+	return p.DstIP
+}
+
+func (p *Packet) port(flag string) int {
+	if flag == "src" {
+		return p.SrcPort
+	}
+	return p.DstPort
+}
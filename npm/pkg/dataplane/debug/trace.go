@@ -0,0 +1,402 @@
+package debug
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	npmcommon "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
+	NPMIPtable "github.com/Azure/azure-container-networking/npm/pkg/dataplane/iptables"
+	"github.com/Azure/azure-container-networking/npm/util"
+)
+
+const (
+	// VerdictNotDecided means the packet left the NPM chains without being accepted or dropped.
+	VerdictNotDecided = "NOT DECIDED BY NPM"
+	// maxChainDepth bounds the jumps between chains, in case of a loop.
+	maxChainDepth = 32
+	markMask      = 0xffffffff
+)
+
+var (
+	ErrChainNotExist = fmt.Errorf("chain does not exist")
+	ErrChainLoop     = fmt.Errorf("chains jump deeper than the maximum depth")
+	ErrPodIPNotFound = fmt.Errorf("pod has no IP")
+)
+
+// SetMatch is the evaluation of a match-set of a rule against the packet.
+type SetMatch struct {
+	// HashedSetName is the name of the ipset in the kernel, and Name the NPM name it was hashed from, if known.
+	HashedSetName string
+	Name          string
+	Flags         string
+	Included      bool
+	Matched       bool
+	// Member is the member of the set which contains the packet, or the nomatch member which excludes it.
+	Member string
+}
+
+// TraceStep is a rule of the NPM chains evaluated for the packet, in the order iptables evaluates them.
+type TraceStep struct {
+	Chain string
+	// Rule is the position of the rule in its chain, starting at 1 like iptables --line-numbers.
+	Rule    int
+	Depth   int
+	Matched bool
+	Target  string
+	Comment string
+	Sets    []SetMatch
+	// Mark is the packet mark after the rule.
+	Mark uint32
+	// Reason explains why the rule did not match, or notes about its evaluation.
+	Reason string
+}
+
+// Trace is the path of a packet through the NPM chains and its verdict.
+type Trace struct {
+	Packet  *Packet
+	Steps   []*TraceStep
+	Verdict string
+}
+
+// Tracer walks a packet through the NPM chains of an iptables filter table, evaluating the set matches against the
+// ipsets of the same node.
+type Tracer struct {
+	Table  *NPMIPtable.Table
+	IPSets *IPSetSave
+	// Cache is optional. It names the hashed ipsets and resolves pod names to IPs.
+	Cache npmcommon.GenericCache
+}
+
+// ResolveIP returns the IP of the input, which is either an IP or a namespace/pod name resolved with the cache.
+func (t *Tracer) ResolveIP(input string) (net.IP, error) {
+	if ip := net.ParseIP(input); ip != nil {
+		return ip, nil
+	}
+	if t.Cache == nil {
+		return nil, fmt.Errorf("%w: %s is not an IP and there is no NPM cache to look up pods", npmcommon.ErrInvalidInput, input)
+	}
+	pod, err := t.Cache.GetPod(&npmcommon.Input{Content: input, Type: npmcommon.NSPODNAME})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pod %s: %w", input, err)
+	}
+	ip := net.ParseIP(pod.PodIP)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPodIPNotFound, input)
+	}
+	return ip, nil
+}
+
+// Trace walks the packet from the AZURE-NPM chain, like a new connection forwarded by the node.
+func (t *Tracer) Trace(p *Packet) (*Trace, error) {
+	trace := &Trace{Packet: p}
+	var mark uint32
+	verdict, err := t.walk(trace, util.IptablesAzureChain, 0, &mark)
+	if err != nil {
+		return trace, err
+	}
+	if verdict == "" || verdict == util.IptablesReturn {
+		verdict = VerdictNotDecided
+	}
+	trace.Verdict = verdict
+	return trace, nil
+}
+
+// walk evaluates the rules of the chain in order, and returns the terminating target of the packet, or an empty
+// verdict when the packet returns from the chain.
+func (t *Tracer) walk(trace *Trace, chainName string, depth int, mark *uint32) (string, error) {
+	if depth > maxChainDepth {
+		return "", fmt.Errorf("%w: %s", ErrChainLoop, chainName)
+	}
+	chain, ok := t.Table.Chains[chainName]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrChainNotExist, chainName)
+	}
+
+	for i, rule := range chain.Rules {
+		step := &TraceStep{Chain: chainName, Rule: i + 1, Depth: depth}
+		if rule.Target != nil {
+			step.Target = rule.Target.Name
+		}
+		trace.Steps = append(trace.Steps, step)
+
+		matched, err := t.matchRule(rule, trace.Packet, *mark, step)
+		if err != nil {
+			return "", err
+		}
+		step.Mark = *mark
+		if !matched || rule.Target == nil {
+			step.Matched = matched
+			continue
+		}
+		step.Matched = true
+
+		switch rule.Target.Name {
+		case util.IptablesAccept, util.IptablesDrop, util.IptablesReject:
+			return rule.Target.Name, nil
+		case util.IptablesReturn:
+			return "", nil
+		case util.IptablesMark:
+			*mark = applyMark(*mark, rule.Target.OptionValueMap)
+			step.Mark = *mark
+		default:
+			if _, ok := t.Table.Chains[rule.Target.Name]; !ok {
+				step.Reason = fmt.Sprintf("target %s is not traced", rule.Target.Name)
+				continue
+			}
+			verdict, err := t.walk(trace, rule.Target.Name, depth+1, mark)
+			if err != nil || verdict != "" {
+				return verdict, err
+			}
+		}
+	}
+	return "", nil
+}
+
+// matchRule evaluates all matches of the rule, recording the set matches and the first mismatch in the step.
+func (t *Tracer) matchRule(rule *NPMIPtable.Rule, p *Packet, mark uint32, step *TraceStep) (bool, error) {
+	matched := true
+	fail := func(reason string) {
+		if matched {
+			step.Reason = reason
+		}
+		matched = false
+	}
+
+	if rule.Protocol != "" && !strings.EqualFold(rule.Protocol, p.Protocol) {
+		fail(fmt.Sprintf("protocol is not %s", rule.Protocol))
+	}
+
+	for _, module := range rule.Modules {
+		switch module.Verb {
+		case util.IptablesCommentModuleFlag:
+			step.Comment = strings.Trim(strings.Join(module.OptionValueMap[util.IptablesCommentModuleFlag], " "), "\"")
+
+		case util.IptablesSetModuleFlag:
+			for option, values := range module.OptionValueMap {
+				if len(values) < 2 { //nolint:gomnd // set name and flags
+					continue
+				}
+				included := option == "match-set"
+				if !included && option != util.NegationPrefix+"match-set" {
+					continue
+				}
+				setMatch, err := t.matchSet(values[0], values[1], included, p)
+				if err != nil {
+					return false, err
+				}
+				step.Sets = append(step.Sets, setMatch)
+				if !setMatch.Matched {
+					fail(fmt.Sprintf("set %s did not match", setMatch.HashedSetName))
+				}
+			}
+
+		case "tcp", "udp", "sctp":
+			for option, values := range module.OptionValueMap {
+				if len(values) == 0 {
+					continue
+				}
+				negated := strings.HasPrefix(option, util.NegationPrefix)
+				var port int
+				switch strings.TrimPrefix(option, util.NegationPrefix) {
+				case "dport", "destination-port":
+					port = p.DstPort
+				case "sport", "source-port":
+					port = p.SrcPort
+				default:
+					continue
+				}
+				if portInRanges(port, values[0]) == negated {
+					fail(fmt.Sprintf("port %d does not match %s %s", port, option, values[0]))
+				}
+			}
+
+		case "multiport":
+			for option, values := range module.OptionValueMap {
+				if len(values) == 0 {
+					continue
+				}
+				negated := strings.HasPrefix(option, util.NegationPrefix)
+				var port int
+				switch strings.TrimPrefix(option, util.NegationPrefix) {
+				case "dports", "destination-ports":
+					port = p.DstPort
+				case "sports", "source-ports":
+					port = p.SrcPort
+				default:
+					continue
+				}
+				if portInRanges(port, values[0]) == negated {
+					fail(fmt.Sprintf("port %d does not match %s %s", port, option, values[0]))
+				}
+			}
+
+		case util.IptablesMarkVerb:
+			for option, values := range module.OptionValueMap {
+				if len(values) == 0 {
+					continue
+				}
+				negated := strings.HasPrefix(option, util.NegationPrefix)
+				if matchMark(mark, values[0]) == negated {
+					fail(fmt.Sprintf("mark %#x does not match %s", mark, values[0]))
+				}
+			}
+
+		case "conntrack", "state":
+			// the trace is of the first packet of a connection, which is what NPM filters
+			for option, values := range module.OptionValueMap {
+				if len(values) == 0 {
+					continue
+				}
+				negated := strings.HasPrefix(option, util.NegationPrefix)
+				isNew := false
+				for _, state := range strings.Split(values[0], ",") {
+					if state == "NEW" {
+						isNew = true
+					}
+				}
+				if isNew == negated {
+					fail(fmt.Sprintf("state of a new connection does not match %s", values[0]))
+				}
+			}
+
+		default:
+			fail(fmt.Sprintf("match module %s is not traced, assumed not to match", module.Verb))
+		}
+	}
+	return matched, nil
+}
+
+func (t *Tracer) matchSet(hashedSetName, flags string, included bool, p *Packet) (SetMatch, error) {
+	setMatch := SetMatch{HashedSetName: hashedSetName, Flags: flags, Included: included}
+	if t.Cache != nil {
+		setMatch.Name = t.Cache.GetSetMap()[hashedSetName]
+		if setMatch.Name == "" {
+			setMatch.Name = t.Cache.GetListMap()[hashedSetName]
+		}
+	}
+	inSet, member, err := t.IPSets.match(hashedSetName, flags, p)
+	if err != nil {
+		return setMatch, fmt.Errorf("failed to evaluate set %s: %w", hashedSetName, err)
+	}
+	setMatch.Member = member
+	setMatch.Matched = inSet == included
+	return setMatch, nil
+}
+
+// applyMark applies the --set-xmark or --set-mark option of a MARK target.
+func applyMark(mark uint32, options map[string][]string) uint32 {
+	if values := options["set-xmark"]; len(values) > 0 {
+		value, mask := parseMark(values[0])
+		return (mark &^ mask) ^ value
+	}
+	if values := options["set-mark"]; len(values) > 0 {
+		value, mask := parseMark(values[0])
+		return (mark &^ mask) | value
+	}
+	return mark
+}
+
+// matchMark matches the mark against a --mark value[/mask] option.
+func matchMark(mark uint32, option string) bool {
+	value, mask := parseMark(option)
+	return mark&mask == value
+}
+
+func parseMark(option string) (value, mask uint32) {
+	parts := strings.SplitN(option, "/", 2) //nolint:gomnd // value and mask
+	v, _ := strconv.ParseUint(parts[0], 0, 32)
+	m := uint64(markMask)
+	if len(parts) == 2 { //nolint:gomnd // value and mask
+		m, _ = strconv.ParseUint(parts[1], 0, 32)
+	}
+	return uint32(v), uint32(m)
+}
+
+// portInRanges matches the port against a comma separated list of ports and first:last port ranges.
+func portInRanges(port int, ranges string) bool {
+	for _, portRange := range strings.Split(ranges, ",") {
+		bounds := strings.SplitN(portRange, ":", 2) //nolint:gomnd // first and last port
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			continue
+		}
+		last := first
+		if len(bounds) == 2 { //nolint:gomnd // first and last port
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				continue
+			}
+		}
+		if port >= first && port <= last {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the trace as the rules the packet traversed, indented by chain depth, followed by the verdict.
+func (t *Trace) Print(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Tracing %s from chain %s\n", t.Packet, util.IptablesAzureChain)
+	for _, step := range t.Steps {
+		indent := strings.Repeat("  ", step.Depth)
+		result := "no match"
+		if step.Matched {
+			result = "MATCH"
+		}
+		fmt.Fprintf(&sb, "%s%s #%d: %s", indent, step.Chain, step.Rule, result)
+		if step.Target != "" {
+			fmt.Fprintf(&sb, " -j %s", step.Target)
+		}
+		if step.Comment != "" {
+			fmt.Fprintf(&sb, " (%s)", step.Comment)
+		}
+		if step.Matched && step.Target == util.IptablesMark {
+			fmt.Fprintf(&sb, ", mark is now %#x", step.Mark)
+		}
+		if step.Reason != "" {
+			fmt.Fprintf(&sb, ": %s", step.Reason)
+		}
+		sb.WriteString("\n")
+		for i := range step.Sets {
+			fmt.Fprintf(&sb, "%s    %s\n", indent, step.Sets[i].String(t.Packet))
+		}
+	}
+	fmt.Fprintf(&sb, "Verdict: %s\n", t.Verdict)
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	return nil
+}
+
+// String describes the membership of the packet in the set, e.g. "dst 10.0.0.1 in azure-npm-123 (ns-x) as 10.0.0.0/24".
+func (s *SetMatch) String(p *Packet) string {
+	flags := strings.Split(s.Flags, ",")
+	subject := fmt.Sprintf("%s %s", flags[0], p.ip(flags[0]))
+	if len(flags) > 1 {
+		subject += fmt.Sprintf(" %s port %d", flags[1], p.port(flags[1]))
+	}
+	inSet := s.Matched == s.Included
+	relation := "in"
+	if !inSet {
+		relation = "not in"
+	}
+	set := s.HashedSetName
+	if s.Name != "" {
+		set += " (" + s.Name + ")"
+	}
+	description := fmt.Sprintf("%s %s %s", subject, relation, set)
+	if s.Member != "" {
+		description += " via member " + s.Member
+	}
+	if !s.Included {
+		description += ", which the rule excludes"
+	}
+	result := "ok"
+	if !s.Matched {
+		result = "FAILED"
+	}
+	return fmt.Sprintf("[%s] %s", result, description)
+}
@@ -0,0 +1,159 @@
+package debug
+
+import (
+	"archive/zip"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	traceIptableSaveFile = "../testdata/trace/iptablesave"
+	traceIPSetSaveFile   = "../testdata/trace/ipsetsave"
+	traceNPMCacheFile    = "../testdata/trace/npmcache.json"
+)
+
+func TestParseIPSetSave(t *testing.T) {
+	save, err := ParseIPSetSave(strings.NewReader(
+		"create set1 hash:net family inet\nadd set1 10.0.0.0/8\nadd set1 10.1.0.0/16 nomatch\nadd set1 10.1.1.0/24 timeout 5\n"))
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		ip      string
+		matched bool
+		member  string
+	}{
+		"broad member":                   {ip: "10.2.0.1", matched: true, member: "10.0.0.0/8"},
+		"excluded by nomatch":            {ip: "10.1.2.1", matched: false, member: "10.1.0.0/16 nomatch"},
+		"more specific than the nomatch": {ip: "10.1.1.1", matched: true, member: "10.1.1.0/24"},
+		"outside":                        {ip: "192.168.0.1", matched: false},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			matched, member, err := save.match("set1", "src", &Packet{SrcIP: net.ParseIP(tt.ip)})
+			require.NoError(t, err)
+			require.Equal(t, tt.matched, matched)
+			require.Equal(t, tt.member, member)
+		})
+	}
+
+	_, _, err = save.match("set2", "src", &Packet{SrcIP: net.ParseIP("10.0.0.1")})
+	require.ErrorIs(t, err, ErrUnknownIPSet)
+
+	_, err = ParseIPSetSave(strings.NewReader("add set1 10.0.0.1\n"))
+	require.ErrorIs(t, err, ErrIPSetSaveLine)
+}
+
+func TestTrace(t *testing.T) {
+	c := &Converter{EnableV2NPM: true}
+	tracer, err := c.TracerFromFiles(traceNPMCacheFile, traceIptableSaveFile, traceIPSetSaveFile)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		src, dst string
+		dstPort  int
+		verdict  string
+		// lastMatch is the chain of the last rule which matched, which decided the verdict.
+		lastMatch string
+	}{
+		"allowed by named port and namespace label": {src: "x/c", dst: "y/a", dstPort: 80, verdict: "ACCEPT", lastMatch: "AZURE-NPM-ACCEPT"},
+		"denied on other port":                      {src: "x/c", dst: "y/a", dstPort: 81, verdict: "DROP", lastMatch: "AZURE-NPM-INGRESS"},
+		"allowed by cidr":                           {src: "10.1.2.3", dst: "y/a", dstPort: 81, verdict: "ACCEPT", lastMatch: "AZURE-NPM-ACCEPT"},
+		"denied by cidr except":                     {src: "10.1.1.3", dst: "10.224.0.10", dstPort: 81, verdict: "DROP", lastMatch: "AZURE-NPM-INGRESS"},
+		"destination not selected by any policy":    {src: "x/c", dst: "y/b", dstPort: 81, verdict: "ACCEPT", lastMatch: "AZURE-NPM-ACCEPT"},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			srcIP, err := tracer.ResolveIP(tt.src)
+			require.NoError(t, err)
+			dstIP, err := tracer.ResolveIP(tt.dst)
+			require.NoError(t, err)
+
+			trace, err := tracer.Trace(&Packet{SrcIP: srcIP, DstIP: dstIP, Protocol: "tcp", SrcPort: 34567, DstPort: tt.dstPort})
+			require.NoError(t, err)
+			require.Equal(t, tt.verdict, trace.Verdict)
+
+			var last *TraceStep
+			for _, step := range trace.Steps {
+				if step.Matched {
+					last = step
+				}
+			}
+			require.NotNil(t, last)
+			require.Equal(t, tt.lastMatch, last.Chain)
+
+			var sb strings.Builder
+			require.NoError(t, trace.Print(&sb))
+			require.Contains(t, sb.String(), "Verdict: "+tt.verdict)
+		})
+	}
+}
+
+func TestTraceSetMatches(t *testing.T) {
+	c := &Converter{EnableV2NPM: true}
+	tracer, err := c.TracerFromFiles(traceNPMCacheFile, traceIptableSaveFile, traceIPSetSaveFile)
+	require.NoError(t, err)
+
+	trace, err := tracer.Trace(&Packet{
+		SrcIP: net.ParseIP("10.224.0.20"), DstIP: net.ParseIP("10.224.0.10"), Protocol: "tcp", SrcPort: 34567, DstPort: 80,
+	})
+	require.NoError(t, err)
+
+	var allowStep, markStep *TraceStep
+	for _, step := range trace.Steps {
+		if step.Chain == "AZURE-NPM-INGRESS-2697641196" && step.Matched {
+			allowStep = step
+		}
+		if step.Chain == "AZURE-NPM-INGRESS-ALLOW-MARK" && step.Rule == 1 {
+			markStep = step
+		}
+	}
+	require.NotNil(t, allowStep)
+	require.NotNil(t, markStep)
+	require.Equal(t, uint32(0x2000), markStep.Mark)
+	require.Equal(t, 1, allowStep.Rule)
+	require.Equal(t, []SetMatch{
+		{HashedSetName: "azure-npm-1213884878", Name: "namedport:serve-80", Flags: "dst,dst", Included: true, Matched: true, Member: "10.224.0.10,tcp:80"},
+		{HashedSetName: "azure-npm-2129276318", Name: "nslabel-ns:x", Flags: "src", Included: true, Matched: true, Member: "azure-npm-784554818 > 10.224.0.20"},
+	}, allowStep.Sets)
+}
+
+func TestTracerFromBundle(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "bundle.zip")
+	f, err := os.Create(bundle)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	for path, file := range map[string]string{BundleIptablesPath: traceIptableSaveFile, BundleIPSetPath: traceIPSetSaveFile} {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		w, err := zw.Create(path)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	c := &Converter{EnableV2NPM: true}
+	tracer, err := c.TracerFromBundle(bundle)
+	require.NoError(t, err)
+	require.Nil(t, tracer.Cache)
+
+	// without the NPM cache, the trace works with IPs only
+	_, err = tracer.ResolveIP("y/a")
+	require.Error(t, err)
+	trace, err := tracer.Trace(&Packet{
+		SrcIP: net.ParseIP("10.224.0.20"), DstIP: net.ParseIP("10.224.0.10"), Protocol: "udp", SrcPort: 34567, DstPort: 80,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "DROP", trace.Verdict)
+
+	_, err = c.TracerFromBundle(filepath.Join(t.TempDir(), "missing.zip"))
+	require.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package debug
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
+	"github.com/Azure/azure-container-networking/npm/util"
+)
+
+// Paths of the NPM state in a support bundle collected by acncli bundle.
+const (
+	BundleNPMCachePath = "npm/debug/manager.json"
+	BundleIptablesPath = "dataplane/iptables-save.txt"
+	BundleIPSetPath    = "dataplane/ipset-save.txt"
+)
+
+var ErrBundleMissingFile = fmt.Errorf("bundle is missing a file")
+
+// TracerFromBundle creates a Tracer from the iptables, ipsets and NPM cache of a support bundle.
+// The NPM cache is optional, since the bundle does not have it when the NPM debug API was not reachable.
+func (c *Converter) TracerFromBundle(bundlePath string) (*Tracer, error) {
+	archive, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle %s: %w", bundlePath, err)
+	}
+	defer archive.Close()
+
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		if f.Name != BundleNPMCachePath && f.Name != BundleIptablesPath && f.Name != BundleIPSetPath {
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		files[f.Name] = data
+	}
+	for _, path := range []string{BundleIptablesPath, BundleIPSetPath} {
+		if _, ok := files[path]; !ok {
+			return nil, fmt.Errorf("%w: %s, check the manifest.json of the bundle for the error collecting it", ErrBundleMissingFile, path)
+		}
+	}
+
+	return c.tracerFromBytes(files[BundleNPMCachePath], files[BundleIptablesPath], files[BundleIPSetPath])
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in bundle: %w", f.Name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s in bundle: %w", f.Name, err)
+	}
+	return data, nil
+}
+
+// TracerFromFiles creates a Tracer from an iptables-save file, an ipset save file and optionally an NPM cache file.
+func (c *Converter) TracerFromFiles(npmCacheFile, iptableSaveFile, ipsetSaveFile string) (*Tracer, error) {
+	var npmCache []byte
+	if npmCacheFile != "" {
+		var err error
+		if npmCache, err = os.ReadFile(npmCacheFile); err != nil {
+			return nil, fmt.Errorf("failed to read %s file : %w", npmCacheFile, err)
+		}
+	}
+	iptablesSave, err := os.ReadFile(iptableSaveFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s file : %w", iptableSaveFile, err)
+	}
+	ipsetSave, err := os.ReadFile(ipsetSaveFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s file : %w", ipsetSaveFile, err)
+	}
+	return c.tracerFromBytes(npmCache, iptablesSave, ipsetSave)
+}
+
+// TracerFromNode creates a Tracer from the iptables and ipsets of this node, and the NPM cache of its debug API.
+// Without the debug API, the trace still works with IPs, but without the NPM names of the ipsets.
+func (c *Converter) TracerFromNode() (*Tracer, error) {
+	ipTable, err := parse.Iptables(util.IptablesFilterTable)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred during parsing iptables : %w", err)
+	}
+	ipsets, err := IPSetSaveFromNode()
+	if err != nil {
+		return nil, err
+	}
+	tracer := &Tracer{Table: ipTable, IPSets: ipsets}
+	if err := c.NpmCache(); err != nil {
+		log.Printf("tracing without the NPM cache: %v", err)
+		return tracer, nil
+	}
+	tracer.Cache = c.NPMCache
+	return tracer, nil
+}
+
+func (c *Converter) tracerFromBytes(npmCache, iptablesSave, ipsetSave []byte) (*Tracer, error) {
+	ipsets, err := ParseIPSetSave(bytes.NewReader(ipsetSave))
+	if err != nil {
+		return nil, err
+	}
+	tracer := &Tracer{
+		Table:  parse.IptablesBytes(util.IptablesFilterTable, iptablesSave),
+		IPSets: ipsets,
+	}
+	if len(npmCache) > 0 {
+		if err := c.getCacheFromBytes(npmCache); err != nil {
+			return nil, fmt.Errorf("failed to get cache: %w", err)
+		}
+		tracer.Cache = c.NPMCache
+	}
+	return tracer, nil
+}
@@ -1,6 +1,8 @@
 package dpshim
 
-import "k8s.io/klog"
+import npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
+
+var logger = npmlogger.For(npmlogger.Dataplane)
 
 type dirtyCache struct {
 	toAddorUpdateSets     map[string]struct{}
@@ -19,7 +21,7 @@ func newDirtyCache() *dirtyCache {
 }
 
 func (dc *dirtyCache) clearCache() {
-	klog.Infof("Clearing dirty cache")
+	logger.Infof("Clearing dirty cache")
 	dc.toAddorUpdateSets = make(map[string]struct{})
 	dc.toDeleteSets = make(map[string]struct{})
 	dc.toAddorUpdatePolicies = make(map[string]struct{})
@@ -53,8 +55,8 @@ func (dc *dirtyCache) hasContents() bool {
 }
 
 func (dc *dirtyCache) printContents() {
-	klog.Infof("toAddorUpdateSets: %v", dc.toAddorUpdateSets)
-	klog.Infof("toDeleteSets: %v", dc.toDeleteSets)
-	klog.Infof("toAddorUpdatePolicies: %v", dc.toAddorUpdatePolicies)
-	klog.Infof("toDeletePolicies: %v", dc.toDeletePolicies)
+	logger.Infof("toAddorUpdateSets: %v", dc.toAddorUpdateSets)
+	logger.Infof("toDeleteSets: %v", dc.toDeleteSets)
+	logger.Infof("toAddorUpdatePolicies: %v", dc.toAddorUpdatePolicies)
+	logger.Infof("toDeletePolicies: %v", dc.toDeletePolicies)
 }
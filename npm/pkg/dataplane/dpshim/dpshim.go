@@ -14,7 +14,6 @@ import (
 	"github.com/Azure/azure-container-networking/npm/pkg/protos"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
-	"k8s.io/klog"
 )
 
 const cleanEmptySetsInHrs = 24
@@ -58,7 +57,7 @@ func (dp *DPShim) HydrateClients() (*protos.Events, error) {
 	defer dp.unlock()
 
 	if len(dp.setCache) == 0 && len(dp.policyCache) == 0 {
-		klog.Infof("HydrateClients: No local cache objects to hydrate daemon client")
+		logger.Infof("HydrateClients: No local cache objects to hydrate daemon client")
 		return nil, nil
 	}
 
@@ -81,7 +80,7 @@ func (dp *DPShim) HydrateClients() (*protos.Events, error) {
 	}
 
 	if len(goalStates) == 0 {
-		klog.Info("HydrateClients: No changes to apply")
+		logger.Info("HydrateClients: No changes to apply")
 		return nil, nil
 	}
 
@@ -137,14 +136,14 @@ func (dp *DPShim) DeleteIPSet(setMetadata *ipsets.IPSetMetadata, _ util.DeleteOp
 
 func (dp *DPShim) deleteIPSet(setMetadata *ipsets.IPSetMetadata) {
 	setName := setMetadata.GetPrefixName()
-	klog.Infof("deleteIPSet: cleaning up %s", setName)
+	logger.Infof("deleteIPSet: cleaning up %s", setName)
 	set, ok := dp.setCache[setName]
 	if !ok {
 		return
 	}
 
 	if set.HasReferences() {
-		klog.Infof("deleteIPSet: ignore delete since set: %s has references", setName)
+		logger.Infof("deleteIPSet: ignore delete since set: %s has references", setName)
 		return
 	}
 
@@ -161,7 +160,7 @@ func (dp *DPShim) AddToSets(setMetadatas []*ipsets.IPSetMetadata, podMetadata *d
 	defer dp.unlock()
 
 	for _, set := range setMetadatas {
-		klog.Infof("AddToSets: Adding pod IP: %s, Key: %s,  to set %s", podMetadata.PodIP, podMetadata.PodKey, set.GetPrefixName())
+		logger.Infof("AddToSets: Adding pod IP: %s, Key: %s,  to set %s", podMetadata.PodIP, podMetadata.PodKey, set.GetPrefixName())
 		prefixedSetName := set.GetPrefixName()
 		if !dp.setExists(prefixedSetName) {
 			dp.createIPSet(set)
@@ -192,7 +191,7 @@ func (dp *DPShim) RemoveFromSets(setMetadatas []*ipsets.IPSetMetadata, podMetada
 	defer dp.unlock()
 
 	for _, set := range setMetadatas {
-		klog.Infof("RemoveFromSets: removing pod ip: %s, podkey: %s,  from set %s ", podMetadata.PodIP, podMetadata.PodKey, set.GetPrefixName())
+		logger.Infof("RemoveFromSets: removing pod ip: %s, podkey: %s,  from set %s ", podMetadata.PodIP, podMetadata.PodKey, set.GetPrefixName())
 		prefixedSetName := set.GetPrefixName()
 		if !dp.setExists(prefixedSetName) {
 			continue
@@ -209,7 +208,7 @@ func (dp *DPShim) RemoveFromSets(setMetadatas []*ipsets.IPSetMetadata, podMetada
 			continue
 		}
 		if cachedPod.PodKey != podMetadata.PodKey {
-			klog.Infof("DeleteFromSet: PodOwner has changed for Ip: %s, setName:%s, Old podKey: %s, new podKey: %s. Ignore the delete as this is stale update",
+			logger.Infof("DeleteFromSet: PodOwner has changed for Ip: %s, setName:%s, Old podKey: %s, new podKey: %s. Ignore the delete as this is stale update",
 				cachedPod.PodIP, prefixedSetName, cachedPod.PodKey, podMetadata.PodKey)
 			continue
 		}
@@ -400,7 +399,7 @@ func (dp *DPShim) ApplyDataPlane() error {
 
 	// check dirty cache contents
 	if !dp.dirtyCache.hasContents() {
-		klog.Info("ApplyDataPlane: No changes to apply")
+		logger.Info("ApplyDataPlane: No changes to apply")
 		return nil
 	}
 
@@ -441,7 +440,7 @@ func (dp *DPShim) ApplyDataPlane() error {
 	}
 
 	if len(goalStates) == 0 {
-		klog.Info("ApplyDataPlane: No changes to apply")
+		logger.Info("ApplyDataPlane: No changes to apply")
 		return nil
 	}
 
@@ -488,7 +487,7 @@ func (dp *DPShim) processIPSetsApply() (*protos.GoalState, error) {
 	for setName := range dp.dirtyCache.toAddorUpdateSets {
 		set := dp.getCachedIPSet(setName)
 		if set == nil {
-			klog.Errorf("processIPSetsApply: set %s not found", setName)
+			logger.Errorf("processIPSetsApply: set %s not found", setName)
 			return nil, npmerrors.Errorf(npmerrors.AppendIPSet, false, fmt.Sprintf("ipset %s not found", setName))
 		}
 
@@ -498,7 +497,7 @@ func (dp *DPShim) processIPSetsApply() (*protos.GoalState, error) {
 
 	payload, err := controlplane.EncodeControllerIPSets(toApplySets)
 	if err != nil {
-		klog.Errorf("processIPSetsApply: failed to encode sets %v", err)
+		logger.Errorf("processIPSetsApply: failed to encode sets %v", err)
 		return nil, npmerrors.ErrorWrapper(npmerrors.AppendIPSet, false, "processIPSetsApply: failed to encode sets", err)
 	}
 
@@ -520,7 +519,7 @@ func (dp *DPShim) processIPSetsDelete() (*protos.GoalState, error) {
 
 	payload, err := controlplane.EncodeStrings(toDeleteSets)
 	if err != nil {
-		klog.Errorf("processIPSetsDelete: failed to encode sets %v", err)
+		logger.Errorf("processIPSetsDelete: failed to encode sets %v", err)
 		return nil, npmerrors.ErrorWrapper(npmerrors.DeleteIPSet, false, "processIPSetsDelete: failed to encode sets", err)
 	}
 
@@ -547,7 +546,7 @@ func (dp *DPShim) processPoliciesApply() (*protos.GoalState, error) {
 
 	payload, err := controlplane.EncodeNPMNetworkPolicies(toApplyPolicies)
 	if err != nil {
-		klog.Errorf("processPoliciesApply: failed to encode policies %v", err)
+		logger.Errorf("processPoliciesApply: failed to encode policies %v", err)
 		return nil, npmerrors.ErrorWrapper(npmerrors.AddPolicy, false, "processPoliciesApply: failed to encode sets", err)
 	}
 
@@ -569,7 +568,7 @@ func (dp *DPShim) processPoliciesRemove() (*protos.GoalState, error) {
 
 	payload, err := controlplane.EncodeStrings(toDeletePolicies)
 	if err != nil {
-		klog.Errorf("processPoliciesRemove: failed to encode policies %v", err)
+		logger.Errorf("processPoliciesRemove: failed to encode policies %v", err)
 		return nil, npmerrors.ErrorWrapper(npmerrors.RemovePolicy, false, "processPoliciesRemove: failed to encode sets", err)
 	}
 
@@ -590,7 +589,7 @@ func (dp *DPShim) hydrateSetCache() (*protos.GoalState, error) {
 
 	payload, err := controlplane.EncodeControllerIPSets(toApplySets)
 	if err != nil {
-		klog.Errorf("processIPSetsApply: failed to encode sets %v", err)
+		logger.Errorf("processIPSetsApply: failed to encode sets %v", err)
 		return nil, npmerrors.ErrorWrapper(npmerrors.AppendIPSet, false, "processIPSetsApply: failed to encode sets", err)
 	}
 
@@ -612,7 +611,7 @@ func (dp *DPShim) hydratePolicyCache() (*protos.GoalState, error) {
 
 	payload, err := controlplane.EncodeNPMNetworkPolicies(toApplyPolicies)
 	if err != nil {
-		klog.Errorf("processPoliciesApply: failed to encode policies %v", err)
+		logger.Errorf("processPoliciesApply: failed to encode policies %v", err)
 		return nil, npmerrors.ErrorWrapper(npmerrors.AddPolicy, false, "processPoliciesApply: failed to encode sets", err)
 	}
 
@@ -629,11 +628,11 @@ func (dp *DPShim) deleteUnusedSets(stopChannel <-chan struct{}) {
 			case <-stopChannel:
 				return
 			case <-ticker.C:
-				klog.Info("deleteUnusedSets: cleaning up unused sets")
+				logger.Info("deleteUnusedSets: cleaning up unused sets")
 				dp.checkSetReferences()
 				err := dp.ApplyDataPlane()
 				if err != nil {
-					klog.Errorf("deleteUnusedSets: failed to apply dataplane %v", err)
+					logger.Errorf("deleteUnusedSets: failed to apply dataplane %v", err)
 				}
 			}
 		}
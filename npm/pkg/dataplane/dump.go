@@ -0,0 +1,35 @@
+package dataplane
+
+import (
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+)
+
+// StateDump is the state of the dataplane cache, to attach to support tickets.
+type StateDump struct {
+	IPSets   []*ipsets.IPSetDump
+	Policies []*policies.NPMNetworkPolicy
+	// Endpoints are only in Windows
+	Endpoints []*EndpointDump `json:",omitempty"`
+	// StaleChains are only in Linux
+	StaleChains []string `json:",omitempty"`
+}
+
+// EndpointDump is an endpoint in the cache and the policies applied to it.
+type EndpointDump struct {
+	ID       string
+	IP       string
+	PodKey   string
+	Policies []string
+}
+
+// DumpState returns the ipsets and policies in the cache, along with the endpoints and their policies in Windows,
+// and the stale chains yet to be deleted in Linux.
+func (dp *DataPlane) DumpState() *StateDump {
+	return &StateDump{
+		IPSets:      dp.ipsetMgr.DumpIPSets(),
+		Policies:    dp.policyMgr.GetAllPolicies(),
+		Endpoints:   dp.dumpEndpoints(),
+		StaleChains: dp.policyMgr.GetStaleChains(),
+	}
+}
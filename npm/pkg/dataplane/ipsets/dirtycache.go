@@ -6,7 +6,6 @@ import (
 
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/util"
-	"k8s.io/klog"
 )
 
 /*
@@ -95,7 +94,7 @@ func (dc *dirtyCache) create(set *IPSet) {
 	// error checking
 	if _, ok := dc.toUpdateCache[set.Name]; ok {
 		msg := fmt.Sprintf("create should not be called for set %s since it's in the toUpdateCache", set.Name)
-		klog.Warning(msg)
+		logger.Warn(msg)
 		metrics.SendErrorLogAndMetric(util.IpsmID, msg)
 		return
 	}
@@ -135,7 +134,7 @@ func (dc *dirtyCache) deleteMember(set *IPSet, member string) {
 	// error checking #1
 	if dc.isSetToDelete(set.Name) {
 		msg := fmt.Sprintf("attempting to delete member %s for set %s in the toDestroyCache", member, set.Name)
-		klog.Warning(msg)
+		logger.Warn(msg)
 		metrics.SendErrorLogAndMetric(util.IpsmID, msg)
 		return
 	}
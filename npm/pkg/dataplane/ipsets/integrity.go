@@ -0,0 +1,65 @@
+package ipsets
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrCacheIntegrity is returned when the bookkeeping of the IPSetManager cache is inconsistent
+var ErrCacheIntegrity = errors.New("ipset cache integrity violated")
+
+// ValidateCacheIntegrity checks the bookkeeping of the cache:
+// 1. every member of a list is the set with the same name in the cache
+// 2. the ipset reference count of every set is the number of lists it is a member of
+// 3. the kernel reference count of every set is the number of lists in the kernel it is a member of
+// The reference counts of the empty set are unaccounted for, so they are not checked.
+// It is meant for UTs, to catch bookkeeping bugs which do not show up in the dataplane yet.
+func (iMgr *IPSetManager) ValidateCacheIntegrity() error {
+	iMgr.RLock()
+	defer iMgr.RUnlock()
+
+	problems := make([]string, 0)
+	ipsetReferCounts := make(map[string]int, len(iMgr.setMap))
+	kernelReferCounts := make(map[string]int, len(iMgr.setMap))
+	for name, set := range iMgr.setMap {
+		if set.Name != name {
+			problems = append(problems, fmt.Sprintf("set %s is cached with name %s", set.Name, name))
+		}
+		for memberName, member := range set.MemberIPSets {
+			cachedMember, ok := iMgr.setMap[memberName]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("list %s has member %s which is not in the cache", name, memberName))
+				continue
+			}
+			if cachedMember != member {
+				problems = append(problems, fmt.Sprintf("list %s has a stale copy of member %s", name, memberName))
+			}
+			ipsetReferCounts[memberName]++
+			if iMgr.shouldBeInKernel(set) {
+				kernelReferCounts[memberName]++
+			}
+		}
+	}
+
+	for name, set := range iMgr.setMap {
+		if set == iMgr.emptySet {
+			continue
+		}
+		if set.ipsetReferCount != ipsetReferCounts[name] {
+			problems = append(problems, fmt.Sprintf("set %s has ipsetReferCount %d but is a member of %d lists",
+				name, set.ipsetReferCount, ipsetReferCounts[name]))
+		}
+		if set.kernelReferCount != kernelReferCounts[name] {
+			problems = append(problems, fmt.Sprintf("set %s has kernelReferCount %d but is a member of %d lists in the kernel",
+				name, set.kernelReferCount, kernelReferCounts[name]))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("%w: %s", ErrCacheIntegrity, strings.Join(problems, "; "))
+}
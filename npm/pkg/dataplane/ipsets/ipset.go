@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/util"
 )
@@ -330,7 +329,7 @@ func (set *IPSet) addReference(referenceName string, referenceType ReferenceType
 	case NetPolType:
 		set.NetPolReference[referenceName] = struct{}{}
 	default:
-		log.Logf("IPSet_addReference: encountered unknown ReferenceType")
+		logger.Infof("IPSet_addReference: encountered unknown ReferenceType")
 	}
 }
 
@@ -341,7 +340,7 @@ func (set *IPSet) deleteReference(referenceName string, referenceType ReferenceT
 	case NetPolType:
 		delete(set.NetPolReference, referenceName)
 	default:
-		log.Logf("IPSet_deleteReference: encountered unknown ReferenceType")
+		logger.Infof("IPSet_deleteReference: encountered unknown ReferenceType")
 	}
 }
 
@@ -2,14 +2,15 @@ package ipsets
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
-	"k8s.io/klog"
 )
 
 type IPSetMode string
@@ -39,6 +40,8 @@ var (
 		Type: EmptyHashSet,
 	}
 	emptySetPrefixName = emptySetMetadata.GetPrefixName()
+
+	logger = npmlogger.For(npmlogger.IPSets)
 )
 
 type IPSetManager struct {
@@ -50,7 +53,11 @@ type IPSetManager struct {
 	emptySet   *IPSet
 	setMap     map[string]*IPSet
 	dirtyCache dirtyCacheInterface
-	ioShim     *common.IOShim
+	// netPolsWithUpdatedSets holds the NetPols referring to sets which were updated, when SkipSetPolicies is set (Windows only).
+	netPolsWithUpdatedSets map[string]struct{}
+	// nftSets maps the hashed names of the sets in the nftables table to their prefixed names (Linux only with AddSetsToNftables)
+	nftSets map[string]string
+	ioShim  *common.IOShim
 	sync.RWMutex
 }
 
@@ -58,10 +65,36 @@ type IPSetManagerCfg struct {
 	IPSetMode IPSetMode
 	// NetworkName can be left empty or set to 'azure' or 'Calico' (case sensitive)
 	NetworkName string
+	// SecondaryNetworkNames are other HNS networks with pod endpoints (Windows only),
+	// e.g. the transparent network of the secondary NICs of multi-NIC pods.
+	// The SetPolicies are added to every network so that the ACLs of endpoints in any network can refer to them.
+	SecondaryNetworkNames []string
 	// AddEmptySetToLists determines whether all lists should have an empty set as a member.
 	// This is necessary for HNS (Windows); otherwise, an allow ACL with a list condition
 	// allows all IPs if the list has no members.
 	AddEmptySetToLists bool
+	// SkipSetPolicies is used in Windows when ACLs contain the IPs of ipsets instead of referring to them.
+	// Sets are then only kept in the cache and never added to HNS as SetPolicies,
+	// and the NetPols referring to updated sets are tracked so that their ACLs can be updated.
+	SkipSetPolicies bool
+	// AddSetsToNftables is used in Linux when policies are programmed with nftables.
+	// The sets in the kernel are also added to the nftables table of NPM, with lists flattened into their members.
+	AddSetsToNftables bool
+	// AuditMode keeps the sets in the cache and logs the changes it would apply, without programming ipsets in the
+	// kernel or SetPolicies in HNS, like the AuditMode of the PolicyManager.
+	AuditMode bool
+}
+
+// NetworkNames returns the names of the primary network and the secondary networks.
+func (iMgrCfg *IPSetManagerCfg) NetworkNames() []string {
+	names := make([]string, 0, len(iMgrCfg.SecondaryNetworkNames)+1)
+	names = append(names, iMgrCfg.NetworkName)
+	for _, name := range iMgrCfg.SecondaryNetworkNames {
+		if name != "" && name != iMgrCfg.NetworkName {
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
 func NewIPSetManager(iMgrCfg *IPSetManagerCfg, ioShim *common.IOShim) *IPSetManager {
@@ -70,7 +103,10 @@ func NewIPSetManager(iMgrCfg *IPSetManagerCfg, ioShim *common.IOShim) *IPSetMana
 		emptySet:   nil, // will be set if needed in calls to AddToLists
 		setMap:     make(map[string]*IPSet),
 		dirtyCache: newDirtyCache(),
-		ioShim:     ioShim,
+		// will only be used with SkipSetPolicies
+		netPolsWithUpdatedSets: make(map[string]struct{}),
+		nftSets:                make(map[string]string),
+		ioShim:                 ioShim,
 	}
 }
 
@@ -78,6 +114,7 @@ func NewIPSetManager(iMgrCfg *IPSetManagerCfg, ioShim *common.IOShim) *IPSetMana
 Reconcile removes empty/unreferenced sets from the cache.
 For ApplyAllIPSets mode, those sets are added to the toDeleteCache.
 We can't delete from kernel immediately unless we lock iMgr during policy CRUD.
+In Linux with AddSetsToNftables, it also re-creates the nftables sets if their table was deleted.
 */
 func (iMgr *IPSetManager) Reconcile() {
 	iMgr.Lock()
@@ -88,7 +125,10 @@ func (iMgr *IPSetManager) Reconcile() {
 	}
 	numRemovedSets := originalNumSets - len(iMgr.setMap)
 	if numRemovedSets > 0 {
-		klog.Infof("[IPSetManager] removed %d empty/unreferenced ipsets, updating toDeleteCache to: %+v", numRemovedSets, iMgr.dirtyCache.printDeleteCache())
+		logger.Infof("[IPSetManager] removed %d empty/unreferenced ipsets, updating toDeleteCache to: %+v", numRemovedSets, iMgr.dirtyCache.printDeleteCache())
+	}
+	if !iMgr.iMgrCfg.AuditMode {
+		iMgr.reconcileNftablesSets()
 	}
 }
 
@@ -97,9 +137,16 @@ func (iMgr *IPSetManager) ResetIPSets() error {
 	defer iMgr.Unlock()
 	metrics.ResetNumIPSets()
 	metrics.ResetIPSetEntries()
-	err := iMgr.resetIPSets()
+	var err error
+	if iMgr.iMgrCfg.AuditMode {
+		logger.Infof("[IPSetManager] [audit] would reset ipsets")
+	} else {
+		err = iMgr.resetIPSets()
+	}
 	iMgr.setMap = make(map[string]*IPSet)
 	iMgr.emptySet = nil
+	// the PolicyManager deletes the nftables table during bootup
+	iMgr.nftSets = make(map[string]string)
 	iMgr.clearDirtyCache()
 	if err != nil {
 		metrics.SendErrorLogAndMetric(util.IpsmID, "error: failed to reset ipsetmanager: %s", err.Error())
@@ -303,7 +350,7 @@ func (iMgr *IPSetManager) RemoveFromSets(removeFromSets []*IPSetMetadata, ip, po
 		}
 		// in case the IP belongs to a new Pod, then ignore this Delete call as this might be stale
 		if cachedPodKey != podKey {
-			klog.Infof(
+			logger.Infof(
 				"[IPSetManager] DeleteFromSet: PodOwner has changed for Ip: %s, setName:%s, Old podKey: %s, new podKey: %s. Ignore the delete as this is stale update",
 				ip, prefixedName, cachedPodKey, podKey,
 			)
@@ -448,16 +495,23 @@ func (iMgr *IPSetManager) ApplyIPSets() error {
 	defer iMgr.Unlock()
 
 	if iMgr.dirtyCache.numSetsToAddOrUpdate() == 0 && iMgr.dirtyCache.numSetsToDelete() == 0 {
-		klog.Info("[IPSetManager] No IPSets to apply")
+		logger.Info("[IPSetManager] No IPSets to apply")
 		return nil
 	}
 
-	klog.Infof(
+	logger.Infof(
 		"[IPSetManager] dirty caches. toAddUpdateCache: %s, toDeleteCache: %s",
 		iMgr.dirtyCache.printAddOrUpdateCache(), iMgr.dirtyCache.printDeleteCache(),
 	)
 	iMgr.sanitizeDirtyCache()
 
+	if iMgr.iMgrCfg.AuditMode {
+		logger.Infof("[IPSetManager] [audit] would apply ipsets. toAddUpdateCache: %s, toDeleteCache: %s",
+			iMgr.dirtyCache.printAddOrUpdateCache(), iMgr.dirtyCache.printDeleteCache())
+		iMgr.clearDirtyCache()
+		return nil
+	}
+
 	// Call the appropriate apply ipsets
 	prometheusTimer := metrics.StartNewTimer()
 	defer metrics.RecordIPSetExecTime(prometheusTimer) // record execution time regardless of failure
@@ -482,6 +536,65 @@ func (iMgr *IPSetManager) GetAllIPSets() map[string]string {
 	return setMap
 }
 
+// IPSetDump is an ipset in the cache, with its members and references.
+type IPSetDump struct {
+	Name       string
+	HashedName string
+	Type       string
+	Kind       SetKind
+	// Members maps the IPs (or CIDRs) of a hash set to their pod keys
+	Members map[string]string `json:",omitempty"`
+	// MemberSets are the prefixed names of the members of a list
+	MemberSets         []string `json:",omitempty"`
+	SelectorReferences []string `json:",omitempty"`
+	NetPolReferences   []string `json:",omitempty"`
+}
+
+// DumpIPSets returns every ipset in the cache, sorted by name.
+func (iMgr *IPSetManager) DumpIPSets() []*IPSetDump {
+	iMgr.RLock()
+	defer iMgr.RUnlock()
+	dumps := make([]*IPSetDump, 0, len(iMgr.setMap))
+	for _, set := range iMgr.setMap {
+		dump := &IPSetDump{
+			Name:               set.Name,
+			HashedName:         set.HashedName,
+			Type:               set.Type.String(),
+			Kind:               set.Kind,
+			SelectorReferences: sortedKeys(set.SelectorReference),
+			NetPolReferences:   sortedKeys(set.NetPolReference),
+		}
+		if set.Kind == HashSet {
+			dump.Members = make(map[string]string, len(set.IPPodKey))
+			for ip, podKey := range set.IPPodKey {
+				dump.Members[ip] = podKey
+			}
+		} else {
+			for name := range set.MemberIPSets {
+				dump.MemberSets = append(dump.MemberSets, name)
+			}
+			sort.Strings(dump.MemberSets)
+		}
+		dumps = append(dumps, dump)
+	}
+	sort.Slice(dumps, func(i, j int) bool {
+		return dumps[i].Name < dumps[j].Name
+	})
+	return dumps
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (iMgr *IPSetManager) exists(name string) bool {
 	_, ok := iMgr.setMap[name]
 	return ok
@@ -570,7 +683,7 @@ func (iMgr *IPSetManager) sanitizeDirtyCache() {
 	anyProblems := false
 	for setName := range iMgr.dirtyCache.setsToDelete() {
 		if iMgr.dirtyCache.isSetToAddOrUpdate(setName) {
-			klog.Errorf("[IPSetManager] Unexpected state in dirty cache %s set is part of both update and delete caches", setName)
+			logger.Errorf("[IPSetManager] Unexpected state in dirty cache %s set is part of both update and delete caches", setName)
 			anyProblems = true
 		}
 	}
@@ -10,7 +10,6 @@ import (
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
 	"github.com/Azure/azure-container-networking/npm/util/ioutil"
-	"k8s.io/klog"
 	utilexec "k8s.io/utils/exec"
 )
 
@@ -104,7 +103,7 @@ func (iMgr *IPSetManager) resetIPSets() error {
 	// get current NPM ipsets
 	listNamesCommand := iMgr.ioShim.Exec.Command(ipsetCommand, ipsetListFlag, ipsetNameFlag)
 	grepCommand := iMgr.ioShim.Exec.Command(ioutil.Grep, azureNPMPrefix)
-	klog.Infof("running this command while resetting ipsets: [%s %s %s | %s %s]", ipsetCommand, ipsetListFlag, ipsetNameFlag, ioutil.Grep, azureNPMRegex)
+	logger.Infof("running this command while resetting ipsets: [%s %s %s | %s %s]", ipsetCommand, ipsetListFlag, ipsetNameFlag, ioutil.Grep, azureNPMRegex)
 	azureIPSets, haveAzureNPMIPSets, commandError := ioutil.PipeCommandToGrep(listNamesCommand, grepCommand)
 	if commandError != nil {
 		return npmerrors.SimpleErrorWrapper("failed to run ipset list for resetting IPSets (prometheus metrics may be off now)", commandError)
@@ -117,7 +116,7 @@ func (iMgr *IPSetManager) resetIPSets() error {
 	creator, names, failedNames := iMgr.fileCreatorForFlushAll(azureIPSets)
 	restoreError := creator.RunCommandWithFile(ipsetCommand, ipsetRestoreFlag)
 	if restoreError != nil {
-		klog.Errorf(
+		logger.Errorf(
 			"failed to flush all ipsets (prometheus metrics may be off now). originalNumAzureSets: %d. failed flushes: %+v. err: %v",
 			len(names), failedNames, restoreError,
 		)
@@ -128,7 +127,7 @@ func (iMgr *IPSetManager) resetIPSets() error {
 	creator, destroyFailureCount := iMgr.fileCreatorForDestroyAll(names, failedNames, iMgr.setsWithReferences())
 	destroyError := creator.RunCommandWithFile(ipsetCommand, ipsetRestoreFlag)
 	if destroyError != nil {
-		klog.Errorf(
+		logger.Errorf(
 			"failed to destroy all ipsets (prometheus metrics may be off now). destroyFailureCount %d. err: %v",
 			destroyFailureCount, destroyError,
 		)
@@ -142,7 +141,7 @@ func (iMgr *IPSetManager) resetWithoutRestore() bool {
 	listNamesCommand := iMgr.ioShim.Exec.Command(ipsetCommand, ipsetListFlag, ipsetNameFlag)
 	grepCommand := iMgr.ioShim.Exec.Command(ioutil.Grep, ioutil.GrepQuietFlag, ioutil.GrepAntiMatchFlag, azureNPMPrefix)
 	commandString := fmt.Sprintf(" [%s %s %s | %s %s %s %s]", ipsetCommand, ipsetListFlag, ipsetNameFlag, ioutil.Grep, ioutil.GrepQuietFlag, ioutil.GrepAntiMatchFlag, azureNPMPrefix)
-	klog.Infof("running this command while resetting ipsets: [%s]", commandString)
+	logger.Infof("running this command while resetting ipsets: [%s]", commandString)
 	_, haveNonAzureNPMIPSets, commandError := ioutil.PipeCommandToGrep(listNamesCommand, grepCommand)
 	if commandError != nil {
 		metrics.SendErrorLogAndMetric(util.IpsmID, "failed to determine if there were non-azure sets while resetting. err: %v", commandError)
@@ -153,7 +152,7 @@ func (iMgr *IPSetManager) resetWithoutRestore() bool {
 	}
 
 	flushAndDestroy := iMgr.ioShim.Exec.Command(util.BashCommand, util.BashCommandFlag, ipsetFlushAndDestroyString)
-	klog.Infof("running this command while resetting ipsets: [%s %s '%s']", util.BashCommand, util.BashCommandFlag, ipsetFlushAndDestroyString)
+	logger.Infof("running this command while resetting ipsets: [%s %s '%s']", util.BashCommand, util.BashCommandFlag, ipsetFlushAndDestroyString)
 	output, err := flushAndDestroy.CombinedOutput()
 	if err != nil {
 		exitCode := -1
@@ -193,7 +192,7 @@ func (iMgr *IPSetManager) fileCreatorForFlushAll(ipsetListOutput []byte) (creato
 				Definition: setDoesntExistDefinition,
 				Method:     ioutil.Continue,
 				Callback: func() {
-					klog.Infof("[RESET-IPSETS] skipping flush and upcoming destroy for set %s since the set doesn't exist", hashedSetName)
+					logger.Infof("[RESET-IPSETS] skipping flush and upcoming destroy for set %s since the set doesn't exist", hashedSetName)
 					failedNames[hashedSetName] = struct{}{}
 				},
 			},
@@ -219,7 +218,7 @@ func (iMgr *IPSetManager) setsWithReferences() map[string]struct{} {
 	listAllCommand := iMgr.ioShim.Exec.Command(ipsetCommand, ipsetListFlag)
 	grep1 := iMgr.ioShim.Exec.Command(ioutil.Grep, ioutil.GrepBeforeFlag, referenceGrepLookBack, ioutil.GrepRegexFlag, positiveRefsRegex)
 	grep2 := iMgr.ioShim.Exec.Command(ioutil.Grep, ioutil.GrepOnlyMatchingFlag, ioutil.GrepRegexFlag, azureNPMRegex)
-	klog.Infof("running this command while resetting ipsets: [%s %s | %s %s %s %s %s | %s %s %s %s]", ipsetCommand, ipsetListFlag,
+	logger.Infof("running this command while resetting ipsets: [%s %s | %s %s %s %s %s | %s %s %s %s]", ipsetCommand, ipsetListFlag,
 		ioutil.Grep, ioutil.GrepBeforeFlag, referenceGrepLookBack, ioutil.GrepRegexFlag, positiveRefsRegex,
 		ioutil.Grep, ioutil.GrepOnlyMatchingFlag, ioutil.GrepRegexFlag, azureNPMRegex)
 	setsWithReferencesBytes, haveRefsStill, err := ioutil.DoublePipeToGrep(listAllCommand, grep1, grep2)
@@ -243,12 +242,12 @@ func (iMgr *IPSetManager) fileCreatorForDestroyAll(names []string, failedNames,
 	// destroy all the sets
 	for _, hashedSetName := range names {
 		if _, ok := failedNames[hashedSetName]; ok {
-			klog.Infof("skipping destroy for set %s since it failed to flush", hashedSetName)
+			logger.Infof("skipping destroy for set %s since it failed to flush", hashedSetName)
 			continue
 		}
 
 		if _, ok := setsWithReferences[hashedSetName]; ok {
-			klog.Infof("skipping destroy for set %s since it has leaked reference counts", hashedSetName)
+			logger.Infof("skipping destroy for set %s since it has leaked reference counts", hashedSetName)
 			continue
 		}
 
@@ -268,7 +267,7 @@ func (iMgr *IPSetManager) fileCreatorForDestroyAll(names []string, failedNames,
 				Definition: setDoesntExistDefinition,
 				Method:     ioutil.Continue,
 				Callback: func() {
-					klog.Infof("[RESET-IPSETS] skipping destroy for set %s since the set does not exist", hashedSetName)
+					logger.Infof("[RESET-IPSETS] skipping destroy for set %s since the set does not exist", hashedSetName)
 				},
 			},
 			{
@@ -410,6 +409,11 @@ func (iMgr *IPSetManager) applyIPSets() error {
 	if restoreError != nil {
 		return npmerrors.SimpleErrorWrapper("ipset restore failed when applying ipsets", restoreError)
 	}
+	if iMgr.iMgrCfg.AddSetsToNftables {
+		if err := iMgr.applyNftablesSets(); err != nil {
+			return npmerrors.SimpleErrorWrapper("nft failed when applying ipsets", err)
+		}
+	}
 	return nil
 }
 
@@ -647,7 +651,7 @@ func nextCreateLine(originalReadIndex int, saveFile []byte) (createLine []byte,
 func haveTypeProblem(set *IPSet, restOfSpaceSplitCreateLine []string) bool {
 	// TODO check type based on maxelem for hash sets? CIDR blocks have a different maxelem
 	if len(restOfSpaceSplitCreateLine) == 0 {
-		klog.Error("expected a type specification for the create line but received nothing after the set name")
+		logger.Error("expected a type specification for the create line but received nothing after the set name")
 		return true
 	}
 	typeString := restOfSpaceSplitCreateLine[0]
@@ -687,7 +691,7 @@ func (iMgr *IPSetManager) flushSetForApply(creator *ioutil.FileCreator, prefixed
 			Definition: setDoesntExistDefinition,
 			Method:     ioutil.ContinueAndAbortSection,
 			Callback: func() {
-				klog.Infof("skipping flush and upcoming destroy for set %s since the set doesn't exist", prefixedName)
+				logger.Infof("skipping flush and upcoming destroy for set %s since the set doesn't exist", prefixedName)
 			},
 		},
 		{
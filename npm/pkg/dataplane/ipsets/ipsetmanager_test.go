@@ -286,6 +286,26 @@ func TestResetIPSets(t *testing.T) {
 	})
 }
 
+func TestAuditModeSkipsKernel(t *testing.T) {
+	metrics.ReinitializeAll()
+	ioShim := common.NewMockIOShim(nil)
+	defer ioShim.VerifyCalls(t, nil)
+	iMgr := NewIPSetManager(&IPSetManagerCfg{IPSetMode: ApplyAllIPSets, NetworkName: "azure", AuditMode: true}, ioShim)
+
+	require.NoError(t, iMgr.ResetIPSets())
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{keyLabelOfPodSet}, testPodIP, testPodKey))
+	require.NoError(t, iMgr.ApplyIPSets())
+	iMgr.Reconcile()
+
+	// the cache is kept, but nothing is left to apply
+	assertExpectedInfo(t, iMgr, &expectedInfo{
+		mainCache: []setMembers{
+			{metadata: keyLabelOfPodSet, members: []member{{testPodIP, isHashMember}}},
+		},
+	})
+}
+
 func TestCreateIPSet(t *testing.T) {
 	type args struct {
 		cfg       *IPSetManagerCfg
@@ -1120,6 +1140,57 @@ func TestRemoveFromListMissing(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestValidateCacheIntegrity(t *testing.T) {
+	setMetadata := NewIPSetMetadata(testSetName, Namespace)
+	listMetadata := NewIPSetMetadata(testListName, KeyLabelOfNamespace)
+	newManager := func(t *testing.T) *IPSetManager {
+		iMgr := NewIPSetManager(applyOnNeedCfg, common.NewMockIOShim([]testutils.TestCmd{}))
+		require.NoError(t, iMgr.AddReference(listMetadata, testNetPolKey, NetPolType))
+		require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{listMetadata}, []*IPSetMetadata{setMetadata}))
+		require.NoError(t, iMgr.ValidateCacheIntegrity())
+		return iMgr
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(iMgr *IPSetManager)
+	}{
+		{
+			name: "member missing from cache",
+			corrupt: func(iMgr *IPSetManager) {
+				delete(iMgr.setMap, setMetadata.GetPrefixName())
+			},
+		},
+		{
+			name: "stale member",
+			corrupt: func(iMgr *IPSetManager) {
+				iMgr.setMap[setMetadata.GetPrefixName()] = NewIPSet(setMetadata)
+			},
+		},
+		{
+			name: "ipset refer count",
+			corrupt: func(iMgr *IPSetManager) {
+				iMgr.GetIPSet(setMetadata.GetPrefixName()).decIPSetReferCount()
+			},
+		},
+		{
+			name: "kernel refer count",
+			corrupt: func(iMgr *IPSetManager) {
+				iMgr.GetIPSet(setMetadata.GetPrefixName()).incKernelReferCount()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			iMgr := newManager(t)
+			tt.corrupt(iMgr)
+			require.ErrorIs(t, iMgr.ValidateCacheIntegrity(), ErrCacheIntegrity)
+		})
+	}
+}
+
 func TestAddReference(t *testing.T) {
 	ref0 := "ref0" // for alreadyReferenced
 	ref1 := "ref1"
@@ -1467,6 +1538,89 @@ func TestDeleteReferenceApplyOnNeed(t *testing.T) {
 	}
 }
 
+// benchmarkSizes are representative of large clusters: many small sets, and a few sets with many members.
+var benchmarkSizes = []struct {
+	name          string
+	numSets       int
+	membersPerSet int
+}{
+	{"sets=1000/members=10", 1000, 10},
+	{"sets=100/members=100", 100, 100},
+	{"sets=10/members=2000", 10, 2000},
+}
+
+const benchmarkSetsPerList = 10
+
+// benchmarkIPSetManager creates the sets with unique members, and a list for every benchmarkSetsPerList sets.
+func benchmarkIPSetManager(b *testing.B, numSets, membersPerSet int, calls []testutils.TestCmd) (*IPSetManager, []*IPSetMetadata) {
+	iMgr := NewIPSetManager(applyAlwaysCfg, common.NewMockIOShim(calls))
+	sets := make([]*IPSetMetadata, 0, numSets)
+	for i := 0; i < numSets; i++ {
+		set := NewIPSetMetadata(fmt.Sprintf("bench-set-%d", i), KeyValueLabelOfPod)
+		sets = append(sets, set)
+		for j := 0; j < membersPerSet; j++ {
+			require.NoError(b, iMgr.AddToSets([]*IPSetMetadata{set}, benchmarkIP(i*membersPerSet+j), fmt.Sprintf("pod-%d-%d", i, j)))
+		}
+	}
+	for i := 0; i < numSets; i += benchmarkSetsPerList {
+		list := NewIPSetMetadata(fmt.Sprintf("bench-list-%d", i/benchmarkSetsPerList), KeyValueLabelOfNamespace)
+		end := i + benchmarkSetsPerList
+		if end > numSets {
+			end = numSets
+		}
+		require.NoError(b, iMgr.AddToLists([]*IPSetMetadata{list}, sets[i:end]))
+	}
+	return iMgr, sets
+}
+
+func benchmarkIP(i int) string {
+	return fmt.Sprintf("10.%d.%d.%d", (i>>16)&0xff, (i>>8)&0xff, i&0xff)
+}
+
+func BenchmarkApplyIPSets(b *testing.B) {
+	testutils.DiscardKlog(b)
+	for _, size := range benchmarkSizes {
+		size := size
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				iMgr, _ := benchmarkIPSetManager(b, size.numSets, size.membersPerSet, GetApplyIPSetsTestCalls([]*IPSetMetadata{namespaceSet}, nil))
+				b.StartTimer()
+
+				require.NoError(b, iMgr.ApplyIPSets())
+			}
+		})
+	}
+}
+
+// BenchmarkApplyIPSetsChurn measures applying a tenth of the pods being replaced after the sets are in the kernel.
+func BenchmarkApplyIPSetsChurn(b *testing.B) {
+	testutils.DiscardKlog(b)
+	for _, size := range benchmarkSizes {
+		size := size
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				calls := GetApplyIPSetsTestCalls([]*IPSetMetadata{namespaceSet}, nil)
+				iMgr, sets := benchmarkIPSetManager(b, size.numSets, size.membersPerSet, append(calls, calls...))
+				require.NoError(b, iMgr.ApplyIPSets())
+				numMembers := size.numSets * size.membersPerSet
+				for j := 0; j < numMembers; j += 10 {
+					set := sets[j/size.membersPerSet]
+					podKey := fmt.Sprintf("pod-%d-%d", j/size.membersPerSet, j%size.membersPerSet)
+					require.NoError(b, iMgr.RemoveFromSets([]*IPSetMetadata{set}, benchmarkIP(j), podKey))
+					require.NoError(b, iMgr.AddToSets([]*IPSetMetadata{set}, benchmarkIP(numMembers+j), podKey+"-new"))
+				}
+				b.StartTimer()
+
+				require.NoError(b, iMgr.ApplyIPSets())
+			}
+		})
+	}
+}
+
 func TestMain(m *testing.M) {
 	metrics.InitializeAll()
 
@@ -1627,6 +1781,9 @@ func assertExpectedInfo(t *testing.T, iMgr *IPSetManager, info *expectedInfo) {
 		require.True(t, iMgr.shouldBeInKernel(set), "set %s should be in kernel", setName)
 	}
 
+	// 1.5. make sure the references between sets are consistent
+	require.NoError(t, iMgr.ValidateCacheIntegrity())
+
 	// 2. assert prometheus metrics
 	// at this point, the expected cache/kernel is the same as the actual cache/kernel
 	numIPSetsInKernel, err := metrics.GetNumIPSets()
@@ -4,13 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
 	"github.com/Microsoft/hcsshim/hcn"
-	"k8s.io/klog"
 )
 
 const (
@@ -31,7 +31,7 @@ type networkPolicyBuilder struct {
 
 func (iMgr *IPSetManager) DoesIPSatisfySelectorIPSets(ip, podKey string, setList map[string]struct{}) (bool, error) {
 	if len(setList) == 0 {
-		klog.Infof("[ipset manager] unexpectedly encountered empty selector list")
+		logger.Infof("[ipset manager] unexpectedly encountered empty selector list")
 		return true, nil
 	}
 	iMgr.Lock()
@@ -108,7 +108,7 @@ func (iMgr *IPSetManager) GetIPsFromSelectorIPSets(setList map[string]struct{})
 					// 2. Pod B create is somehow processed before Pod A delete
 					// 3. This method is called before Pod A delete
 					// again, this
-					klog.Warningf("[GetIPsFromSelectorIPSets] IP currently associated with two different pod keys. to ensure no issues occur with network policies, restart this ip: %s", ip)
+					logger.Warnf("[GetIPsFromSelectorIPSets] IP currently associated with two different pod keys. to ensure no issues occur with network policies, restart this ip: %s", ip)
 				}
 				ips[ip] = podKey
 			}
@@ -152,6 +152,87 @@ func (iMgr *IPSetManager) GetSelectorReferencesBySet(setName string) (map[string
 	return m, nil
 }
 
+// GetIPsFromSets returns the sorted IPs or CIDRs which are members of every given set, where the members of a list are
+// the members of its member sets. The given sets are prefixed set names.
+// In IP policy mode, ACLs contain these IPs instead of referring to the sets.
+func (iMgr *IPSetManager) GetIPsFromSets(setNames []string) ([]string, error) {
+	iMgr.RLock()
+	defer iMgr.RUnlock()
+
+	var ips map[string]struct{}
+	for _, setName := range setNames {
+		set, ok := iMgr.setMap[setName]
+		if !ok {
+			return nil, npmerrors.Errorf(
+				npmerrors.IPSetIntersection,
+				false,
+				fmt.Sprintf("[ipset manager] ipset %s does not exist", setName))
+		}
+
+		setIPs := make(map[string]struct{})
+		for ip := range set.IPPodKey {
+			setIPs[ip] = struct{}{}
+		}
+		for _, memberSet := range set.MemberIPSets {
+			for ip := range memberSet.IPPodKey {
+				setIPs[ip] = struct{}{}
+			}
+		}
+
+		if ips == nil {
+			ips = setIPs
+			continue
+		}
+		for ip := range ips {
+			if _, ok := setIPs[ip]; !ok {
+				delete(ips, ip)
+			}
+		}
+	}
+
+	result := make([]string, 0, len(ips))
+	for ip := range ips {
+		result = append(result, ip)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// TakeNetPolsWithUpdatedSets returns the NetPols referring to sets, directly or through a list, which were updated
+// since the last call. The NetPols are only tracked with SkipSetPolicies.
+func (iMgr *IPSetManager) TakeNetPolsWithUpdatedSets() map[string]struct{} {
+	iMgr.Lock()
+	defer iMgr.Unlock()
+	netPols := iMgr.netPolsWithUpdatedSets
+	iMgr.netPolsWithUpdatedSets = make(map[string]struct{})
+	return netPols
+}
+
+// trackNetPolsWithUpdatedSets adds the NetPols referring to the sets in the dirty cache to netPolsWithUpdatedSets.
+// Assumes that the dirty cache is locked (or equivalently, the ipsetmanager itself).
+func (iMgr *IPSetManager) trackNetPolsWithUpdatedSets() {
+	updatedSets := iMgr.dirtyCache.setsToAddOrUpdate()
+	for _, set := range iMgr.setMap {
+		if _, ok := updatedSets[set.Name]; !ok {
+			// a list is affected by the updates of its members too
+			isListOfUpdatedSet := false
+			for memberName := range set.MemberIPSets {
+				if _, ok := updatedSets[memberName]; ok {
+					isListOfUpdatedSet = true
+					break
+				}
+			}
+			if !isListOfUpdatedSet {
+				continue
+			}
+		}
+
+		for netPolKey := range set.NetPolReference {
+			iMgr.netPolsWithUpdatedSets[netPolKey] = struct{}{}
+		}
+	}
+}
+
 func (iMgr *IPSetManager) validateSelectorIPSets(setList map[string]struct{}) error {
 	for setName := range setList {
 		if !iMgr.exists(setName) {
@@ -171,68 +252,90 @@ func (iMgr *IPSetManager) validateSelectorIPSets(setList map[string]struct{}) er
 	return nil
 }
 
+// reconcileNftablesSets is a no-op in Windows, which has no nftables.
+func (iMgr *IPSetManager) reconcileNftablesSets() {}
+
 func (iMgr *IPSetManager) resetIPSets() error {
-	klog.Infof("[IPSetManager Windows] Resetting Dataplane")
-	network, err := iMgr.getHCnNetwork()
+	logger.Infof("[IPSetManager Windows] Resetting Dataplane")
+	networks, err := iMgr.getHCnNetworks()
 	if err != nil {
 		return err
 	}
 
-	_, toDeleteSets := iMgr.segregateSetPolicies(network.Policies, resetIPSetsTrue)
+	for _, network := range networks {
+		_, toDeleteSets := iMgr.segregateSetPolicies(network.Policies, resetIPSetsTrue)
 
-	if len(toDeleteSets) == 0 {
-		klog.Infof("[IPSetManager Windows] No IPSets to delete")
-		return nil
-	}
+		if len(toDeleteSets) == 0 {
+			logger.Infof("[IPSetManager Windows] No IPSets to delete on network %s", network.Name)
+			continue
+		}
 
-	klog.Infof("[IPSetManager Windows] Deleting %d Set Policies", len(toDeleteSets))
-	err = iMgr.modifySetPolicies(network, hcn.RequestTypeRemove, toDeleteSets)
-	if err != nil {
-		klog.Infof("[IPSetManager Windows] Update set policies failed with error %s", err.Error())
-		return err
+		logger.Infof("[IPSetManager Windows] Deleting %d Set Policies on network %s", len(toDeleteSets), network.Name)
+		err = iMgr.modifySetPolicies(network, hcn.RequestTypeRemove, toDeleteSets)
+		if err != nil {
+			logger.Infof("[IPSetManager Windows] Update set policies failed with error %s", err.Error())
+			return err
+		}
 	}
 
 	return nil
 }
 
 func (iMgr *IPSetManager) applyIPSets() error {
-	network, err := iMgr.getHCnNetwork()
-	if err != nil {
-		return err
+	if iMgr.iMgrCfg.SkipSetPolicies {
+		iMgr.trackNetPolsWithUpdatedSets()
+		logger.Info("[IPSetManager Windows] Skipped applying IPSets as SetPolicies.")
+		iMgr.clearDirtyCache()
+		return nil
 	}
 
-	setPolicyBuilder, err := iMgr.calculateNewSetPolicies(network.Policies)
+	networks, err := iMgr.getHCnNetworks()
 	if err != nil {
 		return err
 	}
 
-	if len(setPolicyBuilder.toAddSets) > 0 {
-		err = iMgr.modifySetPolicies(network, hcn.RequestTypeAdd, setPolicyBuilder.toAddSets)
+	// each network has its own SetPolicies, so the sets to add, update and delete are calculated per network
+	setPolicyBuilders := make([]*networkPolicyBuilder, len(networks))
+	for i, network := range networks {
+		setPolicyBuilders[i], err = iMgr.calculateNewSetPolicies(network.Policies)
 		if err != nil {
-			klog.Infof("[IPSetManager Windows] Add set policies failed with error %s", err.Error())
 			return err
 		}
 	}
 
-	if len(setPolicyBuilder.toUpdateSets) > 0 {
-		err = iMgr.modifySetPolicies(network, hcn.RequestTypeUpdate, setPolicyBuilder.toUpdateSets)
-		if err != nil {
-			klog.Infof("[IPSetManager Windows] Update set policies failed with error %s", err.Error())
-			return err
+	for i, network := range networks {
+		setPolicyBuilder := setPolicyBuilders[i]
+		if len(setPolicyBuilder.toAddSets) > 0 {
+			err = iMgr.modifySetPolicies(network, hcn.RequestTypeAdd, setPolicyBuilder.toAddSets)
+			if err != nil {
+				logger.Infof("[IPSetManager Windows] Add set policies failed on network %s with error %s", network.Name, err.Error())
+				return err
+			}
+		}
+
+		if len(setPolicyBuilder.toUpdateSets) > 0 {
+			err = iMgr.modifySetPolicies(network, hcn.RequestTypeUpdate, setPolicyBuilder.toUpdateSets)
+			if err != nil {
+				logger.Infof("[IPSetManager Windows] Update set policies failed on network %s with error %s", network.Name, err.Error())
+				return err
+			}
 		}
 	}
 
 	iMgr.dirtyCache.resetAddOrUpdateCache()
 
-	if len(setPolicyBuilder.toDeleteSets) > 0 {
-		err = iMgr.modifySetPolicies(network, hcn.RequestTypeRemove, setPolicyBuilder.toDeleteSets)
-		if err != nil {
-			klog.Infof("[IPSetManager Windows] Delete set policies failed with error %s", err.Error())
-			return err
+	for i, network := range networks {
+		setPolicyBuilder := setPolicyBuilders[i]
+		if len(setPolicyBuilder.toDeleteSets) > 0 {
+			err = iMgr.modifySetPolicies(network, hcn.RequestTypeRemove, setPolicyBuilder.toDeleteSets)
+			if err != nil {
+				logger.Infof("[IPSetManager Windows] Delete set policies failed on network %s with error %s", network.Name, err.Error())
+				return err
+			}
 		}
 	}
 
-	klog.Info("[IPSetManager Windows] Done applying IPSets.")
+	logger.Info("[IPSetManager Windows] Done applying IPSets.")
 
 	iMgr.clearDirtyCache()
 
@@ -309,7 +412,8 @@ func (iMgr *IPSetManager) calculateNewSetPolicies(networkPolicies []hcn.NetworkP
 	return setPolicyBuilder, nil
 }
 
-func (iMgr *IPSetManager) getHCnNetwork() (*hcn.HostComputeNetwork, error) {
+// getHCnNetworks returns the primary network followed by the secondary networks.
+func (iMgr *IPSetManager) getHCnNetworks() ([]*hcn.HostComputeNetwork, error) {
 	if iMgr.iMgrCfg.NetworkName == "" {
 		iMgr.iMgrCfg.NetworkName = util.AzureNetworkName
 	}
@@ -317,18 +421,23 @@ func (iMgr *IPSetManager) getHCnNetwork() (*hcn.HostComputeNetwork, error) {
 		return nil, errUnsupportedNetwork
 	}
 
-	timer := metrics.StartNewTimer()
-	network, err := iMgr.ioShim.Hns.GetNetworkByName(iMgr.iMgrCfg.NetworkName)
-	metrics.RecordGetNetworkLatency(timer)
-	if err != nil {
-		metrics.IncGetNetworkFailures()
-		return nil, err
+	networkNames := iMgr.iMgrCfg.NetworkNames()
+	networks := make([]*hcn.HostComputeNetwork, 0, len(networkNames))
+	for _, networkName := range networkNames {
+		timer := metrics.StartNewTimer()
+		network, err := iMgr.ioShim.Hns.GetNetworkByName(networkName)
+		metrics.RecordGetNetworkLatency(timer)
+		if err != nil {
+			metrics.IncGetNetworkFailures()
+			return nil, err
+		}
+		networks = append(networks, network)
 	}
-	return network, nil
+	return networks, nil
 }
 
 func (iMgr *IPSetManager) modifySetPolicies(network *hcn.HostComputeNetwork, operation hcn.RequestType, setPolicies map[string]*hcn.SetPolicySetting) error {
-	klog.Infof("[IPSetManager Windows] %s operation on set policies is called", operation)
+	logger.Infof("[IPSetManager Windows] %s operation on set policies is called", operation)
 	/*
 		Due to complexities in HNS, we need to do the following:
 		for (Add)
@@ -346,7 +455,7 @@ func (iMgr *IPSetManager) modifySetPolicies(network *hcn.HostComputeNetwork, ope
 	for _, policyType := range policySettingsOrder {
 		policyRequest, err := getPolicyNetworkRequestMarshal(setPolicies, policyType)
 		if err != nil {
-			klog.Infof("[IPSetManager Windows] Failed to marshal %s operations sets with error %s", operation, err.Error())
+			logger.Infof("[IPSetManager Windows] Failed to marshal %s operations sets with error %s", operation, err.Error())
 			return err
 		}
 
@@ -360,7 +469,7 @@ func (iMgr *IPSetManager) modifySetPolicies(network *hcn.HostComputeNetwork, ope
 			Settings:     policyRequest,
 		}
 
-		klog.Infof("[IPSetManager Windows] modifying network settings. operation: %s, policyType: %s", operation, policyType)
+		logger.Infof("[IPSetManager Windows] modifying network settings. operation: %s, policyType: %s", operation, policyType)
 
 		// metrics.CreateOp would be for hcn.RequestTypeAdd
 		op := metrics.CreateOp
@@ -379,7 +488,7 @@ func (iMgr *IPSetManager) modifySetPolicies(network *hcn.HostComputeNetwork, ope
 		metrics.RecordSetPolicyLatency(timer, op, isNested)
 		if err != nil {
 			metrics.IncSetPolicyFailures(op, isNested)
-			klog.Infof("[IPSetManager Windows] %s operation has failed with error %s", operation, err.Error())
+			logger.Infof("[IPSetManager Windows] %s operation has failed with error %s", operation, err.Error())
 			return err
 		}
 	}
@@ -396,7 +505,7 @@ func (iMgr *IPSetManager) segregateSetPolicies(networkPolicies []hcn.NetworkPoli
 		var set hcn.SetPolicySetting
 		err := json.Unmarshal(netpol.Settings, &set)
 		if err != nil {
-			klog.Error(err.Error())
+			logger.Error(err.Error())
 			continue
 		}
 		if !strings.HasPrefix(set.Id, util.AzureNpmPrefix) {
@@ -425,10 +534,10 @@ func (setPolicyBuilder *networkPolicyBuilder) setNameExists(setName string) bool
 
 func getPolicyNetworkRequestMarshal(setPolicySettings map[string]*hcn.SetPolicySetting, policyType hcn.SetPolicyType) ([]byte, error) {
 	if len(setPolicySettings) == 0 {
-		klog.Info("[Dataplane Windows] no set policies to apply on network")
+		logger.Info("[Dataplane Windows] no set policies to apply on network")
 		return nil, nil
 	}
-	klog.Infof("[Dataplane Windows] marshalling %s(s)", policyType)
+	logger.Infof("[Dataplane Windows] marshalling %s(s)", policyType)
 	policyNetworkRequest := &hcn.PolicyNetworkRequest{
 		Policies: make([]hcn.NetworkPolicy, 0),
 	}
@@ -451,7 +560,7 @@ func getPolicyNetworkRequestMarshal(setPolicySettings map[string]*hcn.SetPolicyS
 	}
 
 	if len(policyNetworkRequest.Policies) == 0 {
-		klog.Infof("[Dataplane Windows] no %s type of sets to apply", policyType)
+		logger.Infof("[Dataplane Windows] no %s type of sets to apply", policyType)
 		return nil, nil
 	}
 
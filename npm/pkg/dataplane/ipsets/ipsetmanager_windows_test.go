@@ -67,6 +67,74 @@ func TestGetIPsFromSelectorIPSets(t *testing.T) {
 	require.Equal(t, expectedintersection, ips)
 }
 
+func TestGetIPsFromSets(t *testing.T) {
+	iMgr := NewIPSetManager(applyAlwaysCfg, common.NewMockIOShim([]testutils.TestCmd{}))
+	kvl := NewIPSetMetadata("kvl-1", KeyValueLabelOfPod)
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{namespaceSet}, "10.0.0.1", "test1"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{namespaceSet, kvl}, "10.0.0.2", "test2"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{kvl}, "10.0.0.3", "test3"))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{nestedPodLabelList}, []*IPSetMetadata{kvl}))
+
+	ips, err := iMgr.GetIPsFromSets([]string{nestedPodLabelList.GetPrefixName()})
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.2", "10.0.0.3"}, ips)
+
+	ips, err = iMgr.GetIPsFromSets([]string{namespaceSet.GetPrefixName(), nestedPodLabelList.GetPrefixName()})
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.2"}, ips)
+
+	_, err = iMgr.GetIPsFromSets([]string{"not-a-set"})
+	require.Error(t, err)
+}
+
+func TestSkipSetPoliciesTracksNetPolsWithUpdatedSets(t *testing.T) {
+	hns := GetHNSFake(t, "azure")
+	io := common.NewMockIOShimWithFakeHNS(hns)
+	iMgr := NewIPSetManager(&IPSetManagerCfg{
+		IPSetMode:       ApplyAllIPSets,
+		NetworkName:     "azure",
+		SkipSetPolicies: true,
+	}, io)
+
+	kvl := NewIPSetMetadata("kvl-1", KeyValueLabelOfPod)
+	require.NoError(t, iMgr.AddReference(namespaceSet, "x/policy1", NetPolType))
+	require.NoError(t, iMgr.AddReference(nestedPodLabelList, "x/policy2", NetPolType))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{nestedPodLabelList}, []*IPSetMetadata{kvl}))
+	require.NoError(t, iMgr.ApplyIPSets())
+	require.Equal(t, map[string]struct{}{"x/policy1": {}, "x/policy2": {}}, iMgr.TakeNetPolsWithUpdatedSets())
+	require.Empty(t, hns.Cache.AllSetPolicies(common.FakeHNSNetworkID))
+
+	// a policy is updated when a member of its list is updated
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{kvl}, "10.0.0.1", "test1"))
+	require.NoError(t, iMgr.ApplyIPSets())
+	require.Equal(t, map[string]struct{}{"x/policy2": {}}, iMgr.TakeNetPolsWithUpdatedSets())
+	require.Empty(t, iMgr.TakeNetPolsWithUpdatedSets())
+	require.Empty(t, hns.Cache.AllSetPolicies(common.FakeHNSNetworkID))
+}
+
+func TestApplyIPSetsOnSecondaryNetworks(t *testing.T) {
+	hns := GetHNSFake(t, "azure")
+	_, err := hns.CreateNetwork(&hcn.HostComputeNetwork{Id: "5678", Name: "secondary"})
+	require.NoError(t, err)
+	io := common.NewMockIOShimWithFakeHNS(hns)
+	iMgr := NewIPSetManager(&IPSetManagerCfg{
+		IPSetMode:             ApplyAllIPSets,
+		NetworkName:           "azure",
+		SecondaryNetworkNames: []string{"secondary", "azure"},
+	}, io)
+	require.Equal(t, []string{"azure", "secondary"}, iMgr.iMgrCfg.NetworkNames())
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{namespaceSet}, testPodIP, testPodKey))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{nestedPodLabelList}, []*IPSetMetadata{namespaceSet}))
+	require.NoError(t, iMgr.ApplyIPSets())
+	require.Len(t, hns.Cache.AllSetPolicies(common.FakeHNSNetworkID), 2)
+	require.Equal(t, hns.Cache.AllSetPolicies(common.FakeHNSNetworkID), hns.Cache.AllSetPolicies("5678"))
+
+	require.NoError(t, iMgr.ResetIPSets())
+	require.Empty(t, hns.Cache.AllSetPolicies(common.FakeHNSNetworkID))
+	require.Empty(t, hns.Cache.AllSetPolicies("5678"))
+}
+
 func TestAddToSetWindows(t *testing.T) {
 	hns := GetHNSFake(t, "azure")
 	io := common.NewMockIOShimWithFakeHNS(hns)
@@ -376,6 +444,7 @@ func TestFailureOnDeletion(t *testing.T) {
 }
 
 func verifyHNSCache(t *testing.T, expected map[string]hcn.SetPolicySetting, hns *hnswrapper.Hnsv2wrapperFake) {
+	require.NoError(t, hns.Cache.ValidateReferences())
 	for setName, setObj := range expected {
 		cacheObj := hns.Cache.SetPolicy(setObj.Id)
 		require.NotNil(t, cacheObj)
@@ -0,0 +1,109 @@
+package ipsets
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+)
+
+// maxListDepth bounds the nesting of lists while matching, in case of a loop.
+const maxListDepth = 4
+
+// MatchIP returns whether the IP is a member of the set, and the member which decided it.
+// The protocol and port are only used for NamedPorts sets, whose members are ip,protocol:port.
+// In a CIDRBlocks set, the most specific member containing the IP wins like in the kernel,
+// so an IP in a nomatch member is not in the set even though a broader member contains it.
+// A list matches when any of its member sets does.
+func (iMgr *IPSetManager) MatchIP(setName string, ip net.IP, protocol string, port int) (bool, string, error) {
+	iMgr.RLock()
+	defer iMgr.RUnlock()
+	set, ok := iMgr.setMap[setName]
+	if !ok {
+		return false, "", npmerrors.Errorf(npmerrors.TestIPSet, false, fmt.Sprintf("[ipset manager] ipset %s does not exist", setName))
+	}
+	return matchIP(set, ip, protocol, port, 0)
+}
+
+func matchIP(set *IPSet, ip net.IP, protocol string, port int, depth int) (bool, string, error) {
+	if set.Kind == ListSet {
+		if depth >= maxListDepth {
+			return false, "", npmerrors.Errorf(npmerrors.TestIPSet, false, fmt.Sprintf("[ipset manager] list %s nests too deep", set.Name))
+		}
+		for _, memberSet := range set.MemberIPSets {
+			matched, member, err := matchIP(memberSet, ip, protocol, port, depth+1)
+			if err != nil {
+				return false, "", err
+			}
+			if matched {
+				return true, memberSet.Name + " > " + member, nil
+			}
+		}
+		return false, "", nil
+	}
+
+	switch set.Type {
+	case NamedPorts:
+		for member := range set.IPPodKey {
+			if matchNamedPortMember(member, ip, protocol, port) {
+				return true, member, nil
+			}
+		}
+		return false, "", nil
+	case CIDRBlocks:
+		return matchCIDRMembers(set, ip)
+	default:
+		for member := range set.IPPodKey {
+			if memberIP := net.ParseIP(member); memberIP != nil && memberIP.Equal(ip) {
+				return true, member, nil
+			}
+		}
+		return false, "", nil
+	}
+}
+
+// matchNamedPortMember matches a member like 10.0.0.1,TCP:80 or 10.0.0.1,80, which is TCP.
+func matchNamedPortMember(member string, ip net.IP, protocol string, port int) bool {
+	memberIP, protocolPort, ok := strings.Cut(member, ",")
+	if !ok || !net.ParseIP(memberIP).Equal(ip) {
+		return false
+	}
+	memberProtocol, memberPort, ok := strings.Cut(protocolPort, ":")
+	if !ok {
+		memberProtocol, memberPort = "TCP", protocolPort
+	}
+	return strings.EqualFold(memberProtocol, protocol) && memberPort == strconv.Itoa(port)
+}
+
+func matchCIDRMembers(set *IPSet, ip net.IP) (bool, string, error) {
+	bestOnes := -1
+	best := ""
+	for member := range set.IPPodKey {
+		cidr := strings.TrimSpace(strings.TrimSuffix(member, util.IpsetNomatch))
+		if !strings.Contains(cidr, "/") {
+			if ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return false, "", npmerrors.Errorf(npmerrors.TestIPSet, false, fmt.Sprintf("[ipset manager] failed to parse member %s of ipset %s: %v", member, set.Name, err))
+		}
+		if !ipNet.Contains(ip) {
+			continue
+		}
+		if ones, _ := ipNet.Mask.Size(); ones > bestOnes {
+			bestOnes = ones
+			best = member
+		}
+	}
+	if best == "" || strings.HasSuffix(best, util.IpsetNomatch) {
+		return false, best, nil
+	}
+	return true, best, nil
+}
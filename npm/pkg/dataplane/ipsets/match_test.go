@@ -0,0 +1,55 @@
+package ipsets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchIP(t *testing.T) {
+	cidrSet := NewIPSetMetadata("test-cidr-set", CIDRBlocks)
+
+	iMgr := NewIPSetManager(applyAlwaysCfg, common.NewMockIOShim(nil))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{namespaceSet}, "10.0.0.1", testPodKey))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{portSet}, "10.0.0.1,tcp:8080", testPodKey))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{cidrSet}, "10.1.0.0/16", testPodKey))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{cidrSet}, "10.1.2.0/24 nomatch", testPodKey))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{cidrSet}, "10.1.2.3", testPodKey))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{list}, []*IPSetMetadata{namespaceSet}))
+
+	tests := []struct {
+		name     string
+		set      *IPSetMetadata
+		ip       string
+		protocol string
+		port     int
+		matched  bool
+		member   string
+	}{
+		{name: "ip in hash set", set: namespaceSet, ip: "10.0.0.1", matched: true, member: "10.0.0.1"},
+		{name: "ip not in hash set", set: namespaceSet, ip: "10.0.0.2"},
+		{name: "named port", set: portSet, ip: "10.0.0.1", protocol: "TCP", port: 8080, matched: true, member: "10.0.0.1,tcp:8080"},
+		{name: "named port with other protocol", set: portSet, ip: "10.0.0.1", protocol: "UDP", port: 8080},
+		{name: "named port with other port", set: portSet, ip: "10.0.0.1", protocol: "TCP", port: 80},
+		{name: "ip in cidr", set: cidrSet, ip: "10.1.0.5", matched: true, member: "10.1.0.0/16"},
+		{name: "ip in nomatch cidr", set: cidrSet, ip: "10.1.2.4", member: "10.1.2.0/24 nomatch"},
+		{name: "ip in nomatch cidr with more specific member", set: cidrSet, ip: "10.1.2.3", matched: true, member: "10.1.2.3"},
+		{name: "ip outside cidrs", set: cidrSet, ip: "10.2.0.1"},
+		{name: "ip in member of list", set: list, ip: "10.0.0.1", matched: true, member: namespaceSet.GetPrefixName() + " > 10.0.0.1"},
+		{name: "ip not in member of list", set: list, ip: "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			matched, member, err := iMgr.MatchIP(tt.set.GetPrefixName(), net.ParseIP(tt.ip), tt.protocol, tt.port)
+			require.NoError(t, err)
+			require.Equal(t, tt.matched, matched)
+			require.Equal(t, tt.member, member)
+		})
+	}
+
+	_, _, err := iMgr.MatchIP("nonexistent", net.ParseIP("10.0.0.1"), "", 0)
+	require.Error(t, err)
+}
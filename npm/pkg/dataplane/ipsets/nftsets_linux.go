@@ -0,0 +1,328 @@
+package ipsets
+
+// This file contains code for adding the sets in the kernel to the nftables table of NPM, used with AddSetsToNftables.
+
+import (
+	"encoding/binary"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+)
+
+/*
+Each set in the kernel is also an nftables set with its hashed name, which nftables rules of the PolicyManager refer to:
+- NamedPorts sets have type "ipv4_addr . inet_proto . inet_service" with elements like "10.0.0.1 . tcp . 80".
+- CIDRBlocks sets and lists are interval sets of IPs. nftables has neither nomatch elements nor sets of sets,
+  so a nomatch CIDR is cut out of the broader CIDRs containing it, and a list holds the IPs of its member sets.
+- other sets are sets of IPs.
+
+After the ipset restore succeeds, the dirty sets and the lists containing them are rewritten in one nft transaction.
+Sets which are no longer in the kernel are deleted in another transaction. nftables won't delete a set while a rule
+refers to it, so a failed delete is logged and retried on the next apply.
+*/
+
+const (
+	nftablesIPType        = "ipv4_addr"
+	nftablesNamedPortType = "ipv4_addr . inet_proto . inet_service"
+)
+
+func (iMgr *IPSetManager) applyNftablesSets() error {
+	setsToWrite := iMgr.nftablesSetsToWrite()
+	if len(setsToWrite) > 0 {
+		creator := ioutil.NewFileCreator(iMgr.ioShim, maxTryCount)
+		creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesTableObject)...)
+		for _, set := range setsToWrite {
+			writeNftablesSet(creator, set)
+		}
+		if err := creator.RunCommandWithFile(util.Nftables, util.NftablesFileFlag, util.NftablesStdin); err != nil {
+			return npmerrors.SimpleErrorWrapper("failed to write nftables sets", err)
+		}
+		for _, set := range setsToWrite {
+			iMgr.nftSets[set.HashedName] = set.Name
+		}
+	}
+
+	iMgr.deleteStaleNftablesSets()
+	return nil
+}
+
+// nftablesSetsToWrite returns the dirty sets and the lists in the kernel containing them, sorted by name.
+func (iMgr *IPSetManager) nftablesSetsToWrite() []*IPSet {
+	dirtySets := iMgr.dirtyCache.setsToAddOrUpdate()
+	sets := make([]*IPSet, 0, len(dirtySets))
+	for prefixedName := range dirtySets {
+		if set, ok := iMgr.setMap[prefixedName]; ok {
+			sets = append(sets, set)
+		}
+	}
+	for _, set := range iMgr.setMap {
+		if _, ok := dirtySets[set.Name]; ok || set.Kind != ListSet || !iMgr.shouldBeInKernel(set) {
+			continue
+		}
+		for memberName := range set.MemberIPSets {
+			if _, ok := dirtySets[memberName]; ok {
+				sets = append(sets, set)
+				break
+			}
+		}
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].Name < sets[j].Name
+	})
+	return sets
+}
+
+func (iMgr *IPSetManager) deleteStaleNftablesSets() {
+	hashedNames := make([]string, 0)
+	for hashedName, prefixedName := range iMgr.nftSets {
+		if set, ok := iMgr.setMap[prefixedName]; ok && iMgr.shouldBeInKernel(set) {
+			continue
+		}
+		hashedNames = append(hashedNames, hashedName)
+	}
+	if len(hashedNames) == 0 {
+		return
+	}
+	sort.Strings(hashedNames)
+
+	creator := ioutil.NewFileCreator(iMgr.ioShim, maxTryCount)
+	for _, hashedName := range hashedNames {
+		creator.AddLine("", nil, util.NftablesSpecs(util.NftablesDeleteFlag, util.NftablesSetObject, hashedName)...)
+	}
+	if err := creator.RunCommandWithFile(util.Nftables, util.NftablesFileFlag, util.NftablesStdin); err != nil {
+		metrics.SendErrorLogAndMetric(util.IpsmID, "error: failed to delete nftables sets %v. will retry on the next apply. err: %s", hashedNames, err.Error())
+		return
+	}
+	for _, hashedName := range hashedNames {
+		delete(iMgr.nftSets, hashedName)
+	}
+}
+
+// reconcileNftablesSets re-creates the table with the sets written to it if the table was deleted, e.g. by another
+// component, so that the PolicyManager can then re-create the rules which refer to the sets.
+func (iMgr *IPSetManager) reconcileNftablesSets() {
+	if !iMgr.iMgrCfg.AddSetsToNftables || len(iMgr.nftSets) == 0 {
+		return
+	}
+
+	command := iMgr.ioShim.Exec.Command(util.Nftables, util.NftablesListFlag, util.NftablesTablesObject, util.NftablesFamily)
+	output, err := command.CombinedOutput()
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.IpsmID, "error: failed to list nftables tables. err: %s. output: %s", err.Error(), strings.TrimSpace(string(output)))
+		return
+	}
+	tableLine := strings.Join([]string{util.NftablesTableObject, util.NftablesFamily, util.NftablesAzureTable}, " ")
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) == tableLine {
+			return
+		}
+	}
+
+	sets := make([]*IPSet, 0, len(iMgr.nftSets))
+	for _, prefixedName := range iMgr.nftSets {
+		if set, ok := iMgr.setMap[prefixedName]; ok && iMgr.shouldBeInKernel(set) {
+			sets = append(sets, set)
+		}
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].Name < sets[j].Name
+	})
+	logger.Infof("[IPSetManager] re-creating %d sets in nftables table %s since the table is missing", len(sets), util.NftablesAzureTable)
+
+	creator := ioutil.NewFileCreator(iMgr.ioShim, maxTryCount)
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesTableObject)...)
+	for _, set := range sets {
+		writeNftablesSet(creator, set)
+	}
+	if err := creator.RunCommandWithFile(util.Nftables, util.NftablesFileFlag, util.NftablesStdin); err != nil {
+		metrics.SendErrorLogAndMetric(util.IpsmID, "error: failed to re-create nftables sets. will retry on the next reconcile. err: %s", err.Error())
+		return
+	}
+
+	// the other sets were deleted with the table
+	iMgr.nftSets = make(map[string]string, len(sets))
+	for _, set := range sets {
+		iMgr.nftSets[set.HashedName] = set.Name
+	}
+}
+
+func writeNftablesSet(creator *ioutil.FileCreator, set *IPSet) {
+	var definition []string
+	var elements []string
+	switch {
+	case set.Kind == ListSet:
+		definition = []string{"type", nftablesIPType, ";", "flags", "interval", ";"}
+		ranges := make([]ipv4Range, 0)
+		for _, member := range set.MemberIPSets {
+			for _, r := range nftablesRanges(member) {
+				ranges = addRange(ranges, r)
+			}
+		}
+		elements = rangeElements(ranges)
+	case set.Type == CIDRBlocks:
+		definition = []string{"type", nftablesIPType, ";", "flags", "interval", ";"}
+		elements = rangeElements(nftablesRanges(set))
+	case set.Type == NamedPorts:
+		definition = []string{"type", nftablesNamedPortType, ";"}
+		elements = namedPortElements(set)
+	default:
+		definition = []string{"type", nftablesIPType, ";"}
+		elements = ipElements(set)
+	}
+
+	setSpecs := append([]string{set.HashedName, "{"}, definition...)
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesSetObject, append(setSpecs, "}")...)...)
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesFlushFlag, util.NftablesSetObject, set.HashedName)...)
+	if len(elements) > 0 {
+		creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesElement, set.HashedName, "{", strings.Join(elements, ", "), "}")...)
+	}
+}
+
+// ipv4Range is an inclusive range of IPv4 addresses.
+type ipv4Range struct {
+	first uint32
+	last  uint32
+}
+
+// nftablesRanges returns the sorted, disjoint ranges of IPs in a hash set, excluding NamedPorts sets.
+// In a CIDRBlocks set, the most specific member containing an IP decides like in the kernel, so members are
+// applied from the broadest to the most specific and nomatch members remove their IPs.
+func nftablesRanges(set *IPSet) []ipv4Range {
+	if set.Type == NamedPorts {
+		logger.Warnf("[IPSetManager] skipping NamedPorts set %s while adding a list to nftables", set.Name)
+		return nil
+	}
+
+	type cidrMember struct {
+		r       ipv4Range
+		ones    int
+		nomatch bool
+	}
+	members := make([]cidrMember, 0, len(set.IPPodKey))
+	for member := range set.IPPodKey {
+		cidr := strings.TrimSpace(strings.TrimSuffix(member, util.IpsetNomatch))
+		if !strings.Contains(cidr, "/") {
+			cidr += "/32"
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil || ipNet.IP.To4() == nil {
+			logger.Warnf("[IPSetManager] skipping member %s of set %s which isn't an IPv4 CIDR", member, set.Name)
+			continue
+		}
+		ones, _ := ipNet.Mask.Size()
+		first := binary.BigEndian.Uint32(ipNet.IP.To4())
+		last := first | ^binary.BigEndian.Uint32(net.IP(ipNet.Mask).To4())
+		members = append(members, cidrMember{
+			r:       ipv4Range{first: first, last: last},
+			ones:    ones,
+			nomatch: strings.HasSuffix(member, util.IpsetNomatch),
+		})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].ones != members[j].ones {
+			return members[i].ones < members[j].ones
+		}
+		return !members[i].nomatch && members[j].nomatch
+	})
+
+	ranges := make([]ipv4Range, 0, len(members))
+	for _, member := range members {
+		if member.nomatch {
+			ranges = removeRange(ranges, member.r)
+		} else {
+			ranges = addRange(ranges, member.r)
+		}
+	}
+	return ranges
+}
+
+// addRange adds r to the sorted, disjoint ranges, merging the ranges which overlap or touch it.
+func addRange(ranges []ipv4Range, r ipv4Range) []ipv4Range {
+	result := make([]ipv4Range, 0, len(ranges)+1)
+	for _, existing := range ranges {
+		if uint64(existing.last)+1 < uint64(r.first) || uint64(r.last)+1 < uint64(existing.first) {
+			result = append(result, existing)
+			continue
+		}
+		r = ipv4Range{first: min(r.first, existing.first), last: max(r.last, existing.last)}
+	}
+	result = append(result, r)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].first < result[j].first
+	})
+	return result
+}
+
+// removeRange removes the IPs of r from the sorted, disjoint ranges.
+func removeRange(ranges []ipv4Range, r ipv4Range) []ipv4Range {
+	result := make([]ipv4Range, 0, len(ranges)+1)
+	for _, existing := range ranges {
+		if existing.last < r.first || existing.first > r.last {
+			result = append(result, existing)
+			continue
+		}
+		if existing.first < r.first {
+			result = append(result, ipv4Range{first: existing.first, last: r.first - 1})
+		}
+		if existing.last > r.last {
+			result = append(result, ipv4Range{first: r.last + 1, last: existing.last})
+		}
+	}
+	return result
+}
+
+func rangeElements(ranges []ipv4Range) []string {
+	elements := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r.first == r.last {
+			elements = append(elements, uint32ToIP(r.first).String())
+		} else {
+			elements = append(elements, uint32ToIP(r.first).String()+"-"+uint32ToIP(r.last).String())
+		}
+	}
+	return elements
+}
+
+func uint32ToIP(n uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
+
+func ipElements(set *IPSet) []string {
+	elements := make([]string, 0, len(set.IPPodKey))
+	for member := range set.IPPodKey {
+		ip := net.ParseIP(member)
+		if ip == nil || ip.To4() == nil {
+			logger.Warnf("[IPSetManager] skipping member %s of set %s which isn't an IPv4 address", member, set.Name)
+			continue
+		}
+		elements = append(elements, ip.String())
+	}
+	sort.Strings(elements)
+	return elements
+}
+
+// namedPortElements converts members like 10.0.0.1,TCP:80 or 10.0.0.1,80 (which is TCP) to 10.0.0.1 . tcp . 80
+func namedPortElements(set *IPSet) []string {
+	elements := make([]string, 0, len(set.IPPodKey))
+	for member := range set.IPPodKey {
+		ip, protocolPort, ok := strings.Cut(member, ",")
+		if !ok || net.ParseIP(ip).To4() == nil {
+			logger.Warnf("[IPSetManager] skipping member %s of set %s which isn't an IPv4 named port", member, set.Name)
+			continue
+		}
+		protocol, port, ok := strings.Cut(protocolPort, ":")
+		if !ok {
+			protocol, port = "tcp", protocolPort
+		}
+		elements = append(elements, ip+" . "+strings.ToLower(protocol)+" . "+port)
+	}
+	sort.Strings(elements)
+	return elements
+}
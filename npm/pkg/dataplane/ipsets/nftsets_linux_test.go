@@ -0,0 +1,133 @@
+package ipsets
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	nftablesCfg = &IPSetManagerCfg{
+		IPSetMode:         ApplyAllIPSets,
+		AddSetsToNftables: true,
+	}
+
+	nftablesFileStringSlice = []string{"nft", "-f", "-"}
+)
+
+func TestNftablesRangesForCIDRBlocks(t *testing.T) {
+	set := NewIPSet(TestCIDRSet.Metadata)
+	set.IPPodKey = map[string]string{
+		"10.0.0.0/16":         "",
+		"10.0.1.0/24 nomatch": "",
+		"10.0.1.128/25":       "",
+		"10.0.3.0/24 nomatch": "",
+		"192.168.0.1":         "",
+		"fe80::/64":           "",
+	}
+	require.Equal(t, []string{
+		"10.0.0.0-10.0.0.255",
+		"10.0.1.128-10.0.2.255",
+		"10.0.4.0-10.0.255.255",
+		"192.168.0.1",
+	}, rangeElements(nftablesRanges(set)))
+}
+
+func TestWriteNftablesSets(t *testing.T) {
+	iMgr := NewIPSetManager(nftablesCfg, common.NewMockIOShim(nil))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.2", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "b"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestKeyPodSet.Metadata}, "10.0.0.3", "c"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNamedportSet.Metadata}, "10.0.0.1,TCP:80", "b"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNamedportSet.Metadata}, "10.0.0.3,udp:53", "c"))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestNestedLabelList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata, TestKeyPodSet.Metadata}))
+	iMgr.clearDirtyCache()
+
+	// the list contains a dirty set, so it's rewritten too
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestKeyPodSet.Metadata}, "10.0.0.4", "d"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNamedportSet.Metadata}, "10.0.0.4,8080", "d"))
+
+	creator := ioutil.NewFileCreator(iMgr.ioShim, maxTryCount)
+	for _, set := range iMgr.nftablesSetsToWrite() {
+		writeNftablesSet(creator, set)
+	}
+	expectedLines := []string{
+		fmt.Sprintf("add set ip azure-npm %s { type ipv4_addr . inet_proto . inet_service ; }", TestNamedportSet.HashedName),
+		fmt.Sprintf("flush set ip azure-npm %s", TestNamedportSet.HashedName),
+		fmt.Sprintf("add element ip azure-npm %s { 10.0.0.1 . tcp . 80, 10.0.0.3 . udp . 53, 10.0.0.4 . tcp . 8080 }", TestNamedportSet.HashedName),
+		fmt.Sprintf("add set ip azure-npm %s { type ipv4_addr ; flags interval ; }", TestNestedLabelList.HashedName),
+		fmt.Sprintf("flush set ip azure-npm %s", TestNestedLabelList.HashedName),
+		fmt.Sprintf("add element ip azure-npm %s { 10.0.0.1-10.0.0.4 }", TestNestedLabelList.HashedName),
+		fmt.Sprintf("add set ip azure-npm %s { type ipv4_addr ; }", TestKeyPodSet.HashedName),
+		fmt.Sprintf("flush set ip azure-npm %s", TestKeyPodSet.HashedName),
+		fmt.Sprintf("add element ip azure-npm %s { 10.0.0.3, 10.0.0.4 }", TestKeyPodSet.HashedName),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, strings.Split(creator.ToString(), "\n"))
+}
+
+func TestApplyNftablesSets(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{Cmd: ipsetRestoreStringSlice},
+		{Cmd: nftablesFileStringSlice},
+		// the set is deleted from the kernel
+		{Cmd: ipsetRestoreStringSlice},
+		{Cmd: nftablesFileStringSlice, ExitCode: 1},
+	}
+	// a rule still refers to the set, so nft fails to delete it on every try
+	for i := 1; i < maxTryCount; i++ {
+		calls = append(calls, testutils.TestCmd{Cmd: nftablesFileStringSlice, ExitCode: 1})
+	}
+	// the delete is retried on the next apply
+	calls = append(calls,
+		testutils.TestCmd{Cmd: ipsetRestoreStringSlice},
+		testutils.TestCmd{Cmd: nftablesFileStringSlice},
+		testutils.TestCmd{Cmd: nftablesFileStringSlice},
+	)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(nftablesCfg, ioshim)
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a"))
+	require.NoError(t, iMgr.ApplyIPSets())
+	require.Equal(t, map[string]string{TestNSSet.HashedName: TestNSSet.PrefixName}, iMgr.nftSets)
+
+	require.NoError(t, iMgr.RemoveFromSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a"))
+	iMgr.DeleteIPSet(TestNSSet.PrefixName, util.SoftDelete)
+	require.NoError(t, iMgr.ApplyIPSets())
+	require.Equal(t, map[string]string{TestNSSet.HashedName: TestNSSet.PrefixName}, iMgr.nftSets)
+
+	// create another set so there's something to apply
+	iMgr.CreateIPSets([]*IPSetMetadata{TestKeyPodSet.Metadata})
+	require.NoError(t, iMgr.ApplyIPSets())
+	require.Equal(t, map[string]string{TestKeyPodSet.HashedName: TestKeyPodSet.PrefixName}, iMgr.nftSets)
+}
+
+func TestReconcileNftablesSets(t *testing.T) {
+	listTables := []string{"nft", "list", "tables", "ip"}
+	calls := []testutils.TestCmd{
+		{Cmd: ipsetRestoreStringSlice},
+		{Cmd: nftablesFileStringSlice},
+		{Cmd: listTables, Stdout: "table ip filter\ntable ip azure-npm\n"},
+		// the table was deleted, so its sets are re-created
+		{Cmd: listTables, Stdout: "table ip filter\n"},
+		{Cmd: nftablesFileStringSlice},
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(nftablesCfg, ioshim)
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a"))
+	require.NoError(t, iMgr.ApplyIPSets())
+
+	iMgr.Reconcile()
+	iMgr.Reconcile()
+	require.Equal(t, map[string]string{TestNSSet.HashedName: TestNSSet.PrefixName}, iMgr.nftSets)
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func GetHNSFake(t *testing.T, networkName string) *hnswrapper.Hnsv2wrapperFake {
+func GetHNSFake(t testing.TB, networkName string) *hnswrapper.Hnsv2wrapperFake {
 	hns := hnswrapper.NewHnsv2wrapperFake()
 	network := &hcn.HostComputeNetwork{
 		Id:   common.FakeHNSNetworkID,
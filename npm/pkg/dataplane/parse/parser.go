@@ -11,12 +11,14 @@ import (
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	NPMIPtable "github.com/Azure/azure-container-networking/npm/pkg/dataplane/iptables"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
-	"k8s.io/klog"
 	utilexec "k8s.io/utils/exec"
 )
 
+var logger = npmlogger.For(npmlogger.Dataplane)
+
 var (
 	// CommitBytes is the string "COMMIT" in bytes array
 	CommitBytes = []byte("COMMIT")
@@ -32,7 +34,7 @@ type IPTablesParser struct {
 
 // runCommand returns (stdout, stderr, error)
 func (i *IPTablesParser) runCommand(command string, args ...string) ([]byte, error) {
-	klog.Infof("Executing iptables command %v %v", command, args)
+	logger.Infof("Executing iptables command %v %v", command, args)
 
 	commandExec := i.IOShim.Exec.Command(command, args...)
 	output, err := commandExec.CombinedOutput()
@@ -99,6 +101,12 @@ func IptablesFile(tableName, iptableSaveFile string) (*NPMIPtable.Table, error)
 	return &NPMIPtable.Table{Name: tableName, Chains: chains}, nil
 }
 
+// IptablesBytes creates a Go object from specified iptable of the output of iptables-save.
+func IptablesBytes(tableName string, iptablesSave []byte) *NPMIPtable.Table {
+	chains := parseIptablesChainObject(tableName, iptablesSave)
+	return &NPMIPtable.Table{Name: tableName, Chains: chains}
+}
+
 // parseIptablesChainObject creates a map of iptable chain name and iptable chain object.
 // There are some unimplemented flags but they should not affect the current desired functionalities.
 func parseIptablesChainObject(tableName string, iptableBuffer []byte) map[string]*NPMIPtable.Chain {
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -13,7 +14,6 @@ import (
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
 	"github.com/Azure/azure-container-networking/npm/util/ioutil"
-	"k8s.io/klog"
 	utilexec "k8s.io/utils/exec"
 )
 
@@ -34,6 +34,13 @@ var (
 		util.IptablesAzureEgressChain,
 		util.IptablesAzureAcceptChain,
 	}
+	// Chains for the AdminNetworkPolicy and BaselineAdminNetworkPolicy tiers. Only created for iptables with EnableAdminNetworkPolicy.
+	iptablesAzureTierChains = []string{
+		util.IptablesAzureIngressAdminChain,
+		util.IptablesAzureEgressAdminChain,
+		util.IptablesAzureIngressBaselineChain,
+		util.IptablesAzureEgressBaselineChain,
+	}
 	// Should not be used directly. Initialized from iptablesAzureChains on first use of isAzureChain().
 	iptablesAzureChainsMap map[string]struct{}
 
@@ -74,6 +81,26 @@ var (
 		},
 	}
 
+	// tierJumps are the jumps to the tier chains, which are added at bootup with EnableAdminNetworkPolicy.
+	tierJumps = [][]string{
+		{util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesAzureIngressAdminChain},
+		{util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesAzureIngressBaselineChain},
+		{util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureEgressAdminChain},
+		{util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureEgressBaselineChain},
+	}
+
+	checkTierJumpIgnoredErrors = []*exitErrorInfo{
+		{
+			exitCode:     doesNotExistErrorCode,
+			messageToLog: "jump to tier chain doesn't exist",
+		},
+		{
+			// couldntLoadTargetErrorCode happens when the tier chain doesn't exist (and hence the jump rule doesn't exist too)
+			exitCode:     couldntLoadTargetErrorCode,
+			messageToLog: "jump to tier chain doesn't exist because the tier chain doesn't exist",
+		},
+	}
+
 	listForwardEntriesArgs = []string{
 		util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesTableFlag, util.IptablesFilterTable,
 		util.IptablesNumericFlag, util.IptablesListFlag, util.IptablesForwardChain, util.IptablesLineNumbersFlag,
@@ -143,6 +170,19 @@ func (s *staleChains) emptyAndGetAll() []string {
 	return result
 }
 
+// GetStaleChains returns the chains of removed policies which have yet to be deleted, sorted by name.
+func (pMgr *PolicyManager) GetStaleChains() []string {
+	pMgr.reconcileManager.forceLock()
+	defer pMgr.reconcileManager.forceUnlock()
+
+	chains := make([]string, 0, len(pMgr.staleChains.chainsToCleanup))
+	for chain := range pMgr.staleChains.chainsToCleanup {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+	return chains
+}
+
 func (s *staleChains) empty() {
 	s.chainsToCleanup = make(map[string]struct{})
 }
@@ -179,15 +219,22 @@ TODO: could use one grep call instead of separate calls for getting jump line nu
   - would use a grep pattern like so: <line num...AZURE-NPM>|<Chain AZURE-NPM>
 */
 func (pMgr *PolicyManager) bootup(_ []string) error {
-	klog.Infof("booting up iptables Azure chains")
+	if pMgr.UseNftables {
+		return pMgr.bootupNftables()
+	}
+
+	logger.Infof("booting up iptables Azure chains")
 
 	// Stop reconciling so we don't contend for iptables, and so we don't update the staleChains at the same time as reconcile()
 	// Reconciling would only be happening if this function were called to reset iptables well into the azure-npm pod lifecycle.
 	pMgr.reconcileManager.forceLock()
 	defer pMgr.reconcileManager.forceUnlock()
 
+	// the nftables table would still enforce old policies if NPM used nftables before
+	pMgr.deleteNftablesTable()
+
 	if strings.Contains(util.Iptables, "nft") {
-		klog.Info("detected nft iptables. cleaning up legacy iptables")
+		logger.Info("detected nft iptables. cleaning up legacy iptables")
 		util.Iptables = util.IptablesLegacy
 		util.IptablesSave = util.IptablesSaveLegacy
 		util.IptablesRestore = util.IptablesRestoreLegacy
@@ -195,7 +242,7 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 		// 0. delete the deprecated jump to deprecated AZURE-NPM in legacy iptables
 		deprecatedErrCode, deprecatedErr := pMgr.ignoreErrorsAndRunIPTablesCommand(removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, deprecatedJumpFromForwardToAzureChainArgs...)
 		if deprecatedErrCode == 0 {
-			klog.Infof("deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
+			logger.Infof("deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
 		} else if deprecatedErr != nil {
 			metrics.SendErrorLogAndMetric(util.IptmID,
 				"failed to delete deprecated jump rule from FORWARD chain to AZURE-NPM chain for unexpected reason with exit code %d and error: %s",
@@ -205,7 +252,7 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 		// 0. delete the deprecated jump to current AZURE-NPM in legacy iptables
 		deprecatedErrCode, deprecatedErr = pMgr.ignoreErrorsAndRunIPTablesCommand(removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...)
 		if deprecatedErrCode == 0 {
-			klog.Infof("deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
+			logger.Infof("deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
 		} else if deprecatedErr != nil {
 			metrics.SendErrorLogAndMetric(util.IptmID,
 				"failed to delete deprecated jump rule from FORWARD chain to AZURE-NPM chain for unexpected reason with exit code %d and error: %s",
@@ -260,12 +307,12 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 		util.IptablesRestore = util.IptablesRestoreNft
 	}
 
-	klog.Info("cleaning up default iptables")
+	logger.Info("cleaning up default iptables")
 
 	// 1. delete the deprecated jump to AZURE-NPM
 	deprecatedErrCode, deprecatedErr := pMgr.ignoreErrorsAndRunIPTablesCommand(removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, deprecatedJumpFromForwardToAzureChainArgs...)
 	if deprecatedErrCode == 0 {
-		klog.Infof("deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
+		logger.Infof("deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
 	} else if deprecatedErr != nil {
 		metrics.SendErrorLogAndMetric(util.IptmID,
 			"failed to delete deprecated jump rule from FORWARD chain to AZURE-NPM chain for unexpected reason with exit code %d and error: %s",
@@ -277,7 +324,7 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 		return npmerrors.SimpleErrorWrapper("failed to get current chains for bootup", err)
 	}
 
-	klog.Infof("found %d current chains in the default iptables", len(currentChains))
+	logger.Infof("found %d current chains in the default iptables", len(currentChains))
 
 	// 2. cleanup old NPM chains, and configure base chains and their rules.
 	creator := pMgr.creatorForBootup(currentChains)
@@ -286,7 +333,7 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 	}
 
 	// 3. add/reposition the jump to AZURE-NPM
-	if err := pMgr.positionAzureChainJumpRule(); err != nil {
+	if _, err := pMgr.positionAzureChainJumpRule(); err != nil {
 		baseErrString := "failed to add/reposition jump from FORWARD chain to AZURE-NPM chain"
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error: %s", baseErrString, err.Error())
 		return npmerrors.SimpleErrorWrapper(baseErrString, err) // we used to ignore this error in v1
@@ -296,12 +343,27 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 
 // reconcile does the following:
 // - creates the jump rule from FORWARD chain to AZURE-NPM chain (if it does not exist) and makes sure it's after the jumps to KUBE-FORWARD & KUBE-SERVICES chains (if they exist).
+// - re-creates the chains of cached policies which were deleted from iptables, e.g. by another component.
 // - cleans up stale policy chains. It can be forced to stop this process if reconcileManager.forceLock() is called.
+// Re-inserted jumps and chains are counted in the iptables repair metric.
 func (pMgr *PolicyManager) reconcile() {
-	if err := pMgr.positionAzureChainJumpRule(); err != nil {
+	if pMgr.UseNftables {
+		pMgr.reconcileNftables()
+		return
+	}
+
+	if inserted, err := pMgr.positionAzureChainJumpRule(); err != nil {
 		msg := fmt.Sprintf("failed to reconcile jump rule to Azure-NPM due to %s", err.Error())
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
-		klog.Error(msg)
+		logger.Error(msg)
+	} else if inserted {
+		metrics.IncIPTablesRepairs(metrics.ForwardJumpRepair, 1)
+	}
+
+	if err := pMgr.repairPolicyChains(); err != nil {
+		msg := fmt.Sprintf("failed to reconcile policy chains due to %s", err.Error())
+		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
+		logger.Error(msg)
 	}
 
 	pMgr.reconcileManager.Lock()
@@ -312,14 +374,189 @@ func (pMgr *PolicyManager) reconcile() {
 		return
 	}
 
-	klog.Infof("cleaning up these stale chains: %+v", staleChains)
+	logger.Infof("cleaning up these stale chains: %+v", staleChains)
 	if err := pMgr.cleanupChains(staleChains); err != nil {
 		msg := fmt.Sprintf("failed to clean up old policy chains with the following error: %s", err.Error())
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
-		klog.Error(msg)
+		logger.Error(msg)
 	}
 }
 
+// repairPolicyChains re-creates the chains of the cached policies which are missing from iptables, along with their rules
+// and the jumps to them. With EnableAdminNetworkPolicy, it also re-creates the tier chains and the jumps to them.
+func (pMgr *PolicyManager) repairPolicyChains() error {
+	// lock the cache before the reconcileManager, like AddPolicies and RemovePolicy, so that policies aren't re-created while they're removed
+	pMgr.policyMap.RLock()
+	defer pMgr.policyMap.RUnlock()
+	if len(pMgr.policyMap.cache) == 0 && !pMgr.EnableAdminNetworkPolicy {
+		return nil
+	}
+
+	pMgr.reconcileManager.Lock()
+	defer pMgr.reconcileManager.Unlock()
+
+	currentChains, err := ioutil.AllCurrentAzureChains(pMgr.ioShim.Exec, util.IptablesDefaultWaitTime)
+	if err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to get current chains", err)
+	}
+
+	policiesToRepair, numMissingChains := pMgr.policiesWithMissingChains(currentChains)
+	if pMgr.EnableAdminNetworkPolicy {
+		missingTierChains, numMissingTierJumps, err := pMgr.missingTierChainsAndJumps(currentChains)
+		if err != nil {
+			return err
+		}
+		if len(missingTierChains) > 0 || numMissingTierJumps > 0 {
+			return pMgr.repairTierChains(missingTierChains, numMissingTierJumps, policiesToRepair, numMissingChains)
+		}
+	}
+	if len(policiesToRepair) == 0 {
+		return nil
+	}
+
+	logger.Infof("re-creating %d missing chains of %d policies", numMissingChains, len(policiesToRepair))
+	for _, policy := range policiesToRepair {
+		// the jumps to the chains which still exist are added again below, so they must not be duplicated
+		if err := pMgr.deleteOldJumpRulesOnRemove(policy); err != nil {
+			return npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to delete jumps to chains of policy %s", policy.PolicyKey), err)
+		}
+	}
+
+	chainsToCreate := chainNames(policiesToRepair)
+	creator := pMgr.creatorForNewNetworkPolicies(chainsToCreate, policiesToRepair)
+	timer := metrics.StartNewTimer()
+	err = restore(creator)
+	metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
+	if err != nil {
+		metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
+		return npmerrors.SimpleErrorWrapper("failed to restore iptables with missing policy chains", err)
+	}
+
+	for _, chain := range chainsToCreate {
+		pMgr.staleChains.remove(chain)
+	}
+	metrics.IncIPTablesRepairs(metrics.PolicyChainRepair, numMissingChains)
+	return nil
+}
+
+// missingTierChainsAndJumps returns the tier chains which aren't in currentChains, and the number of missing jumps to
+// the tier chains.
+func (pMgr *PolicyManager) missingTierChainsAndJumps(currentChains map[string]struct{}) ([]string, int, error) {
+	var missingChains []string
+	for _, chain := range iptablesAzureTierChains {
+		if _, ok := currentChains[chain]; !ok {
+			missingChains = append(missingChains, chain)
+		}
+	}
+
+	numMissingJumps := 0
+	for _, jump := range tierJumps {
+		errCode, err := pMgr.ignoreErrorsAndRunIPTablesCommand(checkTierJumpIgnoredErrors, util.IptablesCheckFlag, jump...)
+		if err != nil {
+			return nil, 0, npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to check jump from %s to %s", jump[0], jump[2]), err)
+		}
+		if errCode != 0 {
+			numMissingJumps++
+		}
+	}
+	return missingChains, numMissingJumps, nil
+}
+
+// repairTierChains re-creates the missing tier chains and the missing chains of the policies to repair. Since a jump to a
+// tier chain must stay before or after all jumps to NetworkPolicy chains, the AZURE-NPM-INGRESS and AZURE-NPM-EGRESS
+// chains are rewritten with the jumps to the chains of all cached policies, and the tier chains with the rules of all
+// cached tiered policies.
+func (pMgr *PolicyManager) repairTierChains(missingTierChains []string, numMissingTierJumps int, policiesToRepair []*NPMNetworkPolicy, numMissingChains int) error {
+	logger.Infof("re-creating %d missing tier chains, %d missing jumps to tier chains, and %d missing chains of %d policies",
+		len(missingTierChains), numMissingTierJumps, numMissingChains, len(policiesToRepair))
+
+	chainsToCreate := append(chainNames(policiesToRepair), missingTierChains...)
+	creator := pMgr.creatorForTierRepair(chainsToCreate, policiesToRepair)
+	timer := metrics.StartNewTimer()
+	err := restore(creator)
+	metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
+	if err != nil {
+		metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
+		return npmerrors.SimpleErrorWrapper("failed to restore iptables with missing tier chains and jumps", err)
+	}
+
+	for _, chain := range chainsToCreate {
+		pMgr.staleChains.remove(chain)
+	}
+	if numMissingChains > 0 {
+		metrics.IncIPTablesRepairs(metrics.PolicyChainRepair, numMissingChains)
+	}
+	metrics.IncIPTablesRepairs(metrics.TierRepair, len(missingTierChains)+numMissingTierJumps)
+	return nil
+}
+
+// creatorForTierRepair writes the restore file for repairTierChains. The cache must be locked.
+func (pMgr *PolicyManager) creatorForTierRepair(chainsToCreate []string, policiesToRepair []*NPMNetworkPolicy) *ioutil.FileCreator {
+	creator := pMgr.newCreatorWithChains(chainsToCreate)
+	for _, networkPolicy := range policiesToRepair {
+		writeNetworkPolicyRules(creator, networkPolicy)
+	}
+
+	creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureIngressChain)
+	pMgr.writeIngressChainRules(creator)
+	creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureEgressChain)
+	pMgr.writeEgressChainRules(creator)
+
+	keys := make([]string, 0, len(pMgr.policyMap.cache))
+	for key := range pMgr.policyMap.cache {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	ingressJumpLineNumber := pMgr.firstPolicyJumpLineNumber()
+	egressJumpLineNumber := pMgr.firstPolicyJumpLineNumber()
+	tierPolicies := map[PolicyTier][]*NPMNetworkPolicy{AdminTier: {}, BaselineTier: {}}
+	for _, key := range keys {
+		networkPolicy := pMgr.policyMap.cache[key]
+		if networkPolicy.IsTiered() {
+			tierPolicies[networkPolicy.Tier] = append(tierPolicies[networkPolicy.Tier], networkPolicy)
+			continue
+		}
+		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
+		if hasIngress {
+			creator.AddLine("", nil, insertSpecs(util.IptablesAzureIngressChain, ingressJumpLineNumber, ingressJumpSpecs(networkPolicy))...)
+			ingressJumpLineNumber++
+		}
+		if hasEgress {
+			creator.AddLine("", nil, insertSpecs(util.IptablesAzureEgressChain, egressJumpLineNumber, egressJumpSpecs(networkPolicy))...)
+			egressJumpLineNumber++
+		}
+	}
+
+	for _, tier := range []PolicyTier{AdminTier, BaselineTier} {
+		writeTierRules(creator, tier, tierPolicies[tier])
+	}
+	creator.AddLine("", nil, util.IptablesRestoreCommit)
+	return creator
+}
+
+// policiesWithMissingChains returns the cached policies, sorted by key, with a chain which isn't in currentChains, and
+// the number of missing chains.
+func (pMgr *PolicyManager) policiesWithMissingChains(currentChains map[string]struct{}) ([]*NPMNetworkPolicy, int) {
+	var policies []*NPMNetworkPolicy
+	numMissingChains := 0
+	for _, policy := range pMgr.policyMap.cache {
+		missing := 0
+		for _, chain := range chainNames([]*NPMNetworkPolicy{policy}) {
+			if _, ok := currentChains[chain]; !ok {
+				missing++
+			}
+		}
+		if missing > 0 {
+			policies = append(policies, policy)
+			numMissingChains += missing
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].PolicyKey < policies[j].PolicyKey
+	})
+	return policies, numMissingChains
+}
+
 // cleanupChains deletes all the chains in the given list.
 // If a chain fails to delete and it isn't one of the iptablesAzureChains, then it is added to the staleChains.
 // This is a separate function for with a slice argument so that UTs can have deterministic behavior for ioshim.
@@ -363,7 +600,7 @@ func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErro
 	allArgs := []string{util.IptablesWaitFlag, util.IptablesDefaultWaitTime, operationFlag}
 	allArgs = append(allArgs, args...)
 
-	klog.Infof("Executing iptables command with args %v", allArgs)
+	logger.Infof("Executing iptables command with args %v", allArgs)
 
 	command := pMgr.ioShim.Exec.Command(util.Iptables, allArgs...)
 	output, err := command.CombinedOutput()
@@ -375,7 +612,7 @@ func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErro
 		outputString := strings.TrimSuffix(string(output), "\n")
 		for _, info := range ignored {
 			if errCode == info.exitCode && strings.Contains(outputString, info.stdErr) {
-				klog.Infof("%s. not able to run iptables command [%s %s]. exit code: %d, output: %s", info.messageToLog, util.Iptables, allArgsString, errCode, outputString)
+				logger.Infof("%s. not able to run iptables command [%s %s]. exit code: %d, output: %s", info.messageToLog, util.Iptables, allArgsString, errCode, outputString)
 				return errCode, nil
 			}
 		}
@@ -390,8 +627,8 @@ func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErro
 // Writes the restore file for bootup, and marks the following as stale: deprecated chains and old v2 policy chains.
 // This is a separate function to help with UTs.
 func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *ioutil.FileCreator {
-	chainsToCreate := make([]string, 0, len(iptablesAzureChains))
-	for _, chain := range iptablesAzureChains {
+	chainsToCreate := make([]string, 0, len(iptablesAzureChains)+len(iptablesAzureTierChains))
+	for _, chain := range pMgr.baseChains() {
 		_, exists := currentChains[chain]
 		if !exists {
 			chainsToCreate = append(chainsToCreate, chain)
@@ -404,15 +641,15 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 	pMgr.staleChains.empty()
 	for chain := range currentChains {
 		creator.AddLine("", nil, fmt.Sprintf("-F %s", chain))
+		if pMgr.EnableAdminNetworkPolicy && isTierChain(chain) {
+			continue
+		}
 		// Step 2.2 in bootup() comment: delete deprecated chains and old v2 policy chains in the background
 		pMgr.staleChains.add(chain) // won't add base chains
 	}
 
 	// add AZURE-NPM-INGRESS chain rules
-	ingressDropSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesDrop}
-	ingressDropSpecs = append(ingressDropSpecs, onMarkSpecs(util.IptablesAzureIngressDropMarkHex)...)
-	ingressDropSpecs = append(ingressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-INGRESS-DROP-MARK-%s", util.IptablesAzureIngressDropMarkHex))...)
-	creator.AddLine("", nil, ingressDropSpecs...)
+	pMgr.writeIngressChainRules(creator)
 
 	// add AZURE-NPM-INGRESS-ALLOW-MARK chain
 	markIngressAllowSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain}
@@ -422,37 +659,96 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain)
 
 	// add AZURE-NPM-EGRESS chain rules
+	pMgr.writeEgressChainRules(creator)
+
+	// add AZURE-NPM-ACCEPT chain rules
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureAcceptChain, util.IptablesJumpFlag, util.IptablesAccept)
+	creator.AddLine("", nil, util.IptablesRestoreCommit)
+	return creator
+}
+
+// baseChains returns the chains created at bootup, which include the tier chains with EnableAdminNetworkPolicy.
+func (pMgr *PolicyManager) baseChains() []string {
+	if !pMgr.EnableAdminNetworkPolicy {
+		return iptablesAzureChains
+	}
+	chains := make([]string, 0, len(iptablesAzureChains)+len(iptablesAzureTierChains))
+	chains = append(chains, iptablesAzureChains...)
+	return append(chains, iptablesAzureTierChains...)
+}
+
+func isTierChain(chain string) bool {
+	for _, tierChain := range iptablesAzureTierChains {
+		if chain == tierChain {
+			return true
+		}
+	}
+	return false
+}
+
+// writeIngressChainRules appends the base rules of the AZURE-NPM-INGRESS chain.
+// The jumps to the NetworkPolicy chains are inserted before the drop rule, at firstPolicyJumpLineNumber().
+func (pMgr *PolicyManager) writeIngressChainRules(creator *ioutil.FileCreator) {
+	if pMgr.EnableAdminNetworkPolicy {
+		// the admin tier is evaluated before the jumps to NetworkPolicy chains
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesAzureIngressAdminChain)
+	}
+	ingressDropSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesDrop}
+	ingressDropSpecs = append(ingressDropSpecs, onMarkSpecs(util.IptablesAzureIngressDropMarkHex)...)
+	ingressDropSpecs = append(ingressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-INGRESS-DROP-MARK-%s", util.IptablesAzureIngressDropMarkHex))...)
+	creator.AddLine("", nil, ingressDropSpecs...)
+	if pMgr.EnableAdminNetworkPolicy {
+		// the baseline tier is evaluated after the NetworkPolicies had a chance to isolate the pod
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesAzureIngressBaselineChain)
+	}
+}
+
+// writeEgressChainRules appends the base rules of the AZURE-NPM-EGRESS chain.
+// The jumps to the NetworkPolicy chains are inserted before the drop rule, at firstPolicyJumpLineNumber().
+func (pMgr *PolicyManager) writeEgressChainRules(creator *ioutil.FileCreator) {
+	if pMgr.EnableAdminNetworkPolicy {
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureEgressAdminChain)
+	}
 	egressDropSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesDrop}
 	egressDropSpecs = append(egressDropSpecs, onMarkSpecs(util.IptablesAzureEgressDropMarkHex)...)
 	egressDropSpecs = append(egressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-EGRESS-DROP-MARK-%s", util.IptablesAzureEgressDropMarkHex))...)
 	creator.AddLine("", nil, egressDropSpecs...)
+	if pMgr.EnableAdminNetworkPolicy {
+		// the baseline tier also decides egress of flows whose ingress was allowed
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureEgressBaselineChain)
+	}
 
 	jumpOnIngressMatchSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain}
 	jumpOnIngressMatchSpecs = append(jumpOnIngressMatchSpecs, onMarkSpecs(util.IptablesAzureIngressAllowMarkHex)...)
 	jumpOnIngressMatchSpecs = append(jumpOnIngressMatchSpecs, commentSpecs(fmt.Sprintf("ACCEPT-ON-INGRESS-ALLOW-MARK-%s", util.IptablesAzureIngressAllowMarkHex))...)
 	creator.AddLine("", nil, jumpOnIngressMatchSpecs...)
+}
 
-	// add AZURE-NPM-ACCEPT chain rules
-	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureAcceptChain, util.IptablesJumpFlag, util.IptablesAccept)
-	creator.AddLine("", nil, util.IptablesRestoreCommit)
-	return creator
+// firstPolicyJumpLineNumber is the line of the first jump to a NetworkPolicy chain in the AZURE-NPM-INGRESS and
+// AZURE-NPM-EGRESS chains, after the jump to the admin tier chain with EnableAdminNetworkPolicy.
+func (pMgr *PolicyManager) firstPolicyJumpLineNumber() int {
+	if pMgr.EnableAdminNetworkPolicy {
+		return 2
+	}
+	return 1
 }
 
 // add/reposition the jump from FORWARD chain to AZURE-NPM chain to be in the correct position based on config:
 // option 1) jump to AZURE-NPM chain should be the first rule
 // option 2) jump to AZURE-NPM chain should be after the jump to KUBE-SERVICES chain
-func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
+// Returns true if the jump was (re-)inserted because it was missing or in the wrong position.
+func (pMgr *PolicyManager) positionAzureChainJumpRule() (bool, error) {
 	// get the line number for the azure jump
 	azureChainLineNum, err := pMgr.chainLineNumber(util.IptablesAzureChain)
 	if err != nil {
 		baseErrString := "failed to get index of jump from FORWARD chain to AZURE-NPM chain"
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s: %s", baseErrString, err.Error())
-		return npmerrors.SimpleErrorWrapper(baseErrString, err)
+		return false, npmerrors.SimpleErrorWrapper(baseErrString, err)
 	}
 
 	if pMgr.PlaceAzureChainFirst == util.PlaceAzureChainFirst && azureChainLineNum == 1 {
 		// the azure jump is in the right position, so we're done
-		return nil
+		return false, nil
 	}
 
 	// place the azure jump in the first position, unless we want option 2 above and the kube jump exists
@@ -462,7 +758,7 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 		if err != nil {
 			baseErrString := "failed to get index of jump from FORWARD chain to KUBE-SERVICES chain"
 			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s: %s", baseErrString, err.Error())
-			return npmerrors.SimpleErrorWrapper(baseErrString, err)
+			return false, npmerrors.SimpleErrorWrapper(baseErrString, err)
 		}
 
 		if kubeChainLineNum != 0 {
@@ -474,7 +770,7 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 
 	if azureChainLineNum == targetIndex {
 		// the azure jump is in the right position, so we're done
-		return nil
+		return false, nil
 	}
 
 	// delete the azure jump if it exists and update the target index
@@ -483,7 +779,7 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 		if deleteErrCode, deleteErr := pMgr.runIPTablesCommand(util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...); deleteErr != nil {
 			baseErrString := "failed to delete jump from FORWARD chain to AZURE-NPM chain"
 			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error code %d and error %s", baseErrString, deleteErrCode, deleteErr.Error())
-			return npmerrors.SimpleErrorWrapper(baseErrString, deleteErr)
+			return false, npmerrors.SimpleErrorWrapper(baseErrString, deleteErr)
 		}
 
 		if azureChainLineNum < targetIndex {
@@ -495,7 +791,7 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 	}
 
 	// add (back) the azure jump
-	klog.Infof("Inserting jump from FORWARD chain to AZURE-NPM chain")
+	logger.Infof("Inserting jump from FORWARD chain to AZURE-NPM chain")
 	var args []string
 	if targetIndex == 1 {
 		// when no index is provided, index of 1 is implied
@@ -507,9 +803,9 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 	if insertErrCode, err := pMgr.runIPTablesCommand(util.IptablesInsertionFlag, args...); err != nil {
 		baseErrString := "failed to insert jump from FORWARD chain to AZURE-NPM chain"
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error code %d and error %s", baseErrString, insertErrCode, err.Error())
-		return npmerrors.SimpleErrorWrapper(baseErrString, err)
+		return false, npmerrors.SimpleErrorWrapper(baseErrString, err)
 	}
-	return nil
+	return true, nil
 }
 
 // returns 0 if the chain does not exist
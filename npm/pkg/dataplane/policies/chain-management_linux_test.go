@@ -8,6 +8,7 @@ import (
 
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
 	"github.com/Azure/azure-container-networking/npm/util"
 	testutils "github.com/Azure/azure-container-networking/test/utils"
@@ -44,6 +45,7 @@ Chain AZURE-NPM-ACCEPT (1 references)
 func TestBootupFailure(t *testing.T) {
 	metrics.ReinitializeAll()
 	calls := []testutils.TestCmd{
+		fakeDeleteNftablesTableCommand,
 		{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"}, ExitCode: 2}, //nolint // AZURE-NPM chain didn't exist
 		{Cmd: listAllCommandStrings, PipedToCommand: true, HasStartError: true, ExitCode: 1},
 		{Cmd: []string{"grep", "Chain AZURE-NPM"}},
@@ -187,6 +189,149 @@ func TestCleanupChainsFailure(t *testing.T) {
 	assertStaleChainsContain(t, pMgr.staleChains, testChain1, testChain3)
 }
 
+func TestReconcileRepairsIPTables(t *testing.T) {
+	metrics.ReinitializeAll()
+	grepOutputWithIngressChainOnly := grepOutputAzureChainsWithoutPolicies + fmt.Sprintf("Chain %s (1 references)\n", bothDirectionsNetPolIngressChain)
+	grepOutputWithPolicyChains := grepOutputWithIngressChainOnly + fmt.Sprintf("Chain %s (1 references)\n", bothDirectionsNetPolEgressChain)
+
+	calls := GetAddPolicyTestCalls(bothDirectionsNetPol)
+	// 1. the jump to AZURE-NPM was moved below another rule, and the policy's egress chain was deleted
+	calls = append(calls,
+		testutils.TestCmd{Cmd: listLineNumbersCommandStrings, PipedToCommand: true},
+		testutils.TestCmd{Cmd: []string{"grep", "AZURE-NPM"}, Stdout: "2    AZURE-NPM  all  --  0.0.0.0/0            0.0.0.0/0    ..."},
+		testutils.TestCmd{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM", "-m", "conntrack", "--ctstate", "NEW"}},
+		testutils.TestCmd{Cmd: []string{"iptables", "-w", "60", "-I", "FORWARD", "-j", "AZURE-NPM", "-m", "conntrack", "--ctstate", "NEW"}},
+		testutils.TestCmd{Cmd: listAllCommandStrings, PipedToCommand: true},
+		testutils.TestCmd{Cmd: []string{"grep", "Chain AZURE-NPM"}, Stdout: grepOutputWithIngressChainOnly},
+	)
+	repairCalls := GetRemovePolicyTestCalls(bothDirectionsNetPol)
+	repairCalls[1].ExitCode = couldntLoadTargetErrorCode // the egress chain doesn't exist
+	calls = append(calls, repairCalls...)
+	// 2. nothing to repair
+	calls = append(calls,
+		testutils.TestCmd{Cmd: listLineNumbersCommandStrings, PipedToCommand: true},
+		testutils.TestCmd{Cmd: []string{"grep", "AZURE-NPM"}, Stdout: "1    AZURE-NPM  all  --  0.0.0.0/0            0.0.0.0/0    ..."},
+		testutils.TestCmd{Cmd: listAllCommandStrings, PipedToCommand: true},
+		testutils.TestCmd{Cmd: []string{"grep", "Chain AZURE-NPM"}, Stdout: grepOutputWithPolicyChains},
+	)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{bothDirectionsNetPol}, nil))
+
+	pMgr.Reconcile()
+	assertIPTablesRepairs(t, 1, 1)
+
+	pMgr.Reconcile()
+	assertIPTablesRepairs(t, 1, 1)
+}
+
+func TestReconcileRepairsTierChains(t *testing.T) {
+	metrics.ReinitializeAll()
+	grepOutputWithPolicyChains := grepOutputAzureChainsWithoutPolicies +
+		fmt.Sprintf("Chain %s (1 references)\n", bothDirectionsNetPolIngressChain) +
+		fmt.Sprintf("Chain %s (1 references)\n", bothDirectionsNetPolEgressChain) +
+		"Chain AZURE-NPM-INGRESS-ADMIN (1 references)\n" +
+		"Chain AZURE-NPM-EGRESS-ADMIN (1 references)\n" +
+		"Chain AZURE-NPM-INGRESS-BASELINE (1 references)\n"
+	checkTierJumpCalls := func(missingEgressBaselineExitCode int) []testutils.TestCmd {
+		return []testutils.TestCmd{
+			{Cmd: []string{"iptables", "-w", "60", "-C", "AZURE-NPM-INGRESS", "-j", "AZURE-NPM-INGRESS-ADMIN"}},
+			{Cmd: []string{"iptables", "-w", "60", "-C", "AZURE-NPM-INGRESS", "-j", "AZURE-NPM-INGRESS-BASELINE"}},
+			{Cmd: []string{"iptables", "-w", "60", "-C", "AZURE-NPM-EGRESS", "-j", "AZURE-NPM-EGRESS-ADMIN"}},
+			{Cmd: []string{"iptables", "-w", "60", "-C", "AZURE-NPM-EGRESS", "-j", "AZURE-NPM-EGRESS-BASELINE"}, ExitCode: missingEgressBaselineExitCode},
+		}
+	}
+
+	// 1. the egress baseline chain was deleted, and so was the jump to it
+	calls := []testutils.TestCmd{
+		{Cmd: listLineNumbersCommandStrings, PipedToCommand: true},
+		{Cmd: []string{"grep", "AZURE-NPM"}, Stdout: "1    AZURE-NPM  all  --  0.0.0.0/0            0.0.0.0/0    ..."},
+		{Cmd: listAllCommandStrings, PipedToCommand: true},
+		{Cmd: []string{"grep", "Chain AZURE-NPM"}, Stdout: grepOutputWithPolicyChains},
+	}
+	calls = append(calls, checkTierJumpCalls(couldntLoadTargetErrorCode)...)
+	calls = append(calls, fakeIPTablesRestoreCommand)
+	// 2. nothing to repair
+	calls = append(calls,
+		testutils.TestCmd{Cmd: listLineNumbersCommandStrings, PipedToCommand: true},
+		testutils.TestCmd{Cmd: []string{"grep", "AZURE-NPM"}, Stdout: "1    AZURE-NPM  all  --  0.0.0.0/0            0.0.0.0/0    ..."},
+		testutils.TestCmd{Cmd: listAllCommandStrings, PipedToCommand: true},
+		testutils.TestCmd{Cmd: []string{"grep", "Chain AZURE-NPM"}, Stdout: grepOutputWithPolicyChains + "Chain AZURE-NPM-EGRESS-BASELINE (1 references)\n"},
+	)
+	calls = append(calls, checkTierJumpCalls(0)...)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, anpConfig)
+	pMgr.policyMap.cache[bothDirectionsNetPol.PolicyKey] = bothDirectionsNetPol
+
+	pMgr.Reconcile()
+	count, err := metrics.TotalIPTablesRepairs(metrics.TierRepair)
+	require.NoError(t, err)
+	require.Equal(t, 2, count, "wrong number of repaired tier chains and jumps")
+
+	pMgr.Reconcile()
+	count, err = metrics.TotalIPTablesRepairs(metrics.TierRepair)
+	require.NoError(t, err)
+	require.Equal(t, 2, count, "wrong number of repaired tier chains and jumps")
+	assertIPTablesRepairs(t, 0, 0)
+}
+
+func TestCreatorForTierRepair(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), anpConfig)
+	pMgr.policyMap.cache[egressNetPol.PolicyKey] = egressNetPol
+	pMgr.policyMap.cache[ingressNetPol.PolicyKey] = ingressNetPol
+	deny := NewBaselineNPMNetworkPolicy("default")
+	deny.PodSelectorList = []SetInfo{NewSetInfo(ipsets.TestKeyPodSet.Metadata.Name, ipsets.TestKeyPodSet.Metadata.Type, true, EitherMatch)}
+	denyACL := NewACLPolicy(Dropped, Ingress)
+	denyACL.SrcList = []SetInfo{NewSetInfo(ipsets.TestCIDRSet.Metadata.Name, ipsets.TestCIDRSet.Metadata.Type, true, SrcMatch)}
+	denyACL.Protocol = UnspecifiedProtocol
+	deny.ACLs = []*ACLPolicy{denyACL}
+	pMgr.policyMap.cache[deny.PolicyKey] = deny
+
+	// the egress baseline chain and the chain of the ingress policy are missing
+	chainsToCreate := []string{ingressNetPolChain, util.IptablesAzureEgressBaselineChain}
+	creator := pMgr.creatorForTierRepair(chainsToCreate, []*NPMNetworkPolicy{ingressNetPol})
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", ingressNetPolChain),
+		":AZURE-NPM-EGRESS-BASELINE - -",
+		fmt.Sprintf("-A %s %s", ingressNetPolChain, ingressDropRule),
+		"-F AZURE-NPM-INGRESS",
+		"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-ADMIN",
+		"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+		"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-BASELINE",
+		"-F AZURE-NPM-EGRESS",
+		"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-ADMIN",
+		"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+		"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-BASELINE",
+		"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
+		// jumps to the chains of all cached policies, sorted by key, after the jumps to the admin tier chains
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 2 %s", ingressNetPolJump),
+		fmt.Sprintf("-I AZURE-NPM-EGRESS 2 %s", egressNetPolJump),
+		"-F AZURE-NPM-INGRESS-ADMIN",
+		"-F AZURE-NPM-EGRESS-ADMIN",
+		"-F AZURE-NPM-INGRESS-BASELINE",
+		"-F AZURE-NPM-EGRESS-BASELINE",
+		fmt.Sprintf("-A AZURE-NPM-INGRESS-BASELINE -j DROP -m set --match-set %s dst -m set --match-set %s src -m comment --comment BaselineAdminNetworkPolicy/default-DROP-FROM-cidr-test-cidr-set",
+			ipsets.TestKeyPodSet.HashedName, ipsets.TestCIDRSet.HashedName),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func assertIPTablesRepairs(t *testing.T, forwardJumps, policyChains int) {
+	t.Helper()
+	count, err := metrics.TotalIPTablesRepairs(metrics.ForwardJumpRepair)
+	require.NoError(t, err)
+	require.Equal(t, forwardJumps, count, "wrong number of repaired jumps from FORWARD chain")
+	count, err = metrics.TotalIPTablesRepairs(metrics.PolicyChainRepair)
+	require.NoError(t, err)
+	require.Equal(t, policyChains, count, "wrong number of repaired policy chains")
+}
+
 func TestCreatorForBootup(t *testing.T) {
 	v1Chains := []string{
 		"AZURE-NPM-INGRESS-DROPS",
@@ -330,6 +475,109 @@ func TestCreatorForBootup(t *testing.T) {
 	}
 }
 
+func TestCreatorForBootupWithAdminNetworkPolicy(t *testing.T) {
+	tests := []struct {
+		name                string
+		currentChains       []string
+		expectedLines       []string
+		expectedStaleChains []string
+	}{
+		{
+			name:          "no NPM prior",
+			currentChains: []string{},
+			expectedLines: []string{
+				"*filter",
+				":AZURE-NPM - -",
+				":AZURE-NPM-INGRESS - -",
+				":AZURE-NPM-INGRESS-ALLOW-MARK - -",
+				":AZURE-NPM-EGRESS - -",
+				":AZURE-NPM-ACCEPT - -",
+				":AZURE-NPM-INGRESS-ADMIN - -",
+				":AZURE-NPM-EGRESS-ADMIN - -",
+				":AZURE-NPM-INGRESS-BASELINE - -",
+				":AZURE-NPM-EGRESS-BASELINE - -",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-ADMIN",
+				"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-BASELINE",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-ADMIN",
+				"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-BASELINE",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-ACCEPT -j ACCEPT",
+				"COMMIT",
+				"",
+			},
+			expectedStaleChains: []string{},
+		},
+		{
+			name: "NPM v2 existed before with tier chains",
+			currentChains: []string{
+				"AZURE-NPM",
+				"AZURE-NPM-INGRESS",
+				"AZURE-NPM-INGRESS-ALLOW-MARK",
+				"AZURE-NPM-EGRESS",
+				"AZURE-NPM-ACCEPT",
+				"AZURE-NPM-INGRESS-ADMIN",
+				"AZURE-NPM-EGRESS-ADMIN",
+				"AZURE-NPM-INGRESS-BASELINE",
+				"AZURE-NPM-EGRESS-BASELINE",
+			},
+			// the tier chains are flushed but not stale
+			expectedLines: []string{
+				"*filter",
+				"-F AZURE-NPM",
+				"-F AZURE-NPM-INGRESS",
+				"-F AZURE-NPM-INGRESS-ALLOW-MARK",
+				"-F AZURE-NPM-EGRESS",
+				"-F AZURE-NPM-ACCEPT",
+				"-F AZURE-NPM-INGRESS-ADMIN",
+				"-F AZURE-NPM-EGRESS-ADMIN",
+				"-F AZURE-NPM-INGRESS-BASELINE",
+				"-F AZURE-NPM-EGRESS-BASELINE",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-ADMIN",
+				"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-BASELINE",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-ADMIN",
+				"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-BASELINE",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-ACCEPT -j ACCEPT",
+				"COMMIT",
+				"",
+			},
+			expectedStaleChains: []string{},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ioshim := common.NewMockIOShim(nil)
+			defer ioshim.VerifyCalls(t, nil)
+			pMgr := NewPolicyManager(ioshim, anpConfig)
+			creator := pMgr.creatorForBootup(stringsToMap(tt.currentChains))
+			actualLines := strings.Split(creator.ToString(), "\n")
+			sortedActualLines := sortFlushes(actualLines)
+			sortedExpectedLines := sortFlushes(tt.expectedLines)
+			dptestutils.AssertEqualLines(t, sortedExpectedLines, sortedActualLines)
+			assertStaleChainsContain(t, pMgr.staleChains, tt.expectedStaleChains...)
+		})
+	}
+}
+
+func TestCreatorForBootupRemovesTierChainsWhenDisabled(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+	currentChains := append([]string{}, iptablesAzureChains...)
+	currentChains = append(currentChains, iptablesAzureTierChains...)
+	pMgr.creatorForBootup(stringsToMap(currentChains))
+	assertStaleChainsContain(t, pMgr.staleChains, iptablesAzureTierChains...)
+}
+
 func sortFlushes(lines []string) []string {
 	result := make([]string, len(lines))
 	copy(result, lines)
@@ -371,6 +619,7 @@ func TestBootupLinux(t *testing.T) {
 		{
 			name: "success after restore failure (no NPM prior)",
 			calls: []testutils.TestCmd{
+				fakeDeleteNftablesTableCommand,
 				{
 					Cmd:      []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"},
 					ExitCode: 2,
@@ -389,6 +638,7 @@ func TestBootupLinux(t *testing.T) {
 		{
 			name: "success: v2 existed prior",
 			calls: []testutils.TestCmd{
+				fakeDeleteNftablesTableCommand,
 				{
 					Cmd:      []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"},
 					ExitCode: 1,
@@ -409,6 +659,7 @@ func TestBootupLinux(t *testing.T) {
 		{
 			name: "v1 existed prior: successfully delete deprecated jump",
 			calls: []testutils.TestCmd{
+				fakeDeleteNftablesTableCommand,
 				{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"}}, // deprecated rule existed
 				{Cmd: listAllCommandStrings, PipedToCommand: true},
 				{
@@ -425,6 +676,7 @@ func TestBootupLinux(t *testing.T) {
 		{
 			name: "v1 existed prior: unknown error while deleting deprecated jump",
 			calls: []testutils.TestCmd{
+				fakeDeleteNftablesTableCommand,
 				{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"}, ExitCode: 3}, // unknown error
 				{Cmd: listAllCommandStrings, PipedToCommand: true},
 				{
@@ -441,6 +693,7 @@ func TestBootupLinux(t *testing.T) {
 		{
 			name: "failure while finding current chains (no NPM prior)",
 			calls: []testutils.TestCmd{
+				fakeDeleteNftablesTableCommand,
 				{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"}, ExitCode: 2}, // AZURE-NPM chain didn't exist
 				{Cmd: listAllCommandStrings, PipedToCommand: true, HasStartError: true, ExitCode: 1},
 				{Cmd: []string{"grep", "Chain AZURE-NPM"}},
@@ -450,6 +703,7 @@ func TestBootupLinux(t *testing.T) {
 		{
 			name: "failure twice on restore (no NPM prior)",
 			calls: []testutils.TestCmd{
+				fakeDeleteNftablesTableCommand,
 				{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"}, ExitCode: 2}, // AZURE-NPM chain didn't exist
 				{Cmd: listAllCommandStrings, PipedToCommand: true},
 				{Cmd: []string{"grep", "Chain AZURE-NPM"}, ExitCode: 1},
@@ -461,6 +715,7 @@ func TestBootupLinux(t *testing.T) {
 		{
 			name: "failure on position (no NPM prior)",
 			calls: []testutils.TestCmd{
+				fakeDeleteNftablesTableCommand,
 				{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"}, ExitCode: 2}, // AZURE-NPM chain didn't exist
 				{Cmd: listAllCommandStrings, PipedToCommand: true},
 				{
@@ -500,6 +755,7 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 		calls                []testutils.TestCmd
 		placeAzureChainFirst bool
 		wantErr              bool
+		wantInserted         bool
 	}{
 		{
 			name: "place first: no jump rule yet",
@@ -510,6 +766,7 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 			},
 			placeAzureChainFirst: util.PlaceAzureChainFirst,
 			wantErr:              false,
+			wantInserted:         true,
 		},
 		{
 			name: "place first: no jump rule yet and insert fails",
@@ -555,6 +812,7 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 			},
 			placeAzureChainFirst: util.PlaceAzureChainFirst,
 			wantErr:              false,
+			wantInserted:         true,
 		},
 		{
 			name: "place first: jump rule not at top and delete fails",
@@ -594,6 +852,7 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 			},
 			placeAzureChainFirst: util.PlaceAzureChainAfterKubeServices,
 			wantErr:              false,
+			wantInserted:         true,
 		},
 		{
 			name: "after kube: no azure jump rule yet and kube jump rule exists",
@@ -609,6 +868,7 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 			},
 			placeAzureChainFirst: util.PlaceAzureChainAfterKubeServices,
 			wantErr:              false,
+			wantInserted:         true,
 		},
 		{
 			name: "after kube: only azure jump rule exists and the position is correct",
@@ -625,6 +885,7 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 			},
 			placeAzureChainFirst: util.PlaceAzureChainAfterKubeServices,
 			wantErr:              false,
+			wantInserted:         true,
 		},
 		{
 			name: "after kube: only azure jump rule exists and the position is wrong",
@@ -641,6 +902,7 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 			},
 			placeAzureChainFirst: util.PlaceAzureChainAfterKubeServices,
 			wantErr:              false,
+			wantInserted:         true,
 		},
 		{
 			name: "after kube: both jumps exist and positions are correct",
@@ -677,6 +939,7 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 			},
 			placeAzureChainFirst: util.PlaceAzureChainAfterKubeServices,
 			wantErr:              false,
+			wantInserted:         true,
 		},
 		{
 			name: "after kube: both jumps exist and the azure jump is above the kube jump",
@@ -696,6 +959,7 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 			},
 			placeAzureChainFirst: util.PlaceAzureChainAfterKubeServices,
 			wantErr:              false,
+			wantInserted:         true,
 		},
 		{
 			name: "after kube: error getting kube jump line number",
@@ -772,12 +1036,13 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 				PlaceAzureChainFirst: tt.placeAzureChainFirst,
 			}
 			pMgr := NewPolicyManager(ioshim, cfg)
-			err := pMgr.positionAzureChainJumpRule()
+			inserted, err := pMgr.positionAzureChainJumpRule()
 			if tt.wantErr {
 				require.Error(t, err)
 			} else {
 				require.NoError(t, err)
 			}
+			require.Equal(t, tt.wantInserted, inserted)
 		})
 	}
 }
@@ -0,0 +1,452 @@
+package policies
+
+// This file contains code for the nftables implementation of adding/removing policies, used with UseNftables.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+)
+
+/*
+nftables backend:
+NPM programs its own table (ip azure-npm) with the same chains, rules, and marks as in iptables.
+The ipsets referenced by rules are nftables sets in the same table, which are kept in sync by the IPSetManager.
+
+The FORWARD base chain of the table jumps to AZURE-NPM for new connections. Its priority places it just before
+the iptables filter table (PlaceAzureChainFirst) or just after it. Unlike iptables, accepting a packet in this table
+doesn't skip the iptables FORWARD chain (and vice versa).
+
+Every add/remove is a single nft transaction which rewrites the jumps in AZURE-NPM-INGRESS/AZURE-NPM-EGRESS,
+so policy chains are deleted right away instead of being cleaned up in the background like in iptables.
+
+If the table is deleted, e.g. by another component, the IPSetManager re-creates it with its sets while reconciling,
+then the PolicyManager re-creates the base chains and the chains of the cached policies.
+*/
+
+const (
+	// the iptables filter table hooks into forward with priority 0
+	nftablesPriorityBeforeIPTables = "-1"
+	nftablesPriorityAfterIPTables  = "1"
+
+	maxNftablesCommentLength = 128
+)
+
+var nftablesMatchStrings = map[MatchType]string{
+	SrcMatch:    "ip saddr",
+	DstMatch:    "ip daddr",
+	DstDstMatch: "ip daddr . meta l4proto . th dport",
+}
+
+func (pMgr *PolicyManager) bootupNftables() error {
+	logger.Infof("booting up nftables table %s", util.NftablesAzureTable)
+
+	// Stop reconciling so that reconcile() doesn't check the table while it's recreated.
+	pMgr.reconcileManager.forceLock()
+	defer pMgr.reconcileManager.forceUnlock()
+
+	// 1. delete the jumps to AZURE-NPM in iptables in case NPM used iptables before
+	for _, args := range [][]string{deprecatedJumpFromForwardToAzureChainArgs, jumpFromForwardToAzureChainArgs} {
+		errCode, err := pMgr.ignoreErrorsAndRunIPTablesCommand(removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, args...)
+		if errCode == 0 {
+			logger.Infof("deleted jump rule from FORWARD chain to AZURE-NPM chain in iptables")
+		} else if err != nil {
+			metrics.SendErrorLogAndMetric(util.IptmID,
+				"failed to delete jump rule from FORWARD chain to AZURE-NPM chain in iptables for unexpected reason with exit code %d and error: %s",
+				errCode, err.Error())
+		}
+	}
+
+	// 2. recreate the table with its base chains and their rules
+	creator := pMgr.creatorForNftablesBootup()
+	if err := restoreNftables(creator); err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to run nft for bootup", err)
+	}
+	pMgr.nftJumpOrder = nil
+	return nil
+}
+
+// deleteNftablesTable deletes the nftables table in case NPM used nftables before. The table usually doesn't exist.
+func (pMgr *PolicyManager) deleteNftablesTable() {
+	command := pMgr.ioShim.Exec.Command(util.Nftables, util.NftablesSpecs(util.NftablesDeleteFlag, util.NftablesTableObject)...)
+	output, err := command.CombinedOutput()
+	if err != nil {
+		logger.Infof("didn't delete nftables table %s likely because it doesn't exist. err: %v. output: %s", util.NftablesAzureTable, err, strings.TrimSpace(string(output)))
+		return
+	}
+	logger.Infof("deleted nftables table %s", util.NftablesAzureTable)
+}
+
+// reconcileNftables re-creates the base chains and the chains of the cached policies if the FORWARD chain is missing,
+// e.g. because another component deleted the table, like reconcile() does for the chains in iptables.
+func (pMgr *PolicyManager) reconcileNftables() {
+	// lock the cache before the reconcileManager, like AddPolicies and RemovePolicy, so that policies aren't re-created while they're removed
+	pMgr.policyMap.RLock()
+	defer pMgr.policyMap.RUnlock()
+
+	pMgr.reconcileManager.Lock()
+	defer pMgr.reconcileManager.Unlock()
+
+	command := pMgr.ioShim.Exec.Command(util.Nftables, util.NftablesSpecs(util.NftablesListFlag, util.NftablesChainObject, util.IptablesForwardChain)...)
+	output, err := command.CombinedOutput()
+	if err == nil {
+		return
+	}
+	logger.Infof("re-creating nftables table %s with %d policies since its FORWARD chain is missing. err: %v. output: %s",
+		util.NftablesAzureTable, len(pMgr.nftJumpOrder), err, strings.TrimSpace(string(output)))
+
+	creator := pMgr.creatorForRepairingNftables(pMgr.policiesInJumpOrder(pMgr.nftJumpOrder, nil))
+	timer := metrics.StartNewTimer()
+	err = restoreNftables(creator)
+	metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
+	if err != nil {
+		metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
+		metrics.SendErrorLogAndMetric(util.IptmID, "error: failed to re-create nftables table %s. err: %s", util.NftablesAzureTable, err.Error())
+		return
+	}
+	metrics.IncIPTablesRepairs(metrics.NftablesTableRepair, 1)
+}
+
+func (pMgr *PolicyManager) addPoliciesWithNftables(networkPolicies []*NPMNetworkPolicy) error {
+	jumpOrder := make([]string, 0, len(pMgr.nftJumpOrder)+len(networkPolicies))
+	newPolicies := make(map[string]*NPMNetworkPolicy, len(networkPolicies))
+	for _, networkPolicy := range networkPolicies {
+		jumpOrder = append(jumpOrder, networkPolicy.PolicyKey)
+		newPolicies[networkPolicy.PolicyKey] = networkPolicy
+	}
+	// like in iptables, jumps to the new policies are before jumps to existing policies
+	for _, policyKey := range pMgr.nftJumpOrder {
+		if _, ok := newPolicies[policyKey]; !ok {
+			jumpOrder = append(jumpOrder, policyKey)
+		}
+	}
+	creator := pMgr.creatorForNewNftablesPolicies(networkPolicies, pMgr.policiesInJumpOrder(jumpOrder, newPolicies))
+
+	pMgr.reconcileManager.forceLock()
+	defer pMgr.reconcileManager.forceUnlock()
+
+	timer := metrics.StartNewTimer()
+	err := pMgr.restoreOrAudit(creator, restoreNftables, "add policies with nft")
+	metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
+	if err != nil {
+		metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
+		return fmt.Errorf("failed to run nft with updated policies. err: %w", err)
+	}
+	pMgr.nftJumpOrder = jumpOrder
+	return nil
+}
+
+func (pMgr *PolicyManager) removePolicyWithNftables(networkPolicy *NPMNetworkPolicy) error {
+	jumpOrder := make([]string, 0, len(pMgr.nftJumpOrder))
+	for _, policyKey := range pMgr.nftJumpOrder {
+		if policyKey != networkPolicy.PolicyKey {
+			jumpOrder = append(jumpOrder, policyKey)
+		}
+	}
+	creator := pMgr.creatorForRemovingNftablesPolicy(networkPolicy, pMgr.policiesInJumpOrder(jumpOrder, nil))
+
+	pMgr.reconcileManager.forceLock()
+	defer pMgr.reconcileManager.forceUnlock()
+
+	timer := metrics.StartNewTimer()
+	err := pMgr.restoreOrAudit(creator, restoreNftables, "remove policy with nft")
+	metrics.RecordIPTablesRestoreLatency(timer, metrics.DeleteOp)
+	if err != nil {
+		metrics.IncIPTablesRestoreFailures(metrics.DeleteOp)
+		return fmt.Errorf("failed to run nft to delete policy. err: %w", err)
+	}
+	pMgr.nftJumpOrder = jumpOrder
+	return nil
+}
+
+// policiesInJumpOrder returns the policies for the keys, looking in newPolicies before the cache.
+func (pMgr *PolicyManager) policiesInJumpOrder(jumpOrder []string, newPolicies map[string]*NPMNetworkPolicy) []*NPMNetworkPolicy {
+	networkPolicies := make([]*NPMNetworkPolicy, 0, len(jumpOrder))
+	for _, policyKey := range jumpOrder {
+		if networkPolicy, ok := newPolicies[policyKey]; ok {
+			networkPolicies = append(networkPolicies, networkPolicy)
+			continue
+		}
+		if networkPolicy, ok := pMgr.policyMap.cache[policyKey]; ok {
+			networkPolicies = append(networkPolicies, networkPolicy)
+		}
+	}
+	return networkPolicies
+}
+
+func restoreNftables(creator *ioutil.FileCreator) error {
+	err := creator.RunCommandWithFile(util.Nftables, util.NftablesFileFlag, util.NftablesStdin)
+	if err != nil {
+		return fmt.Errorf("failed to run nft file. err: %w", err)
+	}
+	return nil
+}
+
+func (pMgr *PolicyManager) newNftablesCreator() *ioutil.FileCreator {
+	return ioutil.NewFileCreator(pMgr.ioShim, maxTryCount)
+}
+
+func (pMgr *PolicyManager) creatorForNftablesBootup() *ioutil.FileCreator {
+	creator := pMgr.newNftablesCreator()
+
+	// 1. delete the old table (adding it first so the delete succeeds if it doesn't exist)
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesTableObject)...)
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesDeleteFlag, util.NftablesTableObject)...)
+
+	// 2. create the table and its chains with their rules
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesTableObject)...)
+	pMgr.writeNftablesBaseChains(creator, false)
+
+	// 3. add AZURE-NPM-INGRESS and AZURE-NPM-EGRESS chain rules
+	writeNftablesJumpsToPolicies(creator, nil)
+	return creator
+}
+
+// creatorForRepairingNftables re-creates the base chains and the chains of the policies without deleting the table,
+// which holds the sets of the IPSetManager. The cache must be locked.
+func (pMgr *PolicyManager) creatorForRepairingNftables(policiesInJumpOrder []*NPMNetworkPolicy) *ioutil.FileCreator {
+	creator := pMgr.newNftablesCreator()
+
+	// 1. re-create the table and the base chains with their rules (flushing the chains in case some still exist)
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesTableObject)...)
+	pMgr.writeNftablesBaseChains(creator, true)
+
+	// 2. activate NPM if there are policies
+	if len(policiesInJumpOrder) > 0 {
+		addNftablesRule(creator, util.IptablesAzureChain, "jump", util.IptablesAzureIngressChain)
+		addNftablesRule(creator, util.IptablesAzureChain, "jump", util.IptablesAzureEgressChain)
+		addNftablesRule(creator, util.IptablesAzureChain, "jump", util.IptablesAzureAcceptChain)
+	}
+
+	// 3. re-create the chains of the policies
+	for _, networkPolicy := range policiesInJumpOrder {
+		for _, chain := range chainNames([]*NPMNetworkPolicy{networkPolicy}) {
+			creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesChainObject, chain)...)
+			creator.AddLine("", nil, util.NftablesSpecs(util.NftablesFlushFlag, util.NftablesChainObject, chain)...)
+		}
+		writeNftablesPolicyRules(creator, networkPolicy)
+	}
+
+	// 4. rewrite the jumps to the policy chains
+	writeNftablesJumpsToPolicies(creator, policiesInJumpOrder)
+	return creator
+}
+
+// writeNftablesBaseChains adds the FORWARD chain and the Azure chains to the table, with the jump to AZURE-NPM, which is
+// deactivated until there are policies, and the rules of AZURE-NPM-INGRESS-ALLOW-MARK and AZURE-NPM-ACCEPT.
+func (pMgr *PolicyManager) writeNftablesBaseChains(creator *ioutil.FileCreator, flush bool) {
+	priority := nftablesPriorityAfterIPTables
+	if pMgr.PlaceAzureChainFirst {
+		priority = nftablesPriorityBeforeIPTables
+	}
+	chains := append([]string{util.IptablesForwardChain}, iptablesAzureChains...)
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesChainObject, util.IptablesForwardChain,
+		"{", "type", "filter", "hook", "forward", "priority", priority, ";", "policy", "accept", ";", "}")...)
+	for _, chain := range iptablesAzureChains {
+		creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesChainObject, chain)...)
+	}
+	if flush {
+		for _, chain := range chains {
+			creator.AddLine("", nil, util.NftablesSpecs(util.NftablesFlushFlag, util.NftablesChainObject, chain)...)
+		}
+	}
+
+	addNftablesRule(creator, util.IptablesForwardChain, "ct", "state", "new", "jump", util.IptablesAzureChain)
+
+	markIngressAllowSpecs := setMarkNftablesSpecs(util.IptablesAzureIngressAllowMarkHex)
+	markIngressAllowSpecs = append(markIngressAllowSpecs, nftablesCommentSpecs(fmt.Sprintf("SET-INGRESS-ALLOW-MARK-%s", util.IptablesAzureIngressAllowMarkHex))...)
+	addNftablesRule(creator, util.IptablesAzureIngressAllowMarkChain, markIngressAllowSpecs...)
+	addNftablesRule(creator, util.IptablesAzureIngressAllowMarkChain, "jump", util.IptablesAzureEgressChain)
+	addNftablesRule(creator, util.IptablesAzureAcceptChain, "accept")
+}
+
+func (pMgr *PolicyManager) creatorForNewNftablesPolicies(networkPolicies, policiesInJumpOrder []*NPMNetworkPolicy) *ioutil.FileCreator {
+	creator := pMgr.newNftablesCreator()
+
+	// 1. Activate NPM if necessary
+	if pMgr.isFirstPolicy() {
+		creator.AddLine("", nil, util.NftablesSpecs(util.NftablesFlushFlag, util.NftablesChainObject, util.IptablesAzureChain)...)
+		addNftablesRule(creator, util.IptablesAzureChain, "jump", util.IptablesAzureIngressChain)
+		addNftablesRule(creator, util.IptablesAzureChain, "jump", util.IptablesAzureEgressChain)
+		addNftablesRule(creator, util.IptablesAzureChain, "jump", util.IptablesAzureAcceptChain)
+	}
+
+	// 2. Add all rules for the network policies (flushing the chains in case the policies are added again)
+	for _, networkPolicy := range networkPolicies {
+		for _, chain := range chainNames([]*NPMNetworkPolicy{networkPolicy}) {
+			creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesChainObject, chain)...)
+			creator.AddLine("", nil, util.NftablesSpecs(util.NftablesFlushFlag, util.NftablesChainObject, chain)...)
+		}
+		writeNftablesPolicyRules(creator, networkPolicy)
+	}
+
+	// 3. Rewrite the jumps to the policy chains
+	writeNftablesJumpsToPolicies(creator, policiesInJumpOrder)
+	return creator
+}
+
+// NOTE: if removing multiple policies, would need to add a isLastPolicy argument instead
+func (pMgr *PolicyManager) creatorForRemovingNftablesPolicy(networkPolicy *NPMNetworkPolicy, policiesInJumpOrder []*NPMNetworkPolicy) *ioutil.FileCreator {
+	creator := pMgr.newNftablesCreator()
+
+	// 1. Deactivate NPM (if necessary).
+	if pMgr.isLastPolicy() {
+		creator.AddLine("", nil, util.NftablesSpecs(util.NftablesFlushFlag, util.NftablesChainObject, util.IptablesAzureChain)...)
+	}
+
+	// 2. Rewrite the jumps to the remaining policy chains
+	writeNftablesJumpsToPolicies(creator, policiesInJumpOrder)
+
+	// 3. Delete the policy chains (adding them first so the delete succeeds if they don't exist)
+	for _, chain := range chainNames([]*NPMNetworkPolicy{networkPolicy}) {
+		creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesChainObject, chain)...)
+		creator.AddLine("", nil, util.NftablesSpecs(util.NftablesFlushFlag, util.NftablesChainObject, chain)...)
+		creator.AddLine("", nil, util.NftablesSpecs(util.NftablesDeleteFlag, util.NftablesChainObject, chain)...)
+	}
+	return creator
+}
+
+// writeNftablesJumpsToPolicies rewrites AZURE-NPM-INGRESS and AZURE-NPM-EGRESS with jumps to the policy chains in order,
+// followed by the rules acting on the marks.
+func writeNftablesJumpsToPolicies(creator *ioutil.FileCreator, policiesInJumpOrder []*NPMNetworkPolicy) {
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesFlushFlag, util.NftablesChainObject, util.IptablesAzureIngressChain)...)
+	for _, networkPolicy := range policiesInJumpOrder {
+		if hasIngress, _ := networkPolicy.hasIngressAndEgress(); hasIngress {
+			specs := nftablesMatchSpecsForNetworkPolicy(networkPolicy, DstMatch)
+			specs = append(specs, "jump", networkPolicy.ingressChainName())
+			specs = append(specs, nftablesCommentSpecs(networkPolicy.commentForJumpToIngress())...)
+			addNftablesRule(creator, util.IptablesAzureIngressChain, specs...)
+		}
+	}
+	ingressDropSpecs := onMarkNftablesSpecs(util.IptablesAzureIngressDropMarkHex)
+	ingressDropSpecs = append(ingressDropSpecs, "drop")
+	ingressDropSpecs = append(ingressDropSpecs, nftablesCommentSpecs(fmt.Sprintf("DROP-ON-INGRESS-DROP-MARK-%s", util.IptablesAzureIngressDropMarkHex))...)
+	addNftablesRule(creator, util.IptablesAzureIngressChain, ingressDropSpecs...)
+
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesFlushFlag, util.NftablesChainObject, util.IptablesAzureEgressChain)...)
+	for _, networkPolicy := range policiesInJumpOrder {
+		if _, hasEgress := networkPolicy.hasIngressAndEgress(); hasEgress {
+			specs := nftablesMatchSpecsForNetworkPolicy(networkPolicy, SrcMatch)
+			specs = append(specs, "jump", networkPolicy.egressChainName())
+			specs = append(specs, nftablesCommentSpecs(networkPolicy.commentForJumpToEgress())...)
+			addNftablesRule(creator, util.IptablesAzureEgressChain, specs...)
+		}
+	}
+	egressDropSpecs := onMarkNftablesSpecs(util.IptablesAzureEgressDropMarkHex)
+	egressDropSpecs = append(egressDropSpecs, "drop")
+	egressDropSpecs = append(egressDropSpecs, nftablesCommentSpecs(fmt.Sprintf("DROP-ON-EGRESS-DROP-MARK-%s", util.IptablesAzureEgressDropMarkHex))...)
+	addNftablesRule(creator, util.IptablesAzureEgressChain, egressDropSpecs...)
+
+	acceptSpecs := onMarkNftablesSpecs(util.IptablesAzureIngressAllowMarkHex)
+	acceptSpecs = append(acceptSpecs, "jump", util.IptablesAzureAcceptChain)
+	acceptSpecs = append(acceptSpecs, nftablesCommentSpecs(fmt.Sprintf("ACCEPT-ON-INGRESS-ALLOW-MARK-%s", util.IptablesAzureIngressAllowMarkHex))...)
+	addNftablesRule(creator, util.IptablesAzureEgressChain, acceptSpecs...)
+}
+
+// write rules for the policy chain(s)
+func writeNftablesPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
+	for _, aclPolicy := range networkPolicy.ACLs {
+		var chainName string
+		var actionSpecs []string
+		if aclPolicy.hasIngress() {
+			chainName = networkPolicy.ingressChainName()
+			if aclPolicy.Target == Allowed {
+				actionSpecs = []string{"jump", util.IptablesAzureIngressAllowMarkChain}
+			} else {
+				actionSpecs = setMarkNftablesSpecs(util.IptablesAzureIngressDropMarkHex)
+			}
+		} else {
+			chainName = networkPolicy.egressChainName()
+			if aclPolicy.Target == Allowed {
+				actionSpecs = []string{"jump", util.IptablesAzureAcceptChain}
+			} else {
+				actionSpecs = setMarkNftablesSpecs(util.IptablesAzureEgressDropMarkHex)
+			}
+		}
+		specs := nftablesRuleSpecs(aclPolicy)
+		specs = append(specs, actionSpecs...)
+		specs = append(specs, nftablesCommentSpecs(aclPolicy.comment())...)
+		addNftablesRule(creator, chainName, specs...)
+	}
+}
+
+func nftablesRuleSpecs(aclPolicy *ACLPolicy) []string {
+	specs := make([]string, 0)
+	if aclPolicy.Protocol != UnspecifiedProtocol {
+		specs = append(specs, "meta", "l4proto", strings.ToLower(string(aclPolicy.Protocol)))
+	}
+	if aclPolicy.DstPorts.Port != 0 || aclPolicy.DstPorts.EndPort != 0 {
+		specs = append(specs, "th", "dport", aclPolicy.DstPorts.toNftablesString())
+	}
+	for _, setInfo := range aclPolicy.SrcList {
+		specs = append(specs, setInfo.nftablesMatchSpecs(setInfo.MatchType)...)
+	}
+	for _, setInfo := range aclPolicy.DstList {
+		specs = append(specs, setInfo.nftablesMatchSpecs(setInfo.MatchType)...)
+	}
+	return specs
+}
+
+func nftablesMatchSpecsForNetworkPolicy(networkPolicy *NPMNetworkPolicy, matchType MatchType) []string {
+	specs := make([]string, 0)
+	for _, setInfo := range networkPolicy.PodSelectorList {
+		specs = append(specs, setInfo.nftablesMatchSpecs(matchType)...)
+	}
+	return specs
+}
+
+// nftablesMatchSpecs matches the set by its hashed name e.g. "ip saddr != @azure-npm-123".
+func (info SetInfo) nftablesMatchSpecs(matchType MatchType) []string {
+	specs := []string{nftablesMatchStrings[matchType]}
+	if !info.Included {
+		specs = append(specs, "!=")
+	}
+	return append(specs, "@"+info.IPSet.GetHashedName())
+}
+
+func (portRange *Ports) toNftablesString() string {
+	start := strconv.Itoa(int(portRange.Port))
+	if portRange.Port == portRange.EndPort {
+		return start
+	}
+	end := strconv.Itoa(int(portRange.EndPort))
+	return start + "-" + end
+}
+
+// setMarkNftablesSpecs sets an iptables mark like 0x400/0x400. The marks of NPM set all bits of their mask.
+func setMarkNftablesSpecs(mark string) []string {
+	value, _ := splitMark(mark)
+	return []string{"meta", "mark", "set", "meta", "mark", "or", value}
+}
+
+// onMarkNftablesSpecs matches an iptables mark like 0x400/0x400.
+func onMarkNftablesSpecs(mark string) []string {
+	value, mask := splitMark(mark)
+	return []string{"meta", "mark", "and", mask, "==", value}
+}
+
+func splitMark(mark string) (value, mask string) {
+	value, mask, ok := strings.Cut(mark, "/")
+	if !ok {
+		mask = "0xffffffff"
+	}
+	return value, mask
+}
+
+// nftablesCommentSpecs quotes the comment, which can be at most 128 bytes in nftables.
+func nftablesCommentSpecs(comment string) []string {
+	comment = strings.ReplaceAll(comment, "\"", "")
+	if len(comment) > maxNftablesCommentLength {
+		comment = comment[:maxNftablesCommentLength]
+	}
+	return []string{"comment", "\"" + comment + "\""}
+}
+
+func addNftablesRule(creator *ioutil.FileCreator, chain string, specs ...string) {
+	creator.AddLine("", nil, util.NftablesSpecs(util.NftablesAddFlag, util.NftablesRuleObject, append([]string{chain}, specs...)...)...)
+}
@@ -0,0 +1,314 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Azure/azure-container-networking/npm/util"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	nftablesConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		UseNftables:          true,
+	}
+
+	fakeNftablesFileCommand = testutils.TestCmd{Cmd: []string{"nft", "-f", "-"}}
+)
+
+// nftables rule variables for ACLs
+var (
+	ingressDropNftablesRule = fmt.Sprintf(
+		"meta l4proto tcp th dport 222-333 ip saddr @%s ip daddr != @%s meta mark set meta mark or 0x400 comment \"%s\"",
+		ipsets.TestCIDRSet.HashedName,
+		ipsets.TestKeyPodSet.HashedName,
+		ingressDropComment,
+	)
+	ingressAllowNftablesRule = fmt.Sprintf("ip saddr @%s jump AZURE-NPM-INGRESS-ALLOW-MARK comment \"%s\"", ipsets.TestCIDRSet.HashedName, ingressAllowComment)
+	egressDropNftablesRule   = fmt.Sprintf(
+		"meta l4proto udp th dport 144 ip daddr @%s meta mark set meta mark or 0x800 comment \"%s\"",
+		ipsets.TestCIDRSet.HashedName,
+		egressDropComment,
+	)
+	egressAllowNftablesRule = fmt.Sprintf("ip daddr @%s jump AZURE-NPM-ACCEPT comment \"%s\"", ipsets.TestNamedportSet.HashedName, egressAllowComment)
+)
+
+// nftables rule variables for NetworkPolicies
+var (
+	ingressEgressNetPolIngressNftablesJump = fmt.Sprintf(
+		"ip daddr @%s jump %s comment \"%s\"",
+		ipsets.TestKeyPodSet.HashedName,
+		bothDirectionsNetPolIngressChain,
+		bothDirectionsNetPolIngressJumpComment,
+	)
+	ingressEgressNetPolEgressNftablesJump = fmt.Sprintf(
+		"ip saddr @%s jump %s comment \"%s\"",
+		ipsets.TestKeyPodSet.HashedName,
+		bothDirectionsNetPolEgressChain,
+		bothDirectionsNetPolEgressJumpComment,
+	)
+	ingressNetPolNftablesJump = fmt.Sprintf(
+		"ip daddr @%s ip daddr @%s jump %s comment \"%s\"",
+		ipsets.TestKeyPodSet.HashedName,
+		ipsets.TestNSSet.HashedName,
+		ingressNetPolChain,
+		ingressNetPolJumpComment,
+	)
+	egressNetPolNftablesJump = fmt.Sprintf("jump %s comment \"%s\"", egressNetPolChain, egressNetPolJumpComment)
+
+	nftablesIngressDropRule = "add rule ip azure-npm AZURE-NPM-INGRESS meta mark and 0x400 == 0x400 drop comment \"DROP-ON-INGRESS-DROP-MARK-0x400/0x400\""
+	nftablesEgressDropRule  = "add rule ip azure-npm AZURE-NPM-EGRESS meta mark and 0x800 == 0x800 drop comment \"DROP-ON-EGRESS-DROP-MARK-0x800/0x800\""
+	nftablesEgressAccept    = "add rule ip azure-npm AZURE-NPM-EGRESS meta mark and 0x200 == 0x200 jump AZURE-NPM-ACCEPT comment \"ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200\""
+)
+
+func TestCreatorForNftablesBootup(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), nftablesConfig)
+	creator := pMgr.creatorForNftablesBootup()
+	expectedLines := []string{
+		"add table ip azure-npm",
+		"delete table ip azure-npm",
+		"add table ip azure-npm",
+		"add chain ip azure-npm FORWARD { type filter hook forward priority -1 ; policy accept ; }",
+		"add chain ip azure-npm AZURE-NPM",
+		"add chain ip azure-npm AZURE-NPM-INGRESS",
+		"add chain ip azure-npm AZURE-NPM-INGRESS-ALLOW-MARK",
+		"add chain ip azure-npm AZURE-NPM-EGRESS",
+		"add chain ip azure-npm AZURE-NPM-ACCEPT",
+		"add rule ip azure-npm FORWARD ct state new jump AZURE-NPM",
+		"add rule ip azure-npm AZURE-NPM-INGRESS-ALLOW-MARK meta mark set meta mark or 0x200 comment \"SET-INGRESS-ALLOW-MARK-0x200/0x200\"",
+		"add rule ip azure-npm AZURE-NPM-INGRESS-ALLOW-MARK jump AZURE-NPM-EGRESS",
+		"add rule ip azure-npm AZURE-NPM-ACCEPT accept",
+		"flush chain ip azure-npm AZURE-NPM-INGRESS",
+		nftablesIngressDropRule,
+		"flush chain ip azure-npm AZURE-NPM-EGRESS",
+		nftablesEgressDropRule,
+		nftablesEgressAccept,
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, strings.Split(creator.ToString(), "\n"))
+
+	// after KUBE-SERVICES, the base chain runs after the iptables filter table
+	pMgr = NewPolicyManager(common.NewMockIOShim(nil), &PolicyManagerCfg{UseNftables: true, PlaceAzureChainFirst: util.PlaceAzureChainAfterKubeServices})
+	creator = pMgr.creatorForNftablesBootup()
+	require.Contains(t, creator.ToString(), "add chain ip azure-npm FORWARD { type filter hook forward priority 1 ; policy accept ; }\n")
+}
+
+func TestBootupNftables(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"}, ExitCode: 2}, // AZURE-NPM chain didn't exist
+		{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM", "-m", "conntrack", "--ctstate", "NEW"}, ExitCode: 2},
+		fakeNftablesFileCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, nftablesConfig)
+	pMgr.nftJumpOrder = []string{"x/old"}
+
+	require.NoError(t, pMgr.Bootup(nil))
+	require.Empty(t, pMgr.nftJumpOrder)
+}
+
+func TestCreatorForNewNftablesPolicies(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), nftablesConfig)
+
+	// 1. test with activation
+	policies := []*NPMNetworkPolicy{bothDirectionsNetPol}
+	creator := pMgr.creatorForNewNftablesPolicies(policies, policies)
+	expectedLines := []string{
+		"flush chain ip azure-npm AZURE-NPM",
+		"add rule ip azure-npm AZURE-NPM jump AZURE-NPM-INGRESS",
+		"add rule ip azure-npm AZURE-NPM jump AZURE-NPM-EGRESS",
+		"add rule ip azure-npm AZURE-NPM jump AZURE-NPM-ACCEPT",
+		// policy 1
+		fmt.Sprintf("add chain ip azure-npm %s", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf("flush chain ip azure-npm %s", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf("add chain ip azure-npm %s", bothDirectionsNetPolEgressChain),
+		fmt.Sprintf("flush chain ip azure-npm %s", bothDirectionsNetPolEgressChain),
+		fmt.Sprintf("add rule ip azure-npm %s %s", bothDirectionsNetPolIngressChain, ingressDropNftablesRule),
+		fmt.Sprintf("add rule ip azure-npm %s %s", bothDirectionsNetPolIngressChain, ingressAllowNftablesRule),
+		fmt.Sprintf("add rule ip azure-npm %s %s", bothDirectionsNetPolEgressChain, egressDropNftablesRule),
+		fmt.Sprintf("add rule ip azure-npm %s %s", bothDirectionsNetPolEgressChain, egressAllowNftablesRule),
+		// jumps
+		"flush chain ip azure-npm AZURE-NPM-INGRESS",
+		fmt.Sprintf("add rule ip azure-npm AZURE-NPM-INGRESS %s", ingressEgressNetPolIngressNftablesJump),
+		nftablesIngressDropRule,
+		"flush chain ip azure-npm AZURE-NPM-EGRESS",
+		fmt.Sprintf("add rule ip azure-npm AZURE-NPM-EGRESS %s", ingressEgressNetPolEgressNftablesJump),
+		nftablesEgressDropRule,
+		nftablesEgressAccept,
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, strings.Split(creator.ToString(), "\n"))
+
+	// 2. test without activation
+	pMgr.policyMap.cache[bothDirectionsNetPol.PolicyKey] = bothDirectionsNetPol
+	policies = []*NPMNetworkPolicy{ingressNetPol, egressNetPol}
+	creator = pMgr.creatorForNewNftablesPolicies(policies, []*NPMNetworkPolicy{ingressNetPol, egressNetPol, bothDirectionsNetPol})
+	expectedLines = []string{
+		// policy 2
+		fmt.Sprintf("add chain ip azure-npm %s", ingressNetPolChain),
+		fmt.Sprintf("flush chain ip azure-npm %s", ingressNetPolChain),
+		fmt.Sprintf("add rule ip azure-npm %s %s", ingressNetPolChain, ingressDropNftablesRule),
+		// policy 3
+		fmt.Sprintf("add chain ip azure-npm %s", egressNetPolChain),
+		fmt.Sprintf("flush chain ip azure-npm %s", egressNetPolChain),
+		fmt.Sprintf("add rule ip azure-npm %s %s", egressNetPolChain, egressAllowNftablesRule),
+		// jumps to the new policies come first
+		"flush chain ip azure-npm AZURE-NPM-INGRESS",
+		fmt.Sprintf("add rule ip azure-npm AZURE-NPM-INGRESS %s", ingressNetPolNftablesJump),
+		fmt.Sprintf("add rule ip azure-npm AZURE-NPM-INGRESS %s", ingressEgressNetPolIngressNftablesJump),
+		nftablesIngressDropRule,
+		"flush chain ip azure-npm AZURE-NPM-EGRESS",
+		fmt.Sprintf("add rule ip azure-npm AZURE-NPM-EGRESS %s", egressNetPolNftablesJump),
+		fmt.Sprintf("add rule ip azure-npm AZURE-NPM-EGRESS %s", ingressEgressNetPolEgressNftablesJump),
+		nftablesEgressDropRule,
+		nftablesEgressAccept,
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, strings.Split(creator.ToString(), "\n"))
+}
+
+func TestCreatorForRemovingNftablesPolicy(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), nftablesConfig)
+	pMgr.policyMap.cache[bothDirectionsNetPol.PolicyKey] = bothDirectionsNetPol
+	pMgr.policyMap.cache[egressNetPol.PolicyKey] = egressNetPol
+
+	// 1. test without deactivation
+	creator := pMgr.creatorForRemovingNftablesPolicy(bothDirectionsNetPol, []*NPMNetworkPolicy{egressNetPol})
+	expectedLines := []string{
+		"flush chain ip azure-npm AZURE-NPM-INGRESS",
+		nftablesIngressDropRule,
+		"flush chain ip azure-npm AZURE-NPM-EGRESS",
+		fmt.Sprintf("add rule ip azure-npm AZURE-NPM-EGRESS %s", egressNetPolNftablesJump),
+		nftablesEgressDropRule,
+		nftablesEgressAccept,
+		fmt.Sprintf("add chain ip azure-npm %s", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf("flush chain ip azure-npm %s", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf("delete chain ip azure-npm %s", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf("add chain ip azure-npm %s", bothDirectionsNetPolEgressChain),
+		fmt.Sprintf("flush chain ip azure-npm %s", bothDirectionsNetPolEgressChain),
+		fmt.Sprintf("delete chain ip azure-npm %s", bothDirectionsNetPolEgressChain),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, strings.Split(creator.ToString(), "\n"))
+
+	// 2. test with deactivation
+	delete(pMgr.policyMap.cache, bothDirectionsNetPol.PolicyKey)
+	creator = pMgr.creatorForRemovingNftablesPolicy(egressNetPol, nil)
+	expectedLines = []string{
+		"flush chain ip azure-npm AZURE-NPM",
+		"flush chain ip azure-npm AZURE-NPM-INGRESS",
+		nftablesIngressDropRule,
+		"flush chain ip azure-npm AZURE-NPM-EGRESS",
+		nftablesEgressDropRule,
+		nftablesEgressAccept,
+		fmt.Sprintf("add chain ip azure-npm %s", egressNetPolChain),
+		fmt.Sprintf("flush chain ip azure-npm %s", egressNetPolChain),
+		fmt.Sprintf("delete chain ip azure-npm %s", egressNetPolChain),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, strings.Split(creator.ToString(), "\n"))
+}
+
+func TestAddAndRemovePoliciesWithNftables(t *testing.T) {
+	calls := []testutils.TestCmd{
+		fakeNftablesFileCommand,
+		fakeNftablesFileCommand,
+		fakeNftablesFileCommand,
+		fakeNftablesFileCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, nftablesConfig)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{bothDirectionsNetPol}, nil))
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{ingressNetPol, egressNetPol}, nil))
+	require.Equal(t, []string{ingressNetPol.PolicyKey, egressNetPol.PolicyKey, bothDirectionsNetPol.PolicyKey}, pMgr.nftJumpOrder)
+
+	// adding a policy again moves its jumps first
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{bothDirectionsNetPol}, nil))
+	require.Equal(t, []string{bothDirectionsNetPol.PolicyKey, ingressNetPol.PolicyKey, egressNetPol.PolicyKey}, pMgr.nftJumpOrder)
+
+	require.NoError(t, pMgr.RemovePolicy(ingressNetPol.PolicyKey))
+	require.Equal(t, []string{bothDirectionsNetPol.PolicyKey, egressNetPol.PolicyKey}, pMgr.nftJumpOrder)
+	require.Empty(t, pMgr.GetStaleChains())
+}
+
+func TestAddPolicyWithNftablesFailure(t *testing.T) {
+	calls := make([]testutils.TestCmd, 0, maxTryCount)
+	for i := 0; i < maxTryCount; i++ {
+		calls = append(calls, testutils.TestCmd{Cmd: fakeNftablesFileCommand.Cmd, ExitCode: 1})
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, nftablesConfig)
+
+	require.Error(t, pMgr.AddPolicies([]*NPMNetworkPolicy{bothDirectionsNetPol}, nil))
+	require.Empty(t, pMgr.nftJumpOrder)
+	require.False(t, pMgr.PolicyExists(bothDirectionsNetPol.PolicyKey))
+}
+
+func TestCreatorForRepairingNftables(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), nftablesConfig)
+	creator := pMgr.creatorForRepairingNftables([]*NPMNetworkPolicy{ingressNetPol})
+	expectedLines := []string{
+		"add table ip azure-npm",
+		"add chain ip azure-npm FORWARD { type filter hook forward priority -1 ; policy accept ; }",
+		"add chain ip azure-npm AZURE-NPM",
+		"add chain ip azure-npm AZURE-NPM-INGRESS",
+		"add chain ip azure-npm AZURE-NPM-INGRESS-ALLOW-MARK",
+		"add chain ip azure-npm AZURE-NPM-EGRESS",
+		"add chain ip azure-npm AZURE-NPM-ACCEPT",
+		"flush chain ip azure-npm FORWARD",
+		"flush chain ip azure-npm AZURE-NPM",
+		"flush chain ip azure-npm AZURE-NPM-INGRESS",
+		"flush chain ip azure-npm AZURE-NPM-INGRESS-ALLOW-MARK",
+		"flush chain ip azure-npm AZURE-NPM-EGRESS",
+		"flush chain ip azure-npm AZURE-NPM-ACCEPT",
+		"add rule ip azure-npm FORWARD ct state new jump AZURE-NPM",
+		"add rule ip azure-npm AZURE-NPM-INGRESS-ALLOW-MARK meta mark set meta mark or 0x200 comment \"SET-INGRESS-ALLOW-MARK-0x200/0x200\"",
+		"add rule ip azure-npm AZURE-NPM-INGRESS-ALLOW-MARK jump AZURE-NPM-EGRESS",
+		"add rule ip azure-npm AZURE-NPM-ACCEPT accept",
+		"add rule ip azure-npm AZURE-NPM jump AZURE-NPM-INGRESS",
+		"add rule ip azure-npm AZURE-NPM jump AZURE-NPM-EGRESS",
+		"add rule ip azure-npm AZURE-NPM jump AZURE-NPM-ACCEPT",
+		"add chain ip azure-npm " + ingressNetPolChain,
+		"flush chain ip azure-npm " + ingressNetPolChain,
+		"add rule ip azure-npm " + ingressNetPolChain + " " + ingressDropNftablesRule,
+		"flush chain ip azure-npm AZURE-NPM-INGRESS",
+		"add rule ip azure-npm AZURE-NPM-INGRESS " + ingressNetPolNftablesJump,
+		nftablesIngressDropRule,
+		"flush chain ip azure-npm AZURE-NPM-EGRESS",
+		nftablesEgressDropRule,
+		nftablesEgressAccept,
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, strings.Split(creator.ToString(), "\n"))
+}
+
+func TestReconcileNftables(t *testing.T) {
+	listForward := []string{"nft", "list", "chain", "ip", "azure-npm", "FORWARD"}
+	calls := []testutils.TestCmd{
+		fakeNftablesFileCommand,
+		{Cmd: listForward},
+		{Cmd: listForward, ExitCode: 1}, // the table was deleted
+		fakeNftablesFileCommand,
+		{Cmd: listForward},
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, nftablesConfig)
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{ingressNetPol}, nil))
+
+	pMgr.Reconcile()
+	pMgr.Reconcile()
+	pMgr.Reconcile()
+	require.Equal(t, []string{ingressNetPol.PolicyKey}, pMgr.nftJumpOrder)
+}
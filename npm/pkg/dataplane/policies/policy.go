@@ -7,7 +7,6 @@ import (
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
-	"k8s.io/klog"
 )
 
 type NPMNetworkPolicy struct {
@@ -15,6 +14,10 @@ type NPMNetworkPolicy struct {
 	Namespace string
 	// PolicyKey is a unique combination of "namespace/name" of network policy
 	PolicyKey string
+	// Tier is the tier of the policy, which decides when it is evaluated relative to the other tiers
+	Tier PolicyTier
+	// Priority orders the policies of the AdminTier, from 0 (evaluated first)
+	Priority int32
 	// ACLPolicyID is only used in Windows. See aclPolicyID() in policy_windows.go for more info
 	ACLPolicyID string
 	// TODO get rid of PodSelectorIPSets in favor of PodSelectorList (exact same except need to add members field to SetInfo)
@@ -42,6 +45,27 @@ func NewNPMNetworkPolicy(netPolName, netPolNamespace string) *NPMNetworkPolicy {
 	}
 }
 
+// NewAdminNPMNetworkPolicy returns a policy of the AdminTier for an AdminNetworkPolicy.
+func NewAdminNPMNetworkPolicy(anpName string, priority int32) *NPMNetworkPolicy {
+	return newTieredNPMNetworkPolicy(AdminTier, anpName, priority)
+}
+
+// NewBaselineNPMNetworkPolicy returns a policy of the BaselineTier for a BaselineAdminNetworkPolicy.
+func NewBaselineNPMNetworkPolicy(banpName string) *NPMNetworkPolicy {
+	return newTieredNPMNetworkPolicy(BaselineTier, banpName, 0)
+}
+
+// newTieredNPMNetworkPolicy returns a policy of a cluster-scoped resource. The tier is the namespace of the key,
+// which can't collide with the keys of NetworkPolicies since namespaces are lowercase.
+func newTieredNPMNetworkPolicy(tier PolicyTier, name string, priority int32) *NPMNetworkPolicy {
+	return &NPMNetworkPolicy{
+		PolicyKey:   fmt.Sprintf("%s/%s", tier, name),
+		ACLPolicyID: aclPolicyID(string(tier), name),
+		Tier:        tier,
+		Priority:    priority,
+	}
+}
+
 func (netPol *NPMNetworkPolicy) AllPodSelectorIPSets() []*ipsets.TranslatedIPSet {
 	return append(netPol.PodSelectorIPSets, netPol.ChildPodSelectorIPSets...)
 }
@@ -61,7 +85,11 @@ func (netPol *NPMNetworkPolicy) numACLRulesProducedInKernel() int {
 		}
 	}
 
-	// both Windows and Linux have an extra ACL rule for ingress and an extra rule for egress
+	// both Windows and Linux have an extra ACL rule for ingress and an extra rule for egress,
+	// except for the tiers, whose rules are evaluated in tier chains on Linux instead of behind jumps
+	if netPol.IsTiered() && !util.IsWindowsDP() {
+		return numRules
+	}
 	if hasIngress {
 		numRules++
 	}
@@ -73,7 +101,7 @@ func (netPol *NPMNetworkPolicy) numACLRulesProducedInKernel() int {
 
 func (netPol *NPMNetworkPolicy) PrettyString() string {
 	if netPol == nil {
-		klog.Infof("NPMNetworkPolicy is nil when trying to print string")
+		logger.Infof("NPMNetworkPolicy is nil when trying to print string")
 		return "nil NPMNetworkPolicy"
 	}
 	itemStrings := make([]string, 0, len(netPol.ACLs))
@@ -136,6 +164,9 @@ func ValidatePolicy(networkPolicy *NPMNetworkPolicy) error {
 		if !aclPolicy.hasKnownTarget() {
 			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has unknown target [%s]", networkPolicy.PolicyKey, aclPolicy.Target))
 		}
+		if aclPolicy.Target == Passed && networkPolicy.Tier != AdminTier {
+			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has target [%s] outside of the %s tier", networkPolicy.PolicyKey, Passed, AdminTier))
+		}
 		if !aclPolicy.hasKnownDirection() {
 			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has unknown direction [%s]", networkPolicy.PolicyKey, aclPolicy.Direction))
 		}
@@ -224,7 +255,7 @@ func (aclPolicy *ACLPolicy) hasKnownProtocol() bool {
 }
 
 func (aclPolicy *ACLPolicy) hasKnownTarget() bool {
-	return aclPolicy.Target == Allowed || aclPolicy.Target == Dropped
+	return aclPolicy.Target == Allowed || aclPolicy.Target == Dropped || aclPolicy.Target == Passed
 }
 
 func (aclPolicy *ACLPolicy) satisifiesPortAndProtocolConstraints() bool {
@@ -282,10 +313,6 @@ type SetInfo struct {
 	MatchType MatchType
 }
 
-// Ports represents a range of ports.
-// To specify one port, set Port and EndPort to the same value.
-// uint16 is used since there are 2^16 - 1 TCP/UDP ports (0 is invalid)
-// and 2^16 SCTP ports.
 // NewSetInfo creates SetInfo.
 func NewSetInfo(name string, setType ipsets.SetType, included bool, matchType MatchType) SetInfo {
 	return SetInfo{
@@ -299,6 +326,9 @@ func (info SetInfo) PrettyString() string {
 	return fmt.Sprintf("Name:%s  HashedName:%s  MatchType:%v  Included:%v", info.IPSet.GetPrefixName(), info.IPSet.GetHashedName(), info.MatchType, info.Included)
 }
 
+// Ports represents a range of ports, like the port and endPort of a NetworkPolicy.
+// To specify one port, set Port and EndPort to the same value.
+// Linux matches the range with --dport Port:EndPort, and Windows with the HNS port range Port-EndPort.
 type Ports struct {
 	Port    int32
 	EndPort int32
@@ -330,8 +360,33 @@ const (
 	Allowed Verdict = "ALLOW"
 	// Dropped is denying a flow
 	Dropped Verdict = "DROP"
+	// Passed skips the remaining policies of the AdminTier, so that NetworkPolicies decide the flow
+	Passed Verdict = "PASS"
 )
 
+// PolicyTier orders policies: the AdminTier is evaluated before NetworkPolicies, and the BaselineTier after them.
+// A flow is decided by the first tier with a policy matching it.
+type PolicyTier string
+
+const (
+	// NetworkPolicyTier is the tier of NetworkPolicies, where an allowing ACL wins over the dropping ACLs
+	NetworkPolicyTier PolicyTier = ""
+	// AdminTier is the tier of AdminNetworkPolicies, where the first matching ACL of the highest priority policy decides
+	AdminTier PolicyTier = "AdminNetworkPolicy"
+	// BaselineTier is the tier of the BaselineAdminNetworkPolicy, which only decides flows of pods no NetworkPolicy selects
+	BaselineTier PolicyTier = "BaselineAdminNetworkPolicy"
+
+	// MaxWindowsAdminPriority is the highest AdminNetworkPolicy priority supported in windows dataplane,
+	// since the ACLs of AdminNetworkPolicies must be evaluated before the base ACL at priority 200,
+	// and each AdminNetworkPolicy priority takes 4 HNS priorities.
+	MaxWindowsAdminPriority = 48
+)
+
+// IsTiered returns true if the policy is evaluated in the AdminTier or BaselineTier.
+func (netPol *NPMNetworkPolicy) IsTiered() bool {
+	return netPol.Tier != NetworkPolicyTier
+}
+
 // Protocol can be TCP, UDP, SCTP, or unspecified since they are currently supported in networkpolicy.
 // Protocol value is case-sensitive (Capital now).
 // TODO: Need to remove this dependency on case-sensitivity.
@@ -128,9 +128,12 @@ func (aclPolicy *ACLPolicy) comment() string {
 	}
 
 	builder := strings.Builder{}
-	if aclPolicy.Target == Allowed {
+	switch aclPolicy.Target {
+	case Allowed:
 		builder.WriteString("ALLOW")
-	} else {
+	case Passed:
+		builder.WriteString("PASS")
+	default:
 		builder.WriteString("DROP")
 	}
 
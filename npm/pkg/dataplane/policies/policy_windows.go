@@ -3,6 +3,8 @@ package policies
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Microsoft/hcsshim/hcn"
@@ -12,6 +14,13 @@ const (
 	blockRulePriotity = 3000
 	allowRulePriotity = 222
 	policyIDPrefix    = "azure-acl"
+
+	// AdminNetworkPolicy rules are evaluated before the NetworkPolicy rules and the base ACL at priority 200.
+	// Each AdminNetworkPolicy priority has adminRulesPerPriority priorities, so that its rules are evaluated in order.
+	adminRuleBasePriority = 1
+	adminRulesPerPriority = 4
+	// BaselineAdminNetworkPolicy rules are evaluated after the NetworkPolicy rules, in the order of the rules.
+	baselineRuleBasePriority = 4000
 )
 
 var (
@@ -26,6 +35,11 @@ var (
 	ErrNamedPortsNotSupported     = errors.New("Named Port translation is not supported in windows dataplane")
 	ErrNegativeMatchsNotSupported = errors.New("Negative match types is not supported in windows dataplane")
 	ErrProtocolNotSupported       = errors.New("Protocol mentioned is not supported")
+	ErrNoIPSetIPsGetter           = errors.New("IP policy mode requires an IPSetIPsGetter")
+	ErrPassNotSupported           = errors.New("Pass action is not supported in windows dataplane")
+	ErrAdminPriorityNotSupported  = fmt.Errorf("AdminNetworkPolicy priorities above %d are not supported in windows dataplane", MaxWindowsAdminPriority)
+	ErrTooManyAdminRuleActions    = fmt.Errorf("AdminNetworkPolicy rules alternating actions more than %d times in a direction are not supported in windows dataplane",
+		adminRulesPerPriority-1)
 )
 
 // aclPolicyID returns azure-acl-<network policy namespace>-<network policy name> format
@@ -77,6 +91,10 @@ func (acl *ACLPolicy) convertToAclSettings(aclID string) (*NPMACLPolSettings, er
 		return policySettings, ErrNamedPortsNotSupported
 	}
 
+	if acl.Target == Passed {
+		return policySettings, ErrPassNotSupported
+	}
+
 	policySettings.RuleType = hcn.RuleTypeSwitch
 	policySettings.Id = aclID
 	policySettings.Direction = getHCNDirection(acl.Direction)
@@ -140,6 +158,69 @@ func (acl *ACLPolicy) convertToAclSettings(aclID string) (*NPMACLPolSettings, er
 	return policySettings, nil
 }
 
+// tierRulePriority returns the priority of the i-th ACL of an AdminNetworkPolicy or BaselineAdminNetworkPolicy.
+// HNS evaluates ACLs of the same priority in no particular order, so an ACL of an AdminNetworkPolicy gets the next
+// priority of the policy when its action differs from the previous ACL of its direction. Consecutive ACLs with the same
+// action share a priority, since they decide the same regardless of their order.
+func tierRulePriority(policy *NPMNetworkPolicy, i int) (uint16, error) {
+	if policy.Tier == BaselineTier {
+		return uint16(baselineRuleBasePriority + i), nil
+	}
+	if policy.Priority < 0 || policy.Priority > MaxWindowsAdminPriority {
+		return 0, ErrAdminPriorityNotSupported
+	}
+
+	var ingressSlot, egressSlot int
+	var lastIngressTarget, lastEgressTarget Verdict
+	slot := 0
+	for _, acl := range policy.ACLs[:i+1] {
+		slot = 0
+		if acl.hasIngress() {
+			if lastIngressTarget != "" && acl.Target != lastIngressTarget {
+				ingressSlot++
+			}
+			lastIngressTarget = acl.Target
+			slot = ingressSlot
+		}
+		if acl.hasEgress() {
+			if lastEgressTarget != "" && acl.Target != lastEgressTarget {
+				egressSlot++
+			}
+			lastEgressTarget = acl.Target
+			if egressSlot > slot {
+				slot = egressSlot
+			}
+		}
+	}
+	if slot >= adminRulesPerPriority {
+		return 0, ErrTooManyAdminRuleActions
+	}
+	return uint16(adminRuleBasePriority + int(policy.Priority)*adminRulesPerPriority + slot), nil
+}
+
+// replaceSetsWithIPs sets the addresses of the ACL settings to the IPs of the source and destination sets, for IP policy mode.
+// It returns false if the sets of a list have no IPs in common. The ACL then matches no traffic and must not be applied,
+// since HNS matches any address when there are none.
+func (acl *ACLPolicy) replaceSetsWithIPs(policySettings *NPMACLPolSettings, ipsetIPs IPSetIPsGetter) (bool, error) {
+	srcIPs, ok, err := getIPsFromSetInfo(acl.SrcList, ipsetIPs)
+	if err != nil || !ok {
+		return false, err
+	}
+	dstIPs, ok, err := getIPsFromSetInfo(acl.DstList, ipsetIPs)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	// see the mapping of addresses with IPs in convertToAclSettings
+	policySettings.LocalAddresses = srcIPs
+	policySettings.RemoteAddresses = dstIPs
+	if policySettings.Direction == hcn.DirectionTypeOut {
+		policySettings.LocalAddresses = dstIPs
+		policySettings.RemoteAddresses = srcIPs
+	}
+	return true, nil
+}
+
 func (acl *ACLPolicy) checkIPSets() bool {
 	for _, set := range acl.SrcList {
 		if set.IPSet.Type == ipsets.NamedPorts {
@@ -175,17 +256,35 @@ func getAddrListFromSetInfo(setInfoList []SetInfo) string {
 	return setInfoStr
 }
 
+// getIPsFromSetInfo returns the comma-separated IPs which are in every set of the list.
+// It returns false if the list has sets but they have no IPs in common.
+func getIPsFromSetInfo(setInfoList []SetInfo, ipsetIPs IPSetIPsGetter) (string, bool, error) {
+	if len(setInfoList) == 0 {
+		return "", true, nil
+	}
+	setNames := make([]string, 0, len(setInfoList))
+	for _, setInfo := range setInfoList {
+		setNames = append(setNames, setInfo.IPSet.GetPrefixName())
+	}
+	ips, err := ipsetIPs.GetIPsFromSets(setNames)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get IPs of sets %+v: %w", setNames, err)
+	}
+	if len(ips) == 0 {
+		return "", false, nil
+	}
+	return strings.Join(ips, ","), true, nil
+}
+
+// getPortStrFromPorts returns the port, or the range of ports like 8000-8100, in the format of HNS ACLs.
 func getPortStrFromPorts(port Ports) string {
 	if port.Port == 0 {
 		return ""
 	}
-	portStr := fmt.Sprintf("%d", port.Port)
-	if port.EndPort != 0 {
-		for tempPort := port.Port + 1; tempPort <= port.EndPort; tempPort++ {
-			portStr += fmt.Sprintf(",%d", tempPort)
-		}
+	if port.EndPort <= port.Port {
+		return strconv.Itoa(int(port.Port))
 	}
-	return portStr
+	return fmt.Sprintf("%d-%d", port.Port, port.EndPort)
 }
 
 func getHCNDirection(direction Direction) hcn.DirectionType {
@@ -2,13 +2,14 @@ package policies
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
-	"k8s.io/klog"
 )
 
 // PolicyManagerMode will be used in windows to decide if
@@ -19,15 +20,18 @@ const (
 	// IPSetPolicyMode will references IPSets in policies
 	IPSetPolicyMode PolicyManagerMode = "IPSet"
 	// IPPolicyMode will replace ipset names with their value IPs in policies
-	// NOTE: this is currently unimplemented
 	IPPolicyMode PolicyManagerMode = "IP"
 
 	// this number is based on the implementation in chain-management_linux.go
 	// it represents the number of rules unrelated to policies
 	// it's technically 3 off when there are no policies since we flush the AZURE-NPM chain then
 	numLinuxBaseACLRules = 11
+	// the number of jumps to the tier chains, which are only added with EnableAdminNetworkPolicy
+	numLinuxTierJumpRules = 4
 )
 
+var logger = npmlogger.For(npmlogger.Policies)
+
 type PolicyManagerCfg struct {
 	// NodeIP is only used in Windows
 	NodeIP string
@@ -35,10 +39,31 @@ type PolicyManagerCfg struct {
 	PolicyMode PolicyManagerMode
 	// PlaceAzureChainFirst only affects Linux
 	PlaceAzureChainFirst bool
+	// UseNftables only affects Linux. Policies are programmed in an nftables table instead of iptables,
+	// which requires the IPSetManager to add its sets to the same table.
+	UseNftables bool
+	// EnableAdminNetworkPolicy only affects Linux. The chains of the AdminNetworkPolicy and BaselineAdminNetworkPolicy
+	// tiers and the jumps to them are created, and tiered policies can be added.
+	EnableAdminNetworkPolicy bool
 	// MaxBatchedACLsPerPod is the maximum number of ACLs that can be added to a Pod at once in Windows.
 	// The zero value is valid.
 	// A NetworkPolicy's ACLs are always in the same batch, and there will be at least one NetworkPolicy per batch.
 	MaxBatchedACLsPerPod int
+	// EndpointConcurrency is the maximum number of endpoints a NetworkPolicy is added to or removed from at once in Windows.
+	// Values below 1 mean one endpoint at a time.
+	EndpointConcurrency int
+	// AuditMode translates policies and logs the iptables rules or HNS ACLs they would produce without programming them.
+	// The policy metrics count the rules as if they were programmed.
+	AuditMode bool
+	// RollbackPolicyOnFailure only affects Windows. When a policy fails to be added to some endpoints,
+	// its ACLs are removed from the endpoints it was just added to, so that the policy is added to all or none of them.
+	RollbackPolicyOnFailure bool
+}
+
+// IPSetIPsGetter returns the IPs which are members of every given ipset.
+// In IPPolicyMode, ACLs contain these IPs instead of referring to the ipsets.
+type IPSetIPsGetter interface {
+	GetIPsFromSets(setNames []string) ([]string, error)
 }
 
 type PolicyMap struct {
@@ -62,6 +87,13 @@ type PolicyManager struct {
 	ioShim           *common.IOShim
 	staleChains      *staleChains
 	reconcileManager *reconcileManager
+	// ipsetIPs is only used in Windows with IPPolicyMode
+	ipsetIPs IPSetIPsGetter
+	// aclRefs is only used in Windows to apply identical ACLs of policies on the same endpoint once
+	aclRefs *endpointACLRefs
+	// nftJumpOrder is only used in Linux with UseNftables.
+	// It holds the keys of the policies in the order of their jumps from the ingress/egress chains.
+	nftJumpOrder []string
 	*PolicyManagerCfg
 }
 
@@ -72,6 +104,7 @@ func NewPolicyManager(ioShim *common.IOShim, cfg *PolicyManagerCfg) *PolicyManag
 		},
 		ioShim:      ioShim,
 		staleChains: newStaleChains(),
+		aclRefs:     newEndpointACLRefs(),
 		reconcileManager: &reconcileManager{
 			releaseLockSignal: make(chan struct{}, 1),
 		},
@@ -79,8 +112,20 @@ func NewPolicyManager(ioShim *common.IOShim, cfg *PolicyManagerCfg) *PolicyManag
 	}
 }
 
+// SetMaxBatchedACLsPerPod changes the maximum number of ACLs added to a Pod at once in Windows.
+func (pMgr *PolicyManager) SetMaxBatchedACLsPerPod(maxBatchedACLs int) {
+	pMgr.policyMap.Lock()
+	defer pMgr.policyMap.Unlock()
+	pMgr.MaxBatchedACLsPerPod = maxBatchedACLs
+}
+
+// SetIPSetIPsGetter sets how the IPs of ipsets are found, which is required for IPPolicyMode.
+func (pMgr *PolicyManager) SetIPSetIPsGetter(ipsetIPs IPSetIPsGetter) {
+	pMgr.ipsetIPs = ipsetIPs
+}
+
 func (pMgr *PolicyManager) ResetEndpoint(epID string) error {
-	if util.IsWindowsDP() {
+	if util.IsWindowsDP() && !pMgr.AuditMode {
 		return pMgr.bootup([]string{epID})
 	}
 	return nil
@@ -88,7 +133,12 @@ func (pMgr *PolicyManager) ResetEndpoint(epID string) error {
 
 func (pMgr *PolicyManager) Bootup(epIDs []string) error {
 	metrics.ResetNumACLRules()
-	if err := pMgr.bootup(epIDs); err != nil {
+	metrics.ResetNamespacePolicyMetrics()
+	metrics.SetAuditMode(pMgr.AuditMode)
+	if pMgr.AuditMode {
+		// nothing is programmed, so there is nothing to set up or clean up
+		logger.Infof("[DataPlane] [audit] policies will be logged instead of programmed")
+	} else if err := pMgr.bootup(epIDs); err != nil {
 		// NOTE: in Linux, Prometheus metrics may be off at this point since some ACL rules may have been applied successfully
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: failed to bootup policy manager: %s", err.Error())
 		return npmerrors.ErrorWrapper(npmerrors.BootupPolicyMgr, false, "failed to bootup policy manager", err)
@@ -96,7 +146,7 @@ func (pMgr *PolicyManager) Bootup(epIDs []string) error {
 
 	if !util.IsWindowsDP() {
 		// update Prometheus metrics on success
-		metrics.IncNumACLRulesBy(numLinuxBaseACLRules)
+		metrics.IncNumACLRulesBy(pMgr.numBaseACLRules())
 	}
 
 	if util.IsWindowsDP() && pMgr.NodeIP == "" {
@@ -106,7 +156,18 @@ func (pMgr *PolicyManager) Bootup(epIDs []string) error {
 	return nil
 }
 
+// numBaseACLRules is the number of Linux rules unrelated to policies.
+func (pMgr *PolicyManager) numBaseACLRules() int {
+	if pMgr.EnableAdminNetworkPolicy {
+		return numLinuxBaseACLRules + numLinuxTierJumpRules
+	}
+	return numLinuxBaseACLRules
+}
+
 func (pMgr *PolicyManager) Reconcile() {
+	if pMgr.AuditMode {
+		return
+	}
 	pMgr.reconcile()
 }
 
@@ -126,11 +187,32 @@ func (pMgr *PolicyManager) GetPolicy(policyKey string) (*NPMNetworkPolicy, bool)
 	return policy, ok
 }
 
+// GetAllPolicies returns a copy of every policy in the cache, sorted by key.
+func (pMgr *PolicyManager) GetAllPolicies() []*NPMNetworkPolicy {
+	pMgr.policyMap.RLock()
+	defer pMgr.policyMap.RUnlock()
+
+	policies := make([]*NPMNetworkPolicy, 0, len(pMgr.policyMap.cache))
+	for _, policy := range pMgr.policyMap.cache {
+		// PodEndpoints is updated in place as pods come and go
+		policyCopy := *policy
+		policyCopy.PodEndpoints = make(map[string]string, len(policy.PodEndpoints))
+		for ip, epID := range policy.PodEndpoints {
+			policyCopy.PodEndpoints[ip] = epID
+		}
+		policies = append(policies, &policyCopy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].PolicyKey < policies[j].PolicyKey
+	})
+	return policies
+}
+
 func (pMgr *PolicyManager) AddPolicies(policies []*NPMNetworkPolicy, endpointList map[string]string) error {
 	nonEmptyPolicies := make([]*NPMNetworkPolicy, 0, len(policies))
 	for _, policy := range policies {
 		if len(policy.ACLs) == 0 {
-			klog.Infof("[DataPlane] No ACLs in policy %s to apply", policy.PolicyKey)
+			logger.Infof("[DataPlane] No ACLs in policy %s to apply", policy.PolicyKey)
 			continue
 		}
 
@@ -151,6 +233,14 @@ func (pMgr *PolicyManager) AddPolicies(policies []*NPMNetworkPolicy, endpointLis
 	pMgr.policyMap.Lock()
 	defer pMgr.policyMap.Unlock()
 
+	// used for Prometheus metrics later
+	numEndpointsBefore := make(map[string]int, len(nonEmptyPolicies))
+	for _, policy := range nonEmptyPolicies {
+		if cachedPolicy, ok := pMgr.policyMap.cache[policy.PolicyKey]; ok {
+			numEndpointsBefore[policy.PolicyKey] = len(cachedPolicy.PodEndpoints)
+		}
+	}
+
 	// Call actual dataplane function to apply changes
 	timer := metrics.StartNewTimer()
 	err := pMgr.addPolicies(nonEmptyPolicies, endpointList)
@@ -165,11 +255,24 @@ func (pMgr *PolicyManager) AddPolicies(policies []*NPMNetworkPolicy, endpointLis
 
 	for _, policy := range nonEmptyPolicies {
 		// update Prometheus metrics on success
+		var numACLRulesAdded int
 		if util.IsWindowsDP() {
-			metrics.IncNumACLRulesBy((1 + policy.numACLRulesProducedInKernel()) * len(endpointList))
+			numACLRulesAdded = (1 + policy.numACLRulesProducedInKernel()) * len(endpointList)
 		} else {
-			metrics.IncNumACLRulesBy(policy.numACLRulesProducedInKernel())
+			numACLRulesAdded = policy.numACLRulesProducedInKernel()
 		}
+		metrics.IncNumACLRulesBy(numACLRulesAdded)
+
+		numPoliciesAdded := 1
+		before, ok := numEndpointsBefore[policy.PolicyKey]
+		if ok {
+			numPoliciesAdded = 0
+		}
+		numEndpointsAdded := 0
+		if util.IsWindowsDP() {
+			numEndpointsAdded = len(policy.PodEndpoints) - before
+		}
+		metrics.UpdateNamespacePolicyMetrics(policy.Namespace, numPoliciesAdded, numACLRulesAdded, numEndpointsAdded)
 
 		// add policy to cache
 		pMgr.policyMap.cache[policy.PolicyKey] = policy
@@ -189,7 +292,7 @@ func (pMgr *PolicyManager) RemovePolicy(policyKey string) error {
 	}
 
 	if len(policy.ACLs) == 0 {
-		klog.Infof("[DataPlane] No ACLs in policy %s to remove", policyKey)
+		logger.Infof("[DataPlane] No ACLs in policy %s to remove", policyKey)
 		return nil
 	}
 
@@ -211,12 +314,17 @@ func (pMgr *PolicyManager) RemovePolicy(policyKey string) error {
 	}
 
 	// update Prometheus metrics on success
+	var numACLRulesRemoved int
+	numEndpointsCovered := 0
 	if util.IsWindowsDP() {
 		numEndpointsRemoved := numEndpointsBefore - len(policy.PodEndpoints)
-		metrics.DecNumACLRulesBy((1 + policy.numACLRulesProducedInKernel()) * numEndpointsRemoved)
+		numACLRulesRemoved = (1 + policy.numACLRulesProducedInKernel()) * numEndpointsRemoved
+		numEndpointsCovered = numEndpointsBefore
 	} else {
-		metrics.DecNumACLRulesBy(policy.numACLRulesProducedInKernel())
+		numACLRulesRemoved = policy.numACLRulesProducedInKernel()
 	}
+	metrics.DecNumACLRulesBy(numACLRulesRemoved)
+	metrics.UpdateNamespacePolicyMetrics(policy.Namespace, -1, -numACLRulesRemoved, -numEndpointsCovered)
 
 	// remove policy from cache
 	delete(pMgr.policyMap.cache, policyKey)
@@ -233,9 +341,13 @@ func (pMgr *PolicyManager) RemovePolicyForEndpoints(policyKey string, endpointLi
 	}
 
 	if len(policy.ACLs) == 0 {
-		klog.Infof("[DataPlane] No ACLs in policy %s to remove for endpoints", policyKey)
+		logger.Infof("[DataPlane] No ACLs in policy %s to remove for endpoints", policyKey)
 		return nil
 	}
+
+	// used for Prometheus metrics later
+	numEndpointsBefore := len(policy.PodEndpoints)
+
 	// Call actual dataplane function to apply changes
 	err := pMgr.removePolicy(policy, endpointList)
 	// currently we only have acl rule exec time for "adding" rules, so we skip recording here
@@ -247,7 +359,9 @@ func (pMgr *PolicyManager) RemovePolicyForEndpoints(policyKey string, endpointLi
 	}
 
 	// update Prometheus metrics on success
-	metrics.DecNumACLRulesBy((1 + policy.numACLRulesProducedInKernel()) * len(endpointList))
+	numACLRulesRemoved := (1 + policy.numACLRulesProducedInKernel()) * len(endpointList)
+	metrics.DecNumACLRulesBy(numACLRulesRemoved)
+	metrics.UpdateNamespacePolicyMetrics(policy.Namespace, 0, -numACLRulesRemoved, len(policy.PodEndpoints)-numEndpointsBefore)
 
 	return nil
 }
@@ -3,12 +3,14 @@ package policies
 // This file contains code for the iptables implementation of adding/removing policies.
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/util"
 	"github.com/Azure/azure-container-networking/npm/util/ioutil"
-	"k8s.io/klog"
 )
 
 const (
@@ -22,6 +24,20 @@ const (
 	chainSectionPrefix = "chain"
 )
 
+// ErrTiersNotSupportedWithNftables is returned when adding an AdminNetworkPolicy tier policy with UseNftables.
+var ErrTiersNotSupportedWithNftables = errors.New("AdminNetworkPolicy tiers are not supported with nftables")
+
+// ErrTiersNotEnabled is returned when adding an AdminNetworkPolicy tier policy without EnableAdminNetworkPolicy,
+// since the tier chains don't exist.
+var ErrTiersNotEnabled = errors.New("AdminNetworkPolicy tiers are not enabled")
+
+// endpointACLRefs is unused in Linux.
+type endpointACLRefs struct{}
+
+func newEndpointACLRefs() *endpointACLRefs {
+	return &endpointACLRefs{}
+}
+
 /*
 Error handling for iptables-restore:
 Currently we retry on any error and will make two tries max.
@@ -42,6 +58,21 @@ Known errors that we should retry on:
 */
 
 func (pMgr *PolicyManager) addPolicies(networkPolicies []*NPMNetworkPolicy, _ map[string]string) error {
+	for _, networkPolicy := range networkPolicies {
+		if !networkPolicy.IsTiered() {
+			continue
+		}
+		if pMgr.UseNftables {
+			return fmt.Errorf("failed to add policy %s: %w", networkPolicy.PolicyKey, ErrTiersNotSupportedWithNftables)
+		}
+		if !pMgr.EnableAdminNetworkPolicy {
+			return fmt.Errorf("failed to add policy %s: %w", networkPolicy.PolicyKey, ErrTiersNotEnabled)
+		}
+	}
+	if pMgr.UseNftables {
+		return pMgr.addPoliciesWithNftables(networkPolicies)
+	}
+
 	// 1. Add rules for the network policies and activate NPM (if necessary).
 	chainsToCreate := chainNames(networkPolicies)
 	creator := pMgr.creatorForNewNetworkPolicies(chainsToCreate, networkPolicies)
@@ -51,7 +82,7 @@ func (pMgr *PolicyManager) addPolicies(networkPolicies []*NPMNetworkPolicy, _ ma
 	defer pMgr.reconcileManager.forceUnlock()
 
 	timer := metrics.StartNewTimer()
-	err := restore(creator)
+	err := pMgr.restoreOrAudit(creator, restore, "add policies with iptables-restore")
 	metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
 	if err != nil {
 		metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
@@ -66,6 +97,14 @@ func (pMgr *PolicyManager) addPolicies(networkPolicies []*NPMNetworkPolicy, _ ma
 }
 
 func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[string]string) error {
+	if pMgr.UseNftables {
+		return pMgr.removePolicyWithNftables(networkPolicy)
+	}
+
+	if networkPolicy.IsTiered() {
+		return pMgr.removeTieredPolicy(networkPolicy)
+	}
+
 	chainsToDelete := chainNames([]*NPMNetworkPolicy{networkPolicy})
 	creator := pMgr.creatorForRemovingPolicies(chainsToDelete)
 
@@ -82,7 +121,7 @@ func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[s
 
 	// 2. Flush the policy chains and deactivate NPM (if necessary).
 	timer := metrics.StartNewTimer()
-	restoreErr := restore(creator)
+	restoreErr := pMgr.restoreOrAudit(creator, restore, "remove policy with iptables-restore")
 	metrics.RecordIPTablesRestoreLatency(timer, metrics.DeleteOp)
 	if restoreErr != nil {
 		metrics.IncIPTablesRestoreFailures(metrics.DeleteOp)
@@ -96,6 +135,15 @@ func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[s
 	return nil
 }
 
+// restoreOrAudit applies the creator with restoreFn, or only logs what it would apply in AuditMode.
+func (pMgr *PolicyManager) restoreOrAudit(creator *ioutil.FileCreator, restoreFn func(*ioutil.FileCreator) error, action string) error {
+	if pMgr.AuditMode {
+		logger.Infof("[DataPlane] [audit] would %s:\n%s", action, creator.ToString())
+		return nil
+	}
+	return restoreFn(creator)
+}
+
 func restore(creator *ioutil.FileCreator) error {
 	err := creator.RunCommandWithFile(util.IptablesRestore, util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesRestoreTableFlag, util.IptablesFilterTable, util.IptablesRestoreNoFlushFlag)
 	if err != nil {
@@ -124,6 +172,10 @@ func (pMgr *PolicyManager) creatorForRemovingPolicies(allChainNames []string) *i
 func chainNames(networkPolicies []*NPMNetworkPolicy) []string {
 	chainNames := make([]string, 0)
 	for _, networkPolicy := range networkPolicies {
+		if networkPolicy.IsTiered() {
+			// rules of the tiers are in the tier chains
+			continue
+		}
 		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
 
 		if hasIngress {
@@ -181,13 +233,17 @@ func (pMgr *PolicyManager) deleteJumpRule(policy *NPMNetworkPolicy, direction Un
 	}
 
 	specs = append([]string{baseChainName}, specs...)
+	if pMgr.AuditMode {
+		logger.Infof("[DataPlane] [audit] would run: %s %s %s", util.Iptables, util.IptablesDeletionFlag, strings.Join(specs, " "))
+		return nil
+	}
 	timer := metrics.StartNewTimer()
 	errCode, err := pMgr.runIPTablesCommand(util.IptablesDeletionFlag, specs...)
 	metrics.RecordIPTablesDeleteLatency(timer)
 	// if this actually happens (don't think it should), could use ignoreErrorsAndRunIPTablesCommand instead with: "Bad rule (does a matching rule exist in that chain?)"
 	if err != nil && errCode != doesNotExistErrorCode && errCode != couldntLoadTargetErrorCode {
 		errorString := fmt.Sprintf("failed to delete jump from %s chain to %s chain for policy %s with exit code %d", baseChainName, chainName, policy.PolicyKey, errCode)
-		klog.Errorf("%s. err: %s", errorString, err.Error())
+		logger.Errorf("%s. err: %s", errorString, err.Error())
 		return fmt.Errorf("%s. err: %w", errorString, err)
 	}
 	return nil
@@ -221,9 +277,12 @@ func (pMgr *PolicyManager) creatorForNewNetworkPolicies(policyChains []string, n
 	}
 
 	// 2. Add all rules for the network policies
-	ingressJumpLineNumber := 1
-	egressJumpLineNumber := 1
+	ingressJumpLineNumber := pMgr.firstPolicyJumpLineNumber()
+	egressJumpLineNumber := pMgr.firstPolicyJumpLineNumber()
 	for _, networkPolicy := range networkPolicies {
+		if networkPolicy.IsTiered() {
+			continue
+		}
 		// 2.1 add all rules for the policy chain(s)
 		writeNetworkPolicyRules(creator, networkPolicy)
 
@@ -240,6 +299,9 @@ func (pMgr *PolicyManager) creatorForNewNetworkPolicies(policyChains []string, n
 			egressJumpLineNumber++
 		}
 	}
+
+	// 3. Rewrite the tier chains of tiered policies
+	pMgr.rewriteTierChains(creator, networkPolicies, "")
 	creator.AddLine("", nil, util.IptablesRestoreCommit)
 	return creator
 }
@@ -271,6 +333,116 @@ func writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetw
 	}
 }
 
+// removeTieredPolicy rewrites the tier chains of the policy without its rules, and deactivates NPM (if necessary).
+func (pMgr *PolicyManager) removeTieredPolicy(networkPolicy *NPMNetworkPolicy) error {
+	creator := pMgr.newCreatorWithChains(nil)
+	if pMgr.isLastPolicy() {
+		creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureChain)
+	}
+	pMgr.rewriteTierChains(creator, []*NPMNetworkPolicy{networkPolicy}, networkPolicy.PolicyKey)
+	creator.AddLine("", nil, util.IptablesRestoreCommit)
+
+	pMgr.reconcileManager.forceLock()
+	defer pMgr.reconcileManager.forceUnlock()
+
+	timer := metrics.StartNewTimer()
+	err := pMgr.restoreOrAudit(creator, restore, "remove tiered policy with iptables-restore")
+	metrics.RecordIPTablesRestoreLatency(timer, metrics.DeleteOp)
+	if err != nil {
+		metrics.IncIPTablesRestoreFailures(metrics.DeleteOp)
+		return fmt.Errorf("failed to restore iptables without tiered policy. err: %w", err)
+	}
+	return nil
+}
+
+// rewriteTierChains flushes the tier chains of the changed policies and writes the rules of all cached policies of
+// these tiers, replacing cached policies with the changed ones, except for removedKey. Tier chains are rewritten in
+// full since the order of their rules matters. The caller must lock the policyMap.
+func (pMgr *PolicyManager) rewriteTierChains(creator *ioutil.FileCreator, changed []*NPMNetworkPolicy, removedKey string) {
+	changedTiers := make(map[PolicyTier]struct{})
+	changedPolicies := make(map[string]*NPMNetworkPolicy)
+	for _, policy := range changed {
+		if !policy.IsTiered() {
+			continue
+		}
+		changedTiers[policy.Tier] = struct{}{}
+		if policy.PolicyKey != removedKey {
+			changedPolicies[policy.PolicyKey] = policy
+		}
+	}
+
+	for _, tier := range []PolicyTier{AdminTier, BaselineTier} {
+		if _, ok := changedTiers[tier]; !ok {
+			continue
+		}
+		tierPolicies := make([]*NPMNetworkPolicy, 0)
+		for key, policy := range pMgr.policyMap.cache {
+			if _, ok := changedPolicies[key]; ok || key == removedKey || policy.Tier != tier {
+				continue
+			}
+			tierPolicies = append(tierPolicies, policy)
+		}
+		for _, policy := range changedPolicies {
+			if policy.Tier == tier {
+				tierPolicies = append(tierPolicies, policy)
+			}
+		}
+		writeTierRules(creator, tier, tierPolicies)
+	}
+}
+
+// writeTierRules flushes the tier chains and writes the rules of the policies, ordered by priority.
+// The first matching rule decides the flow, and a rule passing the flow returns to the NetworkPolicy jumps.
+func writeTierRules(creator *ioutil.FileCreator, tier PolicyTier, tierPolicies []*NPMNetworkPolicy) {
+	sort.Slice(tierPolicies, func(i, j int) bool {
+		if tierPolicies[i].Priority != tierPolicies[j].Priority {
+			return tierPolicies[i].Priority < tierPolicies[j].Priority
+		}
+		return tierPolicies[i].PolicyKey < tierPolicies[j].PolicyKey
+	})
+
+	ingressChain, egressChain := util.IptablesAzureIngressAdminChain, util.IptablesAzureEgressAdminChain
+	if tier == BaselineTier {
+		ingressChain, egressChain = util.IptablesAzureIngressBaselineChain, util.IptablesAzureEgressBaselineChain
+	}
+	creator.AddLine("", nil, util.IptablesFlushFlag, ingressChain)
+	creator.AddLine("", nil, util.IptablesFlushFlag, egressChain)
+
+	for _, networkPolicy := range tierPolicies {
+		for _, aclPolicy := range networkPolicy.ACLs {
+			var line []string
+			if aclPolicy.hasIngress() {
+				line = []string{util.IptablesAppendFlag, ingressChain}
+				line = append(line, tierActionSpecs(aclPolicy.Target, util.IptablesAzureIngressAllowMarkChain)...)
+				// the subject is the destination of ingress
+				line = append(line, matchSetSpecsForNetworkPolicy(networkPolicy, DstMatch)...)
+			} else {
+				line = []string{util.IptablesAppendFlag, egressChain}
+				line = append(line, tierActionSpecs(aclPolicy.Target, util.IptablesAzureAcceptChain)...)
+				line = append(line, matchSetSpecsForNetworkPolicy(networkPolicy, SrcMatch)...)
+			}
+			specs := iptablesRuleSpecs(aclPolicy)
+			// prefix the ACL comment with the policy, since the tier chains have the rules of every policy of the tier
+			specs[len(specs)-1] = fmt.Sprintf("%s-%s", networkPolicy.PolicyKey, specs[len(specs)-1])
+			line = append(line, specs...)
+			creator.AddLine("", nil, line...)
+		}
+	}
+}
+
+// tierActionSpecs returns the target of a tier rule. Allowed flows go to the allowChain like for NetworkPolicies,
+// and dropped flows are dropped right away, since NetworkPolicies must not allow them.
+func tierActionSpecs(target Verdict, allowChain string) []string {
+	switch target {
+	case Allowed:
+		return []string{util.IptablesJumpFlag, allowChain}
+	case Passed:
+		return []string{util.IptablesJumpFlag, util.IptablesReturn}
+	default:
+		return []string{util.IptablesJumpFlag, util.IptablesDrop}
+	}
+}
+
 func iptablesRuleSpecs(aclPolicy *ACLPolicy) []string {
 	specs := make([]string, 0)
 	if aclPolicy.Protocol != UnspecifiedProtocol {
@@ -14,6 +14,12 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+var anpConfig = &PolicyManagerCfg{
+	PolicyMode:               IPSetPolicyMode,
+	PlaceAzureChainFirst:     util.PlaceAzureChainFirst,
+	EnableAdminNetworkPolicy: true,
+}
+
 // ACLs
 var (
 	ingressDeniedACL = &ACLPolicy{
@@ -282,6 +288,80 @@ func TestCreatorForAddPolicies(t *testing.T) {
 	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
 }
 
+func TestCreatorForAddPoliciesWithAdminNetworkPolicy(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), anpConfig)
+	// add a policy to the cache so that we don't activate
+	pMgr.policyMap.cache[egressNetPol.PolicyKey] = egressNetPol
+
+	policies := []*NPMNetworkPolicy{bothDirectionsNetPol, ingressNetPol}
+	creator := pMgr.creatorForNewNetworkPolicies(chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolEgressChain),
+		fmt.Sprintf(":%s - -", ingressNetPolChain),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressAllowRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressAllowRule),
+		// after the jump to the admin tier chain
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 2 %s", ingressEgressNetPolIngressJump),
+		fmt.Sprintf("-I AZURE-NPM-EGRESS 2 %s", ingressEgressNetPolEgressJump),
+		fmt.Sprintf("-A %s %s", ingressNetPolChain, ingressDropRule),
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 3 %s", ingressNetPolJump),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestAddTieredPolicyWithoutAdminNetworkPolicy(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+
+	deny := NewAdminNPMNetworkPolicy("deny", 20)
+	deny.ACLs = []*ACLPolicy{NewACLPolicy(Dropped, Ingress)}
+	require.ErrorIs(t, pMgr.addPolicies([]*NPMNetworkPolicy{deny}, nil), ErrTiersNotEnabled)
+}
+
+func TestCreatorForTieredPolicies(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), anpConfig)
+
+	deny := NewAdminNPMNetworkPolicy("deny", 20)
+	deny.PodSelectorList = []SetInfo{NewSetInfo(ipsets.TestKeyPodSet.Metadata.Name, ipsets.TestKeyPodSet.Metadata.Type, true, EitherMatch)}
+	denyACL := NewACLPolicy(Dropped, Ingress)
+	denyACL.SrcList = []SetInfo{NewSetInfo(ipsets.TestCIDRSet.Metadata.Name, ipsets.TestCIDRSet.Metadata.Type, true, SrcMatch)}
+	denyACL.Protocol = UnspecifiedProtocol
+	deny.ACLs = []*ACLPolicy{denyACL}
+	// cached policies of the tier are rewritten too, and NPM is already activated
+	pMgr.policyMap.cache[deny.PolicyKey] = deny
+
+	pass := NewAdminNPMNetworkPolicy("pass", 10)
+	pass.PodSelectorList = deny.PodSelectorList
+	passACL := NewACLPolicy(Passed, Egress)
+	passACL.DstList = []SetInfo{NewSetInfo(ipsets.TestCIDRSet.Metadata.Name, ipsets.TestCIDRSet.Metadata.Type, true, DstMatch)}
+	passACL.Protocol = UnspecifiedProtocol
+	pass.ACLs = []*ACLPolicy{passACL}
+
+	creator := pMgr.creatorForNewNetworkPolicies(chainNames([]*NPMNetworkPolicy{pass}), []*NPMNetworkPolicy{pass})
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		"-F AZURE-NPM-INGRESS-ADMIN",
+		"-F AZURE-NPM-EGRESS-ADMIN",
+		// ordered by priority
+		fmt.Sprintf("-A AZURE-NPM-EGRESS-ADMIN -j RETURN -m set --match-set %s src -m set --match-set %s dst -m comment --comment AdminNetworkPolicy/pass-PASS-TO-cidr-test-cidr-set",
+			ipsets.TestKeyPodSet.HashedName, ipsets.TestCIDRSet.HashedName),
+		fmt.Sprintf("-A AZURE-NPM-INGRESS-ADMIN -j DROP -m set --match-set %s dst -m set --match-set %s src -m comment --comment AdminNetworkPolicy/deny-DROP-FROM-cidr-test-cidr-set",
+			ipsets.TestKeyPodSet.HashedName, ipsets.TestCIDRSet.HashedName),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
 func TestCreatorForRemovePolicies(t *testing.T) {
 	calls := []testutils.TestCmd{fakeIPTablesRestoreCommand}
 	ioshim := common.NewMockIOShim(calls)
@@ -1,6 +1,7 @@
 package policies
 
 import (
+	"fmt"
 	"os"
 	"testing"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/Azure/azure-container-networking/npm/metrics/promutil"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Azure/azure-container-networking/npm/util"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
 	"github.com/stretchr/testify/require"
 )
 
@@ -98,6 +100,21 @@ func (p promVals) testPrometheusMetrics(t *testing.T) {
 	require.Equal(t, p.execCount, execCount, "Prometheus didn't register correctly for acl rule exec count")
 }
 
+func testNamespacePrometheusMetrics(t *testing.T, ns string, expectedPolicies, expectedACLs, expectedEndpoints int) {
+	t.Helper()
+	numPolicies, err := metrics.GetNumPoliciesForNamespace(ns)
+	promutil.NotifyIfErrors(t, err)
+	require.Equal(t, expectedPolicies, numPolicies, "Prometheus didn't register correctly for num policies in namespace %s", ns)
+
+	numACLs, err := metrics.GetNumACLRulesForNamespace(ns)
+	promutil.NotifyIfErrors(t, err)
+	require.Equal(t, expectedACLs, numACLs, "Prometheus didn't register correctly for num acls in namespace %s", ns)
+
+	numEndpoints, err := metrics.GetNumPolicyEndpointsForNamespace(ns)
+	promutil.NotifyIfErrors(t, err)
+	require.Equal(t, expectedEndpoints, numEndpoints, "Prometheus didn't register correctly for num policy endpoints in namespace %s", ns)
+}
+
 // see chain-management_linux_test.go for testing when an error occurs
 func TestBootup(t *testing.T) {
 	metrics.ReinitializeAll()
@@ -108,10 +125,12 @@ func TestBootup(t *testing.T) {
 
 	metrics.IncNumACLRules()
 	metrics.IncNumACLRules()
+	metrics.UpdateNamespacePolicyMetrics("x", 1, 2, 0)
 
 	require.NoError(t, pMgr.Bootup(epIDs))
+	testNamespacePrometheusMetrics(t, "x", 0, 0, 0)
 
-	expectedNumACLs := 11
+	expectedNumACLs := numLinuxBaseACLRules
 	if util.IsWindowsDP() {
 		expectedNumACLs = 0
 	}
@@ -131,12 +150,15 @@ func TestAddPolicy(t *testing.T) {
 	_, ok := pMgr.GetPolicy(testNetPol.PolicyKey)
 	require.True(t, ok)
 	numTestNetPolACLRulesProducedInKernel := 3
+	numEndpointsCovered := 0
 	if util.IsWindowsDP() {
 		numEndpoints := 2
 		numTestNetPolACLRulesProducedInKernel++
 		numTestNetPolACLRulesProducedInKernel *= numEndpoints
+		numEndpointsCovered = len(testNetPol.PodEndpoints)
 	}
 	promVals{numTestNetPolACLRulesProducedInKernel, 1}.testPrometheusMetrics(t)
+	testNamespacePrometheusMetrics(t, "x", 1, numTestNetPolACLRulesProducedInKernel, numEndpointsCovered)
 }
 
 func TestAddEmptyPolicy(t *testing.T) {
@@ -195,6 +217,40 @@ func TestRemovePolicy(t *testing.T) {
 	_, ok := pMgr.GetPolicy(testNetPol.PolicyKey)
 	require.False(t, ok)
 	promVals{0, 1}.testPrometheusMetrics(t)
+	testNamespacePrometheusMetrics(t, "x", 0, 0, 0)
+}
+
+// in audit mode, policies are translated and counted but nothing is run
+func TestAuditMode(t *testing.T) {
+	metrics.ReinitializeAll()
+	testNetPol := testNetworkPolicy()
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, &PolicyManagerCfg{
+		NodeIP:     ipsetConfig.NodeIP,
+		PolicyMode: ipsetConfig.PolicyMode,
+		AuditMode:  true,
+	})
+
+	require.NoError(t, pMgr.Bootup(epIDs))
+	auditMode, err := metrics.GetAuditMode()
+	promutil.NotifyIfErrors(t, err)
+	require.Equal(t, 1, auditMode)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{testNetPol}, epList))
+	_, ok := pMgr.GetPolicy(testNetPol.PolicyKey)
+	require.True(t, ok)
+	if !util.IsWindowsDP() {
+		numTestNetPolACLRulesProducedInKernel := 3
+		promVals{numLinuxBaseACLRules + numTestNetPolACLRulesProducedInKernel, 1}.testPrometheusMetrics(t)
+		testNamespacePrometheusMetrics(t, "x", 1, numTestNetPolACLRulesProducedInKernel, 0)
+	}
+
+	pMgr.Reconcile()
+	require.NoError(t, pMgr.RemovePolicy(testNetPol.PolicyKey))
+	_, ok = pMgr.GetPolicy(testNetPol.PolicyKey)
+	require.False(t, ok)
+	testNamespacePrometheusMetrics(t, "x", 0, 0, 0)
 }
 
 // see policymanager_linux.go for testing when an error occurs
@@ -251,6 +307,86 @@ func TestNormalizeAndValidatePolicy(t *testing.T) {
 	}
 }
 
+// benchmarkSizes are representative of large clusters: many small policies, and a single policy with many rules.
+var benchmarkSizes = []struct {
+	name          string
+	numPolicies   int
+	aclsPerPolicy int
+}{
+	{"policies=10/acls=10", 10, 10},
+	{"policies=200/acls=10", 200, 10},
+	{"policies=1/acls=500", 1, 500},
+}
+
+// benchmarkPolicies returns policies with a default drop ACL and aclsPerPolicy allow ACLs on distinct ports.
+func benchmarkPolicies(numPolicies, aclsPerPolicy int) []*NPMNetworkPolicy {
+	netpols := make([]*NPMNetworkPolicy, 0, numPolicies)
+	for i := 0; i < numPolicies; i++ {
+		netpol := testNetworkPolicy()
+		netpol.PolicyKey = fmt.Sprintf("x/bench-netpol-%d", i)
+		netpol.ACLPolicyID = fmt.Sprintf("azure-acl-x-bench-netpol-%d", i)
+		netpol.PodEndpoints = nil
+		netpol.ACLs = []*ACLPolicy{{Target: Dropped, Direction: Ingress}}
+		for j := 0; j < aclsPerPolicy; j++ {
+			netpol.ACLs = append(netpol.ACLs, &ACLPolicy{
+				Target:    Allowed,
+				Direction: Ingress,
+				Protocol:  TCP,
+				DstPorts:  Ports{Port: int32(8000 + j)},
+				SrcList: []SetInfo{
+					{IPSet: testNSSet, Included: true, MatchType: SrcMatch},
+					{IPSet: testKeyPodSet, Included: true, MatchType: SrcMatch},
+				},
+			})
+		}
+		netpols = append(netpols, netpol)
+	}
+	return netpols
+}
+
+func BenchmarkAddPolicies(b *testing.B) {
+	testutils.DiscardKlog(b)
+	for _, size := range benchmarkSizes {
+		size := size
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				netpols := benchmarkPolicies(size.numPolicies, size.aclsPerPolicy)
+				pMgr := NewPolicyManager(common.NewMockIOShim(GetAddPolicyTestCalls(nil)), ipsetConfig)
+				b.StartTimer()
+
+				require.NoError(b, pMgr.AddPolicies(netpols, epList))
+			}
+		})
+	}
+}
+
+func BenchmarkRemovePolicy(b *testing.B) {
+	testutils.DiscardKlog(b)
+	for _, size := range benchmarkSizes {
+		size := size
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				netpols := benchmarkPolicies(size.numPolicies, size.aclsPerPolicy)
+				calls := GetAddPolicyTestCalls(nil)
+				for _, netpol := range netpols {
+					calls = append(calls, GetRemovePolicyTestCalls(netpol)...)
+				}
+				pMgr := NewPolicyManager(common.NewMockIOShim(calls), ipsetConfig)
+				require.NoError(b, pMgr.AddPolicies(netpols, epList))
+				b.StartTimer()
+
+				for _, netpol := range netpols {
+					require.NoError(b, pMgr.RemovePolicy(netpol.PolicyKey))
+				}
+			}
+		})
+	}
+}
+
 func TestMain(m *testing.M) {
 	metrics.InitializeAll()
 
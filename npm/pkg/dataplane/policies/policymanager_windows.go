@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
 	"github.com/Microsoft/hcsshim/hcn"
-	"k8s.io/klog"
 )
 
 const (
@@ -17,6 +19,11 @@ const (
 	priority200   = 200
 	priority201   = 201
 	priority65499 = 65499
+
+	// hcnEndpointStateAttachedSharing is the state of the endpoints of running pods, as in the dataplane's endpoint query
+	hcnEndpointStateAttachedSharing = 3
+	hcnSchemaMajorVersion           = 2
+	hcnSchemaMinorVersion           = 0
 )
 
 var (
@@ -92,7 +99,8 @@ type aclBatch struct {
 	policies []string
 }
 
-type staleChains struct{} // unused in Windows
+// staleChains is unused in Windows. RemoveOrphanedACLs cleans up the ACLs of deleted policies instead.
+type staleChains struct{}
 
 type shouldResetAllACLs bool
 
@@ -105,6 +113,11 @@ func newStaleChains() *staleChains {
 	return &staleChains{}
 }
 
+// GetStaleChains is a no-op in Windows, which has no chains.
+func (pMgr *PolicyManager) GetStaleChains() []string {
+	return nil
+}
+
 func (pMgr *PolicyManager) bootup(epIDs []string) error {
 	var aggregateErr error
 	for _, epID := range epIDs {
@@ -126,8 +139,441 @@ func (pMgr *PolicyManager) bootup(epIDs []string) error {
 	return nil
 }
 
+// reconcile repairs the ACLs of the local endpoints in HNS which drifted from the policyMap, e.g. after an HNS restart:
+//   - a policy missing, partially applied, or with outdated IPs in IPPolicyMode, on an endpoint of its PodEndpoints is re-applied to it;
+//   - an endpoint recreated out of band, with the IP but not the ID of one of the PodEndpoints, gets its policies;
+//   - the rules of policies which don't apply to the endpoint, or don't exist anymore, are removed.
+//
+// The PodEndpoints which don't exist in HNS anymore are forgotten.
 func (pMgr *PolicyManager) reconcile() {
-	// not implemented
+	endpoints, err := pMgr.getLocalEndpoints()
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.IptmID, "error: [PolicyManagerWindows] failed to reconcile ACLs while listing endpoints. err: %s", err.Error())
+		return
+	}
+
+	pMgr.policyMap.Lock()
+	defer pMgr.policyMap.Unlock()
+
+	epIDByIP := make(map[string]string, len(endpoints))
+	for _, ep := range endpoints {
+		if len(ep.IpConfigurations) > 0 && ep.IpConfigurations[0].IpAddress != "" {
+			epIDByIP[ep.IpConfigurations[0].IpAddress] = ep.Id
+		}
+	}
+
+	// the policies expected on each endpoint, by ACL policy ID
+	expectedPolicies := make(map[string]map[string]*NPMNetworkPolicy)
+	for _, policy := range pMgr.policyMap.cache {
+		for epIP, epID := range policy.PodEndpoints {
+			currentEPID, ok := epIDByIP[epIP]
+			if !ok {
+				logger.Infof("[PolicyManagerWindows] while reconciling, removing endpoint which no longer exists from policy's current endpoints. policy: %s, endpoint IP: %s, endpoint ID: %s",
+					policy.PolicyKey, epIP, epID)
+				delete(policy.PodEndpoints, epIP)
+				continue
+			}
+			if currentEPID != epID {
+				logger.Infof("[PolicyManagerWindows] while reconciling, moving policy to endpoint recreated with the same IP. policy: %s, endpoint IP: %s, new ID: %s, previous ID: %s",
+					policy.PolicyKey, epIP, currentEPID, epID)
+				policy.PodEndpoints[epIP] = currentEPID
+			}
+			if expectedPolicies[currentEPID] == nil {
+				expectedPolicies[currentEPID] = make(map[string]*NPMNetworkPolicy)
+			}
+			expectedPolicies[currentEPID][policy.ACLPolicyID] = policy
+		}
+	}
+
+	epIDs := make(map[string]struct{}, len(endpoints))
+	for _, ep := range endpoints {
+		epIDs[ep.Id] = struct{}{}
+		if err := pMgr.reconcileEndpoint(ep, expectedPolicies[ep.Id]); err != nil {
+			metrics.SendErrorLogAndMetric(util.IptmID, "error: [PolicyManagerWindows] failed to reconcile ACLs. endpoint: %s, err: %s", ep.Id, err.Error())
+		}
+	}
+	// forget the references to the ACLs of endpoints which no longer exist
+	pMgr.aclRefs.retain(epIDs)
+}
+
+// reconcileEndpoint makes the NPM ACLs of the endpoint match the rules of the expected policies, keyed by ACL policy ID.
+// Partially applied policies are removed and re-applied whole, so that their rules are never duplicated.
+// When ACLs are deduplicated, a policy is expected to have only the rules it holds on the endpoint.
+func (pMgr *PolicyManager) reconcileEndpoint(ep *hcn.HostComputeEndpoint, expectedPolicies map[string]*NPMNetworkPolicy) error {
+	epBuilder, err := splitEndpointPolicies(ep.Policies)
+	if err != nil {
+		return fmt.Errorf("couldn't split endpoint policies. err: %w", err)
+	}
+
+	rulesByACLID := make(map[string][]*NPMACLPolSettings)
+	for _, acl := range epBuilder.aclPolicies {
+		rulesByACLID[acl.Id] = append(rulesByACLID[acl.Id], acl)
+	}
+
+	expectedRules, refs, err := pMgr.expectedRulesOnEndpoint(ep.Id, expectedPolicies)
+	if err != nil {
+		return err
+	}
+
+	rulesToAdd := make([]*NPMACLPolSettings, 0)
+	reappliedACLIDs := make(map[string]struct{})
+	for aclID, policy := range expectedPolicies {
+		rules := expectedRules[aclID]
+		if pMgr.rulesUpToDate(rulesByACLID[aclID], rules) {
+			continue
+		}
+		logger.Infof("[PolicyManagerWindows] while reconciling, re-applying policy with %d rules, expecting %d rules, on endpoint. policy: %s, endpoint: %s",
+			len(rulesByACLID[aclID]), len(rules), policy.PolicyKey, ep.Id)
+		rulesToAdd = append(rulesToAdd, rules...)
+		reappliedACLIDs[aclID] = struct{}{}
+	}
+
+	toDeleteIndexes := make(map[int]struct{})
+	for i, acl := range epBuilder.aclPolicies {
+		if !strings.HasPrefix(acl.Id, policyIDPrefix) || isBaseACLForCalicoCNI(acl.Id) {
+			continue
+		}
+		if _, ok := reappliedACLIDs[acl.Id]; ok {
+			toDeleteIndexes[i] = struct{}{}
+			continue
+		}
+		if _, ok := expectedPolicies[acl.Id]; !ok {
+			logger.Infof("[PolicyManagerWindows] while reconciling, removing orphaned ACL from endpoint. ACL ID: %s, endpoint: %s", acl.Id, ep.Id)
+			toDeleteIndexes[i] = struct{}{}
+		}
+	}
+
+	if len(toDeleteIndexes) == 0 {
+		if len(rulesToAdd) == 0 {
+			if refs != nil {
+				pMgr.aclRefs.set(ep.Id, refs)
+			}
+			return nil
+		}
+		epPolicyRequest, err := getEPPolicyReqFromACLSettings(rulesToAdd)
+		if err != nil {
+			return err
+		}
+		timer := metrics.StartNewTimer()
+		err = pMgr.applyEndpointPolicy(ep, hcn.RequestTypeAdd, epPolicyRequest)
+		metrics.RecordACLLatency(timer, metrics.CreateOp)
+		if err != nil {
+			metrics.IncACLFailures(metrics.CreateOp)
+			return fmt.Errorf("unable to add missing ACLs. err: %w", err)
+		}
+		if refs != nil {
+			pMgr.aclRefs.set(ep.Id, refs)
+		}
+		return nil
+	}
+
+	// the orphaned and partial rules are removed, and the missing rules added, with a single update
+	epBuilder.removeACLPolicyAtIndex(toDeleteIndexes)
+	epBuilder.aclPolicies = append(epBuilder.aclPolicies, rulesToAdd...)
+	epPolicies, err := epBuilder.getHCNPolicyRequest()
+	if err != nil {
+		return err
+	}
+	timer := metrics.StartNewTimer()
+	err = pMgr.applyEndpointPolicy(ep, hcn.RequestTypeUpdate, epPolicies)
+	metrics.RecordACLLatency(timer, metrics.UpdateOp)
+	if err != nil {
+		metrics.IncACLFailures(metrics.UpdateOp)
+		return fmt.Errorf("unable to update ACLs. err: %w", err)
+	}
+	if refs != nil {
+		pMgr.aclRefs.set(ep.Id, refs)
+	}
+	return nil
+}
+
+// expectedRulesOnEndpoint returns the rules expected on the endpoint for each of its expected policies, by ACL policy ID.
+// When ACLs are deduplicated, these are the rules each policy holds, and the endpoint's updated references are returned too.
+// The current holders keep their rules, so that reconciling doesn't move ACLs between policies.
+func (pMgr *PolicyManager) expectedRulesOnEndpoint(epID string, expectedPolicies map[string]*NPMNetworkPolicy) (map[string][]*NPMACLPolSettings, aclRefs, error) {
+	aclIDs := make([]string, 0, len(expectedPolicies))
+	for aclID := range expectedPolicies {
+		aclIDs = append(aclIDs, aclID)
+	}
+	sort.Strings(aclIDs)
+
+	expectedRules := make(map[string][]*NPMACLPolSettings, len(expectedPolicies))
+	for _, aclID := range aclIDs {
+		policy := expectedPolicies[aclID]
+		rules, err := pMgr.getSettingsFromACL(policy)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error while getting settings of policy %s. err: %w", policy.PolicyKey, err)
+		}
+		expectedRules[aclID] = rules
+	}
+	if !pMgr.dedupACLs() {
+		return expectedRules, nil, nil
+	}
+
+	refs := pMgr.aclRefs.get(epID)
+	staleACLIDs := make(map[string]struct{})
+	for _, ref := range refs {
+		for aclID := range ref.referrers {
+			if _, ok := expectedPolicies[aclID]; !ok {
+				staleACLIDs[aclID] = struct{}{}
+			}
+		}
+	}
+	refs.remove(staleACLIDs)
+	for _, aclID := range aclIDs {
+		refs.add(expectedRules[aclID])
+	}
+	return refs.heldRules(), refs, nil
+}
+
+// RemoveOrphanedACLs removes the ACLs of policies which are no longer in the policyMap from the given endpoints,
+// e.g. the endpoints of the network. Unlike reconcile, it covers endpoints which aren't attached to running pods,
+// and keeps the ACLs of existing policies, whichever endpoints they apply to.
+// It returns the number of orphaned ACLs removed.
+func (pMgr *PolicyManager) RemoveOrphanedACLs(endpoints []*hcn.HostComputeEndpoint) (int, error) {
+	pMgr.policyMap.Lock()
+	defer pMgr.policyMap.Unlock()
+
+	aclIDs := make(map[string]struct{}, len(pMgr.policyMap.cache))
+	for _, policy := range pMgr.policyMap.cache {
+		aclIDs[policy.ACLPolicyID] = struct{}{}
+	}
+
+	var aggregateErr error
+	numRemoved := 0
+	for _, ep := range endpoints {
+		n, err := pMgr.removeOrphanedACLsFromEndpoint(ep, aclIDs)
+		if err != nil {
+			if aggregateErr == nil {
+				aggregateErr = fmt.Errorf("failed to remove orphaned ACLs from endpoint %s. err: %w", ep.Id, err)
+			} else {
+				aggregateErr = fmt.Errorf("failed to remove orphaned ACLs from endpoint %s. err: %s. previous err: [%w]", ep.Id, err.Error(), aggregateErr)
+			}
+			continue
+		}
+		numRemoved += n
+	}
+
+	metrics.IncOrphanedACLsRemovedBy(numRemoved)
+	if aggregateErr != nil {
+		return numRemoved, fmt.Errorf("[PolicyManagerWindows] %w", aggregateErr)
+	}
+	return numRemoved, nil
+}
+
+// removeOrphanedACLsFromEndpoint removes the NPM ACLs whose ID isn't in aclIDs from the endpoint with a single HNS Update.
+func (pMgr *PolicyManager) removeOrphanedACLsFromEndpoint(ep *hcn.HostComputeEndpoint, aclIDs map[string]struct{}) (int, error) {
+	epBuilder, err := splitEndpointPolicies(ep.Policies)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't split endpoint policies. err: %w", err)
+	}
+
+	toDeleteIndexes := make(map[int]struct{})
+	for i, acl := range epBuilder.aclPolicies {
+		if !strings.HasPrefix(acl.Id, policyIDPrefix) || isBaseACLForCalicoCNI(acl.Id) {
+			continue
+		}
+		if _, ok := aclIDs[acl.Id]; !ok {
+			logger.Infof("[PolicyManagerWindows] removing orphaned ACL of deleted policy from endpoint. ACL ID: %s, endpoint: %s", acl.Id, ep.Id)
+			toDeleteIndexes[i] = struct{}{}
+		}
+	}
+	if len(toDeleteIndexes) == 0 {
+		return 0, nil
+	}
+
+	epBuilder.removeACLPolicyAtIndex(toDeleteIndexes)
+	epPolicies, err := epBuilder.getHCNPolicyRequest()
+	if err != nil {
+		return 0, err
+	}
+	timer := metrics.StartNewTimer()
+	err = pMgr.applyEndpointPolicy(ep, hcn.RequestTypeUpdate, epPolicies)
+	metrics.RecordACLLatency(timer, metrics.UpdateOp)
+	if err != nil {
+		metrics.IncACLFailures(metrics.UpdateOp)
+		return 0, fmt.Errorf("unable to remove orphaned ACLs. err: %w", err)
+	}
+	return len(toDeleteIndexes), nil
+}
+
+// rulesUpToDate returns whether the rules of a policy on an endpoint are the expected rules.
+// In IPPolicyMode, the addresses of the rules change with the members of ipsets, so the rules must be equal in any order.
+// Otherwise, they only have to be as many, since HNS may not return the rules exactly as they were applied.
+func (pMgr *PolicyManager) rulesUpToDate(rules, expectedRules []*NPMACLPolSettings) bool {
+	if len(rules) != len(expectedRules) {
+		return false
+	}
+	if pMgr.PolicyMode != IPPolicyMode {
+		return true
+	}
+
+	matched := make([]bool, len(rules))
+	for _, expected := range expectedRules {
+		found := false
+		for i, rule := range rules {
+			if !matched[i] && rule.compare(expected) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// RefreshPolicyIPs is used in Windows with IPPolicyMode to update the ACLs of the given policies on their endpoints,
+// after the members of the ipsets they refer to changed. All the policies of these endpoints are reconciled,
+// but only the policies whose ACLs changed are re-applied.
+func (pMgr *PolicyManager) RefreshPolicyIPs(policyKeys map[string]struct{}) error {
+	pMgr.policyMap.Lock()
+	defer pMgr.policyMap.Unlock()
+
+	epIDs := make(map[string]struct{})
+	for policyKey := range policyKeys {
+		policy, ok := pMgr.policyMap.cache[policyKey]
+		if !ok {
+			continue
+		}
+		for _, epID := range policy.PodEndpoints {
+			epIDs[epID] = struct{}{}
+		}
+	}
+	if len(epIDs) == 0 {
+		return nil
+	}
+
+	logger.Infow("refreshing the IPs of policies on endpoints", "policies", policyKeys, "endpoints", epIDs)
+
+	// the policies expected on each endpoint, by ACL policy ID
+	expectedPolicies := make(map[string]map[string]*NPMNetworkPolicy, len(epIDs))
+	for _, policy := range pMgr.policyMap.cache {
+		for _, epID := range policy.PodEndpoints {
+			if _, ok := epIDs[epID]; !ok {
+				continue
+			}
+			if expectedPolicies[epID] == nil {
+				expectedPolicies[epID] = make(map[string]*NPMNetworkPolicy)
+			}
+			expectedPolicies[epID][policy.ACLPolicyID] = policy
+		}
+	}
+
+	var aggregateErr error
+	for epID := range epIDs {
+		timer := metrics.StartNewTimer()
+		ep, err := pMgr.ioShim.Hns.GetEndpointByID(epID)
+		metrics.RecordGetEndpointLatency(timer)
+		if err != nil {
+			// IsNotFound check is being skipped at times. So adding a redundant check here.
+			if isNotFoundErr(err) || strings.Contains(err.Error(), "endpoint was not found") {
+				logger.Infof("[PolicyManagerWindows] ignoring refresh of policy IPs since the endpoint wasn't found. endpoint: %s", epID)
+				continue
+			}
+			metrics.IncGetEndpointFailures()
+		} else {
+			err = pMgr.reconcileEndpoint(ep, expectedPolicies[epID])
+		}
+
+		if err != nil {
+			if aggregateErr == nil {
+				aggregateErr = fmt.Errorf("failed to refresh policy IPs on %s ID Endpoint with err: %w", epID, err)
+			} else {
+				aggregateErr = fmt.Errorf("failed to refresh policy IPs on %s ID Endpoint with err: %s. previous err: [%w]", epID, err.Error(), aggregateErr)
+			}
+		}
+	}
+
+	if aggregateErr != nil {
+		return fmt.Errorf("[PolicyManagerWindows] %w", aggregateErr)
+	}
+	return nil
+}
+
+// GetEndpointACLs returns the ACLs in HNS on the endpoint which belong to the given policies.
+func (pMgr *PolicyManager) GetEndpointACLs(epID string, policyKeys []string) ([]HNSACL, error) {
+	pMgr.policyMap.RLock()
+	aclPolicyIDs := make(map[string]struct{}, len(policyKeys))
+	for _, policyKey := range policyKeys {
+		if policy, ok := pMgr.policyMap.cache[policyKey]; ok {
+			aclPolicyIDs[policy.ACLPolicyID] = struct{}{}
+		}
+	}
+	pMgr.policyMap.RUnlock()
+
+	timer := metrics.StartNewTimer()
+	ep, err := pMgr.ioShim.Hns.GetEndpointByID(epID)
+	metrics.RecordGetEndpointLatency(timer)
+	if err != nil {
+		metrics.IncGetEndpointFailures()
+		return nil, fmt.Errorf("failed to get endpoint %s. err: %w", epID, err)
+	}
+	epBuilder, err := splitEndpointPolicies(ep.Policies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the policies of endpoint %s. err: %w", epID, err)
+	}
+
+	acls := make([]HNSACL, 0, len(epBuilder.aclPolicies))
+	for _, acl := range epBuilder.aclPolicies {
+		if _, ok := aclPolicyIDs[acl.Id]; !ok {
+			continue
+		}
+		acls = append(acls, HNSACL{
+			EndpointID:      epID,
+			ID:              acl.Id,
+			Action:          string(acl.Action),
+			Direction:       string(acl.Direction),
+			Protocols:       acl.Protocols,
+			LocalAddresses:  acl.LocalAddresses,
+			RemoteAddresses: acl.RemoteAddresses,
+			LocalPorts:      acl.LocalPorts,
+			RemotePorts:     acl.RemotePorts,
+			Priority:        acl.Priority,
+		})
+	}
+	return acls, nil
+}
+
+// getLocalEndpoints lists the endpoints of the running pods on this node.
+func (pMgr *PolicyManager) getLocalEndpoints() ([]*hcn.HostComputeEndpoint, error) {
+	filter, err := json.Marshal(map[string]uint16{"State": hcnEndpointStateAttachedSharing})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal endpoint filter map. err: %w", err)
+	}
+	query := hcn.HostComputeQuery{
+		SchemaVersion: hcn.SchemaVersion{
+			Major: hcnSchemaMajorVersion,
+			Minor: hcnSchemaMinorVersion,
+		},
+		Flags:  hcn.HostComputeQueryFlagsNone,
+		Filter: string(filter),
+	}
+
+	timer := metrics.StartNewTimer()
+	endpoints, err := pMgr.ioShim.Hns.ListEndpointsQuery(query)
+	metrics.RecordListEndpointsLatency(timer)
+	if err != nil {
+		metrics.IncListEndpointsFailures()
+		return nil, fmt.Errorf("failed to list local endpoints. err: %w", err)
+	}
+
+	epPointers := make([]*hcn.HostComputeEndpoint, 0, len(endpoints))
+	for k := range endpoints {
+		epPointers = append(epPointers, &endpoints[k])
+	}
+	return epPointers, nil
+}
+
+func isBaseACLForCalicoCNI(aclID string) bool {
+	for _, acl := range baseACLsForCalicoCNI {
+		if acl.Id == aclID {
+			return true
+		}
+	}
+	return false
 }
 
 // AddAllPolicies is used in Windows to add all NetworkPolicies to an endpoint.
@@ -137,7 +583,7 @@ func (pMgr *PolicyManager) AddAllPolicies(policyKeys map[string]struct{}, epToMo
 	pMgr.policyMap.Lock()
 	defer pMgr.policyMap.Unlock()
 
-	klog.Infof("[PolicyManagerWindows] adding all policies. epID: %s. epIP: %s. policyKeys: %+v", epToModifyID, epToModifyIP, policyKeys)
+	logger.Infow("adding all policies", "endpointID", epToModifyID, "endpointIP", epToModifyIP, "policies", policyKeys)
 
 	batches, err := pMgr.batchPolicies(policyKeys, epToModifyID, epToModifyIP)
 	if err != nil {
@@ -145,29 +591,30 @@ func (pMgr *PolicyManager) AddAllPolicies(policyKeys map[string]struct{}, epToMo
 	}
 
 	successfulPolicies := make(map[string]struct{})
+	if len(batches) > 0 {
+		metrics.RecordACLBatchesPerPod(len(batches))
+	}
 
 	for i, batch := range batches {
-		klog.Infof("[PolicyManagerWindows] processing batch %d out of %d for adding all policies to endpoint. endpoint ID: %s. policyBatch: %+v", i+1, len(batches), epToModifyID, batch.policies)
-
-		epPolicyRequest, err := getEPPolicyReqFromACLSettings(batch.rules)
-		if err != nil {
-			return successfulPolicies, fmt.Errorf("error while applying all policies for batch %d out of %d. ruleBatch: %+v. err: %w", i+1, len(batches), batch, err)
-		}
+		logger.Debugw("processing batch for adding all policies to endpoint",
+			"batch", i+1, "batches", len(batches), "endpointID", epToModifyID, "policies", batch.policies)
 
-		klog.Infof("[PolicyManager] applying all rules to endpoint for batch %d out of %d. endpoint ID: %s", i+1, len(batches), epToModifyID)
-		err = pMgr.applyPoliciesToEndpointID(epToModifyID, epPolicyRequest)
+		logger.Debugw("applying all rules to endpoint", "batch", i+1, "batches", len(batches), "endpointID", epToModifyID)
+		timer := metrics.StartNewTimer()
+		err = pMgr.addRulesToEndpointID(epToModifyID, batch.rules)
+		metrics.RecordACLBatch(timer, len(batch.rules))
 		if err != nil {
 			return successfulPolicies, fmt.Errorf("failed to add all policies on endpoint for batch %d out of %d. ruleBatch: %+v. err: %w", i+1, len(batches), batch, err)
 		}
 
-		klog.Infof("[PolicyManager] finished applying all rules to endpoint for batch %d out of %d. endpoint ID: %s, policyBatch: %+v", i+1, len(batches), epToModifyID, batch.policies)
+		logger.Debugw("finished applying all rules to endpoint", "batch", i+1, "batches", len(batches), "endpointID", epToModifyID)
 		for _, policyKey := range batch.policies {
 			policy, ok := pMgr.policyMap.cache[policyKey]
 			if ok {
 				policy.PodEndpoints[epToModifyIP] = epToModifyID
 				successfulPolicies[policyKey] = struct{}{}
 			} else {
-				klog.Errorf("[PolicyManagerWindows] unexpected error: policy not found after adding all policies for batch %d out of %d. policyKey: %s. epID: %s",
+				logger.Errorf("[PolicyManagerWindows] unexpected error: policy not found after adding all policies for batch %d out of %d. policyKey: %s. epID: %s",
 					i+1, len(batches), policyKey, epToModifyID)
 				metrics.SendErrorLogAndMetric(util.IptmID, "[PolicyManagerWindows] unexpected error: policy not found after adding all policies for batch %d out of %d. policyKey: %s. epID: %s",
 					i+1, len(batches), policyKey, epToModifyID)
@@ -178,13 +625,54 @@ func (pMgr *PolicyManager) AddAllPolicies(policyKeys map[string]struct{}, epToMo
 	return successfulPolicies, nil
 }
 
+// RemoveAllPolicies is used in Windows to remove NetworkPolicies from an endpoint.
+// Instead of an HNS Update per NetworkPolicy, the ACLs of all the NetworkPolicies are removed with a single HNS Update.
+// Returns the NetworkPolicies which are no longer on the endpoint, including the ones which are not in the cache.
+func (pMgr *PolicyManager) RemoveAllPolicies(policyKeys map[string]struct{}, epToModifyID, epToModifyIP string) (map[string]struct{}, error) {
+	pMgr.policyMap.Lock()
+	defer pMgr.policyMap.Unlock()
+
+	logger.Infow("removing all policies", "endpointID", epToModifyID, "endpointIP", epToModifyIP, "policies", policyKeys)
+
+	removedPolicies := make(map[string]struct{}, len(policyKeys))
+	policiesToRemove := make([]*NPMNetworkPolicy, 0, len(policyKeys))
+	aclIDs := make(map[string]struct{}, len(policyKeys))
+	for policyKey := range policyKeys {
+		policy, ok := pMgr.policyMap.cache[policyKey]
+		if !ok || len(policy.ACLs) == 0 {
+			logger.Debugw("no ACLs to remove for policy while removing all policies", "policy", policyKey, "endpointID", epToModifyID)
+			removedPolicies[policyKey] = struct{}{}
+			continue
+		}
+		policiesToRemove = append(policiesToRemove, policy)
+		aclIDs[policy.ACLPolicyID] = struct{}{}
+	}
+
+	if len(aclIDs) == 0 {
+		return removedPolicies, nil
+	}
+
+	if err := pMgr.removePoliciesByEndpointID(aclIDs, epToModifyID); err != nil {
+		msg := fmt.Sprintf("failed to remove all policies. endpoint: %s. policies: [%+v]. err: [%s]", epToModifyID, policyKeys, err.Error())
+		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
+		return removedPolicies, npmerrors.Errorf(npmerrors.RemovePolicy, false, msg)
+	}
+
+	for _, policy := range policiesToRemove {
+		delete(policy.PodEndpoints, epToModifyIP)
+		removedPolicies[policy.PolicyKey] = struct{}{}
+		metrics.DecNumACLRulesBy(1 + policy.numACLRulesProducedInKernel())
+	}
+	return removedPolicies, nil
+}
+
 // batchPolicies returns a list of batches
 func (pMgr *PolicyManager) batchPolicies(policyKeys map[string]struct{}, epToModifyID, epToModifyIP string) ([]*aclBatch, error) {
 	batches := make([]*aclBatch, 0)
 	for policyKey := range policyKeys {
 		policy, ok := pMgr.policyMap.cache[policyKey]
 		if !ok {
-			klog.Infof("[PolicyManagerWindows] policy not found while adding all policies. policyKey: %s. epID: %s", policyKey, epToModifyID)
+			logger.Debugw("policy not found while adding all policies", "policy", policyKey, "endpointID", epToModifyID)
 			delete(policyKeys, policyKey)
 			continue
 		}
@@ -197,8 +685,8 @@ func (pMgr *PolicyManager) batchPolicies(policyKeys map[string]struct{}, epToMod
 		epID, ok := policy.PodEndpoints[epToModifyIP]
 		if ok {
 			if epID == epToModifyID {
-				klog.Infof("[PolicyManagerWindows] while adding all policies, will not add policy %s to endpoint since it already exists there. endpoint IP: %s, endpoint ID: %s",
-					policy.PolicyKey, epToModifyIP, epToModifyID)
+				logger.Debugw("while adding all policies, will not add policy to endpoint since it already exists there",
+					"policy", policy.PolicyKey, "endpointIP", epToModifyIP, "endpointID", epToModifyID)
 				delete(policyKeys, policyKey)
 				continue
 			}
@@ -206,7 +694,7 @@ func (pMgr *PolicyManager) batchPolicies(policyKeys map[string]struct{}, epToMod
 			// If the expected ID is not same as epID, there is a chance that old pod got deleted
 			// and same IP is used by new pod with new endpoint.
 			// so we should delete the non-existent endpoint from policy reference
-			klog.Infof("[PolicyManagerWindows] while adding all policies, removing deleted endpoint from policy's current endpoints. policy: %s, endpoint IP: %s, new ID: %s, previous ID: %s",
+			logger.Infof("[PolicyManagerWindows] while adding all policies, removing deleted endpoint from policy's current endpoints. policy: %s, endpoint IP: %s, new ID: %s, previous ID: %s",
 				policy.PolicyKey, epToModifyIP, epToModifyID, epID)
 			delete(policy.PodEndpoints, epToModifyIP)
 		}
@@ -242,12 +730,12 @@ func (pMgr *PolicyManager) batchPolicies(policyKeys map[string]struct{}, epToMod
 func (pMgr *PolicyManager) AddBaseACLsForCalicoCNI(epID string) {
 	epPolicyRequest, err := getEPPolicyReqFromACLSettings(baseACLsForCalicoCNI)
 	if err != nil {
-		klog.Errorf("failed to get policy request for base ACLs for Calico CNI. endpoint: %s. err: %v", epID, err)
+		logger.Errorf("failed to get policy request for base ACLs for Calico CNI. endpoint: %s. err: %v", epID, err)
 		return
 	}
 
 	if err := pMgr.applyPoliciesToEndpointID(epID, epPolicyRequest); err != nil {
-		klog.Errorf("failed to apply base ACLs for Calico CNI. endpoint: %s. err: %v", epID, err)
+		logger.Errorf("failed to apply base ACLs for Calico CNI. endpoint: %s. err: %v", epID, err)
 	}
 }
 
@@ -267,10 +755,10 @@ func (pMgr *PolicyManager) addPolicies(policies []*NPMNetworkPolicy, endpointLis
 // addPolicy may modify the endpointList input.
 func (pMgr *PolicyManager) addPolicy(policy *NPMNetworkPolicy, endpointList map[string]string) error {
 	if len(endpointList) == 0 {
-		klog.Infof("[PolicyManagerWindows] No Endpoints to apply policy %s on", policy.PolicyKey)
+		logger.Debugw("no endpoints to apply policy on", "policy", policy.PolicyKey)
 		return nil
 	}
-	klog.Infof("[PolicyManagerWindows] adding policy %s on %+v", policy.PolicyKey, endpointList)
+	logger.Infow("adding policy", "policy", policy.PolicyKey, "endpoints", endpointList)
 
 	// 1. remove stale endpoints from policy.PodEndpoints and skip adding to endpoints that already have the policy
 	if policy.PodEndpoints == nil {
@@ -287,18 +775,18 @@ func (pMgr *PolicyManager) addPolicy(policy *NPMNetworkPolicy, endpointList map[
 			// If the expected ID is not same as epID, there is a chance that old pod got deleted
 			// and same IP is used by new pod with new endpoint.
 			// so we should delete the non-existent endpoint from policy reference
-			klog.Infof("[PolicyManagerWindows] removing endpoint from policy's current endpoints since the endpoint ID has changed. policy: %s, endpoint IP: %s, new ID: %s, previous ID: %s", policy.PolicyKey, epIP, epID, oldEPID)
+			logger.Infof("[PolicyManagerWindows] removing endpoint from policy's current endpoints since the endpoint ID has changed. policy: %s, endpoint IP: %s, new ID: %s, previous ID: %s", policy.PolicyKey, epIP, epID, oldEPID)
 			delete(policy.PodEndpoints, epIP)
 			continue
 		}
 
-		klog.Infof("[PolicyManagerWindows] will not add policy %s to endpoint since it already exists there. endpoint IP: %s, endpoint ID: %s", policy.PolicyKey, epIP, epID)
+		logger.Debugw("will not add policy to endpoint since it already exists there", "policy", policy.PolicyKey, "endpointIP", epIP, "endpointID", epID)
 		// Deleting the endpoint from EPList so that the policy is not added to this endpoint again
 		delete(endpointList, epIP)
 	}
 
 	if len(endpointList) == 0 {
-		klog.Infof("[PolicyManagerWindows] After checking policy's current endpoints, no endpoints to apply policy %s on", policy.PolicyKey)
+		logger.Debugw("after checking policy's current endpoints, no endpoints to apply policy on", "policy", policy.PolicyKey)
 		return nil
 	}
 
@@ -307,16 +795,16 @@ func (pMgr *PolicyManager) addPolicy(policy *NPMNetworkPolicy, endpointList map[
 	if err != nil {
 		return err
 	}
-	epPolicyRequest, err := getEPPolicyReqFromACLSettings(rulesToAdd)
-	if err != nil {
-		return err
-	}
+
+	errs := pMgr.forEachEndpoint(endpointList, func(epID string) error {
+		return pMgr.addRulesToEndpointID(epID, rulesToAdd)
+	})
 
 	var aggregateErr error
+	addedEndpoints := make(map[string]string, len(endpointList))
 	for epIP, epID := range endpointList {
-		err = pMgr.applyPoliciesToEndpointID(epID, epPolicyRequest)
-		if err != nil {
-			klog.Errorf("failed to add policy to kernel. policy %s, endpoint: %s, err: %s", policy.PolicyKey, epID, err.Error())
+		if err := errs[epIP]; err != nil {
+			logger.Errorf("failed to add policy to kernel. policy %s, endpoint: %s, err: %s", policy.PolicyKey, epID, err.Error())
 			// Do not return if one endpoint fails, try all endpoints.
 			// aggregate the error message and return it at the end
 			if aggregateErr == nil {
@@ -326,7 +814,15 @@ func (pMgr *PolicyManager) addPolicy(policy *NPMNetworkPolicy, endpointList map[
 			}
 			continue
 		}
-		// Now update policy cache to reflect new endpoint
+		addedEndpoints[epIP] = epID
+	}
+
+	if aggregateErr != nil && pMgr.RollbackPolicyOnFailure {
+		addedEndpoints = pMgr.rollbackPolicy(policy, rulesToAdd, addedEndpoints)
+	}
+
+	// Now update policy cache to reflect new endpoints
+	for epIP, epID := range addedEndpoints {
 		policy.PodEndpoints[epIP] = epID
 	}
 
@@ -336,12 +832,34 @@ func (pMgr *PolicyManager) addPolicy(policy *NPMNetworkPolicy, endpointList map[
 	return nil
 }
 
+// rollbackPolicy removes the rules of a policy from the endpoints it was added to before it failed on other endpoints.
+// It returns the endpoints which the rules couldn't be removed from, which still have the policy.
+func (pMgr *PolicyManager) rollbackPolicy(policy *NPMNetworkPolicy, rules []*NPMACLPolSettings, endpointList map[string]string) map[string]string {
+	if len(endpointList) == 0 {
+		return endpointList
+	}
+	logger.Infow("rolling back partially added policy", "policy", policy.PolicyKey, "endpoints", endpointList)
+
+	errs := pMgr.forEachEndpoint(endpointList, func(epID string) error {
+		return pMgr.removePolicyByEndpointID(rules[0].Id, epID, len(rules), removeOnlyGivenPolicy)
+	})
+
+	remaining := make(map[string]string, len(errs))
+	for epIP, err := range errs {
+		epID := endpointList[epIP]
+		metrics.SendErrorLogAndMetric(util.IptmID, "error: failed to roll back policy on endpoint. policy: %s, endpoint: %s, err: %s",
+			policy.PolicyKey, epID, err.Error())
+		remaining[epIP] = epID
+	}
+	return remaining
+}
+
 // removePolicy will remove the policy from the specified endpoints, or
 // if the endpointList is nil, then the policy will be removed from the PodEndpoints of the policy
 func (pMgr *PolicyManager) removePolicy(policy *NPMNetworkPolicy, endpointList map[string]string) error {
 	if endpointList == nil {
 		if len(policy.PodEndpoints) == 0 {
-			klog.Infof("[PolicyManagerWindows] No Endpoints to remove policy %s on", policy.PolicyKey)
+			logger.Debugw("no endpoints to remove policy on", "policy", policy.PolicyKey)
 			return nil
 		}
 		endpointList = policy.PodEndpoints
@@ -352,15 +870,19 @@ func (pMgr *PolicyManager) removePolicy(policy *NPMNetworkPolicy, endpointList m
 		return err
 	}
 	// FIXME rulesToRemove is a list of pointers
-	klog.Infof("[PolicyManagerWindows] To Remove Policy: %s \n To Delete ACLs: %+v \n To Remove From %+v endpoints", policy.PolicyKey, rulesToRemove, endpointList)
+	logger.Infow("removing policy", "policy", policy.PolicyKey, "endpoints", endpointList)
+	logger.Debugw("ACLs to remove", "policy", policy.PolicyKey, "acls", rulesToRemove)
 	// If remove bug is solved we can directly remove the exact policy from the endpoint
 	// but if the bug is not solved then get all existing policies and remove relevant policies from list
 	// then apply remaining policies onto the endpoint
 	var aggregateErr error
 	numOfRulesToRemove := len(rulesToRemove)
+	errs := pMgr.forEachEndpoint(endpointList, func(epID string) error {
+		return pMgr.removePolicyByEndpointID(rulesToRemove[0].Id, epID, numOfRulesToRemove, removeOnlyGivenPolicy)
+	})
+
 	for epIPAddr, epID := range endpointList {
-		err := pMgr.removePolicyByEndpointID(rulesToRemove[0].Id, epID, numOfRulesToRemove, removeOnlyGivenPolicy)
-		if err != nil {
+		if err := errs[epIPAddr]; err != nil {
 			if aggregateErr == nil {
 				aggregateErr = fmt.Errorf("skipping removing policy on %s ID Endpoint with err: %w", epID, err)
 			} else {
@@ -379,6 +901,39 @@ func (pMgr *PolicyManager) removePolicy(policy *NPMNetworkPolicy, endpointList m
 	return nil
 }
 
+// forEachEndpoint calls fn with the ID of each endpoint, with up to EndpointConcurrency calls at once.
+// It returns the errors of the failed calls, keyed by endpoint IP.
+func (pMgr *PolicyManager) forEachEndpoint(endpointList map[string]string, fn func(epID string) error) map[string]error {
+	concurrency := pMgr.EndpointConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = make(map[string]error)
+	)
+	workers := make(chan struct{}, concurrency)
+	for epIP, epID := range endpointList {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func(epIP, epID string) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			if err := fn(epID); err != nil {
+				mu.Lock()
+				errs[epIP] = err
+				mu.Unlock()
+			}
+		}(epIP, epID)
+	}
+	wg.Wait()
+	return errs
+}
+
 func (pMgr *PolicyManager) removePolicyByEndpointID(ruleID, epID string, noOfRulesToRemove int, resetAllACL shouldResetAllACLs) error {
 	timer := metrics.StartNewTimer()
 	epObj, err := pMgr.ioShim.Hns.GetEndpointByID(epID)
@@ -386,7 +941,8 @@ func (pMgr *PolicyManager) removePolicyByEndpointID(ruleID, epID string, noOfRul
 	if err != nil {
 		// IsNotFound check is being skipped at times. So adding a redundant check here.
 		if isNotFoundErr(err) || strings.Contains(err.Error(), "endpoint was not found") {
-			klog.Infof("[PolicyManagerWindows] ignoring remove policy since the endpoint wasn't found. the corresponding pod might be deleted. policy: %s, endpoint: %s, HNS response: %s", ruleID, epID, err.Error())
+			logger.Infof("[PolicyManagerWindows] ignoring remove policy since the endpoint wasn't found. the corresponding pod might be deleted. policy: %s, endpoint: %s, HNS response: %s", ruleID, epID, err.Error())
+			pMgr.aclRefs.delete(epID)
 			return nil
 		}
 
@@ -395,7 +951,7 @@ func (pMgr *PolicyManager) removePolicyByEndpointID(ruleID, epID string, noOfRul
 	}
 
 	if len(epObj.Policies) == 0 {
-		klog.Infof("[DataPlanewindows] No Policies to remove on %s ID Endpoint", epID)
+		logger.Debugw("no policies to remove on endpoint", "endpointID", epID)
 	}
 
 	epBuilder, err := splitEndpointPolicies(epObj.Policies)
@@ -403,34 +959,106 @@ func (pMgr *PolicyManager) removePolicyByEndpointID(ruleID, epID string, noOfRul
 		return fmt.Errorf("couldn't split endpoint policies while trying to remove policy. policy: %s, endpoint: %s, err: %s", ruleID, epID, err.Error())
 	}
 
+	var refs aclRefs
 	if resetAllACL {
-		klog.Infof("[PolicyManagerWindows] Resetting all ACL Policies on %s ID Endpoint", epID)
+		logger.Debugw("resetting all ACL policies on endpoint", "endpointID", epID)
 		if !epBuilder.resetAllNPMAclPolicies() {
-			klog.Infof("[PolicyManagerWindows] No Azure-NPM ACL Policies on %s ID Endpoint to reset", epID)
+			logger.Debugw("no Azure-NPM ACL policies on endpoint to reset", "endpointID", epID)
+			pMgr.aclRefs.delete(epID)
 			return nil
 		}
 	} else {
-		klog.Infof("[PolicyManagerWindows] Resetting only ACL Policies with %s ID on %s ID Endpoint", ruleID, epID)
-		if !epBuilder.compareAndRemovePolicies(ruleID, noOfRulesToRemove) {
-			klog.Infof("[PolicyManagerWindows] No Policies with ID %s on %s ID Endpoint", ruleID, epID)
+		logger.Debugw("resetting ACL policies with ID on endpoint", "aclID", ruleID, "endpointID", epID)
+		var reassignedRules []*NPMACLPolSettings
+		if pMgr.dedupACLs() {
+			refs = pMgr.aclRefs.get(epID)
+			reassignedRules = refs.remove(map[string]struct{}{ruleID: {}})
+		}
+		if !epBuilder.compareAndRemovePolicies(ruleID, noOfRulesToRemove) && len(reassignedRules) == 0 {
+			logger.Debugw("no ACL policies with ID on endpoint", "aclID", ruleID, "endpointID", epID)
+			if refs != nil {
+				pMgr.aclRefs.set(epID, refs)
+			}
 			return nil
 		}
+		epBuilder.reassignACLs(reassignedRules)
 	}
 	// FIXME epBuilder.aclPolicies is a list of pointers
-	klog.Infof("[DataPlanewindows] Epbuilder ACL policies before removing %+v", epBuilder.aclPolicies)
-	klog.Infof("[DataPlanewindows] Epbuilder Other policies before removing %+v", epBuilder.otherPolicies)
+	logger.Debugw("endpoint policies before removing", "endpointID", epID, "aclPolicies", epBuilder.aclPolicies, "otherPolicies", epBuilder.otherPolicies)
 	epPolicies, err := epBuilder.getHCNPolicyRequest()
 	if err != nil {
 		return fmt.Errorf("unable to get HCN policy request while trying to remove policy. policy: %s, endpoint: %s, err: %s", ruleID, epID, err.Error())
 	}
 
 	timer = metrics.StartNewTimer()
-	err = pMgr.ioShim.Hns.ApplyEndpointPolicy(epObj, hcn.RequestTypeUpdate, epPolicies)
+	err = pMgr.applyEndpointPolicy(epObj, hcn.RequestTypeUpdate, epPolicies)
 	metrics.RecordACLLatency(timer, metrics.UpdateOp)
 	if err != nil {
 		metrics.IncACLFailures(metrics.UpdateOp)
 		return fmt.Errorf("unable to apply changes when removing policy. policy: %s, endpoint: %s, err: %w", ruleID, epID, err)
 	}
+
+	if resetAllACL {
+		pMgr.aclRefs.delete(epID)
+	} else if refs != nil {
+		pMgr.aclRefs.set(epID, refs)
+	}
+	return nil
+}
+
+// removePoliciesByEndpointID removes the ACLs with any of the IDs from the endpoint with a single HNS Update.
+func (pMgr *PolicyManager) removePoliciesByEndpointID(aclIDs map[string]struct{}, epID string) error {
+	timer := metrics.StartNewTimer()
+	epObj, err := pMgr.ioShim.Hns.GetEndpointByID(epID)
+	metrics.RecordGetEndpointLatency(timer)
+	if err != nil {
+		// IsNotFound check is being skipped at times. So adding a redundant check here.
+		if isNotFoundErr(err) || strings.Contains(err.Error(), "endpoint was not found") {
+			logger.Infof("[PolicyManagerWindows] ignoring remove policies since the endpoint wasn't found. the corresponding pod might be deleted. policies: %+v, endpoint: %s, HNS response: %s", aclIDs, epID, err.Error())
+			pMgr.aclRefs.delete(epID)
+			return nil
+		}
+
+		metrics.IncGetEndpointFailures()
+		return fmt.Errorf("[PolicyManagerWindows] failed to remove policies while getting the endpoint. policies: %+v, endpoint: %s, err: %w", aclIDs, epID, err)
+	}
+
+	epBuilder, err := splitEndpointPolicies(epObj.Policies)
+	if err != nil {
+		return fmt.Errorf("couldn't split endpoint policies while trying to remove policies. policies: %+v, endpoint: %s, err: %s", aclIDs, epID, err.Error())
+	}
+
+	var refs aclRefs
+	var reassignedRules []*NPMACLPolSettings
+	if pMgr.dedupACLs() {
+		refs = pMgr.aclRefs.get(epID)
+		reassignedRules = refs.remove(aclIDs)
+	}
+	if !epBuilder.removePoliciesWithIDs(aclIDs) && len(reassignedRules) == 0 {
+		logger.Debugw("no ACL policies with IDs on endpoint", "aclIDs", aclIDs, "endpointID", epID)
+		if refs != nil {
+			pMgr.aclRefs.set(epID, refs)
+		}
+		return nil
+	}
+	epBuilder.reassignACLs(reassignedRules)
+
+	epPolicies, err := epBuilder.getHCNPolicyRequest()
+	if err != nil {
+		return fmt.Errorf("unable to get HCN policy request while trying to remove policies. policies: %+v, endpoint: %s, err: %s", aclIDs, epID, err.Error())
+	}
+
+	timer = metrics.StartNewTimer()
+	err = pMgr.applyEndpointPolicy(epObj, hcn.RequestTypeUpdate, epPolicies)
+	metrics.RecordACLLatency(timer, metrics.UpdateOp)
+	if err != nil {
+		metrics.IncACLFailures(metrics.UpdateOp)
+		return fmt.Errorf("unable to apply changes when removing policies. policies: %+v, endpoint: %s, err: %w", aclIDs, epID, err)
+	}
+
+	if refs != nil {
+		pMgr.aclRefs.set(epID, refs)
+	}
 	return nil
 }
 
@@ -452,16 +1080,65 @@ func (pMgr *PolicyManager) applyPoliciesToEndpointID(epID string, policies hcn.P
 	}
 
 	timer = metrics.StartNewTimer()
-	err = pMgr.ioShim.Hns.ApplyEndpointPolicy(epObj, hcn.RequestTypeAdd, policies)
+	err = pMgr.applyEndpointPolicy(epObj, hcn.RequestTypeAdd, policies)
 	metrics.RecordACLLatency(timer, metrics.CreateOp)
 	if err != nil {
 		metrics.IncACLFailures(metrics.CreateOp)
-		klog.Errorf("[PolicyManagerWindows] failed to apply policies. endpoint: %s, err: %s", epID, err.Error())
+		logger.Errorf("[PolicyManagerWindows] failed to apply policies. endpoint: %s, err: %s", epID, err.Error())
 		return err
 	}
 	return nil
 }
 
+// applyEndpointPolicy makes the HNS request on the endpoint, or only logs the request in AuditMode.
+// For an Update request, the policies are all the policies the endpoint would have.
+func (pMgr *PolicyManager) applyEndpointPolicy(ep *hcn.HostComputeEndpoint, requestType hcn.RequestType, policies hcn.PolicyEndpointRequest) error {
+	if !pMgr.AuditMode {
+		return pMgr.ioShim.Hns.ApplyEndpointPolicy(ep, requestType, policies)
+	}
+
+	bytes, err := json.Marshal(policies)
+	if err != nil {
+		return fmt.Errorf("[PolicyManagerWindows] failed to marshal policies for audit. endpoint: %s, err: %w", ep.Id, err)
+	}
+	logger.Infof("[PolicyManagerWindows] [audit] would apply %s request to endpoint %s: %s", requestType, ep.Id, string(bytes))
+	return nil
+}
+
+// dedupACLs returns whether identical ACLs of the policies on an endpoint are applied once (see endpointACLRefs).
+func (pMgr *PolicyManager) dedupACLs() bool {
+	return pMgr.PolicyMode != IPPolicyMode
+}
+
+// addRulesToEndpointID adds the rules to the endpoint with an HNS Add,
+// except for the rules which are already on the endpoint for other policies.
+func (pMgr *PolicyManager) addRulesToEndpointID(epID string, rules []*NPMACLPolSettings) error {
+	rulesToAdd := rules
+	var refs aclRefs
+	if pMgr.dedupACLs() {
+		refs = pMgr.aclRefs.get(epID)
+		rulesToAdd = refs.add(rules)
+		if len(rulesToAdd) < len(rules) {
+			logger.Debugw("skipping ACLs which are already on the endpoint for other policies", "skipped", len(rules)-len(rulesToAdd), "endpointID", epID)
+		}
+	}
+
+	if len(rulesToAdd) > 0 {
+		epPolicyRequest, err := getEPPolicyReqFromACLSettings(rulesToAdd)
+		if err != nil {
+			return err
+		}
+		if err := pMgr.applyPoliciesToEndpointID(epID, epPolicyRequest); err != nil {
+			return err
+		}
+	}
+
+	if refs != nil {
+		pMgr.aclRefs.set(epID, refs)
+	}
+	return nil
+}
+
 // getEPPolicyReqFromACLSettings converts given ACLSettings into PolicyEndpointRequest
 func getEPPolicyReqFromACLSettings(settings []*NPMACLPolSettings) (hcn.PolicyEndpointRequest, error) {
 	policyToAdd := hcn.PolicyEndpointRequest{
@@ -470,10 +1147,10 @@ func getEPPolicyReqFromACLSettings(settings []*NPMACLPolSettings) (hcn.PolicyEnd
 
 	for i, acl := range settings {
 		// FIXME a lot of prints
-		klog.Infof("Acl settings: %+v", acl)
+		logger.Debugw("ACL settings", "acl", acl)
 		byteACL, err := json.Marshal(acl)
 		if err != nil {
-			klog.Infof("[PolicyManagerWindows] Failed to marshall ACL settings %+v", acl)
+			logger.Errorw("failed to marshal ACL settings", "acl", acl, "error", err)
 			return hcn.PolicyEndpointRequest{}, ErrFailedMarshalACLSettings
 		}
 
@@ -487,20 +1164,40 @@ func getEPPolicyReqFromACLSettings(settings []*NPMACLPolSettings) (hcn.PolicyEnd
 }
 
 func (pMgr *PolicyManager) getSettingsFromACL(policy *NPMNetworkPolicy) ([]*NPMACLPolSettings, error) {
+	if pMgr.PolicyMode == IPPolicyMode && pMgr.ipsetIPs == nil {
+		return nil, ErrNoIPSetIPsGetter
+	}
+
 	// +1 for readiness probe ACL
-	hnsRules := make([]*NPMACLPolSettings, len(policy.ACLs)+1)
+	hnsRules := make([]*NPMACLPolSettings, 0, len(policy.ACLs)+1)
 	for i, acl := range policy.ACLs {
 		rule, err := acl.convertToAclSettings(policy.ACLPolicyID)
 		if err != nil {
 			// TODO need some retry mechanism to check why the translations failed
 			return hnsRules, err
 		}
-		hnsRules[i] = rule
+		if policy.IsTiered() {
+			rule.Priority, err = tierRulePriority(policy, i)
+			if err != nil {
+				return hnsRules, err
+			}
+		}
+		if pMgr.PolicyMode == IPPolicyMode {
+			ok, err := acl.replaceSetsWithIPs(rule, pMgr.ipsetIPs)
+			if err != nil {
+				return hnsRules, err
+			}
+			if !ok {
+				logger.Debugw("skipping ACL whose sets have no IPs in common", "policy", policy.PolicyKey)
+				continue
+			}
+		}
+		hnsRules = append(hnsRules, rule)
 	}
 
 	// fixes #1881
 	// readiness probe ACL. allows ingress from host to pod
-	hnsRules[len(policy.ACLs)] = &NPMACLPolSettings{
+	hnsRules = append(hnsRules, &NPMACLPolSettings{
 		Id:              policy.ACLPolicyID,
 		Action:          hcn.ActionTypeAllow,
 		Direction:       hcn.DirectionTypeIn,
@@ -508,7 +1205,7 @@ func (pMgr *PolicyManager) getSettingsFromACL(policy *NPMNetworkPolicy) ([]*NPMA
 		Protocols:       "", // any protocol
 		Priority:        priority201,
 		RuleType:        hcn.RuleTypeSwitch,
-	}
+	})
 	return hnsRules, nil
 }
 
@@ -557,7 +1254,7 @@ func (epBuilder *endpointPolicyBuilder) compareAndRemovePolicies(ruleIDToRemove
 		// First check if ID is present and equal, this saves compute cycles to compare both objects
 		if ruleIDToRemove == acl.Id {
 			// Remove the ACL policy from the list
-			klog.Infof("[PolicyManagerWindows] Found ACL with ID %s and removing it", acl.Id)
+			logger.Debugw("found ACL and removing it", "aclID", acl.Id)
 			toDeleteIndexes[i] = struct{}{}
 			lenOfRulesToRemove--
 			aclFound = true
@@ -566,18 +1263,39 @@ func (epBuilder *endpointPolicyBuilder) compareAndRemovePolicies(ruleIDToRemove
 	// If ACl Policies are not found, it means that we might have removed them earlier
 	// or never applied them
 	if !aclFound {
-		klog.Infof("[PolicyManagerWindows] ACL with ID %s is not Found in Dataplane", ruleIDToRemove)
+		logger.Debugw("ACL with ID is not found in dataplane", "aclID", ruleIDToRemove)
 		return aclFound
 	}
 	epBuilder.removeACLPolicyAtIndex(toDeleteIndexes)
 	// if there are still rules to remove, it means that we might have not added all the policies in the add
 	// case and were only able to find a portion of the rules to remove
 	if lenOfRulesToRemove > 0 {
-		klog.Infof("[PolicyManagerWindows] did not find %d no of ACLs to remove", lenOfRulesToRemove)
+		logger.Debugw("did not find all ACLs to remove", "missing", lenOfRulesToRemove)
 	}
 	return aclFound
 }
 
+// removePoliciesWithIDs removes the ACLs with any of the IDs, and returns whether any ACL was found.
+func (epBuilder *endpointPolicyBuilder) removePoliciesWithIDs(aclIDs map[string]struct{}) bool {
+	toDeleteIndexes := map[int]struct{}{}
+	for i, acl := range epBuilder.aclPolicies {
+		if _, ok := aclIDs[acl.Id]; ok {
+			toDeleteIndexes[i] = struct{}{}
+		}
+	}
+	epBuilder.removeACLPolicyAtIndex(toDeleteIndexes)
+	return len(toDeleteIndexes) > 0
+}
+
+// reassignACLs adds back the ACLs of removed policies which other policies on the endpoint still reference,
+// with the ID of their new holder (see endpointACLRefs).
+func (epBuilder *endpointPolicyBuilder) reassignACLs(rules []*NPMACLPolSettings) {
+	for _, rule := range rules {
+		logger.Debugw("reassigning shared ACL to policy", "aclID", rule.Id)
+	}
+	epBuilder.aclPolicies = append(epBuilder.aclPolicies, rules...)
+}
+
 func (epBuilder *endpointPolicyBuilder) resetAllNPMAclPolicies() bool {
 	if len(epBuilder.aclPolicies) == 0 {
 		return false
@@ -588,7 +1306,7 @@ func (epBuilder *endpointPolicyBuilder) resetAllNPMAclPolicies() bool {
 		// First check if ID is present and equal, this saves compute cycles to compare both objects
 		if strings.HasPrefix(acl.Id, policyIDPrefix) {
 			// Remove the ACL policy from the list
-			klog.Infof("[PolicyManagerWindows] Found ACL with ID %s and removing it", acl.Id)
+			logger.Debugw("found ACL and removing it", "aclID", acl.Id)
 			toDeleteIndexes[i] = struct{}{}
 			aclFound = true
 		}
@@ -618,3 +1336,138 @@ func isNotFoundErr(err error) bool {
 	var notFoundErr hcn.EndpointNotFoundError
 	return errors.As(err, &notFoundErr)
 }
+
+// endpointACLRefs has the references to the NPM ACLs of each endpoint, by endpoint ID.
+// Identical ACLs of the policies on an endpoint are applied once to save the endpoint's HNS rule budget,
+// with the ACL policy ID of one of these policies (the holder), and are only removed with the last of these policies.
+// ACLs aren't deduplicated in IPPolicyMode, where the rules of a policy change with the IPs of its ipsets.
+type endpointACLRefs struct {
+	sync.Mutex
+	byEndpoint map[string]aclRefs
+}
+
+func newEndpointACLRefs() *endpointACLRefs {
+	return &endpointACLRefs{
+		byEndpoint: make(map[string]aclRefs),
+	}
+}
+
+// get returns a copy of the endpoint's references, to be modified and then saved with set once HNS is updated.
+func (r *endpointACLRefs) get(epID string) aclRefs {
+	r.Lock()
+	defer r.Unlock()
+	return r.byEndpoint[epID].clone()
+}
+
+func (r *endpointACLRefs) set(epID string, refs aclRefs) {
+	r.Lock()
+	defer r.Unlock()
+	if len(refs) == 0 {
+		delete(r.byEndpoint, epID)
+		return
+	}
+	r.byEndpoint[epID] = refs
+}
+
+func (r *endpointACLRefs) delete(epID string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.byEndpoint, epID)
+}
+
+// retain deletes the references of the endpoints which aren't in epIDs.
+func (r *endpointACLRefs) retain(epIDs map[string]struct{}) {
+	r.Lock()
+	defer r.Unlock()
+	for epID := range r.byEndpoint {
+		if _, ok := epIDs[epID]; !ok {
+			delete(r.byEndpoint, epID)
+		}
+	}
+}
+
+// aclRef is an ACL applied once to an endpoint for every policy there with an identical rule.
+type aclRef struct {
+	// rule is the ACL in HNS, with the ACL policy ID of its holder
+	rule *NPMACLPolSettings
+	// referrers are the ACL policy IDs of the policies with the rule, including the holder
+	referrers map[string]struct{}
+}
+
+// aclRefs are the references to the ACLs of an endpoint, keyed by rule without its ID.
+type aclRefs map[string]*aclRef
+
+func aclRefKey(rule *NPMACLPolSettings) string {
+	key := *rule
+	key.Id = ""
+	return fmt.Sprintf("%+v", key)
+}
+
+func (refs aclRefs) clone() aclRefs {
+	c := make(aclRefs, len(refs))
+	for key, ref := range refs {
+		referrers := make(map[string]struct{}, len(ref.referrers))
+		for aclID := range ref.referrers {
+			referrers[aclID] = struct{}{}
+		}
+		c[key] = &aclRef{rule: ref.rule, referrers: referrers}
+	}
+	return c
+}
+
+// add references the rules for the policies of their IDs, and returns the rules which aren't on the endpoint yet.
+func (refs aclRefs) add(rules []*NPMACLPolSettings) []*NPMACLPolSettings {
+	rulesToAdd := make([]*NPMACLPolSettings, 0, len(rules))
+	for _, rule := range rules {
+		key := aclRefKey(rule)
+		if ref, ok := refs[key]; ok {
+			ref.referrers[rule.Id] = struct{}{}
+			continue
+		}
+		refs[key] = &aclRef{
+			rule:      rule,
+			referrers: map[string]struct{}{rule.Id: {}},
+		}
+		rulesToAdd = append(rulesToAdd, rule)
+	}
+	return rulesToAdd
+}
+
+// remove dereferences the rules of the ACL policy IDs. It returns the rules held by these policies
+// which other policies still reference, with the ID of their new holder. They must be re-applied.
+func (refs aclRefs) remove(aclIDs map[string]struct{}) []*NPMACLPolSettings {
+	reassignedRules := make([]*NPMACLPolSettings, 0)
+	for key, ref := range refs {
+		for aclID := range aclIDs {
+			delete(ref.referrers, aclID)
+		}
+		if len(ref.referrers) == 0 {
+			delete(refs, key)
+			continue
+		}
+		if _, ok := aclIDs[ref.rule.Id]; !ok {
+			continue
+		}
+
+		// the new holder is the first remaining referrer, so that it doesn't depend on map order
+		remaining := make([]string, 0, len(ref.referrers))
+		for aclID := range ref.referrers {
+			remaining = append(remaining, aclID)
+		}
+		sort.Strings(remaining)
+		rule := *ref.rule
+		rule.Id = remaining[0]
+		ref.rule = &rule
+		reassignedRules = append(reassignedRules, &rule)
+	}
+	return reassignedRules
+}
+
+// heldRules returns the rules held by each ACL policy ID, i.e. the rules with this ID in HNS.
+func (refs aclRefs) heldRules() map[string][]*NPMACLPolSettings {
+	held := make(map[string][]*NPMACLPolSettings)
+	for _, ref := range refs {
+		held[ref.rule.Id] = append(held[ref.rule.Id], ref.rule)
+	}
+	return held
+}
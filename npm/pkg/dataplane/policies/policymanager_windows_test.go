@@ -10,6 +10,8 @@ import (
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/Microsoft/hcsshim/hcn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -62,7 +64,7 @@ var (
 			LocalAddresses:  ipsets.TestCIDRSet.HashedName,
 			RemoteAddresses: ipsets.TestKeyPodSet.HashedName,
 			RemotePorts:     "",
-			LocalPorts:      getPortStr(222, 333),
+			LocalPorts:      "222-333",
 			Priority:        blockRulePriotity,
 		},
 		{
@@ -143,6 +145,13 @@ func TestCompareAndRemovePolicies(t *testing.T) {
 	}
 }
 
+func TestGetPortStrFromPorts(t *testing.T) {
+	require.Equal(t, "", getPortStrFromPorts(Ports{}))
+	require.Equal(t, "80", getPortStrFromPorts(Ports{Port: 80}))
+	require.Equal(t, "80", getPortStrFromPorts(Ports{Port: 80, EndPort: 80}))
+	require.Equal(t, "8000-8100", getPortStrFromPorts(Ports{Port: 8000, EndPort: 8100}))
+}
+
 func TestAddPolicies(t *testing.T) {
 	metrics.InitializeWindowsMetrics()
 
@@ -175,6 +184,26 @@ func TestAddPolicies(t *testing.T) {
 	}.test(t)
 }
 
+func TestAddPoliciesAuditMode(t *testing.T) {
+	metrics.InitializeWindowsMetrics()
+
+	pMgr, hns := getPMgr(t)
+	pMgr.PolicyManagerCfg = &PolicyManagerCfg{NodeIP: ipsetConfig.NodeIP, PolicyMode: ipsetConfig.PolicyMode, AuditMode: true}
+
+	err := pMgr.AddPolicies([]*NPMNetworkPolicy{TestNetworkPolicies[0]}, endpointIDListCopy())
+	require.NoError(t, err)
+	require.Len(t, TestNetworkPolicies[0].PodEndpoints, len(endPointIDList))
+
+	aclPolicies, err := hns.Cache.ACLPolicies(endPointIDList, TestNetworkPolicies[0].ACLPolicyID)
+	require.NoError(t, err)
+	for _, id := range endPointIDList {
+		require.Empty(t, aclPolicies[id], "expected endpoint ID %s to have no ACLs in audit mode", id)
+	}
+
+	require.NoError(t, pMgr.RemovePolicy(TestNetworkPolicies[0].PolicyKey))
+	require.Empty(t, TestNetworkPolicies[0].PodEndpoints)
+}
+
 func TestRemovePolicies(t *testing.T) {
 	metrics.InitializeWindowsMetrics()
 
@@ -270,6 +299,406 @@ func TestRemovePoliciesEndpointNotFound(t *testing.T) {
 	}.test(t)
 }
 
+func TestRemoveAllPolicies(t *testing.T) {
+	metrics.InitializeWindowsMetrics()
+
+	pMgr, hns := getPMgr(t)
+
+	// AddPolicy may modify the endpointIDList, so we need to pass a copy
+	err := pMgr.AddPolicies([]*NPMNetworkPolicy{TestNetworkPolicies[0]}, endpointIDListCopy())
+	require.NoError(t, err)
+	err = pMgr.AddPolicies([]*NPMNetworkPolicy{TestNetworkPolicies[1]}, endpointIDListCopy())
+	require.NoError(t, err)
+
+	policyKeys := map[string]struct{}{
+		TestNetworkPolicies[0].PolicyKey: {},
+		TestNetworkPolicies[1].PolicyKey: {},
+		"x/not-in-cache":                 {},
+	}
+	removedPolicies, err := pMgr.RemoveAllPolicies(policyKeys, "test1", "10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, policyKeys, removedPolicies)
+
+	epACLs := hns.Cache.GetAllACLs()
+	require.Empty(t, epACLs["test1"])
+	require.NotEmpty(t, epACLs["test2"])
+	for _, policy := range TestNetworkPolicies[:2] {
+		require.NotContains(t, policy.PodEndpoints, "10.0.0.1")
+		require.Contains(t, policy.PodEndpoints, "10.0.0.2")
+	}
+
+	// the endpoint is gone, so there is nothing to remove
+	removedPolicies, err = pMgr.RemoveAllPolicies(policyKeys, "test10", "10.0.0.5")
+	require.NoError(t, err)
+	require.Equal(t, policyKeys, removedPolicies)
+}
+
+func TestAddRemovePolicyConcurrentEndpoints(t *testing.T) {
+	metrics.InitializeWindowsMetrics()
+
+	hns := ipsets.GetHNSFake(t, "azure")
+	io := common.NewMockIOShimWithFakeHNS(hns)
+	endpoints := make(map[string]string, 20)
+	for i := 0; i < 20; i++ {
+		endpoints[fmt.Sprintf("10.0.1.%d", i)] = fmt.Sprintf("test-concurrent-%d", i)
+	}
+	dptestutils.AddIPsToHNS(t, hns, endpoints)
+
+	cfg := *ipsetConfig
+	cfg.EndpointConcurrency = 4
+	pMgr := NewPolicyManager(io, &cfg)
+
+	policy := *TestNetworkPolicies[0]
+	policy.PodEndpoints = nil
+	endpointsCopy := make(map[string]string, len(endpoints))
+	for ip, epID := range endpoints {
+		endpointsCopy[ip] = epID
+	}
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{&policy}, endpointsCopy))
+	require.Equal(t, endpoints, policy.PodEndpoints)
+
+	aclPolicies, err := hns.Cache.ACLPolicies(endpoints, policy.ACLPolicyID)
+	require.NoError(t, err)
+	for _, epID := range endpoints {
+		verifyFakeHNSCacheACLs(t, expectedACLs, aclPolicies[epID])
+	}
+
+	require.NoError(t, pMgr.RemovePolicy(policy.PolicyKey))
+	require.Empty(t, policy.PodEndpoints)
+	verifyACLCacheIsCleaned(t, hns, len(endpoints))
+
+	winPromVals{
+		getEndpointLatencyCalls: 2 * len(endpoints),
+		getEndpointFailures:     0,
+		createLatencyCalls:      len(endpoints),
+		createFailures:          0,
+		updateLatencyCalls:      len(endpoints),
+		updateFailures:          0,
+	}.test(t)
+}
+
+func TestAddPolicyPartialFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		rollback bool
+	}{
+		{name: "without rollback", rollback: false},
+		{name: "with rollback", rollback: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.InitializeWindowsMetrics()
+
+			pMgr, hns := getPMgr(t)
+			cfg := *ipsetConfig
+			cfg.RollbackPolicyOnFailure = tt.rollback
+			pMgr.PolicyManagerCfg = &cfg
+
+			// endpoints are updated one at a time, so the policy is only added to the second endpoint
+			hns.FailNextCalls("ApplyEndpointPolicy", 1, nil)
+
+			policy := *TestNetworkPolicies[0]
+			policy.PodEndpoints = nil
+			require.Error(t, pMgr.AddPolicies([]*NPMNetworkPolicy{&policy}, endpointIDListCopy()))
+
+			aclPolicies, err := hns.Cache.ACLPolicies(endPointIDList, policy.ACLPolicyID)
+			require.NoError(t, err)
+			numWithACLs := 0
+			for _, epID := range endPointIDList {
+				if len(aclPolicies[epID]) > 0 {
+					numWithACLs++
+				}
+			}
+
+			if tt.rollback {
+				require.Empty(t, policy.PodEndpoints)
+				require.Zero(t, numWithACLs, "expected the ACLs to be removed from the endpoint the policy was added to")
+			} else {
+				require.Len(t, policy.PodEndpoints, 1)
+				require.Equal(t, 1, numWithACLs, "expected the ACLs to stay on the endpoint the policy was added to")
+			}
+		})
+	}
+}
+
+func TestReconcileRepairsACLDrift(t *testing.T) {
+	metrics.InitializeWindowsMetrics()
+
+	pMgr, hns := getPMgr(t)
+
+	// AddPolicy may modify the endpointIDList, so we need to pass a copy
+	err := pMgr.AddPolicies([]*NPMNetworkPolicy{TestNetworkPolicies[0]}, endpointIDListCopy())
+	require.NoError(t, err)
+
+	// test1 lost its ACLs, e.g. after an HNS restart
+	ep1, err := hns.GetEndpointByID("test1")
+	require.NoError(t, err)
+	require.NoError(t, hns.ApplyEndpointPolicy(ep1, hcn.RequestTypeUpdate, hcn.PolicyEndpointRequest{}))
+
+	// test2 was recreated out of band with the same IP
+	ep2, err := hns.GetEndpointByID("test2")
+	require.NoError(t, err)
+	require.NoError(t, hns.DeleteEndpoint(ep2))
+	dptestutils.AddIPsToHNS(t, hns, map[string]string{"10.0.0.2": "test2-new"})
+
+	// test3 has the ACLs of a policy which no longer exists
+	dptestutils.AddIPsToHNS(t, hns, map[string]string{"10.0.0.3": "test3"})
+	orphanedACLs, err := getEPPolicyReqFromACLSettings([]*NPMACLPolSettings{
+		{Id: aclPolicyID("x", "deleted-policy"), Action: hcn.ActionTypeAllow, Direction: hcn.DirectionTypeIn, Priority: allowRulePriotity},
+		baseACLsForCalicoCNI[0],
+	})
+	require.NoError(t, err)
+	ep3, err := hns.GetEndpointByID("test3")
+	require.NoError(t, err)
+	require.NoError(t, hns.ApplyEndpointPolicy(ep3, hcn.RequestTypeAdd, orphanedACLs))
+
+	pMgr.Reconcile()
+
+	aclID := TestNetworkPolicies[0].ACLPolicyID
+	reconciledEndpoints := map[string]string{"10.0.0.1": "test1", "10.0.0.2": "test2-new"}
+	aclPolicies, err := hns.Cache.ACLPolicies(reconciledEndpoints, aclID)
+	require.NoError(t, err)
+	for _, id := range []string{"test1", "test2-new"} {
+		acls, ok := aclPolicies[id]
+		require.True(t, ok, "Expected endpoint ID %s to have ACLs", id)
+		verifyFakeHNSCacheACLs(t, expectedACLs, acls)
+	}
+	require.Equal(t, reconciledEndpoints, TestNetworkPolicies[0].PodEndpoints)
+
+	// only the base ACL for Calico CNI is left on test3
+	acls := hns.Cache.GetAllACLs()["test3"]
+	require.Len(t, acls, 1)
+	require.Equal(t, baseACLsForCalicoCNI[0].Id, acls[0].ID)
+
+	// reconciling again changes nothing
+	pMgr.Reconcile()
+	aclPolicies, err = hns.Cache.ACLPolicies(reconciledEndpoints, aclID)
+	require.NoError(t, err)
+	for _, id := range reconciledEndpoints {
+		verifyFakeHNSCacheACLs(t, expectedACLs, aclPolicies[id])
+	}
+}
+
+func TestRemoveOrphanedACLs(t *testing.T) {
+	metrics.InitializeWindowsMetrics()
+
+	pMgr, hns := getPMgr(t)
+	err := pMgr.AddPolicies([]*NPMNetworkPolicy{TestNetworkPolicies[0]}, endpointIDListCopy())
+	require.NoError(t, err)
+
+	// test1 has the ACLs of an existing policy and of a deleted one, test3 isn't attached to a running pod
+	dptestutils.AddIPsToHNS(t, hns, map[string]string{"10.0.0.3": "test3"})
+	orphanedACLs, err := getEPPolicyReqFromACLSettings([]*NPMACLPolSettings{
+		{Id: aclPolicyID("x", "deleted-policy"), Action: hcn.ActionTypeAllow, Direction: hcn.DirectionTypeIn, Priority: allowRulePriotity},
+		baseACLsForCalicoCNI[0],
+	})
+	require.NoError(t, err)
+	endpoints := make([]*hcn.HostComputeEndpoint, 0, 3)
+	for _, id := range []string{"test1", "test3"} {
+		ep, err := hns.GetEndpointByID(id)
+		require.NoError(t, err)
+		require.NoError(t, hns.ApplyEndpointPolicy(ep, hcn.RequestTypeAdd, orphanedACLs))
+	}
+	for _, id := range []string{"test1", "test2", "test3"} {
+		ep, err := hns.GetEndpointByID(id)
+		require.NoError(t, err)
+		endpoints = append(endpoints, ep)
+	}
+
+	numRemoved, err := pMgr.RemoveOrphanedACLs(endpoints)
+	require.NoError(t, err)
+	require.Equal(t, 2, numRemoved)
+	count, err := metrics.TotalOrphanedACLsRemoved()
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	// the ACLs of the existing policy and the base ACL for Calico CNI are kept
+	aclPolicies, err := hns.Cache.ACLPolicies(endpointIDListCopy(), TestNetworkPolicies[0].ACLPolicyID)
+	require.NoError(t, err)
+	for _, id := range endpointIDListCopy() {
+		verifyFakeHNSCacheACLs(t, expectedACLs, aclPolicies[id])
+	}
+	acls := hns.Cache.GetAllACLs()["test3"]
+	require.Len(t, acls, 1)
+	require.Equal(t, baseACLsForCalicoCNI[0].Id, acls[0].ID)
+	require.Len(t, hns.Cache.GetAllACLs()["test1"], len(expectedACLs)+1)
+}
+
+func TestDedupACLsAcrossPolicies(t *testing.T) {
+	metrics.InitializeWindowsMetrics()
+
+	pMgr, hns := getPMgr(t)
+
+	// a copy of the first policy with another name has identical ACLs
+	policy1 := TestNetworkPolicies[0]
+	policy2 := *TestNetworkPolicies[0]
+	policy2.PolicyKey = "x/test1-copy"
+	policy2.ACLPolicyID = aclPolicyID("x", "test1-copy")
+	policy2.PodEndpoints = nil
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{policy1}, endpointIDListCopy()))
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{&policy2}, endpointIDListCopy()))
+	require.Equal(t, endPointIDList, policy2.PodEndpoints)
+
+	// the ACLs are only applied once, with the ID of the first policy
+	epACLs := hns.Cache.GetAllACLs()
+	for _, epID := range endPointIDList {
+		verifyFakeHNSCacheACLs(t, expectedACLs, epACLs[epID])
+	}
+
+	// reconciling doesn't re-apply the second policy's ACLs
+	pMgr.Reconcile()
+	epACLs = hns.Cache.GetAllACLs()
+	for _, epID := range endPointIDList {
+		verifyFakeHNSCacheACLs(t, expectedACLs, epACLs[epID])
+	}
+
+	// the ACLs are kept for the second policy when the first is removed
+	require.NoError(t, pMgr.RemovePolicy(policy1.PolicyKey))
+	expectedCopyACLs := make([]*hnswrapper.FakeEndpointPolicy, 0, len(expectedACLs))
+	for _, acl := range expectedACLs {
+		aclCopy := *acl
+		aclCopy.ID = policy2.ACLPolicyID
+		expectedCopyACLs = append(expectedCopyACLs, &aclCopy)
+	}
+	epACLs = hns.Cache.GetAllACLs()
+	for _, epID := range endPointIDList {
+		verifyFakeHNSCacheACLs(t, expectedCopyACLs, epACLs[epID])
+	}
+
+	// the ACLs are removed with the last policy
+	require.NoError(t, pMgr.RemovePolicy(policy2.PolicyKey))
+	verifyACLCacheIsCleaned(t, hns, len(endPointIDList))
+}
+
+func TestACLRefs(t *testing.T) {
+	ruleA := &NPMACLPolSettings{Id: "a", Action: hcn.ActionTypeAllow, Direction: hcn.DirectionTypeIn, Priority: allowRulePriotity}
+	ruleB := &NPMACLPolSettings{Id: "b", Action: hcn.ActionTypeAllow, Direction: hcn.DirectionTypeIn, Priority: allowRulePriotity}
+	ruleC := &NPMACLPolSettings{Id: "c", Action: hcn.ActionTypeAllow, Direction: hcn.DirectionTypeIn, Priority: allowRulePriotity}
+	otherRuleB := &NPMACLPolSettings{Id: "b", Action: hcn.ActionTypeBlock, Direction: hcn.DirectionTypeOut, Priority: blockRulePriotity}
+
+	refs := aclRefs{}
+	require.Equal(t, []*NPMACLPolSettings{ruleA}, refs.add([]*NPMACLPolSettings{ruleA}))
+	require.Equal(t, []*NPMACLPolSettings{otherRuleB}, refs.add([]*NPMACLPolSettings{ruleB, otherRuleB}))
+	require.Empty(t, refs.add([]*NPMACLPolSettings{ruleC}))
+
+	// changes to a clone don't affect the original
+	clone := refs.clone()
+	clone.remove(map[string]struct{}{"a": {}, "b": {}, "c": {}})
+	require.Empty(t, clone)
+	require.Len(t, refs, 2)
+
+	// the shared rule moves to the first remaining referrer
+	reassigned := refs.remove(map[string]struct{}{"a": {}})
+	require.Len(t, reassigned, 1)
+	require.Equal(t, "b", reassigned[0].Id)
+	held := refs.heldRules()
+	require.Len(t, held, 1)
+	require.ElementsMatch(t, []*NPMACLPolSettings{reassigned[0], otherRuleB}, held["b"])
+
+	// rules which aren't held by the removed policy stay where they are
+	require.Empty(t, refs.remove(map[string]struct{}{"c": {}}))
+	require.Empty(t, refs.remove(map[string]struct{}{"b": {}}))
+	require.Empty(t, refs)
+}
+
+func TestRefreshPolicyIPs(t *testing.T) {
+	metrics.InitializeWindowsMetrics()
+
+	hns := ipsets.GetHNSFake(t, "azure")
+	io := common.NewMockIOShimWithFakeHNS(hns)
+	endpoints := map[string]string{"10.0.0.1": "test1"}
+	dptestutils.AddIPsToHNS(t, hns, endpoints)
+
+	iMgr := ipsets.NewIPSetManager(&ipsets.IPSetManagerCfg{
+		IPSetMode:       ipsets.ApplyAllIPSets,
+		NetworkName:     "azure",
+		SkipSetPolicies: true,
+	}, io)
+	cfg := *ipsetConfig
+	cfg.PolicyMode = IPPolicyMode
+	pMgr := NewPolicyManager(io, &cfg)
+	pMgr.SetIPSetIPsGetter(iMgr)
+
+	podSet := ipsets.TestKVPodSet.Metadata
+	nsSet := ipsets.TestNSSet.Metadata
+	policy := &NPMNetworkPolicy{
+		Namespace:   "x",
+		PolicyKey:   "x/test-ip-mode",
+		ACLPolicyID: aclPolicyID("x", "test-ip-mode"),
+		ACLs: []*ACLPolicy{
+			{
+				SrcList:   []SetInfo{{IPSet: podSet, Included: true, MatchType: SrcMatch}},
+				Target:    Allowed,
+				Direction: Ingress,
+				DstPorts:  Ports{Port: 80},
+				Protocol:  TCP,
+			},
+			{
+				DstList:   []SetInfo{{IPSet: nsSet, Included: true, MatchType: DstMatch}},
+				Target:    Allowed,
+				Direction: Egress,
+				Protocol:  TCP,
+			},
+		},
+	}
+	for _, set := range []*ipsets.IPSetMetadata{podSet, nsSet} {
+		require.NoError(t, iMgr.AddReference(set, policy.PolicyKey, ipsets.NetPolType))
+	}
+	require.NoError(t, iMgr.AddToSets([]*ipsets.IPSetMetadata{podSet}, "10.0.0.5", "x/a"))
+	require.NoError(t, iMgr.AddToSets([]*ipsets.IPSetMetadata{nsSet}, "10.0.0.6", "y/b"))
+	require.NoError(t, iMgr.ApplyIPSets())
+	require.Equal(t, map[string]struct{}{policy.PolicyKey: {}}, iMgr.TakeNetPolsWithUpdatedSets())
+	require.Empty(t, hns.Cache.AllSetPolicies(common.FakeHNSNetworkID), "sets shouldn't be added to HNS")
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{policy}, map[string]string{"10.0.0.1": "test1"}))
+
+	readinessACL := &hnswrapper.FakeEndpointPolicy{
+		ID:              policy.ACLPolicyID,
+		Action:          hcn.ActionTypeAllow,
+		Direction:       hcn.DirectionTypeIn,
+		RemoteAddresses: ipsetConfig.NodeIP,
+		Priority:        priority201,
+	}
+	ingressACL := &hnswrapper.FakeEndpointPolicy{
+		ID:             policy.ACLPolicyID,
+		Protocols:      "6",
+		Action:         hcn.ActionTypeAllow,
+		Direction:      hcn.DirectionTypeIn,
+		LocalAddresses: "10.0.0.5",
+		LocalPorts:     "80",
+		Priority:       allowRulePriotity,
+	}
+	// with IPs, the destination IPs of egress ACLs are the local addresses
+	egressACL := &hnswrapper.FakeEndpointPolicy{
+		ID:             policy.ACLPolicyID,
+		Protocols:      "6",
+		Action:         hcn.ActionTypeAllow,
+		Direction:      hcn.DirectionTypeOut,
+		LocalAddresses: "10.0.0.6",
+		Priority:       allowRulePriotity,
+	}
+	aclPolicies, err := hns.Cache.ACLPolicies(endpoints, policy.ACLPolicyID)
+	require.NoError(t, err)
+	verifyFakeHNSCacheACLs(t, []*hnswrapper.FakeEndpointPolicy{ingressACL, egressACL, readinessACL}, aclPolicies["test1"])
+
+	// a pod joins the ingress set and the namespace of the egress set becomes empty
+	require.NoError(t, iMgr.AddToSets([]*ipsets.IPSetMetadata{podSet}, "10.0.0.7", "x/c"))
+	require.NoError(t, iMgr.RemoveFromSets([]*ipsets.IPSetMetadata{nsSet}, "10.0.0.6", "y/b"))
+	require.NoError(t, iMgr.ApplyIPSets())
+	policyKeys := iMgr.TakeNetPolsWithUpdatedSets()
+	require.Equal(t, map[string]struct{}{policy.PolicyKey: {}}, policyKeys)
+
+	require.NoError(t, pMgr.RefreshPolicyIPs(policyKeys))
+
+	ingressACL.LocalAddresses = "10.0.0.5,10.0.0.7"
+	aclPolicies, err = hns.Cache.ACLPolicies(endpoints, policy.ACLPolicyID)
+	require.NoError(t, err)
+	// the egress ACL would allow all traffic without addresses, so it's removed
+	verifyFakeHNSCacheACLs(t, []*hnswrapper.FakeEndpointPolicy{ingressACL, readinessACL}, aclPolicies["test1"])
+}
+
 // Helper functions for UTS
 
 func getPMgr(t *testing.T) (*PolicyManager, *hnswrapper.Hnsv2wrapperFake) {
@@ -326,15 +755,72 @@ func verifyACLCacheIsCleaned(t *testing.T, hns *hnswrapper.Hnsv2wrapperFake, len
 	}
 }
 
-func getPortStr(start, end int32) string {
-	portStr := fmt.Sprintf("%d", start)
-	if start == end || end == 0 {
-		return portStr
+// BenchmarkAddAllPolicies measures adding all policies to a new endpoint, in batches of MaxBatchedACLsPerPod rules.
+func BenchmarkAddAllPolicies(b *testing.B) {
+	testutils.DiscardKlog(b)
+	const (
+		epIP = "10.0.0.3"
+		epID = "test3"
+	)
+	for _, maxBatchedACLs := range []int{30, 100, 1000} {
+		maxBatchedACLs := maxBatchedACLs
+		b.Run(fmt.Sprintf("policies=200/acls=10/batch=%d", maxBatchedACLs), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				hns := ipsets.GetHNSFake(b, "azure")
+				dptestutils.AddIPsToHNS(b, hns, map[string]string{epIP: epID})
+				cfg := *ipsetConfig
+				cfg.MaxBatchedACLsPerPod = maxBatchedACLs
+				pMgr := NewPolicyManager(common.NewMockIOShimWithFakeHNS(hns), &cfg)
+				netpols := benchmarkPolicies(200, 10)
+				// caches the policies without adding them to any endpoint
+				require.NoError(b, pMgr.AddPolicies(netpols, nil))
+				policyKeys := make(map[string]struct{}, len(netpols))
+				for _, netpol := range netpols {
+					policyKeys[netpol.PolicyKey] = struct{}{}
+				}
+				b.StartTimer()
+
+				added, err := pMgr.AddAllPolicies(policyKeys, epID, epIP)
+				require.NoError(b, err)
+				require.Len(b, added, len(netpols))
+			}
+		})
 	}
+}
 
-	for i := start + 1; i <= end; i++ {
-		portStr += fmt.Sprintf(",%d", i)
+func TestTierRulePriority(t *testing.T) {
+	anp := NewAdminNPMNetworkPolicy("anp", 10)
+	anp.ACLs = []*ACLPolicy{
+		NewACLPolicy(Allowed, Ingress),
+		NewACLPolicy(Allowed, Ingress),
+		NewACLPolicy(Dropped, Egress),
+		NewACLPolicy(Dropped, Ingress),
+		NewACLPolicy(Allowed, Ingress),
+		NewACLPolicy(Allowed, Egress),
+	}
+	// the priority only advances when the action changes from the previous ACL of the same direction
+	expected := []uint16{41, 41, 41, 42, 43, 42}
+	for i, priority := range expected {
+		actual, err := tierRulePriority(anp, i)
+		require.NoError(t, err)
+		require.Equal(t, priority, actual, "wrong priority for ACL %d", i)
 	}
 
-	return portStr
+	anp.ACLs = append(anp.ACLs, NewACLPolicy(Dropped, Ingress), NewACLPolicy(Allowed, Ingress))
+	_, err := tierRulePriority(anp, len(anp.ACLs)-1)
+	require.ErrorIs(t, err, ErrTooManyAdminRuleActions)
+
+	highest, err := tierRulePriority(NewAdminNPMNetworkPolicy("highest", MaxWindowsAdminPriority), 0)
+	require.NoError(t, err)
+	require.Less(t, highest+adminRulesPerPriority-1, uint16(priority200), "admin ACLs must be evaluated before the base ACLs")
+	_, err = tierRulePriority(NewAdminNPMNetworkPolicy("unsupported", MaxWindowsAdminPriority+1), 0)
+	require.ErrorIs(t, err, ErrAdminPriorityNotSupported)
+
+	banp := NewBaselineNPMNetworkPolicy("default")
+	banp.ACLs = []*ACLPolicy{NewACLPolicy(Allowed, Ingress), NewACLPolicy(Allowed, Ingress)}
+	actual, err := tierRulePriority(banp, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint16(4001), actual)
 }
@@ -0,0 +1,9 @@
+package policies
+
+// RenderPolicies returns the iptables-restore payload which the PolicyManager applies when adding the policies
+// to a node without any other policies. It is used to compare the dataplane output with golden files.
+func RenderPolicies(networkPolicies []*NPMNetworkPolicy) ([]byte, error) {
+	pMgr := NewPolicyManager(nil, &PolicyManagerCfg{})
+	creator := pMgr.creatorForNewNetworkPolicies(chainNames(networkPolicies), networkPolicies)
+	return []byte(creator.ToString()), nil
+}
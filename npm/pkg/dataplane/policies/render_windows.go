@@ -0,0 +1,33 @@
+package policies
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// renderNodeIP is the node IP allowed by the readiness probe ACL of rendered policies.
+const renderNodeIP = "10.240.0.4"
+
+type renderedPolicy struct {
+	PolicyKey string
+	ACLs      []*NPMACLPolSettings
+}
+
+// RenderPolicies returns the ACL settings which the PolicyManager applies to an endpoint selected by the policies,
+// as JSON. It is used to compare the dataplane output with golden files.
+func RenderPolicies(networkPolicies []*NPMNetworkPolicy) ([]byte, error) {
+	pMgr := NewPolicyManager(nil, &PolicyManagerCfg{NodeIP: renderNodeIP, PolicyMode: IPSetPolicyMode})
+	rendered := make([]renderedPolicy, 0, len(networkPolicies))
+	for _, policy := range networkPolicies {
+		rules, err := pMgr.getSettingsFromACL(policy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ACL settings for policy %s: %w", policy.PolicyKey, err)
+		}
+		rendered = append(rendered, renderedPolicy{PolicyKey: policy.PolicyKey, ACLs: rules})
+	}
+	bytes, err := json.MarshalIndent(rendered, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ACL settings: %w", err)
+	}
+	return append(bytes, '\n'), nil
+}
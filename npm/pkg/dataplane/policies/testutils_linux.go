@@ -11,6 +11,8 @@ var (
 	fakeIPTablesRestoreCommand        = testutils.TestCmd{Cmd: []string{"iptables-restore", "-w", "60", "-T", "filter", "--noflush"}}
 	fakeIPTablesRestoreFailureCommand = testutils.TestCmd{Cmd: []string{"iptables-restore", "-w", "60", "-T", "filter", "--noflush"}, ExitCode: 1}
 
+	fakeDeleteNftablesTableCommand = testutils.TestCmd{Cmd: []string{"nft", "delete", "table", "ip", "azure-npm"}, ExitCode: 1} // table didn't exist
+
 	listLineNumbersCommandStrings = []string{"iptables", "-w", "60", "-t", "filter", "-n", "-L", "FORWARD", "--line-numbers"}
 	listAllCommandStrings         = []string{"iptables", "-w", "60", "-t", "filter", "-n", "-L"}
 )
@@ -66,6 +68,7 @@ func GetBootupTestCalls(addDetectCalls bool) []testutils.TestCmd {
 			COMMIT`}, //nolint // AZURE-NPM chain didn't exist
 	}
 	bootUp := []testutils.TestCmd{
+		fakeDeleteNftablesTableCommand,
 		{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"}, ExitCode: 2}, //nolint // AZURE-NPM chain didn't exist
 		{Cmd: listAllCommandStrings, PipedToCommand: true},
 		{
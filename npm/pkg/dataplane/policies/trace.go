@@ -0,0 +1,339 @@
+package policies
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+const (
+	// FlowNotDecided means no policy selects the pod in that direction and matches the flow, so the flow is not filtered by NPM.
+	FlowNotDecided = "NOT DECIDED BY NPM"
+	// FlowAllowed means an ACL allows the flow.
+	FlowAllowed = "ALLOWED"
+	// FlowDropped means NetworkPolicies select the pod, and none of their ACLs allows the flow.
+	FlowDropped = "DROPPED"
+)
+
+// SetMatcher tells whether an IP is a member of an ipset, and which member decided it.
+// The protocol and port are only used for named port ipsets.
+type SetMatcher interface {
+	MatchIP(setName string, ip net.IP, protocol string, port int) (bool, string, error)
+}
+
+// Flow is the first packet of a connection traced through the NetworkPolicies.
+type Flow struct {
+	SrcIP net.IP
+	DstIP net.IP
+	// DstPort is 0 for protocols without ports.
+	DstPort  int
+	Protocol Protocol
+}
+
+func (flow *Flow) String() string {
+	return fmt.Sprintf("%s %s -> %s:%d", flow.Protocol, flow.SrcIP, flow.DstIP, flow.DstPort)
+}
+
+// TracedSet is the evaluation of an ipset of a pod selector or ACL against the flow.
+type TracedSet struct {
+	Name      string
+	Included  bool
+	MatchType MatchType
+	// Matched is true when the set satisfies the condition, i.e. the IP is in the set if it's included,
+	// or not in the set if it's excluded.
+	Matched bool
+	// Member is the member which contains the IP, or the nomatch member which excludes it.
+	Member string `json:",omitempty"`
+}
+
+// TracedACL is the evaluation of an ACL against the flow.
+type TracedACL struct {
+	Index   int
+	Target  Verdict
+	Comment string `json:",omitempty"`
+	Matched bool
+	// Reason explains why the ACL did not match.
+	Reason string      `json:",omitempty"`
+	Sets   []TracedSet `json:",omitempty"`
+}
+
+// TracedPolicy is a NetworkPolicy which selects the pod of the direction, with its ACLs of the direction.
+type TracedPolicy struct {
+	PolicyKey string
+	ACLs      []TracedACL
+}
+
+// DirectionTrace is the evaluation of the NetworkPolicies of the source pod for egress, or of the destination pod
+// for ingress.
+type DirectionTrace struct {
+	Direction Direction
+	// Policies are the policies selecting the pod in the order they were evaluated: AdminNetworkPolicies by priority,
+	// then NetworkPolicies and the BaselineAdminNetworkPolicy, sorted by key.
+	Policies []TracedPolicy `json:",omitempty"`
+	Verdict  string
+	// DecidingPolicy and DecidingACL are the ACL which allowed or dropped the flow, if any.
+	DecidingPolicy string `json:",omitempty"`
+	DecidingACL    int
+}
+
+// HNSACL is an ACL in HNS on the endpoint of a traced pod. Only traced on Windows.
+type HNSACL struct {
+	EndpointID      string
+	ID              string
+	Action          string
+	Direction       string
+	Protocols       string `json:",omitempty"`
+	LocalAddresses  string `json:",omitempty"`
+	RemoteAddresses string `json:",omitempty"`
+	LocalPorts      string `json:",omitempty"`
+	RemotePorts     string `json:",omitempty"`
+	Priority        uint16
+}
+
+// FlowTrace reports which NetworkPolicies and ACLs allow or drop a flow. The flow is allowed when both its egress
+// and ingress are.
+type FlowTrace struct {
+	Flow    Flow
+	Egress  DirectionTrace
+	Ingress DirectionTrace
+	Verdict string
+	// HNSACLs are the ACLs in HNS of the traced NetworkPolicies on the endpoints of the source and destination pods.
+	HNSACLs []HNSACL `json:",omitempty"`
+}
+
+// TraceFlow evaluates the flow against the policies in the cache, matching their ipsets with the sets.
+// Like in the dataplane, an ACL allowing the flow wins over the ACLs dropping it among NetworkPolicies,
+// while the first matching ACL decides among AdminNetworkPolicies and in the BaselineAdminNetworkPolicy.
+func (pMgr *PolicyManager) TraceFlow(flow *Flow, sets SetMatcher) (*FlowTrace, error) {
+	pMgr.policyMap.RLock()
+	defer pMgr.policyMap.RUnlock()
+
+	policies := make([]*NPMNetworkPolicy, 0, len(pMgr.policyMap.cache))
+	for _, policy := range pMgr.policyMap.cache {
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].PolicyKey < policies[j].PolicyKey
+	})
+
+	trace := &FlowTrace{Flow: *flow}
+	var err error
+	if trace.Egress, err = traceDirection(policies, flow, Egress, sets); err != nil {
+		return nil, err
+	}
+	if trace.Ingress, err = traceDirection(policies, flow, Ingress, sets); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case trace.Egress.Verdict == FlowDropped || trace.Ingress.Verdict == FlowDropped:
+		trace.Verdict = FlowDropped
+	case trace.Egress.Verdict == FlowAllowed || trace.Ingress.Verdict == FlowAllowed:
+		trace.Verdict = FlowAllowed
+	default:
+		trace.Verdict = FlowNotDecided
+	}
+	return trace, nil
+}
+
+func traceDirection(policies []*NPMNetworkPolicy, flow *Flow, direction Direction, sets SetMatcher) (DirectionTrace, error) {
+	podIP := flow.DstIP
+	if direction == Egress {
+		podIP = flow.SrcIP
+	}
+
+	trace := DirectionTrace{Direction: direction, Verdict: FlowNotDecided}
+	adminPolicies := make([]*NPMNetworkPolicy, 0)
+	netPols := make([]*NPMNetworkPolicy, 0, len(policies))
+	baselinePolicies := make([]*NPMNetworkPolicy, 0)
+	for _, policy := range policies {
+		switch policy.Tier {
+		case AdminTier:
+			adminPolicies = append(adminPolicies, policy)
+		case BaselineTier:
+			baselinePolicies = append(baselinePolicies, policy)
+		default:
+			netPols = append(netPols, policy)
+		}
+	}
+	// policies are sorted by key, so the sort must be stable to break ties of priorities by key
+	sort.SliceStable(adminPolicies, func(i, j int) bool {
+		return adminPolicies[i].Priority < adminPolicies[j].Priority
+	})
+
+	// 1. the AdminNetworkPolicies, unless one of their ACLs passes the flow to the NetworkPolicies
+	decided, err := traceTier(&trace, adminPolicies, flow, podIP, direction, sets)
+	if err != nil || decided {
+		return trace, err
+	}
+
+	// 2. the NetworkPolicies
+	var dropPolicy string
+	var dropACL int
+	numTieredPolicies := len(trace.Policies)
+	for _, policy := range netPols {
+		tracedPolicy, selected, err := tracePolicy(policy, flow, podIP, direction, sets)
+		if err != nil {
+			return trace, err
+		}
+		if !selected {
+			continue
+		}
+		for _, tracedACL := range tracedPolicy.ACLs {
+			if !tracedACL.Matched {
+				continue
+			}
+			if tracedACL.Target == Allowed && trace.Verdict != FlowAllowed {
+				trace.Verdict = FlowAllowed
+				trace.DecidingPolicy = policy.PolicyKey
+				trace.DecidingACL = tracedACL.Index
+			}
+			if tracedACL.Target == Dropped && dropPolicy == "" {
+				dropPolicy = policy.PolicyKey
+				dropACL = tracedACL.Index
+			}
+		}
+		trace.Policies = append(trace.Policies, tracedPolicy)
+	}
+
+	if trace.Verdict == FlowAllowed {
+		return trace, nil
+	}
+	if len(trace.Policies) > numTieredPolicies {
+		// the pod is isolated by the policies selecting it
+		trace.Verdict = FlowDropped
+		trace.DecidingPolicy = dropPolicy
+		trace.DecidingACL = dropACL
+		return trace, nil
+	}
+
+	// 3. the BaselineAdminNetworkPolicy, for pods which no NetworkPolicy selects
+	_, err = traceTier(&trace, baselinePolicies, flow, podIP, direction, sets)
+	return trace, err
+}
+
+// traceTier evaluates the policies of the AdminTier or BaselineTier in order, where the first matching ACL decides
+// the flow. It returns false if no ACL matched, or if an ACL passed the flow to the next tier.
+func traceTier(trace *DirectionTrace, policies []*NPMNetworkPolicy, flow *Flow, podIP net.IP, direction Direction, sets SetMatcher) (bool, error) {
+	for _, policy := range policies {
+		tracedPolicy, selected, err := tracePolicy(policy, flow, podIP, direction, sets)
+		if err != nil {
+			return false, err
+		}
+		if !selected {
+			continue
+		}
+		trace.Policies = append(trace.Policies, tracedPolicy)
+		for _, tracedACL := range tracedPolicy.ACLs {
+			if !tracedACL.Matched {
+				continue
+			}
+			if tracedACL.Target == Passed {
+				return false, nil
+			}
+			trace.Verdict = FlowDropped
+			if tracedACL.Target == Allowed {
+				trace.Verdict = FlowAllowed
+			}
+			trace.DecidingPolicy = policy.PolicyKey
+			trace.DecidingACL = tracedACL.Index
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tracePolicy evaluates the ACLs of the direction if the policy selects the pod of the direction.
+func tracePolicy(policy *NPMNetworkPolicy, flow *Flow, podIP net.IP, direction Direction, sets SetMatcher) (TracedPolicy, bool, error) {
+	tracedPolicy := TracedPolicy{PolicyKey: policy.PolicyKey}
+	if !policy.hasDirection(direction) {
+		return tracedPolicy, false, nil
+	}
+	selected, err := selectsIP(policy, podIP, sets)
+	if err != nil || !selected {
+		return tracedPolicy, false, err
+	}
+
+	for i, acl := range policy.ACLs {
+		if acl.Direction != direction && acl.Direction != Both {
+			continue
+		}
+		tracedACL, err := acl.trace(i, flow, sets)
+		if err != nil {
+			return tracedPolicy, false, fmt.Errorf("failed to trace ACL %d of NetworkPolicy %s: %w", i, policy.PolicyKey, err)
+		}
+		tracedPolicy.ACLs = append(tracedPolicy.ACLs, tracedACL)
+	}
+	return tracedPolicy, true, nil
+}
+
+func (netPol *NPMNetworkPolicy) hasDirection(direction Direction) bool {
+	for _, acl := range netPol.ACLs {
+		if acl.Direction == direction || acl.Direction == Both {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsIP returns whether the pod selector of the policy selects the IP.
+func selectsIP(netPol *NPMNetworkPolicy, ip net.IP, sets SetMatcher) (bool, error) {
+	for _, setInfo := range netPol.PodSelectorList {
+		inSet, _, err := sets.MatchIP(setInfo.IPSet.GetPrefixName(), ip, "", 0)
+		if err != nil {
+			return false, fmt.Errorf("failed to match pod selector of NetworkPolicy %s: %w", netPol.PolicyKey, err)
+		}
+		if inSet != setInfo.Included {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (aclPolicy *ACLPolicy) trace(index int, flow *Flow, sets SetMatcher) (TracedACL, error) {
+	tracedACL := TracedACL{Index: index, Target: aclPolicy.Target, Comment: aclPolicy.Comment, Matched: true}
+	fail := func(reason string) {
+		if tracedACL.Matched {
+			tracedACL.Reason = reason
+		}
+		tracedACL.Matched = false
+	}
+
+	if aclPolicy.Protocol != UnspecifiedProtocol && aclPolicy.Protocol != "" && !strings.EqualFold(string(aclPolicy.Protocol), string(flow.Protocol)) {
+		fail(fmt.Sprintf("protocol is not %s", aclPolicy.Protocol))
+	}
+	if !aclPolicy.DstPorts.isUnspecified() {
+		endPort := aclPolicy.DstPorts.EndPort
+		if endPort == 0 {
+			endPort = aclPolicy.DstPorts.Port
+		}
+		if int32(flow.DstPort) < aclPolicy.DstPorts.Port || int32(flow.DstPort) > endPort {
+			fail(fmt.Sprintf("port %d is not in %d-%d", flow.DstPort, aclPolicy.DstPorts.Port, endPort))
+		}
+	}
+
+	setInfos := append(append([]SetInfo{}, aclPolicy.SrcList...), aclPolicy.DstList...)
+	for _, setInfo := range setInfos {
+		ip := flow.DstIP
+		if setInfo.MatchType == SrcMatch {
+			ip = flow.SrcIP
+		}
+		inSet, member, err := sets.MatchIP(setInfo.IPSet.GetPrefixName(), ip, string(flow.Protocol), flow.DstPort)
+		if err != nil {
+			return tracedACL, err
+		}
+		tracedSet := TracedSet{
+			Name:      setInfo.IPSet.GetPrefixName(),
+			Included:  setInfo.Included,
+			MatchType: setInfo.MatchType,
+			Matched:   inSet == setInfo.Included,
+			Member:    member,
+		}
+		tracedACL.Sets = append(tracedACL.Sets, tracedSet)
+		if !tracedSet.Matched {
+			fail(fmt.Sprintf("set %s did not match", tracedSet.Name))
+		}
+	}
+	return tracedACL, nil
+}
@@ -0,0 +1,208 @@
+package policies
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSetMatcher has the IPs of each set by prefixed name.
+type fakeSetMatcher map[string][]string
+
+func (sets fakeSetMatcher) MatchIP(setName string, ip net.IP, _ string, _ int) (bool, string, error) {
+	for _, member := range sets[setName] {
+		if member == ip.String() {
+			return true, member, nil
+		}
+	}
+	return false, "", nil
+}
+
+func TestTraceFlow(t *testing.T) {
+	serverSet := ipsets.NewIPSetMetadata("app:server", ipsets.KeyValueLabelOfPod)
+	clientSet := ipsets.NewIPSetMetadata("app:client", ipsets.KeyValueLabelOfPod)
+	sets := fakeSetMatcher{
+		serverSet.GetPrefixName(): {"10.0.0.1"},
+		clientSet.GetPrefixName(): {"10.0.0.2"},
+	}
+
+	allowClient := NewACLPolicy(Allowed, Ingress)
+	allowClient.SrcList = []SetInfo{NewSetInfo(clientSet.Name, clientSet.Type, true, SrcMatch)}
+	allowClient.Protocol = TCP
+	allowClient.DstPorts = Ports{Port: 80, EndPort: 80}
+	netPol := &NPMNetworkPolicy{
+		PolicyKey:       "x/allow-client",
+		PodSelectorList: []SetInfo{NewSetInfo(serverSet.Name, serverSet.Type, true, DstMatch)},
+		ACLs:            []*ACLPolicy{allowClient, NewACLPolicy(Dropped, Ingress)},
+	}
+
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), ipsetConfig)
+	pMgr.policyMap.cache[netPol.PolicyKey] = netPol
+
+	tests := []struct {
+		name           string
+		flow           *Flow
+		verdict        string
+		decidingACL    int
+		decidingPolicy string
+		reason         string
+	}{
+		{
+			name:           "allowed client",
+			flow:           &Flow{SrcIP: net.ParseIP("10.0.0.2"), DstIP: net.ParseIP("10.0.0.1"), DstPort: 80, Protocol: TCP},
+			verdict:        FlowAllowed,
+			decidingPolicy: "x/allow-client",
+			decidingACL:    0,
+		},
+		{
+			name:           "other port",
+			flow:           &Flow{SrcIP: net.ParseIP("10.0.0.2"), DstIP: net.ParseIP("10.0.0.1"), DstPort: 443, Protocol: TCP},
+			verdict:        FlowDropped,
+			decidingPolicy: "x/allow-client",
+			decidingACL:    1,
+			reason:         "port 443 is not in 80-80",
+		},
+		{
+			name:           "other client",
+			flow:           &Flow{SrcIP: net.ParseIP("10.0.0.3"), DstIP: net.ParseIP("10.0.0.1"), DstPort: 80, Protocol: TCP},
+			verdict:        FlowDropped,
+			decidingPolicy: "x/allow-client",
+			decidingACL:    1,
+			reason:         "set " + clientSet.GetPrefixName() + " did not match",
+		},
+		{
+			name:    "pod not selected",
+			flow:    &Flow{SrcIP: net.ParseIP("10.0.0.1"), DstIP: net.ParseIP("10.0.0.2"), DstPort: 80, Protocol: TCP},
+			verdict: FlowNotDecided,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			trace, err := pMgr.TraceFlow(tt.flow, sets)
+			require.NoError(t, err)
+			require.Equal(t, tt.verdict, trace.Verdict)
+			require.Equal(t, FlowNotDecided, trace.Egress.Verdict)
+			require.Equal(t, tt.verdict, trace.Ingress.Verdict)
+			require.Equal(t, tt.decidingPolicy, trace.Ingress.DecidingPolicy)
+			require.Equal(t, tt.decidingACL, trace.Ingress.DecidingACL)
+			if tt.verdict == FlowNotDecided {
+				require.Empty(t, trace.Ingress.Policies)
+				return
+			}
+			require.Len(t, trace.Ingress.Policies, 1)
+			require.Equal(t, tt.reason, trace.Ingress.Policies[0].ACLs[0].Reason)
+		})
+	}
+}
+
+func TestTraceFlowTiers(t *testing.T) {
+	serverSet := ipsets.NewIPSetMetadata("app:server", ipsets.KeyValueLabelOfPod)
+	dbSet := ipsets.NewIPSetMetadata("app:db", ipsets.KeyValueLabelOfPod)
+	clientSet := ipsets.NewIPSetMetadata("app:client", ipsets.KeyValueLabelOfPod)
+	otherSet := ipsets.NewIPSetMetadata("app:other", ipsets.KeyValueLabelOfPod)
+	sets := fakeSetMatcher{
+		serverSet.GetPrefixName(): {"10.0.0.1"},
+		clientSet.GetPrefixName(): {"10.0.0.2"},
+		otherSet.GetPrefixName():  {"10.0.0.3"},
+		dbSet.GetPrefixName():     {"10.0.0.5"},
+	}
+	selectServer := []SetInfo{NewSetInfo(serverSet.Name, serverSet.Type, true, DstMatch)}
+	fromClient := []SetInfo{NewSetInfo(clientSet.Name, clientSet.Type, true, SrcMatch)}
+
+	passHTTPS := NewACLPolicy(Passed, Ingress)
+	passHTTPS.SrcList = fromClient
+	passHTTPS.Protocol = TCP
+	passHTTPS.DstPorts = Ports{Port: 443}
+	passPolicy := NewAdminNPMNetworkPolicy("pass-https", 10)
+	passPolicy.PodSelectorList = selectServer
+	passPolicy.ACLs = []*ACLPolicy{passHTTPS}
+
+	denyClient := NewACLPolicy(Dropped, Ingress)
+	denyClient.SrcList = fromClient
+	denyPolicy := NewAdminNPMNetworkPolicy("deny-client", 20)
+	denyPolicy.PodSelectorList = selectServer
+	denyPolicy.ACLs = []*ACLPolicy{denyClient}
+
+	allowHTTPS := NewACLPolicy(Allowed, Ingress)
+	allowHTTPS.SrcList = fromClient
+	allowHTTPS.Protocol = TCP
+	allowHTTPS.DstPorts = Ports{Port: 443}
+	netPol := &NPMNetworkPolicy{
+		PolicyKey:       "x/allow-https",
+		PodSelectorList: selectServer,
+		ACLs:            []*ACLPolicy{allowHTTPS, NewACLPolicy(Dropped, Ingress)},
+	}
+
+	allowOther := NewACLPolicy(Allowed, Ingress)
+	allowOther.SrcList = []SetInfo{NewSetInfo(otherSet.Name, otherSet.Type, true, SrcMatch)}
+	baselinePolicy := NewBaselineNPMNetworkPolicy("default")
+	baselinePolicy.PodSelectorList = []SetInfo{NewSetInfo(dbSet.Name, dbSet.Type, true, DstMatch)}
+	baselinePolicy.ACLs = []*ACLPolicy{allowOther, NewACLPolicy(Dropped, Ingress)}
+
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), ipsetConfig)
+	for _, policy := range []*NPMNetworkPolicy{passPolicy, denyPolicy, netPol, baselinePolicy} {
+		pMgr.policyMap.cache[policy.PolicyKey] = policy
+	}
+
+	tests := []struct {
+		name              string
+		flow              *Flow
+		verdict           string
+		decidingPolicy    string
+		decidingACL       int
+		evaluatedPolicies []string
+	}{
+		{
+			name:              "passed to NetworkPolicy",
+			flow:              &Flow{SrcIP: net.ParseIP("10.0.0.2"), DstIP: net.ParseIP("10.0.0.1"), DstPort: 443, Protocol: TCP},
+			verdict:           FlowAllowed,
+			decidingPolicy:    "x/allow-https",
+			decidingACL:       0,
+			evaluatedPolicies: []string{passPolicy.PolicyKey, "x/allow-https"},
+		},
+		{
+			name:              "denied by AdminNetworkPolicy",
+			flow:              &Flow{SrcIP: net.ParseIP("10.0.0.2"), DstIP: net.ParseIP("10.0.0.1"), DstPort: 80, Protocol: TCP},
+			verdict:           FlowDropped,
+			decidingPolicy:    denyPolicy.PolicyKey,
+			decidingACL:       0,
+			evaluatedPolicies: []string{passPolicy.PolicyKey, denyPolicy.PolicyKey},
+		},
+		{
+			name:              "allowed by BaselineAdminNetworkPolicy",
+			flow:              &Flow{SrcIP: net.ParseIP("10.0.0.3"), DstIP: net.ParseIP("10.0.0.5"), DstPort: 80, Protocol: TCP},
+			verdict:           FlowAllowed,
+			decidingPolicy:    baselinePolicy.PolicyKey,
+			decidingACL:       0,
+			evaluatedPolicies: []string{baselinePolicy.PolicyKey},
+		},
+		{
+			name:              "denied by BaselineAdminNetworkPolicy",
+			flow:              &Flow{SrcIP: net.ParseIP("10.0.0.2"), DstIP: net.ParseIP("10.0.0.5"), DstPort: 80, Protocol: TCP},
+			verdict:           FlowDropped,
+			decidingPolicy:    baselinePolicy.PolicyKey,
+			decidingACL:       1,
+			evaluatedPolicies: []string{baselinePolicy.PolicyKey},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			trace, err := pMgr.TraceFlow(tt.flow, sets)
+			require.NoError(t, err)
+			require.Equal(t, tt.verdict, trace.Verdict)
+			require.Equal(t, tt.verdict, trace.Ingress.Verdict)
+			require.Equal(t, tt.decidingPolicy, trace.Ingress.DecidingPolicy)
+			require.Equal(t, tt.decidingACL, trace.Ingress.DecidingACL)
+			evaluatedPolicies := make([]string, 0, len(trace.Ingress.Policies))
+			for _, policy := range trace.Ingress.Policies {
+				evaluatedPolicies = append(evaluatedPolicies, policy.PolicyKey)
+			}
+			require.Equal(t, tt.evaluatedPolicies, evaluatedPolicies)
+		})
+	}
+}
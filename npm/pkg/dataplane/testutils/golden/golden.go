@@ -0,0 +1,138 @@
+// Package golden compares the dataplane output NPM programs for a set of NetworkPolicies with checked-in golden
+// files, so that unintended changes to the translation or to the dataplane rendering are caught in CI.
+// The output is an iptables-restore payload on Linux and the ACL settings JSON on Windows.
+// Golden files are named <name>.<GOOS>.golden and are rewritten by running the tests with -update-golden.
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/google/go-cmp/cmp"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+var update = flag.Bool("update-golden", false, "rewrite golden files with the current dataplane output")
+
+// LoadPolicies reads the NetworkPolicies from a file with one or more YAML documents.
+func LoadPolicies(path string) ([]*networkingv1.NetworkPolicy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var netpols []*networkingv1.NetworkPolicy
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096) //nolint:gomnd // buffer size
+	for {
+		netpol := &networkingv1.NetworkPolicy{}
+		if err := decoder.Decode(netpol); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode policy in %s: %w", path, err)
+		}
+		if netpol.Name == "" {
+			// empty document
+			continue
+		}
+		netpols = append(netpols, netpol)
+	}
+	return netpols, nil
+}
+
+// Render translates the NetworkPolicies and renders the dataplane output NPM would program for them on this OS.
+// If a policy fails to translate, the error is rendered instead, so that changes to which policies are supported
+// are caught as well.
+func Render(netpols []*networkingv1.NetworkPolicy) ([]byte, error) {
+	npmNetPols := make([]*policies.NPMNetworkPolicy, 0, len(netpols))
+	for _, netpol := range netpols {
+		npmNetPol, err := translation.TranslatePolicy(netpol)
+		if err != nil {
+			return []byte(fmt.Sprintf("error: failed to translate policy %s/%s: %s\n", netpol.Namespace, netpol.Name, err)), nil
+		}
+		policies.NormalizePolicy(npmNetPol)
+		if err := policies.ValidatePolicy(npmNetPol); err != nil {
+			return []byte(fmt.Sprintf("error: invalid policy %s/%s: %s\n", netpol.Namespace, netpol.Name, err)), nil
+		}
+		sortSetInfos(npmNetPol)
+		npmNetPols = append(npmNetPols, npmNetPol)
+	}
+	out, err := policies.RenderPolicies(npmNetPols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render policies: %w", err)
+	}
+	return out, nil
+}
+
+// sortSetInfos orders the sets matched by the policy. The translation iterates over label maps,
+// so the order of the sets is not stable otherwise.
+func sortSetInfos(npmNetPol *policies.NPMNetworkPolicy) {
+	sortInfos := func(infos []policies.SetInfo) {
+		sort.SliceStable(infos, func(i, j int) bool {
+			if infos[i].IPSet.GetPrefixName() != infos[j].IPSet.GetPrefixName() {
+				return infos[i].IPSet.GetPrefixName() < infos[j].IPSet.GetPrefixName()
+			}
+			return infos[i].Included && !infos[j].Included
+		})
+	}
+	sortInfos(npmNetPol.PodSelectorList)
+	for _, acl := range npmNetPol.ACLs {
+		sortInfos(acl.SrcList)
+		sortInfos(acl.DstList)
+	}
+}
+
+// Path returns the path of the golden file for this OS.
+func Path(dir, name string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s.%s.golden", name, runtime.GOOS))
+}
+
+// Assert fails the test with a line diff if the output differs from the golden file.
+// With -update-golden, the golden file is rewritten with the output instead.
+func Assert(t *testing.T, goldenPath string, actual []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(goldenPath, actual, 0o644); err != nil { //nolint:gosec // golden files are checked in
+			t.Fatalf("failed to update golden file %s: %v", goldenPath, err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s, run the test with -update-golden to create it: %v", goldenPath, err)
+	}
+	if bytes.Equal(expected, actual) {
+		return
+	}
+	diff := cmp.Diff(strings.Split(string(expected), "\n"), strings.Split(string(actual), "\n"))
+	t.Errorf("dataplane output differs from golden file %s (-golden +actual):\n%s\n"+
+		"if the change is intended, run the test with -update-golden and check in the golden file", goldenPath, diff)
+}
+
+// AssertPolicies renders the NetworkPolicies in the YAML file and compares the output with the golden file of the
+// same name in the directory.
+func AssertPolicies(t *testing.T, dir, name string) {
+	t.Helper()
+	netpols, err := LoadPolicies(filepath.Join(dir, name+".yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := Render(netpols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Assert(t, Path(dir, name), actual)
+}
@@ -0,0 +1,25 @@
+package golden
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testdataDir = "testdata"
+
+func TestGoldenPolicies(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(testdataDir, "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no policies found in testdata")
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			AssertPolicies(t, testdataDir, name)
+		})
+	}
+}
@@ -9,13 +9,15 @@ import (
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/network/hnswrapper"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Microsoft/hcsshim/hcn"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"k8s.io/klog"
 )
 
+var logger = npmlogger.For(npmlogger.Dataplane)
+
 func PrefixNames(sets []*ipsets.IPSetMetadata) []string {
 	a := make([]string, len(sets))
 	for k, s := range sets {
@@ -74,6 +76,7 @@ func VerifyHNSCache(t *testing.T, hns *hnswrapper.Hnsv2wrapperFake, expectedSetP
 	// we want to evaluate both verify functions even if one fails, so don't write as verifySetPolicies() && verifyACLs() in case of short-circuiting
 	success := VerifySetPolicies(t, hns, expectedSetPolicies)
 	success = VerifyACLs(t, hns, expectedEndpointACLs) && success
+	success = assert.NoError(t, hns.Cache.ValidateReferences(), "hns cache has dangling references") && success
 
 	if !success {
 		require.FailNow(t, fmt.Sprintf("hns cache had unexpected state. printing hns cache...\n%s", hns.Cache.PrettyString()))
@@ -139,16 +142,16 @@ func VerifyACLs(t *testing.T, hns *hnswrapper.Hnsv2wrapperFake, expectedEndpoint
 
 // helpful for debugging if there's a discrepancy between GetAll functions and the HNS PrettyString
 func PrintGetAllOutput(hns *hnswrapper.Hnsv2wrapperFake) {
-	klog.Info("SETPOLICIES...")
+	logger.Info("SETPOLICIES...")
 	for _, setPol := range hns.Cache.AllSetPolicies(common.FakeHNSNetworkID) {
-		klog.Infof("%+v", setPol)
+		logger.Infof("%+v", setPol)
 	}
-	klog.Info("Endpoint ACLs...")
+	logger.Info("Endpoint ACLs...")
 	for id, acls := range hns.Cache.GetAllACLs() {
 		a := make([]string, len(acls))
 		for k, v := range acls {
 			a[k] = fmt.Sprintf("%+v", v)
 		}
-		klog.Infof("%s: %s", id, strings.Join(a, ","))
+		logger.Infof("%s: %s", id, strings.Join(a, ","))
 	}
 }
@@ -7,7 +7,6 @@ import (
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
 	"github.com/Azure/azure-container-networking/npm/util"
-	"k8s.io/klog"
 )
 
 type GenericDataplane interface {
@@ -83,18 +82,37 @@ func newUpdateNPMPod(podMetadata *PodMetadata) *updateNPMPod {
 	}
 }
 
+// updateIPSetsToAdd records the sets the pod was added to. A set the pod is still pending removal from
+// cancels out instead, so a label flapping between applies doesn't touch the pod's endpoint.
 func (npmPod *updateNPMPod) updateIPSetsToAdd(setNames []*ipsets.IPSetMetadata) {
 	for _, set := range setNames {
-		npmPod.IPSetsToAdd = append(npmPod.IPSetsToAdd, set.GetPrefixName())
+		npmPod.IPSetsToRemove, npmPod.IPSetsToAdd = diffSetName(set.GetPrefixName(), npmPod.IPSetsToRemove, npmPod.IPSetsToAdd)
 	}
 }
 
+// updateIPSetsToRemove records the sets the pod was removed from. A set the pod is still pending addition to
+// cancels out instead.
 func (npmPod *updateNPMPod) updateIPSetsToRemove(setNames []*ipsets.IPSetMetadata) {
 	for _, set := range setNames {
-		npmPod.IPSetsToRemove = append(npmPod.IPSetsToRemove, set.GetPrefixName())
+		npmPod.IPSetsToAdd, npmPod.IPSetsToRemove = diffSetName(set.GetPrefixName(), npmPod.IPSetsToAdd, npmPod.IPSetsToRemove)
 	}
 }
 
+// diffSetName drops setName from opposite if it's there, and otherwise appends it to same unless it's already there.
+func diffSetName(setName string, opposite, same []string) (newOpposite, newSame []string) {
+	for i, name := range opposite {
+		if name == setName {
+			return append(opposite[:i], opposite[i+1:]...), same
+		}
+	}
+	for _, name := range same {
+		if name == setName {
+			return opposite, same
+		}
+	}
+	return opposite, append(same, setName)
+}
+
 // updatePodCache's ordered queue implementation is similar to that of netPolQueue.
 type updatePodCache struct {
 	sync.Mutex
@@ -120,11 +138,12 @@ func newUpdatePodCache(initialCapacity int) *updatePodCache {
 func (c *updatePodCache) enqueue(m *PodMetadata) *updateNPMPod {
 	pod, ok := c.cache[m.PodKey]
 
-	if ok && pod.NodeName != m.NodeName {
-		// Currently, don't expect this path to be taken because dataplane makes sure to only enqueue on-node Pods.
-		// If the pod is already in the cache but the node name has changed, we need to requeue it.
-		// Can discard the old Pod info since the Pod must have been deleted and brought back up on a different node.
-		klog.Infof("[DataPlane] pod already in cache but node name has changed. deleting the old pod object from the queue. podKey: %s", m.PodKey)
+	if ok && (pod.NodeName != m.NodeName || pod.PodIP != m.PodIP) {
+		// Currently, don't expect a node change because dataplane makes sure to only enqueue on-node Pods.
+		// If the pod is already in the cache but the node name or IP has changed, we need to requeue it.
+		// Can discard the old Pod info since the Pod must have been deleted and brought back up with a different endpoint.
+		// This also keeps the old Pod's set changes from cancelling out the new Pod's.
+		logger.Infof("[DataPlane] pod already in cache but node name or IP has changed. deleting the old pod object from the queue. podKey: %s", m.PodKey)
 
 		// remove the old pod from the cache and queue
 		delete(c.cache, m.PodKey)
@@ -144,7 +163,7 @@ func (c *updatePodCache) enqueue(m *PodMetadata) *updateNPMPod {
 	}
 
 	if !ok {
-		klog.Infof("[DataPlane] pod key %s not found in updatePodCache. creating a new obj", m.PodKey)
+		logger.Infof("[DataPlane] pod key %s not found in updatePodCache. creating a new obj", m.PodKey)
 
 		pod = newUpdateNPMPod(m)
 		c.cache[m.PodKey] = pod
@@ -157,7 +176,7 @@ func (c *updatePodCache) enqueue(m *PodMetadata) *updateNPMPod {
 // dequeue returns the first pod in the queue and removes it from the queue.
 func (c *updatePodCache) dequeue() *updateNPMPod {
 	if c.isEmpty() {
-		klog.Infof("[DataPlane] updatePodCache is empty. returning nil for dequeue()")
+		logger.Infof("[DataPlane] updatePodCache is empty. returning nil for dequeue()")
 		return nil
 	}
 
@@ -177,7 +196,7 @@ func (c *updatePodCache) dequeue() *updateNPMPod {
 func (c *updatePodCache) requeue(pod *updateNPMPod) {
 	if _, ok := c.cache[pod.PodKey]; ok {
 		// should not happen
-		klog.Infof("[DataPlane] pod key %s already exists in updatePodCache. skipping requeue", pod.PodKey)
+		logger.Infof("[DataPlane] pod key %s already exists in updatePodCache. skipping requeue", pod.PodKey)
 		return
 	}
 
@@ -209,9 +228,9 @@ func (q *netPolQueue) len() int {
 // enqueue adds a NetPol to the queue. If the NetPol already exists in the queue, the NetPol object is updated.
 func (q *netPolQueue) enqueue(policy *policies.NPMNetworkPolicy) {
 	if _, ok := q.toAdd[policy.PolicyKey]; ok {
-		klog.Infof("[DataPlane] policy %s exists in netPolQueue. updating", policy.PolicyKey)
+		logger.Infof("[DataPlane] policy %s exists in netPolQueue. updating", policy.PolicyKey)
 	} else {
-		klog.Infof("[DataPlane] enqueuing policy %s in netPolQueue", policy.PolicyKey)
+		logger.Infof("[DataPlane] enqueuing policy %s in netPolQueue", policy.PolicyKey)
 	}
 	q.toAdd[policy.PolicyKey] = policy
 }
@@ -15,10 +15,11 @@ const (
 
 // npmEndpoint holds info relevant for endpoints in windows
 type npmEndpoint struct {
-	name   string
-	id     string
-	ip     string
-	podKey string
+	name      string
+	id        string
+	networkID string
+	ip        string
+	podKey    string
 	// previousIncorrectPodKey represents a Pod that was previously and incorrectly assigned to this endpoint (see issue 1729)
 	previousIncorrectPodKey string
 	// Map with Key as Network Policy name to to emulate set
@@ -32,6 +33,7 @@ func newNPMEndpoint(endpoint *hcn.HostComputeEndpoint) *npmEndpoint {
 	return &npmEndpoint{
 		name:            endpoint.Name,
 		id:              endpoint.Id,
+		networkID:       endpoint.HostComputeNetwork,
 		podKey:          unspecifiedPodKey,
 		netPolReference: make(map[string]struct{}),
 		ip:              endpoint.IpConfigurations[0].IpAddress,
@@ -0,0 +1,112 @@
+// Package logger provides the structured loggers of NPM components. Entries are filtered by the runtime level of
+// their component, which is set by the LogLevels of the npm ConfigMap and can be changed through the HTTP debug API.
+// The loggers discard entries until main sets the base logger with SetBase, so that importing an NPM package has no
+// logging side effects.
+package logger
+
+import (
+	"os"
+	"sync/atomic"
+
+	"github.com/Azure/azure-container-networking/log/zaplog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Components with their own log level.
+const (
+	NPM         = "npm"
+	Policies    = "policies"
+	IPSets      = "ipsets"
+	Controllers = "controllers"
+	Dataplane   = "dataplane"
+)
+
+// Levels are the runtime levels of the NPM components. Everything is logged at info by default.
+var Levels = zaplog.NewLevels(zapcore.InfoLevel)
+
+// baseCore is the core of the base logger. A new one is stored each time the base logger is set, so that the
+// component loggers can tell that they must rebuild their core.
+type baseCore struct {
+	core zapcore.Core
+}
+
+var base atomic.Pointer[baseCore]
+
+func init() {
+	base.Store(&baseCore{core: zapcore.NewNopCore()})
+}
+
+// New returns the base logger of NPM, which encodes entries to JSON on stdout, filtered by Levels.
+func New() (*zap.Logger, func(), error) {
+	logger, cleanup, err := zaplog.New(&zaplog.Config{
+		Encoding: zaplog.EncodingJSON,
+		Stdout:   true,
+		Levels:   Levels,
+	})
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck // zaplog errors are descriptive
+	}
+	return logger.With(zap.Int("pid", os.Getpid())), cleanup, nil
+}
+
+// SetBase makes the component loggers, including those already returned by For, write to the logger.
+func SetBase(logger *zap.Logger) {
+	base.Store(&baseCore{core: logger.Core()})
+}
+
+// For returns the logger of a component.
+func For(component string) *zap.SugaredLogger {
+	return zap.New(&lazyCore{fields: []zapcore.Field{zap.String("component", component)}}).Sugar()
+}
+
+// SetLevels applies the component levels of the npm ConfigMap, e.g. {"default": "info", "policies": "debug"}.
+// Nothing is changed if any of the levels is invalid.
+func SetLevels(spec map[string]string) error {
+	return Levels.Apply(spec) //nolint:wrapcheck // zaplog errors name the invalid component
+}
+
+// lazyCore writes to the core of the base logger with its fields, rebuilding its core when the base logger is set.
+type lazyCore struct {
+	fields []zapcore.Field
+
+	// cached is the core built from the base core it points to, loaded without locking on every log call.
+	cached atomic.Pointer[builtCore]
+}
+
+// builtCore is the core of a lazyCore, built from a base core.
+type builtCore struct {
+	from *baseCore
+	core zapcore.Core
+}
+
+func (c *lazyCore) current() zapcore.Core {
+	b := base.Load()
+	if built := c.cached.Load(); built != nil && built.from == b {
+		return built.core
+	}
+	// concurrent callers may build the core more than once, which is harmless
+	built := &builtCore{from: b, core: b.core.With(c.fields)}
+	c.cached.Store(built)
+	return built.core
+}
+
+func (c *lazyCore) Enabled(level zapcore.Level) bool {
+	return c.current().Enabled(level)
+}
+
+func (c *lazyCore) With(fields []zapcore.Field) zapcore.Core {
+	return &lazyCore{fields: append(append([]zapcore.Field{}, c.fields...), fields...)}
+}
+
+func (c *lazyCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.current().Check(ent, ce)
+}
+
+func (c *lazyCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.current().Write(ent, fields) //nolint:wrapcheck // the error of the base core is returned as is
+}
+
+func (c *lazyCore) Sync() error {
+	return c.current().Sync() //nolint:wrapcheck // the error of the base core is returned as is
+}
@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestForWritesToBaseOnceSet(t *testing.T) {
+	t.Cleanup(func() { SetBase(zap.NewNop()) })
+	logger := For(Policies).With("policy", "ns/a")
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger.Info("before the base logger is set")
+	SetBase(zap.New(core))
+	logger.Info("after the base logger is set")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	require.Equal(t, "after the base logger is set", entries[0].Message)
+	require.Equal(t, map[string]interface{}{"component": Policies, "policy": "ns/a"}, entries[0].ContextMap())
+}
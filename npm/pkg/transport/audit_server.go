@@ -0,0 +1,163 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var logger = npmlogger.For(npmlogger.NPM)
+
+// auditSubscriberBufferSize is how many events a watcher can fall behind before it's disconnected.
+const auditSubscriberBufferSize = 1000
+
+// AuditServer streams the dataplane's audit events to external policy auditors.
+// Each watcher gets every event published after it connects. A watcher that falls too far behind
+// is disconnected with ResourceExhausted rather than silently missing events, so it knows to resync.
+type AuditServer struct {
+	sync.Mutex
+	subscribers map[uint64]*auditSubscriber
+	nextID      uint64
+	bufferSize  int
+}
+
+type auditSubscriber struct {
+	events chan *dataplane.AuditEvent
+	// overflow is closed when the subscriber is dropped for falling behind
+	overflow chan struct{}
+}
+
+// NewAuditServer creates an AuditServer with no watchers.
+func NewAuditServer() *AuditServer {
+	return &AuditServer{
+		subscribers: make(map[uint64]*auditSubscriber),
+		bufferSize:  auditSubscriberBufferSize,
+	}
+}
+
+// Publish sends the event to every watcher without blocking.
+func (a *AuditServer) Publish(event *dataplane.AuditEvent) {
+	a.Lock()
+	defer a.Unlock()
+	for id, sub := range a.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			logger.Warnf("[AuditServer] dropping audit watcher %d since it has %d pending events", id, len(sub.events))
+			close(sub.overflow)
+			delete(a.subscribers, id)
+		}
+	}
+}
+
+// Watch streams audit events until the client disconnects.
+func (a *AuditServer) Watch(_ *emptypb.Empty, stream AuditWatchServer) error {
+	id, sub := a.subscribe()
+	defer a.unsubscribe(id)
+
+	logger.Infof("[AuditServer] audit watcher %d connected", id)
+	for {
+		select {
+		case <-stream.Context().Done():
+			logger.Infof("[AuditServer] audit watcher %d disconnected", id)
+			return nil
+		case <-sub.overflow:
+			return status.Error(codes.ResourceExhausted, "audit watcher fell behind and missed events") //nolint:wrapcheck // grpc status
+		case event := <-sub.events:
+			msg, err := auditEventToStruct(event)
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to encode audit event: %v", err) //nolint:wrapcheck // grpc status
+			}
+			if err := stream.Send(msg); err != nil {
+				return fmt.Errorf("failed to send audit event to watcher %d: %w", id, err)
+			}
+		}
+	}
+}
+
+// Start serves the PolicyAudit service on the address with the transport's TLS certificates until stopCh is closed.
+func (a *AuditServer) Start(address string, port int, stopCh <-chan struct{}) error {
+	addr := net.JoinHostPort(address, strconv.Itoa(port))
+	logger.Infof("Starting audit server listener on %s", addr)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for audit watchers: %w", err)
+	}
+
+	creds, err := serverTLSCreds()
+	if err != nil {
+		lis.Close()
+		return fmt.Errorf("failed to load TLS certificates: %w", err)
+	}
+
+	server := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.MaxConcurrentStreams(grpcMaxConcurrentStreams),
+	)
+	RegisterPolicyAuditServer(server, a)
+
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			logger.Errorf("audit server stopped: %v", err)
+		}
+	}()
+	go func() {
+		<-stopCh
+		server.Stop()
+	}()
+	return nil
+}
+
+func (a *AuditServer) subscribe() (uint64, *auditSubscriber) {
+	a.Lock()
+	defer a.Unlock()
+	a.nextID++
+	sub := &auditSubscriber{
+		events:   make(chan *dataplane.AuditEvent, a.bufferSize),
+		overflow: make(chan struct{}),
+	}
+	a.subscribers[a.nextID] = sub
+	return a.nextID, sub
+}
+
+func (a *AuditServer) unsubscribe(id uint64) {
+	a.Lock()
+	defer a.Unlock()
+	delete(a.subscribers, id)
+}
+
+// auditEventToStruct encodes the event with only the fields relevant to its type.
+func auditEventToStruct(event *dataplane.AuditEvent) (*structpb.Struct, error) {
+	fields := map[string]interface{}{
+		"type":      string(event.Type),
+		"timestamp": event.Timestamp.Format(time.RFC3339Nano),
+	}
+	optional := map[string]string{
+		"policyKey":  event.PolicyKey,
+		"setName":    event.SetName,
+		"member":     event.Member,
+		"podKey":     event.PodKey,
+		"endpointID": event.EndpointID,
+		"endpointIP": event.EndpointIP,
+	}
+	for k, v := range optional {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+	msg, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit event: %w", err)
+	}
+	return msg, nil
+}
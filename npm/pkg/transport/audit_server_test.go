@@ -0,0 +1,105 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startTestAuditServer(t *testing.T, a *AuditServer) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	RegisterPolicyAuditServer(server, a)
+	go server.Serve(lis) //nolint:errcheck // stopped in cleanup
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// waitForWatchers waits until the server has registered n watchers, since Watch subscribes asynchronously.
+func waitForWatchers(t *testing.T, a *AuditServer, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		a.Lock()
+		defer a.Unlock()
+		return len(a.subscribers) == n
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestAuditServerWatch(t *testing.T) {
+	a := NewAuditServer()
+	conn := startTestAuditServer(t, a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := WatchAuditEvents(ctx, conn)
+	require.NoError(t, err)
+	waitForWatchers(t, a, 1)
+
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	a.Publish(&dataplane.AuditEvent{Type: dataplane.AuditIPSetMemberAdded, Timestamp: ts, SetName: "azure-npm-123", Member: "10.0.0.1", PodKey: "x/a"})
+	a.Publish(&dataplane.AuditEvent{Type: dataplane.AuditEndpointPolicyAttached, Timestamp: ts, PolicyKey: "x/deny", EndpointID: "ep1", EndpointIP: "10.0.0.1"})
+
+	msg, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"type":      "IPSetMemberAdded",
+		"timestamp": "2024-01-02T03:04:05Z",
+		"setName":   "azure-npm-123",
+		"member":    "10.0.0.1",
+		"podKey":    "x/a",
+	}, msg.AsMap())
+
+	msg, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"type":       "EndpointPolicyAttached",
+		"timestamp":  "2024-01-02T03:04:05Z",
+		"policyKey":  "x/deny",
+		"endpointID": "ep1",
+		"endpointIP": "10.0.0.1",
+	}, msg.AsMap())
+
+	cancel()
+	waitForWatchers(t, a, 0)
+}
+
+func TestAuditServerDropsSlowWatcher(t *testing.T) {
+	a := NewAuditServer()
+	a.bufferSize = 1
+	conn := startTestAuditServer(t, a)
+
+	stream, err := WatchAuditEvents(context.Background(), conn)
+	require.NoError(t, err)
+	waitForWatchers(t, a, 1)
+
+	// the watcher's buffer only fits one event, so publishing faster than it's drained overflows it
+	for i := 0; i < 100; i++ {
+		a.Publish(&dataplane.AuditEvent{Type: dataplane.AuditPolicyAdded, PolicyKey: "x/deny"})
+	}
+	waitForWatchers(t, a, 0)
+
+	for {
+		_, err = stream.Recv()
+		if err != nil {
+			break
+		}
+	}
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// The PolicyAudit service only uses well-known protobuf types, so it's declared here rather than generated:
+//
+//	service PolicyAudit {
+//		rpc Watch(google.protobuf.Empty) returns (stream google.protobuf.Struct);
+//	}
+const (
+	auditServiceName    = "npm.audit.PolicyAudit"
+	auditWatchStream    = "Watch"
+	auditWatchFullRoute = "/" + auditServiceName + "/" + auditWatchStream
+)
+
+// PolicyAuditServer is the server API for the PolicyAudit service.
+type PolicyAuditServer interface {
+	Watch(*emptypb.Empty, AuditWatchServer) error
+}
+
+// AuditWatchServer is the server side of a Watch stream.
+type AuditWatchServer interface {
+	Send(*structpb.Struct) error
+	grpc.ServerStream
+}
+
+type auditWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *auditWatchServer) Send(m *structpb.Struct) error {
+	return x.ServerStream.SendMsg(m) //nolint:wrapcheck // same as generated code
+}
+
+func auditWatchHandler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err //nolint:wrapcheck // same as generated code
+	}
+	return srv.(PolicyAuditServer).Watch(m, &auditWatchServer{stream})
+}
+
+// PolicyAuditServiceDesc is the grpc.ServiceDesc for the PolicyAudit service.
+var PolicyAuditServiceDesc = grpc.ServiceDesc{
+	ServiceName: auditServiceName,
+	HandlerType: (*PolicyAuditServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    auditWatchStream,
+			Handler:       auditWatchHandler,
+			ServerStreams: true,
+		},
+	},
+}
+
+// RegisterPolicyAuditServer registers the PolicyAudit service on the gRPC server.
+func RegisterPolicyAuditServer(s grpc.ServiceRegistrar, srv PolicyAuditServer) {
+	s.RegisterService(&PolicyAuditServiceDesc, srv)
+}
+
+// AuditWatchClient is the client side of a Watch stream.
+type AuditWatchClient interface {
+	Recv() (*structpb.Struct, error)
+	grpc.ClientStream
+}
+
+type auditWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *auditWatchClient) Recv() (*structpb.Struct, error) {
+	m := new(structpb.Struct)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err //nolint:wrapcheck // io.EOF must be returned as is
+	}
+	return m, nil
+}
+
+// WatchAuditEvents opens a PolicyAudit Watch stream on the connection.
+func WatchAuditEvents(ctx context.Context, cc grpc.ClientConnInterface, opts ...grpc.CallOption) (AuditWatchClient, error) {
+	stream, err := cc.NewStream(ctx, &PolicyAuditServiceDesc.Streams[0], auditWatchFullRoute, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit watch stream: %w", err)
+	}
+	x := &auditWatchClient{stream}
+	if err := x.ClientStream.SendMsg(&emptypb.Empty{}); err != nil {
+		return nil, fmt.Errorf("failed to send audit watch request: %w", err)
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, fmt.Errorf("failed to close audit watch request: %w", err)
+	}
+	return x, nil
+}
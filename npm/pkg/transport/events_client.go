@@ -7,7 +7,6 @@ import (
 	"github.com/Azure/azure-container-networking/npm/pkg/protos"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
-	"k8s.io/klog/v2"
 )
 
 // EventsClient is a client for the DataplaneEvents service
@@ -36,11 +35,11 @@ func NewEventsClient(ctx context.Context, pod, node, addr string) (*EventsClient
 		return nil, ErrAddressNil
 	}
 
-	klog.Infof("Connecting to NPM controller gRPC server at address %s\n", addr)
+	logger.Infof("Connecting to NPM controller gRPC server at address %s\n", addr)
 
 	config, err := clientTLSConfig()
 	if err != nil {
-		klog.Errorf("failed to load client tls config : %s", err)
+		logger.Errorf("failed to load client tls config : %s", err)
 		return nil, fmt.Errorf("failed to load client tls config : %w", err)
 	}
 
@@ -82,28 +81,28 @@ func (c *EventsClient) run(ctx context.Context, stopCh <-chan struct{}) error {
 	for {
 		select {
 		case <-ctx.Done():
-			klog.Errorf("recevied done event on context channel: %v", ctx.Err())
+			logger.Errorf("recevied done event on context channel: %v", ctx.Err())
 			return fmt.Errorf("recevied done event on context channel: %w", ctx.Err())
 		case <-stopCh:
-			klog.Info("Received message on stop channel. Stopping transport client")
+			logger.Info("Received message on stop channel. Stopping transport client")
 			return nil
 		default:
 			if connectClient == nil {
-				klog.Info("Reconnecting to gRPC server controller")
+				logger.Info("Reconnecting to gRPC server controller")
 				opts := []grpc.CallOption{grpc.WaitForReady(false)}
 				connectClient, err = c.Connect(ctx, clientMetadata, opts...)
 				if err != nil {
 					return fmt.Errorf("failed to connect to dataplane events server: %w", err)
 				}
-				klog.Info("Successfully connected to gRPC server controller")
+				logger.Info("Successfully connected to gRPC server controller")
 			}
 			event, err := connectClient.Recv()
 			if err != nil {
-				klog.Errorf("failed to receive event: %v", err)
+				logger.Errorf("failed to receive event: %v", err)
 				connectClient = nil
 				continue
 			}
-			klog.Infof("### Received event: %v", event)
+			logger.Infof("### Received event: %v", event)
 			c.outCh <- event
 		}
 	}
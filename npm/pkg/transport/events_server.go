@@ -10,7 +10,6 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/stats"
-	"k8s.io/klog/v2"
 )
 
 // EventsServer contains of the grpc server and the watchdog server
@@ -75,9 +74,9 @@ func (m *EventsServer) InputChannel() chan *protos.Events {
 
 // Start starts the events manager (grpc server and watchdog)
 func (m *EventsServer) Start(stopCh <-chan struct{}) error {
-	klog.Info("Starting transport manager")
+	logger.Info("Starting transport manager")
 	if err := m.start(stopCh); err != nil {
-		klog.Errorf("Failed to Start transport manager: %v", err)
+		logger.Errorf("Failed to Start transport manager: %v", err)
 		return err
 	}
 	return nil
@@ -98,60 +97,60 @@ func (m *EventsServer) start(stopCh <-chan struct{}) error {
 			// 2. Nested IPSets
 			// 3. Network Policies
 			// within the same castegory we will have to paginate.
-			klog.Infof("Registering remote client %s", client)
+			logger.Infof("Registering remote client %s", client)
 			m.Registrations[client.String()] = client
 			event, err := m.dp.HydrateClients()
 			if err != nil {
-				klog.Errorf("Failed to hydrate client %s: %v", client, err)
+				logger.Errorf("Failed to hydrate client %s: %v", client, err)
 			}
 			// (TODO) Hydration event takes a lock of whole DPShim instance, essentially blocking the
 			// controllers from receiving any more new events or servicing existing daemons.
 			// So we will need to add a buffering mechanism to wait until either we have a N number of daemons
 			// or hit S milliseconds of wait time and send huydration event to all the buffered daemons.
 			go func() {
-				klog.Infof("Hydrating remote client %s", client)
+				logger.Infof("Hydrating remote client %s", client)
 				if err := client.stream.SendMsg(event); err != nil {
-					klog.Errorf("Failed to hydrate client %s: %v", client, err)
+					logger.Errorf("Failed to hydrate client %s: %v", client, err)
 				}
 			}()
 		case ev := <-m.deregCh:
 			// (TODO) A heart beat for each daemon should also be added alongside watchdog to monitor
 			// daemon restarts and then if that fails, we will need to delete the client.
-			klog.Infof("Degregistering remote client %s", ev.remoteAddr)
+			logger.Infof("Degregistering remote client %s", ev.remoteAddr)
 			if v, ok := m.Registrations[ev.remoteAddr]; ok {
 				if v.timestamp <= ev.timestamp {
-					klog.Infof("Deregistering remote client %s", ev.remoteAddr)
+					logger.Infof("Deregistering remote client %s", ev.remoteAddr)
 					delete(m.Registrations, ev.remoteAddr)
 				} else {
-					klog.Info("Ignoring stale deregistration event")
+					logger.Info("Ignoring stale deregistration event")
 				}
 			}
 		case msg := <-m.inCh:
-			klog.Infof("######## Received event to broadcast ######")
+			logger.Infof("######## Received event to broadcast ######")
 			for clientName, client := range m.Registrations {
 				// (TODO) Should we call this SendMsg per client in a separate go routine?
-				klog.Infof("######## Servicing the event to %s ######", clientName)
+				logger.Infof("######## Servicing the event to %s ######", clientName)
 				if err := client.stream.SendMsg(msg); err != nil {
 					// (TODO) What happens if a portion of the clients fails?
 					// there should be a mechanism to retry the failed clients.
-					klog.Errorf("Failed to send message to client %s: %v", client, err)
+					logger.Errorf("Failed to send message to client %s: %v", client, err)
 				}
 			}
 		case <-m.ctx.Done():
-			klog.Info("Context Done. Stopping transport manager")
+			logger.Info("Context Done. Stopping transport manager")
 			return nil
 		case err := <-m.errCh:
-			klog.Errorf("Error in transport manager: %v", err)
+			logger.Errorf("Error in transport manager: %v", err)
 			return err
 		case <-stopCh:
-			klog.Info("Received message on stop channel. Stopping transport manager")
+			logger.Info("Received message on stop channel. Stopping transport manager")
 			return nil
 		}
 	}
 }
 
 func (m *EventsServer) handle() error {
-	klog.Infof("Starting transport manager listener on port %v", m.port)
+	logger.Infof("Starting transport manager listener on port %v", m.port)
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", m.port))
 	if err != nil {
 		return fmt.Errorf("failed to handle server connections: %w", err)
@@ -179,7 +178,7 @@ func (m *EventsServer) handle() error {
 	// This is useful for debugging and testing with grpcurl and other CLI tools.
 	reflection.Register(server)
 
-	klog.Info("Starting transport manager server")
+	logger.Info("Starting transport manager server")
 
 	// Start gRPC Server in background
 	go func() {
@@ -121,6 +121,11 @@ const (
 	// NPM v2 Chains
 	IptablesAzureIngressPolicyChainPrefix string = "AZURE-NPM-INGRESS"
 	IptablesAzureEgressPolicyChainPrefix  string = "AZURE-NPM-EGRESS"
+	// NPM v2 chains of the AdminNetworkPolicy tiers, jumped to before and after the NetworkPolicy jumps
+	IptablesAzureIngressAdminChain    string = "AZURE-NPM-INGRESS-ADMIN"
+	IptablesAzureEgressAdminChain     string = "AZURE-NPM-EGRESS-ADMIN"
+	IptablesAzureIngressBaselineChain string = "AZURE-NPM-INGRESS-BASELINE"
+	IptablesAzureEgressBaselineChain  string = "AZURE-NPM-EGRESS-BASELINE"
 
 	// Below chain exists only in NPM before v1.2.6
 	IptablesAzureTargetSetsChain string = "AZURE-NPM-TARGET-SETS"
@@ -158,6 +163,27 @@ const (
 	IptablesAzureAcceptMarkHex string = "0x3000"
 )
 
+// nftables related constants.
+// The nftables backend programs its own table with the same chain names and marks as iptables,
+// and mirrors the ipsets as nftables sets named by their hashed names.
+const (
+	Nftables             string = "nft"
+	NftablesFileFlag     string = "-f"
+	NftablesStdin        string = "-"
+	NftablesFamily       string = "ip"
+	NftablesAzureTable   string = "azure-npm"
+	NftablesAddFlag      string = "add"
+	NftablesDeleteFlag   string = "delete"
+	NftablesFlushFlag    string = "flush"
+	NftablesListFlag     string = "list"
+	NftablesTableObject  string = "table"
+	NftablesTablesObject string = "tables"
+	NftablesChainObject  string = "chain"
+	NftablesSetObject    string = "set"
+	NftablesRuleObject   string = "rule"
+	NftablesElement      string = "element"
+)
+
 // ipset related constants.
 const (
 	Ipset               string = "ipset"
@@ -233,6 +259,8 @@ const (
 	FunctionName string = "FunctionName"
 	ErrorCode    string = "ErrorCode"
 
+	NumPoliciesDimension string = "NumPolicies"
+
 	// Default batch size in AI telemetry
 	// Defined here https://docs.microsoft.com/en-us/azure/azure-monitor/app/pricing
 	BatchSizeInBytes          int = 32768
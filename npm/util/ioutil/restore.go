@@ -12,11 +12,13 @@ import (
 
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
-	"k8s.io/klog"
 )
 
+var logger = npmlogger.For(npmlogger.Dataplane)
+
 // FileCreator is a tool for:
 // - building a buffer file
 // - running a command with the file
@@ -155,7 +157,7 @@ func (creator *FileCreator) RunCommandWithFile(cmd string, args ...string) error
 		}
 		wasFileAltered, err = creator.runCommandOnceWithFile(fileString, cmd, args...)
 		if err == nil {
-			klog.Infof("successfully ran command [%s] on try number %d", commandString, creator.tryCount)
+			logger.Infof("successfully ran command [%s] on try number %d", commandString, creator.tryCount)
 			return nil
 		}
 	}
@@ -181,11 +183,11 @@ func (creator *FileCreator) RunCommandOnceWithFile(cmd string, args ...string) (
 func (creator *FileCreator) runCommandOnceWithFile(fileString, cmd string, args ...string) (bool, error) {
 	commandString := cmd + " " + strings.Join(args, " ")
 	if fileString == "" { // NOTE this wouldn't prevent us from running an iptables restore file with just "COMMIT\n"
-		klog.Infof("returning as a success without running command [%s] since the fileString is empty", commandString)
+		logger.Infof("returning as a success without running command [%s] since the fileString is empty", commandString)
 		return false, nil
 	}
 
-	klog.Infof("running this restore command: [%s]", commandString)
+	logger.Infof("running this restore command: [%s]", commandString)
 
 	if creator.verbose {
 		creator.logLines(commandString)
@@ -296,12 +298,12 @@ func (creator *FileCreator) handleLineError(stdErr, commandString string, lineNu
 		}
 		switch errorHandler.Method {
 		case Continue:
-			klog.Infof("continuing after line %d for command [%s]", lineNum, commandString)
+			logger.Infof("continuing after line %d for command [%s]", lineNum, commandString)
 			for i := 0; i <= lineIndex; i++ {
 				creator.lineNumbersToOmit[i] = struct{}{}
 			}
 		case ContinueAndAbortSection:
-			klog.Infof("continuing after line %d and aborting section [%s] for command [%s]", lineNum, line.sectionID, commandString)
+			logger.Infof("continuing after line %d and aborting section [%s] for command [%s]", lineNum, line.sectionID, commandString)
 			for i := 0; i <= lineIndex; i++ {
 				creator.lineNumbersToOmit[i] = struct{}{}
 			}
@@ -325,7 +327,7 @@ func (creator *FileCreator) logLines(commandString string) {
 				metrics.SendErrorLogAndMetric(util.UtilID, "unexpectedly seeing an omitted line for tryCount=0. line num: %d", i)
 				continue
 			}
-			klog.Infof("line %d of restore command [%s] with section ID [%s]: [%s]", lineNum, commandString, line.sectionID, line.content)
+			logger.Infof("line %d of restore command [%s] with section ID [%s]: [%s]", lineNum, commandString, line.sectionID, line.content)
 			lineNum++
 		}
 
@@ -334,7 +336,7 @@ func (creator *FileCreator) logLines(commandString string) {
 
 	// don't print every line because printing all lines can pollute the logs and we already know the lines
 	if len(creator.lineNumbersToOmit) == 0 {
-		klog.Infof("on try %d of restore command [%s]. repeating with same lines", creator.tryCount, commandString)
+		logger.Infof("on try %d of restore command [%s]. repeating with same lines", creator.tryCount, commandString)
 		return
 	}
 
@@ -349,5 +351,5 @@ func (creator *FileCreator) logLines(commandString string) {
 		lineNum++
 	}
 
-	klog.Infof("on try %d of restore command [%s]. mapping of current line numbers to original line numbers: %+v", creator.tryCount, commandString, lineNumMappings)
+	logger.Infof("on try %d of restore command [%s]. mapping of current line numbers to original line numbers: %+v", creator.tryCount, commandString, lineNumMappings)
 }
@@ -15,12 +15,14 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/Azure/azure-container-networking/log"
+	npmlogger "github.com/Azure/azure-container-networking/npm/pkg/logger"
 	"github.com/Masterminds/semver"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/tools/cache"
 )
 
+var logger = npmlogger.For(npmlogger.NPM)
+
 // DeleteOption is used to decide if a delete is force delete or soft delete
 type DeleteOption bool
 
@@ -164,6 +166,13 @@ func GetHashedName(name string) string {
 	return AzureNpmPrefix + Hash(name)
 }
 
+// NftablesSpecs returns an nft command or file line for an object in the nftables table of NPM,
+// e.g. "add set ip azure-npm <items>".
+func NftablesSpecs(operation, object string, items ...string) []string {
+	specs := []string{operation, object, NftablesFamily, NftablesAzureTable}
+	return append(specs, items...)
+}
+
 // CompareK8sVer compares two k8s versions.
 // returns -1, 0, 1 if firstVer smaller, equals, bigger than secondVer respectively.
 // returns -2 for error.
@@ -261,7 +270,7 @@ func ParseResourceVersion(rv string) uint64 {
 	}
 	rvInt, err := strconv.ParseUint(rv, 10, 64)
 	if err != nil {
-		log.Logf("Error: while parsing resource version to uint64 %s", rv)
+		logger.Infof("Error: while parsing resource version to uint64 %s", rv)
 	}
 
 	return rvInt
@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"context"
 	"errors"
 	"time"
 )
@@ -37,6 +38,10 @@ func (e *MockExecClient) ExecuteCommand(cmd string) (string, error) {
 	return "", nil
 }
 
+func (e *MockExecClient) ExecuteCommandContext(_ context.Context, cmd string) (string, error) {
+	return e.ExecuteCommand(cmd)
+}
+
 func (e *MockExecClient) SetExecCommand(fn execCommandValidator) {
 	e.setExecCommand = fn
 }
@@ -56,6 +61,10 @@ func (e *MockExecClient) ExecutePowershellCommand(cmd string) (string, error) {
 	return "", nil
 }
 
+func (e *MockExecClient) ExecutePowershellCommandContext(_ context.Context, cmd string) (string, error) {
+	return e.ExecutePowershellCommand(cmd)
+}
+
 func (e *MockExecClient) GetLastRebootTime() (time.Time, error) {
 	return time.Time{}, nil
 }
@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"context"
 	"time"
 
 	"go.uber.org/zap"
@@ -8,6 +9,8 @@ import (
 
 const (
 	defaultExecTimeout = 10
+	// execWaitDelay bounds how long we wait for output pipes to close after a command has been killed.
+	execWaitDelay = 2 * time.Second
 )
 
 type execClient struct {
@@ -17,10 +20,17 @@ type execClient struct {
 
 //nolint:revive // ExecClient make sense
 type ExecClient interface {
+	// ExecuteCommand runs the command bounded by the client timeout.
 	ExecuteCommand(command string) (string, error)
+	// ExecuteCommandContext runs the command until it exits or ctx is done, whichever is first.
+	// When ctx is done the whole process tree of the command is killed.
+	ExecuteCommandContext(ctx context.Context, command string) (string, error)
 	GetLastRebootTime() (time.Time, error)
 	ClearNetworkConfiguration() (bool, error)
+	// ExecutePowershellCommand runs the powershell command bounded by the client timeout.
 	ExecutePowershellCommand(command string) (string, error)
+	// ExecutePowershellCommandContext is the context aware variant of ExecutePowershellCommand.
+	ExecutePowershellCommandContext(ctx context.Context, command string) (string, error)
 	KillProcessByName(processName string) error
 }
 
@@ -36,3 +46,11 @@ func NewExecClientTimeout(timeout time.Duration) ExecClient {
 		Timeout: timeout,
 	}
 }
+
+// withTimeout derives a context bounded by the client's default timeout.
+func (p *execClient) withTimeout() (context.Context, context.CancelFunc) {
+	if p.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), p.Timeout)
+}
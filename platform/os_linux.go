@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Azure/azure-container-networking/log"
@@ -43,16 +44,6 @@ const (
 	osReleaseFile = "/etc/os-release"
 )
 
-// GetOSInfo returns OS version information.
-func GetOSInfo() string {
-	info, err := os.ReadFile("/proc/version")
-	if err != nil {
-		return "unknown"
-	}
-
-	return string(info)
-}
-
 func GetProcessSupport() error {
 	p := NewExecClient(nil)
 	cmd := fmt.Sprintf("ps -p %v -o comm=", os.Getpid())
@@ -89,6 +80,15 @@ func (p *execClient) GetLastRebootTime() (time.Time, error) {
 }
 
 func (p *execClient) ExecuteCommand(command string) (string, error) {
+	ctx, cancel := p.withTimeout()
+	defer cancel()
+
+	return p.ExecuteCommandContext(ctx, command)
+}
+
+// ExecuteCommandContext runs the command in its own process group. If ctx is done before the
+// command exits, the whole group is killed so that children spawned by the shell do not outlive it.
+func (p *execClient) ExecuteCommandContext(ctx context.Context, command string) (string, error) {
 	if p.logger != nil {
 		p.logger.Info("[Azure-Utils]", zap.String("command", command))
 	} else {
@@ -98,16 +98,21 @@ func (p *execClient) ExecuteCommand(command string) (string, error) {
 	var stderr bytes.Buffer
 	var out bytes.Buffer
 
-	// Create a new context and add a timeout to it
-	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
-	defer cancel() // The cancel should be deferred so resources are cleaned up
-
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Stderr = &stderr
 	cmd.Stdout = &out
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// a negative pid signals every process in the group
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = execWaitDelay
 
 	err := cmd.Run()
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("command %q aborted: %w: %s:%s", command, ctxErr, err.Error(), stderr.String())
+		}
 		return "", fmt.Errorf("%s:%s", err.Error(), stderr.String())
 	}
 
@@ -136,6 +141,10 @@ func (p *execClient) ExecutePowershellCommand(_ string) (string, error) {
 	return "", nil
 }
 
+func (p *execClient) ExecutePowershellCommandContext(_ context.Context, _ string) (string, error) {
+	return "", nil
+}
+
 func (p *execClient) KillProcessByName(processName string) error {
 	cmd := fmt.Sprintf("pkill -f %v", processName)
 	_, err := p.ExecuteCommand(cmd)
@@ -1,6 +1,9 @@
 package platform
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -27,3 +30,35 @@ func TestExecuteCommandNoTimeout(t *testing.T) {
 		t.Errorf("TestExecuteCommandNoTimeout failed with error %v", err)
 	}
 }
+
+// Cancelling the context should kill the command along with any children the shell spawned
+func TestExecuteCommandContextKillsProcessGroup(t *testing.T) {
+	client := NewExecClient(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.ExecuteCommandContext(ctx, "sleep 30 & sleep 30; wait")
+	if err == nil {
+		t.Fatal("ExecuteCommandContext should have returned an error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command was not killed in time, took %v", elapsed)
+	}
+}
+
+// Errors from failed commands should carry the command's stderr
+func TestExecuteCommandContextCapturesStderr(t *testing.T) {
+	client := NewExecClient(nil)
+
+	_, err := client.ExecuteCommandContext(context.Background(), "echo boom >&2; exit 1")
+	if err == nil {
+		t.Fatal("ExecuteCommandContext should have returned an error")
+	}
+	if !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected stderr in error, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -82,16 +83,14 @@ const (
 	// reg key value for PriorityVLANTag = 3  --> Packet priority and VLAN enabled
 	// for more details goto https://learn.microsoft.com/en-us/windows-hardware/drivers/network/standardized-inf-keywords-for-ndis-qos
 	desiredVLANTagForMellanox = 3
+
+	// Upper bound on how long we wait for the hns service to restart
+	restartHnsServiceTimeout = 2 * time.Minute
 )
 
 // Flag to check if sdnRemoteArpMacAddress registry key is set
 var sdnRemoteArpMacAddressSet = false
 
-// GetOSInfo returns OS version information.
-func GetOSInfo() string {
-	return "windows"
-}
-
 func GetProcessSupport() error {
 	p := NewExecClient(nil)
 	cmd := fmt.Sprintf("Get-Process -Id %v", os.Getpid())
@@ -122,7 +121,17 @@ func (p *execClient) GetLastRebootTime() (time.Time, error) {
 	return rebootTime.UTC(), nil
 }
 
+// ExecuteCommand runs the command through cmd.exe, bounded by the client timeout.
 func (p *execClient) ExecuteCommand(command string) (string, error) {
+	ctx, cancel := p.withTimeout()
+	defer cancel()
+
+	return p.ExecuteCommandContext(ctx, command)
+}
+
+// ExecuteCommandContext runs the command through cmd.exe. If ctx is done before the command exits,
+// the whole process tree rooted at cmd.exe is killed.
+func (p *execClient) ExecuteCommandContext(ctx context.Context, command string) (string, error) {
 	if p.logger != nil {
 		p.logger.Info("[Azure-Utils]", zap.String("ExecuteCommand", command))
 	} else {
@@ -131,17 +140,34 @@ func (p *execClient) ExecuteCommand(command string) (string, error) {
 
 	var stderr, stdout bytes.Buffer
 
-	cmd := exec.Command("cmd", "/c", command)
+	cmd := exec.CommandContext(ctx, "cmd", "/c", command)
 	cmd.Stderr = &stderr
 	cmd.Stdout = &stdout
+	setProcessTreeKill(cmd)
 
 	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", errors.Wrapf(fmt.Errorf("%w: %w", ctxErr, err), "ExecuteCommand aborted. stdout: %q, stderr: %q", stdout.String(), stderr.String())
+		}
 		return "", errors.Wrapf(err, "ExecuteCommand failed. stdout: %q, stderr: %q", stdout.String(), stderr.String())
 	}
 
 	return stdout.String(), nil
 }
 
+// setProcessTreeKill makes cancellation of cmd kill the process and all of its descendants.
+// Windows has no process groups that map onto exec.Cmd, so this shells out to taskkill /T.
+func setProcessTreeKill(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		if err := kill.Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = execWaitDelay
+}
+
 func SetOutboundSNAT(subnet string) error {
 	return nil
 }
@@ -167,8 +193,16 @@ func (p *execClient) KillProcessByName(processName string) error {
 	return err // nolint
 }
 
-// ExecutePowershellCommand executes powershell command
+// ExecutePowershellCommand executes powershell command, bounded by the client timeout.
 func (p *execClient) ExecutePowershellCommand(command string) (string, error) {
+	ctx, cancel := p.withTimeout()
+	defer cancel()
+
+	return p.ExecutePowershellCommandContext(ctx, command)
+}
+
+// ExecutePowershellCommandContext executes powershell command, killing it if ctx is done first.
+func (p *execClient) ExecutePowershellCommandContext(ctx context.Context, command string) (string, error) {
 	ps, err := exec.LookPath("powershell.exe")
 	if err != nil {
 		return "", fmt.Errorf("Failed to find powershell executable")
@@ -180,14 +214,18 @@ func (p *execClient) ExecutePowershellCommand(command string) (string, error) {
 		log.Printf("[Azure-Utils] %s", command)
 	}
 
-	cmd := exec.Command(ps, command)
+	cmd := exec.CommandContext(ctx, ps, command)
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	setProcessTreeKill(cmd)
 
 	err = cmd.Run()
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("command %q aborted: %w: %s:%s", command, ctxErr, err.Error(), stderr.String())
+		}
 		return "", fmt.Errorf("%s:%s", err.Error(), stderr.String())
 	}
 
@@ -220,7 +258,10 @@ func SetSdnRemoteArpMacAddress(execClient ExecClient) error {
 			}
 
 			log.Printf("[Azure CNS] SDNRemoteArpMacAddress regKey set successfully. Restarting hns service.")
-			if _, err := execClient.ExecutePowershellCommand(RestartHnsServiceCommand); err != nil {
+			// restarting hns can take much longer than the default command timeout
+			ctx, cancel := context.WithTimeout(context.Background(), restartHnsServiceTimeout)
+			defer cancel()
+			if _, err := execClient.ExecutePowershellCommandContext(ctx, RestartHnsServiceCommand); err != nil {
 				log.Printf("Failed to Restart HNS Service due to error %s", err.Error())
 				return err
 			}
@@ -0,0 +1,134 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Distro IDs as reported in OSInfo.DistroID.
+const (
+	DistroUbuntu     = "ubuntu"
+	DistroMariner    = "mariner"
+	DistroAzureLinux = "azurelinux"
+	DistroWindows    = "windows"
+)
+
+// containerRuntimeVersionTimeout bounds the containerd --version call, the runtime version is best effort.
+const containerRuntimeVersionTimeout = 2 * time.Second
+
+// Windows Server builds, by release.
+const (
+	WindowsServer2019Build = 17763
+	WindowsServer2022Build = 20348
+	WindowsServer2025Build = 26100
+)
+
+// OSInfo is the structured description of the host OS.
+type OSInfo struct {
+	// Type is the OS family, linux or windows.
+	Type string
+	// DistroID identifies the distribution, e.g. ubuntu, mariner, azurelinux or windows.
+	DistroID string
+	// Name is the human readable name of the OS, e.g. "Ubuntu 22.04.4 LTS".
+	Name string
+	// Version is the distribution version, e.g. "22.04", or the Windows Server release, e.g. "2022".
+	Version string
+	// Kernel is the kernel release on linux and the major.minor.build.UBR version on windows.
+	Kernel string
+	// Build and UBR (update build revision) identify the patch level of Windows. They are zero on linux.
+	Build int
+	UBR   int
+	// ContainerRuntime and ContainerRuntimeVersion describe the node's container runtime, if detected.
+	ContainerRuntime        string
+	ContainerRuntimeVersion string
+}
+
+func (o OSInfo) String() string {
+	s := strings.TrimSpace(o.DistroID + " " + o.Version)
+	if o.Kernel != "" {
+		s += " (kernel " + o.Kernel + ")"
+	}
+	if o.ContainerRuntime != "" {
+		s += " " + o.ContainerRuntime + " " + o.ContainerRuntimeVersion
+	}
+	return s
+}
+
+// parseOSRelease parses the KEY=value lines of an os-release file, removing quotes from values.
+func parseOSRelease(content string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		fields[key] = strings.Trim(value, `"'`)
+	}
+	return fields
+}
+
+// osInfoFromRelease fills the distro fields of info from parsed os-release fields.
+func osInfoFromRelease(info *OSInfo, release map[string]string) {
+	info.DistroID = strings.ToLower(release["ID"])
+	info.Name = release["PRETTY_NAME"]
+	if info.Name == "" {
+		info.Name = release["NAME"]
+	}
+	info.Version = release["VERSION_ID"]
+
+	// CBL-Mariner 3.0 was renamed Azure Linux, but hosts upgraded in place can still carry the old ID
+	if info.DistroID == DistroMariner && strings.HasPrefix(info.Version, "3.") {
+		info.DistroID = DistroAzureLinux
+	}
+}
+
+// windowsServerRelease returns the Windows Server release of the build, or the build number if it is
+// not a known Windows Server release.
+func windowsServerRelease(build int) string {
+	switch build {
+	case WindowsServer2019Build:
+		return "2019"
+	case WindowsServer2022Build:
+		return "2022"
+	case WindowsServer2025Build:
+		return "2025"
+	default:
+		return fmt.Sprint(build)
+	}
+}
+
+var containerdVersionRe = regexp.MustCompile(`\bv?(\d+\.\d+\.\d+[^\s]*)`)
+
+// parseContainerdVersion extracts the version from the output of "containerd --version", e.g.
+// "containerd github.com/containerd/containerd v1.7.15-1 926c9586fe4a6236699318391cd44976a98e31f1".
+func parseContainerdVersion(out string) string {
+	m := containerdVersionRe.FindStringSubmatch(out)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// DetectContainerRuntime fills the container runtime fields of info, on a best effort basis.
+func DetectContainerRuntime(info *OSInfo) {
+	path, err := exec.LookPath(containerdBinary)
+	if err != nil {
+		return
+	}
+	info.ContainerRuntime = "containerd"
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerRuntimeVersionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err == nil {
+		info.ContainerRuntimeVersion = parseContainerdVersion(string(out))
+	}
+}
@@ -0,0 +1,39 @@
+package platform
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	containerdBinary = "containerd"
+)
+
+// GetOSInfo returns the structured description of the host OS. Fields which could not be detected are
+// left empty and the error describes why. The container runtime fields are left to DetectContainerRuntime,
+// as running the runtime binary is too slow for every caller.
+func GetOSInfo() (OSInfo, error) {
+	info := OSInfo{Type: runtime.GOOS}
+
+	// keep going on errors so the caller gets whatever could be detected
+	var firstErr error
+	if b, err := os.ReadFile(osReleaseFile); err != nil {
+		firstErr = errors.Wrap(err, "failed to read os release")
+	} else {
+		osInfoFromRelease(&info, parseOSRelease(string(b)))
+	}
+
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		if firstErr == nil {
+			firstErr = errors.Wrap(err, "uname failed")
+		}
+	} else {
+		info.Kernel = unix.ByteSliceToString(uts.Release[:])
+	}
+
+	return info, firstErr
+}
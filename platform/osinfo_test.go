@@ -0,0 +1,90 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOSInfoFromRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		release string
+		want    OSInfo
+	}{
+		{
+			name: "ubuntu",
+			release: `PRETTY_NAME="Ubuntu 22.04.4 LTS"
+NAME="Ubuntu"
+VERSION_ID="22.04"
+VERSION="22.04.4 LTS (Jammy Jellyfish)"
+ID=ubuntu
+ID_LIKE=debian
+`,
+			want: OSInfo{DistroID: DistroUbuntu, Name: "Ubuntu 22.04.4 LTS", Version: "22.04"},
+		},
+		{
+			name: "mariner",
+			release: `NAME="Common Base Linux Mariner"
+VERSION="2.0.20240301"
+ID=mariner
+VERSION_ID="2.0"
+PRETTY_NAME="CBL-Mariner/Linux"
+`,
+			want: OSInfo{DistroID: DistroMariner, Name: "CBL-Mariner/Linux", Version: "2.0"},
+		},
+		{
+			name: "azure linux",
+			release: `NAME="Microsoft Azure Linux"
+VERSION="3.0.20240824"
+ID=azurelinux
+VERSION_ID="3.0"
+PRETTY_NAME="Microsoft Azure Linux 3.0"
+`,
+			want: OSInfo{DistroID: DistroAzureLinux, Name: "Microsoft Azure Linux 3.0", Version: "3.0"},
+		},
+		{
+			name: "mariner upgraded to azure linux",
+			release: `# upgraded in place
+ID=mariner
+VERSION_ID=3.0
+NAME='Microsoft Azure Linux'
+`,
+			want: OSInfo{DistroID: DistroAzureLinux, Name: "Microsoft Azure Linux", Version: "3.0"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var got OSInfo
+			osInfoFromRelease(&got, parseOSRelease(tt.release))
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWindowsServerRelease(t *testing.T) {
+	assert.Equal(t, "2019", windowsServerRelease(WindowsServer2019Build))
+	assert.Equal(t, "2022", windowsServerRelease(WindowsServer2022Build))
+	assert.Equal(t, "2025", windowsServerRelease(WindowsServer2025Build))
+	assert.Equal(t, "22631", windowsServerRelease(22631))
+}
+
+func TestParseContainerdVersion(t *testing.T) {
+	assert.Equal(t, "1.7.15-1", parseContainerdVersion("containerd github.com/containerd/containerd v1.7.15-1 926c9586fe4a6236699318391cd44976a98e31f1\n"))
+	assert.Equal(t, "1.6.26", parseContainerdVersion("containerd github.com/containerd/containerd 1.6.26 \n"))
+	assert.Empty(t, parseContainerdVersion("containerd: command failed"))
+}
+
+func TestOSInfoString(t *testing.T) {
+	info := OSInfo{DistroID: DistroUbuntu, Version: "22.04", Kernel: "5.15.0-1061-azure", ContainerRuntime: "containerd", ContainerRuntimeVersion: "1.7.15"}
+	assert.Equal(t, "ubuntu 22.04 (kernel 5.15.0-1061-azure) containerd 1.7.15", info.String())
+}
+
+func TestGetOSInfo(t *testing.T) {
+	info, err := GetOSInfo()
+	require.NoError(t, err)
+	assert.NotEmpty(t, info.Type)
+	assert.NotEmpty(t, info.Kernel)
+}
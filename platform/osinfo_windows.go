@@ -0,0 +1,49 @@
+package platform
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	currentVersionKey = `SOFTWARE\Microsoft\Windows NT\CurrentVersion`
+	containerdBinary  = "containerd.exe"
+)
+
+// GetOSInfo returns the structured description of the host OS. Fields which could not be detected are
+// left empty and the error describes why. The container runtime fields are left to DetectContainerRuntime,
+// as running the runtime binary is too slow for every caller.
+func GetOSInfo() (OSInfo, error) {
+	info := OSInfo{Type: runtime.GOOS, DistroID: DistroWindows}
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, currentVersionKey, registry.QUERY_VALUE)
+	if err != nil {
+		return info, errors.Wrap(err, "failed to open windows version key")
+	}
+	defer k.Close()
+
+	info.Name, _, _ = k.GetStringValue("ProductName")
+
+	buildStr, _, err := k.GetStringValue("CurrentBuildNumber")
+	if err != nil {
+		return info, errors.Wrap(err, "failed to read windows build number")
+	}
+	if info.Build, err = strconv.Atoi(buildStr); err != nil {
+		return info, errors.Wrapf(err, "invalid windows build number %q", buildStr)
+	}
+
+	// UBR is missing on builds without cumulative updates
+	if ubr, _, err := k.GetIntegerValue("UBR"); err == nil {
+		info.UBR = int(ubr)
+	}
+
+	major, _, _ := k.GetIntegerValue("CurrentMajorVersionNumber")
+	minor, _, _ := k.GetIntegerValue("CurrentMinorVersionNumber")
+	info.Kernel = fmt.Sprintf("%d.%d.%d.%d", major, minor, info.Build, info.UBR)
+	info.Version = windowsServerRelease(info.Build)
+
+	return info, nil
+}
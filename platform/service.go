@@ -0,0 +1,189 @@
+package platform
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CNSServiceName is the name CNS is registered under when run as an OS managed service.
+	CNSServiceName = "azure-cns"
+	// TelemetryServiceName is the name the CNI telemetry service is registered under.
+	TelemetryServiceName = "azure-vnet-telemetry"
+
+	defaultRecoveryResetPeriod = 24 * time.Hour
+	// defaultServiceStopTimeout bounds how long the stop command waits for the service to stop.
+	defaultServiceStopTimeout = 30 * time.Second
+)
+
+// Service lifecycle commands accepted by RunServiceCommand.
+const (
+	ServiceCommandInstall   = "install"
+	ServiceCommandUninstall = "uninstall"
+	ServiceCommandStart     = "start"
+	ServiceCommandStop      = "stop"
+	ServiceCommandStatus    = "status"
+)
+
+var (
+	// ErrServiceNotSupported is returned by the service helpers on platforms without a service manager integration.
+	ErrServiceNotSupported = errors.New("service management is not supported on this platform")
+	// ErrServiceExists is returned when installing a service that is already registered.
+	ErrServiceExists = errors.New("service already exists")
+	// ErrServiceNotInstalled is returned when operating on a service that is not registered.
+	ErrServiceNotInstalled = errors.New("service is not installed")
+	// ErrUnknownServiceCommand is returned by RunServiceCommand for a command it does not know.
+	ErrUnknownServiceCommand = errors.New("unknown service command")
+)
+
+// ServiceState is the run state of an installed service.
+type ServiceState string
+
+const (
+	ServiceStateUnknown         ServiceState = "Unknown"
+	ServiceStateStopped         ServiceState = "Stopped"
+	ServiceStateStartPending    ServiceState = "StartPending"
+	ServiceStateStopPending     ServiceState = "StopPending"
+	ServiceStateRunning         ServiceState = "Running"
+	ServiceStateContinuePending ServiceState = "ContinuePending"
+	ServiceStatePausePending    ServiceState = "PausePending"
+	ServiceStatePaused          ServiceState = "Paused"
+)
+
+// ServiceRecoveryType is what the service manager does when a service fails.
+type ServiceRecoveryType int
+
+const (
+	ServiceRecoveryNone ServiceRecoveryType = iota
+	ServiceRecoveryRestart
+)
+
+// ServiceRecoveryAction is taken by the service manager after a failure. Actions are applied in order
+// to the first, second and subsequent failures.
+type ServiceRecoveryAction struct {
+	Type  ServiceRecoveryType
+	Delay time.Duration
+}
+
+// ServiceConfig describes how a component is registered with the service manager.
+type ServiceConfig struct {
+	Name        string
+	DisplayName string
+	Description string
+	ExecPath    string
+	Args        []string
+	// RecoveryActions to configure for the service. No recovery is configured if empty.
+	RecoveryActions []ServiceRecoveryAction
+	// RecoveryResetPeriod is how long the service must run without failing before the failure count is reset.
+	RecoveryResetPeriod time.Duration
+}
+
+// defaultRecoveryActions restarts the service with a growing delay so a crash looping service does not spin.
+func defaultRecoveryActions() []ServiceRecoveryAction {
+	return []ServiceRecoveryAction{
+		{Type: ServiceRecoveryRestart, Delay: 5 * time.Second},
+		{Type: ServiceRecoveryRestart, Delay: 10 * time.Second},
+		{Type: ServiceRecoveryRestart, Delay: 30 * time.Second},
+	}
+}
+
+// CNSServiceConfig returns the service configuration for CNS running from execPath.
+func CNSServiceConfig(execPath string, args ...string) ServiceConfig {
+	return ServiceConfig{
+		Name:                CNSServiceName,
+		DisplayName:         "Azure Container Networking Service",
+		Description:         "Manages IP address allocation and network containers for Azure container networking.",
+		ExecPath:            execPath,
+		Args:                args,
+		RecoveryActions:     defaultRecoveryActions(),
+		RecoveryResetPeriod: defaultRecoveryResetPeriod,
+	}
+}
+
+// TelemetryServiceConfig returns the service configuration for the CNI telemetry service running from execPath.
+func TelemetryServiceConfig(execPath string, args ...string) ServiceConfig {
+	return ServiceConfig{
+		Name:                TelemetryServiceName,
+		DisplayName:         "Azure CNI Telemetry",
+		Description:         "Forwards Azure CNI telemetry reports to Application Insights.",
+		ExecPath:            execPath,
+		Args:                args,
+		RecoveryActions:     defaultRecoveryActions(),
+		RecoveryResetPeriod: defaultRecoveryResetPeriod,
+	}
+}
+
+// RunServiceCommand runs the lifecycle command on the service described by config, and returns
+// what should be printed to the user.
+func RunServiceCommand(ctx context.Context, command string, config ServiceConfig) (string, error) {
+	switch strings.ToLower(command) {
+	case ServiceCommandInstall:
+		if err := InstallService(config); err != nil {
+			return "", err
+		}
+		return "installed service " + config.Name, nil
+	case ServiceCommandUninstall:
+		if err := UninstallService(config.Name); err != nil {
+			return "", err
+		}
+		return "uninstalled service " + config.Name, nil
+	case ServiceCommandStart:
+		if err := StartService(config.Name); err != nil {
+			return "", err
+		}
+		return "started service " + config.Name, nil
+	case ServiceCommandStop:
+		ctx, cancel := context.WithTimeout(ctx, defaultServiceStopTimeout)
+		defer cancel()
+		if err := StopService(ctx, config.Name); err != nil {
+			return "", err
+		}
+		return "stopped service " + config.Name, nil
+	case ServiceCommandStatus:
+		state, err := QueryServiceState(config.Name)
+		if err != nil {
+			return "", err
+		}
+		return config.Name + ": " + string(state), nil
+	default:
+		return "", errors.Wrapf(ErrUnknownServiceCommand, "%q", command)
+	}
+}
+
+// ServiceArgs returns the command line args without the service lifecycle flag, so that the
+// installed service is started with the same args as the install command otherwise had.
+func ServiceArgs(args []string, name, shorthand string) []string {
+	isFlag := func(arg string) (match, hasValue bool) {
+		arg = strings.TrimLeft(arg, "-")
+		for _, n := range []string{name, shorthand} {
+			if arg == n {
+				return true, false
+			}
+			if strings.HasPrefix(arg, n+"=") {
+				return true, true
+			}
+		}
+		return false, false
+	}
+
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			out = append(out, args[i])
+			continue
+		}
+		match, hasValue := isFlag(args[i])
+		if !match {
+			out = append(out, args[i])
+			continue
+		}
+		if !hasValue {
+			// skip the value given as the next arg
+			i++
+		}
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package platform
+
+import "context"
+
+// Services on linux are managed by systemd units shipped alongside the binaries,
+// so the lifecycle helpers are not supported here.
+
+func InstallService(_ ServiceConfig) error {
+	return ErrServiceNotSupported
+}
+
+func UninstallService(_ string) error {
+	return ErrServiceNotSupported
+}
+
+func StartService(_ string) error {
+	return ErrServiceNotSupported
+}
+
+func StopService(_ context.Context, _ string) error {
+	return ErrServiceNotSupported
+}
+
+func QueryServiceState(_ string) (ServiceState, error) {
+	return ServiceStateUnknown, ErrServiceNotSupported
+}
+
+// ServiceEventLog reports service level events to the OS event log.
+type ServiceEventLog struct{}
+
+func OpenServiceEventLog(_ string) (*ServiceEventLog, error) {
+	return nil, ErrServiceNotSupported
+}
+
+func (*ServiceEventLog) Info(_ string) error {
+	return ErrServiceNotSupported
+}
+
+func (*ServiceEventLog) Error(_ error) error {
+	return ErrServiceNotSupported
+}
+
+func (*ServiceEventLog) Close() error {
+	return nil
+}
+
+// ServiceHost connects a process to the OS service manager. It is not needed with systemd.
+type ServiceHost struct{}
+
+// RunAsService returns nil, as services on linux need no host.
+func RunAsService(_ string, _ func()) (*ServiceHost, error) {
+	return nil, nil
+}
+
+// Exited is a no-op on linux.
+func (*ServiceHost) Exited(_ error) {}
@@ -0,0 +1,49 @@
+package platform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "separate value",
+			args: []string{"-c", "tcp://localhost:10090", "--service", "install", "-t", "1"},
+			want: []string{"-c", "tcp://localhost:10090", "-t", "1"},
+		},
+		{
+			name: "joined value",
+			args: []string{"-service=install", "-t", "1"},
+			want: []string{"-t", "1"},
+		},
+		{
+			name: "shorthand",
+			args: []string{"-svc", "install", "-servicename", "x"},
+			want: []string{"-servicename", "x"},
+		},
+		{
+			name: "no service flag",
+			args: []string{"-t", "1"},
+			want: []string{"-t", "1"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ServiceArgs(tt.args, "service", "svc"))
+		})
+	}
+}
+
+func TestRunServiceCommandUnknown(t *testing.T) {
+	_, err := RunServiceCommand(context.Background(), "restart", CNSServiceConfig("azure-cns.exe"))
+	require.ErrorIs(t, err, ErrUnknownServiceCommand)
+}
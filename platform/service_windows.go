@@ -0,0 +1,377 @@
+package platform
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	// Interval between successive status queries while waiting for a service to stop
+	serviceStatePollInterval = 500 * time.Millisecond
+
+	// Event IDs written to the event log by ServiceEventLog
+	serviceInfoEventID  = 1
+	serviceErrorEventID = 2
+)
+
+// InstallService registers the service with the service control manager, configures its recovery
+// actions and registers it as an event log source. The service is set to start automatically.
+func InstallService(config ServiceConfig) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to service manager")
+	}
+	defer m.Disconnect() //nolint:errcheck // ignore error on disconnect
+
+	if s, err := m.OpenService(config.Name); err == nil {
+		s.Close()
+		return errors.Wrapf(ErrServiceExists, "service %s", config.Name)
+	}
+
+	s, err := m.CreateService(config.Name, config.ExecPath, mgr.Config{
+		DisplayName: config.DisplayName,
+		Description: config.Description,
+		StartType:   mgr.StartAutomatic,
+	}, config.Args...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create service %s", config.Name)
+	}
+	defer s.Close()
+
+	if err := setRecoveryActions(s, config); err != nil {
+		deleteService(s, config.Name)
+		return err
+	}
+
+	if err := eventlog.InstallAsEventCreate(config.Name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		deleteService(s, config.Name)
+		return errors.Wrapf(err, "failed to register event log source for service %s", config.Name)
+	}
+
+	log.Printf("[platform] Installed service %s with binary %s", config.Name, config.ExecPath)
+	return nil
+}
+
+func setRecoveryActions(s *mgr.Service, config ServiceConfig) error {
+	if len(config.RecoveryActions) == 0 {
+		return nil
+	}
+
+	if err := s.SetRecoveryActions(toMgrRecoveryActions(config.RecoveryActions), uint32(config.RecoveryResetPeriod.Seconds())); err != nil {
+		return errors.Wrapf(err, "failed to set recovery actions for service %s", config.Name)
+	}
+
+	// also recover when the service stops with a non-zero exit code rather than crashing
+	if err := s.SetRecoveryActionsOnNonCrashFailures(true); err != nil {
+		return errors.Wrapf(err, "failed to enable recovery on non-crash failures for service %s", config.Name)
+	}
+
+	return nil
+}
+
+func toMgrRecoveryActions(actions []ServiceRecoveryAction) []mgr.RecoveryAction {
+	out := make([]mgr.RecoveryAction, 0, len(actions))
+	for _, a := range actions {
+		t := mgr.NoAction
+		if a.Type == ServiceRecoveryRestart {
+			t = mgr.ServiceRestart
+		}
+		out = append(out, mgr.RecoveryAction{Type: t, Delay: a.Delay})
+	}
+	return out
+}
+
+func deleteService(s *mgr.Service, name string) {
+	if err := s.Delete(); err != nil {
+		log.Errorf("[platform] Failed to clean up service %s after failed install: %v", name, err)
+	}
+}
+
+// UninstallService stops the service if it is running, removes it from the service control manager
+// and removes its event log source.
+func UninstallService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to service manager")
+	}
+	defer m.Disconnect() //nolint:errcheck // ignore error on disconnect
+
+	s, err := openService(m, name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if status, err := s.Query(); err == nil && status.State != svc.Stopped {
+		if _, err := s.Control(svc.Stop); err != nil {
+			log.Printf("[platform] Failed to stop service %s before uninstall: %v", name, err)
+		}
+	}
+
+	if err := s.Delete(); err != nil {
+		return errors.Wrapf(err, "failed to delete service %s", name)
+	}
+
+	if err := eventlog.Remove(name); err != nil {
+		log.Printf("[platform] Failed to remove event log source for service %s: %v", name, err)
+	}
+
+	log.Printf("[platform] Uninstalled service %s", name)
+	return nil
+}
+
+// StartService asks the service control manager to start the service. It does not wait for the service to be running.
+func StartService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to service manager")
+	}
+	defer m.Disconnect() //nolint:errcheck // ignore error on disconnect
+
+	s, err := openService(m, name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := s.Start(); err != nil {
+		return errors.Wrapf(err, "failed to start service %s", name)
+	}
+
+	return nil
+}
+
+// StopService stops the service and waits until it has stopped or ctx is done.
+func StopService(ctx context.Context, name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to service manager")
+	}
+	defer m.Disconnect() //nolint:errcheck // ignore error on disconnect
+
+	s, err := openService(m, name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		// stopping a service that is not running is not an error
+		if errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to stop service %s", name)
+	}
+
+	ticker := time.NewTicker(serviceStatePollInterval)
+	defer ticker.Stop()
+
+	for status.State != svc.Stopped {
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "timed out waiting for service %s to stop", name)
+		case <-ticker.C:
+		}
+
+		if status, err = s.Query(); err != nil {
+			return errors.Wrapf(err, "failed to query service %s", name)
+		}
+	}
+
+	return nil
+}
+
+// QueryServiceState returns the current state of the service.
+func QueryServiceState(name string) (ServiceState, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return ServiceStateUnknown, errors.Wrap(err, "failed to connect to service manager")
+	}
+	defer m.Disconnect() //nolint:errcheck // ignore error on disconnect
+
+	s, err := openService(m, name)
+	if err != nil {
+		return ServiceStateUnknown, err
+	}
+	defer s.Close()
+
+	status, err := s.Query()
+	if err != nil {
+		return ServiceStateUnknown, errors.Wrapf(err, "failed to query service %s", name)
+	}
+
+	return toServiceState(status.State), nil
+}
+
+func openService(m *mgr.Mgr, name string) (*mgr.Service, error) {
+	s, err := m.OpenService(name)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+			return nil, errors.Wrapf(ErrServiceNotInstalled, "service %s", name)
+		}
+		return nil, errors.Wrapf(err, "failed to open service %s", name)
+	}
+	return s, nil
+}
+
+func toServiceState(state svc.State) ServiceState {
+	switch state {
+	case svc.Stopped:
+		return ServiceStateStopped
+	case svc.StartPending:
+		return ServiceStateStartPending
+	case svc.StopPending:
+		return ServiceStateStopPending
+	case svc.Running:
+		return ServiceStateRunning
+	case svc.ContinuePending:
+		return ServiceStateContinuePending
+	case svc.PausePending:
+		return ServiceStatePausePending
+	case svc.Paused:
+		return ServiceStatePaused
+	default:
+		return ServiceStateUnknown
+	}
+}
+
+// ServiceEventLog reports service level events to the Windows Application event log
+// under the source registered by InstallService.
+type ServiceEventLog struct {
+	elog *eventlog.Log
+}
+
+// OpenServiceEventLog opens the event log source for the named service.
+func OpenServiceEventLog(name string) (*ServiceEventLog, error) {
+	elog, err := eventlog.Open(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open event log for service %s", name)
+	}
+	return &ServiceEventLog{elog: elog}, nil
+}
+
+// Info writes an informational event.
+func (l *ServiceEventLog) Info(msg string) error {
+	return errors.Wrap(l.elog.Info(serviceInfoEventID, msg), "failed to write info event")
+}
+
+// Error writes an error event.
+func (l *ServiceEventLog) Error(err error) error {
+	return errors.Wrap(l.elog.Error(serviceErrorEventID, err.Error()), "failed to write error event")
+}
+
+// Close releases the event log handle.
+func (l *ServiceEventLog) Close() error {
+	return errors.Wrap(l.elog.Close(), "failed to close event log")
+}
+
+// ServiceHost connects a process started by the service control manager to it: the manager is told
+// the service is running, stop and shutdown requests are forwarded to the process, and the process
+// exit is reported back, with an event log entry if it failed.
+type ServiceHost struct {
+	name    string
+	elog    *ServiceEventLog
+	exited  chan error
+	stopped chan struct{}
+}
+
+// RunAsService starts the ServiceHost if the process was started by the service control manager.
+// stop is called when the manager asks the service to stop. It returns nil if the process is not
+// running as a service.
+func RunAsService(name string, stop func()) (*ServiceHost, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to determine if running as a service")
+	}
+	if !isService {
+		return nil, nil
+	}
+
+	h := &ServiceHost{
+		name:    name,
+		exited:  make(chan error, 1),
+		stopped: make(chan struct{}),
+	}
+	if h.elog, err = OpenServiceEventLog(name); err != nil {
+		// the service still runs without the event log, errors are in the service log
+		log.Errorf("[platform] %v", err)
+	}
+
+	go func() {
+		defer close(h.stopped)
+		if err := svc.Run(name, &serviceHandler{host: h, stop: stop}); err != nil {
+			log.Errorf("[platform] Service %s failed: %v", name, err)
+			h.reportError(err)
+		}
+	}()
+	return h, nil
+}
+
+// Exited reports the process exit, with the error it failed with if any, to the service control
+// manager, and waits until the manager has been told. It is a no-op if h is nil.
+func (h *ServiceHost) Exited(err error) {
+	if h == nil {
+		return
+	}
+	h.exited <- err
+	<-h.stopped
+	if h.elog != nil {
+		h.elog.Close() //nolint:errcheck // nothing to do if closing fails on exit
+	}
+}
+
+func (h *ServiceHost) reportError(err error) {
+	if h.elog == nil {
+		return
+	}
+	if elogErr := h.elog.Error(err); elogErr != nil {
+		log.Errorf("[platform] %v", elogErr)
+	}
+}
+
+type serviceHandler struct {
+	host *ServiceHost
+	stop func()
+}
+
+// Execute implements svc.Handler. A non-zero exit code is returned when the process failed, so that
+// the recovery actions, which InstallService enables for non-crash failures, are taken.
+func (s *serviceHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	if s.host.elog != nil {
+		s.host.elog.Info("service " + s.host.name + " started") //nolint:errcheck // best effort
+	}
+
+	stopping := false
+	for {
+		select {
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				if !stopping {
+					stopping = true
+					status <- svc.Status{State: svc.StopPending}
+					s.stop()
+				}
+			}
+		case err := <-s.host.exited:
+			status <- svc.Status{State: svc.StopPending}
+			if err != nil {
+				s.host.reportError(errors.Wrapf(err, "service %s exited", s.host.name))
+				return true, 1
+			}
+			return false, 0
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package platform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func TestToMgrRecoveryActions(t *testing.T) {
+	actions := []ServiceRecoveryAction{
+		{Type: ServiceRecoveryRestart, Delay: 5 * time.Second},
+		{Type: ServiceRecoveryNone},
+	}
+
+	got := toMgrRecoveryActions(actions)
+	assert.Equal(t, []mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.NoAction},
+	}, got)
+}
+
+func TestToServiceState(t *testing.T) {
+	assert.Equal(t, ServiceStateRunning, toServiceState(svc.Running))
+	assert.Equal(t, ServiceStateStopped, toServiceState(svc.Stopped))
+	assert.Equal(t, ServiceStateUnknown, toServiceState(svc.State(42)))
+}
@@ -0,0 +1,355 @@
+package processlock
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultStaleLockAge is how long a waiter blocks on a held lock before it checks whether the owner is still alive.
+	DefaultStaleLockAge = 5 * time.Second
+
+	lockPollInterval = 50 * time.Millisecond
+
+	// guardExtension is added to the lock file path for the guard lock, see tryAcquire.
+	guardExtension = ".guard"
+	// staleExtension is added to the lock file path when a stale lock is moved aside.
+	staleExtension = ".stale."
+)
+
+var (
+	// ErrLockHeld is returned by a non-blocking lock attempt when another owner holds the lock.
+	ErrLockHeld = errors.New("lock is held by another owner")
+
+	errProcessNotFound = errors.New("process not found")
+)
+
+// ContextLocker is implemented by locks which can give up waiting when a context is done.
+type ContextLocker interface {
+	LockContext(ctx context.Context) error
+}
+
+// LockOwner identifies the process holding a lock. The start time guards against the PID having been
+// reused by an unrelated process after the owner exited.
+type LockOwner struct {
+	PID       int    `json:"pid"`
+	StartTime uint64 `json:"startTime"`
+}
+
+// LockStats are cumulative counters of a LockManager.
+type LockStats struct {
+	Acquisitions int64
+	Takeovers    int64
+	TotalWait    time.Duration
+	MaxWait      time.Duration
+}
+
+// LockManager serializes processes on a lock file. Each acquisition stamps the file with the owner's PID
+// and process start time so that waiters can detect, and take over, a lock left behind by an owner which
+// no longer exists. The OS releases the lock when its owner exits, but a lock can outlive its owner if
+// the handle was inherited by a process the owner started, and on Windows the release of the locks of an
+// exited process can be delayed.
+type LockManager struct {
+	filePath     string
+	staleLockAge time.Duration
+	logger       *zap.Logger
+
+	mu   sync.Mutex
+	file *os.File
+
+	acquisitions atomic.Int64
+	takeovers    atomic.Int64
+	totalWait    atomic.Int64
+	maxWait      atomic.Int64
+}
+
+// NewLockManager creates a LockManager for the lock file at fileAbsPath. Waiters consider taking over the
+// lock once they have waited staleLockAge; DefaultStaleLockAge is used if it is not positive.
+func NewLockManager(fileAbsPath string, staleLockAge time.Duration, logger *zap.Logger) (*LockManager, error) {
+	if fileAbsPath == "" {
+		return nil, ErrEmptyFilePath
+	}
+
+	//nolint:gomnd //0o664 - permission to create directory in octal
+	if err := os.MkdirAll(filepath.Dir(fileAbsPath), os.FileMode(0o664)); err != nil {
+		return nil, errors.Wrap(err, "mkdir lock dir returned error")
+	}
+
+	if staleLockAge <= 0 {
+		staleLockAge = DefaultStaleLockAge
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &LockManager{
+		filePath:     fileAbsPath,
+		staleLockAge: staleLockAge,
+		logger:       logger,
+	}, nil
+}
+
+// Lock blocks until the lock is acquired.
+func (m *LockManager) Lock() error {
+	return m.LockContext(context.Background())
+}
+
+// LockContext blocks until the lock is acquired or ctx is done. A lock whose owner has exited, or whose
+// PID now belongs to a different process, is taken over once the caller has waited for the stale lock age.
+func (m *LockManager) LockContext(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	start := time.Now()
+	nextStaleCheck := start.Add(m.staleLockAge)
+
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	for {
+		checkStale := !time.Now().Before(nextStaleCheck)
+		f, err := m.tryAcquire(checkStale)
+		if err == nil {
+			m.file = f
+			m.recordWait(time.Since(start))
+			return nil
+		}
+		if !errors.Is(err, ErrLockHeld) {
+			return err
+		}
+
+		if checkStale {
+			nextStaleCheck = time.Now().Add(m.staleLockAge)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "gave up waiting for lock %s after %v", m.filePath, time.Since(start))
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unlock releases the lock.
+func (m *LockManager) Unlock() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.file == nil {
+		return ErrInvalidFile
+	}
+
+	err := m.file.Close()
+	m.file = nil
+	return errors.Wrap(err, "file close error in unlock")
+}
+
+// Stats returns a snapshot of the lock counters.
+func (m *LockManager) Stats() LockStats {
+	return LockStats{
+		Acquisitions: m.acquisitions.Load(),
+		Takeovers:    m.takeovers.Load(),
+		TotalWait:    time.Duration(m.totalWait.Load()),
+		MaxWait:      time.Duration(m.maxWait.Load()),
+	}
+}
+
+// tryAcquire makes a single non-blocking attempt to lock and stamp the lock file, and if checkStale is set
+// and the lock is held by an owner which no longer exists, takes it over.
+//
+// The attempt is made under the guard lock, which every LockManager holds while it locks and stamps the
+// lock file. So while the guard is held, the stamp is that of the owner holding the lock, and the lock
+// cannot change hands between the stale check and the takeover.
+func (m *LockManager) tryAcquire(checkStale bool) (*os.File, error) {
+	guard, err := openLockFile(m.filePath + guardExtension)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open lock guard file")
+	}
+	defer guard.Close()
+	// the guard is only held for a few file operations, so a held guard is waited for like a held lock
+	if err = tryLockFile(guard); err != nil {
+		return nil, err
+	}
+
+	f, err := m.tryLock()
+	if errors.Is(err, ErrLockHeld) && checkStale && m.takeOverIfStale() {
+		f, err = m.tryLock()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.stamp(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// tryLock makes a single non-blocking attempt to lock the file currently at the lock path.
+func (m *LockManager) tryLock() (*os.File, error) {
+	f, err := openLockFile(m.filePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open lock file")
+	}
+
+	if err = tryLockFile(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	// a waiter may have taken over and replaced the file after we opened it, in which case the lock
+	// we hold is on an unlinked file and protects nothing.
+	if !m.isCurrentFile(f) {
+		_ = f.Close()
+		return nil, ErrLockHeld
+	}
+
+	return f, nil
+}
+
+func (m *LockManager) isCurrentFile(f *os.File) bool {
+	held, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(m.filePath)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(held, current)
+}
+
+func (m *LockManager) stamp(f *os.File) error {
+	owner, err := currentOwner()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(owner)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode lock owner")
+	}
+
+	if err = f.Truncate(0); err != nil {
+		return errors.Wrap(err, "failed to truncate lockfile")
+	}
+	if _, err = f.WriteAt(b, 0); err != nil {
+		return errors.Wrap(err, "write to lockfile failed")
+	}
+
+	return nil
+}
+
+// takeOverIfStale moves the lock file aside if its owner no longer exists, so the next attempt locks a fresh
+// file. It must be called with the guard lock held.
+func (m *LockManager) takeOverIfStale() bool {
+	owner, err := readLockOwner(m.filePath)
+	if err != nil {
+		m.logger.Info("Unable to read lock owner", zap.String("file", m.filePath), zap.Error(err))
+		return false
+	}
+
+	stale, reason := isStaleOwner(owner)
+	if !stale {
+		m.logger.Info("Waiting for lock held by live owner", zap.String("file", m.filePath), zap.Int("pid", owner.PID))
+		return false
+	}
+
+	// the file is renamed rather than removed, so the takeover is a single atomic step even where the
+	// removal of a file which is still open is deferred until it is closed.
+	stalePath := m.filePath + staleExtension + strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := os.Rename(m.filePath, stalePath); err != nil {
+		m.logger.Error("Failed to move stale lock aside", zap.String("file", m.filePath), zap.Error(err))
+		return false
+	}
+	if err := os.Remove(stalePath); err != nil {
+		m.logger.Info("Unable to remove stale lock", zap.String("file", stalePath), zap.Error(err))
+	}
+
+	m.takeovers.Add(1)
+	m.logger.Warn("Took over stale lock",
+		zap.String("file", m.filePath),
+		zap.Int("pid", owner.PID),
+		zap.Uint64("startTime", owner.StartTime),
+		zap.String("reason", reason))
+	return true
+}
+
+func (m *LockManager) recordWait(wait time.Duration) {
+	m.acquisitions.Add(1)
+	m.totalWait.Add(int64(wait))
+	for {
+		maxWait := m.maxWait.Load()
+		if int64(wait) <= maxWait || m.maxWait.CompareAndSwap(maxWait, int64(wait)) {
+			break
+		}
+	}
+
+	m.logger.Info("Acquired lock", zap.String("file", m.filePath), zap.Duration("wait", wait))
+}
+
+func currentOwner() (LockOwner, error) {
+	pid := os.Getpid()
+	startTime, err := processStartTime(pid)
+	if err != nil {
+		return LockOwner{}, errors.Wrap(err, "failed to get process start time")
+	}
+	return LockOwner{PID: pid, StartTime: startTime}, nil
+}
+
+// readLockOwner reads the owner stamp of the lock file. Files written by NewFileLock only carry the PID.
+func readLockOwner(path string) (LockOwner, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return LockOwner{}, errors.Wrap(err, "failed to open lock file")
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return LockOwner{}, errors.Wrap(err, "failed to read lock file")
+	}
+
+	var owner LockOwner
+	if err = json.Unmarshal(b, &owner); err == nil {
+		return owner, nil
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return LockOwner{}, errors.Errorf("unrecognized lock owner %q", string(b))
+	}
+	return LockOwner{PID: pid}, nil
+}
+
+func isStaleOwner(owner LockOwner) (stale bool, reason string) {
+	if owner.PID <= 0 {
+		return false, ""
+	}
+
+	startTime, err := processStartTime(owner.PID)
+	if errors.Is(err, errProcessNotFound) {
+		return true, "owner process exited"
+	}
+	if err != nil {
+		return false, ""
+	}
+
+	if owner.StartTime != 0 && owner.StartTime != startTime {
+		return true, "owner pid reused by another process"
+	}
+
+	return false, ""
+}
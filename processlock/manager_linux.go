@@ -0,0 +1,52 @@
+package processlock
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// index of the starttime field in /proc/<pid>/stat, counted from the field after the command name.
+const procStatStartTimeIndex = 19
+
+func openLockFile(path string) (*os.File, error) {
+	//nolint:gomnd //0o666 - permission of the lock file in octal
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o666)
+}
+
+func tryLockFile(f *os.File) error {
+	err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return ErrLockHeld
+	}
+	return errors.Wrap(err, "flock failed")
+}
+
+// processStartTime returns the start time of the process in clock ticks since boot.
+func processStartTime(pid int) (uint64, error) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, errProcessNotFound
+		}
+		return 0, errors.Wrap(err, "failed to read process stat")
+	}
+
+	// the command name may contain spaces and parentheses, so split after its closing parenthesis.
+	stat := string(b)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) <= procStatStartTimeIndex {
+		return 0, errors.Errorf("malformed stat for pid %d", pid)
+	}
+
+	// zombies have exited and will never release anything they hold
+	if fields[0] == "Z" {
+		return 0, errProcessNotFound
+	}
+
+	startTime, err := strconv.ParseUint(fields[procStatStartTimeIndex], 10, 64)
+	return startTime, errors.Wrap(err, "failed to parse process start time")
+}
@@ -0,0 +1,197 @@
+package processlock
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+const testStaleLockAge = 100 * time.Millisecond
+
+// holdLock locks path through a separate file handle, as another process would, and stamps it with owner.
+func holdLock(t *testing.T, path string, owner LockOwner) *os.File {
+	t.Helper()
+	f, err := openLockFile(path)
+	require.NoError(t, err)
+	require.NoError(t, tryLockFile(f))
+	b, err := json.Marshal(owner)
+	require.NoError(t, err)
+	_, err = f.WriteAt(b, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// exitedPID returns a PID which does not belong to any running process.
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	for pid := 1 << 30; pid > 1<<29; pid -= 4 {
+		if _, err := processStartTime(pid); errors.Is(err, errProcessNotFound) {
+			return pid
+		}
+	}
+	t.Fatal("no unused pid found")
+	return 0
+}
+
+func TestLockManagerStampsOwner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stamp.lock")
+	m, err := NewLockManager(path, testStaleLockAge, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Lock())
+	owner, err := readLockOwner(path)
+	require.NoError(t, err)
+	want, err := currentOwner()
+	require.NoError(t, err)
+	require.Equal(t, want, owner)
+
+	require.NoError(t, m.Unlock())
+	require.ErrorIs(t, m.Unlock(), ErrInvalidFile)
+	require.EqualValues(t, 1, m.Stats().Acquisitions)
+}
+
+func TestLockManagerTakesOverStaleLock(t *testing.T) {
+	self, err := currentOwner()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		owner LockOwner
+	}{
+		{
+			name:  "owner exited",
+			owner: LockOwner{PID: exitedPID(t), StartTime: 1},
+		},
+		{
+			name:  "owner pid reused",
+			owner: LockOwner{PID: self.PID, StartTime: self.StartTime + 1},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "stale.lock")
+			holdLock(t, path, tt.owner)
+
+			m, err := NewLockManager(path, testStaleLockAge, nil)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			require.NoError(t, m.LockContext(ctx))
+			defer m.Unlock() //nolint:errcheck // test cleanup
+
+			stats := m.Stats()
+			require.EqualValues(t, 1, stats.Takeovers)
+			require.GreaterOrEqual(t, stats.MaxWait, testStaleLockAge)
+		})
+	}
+}
+
+func TestLockManagerTakeoverKeepsExclusion(t *testing.T) {
+	const (
+		managers     = 6
+		acquisitions = 10
+	)
+	path := filepath.Join(t.TempDir(), "race.lock")
+	holdLock(t, path, LockOwner{PID: exitedPID(t), StartTime: 1})
+
+	var (
+		wg       sync.WaitGroup
+		holders  atomic.Int32
+		overlaps atomic.Int32
+		stats    = make([]LockStats, managers)
+	)
+	for i := 0; i < managers; i++ {
+		// each manager stands in for a separate process contending for the stale lock
+		m, err := NewLockManager(path, testStaleLockAge, nil)
+		require.NoError(t, err)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < acquisitions; n++ {
+				if err := m.Lock(); err != nil {
+					t.Error(err)
+					return
+				}
+				if holders.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				time.Sleep(time.Millisecond)
+				holders.Add(-1)
+				if err := m.Unlock(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			stats[i] = m.Stats()
+		}(i)
+	}
+	wg.Wait()
+
+	require.Zero(t, overlaps.Load())
+	var takeovers int64
+	for _, s := range stats {
+		takeovers += s.Takeovers
+	}
+	// only the first waiter to check the stale lock takes it over, the others then find a live owner
+	require.EqualValues(t, 1, takeovers)
+}
+
+func TestLockManagerDoesNotTakeOverWhileGuardHeld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guarded.lock")
+	holdLock(t, path, LockOwner{PID: exitedPID(t), StartTime: 1})
+	// another manager in the middle of an acquisition holds the guard
+	guard := holdLock(t, path+guardExtension, LockOwner{})
+
+	m, err := NewLockManager(path, testStaleLockAge, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*testStaleLockAge)
+	defer cancel()
+	require.ErrorIs(t, m.LockContext(ctx), context.DeadlineExceeded)
+	require.Zero(t, m.Stats().Takeovers)
+
+	require.NoError(t, guard.Close())
+	require.NoError(t, m.Lock())
+	require.NoError(t, m.Unlock())
+	require.EqualValues(t, 1, m.Stats().Takeovers)
+}
+
+func TestLockManagerWaitsForLiveOwner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "live.lock")
+	self, err := currentOwner()
+	require.NoError(t, err)
+	holder := holdLock(t, path, self)
+
+	m, err := NewLockManager(path, testStaleLockAge, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*testStaleLockAge)
+	defer cancel()
+	require.ErrorIs(t, m.LockContext(ctx), context.DeadlineExceeded)
+	require.Zero(t, m.Stats().Takeovers)
+
+	// the lock is acquired as soon as the owner releases it
+	require.NoError(t, holder.Close())
+	require.NoError(t, m.Lock())
+	require.NoError(t, m.Unlock())
+}
+
+func TestReadLockOwnerLegacyPID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.lock")
+	require.NoError(t, os.WriteFile(path, []byte("1234"), 0o600))
+
+	owner, err := readLockOwner(path)
+	require.NoError(t, err)
+	require.Equal(t, LockOwner{PID: 1234}, owner)
+}
@@ -0,0 +1,67 @@
+package processlock
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// the lock covers a single byte far beyond the owner stamp, so the stamp stays readable by waiters.
+	lockRangeOffsetHigh = 0x7fffffff
+	stillActive         = 259
+)
+
+// openLockFile opens the lock file shared for deletion, so that a stale lock can be moved aside while the
+// handle of its owner is still open.
+func openLockFile(path string) (*os.File, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid lock file path")
+	}
+	h, err := windows.CreateFile(p,
+		windows.GENERIC_READ|windows.GENERIC_WRITE,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_ALWAYS, windows.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
+
+func tryLockFile(f *os.File) error {
+	ol := &windows.Overlapped{OffsetHigh: lockRangeOffsetHigh}
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) || errors.Is(err, windows.ERROR_IO_PENDING) {
+		return ErrLockHeld
+	}
+	return errors.Wrap(err, "LockFileEx failed")
+}
+
+// processStartTime returns the creation time of the process in 100ns intervals since January 1, 1601.
+func processStartTime(pid int) (uint64, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		if errors.Is(err, windows.ERROR_INVALID_PARAMETER) {
+			return 0, errProcessNotFound
+		}
+		return 0, errors.Wrap(err, "failed to open process")
+	}
+	defer windows.CloseHandle(h) //nolint:errcheck // ignore error on close
+
+	var exitCode uint32
+	if err = windows.GetExitCodeProcess(h, &exitCode); err != nil {
+		return 0, errors.Wrap(err, "failed to get process exit code")
+	}
+	if exitCode != stillActive {
+		return 0, errProcessNotFound
+	}
+
+	var creation, exit, kernel, user windows.Filetime
+	if err = windows.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return 0, errors.Wrap(err, "failed to get process times")
+	}
+
+	return uint64(creation.HighDateTime)<<32 | uint64(creation.LowDateTime), nil
+}
@@ -4,6 +4,7 @@
 package store
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Azure/azure-container-networking/internal/lockedfile"
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/platform"
 	"github.com/Azure/azure-container-networking/processlock"
@@ -27,12 +29,25 @@ const (
 	DefaultLockTimeout        = 10000 * time.Millisecond
 	DefaultLockTimeoutLinux   = 30000 * time.Millisecond
 	DefaultLockTimeoutWindows = 60000 * time.Millisecond
+
+	// versionsKey is the reserved key under which the versions of versioned keys are persisted.
+	versionsKey = "_versions"
+
+	// writeLockExtension is added to the file name for the lock held while the file is written,
+	// which makes WriteIfVersion atomic across processes.
+	writeLockExtension = ".write" + LockExtension
 )
 
+// errCorruptStoreFile is returned when the store file can't be decoded.
+var errCorruptStoreFile = errors.New("failed to decode store file")
+
 // jsonFileStore is an implementation of KeyValueStore using a local JSON file.
 type jsonFileStore struct {
-	fileName    string
-	data        map[string]*json.RawMessage
+	fileName string
+	data     map[string]*json.RawMessage
+	versions map[string]uint64
+	// pending are the plain writes not yet persisted, applied again over the file until a flush succeeds.
+	pending     map[string]*json.RawMessage
 	inSync      bool
 	processLock processlock.Interface
 	sync.Mutex
@@ -95,10 +110,10 @@ func (kvs *jsonFileStore) Read(key string, value interface{}) error {
 			return ErrStoreEmpty
 		}
 
-		// Decode to raw JSON messages.
-		if err := json.Unmarshal(b, &kvs.data); err != nil {
+		if err := kvs.decode(b); err != nil {
 			return err
 		}
+		kvs.applyPending()
 
 		kvs.inSync = true
 	}
@@ -111,33 +126,215 @@ func (kvs *jsonFileStore) Read(key string, value interface{}) error {
 	return json.Unmarshal(*raw, value)
 }
 
+// decode replaces the in-memory state with the JSON encoded contents of the file.
+func (kvs *jsonFileStore) decode(b []byte) error {
+	// Decode to raw JSON messages.
+	data := make(map[string]*json.RawMessage)
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	versions := make(map[string]uint64)
+	if raw, ok := data[versionsKey]; ok {
+		if err := json.Unmarshal(*raw, &versions); err != nil {
+			return errors.Wrap(err, "failed to decode key versions")
+		}
+		delete(data, versionsKey)
+	}
+
+	kvs.data = data
+	kvs.versions = versions
+	return nil
+}
+
+// refresh reloads the in-memory state from the file, so that writes are applied over the writes of other
+// processes, and applies the pending writes on top of it. A missing or empty file is an empty store.
+func (kvs *jsonFileStore) refresh() error {
+	b, err := os.ReadFile(kvs.fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to read store file")
+	}
+
+	if len(b) == 0 {
+		kvs.data = make(map[string]*json.RawMessage)
+		kvs.versions = make(map[string]uint64)
+	} else if err := kvs.decode(b); err != nil {
+		return fmt.Errorf("%w: %v", errCorruptStoreFile, err) //nolint:errorlint // the decode error is only reported
+	}
+	kvs.applyPending()
+
+	kvs.inSync = true
+	return nil
+}
+
+// applyPending applies the pending writes to the in-memory state, bumping the versioned keys so that
+// versioned writers notice plain writes.
+func (kvs *jsonFileStore) applyPending() {
+	for key, raw := range kvs.pending {
+		kvs.data[key] = raw
+		if _, ok := kvs.versions[key]; ok {
+			kvs.versions[key]++
+		}
+	}
+}
+
+// refreshForWrite is refresh for plain writes, which replace a corrupt file with the in-memory state
+// instead of failing on it.
+func (kvs *jsonFileStore) refreshForWrite() error {
+	err := kvs.refresh()
+	if err == nil || !errors.Is(err, errCorruptStoreFile) {
+		return err
+	}
+
+	if kvs.logger != nil {
+		kvs.logger.Error("Replacing corrupt store file", zap.String("fileName", kvs.fileName), zap.Error(err))
+	} else {
+		log.Errorf("Replacing corrupt store file %s: %v", kvs.fileName, err)
+	}
+	kvs.applyPending()
+	return nil
+}
+
+// ReadWithVersion restores the value for the given key from the persistent store file and returns its version.
+func (kvs *jsonFileStore) ReadWithVersion(key string, value interface{}) (uint64, error) {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if err := kvs.refresh(); err != nil {
+		return 0, err
+	}
+
+	raw, ok := kvs.data[key]
+	if !ok {
+		return 0, ErrKeyNotFound
+	}
+
+	if err := json.Unmarshal(*raw, value); err != nil {
+		return 0, errors.Wrapf(err, "failed to decode key %s", key)
+	}
+
+	return kvs.versions[key], nil
+}
+
 // Write saves the given key value pair to persistent store.
 func (kvs *jsonFileStore) Write(key string, value interface{}) error {
 	kvs.Mutex.Lock()
 	defer kvs.Mutex.Unlock()
 
+	if key == versionsKey {
+		return errors.Errorf("key %s is reserved", key)
+	}
+
 	var raw json.RawMessage
 	raw, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
 
-	kvs.data[key] = &raw
+	// apply only this write over the keys and versions written by other processes, which it must not roll back
+	unlock, err := kvs.lockWrites()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if kvs.pending == nil {
+		kvs.pending = make(map[string]*json.RawMessage)
+	}
+	kvs.pending[key] = &raw
+
+	if err := kvs.refreshForWrite(); err != nil {
+		return err
+	}
 
 	return kvs.flush()
 }
 
+// WriteIfVersion saves the given key value pair to persistent store if the key is at the expected version.
+func (kvs *jsonFileStore) WriteIfVersion(key string, value interface{}, version uint64) (uint64, error) {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if key == versionsKey {
+		return 0, errors.Errorf("key %s is reserved", key)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to encode key %s", key)
+	}
+
+	// the file must not be written by another process between the version check and the flush
+	unlock, err := kvs.lockWrites()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if err := kvs.refresh(); err != nil {
+		return 0, err
+	}
+
+	if actual := kvs.versions[key]; actual != version {
+		return 0, &VersionConflictError{Key: key, Expected: version, Actual: actual}
+	}
+
+	kvs.data[key] = (*json.RawMessage)(&raw)
+	kvs.versions[key] = version + 1
+
+	if err := kvs.flush(); err != nil {
+		// the in-memory state is ahead of the file, reload it on the next read
+		kvs.inSync = false
+		return 0, err
+	}
+
+	return version + 1, nil
+}
+
 // Flush commits in-memory state to persistent store.
 func (kvs *jsonFileStore) Flush() error {
 	kvs.Mutex.Lock()
 	defer kvs.Mutex.Unlock()
 
+	unlock, err := kvs.lockWrites()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := kvs.refreshForWrite(); err != nil {
+		return err
+	}
+
 	return kvs.flush()
 }
 
+// lockWrites takes the write lock of the file, which is held while the file is read and written back. Unlike
+// the store Lock, it is taken by the store itself, so writes are atomic for every caller.
+func (kvs *jsonFileStore) lockWrites() (func(), error) {
+	unlock, err := lockedfile.MutexAt(kvs.fileName + writeLockExtension).Lock()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to lock store file for writing")
+	}
+	return unlock, nil
+}
+
 // Lock-free flush for internal callers.
 func (kvs *jsonFileStore) flush() error {
-	buf, err := json.MarshalIndent(&kvs.data, "", "\t")
+	data := kvs.data
+	if len(kvs.versions) > 0 {
+		data = make(map[string]*json.RawMessage, len(kvs.data)+1)
+		for k, v := range kvs.data {
+			data[k] = v
+		}
+		versions, err := json.Marshal(kvs.versions)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode key versions")
+		}
+		data[versionsKey] = (*json.RawMessage)(&versions)
+	}
+
+	buf, err := json.MarshalIndent(&data, "", "\t")
 	if err != nil {
 		return err
 	}
@@ -176,6 +373,8 @@ func (kvs *jsonFileStore) flush() error {
 		return fmt.Errorf("rename temp file to state file failed:%v", err)
 	}
 
+	// the pending writes are persisted
+	kvs.pending = nil
 	return nil
 }
 
@@ -189,22 +388,30 @@ func (kvs *jsonFileStore) Lock(timeout time.Duration) error {
 	kvs.Mutex.Lock()
 	defer kvs.Mutex.Unlock()
 
-	afterTime := time.After(timeout)
-	status := make(chan error)
-
 	if kvs.logger != nil {
 		kvs.logger.Info("Acquiring process lock")
 	} else {
 		log.Printf("Acquiring process lock")
 	}
 
-	go kvs.lockUtil(status)
-
 	var err error
-	select {
-	case <-afterTime:
-		return ErrTimeoutLockingStore
-	case err = <-status:
+	if cl, ok := kvs.processLock.(processlock.ContextLocker); ok {
+		// locks which can give up waiting don't leave a goroutine behind to acquire the lock after we timed out
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err = cl.LockContext(ctx); err != nil && errors.Is(err, context.DeadlineExceeded) {
+			return ErrTimeoutLockingStore
+		}
+	} else {
+		afterTime := time.After(timeout)
+		status := make(chan error)
+		go kvs.lockUtil(status)
+
+		select {
+		case <-afterTime:
+			return ErrTimeoutLockingStore
+		case err = <-status:
+		}
 	}
 
 	if err != nil {
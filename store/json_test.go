@@ -5,12 +5,15 @@ package store
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cni/log"
 	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -31,12 +34,13 @@ type testType1 struct {
 
 // Tests that the key value pairs are reinstantiated correctly from a pre-existing JSON encoded file.
 func TestKeyValuePairsAreReinstantiatedFromJSONFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
 	encodedPair := `{"key1":{"Field1":"test","Field2":42}}`
 	expectedValue := testType1{"test", 42}
 	var actualValue testType1
 
 	// Create a JSON file containing the encoded pair.
-	file, err := os.Create(testFileName)
+	file, err := os.Create(fileName)
 	if err != nil {
 		t.Fatalf("Failed to create file %v", err)
 	}
@@ -47,10 +51,10 @@ func TestKeyValuePairsAreReinstantiatedFromJSONFile(t *testing.T) {
 	}
 
 	file.Close()
-	defer os.Remove(testFileName)
+	defer os.Remove(fileName)
 
 	// Create the store, initialized using the JSON file.
-	kvs, err := NewJsonFileStore(testFileName, processlock.NewMockFileLock(false), nil)
+	kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
 	if err != nil {
 		t.Fatalf("Failed to create KeyValueStore %v\n", err)
 	}
@@ -70,12 +74,13 @@ func TestKeyValuePairsAreReinstantiatedFromJSONFile(t *testing.T) {
 
 // Tests that the key value pairs written to the store are persisted correctly in JSON encoded file.
 func TestKeyValuePairsArePersistedToJSONFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
 	writtenValue := testType1{"test", 42}
 	expectedPair := `{"key1":{"Field1":"test","Field2":42}}`
 	var actualPair string
 
 	// Create the store.
-	kvs, err := NewJsonFileStore(testFileName, processlock.NewMockFileLock(false), nil)
+	kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
 	if err != nil {
 		t.Fatalf("Failed to create KeyValueStore %v\n", err)
 	}
@@ -87,7 +92,7 @@ func TestKeyValuePairsArePersistedToJSONFile(t *testing.T) {
 	}
 
 	// Read the persisted file contents.
-	file, err := os.Open(testFileName)
+	file, err := os.Open(fileName)
 	if err != nil {
 		t.Fatalf("Failed to open file %v", err)
 	}
@@ -99,7 +104,7 @@ func TestKeyValuePairsArePersistedToJSONFile(t *testing.T) {
 	}
 
 	file.Close()
-	os.Remove(testFileName)
+	os.Remove(fileName)
 
 	// Remove indentation to normalize the JSON encoding.
 	actualPair = string(data[:n])
@@ -116,6 +121,7 @@ func TestKeyValuePairsArePersistedToJSONFile(t *testing.T) {
 
 // Tests that key value pairs are written and read back correctly.
 func TestKeyValuePairsAreWrittenAndReadCorrectly(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
 	writtenValue := testType1{"test", 42}
 	anotherValue := testType1{"any", 14}
 	var readValue testType1
@@ -124,7 +130,7 @@ func TestKeyValuePairsAreWrittenAndReadCorrectly(t *testing.T) {
 	logger := log.CNILogger
 
 	// Create the store.
-	kvs, err := NewJsonFileStore(testFileName, processlock.NewMockFileLock(false), logger)
+	kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), logger)
 	if err != nil {
 		t.Fatalf("Failed to create KeyValueStore %v\n", err)
 	}
@@ -154,7 +160,7 @@ func TestKeyValuePairsAreWrittenAndReadCorrectly(t *testing.T) {
 	}
 
 	// Cleanup.
-	os.Remove(testFileName)
+	os.Remove(fileName)
 }
 
 // test case for testing newjsonfilestore idempotent
@@ -237,3 +243,181 @@ func TestFileName(t *testing.T) {
 		t.Fatalf("This should not fail for a non-empty file %v", err)
 	}
 }
+
+func TestWriteIfVersionDetectsConcurrentWriters(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+	// two stores on the same file stand in for two processes
+	cni, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	cns, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+
+	version, err := cni.WriteIfVersion(testKey1, testType1{"cni", 1}, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), version)
+
+	var value testType1
+	cnsVersion, err := cns.ReadWithVersion(testKey1, &value)
+	require.NoError(t, err)
+	require.Equal(t, version, cnsVersion)
+	require.Equal(t, testType1{"cni", 1}, value)
+
+	// cni updates the key, so the update of cns based on its earlier read must be rejected
+	version, err = cni.WriteIfVersion(testKey1, testType1{"cni", 2}, version)
+	require.NoError(t, err)
+
+	_, err = cns.WriteIfVersion(testKey1, testType1{"cns", 1}, cnsVersion)
+	require.ErrorIs(t, err, ErrVersionConflict)
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, VersionConflictError{Key: testKey1, Expected: 1, Actual: 2}, *conflict)
+
+	cnsVersion, err = cns.ReadWithVersion(testKey1, &value)
+	require.NoError(t, err)
+	require.Equal(t, version, cnsVersion)
+	require.Equal(t, testType1{"cni", 2}, value)
+
+	_, err = cns.WriteIfVersion(testKey1, testType1{"cns", 1}, cnsVersion)
+	require.NoError(t, err)
+}
+
+func TestWriteDoesNotRollBackOtherWriters(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+	// two stores on the same file stand in for two processes
+	cni, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	cns, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+
+	version, err := cni.WriteIfVersion(testKey1, testType1{"cni", 1}, 0)
+	require.NoError(t, err)
+	var value testType1
+	require.NoError(t, cns.Read(testKey1, &value))
+
+	// cns has read the store before cni writes it again, so its cache is stale
+	version, err = cni.WriteIfVersion(testKey1, testType1{"cni", 2}, version)
+	require.NoError(t, err)
+	require.NoError(t, cns.Write(testKey2, testType1{"cns", 1}))
+
+	// the plain write kept the key and version of cni
+	cniVersion, err := cni.ReadWithVersion(testKey1, &value)
+	require.NoError(t, err)
+	require.Equal(t, version, cniVersion)
+	require.Equal(t, testType1{"cni", 2}, value)
+	_, err = cni.WriteIfVersion(testKey1, testType1{"cni", 3}, version-1)
+	require.ErrorIs(t, err, ErrVersionConflict)
+
+	// a plain write of the versioned key bumps the version cni wrote, so cni notices it
+	require.NoError(t, cns.Write(testKey1, testType1{"cns", 2}))
+	_, err = cni.WriteIfVersion(testKey1, testType1{"cni", 3}, version)
+	require.ErrorIs(t, err, ErrVersionConflict)
+	cniVersion, err = cni.ReadWithVersion(testKey1, &value)
+	require.NoError(t, err)
+	require.Equal(t, version+1, cniVersion)
+	require.Equal(t, testType1{"cns", 2}, value)
+	require.NoError(t, cni.Read(testKey2, &value))
+	require.Equal(t, testType1{"cns", 1}, value)
+}
+
+func TestWriteBumpsVersionedKeys(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+	kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+
+	// keys which were never written with a version stay unversioned
+	require.NoError(t, kvs.Write(testKey2, testType1{"plain", 1}))
+	var value testType1
+	version, err := kvs.ReadWithVersion(testKey2, &value)
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	version, err = kvs.WriteIfVersion(testKey1, testType1{"versioned", 1}, 0)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Write(testKey1, testType1{"plain", 2}))
+
+	_, err = kvs.WriteIfVersion(testKey1, testType1{"versioned", 2}, version)
+	require.ErrorIs(t, err, ErrVersionConflict)
+
+	// versions survive reopening the store
+	reopened, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	version, err = reopened.ReadWithVersion(testKey1, &value)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), version)
+	require.Equal(t, testType1{"plain", 2}, value)
+	require.ErrorIs(t, reopened.Read(versionsKey, &value), ErrKeyNotFound)
+}
+
+func TestReadWithVersionMissingFile(t *testing.T) {
+	kvs, err := NewJsonFileStore(filepath.Join(t.TempDir(), testFileName), processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+
+	var value testType1
+	_, err = kvs.ReadWithVersion(testKey1, &value)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestWriteIfVersionConcurrentWritersDoNotLoseUpdates(t *testing.T) {
+	const (
+		writers    = 8
+		increments = 25
+	)
+	fileName := filepath.Join(t.TempDir(), testFileName)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		// each writer has its own store on the file and does not hold the store Lock, standing in
+		// for processes which do a read-modify-write of the same key
+		kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < increments; {
+				var counter int
+				version, err := kvs.ReadWithVersion(testKey1, &counter)
+				if err != nil && !errors.Is(err, ErrKeyNotFound) {
+					errs <- err
+					return
+				}
+				_, err = kvs.WriteIfVersion(testKey1, counter+1, version)
+				if errors.Is(err, ErrVersionConflict) {
+					continue
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+				n++
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	var counter int
+	version, err := kvs.ReadWithVersion(testKey1, &counter)
+	require.NoError(t, err)
+	require.Equal(t, writers*increments, counter)
+	require.Equal(t, uint64(writers*increments), version)
+}
+
+func TestWriteDoesNotReadCorruptFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+	require.NoError(t, os.WriteFile(fileName, []byte("{corrupt"), 0o600))
+
+	kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	// a plain write replaces the corrupt file instead of failing on it
+	require.NoError(t, kvs.Write(testKey1, testType1{"plain", 1}))
+
+	var value testType1
+	require.NoError(t, kvs.Read(testKey1, &value))
+	require.Equal(t, testType1{"plain", 1}, value)
+}
@@ -9,6 +9,7 @@ import (
 type mockStore struct {
 	lockFilePath string
 	data         map[string]*json.RawMessage
+	versions     map[string]uint64
 }
 
 // NewMockStore creates a new jsonFileStore object, accessed as a KeyValueStore.
@@ -16,6 +17,7 @@ func NewMockStore(lockFilePath string) KeyValueStore {
 	return &mockStore{
 		lockFilePath: lockFilePath,
 		data:         make(map[string]*json.RawMessage),
+		versions:     make(map[string]uint64),
 	}
 }
 
@@ -43,9 +45,30 @@ func (ms *mockStore) Write(key string, value interface{}) error {
 	}
 
 	ms.data[key] = &raw
+	if _, ok := ms.versions[key]; ok {
+		ms.versions[key]++
+	}
 	return nil
 }
 
+func (ms *mockStore) ReadWithVersion(key string, value interface{}) (uint64, error) {
+	if err := ms.Read(key, value); err != nil {
+		return 0, err
+	}
+	return ms.versions[key], nil
+}
+
+func (ms *mockStore) WriteIfVersion(key string, value interface{}, version uint64) (uint64, error) {
+	if actual := ms.versions[key]; actual != version {
+		return 0, &VersionConflictError{Key: key, Expected: version, Actual: actual}
+	}
+	if err := ms.Write(key, value); err != nil {
+		return 0, err
+	}
+	ms.versions[key] = version + 1
+	return version + 1, nil
+}
+
 func (ms *mockStore) Flush() error {
 	return nil
 }
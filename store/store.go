@@ -13,6 +13,15 @@ type KeyValueStore interface {
 	Exists() bool
 	Read(key string, value interface{}) error
 	Write(key string, value interface{}) error
+	// ReadWithVersion reads the value for the key from persistent storage, bypassing any cached state,
+	// and returns the version of the key. Keys never written by WriteIfVersion have version 0.
+	ReadWithVersion(key string, value interface{}) (uint64, error)
+	// WriteIfVersion writes the value for the key only if the version of the key in persistent storage is
+	// the expected version, and returns the new version. An expected version of 0 matches keys that are
+	// absent or not yet versioned. On mismatch the value is not written and a *VersionConflictError is
+	// returned. The check and the write are atomic across processes, so a read-modify-write which retries
+	// on conflict does not lose updates, even if the caller does not hold the store Lock.
+	WriteIfVersion(key string, value interface{}, version uint64) (uint64, error)
 	Flush() error
 	Lock(timeout time.Duration) error
 	Unlock() error
@@ -28,4 +37,21 @@ var (
 	ErrStoreEmpty                     = fmt.Errorf("store is empty")
 	ErrTimeoutLockingStore            = fmt.Errorf("timed out locking store")
 	ErrNonBlockingLockIsAlreadyLocked = fmt.Errorf("attempted to perform non-blocking lock on an already locked store")
+	ErrVersionConflict                = fmt.Errorf("version conflict")
 )
+
+// VersionConflictError is returned by WriteIfVersion when the key was modified since it was read.
+type VersionConflictError struct {
+	Key      string
+	Expected uint64
+	Actual   uint64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%v: key %s is at version %d, expected %d", ErrVersionConflict, e.Key, e.Actual, e.Expected)
+}
+
+// Is makes the error match ErrVersionConflict.
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict //nolint:errorlint // comparing to the sentinel is the point
+}
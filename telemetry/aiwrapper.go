@@ -3,6 +3,8 @@ package telemetry
 
 import (
 	"errors"
+	"strconv"
+	"strings"
 
 	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/log"
@@ -61,18 +63,36 @@ func SendAITelemetry(cnireport CNIReport) {
 		Message:          msg,
 		Context:          cnireport.ContainerName,
 		AppVersion:       cnireport.Version,
-		CustomDimensions: make(map[string]string),
+		CustomDimensions: cniReportDimensions(&cnireport),
 	}
 
-	report.CustomDimensions[ContextStr] = cnireport.Context
-	report.CustomDimensions[SubContextStr] = cnireport.SubContext
-	report.CustomDimensions[VMUptimeStr] = cnireport.VMUptime
-	report.CustomDimensions[OperationTypeStr] = cnireport.OperationType
-	report.CustomDimensions[VersionStr] = cnireport.Version
-
 	th.TrackLog(report)
 }
 
+// cniReportDimensions returns the dimensions which CNI reports are sent with.
+func cniReportDimensions(cnireport *CNIReport) map[string]string {
+	dimensions := map[string]string{
+		ContextStr:       cnireport.Context,
+		SubContextStr:    cnireport.SubContext,
+		VMUptimeStr:      cnireport.VMUptime,
+		OperationTypeStr: cnireport.OperationType,
+		VersionStr:       cnireport.Version,
+		SchemaVersionStr: strconv.Itoa(cnireport.SchemaVersion),
+	}
+
+	if cnireport.SchemaVersion >= resultSchemaVersion {
+		dimensions[ResultCodeStr] = cnireport.ResultCode
+		dimensions[InterfaceCountStr] = strconv.Itoa(cnireport.InterfaceCount)
+		dimensions[IPFamiliesStr] = strings.Join(cnireport.IPFamilies, ",")
+		dimensions[CNIErrorCodeStr] = strconv.FormatUint(uint64(cnireport.CNIErrorCode), 10)
+	}
+
+	if cnireport.OccurrenceCount > 0 {
+		dimensions[OccurrenceCountStr] = strconv.Itoa(cnireport.OccurrenceCount)
+	}
+	return dimensions
+}
+
 func SendAIMetric(aiMetric AIMetric) {
 	if th == nil || gDisableMetric {
 		return
@@ -0,0 +1,211 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Defaults of the BatchConfig fields left unset.
+const (
+	DefaultBatchFlushInterval       = 5 * time.Second
+	DefaultBatchSize                = 16384
+	DefaultMaxQueuedBatches         = 10
+	DefaultBatchBackpressureTimeout = time.Second
+)
+
+// maxDecodedBatchSize bounds the size of a decompressed batch, so a malformed payload can't exhaust the service memory.
+const maxDecodedBatchSize = 1 << 20
+
+// batchMarker prefixes batch payloads on the wire, which are base64 encoded so the compressed reports can't contain
+// the Delimiter.
+var batchMarker = []byte("gzbatch:")
+
+var (
+	errBatchQueueFull  = errors.New("telemetry batch queue full, batch dropped")
+	errBatchTooLarge   = errors.New("telemetry batch exceeds maximum decoded size")
+	errBatchingStopped = errors.New("telemetry batching stopped")
+)
+
+// BatchConfig configures the batching of the reports a client writes to the telemetry service.
+type BatchConfig struct {
+	// FlushInterval is the longest a report is held before its batch is written.
+	FlushInterval time.Duration
+	// MaxBatchSize is the uncompressed size in bytes at which a batch is written.
+	MaxBatchSize int
+	// MaxQueuedBatches is the number of batches waiting to be written before Write blocks.
+	MaxQueuedBatches int
+	// BackpressureTimeout is how long Write blocks on a full queue before the batch is dropped.
+	BackpressureTimeout time.Duration
+}
+
+func (c *BatchConfig) setDefaults() {
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultBatchFlushInterval
+	}
+	if c.MaxBatchSize <= 0 {
+		c.MaxBatchSize = DefaultBatchSize
+	}
+	if c.MaxQueuedBatches <= 0 {
+		c.MaxQueuedBatches = DefaultMaxQueuedBatches
+	}
+	if c.BackpressureTimeout <= 0 {
+		c.BackpressureTimeout = DefaultBatchBackpressureTimeout
+	}
+}
+
+// batch is a sealed batch of reports ready to be written.
+type batch struct {
+	payload []byte
+	reports int
+}
+
+// batcher accumulates delimited reports until the batch is large or old enough to be compressed and queued
+// for the sender.
+type batcher struct {
+	config  BatchConfig
+	mutex   sync.Mutex
+	buf     bytes.Buffer
+	reports int
+	queue   chan batch
+	done    chan struct{}
+	stopped chan struct{}
+	stop    sync.Once
+}
+
+func newBatcher(config BatchConfig) *batcher {
+	config.setDefaults()
+	return &batcher{
+		config:  config,
+		queue:   make(chan batch, config.MaxQueuedBatches),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// add appends the report to the current batch, and returns the batch if it is now full.
+func (b *batcher) add(report []byte) (*batch, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	select {
+	case <-b.done:
+		return nil, errBatchingStopped
+	default:
+	}
+
+	b.buf.Write(report)
+	b.buf.WriteByte(Delimiter)
+	b.reports++
+	if b.buf.Len() < b.config.MaxBatchSize {
+		return nil, nil
+	}
+	return b.seal()
+}
+
+// flush seals the current batch, if it holds any report.
+func (b *batcher) flush() (*batch, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.seal()
+}
+
+// seal compresses the current batch and starts a new one. It must be called with the mutex held.
+func (b *batcher) seal() (*batch, error) {
+	if b.reports == 0 {
+		return nil, nil
+	}
+	defer func() {
+		b.buf.Reset()
+		b.reports = 0
+	}()
+
+	payload, err := encodeBatch(b.buf.Bytes())
+	if err != nil {
+		return &batch{reports: b.reports}, err
+	}
+	return &batch{payload: payload, reports: b.reports}, nil
+}
+
+// enqueue hands the batch to the sender, blocking up to the backpressure timeout while the queue is full.
+func (b *batcher) enqueue(bt *batch) error {
+	select {
+	case b.queue <- *bt:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(b.config.BackpressureTimeout)
+	defer timer.Stop()
+	select {
+	case b.queue <- *bt:
+		return nil
+	case <-timer.C:
+		return errBatchQueueFull
+	}
+}
+
+// encodeBatch gzips the delimited reports and base64 encodes them behind the batch marker.
+func encodeBatch(reports []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(reports); err != nil {
+		return nil, errors.Wrap(err, "failed to compress telemetry batch")
+	}
+	if err := zw.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to compress telemetry batch")
+	}
+
+	payload := make([]byte, len(batchMarker)+base64.StdEncoding.EncodedLen(compressed.Len()))
+	copy(payload, batchMarker)
+	base64.StdEncoding.Encode(payload[len(batchMarker):], compressed.Bytes())
+	return payload, nil
+}
+
+// isBatch returns whether the payload read from a client is a batch of reports.
+func isBatch(payload []byte) bool {
+	return bytes.HasPrefix(payload, batchMarker)
+}
+
+// decodeBatch returns the reports of a batch payload.
+func decodeBatch(payload []byte) ([][]byte, error) {
+	encoded := payload[len(batchMarker):]
+	compressed := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(compressed, encoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode telemetry batch")
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed[:n]))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress telemetry batch")
+	}
+	defer zr.Close()
+
+	decoded, err := io.ReadAll(io.LimitReader(zr, maxDecodedBatchSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress telemetry batch")
+	}
+	if len(decoded) > maxDecodedBatchSize {
+		return nil, errBatchTooLarge
+	}
+
+	var reports [][]byte
+	reader := bufio.NewReader(bytes.NewReader(decoded))
+	for {
+		report, err := read(reader)
+		if err != nil {
+			break
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
@@ -0,0 +1,117 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeBatch(t *testing.T) {
+	reports := []byte("{\"a\":1}\n{\"b\":2}\n")
+	payload, err := encodeBatch(reports)
+	require.NoError(t, err)
+	require.True(t, isBatch(payload))
+	require.False(t, bytes.ContainsRune(payload, Delimiter))
+
+	decoded, err := decodeBatch(payload)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("{\"a\":1}"), []byte("{\"b\":2}")}, decoded)
+
+	require.False(t, isBatch([]byte("{\"a\":1}")))
+	_, err = decodeBatch(append(append([]byte{}, batchMarker...), "not base64!"...))
+	require.Error(t, err)
+}
+
+func TestDecodeBatchTooLarge(t *testing.T) {
+	payload, err := encodeBatch(make([]byte, maxDecodedBatchSize+1))
+	require.NoError(t, err)
+	_, err = decodeBatch(payload)
+	require.ErrorIs(t, err, errBatchTooLarge)
+}
+
+func TestBatcherSealsAtMaxSize(t *testing.T) {
+	b := newBatcher(BatchConfig{MaxBatchSize: 10})
+	require.Equal(t, DefaultBatchFlushInterval, b.config.FlushInterval)
+
+	bt, err := b.add([]byte("1234"))
+	require.NoError(t, err)
+	require.Nil(t, bt)
+
+	bt, err = b.add([]byte("5678"))
+	require.NoError(t, err)
+	require.NotNil(t, bt)
+	require.Equal(t, 2, bt.reports)
+	require.Zero(t, b.buf.Len())
+
+	bt, err = b.flush()
+	require.NoError(t, err)
+	require.Nil(t, bt)
+}
+
+func TestBatcherBackpressure(t *testing.T) {
+	b := newBatcher(BatchConfig{MaxQueuedBatches: 1, BackpressureTimeout: 10 * time.Millisecond})
+	require.NoError(t, b.enqueue(&batch{reports: 1}))
+
+	start := time.Now()
+	require.ErrorIs(t, b.enqueue(&batch{reports: 1}), errBatchQueueFull)
+	require.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
+
+func TestWriteBatched(t *testing.T) {
+	tbServer, closeTBServer := createTBServer(t)
+	defer closeTBServer()
+
+	tbClient := NewTelemetryBuffer(nil)
+	require.NoError(t, tbClient.Connect())
+	tbClient.EnableBatching(BatchConfig{FlushInterval: time.Hour, MaxBatchSize: 256})
+
+	const numReports = 10
+	for i := 0; i < numReports; i++ {
+		report, err := json.Marshal(CNIReport{Name: "azure-vnet", CniSucceeded: true})
+		require.NoError(t, err)
+		n, err := tbClient.Write(report)
+		require.NoError(t, err)
+		require.Equal(t, len(report)+1, n)
+	}
+
+	// the batches which didn't fill up are written on close
+	tbClient.Close()
+	_, err := tbClient.Write([]byte("{}"))
+	require.ErrorIs(t, err, errBatchingStopped)
+
+	for i := 0; i < numReports; i++ {
+		select {
+		case report := <-tbServer.data:
+			require.Equal(t, "azure-vnet", report.(CNIReport).Name)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d of %d reports", i, numReports)
+		}
+	}
+	require.Zero(t, tbClient.Stats().DroppedReports)
+}
+
+func TestWriteBatchedFlushInterval(t *testing.T) {
+	tbServer, closeTBServer := createTBServer(t)
+	defer closeTBServer()
+
+	tbClient := NewTelemetryBuffer(nil)
+	require.NoError(t, tbClient.Connect())
+	tbClient.EnableBatching(BatchConfig{FlushInterval: 10 * time.Millisecond})
+	defer tbClient.Close()
+
+	report, err := json.Marshal(CNIReport{Name: "azure-vnet", CniSucceeded: true})
+	require.NoError(t, err)
+	_, err = tbClient.Write(report)
+	require.NoError(t, err)
+
+	select {
+	case <-tbServer.data:
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch not flushed")
+	}
+}
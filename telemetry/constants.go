@@ -9,17 +9,25 @@ const (
 	CNIDelTimeMetricStr    = "CNIDelTimeMs"
 	CNIUpdateTimeMetricStr = "CNIUpdateTimeMs"
 	CNILockTimeoutStr      = "CNILockTimeoutError"
+	CNILockWaitTimeStr     = "CNILockWaitTimeMs"
+	CNILockTakeoverStr     = "CNILockTakeover"
 
 	// Dimension Names
-	ContextStr        = "Context"
-	SubContextStr     = "SubContext"
-	VMUptimeStr       = "VMUptime"
-	OperationTypeStr  = "OperationType"
-	VersionStr        = "Version"
-	StatusStr         = "Status"
-	CNIModeStr        = "CNIMode"
-	CNINetworkModeStr = "CNINetworkMode"
-	OSTypeStr         = "OSType"
+	ContextStr         = "Context"
+	SubContextStr      = "SubContext"
+	VMUptimeStr        = "VMUptime"
+	OperationTypeStr   = "OperationType"
+	VersionStr         = "Version"
+	StatusStr          = "Status"
+	CNIModeStr         = "CNIMode"
+	CNINetworkModeStr  = "CNINetworkMode"
+	OSTypeStr          = "OSType"
+	SchemaVersionStr   = "SchemaVersion"
+	ResultCodeStr      = "ResultCode"
+	InterfaceCountStr  = "InterfaceCount"
+	IPFamiliesStr      = "IPFamilies"
+	CNIErrorCodeStr    = "CNIErrorCode"
+	OccurrenceCountStr = "OccurrenceCount"
 
 	// Values
 	SucceededStr     = "Succeeded"
@@ -0,0 +1,83 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"regexp"
+	"time"
+)
+
+// maxDedupEntries bounds the number of distinct error signatures tracked in a window. Reports with new
+// signatures are sent without deduplication once it is reached.
+const maxDedupEntries = 1000
+
+// variable parts of error messages, such as container IDs, addresses and counters, are masked so that
+// repeats of the same error share a signature.
+var errorSignatureMask = regexp.MustCompile(`[0-9a-fA-F]{8,}|[0-9]+`)
+
+// dedupEntry tracks the repeats of one error signature within a window.
+type dedupEntry struct {
+	expires    time.Time
+	suppressed int
+	last       CNIReport
+}
+
+// dedupCache collapses error reports with the same component and error signature. The first occurrence
+// in a window is sent immediately, the repeats are suppressed and sent as a single report carrying their
+// count when the window expires.
+type dedupCache struct {
+	window  time.Duration
+	entries map[string]*dedupEntry
+}
+
+func newDedupCache(window time.Duration) *dedupCache {
+	return &dedupCache{
+		window:  window,
+		entries: map[string]*dedupEntry{},
+	}
+}
+
+// errorSignature returns the dedup key of the report, or "" if the report is not an error report.
+func errorSignature(report *CNIReport) string {
+	if report.ErrorMessage == "" {
+		return ""
+	}
+	return report.Name + "|" + report.OperationType + "|" + errorSignatureMask.ReplaceAllString(report.ErrorMessage, "#")
+}
+
+// observe returns the report and true if it should be sent now, or false if it was suppressed as a repeat.
+func (d *dedupCache) observe(report CNIReport, now time.Time) (CNIReport, bool) {
+	key := errorSignature(&report)
+	if key == "" {
+		return report, true
+	}
+
+	if e, ok := d.entries[key]; ok && now.Before(e.expires) {
+		e.suppressed++
+		e.last = report
+		return report, false
+	}
+
+	if len(d.entries) < maxDedupEntries {
+		d.entries[key] = &dedupEntry{expires: now.Add(d.window)}
+	}
+	report.OccurrenceCount = 1
+	return report, true
+}
+
+// expire removes the entries whose window has passed and returns a report for each of them which
+// suppressed repeats, carrying the number of repeats.
+func (d *dedupCache) expire(now time.Time) []CNIReport {
+	var reports []CNIReport
+	for key, e := range d.entries {
+		if now.Before(e.expires) {
+			continue
+		}
+		delete(d.entries, key)
+		if e.suppressed > 0 {
+			e.last.OccurrenceCount = e.suppressed
+			reports = append(reports, e.last)
+		}
+	}
+	return reports
+}
@@ -0,0 +1,66 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupCollapsesRepeats(t *testing.T) {
+	d := newDedupCache(time.Minute)
+	now := time.Now()
+
+	first := CNIReport{Name: "azure-vnet", OperationType: "ADD", ErrorMessage: "failed to allocate 10.0.0.4 for container 3f1c9e0a8b2d"}
+	sent, ok := d.observe(first, now)
+	require.True(t, ok)
+	require.Equal(t, 1, sent.OccurrenceCount)
+
+	// repeats which differ only in ids and addresses are suppressed
+	for i := 0; i < 3; i++ {
+		repeat := CNIReport{Name: "azure-vnet", OperationType: "ADD", ErrorMessage: "failed to allocate 10.0.0.5 for container 77aa0e1f2c3d"}
+		_, ok = d.observe(repeat, now.Add(time.Second))
+		require.False(t, ok)
+	}
+
+	// other components, operations and successful reports are not
+	_, ok = d.observe(CNIReport{Name: "azure-vnet-ipam", OperationType: "ADD", ErrorMessage: first.ErrorMessage}, now)
+	require.True(t, ok)
+	_, ok = d.observe(CNIReport{Name: "azure-vnet", OperationType: "DEL", ErrorMessage: first.ErrorMessage}, now)
+	require.True(t, ok)
+	_, ok = d.observe(CNIReport{Name: "azure-vnet", OperationType: "ADD", EventMessage: "done"}, now)
+	require.True(t, ok)
+
+	require.Empty(t, d.expire(now.Add(30*time.Second)))
+
+	summaries := d.expire(now.Add(time.Minute))
+	require.Len(t, summaries, 1)
+	require.Equal(t, 3, summaries[0].OccurrenceCount)
+	require.Contains(t, summaries[0].ErrorMessage, "77aa0e1f2c3d")
+	require.Empty(t, d.entries)
+
+	// a new window starts with the next occurrence
+	sent, ok = d.observe(first, now.Add(2*time.Minute))
+	require.True(t, ok)
+	require.Equal(t, 1, sent.OccurrenceCount)
+}
+
+func TestDedupWindowWithoutRepeats(t *testing.T) {
+	d := newDedupCache(time.Minute)
+	now := time.Now()
+
+	_, ok := d.observe(CNIReport{Name: "azure-vnet", ErrorMessage: "boom"}, now)
+	require.True(t, ok)
+	require.Empty(t, d.expire(now.Add(time.Minute)))
+	require.Empty(t, d.entries)
+}
+
+func TestSetDedupWindow(t *testing.T) {
+	tb := NewTelemetryBuffer(nil)
+	tb.SetDedupWindow(time.Minute)
+	require.NotNil(t, tb.dedup)
+	tb.SetDedupWindow(-time.Second)
+	require.Nil(t, tb.dedup)
+}
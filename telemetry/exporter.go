@@ -0,0 +1,91 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Exporters selectable with TelemetryConfig.Exporter.
+const (
+	// ExporterAI sends reports to Application Insights. It is the default.
+	ExporterAI = "ai"
+	// ExporterOTLP sends reports to an OpenTelemetry collector.
+	ExporterOTLP = "otlp"
+)
+
+var errUnknownExporter = errors.New("unknown telemetry exporter")
+
+// Report is a telemetry report: a CNIReport or an AIMetric, or a pointer to one.
+type Report interface{}
+
+// Exporter sends telemetry reports to a backend.
+type Exporter interface {
+	Send(report Report) error
+}
+
+// NewExporter returns the exporter selected by the config, which the telemetry service sends the reports it
+// receives through.
+func NewExporter(config TelemetryConfig, serviceName string) (Exporter, error) {
+	switch config.Exporter {
+	case "", ExporterAI:
+		return AIExporter{}, nil
+	case ExporterOTLP:
+		return NewOTLPExporter(config.OTLP, serviceName)
+	default:
+		return nil, errors.Wrap(errUnknownExporter, config.Exporter)
+	}
+}
+
+// AIExporter sends reports to Application Insights through the handle created by CreateAITelemetryHandle.
+type AIExporter struct{}
+
+func (AIExporter) Send(report Report) error {
+	switch r := report.(type) {
+	case CNIReport:
+		SendAITelemetry(r)
+	case *CNIReport:
+		SendAITelemetry(*r)
+	case AIMetric:
+		SendAIMetric(r)
+	case *AIMetric:
+		SendAIMetric(*r)
+	default:
+		return errors.Errorf("Invalid report type: %T", report)
+	}
+	return nil
+}
+
+// TelemetryBufferExporter writes reports to the telemetry service through a connected TelemetryBuffer.
+// If the buffer isn't connected, the reports are spooled if it has a Spool and dropped otherwise.
+type TelemetryBufferExporter struct {
+	tb       *TelemetryBuffer
+	redactor *Redactor
+}
+
+// NewTelemetryBufferExporter returns an exporter writing to the telemetry service through tb.
+func NewTelemetryBufferExporter(tb *TelemetryBuffer, redactor *Redactor) *TelemetryBufferExporter {
+	return &TelemetryBufferExporter{tb: tb, redactor: redactor}
+}
+
+func (e *TelemetryBufferExporter) Send(report Report) error {
+	if e.tb == nil || (!e.tb.Connected && e.tb.spool == nil) {
+		return nil
+	}
+
+	reportMgr := &ReportManager{Report: report, Redactor: e.redactor}
+	b, err := reportMgr.ReportToBytes()
+	if err != nil {
+		return err
+	}
+	if err = e.tb.writeOrSpool(b); err != nil {
+		if e.tb.logger != nil {
+			e.tb.logger.Error("telemetry write failed", zap.Error(err))
+		} else {
+			log.Printf("telemetry write failed:%v", err)
+		}
+	}
+	return err
+}
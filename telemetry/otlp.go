@@ -0,0 +1,246 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	otlpLogsPath          = "/v1/logs"
+	otlpMetricsPath       = "/v1/metrics"
+	otlpScopeName         = "github.com/Azure/azure-container-networking/telemetry"
+	defaultOTLPTimeout    = 10 * time.Second
+	otlpSeverityInfo      = 9
+	otlpSeverityError     = 17
+	otlpContainerNameAttr = "ContainerName"
+)
+
+// OTLPConfig configures the OpenTelemetry collector reports are sent to with ExporterOTLP.
+type OTLPConfig struct {
+	// Endpoint is the base URL of the OTLP/HTTP receiver of the collector, e.g. http://otel-collector:4318.
+	// Reports are sent JSON encoded to its /v1/logs and /v1/metrics paths.
+	Endpoint string
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string
+	// TimeoutInSecs bounds each request. It defaults to 10s.
+	TimeoutInSecs int
+}
+
+// OTLPExporter sends CNI reports as OpenTelemetry log records, and metrics as gauges, to a collector over OTLP/HTTP.
+// Like with Application Insights, CNI reports without an error or event message are not sent.
+type OTLPExporter struct {
+	client      *http.Client
+	logsURL     string
+	metricsURL  string
+	headers     map[string]string
+	serviceName string
+}
+
+// NewOTLPExporter returns an exporter sending to the collector of the config, as the service called serviceName.
+func NewOTLPExporter(config OTLPConfig, serviceName string) (*OTLPExporter, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, errors.Errorf("invalid OTLP endpoint %q, expected an http(s) URL", config.Endpoint)
+	}
+	timeout := defaultOTLPTimeout
+	if config.TimeoutInSecs > 0 {
+		timeout = time.Duration(config.TimeoutInSecs) * time.Second
+	}
+	return &OTLPExporter{
+		client:      &http.Client{Timeout: timeout},
+		logsURL:     endpoint.JoinPath(otlpLogsPath).String(),
+		metricsURL:  endpoint.JoinPath(otlpMetricsPath).String(),
+		headers:     config.Headers,
+		serviceName: serviceName,
+	}, nil
+}
+
+func (e *OTLPExporter) Send(report Report) error {
+	switch r := report.(type) {
+	case CNIReport:
+		return e.sendCNIReport(&r)
+	case *CNIReport:
+		return e.sendCNIReport(r)
+	case AIMetric:
+		return e.sendMetric(&r)
+	case *AIMetric:
+		return e.sendMetric(r)
+	default:
+		return errors.Errorf("Invalid report type: %T", report)
+	}
+}
+
+func (e *OTLPExporter) sendCNIReport(report *CNIReport) error {
+	record := otlpLogRecord{
+		TimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes:   otlpAttributes(cniReportDimensions(report)),
+	}
+	switch {
+	case report.ErrorMessage != "":
+		record.SeverityNumber, record.SeverityText = otlpSeverityError, "ERROR"
+		record.Body = otlpAnyValue{StringValue: report.ErrorMessage}
+	case report.EventMessage != "":
+		record.SeverityNumber, record.SeverityText = otlpSeverityInfo, "INFO"
+		record.Body = otlpAnyValue{StringValue: report.EventMessage}
+	default:
+		return nil
+	}
+	if report.ContainerName != "" {
+		record.Attributes = append(record.Attributes, otlpKeyValue{Key: otlpContainerNameAttr, Value: otlpAnyValue{StringValue: report.ContainerName}})
+	}
+
+	return e.post(e.logsURL, otlpLogsRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource:  e.resource(),
+			ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: otlpScopeName}, LogRecords: []otlpLogRecord{record}}},
+		}},
+	})
+}
+
+func (e *OTLPExporter) sendMetric(metric *AIMetric) error {
+	dimensions := make(map[string]string, len(metric.Metric.CustomDimensions)+1)
+	for k, v := range metric.Metric.CustomDimensions {
+		dimensions[k] = v
+	}
+	if metric.Metric.AppVersion != "" {
+		dimensions[VersionStr] = metric.Metric.AppVersion
+	}
+
+	return e.post(e.metricsURL, otlpMetricsRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: e.resource(),
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope: otlpScope{Name: otlpScopeName},
+				Metrics: []otlpMetric{{
+					Name: metric.Metric.Name,
+					Gauge: otlpGauge{DataPoints: []otlpNumberDataPoint{{
+						TimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+						AsDouble:     metric.Metric.Value,
+						Attributes:   otlpAttributes(dimensions),
+					}}},
+				}},
+			}},
+		}},
+	})
+}
+
+func (e *OTLPExporter) resource() otlpResource {
+	return otlpResource{Attributes: []otlpKeyValue{{Key: "service.name", Value: otlpAnyValue{StringValue: e.serviceName}}}}
+}
+
+func (e *OTLPExporter) post(u string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode OTLP request")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to build OTLP request")
+	}
+	req.Header.Set("Content-Type", ContentType)
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	res, err := e.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "OTLP request failed")
+	}
+	defer res.Body.Close()
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("OTLP request to %s failed with status %d", u, res.StatusCode)
+	}
+	return nil
+}
+
+// otlpAttributes returns the dimensions as OTLP attributes, sorted by key.
+func otlpAttributes(dimensions map[string]string) []otlpKeyValue {
+	attributes := make([]otlpKeyValue, 0, len(dimensions))
+	for k, v := range dimensions {
+		attributes = append(attributes, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: v}})
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].Key < attributes[j].Key })
+	return attributes
+}
+
+// The types below are the subset of the OTLP/JSON encoding of the logs and metrics services used by the exporter.
+// See https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding.
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otlpAnyValue   `json:"body"`
+	Attributes     []otlpKeyValue `json:"attributes"`
+}
+
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Gauge otlpGauge `json:"gauge"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpNumberDataPoint struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsDouble     float64        `json:"asDouble"`
+	Attributes   []otlpKeyValue `json:"attributes"`
+}
@@ -0,0 +1,94 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/aitelemetry"
+	"github.com/stretchr/testify/require"
+)
+
+func newOTLPTestServer(t *testing.T, requests map[string][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, ContentType, r.Header.Get("Content-Type"))
+		require.Equal(t, "secret", r.Header.Get("Authorization"))
+		var body json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests[r.URL.Path] = body
+	}))
+}
+
+func TestOTLPExporterSendCNIReport(t *testing.T) {
+	requests := map[string][]byte{}
+	server := newOTLPTestServer(t, requests)
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(OTLPConfig{Endpoint: server.URL, Headers: map[string]string{"Authorization": "secret"}}, "azure-vnet-telemetry")
+	require.NoError(t, err)
+
+	// reports without a message are not sent
+	require.NoError(t, exporter.Send(CNIReport{Name: "CNI"}))
+	require.Empty(t, requests)
+
+	require.NoError(t, exporter.Send(&CNIReport{Context: "AzureCNI", ErrorMessage: "failed to add", ContainerName: "pod"}))
+	var logs otlpLogsRequest
+	require.NoError(t, json.Unmarshal(requests[otlpLogsPath], &logs))
+	require.Len(t, logs.ResourceLogs, 1)
+	require.Equal(t, []otlpKeyValue{{Key: "service.name", Value: otlpAnyValue{StringValue: "azure-vnet-telemetry"}}}, logs.ResourceLogs[0].Resource.Attributes)
+	record := logs.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	require.Equal(t, "failed to add", record.Body.StringValue)
+	require.Equal(t, "ERROR", record.SeverityText)
+	require.NotEmpty(t, record.TimeUnixNano)
+	require.Contains(t, record.Attributes, otlpKeyValue{Key: otlpContainerNameAttr, Value: otlpAnyValue{StringValue: "pod"}})
+	require.Contains(t, record.Attributes, otlpKeyValue{Key: ContextStr, Value: otlpAnyValue{StringValue: "AzureCNI"}})
+}
+
+func TestOTLPExporterSendMetric(t *testing.T) {
+	requests := map[string][]byte{}
+	server := newOTLPTestServer(t, requests)
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(OTLPConfig{Endpoint: server.URL + "/", Headers: map[string]string{"Authorization": "secret"}}, "azure-vnet-telemetry")
+	require.NoError(t, err)
+
+	metric := AIMetric{Metric: aitelemetry.Metric{Name: "Reconnects", Value: 3, CustomDimensions: map[string]string{"node": "n1"}}}
+	require.NoError(t, exporter.Send(metric))
+	var metrics otlpMetricsRequest
+	require.NoError(t, json.Unmarshal(requests[otlpMetricsPath], &metrics))
+	got := metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics[0]
+	require.Equal(t, "Reconnects", got.Name)
+	require.InDelta(t, 3, got.Gauge.DataPoints[0].AsDouble, 0)
+	require.Equal(t, []otlpKeyValue{{Key: "node", Value: otlpAnyValue{StringValue: "n1"}}}, got.Gauge.DataPoints[0].Attributes)
+
+	require.Error(t, exporter.Send("not a report"))
+}
+
+func TestOTLPExporterErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(OTLPConfig{Endpoint: server.URL}, "azure-vnet-telemetry")
+	require.NoError(t, err)
+	require.Error(t, exporter.Send(CNIReport{EventMessage: "added"}))
+}
+
+func TestNewExporter(t *testing.T) {
+	exporter, err := NewExporter(TelemetryConfig{}, "azure-vnet-telemetry")
+	require.NoError(t, err)
+	require.IsType(t, AIExporter{}, exporter)
+
+	exporter, err = NewExporter(TelemetryConfig{Exporter: ExporterOTLP, OTLP: OTLPConfig{Endpoint: "http://localhost:4318"}}, "azure-vnet-telemetry")
+	require.NoError(t, err)
+	require.IsType(t, &OTLPExporter{}, exporter)
+
+	_, err = NewExporter(TelemetryConfig{Exporter: ExporterOTLP, OTLP: OTLPConfig{Endpoint: "localhost:4318"}}, "azure-vnet-telemetry")
+	require.Error(t, err)
+
+	_, err = NewExporter(TelemetryConfig{Exporter: "statsd"}, "azure-vnet-telemetry")
+	require.ErrorIs(t, err, errUnknownExporter)
+}
@@ -0,0 +1,140 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RedactionAction is applied to a report field before the report leaves the node.
+type RedactionAction string
+
+const (
+	// RedactHash replaces the field value with a salted sha256 of it, so values can still be correlated.
+	RedactHash RedactionAction = "hash"
+	// RedactDrop removes the field from the report.
+	RedactDrop RedactionAction = "drop"
+
+	hashedValuePrefix = "sha256:"
+)
+
+var ErrInvalidRedactionAction = errors.New("invalid redaction action")
+
+// RedactionConfig configures which report fields are redacted and how.
+// Fields are keyed by their dotted JSON path in the report, e.g. "InterfaceDetails.PrimaryCA"
+// or "Metric.CustomDimensions.PodIP".
+type RedactionConfig struct {
+	Fields map[string]RedactionAction `json:"fields"`
+	Salt   string                     `json:"salt"`
+}
+
+// SensitiveReportFields are the CNIReport fields which carry pod IPs, container IDs and VNet IDs.
+// They can be used as a starting point for RedactionConfig.Fields.
+func SensitiveReportFields(action RedactionAction) map[string]RedactionAction {
+	return map[string]RedactionAction{
+		"ContainerName":              action,
+		"InfraVnetID":                action,
+		"VnetAddressSpace":           action,
+		"InterfaceDetails.PrimaryCA": action,
+		"InterfaceDetails.Subnet":    action,
+		"InterfaceDetails.MAC":       action,
+	}
+}
+
+type redactionRule struct {
+	path   []string
+	action RedactionAction
+}
+
+// Redactor hashes or drops configured fields of serialized reports.
+type Redactor struct {
+	rules []redactionRule
+	salt  string
+}
+
+// NewRedactor validates the config and returns a Redactor for it.
+func NewRedactor(config RedactionConfig) (*Redactor, error) {
+	r := &Redactor{salt: config.Salt}
+	for field, action := range config.Fields {
+		if action != RedactHash && action != RedactDrop {
+			return nil, errors.Wrapf(ErrInvalidRedactionAction, "%q for field %s", action, field)
+		}
+		r.rules = append(r.rules, redactionRule{path: strings.Split(field, "."), action: action})
+	}
+	return r, nil
+}
+
+// Redact applies the configured rules to a JSON encoded report.
+// Fields which are not present in the report are ignored.
+func (r *Redactor) Redact(report []byte) ([]byte, error) {
+	if r == nil || len(r.rules) == 0 {
+		return report, nil
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(report, &doc); err != nil {
+		return nil, errors.Wrap(err, "failed to decode report for redaction")
+	}
+
+	for _, rule := range r.rules {
+		r.apply(doc, rule.path, rule.action)
+	}
+
+	b, err := json.Marshal(doc)
+	return b, errors.Wrap(err, "failed to encode redacted report")
+}
+
+func (r *Redactor) apply(doc map[string]interface{}, path []string, action RedactionAction) {
+	for len(path) > 1 {
+		next, ok := doc[path[0]].(map[string]interface{})
+		if !ok {
+			return
+		}
+		doc, path = next, path[1:]
+	}
+
+	value, ok := doc[path[0]]
+	if !ok {
+		return
+	}
+
+	switch action {
+	case RedactDrop:
+		delete(doc, path[0])
+	case RedactHash:
+		doc[path[0]] = r.hashValue(value)
+	}
+}
+
+// hashValue hashes strings, and each element of string lists, so the redacted report keeps its shape.
+// Other values are hashed by their JSON encoding.
+func (r *Redactor) hashValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		if v == "" {
+			return v
+		}
+		return r.hash(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = r.hashValue(v[i])
+		}
+		return out
+	default:
+		b, _ := json.Marshal(v)
+		return r.hash(string(b))
+	}
+}
+
+func (r *Redactor) hash(s string) string {
+	sum := sha256.Sum256([]byte(r.salt + s))
+	return hashedValuePrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/aitelemetry"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactCNIReport(t *testing.T) {
+	r, err := NewRedactor(RedactionConfig{
+		Fields: map[string]RedactionAction{
+			"ContainerName":              RedactHash,
+			"VnetAddressSpace":           RedactHash,
+			"InterfaceDetails.PrimaryCA": RedactDrop,
+			"InfraVnetID":                RedactDrop,
+			"Not.There":                  RedactDrop,
+		},
+		Salt: "salt",
+	})
+	require.NoError(t, err)
+
+	reportMgr := &ReportManager{
+		Report: &CNIReport{
+			ContainerName:    "abc123",
+			InfraVnetID:      "vnet",
+			VnetAddressSpace: []string{"10.0.0.0/8"},
+			InterfaceDetails: InterfaceInfo{PrimaryCA: "10.0.0.4", Name: "eth0"},
+		},
+		Redactor: r,
+	}
+
+	b, err := reportMgr.ReportToBytes()
+	require.NoError(t, err)
+
+	var got CNIReport
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.True(t, strings.HasPrefix(got.ContainerName, hashedValuePrefix))
+	require.Equal(t, r.hash("abc123"), got.ContainerName)
+	require.Equal(t, []string{r.hash("10.0.0.0/8")}, got.VnetAddressSpace)
+	require.Empty(t, got.InfraVnetID)
+	require.Empty(t, got.InterfaceDetails.PrimaryCA)
+	require.Equal(t, "eth0", got.InterfaceDetails.Name)
+}
+
+func TestRedactAIMetricDimensions(t *testing.T) {
+	r, err := NewRedactor(RedactionConfig{Fields: map[string]RedactionAction{"Metric.CustomDimensions.PodIP": RedactDrop}})
+	require.NoError(t, err)
+
+	reportMgr := &ReportManager{
+		Report: &AIMetric{Metric: aitelemetry.Metric{
+			Name:             "test",
+			CustomDimensions: map[string]string{"PodIP": "10.0.0.4", "Status": "Succeeded"},
+		}},
+		Redactor: r,
+	}
+
+	b, err := reportMgr.ReportToBytes()
+	require.NoError(t, err)
+
+	var got AIMetric
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Equal(t, map[string]string{"Status": "Succeeded"}, got.Metric.CustomDimensions)
+}
+
+func TestNewRedactorInvalidAction(t *testing.T) {
+	_, err := NewRedactor(RedactionConfig{Fields: map[string]RedactionAction{"ContainerName": "encrypt"}})
+	require.ErrorIs(t, err, ErrInvalidRedactionAction)
+}
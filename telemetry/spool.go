@@ -0,0 +1,159 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CNITelemetrySpoolDir is where the CNI spools reports while the telemetry service is unreachable.
+	CNITelemetrySpoolDir = platform.CNIRuntimePath + "AzureCNITelemetrySpool"
+	// DefaultSpoolSizeInBytes bounds the size of the spooled reports if the spool is created without a size.
+	DefaultSpoolSizeInBytes = 1 << 20
+)
+
+const (
+	spoolFileExt = ".report"
+	// replaying files are claimed by renaming, so that concurrent CNI invocations don't replay the same report
+	spoolReplayExt = ".replaying"
+)
+
+// Spool persists the reports sent while a TelemetryBuffer is disconnected, so they can be replayed once it connects,
+// possibly by a later process. The spool is bounded in size and evicts the oldest reports first.
+// Reports are only redacted by the telemetry service, so they are spooled readable by their owner only.
+type Spool struct {
+	dir      string
+	maxBytes int64
+	mutex    sync.Mutex
+	// seq orders the reports spooled within the resolution of the clock
+	seq uint64
+}
+
+// NewSpool returns a spool of at most maxBytes of reports in dir, which is created if it doesn't exist.
+// A non-positive maxBytes uses DefaultSpoolSizeInBytes.
+func NewSpool(dir string, maxBytes int64) (*Spool, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultSpoolSizeInBytes
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil { //nolint:gomnd // the spooled reports aren't redacted
+		return nil, errors.Wrapf(err, "failed to create telemetry spool directory %s", dir)
+	}
+	return &Spool{dir: dir, maxBytes: maxBytes}, nil
+}
+
+// Append persists the report, evicting the oldest reports if the spool exceeds its size.
+// It returns the number of reports evicted.
+func (s *Spool) Append(report []byte) (int, error) {
+	if int64(len(report)) > s.maxBytes {
+		return 0, errors.Errorf("telemetry report of %d bytes exceeds spool size of %d bytes", len(report), s.maxBytes)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// file names sort in the order the reports were spooled
+	s.seq++
+	name := fmt.Sprintf("%020d-%010d-%020d%s", time.Now().UnixNano(), os.Getpid(), s.seq, spoolFileExt)
+	if err := os.WriteFile(filepath.Join(s.dir, name), report, 0o600); err != nil { //nolint:gomnd // the spooled reports aren't redacted
+		return 0, errors.Wrap(err, "failed to spool telemetry report")
+	}
+
+	return s.evict()
+}
+
+// Replay hands the spooled reports to send, oldest first, removing each once it has been handed over.
+// It stops at the first report send fails on, which is removed too since writes queue the reports they fail on.
+// It returns the number of reports replayed.
+func (s *Spool) Replay(send func([]byte) error) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	files, err := s.files()
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, f := range files {
+		path := filepath.Join(s.dir, f.Name())
+		claimed := strings.TrimSuffix(path, spoolFileExt) + spoolReplayExt
+		if err := os.Rename(path, claimed); err != nil {
+			// another process is replaying or evicted the report
+			continue
+		}
+
+		report, err := os.ReadFile(claimed)
+		os.Remove(claimed) //nolint:errcheck // a leftover claimed file is never replayed
+		if err != nil {
+			continue
+		}
+
+		if err := send(report); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// evict removes the oldest reports until the spool is within its size, and returns the number removed.
+func (s *Spool) evict() (int, error) {
+	files, err := s.files()
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, f := range files {
+		size += f.Size()
+	}
+
+	evicted := 0
+	for _, f := range files {
+		if size <= s.maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(s.dir, f.Name())); err != nil && !os.IsNotExist(err) {
+			return evicted, errors.Wrap(err, "failed to evict spooled telemetry report")
+		}
+		size -= f.Size()
+		evicted++
+	}
+
+	return evicted, nil
+}
+
+// files returns the spooled reports, oldest first.
+func (s *Spool) files() ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read telemetry spool directory")
+	}
+
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != spoolFileExt {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// removed by another process
+			continue
+		}
+		files = append(files, info)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	return files, nil
+}
@@ -0,0 +1,133 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/aitelemetry"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpoolEvictsOldest(t *testing.T) {
+	s, err := NewSpool(t.TempDir(), 10)
+	require.NoError(t, err)
+
+	for _, r := range []string{"aaaa", "bbbb"} {
+		evicted, err := s.Append([]byte(r))
+		require.NoError(t, err)
+		require.Zero(t, evicted)
+	}
+	evicted, err := s.Append([]byte("cccc"))
+	require.NoError(t, err)
+	require.Equal(t, 1, evicted)
+
+	_, err = s.Append([]byte("reports larger than the spool are rejected"))
+	require.Error(t, err)
+
+	var replayed []string
+	n, err := s.Replay(func(b []byte) error {
+		replayed = append(replayed, string(b))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{"bbbb", "cccc"}, replayed)
+
+	files, err := s.files()
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestSpoolIsOwnerOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes don't apply on Windows")
+	}
+	dir := filepath.Join(t.TempDir(), "spool")
+	s, err := NewSpool(dir, 0)
+	require.NoError(t, err)
+	_, err = s.Append([]byte(`{"podName":"unredacted"}`))
+	require.NoError(t, err)
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	require.Zero(t, info.Mode().Perm()&0o077)
+	files, err := s.files()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Zero(t, files[0].Mode().Perm()&0o077)
+}
+
+func TestSpoolReplayStopsOnError(t *testing.T) {
+	s, err := NewSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	for _, r := range []string{"a", "b", "c"} {
+		_, err = s.Append([]byte(r))
+		require.NoError(t, err)
+	}
+
+	errSend := errors.New("send failed")
+	n, err := s.Replay(func(b []byte) error {
+		if string(b) == "b" {
+			return errSend
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, errSend)
+	require.Equal(t, 1, n)
+
+	// the report send failed on was handed over, so only the rest stay spooled
+	var replayed []string
+	_, err = s.Replay(func(b []byte) error {
+		replayed = append(replayed, string(b))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"c"}, replayed)
+}
+
+func TestSpoolReplaysOnConnect(t *testing.T) {
+	s, err := NewSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	tbClient := NewTelemetryBuffer(nil)
+	tbClient.SetSpool(s)
+
+	// reports sent while disconnected are spooled instead of dropped
+	SendCNIEvent(tbClient, &CNIReport{Name: "azure-vnet", EventMessage: "spooled"})
+	metric := &AIMetric{Metric: aitelemetry.Metric{Name: CNIAddTimeMetricStr, Value: 1}}
+	require.NoError(t, SendCNIMetric(metric, tbClient))
+
+	files, err := s.files()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	tbServer, closeTBServer := createTBServer(t)
+	defer closeTBServer()
+
+	require.NoError(t, tbClient.Connect())
+	defer tbClient.Close()
+
+	select {
+	case r := <-tbServer.data:
+		require.Equal(t, "spooled", r.(CNIReport).EventMessage)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "spooled report not replayed")
+	}
+	select {
+	case r := <-tbServer.data:
+		require.Equal(t, CNIAddTimeMetricStr, r.(AIMetric).Metric.Name)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "spooled metric not replayed")
+	}
+
+	files, err = s.files()
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
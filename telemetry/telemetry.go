@@ -5,11 +5,13 @@ package telemetry
 
 import (
 	"encoding/json"
+	"net"
+	"sync"
 
 	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/common"
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/platform"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -19,6 +21,18 @@ const (
 	CNITelemetryFile = platform.CNIRuntimePath + "AzureCNITelemetry.json"
 	// ContentType of JSON
 	ContentType = "application/json"
+	// CNIReportSchemaVersion is the version of the CNIReport schema written by this build.
+	// Version 1 reports predate versioning and carry no SchemaVersion.
+	CNIReportSchemaVersion = 2
+	// resultSchemaVersion is the CNIReport schema version which added the result code, interface count,
+	// IP families and CNI error code.
+	resultSchemaVersion = 2
+)
+
+// IP families reported in CNIReport.IPFamilies
+const (
+	IPFamilyV4 = "IPv4"
+	IPFamilyV6 = "IPv6"
 )
 
 // OS Details structure.
@@ -28,6 +42,12 @@ type OSInfo struct {
 	KernelVersion  string
 	OSDistribution string
 	ErrorMessage   string
+	// Fields below were added in schema version 2.
+	OSName                  string
+	OSBuild                 int
+	OSUpdateBuildRevision   int
+	ContainerRuntime        string
+	ContainerRuntimeVersion string
 }
 
 // System Details structure.
@@ -83,17 +103,68 @@ type CNIReport struct {
 	BridgeDetails     BridgeInfo
 	Metadata          common.Metadata `json:"compute"`
 	Logger            *zap.Logger
+	// Fields below were added in schema version 2.
+	SchemaVersion  int
+	ResultCode     string
+	InterfaceCount int
+	IPFamilies     []string
+	CNIErrorCode   uint
+	// OccurrenceCount is the number of identical error reports this report stands for when the
+	// telemetry service deduplicates repeats. It is zero when deduplication is disabled.
+	OccurrenceCount int `json:",omitempty"`
 }
 
 type AIMetric struct {
 	Metric aitelemetry.Metric
 }
 
+// GetOSDetails fills the report with the structured details of the host OS. The container runtime
+// is left to the telemetry service, see fillContainerRuntime.
+func (report *CNIReport) GetOSDetails() {
+	info, err := platform.GetOSInfo()
+
+	report.OSDetails = OSInfo{
+		OSType:                info.Type,
+		OSVersion:             info.Version,
+		KernelVersion:         info.Kernel,
+		OSDistribution:        info.DistroID,
+		OSName:                info.Name,
+		OSBuild:               info.Build,
+		OSUpdateBuildRevision: info.UBR,
+	}
+	if err != nil {
+		report.OSDetails.ErrorMessage = "GetOSInfo failed with " + err.Error()
+	}
+}
+
+var (
+	containerRuntimeOnce sync.Once
+	containerRuntime     platform.OSInfo
+)
+
+// fillContainerRuntime sets the container runtime of the host on a report which does not carry one.
+// Detection runs the runtime binary, so the telemetry service does it once rather than every CNI
+// invocation doing it.
+func (report *CNIReport) fillContainerRuntime() {
+	if report.OSDetails.ContainerRuntime != "" {
+		return
+	}
+	containerRuntimeOnce.Do(func() {
+		platform.DetectContainerRuntime(&containerRuntime)
+	})
+	report.OSDetails.ContainerRuntime = containerRuntime.ContainerRuntime
+	report.OSDetails.ContainerRuntimeVersion = containerRuntime.ContainerRuntimeVersion
+}
+
 // ReportManager structure.
 type ReportManager struct {
 	HostNetAgentURL string
 	ContentType     string
 	Report          interface{}
+	// Redactor, if set, hashes or drops configured fields of the serialized report.
+	Redactor *Redactor
+	// Exporter, if set, is sent the report instead of the telemetry service.
+	Exporter Exporter
 }
 
 // GetReport retrieves orchestrator, system, OS and Interface details and create a report structure.
@@ -105,25 +176,74 @@ func (report *CNIReport) GetReport(name string, version string, ipamQueryURL str
 	report.GetOSDetails()
 }
 
-// SendReport will send telemetry report to HostNetAgent.
-func (reportMgr *ReportManager) SendReport(tb *TelemetryBuffer) error {
-	var err error
-	var report []byte
+// SetResult records the outcome of the current operation. CNI errors carry their own code,
+// any other error is reported as an internal error.
+func (report *CNIReport) SetResult(err error) {
+	if err == nil {
+		report.CniSucceeded = true
+		report.ResultCode = SucceededStr
+		report.CNIErrorCode = 0
+		return
+	}
 
-	if tb != nil && tb.Connected {
-		report, err = reportMgr.ReportToBytes()
-		if err == nil {
-			if _, err = tb.Write(report); err != nil {
-				if tb.logger != nil {
-					tb.logger.Error("telemetry write failed", zap.Error(err))
-				} else {
-					log.Printf("telemetry write failed:%v", err)
-				}
-			}
+	report.CniSucceeded = false
+	report.ResultCode = FailedStr
+	report.CNIErrorCode = cniTypes.ErrInternal
+	var cniErr *cniTypes.Error
+	if errors.As(err, &cniErr) {
+		report.CNIErrorCode = cniErr.Code
+	}
+}
+
+// SetInterfaceSummary records the number of interfaces and the IP families assigned to them.
+func (report *CNIReport) SetInterfaceSummary(interfaceCount int, ips []net.IP) {
+	report.InterfaceCount = interfaceCount
+	report.IPFamilies = nil
+
+	var hasV4, hasV6 bool
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			hasV4 = true
+		} else if ip.To16() != nil {
+			hasV6 = true
 		}
 	}
+	if hasV4 {
+		report.IPFamilies = append(report.IPFamilies, IPFamilyV4)
+	}
+	if hasV6 {
+		report.IPFamilies = append(report.IPFamilies, IPFamilyV6)
+	}
+}
 
-	return err
+// normalize upgrades a report decoded from an older schema version so that consumers can rely
+// on the current fields. Reports from newer versions are passed through, unknown fields having
+// already been dropped while decoding.
+func (report *CNIReport) normalize() {
+	if report.SchemaVersion >= CNIReportSchemaVersion {
+		return
+	}
+
+	if report.SchemaVersion == 0 {
+		report.SchemaVersion = 1
+	}
+
+	if report.ResultCode == "" {
+		if report.ErrorMessage != "" {
+			report.ResultCode = FailedStr
+		} else if report.CniSucceeded {
+			report.ResultCode = SucceededStr
+		}
+	}
+}
+
+// SendReport sends the report through the Exporter, or to the telemetry service through tb if it's unset.
+func (reportMgr *ReportManager) SendReport(tb *TelemetryBuffer) error {
+	exporter := reportMgr.Exporter
+	if exporter == nil {
+		exporter = NewTelemetryBufferExporter(tb, reportMgr.Redactor)
+	}
+	return exporter.Send(reportMgr.Report)
 }
 
 // ReportToBytes - returns the report bytes
@@ -136,7 +256,11 @@ func (reportMgr *ReportManager) ReportToBytes() ([]byte, error) {
 	}
 
 	report, err := json.Marshal(reportMgr.Report)
-	return report, err
+	if err != nil {
+		return report, err
+	}
+
+	return reportMgr.Redactor.Redact(report)
 }
 
 // This function for sending CNI metrics to telemetry service
@@ -144,11 +268,11 @@ func SendCNIMetric(cniMetric *AIMetric, tb *TelemetryBuffer) error {
 	var err error
 	var report []byte
 
-	if tb != nil && tb.Connected {
+	if tb != nil && (tb.Connected || tb.spool != nil) {
 		reportMgr := &ReportManager{Report: cniMetric}
 		report, err = reportMgr.ReportToBytes()
 		if err == nil {
-			if _, err = tb.Write(report); err != nil {
+			if err = tb.writeOrSpool(report); err != nil {
 				tb.logger.Error("Error writing to telemetry socket", zap.Error(err))
 			}
 		}
@@ -158,11 +282,11 @@ func SendCNIMetric(cniMetric *AIMetric, tb *TelemetryBuffer) error {
 }
 
 func SendCNIEvent(tb *TelemetryBuffer, report *CNIReport) {
-	if tb != nil && tb.Connected {
+	if tb != nil && (tb.Connected || tb.spool != nil) {
 		reportMgr := &ReportManager{Report: report}
 		reportBytes, err := reportMgr.ReportToBytes()
 		if err == nil {
-			if _, err = tb.Write(reportBytes); err != nil {
+			if err = tb.writeOrSpool(reportBytes); err != nil {
 				tb.logger.Error("Error writing to telemetry socket", zap.Error(err))
 			}
 		}
@@ -4,12 +4,9 @@
 package telemetry
 
 import (
-	"os/exec"
 	"runtime"
-	"strings"
 	"syscall"
 
-	"github.com/Azure/azure-container-networking/platform"
 	"github.com/pkg/errors"
 )
 
@@ -86,30 +83,3 @@ func (report *CNIReport) GetSystemDetails() {
 		ErrorMessage: errMsg,
 	}
 }
-
-// This function  creates a report with os details(ostype, version).
-func (report *CNIReport) GetOSDetails() {
-	osInfoArr, err := platform.GetOSDetails()
-	if err != nil {
-		report.OSDetails = OSInfo{OSType: runtime.GOOS}
-		report.OSDetails.ErrorMessage = "GetOSDetails failed with" + err.Error()
-		return
-	}
-
-	out, err := exec.Command("uname", "-r").Output()
-	if err != nil {
-		report.OSDetails = OSInfo{OSType: runtime.GOOS}
-		report.OSDetails.ErrorMessage = "uname -r failed with " + err.Error()
-		return
-	}
-
-	kernelVersion := string(out)
-	kernelVersion = strings.TrimSuffix(kernelVersion, "\n")
-
-	report.OSDetails = OSInfo{
-		OSType:         runtime.GOOS,
-		OSVersion:      osInfoArr["VERSION"],
-		KernelVersion:  kernelVersion,
-		OSDistribution: osInfoArr["ID"],
-	}
-}
@@ -4,11 +4,15 @@
 package telemetry
 
 import (
+	"encoding/json"
+	"errors"
+	"net"
 	"os"
 	"testing"
 
 	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/cni/log"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -148,9 +152,56 @@ func TestGetOSDetails(t *testing.T) {
 	require.Equal(t, expectedErrMsg, cniReport.ErrorMessage)
 }
 
+func TestFillContainerRuntimeKeepsReported(t *testing.T) {
+	report := &CNIReport{OSDetails: OSInfo{ContainerRuntime: "cri-o", ContainerRuntimeVersion: "1.28"}}
+	report.fillContainerRuntime()
+	require.Equal(t, "cri-o", report.OSDetails.ContainerRuntime)
+	require.Equal(t, "1.28", report.OSDetails.ContainerRuntimeVersion)
+}
+
 func TestGetSystemDetails(t *testing.T) {
 	expectedErrMsg := ""
 	cniReport := &CNIReport{}
 	cniReport.GetSystemDetails()
 	require.Equal(t, expectedErrMsg, cniReport.ErrorMessage)
 }
+
+func TestCNIReportSetResult(t *testing.T) {
+	report := &CNIReport{SchemaVersion: CNIReportSchemaVersion}
+
+	report.SetResult(&cniTypes.Error{Code: cniTypes.ErrTryAgainLater, Msg: "busy"})
+	require.False(t, report.CniSucceeded)
+	require.Equal(t, FailedStr, report.ResultCode)
+	require.Equal(t, uint(cniTypes.ErrTryAgainLater), report.CNIErrorCode)
+
+	report.SetResult(errors.New("boom"))
+	require.Equal(t, uint(cniTypes.ErrInternal), report.CNIErrorCode)
+
+	report.SetResult(nil)
+	require.True(t, report.CniSucceeded)
+	require.Equal(t, SucceededStr, report.ResultCode)
+	require.Zero(t, report.CNIErrorCode)
+}
+
+func TestCNIReportSetInterfaceSummary(t *testing.T) {
+	report := &CNIReport{}
+	report.SetInterfaceSummary(2, []net.IP{net.ParseIP("10.0.0.4"), net.ParseIP("fd00::4"), net.ParseIP("10.0.0.5")})
+	require.Equal(t, 2, report.InterfaceCount)
+	require.Equal(t, []string{IPFamilyV4, IPFamilyV6}, report.IPFamilies)
+}
+
+func TestCNIReportNormalize(t *testing.T) {
+	// a report written before schema versioning decodes with SchemaVersion 0
+	var v1 CNIReport
+	require.NoError(t, json.Unmarshal([]byte(`{"CniSucceeded":false,"ErrorMessage":"failed"}`), &v1))
+	v1.normalize()
+	require.Equal(t, 1, v1.SchemaVersion)
+	require.Equal(t, FailedStr, v1.ResultCode)
+
+	// reports from newer schema versions keep their version and ignore unknown fields
+	var v3 CNIReport
+	require.NoError(t, json.Unmarshal([]byte(`{"CniSucceeded":true,"SchemaVersion":3,"ResultCode":"Succeeded","NewField":1}`), &v3))
+	v3.normalize()
+	require.Equal(t, 3, v3.SchemaVersion)
+	require.Equal(t, SucceededStr, v3.ResultCode)
+}
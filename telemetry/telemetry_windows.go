@@ -3,18 +3,6 @@
 
 package telemetry
 
-import (
-	"runtime"
-	"strings"
-
-	"github.com/Azure/azure-container-networking/platform"
-)
-
-const (
-	delimiter  = "\r\n"
-	versionCmd = "ver"
-)
-
 type MemInfo struct {
 	MemTotal uint64
 	MemFree  uint64
@@ -36,12 +24,3 @@ func getDiskInfo(path string) (*DiskInfo, error) {
 func (report *CNIReport) GetSystemDetails() {
 	report.SystemDetails = SystemInfo{}
 }
-
-func (report *CNIReport) GetOSDetails() {
-	p := platform.NewExecClient(report.Logger)
-	report.OSDetails = OSInfo{OSType: runtime.GOOS}
-	out, err := p.ExecuteCommand(versionCmd)
-	if err == nil {
-		report.OSDetails.OSVersion = strings.Replace(out, delimiter, "", -1)
-	}
-}
@@ -7,13 +7,17 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"math/rand"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/platform"
@@ -34,6 +38,23 @@ type TelemetryConfig struct {
 	BatchSizeInBytes              int
 	GetEnvRetryCount              int
 	GetEnvRetryWaitTimeInSecs     int
+	// DedupWindowInSeconds collapses repeats of the same error report within the window into a single
+	// report carrying the number of repeats. The telemetry service uses 60s if it is unset, and disables
+	// deduplication if it is negative.
+	DedupWindowInSeconds int
+	// HeartbeatIntervalInMins is the interval at which the telemetry service reports its heartbeat.
+	HeartbeatIntervalInMins int
+	// Redaction configures fields hashed or dropped from reports before they are sent off the node.
+	Redaction RedactionConfig
+	// IngestionEndpoint overrides the Application Insights endpoint reports are sent to, for sovereign and
+	// air-gapped clouds. DisableAll turns off sending altogether.
+	IngestionEndpoint string
+	// Proxy configures the proxy reports are sent through. Unset fields are taken from the environment.
+	Proxy aitelemetry.ProxyConfig
+	// Exporter selects the backend reports are sent to: ExporterAI, the default, or ExporterOTLP.
+	Exporter string
+	// OTLP configures the collector reports are sent to with ExporterOTLP.
+	OTLP OTLPConfig
 }
 
 // FdName - file descriptor name
@@ -44,8 +65,30 @@ const (
 	Delimiter      = '\n'
 	MaxPayloadSize = 4096
 	MaxNumReports  = 1000
+	// MaxPendingReports is the number of reports a client holds while it is disconnected
+	MaxPendingReports = 100
 )
 
+// writeTimeout bounds a write to a slow telemetry service, after which the connection is dropped and the report queued.
+const writeTimeout = 5 * time.Second
+
+const (
+	minReconnectBackoff = 100 * time.Millisecond
+	maxReconnectBackoff = 30 * time.Second
+)
+
+var errReconnectBackoff = errors.New("waiting to reconnect to telemetry service")
+
+// TelemetryBufferStats are counters of connection health and report loss.
+type TelemetryBufferStats struct {
+	// Reconnects is the number of times the client re-established its connection.
+	Reconnects uint64
+	// WriteFailures is the number of failed report writes.
+	WriteFailures uint64
+	// DroppedReports is the number of reports discarded because a queue was full.
+	DroppedReports uint64
+}
+
 // TelemetryBuffer object
 type TelemetryBuffer struct {
 	client      net.Conn
@@ -58,6 +101,26 @@ type TelemetryBuffer struct {
 	mutex       sync.Mutex
 	logger      *zap.Logger
 	plc         platform.ExecClient
+	redactor    *Redactor
+	dedup       *dedupCache
+	exporter    Exporter
+	transport   transport
+
+	// clientMutex guards the client connection and the reports pending on it
+	clientMutex   sync.Mutex
+	pending       [][]byte
+	backoff       time.Duration
+	nextReconnect time.Time
+
+	// batcher batches and compresses the reports written by the client, if enabled
+	batcher *batcher
+
+	// spool persists the reports sent while disconnected, if set
+	spool *Spool
+
+	reconnects     atomic.Uint64
+	writeFailures  atomic.Uint64
+	droppedReports atomic.Uint64
 }
 
 // Buffer object holds the different types of reports
@@ -74,10 +137,48 @@ func NewTelemetryBuffer(logger *zap.Logger) *TelemetryBuffer {
 	tb.connections = make([]net.Conn, 0)
 	tb.logger = logger
 	tb.plc = platform.NewExecClient(tb.logger)
+	tb.transport = newTransport()
 
 	return &tb
 }
 
+// SetRedactor sets the redactor applied to every report received by the server.
+func (tb *TelemetryBuffer) SetRedactor(r *Redactor) {
+	tb.redactor = r
+}
+
+// SetExporter sets the exporter the server sends the reports it receives through. It defaults to AIExporter.
+// It must be called before PushData.
+func (tb *TelemetryBuffer) SetExporter(e Exporter) {
+	tb.exporter = e
+}
+
+// SetDedupWindow enables deduplication of repeated error reports within window. A zero window disables it.
+// It must be called before PushData.
+func (tb *TelemetryBuffer) SetDedupWindow(window time.Duration) {
+	if window <= 0 {
+		tb.dedup = nil
+		return
+	}
+	tb.dedup = newDedupCache(window)
+}
+
+// EnableBatching batches the reports written by the client, which are compressed and written to the telemetry
+// service when the batch reaches the configured size, after the flush interval, and on Close. Writes block while
+// the batches queue up behind a slow telemetry service, and drop the batch once the backpressure timeout expires.
+// It must be called before Write, and only when the telemetry service reads batches: older ones drop them as
+// malformed reports, so the CNI, which may run against any version of the service, leaves its reports unbatched.
+func (tb *TelemetryBuffer) EnableBatching(config BatchConfig) {
+	tb.batcher = newBatcher(config)
+	go tb.sendBatches(tb.batcher)
+}
+
+// SetSpool persists the reports sent while the buffer isn't connected to s, instead of dropping them. The spooled
+// reports are replayed when the buffer connects. It must be called before Connect.
+func (tb *TelemetryBuffer) SetSpool(s *Spool) {
+	tb.spool = s
+}
+
 func remove(s []net.Conn, i int) []net.Conn {
 	if len(s) > 0 && i < len(s) {
 		s[i] = s[len(s)-1]
@@ -116,32 +217,25 @@ func (tb *TelemetryBuffer) StartServer() error {
 				tb.connections = append(tb.connections, conn)
 				tb.mutex.Unlock()
 				go func() {
+					// one reader per connection, so reports that arrive together are not lost
+					reader := bufio.NewReader(conn)
 					for {
-						reportStr, err := read(conn)
+						reportStr, err := read(reader)
 						if err == nil {
-							var tmp map[string]interface{}
-							err = json.Unmarshal(reportStr, &tmp)
-							if err != nil {
-								if tb.logger != nil {
-									tb.logger.Error("StartServer: unmarshal error", zap.Error(err))
-								} else {
-									log.Logf("StartServer: unmarshal error:%v", err)
+							reports := [][]byte{reportStr}
+							if isBatch(reportStr) {
+								if reports, err = decodeBatch(reportStr); err != nil {
+									if tb.logger != nil {
+										tb.logger.Error("StartServer: batch decode error", zap.Error(err))
+									} else {
+										log.Logf("StartServer: batch decode error:%v", err)
+									}
+									return
 								}
-								return
 							}
-							if _, ok := tmp["CniSucceeded"]; ok {
-								var cniReport CNIReport
-								json.Unmarshal([]byte(reportStr), &cniReport)
-								tb.data <- cniReport
-							} else if _, ok := tmp["Metric"]; ok {
-								var aiMetric AIMetric
-								json.Unmarshal([]byte(reportStr), &aiMetric)
-								tb.data <- aiMetric
-							} else {
-								if tb.logger != nil {
-									tb.logger.Info("StartServer: default", zap.Any("case", tmp))
-								} else {
-									log.Logf("StartServer: default case:%+v...", tmp)
+							for _, report := range reports {
+								if err = tb.handleReport(report); err != nil {
+									return
 								}
 							}
 						} else {
@@ -182,10 +276,52 @@ func (tb *TelemetryBuffer) StartServer() error {
 	return nil
 }
 
+// handleReport redacts and decodes a report received from a client and hands it to PushData.
+func (tb *TelemetryBuffer) handleReport(report []byte) error {
+	var err error
+	if report, err = tb.redactor.Redact(report); err != nil {
+		if tb.logger != nil {
+			tb.logger.Error("StartServer: redaction error", zap.Error(err))
+		} else {
+			log.Logf("StartServer: redaction error:%v", err)
+		}
+		return err
+	}
+	var tmp map[string]interface{}
+	err = json.Unmarshal(report, &tmp)
+	if err != nil {
+		if tb.logger != nil {
+			tb.logger.Error("StartServer: unmarshal error", zap.Error(err))
+		} else {
+			log.Logf("StartServer: unmarshal error:%v", err)
+		}
+		return err
+	}
+	if _, ok := tmp["CniSucceeded"]; ok {
+		var cniReport CNIReport
+		json.Unmarshal([]byte(report), &cniReport)
+		cniReport.normalize()
+		cniReport.fillContainerRuntime()
+		tb.enqueueData(cniReport)
+	} else if _, ok := tmp["Metric"]; ok {
+		var aiMetric AIMetric
+		json.Unmarshal([]byte(report), &aiMetric)
+		tb.enqueueData(aiMetric)
+	} else {
+		if tb.logger != nil {
+			tb.logger.Info("StartServer: default", zap.Any("case", tmp))
+		} else {
+			log.Logf("StartServer: default case:%+v...", tmp)
+		}
+	}
+	return nil
+}
+
 func (tb *TelemetryBuffer) Connect() error {
 	err := tb.Dial(FdName)
 	if err == nil {
 		tb.Connected = true
+		tb.replaySpool()
 	} else if tb.FdExists {
 		tb.Cleanup(FdName)
 	}
@@ -197,11 +333,25 @@ func (tb *TelemetryBuffer) Connect() error {
 func (tb *TelemetryBuffer) PushData(ctx context.Context) {
 	defer tb.Close()
 
+	// expired dedup windows are flushed periodically so the repeat counts are sent even when idle
+	var dedupExpiry <-chan time.Time
+	if tb.dedup != nil {
+		ticker := time.NewTicker(tb.dedup.window)
+		defer ticker.Stop()
+		dedupExpiry = ticker.C
+	}
+
 	for {
 		select {
 		case report := <-tb.data:
 			tb.mutex.Lock()
-			push(report)
+			tb.pushDeduped(report)
+			tb.mutex.Unlock()
+		case now := <-dedupExpiry:
+			tb.mutex.Lock()
+			for _, r := range tb.dedup.expire(now) {
+				tb.push(r)
+			}
 			tb.mutex.Unlock()
 		case <-tb.cancel:
 			if tb.logger != nil {
@@ -221,9 +371,9 @@ func (tb *TelemetryBuffer) PushData(ctx context.Context) {
 	}
 }
 
-// read - read from the file descriptor
-func read(conn net.Conn) (b []byte, err error) {
-	b, err = bufio.NewReader(conn).ReadBytes(Delimiter)
+// read - read the next delimited report from the connection reader
+func read(r *bufio.Reader) (b []byte, err error) {
+	b, err = r.ReadBytes(Delimiter)
 	if err == nil {
 		b = b[:len(b)-1]
 	}
@@ -231,12 +381,130 @@ func read(conn net.Conn) (b []byte, err error) {
 	return
 }
 
-// Write - write to the file descriptor.
+// Write - write to the file descriptor, or to the current batch if batching is enabled. If the connection is down
+// the report is queued, up to MaxPendingReports, and the client reconnects with backoff on a later write.
 func (tb *TelemetryBuffer) Write(b []byte) (c int, err error) {
+	if tb.batcher != nil {
+		return tb.writeBatched(b)
+	}
+	return tb.writeReport(b)
+}
+
+// writeReport writes the report, or batch payload, to the connection.
+func (tb *TelemetryBuffer) writeReport(b []byte) (c int, err error) {
+	tb.clientMutex.Lock()
+	defer tb.clientMutex.Unlock()
+
+	if tb.client == nil {
+		if err = tb.reconnect(); err != nil {
+			tb.enqueuePending(b)
+			return 0, err
+		}
+	}
+
+	for len(tb.pending) > 0 {
+		if _, err = tb.write(tb.pending[0]); err != nil {
+			tb.writeFailed(err)
+			tb.enqueuePending(b)
+			return 0, err
+		}
+		tb.pending = tb.pending[1:]
+	}
+
+	if c, err = tb.write(b); err != nil {
+		tb.writeFailed(err)
+		tb.enqueuePending(b)
+	}
+
+	return c, err
+}
+
+// writeBatched adds the report to the current batch, and queues the batch for the sender once it is full.
+func (tb *TelemetryBuffer) writeBatched(b []byte) (int, error) {
+	bt, err := tb.batcher.add(b)
+	if err == nil && bt != nil {
+		err = tb.batcher.enqueue(bt)
+	}
+	if err != nil {
+		if bt != nil {
+			tb.dropBatch(*bt, err)
+		}
+		return 0, err
+	}
+	return len(b) + 1, nil
+}
+
+// sendBatches writes the queued batches, and the current batch every flush interval, until batching is stopped.
+func (tb *TelemetryBuffer) sendBatches(b *batcher) {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(b.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case bt := <-b.queue:
+			tb.sendBatch(bt)
+		case <-ticker.C:
+			bt, err := b.flush()
+			if err != nil {
+				tb.dropBatch(*bt, err)
+			} else if bt != nil {
+				tb.sendBatch(*bt)
+			}
+		case <-b.done:
+			for {
+				select {
+				case bt := <-b.queue:
+					tb.sendBatch(bt)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// sendBatch writes the batch payload. A failed write is queued as any other report, and logged by writeReport.
+func (tb *TelemetryBuffer) sendBatch(bt batch) {
+	tb.writeReport(bt.payload) //nolint:errcheck // the payload is queued for the next write
+}
+
+// stopBatching writes the queued batches and the current batch, after which writes fail.
+func (tb *TelemetryBuffer) stopBatching() {
+	b := tb.batcher
+	b.stop.Do(func() {
+		b.mutex.Lock()
+		close(b.done)
+		bt, err := b.seal()
+		b.mutex.Unlock()
+
+		<-b.stopped
+		if err != nil {
+			tb.dropBatch(*bt, err)
+		} else if bt != nil {
+			tb.sendBatch(*bt)
+		}
+	})
+}
+
+// dropBatch discards the reports of a batch which couldn't be encoded or queued.
+func (tb *TelemetryBuffer) dropBatch(bt batch, err error) {
+	tb.droppedReports.Add(uint64(bt.reports))
+	if tb.logger != nil {
+		tb.logger.Error("telemetry batch dropped", zap.Int("reports", bt.reports), zap.Error(err))
+	} else {
+		log.Logf("telemetry batch of %d reports dropped: %v", bt.reports, err)
+	}
+}
+
+func (tb *TelemetryBuffer) write(b []byte) (c int, err error) {
 	buf := make([]byte, len(b))
 	copy(buf, b)
 	//nolint:makezero //keeping old code
 	buf = append(buf, Delimiter)
+	// a slow telemetry service fails the write rather than blocking the client
+	tb.client.SetWriteDeadline(time.Now().Add(writeTimeout)) //nolint:errcheck // not all connections support deadlines
 	w := bufio.NewWriter(tb.client)
 	c, err = w.Write(buf)
 	if err == nil {
@@ -246,6 +514,130 @@ func (tb *TelemetryBuffer) Write(b []byte) (c int, err error) {
 	return
 }
 
+// writeFailed drops the broken connection so that the next write reconnects.
+func (tb *TelemetryBuffer) writeFailed(err error) {
+	tb.writeFailures.Add(1)
+	if tb.logger != nil {
+		tb.logger.Error("telemetry write failed, dropping connection", zap.Error(err))
+	} else {
+		log.Logf("telemetry write failed, dropping connection: %v", err)
+	}
+	tb.client.Close()
+	tb.client = nil
+}
+
+// reconnect dials the telemetry service unless still backing off from a previous failed attempt.
+// The backoff doubles on every failure up to maxReconnectBackoff and is jittered so that many
+// clients do not reconnect in lockstep.
+func (tb *TelemetryBuffer) reconnect() error {
+	now := time.Now()
+	if now.Before(tb.nextReconnect) {
+		return errReconnectBackoff
+	}
+
+	if err := tb.Dial(FdName); err != nil {
+		if tb.backoff == 0 {
+			tb.backoff = minReconnectBackoff
+		} else if tb.backoff *= 2; tb.backoff > maxReconnectBackoff {
+			tb.backoff = maxReconnectBackoff
+		}
+		// wait between half and the full backoff
+		jitter := time.Duration(rand.Int63n(int64(tb.backoff/2) + 1)) //nolint:gosec // jitter does not need crypto rand
+		tb.nextReconnect = now.Add(tb.backoff/2 + jitter)
+		return err
+	}
+
+	tb.backoff = 0
+	tb.nextReconnect = time.Time{}
+	tb.reconnects.Add(1)
+	return nil
+}
+
+// enqueuePending holds a report until the connection is back, dropping the oldest if full.
+func (tb *TelemetryBuffer) enqueuePending(b []byte) {
+	if len(tb.pending) >= MaxPendingReports {
+		tb.pending = tb.pending[1:]
+		tb.droppedReports.Add(1)
+	}
+	buf := make([]byte, len(b))
+	copy(buf, b)
+	tb.pending = append(tb.pending, buf)
+}
+
+// writeOrSpool writes the report if the buffer is connected, and spools it otherwise.
+// The report is dropped if the buffer is disconnected and has no spool.
+func (tb *TelemetryBuffer) writeOrSpool(b []byte) error {
+	if tb.Connected {
+		_, err := tb.Write(b)
+		return err
+	}
+	if tb.spool == nil {
+		return nil
+	}
+
+	evicted, err := tb.spool.Append(b)
+	tb.droppedReports.Add(uint64(evicted))
+	return err
+}
+
+// replaySpool writes the spooled reports. Those failing to write are queued as any other report.
+func (tb *TelemetryBuffer) replaySpool() {
+	if tb.spool == nil {
+		return
+	}
+
+	replayed, err := tb.spool.Replay(func(b []byte) error {
+		_, err := tb.Write(b)
+		return err
+	})
+	if replayed == 0 && err == nil {
+		return
+	}
+	if tb.logger != nil {
+		tb.logger.Info("replayed spooled telemetry reports", zap.Int("reports", replayed), zap.Error(err))
+	} else {
+		log.Logf("replayed %d spooled telemetry reports, err:%v", replayed, err)
+	}
+}
+
+// enqueueData hands a received report to PushData without blocking the reader, dropping it if the queue is full.
+func (tb *TelemetryBuffer) enqueueData(report interface{}) {
+	select {
+	case tb.data <- report:
+	default:
+		tb.droppedReports.Add(1)
+	}
+}
+
+// Stats returns the connection health and report loss counters.
+func (tb *TelemetryBuffer) Stats() TelemetryBufferStats {
+	return TelemetryBufferStats{
+		Reconnects:     tb.reconnects.Load(),
+		WriteFailures:  tb.writeFailures.Load(),
+		DroppedReports: tb.droppedReports.Load(),
+	}
+}
+
+// Dial - try to connect to the telemetry endpoint with 'name'
+func (tb *TelemetryBuffer) Dial(name string) error {
+	conn, err := tb.transport.dial(name)
+	if err == nil {
+		tb.client = conn
+	}
+
+	return err
+}
+
+// Listen - try to create and listen on the telemetry endpoint with 'name'
+func (tb *TelemetryBuffer) Listen(name string) error {
+	listener, err := tb.transport.listen(name)
+	if err == nil {
+		tb.listener = listener
+	}
+
+	return err
+}
+
 // Cancel - signal to tear down telemetry buffer
 func (tb *TelemetryBuffer) Cancel() {
 	tb.cancel <- true
@@ -253,10 +645,28 @@ func (tb *TelemetryBuffer) Cancel() {
 
 // Close - close all connections
 func (tb *TelemetryBuffer) Close() {
+	if tb.batcher != nil {
+		tb.stopBatching()
+	}
+
+	tb.clientMutex.Lock()
 	if tb.client != nil {
 		tb.client.Close()
 		tb.client = nil
 	}
+	pending := len(tb.pending)
+	tb.pending = nil
+	tb.clientMutex.Unlock()
+
+	if stats := tb.Stats(); pending > 0 || stats != (TelemetryBufferStats{}) {
+		if tb.logger != nil {
+			tb.logger.Info("telemetry buffer stats", zap.Uint64("reconnects", stats.Reconnects),
+				zap.Uint64("writeFailures", stats.WriteFailures), zap.Uint64("droppedReports", stats.DroppedReports),
+				zap.Int("unsentReports", pending))
+		} else {
+			log.Logf("telemetry buffer stats: %+v unsentReports:%d", stats, pending)
+		}
+	}
 
 	if tb.listener != nil {
 		if tb.logger != nil {
@@ -280,16 +690,35 @@ func (tb *TelemetryBuffer) Close() {
 	tb.connections = make([]net.Conn, 0)
 }
 
-// push - push the report (x) to corresponding slice
-func push(x interface{}) {
-	switch y := x.(type) {
-	case CNIReport:
-		SendAITelemetry(y)
+// pushDeduped pushes the report unless it repeats an error report sent within the dedup window.
+func (tb *TelemetryBuffer) pushDeduped(x interface{}) {
+	report, ok := x.(CNIReport)
+	if !ok || tb.dedup == nil {
+		tb.push(x)
+		return
+	}
 
-	case AIMetric:
-		SendAIMetric(y)
-	default:
-		log.Printf("Push fn: Default case:%+v", y)
+	now := time.Now()
+	for _, r := range tb.dedup.expire(now) {
+		tb.push(r)
+	}
+	if report, ok = tb.dedup.observe(report, now); ok {
+		tb.push(report)
+	}
+}
+
+// push sends the report (x) through the exporter
+func (tb *TelemetryBuffer) push(x interface{}) {
+	var exporter Exporter = AIExporter{}
+	if tb.exporter != nil {
+		exporter = tb.exporter
+	}
+	if err := exporter.Send(x); err != nil {
+		if tb.logger != nil {
+			tb.logger.Error("failed to export telemetry report", zap.Error(err))
+		} else {
+			log.Printf("failed to export telemetry report: %v", err)
+		}
 	}
 }
 
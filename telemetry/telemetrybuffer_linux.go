@@ -16,24 +16,19 @@ const (
 	metadataFile                = "/tmp/azuremetadata.json"
 )
 
-// Dial - try to connect to/create a socket with 'name'
-func (tb *TelemetryBuffer) Dial(name string) (err error) {
-	conn, err := net.Dial("unix", fmt.Sprintf(fdTemplate, name))
-	if err == nil {
-		tb.client = conn
-	}
+// unixTransport connects over a unix domain socket under /var/run.
+type unixTransport struct{}
 
-	return err
+func newTransport() transport {
+	return unixTransport{}
 }
 
-// Listen - try to create and listen on socket with 'name'
-func (tb *TelemetryBuffer) Listen(name string) (err error) {
-	conn, err := net.Listen("unix", fmt.Sprintf(fdTemplate, name))
-	if err == nil {
-		tb.listener = conn
-	}
+func (unixTransport) dial(name string) (net.Conn, error) {
+	return net.Dial("unix", fmt.Sprintf(fdTemplate, name)) //nolint:wrapcheck // callers inspect the raw error
+}
 
-	return err
+func (unixTransport) listen(name string) (net.Listener, error) {
+	return net.Listen("unix", fmt.Sprintf(fdTemplate, name)) //nolint:wrapcheck // callers inspect the raw error
 }
 
 // cleanup - manually remove socket
@@ -113,6 +113,56 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+func TestWriteQueuesAndReconnects(t *testing.T) {
+	_, closeTBServer := createTBServer(t)
+
+	tbClient := NewTelemetryBuffer(nil)
+	err := tbClient.Connect()
+	require.NoError(t, err)
+	defer tbClient.Close()
+
+	// with the server gone, writes fail and reports are held until the queue is full
+	closeTBServer()
+	for i := 0; i < MaxPendingReports+5; i++ {
+		_, err = tbClient.Write([]byte("testdata"))
+		require.Error(t, err)
+	}
+
+	stats := tbClient.Stats()
+	require.GreaterOrEqual(t, stats.WriteFailures, uint64(1))
+	require.Equal(t, uint64(5), stats.DroppedReports)
+	require.Len(t, tbClient.pending, MaxPendingReports)
+
+	// once the server is back and the backoff has elapsed, pending reports are flushed first
+	_, closeTBServer = createTBServer(t)
+	defer closeTBServer()
+	tbClient.clientMutex.Lock()
+	tbClient.nextReconnect = time.Time{}
+	tbClient.clientMutex.Unlock()
+
+	_, err = tbClient.Write([]byte("testdata"))
+	require.NoError(t, err)
+	require.Empty(t, tbClient.pending)
+	require.Equal(t, uint64(1), tbClient.Stats().Reconnects)
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	tb := NewTelemetryBuffer(nil)
+	tb.Cleanup(FdName) //nolint:errcheck // socket may not exist
+
+	require.Error(t, tb.reconnect())
+	require.Equal(t, minReconnectBackoff, tb.backoff)
+	require.ErrorIs(t, tb.reconnect(), errReconnectBackoff)
+
+	for i := 0; i < 20; i++ {
+		tb.nextReconnect = time.Time{}
+		require.Error(t, tb.reconnect())
+	}
+	require.Equal(t, maxReconnectBackoff, tb.backoff)
+	require.LessOrEqual(t, time.Until(tb.nextReconnect), maxReconnectBackoff)
+	require.GreaterOrEqual(t, time.Until(tb.nextReconnect), maxReconnectBackoff/2-time.Second)
+}
+
 func TestReadConfigFile(t *testing.T) {
 	tests := []struct {
 		name     string
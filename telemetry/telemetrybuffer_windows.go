@@ -5,7 +5,9 @@ package telemetry
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"time"
 
 	"github.com/Microsoft/go-winio"
 )
@@ -17,24 +19,40 @@ const (
 	metadataFile                = "azuremetadata.json"
 )
 
-// Dial - try to connect to a named pipe with 'name'
-func (tb *TelemetryBuffer) Dial(name string) (err error) {
-	conn, err := winio.DialPipe(fmt.Sprintf(fdTemplate, name), nil)
-	if err == nil {
-		tb.client = conn
-	}
+const (
+	// pipeSecurityDescriptor grants full access to LocalSystem and the built-in Administrators
+	// group only, so unprivileged processes on the node cannot read or inject reports.
+	pipeSecurityDescriptor = "D:P(A;;GA;;;SY)(A;;GA;;;BA)"
+	pipeBufferSize         = 64 * 1024
+	pipeDialTimeout        = 2 * time.Second
+)
 
-	return err
+// pipeTransport connects over a message mode named pipe. Each report is written as a single
+// message, and reads still split on Delimiter so that clients using byte mode interoperate.
+type pipeTransport struct {
+	securityDescriptor string
+	dialTimeout        time.Duration
 }
 
-// Listen - try to create and listen on named pipe with 'name'
-func (tb *TelemetryBuffer) Listen(name string) (err error) {
-	listener, err := winio.ListenPipe(fmt.Sprintf(fdTemplate, name), nil)
-	if err == nil {
-		tb.listener = listener
+func newTransport() transport {
+	return pipeTransport{
+		securityDescriptor: pipeSecurityDescriptor,
+		dialTimeout:        pipeDialTimeout,
 	}
+}
+
+func (p pipeTransport) dial(name string) (net.Conn, error) {
+	timeout := p.dialTimeout
+	return winio.DialPipe(fmt.Sprintf(fdTemplate, name), &timeout) //nolint:wrapcheck // callers inspect the raw error
+}
 
-	return err
+func (p pipeTransport) listen(name string) (net.Listener, error) {
+	return winio.ListenPipe(fmt.Sprintf(fdTemplate, name), &winio.PipeConfig{ //nolint:wrapcheck // callers inspect the raw error
+		SecurityDescriptor: p.securityDescriptor,
+		MessageMode:        true,
+		InputBufferSize:    pipeBufferSize,
+		OutputBufferSize:   pipeBufferSize,
+	})
 }
 
 // Cleanup - cleanup named pipe
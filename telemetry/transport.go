@@ -0,0 +1,14 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import "net"
+
+// transport is the IPC mechanism between telemetry clients and the telemetry service:
+// a unix domain socket on linux and a named pipe on windows.
+type transport interface {
+	// dial connects to the endpoint called name
+	dial(name string) (net.Conn, error)
+	// listen creates the endpoint called name
+	listen(name string) (net.Listener, error)
+}
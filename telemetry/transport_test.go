@@ -0,0 +1,103 @@
+// Copyright Microsoft. All rights reserved.
+
+package telemetry
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakePipeServer is an in-memory transport standing in for the OS socket or named pipe.
+type fakePipeServer struct {
+	mu     sync.Mutex
+	conns  chan net.Conn
+	closed chan struct{}
+}
+
+func newFakePipeServer() *fakePipeServer {
+	return &fakePipeServer{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (f *fakePipeServer) dial(string) (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case f.conns <- server:
+		return client, nil
+	case <-f.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (f *fakePipeServer) listen(string) (net.Listener, error) {
+	return f, nil
+}
+
+func (f *fakePipeServer) Accept() (net.Conn, error) {
+	select {
+	case c := <-f.conns:
+		return c, nil
+	case <-f.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (f *fakePipeServer) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-f.closed:
+	default:
+		close(f.closed)
+	}
+	return nil
+}
+
+func (f *fakePipeServer) Addr() net.Addr {
+	return &net.UnixAddr{Name: FdName, Net: "fake"}
+}
+
+func TestFakePipeTransportDeliversFramedReports(t *testing.T) {
+	pipe := newFakePipeServer()
+
+	tbServer := NewTelemetryBuffer(nil)
+	tbServer.transport = pipe
+	require.NoError(t, tbServer.StartServer())
+	defer tbServer.Close()
+
+	tbClient := NewTelemetryBuffer(nil)
+	tbClient.transport = pipe
+	require.NoError(t, tbClient.Connect())
+	defer tbClient.Close()
+
+	// two reports written back to back must both be read, even when they arrive in a single read
+	for _, name := range []string{"first", "second"} {
+		b, err := json.Marshal(&CNIReport{Name: name})
+		require.NoError(t, err)
+		_, err = tbClient.Write(b)
+		require.NoError(t, err)
+	}
+
+	for _, want := range []string{"first", "second"} {
+		select {
+		case got := <-tbServer.data:
+			require.Equal(t, want, got.(CNIReport).Name)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for report %s", want)
+		}
+	}
+}
+
+func TestFakePipeTransportDialFailsWhenServerClosed(t *testing.T) {
+	pipe := newFakePipeServer()
+	pipe.Close()
+
+	tbClient := NewTelemetryBuffer(nil)
+	tbClient.transport = pipe
+	require.Error(t, tbClient.Connect())
+	require.False(t, tbClient.Connected)
+}
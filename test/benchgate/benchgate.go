@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	metricTime   = "ns/op"
+	metricBytes  = "B/op"
+	metricAllocs = "allocs/op"
+)
+
+var (
+	errNoBenchmarks    = errors.New("no benchmark results in input")
+	errBenchmarkFailed = errors.New("benchmark run failed")
+
+	resultLine = regexp.MustCompile(`^(Benchmark\S+)\s+\d+\s+(.+)$`)
+	// procsSuffix is the GOMAXPROCS suffix go test appends to benchmark names, which differs between machines.
+	procsSuffix = regexp.MustCompile(`-\d+$`)
+)
+
+// results are the values of each metric of each benchmark, keyed by package qualified benchmark name.
+type results map[string]map[string][]float64
+
+// parse reads the output of go test -bench. A benchmark run with -count has a value per run.
+func parse(r io.Reader) (results, error) {
+	res := results{}
+	pkg := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "pkg: ") {
+			pkg = strings.TrimPrefix(line, "pkg: ")
+			continue
+		}
+		if strings.HasPrefix(line, "FAIL") || strings.HasPrefix(line, "--- FAIL") {
+			return nil, fmt.Errorf("%w: %s", errBenchmarkFailed, line)
+		}
+		match := resultLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		name := procsSuffix.ReplaceAllString(match[1], "")
+		if pkg != "" {
+			name = pkg + "." + name
+		}
+		fields := strings.Fields(match[2])
+		for i := 0; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			if res[name] == nil {
+				res[name] = map[string][]float64{}
+			}
+			res[name][fields[i+1]] = append(res[name][fields[i+1]], value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read benchmark results: %w", err)
+	}
+	if len(res) == 0 {
+		return nil, errNoBenchmarks
+	}
+	return res, nil
+}
+
+// baseline is the median of each metric of each benchmark.
+type baseline map[string]map[string]float64
+
+// medians reduces the runs of each benchmark to their median, which is robust to a noisy run.
+func (res results) medians() baseline {
+	b := baseline{}
+	for name, metrics := range res {
+		b[name] = map[string]float64{}
+		for metric, values := range metrics {
+			b[name][metric] = median(values)
+		}
+	}
+	return b
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2 //nolint:gomnd // mean of the middle values
+	}
+	return sorted[mid]
+}
+
+func readBaseline(path string) (baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline, create it with -update: %w", err)
+	}
+	var b baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	return b, nil
+}
+
+func writeBaseline(path string, b baseline) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baseline: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil { //nolint:gosec // checked in file
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// comparison is the change of a metric of a benchmark against the baseline.
+type comparison struct {
+	name      string
+	metric    string
+	base      float64
+	current   float64
+	threshold float64
+}
+
+// delta is the relative change against the baseline, positive for a regression.
+func (c comparison) delta() float64 {
+	if c.base == 0 {
+		if c.current == 0 {
+			return 0
+		}
+		return 1
+	}
+	return (c.current - c.base) / c.base
+}
+
+func (c comparison) regressed() bool {
+	return c.delta() > c.threshold
+}
+
+// compare compares the metrics of the benchmarks in both the baseline and the current results, with the threshold
+// of each metric. It also returns the benchmarks which are only in one of them.
+func compare(base, current baseline, thresholds map[string]float64) (comparisons []comparison, missing, added []string) {
+	for name, baseMetrics := range base {
+		currentMetrics, ok := current[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		for metric, threshold := range thresholds {
+			baseValue, inBase := baseMetrics[metric]
+			currentValue, inCurrent := currentMetrics[metric]
+			if !inBase || !inCurrent {
+				continue
+			}
+			comparisons = append(comparisons, comparison{name: name, metric: metric, base: baseValue, current: currentValue, threshold: threshold})
+		}
+	}
+	for name := range current {
+		if _, ok := base[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	sort.Slice(comparisons, func(i, j int) bool {
+		if comparisons[i].name != comparisons[j].name {
+			return comparisons[i].name < comparisons[j].name
+		}
+		return comparisons[i].metric < comparisons[j].metric
+	})
+	sort.Strings(missing)
+	sort.Strings(added)
+	return comparisons, missing, added
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const benchOutput = `goos: linux
+goarch: amd64
+pkg: github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkAddPolicies/policies=10/acls=10-8         	    2000	    500000 ns/op	  504351 B/op	    4662 allocs/op
+BenchmarkAddPolicies/policies=10/acls=10-8         	    2000	    700000 ns/op	  504351 B/op	    4662 allocs/op
+BenchmarkAddPolicies/policies=10/acls=10-8         	    2000	    600000 ns/op	  504351 B/op	    4662 allocs/op
+I1015 21:50:09.230920   10830 restore.go:188] running this restore command
+BenchmarkRemovePolicy/policies=1/acls=500          	   30000	     43715 ns/op	   12614 B/op	     144 allocs/op
+PASS
+ok  	github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies	0.696s
+`
+
+const (
+	addPolicies   = "github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies.BenchmarkAddPolicies/policies=10/acls=10"
+	removePolicy  = "github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies.BenchmarkRemovePolicy/policies=1/acls=500"
+	testThreshold = 0.2
+)
+
+func TestParse(t *testing.T) {
+	res, err := parse(strings.NewReader(benchOutput))
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, []float64{500000, 700000, 600000}, res[addPolicies][metricTime])
+
+	medians := res.medians()
+	assert.InDelta(t, 600000, medians[addPolicies][metricTime], 0.1)
+	assert.InDelta(t, 144, medians[removePolicy][metricAllocs], 0.1)
+
+	_, err = parse(strings.NewReader("PASS\n"))
+	require.ErrorIs(t, err, errNoBenchmarks)
+
+	_, err = parse(strings.NewReader(benchOutput + "FAIL\tgithub.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets\t0.1s\n"))
+	require.ErrorIs(t, err, errBenchmarkFailed)
+}
+
+func TestMedian(t *testing.T) {
+	assert.InDelta(t, 2, median([]float64{3, 1, 2}), 0)
+	assert.InDelta(t, 2.5, median([]float64{4, 1, 3, 2}), 0)
+}
+
+func TestCompare(t *testing.T) {
+	base := baseline{
+		addPolicies:  {metricTime: 100, metricAllocs: 10},
+		removePolicy: {metricTime: 100},
+		"gone":       {metricTime: 1},
+	}
+	current := baseline{
+		addPolicies:  {metricTime: 119, metricAllocs: 13},
+		removePolicy: {metricTime: 50},
+		"new":        {metricTime: 1},
+	}
+	comparisons, missing, added := compare(base, current, map[string]float64{metricTime: testThreshold, metricAllocs: testThreshold})
+	require.Len(t, comparisons, 3)
+	assert.Equal(t, []string{"gone"}, missing)
+	assert.Equal(t, []string{"new"}, added)
+
+	regressed := map[string]bool{}
+	for _, c := range comparisons {
+		regressed[c.name+" "+c.metric] = c.regressed()
+	}
+	assert.Equal(t, map[string]bool{
+		addPolicies + " " + metricTime:   false,
+		addPolicies + " " + metricAllocs: true,
+		removePolicy + " " + metricTime:  false,
+	}, regressed)
+
+	assert.True(t, comparison{base: 0, current: 1, threshold: testThreshold}.regressed())
+	assert.False(t, comparison{base: 0, current: 0, threshold: testThreshold}.regressed())
+}
+
+func TestBaselineRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	b := baseline{addPolicies: {metricTime: 100, metricBytes: 2048}}
+	require.NoError(t, writeBaseline(path, b))
+	read, err := readBaseline(path)
+	require.NoError(t, err)
+	assert.Equal(t, b, read)
+
+	_, err = readBaseline(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
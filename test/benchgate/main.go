@@ -0,0 +1,91 @@
+// benchgate compares the output of go test -bench against a stored baseline, and fails when the median of a metric
+// of a benchmark regressed by more than its threshold. Run the benchmarks with -benchmem and a -count of at least 5,
+// so that a single noisy run does not fail the gate:
+//
+//	go test -run '^$' -bench . -benchmem -benchtime 20x -count 5 ./npm/pkg/dataplane/... | go run ./test/benchgate -baseline npm/pkg/dataplane/testdata/benchmarks/linux.json
+//
+// Pass -update to write the results as the new baseline instead, e.g. after an intended change in performance.
+// Time is noisier than memory across machines, so it has a looser threshold.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var (
+		baselinePath  string
+		update        bool
+		timeThreshold float64
+		memThreshold  float64
+	)
+	flag.StringVar(&baselinePath, "baseline", "", "path of the baseline JSON file")
+	flag.BoolVar(&update, "update", false, "write the results as the new baseline instead of comparing against it")
+	flag.Float64Var(&timeThreshold, "time-threshold", 0.25, "maximum relative increase of ns/op")
+	flag.Float64Var(&memThreshold, "mem-threshold", 0.1, "maximum relative increase of B/op and allocs/op")
+	flag.Parse()
+
+	if baselinePath == "" {
+		fmt.Fprintln(os.Stderr, "-baseline is required")
+		return 2 //nolint:gomnd // usage error
+	}
+
+	res, err := parse(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2 //nolint:gomnd // invalid input
+	}
+	current := res.medians()
+
+	if update {
+		if err := writeBaseline(baselinePath, current); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("wrote baseline of %d benchmarks to %s\n", len(current), baselinePath)
+		return 0
+	}
+
+	base, err := readBaseline(baselinePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2 //nolint:gomnd // missing baseline
+	}
+
+	thresholds := map[string]float64{metricTime: timeThreshold, metricBytes: memThreshold, metricAllocs: memThreshold}
+	comparisons, missing, added := compare(base, current, thresholds)
+
+	regressions := 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd // padding
+	fmt.Fprintln(tw, "BENCHMARK\tMETRIC\tBASELINE\tCURRENT\tDELTA\t")
+	for _, c := range comparisons {
+		status := ""
+		if c.regressed() {
+			status = fmt.Sprintf("REGRESSION (> %+.0f%%)", c.threshold*100) //nolint:gomnd // percent
+			regressions++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%.0f\t%+.1f%%\t%s\n", c.name, c.metric, c.base, c.current, c.delta()*100, status) //nolint:gomnd // percent
+	}
+	_ = tw.Flush()
+
+	for _, name := range missing {
+		fmt.Printf("warning: %s is in the baseline but was not run\n", name)
+	}
+	for _, name := range added {
+		fmt.Printf("warning: %s is not in the baseline, update it with -update\n", name)
+	}
+
+	if regressions > 0 {
+		fmt.Fprintf(os.Stderr, "%d metrics regressed beyond their threshold\n", regressions)
+		return 1
+	}
+	fmt.Println("no regressions")
+	return 0
+}
@@ -0,0 +1,90 @@
+// Package chaos injects faults into the agents and dataplane of a cluster and measures how long the agents take
+// to recover from them, so that e2e suites can assert that CNS and NPM self-heal.
+// Faults injected on a node are run through the privileged daemonset, which must be running on that node.
+package chaos
+
+import (
+	"context"
+	"log"
+	"time"
+
+	acnk8s "github.com/Azure/azure-container-networking/test/internal/kubernetes"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// RecoveryPollInterval is the interval at which recovery is checked, and so the precision of recovery times.
+	RecoveryPollInterval = 1 * time.Second
+)
+
+// ErrNotRecovered is returned when an agent does not recover from a fault in time.
+var ErrNotRecovered = errors.New("not recovered from fault")
+
+// Fault is a fault which was injected into the cluster.
+type Fault struct {
+	Name string
+	// Node is the node the fault was injected on, if any.
+	Node       string
+	InjectedAt time.Time
+	// revert undoes faults which the agents are not expected to recover from by themselves.
+	revert func(context.Context) error
+}
+
+// Revert undoes the fault, for faults which persist until they are reverted.
+// It is a no-op for faults which the agents recover from by themselves.
+func (f *Fault) Revert(ctx context.Context) error {
+	if f.revert == nil {
+		return nil
+	}
+	return errors.Wrapf(f.revert(ctx), "failed to revert fault %s", f.Name)
+}
+
+// Recovery is the outcome of waiting for recovery from a Fault.
+type Recovery struct {
+	Fault       *Fault
+	RecoveredAt time.Time
+}
+
+// Duration returns the time between injecting the fault and observing the recovery.
+func (r Recovery) Duration() time.Duration {
+	return r.RecoveredAt.Sub(r.Fault.InjectedAt)
+}
+
+// RecoveredWithin returns an error if the recovery took longer than the max.
+func (r Recovery) RecoveredWithin(max time.Duration) error {
+	if d := r.Duration(); d > max {
+		return errors.Errorf("recovery from fault %s took %s, expected at most %s", r.Fault.Name, d, max)
+	}
+	return nil
+}
+
+// CheckFunc returns nil once the cluster has recovered from a fault.
+type CheckFunc func(context.Context) error
+
+// WaitForRecovery polls the check until it passes or the timeout expires, and returns the time it took to recover.
+func WaitForRecovery(ctx context.Context, fault *Fault, timeout time.Duration, check CheckFunc) (Recovery, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(RecoveryPollInterval)
+	defer ticker.Stop()
+	for {
+		err := check(ctx)
+		if err == nil {
+			recovery := Recovery{Fault: fault, RecoveredAt: time.Now()}
+			log.Printf("recovered from fault %s in %s", fault.Name, recovery.Duration())
+			return recovery, nil
+		}
+		select {
+		case <-ctx.Done():
+			return Recovery{Fault: fault}, errors.Wrapf(ErrNotRecovered, "fault %s after %s: %v", fault.Name, timeout, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// execOnNode runs the command on the node through the privileged daemonset.
+func execOnNode(ctx context.Context, clientset *kubernetes.Clientset, config *rest.Config, nodeName string, cmd []string) ([]byte, error) {
+	return acnk8s.ExecCmdOnNode(ctx, clientset, nodeName, cmd, config) //nolint:wrapcheck // wrapped with the node name
+}
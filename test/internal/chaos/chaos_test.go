@@ -0,0 +1,48 @@
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForRecovery(t *testing.T) {
+	fault := &Fault{Name: "test", InjectedAt: time.Now()}
+	checks := 0
+	recovery, err := WaitForRecovery(context.Background(), fault, 10*time.Second, func(context.Context) error {
+		checks++
+		if checks < 2 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, checks)
+	assert.GreaterOrEqual(t, recovery.Duration(), RecoveryPollInterval)
+	require.NoError(t, recovery.RecoveredWithin(10*time.Second))
+	require.Error(t, recovery.RecoveredWithin(time.Millisecond))
+}
+
+func TestWaitForRecoveryTimeout(t *testing.T) {
+	fault := &Fault{Name: "test", InjectedAt: time.Now()}
+	_, err := WaitForRecovery(context.Background(), fault, 10*time.Millisecond, func(context.Context) error {
+		return errors.New("never")
+	})
+	require.ErrorIs(t, err, ErrNotRecovered)
+}
+
+func TestFaultRevert(t *testing.T) {
+	require.NoError(t, (&Fault{Name: "self-healing"}).Revert(context.Background()))
+
+	reverted := false
+	fault := &Fault{Name: "persistent", revert: func(context.Context) error {
+		reverted = true
+		return nil
+	}}
+	require.NoError(t, fault.Revert(context.Background()))
+	assert.True(t, reverted)
+}
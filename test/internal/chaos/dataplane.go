@@ -0,0 +1,113 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// WireserverIP is the address of the Azure wireserver, which CNS and CNI call on every node.
+	WireserverIP = "168.63.129.16"
+	// NPMChain is the iptables chain NPM jumps to from the FORWARD chain on Linux.
+	NPMChain = "AZURE-NPM"
+)
+
+// FlushIPTablesChain deletes all rules in the iptables chain on the Linux node. Check recovery with
+// IPTablesChainHasRules.
+func FlushIPTablesChain(ctx context.Context, clientset *kubernetes.Clientset, config *rest.Config, nodeName, table, chain string) (*Fault, error) {
+	fault := &Fault{Name: fmt.Sprintf("flush iptables chain %s/%s", table, chain), Node: nodeName, InjectedAt: time.Now()}
+	cmd := []string{"bash", "-c", fmt.Sprintf("iptables -w 60 -t %s -F %s", table, chain)}
+	if _, err := execOnNode(ctx, clientset, config, nodeName, cmd); err != nil {
+		return nil, errors.Wrapf(err, "failed to flush iptables chain %s/%s", table, chain)
+	}
+	return fault, nil
+}
+
+// IPTablesChainHasRules checks that the iptables chain on the Linux node has at least minRules rules.
+func IPTablesChainHasRules(clientset *kubernetes.Clientset, config *rest.Config, nodeName, table, chain string, minRules int) CheckFunc {
+	// iptables -S prints the chain declaration followed by one line per rule.
+	cmd := []string{"bash", "-c", fmt.Sprintf("iptables -w 60 -t %s -S %s | grep -c -- '^-A'", table, chain)}
+	return func(ctx context.Context) error {
+		out, err := execOnNode(ctx, clientset, config, nodeName, cmd)
+		if err != nil {
+			// grep exits with 1 when there are no rules.
+			return errors.Wrapf(err, "iptables chain %s/%s has no rules", table, chain)
+		}
+		rules, err := strconv.Atoi(strings.TrimSpace(string(out)))
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse rule count %q", out)
+		}
+		if rules < minRules {
+			return errors.Errorf("iptables chain %s/%s has %d rules, expected at least %d", table, chain, rules, minRules)
+		}
+		return nil
+	}
+}
+
+// DeleteHNSPolicyLists deletes all HNS policy lists on the Windows node, which are the load balancer policies
+// programmed by kube-proxy. Check recovery with HNSPolicyListsPresent.
+func DeleteHNSPolicyLists(ctx context.Context, clientset *kubernetes.Clientset, config *rest.Config, nodeName string) (*Fault, error) {
+	fault := &Fault{Name: "delete hns policy lists", Node: nodeName, InjectedAt: time.Now()}
+	cmd := []string{"powershell", "-c", "Get-HnsPolicyList | Remove-HnsPolicyList"}
+	if _, err := execOnNode(ctx, clientset, config, nodeName, cmd); err != nil {
+		return nil, errors.Wrap(err, "failed to delete hns policy lists")
+	}
+	return fault, nil
+}
+
+// HNSPolicyListsPresent checks that the Windows node has at least minPolicyLists HNS policy lists.
+func HNSPolicyListsPresent(clientset *kubernetes.Clientset, config *rest.Config, nodeName string, minPolicyLists int) CheckFunc {
+	cmd := []string{"powershell", "-c", "@(Get-HnsPolicyList).Count"}
+	return func(ctx context.Context) error {
+		out, err := execOnNode(ctx, clientset, config, nodeName, cmd)
+		if err != nil {
+			return err
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(string(out)))
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse policy list count %q", out)
+		}
+		if count < minPolicyLists {
+			return errors.Errorf("node has %d hns policy lists, expected at least %d", count, minPolicyLists)
+		}
+		return nil
+	}
+}
+
+// InjectWireserverLatency delays all traffic from the Linux node to the wireserver by the latency with a netem
+// qdisc on the interface routing to it. The latency persists until the returned Fault is reverted.
+// Latency can only be injected on Linux nodes.
+func InjectWireserverLatency(ctx context.Context, clientset *kubernetes.Clientset, config *rest.Config, nodeName string, latency time.Duration) (*Fault, error) {
+	dev := fmt.Sprintf("$(ip route get %s | grep -o 'dev [^ ]*' | cut -d' ' -f2)", WireserverIP)
+	inject := strings.Join([]string{
+		"set -e",
+		"dev=" + dev,
+		"tc qdisc replace dev $dev root handle 1: prio",
+		fmt.Sprintf("tc qdisc add dev $dev parent 1:3 handle 30: netem delay %dms", latency.Milliseconds()),
+		fmt.Sprintf("tc filter add dev $dev protocol ip parent 1:0 prio 3 u32 match ip dst %s/32 flowid 1:3", WireserverIP),
+	}, "\n")
+	revert := "tc qdisc del dev " + dev + " root"
+
+	fault := &Fault{
+		Name:       fmt.Sprintf("wireserver latency %s", latency),
+		Node:       nodeName,
+		InjectedAt: time.Now(),
+		revert: func(ctx context.Context) error {
+			_, err := execOnNode(ctx, clientset, config, nodeName, []string{"bash", "-c", revert})
+			return err
+		},
+	}
+	if _, err := execOnNode(ctx, clientset, config, nodeName, []string{"bash", "-c", inject}); err != nil {
+		// clean up a partially applied qdisc.
+		_ = fault.Revert(ctx)
+		return nil, errors.Wrap(err, "failed to inject wireserver latency")
+	}
+	return fault, nil
+}
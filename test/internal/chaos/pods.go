@@ -0,0 +1,102 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	acnk8s "github.com/Azure/azure-container-networking/test/internal/kubernetes"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	AgentNamespace          = "kube-system"
+	CNSLabelSelector        = "k8s-app=azure-cns"
+	CNSWindowsLabelSelector = "k8s-app=azure-cns-win"
+	NPMLabelSelector        = "k8s-app=azure-npm"
+)
+
+// KillPods force deletes the pods matching the label selector, on the node if nodeName is set, so that their
+// controller has to replace them. Check recovery with PodsReplaced.
+func KillPods(ctx context.Context, clientset *kubernetes.Clientset, namespace, labelSelector, nodeName string) (*Fault, error) {
+	pods, err := listPods(ctx, clientset, namespace, labelSelector, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) == 0 {
+		return nil, errors.Errorf("no pods with label selector %s to kill", labelSelector)
+	}
+	fault := &Fault{Name: fmt.Sprintf("kill pods %s", labelSelector), Node: nodeName, InjectedAt: time.Now()}
+	gracePeriod := int64(0)
+	for index := range pods.Items {
+		pod := pods.Items[index]
+		if err := clientset.CoreV1().Pods(namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod}); err != nil {
+			return nil, errors.Wrapf(err, "failed to delete pod %s/%s", namespace, pod.Name)
+		}
+	}
+	return fault, nil
+}
+
+// KillCNS force deletes the CNS pods, on the node if nodeName is set.
+func KillCNS(ctx context.Context, clientset *kubernetes.Clientset, nodeOS, nodeName string) (*Fault, error) {
+	return KillPods(ctx, clientset, AgentNamespace, cnsLabelSelector(nodeOS), nodeName)
+}
+
+// KillNPM force deletes the NPM pods, on the node if nodeName is set.
+func KillNPM(ctx context.Context, clientset *kubernetes.Clientset, nodeName string) (*Fault, error) {
+	return KillPods(ctx, clientset, AgentNamespace, NPMLabelSelector, nodeName)
+}
+
+// RestartDaemonset triggers a rolling restart of the daemonset. Check recovery with PodsReplaced.
+func RestartDaemonset(ctx context.Context, clientset *kubernetes.Clientset, namespace, daemonsetName string) (*Fault, error) {
+	fault := &Fault{Name: fmt.Sprintf("restart daemonset %s/%s", namespace, daemonsetName), InjectedAt: time.Now()}
+	if err := acnk8s.MustRestartDaemonset(ctx, clientset, namespace, daemonsetName); err != nil {
+		return nil, errors.Wrapf(err, "failed to restart daemonset %s/%s", namespace, daemonsetName)
+	}
+	return fault, nil
+}
+
+// PodsReplaced checks that the pods matching the label selector, on the node if nodeName is set, were all created
+// after the fault was injected and are ready.
+func PodsReplaced(clientset *kubernetes.Clientset, namespace, labelSelector, nodeName string, fault *Fault) CheckFunc {
+	return func(ctx context.Context) error {
+		pods, err := listPods(ctx, clientset, namespace, labelSelector, nodeName)
+		if err != nil {
+			return err
+		}
+		if len(pods.Items) == 0 {
+			return errors.Errorf("no pods with label selector %s", labelSelector)
+		}
+		// creation timestamps have second precision, so allow for a pod created in the same second as the fault.
+		injectedAt := fault.InjectedAt.Truncate(time.Second)
+		for index := range pods.Items {
+			pod := &pods.Items[index]
+			if pod.CreationTimestamp.Time.Before(injectedAt) {
+				return errors.Errorf("pod %s/%s has not been replaced", pod.Namespace, pod.Name)
+			}
+			if !acnk8s.IsPodReady(pod) {
+				return errors.Errorf("pod %s/%s is not ready", pod.Namespace, pod.Name)
+			}
+		}
+		return nil
+	}
+}
+
+func cnsLabelSelector(nodeOS string) string {
+	if nodeOS == string(corev1.Windows) {
+		return CNSWindowsLabelSelector
+	}
+	return CNSLabelSelector
+}
+
+func listPods(ctx context.Context, clientset *kubernetes.Clientset, namespace, labelSelector, nodeName string) (*corev1.PodList, error) {
+	if nodeName != "" {
+		pods, err := acnk8s.GetPodsByNode(ctx, clientset, namespace, labelSelector, nodeName)
+		return pods, errors.Wrap(err, "failed to list pods")
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	return pods, errors.Wrapf(err, "failed to list pods with label selector %s", labelSelector)
+}
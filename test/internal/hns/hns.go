@@ -0,0 +1,157 @@
+// Package hns reads the HNS state of Windows nodes and validates that the networks, endpoints, SetPolicies and ACLs
+// which CNI and NPM program for a workload exist, so that Windows datapath e2e tests can verify the state of the node
+// rather than only pod connectivity.
+// The state is read with the HNS powershell module through the privileged daemonset, which must be running on the node.
+package hns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	acnk8s "github.com/Azure/azure-container-networking/test/internal/kubernetes"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// PolicyTypeACL is the type of the endpoint policies programmed by NPM for network policies.
+	PolicyTypeACL = "ACL"
+	// PolicyTypeSetPolicy is the type of the network policies programmed by NPM for IP sets.
+	PolicyTypeSetPolicy = "SetPolicy"
+
+	// networkStateCreated is the state of a network which HNS has finished creating.
+	networkStateCreated = 1
+)
+
+var (
+	getNetworksCmd  = []string{"powershell", "-c", "Get-HnsNetwork | ConvertTo-Json -Depth 10"}
+	getEndpointsCmd = []string{"powershell", "-c", "Get-HnsEndpoint | ConvertTo-Json -Depth 10"}
+)
+
+// Policy is a network or endpoint policy. Only the fields of the ACL and SetPolicy policies are decoded.
+type Policy struct {
+	Type string `json:"Type"`
+	ID   string `json:"Id"`
+
+	// SetPolicy fields. Values is the comma separated list of the members of the set.
+	Name   string `json:"Name"`
+	Values string `json:"Values"`
+
+	// ACL fields.
+	Action          string `json:"Action"`
+	Direction       string `json:"Direction"`
+	Protocols       string `json:"Protocols"`
+	LocalAddresses  string `json:"LocalAddresses"`
+	RemoteAddresses string `json:"RemoteAddresses"`
+	LocalPorts      string `json:"LocalPorts"`
+	RemotePorts     string `json:"RemotePorts"`
+	Priority        uint16 `json:"Priority"`
+}
+
+// Members returns the members of a SetPolicy.
+func (p Policy) Members() []string {
+	if p.Values == "" {
+		return nil
+	}
+	return strings.Split(p.Values, ",")
+}
+
+// Network is an HNS network.
+type Network struct {
+	ID       string   `json:"ID"`
+	Name     string   `json:"Name"`
+	Type     string   `json:"Type"`
+	State    int      `json:"State"`
+	Policies []Policy `json:"Policies"`
+}
+
+// Endpoint is an HNS endpoint.
+type Endpoint struct {
+	ID                 string   `json:"ID"`
+	Name               string   `json:"Name"`
+	VirtualNetworkName string   `json:"VirtualNetworkName"`
+	IPAddress          string   `json:"IPAddress"`
+	IPv6Address        string   `json:"IPv6Address"`
+	MacAddress         string   `json:"MacAddress"`
+	IsRemoteEndpoint   bool     `json:"IsRemoteEndpoint"`
+	Policies           []Policy `json:"Policies"`
+}
+
+// State is the HNS state of a node.
+type State struct {
+	Node      string
+	Networks  []Network
+	Endpoints []Endpoint
+}
+
+// GetState reads the HNS networks and endpoints of the Windows node.
+func GetState(ctx context.Context, clientset *kubernetes.Clientset, config *rest.Config, nodeName string) (*State, error) {
+	out, err := acnk8s.ExecCmdOnNode(ctx, clientset, nodeName, getNetworksCmd, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get hns networks")
+	}
+	var networks []Network
+	if err = unmarshalList(out, &networks); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal hns networks of node %s", nodeName)
+	}
+	out, err = acnk8s.ExecCmdOnNode(ctx, clientset, nodeName, getEndpointsCmd, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get hns endpoints")
+	}
+	var endpoints []Endpoint
+	if err = unmarshalList(out, &endpoints); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal hns endpoints of node %s", nodeName)
+	}
+	return &State{Node: nodeName, Networks: networks, Endpoints: endpoints}, nil
+}
+
+// unmarshalList unmarshals the output of ConvertTo-Json into the slice pointed to by v.
+// ConvertTo-Json prints a single object rather than an array when there is one item, and nothing when there are none.
+func unmarshalList(out []byte, v interface{}) error {
+	out = bytes.TrimSpace(out)
+	switch {
+	case len(out) == 0:
+		return nil
+	case out[0] == '{':
+		out = append(append([]byte{'['}, out...), ']')
+	case out[0] != '[':
+		return errors.Errorf("json is malformed and does not start with an object or array: %q", out)
+	}
+	return errors.Wrap(json.Unmarshal(out, v), "failed to unmarshal json")
+}
+
+// Network returns the network with the name.
+func (s *State) Network(name string) (*Network, bool) {
+	for i := range s.Networks {
+		if s.Networks[i].Name == name {
+			return &s.Networks[i], true
+		}
+	}
+	return nil, false
+}
+
+// LocalEndpoint returns the local endpoint with the IPv4 or IPv6 address. Remote endpoints of other nodes are ignored.
+func (s *State) LocalEndpoint(ip string) (*Endpoint, bool) {
+	for i := range s.Endpoints {
+		ep := &s.Endpoints[i]
+		if !ep.IsRemoteEndpoint && (ep.IPAddress == ip || ep.IPv6Address == ip) {
+			return ep, true
+		}
+	}
+	return nil, false
+}
+
+// PoliciesOfType returns the policies of the type, which is compared case insensitively since HNS versions differ
+// in the case they report.
+func PoliciesOfType(policies []Policy, policyType string) []Policy {
+	var matching []Policy
+	for _, p := range policies {
+		if strings.EqualFold(p.Type, policyType) {
+			matching = append(matching, p)
+		}
+	}
+	return matching
+}
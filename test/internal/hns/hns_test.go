@@ -0,0 +1,109 @@
+package hns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	networksJSON = `{
+    "ID": "5D8E2C2A-1F36-4C2A-8D3B-6F3A2C1B0E11",
+    "Name": "azure",
+    "Type": "L2Bridge",
+    "State": 1,
+    "Policies": [
+        {"Type": "SetPolicy", "Id": "azure-npm-1234", "Name": "ns-x", "Values": "10.240.0.10,10.240.0.11"},
+        {"Type": "SetPolicy", "Id": "azure-npm-5678", "Name": "podlabel-app:web", "Values": "10.240.0.10"}
+    ]
+}`
+
+	endpointsJSON = `[
+    {
+        "ID": "0A1B2C3D-0000-0000-0000-000000000001",
+        "VirtualNetworkName": "azure",
+        "IPAddress": "10.240.0.10",
+        "MacAddress": "00-15-5D-00-00-01",
+        "Policies": [
+            {"Type": "ACL", "Id": "azure-acl-x-allow-web", "Action": "Allow", "Direction": "In", "Priority": 222},
+            {"Type": "OutBoundNAT", "ExceptionList": ["10.0.0.0/8"]}
+        ]
+    },
+    {
+        "ID": "0A1B2C3D-0000-0000-0000-000000000002",
+        "VirtualNetworkName": "azure",
+        "IPAddress": "10.240.0.12",
+        "IsRemoteEndpoint": true
+    }
+]`
+)
+
+func testState(t *testing.T) *State {
+	t.Helper()
+	state := &State{Node: "node"}
+	require.NoError(t, unmarshalList([]byte(networksJSON), &state.Networks))
+	require.NoError(t, unmarshalList([]byte(endpointsJSON), &state.Endpoints))
+	return state
+}
+
+func TestUnmarshalList(t *testing.T) {
+	var endpoints []Endpoint
+	require.NoError(t, unmarshalList([]byte("\r\n"), &endpoints))
+	assert.Empty(t, endpoints)
+
+	require.Error(t, unmarshalList([]byte("Get-HnsEndpoint : not found"), &endpoints))
+
+	state := testState(t)
+	require.Len(t, state.Networks, 1)
+	assert.Equal(t, []string{"10.240.0.10", "10.240.0.11"}, state.Networks[0].Policies[0].Members())
+	require.Len(t, state.Endpoints, 2)
+	assert.Len(t, PoliciesOfType(state.Endpoints[0].Policies, "acl"), 1)
+}
+
+func TestValidate(t *testing.T) {
+	state := testState(t)
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Status:     corev1.PodStatus{PodIPs: []corev1.PodIP{{IP: "10.240.0.10"}}},
+	}
+	valid := Workload{
+		Network:     "azure",
+		SetPolicies: []ExpectedSetPolicy{{Name: "ns-x", Members: []string{"10.240.0.10"}}},
+		ACLs:        []ExpectedACL{{ID: "azure-acl-x-allow-web", Action: "allow", Direction: "in"}},
+	}
+	require.NoError(t, valid.validate(state, []corev1.Pod{pod}))
+
+	tests := []struct {
+		name   string
+		mutate func(w *Workload, pod *corev1.Pod)
+	}{
+		{"missing network", func(w *Workload, _ *corev1.Pod) { w.Network = "nat" }},
+		{"missing set", func(w *Workload, _ *corev1.Pod) { w.SetPolicies[0].Name = "ns-y" }},
+		{"missing set member", func(w *Workload, _ *corev1.Pod) { w.SetPolicies[0].Members = []string{"10.240.0.12"} }},
+		{"missing acl", func(w *Workload, _ *corev1.Pod) { w.ACLs[0].ID = "azure-acl-x-deny-all" }},
+		{"acl with other action", func(w *Workload, _ *corev1.Pod) { w.ACLs[0].Action = "Block" }},
+		{"missing endpoint", func(_ *Workload, pod *corev1.Pod) { pod.Status.PodIPs[0].IP = "10.240.0.13" }},
+		{"remote endpoint", func(_ *Workload, pod *corev1.Pod) { pod.Status.PodIPs[0].IP = "10.240.0.12" }},
+		{"pod without ip", func(_ *Workload, pod *corev1.Pod) { pod.Status.PodIPs = nil }},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := valid
+			w.SetPolicies = []ExpectedSetPolicy{valid.SetPolicies[0]}
+			w.ACLs = []ExpectedACL{valid.ACLs[0]}
+			p := *pod.DeepCopy()
+			tt.mutate(&w, &p)
+			require.Error(t, w.validate(state, []corev1.Pod{p}))
+		})
+	}
+}
+
+func TestValidateNetworkState(t *testing.T) {
+	state := testState(t)
+	state.Networks[0].State = 2
+	require.Error(t, ValidateNetwork(state, "azure"))
+}
@@ -0,0 +1,168 @@
+package hns
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// ExpectedSetPolicy is a SetPolicy which must exist on the network, identified by its name.
+type ExpectedSetPolicy struct {
+	Name string
+	// Members must all be members of the set. The set may have other members.
+	Members []string
+}
+
+// ExpectedACL is an ACL which must exist on the endpoint of every pod of the workload. NPM gives all ACLs of a network
+// policy the ID azure-acl-<namespace>-<name>. Action and Direction are only checked when they are set.
+type ExpectedACL struct {
+	ID        string
+	Action    string
+	Direction string
+}
+
+// Workload is the HNS state expected for a set of pods.
+type Workload struct {
+	Namespace     string
+	LabelSelector string
+	// Network is the name of the HNS network the endpoints of the pods are on.
+	Network     string
+	SetPolicies []ExpectedSetPolicy
+	ACLs        []ExpectedACL
+}
+
+// ValidateWorkload checks that the network, an endpoint for every pod IP, and the expected SetPolicies and ACLs exist
+// in HNS on every node running pods of the workload. The pods must all be running on Windows nodes.
+func ValidateWorkload(ctx context.Context, clientset *kubernetes.Clientset, config *rest.Config, w Workload) error {
+	pods, err := clientset.CoreV1().Pods(w.Namespace).List(ctx, metav1.ListOptions{LabelSelector: w.LabelSelector})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list pods %s in namespace %s", w.LabelSelector, w.Namespace)
+	}
+	if len(pods.Items) == 0 {
+		return errors.Errorf("there are no pods %s in namespace %s", w.LabelSelector, w.Namespace)
+	}
+	podsByNode := map[string][]corev1.Pod{}
+	for i := range pods.Items {
+		pod := pods.Items[i]
+		if pod.Spec.NodeName == "" {
+			return errors.Errorf("pod %s is not scheduled", pod.Name)
+		}
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+	for nodeName, nodePods := range podsByNode {
+		state, err := GetState(ctx, clientset, config, nodeName)
+		if err != nil {
+			return err
+		}
+		if err := w.validate(state, nodePods); err != nil {
+			return errors.Wrapf(err, "node %s", nodeName)
+		}
+	}
+	return nil
+}
+
+// validate checks the HNS state of a node running the pods of the workload.
+func (w Workload) validate(state *State, pods []corev1.Pod) error {
+	if err := ValidateNetwork(state, w.Network); err != nil {
+		return err
+	}
+	network, _ := state.Network(w.Network)
+	if err := ValidateSetPolicies(network, w.SetPolicies); err != nil {
+		return err
+	}
+	for i := range pods {
+		if len(pods[i].Status.PodIPs) == 0 {
+			return errors.Errorf("pod %s has no IP", pods[i].Name)
+		}
+		for _, ip := range pods[i].Status.PodIPs {
+			ep, err := ValidateEndpoint(state, w.Network, ip.IP)
+			if err != nil {
+				return errors.Wrapf(err, "pod %s", pods[i].Name)
+			}
+			if err := ValidateACLs(ep, w.ACLs); err != nil {
+				return errors.Wrapf(err, "pod %s", pods[i].Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateNetwork checks that the network exists and HNS has finished creating it.
+func ValidateNetwork(state *State, name string) error {
+	network, ok := state.Network(name)
+	if !ok {
+		return errors.Errorf("hns network %s does not exist", name)
+	}
+	if network.State != networkStateCreated {
+		return errors.Errorf("hns network %s is in state %d, expected %d", name, network.State, networkStateCreated)
+	}
+	return nil
+}
+
+// ValidateEndpoint checks that a local endpoint with the IP exists on the network, and returns it.
+func ValidateEndpoint(state *State, networkName, ip string) (*Endpoint, error) {
+	ep, ok := state.LocalEndpoint(ip)
+	if !ok {
+		return nil, errors.Errorf("hns endpoint with ip %s does not exist", ip)
+	}
+	if ep.VirtualNetworkName != networkName {
+		return nil, errors.Errorf("hns endpoint %s with ip %s is on network %s, expected %s", ep.ID, ip, ep.VirtualNetworkName, networkName)
+	}
+	return ep, nil
+}
+
+// ValidateSetPolicies checks that the network has the expected SetPolicies with at least the expected members.
+func ValidateSetPolicies(network *Network, expected []ExpectedSetPolicy) error {
+	sets := map[string]Policy{}
+	for _, p := range PoliciesOfType(network.Policies, PolicyTypeSetPolicy) {
+		sets[p.Name] = p
+	}
+	for _, want := range expected {
+		set, ok := sets[want.Name]
+		if !ok {
+			return errors.Errorf("hns network %s has no SetPolicy %s", network.Name, want.Name)
+		}
+		members := map[string]struct{}{}
+		for _, m := range set.Members() {
+			members[m] = struct{}{}
+		}
+		for _, m := range want.Members {
+			if _, ok := members[m]; !ok {
+				return errors.Errorf("SetPolicy %s does not have member %s, has %s", want.Name, m, set.Values)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateACLs checks that the endpoint has an ACL matching every expected ACL.
+func ValidateACLs(ep *Endpoint, expected []ExpectedACL) error {
+	acls := PoliciesOfType(ep.Policies, PolicyTypeACL)
+	for _, want := range expected {
+		if !hasACL(acls, want) {
+			return errors.Errorf("hns endpoint %s has no ACL %+v", ep.ID, want)
+		}
+	}
+	return nil
+}
+
+func hasACL(acls []Policy, want ExpectedACL) bool {
+	for _, acl := range acls {
+		if acl.ID != want.ID {
+			continue
+		}
+		if want.Action != "" && !strings.EqualFold(acl.Action, want.Action) {
+			continue
+		}
+		if want.Direction != "" && !strings.EqualFold(acl.Direction, want.Direction) {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package kubernetes
+
+import (
+	"context"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/test/internal/retry"
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	k8sRetry "k8s.io/client-go/util/retry"
+)
+
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// RestoreFunc undoes a change made to a node. Only the first call has an effect.
+type RestoreFunc func(ctx context.Context) error
+
+func onceRestore(restore RestoreFunc) RestoreFunc {
+	var once sync.Once
+	var err error
+	return func(ctx context.Context) error {
+		once.Do(func() { err = restore(ctx) })
+		return err
+	}
+}
+
+// RestoreOnCleanup registers the restore function to run when the test and its subtests complete,
+// including when they fail, and fails the test if the node could not be restored.
+func RestoreOnCleanup(t testing.TB, restore RestoreFunc) {
+	t.Helper()
+	t.Cleanup(func() {
+		if err := restore(context.Background()); err != nil {
+			t.Errorf("failed to restore node: %v", err)
+		}
+	})
+}
+
+// updateNode applies the mutation to the latest version of the node, retrying on conflicts.
+func updateNode(ctx context.Context, nodes corev1.NodeInterface, nodeName string, mutate func(*apiv1.Node)) error {
+	err := k8sRetry.RetryOnConflict(k8sRetry.DefaultRetry, func() error {
+		node, err := nodes.Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		mutate(node)
+		_, err = nodes.Update(ctx, node, metav1.UpdateOptions{})
+		return err //nolint:wrapcheck // wrapped below
+	})
+	return errors.Wrapf(err, "failed to update node %s", nodeName)
+}
+
+// CordonNode marks the node unschedulable. The returned RestoreFunc restores its previous schedulability.
+func CordonNode(ctx context.Context, nodes corev1.NodeInterface, nodeName string) (RestoreFunc, error) {
+	var wasUnschedulable bool
+	err := updateNode(ctx, nodes, nodeName, func(node *apiv1.Node) {
+		wasUnschedulable = node.Spec.Unschedulable
+		node.Spec.Unschedulable = true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return onceRestore(func(ctx context.Context) error {
+		return updateNode(ctx, nodes, nodeName, func(node *apiv1.Node) {
+			node.Spec.Unschedulable = wasUnschedulable
+		})
+	}), nil
+}
+
+// TaintNode adds the taint to the node, replacing any taint with the same key and effect.
+// The returned RestoreFunc removes the taint and puts back the replaced taint, if any.
+func TaintNode(ctx context.Context, nodes corev1.NodeInterface, nodeName string, taint apiv1.Taint) (RestoreFunc, error) {
+	var replaced *apiv1.Taint
+	err := updateNode(ctx, nodes, nodeName, func(node *apiv1.Node) {
+		replaced = nil
+		taints := []apiv1.Taint{taint}
+		for i := range node.Spec.Taints {
+			if node.Spec.Taints[i].MatchTaint(&taint) {
+				replaced = node.Spec.Taints[i].DeepCopy()
+				continue
+			}
+			taints = append(taints, node.Spec.Taints[i])
+		}
+		node.Spec.Taints = taints
+	})
+	if err != nil {
+		return nil, err
+	}
+	return onceRestore(func(ctx context.Context) error {
+		return updateNode(ctx, nodes, nodeName, func(node *apiv1.Node) {
+			var taints []apiv1.Taint
+			for i := range node.Spec.Taints {
+				if !node.Spec.Taints[i].MatchTaint(&taint) {
+					taints = append(taints, node.Spec.Taints[i])
+				}
+			}
+			if replaced != nil {
+				taints = append(taints, *replaced)
+			}
+			node.Spec.Taints = taints
+		})
+	}), nil
+}
+
+// LabelNode adds or replaces labels on the node. The returned RestoreFunc puts back the replaced values
+// and removes the labels which were added.
+func LabelNode(ctx context.Context, nodes corev1.NodeInterface, nodeName string, labels map[string]string) (RestoreFunc, error) {
+	previous := map[string]*string{}
+	err := updateNode(ctx, nodes, nodeName, func(node *apiv1.Node) {
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+		for key, value := range labels {
+			previous[key] = nil
+			if old, ok := node.Labels[key]; ok {
+				old := old
+				previous[key] = &old
+			}
+			node.Labels[key] = value
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return onceRestore(func(ctx context.Context) error {
+		return updateNode(ctx, nodes, nodeName, func(node *apiv1.Node) {
+			for key, old := range previous {
+				if old == nil {
+					delete(node.Labels, key)
+					continue
+				}
+				node.Labels[key] = *old
+			}
+		})
+	}), nil
+}
+
+// DrainNode cordons the node and evicts all pods from it, except for DaemonSet and static pods, then waits for the
+// evicted pods to be deleted. Evictions blocked by a PodDisruptionBudget are retried.
+// The returned RestoreFunc uncordons the node, but does not bring the evicted pods back.
+func DrainNode(ctx context.Context, clientset *kubernetes.Clientset, nodeName string) (RestoreFunc, error) {
+	restore, err := CordonNode(ctx, clientset.CoreV1().Nodes(), nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+	if err != nil {
+		return restore, errors.Wrapf(err, "failed to list pods on node %s", nodeName)
+	}
+	var evicted []apiv1.Pod
+	for index := range pods.Items {
+		pod := pods.Items[index]
+		if !isDrainable(&pod) {
+			continue
+		}
+		evictFn := func() error {
+			err := clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name},
+			})
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to evict pod %s/%s", pod.Namespace, pod.Name)
+		}
+		retrier := retry.Retrier{Attempts: RetryAttempts, Delay: RetryDelay}
+		if err := retrier.Do(ctx, evictFn); err != nil {
+			return restore, err
+		}
+		log.Printf("evicted pod %s/%s from node %s", pod.Namespace, pod.Name, nodeName)
+		evicted = append(evicted, pod)
+	}
+
+	for index := range evicted {
+		pod := evicted[index]
+		assertPodDeleted := func() error {
+			current, err := clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+				return nil
+			}
+			return errors.Errorf("pod %s/%s has not been deleted yet", pod.Namespace, pod.Name)
+		}
+		retrier := retry.Retrier{Attempts: RetryAttempts, Delay: RetryDelay}
+		if err := retrier.Do(ctx, assertPodDeleted); err != nil {
+			return restore, errors.Wrapf(err, "failed to wait for evicted pods on node %s to be deleted", nodeName)
+		}
+	}
+	return restore, nil
+}
+
+// isDrainable returns false for pods which are not evicted when draining a node: DaemonSet pods, which would be
+// recreated on the node, static pods, which are managed by the kubelet, and pods which have already completed.
+func isDrainable(pod *apiv1.Pod) bool {
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+	if pod.Status.Phase == apiv1.PodSucceeded || pod.Status.Phase == apiv1.PodFailed {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
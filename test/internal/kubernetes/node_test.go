@@ -0,0 +1,64 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeRestore(t *testing.T) {
+	ctx := context.Background()
+	existingTaint := apiv1.Taint{Key: "dedicated", Value: "cns", Effect: apiv1.TaintEffectNoSchedule}
+	clientset := fake.NewSimpleClientset(&apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: map[string]string{"keep": "old"}},
+		Spec:       apiv1.NodeSpec{Taints: []apiv1.Taint{existingTaint}},
+	})
+	nodes := clientset.CoreV1().Nodes()
+
+	restoreCordon, err := CordonNode(ctx, nodes, "node")
+	require.NoError(t, err)
+	restoreTaint, err := TaintNode(ctx, nodes, "node", apiv1.Taint{Key: "dedicated", Value: "npm", Effect: apiv1.TaintEffectNoSchedule})
+	require.NoError(t, err)
+	restoreLabels, err := LabelNode(ctx, nodes, "node", map[string]string{"keep": "new", "added": "true"})
+	require.NoError(t, err)
+
+	node, err := nodes.Get(ctx, "node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+	assert.Equal(t, []apiv1.Taint{{Key: "dedicated", Value: "npm", Effect: apiv1.TaintEffectNoSchedule}}, node.Spec.Taints)
+	assert.Equal(t, map[string]string{"keep": "new", "added": "true"}, node.Labels)
+
+	for _, restore := range []RestoreFunc{restoreLabels, restoreTaint, restoreCordon} {
+		require.NoError(t, restore(ctx))
+	}
+
+	node, err = nodes.Get(ctx, "node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, node.Spec.Unschedulable)
+	assert.Equal(t, []apiv1.Taint{existingTaint}, node.Spec.Taints)
+	assert.Equal(t, map[string]string{"keep": "old"}, node.Labels)
+
+	// restoring again is a no-op, even if the node was changed in the meantime.
+	_, err = CordonNode(ctx, nodes, "node")
+	require.NoError(t, err)
+	require.NoError(t, restoreCordon(ctx))
+	node, err = nodes.Get(ctx, "node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+}
+
+func TestIsDrainable(t *testing.T) {
+	assert.True(t, isDrainable(&apiv1.Pod{}))
+	assert.False(t, isDrainable(&apiv1.Pod{ObjectMeta: metav1.ObjectMeta{
+		OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "azure-cns"}},
+	}}))
+	assert.False(t, isDrainable(&apiv1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{mirrorPodAnnotation: "hash"},
+	}}))
+	assert.False(t, isDrainable(&apiv1.Pod{Status: apiv1.PodStatus{Phase: apiv1.PodSucceeded}}))
+}
@@ -415,6 +415,19 @@ func ExecCmdOnPod(ctx context.Context, clientset *kubernetes.Clientset, namespac
 	return stdout.Bytes(), nil
 }
 
+// ExecCmdOnNode runs the command on the node through the privileged daemonset, which must be running on that node.
+func ExecCmdOnNode(ctx context.Context, clientset *kubernetes.Clientset, nodeName string, cmd []string, config *rest.Config) ([]byte, error) {
+	pods, err := GetPodsByNode(ctx, clientset, PrivilegedNamespace, PrivilegedLabelSelector, nodeName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get privileged pod on node %s", nodeName)
+	}
+	if len(pods.Items) == 0 {
+		return nil, errors.Errorf("there are no privileged pods on node %s", nodeName)
+	}
+	out, err := ExecCmdOnPod(ctx, clientset, PrivilegedNamespace, pods.Items[0].Name, cmd, config)
+	return out, errors.Wrapf(err, "failed to exec on node %s", nodeName)
+}
+
 func NamespaceExists(ctx context.Context, clientset *kubernetes.Clientset, namespace string) (bool, error) {
 	_, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
 	if err != nil {
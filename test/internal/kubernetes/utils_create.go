@@ -293,7 +293,7 @@ func RestartCNSDaemonset(ctx context.Context, clientset *kubernetes.Clientset, w
 				return errors.Wrap(err, "failed to parse cns daemonset")
 			}
 
-			if err := WaitForPodDaemonset(ctx, clientset, cns.Namespace, cns.Name, cnsScenarioDetails.labelSelector); err != nil {
+			if err := WaitForDaemonsetReady(ctx, clientset, cns.Namespace, cns.Name, cnsScenarioDetails.labelSelector); err != nil {
 				return errors.Wrap(err, "failed to check daemonset ready")
 			}
 		}
@@ -547,7 +547,7 @@ func setupCNSDaemonset(ctx context.Context, clientset *kubernetes.Clientset, cns
 	MustSetUpRBAC(ctx, clientset, cnsScenarioDetails.rolePath, cnsScenarioDetails.roleBindingPath)
 	MustCreateDaemonset(ctx, cnsDaemonsetClient, cns)
 
-	if err := WaitForPodDaemonset(ctx, clientset, cns.Namespace, cns.Name, cnsScenarioDetails.labelSelector); err != nil {
+	if err := WaitForDaemonsetReady(ctx, clientset, cns.Namespace, cns.Name, cnsScenarioDetails.labelSelector); err != nil {
 		return appsv1.DaemonSet{}, cnsDetails{}, errors.Wrap(err, "failed to check daemonset running")
 	}
 	return cns, cnsScenarioDetails, nil
@@ -0,0 +1,185 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DiagnosticsTimeout bounds the time spent collecting pod diagnostics after a wait has failed.
+	DiagnosticsTimeout = 1 * time.Minute
+	// DiagnosticsEventCount is the number of most recent events reported per pod.
+	DiagnosticsEventCount = 5
+	// DiagnosticsLogTailLines is the number of log lines reported per container.
+	DiagnosticsLogTailLines int64 = 20
+)
+
+// WaitForDaemonsetReady waits for every pod of the daemonset to be updated and ready, like WaitForPodDaemonset.
+// If the daemonset does not become ready, the container statuses, most recent events and most recent logs of
+// its pods which are not ready are collected into the returned error, so that a failed rollout can be
+// diagnosed from the test output alone.
+func WaitForDaemonsetReady(ctx context.Context, clientset *kubernetes.Clientset, namespace, daemonsetName, podLabelSelector string) error {
+	err := WaitForPodDaemonset(ctx, clientset, namespace, daemonsetName, podLabelSelector)
+	if err == nil {
+		return nil
+	}
+	// the passed context has likely expired, so the diagnostics are collected with a new one.
+	diagCtx, cancel := context.WithTimeout(context.Background(), DiagnosticsTimeout)
+	defer cancel()
+	return errors.Wrapf(err, "daemonset %s/%s is not ready, pod diagnostics:\n%s",
+		namespace, daemonsetName, DescribePodsByLabelSelector(diagCtx, clientset, namespace, podLabelSelector))
+}
+
+// DescribePodsByLabelSelector returns a human readable summary of the pods matching the label selector which
+// are not ready, including their container statuses, most recent events and most recent container logs.
+// Failures to collect any part of the summary are reported inline instead of being returned.
+func DescribePodsByLabelSelector(ctx context.Context, clientset *kubernetes.Clientset, namespace, labelselector string) string {
+	podList, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelselector})
+	if err != nil {
+		return fmt.Sprintf("could not list pods with label selector %s: %v\n", labelselector, err)
+	}
+	if len(podList.Items) == 0 {
+		return fmt.Sprintf("no pods found with label selector %s\n", labelselector)
+	}
+
+	var sb strings.Builder
+	notReady := 0
+	for index := range podList.Items {
+		pod := &podList.Items[index]
+		if IsPodReady(pod) {
+			continue
+		}
+		notReady++
+		describePod(ctx, clientset, pod, &sb)
+	}
+	if notReady == 0 {
+		fmt.Fprintf(&sb, "all %d pods with label selector %s are ready\n", len(podList.Items), labelselector)
+	}
+	return sb.String()
+}
+
+// IsPodReady returns whether the pod has the Ready condition.
+func IsPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func describePod(ctx context.Context, clientset *kubernetes.Clientset, pod *corev1.Pod, sb *strings.Builder) {
+	fmt.Fprintf(sb, "pod %s/%s on node %s is %s", pod.Namespace, pod.Name, pod.Spec.NodeName, pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		fmt.Fprintf(sb, " (%s: %s)", pod.Status.Reason, pod.Status.Message)
+	}
+	sb.WriteString("\n")
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for index := range statuses {
+		status := &statuses[index]
+		fmt.Fprintf(sb, "  container %s: ready=%t restarts=%d state=%s", status.Name, status.Ready, status.RestartCount, describeContainerState(status.State))
+		if status.LastTerminationState.Terminated != nil {
+			fmt.Fprintf(sb, " last=%s", describeContainerState(status.LastTerminationState))
+		}
+		sb.WriteString("\n")
+	}
+
+	describePodEvents(ctx, clientset, pod, sb)
+
+	for index := range statuses {
+		status := &statuses[index]
+		if status.State.Waiting != nil && status.RestartCount == 0 {
+			// the container has never run, so there are no logs to collect.
+			continue
+		}
+		describeContainerLogs(ctx, clientset, pod, status.Name, false, sb)
+		if status.RestartCount > 0 {
+			describeContainerLogs(ctx, clientset, pod, status.Name, true, sb)
+		}
+	}
+}
+
+func describeContainerState(state corev1.ContainerState) string {
+	switch {
+	case state.Waiting != nil:
+		return fmt.Sprintf("waiting(%s: %s)", state.Waiting.Reason, state.Waiting.Message)
+	case state.Terminated != nil:
+		return fmt.Sprintf("terminated(%s, exit code %d: %s)", state.Terminated.Reason, state.Terminated.ExitCode, state.Terminated.Message)
+	case state.Running != nil:
+		return fmt.Sprintf("running(since %s)", state.Running.StartedAt.Format(time.RFC3339))
+	default:
+		return "unknown"
+	}
+}
+
+func describePodEvents(ctx context.Context, clientset *kubernetes.Clientset, pod *corev1.Pod, sb *strings.Builder) {
+	selector := fields.Set{"involvedObject.kind": "Pod", "involvedObject.name": pod.Name}.AsSelector().String()
+	eventList, err := clientset.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		fmt.Fprintf(sb, "  could not list events: %v\n", err)
+		return
+	}
+	events := eventList.Items
+	sort.Slice(events, func(i, j int) bool {
+		return eventTime(&events[i]).Before(eventTime(&events[j]))
+	})
+	if len(events) > DiagnosticsEventCount {
+		events = events[len(events)-DiagnosticsEventCount:]
+	}
+	for index := range events {
+		event := &events[index]
+		fmt.Fprintf(sb, "  event %s %s %s (x%d): %s\n",
+			eventTime(event).Format(time.RFC3339), event.Type, event.Reason, event.Count, event.Message)
+	}
+}
+
+// eventTime returns the last time the event was seen, falling back to the fields set by newer event reporters.
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+func describeContainerLogs(ctx context.Context, clientset *kubernetes.Clientset, pod *corev1.Pod, container string, previous bool, sb *strings.Builder) {
+	tailLines := DiagnosticsLogTailLines
+	req := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		Previous:  previous,
+		TailLines: &tailLines,
+	})
+	kind := "logs"
+	if previous {
+		kind = "previous logs"
+	}
+	podLogs, err := req.Stream(ctx)
+	if err != nil {
+		fmt.Fprintf(sb, "  could not get %s of container %s: %v\n", kind, container, err)
+		return
+	}
+	defer podLogs.Close()
+	logs, err := io.ReadAll(podLogs)
+	if err != nil {
+		fmt.Fprintf(sb, "  could not read %s of container %s: %v\n", kind, container, err)
+		return
+	}
+	fmt.Fprintf(sb, "  %s of container %s:\n", kind, container)
+	for _, line := range strings.Split(strings.TrimRight(string(logs), "\n"), "\n") {
+		fmt.Fprintf(sb, "    %s\n", line)
+	}
+}
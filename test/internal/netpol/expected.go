@@ -0,0 +1,275 @@
+package netpol
+
+import (
+	"net"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	Linux   = "linux"
+	Windows = "windows"
+)
+
+// Expectation computes the expected Connectivity of Probes from the NPM translation of a set of NetworkPolicies,
+// by evaluating the translated ACLs against the ipset membership of the pods of a Model.
+type Expectation struct {
+	policies []*translatedPolicy
+}
+
+type translatedPolicy struct {
+	*policies.NPMNetworkPolicy
+	// sets holds the translated ipsets with members (nested label and CIDR sets) by name.
+	sets map[string]*ipsets.TranslatedIPSet
+}
+
+// endpoint is a pod of the Model as seen by the dataplane.
+type endpoint struct {
+	ns  *Namespace
+	pod *Pod
+}
+
+// NewExpectation translates the NetworkPolicies for the node OS.
+// NPM on Windows does not support every policy feature, so for Windows an error is returned if any of the
+// policies uses an unsupported feature, like NPM returns when translating it on a Windows node.
+func NewExpectation(netpols []*networkingv1.NetworkPolicy, nodeOS string) (*Expectation, error) {
+	e := &Expectation{}
+	for _, netpol := range netpols {
+		if nodeOS == Windows {
+			if err := windowsUnsupported(netpol); err != nil {
+				return nil, errors.Wrapf(err, "policy %s/%s is not supported on windows", netpol.Namespace, netpol.Name)
+			}
+		}
+		npmNetPol, err := translation.TranslatePolicy(netpol)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to translate policy %s/%s", netpol.Namespace, netpol.Name)
+		}
+		policies.NormalizePolicy(npmNetPol)
+		p := &translatedPolicy{NPMNetworkPolicy: npmNetPol, sets: map[string]*ipsets.TranslatedIPSet{}}
+		for _, set := range append(npmNetPol.AllPodSelectorIPSets(), npmNetPol.RuleIPSets...) {
+			if set != nil && len(set.Members) > 0 {
+				p.sets[set.Metadata.GetPrefixName()] = set
+			}
+		}
+		e.policies = append(e.policies, p)
+	}
+	return e, nil
+}
+
+// Matrix returns the expected Connectivity of every Probe of the Model. The pods of the Model must have IPs.
+func (e *Expectation) Matrix(m *Model) Matrix {
+	matrix := Matrix{}
+	for _, probe := range m.Probes() {
+		matrix[probe] = e.Connectivity(m, probe)
+	}
+	return matrix
+}
+
+// Connectivity returns the expected Connectivity of the Probe. Traffic is allowed if it is allowed both
+// out of the source pod and into the destination pod.
+func (e *Expectation) Connectivity(m *Model, probe Probe) Connectivity {
+	fromNs, fromPod := m.Pod(probe.From)
+	toNs, toPod := m.Pod(probe.To)
+	if fromPod == nil || toPod == nil {
+		return Unknown
+	}
+	from := endpoint{ns: fromNs, pod: fromPod}
+	to := endpoint{ns: toNs, pod: toPod}
+	if e.allowed(policies.Egress, from, from, to, probe.Port) && e.allowed(policies.Ingress, to, from, to, probe.Port) {
+		return Allowed
+	}
+	return Blocked
+}
+
+// allowed returns true if the traffic is allowed in the direction of the target pod. Like the dataplane, the allow
+// ACLs of every policy selecting the target are evaluated before any of their drop ACLs, and traffic to or from a
+// pod which is not selected by any policy for the direction is allowed.
+func (e *Expectation) allowed(direction policies.Direction, target, from, to endpoint, port Port) bool {
+	var drops []*policies.ACLPolicy
+	for _, p := range e.policies {
+		if !p.selects(target) {
+			continue
+		}
+		for _, acl := range p.ACLs {
+			if acl.Direction != direction && acl.Direction != policies.Both {
+				continue
+			}
+			if !p.matches(acl, from, to, port) {
+				continue
+			}
+			if acl.Target == policies.Allowed {
+				return true
+			}
+			drops = append(drops, acl)
+		}
+	}
+	return len(drops) == 0
+}
+
+func (p *translatedPolicy) selects(ep endpoint) bool {
+	for _, info := range p.PodSelectorList {
+		if p.isMember(info, ep) != info.Included {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *translatedPolicy) matches(acl *policies.ACLPolicy, from, to endpoint, port Port) bool {
+	if acl.Protocol != policies.UnspecifiedProtocol && string(acl.Protocol) != string(port.Protocol) {
+		return false
+	}
+	if acl.DstPorts.Port != 0 && (port.Number < acl.DstPorts.Port || port.Number > acl.DstPorts.EndPort) {
+		return false
+	}
+	for _, info := range acl.SrcList {
+		if p.isMember(info, from) != info.Included {
+			return false
+		}
+	}
+	for _, info := range acl.DstList {
+		member := p.isMember(info, to)
+		if info.IPSet.Type == ipsets.NamedPorts {
+			member = hasNamedPort(info.IPSet.Name, acl.Protocol, port)
+		}
+		if member != info.Included {
+			return false
+		}
+	}
+	return true
+}
+
+// isMember returns true if the endpoint is in the ipset, following how the controllers populate each type of set.
+func (p *translatedPolicy) isMember(info policies.SetInfo, ep endpoint) bool {
+	name := info.IPSet.Name
+	switch info.IPSet.Type {
+	case ipsets.Namespace:
+		return ep.ns.Name == name
+	case ipsets.KeyLabelOfNamespace:
+		if name == util.KubeAllNamespacesFlag {
+			return true
+		}
+		_, ok := ep.ns.Labels[name]
+		return ok
+	case ipsets.KeyValueLabelOfNamespace:
+		return hasLabel(ep.ns.Labels, name)
+	case ipsets.KeyLabelOfPod:
+		_, ok := ep.pod.Labels[name]
+		return ok
+	case ipsets.KeyValueLabelOfPod:
+		return hasLabel(ep.pod.Labels, name)
+	case ipsets.NestedLabelOfPod:
+		set, ok := p.sets[info.IPSet.GetPrefixName()]
+		if !ok {
+			return false
+		}
+		for _, member := range set.Members {
+			if hasLabel(ep.pod.Labels, member) {
+				return true
+			}
+		}
+		return false
+	case ipsets.CIDRBlocks:
+		set, ok := p.sets[info.IPSet.GetPrefixName()]
+		if !ok {
+			return false
+		}
+		return inCIDRSet(ep.pod.IP, set.Members)
+	default:
+		return false
+	}
+}
+
+// hasLabel returns true if the labels have the key and value of a key-value label set name.
+func hasLabel(labels map[string]string, setName string) bool {
+	key, value := util.GetLabelKVFromSet(setName)
+	v, ok := labels[key]
+	return ok && v == value
+}
+
+// hasNamedPort returns true if the port is the named port. Every pod of a Model serves every port,
+// so the destination pod has the named port if the probed port has its name.
+func hasNamedPort(name string, protocol policies.Protocol, port Port) bool {
+	if protocol != policies.UnspecifiedProtocol && string(protocol) != string(port.Protocol) {
+		return false
+	}
+	return port.Name == name
+}
+
+// inCIDRSet returns true if the ip is in the CIDR set. Like a hash:net ipset, the most specific CIDR
+// containing the ip decides, and the ip is not a member if that CIDR is marked nomatch.
+func inCIDRSet(ip string, members []string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	bestPrefix := -1
+	member := false
+	for _, m := range members {
+		fields := strings.Fields(m)
+		if len(fields) == 0 {
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(fields[0])
+		if err != nil || !cidr.Contains(addr) {
+			continue
+		}
+		prefix, _ := cidr.Mask.Size()
+		if prefix > bestPrefix {
+			bestPrefix = prefix
+			member = len(fields) == 1 || fields[1] != util.IpsetNomatch
+		}
+	}
+	return member
+}
+
+// windowsUnsupported returns the error NPM returns when translating the policy on Windows, if any.
+func windowsUnsupported(netpol *networkingv1.NetworkPolicy) error {
+	if hasNegativeMatch(&netpol.Spec.PodSelector) {
+		return translation.ErrUnsupportedNegativeMatch
+	}
+	var ports []networkingv1.NetworkPolicyPort
+	var peers []networkingv1.NetworkPolicyPeer
+	for _, rule := range netpol.Spec.Ingress {
+		ports = append(ports, rule.Ports...)
+		peers = append(peers, rule.From...)
+	}
+	for _, rule := range netpol.Spec.Egress {
+		ports = append(ports, rule.Ports...)
+		peers = append(peers, rule.To...)
+	}
+	for _, port := range ports {
+		if port.Protocol != nil && *port.Protocol == corev1.ProtocolSCTP {
+			return translation.ErrUnsupportedSCTP
+		}
+		if port.Port != nil && port.Port.IntValue() == 0 && port.Port.String() != "" {
+			return translation.ErrUnsupportedNamedPort
+		}
+	}
+	for _, peer := range peers {
+		if peer.PodSelector != nil && hasNegativeMatch(peer.PodSelector) {
+			return translation.ErrUnsupportedNegativeMatch
+		}
+		if peer.IPBlock != nil && len(peer.IPBlock.Except) > 0 {
+			return translation.ErrUnsupportedExceptCIDR
+		}
+	}
+	return nil
+}
+
+func hasNegativeMatch(selector *metav1.LabelSelector) bool {
+	for _, req := range selector.MatchExpressions {
+		if req.Operator == metav1.LabelSelectorOpNotIn || req.Operator == metav1.LabelSelectorOpDoesNotExist {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package netpol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var (
+	tcp80 = Port{Name: "serve-80-tcp", Number: 80, Protocol: corev1.ProtocolTCP}
+	tcp81 = Port{Name: "serve-81-tcp", Number: 81, Protocol: corev1.ProtocolTCP}
+)
+
+func testModel() *Model {
+	m := NewModel([]string{"x", "y"}, []string{"a", "b"}, []Port{tcp80, tcp81})
+	ips := map[PodKey]string{
+		{Namespace: "x", Name: "a"}: "10.0.0.1",
+		{Namespace: "x", Name: "b"}: "10.0.0.2",
+		{Namespace: "y", Name: "a"}: "10.0.1.1",
+		{Namespace: "y", Name: "b"}: "10.0.1.2",
+	}
+	for key, ip := range ips {
+		_, pod := m.Pod(key)
+		pod.IP = ip
+	}
+	return m
+}
+
+func probe(from, to string, port Port) Probe {
+	return Probe{
+		From: PodKey{Namespace: from[:1], Name: from[2:]},
+		To:   PodKey{Namespace: to[:1], Name: to[2:]},
+		Port: port,
+	}
+}
+
+func port(p Port) *intstr.IntOrString {
+	v := intstr.FromInt(int(p.Number))
+	return &v
+}
+
+func TestExpectation(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies []*networkingv1.NetworkPolicy
+		allowed  []Probe
+		blocked  []Probe
+	}{
+		{
+			name:    "no policies",
+			allowed: []Probe{probe("x/a", "y/b", tcp80), probe("y/b", "x/a", tcp81)},
+		},
+		{
+			name: "deny all ingress in x",
+			policies: []*networkingv1.NetworkPolicy{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "deny"},
+				Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}},
+			}},
+			allowed: []Probe{probe("x/a", "y/a", tcp80)},
+			blocked: []Probe{probe("y/a", "x/a", tcp80), probe("x/b", "x/a", tcp81)},
+		},
+		{
+			name: "allow port 80 from namespace y to pod b",
+			policies: []*networkingv1.NetworkPolicy{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "allow-y"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{PodLabelKey: "b"}},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+					Ingress: []networkingv1.NetworkPolicyIngressRule{{
+						From: []networkingv1.NetworkPolicyPeer{{
+							NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{NamespaceLabelKey: "y"}},
+						}},
+						Ports: []networkingv1.NetworkPolicyPort{{Port: port(tcp80)}},
+					}},
+				},
+			}},
+			allowed: []Probe{probe("y/a", "x/b", tcp80), probe("y/a", "x/a", tcp81)},
+			blocked: []Probe{probe("y/a", "x/b", tcp81), probe("x/a", "x/b", tcp80)},
+		},
+		{
+			name: "allow egress to a named port",
+			policies: []*networkingv1.NetworkPolicy{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "y", Name: "named-port"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+					Egress: []networkingv1.NetworkPolicyEgressRule{{
+						Ports: []networkingv1.NetworkPolicyPort{{Port: &intstr.IntOrString{Type: intstr.String, StrVal: tcp81.Name}}},
+					}},
+				},
+			}},
+			allowed: []Probe{probe("y/a", "x/a", tcp81), probe("x/a", "y/a", tcp80)},
+			blocked: []Probe{probe("y/a", "x/a", tcp80), probe("y/b", "y/a", tcp80)},
+		},
+		{
+			name: "allow ingress from an ip block with an exception",
+			policies: []*networkingv1.NetworkPolicy{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "ip-block"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+					Ingress: []networkingv1.NetworkPolicyIngressRule{{
+						From: []networkingv1.NetworkPolicyPeer{{
+							IPBlock: &networkingv1.IPBlock{CIDR: "10.0.1.0/24", Except: []string{"10.0.1.2/32"}},
+						}},
+					}},
+				},
+			}},
+			allowed: []Probe{probe("y/a", "x/a", tcp80)},
+			blocked: []Probe{probe("y/b", "x/a", tcp80), probe("x/b", "x/a", tcp80)},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			m := testModel()
+			e, err := NewExpectation(tt.policies, Linux)
+			require.NoError(t, err)
+			for _, p := range tt.allowed {
+				assert.Equal(t, Allowed, e.Connectivity(m, p), p.String())
+			}
+			for _, p := range tt.blocked {
+				assert.Equal(t, Blocked, e.Connectivity(m, p), p.String())
+			}
+		})
+	}
+}
+
+func TestExpectationWindowsUnsupported(t *testing.T) {
+	netpol := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "except"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{
+					IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/16", Except: []string{"10.0.1.0/24"}},
+				}},
+			}},
+		},
+	}
+	_, err := NewExpectation([]*networkingv1.NetworkPolicy{netpol}, Windows)
+	require.Error(t, err)
+	_, err = NewExpectation([]*networkingv1.NetworkPolicy{netpol}, Linux)
+	require.NoError(t, err)
+}
+
+func TestResultMismatches(t *testing.T) {
+	m := testModel()
+	e, err := NewExpectation(nil, Linux)
+	require.NoError(t, err)
+	r := &Result{Scenario: "test", Model: m, Expected: e.Matrix(m), Actual: e.Matrix(m)}
+	assert.True(t, r.Passed())
+
+	blocked := probe("x/a", "y/b", tcp80)
+	r.Actual[blocked] = Blocked
+	assert.Equal(t, []Probe{blocked}, r.Mismatches())
+	assert.Contains(t, r.String(), "x/a -> y/b:80/TCP: expected allowed, got blocked")
+}
@@ -0,0 +1,157 @@
+// Package netpol runs NetworkPolicy connectivity scenarios against a cluster.
+// A Model describes a grid of namespaces and pods which all serve the same ports. For every Scenario, the Runner
+// applies the scenario's NetworkPolicies, probes every (source pod, destination pod, port) combination, and compares
+// the observed connectivity with the connectivity expected from the NPM translation of the policies.
+package netpol
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// NamespaceLabelKey is the label set on every namespace of a Model with the namespace name as value.
+	NamespaceLabelKey = "ns"
+	// PodLabelKey is the label set on every pod of a Model with the pod name as value.
+	PodLabelKey = "pod"
+	// kubernetesNamespaceNameLabel is set by the API server on every namespace.
+	kubernetesNamespaceNameLabel = "kubernetes.io/metadata.name"
+)
+
+// Port is a port which is served by every pod of a Model.
+type Port struct {
+	// Name is the name of the container port, which policies can refer to as a named port.
+	Name     string
+	Number   int32
+	Protocol corev1.Protocol
+}
+
+func (p Port) String() string {
+	return fmt.Sprintf("%d/%s", p.Number, p.Protocol)
+}
+
+// Pod is a pod of a Model.
+type Pod struct {
+	Name   string
+	Labels map[string]string
+	// IP is set by the Runner once the pod is running.
+	IP string
+}
+
+// Namespace is a namespace of a Model and the pods in it.
+type Namespace struct {
+	Name   string
+	Labels map[string]string
+	Pods   []*Pod
+}
+
+// Model is the grid of namespaces and pods which is probed by a Runner.
+type Model struct {
+	Namespaces []*Namespace
+	Ports      []Port
+}
+
+// NewModel creates a Model with a pod of each name in each namespace, where every pod serves all of the ports.
+// Namespaces are labeled with NamespaceLabelKey and pods with PodLabelKey, so that policies can select them by name.
+func NewModel(namespaces, pods []string, ports []Port) *Model {
+	m := &Model{Ports: ports}
+	for _, ns := range namespaces {
+		namespace := &Namespace{
+			Name: ns,
+			Labels: map[string]string{
+				NamespaceLabelKey:            ns,
+				kubernetesNamespaceNameLabel: ns,
+			},
+		}
+		for _, pod := range pods {
+			namespace.Pods = append(namespace.Pods, &Pod{
+				Name:   pod,
+				Labels: map[string]string{PodLabelKey: pod},
+			})
+		}
+		m.Namespaces = append(m.Namespaces, namespace)
+	}
+	return m
+}
+
+// PodKey identifies a pod of a Model.
+type PodKey struct {
+	Namespace string
+	Name      string
+}
+
+func (k PodKey) String() string {
+	return k.Namespace + "/" + k.Name
+}
+
+// PodKeys returns the keys of all pods of the Model.
+func (m *Model) PodKeys() []PodKey {
+	var keys []PodKey
+	for _, ns := range m.Namespaces {
+		for _, pod := range ns.Pods {
+			keys = append(keys, PodKey{Namespace: ns.Name, Name: pod.Name})
+		}
+	}
+	return keys
+}
+
+// Pod returns the namespace and pod for the key, or nil if the pod is not part of the Model.
+func (m *Model) Pod(key PodKey) (*Namespace, *Pod) {
+	for _, ns := range m.Namespaces {
+		if ns.Name != key.Namespace {
+			continue
+		}
+		for _, pod := range ns.Pods {
+			if pod.Name == key.Name {
+				return ns, pod
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Probe is a connection attempt from one pod of the Model to a port of another.
+type Probe struct {
+	From PodKey
+	To   PodKey
+	Port Port
+}
+
+func (p Probe) String() string {
+	return fmt.Sprintf("%s -> %s:%s", p.From, p.To, p.Port)
+}
+
+// Probes returns every combination of source pod, destination pod and port of the Model.
+// A pod connecting to itself is not probed, since that traffic never leaves the pod and is not subject to policy.
+func (m *Model) Probes() []Probe {
+	keys := m.PodKeys()
+	if len(keys) == 0 {
+		return nil
+	}
+	probes := make([]Probe, 0, len(keys)*(len(keys)-1)*len(m.Ports))
+	for _, from := range keys {
+		for _, to := range keys {
+			if from == to {
+				continue
+			}
+			for _, port := range m.Ports {
+				probes = append(probes, Probe{From: from, To: to, Port: port})
+			}
+		}
+	}
+	return probes
+}
+
+// Connectivity is the outcome of a Probe.
+type Connectivity string
+
+const (
+	Allowed Connectivity = "allowed"
+	Blocked Connectivity = "blocked"
+	// Unknown is the outcome of a Probe which could not be run.
+	Unknown Connectivity = "unknown"
+)
+
+// Matrix holds the Connectivity of each Probe.
+type Matrix map[Probe]Connectivity
@@ -0,0 +1,90 @@
+package netpol
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Result holds the expected and observed connectivity of a Scenario.
+type Result struct {
+	Scenario string
+	Model    *Model
+	Expected Matrix
+	Actual   Matrix
+}
+
+// Mismatches returns the probes whose observed connectivity differs from the expected connectivity.
+func (r *Result) Mismatches() []Probe {
+	var mismatches []Probe
+	for _, probe := range r.Model.Probes() {
+		if r.Expected[probe] != r.Actual[probe] {
+			mismatches = append(mismatches, probe)
+		}
+	}
+	sort.SliceStable(mismatches, func(i, j int) bool {
+		return mismatches[i].String() < mismatches[j].String()
+	})
+	return mismatches
+}
+
+// Passed returns true if every probe had the expected connectivity.
+func (r *Result) Passed() bool {
+	return len(r.Mismatches()) == 0
+}
+
+// String renders a truth table of the observed connectivity per port, with sources as rows and destinations as
+// columns, followed by the list of mismatches. In the tables, "." is allowed, "X" is blocked and "?" is unknown,
+// and a mismatch with the expected connectivity is marked with "!".
+func (r *Result) String() string {
+	var sb strings.Builder
+	mismatches := r.Mismatches()
+	fmt.Fprintf(&sb, "scenario %s: %d of %d probes mismatched\n", r.Scenario, len(mismatches), len(r.Expected))
+	keys := r.Model.PodKeys()
+	width := 0
+	for _, key := range keys {
+		if l := len(key.String()); l > width {
+			width = l
+		}
+	}
+	for _, port := range r.Model.Ports {
+		fmt.Fprintf(&sb, "\n%s (%s)\n%-*s", port, port.Name, width, "")
+		for _, to := range keys {
+			fmt.Fprintf(&sb, " %s", to)
+		}
+		sb.WriteString("\n")
+		for _, from := range keys {
+			fmt.Fprintf(&sb, "%-*s", width, from)
+			for _, to := range keys {
+				cell := "-"
+				if from != to {
+					probe := Probe{From: from, To: to, Port: port}
+					cell = symbol(r.Actual[probe])
+					if r.Expected[probe] != r.Actual[probe] {
+						cell += "!"
+					}
+				}
+				fmt.Fprintf(&sb, " %-*s", len(to.String()), cell)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	if len(mismatches) > 0 {
+		sb.WriteString("\nmismatches:\n")
+		for _, probe := range mismatches {
+			fmt.Fprintf(&sb, "  %s: expected %s, got %s\n", probe, r.Expected[probe], r.Actual[probe])
+		}
+	}
+	return sb.String()
+}
+
+func symbol(c Connectivity) string {
+	switch c {
+	case Allowed:
+		return "."
+	case Blocked:
+		return "X"
+	default:
+		return "?"
+	}
+}
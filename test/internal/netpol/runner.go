@@ -0,0 +1,235 @@
+package netpol
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	acnk8s "github.com/Azure/azure-container-networking/test/internal/kubernetes"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+const (
+	// AgnhostImage serves the ports of the pods and probes them. It is published for Linux and Windows.
+	AgnhostImage = "registry.k8s.io/e2e-test-images/agnhost:2.43"
+
+	DefaultProbeTimeout      = 1 * time.Second
+	DefaultPolicySettleDelay = 10 * time.Second
+	DefaultProbeWorkers      = 16
+
+	containerName = "agnhost"
+	// agnhost porter replies with the value of the environment variable on the port in the variable name.
+	porterResponse = "ok"
+)
+
+// Scenario is a set of NetworkPolicies whose connectivity is checked by the Runner.
+type Scenario struct {
+	Name     string
+	Policies []*networkingv1.NetworkPolicy
+}
+
+// Runner creates the pods of a Model on the nodes of one OS and runs Scenarios against them.
+type Runner struct {
+	clientset *kubernetes.Clientset
+	config    *rest.Config
+	model     *Model
+	nodeOS    string
+
+	// ProbeTimeout is the time each probe waits for a connection.
+	ProbeTimeout time.Duration
+	// PolicySettleDelay is the time waited after creating or deleting policies for NPM to program the dataplane.
+	PolicySettleDelay time.Duration
+	// ProbeWorkers is the number of probes run in parallel.
+	ProbeWorkers int
+}
+
+// NewRunner creates a Runner which schedules the pods of the Model on nodes with the OS, which is Linux or Windows.
+func NewRunner(clientset *kubernetes.Clientset, config *rest.Config, model *Model, nodeOS string) *Runner {
+	return &Runner{
+		clientset:         clientset,
+		config:            config,
+		model:             model,
+		nodeOS:            nodeOS,
+		ProbeTimeout:      DefaultProbeTimeout,
+		PolicySettleDelay: DefaultPolicySettleDelay,
+		ProbeWorkers:      DefaultProbeWorkers,
+	}
+}
+
+// Setup creates the namespaces and pods of the Model, waits for the pods to run and records their IPs.
+func (r *Runner) Setup(ctx context.Context) error {
+	for _, ns := range r.model.Namespaces {
+		_, err := r.clientset.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: ns.Name, Labels: ns.Labels},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create namespace %s", ns.Name)
+		}
+		for _, pod := range ns.Pods {
+			if _, err := r.clientset.CoreV1().Pods(ns.Name).Create(ctx, r.podSpec(ns, pod), metav1.CreateOptions{}); err != nil {
+				return errors.Wrapf(err, "failed to create pod %s/%s", ns.Name, pod.Name)
+			}
+		}
+	}
+
+	for _, ns := range r.model.Namespaces {
+		if err := acnk8s.WaitForPodsRunning(ctx, r.clientset, ns.Name, PodLabelKey); err != nil {
+			return errors.Wrapf(err, "failed to wait for pods in namespace %s", ns.Name)
+		}
+		for _, pod := range ns.Pods {
+			p, err := r.clientset.CoreV1().Pods(ns.Name).Get(ctx, pod.Name, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrapf(err, "failed to get pod %s/%s", ns.Name, pod.Name)
+			}
+			pod.IP = p.Status.PodIP
+		}
+	}
+	return nil
+}
+
+// Teardown deletes the namespaces of the Model.
+func (r *Runner) Teardown(ctx context.Context) error {
+	for _, ns := range r.model.Namespaces {
+		if err := r.clientset.CoreV1().Namespaces().Delete(ctx, ns.Name, metav1.DeleteOptions{}); err != nil {
+			return errors.Wrapf(err, "failed to delete namespace %s", ns.Name)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) podSpec(ns *Namespace, pod *Pod) *corev1.Pod {
+	var env []corev1.EnvVar
+	var ports []corev1.ContainerPort
+	for _, port := range r.model.Ports {
+		prefix := "SERVE_PORT_"
+		switch port.Protocol {
+		case corev1.ProtocolUDP:
+			prefix = "SERVE_UDP_PORT_"
+		case corev1.ProtocolSCTP:
+			prefix = "SERVE_SCTP_PORT_"
+		}
+		env = append(env, corev1.EnvVar{Name: fmt.Sprintf("%s%d", prefix, port.Number), Value: porterResponse})
+		ports = append(ports, corev1.ContainerPort{Name: port.Name, ContainerPort: port.Number, Protocol: port.Protocol})
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: ns.Name,
+			Labels:    pod.Labels,
+		},
+		Spec: corev1.PodSpec{
+			NodeSelector: map[string]string{corev1.LabelOSStable: r.nodeOS},
+			Containers: []corev1.Container{
+				{
+					Name:  containerName,
+					Image: AgnhostImage,
+					Args:  []string{"porter"},
+					Env:   env,
+					Ports: ports,
+				},
+			},
+		},
+	}
+}
+
+// Run applies the policies of the Scenario, probes the Model and removes the policies again.
+// The Result holds the expected and observed connectivity of every probe.
+func (r *Runner) Run(ctx context.Context, scenario Scenario) (*Result, error) {
+	expectation, err := NewExpectation(scenario.Policies, r.nodeOS)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to compute expected connectivity of scenario %s", scenario.Name)
+	}
+
+	if err := r.deletePolicies(ctx); err != nil {
+		return nil, err
+	}
+	for _, netpol := range scenario.Policies {
+		if _, err := r.clientset.NetworkingV1().NetworkPolicies(netpol.Namespace).Create(ctx, netpol, metav1.CreateOptions{}); err != nil {
+			return nil, errors.Wrapf(err, "failed to create policy %s/%s", netpol.Namespace, netpol.Name)
+		}
+	}
+	log.Printf("scenario %s: created %d policies, waiting %s for them to be programmed", scenario.Name, len(scenario.Policies), r.PolicySettleDelay)
+	time.Sleep(r.PolicySettleDelay)
+
+	result := &Result{
+		Scenario: scenario.Name,
+		Model:    r.model,
+		Expected: expectation.Matrix(r.model),
+		Actual:   r.probeAll(ctx),
+	}
+
+	if err := r.deletePolicies(ctx); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func (r *Runner) deletePolicies(ctx context.Context) error {
+	for _, ns := range r.model.Namespaces {
+		err := r.clientset.NetworkingV1().NetworkPolicies(ns.Name).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete policies in namespace %s", ns.Name)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) probeAll(ctx context.Context) Matrix {
+	probes := r.model.Probes()
+	work := make(chan Probe)
+	matrix := Matrix{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < r.ProbeWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for probe := range work {
+				connectivity := r.probe(ctx, probe)
+				mu.Lock()
+				matrix[probe] = connectivity
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, probe := range probes {
+		work <- probe
+	}
+	close(work)
+	wg.Wait()
+	return matrix
+}
+
+// probe runs agnhost connect in the source pod. A connection failure makes the command exit with a non-zero code,
+// while any other error means the probe itself failed.
+func (r *Runner) probe(ctx context.Context, probe Probe) Connectivity {
+	_, to := r.model.Pod(probe.To)
+	if to == nil || to.IP == "" {
+		return Unknown
+	}
+	cmd := []string{
+		"/agnhost", "connect", net.JoinHostPort(to.IP, fmt.Sprint(probe.Port.Number)),
+		fmt.Sprintf("--timeout=%s", r.ProbeTimeout),
+		fmt.Sprintf("--protocol=%s", strings.ToLower(string(probe.Port.Protocol))),
+	}
+	_, err := acnk8s.ExecCmdOnPod(ctx, r.clientset, probe.From.Namespace, probe.From.Name, cmd, r.config)
+	if err == nil {
+		return Allowed
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		return Blocked
+	}
+	log.Printf("probe %s failed: %v", probe, err)
+	return Unknown
+}
@@ -4,6 +4,7 @@
 package testingutils
 
 import (
+	"flag"
 	"io"
 	"log"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"k8s.io/klog"
 	"k8s.io/utils/exec"
 
 	fakeexec "k8s.io/utils/exec/testing"
@@ -83,3 +85,20 @@ func RequireRootforTestMain() {
 		os.Exit(1)
 	}
 }
+
+// DiscardKlog drops klog output until the end of the test, e.g. so that logs don't interleave with benchmark results.
+func DiscardKlog(tb testing.TB) {
+	setKlogFlags(tb, map[string]string{"logtostderr": "false", "alsologtostderr": "false", "stderrthreshold": "FATAL"})
+	klog.SetOutput(io.Discard)
+	tb.Cleanup(func() {
+		setKlogFlags(tb, map[string]string{"logtostderr": "true", "stderrthreshold": "ERROR"})
+	})
+}
+
+func setKlogFlags(tb testing.TB, values map[string]string) {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	for name, value := range values {
+		require.NoError(tb, fs.Set(name, value))
+	}
+}
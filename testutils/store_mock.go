@@ -20,6 +20,7 @@ type KeyValueStoreMock struct {
 	UnlockError              error
 	ModificationTime         time.Time
 	GetModificationTimeError error
+	Version                  uint64
 }
 
 func (mockst *KeyValueStoreMock) Exists() bool {
@@ -34,6 +35,21 @@ func (mockst *KeyValueStoreMock) Write(key string, value interface{}) error {
 	return mockst.WriteError
 }
 
+func (mockst *KeyValueStoreMock) ReadWithVersion(key string, value interface{}) (uint64, error) {
+	return mockst.Version, mockst.ReadError
+}
+
+func (mockst *KeyValueStoreMock) WriteIfVersion(key string, value interface{}, version uint64) (uint64, error) {
+	if mockst.WriteError != nil {
+		return 0, mockst.WriteError
+	}
+	if version != mockst.Version {
+		return 0, &store.VersionConflictError{Key: key, Expected: version, Actual: mockst.Version}
+	}
+	mockst.Version++
+	return mockst.Version, nil
+}
+
 func (mockst *KeyValueStoreMock) Flush() error {
 	return mockst.FlushError
 }
@@ -24,11 +24,21 @@ const (
 	FlagBinDirectory      = "bin-directory"
 	FlagConflistDirectory = "conflist-directory"
 	FlagVersion           = "version"
+	FlagSrcDirectory      = "src-directory"
+
+	// CNI Upgrade Flags
+	FlagRollback = "rollback"
 
 	// CNI Log Flags
 	FlagFollow      = "follow"
 	FlagLogFilePath = "log-file"
 
+	// Bundle Flags
+	FlagOutputDir = "output-dir"
+	FlagRedact    = "redact"
+	FlagLogLines  = "log-lines"
+	FlagNPMUrl    = "npmurl"
+
 	// tenancy flags
 	Singletenancy = "singletenancy"
 	Multitenancy  = "multitenancy"
@@ -76,6 +86,11 @@ const (
 	DefaultCNSUrl                     = "http://localhost:10090"
 	DefaultEnableExactMatchForPodName = "false"
 	DefaultNetworkName                = "azure"
+
+	// Bundle defaults
+	DefaultOutputDir = "."
+	DefaultLogLines  = "5000"
+	DefaultNPMUrl    = "http://localhost:10091"
 )
 
 var (
@@ -111,10 +126,15 @@ var (
 		EnvCNIDestinationBinDir:        DefaultBinDirLinux,
 		EnvCNIDestinationConflistDir:   DefaultConflistDirLinux,
 		FlagNetworkName:                DefaultNetworkName,
+		FlagOutputDir:                  DefaultOutputDir,
+		FlagLogLines:                   DefaultLogLines,
+		FlagNPMUrl:                     DefaultNPMUrl,
 	}
 
 	DefaultToggles = map[string]bool{
-		FlagFollow: false,
+		FlagFollow:   false,
+		FlagRedact:   false,
+		FlagRollback: false,
 	}
 )
 
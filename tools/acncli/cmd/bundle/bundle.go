@@ -0,0 +1,260 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package bundle
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// itemTimeout bounds the time spent collecting a single item, so that a hung command or API does not block the bundle.
+const itemTimeout = 30 * time.Second
+
+// BundleCmd collects the state files, debug API dumps, dataplane exports, conflists and recent logs of the ACN
+// components on this node into a single timestamped archive, which is what support asks customers to collect.
+func BundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Collects a support bundle of the ACN components on this node",
+		Long: "The bundle command collects the CNS and CNI state files, the CNS and NPM debug APIs, the iptables and ipset " +
+			"or HNS state, the CNI conflists and the recent logs of this node into a zip archive. Items which cannot be " +
+			"collected are listed in the manifest.json of the archive rather than failing the bundle.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logLines, err := strconv.Atoi(viper.GetString(c.FlagLogLines))
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", c.FlagLogLines, err)
+			}
+			cfg := &config{
+				cnsURL:   viper.GetString(c.FlagCNSUrl),
+				npmURL:   viper.GetString(c.FlagNPMUrl),
+				logLines: logLines,
+			}
+			path, err := writeBundle(cmd.Context(), viper.GetString(c.FlagOutputDir), sources(cfg), viper.GetBool(c.FlagRedact))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("📦 - wrote support bundle %s\n", path)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(c.FlagOutputDir, c.Defaults[c.FlagOutputDir], "Directory to write the bundle to")
+	cmd.Flags().Bool(c.FlagRedact, c.DefaultToggles[c.FlagRedact], "Redact credentials from the collected items")
+	cmd.Flags().String(c.FlagLogLines, c.Defaults[c.FlagLogLines], "Number of most recent lines to collect from each log file")
+	cmd.Flags().String(c.FlagCNSUrl, c.Defaults[c.FlagCNSUrl], "URL of the CNS API")
+	cmd.Flags().String(c.FlagNPMUrl, c.Defaults[c.FlagNPMUrl], "URL of the NPM debug API")
+
+	return cmd
+}
+
+// config is the configuration of the sources of a bundle.
+type config struct {
+	cnsURL   string
+	npmURL   string
+	logLines int
+}
+
+// item is a single file in the bundle.
+type item struct {
+	// path is the path of the item in the bundle.
+	path string
+	// source describes where the item is collected from.
+	source  string
+	collect func(ctx context.Context) ([]byte, error)
+}
+
+// manifestItem is the record of an item in the manifest of the bundle.
+type manifestItem struct {
+	Path   string `json:"path"`
+	Source string `json:"source"`
+	Size   int    `json:"size"`
+	Error  string `json:"error,omitempty"`
+}
+
+type manifest struct {
+	Created  time.Time      `json:"created"`
+	Hostname string         `json:"hostname"`
+	OS       string         `json:"os"`
+	Redacted bool           `json:"redacted"`
+	Items    []manifestItem `json:"items"`
+}
+
+// writeBundle collects the items into a zip archive in the directory, and returns the path of the archive.
+// Items which fail to be collected are recorded in the manifest of the archive.
+func writeBundle(ctx context.Context, dir string, items []item, redactItems bool) (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	m := manifest{Created: time.Now().UTC(), Hostname: hostname, OS: runtime.GOOS, Redacted: redactItems}
+	path := filepath.Join(dir, fmt.Sprintf("acn-bundle-%s-%s.zip", hostname, m.Created.Format("20060102T150405Z")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer f.Close()
+
+	if err := write(ctx, f, items, &m); err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to close bundle: %w", err)
+	}
+	return path, nil
+}
+
+func write(ctx context.Context, w io.Writer, items []item, m *manifest) error {
+	zw := zip.NewWriter(w)
+	for _, it := range items {
+		record := manifestItem{Path: it.path, Source: it.source}
+		data, err := collectWithTimeout(ctx, it)
+		if err != nil {
+			record.Error = err.Error()
+		}
+		// commands may fail after printing some of their output, which is still worth keeping.
+		if len(data) > 0 {
+			if m.Redacted {
+				data = redact(data)
+			}
+			if err := writeFile(zw, it.path, m.Created, data); err != nil {
+				return err
+			}
+			record.Size = len(data)
+		}
+		m.Items = append(m.Items, record)
+	}
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := writeFile(zw, "manifest.json", m.Created, data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	return nil
+}
+
+func collectWithTimeout(ctx context.Context, it item) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, itemTimeout)
+	defer cancel()
+	return it.collect(ctx)
+}
+
+func writeFile(zw *zip.Writer, path string, modified time.Time, data []byte) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: path, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", path, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to bundle: %w", path, err)
+	}
+	return nil
+}
+
+// fileItem collects the file.
+func fileItem(path, src string) item {
+	return item{path: path, source: src, collect: func(context.Context) ([]byte, error) {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		return data, nil
+	}}
+}
+
+// globItems collects the files matching the pattern into the directory of the bundle.
+func globItems(dir, pattern string) []item {
+	matches, _ := filepath.Glob(pattern)
+	items := make([]item, 0, len(matches))
+	for _, match := range matches {
+		items = append(items, fileItem(dir+"/"+filepath.Base(match), match))
+	}
+	return items
+}
+
+// logItems collects the last lines of the log files matching the pattern into the directory of the bundle.
+func logItems(dir, pattern string, lines int) []item {
+	matches, _ := filepath.Glob(pattern)
+	items := make([]item, 0, len(matches))
+	for _, match := range matches {
+		match := match
+		items = append(items, item{path: dir + "/" + filepath.Base(match), source: match, collect: func(context.Context) ([]byte, error) {
+			return tailFile(match, lines)
+		}})
+	}
+	return items
+}
+
+// tailFile returns the last lines of the file, or all of them if lines is not positive.
+func tailFile(path string, lines int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer f.Close()
+	var ring []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024) //nolint:gomnd // long log lines
+	for scanner.Scan() {
+		if lines > 0 && len(ring) == lines {
+			ring = ring[1:]
+		}
+		ring = append(ring, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read log file: %w", err)
+	}
+	if len(ring) == 0 {
+		return nil, nil
+	}
+	return []byte(strings.Join(ring, "\n") + "\n"), nil
+}
+
+// httpItem collects the response of the API. The request is a GET if the body is nil, and a JSON POST otherwise.
+func httpItem(path, url string, body []byte) item {
+	return item{path: path, source: url, collect: func(ctx context.Context) ([]byte, error) {
+		method := http.MethodGet
+		if body != nil {
+			method = http.MethodPost
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build request: %w", err)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to call api: %w", err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return data, fmt.Errorf("api returned status %d", resp.StatusCode) //nolint:goerr113 // status is only reported
+		}
+		return data, nil
+	}}
+}
@@ -0,0 +1,39 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package bundle
+
+import "regexp"
+
+const redacted = "REDACTED"
+
+// redactions match credentials which may appear in the state files, API dumps and logs. IPs, MACs and resource names
+// are kept, since they are needed to debug the datapath.
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// JSON fields holding credentials, e.g. the AuthorizationToken of network container requests.
+	{
+		pattern:     regexp.MustCompile(`(?i)("[^"]*(?:password|secret|token|apikey|accesskey|primarykey|secondarykey|connectionstring)[^"]*"\s*:\s*)"[^"]*"`),
+		replacement: `${1}"` + redacted + `"`,
+	},
+	// bearer tokens, e.g. in logged request headers.
+	{
+		pattern:     regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-_.~+/]+=*`),
+		replacement: `${1}` + redacted,
+	},
+	// SAS signatures of storage URLs.
+	{
+		pattern:     regexp.MustCompile(`(?i)([?&]sig=)[^&\s"]+`),
+		replacement: `${1}` + redacted,
+	},
+}
+
+// redact replaces the credentials in the data.
+func redact(data []byte) []byte {
+	for _, r := range redactions {
+		data = r.pattern.ReplaceAll(data, []byte(r.replacement))
+	}
+	return data
+}
@@ -0,0 +1,35 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	npmapi "github.com/Azure/azure-container-networking/npm/http/api"
+	"github.com/Azure/azure-container-networking/platform"
+)
+
+// apiItems collects the CNS and NPM debug APIs, which are served on every OS.
+func apiItems(cfg *config) []item {
+	ipAddressesRequest, _ := json.Marshal(cns.GetIPAddressesRequest{
+		IPConfigStateFilter: []types.IPState{types.Assigned, types.Available, types.PendingRelease, types.PendingProgramming},
+	})
+	return []item{
+		httpItem("cns/debug/ipaddresses.json", cfg.cnsURL+cns.PathDebugIPAddresses, ipAddressesRequest),
+		httpItem("cns/debug/podcontext.json", cfg.cnsURL+cns.PathDebugPodContext, nil),
+		httpItem("cns/debug/restdata.json", cfg.cnsURL+cns.PathDebugRestData, nil),
+		httpItem("npm/debug/manager.json", cfg.npmURL+npmapi.NPMMgrPath, nil),
+	}
+}
+
+// commandItem collects the output of the shell command.
+func commandItem(path, command string) item {
+	return item{path: path, source: command, collect: func(ctx context.Context) ([]byte, error) {
+		out, err := platform.NewExecClient(nil).ExecuteCommandContext(ctx, command)
+		return []byte(out), err //nolint:wrapcheck // the error includes the command output
+	}}
+}
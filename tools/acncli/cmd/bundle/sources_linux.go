@@ -0,0 +1,34 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package bundle
+
+import (
+	"path/filepath"
+
+	"github.com/Azure/azure-container-networking/platform"
+)
+
+// sources returns the items of a bundle of a Linux node.
+func sources(cfg *config) []item {
+	items := []item{
+		fileItem("cns/azure-cns.json", platform.CNMRuntimePath+"azure-cns.json"),
+		fileItem("cns/azure-endpoints.json", "/var/run/azure-cns/azure-endpoints.json"),
+		fileItem("cni/azure-vnet.json", platform.CNIStateFilePath),
+		fileItem("cni/azure-vnet-ipam.json", platform.CNIIpamStatePath),
+	}
+	items = append(items, globItems("cni/conflist", filepath.Join(platform.K8SNetConfigPath, "*.conflist"))...)
+	items = append(items, apiItems(cfg)...)
+	items = append(items,
+		commandItem("dataplane/iptables-save.txt", "iptables-save"),
+		commandItem("dataplane/ip6tables-save.txt", "ip6tables-save"),
+		commandItem("dataplane/ipset-save.txt", "ipset save"),
+		commandItem("dataplane/ip-addr.txt", "ip addr show"),
+		commandItem("dataplane/ip-route.txt", "ip route show table all"),
+		commandItem("dataplane/ip-rule.txt", "ip rule show"),
+	)
+	items = append(items, logItems("logs", "/var/log/azure-vnet*.log", cfg.logLines)...)
+	items = append(items, logItems("logs", "/var/log/azure-cns*.log", cfg.logLines)...)
+	items = append(items, logItems("logs/azure-cns", "/var/log/azure-cns/*.log", cfg.logLines)...)
+	return items
+}
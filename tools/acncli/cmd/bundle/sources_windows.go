@@ -0,0 +1,40 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package bundle
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/Azure/azure-container-networking/platform"
+)
+
+// sources returns the items of a bundle of a Windows node.
+func sources(cfg *config) []item {
+	items := []item{
+		fileItem("cns/azure-endpoints.json", `C:\k\azurecns\azure-endpoints.json`),
+		fileItem("cni/azure-vnet.json", platform.CNIStateFilePath),
+		fileItem("cni/azure-vnet-ipam.json", platform.CNIIpamStatePath),
+	}
+	items = append(items, globItems("cni/conflist", filepath.Join(platform.K8SNetConfigPath, "*.conflist"))...)
+	items = append(items, apiItems(cfg)...)
+	items = append(items,
+		powershellItem("dataplane/hns-networks.json", "Get-HnsNetwork | ConvertTo-Json -Depth 10"),
+		powershellItem("dataplane/hns-endpoints.json", "Get-HnsEndpoint | ConvertTo-Json -Depth 10"),
+		powershellItem("dataplane/hns-policylists.json", "Get-HnsPolicyList | ConvertTo-Json -Depth 10"),
+		commandItem("dataplane/ipconfig.txt", "ipconfig /allcompartments /all"),
+		commandItem("dataplane/route.txt", "route print"),
+	)
+	items = append(items, logItems("logs", `C:\k\azure-vnet*.log`, cfg.logLines)...)
+	items = append(items, logItems("logs/azure-cns", `C:\k\azurecns\*.log`, cfg.logLines)...)
+	return items
+}
+
+// powershellItem collects the output of the powershell command.
+func powershellItem(path, command string) item {
+	return item{path: path, source: command, collect: func(ctx context.Context) ([]byte, error) {
+		out, err := platform.NewExecClient(nil).ExecutePowershellCommandContext(ctx, command)
+		return []byte(out), err //nolint:wrapcheck // the error includes the command output
+	}}
+}
@@ -21,6 +21,7 @@ func CNICmd() *cobra.Command {
 	viper.AutomaticEnv()
 
 	cmd.AddCommand(InstallCmd())
+	cmd.AddCommand(UpgradeCmd())
 	cmd.AddCommand(LogsCmd())
 	cmd.AddCommand(ManagerCmd())
 	return cmd
@@ -0,0 +1,72 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package cni
+
+import (
+	"fmt"
+	"strings"
+
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	i "github.com/Azure/azure-container-networking/tools/acncli/installer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// UpgradeCmd installs or upgrades CNI atomically, for init containers
+func UpgradeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Atomically installs or upgrades an ACN component",
+	}
+	cmd.AddCommand(UpgradeCNICmd())
+	return cmd
+}
+
+func UpgradeCNICmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cni",
+		Short: "Atomically installs or upgrades CNI binaries and conflists, keeping the previous version for rollback",
+		Long: "Validates the conflists of the source directory, writes every file to a temporary file next to its " +
+			"destination, then renames them over the installed binaries and conflists, keeping the replaced files with " +
+			"the .old suffix. A failed upgrade leaves the previous version in place. --rollback restores the version " +
+			"replaced by the last upgrade.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if viper.GetBool(c.FlagRollback) {
+				return i.RollbackAtomic(viper.GetString(c.FlagBinDirectory))
+			}
+
+			envs := i.InstallerConfig{
+				ExemptBins: make(map[string]bool),
+			}
+
+			// only allow windows and linux binaries
+			if err := envs.SetOSType(viper.GetString(c.FlagOS)); err != nil {
+				return err
+			}
+
+			// only allow singletenancy and multitenancy
+			if err := envs.SetCNIType(viper.GetString(c.FlagTenancy)); err != nil {
+				return err
+			}
+
+			envs.SetExempt(strings.Split(strings.Replace(strings.ToLower(viper.GetString(c.FlagExempt)), " ", "", -1), ","))
+
+			envs.SrcDir = i.SetOrUseDefault(viper.GetString(c.FlagSrcDirectory), fmt.Sprintf("%s%s/%s/", c.DefaultSrcDirLinux, envs.OSType, envs.CNITenancy))
+			envs.DstBinDir = viper.GetString(c.FlagBinDirectory)
+			envs.DstConflistDir = viper.GetString(c.FlagConflistDirectory)
+
+			return i.InstallAtomic(envs)
+		},
+	}
+
+	cmd.Flags().String(c.FlagOS, c.Defaults[c.FlagOS], fmt.Sprintf("Specify which operating system to install, options are %s and %s", c.Linux, c.Windows))
+	cmd.Flags().String(c.FlagTenancy, c.Defaults[c.FlagTenancy], fmt.Sprintf("Tenancy option for Azure CNI, options are %s and %s", c.Singletenancy, c.Multitenancy))
+	cmd.Flags().String(c.FlagSrcDirectory, "", "Source of the Azure CNI binaries and conflists, defaults to the packaged files of the os and tenancy")
+	cmd.Flags().String(c.FlagBinDirectory, c.Defaults[c.FlagBinDirectory], "Destination where Azure CNI binaries will be installed")
+	cmd.Flags().String(c.FlagConflistDirectory, c.Defaults[c.FlagConflistDirectory], "Destination where Azure CNI conflists will be installed")
+	cmd.Flags().String(c.FlagExempt, c.Defaults[c.FlagExempt], "Exempt files that won't be installed")
+	cmd.Flags().Bool(c.FlagRollback, c.DefaultToggles[c.FlagRollback], "Restore the binaries and conflists replaced by the last upgrade")
+
+	return cmd
+}
@@ -0,0 +1,303 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package installer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+const (
+	// previousSuffix is appended to the installed files an upgrade replaces, like dropgz does.
+	previousSuffix = ".old"
+	// manifestName is the record of the last atomic install in the bin directory, which rollback reverts.
+	manifestName = ".azure-cni-install.json"
+)
+
+var (
+	ErrInvalidConflist = errors.New("invalid conflist")
+	ErrNothingToRoll   = errors.New("no install to roll back")
+	ErrNoFilesToDeploy = errors.New("no binaries or conflists to install")
+)
+
+// installedFile is a file of an atomic install, in its manifest.
+type installedFile struct {
+	Path string `json:"path"`
+	// HasPrevious is true when the install replaced a file, which was kept with the previous suffix.
+	HasPrevious bool `json:"hasPrevious"`
+}
+
+// stagedFile is a file written to a temporary file next to its destination, ready to be renamed over it.
+type stagedFile struct {
+	tmp string
+	dst string
+}
+
+// InstallAtomic installs or upgrades the binaries and conflists of the source directory, so that a failure at any
+// point leaves either the previous or the new version of each file in place, never a partial file:
+//   - the conflists are validated before anything is written;
+//   - every file is written and synced to a temporary file in its destination directory;
+//   - the binaries, then the conflists, are renamed over their destinations, so that a conflist never references a
+//     binary which is not installed yet, keeping the file they replace with the .old suffix.
+//
+// The installed files are recorded, so that RollbackAtomic can restore the previous version.
+func InstallAtomic(installerConf InstallerConfig) error {
+	for _, dir := range []string{installerConf.DstBinDir, installerConf.DstConflistDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gomnd // directory permissions
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	fmt.Printf("📦 - Getting binary and conflist paths in (%s)...\n", installerConf.SrcDir)
+	binaries, conflists, err := getFiles(installerConf.SrcDir)
+	if err != nil {
+		return fmt.Errorf("failed to get CNI related file paths: %w", err)
+	}
+	installBinaries := make([]string, 0, len(binaries))
+	for _, path := range binaries {
+		if installerConf.ExemptBins[filepath.Base(path)] {
+			fmt.Printf("Skipping %s, marked as exempt\n", filepath.Base(path))
+			continue
+		}
+		installBinaries = append(installBinaries, path)
+	}
+	if len(installBinaries) == 0 && len(conflists) == 0 {
+		return fmt.Errorf("%w in %s", ErrNoFilesToDeploy, installerConf.SrcDir)
+	}
+
+	fmt.Printf("🔍 - Validating conflists...\n")
+	for _, conflist := range conflists {
+		if err := validateConflist(conflist, installBinaries, installerConf.DstBinDir); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("🚚 - Staging files...\n")
+	staged := make([]stagedFile, 0, len(installBinaries)+len(conflists))
+	cleanup := func() {
+		for _, s := range staged {
+			_ = os.Remove(s.tmp)
+		}
+	}
+	for _, path := range installBinaries {
+		s, err := stageFile(path, filepath.Join(installerConf.DstBinDir, filepath.Base(path)), 0o755) //nolint:gomnd // executable
+		if err != nil {
+			cleanup()
+			return err
+		}
+		staged = append(staged, s)
+	}
+	for _, path := range conflists {
+		s, err := stageFile(path, filepath.Join(installerConf.DstConflistDir, filepath.Base(path)), 0o644) //nolint:gomnd // config
+		if err != nil {
+			cleanup()
+			return err
+		}
+		staged = append(staged, s)
+	}
+
+	installed := make([]installedFile, 0, len(staged))
+	for i, s := range staged {
+		fmt.Printf("🚛 - Installing %v...\n", s.dst)
+		file, err := commitFile(s)
+		if err != nil {
+			cleanup()
+			if rollbackErr := revert(installed); rollbackErr != nil {
+				return fmt.Errorf("failed to install %s: %w, and to revert the files installed before it: %v", s.dst, err, rollbackErr) //nolint:errorlint // both errors are reported
+			}
+			return fmt.Errorf("failed to install %s, reverted the files installed before it: %w", s.dst, err)
+		}
+		installed = append(installed, file)
+		staged[i].tmp = ""
+	}
+
+	if err := writeManifest(installerConf.DstBinDir, installed); err != nil {
+		return err
+	}
+	fmt.Printf("🚀 - Successfully installed Azure CNI and binaries to %s and conflist to %s\n", installerConf.DstBinDir, installerConf.DstConflistDir)
+	return nil
+}
+
+// RollbackAtomic restores the files replaced by the last InstallAtomic into the bin directory, and removes the files it
+// added. It can only roll back once, since the previous version is restored in place.
+func RollbackAtomic(dstBinDir string) error {
+	manifestPath := filepath.Join(dstBinDir, manifestName)
+	data, err := os.ReadFile(manifestPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s does not exist", ErrNothingToRoll, manifestPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read install manifest: %w", err)
+	}
+	var installed []installedFile
+	if err := json.Unmarshal(data, &installed); err != nil {
+		return fmt.Errorf("failed to parse install manifest %s: %w", manifestPath, err)
+	}
+
+	if err := revert(installed); err != nil {
+		return err
+	}
+	if err := os.Remove(manifestPath); err != nil {
+		return fmt.Errorf("failed to remove install manifest: %w", err)
+	}
+	fmt.Printf("⏪ - Successfully rolled back %d files\n", len(installed))
+	return nil
+}
+
+// revert restores the previous version of the files in reverse order of install, so that the conflists are reverted
+// before the binaries they reference.
+func revert(installed []installedFile) error {
+	for i := len(installed) - 1; i >= 0; i-- {
+		file := installed[i]
+		if file.HasPrevious {
+			fmt.Printf("⏪ - Restoring %v...\n", file.Path)
+			if err := os.Rename(file.Path+previousSuffix, file.Path); err != nil {
+				return fmt.Errorf("failed to restore previous version of %s: %w", file.Path, err)
+			}
+			continue
+		}
+		fmt.Printf("⏪ - Removing %v...\n", file.Path)
+		if err := os.Remove(file.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// validateConflist checks that the conflist parses like the container runtime parses it, has a name and a supported
+// CNI version, and that the binary of each of its plugins is installed or being installed.
+func validateConflist(path string, binaries []string, dstBinDir string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read conflist %s: %w", path, err)
+	}
+	conflist, err := libcni.ConfListFromBytes(data)
+	if err != nil {
+		return fmt.Errorf("%w %s: %v", ErrInvalidConflist, path, err) //nolint:errorlint // the sentinel is wrapped
+	}
+	if conflist.Name == "" {
+		return fmt.Errorf("%w %s: empty name", ErrInvalidConflist, path)
+	}
+	supported := false
+	for _, v := range version.All.SupportedVersions() {
+		if v == conflist.CNIVersion {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("%w %s: unsupported cniVersion %q", ErrInvalidConflist, path, conflist.CNIVersion)
+	}
+
+	for _, plugin := range conflist.Plugins {
+		if !binaryAvailable(plugin.Network.Type, binaries, dstBinDir) {
+			return fmt.Errorf("%w %s: binary of plugin %s is neither installed in %s nor being installed",
+				ErrInvalidConflist, path, plugin.Network.Type, dstBinDir)
+		}
+	}
+	return nil
+}
+
+func binaryAvailable(pluginType string, binaries []string, dstBinDir string) bool {
+	for _, path := range binaries {
+		name := filepath.Base(path)
+		if name == pluginType || strings.TrimSuffix(name, ".exe") == pluginType {
+			return true
+		}
+	}
+	for _, name := range []string{pluginType, pluginType + ".exe"} {
+		if _, err := os.Stat(filepath.Join(dstBinDir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// stageFile writes the source to a synced temporary file in the directory of the destination, so that it can be
+// renamed over the destination atomically.
+func stageFile(src, dst string, perm os.FileMode) (stagedFile, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return stagedFile{}, fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return stagedFile{}, fmt.Errorf("failed to create temporary file for %s: %w", dst, err)
+	}
+	s := stagedFile{tmp: tmp.Name(), dst: dst}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(s.tmp)
+		return stagedFile{}, fmt.Errorf("failed to write temporary file for %s: %w", dst, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(s.tmp)
+		return stagedFile{}, fmt.Errorf("failed to sync temporary file for %s: %w", dst, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(s.tmp)
+		return stagedFile{}, fmt.Errorf("failed to close temporary file for %s: %w", dst, err)
+	}
+	if err := os.Chmod(s.tmp, perm); err != nil {
+		os.Remove(s.tmp)
+		return stagedFile{}, fmt.Errorf("failed to set permissions of temporary file for %s: %w", dst, err)
+	}
+	return s, nil
+}
+
+// commitFile keeps the current version of the destination with the previous suffix, and renames the staged file over
+// it. The destination is a hard link to the previous version until the rename, so it never goes missing.
+func commitFile(s stagedFile) (installedFile, error) {
+	file := installedFile{Path: s.dst}
+	if info, err := os.Stat(s.dst); err == nil {
+		previous := s.dst + previousSuffix
+		if err := os.Remove(previous); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return file, fmt.Errorf("failed to remove %s: %w", previous, err)
+		}
+		if err := os.Link(s.dst, previous); err != nil {
+			// hard links may not be supported by the file system, fall back to a copy
+			if err := copyFile(s.dst, previous, info.Mode().Perm()); err != nil {
+				return file, fmt.Errorf("failed to keep previous version of %s: %w", s.dst, err)
+			}
+		}
+		file.HasPrevious = true
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return file, fmt.Errorf("failed to stat %s: %w", s.dst, err)
+	}
+	if err := os.Rename(s.tmp, s.dst); err != nil {
+		return file, fmt.Errorf("failed to rename %s to %s: %w", s.tmp, s.dst, err)
+	}
+	return file, nil
+}
+
+func writeManifest(dstBinDir string, installed []installedFile) error {
+	data, err := json.MarshalIndent(installed, "", "\t")
+	if err != nil {
+		return fmt.Errorf("failed to marshal install manifest: %w", err)
+	}
+	tmp, err := os.CreateTemp(dstBinDir, manifestName+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create install manifest: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dstBinDir, manifestName))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write install manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package zapai
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/microsoft/ApplicationInsights-Go/appinsights"
+	"github.com/microsoft/ApplicationInsights-Go/appinsights/contracts"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultQueueSize     = 4096
+	defaultBatchSize     = 256
+	defaultFlushInterval = 5 * time.Second
+	defaultGracePeriod   = 10 * time.Second
+)
+
+// BatchConfig is the configuration of a BatchCore.
+type BatchConfig struct {
+	appinsights.TelemetryConfiguration
+	// Level is the minimum severity forwarded to appinsights.
+	Level zapcore.Level
+	// QueueSize is the number of traces buffered ahead of the appinsights client.
+	QueueSize int
+	// BatchSize is the number of traces handed to the appinsights client at once.
+	BatchSize int
+	// FlushInterval is the longest a trace waits in the queue before it is handed to the appinsights client.
+	FlushInterval time.Duration
+	// EnqueueTimeout is how long a log call waits for room when the queue is full before its trace is dropped.
+	// Zero drops it right away, so that logging never blocks on appinsights.
+	EnqueueTimeout time.Duration
+	// GracePeriod is how long Close waits for the queued traces to be sent.
+	GracePeriod time.Duration
+	// Tags maps zap field names to appinsights tags, like the DefaultMappers.
+	Tags map[string]string
+	// Dimensions maps zap field names to the custom dimension they are sent as. Other fields keep their name.
+	Dimensions map[string]string
+}
+
+func (cfg *BatchConfig) setDefaults() {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = defaultGracePeriod
+	}
+}
+
+var _ zapcore.Core = (*BatchCore)(nil)
+
+// BatchCore implements zapcore.Core for appinsights, without the Sink.
+//
+// Unlike the Core, which serializes every entry for the Sink under a shared lock, the BatchCore builds the
+// appinsights.TraceTelemetry in the calling goroutine and queues it. A single background goroutine hands the
+// queued traces to the appinsights client in batches. When the queue is full, traces are dropped rather than
+// blocking the caller, and the number dropped is reported to appinsights with the next batch.
+type BatchCore struct {
+	zapcore.LevelEnabler
+	tags       map[string]string
+	dimensions map[string]string
+	fields     []zapcore.Field
+	q          *traceQueue
+}
+
+// NewBatchCore creates a BatchCore sending to appinsights with the passed configuration, and starts its
+// background goroutine. The BatchCore must be closed to send the queued traces and stop the goroutine.
+func NewBatchCore(cfg *BatchConfig) *BatchCore {
+	return newBatchCore(cfg, appinsights.NewTelemetryClientFromConfig(&cfg.TelemetryConfiguration))
+}
+
+func newBatchCore(cfg *BatchConfig, cli telemetryTracker) *BatchCore {
+	cfg.setDefaults()
+	tags := make(map[string]string, len(cfg.Tags))
+	for field, tag := range cfg.Tags {
+		tags[field] = tag
+	}
+	dimensions := make(map[string]string, len(cfg.Dimensions))
+	for field, dimension := range cfg.Dimensions {
+		dimensions[field] = dimension
+	}
+	q := &traceQueue{
+		cli:            cli,
+		traces:         make(chan *appinsights.TraceTelemetry, cfg.QueueSize),
+		batchSize:      cfg.BatchSize,
+		flushInterval:  cfg.FlushInterval,
+		enqueueTimeout: cfg.EnqueueTimeout,
+		gracePeriod:    cfg.GracePeriod,
+		syncs:          make(chan chan struct{}),
+		done:           make(chan struct{}),
+		stopped:        make(chan struct{}),
+	}
+	go q.run()
+	return &BatchCore{
+		LevelEnabler: cfg.Level,
+		tags:         tags,
+		dimensions:   dimensions,
+		q:            q,
+	}
+}
+
+func (c *BatchCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = make([]zapcore.Field, len(c.fields), len(c.fields)+len(fields))
+	copy(clone.fields, c.fields)
+	clone.fields = append(clone.fields, fields...)
+	return &clone
+}
+
+// Check implements zapcore.Core
+//nolint:gocritic // ignore hugeparam in interface impl
+func (c *BatchCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core
+//nolint:gocritic // ignore hugeparam in interface impl
+func (c *BatchCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	t := appinsights.NewTraceTelemetry(entry.Message, levelToSev[entry.Level])
+	t.Timestamp = entry.Time
+
+	if entry.Caller.Defined {
+		t.Properties["caller"] = entry.Caller.String()
+	}
+
+	// the gobber is only used as the zapcore.ObjectEncoder of the trace properties here
+	enc := &gobber{traceTelemetry: t}
+	for _, fs := range [][]zapcore.Field{c.fields, fields} {
+		for i := range fs {
+			f := &fs[i]
+			if f.Type == zapcore.ObjectMarshalerType {
+				f.AddTo(enc)
+			} else if tag, ok := c.tags[f.Key]; ok {
+				t.Tags[tag] = fieldStringer(f)
+			} else if dimension, ok := c.dimensions[f.Key]; ok {
+				t.Properties[dimension] = fieldStringer(f)
+			} else {
+				t.Properties[f.Key] = fieldStringer(f)
+			}
+		}
+	}
+
+	// a dropped trace is not an error, which zap would report for every entry while the queue is full
+	c.q.enqueue(t)
+	return nil
+}
+
+// Sync hands all queued traces to the appinsights client and flushes it.
+func (c *BatchCore) Sync() error {
+	return c.q.sync()
+}
+
+// Dropped returns the number of traces dropped because the queue was full.
+func (c *BatchCore) Dropped() uint64 {
+	return c.q.dropped.Load()
+}
+
+// Close sends the queued traces, waiting up to the GracePeriod, and stops the background goroutine.
+// Logging to the BatchCore after Close drops the traces.
+func (c *BatchCore) Close() error {
+	return c.q.close()
+}
+
+// traceQueue is the bounded queue shared by a BatchCore and its clones, drained by a single goroutine.
+type traceQueue struct {
+	cli            telemetryTracker
+	traces         chan *appinsights.TraceTelemetry
+	batchSize      int
+	flushInterval  time.Duration
+	enqueueTimeout time.Duration
+	gracePeriod    time.Duration
+
+	dropped atomic.Uint64
+	// reported is the count of dropped traces already reported to appinsights, only used by run.
+	reported uint64
+
+	// mu is held shared by enqueue and exclusively by close, so that no trace is queued after the final drain.
+	mu     sync.RWMutex
+	closed bool
+
+	syncs     chan chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (q *traceQueue) enqueue(t *appinsights.TraceTelemetry) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		q.dropped.Add(1)
+		return
+	}
+	select {
+	case q.traces <- t:
+		return
+	default:
+	}
+	if q.enqueueTimeout > 0 {
+		timer := time.NewTimer(q.enqueueTimeout)
+		defer timer.Stop()
+		select {
+		case q.traces <- t:
+			return
+		case <-timer.C:
+		}
+	}
+	q.dropped.Add(1)
+}
+
+func (q *traceQueue) run() {
+	defer close(q.stopped)
+	ticker := time.NewTicker(q.flushInterval)
+	defer ticker.Stop()
+	batch := make([]*appinsights.TraceTelemetry, 0, q.batchSize)
+	for {
+		select {
+		case t := <-q.traces:
+			batch = append(batch, t)
+			if len(batch) >= q.batchSize {
+				batch = q.send(batch)
+			}
+		case <-ticker.C:
+			batch = q.send(batch)
+		case synced := <-q.syncs:
+			batch = q.send(q.drain(batch))
+			q.cli.Channel().Flush()
+			close(synced)
+		case <-q.done:
+			q.send(q.drain(batch))
+			return
+		}
+	}
+}
+
+// drain appends the traces queued so far to the batch.
+func (q *traceQueue) drain(batch []*appinsights.TraceTelemetry) []*appinsights.TraceTelemetry {
+	for {
+		select {
+		case t := <-q.traces:
+			batch = append(batch, t)
+		default:
+			return batch
+		}
+	}
+}
+
+// send hands the batch to the appinsights client, along with a warning when traces were dropped since the last
+// batch, and returns the emptied batch.
+func (q *traceQueue) send(batch []*appinsights.TraceTelemetry) []*appinsights.TraceTelemetry {
+	for _, t := range batch {
+		q.cli.Track(t)
+	}
+	if dropped := q.dropped.Load(); dropped > q.reported {
+		t := appinsights.NewTraceTelemetry("appinsights log queue was full, dropped traces", contracts.Warning)
+		t.Properties["dropped"] = strconv.FormatUint(dropped-q.reported, 10)
+		q.cli.Track(t)
+		q.reported = dropped
+	}
+	return batch[:0]
+}
+
+func (q *traceQueue) sync() error {
+	synced := make(chan struct{})
+	select {
+	case q.syncs <- synced:
+	case <-q.stopped:
+		return nil
+	}
+	<-synced
+	return nil
+}
+
+func (q *traceQueue) close() error {
+	q.closeOnce.Do(func() {
+		q.mu.Lock()
+		q.closed = true
+		close(q.done)
+		q.mu.Unlock()
+		<-q.stopped
+		ctx, cancel := context.WithTimeout(context.Background(), q.gracePeriod)
+		defer cancel()
+		select {
+		case <-ctx.Done():
+			q.closeErr = errors.Wrap(ctx.Err(), "batch core close context timeout")
+		case <-q.cli.Channel().Close(q.gracePeriod):
+		}
+	})
+	return q.closeErr
+}
@@ -0,0 +1,240 @@
+package zapai
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/microsoft/ApplicationInsights-Go/appinsights"
+	"github.com/microsoft/ApplicationInsights-Go/appinsights/contracts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeTracker records the traces handed to the appinsights client.
+type fakeTracker struct {
+	sync.Mutex
+	traces  []*appinsights.TraceTelemetry
+	flushes int
+	// tracking, when set, is signaled when Track is called, which then blocks until release is closed.
+	tracking chan struct{}
+	release  chan struct{}
+	// closed is returned from the Close of the channel, never closed when nil.
+	closed chan struct{}
+}
+
+func (f *fakeTracker) Track(t appinsights.Telemetry) {
+	f.Lock()
+	f.traces = append(f.traces, t.(*appinsights.TraceTelemetry))
+	f.Unlock()
+	if f.release != nil {
+		select {
+		case f.tracking <- struct{}{}:
+		default:
+		}
+		<-f.release
+	}
+}
+
+func (f *fakeTracker) Channel() appinsights.TelemetryChannel {
+	return &fakeChannel{f}
+}
+
+func (f *fakeTracker) tracked() []*appinsights.TraceTelemetry {
+	f.Lock()
+	defer f.Unlock()
+	return append([]*appinsights.TraceTelemetry(nil), f.traces...)
+}
+
+func (f *fakeTracker) messages() []string {
+	var msgs []string
+	for _, t := range f.tracked() {
+		msgs = append(msgs, t.Message)
+	}
+	return msgs
+}
+
+type fakeChannel struct {
+	f *fakeTracker
+}
+
+func (c *fakeChannel) EndpointAddress() string                { return "" }
+func (c *fakeChannel) Send(*contracts.Envelope)               {}
+func (c *fakeChannel) Stop()                                  {}
+func (c *fakeChannel) IsThrottled() bool                      { return false }
+func (c *fakeChannel) Close(...time.Duration) <-chan struct{} { return c.f.closed }
+
+func (c *fakeChannel) Flush() {
+	c.f.Lock()
+	c.f.flushes++
+	c.f.Unlock()
+}
+
+func newTestBatchCore(t *testing.T, cfg *BatchConfig, f *fakeTracker) (*BatchCore, *zap.Logger) {
+	t.Helper()
+	if f.closed == nil {
+		f.closed = make(chan struct{})
+		close(f.closed)
+	}
+	c := newBatchCore(cfg, f)
+	t.Cleanup(func() { _ = c.Close() })
+	return c, zap.New(c)
+}
+
+func TestBatchCoreFlushesFullBatch(t *testing.T) {
+	f := &fakeTracker{}
+	_, logger := newTestBatchCore(t, &BatchConfig{BatchSize: 2, FlushInterval: time.Hour}, f)
+
+	logger.Info("first")
+	require.Never(t, func() bool { return len(f.tracked()) > 0 }, 50*time.Millisecond, 5*time.Millisecond)
+
+	logger.Info("second")
+	require.Eventually(t, func() bool { return len(f.tracked()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"first", "second"}, f.messages())
+}
+
+func TestBatchCoreFlushesOnInterval(t *testing.T) {
+	f := &fakeTracker{}
+	_, logger := newTestBatchCore(t, &BatchConfig{BatchSize: 100, FlushInterval: 10 * time.Millisecond}, f)
+
+	logger.Info("lonely")
+	require.Eventually(t, func() bool { return len(f.tracked()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"lonely"}, f.messages())
+}
+
+func TestBatchCoreLevel(t *testing.T) {
+	f := &fakeTracker{}
+	c, logger := newTestBatchCore(t, &BatchConfig{Level: zapcore.WarnLevel, FlushInterval: time.Hour}, f)
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+	require.NoError(t, c.Sync())
+
+	traces := f.tracked()
+	require.Len(t, traces, 2)
+	assert.Equal(t, "warn", traces[0].Message)
+	assert.Equal(t, contracts.Warning, traces[0].SeverityLevel)
+	assert.Equal(t, "error", traces[1].Message)
+	assert.Equal(t, contracts.Error, traces[1].SeverityLevel)
+}
+
+func TestBatchCoreFieldMapping(t *testing.T) {
+	f := &fakeTracker{}
+	c, logger := newTestBatchCore(t, &BatchConfig{
+		FlushInterval: time.Hour,
+		Tags:          map[string]string{"node": "ai.cloud.roleInstance"},
+		Dimensions:    map[string]string{"pod": "PodName"},
+	}, f)
+
+	logger.With(zap.String("node", "node-1")).Info("mapped", zap.String("pod", "pod-1"), zap.Int64("other", 3))
+	require.NoError(t, c.Sync())
+
+	traces := f.tracked()
+	require.Len(t, traces, 1)
+	assert.Equal(t, "node-1", traces[0].Tags["ai.cloud.roleInstance"])
+	assert.Equal(t, "pod-1", traces[0].Properties["PodName"])
+	assert.Equal(t, "3", traces[0].Properties["other"])
+	assert.NotContains(t, traces[0].Properties, "node")
+	assert.NotContains(t, traces[0].Properties, "pod")
+}
+
+func TestBatchCoreDropsWhenQueueFull(t *testing.T) {
+	f := &fakeTracker{tracking: make(chan struct{}, 1), release: make(chan struct{})}
+	c, logger := newTestBatchCore(t, &BatchConfig{QueueSize: 1, BatchSize: 1, FlushInterval: time.Hour}, f)
+
+	// the first trace blocks the background goroutine in Track, the second fills the queue
+	logger.Info("sent")
+	<-f.tracking
+	logger.Info("queued")
+	logger.Info("dropped")
+	logger.Info("dropped")
+	assert.Equal(t, uint64(2), c.Dropped())
+
+	close(f.release)
+	require.NoError(t, c.Sync())
+
+	traces := f.tracked()
+	require.Len(t, traces, 3)
+	assert.Equal(t, []string{"sent", "appinsights log queue was full, dropped traces", "queued"}, f.messages())
+	assert.Equal(t, contracts.Warning, traces[1].SeverityLevel)
+	assert.Equal(t, "2", traces[1].Properties["dropped"])
+
+	// the drops are reported once
+	logger.Info("after")
+	require.NoError(t, c.Sync())
+	assert.Len(t, f.tracked(), 4)
+}
+
+func TestBatchCoreSyncDrainsQueue(t *testing.T) {
+	f := &fakeTracker{}
+	c, logger := newTestBatchCore(t, &BatchConfig{BatchSize: 100, FlushInterval: time.Hour}, f)
+
+	logger.Info("one")
+	logger.Info("two")
+	logger.Info("three")
+	require.NoError(t, c.Sync())
+
+	assert.Equal(t, []string{"one", "two", "three"}, f.messages())
+	f.Lock()
+	assert.Equal(t, 1, f.flushes)
+	f.Unlock()
+}
+
+func TestBatchCoreClose(t *testing.T) {
+	f := &fakeTracker{}
+	c, logger := newTestBatchCore(t, &BatchConfig{BatchSize: 100, FlushInterval: time.Hour}, f)
+
+	logger.Info("queued")
+	require.NoError(t, c.Close())
+	assert.Equal(t, []string{"queued"}, f.messages())
+
+	// traces logged after Close are dropped
+	logger.Info("late")
+	require.NoError(t, c.Sync())
+	assert.Equal(t, []string{"queued"}, f.messages())
+	assert.Equal(t, uint64(1), c.Dropped())
+	require.NoError(t, c.Close())
+}
+
+func TestBatchCoreCloseWhileLogging(t *testing.T) {
+	f := &fakeTracker{}
+	c, logger := newTestBatchCore(t, &BatchConfig{QueueSize: 16, BatchSize: 4, FlushInterval: time.Hour}, f)
+
+	const loggers, traces = 8, 200
+	var wg sync.WaitGroup
+	for i := 0; i < loggers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < traces; j++ {
+				logger.Info("trace")
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	require.NoError(t, c.Close())
+	wg.Wait()
+
+	// every trace is either sent or counted as dropped, none is left in the queue after Close
+	var sent uint64
+	for _, msg := range f.messages() {
+		if msg == "trace" {
+			sent++
+		}
+	}
+	assert.Equal(t, uint64(loggers*traces), sent+c.Dropped())
+}
+
+func TestBatchCoreCloseGracePeriod(t *testing.T) {
+	// the channel never finishes sending, so Close gives up after the GracePeriod
+	f := &fakeTracker{closed: make(chan struct{})}
+	c, _ := newTestBatchCore(t, &BatchConfig{GracePeriod: 20 * time.Millisecond}, f)
+
+	start := time.Now()
+	require.Error(t, c.Close())
+	assert.Less(t, time.Since(start), time.Second)
+}